@@ -0,0 +1,416 @@
+// access classifies the guards of the functions: the checks of their
+// modifiers and the leading checks of their bodies that restrict who can
+// call them and when. Like the metrics, the classification is purely
+// syntactic; the only thing that needs to be resolved are the modifiers,
+// whose leading checks guard every function that invokes them.
+package access
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/token"
+	"strings"
+)
+
+// Kind is the category of a guard.
+type Kind string
+
+const (
+	Owner     Kind = "owner-only"  // the caller is compared to an owner e.g. `msg.sender == owner`
+	Role      Kind = "role-gated"  // the caller must have a role e.g. `hasRole(MINTER_ROLE, msg.sender)`
+	Pause     Kind = "pause-gated" // the contract must be paused or not e.g. `whenNotPaused`
+	Other     Kind = "other"       // an unrecognized check
+	Unguarded Kind = "unguarded"   // the category of the functions without guards
+)
+
+// Kinds are the categories in the order of the reports.
+var Kinds = []Kind{Owner, Role, Pause, Other, Unguarded}
+
+// The subjects of the pause guards.
+const (
+	NotPaused = "not paused"
+	Paused    = "paused"
+)
+
+type Guard struct {
+	Kind     Kind
+	Subject  string      // owner expression e.g. "owner()", role e.g. "MINTER_ROLE", Paused or NotPaused, or the condition text of the other guards
+	Modifier string      // name of the modifier with the check; or empty if the check is in the function
+	Range    token.Range // range of the modifier invocation or of the check in the function
+}
+
+// ModifierResolver returns the declaration of the modifier with the given
+// name, as seen from the contract; or nil if there is none.
+type ModifierResolver func(contract *ast.ContractDeclaration, name string) *ast.ModifierDeclaration
+
+// knownModifiers are classified by name, whether their declarations can be
+// found or not.
+var knownModifiers = map[string]Guard{
+	"onlyOwner":      {Kind: Owner, Subject: "owner"},
+	"onlyAdmin":      {Kind: Owner, Subject: "admin"},
+	"onlyGovernance": {Kind: Owner, Subject: "governance"},
+	"whenNotPaused":  {Kind: Pause, Subject: NotPaused},
+	"whenPaused":     {Kind: Pause, Subject: Paused},
+}
+
+// nonGuards are the modifiers that don't restrict the callers, even though
+// they start with a check.
+var nonGuards = map[string]bool{
+	"nonReentrant":     true,
+	"nonReentrantView": true,
+}
+
+// FunctionGuards returns the guards of the function, as invoked on the
+// contract: the ones of its modifiers in the order of the invocations,
+// followed by the leading checks of its body. The modifiers that can't be
+// resolved and aren't known by name are the other guards.
+func FunctionGuards(fn *ast.FunctionDeclaration, contract *ast.ContractDeclaration, resolve ModifierResolver) []Guard {
+	res := []Guard{}
+	for _, inv := range fn.Modifiers {
+		name := ast.ModifierName(inv)
+		if name == "" || nonGuards[name] {
+			continue
+		}
+		invRange := ast.NodeRange(inv)
+		if g, ok := knownModifiers[name]; ok {
+			g.Modifier, g.Range = name, invRange
+			res = append(res, g)
+			continue
+		}
+		if name == "onlyRole" || name == "onlyRoles" {
+			roles := []string{}
+			for _, arg := range inv.Args {
+				roles = append(roles, ast.ExprString(arg))
+			}
+			res = append(res, Guard{Kind: Role, Subject: strings.Join(roles, ", "), Modifier: name, Range: invRange})
+			continue
+		}
+
+		var mod *ast.ModifierDeclaration
+		if contract != nil && resolve != nil {
+			mod = resolve(contract, name)
+		}
+		if mod == nil {
+			// The constructors call the base constructors the same way.
+			if fn.Kind != token.CONSTRUCTOR {
+				res = append(res, Guard{Kind: Other, Subject: ast.ExprString(inv.Name) + args(inv), Modifier: name, Range: invRange})
+			}
+			continue
+		}
+		if mod.Body == nil {
+			continue
+		}
+		c := checker{bindings: map[string]ast.Expression{}, params: map[string]bool{}}
+		if mod.Params != nil {
+			for i, p := range mod.Params.List {
+				if p.Name != nil && i < len(inv.Args) {
+					c.bindings[p.Name.Name] = inv.Args[i]
+				}
+			}
+		}
+		for _, g := range c.checks(mod.Body) {
+			g.Modifier, g.Range = name, invRange
+			res = append(res, g)
+		}
+	}
+
+	if fn.Body != nil {
+		c := checker{bindings: map[string]ast.Expression{}, params: map[string]bool{}}
+		if fn.Type.Params != nil {
+			for _, p := range fn.Type.Params.List {
+				if p.Name != nil {
+					c.params[p.Name.Name] = true
+				}
+			}
+		}
+		res = append(res, c.checks(fn.Body)...)
+	}
+	return res
+}
+
+// Categories returns the distinct kinds of the guards in the order of
+// Kinds; or Unguarded if there are none.
+func Categories(guards []Guard) []Kind {
+	res := []Kind{}
+	for _, kind := range Kinds {
+		for _, g := range guards {
+			if g.Kind == kind {
+				res = append(res, kind)
+				break
+			}
+		}
+	}
+	if len(res) == 0 {
+		res = append(res, Unguarded)
+	}
+	return res
+}
+
+// Totals count the functions of each category. A function with guards of
+// several kinds is counted in each of their categories.
+type Totals map[Kind]int
+
+// Add counts the function with the guards.
+func (t Totals) Add(guards []Guard) {
+	for _, kind := range Categories(guards) {
+		t[kind]++
+	}
+}
+
+// String returns the summary of the totals in the order of Kinds e.g.
+// "7 functions owner-only, 3 functions pause-gated, 12 functions unguarded".
+// The categories without functions are left out.
+func (t Totals) String() string {
+	parts := []string{}
+	for _, kind := range Kinds {
+		switch n := t[kind]; n {
+		case 0:
+		case 1:
+			parts = append(parts, fmt.Sprintf("1 function %s", kind))
+		default:
+			parts = append(parts, fmt.Sprintf("%d functions %s", n, kind))
+		}
+	}
+	if len(parts) == 0 {
+		return "No functions"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checker classifies the checks of a function or modifier body.
+type checker struct {
+	bindings map[string]ast.Expression // modifier parameter -> argument of the invocation
+	params   map[string]bool           // the function parameters, which are never owners
+}
+
+// checks returns the guards of the leading checks of the body: the
+// requires, the ifs reverting and the calls to the OpenZeppelin checks like
+// `_checkOwner()`. The first other statement ends them, e.g. the
+// placeholder `_;` of a modifier. The ranges are the ones of the checks.
+func (c checker) checks(body *ast.BlockStatement) []Guard {
+	res := []Guard{}
+	for _, stmt := range body.Statements {
+		var guards []Guard
+		isCheck := false
+		switch stmt := stmt.(type) {
+		case *ast.ExpressionStatement:
+			if call, ok := stmt.Expression.(*ast.CallExpression); ok {
+				guards, isCheck = c.call(call)
+			}
+		case *ast.IfStatement:
+			if stmt.Alternative == nil && ast.Reverts(stmt.Consequence) {
+				guards, isCheck = c.classify(stmt.Condition, true), true
+			}
+		}
+		if !isCheck {
+			return res
+		}
+		for _, g := range guards {
+			g.Range = ast.NodeRange(stmt)
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+// call returns the guards of the check called; or false if it's not one.
+func (c checker) call(call *ast.CallExpression) ([]Guard, bool) {
+	fn, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	switch fn.Name {
+	case "require":
+		if len(call.Args) > 0 {
+			return c.classify(call.Args[0], false), true
+		}
+	case "_checkOwner":
+		return []Guard{{Kind: Owner, Subject: "owner()"}}, true
+	case "_checkRole":
+		if len(call.Args) > 0 {
+			return []Guard{{Kind: Role, Subject: c.text(call.Args[0])}}, true
+		}
+	case "_requireNotPaused":
+		return []Guard{{Kind: Pause, Subject: NotPaused}}, true
+	case "_requirePaused":
+		return []Guard{{Kind: Pause, Subject: Paused}}, true
+	}
+	return nil, false
+}
+
+// onlyParams reports whether the expression refers to nothing but the
+// parameters of the function and the literals.
+func (c checker) onlyParams(x ast.Expression) bool {
+	res := true
+	ast.Inspect(x, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok && !c.params[ident.Name] {
+			res = false
+		}
+		return res
+	})
+	return res
+}
+
+// classify returns the guards of the condition that must hold; or of its
+// negation if negated is true, for the ifs reverting.
+func (c checker) classify(x ast.Expression, negated bool) []Guard {
+	x = unparen(x)
+	switch x := x.(type) {
+	case *ast.UnaryExpression:
+		if x.Operator == token.NOT {
+			return c.classify(x.Operand, !negated)
+		}
+	case *ast.BinaryExpression:
+		// Both sides of `a && b` must hold, and so must the negations of
+		// both sides of `!(a || b)`.
+		if !negated && x.Operator == token.AND || negated && x.Operator == token.OR {
+			return append(c.classify(x.Left, negated), c.classify(x.Right, negated)...)
+		}
+		if x.Operator == token.EQUAL && !negated || x.Operator == token.NOT_EQUAL && negated {
+			if isSender(x.Left) && c.isOwner(x.Right) {
+				return []Guard{{Kind: Owner, Subject: c.text(x.Right)}}
+			}
+			if isSender(x.Right) && c.isOwner(x.Left) {
+				return []Guard{{Kind: Owner, Subject: c.text(x.Left)}}
+			}
+		}
+	case *ast.CallExpression:
+		if callee(x) == "hasRole" && len(x.Args) > 0 && !negated {
+			return []Guard{{Kind: Role, Subject: c.text(x.Args[0])}}
+		}
+	}
+	if isPaused(x) {
+		if negated {
+			return []Guard{{Kind: Pause, Subject: NotPaused}}
+		}
+		return []Guard{{Kind: Pause, Subject: Paused}}
+	}
+	// The validation of the parameters like `amount > 0` restricts neither
+	// the callers nor the states.
+	if c.onlyParams(x) {
+		return nil
+	}
+	if negated {
+		return []Guard{{Kind: Other, Subject: negate(x)}}
+	}
+	return []Guard{{Kind: Other, Subject: ast.ExprString(x)}}
+}
+
+// isOwner reports whether the expression compared to the caller can be an
+// owner: a state variable, a getter like `owner()` or a member like
+// `config.admin`. The parameters of the function are not, and neither are
+// the global variables like `tx.origin`.
+func (c checker) isOwner(x ast.Expression) bool {
+	switch x := unparen(x).(type) {
+	case *ast.Identifier:
+		if arg, ok := c.bindings[x.Name]; ok {
+			return c.isOwner(arg)
+		}
+		return !c.params[x.Name]
+	case *ast.CallExpression:
+		return len(x.Args) == 0 && callee(x) != "" && !isSender(x)
+	case *ast.MemberAccessExpression:
+		if ident, ok := x.Expression.(*ast.Identifier); ok {
+			return ident.Name != "msg" && ident.Name != "tx" && ident.Name != "block" && !c.params[ident.Name]
+		}
+		return true
+	}
+	return false
+}
+
+// text returns the expression text, with the modifier parameters replaced
+// by the arguments.
+func (c checker) text(x ast.Expression) string {
+	if ident, ok := x.(*ast.Identifier); ok {
+		if arg, ok := c.bindings[ident.Name]; ok {
+			return ast.ExprString(arg)
+		}
+	}
+	return ast.ExprString(x)
+}
+
+// isSender reports whether the expression is the caller: `msg.sender` or
+// `_msgSender()`.
+func isSender(x ast.Expression) bool {
+	switch x := unparen(x).(type) {
+	case *ast.MemberAccessExpression:
+		ident, ok := x.Expression.(*ast.Identifier)
+		return ok && ident.Name == "msg" && x.Member.Name == "sender"
+	case *ast.CallExpression:
+		return len(x.Args) == 0 && callee(x) == "_msgSender"
+	}
+	return false
+}
+
+// isPaused reports whether the expression is the pause state e.g. `paused`
+// or `paused()`.
+func isPaused(x ast.Expression) bool {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		return x.Name == "paused" || x.Name == "_paused" || x.Name == "isPaused"
+	case *ast.CallExpression:
+		name := callee(x)
+		return len(x.Args) == 0 && (name == "paused" || name == "isPaused")
+	}
+	return false
+}
+
+// callee returns the name of the called function e.g. "hasRole" in both
+// `hasRole(...)` and `acl.hasRole(...)`; or an empty string.
+func callee(call *ast.CallExpression) string {
+	switch fn := call.Function.(type) {
+	case *ast.Identifier:
+		return fn.Name
+	case *ast.MemberAccessExpression:
+		return fn.Member.Name
+	}
+	return ""
+}
+
+// negations are the comparisons written the other way round.
+var negations = map[token.TokenType]token.TokenType{
+	token.EQUAL:                 token.NOT_EQUAL,
+	token.NOT_EQUAL:             token.EQUAL,
+	token.LESS_THAN:             token.GREATER_THAN_OR_EQUAL,
+	token.GREATER_THAN_OR_EQUAL: token.LESS_THAN,
+	token.GREATER_THAN:          token.LESS_THAN_OR_EQUAL,
+	token.LESS_THAN_OR_EQUAL:    token.GREATER_THAN,
+}
+
+// negate returns the text of the negated condition e.g. `a >= b` for
+// `a < b`.
+func negate(x ast.Expression) string {
+	switch x := x.(type) {
+	case *ast.BinaryExpression:
+		if op, ok := negations[x.Operator]; ok {
+			negated := &ast.BinaryExpression{Left: x.Left, OpPos: x.OpPos, Operator: op, Right: x.Right}
+			return ast.ExprString(negated)
+		}
+	case *ast.Identifier, *ast.CallExpression, *ast.MemberAccessExpression, *ast.IndexAccessExpression:
+		return "!" + ast.ExprString(x)
+	}
+	return "!(" + ast.ExprString(x) + ")"
+}
+
+func unparen(x ast.Expression) ast.Expression {
+	for {
+		tuple, ok := x.(*ast.TupleExpression)
+		if !ok || len(tuple.Elements) != 1 || tuple.Elements[0] == nil {
+			return x
+		}
+		x = tuple.Elements[0]
+	}
+}
+
+// args returns the text of the arguments of the invocation e.g. "(1, 2)";
+// or an empty string if there are no parentheses.
+func args(inv *ast.ModifierInvocation) string {
+	if inv.Lparen == 0 {
+		return ""
+	}
+	texts := []string{}
+	for _, arg := range inv.Args {
+		texts = append(texts, ast.ExprString(arg))
+	}
+	return "(" + strings.Join(texts, ", ") + ")"
+}
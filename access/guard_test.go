@@ -0,0 +1,114 @@
+package access
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"solbot/ast"
+	"solbot/metrics"
+	"solbot/parser"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+func Test_FunctionGuards(t *testing.T) {
+	src, err := os.ReadFile("testdata/Treasury.sol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := parse(t, string(src))
+	resolve := ModifierResolver(metrics.LocalModifiers(file))
+
+	treasury := file.Declarations[len(file.Declarations)-1].(*ast.ContractDeclaration)
+	got := map[string]string{}
+	for _, member := range treasury.Body {
+		fn, ok := member.(*ast.FunctionDeclaration)
+		if !ok {
+			continue
+		}
+		guards := []string{}
+		for _, g := range FunctionGuards(fn, treasury, resolve) {
+			guards = append(guards, fmt.Sprintf("%s %s %s", g.Kind, g.Subject, g.Modifier))
+		}
+		got[fn.Name.Name] = strings.Join(guards, "; ")
+	}
+
+	expected := map[string]string{
+		"hasRole":     "",
+		"setGuardian": "owner-only owner onlyOwner",
+		"pause":       "owner-only guardian onlyGuardian",
+		"harvest":     "role-gated KEEPER_ROLE onlyKeeper",
+		"deposit":     "pause-gated not paused ",
+		"withdraw":    "other block.timestamp >= unlockTime ",
+		"sweep":       "owner-only owner onlyOwner; pause-gated not paused ",
+		"donate":      "",
+	}
+	for name, guards := range expected {
+		if got[name] != guards {
+			t.Errorf("Expected the guards of %s to be %q, got %q", name, guards, got[name])
+		}
+	}
+}
+
+func Test_Classify(t *testing.T) {
+	tests := []struct {
+		check    string
+		expected string
+	}{
+		{"require(_msgSender() == owner());", "owner-only owner()"},
+		{"require(msg.sender == config.admin);", "owner-only config.admin"},
+		{"require(msg.sender == tx.origin);", "other msg.sender == tx.origin"},
+		{"require(msg.sender == recipient);", "other msg.sender == recipient"},
+		{"if (!acl.hasRole(MINTER, msg.sender)) revert();", "role-gated MINTER"},
+		{"if (paused()) revert Paused();", "pause-gated not paused"},
+		{"require(paused);", "pause-gated paused"},
+		{"if (msg.sender != owner || paused) revert();", "owner-only owner; pause-gated not paused"},
+		{"require(msg.sender == owner || msg.sender == admin);", "other msg.sender == owner || msg.sender == admin"},
+		{"_checkRole(DEFAULT_ADMIN_ROLE);", "role-gated DEFAULT_ADMIN_ROLE"},
+		// The first statement other than a check ends them.
+		{"uint256 x = 1; require(msg.sender == owner);", ""},
+	}
+
+	for _, tt := range tests {
+		src := fmt.Sprintf("contract C { function f(address recipient) external { %s } }", tt.check)
+		file := parse(t, src)
+		c := file.Declarations[0].(*ast.ContractDeclaration)
+		guards := []string{}
+		for _, g := range FunctionGuards(c.Body[0].(*ast.FunctionDeclaration), c, nil) {
+			guards = append(guards, fmt.Sprintf("%s %s", g.Kind, g.Subject))
+		}
+		if got := strings.Join(guards, "; "); got != tt.expected {
+			t.Errorf("Expected %q for %s, got %q", tt.expected, tt.check, got)
+		}
+	}
+}
+
+func Test_Categories(t *testing.T) {
+	if got := Categories(nil); !slices.Equal(got, []Kind{Unguarded}) {
+		t.Errorf("Expected unguarded, got %v", got)
+	}
+	got := Categories([]Guard{{Kind: Pause}, {Kind: Owner}, {Kind: Pause}})
+	if !slices.Equal(got, []Kind{Owner, Pause}) {
+		t.Errorf("Expected [owner-only pause-gated], got %v", got)
+	}
+
+	totals := Totals{}
+	totals.Add([]Guard{{Kind: Owner}, {Kind: Pause}})
+	totals.Add([]Guard{{Kind: Owner}})
+	totals.Add(nil)
+	if expected := "2 functions owner-only, 1 function pause-gated, 1 function unguarded"; totals.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, totals.String())
+	}
+}
+
+func parse(t *testing.T, src string) *ast.File {
+	t.Helper()
+	p := parser.Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	file := p.ParseFile()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Expected no parser errors, got %v", p.Errors())
+	}
+	return file
+}
@@ -0,0 +1,60 @@
+pragma solidity ^0.8.20;
+
+contract Ownable {
+    address public owner;
+
+    modifier onlyOwner() {
+        require(msg.sender == owner, "not the owner");
+        _;
+    }
+}
+
+contract Treasury is Ownable {
+    bytes32 public constant KEEPER_ROLE = keccak256("KEEPER_ROLE");
+
+    bool public paused;
+    address public guardian;
+    uint256 public unlockTime;
+    mapping(bytes32 => mapping(address => bool)) roles;
+
+    error Unauthorized();
+
+    modifier onlyGuardian() {
+        if (msg.sender != guardian) revert Unauthorized();
+        _;
+    }
+
+    modifier onlyKeeper(bytes32 role) {
+        require(hasRole(role, msg.sender));
+        _;
+    }
+
+    function hasRole(bytes32 role, address account) public view returns (bool) {
+        return roles[role][account];
+    }
+
+    function setGuardian(address newGuardian) external onlyOwner {
+        guardian = newGuardian;
+    }
+
+    function pause() external onlyGuardian {
+        paused = true;
+    }
+
+    function harvest() external onlyKeeper(KEEPER_ROLE) {}
+
+    function deposit(uint256 amount) external {
+        require(!paused, "paused");
+        require(amount > 0);
+    }
+
+    function withdraw(uint256 amount) public {
+        if (block.timestamp < unlockTime) revert Unauthorized();
+    }
+
+    function sweep(address to) external onlyOwner {
+        require(!paused && to != address(0));
+    }
+
+    function donate() external payable {}
+}
@@ -1,29 +1,104 @@
 package analyzer
 
 import (
+	"fmt"
+	"slices"
+	"solbot/analyzer/costlyloop"
+	"solbot/analyzer/missingsafemath"
+	"solbot/analyzer/msgvalue"
 	"solbot/analyzer/screamingsnakeconst"
+	"solbot/analyzer/uncheckedarithmetic"
 	"solbot/ast"
 	"solbot/reporter"
 )
 
+// Detector finds a kind of problem in a file. Its rule describes the
+// problem for the users, see `solbot explain`.
 type Detector interface {
 	Detect(node ast.Node) *reporter.Finding
+	Rule() reporter.Rule
 }
 
+// registered are the detectors added with RegisterDetector.
+var registered []Detector
+
+// RegisterDetector adds a detector to the built-in ones, so that the
+// commands and the language server run it and list its rule. It's meant to
+// be called from the init function of the package of the detector, which
+// the build of solbot imports e.g.
+//
+//	func init() {
+//		analyzer.RegisterDetector(&Detector{})
+//	}
+//
+// Use the detectortest package for its tests, and `solbot new-detector` for
+// the skeleton. It panics if the code of the rule is empty or already taken.
+func RegisterDetector(d Detector) {
+	code := d.Rule().Code
+	if code == "" {
+		panic(fmt.Sprintf("analyzer: the rule of the detector %T has no code", d))
+	}
+	if _, ok := LookupRule(code); ok {
+		panic(fmt.Sprintf("analyzer: the code %q of the detector %T is already registered", code, d))
+	}
+	registered = append(registered, d)
+}
+
+// GetAllDetectors returns the built-in detectors followed by the registered
+// ones.
 func GetAllDetectors() *[]Detector {
-	return &[]Detector{
+	detectors := []Detector{
 		&screamingsnakeconst.Detector{},
+		&missingsafemath.Detector{},
+		&uncheckedarithmetic.Detector{},
+		&msgvalue.LoopDetector{},
+		&msgvalue.NonPayableDetector{},
+		&msgvalue.UnreachableDetector{},
+		&costlyloop.CallDetector{},
+		&costlyloop.StorageDetector{},
 	}
+	detectors = append(detectors, registered...)
+	return &detectors
 }
 
-func AnalyzeFile(file *ast.File) []reporter.Finding {
+// Rules returns the rules of all of the detectors.
+func Rules() []reporter.Rule {
+	rules := []reporter.Rule{}
+	for _, detector := range *GetAllDetectors() {
+		rules = append(rules, detector.Rule())
+	}
+	return rules
+}
+
+// LookupRule returns the rule with the code e.g. "msg-value-loop".
+func LookupRule(code string) (reporter.Rule, bool) {
+	for _, rule := range Rules() {
+		if rule.Code == code {
+			return rule, true
+		}
+	}
+	return reporter.Rule{}, false
+}
+
+// AnalyzeFile runs the detectors except the disabled ones, identified by
+// the codes of their rules. The findings carry the code and, unless the
+// detector says otherwise, the confidence of the rule.
+func AnalyzeFile(file *ast.File, disabled ...string) []reporter.Finding {
 	var findings []reporter.Finding
 
 	detectors := *GetAllDetectors()
 
 	for _, detector := range detectors {
+		rule := detector.Rule()
+		if slices.Contains(disabled, rule.Code) {
+			continue
+		}
 		finding := detector.Detect(file)
 		if finding != nil {
+			finding.Code = rule.Code
+			if finding.Confidence == 0 {
+				finding.Confidence = rule.Confidence
+			}
 			findings = append(findings, *finding)
 		}
 	}
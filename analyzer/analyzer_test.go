@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"solbot/analyzer/detectortest"
+	"solbot/reporter"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func Test_RulesComplete(t *testing.T) {
+	codes := map[string]bool{}
+	for _, detector := range *GetAllDetectors() {
+		rule := detector.Rule()
+		name := reflect.TypeOf(detector).String()
+		if rule.Code == "" {
+			t.Errorf("Expected the code of %s, got none", name)
+		}
+		if codes[rule.Code] {
+			t.Errorf("Expected the code %q of %s to be unique", rule.Code, name)
+		}
+		codes[rule.Code] = true
+		fields := map[string]string{
+			"title":       rule.Title,
+			"explanation": rule.Explanation,
+			"scenario":    rule.Scenario,
+			"remediation": rule.Remediation,
+		}
+		for field, value := range fields {
+			if value == "" {
+				t.Errorf("Expected the %s of %s, got none", field, name)
+			}
+		}
+		if len(rule.References) == 0 {
+			t.Errorf("Expected the references of %s, got none", name)
+		}
+		if rule.Confidence.String() == "unknown" {
+			t.Errorf("Expected the confidence of %s, got none", name)
+		}
+	}
+	if rule, ok := LookupRule("msg-value-loop"); !ok || rule.Code != "msg-value-loop" {
+		t.Errorf("Expected the rule msg-value-loop, got %v", rule)
+	}
+}
+
+// Test_APICompatibility fails when the API of the detectors changes shape,
+// so that the change breaking the detectors outside of the repository is
+// made on purpose. Update the golden file with `go test -update` then.
+func Test_APICompatibility(t *testing.T) {
+	var b strings.Builder
+	for _, typ := range []reflect.Type{
+		reflect.TypeOf((*Detector)(nil)).Elem(),
+		reflect.TypeOf(reporter.Finding{}),
+		reflect.TypeOf(reporter.Rule{}),
+		reflect.TypeOf(reporter.Location{}),
+		reflect.TypeOf(reporter.Confidence(0)),
+		reflect.TypeOf((*detectortest.Detector)(nil)).Elem(),
+		reflect.TypeOf(detectortest.Finding{}),
+	} {
+		fmt.Fprintf(&b, "type %s %s\n", typ, typ.Kind())
+		if typ.Kind() == reflect.Struct {
+			for i := 0; i < typ.NumField(); i++ {
+				if field := typ.Field(i); field.IsExported() {
+					fmt.Fprintf(&b, "\t%s %s\n", field.Name, field.Type)
+				}
+			}
+		}
+		methods := typ
+		if typ.Kind() != reflect.Interface {
+			methods = reflect.PointerTo(typ)
+		}
+		for i := 0; i < methods.NumMethod(); i++ {
+			m := methods.Method(i)
+			signature := m.Type
+			if typ.Kind() != reflect.Interface {
+				// Without the receiver.
+				in := []reflect.Type{}
+				for j := 1; j < signature.NumIn(); j++ {
+					in = append(in, signature.In(j))
+				}
+				out := []reflect.Type{}
+				for j := 0; j < signature.NumOut(); j++ {
+					out = append(out, signature.Out(j))
+				}
+				signature = reflect.FuncOf(in, out, signature.IsVariadic())
+			}
+			fmt.Fprintf(&b, "\tmethod %s %s\n", m.Name, signature)
+		}
+	}
+	funcs := []struct {
+		name string
+		fn   any
+	}{
+		{"analyzer.AnalyzeFile", AnalyzeFile},
+		{"analyzer.RegisterDetector", RegisterDetector},
+		{"detectortest.Run", detectortest.Run},
+		{"detectortest.Parse", detectortest.Parse},
+	}
+	for _, f := range funcs {
+		fmt.Fprintf(&b, "func %s %s\n", f.name, reflect.TypeOf(f.fn))
+	}
+
+	golden := "testdata/api.golden"
+	if *update {
+		if err := os.WriteFile(golden, []byte(b.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Cannot read the golden file: %s", err)
+	}
+	if b.String() != string(expected) {
+		t.Errorf("The API of the detectors changed, update %s with -update if it's on purpose.\nExpected:\n%s\ngot:\n%s", golden, expected, b.String())
+	}
+}
@@ -0,0 +1,275 @@
+// costlyloop detects the loops doing the most expensive work in every
+// iteration:
+//   - an external call e.g. paying the dividends to every holder, where a
+//     single recipient reverting blocks the payments of all of them,
+//   - a write to an element of a storage mapping or array whose value
+//     doesn't depend on the previous iterations e.g. crediting every
+//     recipient of an airdrop, which could be batched or emitted as events.
+//
+// The findings show the rough cost of an iteration, see the gas package.
+// The writes to a single slot e.g. `total += amount` are not reported, the
+// same as the writes of the values carried between the iterations.
+package costlyloop
+
+import (
+	"solbot/analyzer/gas"
+	"solbot/ast"
+	"solbot/reporter"
+	"solbot/token"
+)
+
+const (
+	callTitle          = "External call in a loop"
+	callSeverity       = "Warning"
+	callDescTempl      = "The loop calls another contract in every iteration, so a single call reverting, or running out of gas, reverts all of them, and the cost grows with the number of iterations: {{ range .Locations }}\n- {{ .Context }}{{ end }}"
+	callRecommendation = "Let the recipients withdraw what they are owed themselves (the pull-payment pattern): record the amounts in the loop, or compute them on withdrawal, instead of sending them."
+
+	storageTitle          = "Storage write in a loop"
+	storageSeverity       = "Info"
+	storageDescTempl      = "The loop writes a storage slot in every iteration with a value that doesn't depend on the previous ones, and each write costs thousands of gas: {{ range .Locations }}\n- {{ .Context }}{{ end }}"
+	storageRecommendation = "Batch the writes e.g. pack the values written together into a single slot, or emit events and keep the data off-chain if it isn't read by the contracts."
+)
+
+var (
+	callRule = reporter.Rule{
+		Code:  "loop-external-call",
+		Title: callTitle,
+		Explanation: "A loop making an external call in every iteration fails as a whole when any of the calls fails. The recipients " +
+			"control whether their call succeeds, and the gas of the loop grows with their number.",
+		Scenario: "A contract pays the dividends to every holder with `payable(holders[i]).transfer(share)`. A holder that is a " +
+			"contract reverting in its receive function blocks the payments of everyone, forever.",
+		Remediation: callRecommendation,
+		References:  []string{"SWC-113", "https://github.com/crytic/slither/wiki/Detector-Documentation#calls-inside-a-loop"},
+		Confidence:  reporter.Medium,
+	}
+	storageRule = reporter.Rule{
+		Code:  "loop-storage-write",
+		Title: storageTitle,
+		Explanation: "Writing a storage slot costs up to 22,100 gas, the most expensive operation of the usual contracts. A loop " +
+			"writing an element in every iteration pays it for every element.",
+		Scenario: "An airdrop credits `balances[recipients[i]] = amount` for a thousand recipients. The transaction costs over 20 " +
+			"million gas, and can't be included in a block once the list grows a bit more.",
+		Remediation: storageRecommendation,
+		References:  []string{"https://ethereum.org/en/developers/docs/smart-contracts/security/#dos-with-block-gas-limit"},
+		Confidence:  reporter.Low,
+	}
+)
+
+type CallDetector struct{}
+
+func (*CallDetector) Rule() reporter.Rule { return callRule }
+
+func (*CallDetector) Detect(node ast.Node) *reporter.Finding {
+	file, ok := node.(*ast.File)
+	if !ok {
+		return nil
+	}
+
+	locations := []reporter.Location{}
+	for _, f := range functions(file) {
+		e := gas.NewEstimator(file, f.contract, f.fn)
+		for _, l := range loops(f.fn) {
+			inspectIteration(l.body, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpression)
+				if !ok || !e.IsExternalCall(call) {
+					return true
+				}
+				locations = append(locations, reporter.Location{
+					Position: token.Position{Offset: call.Start()},
+					Context: "`" + ast.ExprString(call) + "` is called in every iteration of the loop in `" + f.name() + "`, " +
+						l.cost(e).String() + " per iteration",
+				})
+				return true
+			})
+		}
+	}
+	return finding(locations, callTitle, callSeverity, callDescTempl, callRecommendation)
+}
+
+type StorageDetector struct{}
+
+func (*StorageDetector) Rule() reporter.Rule { return storageRule }
+
+func (*StorageDetector) Detect(node ast.Node) *reporter.Finding {
+	file, ok := node.(*ast.File)
+	if !ok {
+		return nil
+	}
+
+	locations := []reporter.Location{}
+	for _, f := range functions(file) {
+		e := gas.NewEstimator(file, f.contract, f.fn)
+		for _, l := range loops(f.fn) {
+			carried := carriedNames(l.body)
+			inspectIteration(l.body, func(node ast.Node) bool {
+				assign, ok := node.(*ast.AssignmentExpression)
+				if !ok || !isElement(assign.Left) || !e.IsStorage(assign.Left) {
+					return true
+				}
+				if written := ast.Root(assign.Left); mentions(assign.Right, written.Name) || mentionsAny(assign.Right, carried) {
+					return true
+				}
+				locations = append(locations, reporter.Location{
+					Position: token.Position{Offset: assign.Start()},
+					Context: "`" + ast.ExprString(assign.Left) + "` is written in every iteration of the loop in `" + f.name() + "`, " +
+						l.cost(e).String() + " per iteration",
+				})
+				return true
+			})
+		}
+	}
+	return finding(locations, storageTitle, storageSeverity, storageDescTempl, storageRecommendation)
+}
+
+func finding(locations []reporter.Location, title, severity, descTempl, recommendation string) *reporter.Finding {
+	if len(locations) == 0 {
+		return nil
+	}
+	return &reporter.Finding{
+		Title:          title,
+		Severity:       severity,
+		Description:    reporter.GenerateCustomDescription(descTempl, locations),
+		Recommendation: recommendation,
+		Locations:      locations,
+	}
+}
+
+// function is a function declaration with the contract declaring it; or
+// nil for the free functions.
+type function struct {
+	fn       *ast.FunctionDeclaration
+	contract *ast.ContractDeclaration
+}
+
+func functions(file *ast.File) []function {
+	res := []function{}
+	for _, decl := range file.Declarations {
+		switch d := decl.(type) {
+		case *ast.FunctionDeclaration:
+			res = append(res, function{fn: d})
+		case *ast.ContractDeclaration:
+			for _, member := range d.Body {
+				if fn, ok := member.(*ast.FunctionDeclaration); ok {
+					res = append(res, function{fn: fn, contract: d})
+				}
+			}
+		}
+	}
+	return res
+}
+
+func (f function) name() string {
+	if f.fn.Name != nil {
+		return f.fn.Name.Name
+	}
+	return f.fn.Kind.String()
+}
+
+// loop is the part of a loop statement executed in every iteration.
+type loop struct {
+	body      ast.Statement
+	condition ast.Expression // or nil
+	post      ast.Expression // or nil
+}
+
+// cost returns the cost of an iteration of the loop.
+func (l loop) cost(e *gas.Estimator) gas.Cost {
+	return e.Estimate(l.body).Add(e.Estimate(l.condition)).Add(e.Estimate(l.post))
+}
+
+// loops returns the loops of the function, the nested ones included.
+func loops(fn *ast.FunctionDeclaration) []loop {
+	res := []loop{}
+	if fn.Body == nil {
+		return res
+	}
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.ForStatement:
+			res = append(res, loop{body: n.Body, condition: n.Condition, post: n.Post})
+		case *ast.WhileStatement:
+			res = append(res, loop{body: n.Body, condition: n.Condition})
+		case *ast.DoWhileStatement:
+			res = append(res, loop{body: n.Body, condition: n.Condition})
+		}
+		return true
+	})
+	return res
+}
+
+// inspectIteration inspects the body of the loop without the nested loops,
+// which are checked on their own with the cost of their iterations.
+func inspectIteration(body ast.Statement, f func(ast.Node) bool) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.ForStatement, *ast.WhileStatement, *ast.DoWhileStatement:
+			return false
+		}
+		return f(node)
+	})
+}
+
+// isElement reports whether the expression accesses an element of a
+// mapping or an array e.g. `balances[to]` or `positions[id].amount`.
+func isElement(x ast.Expression) bool {
+	for {
+		switch e := x.(type) {
+		case *ast.IndexAccessExpression:
+			return true
+		case *ast.MemberAccessExpression:
+			x = e.Expression
+		default:
+			return false
+		}
+	}
+}
+
+// carriedNames returns the names of the variables assigned in the body of
+// the loop, but declared outside of it, so that their values are carried
+// between the iterations.
+func carriedNames(body ast.Statement) map[string]bool {
+	declared := map[string]bool{}
+	assigned := map[string]bool{}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.VariableDeclarationStatement:
+			for _, decl := range n.Declarations {
+				if decl != nil {
+					declared[decl.Name.Name] = true
+				}
+			}
+		case *ast.AssignmentExpression:
+			if ident := ast.Root(n.Left); ident != nil {
+				assigned[ident.Name] = true
+			}
+		case *ast.UnaryExpression:
+			switch n.Operator {
+			case token.INC, token.DEC:
+				if ident := ast.Root(n.Operand); ident != nil {
+					assigned[ident.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	for name := range declared {
+		delete(assigned, name)
+	}
+	return assigned
+}
+
+func mentions(x ast.Expression, name string) bool {
+	return mentionsAny(x, map[string]bool{name: true})
+}
+
+// mentionsAny reports whether the expression reads any of the variables.
+func mentionsAny(x ast.Expression, names map[string]bool) bool {
+	found := false
+	ast.Inspect(x, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok && names[ident.Name] {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
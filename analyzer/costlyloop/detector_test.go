@@ -0,0 +1,32 @@
+package costlyloop
+
+import (
+	"solbot/analyzer/detectortest"
+	"testing"
+)
+
+func Test_DetectPushPaymentLoop(t *testing.T) {
+	findings := detectortest.Run(t, &CallDetector{}, "testdata/dividends.sol", []detectortest.Finding{
+		{Line: 11, Contains: "`payable(holders[i]).transfer(share)` is called in every iteration of the loop in `distribute`, approx. 12,226+ gas per iteration"},
+	})
+	if len(findings) == 1 && findings[0].Severity != "Warning" {
+		t.Errorf("Expected severity Warning, got %s", findings[0].Severity)
+	}
+	detectortest.Run(t, &StorageDetector{}, "testdata/dividends.sol", nil)
+}
+
+func Test_DetectStorageWriteInLoop(t *testing.T) {
+	findings := detectortest.Run(t, &StorageDetector{}, "testdata/airdrop.sol", []detectortest.Finding{
+		{Line: 9, Contains: "`balances[recipients[i]]` is written in every iteration of the loop in `airdrop`, approx. 3,042-22,242 gas per iteration"},
+	})
+	if len(findings) == 1 && findings[0].Severity != "Info" {
+		t.Errorf("Expected severity Info, got %s", findings[0].Severity)
+	}
+	detectortest.Run(t, &CallDetector{}, "testdata/airdrop.sol", nil)
+}
+
+func Test_ShouldNotDetectWriteAfterLoop(t *testing.T) {
+	for _, d := range []detectortest.Detector{&CallDetector{}, &StorageDetector{}} {
+		detectortest.Run(t, d, "testdata/accumulation.sol", nil)
+	}
+}
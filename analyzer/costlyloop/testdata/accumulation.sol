@@ -0,0 +1,14 @@
+pragma solidity ^0.8.0;
+
+contract Staking {
+    mapping(address => uint256) stakes;
+    uint256 totalStaked;
+
+    function sum(address[] calldata users) external {
+        uint256 total;
+        for (uint256 i = 0; i < users.length; i++) {
+            total += stakes[users[i]];
+        }
+        totalStaked = total;
+    }
+}
@@ -0,0 +1,21 @@
+pragma solidity ^0.8.0;
+
+contract Airdrop {
+    mapping(address => uint256) balances;
+    uint256[] history;
+
+    function airdrop(address[] calldata recipients, uint256 amount) external {
+        for (uint256 i = 0; i < recipients.length; i++) {
+            balances[recipients[i]] += amount; // match
+        }
+    }
+
+    // The values depend on the previous iterations.
+    function record(uint256 n) external {
+        uint256 last = 1;
+        for (uint256 i = 0; i < n; i++) {
+            last = last * 2;
+            history[i] = last;
+        }
+    }
+}
@@ -0,0 +1,14 @@
+pragma solidity ^0.8.0;
+
+contract Dividends {
+    address[] holders;
+    mapping(address => uint256) shares;
+    uint256 total;
+
+    function distribute() external payable {
+        for (uint256 i = 0; i < holders.length; i++) {
+            uint256 share = msg.value * shares[holders[i]] / total;
+            payable(holders[i]).transfer(share); // match
+        }
+    }
+}
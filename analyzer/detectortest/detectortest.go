@@ -0,0 +1,146 @@
+// detectortest runs a detector on the Solidity fixtures and checks its
+// findings, for the tests of the built-in detectors and the ones registered
+// with analyzer.RegisterDetector e.g.
+//
+//	func Test_Detect(t *testing.T) {
+//		detectortest.Run(t, &Detector{}, "testdata/vault.sol", []detectortest.Finding{
+//			{Line: 8, Contains: "`total - amount`"},
+//		})
+//	}
+//
+// The expected findings are written as the lines of the fixture and the
+// substrings of their contexts, so that the fixtures can be edited without
+// recounting the offsets. A fixture is either a file, or a directory whose
+// .sol files are analyzed one by one, since the detectors see one file at a
+// time; the expected findings name the file then.
+package detectortest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"solbot/ast"
+	"solbot/parser"
+	"solbot/reporter"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+// Detector is the part of analyzer.Detector the harness needs. The analyzer
+// package can't be imported here, since it imports the built-in detectors
+// whose tests use the harness.
+type Detector interface {
+	Detect(node ast.Node) *reporter.Finding
+}
+
+// Finding is an expected location of a finding.
+type Finding struct {
+	File     string // name of the file in the fixture directory; or empty for a fixture file
+	Line     int    // line of the location, starting from 1
+	Contains string // substring of the context of the location; or empty for any context
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("line %d", f.Line)
+	if f.File != "" {
+		s = f.File + ":" + s
+	}
+	if f.Contains != "" {
+		s += fmt.Sprintf(" containing %q", f.Contains)
+	}
+	return s
+}
+
+// Run runs the detector on every file of the fixture and checks that the
+// locations of its findings are exactly the expected ones, in any order.
+// Every location matches at most one expected finding. The fixture must
+// parse without errors. The findings are returned with their positions
+// calculated, for the checks of the other fields, like the severity; there
+// is at most one per file.
+func Run(t testing.TB, d Detector, fixture string, expected []Finding) []reporter.Finding {
+	t.Helper()
+	files, err := fixtureFiles(fixture)
+	if err != nil {
+		t.Fatalf("Cannot read the fixture: %s", err)
+	}
+
+	findings := []reporter.Finding{}
+	unmatched := slices.Clone(expected)
+	for _, name := range files {
+		path := fixture
+		if name != "" {
+			path = filepath.Join(fixture, name)
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Cannot read the fixture: %s", err)
+		}
+		handle := token.NewFile(path, string(src))
+		finding := d.Detect(Parse(t, handle))
+		if finding == nil {
+			continue
+		}
+		finding.CalculatePositions(handle)
+		findings = append(findings, *finding)
+
+		for _, loc := range finding.Locations {
+			i := slices.IndexFunc(unmatched, func(f Finding) bool {
+				return f.File == name && f.Line == loc.Position.Line && strings.Contains(loc.Context, f.Contains)
+			})
+			if i < 0 {
+				got := Finding{File: name, Line: loc.Position.Line}
+				t.Errorf("Unexpected finding at %s: %s", got, loc.Context)
+				continue
+			}
+			unmatched = slices.Delete(unmatched, i, i+1)
+		}
+	}
+	for _, f := range unmatched {
+		t.Errorf("Expected a finding at %s, got none", f)
+	}
+	return findings
+}
+
+// Parse parses the file and fails the test if it has syntax errors.
+func Parse(t testing.TB, handle *token.File) *ast.File {
+	t.Helper()
+	p := parser.Parser{}
+	p.Init(handle)
+	file := p.ParseFile()
+	errors := p.Errors()
+	for _, err := range errors {
+		t.Errorf("Parser error in %s: %s", handle.Name(), err.Msg)
+	}
+	if len(errors) > 0 {
+		t.FailNow()
+	}
+	return file
+}
+
+// fixtureFiles returns the names of the .sol files of the fixture directory
+// in order; or a single empty name if the fixture is a file.
+func fixtureFiles(fixture string) ([]string, error) {
+	info, err := os.Stat(fixture)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{""}, nil
+	}
+	entries, err := os.ReadDir(fixture)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".sol" {
+			files = append(files, e.Name())
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .sol files in %s", fixture)
+	}
+	return files, nil
+}
@@ -0,0 +1,78 @@
+package detectortest
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/reporter"
+	"solbot/token"
+	"testing"
+)
+
+// stateVariables finds every state variable, with its name as the context.
+type stateVariables struct{}
+
+func (stateVariables) Detect(node ast.Node) *reporter.Finding {
+	finding := &reporter.Finding{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if v, ok := n.(*ast.VariableDeclaration); ok {
+			finding.Locations = append(finding.Locations, reporter.Location{Position: token.Position{Offset: v.Name.NamePos}, Context: v.Name.Name})
+		}
+		return true
+	})
+	return finding
+}
+
+// recorder records the errors of the harness instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+func (r *recorder) FailNow() {}
+
+func Test_Run(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []Finding
+		errors   []string
+	}{
+		{"exact", []Finding{{Line: 3, Contains: "fee"}, {Line: 2}}, nil},
+		{
+			"unexpected and missing",
+			[]Finding{{Line: 2, Contains: "total"}, {Line: 3, Contains: "tax"}},
+			[]string{`Unexpected finding at line 3: fee`, `Expected a finding at line 3 containing "tax", got none`},
+		},
+		{
+			"matched once",
+			[]Finding{{Line: 2}, {Line: 2}, {Line: 3}},
+			[]string{`Expected a finding at line 2, got none`},
+		},
+	}
+
+	for _, tt := range tests {
+		r := &recorder{}
+		findings := Run(r, stateVariables{}, "testdata/vault.sol", tt.expected)
+		if fmt.Sprint(r.errors) != fmt.Sprint(tt.errors) {
+			t.Errorf("%s: expected errors %q, got %q", tt.name, tt.errors, r.errors)
+		}
+		if len(findings) != 1 || findings[0].Locations[1].Position.Line != 3 {
+			t.Errorf("%s: expected the finding with the positions, got %v", tt.name, findings)
+		}
+	}
+
+	r := &recorder{}
+	Run(r, stateVariables{}, "testdata/missing.sol", nil)
+	if len(r.errors) != 1 {
+		t.Errorf("Expected an error about the missing fixture, got %q", r.errors)
+	}
+}
@@ -0,0 +1,72 @@
+package analyzer_test
+
+import (
+	"fmt"
+	"solbot/analyzer"
+	"solbot/ast"
+	"solbot/parser"
+	"solbot/reporter"
+	"solbot/token"
+)
+
+// TxOriginDetector finds the authorization with `tx.origin`.
+type TxOriginDetector struct{}
+
+func (*TxOriginDetector) Rule() reporter.Rule {
+	return reporter.Rule{
+		Code:        "tx-origin",
+		Title:       "Authorization with `tx.origin`",
+		Explanation: "`tx.origin` is the account that started the transaction, not the caller.",
+		Scenario:    "The owner calls a malicious contract, which calls the wallet and passes the `tx.origin == owner` check.",
+		Remediation: "Use `msg.sender` instead.",
+		References:  []string{"SWC-115"},
+		Confidence:  reporter.High,
+	}
+}
+
+func (*TxOriginDetector) Detect(node ast.Node) *reporter.Finding {
+	finding := &reporter.Finding{Title: "Authorization with `tx.origin`", Severity: "Warning"}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if m, ok := n.(*ast.MemberAccessExpression); ok && ast.ExprString(m) == "tx.origin" {
+			finding.Locations = append(finding.Locations, reporter.Location{
+				Position: token.Position{Offset: m.Start()},
+				Context:  "`tx.origin`",
+			})
+		}
+		return true
+	})
+	if len(finding.Locations) == 0 {
+		return nil
+	}
+	return finding
+}
+
+// Registers a detector, so that `solbot analyze` and the rules list include
+// it.
+func ExampleRegisterDetector() {
+	analyzer.RegisterDetector(&TxOriginDetector{})
+
+	handle := token.NewFile("Wallet.sol", `pragma solidity ^0.8.0;
+
+contract Wallet {
+    address owner;
+
+    function withdraw() external {
+        require(tx.origin == owner);
+        payable(owner).transfer(address(this).balance);
+    }
+}
+`)
+	p := parser.Parser{}
+	p.Init(handle)
+	file := p.ParseFile()
+
+	for _, finding := range analyzer.AnalyzeFile(file) {
+		finding.CalculatePositions(handle)
+		for _, loc := range finding.Locations {
+			fmt.Printf("%s:%d: %s (%s confidence)\n", handle.Name(), loc.Position.Line, finding.Code, finding.Confidence)
+		}
+	}
+	// Output:
+	// Wallet.sol:7: tx-origin (high confidence)
+}
@@ -0,0 +1,357 @@
+// gas estimates the gas cost of the statements from the shape of their
+// syntax trees, with the costs of the opcode classes in the table below.
+// Nothing is executed: the storage slots are cold or warm and the written
+// values zero or not depending on the bound of the range, and the called
+// contracts cost nothing beyond the call itself, so the cost of the
+// external calls is open-ended. Only the storage, the calls and the events
+// are counted; the arithmetic and the memory are negligible next to them.
+//
+// The estimates are meant for the rough annotations e.g. "approx. 11,700+
+// gas per iteration", not for the gas reports of the tests.
+package gas
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/token"
+	"strconv"
+)
+
+// Costs of the opcode classes after the Berlin upgrade (EIP-2929).
+const (
+	ColdAccount = 2600  // first access of an account by a call
+	CallValue   = 9000  // call transferring a non-zero value
+	ColdSload   = 2100  // first access of a storage slot
+	WarmSload   = 100   // read of a storage slot accessed before
+	SstoreSet   = 20000 // write of a non-zero value to a zero slot
+	SstoreReset = 2900  // write to a non-zero slot
+	Keccak      = 42    // hash of a mapping key and the slot, 64 bytes
+	Log         = 375   // base of an event, and the cost of each of its topics
+	LogData     = 8     // per byte of the event data
+)
+
+// Cost is a range of gas.
+type Cost struct {
+	Min, Max int
+	Open     bool // does it call code of an unknown cost?
+}
+
+func (c Cost) Add(other Cost) Cost {
+	return Cost{Min: c.Min + other.Min, Max: c.Max + other.Max, Open: c.Open || other.Open}
+}
+
+// String returns the cost in words e.g. "approx. 2,900-22,100 gas" or
+// "approx. 11,600+ gas" for the open-ended ones.
+func (c Cost) String() string {
+	switch {
+	case c.Open:
+		return fmt.Sprintf("approx. %s+ gas", group(c.Min))
+	case c.Min == c.Max:
+		return fmt.Sprintf("approx. %s gas", group(c.Min))
+	}
+	return fmt.Sprintf("approx. %s-%s gas", group(c.Min), group(c.Max))
+}
+
+// group writes the number with the thousands separated by commas.
+func group(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+var (
+	read     = Cost{Min: WarmSload, Max: ColdSload}
+	write    = Cost{Min: SstoreReset, Max: SstoreSet + ColdSload}
+	hashing  = Cost{Min: Keccak, Max: Keccak}
+	call     = Cost{Min: ColdAccount, Max: ColdAccount, Open: true}
+	sendCall = call.Add(Cost{Min: CallValue, Max: CallValue})
+)
+
+// Estimator estimates the costs in a function. The variables are told apart
+// by their names: the parameters and the locals of the function shadow the
+// state variables of the contract wherever they are declared.
+type Estimator struct {
+	state    map[string]bool           // names of the state variables, except the constants and the immutables
+	types    map[string]ast.Expression // declared types of the variables
+	storage  map[string]bool           // names of the local storage pointers
+	contract map[string]bool           // names of the contracts and the interfaces of the file
+	events   map[string]*ast.EventDeclaration
+}
+
+// NewEstimator returns the estimator of the function declared in the
+// contract of the file. The contract is nil for the free functions.
+func NewEstimator(file *ast.File, contract *ast.ContractDeclaration, fn *ast.FunctionDeclaration) *Estimator {
+	e := &Estimator{
+		state:    map[string]bool{},
+		types:    map[string]ast.Expression{},
+		storage:  map[string]bool{},
+		contract: map[string]bool{},
+		events:   map[string]*ast.EventDeclaration{},
+	}
+	declarations := file.Declarations
+	for _, decl := range file.Declarations {
+		if c, ok := decl.(*ast.ContractDeclaration); ok {
+			if c.Kind != token.LIBRARY {
+				e.contract[c.Name.Name] = true
+			}
+			if c == contract {
+				declarations = append(declarations, c.Body...)
+			}
+		}
+	}
+	for _, decl := range declarations {
+		switch d := decl.(type) {
+		case *ast.VariableDeclaration:
+			if !d.Constant && !d.Immutable && contract != nil {
+				e.state[d.Name.Name] = true
+				e.types[d.Name.Name] = d.Type
+			}
+		case *ast.EventDeclaration:
+			e.events[d.Name.Name] = d
+		}
+	}
+
+	declare := func(name *ast.Identifier, typ ast.Expression, location ast.DataLocation) {
+		if name == nil {
+			return
+		}
+		delete(e.state, name.Name)
+		e.types[name.Name] = typ
+		if location == ast.Storage {
+			e.storage[name.Name] = true
+		}
+	}
+	for _, params := range []*ast.ParamList{fn.Type.Params, fn.Type.Results} {
+		if params != nil {
+			for _, param := range params.List {
+				declare(param.Name, param.Type, param.Location)
+			}
+		}
+	}
+	if fn.Body != nil {
+		ast.Inspect(fn.Body, func(node ast.Node) bool {
+			if stmt, ok := node.(*ast.VariableDeclarationStatement); ok {
+				for _, decl := range stmt.Declarations {
+					if decl != nil {
+						declare(decl.Name, decl.Type, decl.Location)
+					}
+				}
+			}
+			return true
+		})
+	}
+	return e
+}
+
+// Estimate returns the cost of executing the node once: the reads and the
+// writes of the storage, the external calls and the events.
+func (e *Estimator) Estimate(node ast.Node) Cost {
+	cost := Cost{}
+	if node == nil {
+		return cost
+	}
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignmentExpression:
+			if !e.IsStorage(n.Left) {
+				return true
+			}
+			cost = cost.Add(write).Add(e.slot(n.Left))
+			if n.Operator != token.ASSIGN {
+				// The slot was accessed by the write.
+				cost = cost.Add(Cost{Min: WarmSload, Max: WarmSload})
+			}
+			cost = cost.Add(e.indices(n.Left)).Add(e.Estimate(n.Right))
+			return false
+		case *ast.UnaryExpression:
+			switch n.Operator {
+			case token.INC, token.DEC, token.DELETE:
+				if e.IsStorage(n.Operand) {
+					cost = cost.Add(write).Add(e.slot(n.Operand)).Add(e.indices(n.Operand))
+					return false
+				}
+			}
+		case *ast.CallExpression:
+			if e.isPush(n) {
+				// The element and the length of the array are written.
+				access := n.Function.(*ast.MemberAccessExpression)
+				cost = cost.Add(write).Add(write).Add(e.slot(access.Expression)).Add(e.indices(access.Expression))
+				for _, arg := range n.Args {
+					cost = cost.Add(e.Estimate(arg))
+				}
+				return false
+			}
+			if e.IsExternalCall(n) {
+				if sendsValue(n) {
+					cost = cost.Add(sendCall)
+				} else {
+					cost = cost.Add(call)
+				}
+			}
+		case *ast.EmitStatement:
+			cost = cost.Add(e.event(n.Call))
+			return false
+		case *ast.Identifier, *ast.IndexAccessExpression, *ast.MemberAccessExpression:
+			x := n.(ast.Expression)
+			if !e.IsStorage(x) {
+				if access, ok := x.(*ast.MemberAccessExpression); ok {
+					// The member isn't a variable.
+					cost = cost.Add(e.Estimate(access.Expression))
+					return false
+				}
+				return true
+			}
+			cost = cost.Add(read).Add(e.slot(x)).Add(e.indices(x))
+			return false
+		}
+		return true
+	})
+	return cost
+}
+
+// IsStorage reports whether the expression is a state variable, a local
+// storage pointer or a part of one of them e.g. `balances[to]`.
+func (e *Estimator) IsStorage(x ast.Expression) bool {
+	ident := ast.Root(x)
+	return ident != nil && (e.state[ident.Name] || e.storage[ident.Name])
+}
+
+// IsExternalCall reports whether the call sends a message to another
+// contract: a call of the address members e.g. `to.call("")` and
+// `to.transfer(amount)`, a call of a function of a variable of a contract
+// type e.g. `token.transfer(to, amount)`, or of a conversion to one e.g.
+// `IERC20(token).transfer(to, amount)`, and the calls through `this`.
+func (e *Estimator) IsExternalCall(call *ast.CallExpression) bool {
+	function := call.Function
+	if options, ok := function.(*ast.CallOptionsExpression); ok {
+		function = options.Expression
+	}
+	access, ok := function.(*ast.MemberAccessExpression)
+	if !ok {
+		return false
+	}
+	switch access.Member.Name {
+	case "call", "delegatecall", "staticcall":
+		return true
+	case "transfer", "send":
+		if len(call.Args) == 1 {
+			return true
+		}
+	}
+
+	base := access.Expression
+	if conversion, ok := base.(*ast.CallExpression); ok {
+		ident, ok := conversion.Function.(*ast.Identifier)
+		return ok && e.contract[ident.Name]
+	}
+	ident := ast.Root(base)
+	if ident == nil {
+		return false
+	}
+	if ident.Name == "this" {
+		return true
+	}
+	typ := e.types[ident.Name]
+	for x := base; x != ast.Expression(ident); {
+		index, ok := x.(*ast.IndexAccessExpression)
+		if !ok {
+			// The members of the structs aren't known.
+			return false
+		}
+		switch t := typ.(type) {
+		case *ast.MappingType:
+			typ = t.Value
+		case *ast.ArrayType:
+			typ = t.Elem
+		default:
+			return false
+		}
+		x = index.Expression
+	}
+	name, ok := typ.(*ast.Identifier)
+	return ok && e.contract[name.Name]
+}
+
+// isPush reports whether the call appends to a storage array.
+func (e *Estimator) isPush(call *ast.CallExpression) bool {
+	access, ok := call.Function.(*ast.MemberAccessExpression)
+	return ok && access.Member.Name == "push" && e.IsStorage(access.Expression)
+}
+
+// slot returns the cost of finding the slot of the storage expression: a
+// hash for every index e.g. two for `allowance[owner][spender]`.
+func (e *Estimator) slot(x ast.Expression) Cost {
+	cost := Cost{}
+	for {
+		switch n := x.(type) {
+		case *ast.IndexAccessExpression:
+			cost = cost.Add(hashing)
+			x = n.Expression
+		case *ast.MemberAccessExpression:
+			x = n.Expression
+		default:
+			return cost
+		}
+	}
+}
+
+// indices returns the cost of evaluating the indices of the storage
+// expression e.g. `holders[i]` in `shares[holders[i]]`.
+func (e *Estimator) indices(x ast.Expression) Cost {
+	cost := Cost{}
+	for {
+		switch n := x.(type) {
+		case *ast.IndexAccessExpression:
+			cost = cost.Add(e.Estimate(n.Index))
+			x = n.Expression
+		case *ast.MemberAccessExpression:
+			x = n.Expression
+		default:
+			return cost
+		}
+	}
+}
+
+// event returns the cost of emitting the event: a topic for its selector
+// and one for each indexed parameter, and a word of data for each other
+// one. The events declared outside of the file have no indexed parameters.
+func (e *Estimator) event(call *ast.CallExpression) Cost {
+	cost := Cost{Min: Log, Max: Log}
+	for _, arg := range call.Args {
+		cost = cost.Add(e.Estimate(arg))
+	}
+	topics, data := 1, len(call.Args)
+	if ident, ok := call.Function.(*ast.Identifier); ok && e.events[ident.Name] != nil {
+		decl := e.events[ident.Name]
+		if decl.Anonymous {
+			topics = 0
+		}
+		if decl.Params != nil {
+			for _, param := range decl.Params.List {
+				if param.Indexed {
+					topics++
+					data--
+				}
+			}
+		}
+	}
+	gas := topics*Log + data*32*LogData
+	return cost.Add(Cost{Min: gas, Max: gas})
+}
+
+// sendsValue reports whether the call transfers ether e.g.
+// `to.transfer(amount)` or `to.call{value: amount}("")`.
+func sendsValue(call *ast.CallExpression) bool {
+	if options, ok := call.Function.(*ast.CallOptionsExpression); ok {
+		for _, name := range options.Names {
+			if name.Name == "value" {
+				return true
+			}
+		}
+		return false
+	}
+	access, ok := call.Function.(*ast.MemberAccessExpression)
+	return ok && (access.Member.Name == "transfer" || access.Member.Name == "send") && len(call.Args) == 1
+}
@@ -0,0 +1,87 @@
+package gas
+
+import (
+	"solbot/analyzer/detectortest"
+	"solbot/ast"
+	"solbot/token"
+	"testing"
+)
+
+const src = `pragma solidity ^0.8.0;
+
+interface IERC20 {
+    function transfer(address to, uint256 amount) external returns (bool);
+}
+
+contract Vault {
+    event Deposited(address indexed from, uint256 amount);
+
+    uint256 constant FEE = 1;
+    IERC20 token;
+    mapping(address => mapping(address => uint256)) allowance;
+    uint256[] history;
+
+    function approve(address spender, uint256 amount) external {
+        allowance[msg.sender][spender] = amount;
+    }
+
+    function pay(address to, uint256 amount) external {
+        token.transfer(to, amount - FEE);
+    }
+
+    function send(address payable to) external {
+        to.call{value: 1}("");
+    }
+
+    function deposit(uint256 amount) external {
+        emit Deposited(msg.sender, amount);
+        history.push(amount);
+    }
+
+    function shadowed(uint256 token) external pure returns (uint256) {
+        return token + 1;
+    }
+}
+`
+
+func Test_Estimate(t *testing.T) {
+	file := detectortest.Parse(t, token.NewFile("vault.sol", src))
+	contract := file.Declarations[2].(*ast.ContractDeclaration)
+
+	expected := map[string]string{
+		// A write and two hashes.
+		"approve": "approx. 2,984-22,184 gas",
+		// A read of `token` and the call.
+		"pay":  "approx. 2,700+ gas",
+		"send": "approx. 11,600+ gas",
+		// The event with a topic for the indexed `from`, and two writes.
+		"deposit":  "approx. 7,181-45,581 gas",
+		"shadowed": "approx. 0 gas",
+	}
+	for _, decl := range contract.Body {
+		fn, ok := decl.(*ast.FunctionDeclaration)
+		if !ok {
+			continue
+		}
+		got := NewEstimator(file, contract, fn).Estimate(fn.Body).String()
+		if got != expected[fn.Name.Name] {
+			t.Errorf("Expected the cost of %s to be %q, got %q", fn.Name.Name, expected[fn.Name.Name], got)
+		}
+	}
+}
+
+func Test_CostString(t *testing.T) {
+	tests := []struct {
+		cost     Cost
+		expected string
+	}{
+		{Cost{Min: 100, Max: 100}, "approx. 100 gas"},
+		{Cost{Min: 2900, Max: 1234567}, "approx. 2,900-1,234,567 gas"},
+		{Cost{Min: 23000, Max: 23000, Open: true}, "approx. 23,000+ gas"},
+	}
+	for _, tt := range tests {
+		if got := tt.cost.String(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
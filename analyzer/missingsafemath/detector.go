@@ -0,0 +1,211 @@
+// missingsafemath detects integer arithmetic that is not protected against
+// overflows in contracts compiled with Solidity older than 0.8.0. Before
+// 0.8.0 the arithmetic operators silently wrap around, so the operations
+// have to be routed through a SafeMath-style library e.g. `a.add(b)` or
+// `SafeMath.add(a, b)`. Such calls are not operators, so only the raw `+`,
+// `-`, `*`, `**` and their compound assignments are reported.
+//
+// Libraries are skipped, since SafeMath-style libraries implement the
+// checked operations with the raw operators themselves.
+package missingsafemath
+
+import (
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/reporter"
+	"solbot/semver"
+	"solbot/token"
+)
+
+const (
+	title          = "Integer arithmetic is not protected against overflows before Solidity 0.8.0"
+	severity       = "Warning"
+	descTempl      = "The file can be compiled with Solidity older than 0.8.0, where arithmetic operations wrap around on overflow instead of reverting. The following operations on integers are not routed through a SafeMath-style library: {{ range .Locations }}\n- {{ .Context }}{{ end }}"
+	recommendation = "Consider using a SafeMath library for the arithmetic operations e.g. `using SafeMath for uint256;` and `a.add(b)`, or upgrading the compiler to 0.8.0 or newer."
+)
+
+var checkedArithmeticVersion = semver.MustParse("0.8.0")
+
+var rule = reporter.Rule{
+	Code:  "missing-safemath",
+	Title: title,
+	Explanation: "Before Solidity 0.8.0, the arithmetic operators wrap around silently: `type(uint256).max + 1` is 0 and `0 - 1` is " +
+		"`type(uint256).max`. Unless every operation on untrusted values goes through a library checking the bounds, a single " +
+		"overflow corrupts the balances or the accounting.",
+	Scenario: "A token computes `balances[from] - amount` with the raw operator. An attacker without any tokens transfers 1 token, " +
+		"the subtraction underflows and the balance of the attacker becomes `2**256 - 1`, which is then sold on a market.",
+	Remediation: recommendation,
+	References:  []string{"SWC-101", "CWE-190", "https://swcregistry.io/docs/SWC-101"},
+	Confidence:  reporter.Medium,
+}
+
+type Detector struct{}
+
+func (*Detector) Rule() reporter.Rule { return rule }
+
+func (*Detector) Detect(node ast.Node) *reporter.Finding {
+	file, ok := node.(*ast.File)
+	if !ok || !pragma.AllowsBelow(file, checkedArithmeticVersion) {
+		return nil
+	}
+
+	finding := reporter.Finding{}
+
+	// File level constants are visible in all of the functions.
+	fileTypes := map[string]ast.Expression{}
+	for _, decl := range file.Declarations {
+		if v, ok := decl.(*ast.VariableDeclaration); ok {
+			fileTypes[v.Name.Name] = v.Type
+		}
+	}
+
+	for _, decl := range file.Declarations {
+		switch d := decl.(type) {
+		case *ast.FunctionDeclaration:
+			finding.Locations = append(finding.Locations, checkFunction(d, fileTypes)...)
+		case *ast.ContractDeclaration:
+			if d.Kind != token.CONTRACT {
+				continue
+			}
+			stateTypes := copyTypes(fileTypes)
+			for _, member := range d.Body {
+				if v, ok := member.(*ast.VariableDeclaration); ok {
+					stateTypes[v.Name.Name] = v.Type
+				}
+			}
+			for _, member := range d.Body {
+				switch m := member.(type) {
+				case *ast.FunctionDeclaration:
+					finding.Locations = append(finding.Locations, checkFunction(m, stateTypes)...)
+				case *ast.VariableDeclaration:
+					if m.Value != nil {
+						finding.Locations = append(finding.Locations,
+							checkArithmetic(m.Value, stateTypes, "the `"+m.Name.Name+"` initializer")...)
+					}
+				}
+			}
+		}
+	}
+
+	if len(finding.Locations) == 0 {
+		return nil
+	}
+
+	finding.Title = title
+	finding.Severity = severity
+	finding.Description = reporter.GenerateCustomDescription(descTempl, finding.Locations)
+	finding.Recommendation = recommendation
+	return &finding
+}
+
+// checkFunction reports the unprotected arithmetic in the function body.
+func checkFunction(fn *ast.FunctionDeclaration, stateTypes map[string]ast.Expression) []reporter.Location {
+	if fn.Body == nil {
+		return nil
+	}
+
+	types := copyTypes(stateTypes)
+	for _, params := range []*ast.ParamList{fn.Type.Params, fn.Type.Results} {
+		if params == nil {
+			continue
+		}
+		for _, param := range params.List {
+			if param.Name != nil {
+				types[param.Name.Name] = param.Type
+			}
+		}
+	}
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		if v, ok := node.(*ast.VariableDeclaration); ok {
+			types[v.Name.Name] = v.Type
+		}
+		return true
+	})
+
+	name := "the constructor"
+	if fn.Name != nil {
+		name = "`" + fn.Name.Name + "`"
+	} else if fn.Kind != token.CONSTRUCTOR {
+		name = "the " + fn.Kind.String() + " function"
+	}
+	return checkArithmetic(fn.Body, types, name)
+}
+
+func checkArithmetic(root ast.Node, types map[string]ast.Expression, where string) []reporter.Location {
+	locations := []reporter.Location{}
+	ast.Inspect(root, func(node ast.Node) bool {
+		var operands []ast.Expression
+		switch n := node.(type) {
+		case *ast.BinaryExpression:
+			switch n.Operator {
+			case token.ADD, token.SUB, token.MUL, token.EXP:
+				operands = []ast.Expression{n.Left, n.Right}
+			}
+		case *ast.AssignmentExpression:
+			switch n.Operator {
+			case token.ASSIGN_ADD, token.ASSIGN_SUB, token.ASSIGN_MUL:
+				operands = []ast.Expression{n.Left}
+			}
+		}
+
+		for _, operand := range operands {
+			if isInteger(typeOf(operand, types)) {
+				expr := node.(ast.Expression)
+				locations = append(locations, reporter.Location{
+					Position: token.Position{Offset: expr.Start()},
+					Context:  "`" + ast.ExprString(expr) + "` in " + where,
+				})
+				// Don't report the nested operations e.g. `a + b` in
+				// `a + b + c` separately.
+				return false
+			}
+		}
+		return true
+	})
+	return locations
+}
+
+// typeOf returns the declared type of the expression if it's a variable or
+// an element of a mapping or an array. It returns nil if the type is not
+// known.
+func typeOf(expr ast.Expression, types map[string]ast.Expression) ast.Expression {
+	switch x := expr.(type) {
+	case *ast.Identifier:
+		return types[x.Name]
+	case *ast.IndexAccessExpression:
+		switch base := typeOf(x.Expression, types).(type) {
+		case *ast.MappingType:
+			return base.Value
+		case *ast.ArrayType:
+			return base.Elem
+		}
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 {
+			return typeOf(x.Elements[0], types)
+		}
+	case *ast.BinaryExpression:
+		if t := typeOf(x.Left, types); t != nil {
+			return t
+		}
+		return typeOf(x.Right, types)
+	case *ast.CallExpression:
+		// Type conversions e.g. uint128(x)
+		if t, ok := x.Function.(*ast.ElementaryType); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+func isInteger(typ ast.Expression) bool {
+	t, ok := typ.(*ast.ElementaryType)
+	return ok && token.IsIntegerType(t.Kind.Type)
+}
+
+func copyTypes(types map[string]ast.Expression) map[string]ast.Expression {
+	res := make(map[string]ast.Expression, len(types))
+	for name, typ := range types {
+		res[name] = typ
+	}
+	return res
+}
@@ -0,0 +1,24 @@
+package missingsafemath
+
+import (
+	"solbot/analyzer/detectortest"
+	"testing"
+)
+
+func Test_DetectRawArithmeticBefore080(t *testing.T) {
+	findings := detectortest.Run(t, &Detector{}, "testdata/raw_arithmetic.sol", []detectortest.Finding{
+		{Line: 8, Contains: "`balances[msg.sender] + amount` in `deposit`"},
+		{Line: 9, Contains: "`total += amount` in `deposit`"},
+	})
+	if len(findings) == 1 && findings[0].Severity != "Warning" {
+		t.Errorf("Expected severity Warning, got %s", findings[0].Severity)
+	}
+}
+
+func Test_ShouldNotDetectSafeMath(t *testing.T) {
+	detectortest.Run(t, &Detector{}, "testdata/safemath.sol", nil)
+}
+
+func Test_ShouldNotDetectAfter080(t *testing.T) {
+	detectortest.Run(t, &Detector{}, "testdata/after_080.sol", nil)
+}
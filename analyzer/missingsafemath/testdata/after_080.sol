@@ -0,0 +1,9 @@
+pragma solidity ^0.8.0;
+
+contract Vault {
+    uint256 total;
+
+    function deposit(uint256 amount) public {
+        total += amount;
+    }
+}
@@ -0,0 +1,15 @@
+pragma solidity ^0.7.0;
+
+contract Vault {
+    mapping(address => uint256) balances;
+    uint256 total;
+
+    function deposit(uint256 amount) public {
+        balances[msg.sender] = balances[msg.sender] + amount; // match
+        total += amount;                                      // match
+    }
+
+    function name() public pure returns (string memory) {
+        return "Vault";
+    }
+}
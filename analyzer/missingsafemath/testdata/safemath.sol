@@ -0,0 +1,21 @@
+pragma solidity ^0.7.0;
+
+library SafeMath {
+    function add(uint256 a, uint256 b) internal pure returns (uint256) {
+        uint256 c = a + b;
+        require(c >= a, "SafeMath: addition overflow");
+        return c;
+    }
+}
+
+contract Vault {
+    using SafeMath for uint256;
+
+    mapping(address => uint256) balances;
+    uint256 total;
+
+    function deposit(uint256 amount) public {
+        balances[msg.sender] = balances[msg.sender].add(amount);
+        total = SafeMath.add(total, amount);
+    }
+}
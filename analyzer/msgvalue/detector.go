@@ -0,0 +1,407 @@
+// msgvalue detects the misuses of `msg.value`:
+//   - reading it inside of a loop that credits a balance or sends value in
+//     every iteration e.g. a batch airdrop adding the same msg.value to the
+//     balance of every recipient, although it was paid only once,
+//   - reading it in a function that can't receive ether, where it's always
+//     zero, so the checks like `require(msg.value == price)` never pass,
+//   - reading it in an internal function that none of the payable functions
+//     of the file calls.
+//
+// The detection is syntactic: `msg` must not be shadowed by a declaration
+// with the same name, and the calls between the functions are matched by
+// their names within the file.
+package msgvalue
+
+import (
+	"solbot/ast"
+	"solbot/reporter"
+	"solbot/token"
+)
+
+const (
+	loopTitle          = "`msg.value` used in a loop"
+	loopSeverity       = "Warning"
+	loopDescTempl      = "The value sent with the call is paid once, but it's read in every iteration of a loop that credits value, so the same payment is counted many times e.g. a batch call crediting `msg.value` to every recipient: {{ range .Locations }}\n- {{ .Context }}{{ end }}"
+	loopRecommendation = "Read `msg.value` once before the loop and split it between the iterations e.g. `uint256 share = msg.value / recipients.length;`, or track the value spent in the loop and require the total to equal `msg.value`."
+
+	nonPayableTitle          = "`msg.value` in a non-payable function"
+	nonPayableSeverity       = "Warning"
+	nonPayableDescTempl      = "The function can't receive ether, the call reverts if any is sent, so `msg.value` is always zero and the logic depending on it is dead or always fails: {{ range .Locations }}\n- {{ .Context }}{{ end }}"
+	nonPayableRecommendation = "Mark the function as `payable` if it's meant to receive ether; otherwise remove the use of `msg.value`."
+
+	unreachableTitle          = "`msg.value` in an internal function not called from a payable one"
+	unreachableSeverity       = "Info"
+	unreachableDescTempl      = "None of the payable functions of the file calls the function, so `msg.value` is zero unless it's reached through a delegatecall or from a contract in another file. It's usually a sign of confusion about where the value is received: {{ range .Locations }}\n- {{ .Context }}{{ end }}"
+	unreachableRecommendation = "Pass the value as a parameter from the payable function that receives it."
+)
+
+var (
+	loopRule = reporter.Rule{
+		Code:  "msg-value-loop",
+		Title: loopTitle,
+		Explanation: "`msg.value` is the same in every iteration of a loop, but the ether was sent once. A loop crediting or sending " +
+			"`msg.value` in every iteration counts the same payment many times.",
+		Scenario: "A batch deposit credits `balances[recipients[i]] += msg.value` for every recipient. An attacker sends 1 ether with " +
+			"100 copies of their own address, is credited 100 ether and withdraws the deposits of the other users.",
+		Remediation: loopRecommendation,
+		References:  []string{"CWE-837", "https://github.com/crytic/slither/wiki/Detector-Documentation#msgvalue-inside-a-loop"},
+		Confidence:  reporter.High,
+	}
+	nonPayableRule = reporter.Rule{
+		Code:  "msg-value-non-payable",
+		Title: nonPayableTitle,
+		Explanation: "A function that isn't `payable` reverts when ether is sent with the call, so `msg.value` is always zero in it. " +
+			"The logic reading it is either dead or never succeeds.",
+		Scenario: "A mint function checks `require(msg.value == price)`, but it's not marked `payable`. Every mint reverts, either on the " +
+			"missing ether or on the check, and the sale launches with nothing to sell.",
+		Remediation: nonPayableRecommendation,
+		References:  []string{"CWE-561", "https://docs.soliditylang.org/en/latest/units-and-global-variables.html#block-and-transaction-properties"},
+		Confidence:  reporter.High,
+	}
+	unreachableRule = reporter.Rule{
+		Code:  "msg-value-unreachable",
+		Title: unreachableTitle,
+		Explanation: "An internal function reads `msg.value`, but none of the payable functions of the file calls it, so the value is " +
+			"zero unless the function is reached in a way the file doesn't show.",
+		Scenario: "A refactoring moves the fee check to an internal `_checkFee` helper called from a non-payable `claim`. The check " +
+			"`msg.value >= fee` now never passes, or with a fee of zero, it never charges anything.",
+		Remediation: unreachableRecommendation,
+		References:  []string{"CWE-561"},
+		Confidence:  reporter.Low,
+	}
+)
+
+type LoopDetector struct{}
+
+func (*LoopDetector) Rule() reporter.Rule { return loopRule }
+
+func (*LoopDetector) Detect(node ast.Node) *reporter.Finding {
+	file, ok := node.(*ast.File)
+	if !ok {
+		return nil
+	}
+
+	locations := []reporter.Location{}
+	for _, f := range functions(file) {
+		if f.fn.Body == nil || f.shadowsMsg() {
+			continue
+		}
+		locals := f.localNames()
+		ast.Inspect(f.fn.Body, func(node ast.Node) bool {
+			body := loopBody(node)
+			if body == nil {
+				return true
+			}
+			if !credits(body, locals) {
+				// The nested loops are checked on their own.
+				return true
+			}
+			for _, read := range msgValueReads(body) {
+				locations = append(locations, reporter.Location{
+					Position: token.Position{Offset: read.Start()},
+					Context:  "`msg.value` is read in every iteration of the loop in `" + f.name() + "`, which credits value in each of them",
+				})
+			}
+			// All of the reads in the nested loops are already reported.
+			return false
+		})
+	}
+	return finding(locations, loopTitle, loopSeverity, loopDescTempl, loopRecommendation)
+}
+
+type NonPayableDetector struct{}
+
+func (*NonPayableDetector) Rule() reporter.Rule { return nonPayableRule }
+
+func (*NonPayableDetector) Detect(node ast.Node) *reporter.Finding {
+	file, ok := node.(*ast.File)
+	if !ok {
+		return nil
+	}
+
+	locations := []reporter.Location{}
+	for _, f := range functions(file) {
+		if f.fn.Body == nil || f.payable() || !f.external() || f.shadowsMsg() {
+			continue
+		}
+		for _, read := range msgValueReads(f.fn.Body) {
+			context := "`msg.value` is always zero in the non-payable `" + f.name() + "`"
+			if cmp := enclosingComparison(f.fn.Body, read); cmp != nil {
+				context += ", so `" + ast.ExprString(cmp) + "` has the same result in every call"
+			}
+			locations = append(locations, reporter.Location{
+				Position: token.Position{Offset: read.Start()},
+				Context:  context,
+			})
+		}
+	}
+	return finding(locations, nonPayableTitle, nonPayableSeverity, nonPayableDescTempl, nonPayableRecommendation)
+}
+
+type UnreachableDetector struct{}
+
+func (*UnreachableDetector) Rule() reporter.Rule { return unreachableRule }
+
+func (*UnreachableDetector) Detect(node ast.Node) *reporter.Finding {
+	file, ok := node.(*ast.File)
+	if !ok {
+		return nil
+	}
+
+	fns := functions(file)
+	reachable := reachableFromPayable(fns)
+	locations := []reporter.Location{}
+	for _, f := range fns {
+		if f.fn.Body == nil || f.payable() || f.external() || reachable[f.fn] || f.shadowsMsg() {
+			continue
+		}
+		for _, read := range msgValueReads(f.fn.Body) {
+			locations = append(locations, reporter.Location{
+				Position: token.Position{Offset: read.Start()},
+				Context:  "`" + f.name() + "` reads `msg.value`, but none of the payable functions in the file calls it",
+			})
+		}
+	}
+	return finding(locations, unreachableTitle, unreachableSeverity, unreachableDescTempl, unreachableRecommendation)
+}
+
+func finding(locations []reporter.Location, title, severity, descTempl, recommendation string) *reporter.Finding {
+	if len(locations) == 0 {
+		return nil
+	}
+	return &reporter.Finding{
+		Title:          title,
+		Severity:       severity,
+		Description:    reporter.GenerateCustomDescription(descTempl, locations),
+		Recommendation: recommendation,
+		Locations:      locations,
+	}
+}
+
+// function is a function declaration with the contract declaring it; or
+// nil for the free functions.
+type function struct {
+	fn       *ast.FunctionDeclaration
+	contract *ast.ContractDeclaration
+}
+
+func functions(file *ast.File) []function {
+	res := []function{}
+	for _, decl := range file.Declarations {
+		switch d := decl.(type) {
+		case *ast.FunctionDeclaration:
+			res = append(res, function{fn: d})
+		case *ast.ContractDeclaration:
+			for _, member := range d.Body {
+				if fn, ok := member.(*ast.FunctionDeclaration); ok {
+					res = append(res, function{fn: fn, contract: d})
+				}
+			}
+		}
+	}
+	return res
+}
+
+func (f function) name() string {
+	if f.fn.Name != nil {
+		return f.fn.Name.Name
+	}
+	return f.fn.Kind.String()
+}
+
+// payable reports whether the function can receive ether.
+func (f function) payable() bool {
+	return f.fn.Kind == token.RECEIVE || f.fn.Type.Mutability == ast.Payable
+}
+
+// external reports whether the function is called with a message of its
+// own. The functions without the visibility are public before 0.5.0.
+func (f function) external() bool {
+	switch f.fn.Kind {
+	case token.CONSTRUCTOR, token.FALLBACK, token.RECEIVE:
+		return true
+	}
+	switch f.fn.Type.Visibility {
+	case ast.Public, ast.External:
+		return true
+	case ast.Internal, ast.Private:
+		return false
+	}
+	return f.contract != nil
+}
+
+// shadowsMsg reports whether the global `msg` is shadowed by a parameter, a
+// local variable or a member of the contract.
+func (f function) shadowsMsg() bool {
+	if f.localNames()["msg"] {
+		return true
+	}
+	if f.contract != nil {
+		for _, member := range f.contract.Body {
+			if v, ok := member.(*ast.VariableDeclaration); ok && v.Name.Name == "msg" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// localNames returns the names of the parameters, the named results and the
+// local variables of the function.
+func (f function) localNames() map[string]bool {
+	names := map[string]bool{}
+	for _, params := range []*ast.ParamList{f.fn.Type.Params, f.fn.Type.Results} {
+		if params == nil {
+			continue
+		}
+		for _, param := range params.List {
+			if param.Name != nil {
+				names[param.Name.Name] = true
+			}
+		}
+	}
+	if f.fn.Body != nil {
+		ast.Inspect(f.fn.Body, func(node ast.Node) bool {
+			if stmt, ok := node.(*ast.VariableDeclarationStatement); ok {
+				for _, decl := range stmt.Declarations {
+					if decl != nil {
+						names[decl.Name.Name] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	return names
+}
+
+// loopBody returns the body of the loop statement; or nil if the node is
+// not a loop.
+func loopBody(node ast.Node) ast.Statement {
+	switch n := node.(type) {
+	case *ast.ForStatement:
+		return n.Body
+	case *ast.WhileStatement:
+		return n.Body
+	case *ast.DoWhileStatement:
+		return n.Body
+	}
+	return nil
+}
+
+// credits reports whether the statement writes to the state or sends value
+// e.g. `balances[to] += amount`, `to.transfer(amount)` or
+// `to.call{value: amount}("")`. The writes to the variables other than the
+// locals are assumed to be the writes to the state.
+func credits(stmt ast.Statement, locals map[string]bool) bool {
+	found := false
+	ast.Inspect(stmt, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignmentExpression:
+			if ident := ast.Root(n.Left); ident != nil && !locals[ident.Name] {
+				found = true
+			}
+		case *ast.UnaryExpression:
+			switch n.Operator {
+			case token.INC, token.DEC, token.DELETE:
+				if ident := ast.Root(n.Operand); ident != nil && !locals[ident.Name] {
+					found = true
+				}
+			}
+		case *ast.CallOptionsExpression:
+			for _, name := range n.Names {
+				if name.Name == "value" {
+					found = true
+				}
+			}
+		case *ast.CallExpression:
+			if access, ok := n.Function.(*ast.MemberAccessExpression); ok {
+				switch access.Member.Name {
+				case "transfer", "send":
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// msgValueReads returns the `msg.value` expressions in the node.
+func msgValueReads(node ast.Node) []*ast.MemberAccessExpression {
+	res := []*ast.MemberAccessExpression{}
+	ast.Inspect(node, func(node ast.Node) bool {
+		if access, ok := node.(*ast.MemberAccessExpression); ok && isMsgValue(access) {
+			res = append(res, access)
+			return false
+		}
+		return true
+	})
+	return res
+}
+
+func isMsgValue(access *ast.MemberAccessExpression) bool {
+	ident, ok := access.Expression.(*ast.Identifier)
+	return ok && ident.Name == "msg" && access.Member.Name == "value"
+}
+
+// enclosingComparison returns the innermost comparison containing the read
+// e.g. `msg.value == price`; or nil if there is none.
+func enclosingComparison(body *ast.BlockStatement, read ast.Expression) *ast.BinaryExpression {
+	var res *ast.BinaryExpression
+	ast.Inspect(body, func(node ast.Node) bool {
+		if node == nil || node.Start() > read.Start() || node.End() < read.End() {
+			return false
+		}
+		if bin, ok := node.(*ast.BinaryExpression); ok {
+			switch bin.Operator {
+			case token.EQUAL, token.NOT_EQUAL, token.LESS_THAN, token.LESS_THAN_OR_EQUAL,
+				token.GREATER_THAN, token.GREATER_THAN_OR_EQUAL:
+				res = bin
+			}
+		}
+		return true
+	})
+	return res
+}
+
+// reachableFromPayable returns the functions called, directly or through
+// other functions, by one of the payable functions. The calls are matched
+// by name, so the internal functions of the bases declared in the same file
+// are reachable from the derived contracts too.
+func reachableFromPayable(fns []function) map[*ast.FunctionDeclaration]bool {
+	reachable := map[*ast.FunctionDeclaration]bool{}
+	queue := []function{}
+	for _, f := range fns {
+		if f.payable() {
+			reachable[f.fn] = true
+			queue = append(queue, f)
+		}
+	}
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		if f.fn.Body == nil {
+			continue
+		}
+		ast.Inspect(f.fn.Body, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpression)
+			if !ok {
+				return true
+			}
+			ident, ok := call.Function.(*ast.Identifier)
+			if !ok {
+				return true
+			}
+			for _, callee := range fns {
+				if callee.fn.Name == nil || callee.fn.Name.Name != ident.Name || reachable[callee.fn] {
+					continue
+				}
+				reachable[callee.fn] = true
+				queue = append(queue, callee)
+			}
+			return true
+		})
+	}
+	return reachable
+}
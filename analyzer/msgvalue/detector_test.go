@@ -0,0 +1,46 @@
+package msgvalue
+
+import (
+	"solbot/analyzer/detectortest"
+	"testing"
+)
+
+func Test_DetectMsgValueInCreditingLoop(t *testing.T) {
+	findings := detectortest.Run(t, &LoopDetector{}, "testdata/crediting_loop.sol", []detectortest.Finding{
+		{Line: 8, Contains: "`msg.value` is read in every iteration of the loop in `airdrop`, which credits value in each of them"},
+		{Line: 15, Contains: "in `refund`"},
+	})
+	if len(findings) == 1 && findings[0].Severity != "Warning" {
+		t.Errorf("Expected severity Warning, got %s", findings[0].Severity)
+	}
+}
+
+func Test_ShouldNotDetectValueSplitBeforeLoop(t *testing.T) {
+	for _, d := range []detectortest.Detector{&LoopDetector{}, &NonPayableDetector{}, &UnreachableDetector{}} {
+		detectortest.Run(t, d, "testdata/split_before_loop.sol", nil)
+	}
+}
+
+func Test_DetectMsgValueInNonPayableFunction(t *testing.T) {
+	findings := detectortest.Run(t, &NonPayableDetector{}, "testdata/non_payable.sol", []detectortest.Finding{
+		{Line: 7, Contains: "`msg.value` is always zero in the non-payable `buy`, so `msg.value > 0` has the same result in every call"},
+	})
+	if len(findings) == 1 && findings[0].Severity != "Warning" {
+		t.Errorf("Expected severity Warning, got %s", findings[0].Severity)
+	}
+
+	findings = detectortest.Run(t, &UnreachableDetector{}, "testdata/non_payable.sol", []detectortest.Finding{
+		{Line: 20},
+	})
+	if len(findings) == 1 && findings[0].Severity != "Info" {
+		t.Errorf("Expected severity Info, got %s", findings[0].Severity)
+	}
+}
+
+func Test_DetectUnreachableAcrossFiles(t *testing.T) {
+	// The calls are matched within the file, so the payable caller in
+	// another file doesn't count.
+	detectortest.Run(t, &UnreachableDetector{}, "testdata/unreachable", []detectortest.Finding{
+		{File: "Fees.sol", Line: 7, Contains: "`_collect`"},
+	})
+}
@@ -0,0 +1,19 @@
+pragma solidity ^0.8.0;
+
+contract Airdrop {
+    mapping(address => uint256) balances;
+
+    function airdrop(address[] calldata recipients) external payable {
+        for (uint256 i = 0; i < recipients.length; i++) {
+            balances[recipients[i]] += msg.value; // match
+        }
+    }
+
+    function refund(address payable[] calldata recipients) external payable {
+        uint256 i;
+        while (i < recipients.length) {
+            recipients[i].transfer(msg.value); // match
+            i++;
+        }
+    }
+}
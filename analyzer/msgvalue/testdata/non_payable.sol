@@ -0,0 +1,22 @@
+pragma solidity ^0.8.0;
+
+contract Shop {
+    uint256 sold;
+
+    function buy() external {
+        require(msg.value > 0, "no value"); // match
+        sold++;
+    }
+
+    function order() external payable {
+        _record();
+    }
+
+    function _record() internal {
+        sold += msg.value;
+    }
+
+    function _refund() private {
+        payable(msg.sender).transfer(msg.value); // match
+    }
+}
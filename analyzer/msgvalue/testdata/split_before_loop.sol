@@ -0,0 +1,20 @@
+pragma solidity ^0.8.0;
+
+contract Minter {
+    mapping(uint256 => address) owners;
+    uint256 nextId;
+
+    function mint(address[] calldata recipients) external payable {
+        uint256 price = msg.value / recipients.length;
+        require(price >= 1 ether);
+        for (uint256 i = 0; i < recipients.length; i++) {
+            owners[nextId++] = recipients[i];
+        }
+    }
+
+    function check(uint256 count) external payable {
+        for (uint256 i = 0; i < count; i++) {
+            require(msg.value >= i);
+        }
+    }
+}
@@ -0,0 +1,9 @@
+pragma solidity ^0.8.0;
+
+abstract contract Fees {
+    uint256 collected;
+
+    function _collect() internal {
+        collected += msg.value; // match, the payable caller is in Shop.sol
+    }
+}
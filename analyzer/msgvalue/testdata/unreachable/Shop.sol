@@ -0,0 +1,16 @@
+pragma solidity ^0.8.0;
+
+import "./Fees.sol";
+
+contract Shop is Fees {
+    uint256 sold;
+
+    function order() external payable {
+        _record();
+        _collect();
+    }
+
+    function _record() internal {
+        sold += msg.value;
+    }
+}
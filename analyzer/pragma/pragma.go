@@ -0,0 +1,56 @@
+// pragma evaluates the version pragma of a file, so that detectors can
+// decide which compiler behavior applies e.g. checked arithmetic since 0.8.0.
+package pragma
+
+import (
+	"solbot/ast"
+	"solbot/semver"
+)
+
+// Solidity returns the version constraint of the `pragma solidity` directive.
+// The second result is false if the file doesn't have the pragma or it's
+// invalid.
+func Solidity(file *ast.File) (semver.Constraint, bool) {
+	p := file.Pragma("solidity")
+	if p == nil {
+		return semver.Constraint{}, false
+	}
+	c, err := semver.ParseConstraint(p.Value)
+	if err != nil {
+		return semver.Constraint{}, false
+	}
+	return c, true
+}
+
+// AllowsBelow reports whether the file can be compiled with a compiler older
+// than the given version. Files without a valid pragma are assumed to target
+// the latest compiler.
+func AllowsBelow(file *ast.File, v semver.Version) bool {
+	c, ok := Solidity(file)
+	if !ok {
+		return false
+	}
+	return c.AllowsAny(semver.MustParseConstraint("<" + v.String()))
+}
+
+// AllowsAtLeast reports whether the file can be compiled with the given
+// version or a newer one. Files without a valid pragma are assumed to target
+// the latest compiler.
+func AllowsAtLeast(file *ast.File, v semver.Version) bool {
+	c, ok := Solidity(file)
+	if !ok {
+		return true
+	}
+	return c.AllowsAny(semver.MustParseConstraint(">=" + v.String()))
+}
+
+// LegacyGrammar is the version which dropped the legacy constructs e.g.
+// `throw`, `var` and the constructors named after the contract.
+var LegacyGrammar = semver.MustParse("0.5.0")
+
+// Legacy reports whether the file can only be compiled with a compiler
+// older than LegacyGrammar, so the legacy constructs are accepted. Files
+// without a valid pragma are assumed to target the latest compiler.
+func Legacy(file *ast.File) bool {
+	return !AllowsAtLeast(file, LegacyGrammar)
+}
@@ -17,8 +17,22 @@ const (
 	recommendation = "Consider renaming the variables to make the code more readable and less error-prone."
 )
 
+var rule = reporter.Rule{
+	Code:  "screaming-snake-const",
+	Title: title,
+	Explanation: "The Solidity style guide names the constants in capital letters with underscores, so that they stand out from the state " +
+		"variables. A constant named like a variable makes the reader look for the places it's written to, or worse, assume it can change.",
+	Scenario: "A reviewer sees `fee` used in a calculation and assumes an admin function can change it. The assumption hides that the fee " +
+		"is fixed at compile time, so a contract that was meant to have an adjustable fee ships without a way to adjust it.",
+	Remediation: recommendation,
+	References:  []string{"https://docs.soliditylang.org/en/latest/style-guide.html#constants"},
+	Confidence:  reporter.High,
+}
+
 type Detector struct{}
 
+func (*Detector) Rule() reporter.Rule { return rule }
+
 func (*Detector) Detect(node ast.Node) *reporter.Finding {
 	finding := reporter.Finding{}
 	matches := 0
@@ -0,0 +1,148 @@
+// taint tracks values controlled by the caller of a function. The sources are
+// the function parameters and the `msg.value`, `msg.data` globals. Local
+// variables become tainted when a tainted value is assigned to them.
+//
+// The analysis is intraprocedural and flow insensitive: a variable that is
+// tainted anywhere in the function body is considered tainted everywhere.
+// It's good enough for detectors to decide whether an operation can be
+// influenced by the user.
+package taint
+
+import "solbot/ast"
+
+type Set struct {
+	vars    map[string]bool // names of the tainted variables
+	globals bool            // are the user controlled globals tainted?
+}
+
+// Function computes the set of tainted variables of the function.
+func Function(fn *ast.FunctionDeclaration) *Set {
+	names := []string{}
+	if fn.Type != nil && fn.Type.Params != nil {
+		for _, param := range fn.Type.Params.List {
+			if param.Name != nil {
+				names = append(names, param.Name.Name)
+			}
+		}
+	}
+	s := From(fn, names...)
+	s.globals = true
+	return s
+}
+
+// From computes the set of the variables of the function tainted by the
+// named sources alone e.g. a single parameter, to follow where its value
+// goes. The user controlled globals are not sources. The variables are
+// tracked by their names, so the state variables assigned in the function
+// are tainted too.
+func From(fn *ast.FunctionDeclaration, sources ...string) *Set {
+	s := &Set{vars: map[string]bool{}}
+	for _, name := range sources {
+		s.vars[name] = true
+	}
+	if fn.Body == nil {
+		return s
+	}
+
+	// Propagate until nothing changes, so that the order of assignments in
+	// loops doesn't matter.
+	for changed := true; changed; {
+		changed = false
+		ast.Inspect(fn.Body, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.VariableDeclarationStatement:
+				if n.Value == nil || !s.IsTainted(n.Value) {
+					return true
+				}
+				for _, decl := range n.Declarations {
+					if decl != nil && !s.vars[decl.Name.Name] {
+						s.vars[decl.Name.Name] = true
+						changed = true
+					}
+				}
+			case *ast.AssignmentExpression:
+				if !s.IsTainted(n.Right) {
+					return true
+				}
+				for _, name := range assignedNames(n.Left) {
+					if !s.vars[name] {
+						s.vars[name] = true
+						changed = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	return s
+}
+
+// IsTainted reports whether the value of the expression depends on a
+// tainted variable or a user controlled global.
+func (s *Set) IsTainted(expr ast.Expression) bool {
+	tainted := false
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if tainted {
+			return false
+		}
+		switch n := node.(type) {
+		case *ast.Identifier:
+			tainted = s.vars[n.Name]
+		case *ast.MemberAccessExpression:
+			if s.globals && IsUserControlledGlobal(n) {
+				tainted = true
+				return false
+			}
+			// The member name is not a variable e.g. `amount` in
+			// `info.amount` is not the `amount` parameter.
+			ast.Inspect(n.Expression, func(node ast.Node) bool {
+				if id, ok := node.(*ast.Identifier); ok && s.vars[id.Name] {
+					tainted = true
+				}
+				return !tainted
+			})
+			return false
+		case *ast.CallOptionsExpression:
+			// Call options don't change the returned value.
+			for _, value := range n.Values {
+				if s.IsTainted(value) {
+					tainted = true
+				}
+			}
+			return false
+		}
+		return true
+	})
+	return tainted
+}
+
+// IsUserControlledGlobal reports whether the expression is `msg.value` or
+// `msg.data`.
+func IsUserControlledGlobal(expr ast.Expression) bool {
+	member, ok := expr.(*ast.MemberAccessExpression)
+	if !ok {
+		return false
+	}
+	msg, ok := member.Expression.(*ast.Identifier)
+	if !ok || msg.Name != "msg" {
+		return false
+	}
+	return member.Member.Name == "value" || member.Member.Name == "data"
+}
+
+// assignedNames returns the names of local variables assigned by the left
+// side of an assignment e.g. `x` in `x = 1` or `a, b` in `(a, b) = f()`.
+// Assignments to struct members and mapping entries are not tracked.
+func assignedNames(left ast.Expression) []string {
+	switch x := left.(type) {
+	case *ast.Identifier:
+		return []string{x.Name}
+	case *ast.TupleExpression:
+		names := []string{}
+		for _, elem := range x.Elements {
+			names = append(names, assignedNames(elem)...)
+		}
+		return names
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+// uncheckedarithmetic detects additions and subtractions inside of `unchecked`
+// blocks that operate on values controlled by the caller e.g. function
+// parameters or msg.value. Since Solidity 0.8.0 the arithmetic reverts on
+// overflow, unless it's wrapped in an unchecked block.
+//
+// Unchecked blocks are a common gas optimization. They are safe if the bound
+// is established above e.g. `require(balance >= amount)` followed by
+// `unchecked { balance -= amount; }`. An operation is considered guarded if
+// a preceding require, assert or `if (...) revert` condition in the same
+// function compares all of its non-literal operands.
+package uncheckedarithmetic
+
+import (
+	"solbot/analyzer/pragma"
+	"solbot/analyzer/taint"
+	"solbot/ast"
+	"solbot/reporter"
+	"solbot/semver"
+	"solbot/token"
+)
+
+const (
+	title          = "Unchecked arithmetic on user controlled values"
+	severity       = "Info"
+	descTempl      = "Arithmetic inside of `unchecked` blocks wraps around instead of reverting on overflow. The following operations depend on user controlled values and there is no check above them establishing the bound: {{ range .Locations }}\n- {{ .Context }}{{ end }}"
+	recommendation = "Consider removing the `unchecked` block or validating the operands e.g. with `require(balance >= amount)` before the operation."
+)
+
+var checkedArithmeticVersion = semver.MustParse("0.8.0")
+
+var rule = reporter.Rule{
+	Code:  "unchecked-arithmetic",
+	Title: title,
+	Explanation: "Since Solidity 0.8.0 the arithmetic reverts on overflow, but not inside of `unchecked` blocks, which are used to save " +
+		"gas. An unchecked operation on a value the caller controls is only safe if a check above it bounds the value.",
+	Scenario: "A vault decrements `shares[msg.sender] -= amount` inside of `unchecked` to save gas, without checking the shares first. " +
+		"A caller withdrawing more than they own underflows their shares to a huge number and drains the vault in the next call.",
+	Remediation: recommendation,
+	References:  []string{"SWC-101", "CWE-190", "https://docs.soliditylang.org/en/latest/control-structures.html#checked-or-unchecked-arithmetic"},
+	Confidence:  reporter.Low,
+}
+
+type Detector struct{}
+
+func (*Detector) Rule() reporter.Rule { return rule }
+
+func (*Detector) Detect(node ast.Node) *reporter.Finding {
+	file, ok := node.(*ast.File)
+	if !ok || !pragma.AllowsAtLeast(file, checkedArithmeticVersion) {
+		return nil
+	}
+
+	finding := reporter.Finding{}
+	for _, decl := range file.Declarations {
+		switch d := decl.(type) {
+		case *ast.FunctionDeclaration:
+			finding.Locations = append(finding.Locations, checkFunction(d)...)
+		case *ast.ContractDeclaration:
+			for _, member := range d.Body {
+				if fn, ok := member.(*ast.FunctionDeclaration); ok {
+					finding.Locations = append(finding.Locations, checkFunction(fn)...)
+				}
+			}
+		}
+	}
+
+	if len(finding.Locations) == 0 {
+		return nil
+	}
+
+	finding.Title = title
+	finding.Severity = severity
+	finding.Description = reporter.GenerateCustomDescription(descTempl, finding.Locations)
+	finding.Recommendation = recommendation
+	return &finding
+}
+
+// checkFunction walks the function body in the source order. The guards are
+// collected on the way, so that every operation is checked against the ones
+// above it.
+func checkFunction(fn *ast.FunctionDeclaration) []reporter.Location {
+	if fn.Body == nil {
+		return nil
+	}
+
+	tainted := taint.Function(fn)
+	guards := []*ast.BinaryExpression{}
+	locations := []reporter.Location{}
+
+	// Unchecked blocks can't be nested, but we still keep track of them
+	// with a stack, since Inspect notifies us only about the end of a node.
+	stack := []ast.Node{}
+	unchecked := 0
+
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		if node == nil {
+			if _, ok := stack[len(stack)-1].(*ast.UncheckedBlockStatement); ok {
+				unchecked--
+			}
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, node)
+
+		switch n := node.(type) {
+		case *ast.UncheckedBlockStatement:
+			unchecked++
+		case *ast.CallExpression:
+			if isAssertion(n) && len(n.Args) > 0 {
+				guards = append(guards, comparisons(n.Args[0])...)
+			}
+		case *ast.IfStatement:
+			if ast.Reverts(n.Consequence) {
+				guards = append(guards, comparisons(n.Condition)...)
+			}
+		}
+
+		if unchecked == 0 {
+			return true
+		}
+
+		op, operands := operation(node)
+		if op == "" || !anyTainted(tainted, operands) || isGuarded(guards, operands) {
+			return true
+		}
+		expr := node.(ast.Expression)
+		locations = append(locations, reporter.Location{
+			Position: token.Position{Offset: expr.Start()},
+			Context:  op + " `" + ast.ExprString(expr) + "` in `" + functionName(fn) + "`",
+		})
+		return true
+	})
+	return locations
+}
+
+// operation returns the name of the arithmetic operation performed by the
+// node and its operands. The name is empty if the node is not an addition or
+// a subtraction.
+func operation(node ast.Node) (string, []ast.Expression) {
+	switch n := node.(type) {
+	case *ast.BinaryExpression:
+		switch n.Operator {
+		case token.ADD:
+			return "Addition", []ast.Expression{n.Left, n.Right}
+		case token.SUB:
+			return "Subtraction", []ast.Expression{n.Left, n.Right}
+		}
+	case *ast.AssignmentExpression:
+		switch n.Operator {
+		case token.ASSIGN_ADD:
+			return "Addition", []ast.Expression{n.Left, n.Right}
+		case token.ASSIGN_SUB:
+			return "Subtraction", []ast.Expression{n.Left, n.Right}
+		}
+	case *ast.UnaryExpression:
+		switch n.Operator {
+		case token.INC:
+			return "Increment", []ast.Expression{n.Operand}
+		case token.DEC:
+			return "Decrement", []ast.Expression{n.Operand}
+		}
+	}
+	return "", nil
+}
+
+func anyTainted(tainted *taint.Set, operands []ast.Expression) bool {
+	for _, operand := range operands {
+		if tainted.IsTainted(operand) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGuarded reports whether one of the guard comparisons mentions all of the
+// non-literal operands e.g. `balance >= amount` guards `balance - amount`
+// and `x > 0` guards `x - 1`.
+func isGuarded(guards []*ast.BinaryExpression, operands []ast.Expression) bool {
+	for _, guard := range guards {
+		sides := map[string]bool{
+			ast.ExprString(guard.Left):  true,
+			ast.ExprString(guard.Right): true,
+		}
+		covered := true
+		for _, operand := range operands {
+			if _, ok := operand.(*ast.BasicLit); ok {
+				continue
+			}
+			if !sides[ast.ExprString(operand)] {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
+}
+
+// comparisons returns the relational comparisons that must hold for the
+// condition to be true e.g. both comparisons in `a >= b && c < d`.
+func comparisons(cond ast.Expression) []*ast.BinaryExpression {
+	switch x := cond.(type) {
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 {
+			return comparisons(x.Elements[0])
+		}
+	case *ast.BinaryExpression:
+		switch x.Operator {
+		case token.AND, token.OR:
+			// We only check that the bound is mentioned, not its
+			// direction, so both sides of || are collected as well.
+			return append(comparisons(x.Left), comparisons(x.Right)...)
+		case token.LESS_THAN, token.LESS_THAN_OR_EQUAL,
+			token.GREATER_THAN, token.GREATER_THAN_OR_EQUAL:
+			return []*ast.BinaryExpression{x}
+		}
+	}
+	return nil
+}
+
+// isAssertion reports whether the call is `require(...)` or `assert(...)`.
+func isAssertion(call *ast.CallExpression) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && (ident.Name == "require" || ident.Name == "assert")
+}
+
+func functionName(fn *ast.FunctionDeclaration) string {
+	if fn.Name != nil {
+		return fn.Name.Name
+	}
+	return fn.Kind.String()
+}
@@ -0,0 +1,30 @@
+package uncheckedarithmetic
+
+import (
+	"solbot/analyzer/detectortest"
+	"testing"
+)
+
+func Test_DetectUnguardedUncheckedSubtraction(t *testing.T) {
+	findings := detectortest.Run(t, &Detector{}, "testdata/unguarded.sol", []detectortest.Finding{
+		{Line: 8, Contains: "Subtraction `total - amount` in `withdraw`"},
+	})
+	if len(findings) == 1 && findings[0].Severity != "Info" {
+		t.Errorf("Expected severity Info, got %s", findings[0].Severity)
+	}
+}
+
+func Test_ShouldNotDetectGuardedUnchecked(t *testing.T) {
+	detectortest.Run(t, &Detector{}, "testdata/guarded.sol", nil)
+}
+
+func Test_DetectTaintedLocalAndMsgValue(t *testing.T) {
+	detectortest.Run(t, &Detector{}, "testdata/tainted.sol", []detectortest.Finding{
+		{Line: 7, Contains: "Addition `total += msg.value` in `deposit`"},
+		{Line: 8, Contains: "Subtraction `total -= fee` in `deposit`"},
+	})
+}
+
+func Test_ShouldNotDetectBefore080(t *testing.T) {
+	detectortest.Run(t, &Detector{}, "testdata/before_080.sol", nil)
+}
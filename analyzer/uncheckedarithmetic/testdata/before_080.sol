@@ -0,0 +1,7 @@
+pragma solidity ^0.7.6;
+
+contract Vault {
+    function f(uint256 amount) public pure returns (uint256) {
+        return amount - 1;
+    }
+}
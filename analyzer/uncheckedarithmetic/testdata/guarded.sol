@@ -0,0 +1,22 @@
+pragma solidity ^0.8.0;
+
+contract Vault {
+    mapping(address => uint256) balances;
+
+    error InsufficientBalance();
+
+    function withdraw(uint256 amount) public {
+        uint256 balance = balances[msg.sender];
+        require(balance >= amount, "insufficient balance");
+        unchecked {
+            balances[msg.sender] = balance - amount;
+        }
+    }
+
+    function withdrawAll(uint256 amount) public {
+        if (amount > balances[msg.sender]) revert InsufficientBalance();
+        unchecked {
+            balances[msg.sender] -= amount;
+        }
+    }
+}
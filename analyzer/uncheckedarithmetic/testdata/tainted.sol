@@ -0,0 +1,12 @@
+contract Vault {
+    uint256 total;
+
+    function deposit(uint256 amount) public payable {
+        uint256 fee = amount / 100;
+        unchecked {
+            total += msg.value;   // match
+            total -= fee;         // match
+        }
+        require(total >= fee);
+    }
+}
@@ -0,0 +1,17 @@
+pragma solidity ^0.8.0;
+
+contract Vault {
+    uint256 total;
+
+    function withdraw(uint256 amount) public {
+        unchecked {
+            total = total - amount; // match
+        }
+    }
+
+    function loop(uint256[] calldata values) public {
+        for (uint256 i = 0; i < values.length;) {
+            unchecked { ++i; }
+        }
+    }
+}
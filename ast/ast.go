@@ -1,6 +1,9 @@
 package ast
 
-import "solbot/token"
+import (
+	"solbot/token"
+	"strings"
+)
 
 // All nodes in the AST must implement the Node interface.
 type Node interface {
@@ -8,6 +11,66 @@ type Node interface {
 	End() token.Pos   // First character immediately after the node
 }
 
+// NodeRange returns the source range covered by the node. The range is
+// end-exclusive, see token.Range for the details.
+func NodeRange(n Node) token.Range {
+	return token.Range{Start: n.Start(), End: n.End()}
+}
+
+// Root returns the variable at the base of the accessed expression e.g.
+// `balances` in `balances[to].amount` or `(balances)[to]`; or nil if it's
+// not a variable.
+func Root(x Expression) *Identifier {
+	for {
+		switch e := x.(type) {
+		case *Identifier:
+			return e
+		case *IndexAccessExpression:
+			x = e.Expression
+		case *MemberAccessExpression:
+			x = e.Expression
+		case *TupleExpression:
+			if len(e.Elements) != 1 {
+				return nil
+			}
+			x = e.Elements[0]
+		default:
+			return nil
+		}
+	}
+}
+
+// ModifierName returns the name of the invoked modifier e.g. `onlyOwner`
+// in both `onlyOwner` and `Ownable.onlyOwner`.
+func ModifierName(inv *ModifierInvocation) string {
+	switch name := inv.Name.(type) {
+	case *Identifier:
+		return name.Name
+	case *MemberAccessExpression:
+		return name.Member.Name
+	}
+	return ""
+}
+
+// Reverts reports whether the statement always reverts e.g. `revert
+// Unauthorized();`, `revert("paused");` or a block ending with one of them.
+func Reverts(stmt Statement) bool {
+	switch s := stmt.(type) {
+	case *RevertStatement:
+		return true
+	case *ExpressionStatement:
+		call, ok := s.Expression.(*CallExpression)
+		if !ok {
+			return false
+		}
+		ident, ok := call.Function.(*Identifier)
+		return ok && ident.Name == "revert"
+	case *BlockStatement:
+		return len(s.Statements) > 0 && Reverts(s.Statements[len(s.Statements)-1])
+	}
+	return false
+}
+
 // All expression nodes in the AST must implement the Expression interface.
 type Expression interface {
 	Node
@@ -40,10 +103,11 @@ func (c *Comment) End() token.Pos {
 
 /*~*~*~*~*~*~*~*~*~*~ Expressions and Types *~*~*~*~*~*~*~*~*~*~*/
 
-// @TODO: Data location is missing
 type Param struct {
-	Name *Identifier // param name e.g. "x" or "recipient"
-	Type Expression  // e.g. ElementaryType
+	Name     *Identifier  // param name e.g. "x" or "recipient"; or nil
+	Type     Expression   // e.g. ElementaryType
+	Location DataLocation // data location e.g. memory, calldata; or zero value
+	Indexed  bool         // is it an indexed event parameter?
 }
 
 type ParamList struct {
@@ -58,6 +122,8 @@ type FunctionType struct {
 	Results    *ParamList // output parameters; or nil
 	Mutability Mutability // mutability specifier e.g. pure, view, payable
 	Visibility Visibility // visibility specifier e.g. public, private, internal, external
+	Constant   token.Pos  // position of the legacy "constant" mutability, which is a view; or 0
+	Implicit   bool       // is the visibility the legacy default public, not written in the source?
 }
 
 type Identifier struct {
@@ -72,25 +138,268 @@ type ElementaryType struct {
 	ValuePos token.Pos   // type literal position
 	Kind     token.Token // type of the literal e.g. token.ADDRESS, token.UINT_256, token.BOOL
 	Value    string      // type literal value e.g. "address", "uint256", "bool" as a string
+	Payable  token.Pos   // position of "payable" in "address payable"; or 0
 }
 
-// Start() and End() implementations for Expression type Nodes
+// Literal of a basic type: number, string, hex string or boolean.
+//
+// The adjacent string literals are concatenated e.g. "a long " "message",
+// which wraps the long strings. The concatenated literal keeps its parts,
+// and its value joins them in the quotes of the first part e.g.
+// "\"a long message\"". The parts are not walked.
+type BasicLit struct {
+	ValuePos token.Pos       // literal position
+	Kind     token.TokenType // e.g. token.DECIMAL_NUMBER, token.STRING_LITERAL, token.TRUE_LITERAL
+	Value    string          // literal value as written in the source e.g. "1_000", "\"hello\""
+	Unit     *Identifier     // ether or time subdenomination e.g. "ether", "days"; or nil
+	Parts    []*BasicLit     // adjacent string literals concatenated into this one; or nil
+}
+
+// Solidity's mapping type e.g. mapping(address owner => uint256 balance).
+// Key and value names are optional.
+type MappingType struct {
+	Mapping   token.Pos   // position of the "mapping" keyword
+	Key       Expression  // key type
+	KeyName   *Identifier // key name; or nil
+	Value     Expression  // value type
+	ValueName *Identifier // value name; or nil
+	Rparen    token.Pos   // position of the closing parenthesis
+}
+
+// Static and dynamic arrays e.g. uint256[] or address[10].
+type ArrayType struct {
+	Elem     Expression // element type
+	Lbracket token.Pos  // position of "["
+	Len      Expression // array length; or nil for dynamic arrays
+	Rbracket token.Pos  // position of "]"
+}
+
+// An expression like `-x`, `!ok`, `delete x`, or a postfix `i++`.
+type UnaryExpression struct {
+	OpPos    token.Pos       // position of the operator
+	Operator token.TokenType // e.g. token.NOT, token.SUB, token.INC
+	Operand  Expression      // the operand
+	Postfix  bool            // is it a postfix operation e.g. i++?
+}
+
+// An expression like `a + b` or `x >= y`.
+type BinaryExpression struct {
+	Left     Expression      // left operand
+	OpPos    token.Pos       // position of the operator
+	Operator token.TokenType // e.g. token.ADD, token.EQUAL, token.AND
+	Right    Expression      // right operand
+}
+
+// In Solidity assignments are expressions e.g. `a = b` or `total += amount`.
+type AssignmentExpression struct {
+	Left     Expression      // assigned value
+	OpPos    token.Pos       // position of the operator
+	Operator token.TokenType // e.g. token.ASSIGN, token.ASSIGN_ADD
+	Right    Expression      // value assigned
+}
+
+// The ternary operator e.g. `cond ? a : b`.
+type ConditionalExpression struct {
+	Condition Expression // condition before "?"
+	Question  token.Pos  // position of "?"
+	True      Expression // value if the condition holds
+	False     Expression // value otherwise
+}
+
+// A function call, a type conversion e.g. `address(0)` or a struct
+// constructor. Arguments can be passed by position or by name e.g.
+// `f({to: a, amount: 1})`.
+type CallExpression struct {
+	Function Expression    // function expression e.g. Identifier or MemberAccessExpression
+	Lparen   token.Pos     // position of "("
+	Args     []Expression  // function arguments; or nil
+	Names    []*Identifier // argument names in the named argument form; or nil
+	Rparen   token.Pos     // position of ")"
+}
+
+// Call options e.g. `{value: 1 ether, gas: 2300}` in `to.call{value: 1 ether}("")`.
+type CallOptionsExpression struct {
+	Expression Expression    // the expression the options apply to
+	Lbrace     token.Pos     // position of "{"
+	Names      []*Identifier // option names e.g. value, gas, salt
+	Values     []Expression  // option values
+	Rbrace     token.Pos     // position of "}"
+}
+
+// Member access e.g. `msg.sender` or `token.transfer`.
+type MemberAccessExpression struct {
+	Expression Expression  // expression before the period
+	Member     *Identifier // accessed member
+}
+
+// Index access e.g. `balances[owner]`.
+type IndexAccessExpression struct {
+	Expression Expression // indexed expression
+	Lbracket   token.Pos  // position of "["
+	Index      Expression // index expression; or nil in type names e.g. `Foo[]`
+	Rbracket   token.Pos  // position of "]"
+}
+
+// Slice of a calldata array e.g. `data[4:]`.
+type IndexRangeAccessExpression struct {
+	Expression Expression // indexed expression
+	Lbracket   token.Pos  // position of "["
+	From       Expression // start of the range; or nil
+	To         Expression // end of the range; or nil
+	Rbracket   token.Pos  // position of "]"
+}
+
+// Tuple e.g. `(a, , b)`. Parenthesized expressions are represented as
+// single element tuples, the same as in solc.
+type TupleExpression struct {
+	Lparen   token.Pos    // position of "("
+	Elements []Expression // tuple elements; missing elements are nil
+	Rparen   token.Pos    // position of ")"
+}
+
+// Inline array e.g. `[1, 2, 3]`.
+type InlineArrayExpression struct {
+	Lbracket token.Pos    // position of "["
+	Elements []Expression // array elements
+	Rbracket token.Pos    // position of "]"
+}
+
+// A placeholder for a missing expression, so that the list it's missing
+// from keeps its length e.g. the second argument of `f(a, , b)` or of
+// `f(a, ` while it's being typed. The range spans the whitespace where the
+// expression is expected. It also replaces the expression nested deeper
+// than the limit of the parser, spanning it then, see parser.Limits.
+type BadExpression struct {
+	From token.Pos // position of the first character of the missing expression
+	To   token.Pos // position of the first character after it
+}
+
+// Contract creation or a new dynamic memory array e.g. `new Vault()` or
+// `new uint256[](n)`. The arguments are part of the surrounding CallExpression.
+type NewExpression struct {
+	New  token.Pos  // position of the "new" keyword
+	Type Expression // type name
+}
 
-func (x *Identifier) Start() token.Pos     { return x.NamePos }
-func (x *ElementaryType) Start() token.Pos { return x.ValuePos }
+func (p *Param) Start() token.Pos {
+	if p.Type != nil {
+		return p.Type.Start()
+	}
+	return p.Name.Start()
+}
+func (p *Param) End() token.Pos {
+	if p.Name != nil {
+		return p.Name.End()
+	}
+	return p.Type.End()
+}
+func (l *ParamList) Start() token.Pos { return l.Opening }
+func (l *ParamList) End() token.Pos   { return l.Closing + 1 }
+
+// Start() and End() implementations for Expression type Nodes
 
-func (x *Identifier) End() token.Pos     { return token.Pos(int(x.NamePos) + len(x.Name)) }
-func (x *ElementaryType) End() token.Pos { return token.Pos(int(x.ValuePos) + len(x.Value)) }
+func (x *Identifier) Start() token.Pos                 { return x.NamePos }
+func (x *ElementaryType) Start() token.Pos             { return x.ValuePos }
+func (x *BasicLit) Start() token.Pos                   { return x.ValuePos }
+func (x *MappingType) Start() token.Pos                { return x.Mapping }
+func (x *ArrayType) Start() token.Pos                  { return x.Elem.Start() }
+func (x *FunctionType) Start() token.Pos               { return x.Func }
+func (x *UnaryExpression) Start() token.Pos            { return x.start() }
+func (x *BinaryExpression) Start() token.Pos           { return x.Left.Start() }
+func (x *AssignmentExpression) Start() token.Pos       { return x.Left.Start() }
+func (x *ConditionalExpression) Start() token.Pos      { return x.Condition.Start() }
+func (x *CallExpression) Start() token.Pos             { return x.Function.Start() }
+func (x *CallOptionsExpression) Start() token.Pos      { return x.Expression.Start() }
+func (x *MemberAccessExpression) Start() token.Pos     { return x.Expression.Start() }
+func (x *IndexAccessExpression) Start() token.Pos      { return x.Expression.Start() }
+func (x *IndexRangeAccessExpression) Start() token.Pos { return x.Expression.Start() }
+func (x *TupleExpression) Start() token.Pos            { return x.Lparen }
+func (x *InlineArrayExpression) Start() token.Pos      { return x.Lbracket }
+func (x *NewExpression) Start() token.Pos              { return x.New }
+func (x *BadExpression) Start() token.Pos              { return x.From }
+
+func (x *Identifier) End() token.Pos { return token.Pos(int(x.NamePos) + len(x.Name)) }
+func (x *ElementaryType) End() token.Pos {
+	if x.Payable != 0 {
+		return x.Payable + 7 // length of "payable"
+	}
+	return token.Pos(int(x.ValuePos) + len(x.Value))
+}
+func (x *BasicLit) End() token.Pos {
+	if x.Unit != nil {
+		return x.Unit.End()
+	}
+	if len(x.Parts) > 0 {
+		return x.Parts[len(x.Parts)-1].End()
+	}
+	return token.Pos(int(x.ValuePos) + len(x.Value))
+}
+func (x *MappingType) End() token.Pos { return x.Rparen + 1 }
+func (x *ArrayType) End() token.Pos   { return x.Rbracket + 1 }
+
+// @TODO: The end of a function type ignores the visibility and mutability
+// specifiers written after the parameters.
+func (x *FunctionType) End() token.Pos {
+	if x.Results != nil {
+		return x.Results.End()
+	}
+	return x.Params.End()
+}
+func (x *UnaryExpression) End() token.Pos {
+	if x.Postfix {
+		return x.OpPos + 2 // length of "++" or "--"
+	}
+	return x.Operand.End()
+}
+func (x *BinaryExpression) End() token.Pos           { return x.Right.End() }
+func (x *AssignmentExpression) End() token.Pos       { return x.Right.End() }
+func (x *ConditionalExpression) End() token.Pos      { return x.False.End() }
+func (x *CallExpression) End() token.Pos             { return x.Rparen + 1 }
+func (x *CallOptionsExpression) End() token.Pos      { return x.Rbrace + 1 }
+func (x *MemberAccessExpression) End() token.Pos     { return x.Member.End() }
+func (x *IndexAccessExpression) End() token.Pos      { return x.Rbracket + 1 }
+func (x *IndexRangeAccessExpression) End() token.Pos { return x.Rbracket + 1 }
+func (x *TupleExpression) End() token.Pos            { return x.Rparen + 1 }
+func (x *InlineArrayExpression) End() token.Pos      { return x.Rbracket + 1 }
+func (x *NewExpression) End() token.Pos              { return x.Type.End() }
+func (x *BadExpression) End() token.Pos              { return x.To }
+
+func (x *UnaryExpression) start() token.Pos {
+	if x.Postfix {
+		return x.Operand.Start()
+	}
+	return x.OpPos
+}
 
 // expressionNode() implementations to ensure that only expressions and types
 // can be assigned to an Expression. This is useful if by mistake we try to use
 // a Statement in a place where an Expression should be used instead.
 
-func (*Identifier) expressionNode()     {}
-func (*ElementaryType) expressionNode() {}
+func (*Identifier) expressionNode()                 {}
+func (*ElementaryType) expressionNode()             {}
+func (*BasicLit) expressionNode()                   {}
+func (*MappingType) expressionNode()                {}
+func (*ArrayType) expressionNode()                  {}
+func (*FunctionType) expressionNode()               {}
+func (*UnaryExpression) expressionNode()            {}
+func (*BinaryExpression) expressionNode()           {}
+func (*AssignmentExpression) expressionNode()       {}
+func (*ConditionalExpression) expressionNode()      {}
+func (*CallExpression) expressionNode()             {}
+func (*CallOptionsExpression) expressionNode()      {}
+func (*MemberAccessExpression) expressionNode()     {}
+func (*IndexAccessExpression) expressionNode()      {}
+func (*IndexRangeAccessExpression) expressionNode() {}
+func (*TupleExpression) expressionNode()            {}
+func (*InlineArrayExpression) expressionNode()      {}
+func (*NewExpression) expressionNode()              {}
+func (*BadExpression) expressionNode()              {}
 
 /*~*~*~*~*~*~*~*~*~*~*~*~* Statements *~*~*~*~*~*~*~*~*~*~*~*~*~*/
 
+// Statements that end with a semicolon don't include it in their range. The
+// same goes for declarations.
+
 // In Solidity statements appear in blocks, which are enclosed in curly braces.
 // Block: { <<statement>> (and/or) <<unchecked-block>> }
 // For example: Constructor, Function, Modifier etc. delcarations have a body, which
@@ -101,6 +410,13 @@ type BlockStatement struct {
 	RightBrace token.Pos   // position of the right curly brace
 }
 
+// Arithmetic inside of the unchecked block is not checked for over- and
+// underflows e.g. `unchecked { i++; }`. Available since Solidity 0.8.0.
+type UncheckedBlockStatement struct {
+	Unchecked token.Pos       // position of the "unchecked" keyword
+	Body      *BlockStatement // block with the unchecked statements
+}
+
 // Return statement is in a form of "return <<expression>>;", where
 // the expression is optional. In languages like Go, the return statement can
 // return an array of Expressions e.g., "return x, y, z". In Solidity, however,
@@ -111,11 +427,158 @@ type ReturnStatement struct {
 	Result Expression // result expressions or nil
 }
 
+// An expression used as a statement e.g. `x = 5;` or `foo();`.
+type ExpressionStatement struct {
+	Expression Expression
+}
+
+// Declaration of local variables e.g. `uint256 x = 5;` or the tuple form
+// `(bool ok, ) = to.call("");`.
+type VariableDeclarationStatement struct {
+	Var          token.Pos              // position of the legacy "var" keyword; or 0
+	Lparen       token.Pos              // position of "(" in the tuple form; or 0
+	Declarations []*VariableDeclaration // declared variables, without types after "var"; missing components in the tuple form are nil
+	Value        Expression             // initial value; or nil
+	Rparen       token.Pos              // position of ")" in the tuple form; or 0
+}
+
+type IfStatement struct {
+	If          token.Pos  // position of the "if" keyword
+	Condition   Expression // condition inside of the parentheses
+	Consequence Statement  // statement executed if the condition holds
+	Alternative Statement  // the "else" statement; or nil
+}
+
+type ForStatement struct {
+	For       token.Pos  // position of the "for" keyword
+	Init      Statement  // initialization statement; or nil
+	Condition Expression // loop condition; or nil
+	Post      Expression // expression evaluated after each iteration; or nil
+	Body      Statement  // loop body
+}
+
+type WhileStatement struct {
+	While     token.Pos  // position of the "while" keyword
+	Condition Expression // loop condition
+	Body      Statement  // loop body
+}
+
+type DoWhileStatement struct {
+	Do        token.Pos  // position of the "do" keyword
+	Body      Statement  // loop body
+	Condition Expression // loop condition
+	Rparen    token.Pos  // position of the ")" closing the condition
+}
+
+type ContinueStatement struct {
+	Continue token.Pos // position of the "continue" keyword
+}
+
+type BreakStatement struct {
+	Break token.Pos // position of the "break" keyword
+}
+
+// Event emission e.g. `emit Transfer(from, to, amount);`.
+type EmitStatement struct {
+	Emit token.Pos       // position of the "emit" keyword
+	Call *CallExpression // event call
+}
+
+// Revert with a custom error e.g. `revert Unauthorized(msg.sender);`.
+// Calls like `revert("reason")` are regular function calls.
+type RevertStatement struct {
+	Revert token.Pos       // position of the "revert" identifier or the legacy "throw" keyword
+	Call   *CallExpression // custom error call; or nil for "throw"
+	Legacy bool            // is it the legacy "throw" statement?
+}
+
+// The `_;` statement in modifiers, where the body of the modified function
+// is inserted.
+type PlaceholderStatement struct {
+	Underscore token.Pos // position of "_"
+}
+
+// Inline assembly block. The Yul code inside is kept as a string; only the
+// Solidity variables it refers to are parsed, see the yul package.
+type AssemblyStatement struct {
+	Assembly    token.Pos     // position of the "assembly" keyword
+	LeftBrace   token.Pos     // position of "{"
+	Body        string        // raw source between the braces
+	Identifiers []*Identifier // Solidity variables used in the Yul code e.g. `x` in `x.slot`; or nil if it doesn't parse
+	RightBrace  token.Pos     // position of "}"
+}
+
+// try <<expression>> returns (<<params>>) { ... } catch ... { ... }
+type TryStatement struct {
+	Try        token.Pos       // position of the "try" keyword
+	Expression Expression      // external call or contract creation
+	Returns    *ParamList      // returned values; or nil
+	Body       *BlockStatement // executed if the call succeeds
+	Catches    []*CatchClause  // catch clauses
+}
+
+// A placeholder for a statement nested deeper than the limit of the parser,
+// see parser.Limits. The range spans the statement, which isn't parsed.
+type BadStatement struct {
+	From token.Pos // position of the first character of the statement
+	To   token.Pos // position of the first character after it
+}
+
+// catch <<Error|Panic>>(<<params>>) { ... }
+type CatchClause struct {
+	Catch  token.Pos       // position of the "catch" keyword
+	Kind   *Identifier     // e.g. Error or Panic; or nil
+	Params *ParamList      // parameters; or nil
+	Body   *BlockStatement // clause body
+}
+
 // Start() and End() implementations for Statement type Nodes
 
-func (s *BlockStatement) Start() token.Pos  { return s.LeftBrace }
-func (s *BlockStatement) End() token.Pos    { return s.RightBrace + 1 }
-func (s *ReturnStatement) Start() token.Pos { return s.Return }
+func (s *BlockStatement) Start() token.Pos               { return s.LeftBrace }
+func (s *BlockStatement) End() token.Pos                 { return s.RightBrace + 1 }
+func (s *UncheckedBlockStatement) Start() token.Pos      { return s.Unchecked }
+func (s *UncheckedBlockStatement) End() token.Pos        { return s.Body.End() }
+func (s *ReturnStatement) Start() token.Pos              { return s.Return }
+func (s *ExpressionStatement) Start() token.Pos          { return s.Expression.Start() }
+func (s *ExpressionStatement) End() token.Pos            { return s.Expression.End() }
+func (s *VariableDeclarationStatement) Start() token.Pos { return s.start() }
+func (s *VariableDeclarationStatement) End() token.Pos   { return s.end() }
+func (s *IfStatement) Start() token.Pos                  { return s.If }
+func (s *IfStatement) End() token.Pos {
+	if s.Alternative != nil {
+		return s.Alternative.End()
+	}
+	return s.Consequence.End()
+}
+func (s *ForStatement) Start() token.Pos         { return s.For }
+func (s *ForStatement) End() token.Pos           { return s.Body.End() }
+func (s *WhileStatement) Start() token.Pos       { return s.While }
+func (s *WhileStatement) End() token.Pos         { return s.Body.End() }
+func (s *DoWhileStatement) Start() token.Pos     { return s.Do }
+func (s *DoWhileStatement) End() token.Pos       { return s.Rparen + 1 }
+func (s *ContinueStatement) Start() token.Pos    { return s.Continue }
+func (s *ContinueStatement) End() token.Pos      { return s.Continue + 8 } // length of "continue"
+func (s *BreakStatement) Start() token.Pos       { return s.Break }
+func (s *BreakStatement) End() token.Pos         { return s.Break + 5 } // length of "break"
+func (s *EmitStatement) Start() token.Pos        { return s.Emit }
+func (s *EmitStatement) End() token.Pos          { return s.Call.End() }
+func (s *RevertStatement) Start() token.Pos      { return s.Revert }
+func (s *RevertStatement) End() token.Pos        { return s.end() }
+func (s *PlaceholderStatement) Start() token.Pos { return s.Underscore }
+func (s *PlaceholderStatement) End() token.Pos   { return s.Underscore + 1 }
+func (s *AssemblyStatement) Start() token.Pos    { return s.Assembly }
+func (s *AssemblyStatement) End() token.Pos      { return s.RightBrace + 1 }
+func (s *BadStatement) Start() token.Pos         { return s.From }
+func (s *BadStatement) End() token.Pos           { return s.To }
+func (s *TryStatement) Start() token.Pos         { return s.Try }
+func (s *TryStatement) End() token.Pos {
+	if len(s.Catches) > 0 {
+		return s.Catches[len(s.Catches)-1].End()
+	}
+	return s.Body.End()
+}
+func (s *CatchClause) Start() token.Pos { return s.Catch }
+func (s *CatchClause) End() token.Pos   { return s.Body.End() }
 func (s *ReturnStatement) End() token.Pos {
 	if s.Result != nil {
 		return s.Result.End()
@@ -123,56 +586,341 @@ func (s *ReturnStatement) End() token.Pos {
 	return s.Return + 6 // length of "return"
 }
 
+func (s *RevertStatement) end() token.Pos {
+	if s.Call == nil {
+		return s.Revert + 5 // length of "throw"
+	}
+	return s.Call.End()
+}
+
+func (s *VariableDeclarationStatement) start() token.Pos {
+	if s.Var != 0 {
+		return s.Var
+	}
+	if s.Lparen != 0 || len(s.Declarations) != 1 {
+		return s.Lparen
+	}
+	return s.Declarations[0].Start()
+}
+
+func (s *VariableDeclarationStatement) end() token.Pos {
+	if s.Value != nil {
+		return s.Value.End()
+	}
+	if s.Rparen != 0 {
+		return s.Rparen + 1
+	}
+	return s.Declarations[len(s.Declarations)-1].End()
+}
+
 // statementNode() ensures that only statement nodes can be assigned to a Statement.
-func (*BlockStatement) statementNode()  {}
-func (*ReturnStatement) statementNode() {}
+func (*BlockStatement) statementNode()               {}
+func (*UncheckedBlockStatement) statementNode()      {}
+func (*ReturnStatement) statementNode()              {}
+func (*ExpressionStatement) statementNode()          {}
+func (*VariableDeclarationStatement) statementNode() {}
+func (*IfStatement) statementNode()                  {}
+func (*ForStatement) statementNode()                 {}
+func (*WhileStatement) statementNode()               {}
+func (*DoWhileStatement) statementNode()             {}
+func (*ContinueStatement) statementNode()            {}
+func (*BreakStatement) statementNode()               {}
+func (*EmitStatement) statementNode()                {}
+func (*RevertStatement) statementNode()              {}
+func (*PlaceholderStatement) statementNode()         {}
+func (*AssemblyStatement) statementNode()            {}
+func (*TryStatement) statementNode()                 {}
+func (*BadStatement) statementNode()                 {}
 
 /*~*~*~*~*~*~*~*~*~*~*~*~ Declarations ~*~*~*~*~*~*~*~*~*~*~*~*~*/
 
-// @TODO: Add Contract declaration
-// @TODO: Add Interface declaration
-// @TODO: Add Library declaration
-// @TODO: Add Struct declaration
-// @TODO: Add Enum declaration
-// @TODO: Add Event declaration
-// @TODO: Add Error declaration
-// @TODO: Add Using For Directive declaration
-// @TODO: Add User Defined Value Type declaration
-
-// Pragma and import directives could go into the File struct, since
-// they are connected with a particular file.
-// @TODO?: Add Pragma Directive declaration
-// @TODO?: Add Import Directive declaration
-
-// @TODO: Add modifier invocations *CallExpression
+// Pragma directive e.g. `pragma solidity ^0.8.0;` or `pragma abicoder v2;`.
+type PragmaDirective struct {
+	Pragma    token.Pos   // position of the "pragma" keyword
+	Name      *Identifier // pragma name e.g. "solidity", "abicoder", "experimental"
+	Kind      PragmaKind  // kind of the pragma told by its name
+	Argument  string      // version requirement, feature e.g. "ABIEncoderV2" or coder e.g. "v2"
+	Value     string      // raw pragma value e.g. "^0.8.0" or ">=0.6.0 <0.9.0"
+	Semicolon token.Pos   // position of the closing semicolon
+}
+
+// Kind of a pragma directive.
+type PragmaKind int
+
+const (
+	UnknownPragma      PragmaKind = iota
+	VersionPragma                 // pragma solidity ^0.8.0;
+	ExperimentalPragma            // pragma experimental ABIEncoderV2;
+	AbicoderPragma                // pragma abicoder v2;
+)
+
+var pragmaKinds = [...]string{
+	UnknownPragma:      "unknown",
+	VersionPragma:      "version",
+	ExperimentalPragma: "experimental",
+	AbicoderPragma:     "abicoder",
+}
+
+func (k PragmaKind) String() string {
+	if k < 0 || int(k) >= len(pragmaKinds) {
+		return "unknown"
+	}
+	return pragmaKinds[k]
+}
+
+// PragmaKindOf returns the kind of the pragma of the name e.g.
+// VersionPragma for "solidity".
+func PragmaKindOf(name string) PragmaKind {
+	switch name {
+	case "solidity":
+		return VersionPragma
+	case "experimental":
+		return ExperimentalPragma
+	case "abicoder":
+		return AbicoderPragma
+	}
+	return UnknownPragma
+}
+
+// Import directive in one of the forms:
+// - import "./Foo.sol";
+// - import "./Foo.sol" as Foo;
+// - import * as Foo from "./Foo.sol";
+// - import {A, B as C} from "./Foo.sol";
+type ImportDirective struct {
+	Import    token.Pos       // position of the "import" keyword
+	Path      *BasicLit       // imported path as a string literal
+	Alias     *Identifier     // unit alias in `as Foo` and `* as Foo` forms; or nil
+	Symbols   []*ImportSymbol // imported symbols in the `{A, B as C}` form; or nil
+	Semicolon token.Pos       // position of the closing semicolon
+}
+
+// A single symbol imported by name e.g. `B as C` in `import {A, B as C} from "..."`.
+type ImportSymbol struct {
+	Name  *Identifier // imported name
+	Alias *Identifier // local alias; or nil
+}
+
+// Contract, interface or library declaration.
+type ContractDeclaration struct {
+	Contract   token.Pos               // position of the first keyword e.g. "abstract" or "contract"
+	Kind       token.TokenType         // token.CONTRACT, token.INTERFACE or token.LIBRARY
+	Abstract   bool                    // is the contract marked as abstract?
+	Name       *Identifier             // contract name
+	Bases      []*InheritanceSpecifier // inherited contracts e.g. `is Ownable, ERC20("Token", "TKN")`
+	LeftBrace  token.Pos               // position of "{"
+	Body       []Declaration           // contract members
+	RightBrace token.Pos               // position of "}"
+}
+
+// A base contract in the inheritance list, optionally with the arguments
+// passed to its constructor e.g. `ERC20("Token", "TKN")`.
+type InheritanceSpecifier struct {
+	Name   Expression   // base name e.g. Identifier or MemberAccessExpression
+	Lparen token.Pos    // position of "(" if there are arguments; or 0
+	Args   []Expression // constructor arguments; or nil
+	Rparen token.Pos    // position of ")" if there are arguments; or 0
+}
+
+// Modifier invocation in a function header e.g. `onlyOwner` or
+// `onlyRole(ADMIN)`. Calls to base constructors e.g. `Ownable(msg.sender)`
+// in constructor headers are represented by this node as well.
+type ModifierInvocation struct {
+	Name   Expression   // modifier name e.g. Identifier or MemberAccessExpression
+	Lparen token.Pos    // position of "(" if there are arguments; or 0
+	Args   []Expression // arguments; or nil
+	Rparen token.Pos    // position of ")" if there are arguments; or 0
+}
+
+// The `override` specifier, optionally with the list of overridden bases
+// e.g. `override(ERC20, IERC20)`.
+type OverrideSpecifier struct {
+	Override token.Pos    // position of the "override" keyword
+	Bases    []Expression // overridden bases; or nil
+	Rparen   token.Pos    // position of ")" if there is a list of bases; or 0
+}
+
 // @TODO: Add documentation comments
 type FunctionDeclaration struct {
-	Name *Identifier     // function name
-	Type *FunctionType   // function signature with input/output parameters, mutability, visibility
-	Body *BlockStatement // function body inside curly braces
+	Kind      token.TokenType       // token.FUNCTION, token.CONSTRUCTOR, token.FALLBACK or token.RECEIVE
+	Name      *Identifier           // function name; or nil for constructors, fallback and receive functions, except the legacy constructors
+	Type      *FunctionType         // function signature with input/output parameters, mutability, visibility
+	Modifiers []*ModifierInvocation // modifier invocations; or nil
+	Virtual   bool                  // is the function marked as virtual?
+	Override  *OverrideSpecifier    // override specifier; or nil
+	Body      *BlockStatement       // function body inside curly braces; or nil
+	Semicolon token.Pos             // position of ";" if there is no body
+	Legacy    bool                  // is it a legacy constructor named after the contract or an unnamed fallback function?
+}
+
+type ModifierDeclaration struct {
+	Modifier  token.Pos          // position of the "modifier" keyword
+	Name      *Identifier        // modifier name
+	Params    *ParamList         // parameters; or nil if there are no parentheses
+	Virtual   bool               // is the modifier marked as virtual?
+	Override  *OverrideSpecifier // override specifier; or nil
+	Body      *BlockStatement    // modifier body; or nil
+	Semicolon token.Pos          // position of ";" if there is no body
+}
+
+type EventDeclaration struct {
+	Event     token.Pos   // position of the "event" keyword
+	Name      *Identifier // event name
+	Params    *ParamList  // event parameters
+	Anonymous bool        // is the event anonymous?
+	Semicolon token.Pos   // position of the closing semicolon
+}
+
+type ErrorDeclaration struct {
+	Error     token.Pos   // position of the "error" identifier
+	Name      *Identifier // error name
+	Params    *ParamList  // error parameters
+	Semicolon token.Pos   // position of the closing semicolon
+}
+
+type StructDeclaration struct {
+	Struct     token.Pos              // position of the "struct" keyword
+	Name       *Identifier            // struct name
+	LeftBrace  token.Pos              // position of "{"
+	Members    []*VariableDeclaration // struct members
+	RightBrace token.Pos              // position of "}"
+}
+
+type EnumDeclaration struct {
+	Enum       token.Pos     // position of the "enum" keyword
+	Name       *Identifier   // enum name
+	LeftBrace  token.Pos     // position of "{"
+	Members    []*Identifier // enum values
+	RightBrace token.Pos     // position of "}"
+}
+
+// User defined value type e.g. `type Price is uint128;`.
+type TypeDeclaration struct {
+	Type       token.Pos   // position of the "type" keyword
+	Name       *Identifier // type name
+	Underlying Expression  // underlying elementary type
+	Semicolon  token.Pos   // position of the closing semicolon
+}
+
+// Using for directive e.g. `using SafeMath for uint256;`,
+// `using {add, sub} for Fixed global;` or `using Address for *;`.
+type UsingForDirective struct {
+	Using     token.Pos    // position of the "using" keyword
+	Library   Expression   // library name; or nil in the `{f, g}` form
+	Functions []Expression // attached free functions in the `{f, g}` form; or nil
+	Type      Expression   // type the functions are attached to; or nil for `*`
+	Global    bool         // is the directive marked as global?
+	Semicolon token.Pos    // position of the closing semicolon
 }
 
 // @TODO: Is it enough to have one VariableDeclaration to handle
 // constant/immutable declarations and normal variables as well?
 type VariableDeclaration struct {
-	Name     *Identifier // variable name
-	Type     Expression  // e.g. ElementaryType
-	Value    Expression  // initial value or nil
-	Constant bool        // is it a constant variable?
+	Name       *Identifier        // variable name
+	Type       Expression         // e.g. ElementaryType
+	Value      Expression         // initial value or nil
+	Constant   bool               // is it a constant variable?
+	Immutable  bool               // is it an immutable variable?
+	Transient  token.Pos          // position of the "transient" keyword of a state variable; or zero
+	Visibility Visibility         // visibility of state variables; or zero value
+	Location   DataLocation       // data location of local variables; or zero value
+	Override   *OverrideSpecifier // override specifier of public state variables; or nil
 }
 
 // Start() and End() implementations for Declaration type Nodes
 
-func (d *VariableDeclaration) Start() token.Pos { return d.Type.Start() }
-func (d *VariableDeclaration) End() token.Pos   { return d.Value.End() }
-func (d *FunctionDeclaration) Start() token.Pos { return 0 }
-func (d *FunctionDeclaration) End() token.Pos   { return 0 }
+func (d *PragmaDirective) Start() token.Pos     { return d.Pragma }
+func (d *PragmaDirective) End() token.Pos       { return d.Semicolon }
+func (d *ImportDirective) Start() token.Pos     { return d.Import }
+func (d *ImportDirective) End() token.Pos       { return d.Semicolon }
+func (d *ContractDeclaration) Start() token.Pos { return d.Contract }
+func (d *ContractDeclaration) End() token.Pos   { return d.RightBrace + 1 }
+func (d *ModifierDeclaration) Start() token.Pos { return d.Modifier }
+func (d *EventDeclaration) Start() token.Pos    { return d.Event }
+func (d *EventDeclaration) End() token.Pos      { return d.Semicolon }
+func (d *ErrorDeclaration) Start() token.Pos    { return d.Error }
+func (d *ErrorDeclaration) End() token.Pos      { return d.Semicolon }
+func (d *StructDeclaration) Start() token.Pos   { return d.Struct }
+func (d *StructDeclaration) End() token.Pos     { return d.RightBrace + 1 }
+func (d *EnumDeclaration) Start() token.Pos     { return d.Enum }
+func (d *EnumDeclaration) End() token.Pos       { return d.RightBrace + 1 }
+func (d *TypeDeclaration) Start() token.Pos     { return d.Type }
+func (d *TypeDeclaration) End() token.Pos       { return d.Semicolon }
+func (d *UsingForDirective) Start() token.Pos   { return d.Using }
+func (d *UsingForDirective) End() token.Pos     { return d.Semicolon }
+func (d *VariableDeclaration) Start() token.Pos {
+	if d.Type == nil {
+		return d.Name.Start()
+	}
+	return d.Type.Start()
+}
+func (d *VariableDeclaration) End() token.Pos {
+	if d.Value != nil {
+		return d.Value.End()
+	}
+	return d.Name.End()
+}
+func (d *FunctionDeclaration) Start() token.Pos { return d.Type.Func }
+func (d *FunctionDeclaration) End() token.Pos {
+	if d.Body != nil {
+		return d.Body.End()
+	}
+	if d.Semicolon != 0 {
+		return d.Semicolon
+	}
+	return d.Type.Params.Closing + 1
+}
+func (d *ModifierDeclaration) End() token.Pos {
+	if d.Body != nil {
+		return d.Body.End()
+	}
+	return d.Semicolon
+}
+
+func (n *ImportSymbol) Start() token.Pos { return n.Name.Start() }
+func (n *ImportSymbol) End() token.Pos {
+	if n.Alias != nil {
+		return n.Alias.End()
+	}
+	return n.Name.End()
+}
+func (n *InheritanceSpecifier) Start() token.Pos { return n.Name.Start() }
+func (n *InheritanceSpecifier) End() token.Pos {
+	if n.Rparen != 0 {
+		return n.Rparen + 1
+	}
+	return n.Name.End()
+}
+func (n *ModifierInvocation) Start() token.Pos { return n.Name.Start() }
+func (n *ModifierInvocation) End() token.Pos {
+	if n.Rparen != 0 {
+		return n.Rparen + 1
+	}
+	return n.Name.End()
+}
+func (n *OverrideSpecifier) Start() token.Pos { return n.Override }
+func (n *OverrideSpecifier) End() token.Pos {
+	if n.Rparen != 0 {
+		return n.Rparen + 1
+	}
+	return n.Override + 8 // length of "override"
+}
 
 // declarationNode() implementations to ensure that only declaration nodes can
 // be assigned to a Declaration.
 
-func (*VariableDeclaration) declarationNode() {}
+func (*PragmaDirective) declarationNode()     {}
+func (*ImportDirective) declarationNode()     {}
+func (*ContractDeclaration) declarationNode() {}
 func (*FunctionDeclaration) declarationNode() {}
+func (*ModifierDeclaration) declarationNode() {}
+func (*EventDeclaration) declarationNode()    {}
+func (*ErrorDeclaration) declarationNode()    {}
+func (*StructDeclaration) declarationNode()   {}
+func (*EnumDeclaration) declarationNode()     {}
+func (*TypeDeclaration) declarationNode()     {}
+func (*UsingForDirective) declarationNode()   {}
+func (*VariableDeclaration) declarationNode() {}
 
 /*~*~*~*~*~*~*~*~*~*~*~*~*~* Files ~*~*~*~*~*~*~*~*~*~*~*~*~*~*~*/
 
@@ -181,6 +929,7 @@ func (*FunctionDeclaration) declarationNode() {}
 type File struct {
 	Name         string
 	Declarations []Declaration
+	Comments     []*Comment // all comments in the file in the source order
 }
 
 func (f *File) Start() token.Pos {
@@ -198,6 +947,31 @@ func (f *File) End() token.Pos {
 	return 0
 }
 
+// Pragma returns the first pragma directive with the given name
+// e.g. "solidity"; or nil if there is none.
+func (f *File) Pragma(name string) *PragmaDirective {
+	for _, decl := range f.Declarations {
+		if p, ok := decl.(*PragmaDirective); ok && p.Name.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// License returns the SPDX license expression of the file e.g. "MIT", from
+// the `// SPDX-License-Identifier: MIT` comment; or an empty string if
+// there is none.
+func (f *File) License() string {
+	const prefix = "SPDX-License-Identifier:"
+	for _, c := range f.Comments {
+		if i := strings.Index(c.Text, prefix); i >= 0 {
+			license, _, _ := strings.Cut(c.Text[i+len(prefix):], "\n")
+			return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(license), "*/"))
+		}
+	}
+	return ""
+}
+
 /*~*~*~*~*~*~ Visibility, Mutability, Data Location *~*~*~*~*~*~*/
 
 // Visibility specifier for functions and function types. For convenience,
@@ -0,0 +1,89 @@
+package ast
+
+import "solbot/token"
+
+// CommentAnchor holds the comments attached to a node.
+type CommentAnchor struct {
+	Leading  []*Comment // comments before the node
+	Trailing []*Comment // comments after the node e.g. on the same line as the statement
+	Inner    []*Comment // comments inside the node with no child to attach to e.g. in an empty block
+}
+
+// CommentMap maps the nodes to the comments attached to them, so that the
+// comments inside of the declarations and the expressions keep their
+// anchors when the code is edited or printed.
+type CommentMap map[Node]*CommentAnchor
+
+// NewCommentMap attaches the comments of the file to its nodes. A comment
+// belongs to the innermost node enclosing it, its parent, and attaches to
+// the siblings around it:
+//
+//   - to the statement or the declaration ending on the line the comment
+//     starts at, as a trailing comment e.g. `x = 1; // solbot-disable-line`;
+//   - otherwise to the next sibling as a leading comment e.g.
+//     `function f(uint a, /* the recipient */ address b)`;
+//   - otherwise to the previous sibling as a trailing comment, since
+//     nothing follows before the parent ends;
+//   - otherwise to the parent itself as an inner comment.
+//
+// It is modeled after ast.NewCommentMap from the Go standard library.
+func NewCommentMap(handle *token.File, file *File) CommentMap {
+	cmap := CommentMap{}
+	for _, c := range file.Comments {
+		parent := PathEnclosingPos(file, c.Start())[0]
+		var prev, next Node
+		for _, child := range children(parent) {
+			// The walk order might differ from the source order, see the
+			// function declarations.
+			switch {
+			case child.End() <= c.Start() && (prev == nil || child.End() > prev.End()):
+				prev = child
+			case child.Start() >= c.End() && (next == nil || child.Start() < next.Start()):
+				next = child
+			}
+		}
+
+		switch {
+		case prev != nil && isStatementOrDeclaration(prev) &&
+			handle.Position(prev.End()).Line == handle.Position(c.Start()).Line:
+			cmap.anchor(prev).Trailing = append(cmap.anchor(prev).Trailing, c)
+		case next != nil:
+			cmap.anchor(next).Leading = append(cmap.anchor(next).Leading, c)
+		case prev != nil:
+			cmap.anchor(prev).Trailing = append(cmap.anchor(prev).Trailing, c)
+		default:
+			cmap.anchor(parent).Inner = append(cmap.anchor(parent).Inner, c)
+		}
+	}
+	return cmap
+}
+
+func (cmap CommentMap) anchor(node Node) *CommentAnchor {
+	a, ok := cmap[node]
+	if !ok {
+		a = &CommentAnchor{}
+		cmap[node] = a
+	}
+	return a
+}
+
+// children returns the direct children of the node in the walk order.
+func children(node Node) []Node {
+	res := []Node{}
+	Inspect(node, func(n Node) bool {
+		if n == nil || n == node {
+			return n == node
+		}
+		res = append(res, n)
+		return false
+	})
+	return res
+}
+
+func isStatementOrDeclaration(node Node) bool {
+	switch node.(type) {
+	case Statement, Declaration:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package ast
+
+import (
+	"bytes"
+	"solbot/token"
+)
+
+// ExprString returns the (possibly shortened) string representation of the
+// expression e.g. `balances[msg.sender] - amount`. Whitespace and comments
+// are normalized, so two expressions that differ only in formatting have
+// the same string. It's modeled after types.ExprString from the Go
+// standard library.
+func ExprString(x Expression) string {
+	var buf bytes.Buffer
+	WriteExpr(&buf, x)
+	return buf.String()
+}
+
+// WriteExpr writes the (possibly shortened) string representation of the
+// expression to buf.
+func WriteExpr(buf *bytes.Buffer, x Expression) {
+	switch x := x.(type) {
+	case nil, *BadExpression:
+		// A missing tuple component or argument.
+
+	case *Identifier:
+		buf.WriteString(x.Name)
+
+	case *ElementaryType:
+		buf.WriteString(x.Value)
+		if x.Payable != 0 {
+			buf.WriteString(" payable")
+		}
+
+	case *BasicLit:
+		buf.WriteString(x.Value)
+		if x.Unit != nil {
+			buf.WriteByte(' ')
+			buf.WriteString(x.Unit.Name)
+		}
+
+	case *MappingType:
+		buf.WriteString("mapping(")
+		WriteExpr(buf, x.Key)
+		if x.KeyName != nil {
+			buf.WriteByte(' ')
+			buf.WriteString(x.KeyName.Name)
+		}
+		buf.WriteString(" => ")
+		WriteExpr(buf, x.Value)
+		if x.ValueName != nil {
+			buf.WriteByte(' ')
+			buf.WriteString(x.ValueName.Name)
+		}
+		buf.WriteByte(')')
+
+	case *ArrayType:
+		WriteExpr(buf, x.Elem)
+		buf.WriteByte('[')
+		WriteExpr(buf, x.Len)
+		buf.WriteByte(']')
+
+	case *FunctionType:
+		buf.WriteString("function(...)")
+
+	case *UnaryExpression:
+		if x.Postfix {
+			WriteExpr(buf, x.Operand)
+			buf.WriteString(x.Operator.String())
+			return
+		}
+		buf.WriteString(x.Operator.String())
+		if x.Operator == token.DELETE {
+			buf.WriteByte(' ')
+		}
+		WriteExpr(buf, x.Operand)
+
+	case *BinaryExpression:
+		WriteExpr(buf, x.Left)
+		buf.WriteByte(' ')
+		buf.WriteString(x.Operator.String())
+		buf.WriteByte(' ')
+		WriteExpr(buf, x.Right)
+
+	case *AssignmentExpression:
+		WriteExpr(buf, x.Left)
+		buf.WriteByte(' ')
+		buf.WriteString(x.Operator.String())
+		buf.WriteByte(' ')
+		WriteExpr(buf, x.Right)
+
+	case *ConditionalExpression:
+		WriteExpr(buf, x.Condition)
+		buf.WriteString(" ? ")
+		WriteExpr(buf, x.True)
+		buf.WriteString(" : ")
+		WriteExpr(buf, x.False)
+
+	case *CallExpression:
+		WriteExpr(buf, x.Function)
+		buf.WriteByte('(')
+		if len(x.Names) > 0 {
+			buf.WriteByte('{')
+		}
+		for i, arg := range x.Args {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if i < len(x.Names) {
+				buf.WriteString(x.Names[i].Name)
+				buf.WriteString(": ")
+			}
+			WriteExpr(buf, arg)
+		}
+		if len(x.Names) > 0 {
+			buf.WriteByte('}')
+		}
+		buf.WriteByte(')')
+
+	case *CallOptionsExpression:
+		WriteExpr(buf, x.Expression)
+		buf.WriteByte('{')
+		for i, value := range x.Values {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(x.Names[i].Name)
+			buf.WriteString(": ")
+			WriteExpr(buf, value)
+		}
+		buf.WriteByte('}')
+
+	case *MemberAccessExpression:
+		WriteExpr(buf, x.Expression)
+		buf.WriteByte('.')
+		buf.WriteString(x.Member.Name)
+
+	case *IndexAccessExpression:
+		WriteExpr(buf, x.Expression)
+		buf.WriteByte('[')
+		WriteExpr(buf, x.Index)
+		buf.WriteByte(']')
+
+	case *IndexRangeAccessExpression:
+		WriteExpr(buf, x.Expression)
+		buf.WriteByte('[')
+		WriteExpr(buf, x.From)
+		buf.WriteByte(':')
+		WriteExpr(buf, x.To)
+		buf.WriteByte(']')
+
+	case *TupleExpression:
+		buf.WriteByte('(')
+		writeExprList(buf, x.Elements)
+		buf.WriteByte(')')
+
+	case *InlineArrayExpression:
+		buf.WriteByte('[')
+		writeExprList(buf, x.Elements)
+		buf.WriteByte(']')
+
+	case *NewExpression:
+		buf.WriteString("new ")
+		WriteExpr(buf, x.Type)
+
+	default:
+		buf.WriteString("(bad expr)")
+	}
+}
+
+func writeExprList(buf *bytes.Buffer, list []Expression) {
+	for i, x := range list {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		WriteExpr(buf, x)
+	}
+}
@@ -0,0 +1,433 @@
+package ast
+
+import (
+	"fmt"
+	"solbot/token"
+)
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+// The design is the same as in the Go standard library's go/ast package.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor
+// w for each of the non-nil children of node, followed by a call of
+// w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// Comments
+	case *Comment:
+		// nothing to do
+
+	// Expressions and Types
+	case *Identifier, *ElementaryType, *BadExpression:
+		// nothing to do
+
+	case *BasicLit:
+		if n.Unit != nil {
+			Walk(v, n.Unit)
+		}
+
+	case *Param:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+
+	case *ParamList:
+		for _, param := range n.List {
+			Walk(v, param)
+		}
+
+	case *MappingType:
+		Walk(v, n.Key)
+		if n.KeyName != nil {
+			Walk(v, n.KeyName)
+		}
+		Walk(v, n.Value)
+		if n.ValueName != nil {
+			Walk(v, n.ValueName)
+		}
+
+	case *ArrayType:
+		Walk(v, n.Elem)
+		if n.Len != nil {
+			Walk(v, n.Len)
+		}
+
+	case *FunctionType:
+		if n.Params != nil {
+			Walk(v, n.Params)
+		}
+		if n.Results != nil {
+			Walk(v, n.Results)
+		}
+
+	case *UnaryExpression:
+		Walk(v, n.Operand)
+
+	case *BinaryExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+
+	case *AssignmentExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+
+	case *ConditionalExpression:
+		Walk(v, n.Condition)
+		Walk(v, n.True)
+		Walk(v, n.False)
+
+	case *CallExpression:
+		Walk(v, n.Function)
+		// Named arguments are walked in the source order: name, value.
+		for i, arg := range n.Args {
+			if i < len(n.Names) {
+				Walk(v, n.Names[i])
+			}
+			Walk(v, arg)
+		}
+
+	case *CallOptionsExpression:
+		Walk(v, n.Expression)
+		for i, value := range n.Values {
+			Walk(v, n.Names[i])
+			Walk(v, value)
+		}
+
+	case *MemberAccessExpression:
+		Walk(v, n.Expression)
+		Walk(v, n.Member)
+
+	case *IndexAccessExpression:
+		Walk(v, n.Expression)
+		if n.Index != nil {
+			Walk(v, n.Index)
+		}
+
+	case *IndexRangeAccessExpression:
+		Walk(v, n.Expression)
+		if n.From != nil {
+			Walk(v, n.From)
+		}
+		if n.To != nil {
+			Walk(v, n.To)
+		}
+
+	case *TupleExpression:
+		walkExpressionList(v, n.Elements)
+
+	case *InlineArrayExpression:
+		walkExpressionList(v, n.Elements)
+
+	case *NewExpression:
+		Walk(v, n.Type)
+
+	// Statements
+	case *BlockStatement:
+		for _, stmt := range n.Statements {
+			Walk(v, stmt)
+		}
+
+	case *UncheckedBlockStatement:
+		Walk(v, n.Body)
+
+	case *ReturnStatement:
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+
+	case *ExpressionStatement:
+		Walk(v, n.Expression)
+
+	case *VariableDeclarationStatement:
+		for _, decl := range n.Declarations {
+			if decl != nil {
+				Walk(v, decl)
+			}
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *IfStatement:
+		Walk(v, n.Condition)
+		Walk(v, n.Consequence)
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+
+	case *ForStatement:
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Post != nil {
+			Walk(v, n.Post)
+		}
+		Walk(v, n.Body)
+
+	case *WhileStatement:
+		Walk(v, n.Condition)
+		Walk(v, n.Body)
+
+	case *DoWhileStatement:
+		Walk(v, n.Body)
+		Walk(v, n.Condition)
+
+	case *ContinueStatement, *BreakStatement, *PlaceholderStatement, *BadStatement:
+		// nothing to do
+
+	case *AssemblyStatement:
+		for _, ident := range n.Identifiers {
+			Walk(v, ident)
+		}
+
+	case *EmitStatement:
+		Walk(v, n.Call)
+
+	case *RevertStatement:
+		if n.Call != nil {
+			Walk(v, n.Call)
+		}
+
+	case *TryStatement:
+		Walk(v, n.Expression)
+		if n.Returns != nil {
+			Walk(v, n.Returns)
+		}
+		Walk(v, n.Body)
+		for _, clause := range n.Catches {
+			Walk(v, clause)
+		}
+
+	case *CatchClause:
+		if n.Kind != nil {
+			Walk(v, n.Kind)
+		}
+		if n.Params != nil {
+			Walk(v, n.Params)
+		}
+		Walk(v, n.Body)
+
+	// Declarations
+	case *PragmaDirective:
+		Walk(v, n.Name)
+
+	case *ImportDirective:
+		for _, symbol := range n.Symbols {
+			Walk(v, symbol)
+		}
+		if n.Alias != nil && n.Alias.Start() < n.Path.Start() {
+			Walk(v, n.Alias)
+		}
+		Walk(v, n.Path)
+		if n.Alias != nil && n.Alias.Start() > n.Path.Start() {
+			Walk(v, n.Alias)
+		}
+
+	case *ImportSymbol:
+		Walk(v, n.Name)
+		if n.Alias != nil {
+			Walk(v, n.Alias)
+		}
+
+	case *ContractDeclaration:
+		Walk(v, n.Name)
+		for _, base := range n.Bases {
+			Walk(v, base)
+		}
+		for _, decl := range n.Body {
+			Walk(v, decl)
+		}
+
+	case *InheritanceSpecifier:
+		Walk(v, n.Name)
+		walkExpressionList(v, n.Args)
+
+	case *ModifierInvocation:
+		Walk(v, n.Name)
+		walkExpressionList(v, n.Args)
+
+	case *OverrideSpecifier:
+		walkExpressionList(v, n.Bases)
+
+	case *FunctionDeclaration:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Type != nil {
+			if n.Type.Params != nil {
+				Walk(v, n.Type.Params)
+			}
+		}
+		// @TODO: Modifiers, override and the results are visited in a
+		// fixed order, which might differ from the source order.
+		for _, mod := range n.Modifiers {
+			Walk(v, mod)
+		}
+		if n.Override != nil {
+			Walk(v, n.Override)
+		}
+		if n.Type != nil && n.Type.Results != nil {
+			Walk(v, n.Type.Results)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *ModifierDeclaration:
+		Walk(v, n.Name)
+		if n.Params != nil {
+			Walk(v, n.Params)
+		}
+		if n.Override != nil {
+			Walk(v, n.Override)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *EventDeclaration:
+		Walk(v, n.Name)
+		Walk(v, n.Params)
+
+	case *ErrorDeclaration:
+		Walk(v, n.Name)
+		Walk(v, n.Params)
+
+	case *StructDeclaration:
+		Walk(v, n.Name)
+		for _, member := range n.Members {
+			Walk(v, member)
+		}
+
+	case *EnumDeclaration:
+		Walk(v, n.Name)
+		for _, member := range n.Members {
+			Walk(v, member)
+		}
+
+	case *TypeDeclaration:
+		Walk(v, n.Name)
+		Walk(v, n.Underlying)
+
+	case *UsingForDirective:
+		if n.Library != nil {
+			Walk(v, n.Library)
+		}
+		walkExpressionList(v, n.Functions)
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+
+	case *VariableDeclaration:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+		if n.Override != nil {
+			Walk(v, n.Override)
+		}
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	// Files
+	case *File:
+		for _, decl := range n.Declarations {
+			Walk(v, decl)
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+// walkExpressionList walks the list skipping the missing elements e.g.
+// in tuples like (a, , b).
+func walkExpressionList(v Visitor, list []Expression) {
+	for _, x := range list {
+		if x != nil {
+			Walk(v, x)
+		}
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// PathEnclosingPos returns the path of nodes enclosing the given position,
+// starting with the innermost node and ending with the file itself. The
+// file is always part of the path, even if it's empty or the position is
+// outside of all of its declarations.
+//
+// A position in the trivia (whitespace, comments) between two sibling nodes
+// belongs to neither of them, so the path ends at their common parent. Node
+// ranges are end-exclusive, so a position right after the last character of
+// an identifier is not inside it.
+//
+// It is modeled after astutil.PathEnclosingInterval from golang.org/x/tools.
+func PathEnclosingPos(file *File, pos token.Pos) []Node {
+	path := []Node{}
+	open := []Node{} // nodes whose children are being visited right now
+	Inspect(file, func(n Node) bool {
+		if n == nil {
+			open = open[:len(open)-1]
+			return false
+		}
+		if n != Node(file) {
+			if !NodeRange(n).Contains(pos) {
+				return false
+			}
+			// A sibling that was visited earlier already contains the
+			// position. It can only happen with zero-width nodes, but the
+			// path must stay a single chain from the file down.
+			if path[len(path)-1] != open[len(open)-1] {
+				return false
+			}
+		}
+		path = append(path, n)
+		open = append(open, n)
+		return true
+	})
+
+	// Inspect collected the nodes from the root down; flip the order, so
+	// that the innermost node comes first.
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,167 @@
+package ast
+
+import (
+	"solbot/token"
+	"testing"
+)
+
+// The parser doesn't produce nested nodes for everything yet, so the file
+// is built by hand. It corresponds to the following source:
+//
+//	function foo() {}  uint256 bar;
+//	0         1         2         3
+//	0123456789012345678901234567890
+func buildTestFile() (*File, *FunctionDeclaration, *VariableDeclaration) {
+	fn := &FunctionDeclaration{
+		Name: &Identifier{NamePos: 9, Name: "foo"},
+		Type: &FunctionType{
+			Func:   0,
+			Params: &ParamList{Opening: 12, Closing: 13},
+		},
+		Body: &BlockStatement{LeftBrace: 15, RightBrace: 16},
+	}
+	v := &VariableDeclaration{
+		Type: &ElementaryType{ValuePos: 19, Value: "uint256"},
+		Name: &Identifier{NamePos: 27, Name: "bar"},
+	}
+	file := &File{Declarations: []Declaration{fn, v}}
+	return file, fn, v
+}
+
+func Test_PathEnclosingPos(t *testing.T) {
+	file, fn, v := buildTestFile()
+
+	tests := []struct {
+		name     string
+		pos      token.Pos
+		expected []Node
+	}{
+		{"start of function", 0, []Node{fn, file}},
+		{"start of identifier", 9, []Node{fn.Name, fn, file}},
+		{"last char of identifier", 11, []Node{fn.Name, fn, file}},
+		{"end of identifier is exclusive", 12, []Node{fn.Type.Params, fn, file}},
+		{"between function siblings", 14, []Node{fn, file}},
+		{"closing brace", 16, []Node{fn.Body, fn, file}},
+		{"trivia between declarations", 17, []Node{file}},
+		{"variable type", 19, []Node{v.Type, v, file}},
+		{"past the end of the file", 100, []Node{file}},
+	}
+
+	for _, tt := range tests {
+		path := PathEnclosingPos(file, tt.pos)
+		if len(path) != len(tt.expected) {
+			t.Errorf("%s: expected path of length %d, got %d: %v",
+				tt.name, len(tt.expected), len(path), path)
+			continue
+		}
+		for i := range path {
+			if path[i] != tt.expected[i] {
+				t.Errorf("%s: path[%d] expected %T, got %T",
+					tt.name, i, tt.expected[i], path[i])
+			}
+		}
+	}
+}
+
+func Test_PathEnclosingPosZeroWidth(t *testing.T) {
+	// An empty parameter list "()" at offset 12 followed by a zero-width node.
+	empty := &Identifier{NamePos: 12, Name: ""}
+	list := &ParamList{
+		Opening: 12,
+		List:    []*Param{{Name: empty}},
+		Closing: 13,
+	}
+	fn := &FunctionDeclaration{
+		Name: &Identifier{NamePos: 9, Name: "foo"},
+		Type: &FunctionType{Func: 0, Params: list},
+	}
+	file := &File{Declarations: []Declaration{fn}}
+
+	path := PathEnclosingPos(file, 12)
+	if len(path) != 5 || path[0] != empty {
+		t.Fatalf("Expected the zero-width identifier to be the innermost node, got %v", path)
+	}
+}
+
+func Test_PathEnclosingPosEmptyFile(t *testing.T) {
+	file := &File{}
+
+	path := PathEnclosingPos(file, 0)
+	if len(path) != 1 || path[0] != file {
+		t.Fatalf("Expected path with just the file, got %v", path)
+	}
+}
+
+func Test_InspectVisitsAllNodes(t *testing.T) {
+	file, _, _ := buildTestFile()
+
+	count := 0
+	Inspect(file, func(n Node) bool {
+		if n != nil {
+			count++
+		}
+		return true
+	})
+
+	// File, FunctionDeclaration, Identifier, ParamList, BlockStatement,
+	// VariableDeclaration, ElementaryType, Identifier
+	if count != 8 {
+		t.Errorf("Expected 8 nodes, got %d", count)
+	}
+}
+
+func Test_Root(t *testing.T) {
+	balances := &Identifier{Name: "balances"}
+	to := &Identifier{Name: "to"}
+	tests := []struct {
+		x        Expression
+		expected *Identifier
+	}{
+		{balances, balances},
+		{&MemberAccessExpression{Expression: &IndexAccessExpression{Expression: balances, Index: to}, Member: &Identifier{Name: "amount"}}, balances},
+		{&IndexAccessExpression{Expression: &TupleExpression{Elements: []Expression{balances}}, Index: to}, balances},
+		{&TupleExpression{Elements: []Expression{balances, to}}, nil},
+		{&CallExpression{Function: balances}, nil},
+	}
+	for _, tt := range tests {
+		if got := Root(tt.x); got != tt.expected {
+			t.Errorf("Expected %v as the root of %s, got %v", tt.expected, ExprString(tt.x), got)
+		}
+	}
+}
+
+func Test_ModifierName(t *testing.T) {
+	tests := []struct {
+		name     Expression
+		expected string
+	}{
+		{&Identifier{Name: "onlyOwner"}, "onlyOwner"},
+		{&MemberAccessExpression{Expression: &Identifier{Name: "Ownable"}, Member: &Identifier{Name: "onlyOwner"}}, "onlyOwner"},
+		{&CallExpression{Function: &Identifier{Name: "onlyOwner"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := ModifierName(&ModifierInvocation{Name: tt.name}); got != tt.expected {
+			t.Errorf("Expected %q as the name of %s, got %q", tt.expected, ExprString(tt.name), got)
+		}
+	}
+}
+
+func Test_Reverts(t *testing.T) {
+	revertCall := &ExpressionStatement{Expression: &CallExpression{Function: &Identifier{Name: "revert"}}}
+	tests := []struct {
+		stmt     Statement
+		expected bool
+	}{
+		{&RevertStatement{}, true},
+		{revertCall, true},
+		{&BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: &Identifier{Name: "x"}}, revertCall}}, true},
+		{&BlockStatement{Statements: []Statement{revertCall, &ExpressionStatement{Expression: &Identifier{Name: "x"}}}}, false},
+		{&BlockStatement{}, false},
+		{&ExpressionStatement{Expression: &CallExpression{Function: &MemberAccessExpression{Expression: &Identifier{Name: "c"}, Member: &Identifier{Name: "revert"}}}}, false},
+	}
+	for i, tt := range tests {
+		if got := Reverts(tt.stmt); got != tt.expected {
+			t.Errorf("tests[%d]: Expected %t, got %t", i, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+// audit tells which declarations changed since the last audit. Every
+// contract, function, modifier, struct and state variable is hashed by its
+// normalized tokens: the comments and the whitespace are dropped, and so is
+// the name of the declaration, so a reformatted or re-commented function
+// keeps its hash, and a function moved to another contract or renamed can be
+// recognized by it. The hashes of an audited commit are kept in a snapshot,
+// and Compare matches them against the current ones. Like the metrics, the
+// hashing is purely syntactic.
+package audit
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"slices"
+	"solbot/ast"
+	"solbot/token"
+	"strings"
+)
+
+// Version is the version of the snapshot format. A snapshot of another
+// version is refused rather than compared, as its hashes aren't comparable.
+const Version = 1
+
+// Declaration is a hashed declaration of a source file.
+type Declaration struct {
+	File     string `json:"file"`               // path of the file relative to the project
+	Contract string `json:"contract,omitempty"` // enclosing contract; or empty at the file level
+	Kind     string `json:"kind"`               // "contract", "function", "modifier", "struct" or "variable"
+	Name     string `json:"name"`               // name, with the parameter types for the functions e.g. "deposit(uint256,address)"
+	Hash     string `json:"hash"`               // hash of the normalized tokens
+	Size     int    `json:"size"`               // number of the normalized tokens
+	Shape    string `json:"shape"`              // hashes of the single tokens, to measure the changes, see Compare
+}
+
+// Snapshot is the hashes of the declarations of a project at a commit.
+type Snapshot struct {
+	Version      int           `json:"version"`
+	Declarations []Declaration `json:"declarations"`
+}
+
+// ReadSnapshot reads the snapshot written by Snapshot.Write.
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return s, fmt.Errorf("invalid snapshot: %s", err)
+	}
+	if s.Version != Version {
+		return s, fmt.Errorf("unsupported snapshot version %d, expected %d", s.Version, Version)
+	}
+	return s, nil
+}
+
+// Write writes the snapshot as indented JSON.
+func (s Snapshot) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// Declarations returns the hashed declarations of the file in the order
+// of the source, with the file left to the caller. The tokens are the
+// lexed tokens of the whole file, the comments included.
+//
+// The hash of a contract covers only what its hashed members don't e.g.
+// the inheritance list, the events and the errors, so a change of a
+// function doesn't modify its contract too.
+func Declarations(file *ast.File, tokens []token.Token) []Declaration {
+	res := []Declaration{}
+	for _, decl := range file.Declarations {
+		switch decl := decl.(type) {
+		case *ast.ContractDeclaration:
+			if decl.Name == nil {
+				continue
+			}
+			members := []Declaration{}
+			skipped := []token.Range{ast.NodeRange(decl.Name)}
+			for _, member := range decl.Body {
+				if d, ok := declaration(member, tokens); ok {
+					d.Contract = decl.Name.Name
+					members = append(members, d)
+					skipped = append(skipped, ast.NodeRange(member))
+				}
+			}
+			res = append(res, hashed(Declaration{Kind: "contract", Name: decl.Name.Name}, tokens, ast.NodeRange(decl), skipped))
+			res = append(res, members...)
+		default:
+			if d, ok := declaration(decl, tokens); ok {
+				res = append(res, d)
+			}
+		}
+	}
+	return res
+}
+
+// declaration returns the hashed declaration of a function, a modifier, a
+// struct or a variable; or false for the other nodes.
+func declaration(node ast.Node, tokens []token.Token) (Declaration, bool) {
+	var d Declaration
+	var name *ast.Identifier
+	switch n := node.(type) {
+	case *ast.FunctionDeclaration:
+		d = Declaration{Kind: "function", Name: n.Kind.String()}
+		if n.Name != nil {
+			d.Name, name = n.Name.Name, n.Name
+		}
+		if n.Kind == token.FUNCTION || n.Kind == token.CONSTRUCTOR {
+			types := []string{}
+			if n.Type.Params != nil {
+				for _, param := range n.Type.Params.List {
+					types = append(types, ast.ExprString(param.Type))
+				}
+			}
+			d.Name += "(" + strings.Join(types, ",") + ")"
+		}
+	case *ast.ModifierDeclaration:
+		d, name = Declaration{Kind: "modifier"}, n.Name
+	case *ast.StructDeclaration:
+		d, name = Declaration{Kind: "struct"}, n.Name
+	case *ast.VariableDeclaration:
+		d, name = Declaration{Kind: "variable"}, n.Name
+	default:
+		return d, false
+	}
+	skipped := []token.Range{}
+	switch {
+	case name != nil:
+		if d.Name == "" {
+			d.Name = name.Name
+		}
+		skipped = append(skipped, ast.NodeRange(name))
+	case d.Kind != "function":
+		return d, false
+	}
+	return hashed(d, tokens, ast.NodeRange(node), skipped), true
+}
+
+// hashed returns the declaration with the hash of the normalized tokens
+// starting in the range, except the ones in the skipped ranges.
+func hashed(d Declaration, tokens []token.Token, r token.Range, skipped []token.Range) Declaration {
+	h := sha256.New()
+	shape := []byte{}
+	for _, tkn := range tokens {
+		if tkn.Pos < r.Start || tkn.Pos >= r.End || tkn.Type == token.COMMENT_LITERAL || slices.ContainsFunc(skipped, func(s token.Range) bool {
+			return s.Start <= tkn.Pos && tkn.Pos < s.End
+		}) {
+			continue
+		}
+		normalized := tkn.Type.String() + " " + tkn.Literal + "\n"
+		h.Write([]byte(normalized))
+		single := fnv.New32a()
+		single.Write([]byte(normalized))
+		shape = binary.BigEndian.AppendUint32(shape, single.Sum32())
+		d.Size++
+	}
+	d.Hash = fmt.Sprintf("%x", h.Sum(nil)[:8])
+	d.Shape = base64.StdEncoding.EncodeToString(shape)
+	return d
+}
+
+// Status is how a declaration changed since the baseline.
+type Status string
+
+const (
+	Unchanged Status = "unchanged" // same place, same hash
+	Modified  Status = "modified"  // same place, different hash
+	Added     Status = "added"     // not in the baseline
+	Removed   Status = "removed"   // only in the baseline
+	Moved     Status = "moved"     // same name and hash, another file or contract
+	Renamed   Status = "renamed"   // same hash, another name
+)
+
+// Statuses are the statuses in the order of the reports.
+var Statuses = []Status{Modified, Added, Removed, Moved, Renamed, Unchanged}
+
+// Ref is the place of a declaration.
+type Ref struct {
+	File     string `json:"file"`
+	Contract string `json:"contract,omitempty"`
+	Name     string `json:"name"`
+}
+
+// String returns the qualified name of the declaration e.g.
+// "src/Vault.sol:Vault.deposit(uint256)".
+func (r Ref) String() string {
+	if r.Contract == "" {
+		return r.File + ":" + r.Name
+	}
+	return r.File + ":" + r.Contract + "." + r.Name
+}
+
+// Change is the status of a declaration of the baseline, the current
+// sources or both.
+type Change struct {
+	Status   Status `json:"status"`
+	Kind     string `json:"kind"`
+	Baseline *Ref   `json:"baseline,omitempty"` // nil if added
+	Current  *Ref   `json:"current,omitempty"`  // nil if removed
+	Inserted int    `json:"inserted,omitempty"` // tokens inserted into a modified declaration
+	Deleted  int    `json:"deleted,omitempty"`  // tokens deleted from a modified declaration
+}
+
+// Compare returns the changes of the declarations since the baseline. The
+// declarations at the same place, with the same file, contract, kind and
+// name, are unchanged or modified. The rest are matched by their hashes:
+// with the same name they're moved, and with another one renamed,
+// preferring the same name. The others are added or removed. The changes
+// are ordered by their current places, the removed ones by their places in
+// the baseline.
+func Compare(baseline, current []Declaration) []Change {
+	type key struct{ file, contract, kind, name string }
+	keyOf := func(d Declaration) key { return key{d.File, d.Contract, d.Kind, d.Name} }
+	ref := func(d Declaration) *Ref { return &Ref{File: d.File, Contract: d.Contract, Name: d.Name} }
+
+	unmatched := map[key][]int{}
+	for i, d := range baseline {
+		unmatched[keyOf(d)] = append(unmatched[keyOf(d)], i)
+	}
+	matched := make([]bool, len(baseline))
+	res := []Change{}
+	rest := []Declaration{}
+	for _, d := range current {
+		indices := unmatched[keyOf(d)]
+		if len(indices) == 0 {
+			rest = append(rest, d)
+			continue
+		}
+		old := baseline[indices[0]]
+		unmatched[keyOf(d)], matched[indices[0]] = indices[1:], true
+		change := Change{Status: Unchanged, Kind: d.Kind, Baseline: ref(old), Current: ref(d)}
+		if old.Hash != d.Hash {
+			change.Status = Modified
+			change.Inserted, change.Deleted = tokenChanges(decodeShape(old.Shape), decodeShape(d.Shape))
+		}
+		res = append(res, change)
+	}
+
+	for _, d := range rest {
+		found := -1
+		for i, old := range baseline {
+			if matched[i] || old.Kind != d.Kind || old.Hash != d.Hash {
+				continue
+			}
+			if found < 0 || old.Name == d.Name && baseline[found].Name != d.Name {
+				found = i
+			}
+		}
+		if found < 0 {
+			res = append(res, Change{Status: Added, Kind: d.Kind, Current: ref(d)})
+			continue
+		}
+		matched[found] = true
+		change := Change{Status: Moved, Kind: d.Kind, Baseline: ref(baseline[found]), Current: ref(d)}
+		if baseline[found].Name != d.Name {
+			change.Status = Renamed
+		}
+		res = append(res, change)
+	}
+	for i, old := range baseline {
+		if !matched[i] {
+			res = append(res, Change{Status: Removed, Kind: old.Kind, Baseline: ref(old)})
+		}
+	}
+
+	slices.SortStableFunc(res, func(a, b Change) int {
+		x, y := a.Current, b.Current
+		if x == nil {
+			x = a.Baseline
+		}
+		if y == nil {
+			y = b.Baseline
+		}
+		if c := cmp.Compare(x.File, y.File); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(x.Contract, y.Contract); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(x.Name, y.Name); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Status, b.Status)
+	})
+	return res
+}
+
+func decodeShape(shape string) []uint32 {
+	b, _ := base64.StdEncoding.DecodeString(shape)
+	res := make([]uint32, 0, len(b)/4)
+	for i := 0; i+4 <= len(b); i += 4 {
+		res = append(res, binary.BigEndian.Uint32(b[i:]))
+	}
+	return res
+}
+
+// maxCells is the size of the table of the longest common subsequence
+// above which the changed tokens are not aligned, and the whole middle of
+// the declarations is counted as replaced.
+const maxCells = 1 << 22
+
+// tokenChanges returns the number of the tokens inserted and deleted to
+// turn the shape before into the one after, by their longest common
+// subsequence.
+func tokenChanges(before, after []uint32) (inserted, deleted int) {
+	for len(before) > 0 && len(after) > 0 && before[0] == after[0] {
+		before, after = before[1:], after[1:]
+	}
+	for len(before) > 0 && len(after) > 0 && before[len(before)-1] == after[len(after)-1] {
+		before, after = before[:len(before)-1], after[:len(after)-1]
+	}
+	if len(before)*len(after) > maxCells {
+		return len(after), len(before)
+	}
+	prev, row := make([]int, len(after)+1), make([]int, len(after)+1)
+	for _, a := range before {
+		for j, b := range after {
+			if a == b {
+				row[j+1] = prev[j] + 1
+			} else {
+				row[j+1] = max(prev[j+1], row[j])
+			}
+		}
+		prev, row = row, prev
+	}
+	common := prev[len(after)]
+	return len(after) - common, len(before) - common
+}
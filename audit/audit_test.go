@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"fmt"
+	"solbot/lexer"
+	"solbot/parser"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+// declarations returns the hashed declarations of the files, given by
+// their paths.
+func declarations(t *testing.T, files map[string]string) []Declaration {
+	t.Helper()
+	res := []Declaration{}
+	for _, path := range []string{"src/Pool.sol", "src/Vault.sol"} {
+		src, ok := files[path]
+		if !ok {
+			continue
+		}
+		handle := token.NewFile(path, src)
+		p := parser.Parser{}
+		p.Init(handle)
+		file := p.ParseFile()
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Fatalf("Unexpected syntax errors in %s: %v", path, errs)
+		}
+		tokens := []token.Token{}
+		l := lexer.Lex(handle)
+		for tkn := l.NextToken(); tkn.Type != token.EOF; tkn = l.NextToken() {
+			tokens = append(tokens, tkn)
+		}
+		for _, d := range Declarations(file, tokens) {
+			d.File = path
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+// changed returns the changes other than the unchanged declarations.
+func changed(changes []Change) []string {
+	res := []string{}
+	for _, c := range changes {
+		if c.Status == Unchanged {
+			continue
+		}
+		line := fmt.Sprintf("%s %s", c.Status, c.Kind)
+		if c.Baseline != nil {
+			line += " " + c.Baseline.String()
+		}
+		if c.Current != nil {
+			line += " -> " + c.Current.String()
+		}
+		if c.Status == Modified {
+			line += fmt.Sprintf(" +%d -%d", c.Inserted, c.Deleted)
+		}
+		res = append(res, line)
+	}
+	return res
+}
+
+const vault = `pragma solidity ^0.8.0;
+
+contract Vault {
+    uint256 public total;
+
+    modifier positive(uint256 amount) {
+        require(amount > 0, "zero");
+        _;
+    }
+
+    function deposit(uint256 amount) external positive(amount) {
+        total += amount;
+    }
+
+    function sweep(address to) external {
+        payable(to).transfer(address(this).balance);
+    }
+}
+`
+
+func Test_ReformatUnchanged(t *testing.T) {
+	baseline := declarations(t, map[string]string{"src/Vault.sol": vault})
+	reformatted := `pragma solidity ^0.8.0;
+
+/// @title The vault.
+contract Vault
+{
+    uint256 public total;
+
+    modifier positive(uint256 amount)
+    {
+        // Nothing to deposit.
+        require(amount > 0,
+            "zero");
+        _;
+    }
+
+    /// @notice Deposits the amount.
+    function deposit(uint256 amount)
+        external
+        positive(amount)
+    {
+        total += amount; // accounted
+    }
+
+    function sweep(address to) external { payable(to).transfer(address(this).balance); }
+}
+`
+	current := declarations(t, map[string]string{"src/Vault.sol": reformatted})
+	changes := Compare(baseline, current)
+	if got := changed(changes); len(got) != 0 || len(changes) != 5 {
+		t.Errorf("Expected the 5 declarations unchanged, got %d changes: %v", len(changes), got)
+	}
+}
+
+func Test_OperatorModified(t *testing.T) {
+	baseline := declarations(t, map[string]string{"src/Vault.sol": vault})
+	current := declarations(t, map[string]string{"src/Vault.sol": strings.Replace(vault, "total += amount", "total -= amount", 1)})
+	got := changed(Compare(baseline, current))
+	expected := []string{"modified function src/Vault.sol:Vault.deposit(uint256) -> src/Vault.sol:Vault.deposit(uint256) +1 -1"}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_MovedAndRenamed(t *testing.T) {
+	baseline := declarations(t, map[string]string{
+		"src/Vault.sol": vault,
+		"src/Pool.sol":  "pragma solidity ^0.8.0;\n\ncontract Pool {\n    uint256 public total;\n}\n",
+	})
+	// sweep moves to Pool, the modifier is renamed and a function is added.
+	current := declarations(t, map[string]string{
+		"src/Vault.sol": strings.Replace(strings.Replace(vault, `    function sweep(address to) external {
+        payable(to).transfer(address(this).balance);
+    }
+`, "", 1), "positive", "nonZero", -1),
+		"src/Pool.sol": `pragma solidity ^0.8.0;
+
+contract Pool {
+    uint256 public total;
+
+    function sweep(address to) external {
+        payable(to).transfer(address(this).balance);
+    }
+
+    function skim() external {}
+}
+`,
+	})
+	got := changed(Compare(baseline, current))
+	expected := []string{
+		"added function -> src/Pool.sol:Pool.skim()",
+		"moved function src/Vault.sol:Vault.sweep(address) -> src/Pool.sol:Pool.sweep(address)",
+		"modified function src/Vault.sol:Vault.deposit(uint256) -> src/Vault.sol:Vault.deposit(uint256) +1 -1",
+		"renamed modifier src/Vault.sol:Vault.positive -> src/Vault.sol:Vault.nonZero",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_ReadSnapshotVersion(t *testing.T) {
+	if _, err := ReadSnapshot(strings.NewReader(`{"version": 2, "declarations": []}`)); err == nil || err.Error() != "unsupported snapshot version 2, expected 1" {
+		t.Errorf("Expected the other version to be refused, got %v", err)
+	}
+	var b strings.Builder
+	snapshot := Snapshot{Version: Version, Declarations: declarations(t, map[string]string{"src/Vault.sol": vault})}
+	snapshot.Write(&b)
+	read, err := ReadSnapshot(strings.NewReader(b.String()))
+	if err != nil || len(read.Declarations) != 5 || read.Declarations[3] != snapshot.Declarations[3] {
+		t.Errorf("Expected the snapshot to be read back, got %v, %v", read, err)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"solbot/baseline"
+	"solbot/lsp/analysis"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const baselineUsage = `Usage: solbot baseline <command> [flags]
+
+Records the findings the team accepted for now, so that 'solbot analyze
+--baseline' reports only the new ones. The owner, the reason and the expiry
+date of every entry are edited in the file, they are kept when the baseline
+is created again.
+
+Commands:
+  create   Record the findings of the files at the path, a file or a directory
+  prune    Remove the entries whose findings are gone
+  stats    Summarize the entries by the detector and by the owner
+`
+
+// startBaseline runs the baseline commands e.g.
+//
+//	solbot baseline create src --output .solbot-baseline.json
+//	solbot baseline prune --baseline .solbot-baseline.json
+//	solbot baseline stats
+func startBaseline(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, baselineUsage)
+		return 2
+	}
+	switch args[0] {
+	case "create":
+		return startBaselineCreate(args[1:], stdout, stderr)
+	case "prune":
+		return startBaselinePrune(args[1:], stdout, stderr)
+	case "stats":
+		return startBaselineStats(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, baselineUsage)
+		return 0
+	}
+	fmt.Fprintf(stderr, "Unknown baseline command: `%s`\n\n%s", args[0], baselineUsage)
+	return 2
+}
+
+func startBaselineCreate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("baseline create", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot baseline create path [--output .solbot-baseline.json] [--root dir]")
+		fs.PrintDefaults()
+	}
+	output := fs.String("output", baseline.DefaultPath, "Baseline file; the entries of the files not at the path are kept")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	path, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	previous, err := readBaseline(*output)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	state, uris, err := loadDocuments(path, *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	findings, analyzed := baselineFindings(state, uris)
+	b := baseline.New(findings, previous, analyzed)
+	if err := writeBaseline(*output, b); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Recorded %d findings of %d files in %s\n", len(findings), len(uris), *output)
+	return 0
+}
+
+func startBaselinePrune(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("baseline prune", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot baseline prune [path] [--baseline .solbot-baseline.json] [--root dir]")
+		fs.PrintDefaults()
+	}
+	file := fs.String("baseline", baseline.DefaultPath, "Baseline file")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	path, ok := optionalPath(fs, args)
+	if !ok {
+		return 2
+	}
+	b, err := readBaseline(*file)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if path == "" {
+		// The baseline is kept at the root of the project.
+		path = filepath.Dir(*file)
+	}
+
+	state, uris, err := loadDocuments(path, *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	findings, analyzed := baselineFindings(state, uris)
+	// The entries of the deleted files are stale too.
+	pruned, removed := b.Prune(findings, func(p string) bool {
+		if analyzed(p) {
+			return true
+		}
+		_, err := os.Stat(filepath.Join(state.Root, filepath.FromSlash(p)))
+		return errors.Is(err, os.ErrNotExist)
+	})
+	for _, e := range removed {
+		fmt.Fprintf(stdout, "%s:%d: removed %s: %s\n", e.Path, e.Line, e.Code, e.Message)
+	}
+	if len(removed) == 0 {
+		fmt.Fprintln(stdout, "No stale entries")
+		return 0
+	}
+	if err := writeBaseline(*file, pruned); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func startBaselineStats(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("baseline stats", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot baseline stats [--baseline .solbot-baseline.json]")
+		fs.PrintDefaults()
+	}
+	file := fs.String("baseline", baseline.DefaultPath, "Baseline file")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	b, err := readBaseline(*file)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	byCode, byOwner := b.Stats(time.Now())
+	fmt.Fprintf(stdout, "%d entries\n", len(b.Entries))
+	for _, table := range []struct {
+		title string
+		debts []baseline.Debt
+	}{{"DETECTOR", byCode}, {"OWNER", byOwner}} {
+		fmt.Fprintln(stdout)
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%s\tENTRIES\tEXPIRED\n", table.title)
+		for _, debt := range table.debts {
+			name := debt.Name
+			if name == "" {
+				name = "(none)"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\n", name, debt.Entries, debt.Expired)
+		}
+		w.Flush()
+	}
+	return 0
+}
+
+// optionalPath parses the flags of the command taking at most one path,
+// which can come before the flags. It returns false if the command should
+// stop.
+func optionalPath(fs *flag.FlagSet, args []string) (string, bool) {
+	if len(args) == 0 {
+		return "", true
+	}
+	if !strings.HasPrefix(args[0], "-") {
+		path, _, ok := parseArgs(fs, args)
+		return path, ok
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		if err != flag.ErrHelp {
+			fs.Usage()
+		}
+		return "", false
+	}
+	return "", true
+}
+
+// baselineFindings returns the findings of the documents, and reports
+// whether a path relative to the root is one of them.
+func baselineFindings(state *analysis.State, uris []string) ([]baseline.Finding, func(string) bool) {
+	findings := []baseline.Finding{}
+	paths := map[string]bool{}
+	for _, uri := range uris {
+		findings = append(findings, state.BaselineFindings(uri)...)
+		paths[state.RelativePath(uri)] = true
+	}
+	return findings, func(p string) bool { return paths[p] }
+}
+
+func readBaseline(name string) (*baseline.Baseline, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the baseline: %w", err)
+	}
+	b, err := baseline.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return b, nil
+}
+
+func writeBaseline(name string, b *baseline.Baseline) error {
+	data, err := b.Marshal()
+	if err == nil {
+		err = os.WriteFile(name, data, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("Error writing the baseline: %s", err)
+	}
+	return nil
+}
+
+// matchBaseline matches the findings of the file against the baseline for
+// `solbot analyze --baseline`.
+func matchBaseline(name, filePath string) (baseline.Result, string, error) {
+	b, err := readBaseline(name)
+	if err != nil {
+		return baseline.Result{}, "", err
+	}
+	state, uris, err := loadDocuments(filePath, "")
+	if err != nil {
+		return baseline.Result{}, "", err
+	}
+	findings, analyzed := baselineFindings(state, uris)
+	return b.Match(findings, analyzed, time.Now()), state.RelativePath(uris[0]), nil
+}
+
+// printBaselineResult writes the section of the report about the baseline:
+// the number of the suppressed findings, and the entries to clean up.
+func printBaselineResult(w io.Writer, r baseline.Result) {
+	fmt.Fprintf(w, "\nBaseline: %d findings suppressed, %d entries expired, %d entries stale\n", len(r.Suppressed), len(r.Expired), len(r.Stale))
+	entry := func(e baseline.Entry) string {
+		res := fmt.Sprintf("  %s:%d: %s: %s", e.Path, e.Line, e.Code, e.Message)
+		if e.Owner != "" {
+			res += " (owner " + e.Owner + ")"
+		}
+		return res
+	}
+	if len(r.Expired) > 0 {
+		fmt.Fprintln(w, "\nExpired baseline entries, fix the findings or extend the expiry dates:")
+		for _, e := range r.Expired {
+			fmt.Fprintf(w, "%s, expired after %s\n", entry(e), e.Expires)
+		}
+	}
+	if len(r.Stale) > 0 {
+		fmt.Fprintln(w, "\nStale baseline entries, the findings are gone, run `solbot baseline prune`:")
+		for _, e := range r.Stale {
+			fmt.Fprintln(w, entry(e))
+		}
+	}
+}
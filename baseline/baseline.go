@@ -0,0 +1,281 @@
+// baseline records the findings a team accepted for now, so that `solbot
+// analyze` reports only the new ones. Every entry of the baseline carries
+// the owner, the reason and the expiry date the team maintains, and the
+// run fails on the entries expired or left without a finding, so that the
+// baselines shrink over time, see `solbot baseline`.
+package baseline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"solbot/reporter"
+	"solbot/token"
+	"strings"
+	"time"
+)
+
+// Version is the version of the format of the baseline files.
+const Version = 1
+
+// DefaultPath is the baseline file of a project, relative to its root. The
+// language server reads it from there.
+const DefaultPath = ".solbot-baseline.json"
+
+// dateLayout is the layout of the expiry dates e.g. "2025-06-30".
+const dateLayout = "2006-01-02"
+
+// Baseline is the content of a baseline file.
+type Baseline struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// Entry is a finding accepted by the team.
+type Entry struct {
+	Fingerprint string `json:"fingerprint"`       // see Fingerprint
+	Code        string `json:"code"`              // code of the detector e.g. "msg-value-loop"
+	Path        string `json:"path"`              // path of the file, relative to the project root
+	Line        int    `json:"line"`              // line of the finding when it was recorded, for the readers only
+	Message     string `json:"message"`           // message of the finding when it was recorded
+	Owner       string `json:"owner,omitempty"`   // who is responsible for the finding e.g. "@alice"
+	Reason      string `json:"reason,omitempty"`  // why the finding is accepted
+	Expires     string `json:"expires,omitempty"` // the last day the entry is valid e.g. "2025-06-30"; or empty if it doesn't expire
+}
+
+// Expired reports whether the expiry date of the entry is before the day.
+func (e Entry) Expired(now time.Time) bool {
+	return e.Expires != "" && now.Format(dateLayout) > e.Expires
+}
+
+// Finding is a finding of a detector or a diagnostic of the language
+// server, as recorded in the baseline.
+type Finding struct {
+	Code        string
+	Path        string // relative to the project root
+	Line        int    // 1-based
+	Message     string
+	Text        string // the source line of the finding
+	Fingerprint string // set by Fingerprint
+}
+
+// FromFinding returns the finding of the detector at its first location in
+// the file.
+func FromFinding(path string, file *token.File, f reporter.Finding) Finding {
+	res := Finding{Code: f.Code, Path: path, Message: f.Title}
+	if len(f.Locations) > 0 {
+		offset := f.Locations[0].Position.Offset
+		res.Line = file.Position(offset).Line
+		res.Text = LineAt(file.Src(), int(offset))
+	}
+	return res
+}
+
+// LineAt returns the line of the source at the offset, without the line
+// break.
+func LineAt(src string, offset int) string {
+	offset = min(max(offset, 0), len(src))
+	start := strings.LastIndexByte(src[:offset], '\n') + 1
+	end := strings.IndexByte(src[offset:], '\n')
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += offset
+	}
+	return strings.TrimSuffix(src[start:end], "\r")
+}
+
+// Fingerprint sets the fingerprints of the findings. A fingerprint hashes
+// the code, the path and the source line of the finding with the spaces
+// collapsed, so that it survives the edits shifting the line or
+// reindenting it. The findings of the same code on identical lines of a
+// file are told apart by their order, they are expected in the order of
+// their positions.
+func Fingerprint(findings []Finding) {
+	seen := map[string]int{}
+	for i, f := range findings {
+		text := strings.Join(strings.Fields(f.Text), " ")
+		key := f.Code + "\x00" + f.Path + "\x00" + text
+		occurrence := seen[key]
+		seen[key]++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, occurrence)))
+		findings[i].Fingerprint = hex.EncodeToString(sum[:16])
+	}
+}
+
+// New returns the baseline of the findings, whose fingerprints are set.
+// The owners, the reasons and the expiry dates of the entries of the
+// previous baseline are kept, and so are its entries of the files which
+// weren't analyzed.
+func New(findings []Finding, previous *Baseline, analyzed func(path string) bool) *Baseline {
+	old := map[string]Entry{}
+	res := &Baseline{Version: Version, Entries: []Entry{}}
+	if previous != nil {
+		for _, e := range previous.Entries {
+			old[e.Fingerprint] = e
+			if !analyzed(e.Path) {
+				res.Entries = append(res.Entries, e)
+			}
+		}
+	}
+	for _, f := range findings {
+		e := Entry{Fingerprint: f.Fingerprint, Code: f.Code, Path: f.Path, Line: f.Line, Message: f.Message}
+		if prev, ok := old[f.Fingerprint]; ok {
+			e.Owner, e.Reason, e.Expires = prev.Owner, prev.Reason, prev.Expires
+		}
+		res.Entries = append(res.Entries, e)
+	}
+	res.sort()
+	return res
+}
+
+func (b *Baseline) sort() {
+	slices.SortStableFunc(b.Entries, func(x, y Entry) int {
+		if c := strings.Compare(x.Path, y.Path); c != 0 {
+			return c
+		}
+		return x.Line - y.Line
+	})
+}
+
+// Parse reads a baseline file. The format is checked against the schema:
+// the version must be known, the fields of the entries known and the
+// required ones set, and the expiry dates valid.
+func Parse(data []byte) (*Baseline, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	b := &Baseline{}
+	if err := decoder.Decode(b); err != nil {
+		return nil, fmt.Errorf("invalid baseline: %s", err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("unsupported baseline version %d, expected %d", b.Version, Version)
+	}
+	errs := []error{}
+	for i, e := range b.Entries {
+		switch {
+		case e.Fingerprint == "" || e.Code == "" || e.Path == "":
+			errs = append(errs, fmt.Errorf("entry %d: fingerprint, code and path are required", i+1))
+		case e.Expires != "":
+			if _, err := time.Parse(dateLayout, e.Expires); err != nil {
+				errs = append(errs, fmt.Errorf("entry %d: invalid expiry date %q, expected YYYY-MM-DD", i+1, e.Expires))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid baseline: %w", errors.Join(errs...))
+	}
+	if b.Entries == nil {
+		b.Entries = []Entry{}
+	}
+	return b, nil
+}
+
+// Marshal returns the content of the baseline file.
+func (b *Baseline) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(b); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Result is the outcome of matching the findings against the baseline.
+type Result struct {
+	New        []Finding // findings not in the baseline, or whose entry expired
+	Suppressed []Finding // findings of the valid entries
+	Expired    []Entry   // entries past their expiry date
+	Stale      []Entry   // entries of the analyzed files without a finding
+}
+
+// Failed reports whether the baseline needs cleaning up: some of its
+// entries expired or are stale.
+func (r Result) Failed() bool {
+	return len(r.Expired) > 0 || len(r.Stale) > 0
+}
+
+// Match sorts the findings, with their fingerprints set, into the new and
+// the suppressed ones. The staleness is only known for the entries of the
+// analyzed files, the entries of the other files are left alone.
+func (b *Baseline) Match(findings []Finding, analyzed func(path string) bool, now time.Time) Result {
+	res := Result{}
+	entries := map[string]Entry{}
+	for _, e := range b.Entries {
+		entries[e.Fingerprint] = e
+	}
+	found := map[string]bool{}
+	for _, f := range findings {
+		found[f.Fingerprint] = true
+		if e, ok := entries[f.Fingerprint]; ok && !e.Expired(now) {
+			res.Suppressed = append(res.Suppressed, f)
+		} else {
+			res.New = append(res.New, f)
+		}
+	}
+	for _, e := range b.Entries {
+		switch {
+		case analyzed(e.Path) && !found[e.Fingerprint]:
+			res.Stale = append(res.Stale, e)
+		case e.Expired(now):
+			res.Expired = append(res.Expired, e)
+		}
+	}
+	return res
+}
+
+// Prune returns the baseline without the stale entries, and the entries
+// removed.
+func (b *Baseline) Prune(findings []Finding, analyzed func(path string) bool) (*Baseline, []Entry) {
+	stale := b.Match(findings, analyzed, time.Time{}).Stale
+	res := &Baseline{Version: Version, Entries: []Entry{}}
+	for _, e := range b.Entries {
+		if !slices.Contains(stale, e) {
+			res.Entries = append(res.Entries, e)
+		}
+	}
+	return res, stale
+}
+
+// Debt is the number of the entries of a detector or an owner.
+type Debt struct {
+	Name    string // the code of the detector or the owner; or "" for the entries without an owner
+	Entries int
+	Expired int
+}
+
+// Stats summarizes the entries by the detector and by the owner, the
+// largest debts first.
+func (b *Baseline) Stats(now time.Time) (byCode, byOwner []Debt) {
+	count := func(name func(Entry) string) []Debt {
+		index := map[string]int{}
+		res := []Debt{}
+		for _, e := range b.Entries {
+			n := name(e)
+			i, ok := index[n]
+			if !ok {
+				i = len(res)
+				index[n] = i
+				res = append(res, Debt{Name: n})
+			}
+			res[i].Entries++
+			if e.Expired(now) {
+				res[i].Expired++
+			}
+		}
+		slices.SortFunc(res, func(x, y Debt) int {
+			if x.Entries != y.Entries {
+				return y.Entries - x.Entries
+			}
+			return strings.Compare(x.Name, y.Name)
+		})
+		return res
+	}
+	return count(func(e Entry) string { return e.Code }), count(func(e Entry) string { return e.Owner })
+}
@@ -0,0 +1,136 @@
+package baseline
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func findings(lines ...string) []Finding {
+	res := []Finding{}
+	for i, line := range lines {
+		code, text, _ := strings.Cut(line, ": ")
+		res = append(res, Finding{Code: code, Path: "src/Vault.sol", Line: i + 1, Message: "message of " + code, Text: text})
+	}
+	Fingerprint(res)
+	return res
+}
+
+func all(string) bool { return true }
+
+func Test_Fingerprint(t *testing.T) {
+	before := findings("msg-value-loop:         total += msg.value;", "now: return now;", "now: return now;")
+	// The lines moved and were reindented.
+	after := findings("now: \treturn now;", "now:   return   now;", "msg-value-loop: total += msg.value;")
+
+	if before[1].Fingerprint == before[2].Fingerprint {
+		t.Errorf("Expected the findings on identical lines to differ, got %s twice", before[1].Fingerprint)
+	}
+	for i, j := range []int{2, 0, 1} {
+		if before[i].Fingerprint != after[j].Fingerprint {
+			t.Errorf("Expected the fingerprint of %s to survive the edit, got %s and %s", before[i].Code, before[i].Fingerprint, after[j].Fingerprint)
+		}
+	}
+	if other := findings("now: return now + 1;"); other[0].Fingerprint == before[1].Fingerprint {
+		t.Errorf("Expected the edited line to change the fingerprint")
+	}
+}
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		src, err string
+	}{
+		{`{"version": 1, "entries": [{"fingerprint": "ab", "code": "now", "path": "Vault.sol", "line": 3, "message": "", "owner": "@alice", "expires": "2025-06-30"}]}`, ""},
+		{`{"version": 2, "entries": []}`, "unsupported baseline version 2, expected 1"},
+		{`{"version": 1, "entries": [{"fingerprint": "ab", "code": "now", "path": "Vault.sol", "expiry": "2025-06-30"}]}`, `invalid baseline: json: unknown field "expiry"`},
+		{`{"version": 1, "entries": [{"fingerprint": "ab", "path": "Vault.sol"}, {"fingerprint": "cd", "code": "now", "path": "Vault.sol", "expires": "30/06/2025"}]}`,
+			"invalid baseline: entry 1: fingerprint, code and path are required\nentry 2: invalid expiry date \"30/06/2025\", expected YYYY-MM-DD"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.src))
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("Expected no error for %s, got %s", tt.src, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("Expected the error %q for %s, got %v", tt.err, tt.src, err)
+		}
+	}
+}
+
+func Test_NewKeepsMetadata(t *testing.T) {
+	b := New(findings("now: return now;", "msg-value-loop: total += msg.value;"), nil, all)
+	b.Entries[0].Owner, b.Entries[0].Reason, b.Entries[0].Expires = "@alice", "migrated in v2", "2030-01-01"
+	other := Entry{Fingerprint: "ab", Code: "now", Path: "src/Other.sol"}
+	b.Entries = append(b.Entries, other)
+
+	data, err := b.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only src/Vault.sol is analyzed again, the finding of msg.value is
+	// fixed.
+	res := New(findings("now: return now;"), previous, func(p string) bool { return p == "src/Vault.sol" })
+	if len(res.Entries) != 2 || res.Entries[0] != other {
+		t.Fatalf("Expected the entry of the other file and the one of now, got %+v", res.Entries)
+	}
+	if e := res.Entries[1]; e.Code != "now" || e.Owner != "@alice" || e.Reason != "migrated in v2" || e.Expires != "2030-01-01" {
+		t.Errorf("Expected the metadata of the entry to be kept, got %+v", e)
+	}
+}
+
+func Test_Match(t *testing.T) {
+	recorded := findings("now: return now;", "msg-value-loop: total += msg.value;", "byte: bytes1 b;")
+	b := New(recorded, nil, all)
+	b.Entries[1].Expires = "2025-06-30"
+
+	current := findings("now: return now;", "msg-value-loop: total += msg.value;", "tx-origin: require(tx.origin == owner);")
+	res := b.Match(current, all, time.Date(2025, 6, 30, 23, 0, 0, 0, time.UTC))
+	if len(res.Suppressed) != 2 || len(res.New) != 1 || res.New[0].Code != "tx-origin" {
+		t.Errorf("Expected now and msg-value-loop suppressed until the end of the expiry date, got %+v", res)
+	}
+	if len(res.Stale) != 1 || res.Stale[0].Code != "byte" || !res.Failed() {
+		t.Errorf("Expected the entry of byte to be stale, got %+v", res.Stale)
+	}
+
+	res = b.Match(current, all, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC))
+	if len(res.Expired) != 1 || res.Expired[0].Code != "msg-value-loop" {
+		t.Errorf("Expected the entry of msg-value-loop to expire, got %+v", res.Expired)
+	}
+	if len(res.New) != 2 || res.New[0].Code != "msg-value-loop" {
+		t.Errorf("Expected the finding of the expired entry to be reported again, got %+v", res.New)
+	}
+
+	// The staleness isn't known for the files not analyzed.
+	res = b.Match(nil, func(string) bool { return false }, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if res.Failed() {
+		t.Errorf("Expected no stale entries of the files not analyzed, got %+v", res.Stale)
+	}
+}
+
+func Test_PruneAndStats(t *testing.T) {
+	b := New(findings("now: return now;", "now: uint256 t = now;", "msg-value-loop: total += msg.value;"), nil, all)
+	b.Entries[0].Owner = "@alice"
+	b.Entries[1].Owner = "@alice"
+	b.Entries[1].Expires = "2020-01-01"
+
+	pruned, removed := b.Prune(findings("now: return now;", "msg-value-loop: total += msg.value;"), all)
+	if len(removed) != 1 || removed[0].Line != 2 {
+		t.Errorf("Expected the second entry of now to be removed, got %+v", removed)
+	}
+	if len(pruned.Entries) != 2 || len(b.Entries) != 3 {
+		t.Errorf("Expected 2 entries left in a copy, got %d and %d", len(pruned.Entries), len(b.Entries))
+	}
+
+	byCode, byOwner := b.Stats(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if expected := []Debt{{"now", 2, 1}, {"msg-value-loop", 1, 0}}; !slices.Equal(byCode, expected) {
+		t.Errorf("Expected %v by detector, got %v", expected, byCode)
+	}
+	if expected := []Debt{{"@alice", 2, 1}, {"", 1, 0}}; !slices.Equal(byOwner, expected) {
+		t.Errorf("Expected %v by owner, got %v", expected, byOwner)
+	}
+}
@@ -0,0 +1,234 @@
+// clones finds the function bodies copied across the workspace, where a
+// fix applied to one copy is easily missed in the others. The bodies are
+// compared by their shapes, the sequences of their tokens with the names
+// and the literals abstracted away, so a copy with renamed variables or
+// different constants is still a copy. The candidates share a fingerprint,
+// one of the hashes of the token windows picked by winnowing, and they're
+// verified with an alignment of the whole shapes. Like the metrics, the
+// detection is purely syntactic.
+package clones
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"solbot/token"
+)
+
+// Fragment is a function body compared with the others.
+type Fragment struct {
+	Shape []token.TokenType // normalized tokens of the body, see Normalize
+	Lines int               // lines spanned by the body
+}
+
+// Options are the thresholds of the reported clones.
+type Options struct {
+	MinLines      int     // lines of the bodies below which they're not compared
+	MinSimilarity float64 // similarity of the shapes, from 0 to 1, below which they're not clones
+}
+
+// DefaultOptions returns the thresholds of `solbot clones` and of the
+// diagnostics.
+func DefaultOptions() Options {
+	return Options{MinLines: 8, MinSimilarity: 0.9}
+}
+
+// Group is a set of the fragments similar to each other.
+type Group struct {
+	ID         string  // identity of the group, the same across runs e.g. "3f2a9c1d0b7e4a51"
+	Members    []int   // indices of the fragments, in ascending order
+	Similarity float64 // lowest similarity of the verified pairs of the members
+}
+
+const (
+	// minTokens is the shape length below which the bodies are too small to
+	// be worth reporting e.g. the getters and the one-line wrappers.
+	minTokens = 30
+	// kgram is the number of the tokens hashed in a window.
+	kgram = 12
+	// window is the number of the consecutive hashes a fingerprint is picked
+	// from, so any copied run of window+kgram-1 tokens shares one.
+	window = 8
+	// literal replaces the literals in the shapes.
+	literal = token.STRING_LITERAL
+)
+
+// Normalize returns the shape of the tokens: the comments are dropped, the
+// identifiers are all the same token and so are the literals.
+func Normalize(tokens []token.Token) []token.TokenType {
+	shape := make([]token.TokenType, 0, len(tokens))
+	for _, tkn := range tokens {
+		switch tkn.Type {
+		case token.COMMENT_LITERAL:
+			continue
+		case token.TRUE_LITERAL, token.FALSE_LITERAL, token.DECIMAL_NUMBER, token.HEX_NUMBER,
+			token.STRING_LITERAL, token.UNICODE_STRING_LITERAL, token.HEX_STRING_LITERAL:
+			shape = append(shape, literal)
+		default:
+			shape = append(shape, tkn.Type)
+		}
+	}
+	return shape
+}
+
+// Find groups the fragments that are clones of each other. A fragment joins
+// a group if it's similar enough to any of its members, so the members at
+// the two ends of a chain may be less similar than the threshold. The
+// groups are ordered by their first members.
+func Find(fragments []Fragment, opts Options) []Group {
+	// The fragments sharing a fingerprint are the candidates.
+	index := map[uint64][]int{}
+	for i, f := range fragments {
+		if f.Lines < opts.MinLines || len(f.Shape) < minTokens {
+			continue
+		}
+		for _, fp := range fingerprints(f.Shape) {
+			index[fp] = append(index[fp], i)
+		}
+	}
+	type pair struct{ a, b int }
+	candidates := map[pair]bool{}
+	for _, members := range index {
+		for i, a := range members {
+			for _, b := range members[i+1:] {
+				candidates[pair{a, b}] = true
+			}
+		}
+	}
+	pairs := make([]pair, 0, len(candidates))
+	for p := range candidates {
+		pairs = append(pairs, p)
+	}
+	slices.SortFunc(pairs, func(x, y pair) int {
+		if x.a != y.a {
+			return x.a - y.a
+		}
+		return x.b - y.b
+	})
+
+	parent := make([]int, len(fragments))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+	lowest := map[pair]float64{}
+	for _, p := range pairs {
+		a, b := fragments[p.a].Shape, fragments[p.b].Shape
+		// The similarity can't exceed the ratio of the lengths.
+		if float64(2*min(len(a), len(b)))/float64(len(a)+len(b)) < opts.MinSimilarity {
+			continue
+		}
+		similarity := Similarity(a, b)
+		if similarity < opts.MinSimilarity {
+			continue
+		}
+		ra, rb := root(p.a), root(p.b)
+		if ra > rb {
+			ra, rb = rb, ra
+		}
+		parent[rb] = ra
+		lowest[p] = similarity
+	}
+
+	members := map[int][]int{}
+	for i := range fragments {
+		members[root(i)] = append(members[root(i)], i)
+	}
+	groups := []Group{}
+	for i := range fragments {
+		if root(i) != i || len(members[i]) < 2 {
+			continue
+		}
+		group := Group{Members: members[i], Similarity: 1}
+		for p, similarity := range lowest {
+			if root(p.a) == i {
+				group.Similarity = min(group.Similarity, similarity)
+			}
+		}
+		group.ID = groupID(fragments, group.Members)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// groupID returns the lowest hash of the shapes of the members, so that the
+// identity doesn't depend on the paths or the order of the files, and stays
+// the same while any of the members is unchanged.
+func groupID(fragments []Fragment, members []int) string {
+	id := uint64(0)
+	for n, i := range members {
+		if h := hashShape(fragments[i].Shape); n == 0 || h < id {
+			id = h
+		}
+	}
+	return fmt.Sprintf("%016x", id)
+}
+
+// Similarity returns the similarity of the shapes from 0 to 1: twice the
+// length of their longest common subsequence over the sum of their lengths.
+func Similarity(a, b []token.TokenType) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	prev, curr := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				curr[j+1] = prev[j] + 1
+			case prev[j+1] >= curr[j]:
+				curr[j+1] = prev[j+1]
+			default:
+				curr[j+1] = curr[j]
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return float64(2*prev[len(b)]) / float64(len(a)+len(b))
+}
+
+// fingerprints returns the distinct hashes picked from the hashes of the
+// k-grams of the shape by winnowing: the lowest one of every window, the
+// rightmost one if there are ties.
+func fingerprints(shape []token.TokenType) []uint64 {
+	if len(shape) < kgram {
+		return nil
+	}
+	hashes := make([]uint64, len(shape)-kgram+1)
+	for i := range hashes {
+		hashes[i] = hashShape(shape[i : i+kgram])
+	}
+	res := []uint64{}
+	seen := map[uint64]bool{}
+	for start := 0; start+window <= len(hashes) || start == 0; start++ {
+		end := min(start+window, len(hashes))
+		lowest := start
+		for i := start; i < end; i++ {
+			if hashes[i] <= hashes[lowest] {
+				lowest = i
+			}
+		}
+		if !seen[hashes[lowest]] {
+			seen[hashes[lowest]] = true
+			res = append(res, hashes[lowest])
+		}
+	}
+	return res
+}
+
+func hashShape(shape []token.TokenType) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 4)
+	for _, t := range shape {
+		binary.LittleEndian.PutUint32(buf, uint32(t))
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
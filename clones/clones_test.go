@@ -0,0 +1,124 @@
+package clones
+
+import (
+	"solbot/lexer"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+const depositBody = `{
+        require(amount > 0, "zero amount");
+        uint256 shares = amount * totalShares / totalAssets;
+        // Round against the depositor.
+        if (shares == 0) {
+            revert ZeroShares();
+        }
+        balances[to] += shares;
+        totalShares += shares;
+        totalAssets += amount;
+        token.transferFrom(msg.sender, address(this), amount);
+        emit Deposit(msg.sender, to, amount, shares);
+    }`
+
+// mintBody is depositBody with the names and the constants changed.
+const mintBody = `{
+        require(assets > 0, "nothing to mint");
+        uint256 minted = assets * supply / reserves;
+        if (minted == 0) {
+            revert NothingMinted();
+        }
+        balanceOf[receiver] += minted;
+        supply += minted;
+        reserves += assets;
+        asset.transferFrom(msg.sender, address(this), assets);
+        emit Mint(msg.sender, receiver, assets, minted);
+    }`
+
+// stakeBody is depositBody with an extra statement.
+const stakeBody = `{
+        require(amount > 0, "zero amount");
+        uint256 shares = amount * totalShares / totalAssets;
+        if (shares == 0) {
+            revert ZeroShares();
+        }
+        balances[to] += shares;
+        totalShares += shares;
+        totalAssets += amount;
+        lastStake[to] = block.timestamp;
+        token.transferFrom(msg.sender, address(this), amount);
+        emit Deposit(msg.sender, to, amount, shares);
+    }`
+
+// loopBody has as many lines but a different structure.
+const loopBody = `{
+        uint256 total;
+        for (uint256 i = 0; i < users.length; i++) {
+            address user = users[i];
+            if (!active[user]) continue;
+            total += rewards[user];
+            rewards[user] = 0;
+        }
+        while (total > limit) {
+            total -= limit;
+        }
+        return total;
+    }`
+
+func fragment(body string) Fragment {
+	l := lexer.Lex(token.NewFile("test.sol", body))
+	tokens := []token.Token{}
+	for tkn := l.NextToken(); tkn.Type != token.EOF; tkn = l.NextToken() {
+		tokens = append(tokens, tkn)
+	}
+	return Fragment{Shape: Normalize(tokens), Lines: strings.Count(body, "\n") + 1}
+}
+
+func Test_RenamedVariables(t *testing.T) {
+	fragments := []Fragment{fragment(depositBody), fragment(loopBody), fragment(mintBody)}
+	groups := Find(fragments, DefaultOptions())
+	if len(groups) != 1 {
+		t.Fatalf("Expected a single group, got %v", groups)
+	}
+	if members := groups[0].Members; len(members) != 2 || members[0] != 0 || members[1] != 2 {
+		t.Errorf("Expected the deposit and the mint, got %v", members)
+	}
+	if groups[0].Similarity != 1 {
+		t.Errorf("Expected the renamed copy to be identical in shape, got %f", groups[0].Similarity)
+	}
+}
+
+func Test_DifferentStructure(t *testing.T) {
+	if s := Similarity(fragment(depositBody).Shape, fragment(loopBody).Shape); s >= 0.9 {
+		t.Errorf("Expected the loop to be dissimilar, got %f", s)
+	}
+	groups := Find([]Fragment{fragment(depositBody), fragment(loopBody)}, DefaultOptions())
+	if len(groups) != 0 {
+		t.Errorf("Expected no groups, got %v", groups)
+	}
+}
+
+func Test_ThreeWayClone(t *testing.T) {
+	fragments := []Fragment{fragment(stakeBody), fragment(depositBody), fragment(loopBody), fragment(mintBody)}
+	groups := Find(fragments, DefaultOptions())
+	if len(groups) != 1 || len(groups[0].Members) != 3 {
+		t.Fatalf("Expected a single group of three, got %v", groups)
+	}
+	if s := groups[0].Similarity; s >= 1 || s < 0.9 {
+		t.Errorf("Expected the extra statement to lower the similarity, got %f", s)
+	}
+
+	// The identity doesn't depend on the order of the fragments.
+	reordered := Find([]Fragment{fragments[3], fragments[2], fragments[1], fragments[0]}, DefaultOptions())
+	if len(reordered) != 1 || reordered[0].ID != groups[0].ID {
+		t.Errorf("Expected the group %s, got %v", groups[0].ID, reordered)
+	}
+}
+
+func Test_MinLines(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MinLines = 20
+	if groups := Find([]Fragment{fragment(depositBody), fragment(mintBody)}, opts); len(groups) != 0 {
+		t.Errorf("Expected the short bodies to be skipped, got %v", groups)
+	}
+}
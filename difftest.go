@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"solbot/difftest"
+	"solbot/parser"
+	"solbot/token"
+	"strings"
+)
+
+// startDifftest compares the parse trees of our parser with solc's for the
+// file or the .sol files of the directory e.g.
+//
+//	solbot difftest contracts --solc solc-0.8.26
+//	solbot difftest contracts --solc-json out/ast
+//
+// solc's trees are either made by the binary, or read from its output
+// generated beforehand: a file with the trees of all the sources, or a
+// directory with the one of every source at its path followed by ".json".
+// It exits with 1 if the parsers diverge.
+func startDifftest(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("difftest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot difftest path [--solc path/to/solc | --solc-json file-or-dir] [--mapping mapping.json] [--format text|json] [--emit]")
+		flags.PrintDefaults()
+	}
+	solcPath := flags.String("solc", "", "solc binary to parse the files with")
+	solcJSON := flags.String("solc-json", "", "solc's output generated beforehand: a file with the ASTs of the sources, or a directory with one per source at its path + .json")
+	mappingPath := flags.String("mapping", "", "JSON mapping of our node types and attributes to solc's, added to the default one")
+	format := flags.String("format", "text", "Output format: text or json")
+	emit := flags.Bool("emit", false, "Print our parse trees in the shape of solc's compact JSON AST instead of comparing them")
+	path, code, ok := parseArgs(flags, args)
+	if !ok {
+		return code
+	}
+	if *format != "text" && *format != "json" || !*emit && (*solcPath == "") == (*solcJSON == "") {
+		flags.Usage()
+		return 2
+	}
+
+	cfg := difftest.DefaultConfig()
+	if *mappingPath != "" {
+		f, err := os.Open(*mappingPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error opening the mapping: %s\n", err)
+			return 1
+		}
+		cfg, err = difftest.LoadConfig(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	root, files, err := solidityFiles(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading %s: %s\n", path, err)
+		return 1
+	}
+
+	if *emit {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetEscapeHTML(false)
+		for _, rel := range files {
+			src, err := os.ReadFile(filepath.Join(root, rel))
+			if err != nil {
+				fmt.Fprintf(stderr, "Error reading %s: %s\n", rel, err)
+				return 1
+			}
+			p := parser.Parser{}
+			p.Init(token.NewFile(rel, string(src)))
+			encoder.Encode(difftest.Emit(p.ParseFile(), cfg))
+		}
+		return 0
+	}
+
+	var combined []byte
+	if info, err := os.Stat(*solcJSON); *solcJSON != "" && err == nil && !info.IsDir() {
+		if combined, err = os.ReadFile(*solcJSON); err != nil {
+			fmt.Fprintf(stderr, "Error reading solc's output: %s\n", err)
+			return 1
+		}
+	}
+	report := difftest.Report{}
+	for _, rel := range files {
+		full := filepath.Join(root, rel)
+		src, err := os.ReadFile(full)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading %s: %s\n", rel, err)
+			return 1
+		}
+		var solc difftest.Solc
+		switch {
+		case *solcPath != "":
+			solc, err = difftest.RunSolc(context.Background(), *solcPath, full, cfg)
+		case combined != nil:
+			solc, err = difftest.ParseSolc(combined, filepath.ToSlash(rel), cfg)
+		default:
+			var data []byte
+			if data, err = os.ReadFile(filepath.Join(*solcJSON, rel+".json")); err == nil {
+				solc, err = difftest.ParseSolc(data, filepath.ToSlash(rel), cfg)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading solc's AST of %s: %s\n", rel, err)
+			return 1
+		}
+		report.Results = append(report.Results, difftest.Check(filepath.ToSlash(rel), string(src), solc, cfg))
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			difftest.Report
+			Summary difftest.Summary `json:"summary"`
+		}{report, report.Summary()})
+	} else {
+		report.Write(stdout)
+	}
+	if !report.Clean() {
+		return 1
+	}
+	return 0
+}
+
+// solidityFiles returns the .sol files of the directory relative to it, or
+// the file relative to its directory if the path is one.
+func solidityFiles(path string) (string, []string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if !info.IsDir() {
+		return filepath.Dir(path), []string{filepath.Base(path)}, nil
+	}
+	res := []string{}
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(p, ".sol") {
+			rel, err := filepath.Rel(path, p)
+			if err != nil {
+				return err
+			}
+			res = append(res, rel)
+		}
+		return nil
+	})
+	slices.Sort(res)
+	return path, res, err
+}
@@ -0,0 +1,418 @@
+package difftest
+
+import (
+	"slices"
+	"solbot/ast"
+	"solbot/token"
+	"strconv"
+	"strings"
+)
+
+// FromAST returns the parse tree of the file in the shape of solc's AST,
+// with the node types named after ours e.g. FunctionDeclaration. The names
+// of the declarations are attributes, the same as in solc, and so are the
+// operators and the accessed members. The roles our AST has no node types
+// for are named after them: TypePath for a user-defined type name, e.g. the
+// type of a variable or a base contract, and EnumMember for the values of
+// an enum. The pragmas keep their kind and argument, solc's have only the
+// tokens.
+func FromAST(file *ast.File) *Node {
+	res := newNode("File", file, map[string]string{"license": file.License()})
+	for _, decl := range file.Declarations {
+		res.add(declaration(decl))
+	}
+	return res
+}
+
+// Emit returns the parse tree of the file as it's compared with solc's, with
+// solc's node types and only the compared attributes.
+func Emit(file *ast.File, cfg Config) *Node {
+	return cfg.normalize(FromAST(file), true)
+}
+
+func newNode(nodeType string, n ast.Node, attrs map[string]string, children ...*Node) *Node {
+	res := &Node{Type: nodeType, Start: int(n.Start()), End: int(n.End()), Attributes: attrs}
+	for _, child := range children {
+		res.add(child)
+	}
+	return res
+}
+
+// add adds the child in the source order, unless it's nil.
+func (n *Node) add(child *Node) {
+	if child == nil {
+		return
+	}
+	i, _ := slices.BinarySearchFunc(n.Nodes, child, func(a, b *Node) int { return a.Start - b.Start })
+	// The children starting at the same offset keep their order.
+	for i < len(n.Nodes) && n.Nodes[i].Start == child.Start {
+		i++
+	}
+	n.Nodes = slices.Insert(n.Nodes, i, child)
+}
+
+// name returns the name of the identifier; or an empty string if it's nil.
+func name(ident *ast.Identifier) string {
+	if ident == nil {
+		return ""
+	}
+	return ident.Name
+}
+
+func declaration(decl ast.Declaration) *Node {
+	switch d := decl.(type) {
+	case *ast.PragmaDirective:
+		return newNode("PragmaDirective", d, map[string]string{"kind": d.Kind.String(), "argument": d.Argument})
+
+	case *ast.ImportDirective:
+		path := d.Path.Value
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		} else if len(path) >= 2 {
+			path = path[1 : len(path)-1] // single quotes
+		}
+		res := newNode("ImportDirective", d, map[string]string{"file": path, "unitAlias": name(d.Alias)})
+		// solc keeps the imported names as identifiers, the aliases as
+		// strings.
+		for _, symbol := range d.Symbols {
+			res.add(newNode("ImportSymbol", symbol.Name, map[string]string{"name": symbol.Name.Name}))
+		}
+		return res
+
+	case *ast.ContractDeclaration:
+		res := newNode("ContractDeclaration", d, map[string]string{
+			"name":         name(d.Name),
+			"contractKind": d.Kind.String(),
+			"abstract":     strconv.FormatBool(d.Abstract),
+		})
+		for _, base := range d.Bases {
+			specifier := newNode("InheritanceSpecifier", base, nil, typePath(base.Name))
+			for _, arg := range base.Args {
+				specifier.add(expression(arg))
+			}
+			res.add(specifier)
+		}
+		for _, member := range d.Body {
+			res.add(declaration(member))
+		}
+		return res
+
+	case *ast.FunctionDeclaration:
+		res := newNode("FunctionDeclaration", d, map[string]string{"name": name(d.Name)})
+		res.add(paramList(d.Type.Params))
+		res.add(paramList(d.Type.Results))
+		for _, mod := range d.Modifiers {
+			invocation := newNode("ModifierInvocation", mod, nil, typePath(mod.Name))
+			for _, arg := range mod.Args {
+				invocation.add(expression(arg))
+			}
+			res.add(invocation)
+		}
+		res.add(overrideSpecifier(d.Override))
+		if d.Body != nil {
+			res.add(statement(d.Body))
+		}
+		return res
+
+	case *ast.ModifierDeclaration:
+		res := newNode("ModifierDeclaration", d, map[string]string{"name": name(d.Name)})
+		res.add(paramList(d.Params))
+		res.add(overrideSpecifier(d.Override))
+		if d.Body != nil {
+			res.add(statement(d.Body))
+		}
+		return res
+
+	case *ast.EventDeclaration:
+		return newNode("EventDeclaration", d, map[string]string{"name": name(d.Name)}, paramList(d.Params))
+
+	case *ast.ErrorDeclaration:
+		return newNode("ErrorDeclaration", d, map[string]string{"name": name(d.Name)}, paramList(d.Params))
+
+	case *ast.StructDeclaration:
+		res := newNode("StructDeclaration", d, map[string]string{"name": name(d.Name)})
+		for _, member := range d.Members {
+			res.add(declaration(member))
+		}
+		return res
+
+	case *ast.EnumDeclaration:
+		res := newNode("EnumDeclaration", d, map[string]string{"name": name(d.Name)})
+		for _, member := range d.Members {
+			res.add(newNode("EnumMember", member, map[string]string{"name": member.Name}))
+		}
+		return res
+
+	case *ast.TypeDeclaration:
+		return newNode("TypeDeclaration", d, map[string]string{"name": name(d.Name)}, typeName(d.Underlying))
+
+	case *ast.UsingForDirective:
+		res := newNode("UsingForDirective", d, nil)
+		if d.Library != nil {
+			res.add(typePath(d.Library))
+		}
+		for _, fn := range d.Functions {
+			res.add(typePath(fn))
+		}
+		if d.Type != nil {
+			res.add(typeName(d.Type))
+		}
+		return res
+
+	case *ast.VariableDeclaration:
+		res := newNode("VariableDeclaration", d, map[string]string{"name": name(d.Name)})
+		if d.Type != nil {
+			res.add(typeName(d.Type))
+		}
+		res.add(overrideSpecifier(d.Override))
+		if d.Value != nil {
+			res.add(expression(d.Value))
+		}
+		return res
+	}
+	return nil
+}
+
+// paramList returns the parameter list; or nil if there is none.
+func paramList(l *ast.ParamList) *Node {
+	if l == nil {
+		return nil
+	}
+	res := newNode("ParamList", l, nil)
+	for _, p := range l.List {
+		param := newNode("Param", p, map[string]string{"name": name(p.Name)})
+		if p.Type != nil {
+			param.add(typeName(p.Type))
+		}
+		res.add(param)
+	}
+	return res
+}
+
+func overrideSpecifier(o *ast.OverrideSpecifier) *Node {
+	if o == nil {
+		return nil
+	}
+	res := newNode("OverrideSpecifier", o, nil)
+	for _, base := range o.Bases {
+		res.add(typePath(base))
+	}
+	return res
+}
+
+// typeName returns the type in a type position, where the user-defined
+// types are paths, see typePath.
+func typeName(x ast.Expression) *Node {
+	switch x := x.(type) {
+	case *ast.Identifier, *ast.MemberAccessExpression:
+		return typePath(x)
+	case *ast.MappingType:
+		return newNode("MappingType", x, nil, typeName(x.Key), typeName(x.Value))
+	case *ast.ArrayType:
+		res := newNode("ArrayType", x, nil, typeName(x.Elem))
+		if x.Len != nil {
+			res.add(expression(x.Len))
+		}
+		return res
+	case *ast.FunctionType:
+		return newNode("FunctionType", x, nil, paramList(x.Params), paramList(x.Results))
+	}
+	return expression(x)
+}
+
+// typePath returns the name of a user-defined type, a contract or a
+// modifier e.g. `IERC20` or `Lib.Data`, which solc keeps as a single node.
+func typePath(x ast.Expression) *Node {
+	if x == nil {
+		return nil
+	}
+	return newNode("TypePath", x, map[string]string{"name": ast.ExprString(x)})
+}
+
+func statement(stmt ast.Statement) *Node {
+	switch s := stmt.(type) {
+	case nil:
+		return nil
+
+	case *ast.BlockStatement:
+		res := newNode("BlockStatement", s, nil)
+		for _, stmt := range s.Statements {
+			res.add(statement(stmt))
+		}
+		return res
+
+	case *ast.UncheckedBlockStatement:
+		// solc's unchecked block holds the statements itself.
+		res := newNode("UncheckedBlockStatement", s, nil)
+		for _, stmt := range s.Body.Statements {
+			res.add(statement(stmt))
+		}
+		return res
+
+	case *ast.ReturnStatement:
+		return newNode("ReturnStatement", s, nil, expression(s.Result))
+
+	case *ast.ExpressionStatement:
+		return newNode("ExpressionStatement", s, nil, expression(s.Expression))
+
+	case *ast.VariableDeclarationStatement:
+		res := newNode("VariableDeclarationStatement", s, nil)
+		for _, decl := range s.Declarations {
+			if decl != nil {
+				res.add(declaration(decl))
+			}
+		}
+		res.add(expression(s.Value))
+		return res
+
+	case *ast.IfStatement:
+		return newNode("IfStatement", s, nil, expression(s.Condition), statement(s.Consequence), statement(s.Alternative))
+
+	case *ast.ForStatement:
+		res := newNode("ForStatement", s, nil, statement(s.Init), expression(s.Condition), statement(s.Body))
+		// solc wraps the expression after each iteration in a statement.
+		if s.Post != nil {
+			res.add(newNode("ExpressionStatement", s.Post, nil, expression(s.Post)))
+		}
+		return res
+
+	case *ast.WhileStatement:
+		return newNode("WhileStatement", s, nil, expression(s.Condition), statement(s.Body))
+
+	case *ast.DoWhileStatement:
+		return newNode("DoWhileStatement", s, nil, statement(s.Body), expression(s.Condition))
+
+	case *ast.ContinueStatement:
+		return newNode("ContinueStatement", s, nil)
+
+	case *ast.BreakStatement:
+		return newNode("BreakStatement", s, nil)
+
+	case *ast.EmitStatement:
+		return newNode("EmitStatement", s, nil, expression(s.Call))
+
+	case *ast.RevertStatement:
+		if s.Legacy {
+			return newNode("ThrowStatement", s, nil)
+		}
+		return newNode("RevertStatement", s, nil, expression(s.Call))
+
+	case *ast.PlaceholderStatement:
+		return newNode("PlaceholderStatement", s, nil)
+
+	case *ast.BadStatement:
+		return newNode("BadStatement", s, nil)
+
+	case *ast.AssemblyStatement:
+		// The Yul code is kept as a string, see Config.Ignored.
+		return newNode("AssemblyStatement", s, nil)
+
+	case *ast.TryStatement:
+		res := newNode("TryStatement", s, nil, expression(s.Expression), paramList(s.Returns), statement(s.Body))
+		for _, clause := range s.Catches {
+			res.add(newNode("CatchClause", clause, map[string]string{"errorName": name(clause.Kind)}, paramList(clause.Params), statement(clause.Body)))
+		}
+		return res
+	}
+	return nil
+}
+
+func expression(expr ast.Expression) *Node {
+	switch x := expr.(type) {
+	case nil:
+		return nil
+
+	case *ast.Identifier:
+		return newNode("Identifier", x, map[string]string{"name": x.Name})
+
+	case *ast.ElementaryType:
+		return newNode("ElementaryType", x, map[string]string{"name": x.Value})
+
+	case *ast.BasicLit:
+		return newNode("BasicLit", x, map[string]string{"kind": literalKind(x.Kind)})
+
+	case *ast.MappingType, *ast.ArrayType, *ast.FunctionType:
+		return typeName(x)
+
+	case *ast.UnaryExpression:
+		return newNode("UnaryExpression", x, map[string]string{"operator": x.Operator.String(), "prefix": strconv.FormatBool(!x.Postfix)}, expression(x.Operand))
+
+	case *ast.BinaryExpression:
+		return newNode("BinaryExpression", x, map[string]string{"operator": x.Operator.String()}, expression(x.Left), expression(x.Right))
+
+	case *ast.AssignmentExpression:
+		return newNode("AssignmentExpression", x, map[string]string{"operator": x.Operator.String()}, expression(x.Left), expression(x.Right))
+
+	case *ast.ConditionalExpression:
+		return newNode("ConditionalExpression", x, nil, expression(x.Condition), expression(x.True), expression(x.False))
+
+	case *ast.CallExpression:
+		res := newNode("CallExpression", x, map[string]string{"names": names(x.Names)}, expression(x.Function))
+		for _, arg := range x.Args {
+			res.add(expression(arg))
+		}
+		return res
+
+	case *ast.CallOptionsExpression:
+		res := newNode("CallOptionsExpression", x, map[string]string{"names": names(x.Names)}, expression(x.Expression))
+		for _, value := range x.Values {
+			res.add(expression(value))
+		}
+		return res
+
+	case *ast.MemberAccessExpression:
+		return newNode("MemberAccessExpression", x, map[string]string{"memberName": name(x.Member)}, expression(x.Expression))
+
+	case *ast.IndexAccessExpression:
+		return newNode("IndexAccessExpression", x, nil, expression(x.Expression), expression(x.Index))
+
+	case *ast.IndexRangeAccessExpression:
+		return newNode("IndexRangeAccessExpression", x, nil, expression(x.Expression), expression(x.From), expression(x.To))
+
+	case *ast.TupleExpression:
+		res := newNode("TupleExpression", x, map[string]string{"isInlineArray": "false"})
+		for _, elem := range x.Elements {
+			res.add(expression(elem))
+		}
+		return res
+
+	case *ast.InlineArrayExpression:
+		res := newNode("InlineArrayExpression", x, map[string]string{"isInlineArray": "true"})
+		for _, elem := range x.Elements {
+			res.add(expression(elem))
+		}
+		return res
+
+	case *ast.NewExpression:
+		return newNode("NewExpression", x, nil, typeName(x.Type))
+
+	case *ast.BadExpression:
+		return newNode("BadExpression", x, nil)
+	}
+	return nil
+}
+
+// names returns the names of the arguments or the call options the way
+// solc writes the list in the attributes.
+func names(idents []*ast.Identifier) string {
+	res := make([]string, len(idents))
+	for i, ident := range idents {
+		res[i] = ident.Name
+	}
+	return strings.Join(res, ",")
+}
+
+// literalKind returns solc's kind of the literal e.g. "number".
+func literalKind(kind token.TokenType) string {
+	switch kind {
+	case token.DECIMAL_NUMBER, token.HEX_NUMBER:
+		return "number"
+	case token.TRUE_LITERAL, token.FALSE_LITERAL:
+		return "bool"
+	case token.HEX_STRING_LITERAL:
+		return "hexString"
+	case token.UNICODE_STRING_LITERAL:
+		return "unicodeString"
+	}
+	return "string"
+}
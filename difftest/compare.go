@@ -0,0 +1,264 @@
+package difftest
+
+import (
+	"fmt"
+	"slices"
+	"solbot/parser"
+	"solbot/token"
+	"strings"
+)
+
+// Kind is the kind of a divergence.
+type Kind string
+
+const (
+	Missing   Kind = "missing"   // solc has a node we don't
+	Extra     Kind = "extra"     // we have a node solc doesn't
+	Range     Kind = "range"     // the node covers a different range
+	Attribute Kind = "attribute" // an attribute of the node differs e.g. the operator
+
+	// The parsers disagree on whether the file is valid. These are the
+	// findings that matter most, since one of the parsers is wrong about
+	// the grammar, not just the shape of the tree.
+	AcceptedBySolbot Kind = "accepted-by-solbot" // we accept a file solc rejects
+	RejectedBySolbot Kind = "rejected-by-solbot" // we reject a file solc accepts
+)
+
+// Acceptance reports whether the parsers disagree on the validity of the
+// file, rather than on the tree.
+func (k Kind) Acceptance() bool {
+	return k == AcceptedBySolbot || k == RejectedBySolbot
+}
+
+// Divergence is a difference between the trees of a file, or between the
+// verdicts of the parsers.
+type Divergence struct {
+	Kind      Kind   `json:"kind"`
+	Path      string `json:"path"`
+	Line      int    `json:"line"`   // 1-based
+	Column    int    `json:"column"` // 1-based
+	NodeType  string `json:"nodeType,omitempty"`
+	Attribute string `json:"attribute,omitempty"`
+	Solbot    string `json:"solbot,omitempty"` // our range, attribute or error
+	Solc      string `json:"solc,omitempty"`   // solc's range, attribute or error
+	Excerpt   string `json:"excerpt,omitempty"`
+}
+
+func (d Divergence) String() string {
+	res := fmt.Sprintf("%s:%d:%d: ", d.Path, d.Line, d.Column)
+	switch d.Kind {
+	case Missing:
+		res += fmt.Sprintf("solc has a %s at %s, solbot doesn't", d.NodeType, d.Solc)
+	case Extra:
+		res += fmt.Sprintf("solbot has a %s at %s, solc doesn't", d.NodeType, d.Solbot)
+	case Range:
+		res += fmt.Sprintf("%s covers %s in solbot, %s in solc", d.NodeType, d.Solbot, d.Solc)
+	case Attribute:
+		res += fmt.Sprintf("%s has the %s %q in solbot, %q in solc", d.NodeType, d.Attribute, d.Solbot, d.Solc)
+	case AcceptedBySolbot:
+		res += "solbot accepts the file, solc rejects it: " + d.Solc
+	case RejectedBySolbot:
+		res += "solbot rejects the file, solc accepts it: " + d.Solbot
+	}
+	return res
+}
+
+// Result is the comparison of the trees of a file.
+type Result struct {
+	Path        string       `json:"path"`
+	Nodes       int          `json:"nodes"`   // solc's nodes compared
+	Matched     int          `json:"matched"` // solc's nodes with the same range and attributes in our tree
+	Rejected    bool         `json:"rejected"`
+	Divergences []Divergence `json:"divergences"`
+}
+
+// Check parses the source with our parser and compares the outcome with
+// solc's. The files both parsers reject are skipped.
+func Check(path, src string, solc Solc, cfg Config) Result {
+	handle := token.NewFile(path, src)
+	p := parser.Parser{}
+	p.Init(handle)
+	file := p.ParseFile()
+	errs := p.Errors()
+
+	res := Result{Path: path, Divergences: []Divergence{}}
+	switch {
+	case len(errs) > 0 && solc.Tree == nil:
+		res.Rejected = true
+		return res
+	case solc.Tree == nil:
+		d := Divergence{Kind: AcceptedBySolbot, Path: path, Line: 1, Column: 1}
+		if len(solc.Errors) > 0 {
+			d.Solc = solc.Errors[0]
+		}
+		res.Divergences = append(res.Divergences, d)
+		return res
+	case len(errs) > 0:
+		// The tree is compared too, since the recovery of the parser keeps
+		// most of it.
+		pos := handle.Position(errs[0].Pos)
+		res.Divergences = append(res.Divergences, Divergence{
+			Kind: RejectedBySolbot, Path: path, Line: pos.Line, Column: pos.Column,
+			Solbot: errs[0].Message(), Excerpt: excerpt(handle, errs[0].Pos),
+		})
+	}
+	c := comparison{cfg: cfg, handle: handle, res: &res}
+	c.compare(Emit(file, cfg), solc.Tree)
+	return res
+}
+
+// comparison compares our tree of a file with solc's, both normalized with
+// the mapping.
+type comparison struct {
+	cfg    Config
+	handle *token.File
+	res    *Result
+}
+
+// compare compares the nodes of the same type and their children.
+func (c *comparison) compare(ours, solc *Node) {
+	c.res.Nodes++
+	matched := true
+	if !c.sameRange(ours, solc) {
+		matched = false
+		c.diverge(Divergence{Kind: Range, NodeType: solc.Type, Solbot: c.rangeString(ours), Solc: c.rangeString(solc)}, ours.Start)
+	}
+	for _, k := range c.cfg.Attributes[solc.Type] {
+		if ours.Attributes[k] != solc.Attributes[k] {
+			matched = false
+			c.diverge(Divergence{Kind: Attribute, NodeType: solc.Type, Attribute: k, Solbot: ours.Attributes[k], Solc: solc.Attributes[k]}, ours.Start)
+		}
+	}
+	if matched {
+		c.res.Matched++
+	}
+
+	// The children are aligned by the longest common subsequence of the
+	// pairs of the same type and overlapping ranges, the rest is missing
+	// from one of the trees.
+	a, b := ours.Nodes, solc.Nodes
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if c.pairs(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && c.pairs(a[i], b[j]) && lcs[i][j] == lcs[i+1][j+1]+1:
+			c.compare(a[i], b[j])
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			c.res.Nodes += b[j].count()
+			c.diverge(Divergence{Kind: Missing, NodeType: b[j].Type, Solc: c.rangeString(b[j])}, b[j].Start)
+			j++
+		default:
+			c.diverge(Divergence{Kind: Extra, NodeType: a[i].Type, Solbot: c.rangeString(a[i])}, a[i].Start)
+			i++
+		}
+	}
+}
+
+// pairs reports whether the nodes are the same node of the two trees: of
+// the same type and overlapping ranges.
+func (c *comparison) pairs(ours, solc *Node) bool {
+	if ours.Type != solc.Type {
+		return false
+	}
+	os, oe := c.trim(ours)
+	ss, se := c.trim(solc)
+	return os < se && ss < oe || os == ss && oe == se
+}
+
+// sameRange reports whether the ranges of the nodes differ only in the
+// trivia at their ends, see Config.Trivia.
+func (c *comparison) sameRange(ours, solc *Node) bool {
+	os, oe := c.trim(ours)
+	ss, se := c.trim(solc)
+	return os == ss && oe == se
+}
+
+// trim returns the range of the node without the whitespace, the comments
+// and the trivia at its ends.
+func (c *comparison) trim(n *Node) (start, end int) {
+	src := c.handle.Src()
+	start, end = max(0, min(n.Start, len(src))), max(0, min(n.End, len(src)))
+front:
+	for start < end {
+		text := src[start:end]
+		switch {
+		case strings.HasPrefix(text, "//"):
+			if i := strings.IndexByte(text, '\n'); i >= 0 {
+				start += i + 1
+			} else {
+				start = end
+			}
+		case strings.HasPrefix(text, "/*"):
+			if i := strings.Index(text[2:], "*/"); i >= 0 {
+				start += i + 4
+			} else {
+				start = end
+			}
+		case strings.ContainsRune(" \t\r\n", rune(text[0])) || strings.IndexByte(c.cfg.Trivia, text[0]) >= 0:
+			start++
+		default:
+			break front
+		}
+	}
+	for start < end {
+		text := src[start:end]
+		last := text[len(text)-1]
+		switch {
+		case strings.HasSuffix(text, "*/") && strings.Contains(text, "/*"):
+			end = start + strings.LastIndex(text, "/*")
+		case strings.ContainsRune(" \t\r\n", rune(last)) || strings.IndexByte(c.cfg.Trivia, last) >= 0:
+			end--
+		default:
+			return start, end
+		}
+	}
+	return start, end
+}
+
+// diverge records the divergence at the offset.
+func (c *comparison) diverge(d Divergence, offset int) {
+	pos := c.handle.Position(token.Pos(offset))
+	d.Path, d.Line, d.Column = c.res.Path, pos.Line, pos.Column
+	d.Excerpt = excerpt(c.handle, token.Pos(offset))
+	c.res.Divergences = append(c.res.Divergences, d)
+}
+
+// rangeString returns the range of the node as "line:column-line:column".
+func (c *comparison) rangeString(n *Node) string {
+	start, end := c.handle.Position(token.Pos(n.Start)), c.handle.Position(token.Pos(n.End))
+	return fmt.Sprintf("%d:%d-%d:%d", start.Line, start.Column, end.Line, end.Column)
+}
+
+// excerpt returns the line of the source at the offset.
+func excerpt(handle *token.File, offset token.Pos) string {
+	pos := handle.Position(offset)
+	start := handle.Offset(pos.Line, 1)
+	line, _, _ := strings.Cut(handle.Src()[start:], "\n")
+	return strings.TrimSpace(line)
+}
+
+// sortDivergences sorts the divergences by their positions.
+func sortDivergences(divergences []Divergence) {
+	slices.SortStableFunc(divergences, func(a, b Divergence) int {
+		if a.Path != b.Path {
+			return strings.Compare(a.Path, b.Path)
+		}
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+}
@@ -0,0 +1,222 @@
+// difftest compares the parse trees of our parser with the ones of solc, so
+// that the constructs the two parse differently show up e.g. a range
+// missing a parenthesis or an operator bound the wrong way.
+//
+// Both trees are brought to the shape of solc's compact JSON AST: ours is
+// built from the AST with the node types named after ours, see FromAST, and
+// renamed to solc's with the mapping of the Config; solc's keeps only the
+// node types and the attributes we produce, see ParseSolc. The trees are
+// then compared node by node, see Check.
+package difftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Node is a node of a parse tree in the shape of solc's compact JSON AST.
+type Node struct {
+	Type       string            // node type e.g. "FunctionDefinition"
+	Start, End int               // byte offsets of the range, end-exclusive
+	Attributes map[string]string // the compared fields e.g. "name"; or nil
+	Nodes      []*Node           // children in the source order
+}
+
+// MarshalJSON writes the node the way solc does, with the range as "src"
+// and the attributes next to the node type.
+func (n *Node) MarshalJSON() ([]byte, error) {
+	fields := map[string]any{
+		"nodeType": n.Type,
+		"src":      fmt.Sprintf("%d:%d:0", n.Start, n.End-n.Start),
+	}
+	for k, v := range n.Attributes {
+		fields[k] = v
+	}
+	if len(n.Nodes) > 0 {
+		fields["nodes"] = n.Nodes
+	}
+	return json.Marshal(fields)
+}
+
+// count returns the number of the nodes of the tree.
+func (n *Node) count() int {
+	res := 1
+	for _, child := range n.Nodes {
+		res += child.count()
+	}
+	return res
+}
+
+// Config is the mapping between the trees. It's read from JSON with
+// LoadConfig, on top of DefaultConfig.
+type Config struct {
+	// Types maps our node types to solc's e.g. FunctionDeclaration to
+	// FunctionDefinition. The ones missing keep their names.
+	Types map[string]string `json:"types"`
+	// Attributes are the fields of solc's nodes we produce too, by solc's
+	// node types e.g. the operator of BinaryOperation. The other fields
+	// are ignored.
+	Attributes map[string][]string `json:"attributes"`
+	// Transparent are solc's node types without a counterpart in our
+	// tree, which are replaced by their children e.g.
+	// ElementaryTypeNameExpression wrapping the ElementaryTypeName.
+	Transparent []string `json:"transparent"`
+	// Ignored are solc's node types dropped together with their children
+	// e.g. StructuredDocumentation. A trailing "*" matches the prefix e.g.
+	// "Yul*".
+	Ignored []string `json:"ignored"`
+	// DropEmpty are solc's node types dropped from both trees when they
+	// have no children e.g. the ParameterList solc gives the functions
+	// without returns.
+	DropEmpty []string `json:"dropEmpty"`
+	// Trivia are the characters, besides the whitespace and the comments,
+	// the ends of a range may include in one tree and not in the other
+	// e.g. the semicolon ending a statement.
+	Trivia string `json:"trivia"`
+}
+
+// DefaultConfig returns the mapping of our AST to the one of solc 0.8.
+func DefaultConfig() Config {
+	return Config{
+		Types: map[string]string{
+			"File":                         "SourceUnit",
+			"PragmaDirective":              "PragmaDirective",
+			"ImportDirective":              "ImportDirective",
+			"ImportSymbol":                 "Identifier",
+			"ContractDeclaration":          "ContractDefinition",
+			"InheritanceSpecifier":         "InheritanceSpecifier",
+			"ModifierInvocation":           "ModifierInvocation",
+			"OverrideSpecifier":            "OverrideSpecifier",
+			"FunctionDeclaration":          "FunctionDefinition",
+			"ModifierDeclaration":          "ModifierDefinition",
+			"EventDeclaration":             "EventDefinition",
+			"ErrorDeclaration":             "ErrorDefinition",
+			"StructDeclaration":            "StructDefinition",
+			"EnumDeclaration":              "EnumDefinition",
+			"EnumMember":                   "EnumValue",
+			"TypeDeclaration":              "UserDefinedValueTypeDefinition",
+			"UsingForDirective":            "UsingForDirective",
+			"VariableDeclaration":          "VariableDeclaration",
+			"Param":                        "VariableDeclaration",
+			"ParamList":                    "ParameterList",
+			"TypePath":                     "IdentifierPath",
+			"ElementaryType":               "ElementaryTypeName",
+			"MappingType":                  "Mapping",
+			"ArrayType":                    "ArrayTypeName",
+			"FunctionType":                 "FunctionTypeName",
+			"Identifier":                   "Identifier",
+			"BasicLit":                     "Literal",
+			"UnaryExpression":              "UnaryOperation",
+			"BinaryExpression":             "BinaryOperation",
+			"AssignmentExpression":         "Assignment",
+			"ConditionalExpression":        "Conditional",
+			"CallExpression":               "FunctionCall",
+			"CallOptionsExpression":        "FunctionCallOptions",
+			"MemberAccessExpression":       "MemberAccess",
+			"IndexAccessExpression":        "IndexAccess",
+			"IndexRangeAccessExpression":   "IndexRangeAccess",
+			"TupleExpression":              "TupleExpression",
+			"InlineArrayExpression":        "TupleExpression",
+			"NewExpression":                "NewExpression",
+			"BlockStatement":               "Block",
+			"UncheckedBlockStatement":      "UncheckedBlock",
+			"ReturnStatement":              "Return",
+			"ExpressionStatement":          "ExpressionStatement",
+			"VariableDeclarationStatement": "VariableDeclarationStatement",
+			"IfStatement":                  "IfStatement",
+			"ForStatement":                 "ForStatement",
+			"WhileStatement":               "WhileStatement",
+			"DoWhileStatement":             "DoWhileStatement",
+			"ContinueStatement":            "Continue",
+			"BreakStatement":               "Break",
+			"EmitStatement":                "EmitStatement",
+			"RevertStatement":              "RevertStatement",
+			"ThrowStatement":               "Throw",
+			"PlaceholderStatement":         "PlaceholderStatement",
+			"AssemblyStatement":            "InlineAssembly",
+			"TryStatement":                 "TryStatement",
+			"CatchClause":                  "TryCatchClause",
+		},
+		Attributes: map[string][]string{
+			"SourceUnit":                     {"license"},
+			"ImportDirective":                {"file", "unitAlias"},
+			"ContractDefinition":             {"name", "contractKind", "abstract"},
+			"FunctionDefinition":             {"name"},
+			"ModifierDefinition":             {"name"},
+			"EventDefinition":                {"name"},
+			"ErrorDefinition":                {"name"},
+			"StructDefinition":               {"name"},
+			"EnumDefinition":                 {"name"},
+			"EnumValue":                      {"name"},
+			"UserDefinedValueTypeDefinition": {"name"},
+			"VariableDeclaration":            {"name"},
+			"IdentifierPath":                 {"name"},
+			"Identifier":                     {"name"},
+			"ElementaryTypeName":             {"name"},
+			"Literal":                        {"kind"},
+			"UnaryOperation":                 {"operator", "prefix"},
+			"BinaryOperation":                {"operator"},
+			"Assignment":                     {"operator"},
+			"FunctionCall":                   {"names"},
+			"FunctionCallOptions":            {"names"},
+			"MemberAccess":                   {"memberName"},
+			"TupleExpression":                {"isInlineArray"},
+			"TryCatchClause":                 {"errorName"},
+		},
+		Transparent: []string{"ElementaryTypeNameExpression", "UserDefinedTypeName"},
+		Ignored:     []string{"StructuredDocumentation", "Yul*"},
+		DropEmpty:   []string{"ParameterList"},
+		Trivia:      ";",
+	}
+}
+
+// LoadConfig reads the mapping from JSON on top of DefaultConfig: the
+// entries of the maps are added to the default ones, the lists and the
+// trivia replace them.
+func LoadConfig(r io.Reader) (Config, error) {
+	cfg := DefaultConfig()
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("cannot decode the mapping: %w", err)
+	}
+	return cfg, nil
+}
+
+// ignored reports whether solc's node type is dropped with its children.
+func (c Config) ignored(nodeType string) bool {
+	return slices.ContainsFunc(c.Ignored, func(pattern string) bool {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			return strings.HasPrefix(nodeType, prefix)
+		}
+		return pattern == nodeType
+	})
+}
+
+// normalize renames the node types of our tree to solc's, keeps only the
+// compared attributes and drops the empty nodes of both trees, see
+// Config.DropEmpty.
+func (c Config) normalize(n *Node, ours bool) *Node {
+	if ours {
+		if t, ok := c.Types[n.Type]; ok {
+			n.Type = t
+		}
+		attrs := map[string]string{}
+		for _, k := range c.Attributes[n.Type] {
+			attrs[k] = n.Attributes[k]
+		}
+		n.Attributes = attrs
+	}
+	nodes := []*Node{}
+	for _, child := range n.Nodes {
+		if child = c.normalize(child, ours); child != nil {
+			nodes = append(nodes, child)
+		}
+	}
+	n.Nodes = nodes
+	if len(n.Nodes) == 0 && slices.Contains(c.DropEmpty, n.Type) {
+		return nil
+	}
+	return n
+}
@@ -0,0 +1,255 @@
+package difftest
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"solbot/parser"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+var (
+	update = flag.Bool("update", false, "update the golden files")
+	solc   = flag.String("solc", "", "solc binary regenerating the outputs in testdata/solc with -update")
+)
+
+// fixtures are the files whose trees are compared with the outputs of solc
+// in testdata/solc. The outputs are generated by the real binary, which
+// records its version in testdata/solc/VERSION, with
+//
+//	go test ./difftest -run Test_CheckFixtures -update -solc /path/to/solc
+var fixtures = []string{"Counter.sol", "Token.sol", "Loops.sol"}
+
+func check(t *testing.T, name, solcPath string) Result {
+	t.Helper()
+	src, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(solcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	solc, err := ParseSolc(data, name, cfg)
+	if err != nil {
+		t.Fatalf("Expected solc's AST of %s, got %s", name, err)
+	}
+	return Check(name, string(src), solc, cfg)
+}
+
+func Test_CheckFixtures(t *testing.T) {
+	dir := filepath.Join("testdata", "solc")
+	if *update && *solc != "" {
+		generateSolcOutputs(t, dir)
+	}
+	version, err := os.ReadFile(filepath.Join(dir, "VERSION"))
+	if err != nil {
+		t.Skipf("No outputs of solc in %s; generate them with -update -solc /path/to/solc", dir)
+	}
+	t.Logf("Comparing with %s", strings.TrimSpace(string(version)))
+
+	report := Report{}
+	for _, name := range fixtures {
+		res := check(t, name, filepath.Join(dir, name+".json"))
+		for _, d := range res.Divergences {
+			t.Errorf("Expected no divergences in %s, got %s", name, d)
+		}
+		report.Results = append(report.Results, res)
+	}
+	s := report.Summary()
+	if s.Ratio != 1 || s.Nodes == 0 || !report.Clean() {
+		t.Errorf("Expected all the nodes to match, got %s", s)
+	}
+}
+
+// generateSolcOutputs writes the output of the solc binary for each of the
+// fixtures, and the version of the binary.
+func generateSolcOutputs(t *testing.T, dir string) {
+	t.Helper()
+	version, err := exec.Command(*solc, "--version").Output()
+	if err != nil {
+		t.Fatalf("Cannot run %s: %s", *solc, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range fixtures {
+		cmd := exec.Command(*solc, "--ast-compact-json", "--stop-after", "parsing", name)
+		cmd.Dir = "testdata"
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Cannot parse %s with %s: %s", name, *solc, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), out, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// e.g. "Version: 0.8.28+commit.7893614a.Linux.g++"
+	for _, line := range strings.Split(string(version), "\n") {
+		if strings.HasPrefix(line, "Version: ") {
+			version = []byte("solc " + strings.TrimPrefix(line, "Version: ") + "\n")
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "VERSION"), version, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The mangled tree is derived from Counter.sol, with the operator of the
+// assignment changed; it exercises the comparison, not the parity with
+// solc, see Test_CheckFixtures.
+func Test_CheckMangled(t *testing.T) {
+	res := check(t, "Counter.sol", filepath.Join("testdata", "mangled", "Counter.sol.json"))
+	if len(res.Divergences) != 1 {
+		t.Fatalf("Expected 1 divergence, got %d: %v", len(res.Divergences), res.Divergences)
+	}
+	d := res.Divergences[0]
+	if d.Kind != Attribute || d.NodeType != "Assignment" || d.Attribute != "operator" || d.Solbot != "+=" || d.Solc != "-=" {
+		t.Errorf("Expected the operator of the Assignment to differ, got %s", d)
+	}
+	if d.Excerpt != "count += by;" {
+		t.Errorf("Expected the excerpt %q, got %q", "count += by;", d.Excerpt)
+	}
+	if res.Matched != res.Nodes-1 {
+		t.Errorf("Expected all the nodes but one to match, got %d of %d", res.Matched, res.Nodes)
+	}
+}
+
+func Test_ParseSolcForms(t *testing.T) {
+	ast, err := os.ReadFile(filepath.Join("testdata", "mangled", "Counter.sol.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	compact, err := ParseSolc(ast, "Counter.sol", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forms := map[string]string{
+		"combined": "JSON AST (compact format):\n\n\n======= Other.sol =======\n" + `{"nodeType":"SourceUnit","src":"0:0:1","nodes":[]}` +
+			"\n\n======= src/Counter.sol =======\n" + string(ast),
+		"standard": `{"sources": {"src/Counter.sol": {"ast": ` + string(ast) + `}, "Other.sol": {"ast": {}}}}`,
+	}
+	for form, data := range forms {
+		res, err := ParseSolc([]byte(data), "/ws/src/Counter.sol", cfg)
+		if err != nil {
+			t.Fatalf("Expected the %s output to be read, got %s", form, err)
+		}
+		if !reflect.DeepEqual(res.Tree, compact.Tree) {
+			t.Errorf("Expected the %s output to give the tree of the compact AST", form)
+		}
+	}
+}
+
+func Test_CheckAcceptance(t *testing.T) {
+	cfg := DefaultConfig()
+	rejected, err := ParseSolc([]byte(`{"errors": [{"severity": "error", "type": "ParserError", "message": "Expected ';' but got '}'"}]}`), "A.sol", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Tree != nil || len(rejected.Errors) != 1 {
+		t.Fatalf("Expected solc to reject the file, got %+v", rejected)
+	}
+
+	res := Check("A.sol", "contract A {}", rejected, cfg)
+	if len(res.Divergences) != 1 || res.Divergences[0].Kind != AcceptedBySolbot {
+		t.Fatalf("Expected the file to be accepted by solbot only, got %v", res.Divergences)
+	}
+	if !strings.Contains(res.Divergences[0].String(), "ParserError: Expected ';' but got '}'") {
+		t.Errorf("Expected solc's error in %q", res.Divergences[0])
+	}
+
+	res = Check("A.sol", "contract A { uint x }", rejected, cfg)
+	if !res.Rejected || len(res.Divergences) != 0 {
+		t.Errorf("Expected the file rejected by both to be skipped, got %+v", res)
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "mangled", "Counter.sol.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := ParseSolc(data, "Counter.sol", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.ReadFile(filepath.Join("testdata", "Counter.sol"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := strings.Replace(string(src), "count += by;", "count += by", 1)
+	res = Check("Counter.sol", broken, accepted, cfg)
+	if len(res.Divergences) == 0 || res.Divergences[0].Kind != RejectedBySolbot {
+		t.Fatalf("Expected the file to be rejected by solbot only, got %v", res.Divergences)
+	}
+
+	report := Report{Results: []Result{res}}
+	if report.Clean() || report.Summary().RejectedBySolbot != 1 {
+		t.Errorf("Expected the report to count the rejection, got %s", report.Summary())
+	}
+}
+
+func Test_LoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(strings.NewReader(`{"types": {"Foo": "Bar"}, "attributes": {"Bar": ["name"]}, "trivia": ""}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Types["Foo"] != "Bar" || cfg.Types["File"] != "SourceUnit" {
+		t.Errorf("Expected the types to be added to the default ones, got %v", cfg.Types)
+	}
+	if len(cfg.Attributes["Bar"]) != 1 || len(cfg.Attributes["Assignment"]) != 1 {
+		t.Errorf("Expected the attributes to be added to the default ones, got %v", cfg.Attributes)
+	}
+	if cfg.Trivia != "" {
+		t.Errorf("Expected the trivia to be replaced, got %q", cfg.Trivia)
+	}
+	if _, err := LoadConfig(strings.NewReader(`{"types": []}`)); err == nil {
+		t.Errorf("Expected an error for an invalid mapping")
+	}
+}
+
+func Test_FromASTPragmas(t *testing.T) {
+	src := `// SPDX-License-Identifier: MIT OR Apache-2.0
+pragma solidity >=0.7.0 <0.9.0;
+pragma experimental ABIEncoderV2;
+pragma experimental "SMTChecker";
+pragma abicoder v1;
+
+contract Registry {
+    struct Entry {
+        address owner;
+        string name;
+    }
+}
+`
+	p := parser.Parser{}
+	p.Init(token.NewFile("Pragmas.sol", src))
+	file := p.ParseFile()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("Expected no parser errors, got %v", errs)
+	}
+	got, err := json.MarshalIndent(FromAST(file), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "Pragmas.golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("Cannot update %s: %s", golden, err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Cannot read %s: %s", golden, err)
+	}
+	if string(got) != string(expected) {
+		t.Errorf("Expected %s:\n%s\ngot:\n%s", golden, expected, got)
+	}
+}
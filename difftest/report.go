@@ -0,0 +1,122 @@
+package difftest
+
+import (
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Report is the comparison of the trees of a corpus.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Summary are the totals of a report, to be tracked over time.
+type Summary struct {
+	Files            int     `json:"files"`
+	Skipped          int     `json:"skipped"` // files both parsers reject
+	Nodes            int     `json:"nodes"`
+	Matched          int     `json:"matched"`
+	Ratio            float64 `json:"ratio"` // matched nodes; or 1 if none were compared
+	Divergences      int     `json:"divergences"`
+	AcceptedBySolbot int     `json:"acceptedBySolbot"`
+	RejectedBySolbot int     `json:"rejectedBySolbot"`
+}
+
+func (r Report) Summary() Summary {
+	res := Summary{Files: len(r.Results), Ratio: 1}
+	for _, result := range r.Results {
+		if result.Rejected {
+			res.Skipped++
+		}
+		res.Nodes += result.Nodes
+		res.Matched += result.Matched
+		for _, d := range result.Divergences {
+			switch d.Kind {
+			case AcceptedBySolbot:
+				res.AcceptedBySolbot++
+			case RejectedBySolbot:
+				res.RejectedBySolbot++
+			default:
+				res.Divergences++
+			}
+		}
+	}
+	if res.Nodes > 0 {
+		res.Ratio = float64(res.Matched) / float64(res.Nodes)
+	}
+	return res
+}
+
+// String returns the summary e.g. "97.2% of nodes match across 3 files
+// (1234 of 1270), 5 divergences".
+func (s Summary) String() string {
+	res := fmt.Sprintf("%.1f%% of nodes match across %d files (%d of %d), %d divergences", 100*s.Ratio, s.Files, s.Matched, s.Nodes, s.Divergences)
+	if s.AcceptedBySolbot > 0 || s.RejectedBySolbot > 0 {
+		res += fmt.Sprintf("; %d files accepted by solbot only, %d rejected by solbot only", s.AcceptedBySolbot, s.RejectedBySolbot)
+	}
+	if s.Skipped > 0 {
+		res += fmt.Sprintf("; %d files rejected by both skipped", s.Skipped)
+	}
+	return res
+}
+
+// Clean reports whether the parsers agree on every file.
+func (r Report) Clean() bool {
+	s := r.Summary()
+	return s.Divergences == 0 && s.AcceptedBySolbot == 0 && s.RejectedBySolbot == 0
+}
+
+// Write writes the report: the files the parsers disagree on first, then
+// the divergences of the trees grouped by solc's node types, and the
+// summary.
+func (r Report) Write(w io.Writer) {
+	acceptance := map[Kind][]Divergence{}
+	byType := map[string][]Divergence{}
+	for _, result := range r.Results {
+		for _, d := range result.Divergences {
+			if d.Kind.Acceptance() {
+				acceptance[d.Kind] = append(acceptance[d.Kind], d)
+			} else {
+				byType[d.NodeType] = append(byType[d.NodeType], d)
+			}
+		}
+	}
+
+	for _, group := range []struct {
+		kind  Kind
+		title string
+	}{
+		{AcceptedBySolbot, "Accepted by solbot, rejected by solc"},
+		{RejectedBySolbot, "Rejected by solbot, accepted by solc"},
+	} {
+		if len(acceptance[group.kind]) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d):\n", group.title, len(acceptance[group.kind]))
+		writeDivergences(w, acceptance[group.kind])
+		fmt.Fprintln(w)
+	}
+
+	types := []string{}
+	for t := range byType {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	for _, t := range types {
+		fmt.Fprintf(w, "%s (%d):\n", t, len(byType[t]))
+		writeDivergences(w, byType[t])
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, r.Summary())
+}
+
+func writeDivergences(w io.Writer, divergences []Divergence) {
+	sortDivergences(divergences)
+	for _, d := range divergences {
+		fmt.Fprintf(w, "  %s\n", d)
+		if d.Excerpt != "" {
+			fmt.Fprintf(w, "      %s\n", d.Excerpt)
+		}
+	}
+}
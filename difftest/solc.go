@@ -0,0 +1,303 @@
+package difftest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Solc is the outcome of parsing a file with solc.
+type Solc struct {
+	Tree   *Node    // parse tree; or nil if solc rejected the file
+	Errors []string // errors solc reported, if it rejected the file
+}
+
+// RunSolc parses the file with the solc binary, stopping after the parsing
+// so that the imports don't need to be resolved.
+func RunSolc(ctx context.Context, solc, path string, cfg Config) (Solc, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, solc, "--ast-compact-json", "--stop-after", "parsing", path)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return Solc{Errors: solcErrors(stderr.String())}, nil
+	}
+	if err != nil {
+		return Solc{}, fmt.Errorf("cannot run solc: %w", err)
+	}
+	return ParseSolc(stdout.Bytes(), path, cfg)
+}
+
+// solcErrors returns the errors printed by solc e.g. "ParserError: Expected
+// ';' but got '}'", without the excerpts of the code.
+func solcErrors(stderr string) []string {
+	res := []string{}
+	for _, line := range strings.Split(stderr, "\n") {
+		if kind, _, ok := strings.Cut(line, ": "); ok && strings.HasSuffix(kind, "Error") && !strings.Contains(kind, " ") {
+			res = append(res, line)
+		}
+	}
+	if len(res) == 0 && strings.TrimSpace(stderr) != "" {
+		res = append(res, strings.TrimSpace(stderr))
+	}
+	return res
+}
+
+// ParseSolc reads solc's parse tree of the file at the path from one of:
+//
+//   - the compact JSON AST of the file alone;
+//   - the output of `solc --ast-compact-json`, where the AST of every file
+//     follows a "======= path =======" header;
+//   - the standard JSON output, whose errors tell that solc rejected the
+//     file.
+//
+// Where there are the trees of several files, the one of the path is
+// picked by its name.
+func ParseSolc(data []byte, path string, cfg Config) (Solc, error) {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("{")) {
+		return parseCombined(data, path, cfg)
+	}
+	var output struct {
+		NodeType string `json:"nodeType"`
+		Errors   []struct {
+			Severity         string `json:"severity"`
+			Type             string `json:"type"`
+			Message          string `json:"message"`
+			FormattedMessage string `json:"formattedMessage"`
+		} `json:"errors"`
+		Sources map[string]struct {
+			AST json.RawMessage `json:"ast"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return Solc{}, fmt.Errorf("cannot decode solc's output: %w", err)
+	}
+	if output.NodeType != "" {
+		tree, err := decodeTree(data, cfg)
+		return Solc{Tree: tree}, err
+	}
+
+	res := Solc{}
+	for _, e := range output.Errors {
+		if e.Severity == "error" {
+			res.Errors = append(res.Errors, e.Type+": "+e.Message)
+		}
+	}
+	if len(res.Errors) > 0 {
+		return res, nil
+	}
+	sources := []string{}
+	for name := range output.Sources {
+		sources = append(sources, name)
+	}
+	name, ok := pickSource(sources, path)
+	if !ok {
+		return Solc{}, fmt.Errorf("no AST of %s in solc's output", path)
+	}
+	tree, err := decodeTree(output.Sources[name].AST, cfg)
+	return Solc{Tree: tree}, err
+}
+
+// parseCombined reads the output of `solc --ast-compact-json`.
+func parseCombined(data []byte, path string, cfg Config) (Solc, error) {
+	const header = "======= "
+	sections := map[string][]byte{}
+	names := []string{}
+	for _, section := range bytes.Split(data, []byte(header))[1:] {
+		name, rest, ok := bytes.Cut(section, []byte(" ======="))
+		if !ok {
+			continue
+		}
+		if i := bytes.IndexByte(rest, '{'); i >= 0 {
+			sections[string(name)] = rest[i:]
+			names = append(names, string(name))
+		}
+	}
+	name, ok := pickSource(names, path)
+	if !ok {
+		return Solc{}, fmt.Errorf("no AST of %s in solc's output", path)
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(sections[name])).Decode(&raw); err != nil {
+		return Solc{}, fmt.Errorf("cannot decode solc's AST of %s: %w", name, err)
+	}
+	tree, err := decodeTree(raw, cfg)
+	return Solc{Tree: tree}, err
+}
+
+// pickSource returns the name of the source which is the file at the path:
+// the only one, or the one whose path is the longest suffix of it.
+func pickSource(names []string, path string) (string, bool) {
+	if len(names) == 1 {
+		return names[0], true
+	}
+	path = filepath.ToSlash(path)
+	slices.Sort(names)
+	best := ""
+	for _, name := range names {
+		if (path == name || strings.HasSuffix(path, "/"+strings.TrimPrefix(name, "./"))) && len(name) > len(best) {
+			best = name
+		}
+	}
+	return best, best != ""
+}
+
+// decodeTree decodes solc's compact JSON AST and keeps the node types and
+// the attributes of the mapping.
+func decodeTree(data []byte, cfg Config) (*Node, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var root any
+	if err := d.Decode(&root); err != nil {
+		return nil, fmt.Errorf("cannot decode solc's AST: %w", err)
+	}
+	nodes, err := decodeNodes(root, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) != 1 || nodes[0].Type != "SourceUnit" {
+		return nil, fmt.Errorf("solc's AST is not a SourceUnit")
+	}
+	return cfg.normalize(nodes[0], false), nil
+}
+
+// decodeNodes returns the nodes of the JSON value: the value itself if it's
+// a node, else the nodes nested in it.
+func decodeNodes(v any, cfg Config) ([]*Node, error) {
+	switch v := v.(type) {
+	case []any:
+		res := []*Node{}
+		for _, elem := range v {
+			nodes, err := decodeNodes(elem, cfg)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, nodes...)
+		}
+		return res, nil
+	case map[string]any:
+		nodeType, _ := v["nodeType"].(string)
+		if nodeType == "" {
+			return decodeChildren(v, cfg)
+		}
+		return decodeNode(nodeType, v, cfg)
+	}
+	return nil, nil
+}
+
+// decodeChildren returns the nodes nested in the fields of the object, in
+// the source order.
+func decodeChildren(v map[string]any, cfg Config) ([]*Node, error) {
+	res := []*Node{}
+	for key, value := range v {
+		nodes, err := decodeNodes(value, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		res = append(res, nodes...)
+	}
+	slices.SortStableFunc(res, func(a, b *Node) int {
+		if a.Start != b.Start {
+			return a.Start - b.Start
+		}
+		return b.End - a.End
+	})
+	return res, nil
+}
+
+func decodeNode(nodeType string, v map[string]any, cfg Config) ([]*Node, error) {
+	if cfg.ignored(nodeType) {
+		return nil, nil
+	}
+	switch nodeType {
+	case "UserDefinedTypeName":
+		// Before 0.8.0 the name is a string, not an IdentifierPath.
+		if _, ok := v["pathNode"]; !ok {
+			nodeType = "IdentifierPath"
+		}
+	case "TryStatement":
+		// The call with its returns and the block run on success are a
+		// clause of solc's try statement, but not of ours.
+		if clauses, ok := v["clauses"].([]any); ok && len(clauses) > 0 {
+			if clause, ok := clauses[0].(map[string]any); ok {
+				success := copyFields(clause)
+				delete(success, "nodeType")
+				v = copyFields(v)
+				v["clauses"] = append([]any{success}, clauses[1:]...)
+			}
+		}
+	}
+
+	children, err := decodeChildren(v, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", nodeType, err)
+	}
+	if slices.Contains(cfg.Transparent, nodeType) {
+		return children, nil
+	}
+	src, _ := v["src"].(string)
+	start, end, err := parseSrc(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", nodeType, err)
+	}
+	res := &Node{Type: nodeType, Start: start, End: end, Attributes: map[string]string{}, Nodes: children}
+	for _, k := range cfg.Attributes[nodeType] {
+		res.Attributes[k] = attribute(v[k])
+	}
+	return []*Node{res}, nil
+}
+
+func copyFields(v map[string]any) map[string]any {
+	res := make(map[string]any, len(v))
+	for k, value := range v {
+		res[k] = value
+	}
+	return res
+}
+
+// parseSrc returns the range of solc's "start:length:source".
+func parseSrc(src string) (start, end int, err error) {
+	parts := strings.Split(src, ":")
+	if len(parts) != 3 {
+		return 0, 0, fmt.Errorf("invalid src %q", src)
+	}
+	start, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid src %q", src)
+	}
+	length, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid src %q", src)
+	}
+	return start, start + length, nil
+}
+
+// attribute returns the value of the field as a string, the lists of
+// strings separated by commas the same as ours e.g. the names of the
+// arguments.
+func attribute(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case []any:
+		res := []string{}
+		for _, elem := range v {
+			res = append(res, attribute(elem))
+		}
+		return strings.Join(res, ",")
+	}
+	return ""
+}
@@ -0,0 +1,15 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+/// @notice Counts the increments of its callers.
+contract Counter {
+    uint256 public count;
+
+    event Incremented(address indexed by, uint256 value);
+
+    function increment(uint256 by) external returns (uint256) {
+        count += by;
+        emit Incremented(msg.sender, count);
+        return count;
+    }
+}
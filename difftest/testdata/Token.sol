@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+import {Ownable} from "./Ownable.sol";
+
+contract Token is Ownable {
+    enum State { Active, Paused }
+
+    struct Account {
+        uint256 balance;
+        bool frozen;
+    }
+
+    mapping(address => Account) accounts;
+    State state;
+
+    modifier whenActive() {
+        require(state == State.Active, "paused");
+        _;
+    }
+
+    function transfer(address to, uint256 amount) public whenActive {
+        Account storage from = accounts[msg.sender];
+        if (!from.frozen && from.balance >= amount) {
+            from.balance -= amount;
+            accounts[to].balance += amount;
+        }
+    }
+}
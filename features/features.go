@@ -0,0 +1,294 @@
+// features maps the language constructs and the builtins to the compiler
+// versions which introduced and removed them, and finds their uses in a
+// file, so that a floating pragma can be checked against the code it
+// claims to compile e.g. `pragma solidity >=0.6.0 <0.9.0;` with a custom
+// error, which needs 0.8.4.
+//
+// The constructs are kept in a table, like the migrations, so that new
+// ones can be added without touching the code that checks them. Their
+// uses are found syntactically, the builtins by their names.
+package features
+
+import (
+	"slices"
+	"solbot/ast"
+	"solbot/semver"
+	"solbot/token"
+)
+
+// Feature is a construct of the language available in a range of the
+// compiler versions.
+type Feature struct {
+	Name    string         // e.g. "custom error"
+	Since   semver.Version // the first version with the feature; or zero
+	Removed semver.Version // the first version without it; or zero if it's still there
+	find    func(file *ast.File) []token.Range
+}
+
+// Constraint returns the versions with the feature e.g. ">=0.8.4".
+func (f *Feature) Constraint() semver.Constraint {
+	if f.Removed == (semver.Version{}) {
+		return semver.MustParseConstraint(">=" + f.Since.String())
+	}
+	return semver.MustParseConstraint(">=" + f.Since.String() + " <" + f.Removed.String())
+}
+
+// Use is the first use of a feature in a file.
+type Use struct {
+	Feature *Feature
+	Range   token.Range
+}
+
+var (
+	v0_4_21 = semver.MustParse("0.4.21")
+	v0_4_22 = semver.MustParse("0.4.22")
+	v0_5_0  = semver.MustParse("0.5.0")
+	v0_6_0  = semver.MustParse("0.6.0")
+	v0_7_0  = semver.MustParse("0.7.0")
+)
+
+// TransientVariables are the transient state variables, which have their
+// own diagnostics in the language server, see transient-version.
+var TransientVariables = &Feature{Name: "transient state variable", Since: semver.MustParse("0.8.28"), find: variables(func(v *ast.VariableDeclaration) bool { return v.Transient != 0 })}
+
+// Features are the known features, by the version introducing them, and
+// then the ones removed.
+var Features = []*Feature{
+	{Name: "`emit`", Since: v0_4_21, find: statements(is[*ast.EmitStatement])},
+	{Name: "`constructor`", Since: v0_4_22, find: functions(func(fn *ast.FunctionDeclaration) bool { return fn.Kind == token.CONSTRUCTOR && !fn.Legacy })},
+	{Name: "try/catch", Since: v0_6_0, find: statements(is[*ast.TryStatement])},
+	{Name: "`receive` function", Since: v0_6_0, find: functions(func(fn *ast.FunctionDeclaration) bool { return fn.Kind == token.RECEIVE })},
+	{Name: "`fallback` function", Since: v0_6_0, find: functions(func(fn *ast.FunctionDeclaration) bool { return fn.Kind == token.FALLBACK && !fn.Legacy })},
+	{Name: "`virtual` and `override`", Since: v0_6_0, find: functions(func(fn *ast.FunctionDeclaration) bool { return fn.Virtual || fn.Override != nil })},
+	{Name: "immutable variable", Since: semver.MustParse("0.6.5"), find: variables(func(v *ast.VariableDeclaration) bool { return v.Immutable })},
+	{Name: "`type(I).interfaceId`", Since: semver.MustParse("0.6.7"), find: typeMembers("interfaceId")},
+	{Name: "`type(T).min` and `type(T).max`", Since: semver.MustParse("0.6.8"), find: typeMembers("min", "max")},
+	{Name: "free function", Since: semver.MustParse("0.7.1"), find: topLevel(is[*ast.FunctionDeclaration])},
+	{Name: "file-level constant", Since: semver.MustParse("0.7.4"), find: topLevel(is[*ast.VariableDeclaration])},
+	{Name: "unchecked block", Since: semver.MustParse("0.8.0"), find: statements(is[*ast.UncheckedBlockStatement])},
+	{Name: "`block.chainid`", Since: semver.MustParse("0.8.0"), find: members("block", "chainid")},
+	{Name: "custom error", Since: semver.MustParse("0.8.4"), find: errors},
+	{Name: "`bytes.concat`", Since: semver.MustParse("0.8.4"), find: members("bytes", "concat")},
+	{Name: "`block.basefee`", Since: semver.MustParse("0.8.7"), find: members("block", "basefee")},
+	{Name: "user-defined value type", Since: semver.MustParse("0.8.8"), find: declarations(is[*ast.TypeDeclaration])},
+	{Name: "`abi.encodeCall`", Since: semver.MustParse("0.8.11"), find: members("abi", "encodeCall")},
+	{Name: "`string.concat`", Since: semver.MustParse("0.8.12"), find: members("string", "concat")},
+	{Name: "file-level `using for`", Since: semver.MustParse("0.8.13"), find: topLevel(is[*ast.UsingForDirective])},
+	{Name: "`block.prevrandao`", Since: semver.MustParse("0.8.18"), find: members("block", "prevrandao")},
+	{Name: "named mapping parameters", Since: semver.MustParse("0.8.18"), find: namedMappings},
+	{Name: "file-level event", Since: semver.MustParse("0.8.22"), find: topLevel(is[*ast.EventDeclaration])},
+	{Name: "`block.blobbasefee`", Since: semver.MustParse("0.8.24"), find: members("block", "blobbasefee")},
+	{Name: "`blobhash`", Since: semver.MustParse("0.8.24"), find: calls("blobhash")},
+	TransientVariables,
+
+	{Name: "`throw`", Removed: v0_5_0, find: statements(func(n ast.Node) bool { r, ok := n.(*ast.RevertStatement); return ok && r.Legacy })},
+	{Name: "`var`", Removed: v0_5_0, find: statements(func(n ast.Node) bool { v, ok := n.(*ast.VariableDeclarationStatement); return ok && v.Var != 0 })},
+	{Name: "constructor named after the contract", Removed: v0_5_0, find: functions(func(fn *ast.FunctionDeclaration) bool { return fn.Kind == token.CONSTRUCTOR && fn.Legacy })},
+	{Name: "`sha3`", Removed: v0_5_0, find: calls("sha3")},
+	{Name: "`suicide`", Removed: v0_5_0, find: calls("suicide")},
+	{Name: "`msg.gas`", Removed: v0_5_0, find: members("msg", "gas")},
+	{Name: "`years`", Removed: v0_5_0, find: units("years")},
+	{Name: "unnamed fallback function", Removed: v0_6_0, find: functions(func(fn *ast.FunctionDeclaration) bool { return fn.Kind == token.FALLBACK && fn.Legacy })},
+	{Name: "`now`", Removed: v0_7_0, find: now},
+}
+
+// Find returns the first use of every feature in the file, sorted by their
+// positions.
+func Find(file *ast.File) []Use {
+	res := []Use{}
+	for _, f := range Features {
+		if found := f.find(file); len(found) > 0 {
+			first := slices.MinFunc(found, func(a, b token.Range) int { return int(a.Start - b.Start) })
+			res = append(res, Use{Feature: f, Range: first})
+		}
+	}
+	slices.SortStableFunc(res, func(a, b Use) int { return int(a.Range.Start - b.Range.Start) })
+	return res
+}
+
+// Required returns the versions with all of the features used.
+func Required(uses []Use) semver.Constraint {
+	res := semver.MustParseConstraint(">=0.0.0")
+	for _, use := range uses {
+		res = res.Intersect(use.Feature.Constraint())
+	}
+	return res
+}
+
+func inspect(file *ast.File, f func(node ast.Node) (token.Range, bool)) []token.Range {
+	res := []token.Range{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if node == nil {
+			return true
+		}
+		if r, ok := f(node); ok {
+			res = append(res, r)
+		}
+		return true
+	})
+	return res
+}
+
+// is reports whether the node is of the type.
+func is[T ast.Node](node ast.Node) bool {
+	_, ok := node.(T)
+	return ok
+}
+
+func statements(match func(ast.Node) bool) func(*ast.File) []token.Range {
+	return func(file *ast.File) []token.Range {
+		return inspect(file, func(node ast.Node) (token.Range, bool) {
+			_, ok := node.(ast.Statement)
+			return ast.NodeRange(node), ok && match(node)
+		})
+	}
+}
+
+// functions finds the functions by their headers, rather than the whole
+// declarations.
+func functions(match func(*ast.FunctionDeclaration) bool) func(*ast.File) []token.Range {
+	return func(file *ast.File) []token.Range {
+		return inspect(file, func(node ast.Node) (token.Range, bool) {
+			fn, ok := node.(*ast.FunctionDeclaration)
+			if !ok || !match(fn) {
+				return token.Range{}, false
+			}
+			if fn.Name != nil {
+				return ast.NodeRange(fn.Name), true
+			}
+			return token.Range{Start: fn.Start(), End: fn.Type.End()}, true
+		})
+	}
+}
+
+func variables(match func(*ast.VariableDeclaration) bool) func(*ast.File) []token.Range {
+	return func(file *ast.File) []token.Range {
+		return inspect(file, func(node ast.Node) (token.Range, bool) {
+			v, ok := node.(*ast.VariableDeclaration)
+			return ast.NodeRange(node), ok && match(v)
+		})
+	}
+}
+
+func topLevel(match func(ast.Node) bool) func(*ast.File) []token.Range {
+	return func(file *ast.File) []token.Range {
+		res := []token.Range{}
+		for _, d := range file.Declarations {
+			if match(d) {
+				res = append(res, ast.NodeRange(d))
+			}
+		}
+		return res
+	}
+}
+
+// declarations finds the declarations at the top level and in the
+// contracts.
+func declarations(match func(ast.Node) bool) func(*ast.File) []token.Range {
+	return func(file *ast.File) []token.Range {
+		return inspect(file, func(node ast.Node) (token.Range, bool) {
+			_, ok := node.(ast.Declaration)
+			return ast.NodeRange(node), ok && match(node)
+		})
+	}
+}
+
+// members finds the builtin members e.g. `block.prevrandao`.
+func members(base string, names ...string) func(*ast.File) []token.Range {
+	return func(file *ast.File) []token.Range {
+		return inspect(file, func(node ast.Node) (token.Range, bool) {
+			access, ok := node.(*ast.MemberAccessExpression)
+			if !ok || !slices.Contains(names, access.Member.Name) {
+				return token.Range{}, false
+			}
+			ident, ok := access.Expression.(*ast.Identifier)
+			return ast.NodeRange(access), ok && ident.Name == base
+		})
+	}
+}
+
+// typeMembers finds the members of `type(T)` e.g. `type(uint256).max`.
+func typeMembers(names ...string) func(*ast.File) []token.Range {
+	return func(file *ast.File) []token.Range {
+		return inspect(file, func(node ast.Node) (token.Range, bool) {
+			access, ok := node.(*ast.MemberAccessExpression)
+			if !ok || !slices.Contains(names, access.Member.Name) {
+				return token.Range{}, false
+			}
+			call, ok := access.Expression.(*ast.CallExpression)
+			if !ok {
+				return token.Range{}, false
+			}
+			ident, ok := call.Function.(*ast.Identifier)
+			return ast.NodeRange(access), ok && ident.Name == "type"
+		})
+	}
+}
+
+// calls finds the calls of the builtin functions e.g. `blobhash(0)`.
+func calls(name string) func(*ast.File) []token.Range {
+	return func(file *ast.File) []token.Range {
+		return inspect(file, func(node ast.Node) (token.Range, bool) {
+			call, ok := node.(*ast.CallExpression)
+			if !ok {
+				return token.Range{}, false
+			}
+			ident, ok := call.Function.(*ast.Identifier)
+			return ast.NodeRange(call), ok && ident.Name == name
+		})
+	}
+}
+
+func units(name string) func(*ast.File) []token.Range {
+	return func(file *ast.File) []token.Range {
+		return inspect(file, func(node ast.Node) (token.Range, bool) {
+			lit, ok := node.(*ast.BasicLit)
+			return ast.NodeRange(node), ok && lit.Unit != nil && lit.Unit.Name == name
+		})
+	}
+}
+
+// errors finds the declarations of the custom errors and the revert
+// statements using them.
+func errors(file *ast.File) []token.Range {
+	return inspect(file, func(node ast.Node) (token.Range, bool) {
+		switch n := node.(type) {
+		case *ast.ErrorDeclaration:
+			return ast.NodeRange(n), true
+		case *ast.RevertStatement:
+			return ast.NodeRange(n), !n.Legacy
+		}
+		return token.Range{}, false
+	})
+}
+
+func namedMappings(file *ast.File) []token.Range {
+	return inspect(file, func(node ast.Node) (token.Range, bool) {
+		m, ok := node.(*ast.MappingType)
+		return ast.NodeRange(node), ok && (m.KeyName != nil || m.ValueName != nil)
+	})
+}
+
+// now finds the uses of `now`, unless the file declares it.
+func now(file *ast.File) []token.Range {
+	declared := false
+	members := map[*ast.Identifier]bool{}
+	res := inspect(file, func(node ast.Node) (token.Range, bool) {
+		switch n := node.(type) {
+		case *ast.VariableDeclaration:
+			declared = declared || n.Name != nil && n.Name.Name == "now"
+		case *ast.FunctionDeclaration:
+			declared = declared || n.Name != nil && n.Name.Name == "now"
+		case *ast.MemberAccessExpression:
+			// e.g. `block.now` is not the builtin.
+			members[n.Member] = true
+		case *ast.Identifier:
+			return ast.NodeRange(n), n.Name == "now" && !members[n]
+		}
+		return token.Range{}, false
+	})
+	if declared {
+		return nil
+	}
+	return res
+}
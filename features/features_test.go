@@ -0,0 +1,97 @@
+package features
+
+import (
+	"solbot/ast"
+	"solbot/parser"
+	"solbot/token"
+	"testing"
+)
+
+func parse(t *testing.T, src string) *ast.File {
+	t.Helper()
+	p := parser.Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	file := p.ParseFile()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Expected no parser errors, got %v", p.Errors())
+	}
+	return file
+}
+
+func Test_Find(t *testing.T) {
+	src := `pragma solidity >=0.6.0 <0.9.0;
+
+error Unauthorized();
+
+contract Vault {
+    address immutable owner;
+    uint256 seed;
+
+    constructor() {
+        owner = msg.sender;
+    }
+
+    function withdraw() external {
+        if (msg.sender != owner) revert Unauthorized();
+        seed = block.prevrandao + type(uint256).max;
+    }
+}
+`
+	file := parse(t, src)
+	uses := Find(file)
+	expected := []string{"custom error", "immutable variable", "`constructor`", "`block.prevrandao`", "`type(T).min` and `type(T).max`"}
+	if len(uses) != len(expected) {
+		names := []string{}
+		for _, use := range uses {
+			names = append(names, use.Feature.Name)
+		}
+		t.Fatalf("Expected the features %v, got %v", expected, names)
+	}
+	for i, use := range uses {
+		if use.Feature.Name != expected[i] {
+			t.Errorf("Expected the feature %d to be %s, got %s", i, expected[i], use.Feature.Name)
+		}
+	}
+	if got := src[uses[0].Range.Start:uses[0].Range.End]; got != "error Unauthorized()" {
+		t.Errorf("Expected the first use of the custom error at its declaration, got %q", got)
+	}
+
+	if got := Required(uses).Format(); got != ">=0.8.18" {
+		t.Errorf("Expected the features to require >=0.8.18, got %s", got)
+	}
+}
+
+func Test_FindRemoved(t *testing.T) {
+	src := `pragma solidity ^0.4.24;
+
+contract Legacy {
+    function Legacy() public {}
+
+    function expired(uint256 start) public view returns (bool) {
+        return now > start + 1 years;
+    }
+}
+`
+	uses := Find(parse(t, src))
+	if len(uses) != 3 {
+		t.Fatalf("Expected 3 features, got %d", len(uses))
+	}
+	if got := Required(uses).Format(); got != ">=0.0.0 <0.5.0" {
+		t.Errorf("Expected the features to require >=0.0.0 <0.5.0, got %s", got)
+	}
+
+	// A file declaring `now` doesn't use the builtin.
+	uses = Find(parse(t, `pragma solidity ^0.8.0;
+
+contract Clock {
+    uint256 now;
+
+    function time() external view returns (uint256) {
+        return now;
+    }
+}
+`))
+	if len(uses) != 0 {
+		t.Errorf("Expected no features, got %s", uses[0].Feature.Name)
+	}
+}
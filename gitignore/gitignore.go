@@ -0,0 +1,285 @@
+// gitignore matches the paths against the patterns of the .gitignore
+// files, following [gitignore(5)]: the wildcards, `**`, the patterns
+// matching the directories only, the anchoring to the directory of the
+// file and the negations.
+//
+// The paths are relative to the root of the repository, with the slashes
+// as the separators e.g. "src/Vault.sol".
+//
+// [gitignore(5)]: https://git-scm.com/docs/gitignore
+package gitignore
+
+import "strings"
+
+// Pattern is a line of an ignore file.
+type Pattern struct {
+	base     string   // directory of the ignore file e.g. "lib/forge-std"; or empty for the root
+	segments []string // the segments of the pattern between the slashes; "**" matches any directories
+	negate   bool     // does the pattern re-include the paths e.g. "!keep.sol"?
+	dirOnly  bool     // does the pattern match the directories only e.g. "out/"?
+}
+
+// ParseLine parses a line of the ignore file in the base directory. It
+// returns false for the blank lines and the comments.
+func ParseLine(line, base string) (Pattern, bool) {
+	p := Pattern{base: strings.Trim(base, "/")}
+	line = strings.TrimSuffix(line, "\r")
+	// The trailing spaces are removed, unless they're escaped.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return p, false
+	}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return p, false
+	}
+	// A pattern with a slash at the beginning or in the middle is relative
+	// to the directory of the file, the others match at any level.
+	anchored := strings.Contains(line, "/")
+	p.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+	if !anchored {
+		p.segments = append([]string{"**"}, p.segments...)
+	}
+	return p, true
+}
+
+// Parse parses the lines of the ignore file in the base directory.
+func Parse(src, base string) []Pattern {
+	res := []Pattern{}
+	for _, line := range strings.Split(src, "\n") {
+		if p, ok := ParseLine(line, base); ok {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Match reports whether the pattern matches the path itself, not any of
+// its parent directories.
+func (p Pattern) Match(path string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	rel, ok := p.relative(path)
+	return ok && matchSegments(p.segments, strings.Split(rel, "/"))
+}
+
+// MayMatchUnder reports whether the pattern may match a path under the
+// directory e.g. "out/Flat.sol" under "out".
+func (p Pattern) MayMatchUnder(dir string) bool {
+	if p.base != "" && (p.base == dir || strings.HasPrefix(p.base, dir+"/")) {
+		return true
+	}
+	rel, ok := p.relative(dir)
+	return ok && matchPrefix(p.segments, strings.Split(rel, "/"))
+}
+
+// relative returns the path relative to the directory of the pattern; or
+// false if it's not under it.
+func (p Pattern) relative(path string) (string, bool) {
+	path = strings.Trim(path, "/")
+	if p.base == "" {
+		return path, path != ""
+	}
+	rel, ok := strings.CutPrefix(path, p.base+"/")
+	return rel, ok && rel != ""
+}
+
+// matchSegments reports whether the segments of the pattern match all of
+// the segments of the path.
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		// The trailing "/**" matches everything inside the directory, but
+		// not the directory itself.
+		if len(pattern) == 1 {
+			return len(path) > 0
+		}
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(path) > 0 && matchGlob(pattern[0], path[0]) && matchSegments(pattern[1:], path[1:])
+}
+
+// matchPrefix reports whether the segments of the directory match the
+// start of the pattern, leaving some of it for the paths under it.
+func matchPrefix(pattern, dir []string) bool {
+	if len(dir) == 0 {
+		return len(pattern) > 0
+	}
+	if len(pattern) == 0 {
+		return false
+	}
+	if pattern[0] == "**" {
+		return true
+	}
+	return matchGlob(pattern[0], dir[0]) && matchPrefix(pattern[1:], dir[1:])
+}
+
+// matchGlob reports whether the name matches the glob: `*` matches any
+// characters, `?` any single one, `[a-z]` the ones in the class, negated
+// with `!` or `^`, and the backslash escapes the next character.
+func matchGlob(glob, name string) bool {
+	for len(glob) > 0 {
+		switch glob[0] {
+		case '*':
+			// The consecutive asterisks are a single one within a name.
+			glob = strings.TrimLeft(glob, "*")
+			if glob == "" {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchGlob(glob, name[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if name == "" {
+				return false
+			}
+			glob, name = glob[1:], name[1:]
+		case '[':
+			if name == "" {
+				return false
+			}
+			matched, rest, ok := matchClass(glob, name[0])
+			if !ok {
+				// An unclosed bracket is a literal one.
+				if name[0] != '[' {
+					return false
+				}
+				glob, name = glob[1:], name[1:]
+				continue
+			}
+			if !matched {
+				return false
+			}
+			glob, name = rest, name[1:]
+		case '\\':
+			if len(glob) > 1 {
+				glob = glob[1:]
+			}
+			fallthrough
+		default:
+			if name == "" || glob[0] != name[0] {
+				return false
+			}
+			glob, name = glob[1:], name[1:]
+		}
+	}
+	return name == ""
+}
+
+// matchClass matches the character against the class at the start of the
+// glob e.g. "[a-z]". It returns the rest of the glob after the class, and
+// false if the class is not closed.
+func matchClass(glob string, c byte) (matched bool, rest string, ok bool) {
+	i := 1
+	negate := i < len(glob) && (glob[i] == '!' || glob[i] == '^')
+	if negate {
+		i++
+	}
+	// A bracket right after the opening one is a literal one.
+	for first := true; i < len(glob); first = false {
+		if glob[i] == ']' && !first {
+			return matched != negate, glob[i+1:], true
+		}
+		lo := glob[i]
+		if lo == '\\' && i+1 < len(glob) {
+			i++
+			lo = glob[i]
+		}
+		hi := lo
+		if i+2 < len(glob) && glob[i+1] == '-' && glob[i+2] != ']' {
+			hi = glob[i+2]
+			if hi == '\\' && i+3 < len(glob) {
+				i++
+				hi = glob[i+2]
+			}
+			i += 2
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+		i++
+	}
+	return false, "", false
+}
+
+// Matcher decides whether the paths are ignored by the patterns of the
+// ignore files, added from the lowest precedence to the highest: the
+// .git/info/exclude file first, then the .gitignore files from the root
+// down. The last pattern matching a path decides.
+type Matcher struct {
+	patterns []Pattern
+}
+
+// Add adds the patterns, with a higher precedence than the ones added
+// before.
+func (m *Matcher) Add(patterns ...Pattern) {
+	m.patterns = append(m.patterns, patterns...)
+}
+
+// Match returns whether the last pattern matching the path ignores it, and
+// whether any pattern matches it. The parent directories are not checked,
+// see Ignored.
+func (m *Matcher) Match(path string, isDir bool) (ignored, matched bool) {
+	for i := len(m.patterns) - 1; i >= 0; i-- {
+		if m.patterns[i].Match(path, isDir) {
+			return !m.patterns[i].negate, true
+		}
+	}
+	return false, false
+}
+
+// Ignored reports whether the path or any of its parent directories is
+// ignored. A path under an ignored directory can't be re-included.
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	path = strings.Trim(path, "/")
+	for i := strings.IndexByte(path, '/'); i >= 0; i = next(path, i) {
+		if ignored, _ := m.Match(path[:i], true); ignored {
+			return true
+		}
+	}
+	ignored, _ := m.Match(path, isDir)
+	return ignored
+}
+
+// MayMatchUnder reports whether a pattern, not a negated one, may match a
+// path under the directory. The directory is to be walked e.g. when the
+// patterns list the paths to be included.
+func (m *Matcher) MayMatchUnder(dir string) bool {
+	for _, p := range m.patterns {
+		if !p.negate && p.MayMatchUnder(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the index of the slash after the one at i; or -1.
+func next(path string, i int) int {
+	j := strings.IndexByte(path[i+1:], '/')
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
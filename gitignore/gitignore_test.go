@@ -0,0 +1,172 @@
+package gitignore
+
+import "testing"
+
+func Test_PatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		base     string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		// The examples of gitignore(5).
+		{"hello.*", "", "hello.txt", false, true},
+		{"hello.*", "", "src/hello.c", false, true},
+		{"hello.*", "", "hello", false, false},
+		{"/hello.*", "", "hello.txt", false, true},
+		{"/hello.*", "", "a/hello.java", false, false},
+		{"foo/", "", "foo", true, true},
+		{"foo/", "", "a/foo", true, true},
+		{"foo/", "", "foo", false, false},
+		{"doc/frotz/", "", "doc/frotz", true, true},
+		{"doc/frotz/", "", "a/doc/frotz", true, false},
+		{"frotz/", "", "a/frotz", true, true},
+		{"**/foo", "", "foo", false, true},
+		{"**/foo", "", "a/b/foo", false, true},
+		{"**/foo/bar", "", "a/foo/bar", false, true},
+		{"**/foo/bar", "", "foo/bar", false, true},
+		{"abc/**", "", "abc/x/y", false, true},
+		{"abc/**", "", "abc", true, false},
+		{"a/**/b", "", "a/b", false, true},
+		{"a/**/b", "", "a/x/b", false, true},
+		{"a/**/b", "", "a/x/y/b", false, true},
+		{"a/**/b", "", "a/x/c", false, false},
+		{"doc/*.txt", "", "doc/notes.txt", false, true},
+		{"doc/*.txt", "", "doc/server/arch.txt", false, false},
+		{"foo/*", "", "foo/test.json", false, true},
+		{"foo/*", "", "foo/bar", true, true},
+		{"foo/*", "", "foo/bar/hello.c", false, false},
+		// The wildcards.
+		{"*.sol", "", "src/Vault.sol", false, true},
+		{"*.sol", "", "src/Vault.t.sol.bak", false, false},
+		{"Vault?.sol", "", "Vault2.sol", false, true},
+		{"Vault?.sol", "", "Vault.sol", false, false},
+		{"*", "", "a/b", false, true},
+		{"[Vv]ault.sol", "", "vault.sol", false, true},
+		{"[a-c]*.sol", "", "Bank.sol", false, false},
+		{"[a-c]*.sol", "", "bank.sol", false, true},
+		{"[!a-c]*.sol", "", "bank.sol", false, false},
+		{"[^a-c]*.sol", "", "dex.sol", false, true},
+		{"[]]", "", "]", false, true},
+		{"[", "", "[", false, true},
+		{"\\*.sol", "", "*.sol", false, true},
+		{"\\*.sol", "", "a.sol", false, false},
+		{"\\#notes", "", "#notes", false, true},
+		{"\\!keep", "", "!keep", false, true},
+		{"trailing\\ ", "", "trailing ", false, true},
+		{"trailing  ", "", "trailing", false, true},
+		{"**.sol", "", "src/Vault.sol", false, true},
+		// The patterns of the nested files are relative to their directory.
+		{"*.sol", "lib/forge-std", "lib/forge-std/src/Test.sol", false, true},
+		{"*.sol", "lib/forge-std", "src/Test.sol", false, false},
+		{"/out", "lib/forge-std", "lib/forge-std/out", true, true},
+		{"/out", "lib/forge-std", "lib/forge-std/src/out", true, false},
+		{"/out", "lib/forge-std", "out", true, false},
+	}
+	for _, tt := range tests {
+		p, ok := ParseLine(tt.pattern, tt.base)
+		if !ok {
+			t.Fatalf("Expected pattern %q to parse", tt.pattern)
+		}
+		if got := p.Match(tt.path, tt.isDir); got != tt.expected {
+			t.Errorf("Expected %q in %q to match %q (dir %v): %v, got %v", tt.pattern, tt.base, tt.path, tt.isDir, tt.expected, got)
+		}
+	}
+}
+
+func Test_ParseSkipsCommentsAndBlankLines(t *testing.T) {
+	patterns := Parse("# build output\n\nout/\n   \n!out/keep.sol\r\n\\#literal\n", "")
+	if len(patterns) != 3 {
+		t.Fatalf("Expected 3 patterns, got %d", len(patterns))
+	}
+	if !patterns[0].dirOnly || patterns[0].negate {
+		t.Errorf("Expected out/ to be a directory pattern, got %+v", patterns[0])
+	}
+	if !patterns[1].negate {
+		t.Errorf("Expected !out/keep.sol to be negated, got %+v", patterns[1])
+	}
+	if patterns[2].negate || patterns[2].segments[1] != "#literal" {
+		t.Errorf("Expected the escaped #literal, got %+v", patterns[2])
+	}
+}
+
+func Test_MatcherIgnored(t *testing.T) {
+	m := &Matcher{}
+	m.Add(Parse("*.log\n/cache\n", "")...)
+	m.Add(Parse("out/\n*.sol\n!Keep.sol\n!important.log\n", "")...)
+	m.Add(Parse("!Flat.sol\n", "out")...)
+	m.Add(Parse("/*\n!/foo\n/foo/*\n!/foo/bar\n", "only")...)
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"debug.log", false, true},
+		{"important.log", false, false},
+		{"logs/important.log", false, false},
+		{"cache", true, true},
+		{"cache/solidity-files-cache.json", false, true},
+		{"src/cache", true, false},
+		{"src/Vault.sol", false, true},
+		{"src/Keep.sol", false, false},
+		{"src", true, false},
+		// A file can't be re-included if its directory is excluded.
+		{"out", true, true},
+		{"out/Flat.sol", false, true},
+		// The example of gitignore(5) excluding everything except foo/bar.
+		{"only/foo", true, false},
+		{"only/foo/bar", true, false},
+		{"only/foo/baz", true, true},
+		{"only/other", true, true},
+	}
+	for _, tt := range tests {
+		if got := m.Ignored(tt.path, tt.isDir); got != tt.expected {
+			t.Errorf("Expected %q to be ignored: %v, got %v", tt.path, tt.expected, got)
+		}
+	}
+}
+
+func Test_MatcherMatch(t *testing.T) {
+	m := &Matcher{}
+	m.Add(Parse("*.sol\n!Keep.sol\n", "")...)
+	tests := []struct {
+		path            string
+		ignored, result bool
+	}{
+		{"Vault.sol", true, true},
+		{"Keep.sol", false, true},
+		{"README.md", false, false},
+	}
+	for _, tt := range tests {
+		ignored, matched := m.Match(tt.path, false)
+		if ignored != tt.ignored || matched != tt.result {
+			t.Errorf("Expected %q to be (%v, %v), got (%v, %v)", tt.path, tt.ignored, tt.result, ignored, matched)
+		}
+	}
+}
+
+func Test_MayMatchUnder(t *testing.T) {
+	m := &Matcher{}
+	m.Add(Parse("out/Flat.sol\n/lib/**/src/*.sol\n!/broadcast/*.sol\n", "")...)
+	m.Add(Parse("Keep.sol\n", "cache/nested")...)
+	tests := []struct {
+		dir      string
+		expected bool
+	}{
+		{"out", true},
+		{"out/sub", false},
+		{"lib", true},
+		{"lib/forge-std/src", true},
+		{"cache", true},
+		{"cache/nested", true},
+		{"cache/other", false},
+		{"broadcast", false},
+	}
+	for _, tt := range tests {
+		if got := m.MayMatchUnder(tt.dir); got != tt.expected {
+			t.Errorf("Expected the patterns to maybe match under %q: %v, got %v", tt.dir, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package corpus parses the corpus of real-world contracts in testdata and
+// compares the parse errors with the manifest of the expected ones, so that
+// both the regressions and the fixes of the parser show up. The manifest is
+// regenerated with `go run ./internal/corpusreport`.
+package corpus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"solbot/parser"
+	"solbot/token"
+	"strconv"
+	"strings"
+)
+
+const (
+	Dir      = "testdata/corpus"       // the contracts, relative to the package
+	Manifest = "testdata/manifest.txt" // the expected errors, relative to the package
+)
+
+// Result is the outcome of parsing a file of the corpus.
+type Result struct {
+	Path   string         // slash-separated, relative to the corpus directory
+	Errors map[string]int // error code -> count
+	Panic  string         // the recovered panic, or empty
+}
+
+// Clean reports whether the file parsed without errors.
+func (r Result) Clean() bool {
+	return len(r.Errors) == 0 && r.Panic == ""
+}
+
+// ErrorCode returns the code of the parse error: its message without the
+// offset e.g. "unexpected token: throw". It's stable across the edits of
+// the file which move the error.
+func ErrorCode(err parser.Error) string {
+	return err.Message()
+}
+
+// Parse parses the .sol files under the directory, in the order of their
+// paths.
+func Parse(dir string) ([]Result, error) {
+	res := []Result{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".sol" {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		res = append(res, parseFile(filepath.ToSlash(rel), string(src)))
+		return nil
+	})
+	return res, err
+}
+
+func parseFile(path, src string) (res Result) {
+	res = Result{Path: path, Errors: map[string]int{}}
+	defer func() {
+		if r := recover(); r != nil {
+			res.Panic = fmt.Sprint(r)
+		}
+	}()
+	p := parser.Parser{}
+	p.Init(token.NewFile(path, src))
+	p.ParseFile()
+	for _, err := range p.Errors() {
+		res.Errors[ErrorCode(err)]++
+	}
+	return res
+}
+
+// Expected maps the paths of the files to the counts of their errors by the
+// code. The clean files map to an empty map.
+type Expected map[string]map[string]int
+
+// ExpectedOf returns the errors of the results, as recorded in the manifest.
+func ExpectedOf(results []Result) Expected {
+	res := Expected{}
+	for _, r := range results {
+		res[r.Path] = r.Errors
+	}
+	return res
+}
+
+// ReadManifest reads the manifest written by WriteManifest.
+func ReadManifest(r io.Reader) (Expected, error) {
+	res := Expected{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, "\t", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected the path and the count of the errors", line)
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil || count < 0 || count > 0 && len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected the count and the code of the error", line)
+		}
+		if res[fields[0]] == nil {
+			res[fields[0]] = map[string]int{}
+		}
+		if count > 0 {
+			res[fields[0]][fields[2]] += count
+		}
+	}
+	return res, scanner.Err()
+}
+
+// WriteManifest writes the expected errors, one line per file and code:
+//
+//	legacy/StandardToken.sol	2	unexpected token: throw
+//	openzeppelin/ERC20.sol	0
+//
+// The clean files have a single line with no errors, so that the new files
+// of the corpus have to be recorded too.
+func WriteManifest(w io.Writer, expected Expected) error {
+	b := &strings.Builder{}
+	b.WriteString("# The parse errors expected in the corpus: the path of the file, the count\n")
+	b.WriteString("# and the code of the error. Regenerate it with `go run ./internal/corpusreport`.\n")
+	for _, path := range sortedKeys(expected) {
+		if len(expected[path]) == 0 {
+			fmt.Fprintf(b, "%s\t0\n", path)
+			continue
+		}
+		for _, code := range sortedKeys(expected[path]) {
+			fmt.Fprintf(b, "%s\t%d\t%s\n", path, expected[path][code], code)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Compare returns the differences between the results and the expected
+// errors e.g. "legacy/StandardToken.sol: 3 × unexpected token: throw,
+// expected 2"; or nil if they match.
+func Compare(expected Expected, results []Result) []string {
+	res := []string{}
+	seen := map[string]bool{}
+	for _, r := range results {
+		seen[r.Path] = true
+		want, ok := expected[r.Path]
+		if !ok {
+			res = append(res, fmt.Sprintf("%s: not in the manifest", r.Path))
+			continue
+		}
+		codes := sortedKeys(r.Errors)
+		for _, code := range sortedKeys(want) {
+			if _, ok := r.Errors[code]; !ok {
+				codes = append(codes, code)
+			}
+		}
+		for _, code := range codes {
+			if got := r.Errors[code]; got != want[code] {
+				res = append(res, fmt.Sprintf("%s: %d × %s, expected %d", r.Path, got, code, want[code]))
+			}
+		}
+	}
+	for _, path := range sortedKeys(expected) {
+		if !seen[path] {
+			res = append(res, fmt.Sprintf("%s: in the manifest, but not in the corpus", path))
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package corpus
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_Corpus(t *testing.T) {
+	results, err := Parse(Dir)
+	if err != nil {
+		t.Fatalf("Expected the corpus to be read, got: %s", err)
+	}
+	if len(results) == 0 {
+		t.Fatalf("Expected the files of the corpus, got none.")
+	}
+	for _, r := range results {
+		if r.Panic != "" {
+			t.Errorf("Expected %s to parse without a panic, got: %s", r.Path, r.Panic)
+		}
+	}
+
+	f, err := os.Open(Manifest)
+	if err != nil {
+		t.Fatalf("Expected the manifest to be read, got: %s", err)
+	}
+	defer f.Close()
+	expected, err := ReadManifest(f)
+	if err != nil {
+		t.Fatalf("Expected a valid manifest, got: %s", err)
+	}
+	if diff := Compare(expected, results); diff != nil {
+		t.Errorf("Expected the parse errors recorded in %s, got:\n%s\n"+
+			"Fix the regressions, or record the changes with `go run ./internal/corpusreport`.", Manifest, strings.Join(diff, "\n"))
+	}
+}
+
+func Test_Compare(t *testing.T) {
+	manifest := "# comment\n" +
+		"a.sol\t0\n" +
+		"b.sol\t2\tunexpected token: throw\n" +
+		"gone.sol\t0\n"
+	expected, err := ReadManifest(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("Expected a valid manifest, got: %s", err)
+	}
+
+	results := []Result{
+		{Path: "a.sol", Errors: map[string]int{"no prefix parse function for ) found": 1}},
+		{Path: "b.sol", Errors: map[string]int{"unexpected token: throw": 1}},
+		{Path: "new.sol", Errors: map[string]int{}},
+	}
+	want := []string{
+		"a.sol: 1 × no prefix parse function for ) found, expected 0",
+		"b.sol: 1 × unexpected token: throw, expected 2",
+		"new.sol: not in the manifest",
+		"gone.sol: in the manifest, but not in the corpus",
+	}
+	if got := Compare(expected, results); !slices.Equal(got, want) {
+		t.Fatalf("Expected the differences:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	b := &strings.Builder{}
+	if err := WriteManifest(b, ExpectedOf(results)); err != nil {
+		t.Fatalf("Expected the manifest to be written, got: %s", err)
+	}
+	written, err := ReadManifest(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("Expected the written manifest to be valid, got: %s", err)
+	}
+	if diff := Compare(written, results); diff != nil {
+		t.Errorf("Expected the written manifest to match the results, got:\n%s", strings.Join(diff, "\n"))
+	}
+}
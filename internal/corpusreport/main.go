@@ -0,0 +1,89 @@
+// Corpusreport parses the corpus of real-world contracts, regenerates the
+// manifest of the expected parse errors and prints the summary:
+//
+//	go run ./internal/corpusreport
+//
+// Run it from the root of the module after a change of the parser fixed or
+// introduced errors, and review the diff of the manifest.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"solbot/internal/corpus"
+)
+
+func main() {
+	pkg := flag.String("dir", filepath.Join("internal", "corpus"), "directory of the corpus package")
+	top := flag.Int("top", 10, "number of the most frequent error codes to print")
+	dryRun := flag.Bool("n", false, "print the summary without writing the manifest")
+	flag.Parse()
+
+	results, err := corpus.Parse(filepath.Join(*pkg, corpus.Dir))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if !*dryRun {
+		b := &bytes.Buffer{}
+		if err := corpus.WriteManifest(b, corpus.ExpectedOf(results)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(filepath.Join(*pkg, corpus.Manifest), b.Bytes(), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	printSummary(results, *top)
+}
+
+// printSummary prints the share of the files parsing clean, the panics and
+// the most frequent error codes, by the number of errors and files.
+func printSummary(results []corpus.Result, top int) {
+	clean, panics := 0, 0
+	errors, files := map[string]int{}, map[string]int{}
+	for _, r := range results {
+		if r.Clean() {
+			clean++
+		}
+		if r.Panic != "" {
+			panics++
+			fmt.Printf("panic: %s: %s\n", r.Path, r.Panic)
+		}
+		for code, count := range r.Errors {
+			errors[code] += count
+			files[code]++
+		}
+	}
+	percent := 0.0
+	if len(results) > 0 {
+		percent = 100 * float64(clean) / float64(len(results))
+	}
+	fmt.Printf("%d files, %d parse clean (%.1f%%), %d panics\n", len(results), clean, percent, panics)
+
+	codes := make([]string, 0, len(errors))
+	for code := range errors {
+		codes = append(codes, code)
+	}
+	slices.SortFunc(codes, func(a, b string) int {
+		if errors[a] != errors[b] {
+			return errors[b] - errors[a]
+		}
+		if a < b {
+			return -1
+		}
+		return 1
+	})
+	if len(codes) == 0 {
+		return
+	}
+	fmt.Println("top error codes:")
+	for _, code := range codes[:min(top, len(codes))] {
+		fmt.Printf("%6d in %3d files  %s\n", errors[code], files[code], code)
+	}
+}
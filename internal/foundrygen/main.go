@@ -0,0 +1,148 @@
+// Foundrygen generates the table of the Foundry cheatcodes and the console
+// logging functions built into the language server from the manifest:
+//
+//	go generate ./lsp/analysis
+//
+// Update the manifest, lsp/analysis/foundry.json, for the new releases of
+// forge-std and run it again; the generated file is checked in.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"slices"
+	"strings"
+)
+
+// library is a contract of forge-std in the manifest.
+type library struct {
+	Name       string     `json:"name"`       // e.g. "Vm"
+	Receivers  []string   `json:"receivers"`  // names the functions are called on e.g. ["vm"]
+	Contracts  []string   `json:"contracts"`  // names of the real declarations e.g. ["Vm", "VmSafe"]
+	Visibility string     `json:"visibility"` // "external" or "internal"
+	Functions  []function `json:"functions"`
+}
+
+// function is a function of the library, an entry for every overload.
+type function struct {
+	Name       string   `json:"name"`
+	Params     []string `json:"params"`     // e.g. ["address msgSender"]
+	Returns    []string `json:"returns"`    // e.g. ["uint256 forkId"]
+	Mutability string   `json:"mutability"` // "pure", "view" or empty
+	Doc        string   `json:"doc"`        // one sentence
+}
+
+func main() {
+	in := flag.String("in", "foundry.json", "manifest of the libraries")
+	out := flag.String("out", "foundry_gen.go", "generated Go file")
+	pkg := flag.String("package", "analysis", "package of the generated file")
+	flag.Parse()
+
+	src, err := os.ReadFile(*in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	libraries := []library{}
+	if err := json.Unmarshal(src, &libraries); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *in, err)
+		os.Exit(1)
+	}
+	code, err := generate(libraries, *in, *pkg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *in, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, code, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the formatted source of the table. The functions are
+// sorted by name, the overloads in the order of the manifest.
+func generate(libraries []library, manifest, pkg string) ([]byte, error) {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// Code generated by foundrygen from %s; DO NOT EDIT.\n\n", manifest)
+	fmt.Fprintf(b, "package %s\n\n", pkg)
+	fmt.Fprintf(b, "// foundryLibraries are the cheatcodes and the logging functions of forge-std, see %s.\n", manifest)
+	b.WriteString("var foundryLibraries = []foundryLibrary{\n")
+	for _, lib := range libraries {
+		if err := validate(lib); err != nil {
+			return nil, err
+		}
+		slices.SortStableFunc(lib.Functions, func(a, b function) int { return strings.Compare(a.Name, b.Name) })
+		b.WriteString("{\n")
+		fmt.Fprintf(b, "name: %q,\n", lib.Name)
+		fmt.Fprintf(b, "receivers: %s,\n", stringSlice(lib.Receivers))
+		fmt.Fprintf(b, "contracts: %s,\n", stringSlice(lib.Contracts))
+		fmt.Fprintf(b, "visibility: %q,\n", lib.Visibility)
+		b.WriteString("functions: []foundryFunction{\n")
+		for _, fn := range lib.Functions {
+			fmt.Fprintf(b, "{name: %q", fn.Name)
+			if len(fn.Params) > 0 {
+				fmt.Fprintf(b, ", params: %s", stringSlice(fn.Params))
+			}
+			if len(fn.Returns) > 0 {
+				fmt.Fprintf(b, ", returns: %s", stringSlice(fn.Returns))
+			}
+			if fn.Mutability != "" {
+				fmt.Fprintf(b, ", mutability: %q", fn.Mutability)
+			}
+			fmt.Fprintf(b, ", doc: %q},\n", fn.Doc)
+		}
+		b.WriteString("},\n},\n")
+	}
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
+
+// validate checks that the library is complete and its overloads differ.
+func validate(lib library) error {
+	if lib.Name == "" || len(lib.Receivers) == 0 || len(lib.Contracts) == 0 {
+		return fmt.Errorf("library %q needs the name, the receivers and the contracts", lib.Name)
+	}
+	if lib.Visibility != "external" && lib.Visibility != "internal" {
+		return fmt.Errorf("library %s: invalid visibility %q", lib.Name, lib.Visibility)
+	}
+	signatures := map[string]bool{}
+	for _, fn := range lib.Functions {
+		if fn.Name == "" || fn.Doc == "" {
+			return fmt.Errorf("library %s: function %q needs the name and the doc", lib.Name, fn.Name)
+		}
+		switch fn.Mutability {
+		case "", "pure", "view":
+		default:
+			return fmt.Errorf("%s.%s: invalid mutability %q", lib.Name, fn.Name, fn.Mutability)
+		}
+		types := []string{}
+		for _, param := range append(slices.Clip(fn.Params), fn.Returns...) {
+			fields := strings.Fields(param)
+			if len(fields) < 2 || len(fields) > 3 {
+				return fmt.Errorf("%s.%s: expected the type and the name of the parameter, got %q", lib.Name, fn.Name, param)
+			}
+		}
+		for _, param := range fn.Params {
+			types = append(types, strings.Fields(param)[0])
+		}
+		signature := fn.Name + "(" + strings.Join(types, ",") + ")"
+		if signatures[signature] {
+			return fmt.Errorf("library %s: duplicate function %s", lib.Name, signature)
+		}
+		signatures[signature] = true
+	}
+	return nil
+}
+
+// stringSlice returns the Go literal of the strings.
+func stringSlice(list []string) string {
+	quoted := []string{}
+	for _, s := range list {
+		quoted = append(quoted, fmt.Sprintf("%q", s))
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
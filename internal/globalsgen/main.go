@@ -0,0 +1,128 @@
+// Globalsgen generates the table of the members of the builtin globals
+// `block`, `msg` and `tx`, and of the global names like `now`, from the
+// manifest:
+//
+//	go generate ./lsp/analysis
+//
+// The members of a global are listed in the manifest,
+// lsp/analysis/globals.json, from the most used one in the real-world
+// contracts to the least used, which ranks their completions. Add the
+// members of the new compiler releases there and run it again; the
+// generated file is checked in.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"solbot/semver"
+	"strings"
+)
+
+// global is a member of a builtin global e.g. "block.timestamp", or a
+// global name e.g. "now", in the manifest.
+type global struct {
+	Name        string `json:"name"`        // e.g. "block.timestamp" or "now"
+	Type        string `json:"type"`        // e.g. "uint256"
+	Doc         string `json:"doc"`         // one sentence
+	Since       string `json:"since"`       // the version introducing it e.g. "0.8.18"; or empty
+	Deprecated  string `json:"deprecated"`  // the version deprecating it; or empty
+	Removed     string `json:"removed"`     // the version removing it; or empty
+	Replacement string `json:"replacement"` // the code to use instead e.g. "block.prevrandao"
+}
+
+func main() {
+	in := flag.String("in", "globals.json", "manifest of the globals")
+	out := flag.String("out", "globals_gen.go", "generated Go file")
+	pkg := flag.String("package", "analysis", "package of the generated file")
+	flag.Parse()
+
+	src, err := os.ReadFile(*in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	globals := []global{}
+	if err := json.Unmarshal(src, &globals); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *in, err)
+		os.Exit(1)
+	}
+	code, err := generate(globals, *in, *pkg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *in, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, code, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the formatted source of the table, in the order of the
+// manifest. The rank of a member is its index among the members of the
+// same global.
+func generate(globals []global, manifest, pkg string) ([]byte, error) {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// Code generated by globalsgen from %s; DO NOT EDIT.\n\n", manifest)
+	fmt.Fprintf(b, "package %s\n\n", pkg)
+	fmt.Fprintf(b, "// builtinGlobals are the members of the builtin globals and the global names, see %s.\n", manifest)
+	b.WriteString("var builtinGlobals = []builtinGlobal{\n")
+	ranks := map[string]int{}
+	seen := map[string]bool{}
+	for _, g := range globals {
+		if err := validate(g); err != nil {
+			return nil, err
+		}
+		if seen[g.Name] {
+			return nil, fmt.Errorf("duplicate global %s", g.Name)
+		}
+		seen[g.Name] = true
+		receiver, name, ok := strings.Cut(g.Name, ".")
+		if !ok {
+			receiver, name = "", g.Name
+		}
+		fmt.Fprintf(b, "{receiver: %q, name: %q, typ: %q, rank: %d", receiver, name, g.Type, ranks[receiver])
+		ranks[receiver]++
+		for _, field := range []struct{ key, value string }{
+			{"since", g.Since}, {"deprecated", g.Deprecated}, {"removed", g.Removed}, {"replacement", g.Replacement},
+		} {
+			if field.value != "" {
+				fmt.Fprintf(b, ", %s: %q", field.key, field.value)
+			}
+		}
+		fmt.Fprintf(b, ", doc: %q},\n", g.Doc)
+	}
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
+
+// validate checks that the global is complete, its versions are valid and
+// in order, and the deprecated or removed ones have a replacement.
+func validate(g global) error {
+	if g.Name == "" || g.Type == "" || g.Doc == "" {
+		return fmt.Errorf("global %q needs the name, the type and the doc", g.Name)
+	}
+	versions := []semver.Version{}
+	for _, v := range []string{g.Since, g.Deprecated, g.Removed} {
+		if v == "" {
+			continue
+		}
+		parsed, err := semver.Parse(v)
+		if err != nil {
+			return fmt.Errorf("%s: %s", g.Name, err)
+		}
+		versions = append(versions, parsed)
+	}
+	for i := 1; i < len(versions); i++ {
+		if versions[i].Less(versions[i-1]) {
+			return fmt.Errorf("%s: the versions are out of order", g.Name)
+		}
+	}
+	if (g.Deprecated != "" || g.Removed != "") != (g.Replacement != "") {
+		return fmt.Errorf("%s: a replacement is needed exactly for the deprecated and the removed globals", g.Name)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package keccak
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func Test_Sum256(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"transfer(address,uint256)", "a9059cbb2ab09eb219583f4a59a5d0623ade346d962bcd4e46b11da047c9049b"},
+		{"The quick brown fox jumps over the lazy dog", "4d741b6f1eb29cb2a9b9911c82f56fa8d73b04959d3d9d222895df6c0b28aa15"},
+		// Longer than a block.
+		{strings.Repeat("a", 200), "96ea54061def936c4be90b518992fdc6f12f535068a256229aca54267b4d084d"},
+	}
+	for _, tt := range tests {
+		sum := Sum256([]byte(tt.input))
+		if got := hex.EncodeToString(sum[:]); got != tt.expected {
+			t.Errorf("Expected %s for %q, got %s", tt.expected, tt.input, got)
+		}
+	}
+}
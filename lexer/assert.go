@@ -0,0 +1,8 @@
+//go:build solbot_assert
+
+package lexer
+
+// assertions enables the checks of the invariants of the state functions,
+// see checkEmit, checkBackup and checkProgress. The tests of the state
+// functions run with `go test -tags solbot_assert`.
+const assertions = true
@@ -0,0 +1,61 @@
+package lexer
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"solbot/token"
+	"strings"
+)
+
+// The invariants of the state functions, checked when the lexer is built
+// with the assertions, see assertions. A broken one panics with the
+// offset where it broke, since a lexer breaking them either drops the
+// input or never reaches the end of it.
+
+// maxStalls limits the state transitions and the reads at the end of the
+// input in a row which don't advance the position. A few are expected
+// e.g. lexSourceUnit backing up before lexIdentifier, or a peek at the end
+// of the input, but a state function looping on them never ends.
+const maxStalls = 16
+
+// checkEmit panics if the token is empty. Only the EOF token is, the other
+// ones must have consumed some input; an empty one means that the state
+// function backed up over its only rune, or emitted twice.
+func (l *Lexer) checkEmit(typ token.TokenType) {
+	if l.start == l.pos && typ != token.EOF {
+		panic(fmt.Sprintf("lexer: emit of an empty %s token at offset %d", typ, l.pos))
+	}
+}
+
+// checkBackup panics if the lexer backs up twice without reading in
+// between. The lexer keeps the width of the last rune only, so the second
+// backup would step back by the width of the wrong rune.
+func (l *Lexer) checkBackup() {
+	if l.backedUp {
+		panic(fmt.Sprintf("lexer: backup twice without a read in between at offset %d", l.pos))
+	}
+	l.backedUp = true
+}
+
+// checkProgress panics if the position didn't advance for more than
+// maxStalls state transitions or reads at the end of the input in a row.
+func (l *Lexer) checkProgress() {
+	if l.pos != l.progressPos {
+		l.progressPos, l.stalls = l.pos, 0
+		return
+	}
+	l.stalls++
+	if l.stalls > maxStalls {
+		panic(fmt.Sprintf("lexer: %s made %d steps without advancing past offset %d", stateName(l.state), l.stalls, l.pos))
+	}
+}
+
+// stateName returns the name of the state function e.g. "lexNumber".
+func stateName(state stateFn) string {
+	if state == nil {
+		return "<nil>"
+	}
+	name := runtime.FuncForPC(reflect.ValueOf(state).Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
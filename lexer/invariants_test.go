@@ -0,0 +1,101 @@
+package lexer
+
+import (
+	"os"
+	"path/filepath"
+	"solbot/token"
+	"testing"
+)
+
+// expectPanic calls f and returns the message it panicked with.
+func expectPanic(t *testing.T, f func()) (msg string) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("Expected a panic, got none")
+		}
+		msg, _ = r.(string)
+	}()
+	f()
+	return ""
+}
+
+func Test_CheckEmitOfEmptyToken(t *testing.T) {
+	l := &Lexer{input: "x + y", start: 2, pos: 2}
+	msg := expectPanic(t, func() { l.checkEmit(token.ADD) })
+	expected := "lexer: emit of an empty + token at offset 2"
+	if msg != expected {
+		t.Errorf("Expected %q, got %q", expected, msg)
+	}
+
+	// The EOF token is the only empty one.
+	l = &Lexer{input: "x", start: 1, pos: 1}
+	l.checkEmit(token.EOF)
+}
+
+func Test_CheckBackupTwice(t *testing.T) {
+	l := &Lexer{input: "ab", pos: 2}
+	l.checkBackup()
+	msg := expectPanic(t, l.checkBackup)
+	expected := "lexer: backup twice without a read in between at offset 2"
+	if msg != expected {
+		t.Errorf("Expected %q, got %q", expected, msg)
+	}
+
+	// A read in between allows the next backup.
+	l.backedUp = false
+	l.checkBackup()
+}
+
+func Test_CheckProgressOfStuckState(t *testing.T) {
+	l := &Lexer{input: "1", pos: 1, progressPos: 1, state: lexNumber}
+	for i := 0; i < maxStalls; i++ {
+		l.checkProgress()
+	}
+	msg := expectPanic(t, l.checkProgress)
+	expected := "lexer: lexNumber made 17 steps without advancing past offset 1"
+	if msg != expected {
+		t.Errorf("Expected %q, got %q", expected, msg)
+	}
+
+	// Advancing the position starts the count again.
+	l = &Lexer{input: "12", pos: 1, progressPos: 1, state: lexNumber, stalls: maxStalls}
+	l.pos = 2
+	l.checkProgress()
+	if l.stalls != 0 || l.progressPos != 2 {
+		t.Errorf("Expected the stalls to be reset at offset 2, got %d stalls at offset %d", l.stalls, l.progressPos)
+	}
+}
+
+func Test_PeekNKeepsWidth(t *testing.T) {
+	// 'ü' is two bytes wide, the runes peeked after it are one byte wide.
+	l := &Lexer{input: "ü.5"}
+	l.readChar()
+	if r := l.peekN(2); r != '5' {
+		t.Fatalf("Expected to peek '5', got %q", r)
+	}
+	l.backup()
+	if l.pos != 0 {
+		t.Errorf("Expected the backup to step over 'ü' to offset 0, got %d", l.pos)
+	}
+}
+
+// BenchmarkLex lexes a large contract. The checks of the invariants are
+// constants in the normal builds, so there should be no difference from
+// the run with them compiled out; compare with the run of
+// `go test -tags solbot_assert -bench Lex` to see what they cost.
+func BenchmarkLex(b *testing.B) {
+	src, err := os.ReadFile(filepath.Join("..", "lsp", "analysis", "testdata", "contractsize", "Registry.sol"))
+	if err != nil {
+		b.Fatalf("Cannot read the fixture: %s", err)
+	}
+	file := token.NewFile("Registry.sol", string(src))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := Lex(file)
+		for l.NextToken().Type != token.EOF {
+		}
+	}
+}
@@ -24,6 +24,10 @@ type stateFn func(*Lexer) stateFn
 func (l *Lexer) run() {
 	// The initial state is lexSourceUnit. SourceUnit is basically a Solidity file.
 	for state := lexSourceUnit; state != nil; {
+		if assertions {
+			l.state = state
+			l.checkProgress()
+		}
 		state = state(l)
 	}
 	// The lexer is done, so we close the channel.
@@ -40,6 +44,12 @@ type Lexer struct {
 	pos    int              // Current position in the input.
 	width  int              // Width of last rune read from input.
 	tokens chan token.Token // Channel of scanned token.
+
+	// The state of the invariant checks, see assertions.
+	state       stateFn // state function being run
+	backedUp    bool    // did the lexer back up since the last read?
+	progressPos int     // position at the last step advancing it
+	stalls      int     // steps in a row not advancing the position
 }
 
 func Lex(file *token.File) *Lexer {
@@ -56,16 +66,20 @@ func Lex(file *token.File) *Lexer {
 }
 
 func (l *Lexer) NextToken() token.Token {
-	for {
-		select {
-		case tkn := <-l.tokens:
-			return tkn
-		}
+	tkn, ok := <-l.tokens
+	if !ok {
+		// The channel is closed after EOF or an error. Keep returning EOF,
+		// so that callers reading past the end can't loop forever.
+		return token.Token{Type: token.EOF, Pos: token.Pos(len(l.input))}
 	}
+	return tkn
 }
 
 // The `emit` function passes an token.Token back to the client.
 func (l *Lexer) emit(typ token.TokenType) {
+	if assertions {
+		l.checkEmit(typ)
+	}
 	// The value is a slice of the input.
 	l.tokens <- token.Token{
 		Type:    typ,
@@ -207,8 +221,14 @@ func lexNumber(l *Lexer) stateFn {
 
 	l.acceptRun(digits)
 
-	// @TODO: Fixed point numbers could go here. Solidity have them,
-	// but you can't use them yet, soooo...
+	// Decimal numbers can have a fractional part e.g. 0.5 ether. Fixed point
+	// types can't be used yet, but such literals are valid as long as the
+	// result is an integer. The period must be followed by a digit, otherwise
+	// it's a member access e.g. 1.foo().
+	if !hex && l.peek() == '.' && isDigit(l.peekN(2)) {
+		l.accept(".")
+		l.acceptRun("_0123456789")
+	}
 
 	// Does it have an exponent at the end? For example: 100e10 or 1000000e-3.
 	// Solidity allows both `e` and `E` as the exponent.
@@ -254,7 +274,17 @@ func lexMultiLineComment(l *Lexer) stateFn {
 	for {
 		switch char := l.readChar(); {
 		case char == eof:
-			return l.errorf("Unexpected EOF in multi-line comment")
+			// Reading the rest of the file as a comment would hide all of
+			// the declarations, so the lexing resumes where the comment
+			// most likely should have ended instead.
+			l.tokens <- token.Token{
+				Type:    token.ILLEGAL,
+				Literal: "comment not closed before end of file",
+				Pos:     token.Pos(l.start),
+			}
+			l.pos = unclosedCommentEnd(l.input, l.start)
+			l.start = l.pos
+			return lexSourceUnit
 		case char == '*':
 			if l.accept("/") {
 				l.emit(token.COMMENT_LITERAL)
@@ -264,24 +294,55 @@ func lexMultiLineComment(l *Lexer) stateFn {
 	}
 }
 
-func lexDoubleQuoteString(l *Lexer) stateFn {
-	for {
-		switch char := l.readChar(); {
-		case char == eof:
-			return l.errorf("Unexpected EOF in string literal")
-		case char == '"':
-			l.emit(token.STRING_LITERAL)
-			return lexSourceUnit
+// unclosedCommentEnd returns the offset of the first line after the one
+// opening the comment at the start that doesn't continue it with a star,
+// the way the lines of the block comments usually do; or the end of the
+// input if there is none.
+func unclosedCommentEnd(input string, start int) int {
+	lineEnd := strings.IndexByte(input[start:], '\n')
+	if lineEnd < 0 {
+		return len(input)
+	}
+	for pos := start + lineEnd + 1; pos < len(input); {
+		line, _, _ := strings.Cut(input[pos:], "\n")
+		if !strings.HasPrefix(strings.TrimSpace(line), "*") {
+			return pos
 		}
+		pos += len(line) + 1
 	}
+	return len(input)
+}
+
+func lexDoubleQuoteString(l *Lexer) stateFn {
+	return lexString(l, '"')
 }
 
 func lexSingleQuoteString(l *Lexer) stateFn {
+	return lexString(l, '\'')
+}
+
+// lexString lexes the string literal up to the closing quote. The string
+// literals can't span lines, so an unterminated one ends at the end of the
+// line with an illegal token, and the lexing resumes on the next line
+// instead of reading the rest of the file as a string.
+func lexString(l *Lexer, quote rune) stateFn {
 	for {
 		switch char := l.readChar(); {
-		case char == eof:
-			return l.errorf("Unexpected EOF in string literal")
-		case char == '\'':
+		case char == '\\':
+			// The escape sequences, including the escaped line breaks.
+			if l.readChar() == '\r' {
+				l.accept("\n")
+			}
+		case char == eof || char == '\n' || char == '\r':
+			l.backup()
+			l.tokens <- token.Token{
+				Type:    token.ILLEGAL,
+				Literal: "string literal not closed before end of line",
+				Pos:     token.Pos(l.start),
+			}
+			l.start = l.pos
+			return lexSourceUnit
+		case char == quote:
 			l.emit(token.STRING_LITERAL)
 			return lexSourceUnit
 		}
@@ -291,8 +352,14 @@ func lexSingleQuoteString(l *Lexer) stateFn {
 // readChar reads the next rune from the input, advances the position
 // and returns the rune.
 func (l *Lexer) readChar() rune {
+	if assertions {
+		l.backedUp = false
+	}
 	if l.pos >= len(l.input) {
 		l.width = 0
+		if assertions {
+			l.checkProgress()
+		}
 		return eof
 	}
 	r, w := utf8.DecodeRuneInString(l.input[l.pos:])
@@ -307,6 +374,9 @@ func (l *Lexer) ignore() {
 }
 
 func (l *Lexer) backup() {
+	if assertions {
+		l.checkBackup()
+	}
 	l.pos -= l.width
 }
 
@@ -316,6 +386,20 @@ func (l *Lexer) peek() rune {
 	return r
 }
 
+// peekN returns the n-th rune ahead without consuming any input.
+// peekN(1) is the same as peek(). The width of the last rune read is kept,
+// so that a backup after peekN steps back over the rune read before it,
+// not over the one peeked.
+func (l *Lexer) peekN(n int) rune {
+	pos, width, backedUp := l.pos, l.width, l.backedUp
+	r := rune(eof)
+	for i := 0; i < n; i++ {
+		r = l.readChar()
+	}
+	l.pos, l.width, l.backedUp = pos, width, backedUp
+	return r
+}
+
 // accept consumes the next rune if it's from the valid set. If not, it backs up.
 func (l *Lexer) accept(valid string) bool {
 	if strings.ContainsRune(valid, l.readChar()) {
@@ -396,8 +480,9 @@ func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
 
+// Solidity identifiers can contain the dollar sign as well e.g. $balance.
 func isLetter(ch rune) bool {
-	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_' || ch == '$'
 }
 
 func isWhitespace(ch rune) bool {
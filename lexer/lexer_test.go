@@ -255,3 +255,96 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func Test_UnterminatedLiterals(t *testing.T) {
+	input := "x = \"not closed);\ny = 'a\\'b';\n/* not closed\n * still the comment\nz;\n"
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     token.Pos
+	}{
+		{token.IDENTIFIER, "x", 0},
+		{token.ASSIGN, "=", 2},
+		{token.ILLEGAL, "string literal not closed before end of line", 4},
+		{token.IDENTIFIER, "y", 18},
+		{token.ASSIGN, "=", 20},
+		{token.STRING_LITERAL, "'a\\'b'", 22},
+		{token.SEMICOLON, ";", 28},
+		{token.ILLEGAL, "comment not closed before end of file", 30},
+		{token.IDENTIFIER, "z", 65},
+		{token.SEMICOLON, ";", 66},
+		{token.EOF, "", 68},
+	}
+
+	lexer := Lex(token.NewFile("test.sol", input))
+	for i, tt := range tests {
+		tkn := lexer.NextToken()
+		if tkn.Type != tt.expectedType || tkn.Literal != tt.expectedLiteral || tkn.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - expected %s %q at %d, got %s %q at %d", i,
+				token.Tokens[tt.expectedType], tt.expectedLiteral, tt.expectedPos,
+				token.Tokens[tkn.Type], tkn.Literal, tkn.Pos)
+		}
+	}
+}
+
+func Test_Comments(t *testing.T) {
+	input := "x; // ends with */ anyway\n/* spans\n   lines */ y; // at the end"
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     token.Pos
+	}{
+		{token.IDENTIFIER, "x", 0},
+		{token.SEMICOLON, ";", 1},
+		{token.COMMENT_LITERAL, "// ends with */ anyway", 3},
+		{token.COMMENT_LITERAL, "/* spans\n   lines */", 26},
+		{token.IDENTIFIER, "y", 47},
+		{token.SEMICOLON, ";", 48},
+		{token.COMMENT_LITERAL, "// at the end", 50},
+		{token.EOF, "", 63},
+	}
+
+	lexer := Lex(token.NewFile("test.sol", input))
+	for i, tt := range tests {
+		tkn := lexer.NextToken()
+		if tkn.Type != tt.expectedType || tkn.Literal != tt.expectedLiteral || tkn.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - expected %s %q at %d, got %s %q at %d", i,
+				token.Tokens[tt.expectedType], tt.expectedLiteral, tt.expectedPos,
+				token.Tokens[tkn.Type], tkn.Literal, tkn.Pos)
+		}
+	}
+}
+
+func Test_DocComments(t *testing.T) {
+	// The NatSpec comments are comment literals like the others, they're
+	// told apart by their prefixes where they're attached to declarations.
+	input := "/// @notice Deposits.\nfunction deposit() {}\n/** @dev Withdraws. */\nfunction withdraw() {}"
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     token.Pos
+	}{
+		{token.COMMENT_LITERAL, "/// @notice Deposits.", 0},
+		{token.FUNCTION, "function", 22},
+		{token.IDENTIFIER, "deposit", 31},
+		{token.LPAREN, "(", 38},
+		{token.RPAREN, ")", 39},
+		{token.LBRACE, "{", 41},
+		{token.RBRACE, "}", 42},
+		{token.COMMENT_LITERAL, "/** @dev Withdraws. */", 44},
+		{token.FUNCTION, "function", 67},
+	}
+
+	lexer := Lex(token.NewFile("test.sol", input))
+	for i, tt := range tests {
+		tkn := lexer.NextToken()
+		if tkn.Type != tt.expectedType || tkn.Literal != tt.expectedLiteral || tkn.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - expected %s %q at %d, got %s %q at %d", i,
+				token.Tokens[tt.expectedType], tt.expectedLiteral, tt.expectedPos,
+				token.Tokens[tkn.Type], tkn.Literal, tkn.Pos)
+		}
+		if input[tkn.Pos:int(tkn.Pos)+len(tkn.Literal)] != tkn.Literal {
+			t.Fatalf("tests[%d] - expected %q at the offset %d of the input", i, tkn.Literal, tkn.Pos)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"solbot/lsp/replay"
+	"solbot/lsp/server"
+	"sync"
+	"time"
+)
+
+// idleDelay is how long the server waits for the first message on a
+// terminal before explaining that it's not meant to be run by hand.
+var idleDelay = 3 * time.Second
+
+const idleMessage = "solbot: this is a language server; configure your editor to launch it, see `solbot lsp --help`\n"
+
+type lspOptions struct {
+	listen  string // TCP address; stdio if empty
+	logPath string // log file; logging is off if empty
+	trace   bool
+	limits  server.Limits
+	record  string // recording of the session; or empty
+	redact  bool   // hash the documents in the recording
+}
+
+// terminal tells whether the standard input is attached to a terminal. It's
+// replaced in the tests.
+type terminal interface {
+	IsTerminal(r io.Reader) bool
+}
+
+type charDevice struct{}
+
+func (charDevice) IsTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+var stdinTerminal terminal = charDevice{}
+
+// parseLspFlags parses the flags of the lsp command e.g.
+//
+//	solbot lsp --stdio
+//	solbot lsp --listen localhost:9257 --log solbot.log --trace
+//	solbot lsp --max-message-size 33554432 --max-document-size 0
+//	solbot lsp --record session.jsonl --redact
+//
+// It returns flag.ErrHelp if the usage was asked for.
+func parseLspFlags(args []string, stderr io.Writer) (lspOptions, error) {
+	opts := lspOptions{limits: server.DefaultLimits()}
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, "Usage: solbot lsp [--stdio | --listen addr] [--log path] [--trace] [--record path [--redact]] [limits]\n\n")
+		fs.PrintDefaults()
+	}
+	stdio := fs.Bool("stdio", false, "Communicate over stdin and stdout; the default")
+	fs.StringVar(&opts.listen, "listen", "", "Accept a single client on the TCP address e.g. localhost:9257")
+	fs.StringVar(&opts.logPath, "log", "log.txt", "Log file; logging is off if empty")
+	fs.BoolVar(&opts.trace, "trace", false, "Log the full content of the LSP messages")
+	fs.StringVar(&opts.record, "record", "", "Append the messages of the session to the file, see `solbot replay`")
+	fs.BoolVar(&opts.redact, "redact", false, "Hash the texts of the documents in the recording")
+	fs.IntVar(&opts.limits.MaxMessageSize, "max-message-size", opts.limits.MaxMessageSize,
+		"Size in bytes of the largest message; the larger ones are skipped, 0 for no limit")
+	fs.IntVar(&opts.limits.MaxDocumentSize, "max-document-size", opts.limits.MaxDocumentSize,
+		"Size in bytes above which a document is not analyzed, 0 for no limit")
+	fs.IntVar(&opts.limits.MaxParsedSize, "max-parsed-size", opts.limits.MaxParsedSize,
+		"Total size in bytes of the parsed documents kept in memory, 0 for no limit")
+	fs.IntVar(&opts.limits.MaxLineLength, "max-line-length", opts.limits.MaxLineLength,
+		"Length in bytes of the lines above which the inlay hints are skipped, 0 for no limit")
+	fs.DurationVar(&opts.limits.ReadTimeout, "read-timeout", opts.limits.ReadTimeout,
+		"How long the rest of a started message can take to arrive over TCP, 0 for no limit")
+	fs.IntVar(&opts.limits.Parser.MaxExpressionDepth, "max-expression-depth", opts.limits.Parser.MaxExpressionDepth,
+		"Depth of the nested expressions above which the rest of an expression is not parsed, 0 for no limit")
+	fs.IntVar(&opts.limits.Parser.MaxStatementDepth, "max-statement-depth", opts.limits.Parser.MaxStatementDepth,
+		"Depth of the nested statements above which the rest of a statement is not parsed, 0 for no limit")
+	fs.IntVar(&opts.limits.Parser.MaxDeclarations, "max-declarations", opts.limits.Parser.MaxDeclarations,
+		"Declarations of a file after which the rest of it is not parsed, 0 for no limit")
+	fs.IntVar(&opts.limits.Parser.MaxNodes, "max-nodes", opts.limits.Parser.MaxNodes,
+		"Declarations, statements and expressions of a file after which the rest of it is not parsed, 0 for no limit")
+	fs.DurationVar(&opts.limits.Parser.Timeout, "parse-timeout", opts.limits.Parser.Timeout,
+		"How long the parsing of a file can take before it's aborted, 0 for no limit")
+	// Passed by the VS Code language client next to --stdio.
+	fs.Int("clientProcessId", 0, "Process ID of the client; ignored")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "Unexpected argument: `%s`\n", fs.Arg(0))
+		fs.Usage()
+		return opts, errors.New("unexpected argument")
+	}
+	if opts.redact && opts.record == "" {
+		fmt.Fprintln(stderr, "The --redact flag needs --record")
+		fs.Usage()
+		return opts, errors.New("redact without record")
+	}
+	if *stdio && opts.listen != "" {
+		fmt.Fprintln(stderr, "The --stdio and --listen flags are mutually exclusive")
+		fs.Usage()
+		return opts, errors.New("conflicting transports")
+	}
+	return opts, nil
+}
+
+func startLanguageServer(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts, err := parseLspFlags(args, stderr)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+
+	logger, err := getLogger(opts.logPath, opts.trace)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error opening the log file: %s\n", err)
+		return 1
+	}
+	logger.Info("logger started", "version", version)
+
+	reader, writer := stdin, stdout
+	if opts.listen != "" {
+		ln, err := net.Listen("tcp", opts.listen)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error listening: %s\n", err)
+			return 1
+		}
+		fmt.Fprintf(stderr, "Listening on %s\n", ln.Addr())
+		conn, err := ln.Accept()
+		ln.Close()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error accepting the client: %s\n", err)
+			return 1
+		}
+		defer conn.Close()
+		reader, writer = conn, conn
+	} else if stdinTerminal.IsTerminal(stdin) {
+		reader = warnIfIdle(stdin, idleDelay, stderr)
+	}
+
+	srv := server.NewServer(writer, logger, opts.trace)
+	srv.SetLimits(opts.limits)
+	if opts.record != "" {
+		f, err := os.OpenFile(opts.record, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error opening the recording: %s\n", err)
+			return 1
+		}
+		defer f.Close()
+		srv.SetRecorder(replay.NewRecorder(f, opts.redact))
+	}
+	if err := srv.Serve(reader); err != nil {
+		logger.Error("cannot read the messages", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// warnIfIdle returns the reader printing the idle message to stderr if
+// nothing is read from r within the delay.
+func warnIfIdle(r io.Reader, delay time.Duration, stderr io.Writer) io.Reader {
+	fr := &firstRead{r: r, done: make(chan struct{})}
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-fr.done:
+		case <-timer.C:
+			fmt.Fprint(stderr, idleMessage)
+		}
+	}()
+	return fr
+}
+
+// firstRead closes done once the first bytes are read.
+type firstRead struct {
+	r    io.Reader
+	once sync.Once
+	done chan struct{}
+}
+
+func (f *firstRead) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if n > 0 || err != nil {
+		f.once.Do(func() { close(f.done) })
+	}
+	return n, err
+}
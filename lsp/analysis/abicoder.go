@@ -0,0 +1,167 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/migration"
+	"solbot/semver"
+	"solbot/token"
+)
+
+// abicoderDefaultVersion made the ABI coder v2 the default.
+var abicoderDefaultVersion = semver.MustParse("0.8.0")
+
+// abicoderDiagnostics checks the pragmas selecting the ABI coder. The
+// checks can be disabled one by one with their codes in the [detectors]
+// section:
+//
+//   - redundant-abicoder: `pragma experimental ABIEncoderV2;` or
+//     `pragma abicoder v2;` under a pragma requiring 0.8.0 or newer, where
+//     v2 is the default, see abicoderActions for the fix;
+//   - abicoder-v1-type: a parameter or a result of a function callable
+//     from the outside whose type only the v2 coder can encode, e.g. a
+//     struct or `string[]`, under `pragma abicoder v1;`, see
+//     needsABICoderV2;
+//   - conflicting-abicoder: a pragma selecting the other coder than an
+//     earlier one of the file.
+func (s *State) abicoderDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(r token.Range, severity lsp.DiagnosticSeverity, code, message string, related ...lsp.DiagnosticRelatedInformation) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		d := lsp.Diagnostic{
+			Range:              toLspRange(doc.Handle, r),
+			Severity:           severity,
+			Code:               code,
+			Source:             "solbot",
+			Message:            message,
+			RelatedInformation: related,
+		}
+		if code == "redundant-abicoder" {
+			d.Tags = []lsp.DiagnosticTag{lsp.TagUnnecessary}
+		}
+		res = append(res, d)
+	}
+	pointAt := func(p *ast.PragmaDirective, message string) lsp.DiagnosticRelatedInformation {
+		return lsp.DiagnosticRelatedInformation{
+			Location: lsp.Location{URI: doc.URI, Range: toLspRange(doc.Handle, ast.NodeRange(p))},
+			Message:  message,
+		}
+	}
+
+	var selected *ast.PragmaDirective
+	for _, p := range s.redundantAbicoderPragmas(doc) {
+		report(ast.NodeRange(p), lsp.SeverityInformation, "redundant-abicoder", "ABI coder v2 is the default since Solidity 0.8.0, the pragma can be removed")
+	}
+	for _, decl := range doc.File.Declarations {
+		p, ok := decl.(*ast.PragmaDirective)
+		if !ok {
+			continue
+		}
+		coder, ok := abicoderOf(p)
+		if !ok {
+			continue
+		}
+		if selected == nil {
+			selected = p
+			continue
+		}
+		if previous, _ := abicoderOf(selected); previous != coder {
+			report(ast.NodeRange(p), lsp.SeverityError, "conflicting-abicoder",
+				fmt.Sprintf("The pragma selects ABI coder %s, but an earlier one selects %s", coder, previous),
+				pointAt(selected, "ABI coder "+previous+" selected here"))
+		}
+	}
+	if selected == nil || selected.Kind != ast.AbicoderPragma || selected.Argument != "v1" {
+		return res
+	}
+
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok {
+			continue
+		}
+		for _, member := range c.Body {
+			fn, ok := member.(*ast.FunctionDeclaration)
+			if !ok || fn.Kind != token.FUNCTION || c.Kind != token.INTERFACE && fn.Type.Visibility != ast.External && fn.Type.Visibility != ast.Public {
+				continue
+			}
+			for _, list := range []*ast.ParamList{fn.Type.Params, fn.Type.Results} {
+				if list == nil {
+					continue
+				}
+				for _, param := range list.List {
+					// The storage references of the library functions are
+					// not encoded.
+					if param.Location == ast.Storage {
+						continue
+					}
+					canonical, ok := s.canonicalType(doc, param.Type, map[ast.Node]bool{})
+					if !ok || !needsABICoderV2(canonical) {
+						continue
+					}
+					report(ast.NodeRange(param), lsp.SeverityError, "abicoder-v1-type",
+						fmt.Sprintf("`%s` is only supported by ABI coder v2, but the file selects v1; use `pragma abicoder v2;`", ast.ExprString(param.Type)),
+						pointAt(selected, "ABI coder v1 selected here"))
+				}
+			}
+		}
+	}
+	return res
+}
+
+// abicoderOf returns the ABI coder the pragma selects, "v1" or "v2"; or
+// false if it's not a pragma of the coder.
+func abicoderOf(p *ast.PragmaDirective) (string, bool) {
+	switch {
+	case p.Kind == ast.AbicoderPragma && (p.Argument == "v1" || p.Argument == "v2"):
+		return p.Argument, true
+	case p.Kind == ast.ExperimentalPragma && p.Argument == "ABIEncoderV2":
+		return "v2", true
+	}
+	return "", false
+}
+
+// redundantAbicoderPragmas returns the pragmas selecting the ABI coder v2
+// in the document whose version pragma requires 0.8.0 or newer.
+func (s *State) redundantAbicoderPragmas(doc *Document) []*ast.PragmaDirective {
+	res := []*ast.PragmaDirective{}
+	if _, ok := pragma.Solidity(doc.File); !ok || pragma.AllowsBelow(doc.File, abicoderDefaultVersion) {
+		return res
+	}
+	for _, decl := range doc.File.Declarations {
+		if p, ok := decl.(*ast.PragmaDirective); ok {
+			if coder, ok := abicoderOf(p); ok && coder == "v2" {
+				res = append(res, p)
+			}
+		}
+	}
+	return res
+}
+
+// abicoderActions offers to remove the redundant pragmas of the ABI coder
+// reported by abicoderDiagnostics.
+func (s *State) abicoderActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	diagnostics := s.abicoderDiagnostics(doc)
+	for _, p := range s.redundantAbicoderPragmas(doc) {
+		r := toLspRange(doc.Handle, ast.NodeRange(p))
+		i := slices.IndexFunc(diagnostics, func(d lsp.Diagnostic) bool { return d.Code == "redundant-abicoder" && d.Range == r })
+		if i < 0 || !touches(selected, ast.NodeRange(p)) {
+			continue
+		}
+		d := diagnostics[i]
+		actions = append(actions, lsp.CodeAction{
+			Title:       "Remove the pragma",
+			Kind:        lsp.CodeActionQuickFix,
+			Diagnostics: []lsp.Diagnostic{d},
+			Edit:        migrationEdit(doc, migration.DeleteDirective(doc.Handle.Src(), p)),
+			IsPreferred: true,
+		})
+	}
+	return actions
+}
@@ -0,0 +1,157 @@
+package analysis
+
+import (
+	"context"
+	"solbot/lsp"
+	"testing"
+)
+
+func Test_RedundantAbicoderFix(t *testing.T) {
+	uri := "file:///ws/src/Registry.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, `// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+pragma experimental ABIEncoderV2;
+
+contract Registry {}
+`)
+
+	diagnostics := s.abicoderDiagnostics(s.Documents[uri])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "redundant-abicoder" || d.Severity != lsp.SeverityInformation || d.Range.Start.Line != 2 {
+		t.Errorf("Expected redundant-abicoder information at line 2, got %s %d at line %d", d.Code, d.Severity, d.Range.Start.Line)
+	}
+
+	doc := s.Documents[uri]
+	actions := s.CodeAction(lsp.IntID(2), uri, d.Range).Result
+	if len(actions) != 1 || actions[0].Title != "Remove the pragma" {
+		t.Fatalf("Expected the pragma to be removed, got %v", actions)
+	}
+	fixed := applyEdits(doc, actions[0].Edit.Changes[uri])
+	expected := `// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+contract Registry {}
+`
+	if fixed != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, fixed)
+	}
+	s.UpdateDocument(uri, 2, fixed)
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "redundant-abicoder" {
+			t.Errorf("Expected no diagnostic after the fix, got %q", d.Message)
+		}
+	}
+
+	// The pragma selects v2 for the compilers older than 0.8.0.
+	s.UpdateDocument(uri, 3, `pragma solidity >=0.7.0 <0.9.0;
+pragma experimental ABIEncoderV2;
+`)
+	if diagnostics := s.abicoderDiagnostics(s.Documents[uri]); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics when 0.7 is allowed, got %v", diagnostics)
+	}
+}
+
+func Test_AbicoderV1Type(t *testing.T) {
+	uri := "file:///ws/src/Registry.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, `pragma solidity ^0.7.6;
+pragma abicoder v1;
+
+contract Registry {
+    struct Entry {
+        address owner;
+        uint256 since;
+    }
+
+    mapping(address => Entry) entries;
+
+    function entry(address owner) external view returns (Entry memory) {
+        return entries[owner];
+    }
+
+    function owners(uint256[2][] calldata pages) external pure returns (uint256) {
+        return pages.length;
+    }
+
+    function names(string[] memory list) public pure returns (uint256) {
+        return list.length;
+    }
+
+    function load(Entry memory e) internal pure returns (Entry memory) {
+        return e;
+    }
+}
+`)
+
+	diagnostics := s.abicoderDiagnostics(s.Documents[uri])
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "abicoder-v1-type" || d.Severity != lsp.SeverityError {
+		t.Errorf("Expected an abicoder-v1-type error, got %s %d", d.Code, d.Severity)
+	}
+	expected := lsp.Range{Start: lsp.Position{Line: 11, Character: 57}, End: lsp.Position{Line: 11, Character: 62}}
+	if d.Range != expected {
+		t.Errorf("Expected the result `Entry` at %v, got %v", expected, d.Range)
+	}
+	if d.Message != "`Entry` is only supported by ABI coder v2, but the file selects v1; use `pragma abicoder v2;`" {
+		t.Errorf("Expected the message about `Entry`, got %q", d.Message)
+	}
+	pragma := lsp.Range{Start: lsp.Position{Line: 1, Character: 0}, End: lsp.Position{Line: 1, Character: 18}}
+	if len(d.RelatedInformation) != 1 || d.RelatedInformation[0].Location.URI != uri || d.RelatedInformation[0].Location.Range != pragma {
+		t.Errorf("Expected the related information at the pragma %v, got %v", pragma, d.RelatedInformation)
+	}
+	if d := diagnostics[1]; d.Range.Start.Line != 19 || d.Message != "`string[]` is only supported by ABI coder v2, but the file selects v1; use `pragma abicoder v2;`" {
+		t.Errorf("Expected the error on `string[]` at line 19, got %q at line %d", d.Message, d.Range.Start.Line)
+	}
+}
+
+func Test_ConflictingAbicoder(t *testing.T) {
+	uri := "file:///ws/src/Registry.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, `pragma solidity ^0.7.6;
+pragma abicoder v1;
+pragma experimental ABIEncoderV2;
+pragma abicoder v1;
+`)
+
+	diagnostics := s.abicoderDiagnostics(s.Documents[uri])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "conflicting-abicoder" || d.Severity != lsp.SeverityError || d.Range.Start.Line != 2 {
+		t.Errorf("Expected conflicting-abicoder error at line 2, got %s %d at line %d", d.Code, d.Severity, d.Range.Start.Line)
+	}
+	if len(d.RelatedInformation) != 1 || d.RelatedInformation[0].Location.Range.Start.Line != 1 {
+		t.Errorf("Expected the related information at the first pragma, got %v", d.RelatedInformation)
+	}
+}
+
+func Test_NeedsABICoderV2(t *testing.T) {
+	tests := []struct {
+		canonical string
+		expected  bool
+	}{
+		{"uint256", false},
+		{"string", false},
+		{"uint256[]", false},
+		{"uint256[2][]", false},
+		{"bytes32[][3]", true},
+		{"string[]", true},
+		{"bytes[2]", true},
+		{"uint256[][]", true},
+		{"(uint256,address)", true},
+		{"(uint256,bytes)[]", true},
+	}
+	for _, tt := range tests {
+		if got := needsABICoderV2(tt.canonical); got != tt.expected {
+			t.Errorf("Expected needsABICoderV2(%q) to be %t, got %t", tt.canonical, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package analysis
+
+import (
+	"solbot/access"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/metrics"
+	"solbot/token"
+)
+
+// FunctionAccess is an external or public function of a contract with its
+// guards.
+type FunctionAccess struct {
+	Contract  *Symbol                  // contract the function is called on
+	Doc       *Document                // document declaring the function, the one of a base for the inherited functions
+	Decl      *ast.FunctionDeclaration // function declaration
+	Signature string                   // e.g. "withdraw(uint256)" or "receive()"
+	Guards    []access.Guard           // ranges in Doc
+}
+
+// NameRange returns the range of the function name, or of the keyword for
+// the fallback and receive functions.
+func (f FunctionAccess) NameRange() token.Range {
+	return metrics.Function{Decl: f.Decl}.NameRange()
+}
+
+// Access returns the external and public functions of the contracts of the
+// document, the inherited ones included, with their guards. The modifiers
+// are resolved from the contract, so the overrides of the virtual ones are
+// followed. The interfaces and the libraries are left out.
+func (s *State) Access(doc *Document) []FunctionAccess {
+	res := []FunctionAccess{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Kind != token.CONTRACT {
+			continue
+		}
+		contract := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		resolve := func(c *ast.ContractDeclaration, name string) *ast.ModifierDeclaration {
+			sym := s.lookupMember(doc, c, name, map[*ast.ContractDeclaration]bool{})
+			if sym == nil {
+				return nil
+			}
+			mod, _ := sym.Node.(*ast.ModifierDeclaration)
+			return mod
+		}
+
+		// The bases that can't be resolved are left out.
+		linearized := s.linearize(contract)
+		if linearized == nil {
+			linearized = []*Symbol{contract}
+		}
+		seen := map[string]bool{}
+		for _, base := range linearized {
+			for _, member := range base.Node.(*ast.ContractDeclaration).Body {
+				fn, ok := member.(*ast.FunctionDeclaration)
+				if !ok || fn.Kind == token.CONSTRUCTOR || fn.Type.Visibility != ast.External && fn.Type.Visibility != ast.Public {
+					continue
+				}
+				signature := fn.Kind.String() + "()"
+				if fn.Kind == token.FUNCTION {
+					var ok bool
+					if signature, ok = s.functionSignature(base.Doc, fn); !ok {
+						signature = fn.Name.Name + "(...)"
+					}
+				}
+				if seen[signature] {
+					continue
+				}
+				seen[signature] = true
+				res = append(res, FunctionAccess{
+					Contract:  contract,
+					Doc:       base.Doc,
+					Decl:      fn,
+					Signature: signature,
+					Guards:    access.FunctionGuards(fn, c, resolve),
+				})
+			}
+		}
+	}
+	return res
+}
+
+// PathAccess returns the functions of the contracts of the documents in
+// the file or directory, see documentsUnder.
+func (s *State) PathAccess(path string) []FunctionAccess {
+	res := []FunctionAccess{}
+	for _, doc := range s.documentsUnder(path) {
+		res = append(res, s.Access(doc)...)
+	}
+	return res
+}
+
+// AccessReport returns the guards of the functions of the contracts of the
+// document with the totals of the categories.
+func (s *State) AccessReport(id lsp.ID, uri string) lsp.AccessReportResponse {
+	report := lsp.AccessReport{Functions: []lsp.AccessFunction{}, Totals: map[string]int{}}
+	doc, ok := s.document(uri)
+	if !ok {
+		return lsp.NewAccessReportResponse(id, report)
+	}
+	totals := access.Totals{}
+	for _, f := range s.Access(doc) {
+		totals.Add(f.Guards)
+		categories := []string{}
+		for _, kind := range access.Categories(f.Guards) {
+			categories = append(categories, string(kind))
+		}
+		guards := []lsp.AccessGuard{}
+		for _, g := range f.Guards {
+			guards = append(guards, lsp.AccessGuard{
+				Kind:     string(g.Kind),
+				Subject:  g.Subject,
+				Modifier: g.Modifier,
+				Location: lsp.Location{URI: f.Doc.URI, Range: toLspRange(f.Doc.Handle, g.Range)},
+			})
+		}
+		report.Functions = append(report.Functions, lsp.AccessFunction{
+			Contract:   f.Contract.Name.Name,
+			Function:   f.Signature,
+			Location:   lsp.Location{URI: f.Doc.URI, Range: toLspRange(f.Doc.Handle, f.NameRange())},
+			Categories: categories,
+			Guards:     guards,
+		})
+	}
+	for kind, n := range totals {
+		report.Totals[string(kind)] = n
+	}
+	return lsp.NewAccessReportResponse(id, report)
+}
@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func Test_AccessReport(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument("file:///ws/src/Base.sol", 1, `pragma solidity ^0.8.0;
+
+abstract contract Base {
+    address public operator;
+
+    modifier onlyOperator() virtual {
+        _;
+    }
+
+    function rebalance() external virtual onlyOperator {}
+
+    function skim() external onlyOperator {}
+}
+`)
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, `pragma solidity ^0.8.0;
+
+import {Base} from "./Base.sol";
+
+library Math {
+    function max(uint256 a, uint256 b) external pure returns (uint256) {}
+}
+
+contract Vault is Base {
+    modifier onlyOperator() override {
+        require(msg.sender == operator);
+        _;
+    }
+
+    function rebalance() external override {}
+
+    receive() external payable {}
+
+    function sync() internal {}
+}
+`)
+
+	report := s.AccessReport(lsp.IntID(1), "file:///ws/src/Vault.sol").Result
+	got := []string{}
+	for _, f := range report.Functions {
+		guards := []string{}
+		for _, g := range f.Guards {
+			guards = append(guards, fmt.Sprintf("%s %s via %s at %d:%d", g.Kind, g.Subject, g.Modifier, g.Location.Range.Start.Line, g.Location.Range.Start.Character))
+		}
+		got = append(got, fmt.Sprintf("%s.%s %s %v [%s]", f.Contract, f.Function, f.Location.URI, f.Categories, strings.Join(guards, "; ")))
+	}
+	// The overriding rebalance has no modifier, and the modifier of skim
+	// is the one overriding the base's.
+	expected := []string{
+		"Vault.rebalance() file:///ws/src/Vault.sol [unguarded] []",
+		"Vault.receive() file:///ws/src/Vault.sol [unguarded] []",
+		"Vault.skim() file:///ws/src/Base.sol [owner-only] [owner-only operator via onlyOperator at 11:29]",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if report.Totals["unguarded"] != 2 || report.Totals["owner-only"] != 1 {
+		t.Errorf("Expected 2 unguarded and 1 owner-only functions, got %v", report.Totals)
+	}
+}
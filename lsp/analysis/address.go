@@ -0,0 +1,286 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/semver"
+	"solbot/token"
+)
+
+var (
+	// payableConversionVersion is the first compiler with `payable(x)`.
+	payableConversionVersion = semver.MustParse("0.6.0")
+	// nonPayableSenderVersion is the first compiler where `msg.sender`,
+	// `tx.origin` and the conversions to `address` are not payable.
+	nonPayableSenderVersion = semver.MustParse("0.8.0")
+)
+
+// addressMember is a member of the address type.
+type addressMember struct {
+	header   string   // declaration shown in the hover e.g. "function send(uint256 amount) returns (bool)"
+	function bool     // is it called?
+	payable  bool     // is it a member of `address payable` only?
+	results  []string // type of the value, or the result types of the function
+}
+
+// addressMembers are the members of the address type, by their names.
+var addressMembers = map[string]addressMember{
+	"balance":      {header: "uint256 balance", results: []string{"uint256"}},
+	"code":         {header: "bytes memory code", results: []string{"bytes"}},
+	"codehash":     {header: "bytes32 codehash", results: []string{"bytes32"}},
+	"transfer":     {header: "function transfer(uint256 amount)", function: true, payable: true},
+	"send":         {header: "function send(uint256 amount) returns (bool)", function: true, payable: true, results: []string{"bool"}},
+	"call":         {header: "function call(bytes memory) payable returns (bool, bytes memory)", function: true, results: []string{"bool", "bytes"}},
+	"delegatecall": {header: "function delegatecall(bytes memory) returns (bool, bytes memory)", function: true, results: []string{"bool", "bytes"}},
+	"staticcall":   {header: "function staticcall(bytes memory) view returns (bool, bytes memory)", function: true, results: []string{"bool", "bytes"}},
+}
+
+// addressType reports whether the expression is an address, and whether
+// it's payable: a value declared as `address` or `address payable`, a
+// conversion with `address(x)` or `payable(x)`, or `msg.sender`,
+// `tx.origin` and `block.coinbase`. The senders and the conversions are not
+// payable since 0.8.0, or 0.6.0 for the conversions, so they're assumed
+// payable if the pragma allows the older compilers.
+func (s *State) addressType(doc *Document, path []ast.Node, x ast.Expression) (isAddress, payable bool) {
+	switch x := x.(type) {
+	case *ast.CallExpression:
+		switch fn := x.Function.(type) {
+		case *ast.Identifier:
+			if fn.Name == "payable" && len(x.Args) == 1 {
+				return true, true
+			}
+		case *ast.ElementaryType:
+			if fn.Value != "address" || len(x.Args) != 1 {
+				return false, false
+			}
+			return true, fn.Payable != 0 || pragma.AllowsBelow(doc.File, payableConversionVersion)
+		}
+	case *ast.MemberAccessExpression:
+		if global, ok := x.Expression.(*ast.Identifier); ok && s.lookup(doc, path, global.Name, global.Start()) == nil {
+			switch global.Name + "." + x.Member.Name {
+			case "msg.sender", "tx.origin":
+				return true, pragma.AllowsBelow(doc.File, nonPayableSenderVersion)
+			case "block.coinbase":
+				return true, true
+			}
+		}
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 && x.Elements[0] != nil {
+			return s.addressType(doc, path, x.Elements[0])
+		}
+		return false, false
+	}
+	_, t := s.typeOf(doc, path, x)
+	e, ok := t.(*ast.ElementaryType)
+	if !ok || e.Value != "address" {
+		return false, false
+	}
+	return true, e.Payable != 0
+}
+
+// addressMemberOf returns the member of the address accessed by the
+// expression e.g. `transfer` in `to.transfer`; or false if it's not an
+// address member, e.g. the contract declares a member of the same name.
+func (s *State) addressMemberOf(doc *Document, path []ast.Node, access *ast.MemberAccessExpression) (addressMember, bool) {
+	member, ok := addressMembers[access.Member.Name]
+	if !ok || s.resolveExpr(doc, path, access) != nil {
+		return addressMember{}, false
+	}
+	if isAddress, _ := s.addressType(doc, path, access.Expression); !isAddress {
+		return addressMember{}, false
+	}
+	return member, true
+}
+
+// addressCallResults returns the result types of the call of an address
+// member e.g. "bool" and "bytes" for `to.call{value: v}("")`; or false if
+// the call is not one.
+func (s *State) addressCallResults(doc *Document, path []ast.Node, call *ast.CallExpression) ([]string, bool) {
+	x := call.Function
+	if options, ok := x.(*ast.CallOptionsExpression); ok {
+		x = options.Expression
+	}
+	access, ok := x.(*ast.MemberAccessExpression)
+	if !ok {
+		return nil, false
+	}
+	member, ok := s.addressMemberOf(doc, path, access)
+	if !ok || !member.function {
+		return nil, false
+	}
+	return member.results, true
+}
+
+// addressDiagnostics checks the uses of the address members. The checks can
+// be disabled one by one with their codes in the [detectors] section:
+//
+//   - non-payable-transfer: `transfer` and `send` are called on an address
+//     which is not payable, which doesn't compile. The quick fix converts
+//     the receiver with `payable(...)`;
+//   - transfer-gas-stipend: since 0.8.0, `transfer` and `send` are flagged
+//     at all. They forward 2300 gas, which isn't enough for the receivers
+//     doing anything in their `receive` function e.g. the multisig wallets,
+//     and the gas costs change between the forks;
+//   - balance-invariant: `address(this).balance` is compared for equality,
+//     or with the state, like an accounting invariant. Ether can be sent
+//     to the contract without calling it, with selfdestruct or as the block
+//     reward, so the balance may exceed the recorded amounts.
+func (s *State) addressDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(node ast.Node, severity lsp.DiagnosticSeverity, code, message string) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(node)),
+			Severity: severity,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+
+	stipend := !pragma.AllowsBelow(doc.File, nonPayableSenderVersion)
+	ast.Inspect(doc.File, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpression:
+			access, ok := n.Function.(*ast.MemberAccessExpression)
+			if !ok || access.Member.Name != "transfer" && access.Member.Name != "send" || len(n.Args) != 1 {
+				return true
+			}
+			path := ast.PathEnclosingPos(doc.File, access.Member.Start())
+			if _, ok := s.addressMemberOf(doc, path, access); !ok {
+				return true
+			}
+			if _, payable := s.addressType(doc, path, access.Expression); !payable {
+				report(access.Member, lsp.SeverityError, "non-payable-transfer",
+					fmt.Sprintf("`%s` is only available on `address payable`, but `%s` is not payable; convert it with `payable(%s)`",
+						access.Member.Name, ast.ExprString(access.Expression), ast.ExprString(access.Expression)))
+			}
+			if stipend {
+				report(access.Member, lsp.SeverityInformation, "transfer-gas-stipend",
+					fmt.Sprintf("`%s` forwards a fixed stipend of 2300 gas, so it fails for the receivers doing anything in their `receive` function e.g. the multisig wallets; "+
+						"use `.call{value: amount}(\"\")`, check the returned bool and guard against the reentrancy", ast.ExprString(n.Function)))
+			}
+		case *ast.BinaryExpression:
+			switch n.Operator {
+			case token.EQUAL, token.NOT_EQUAL, token.LESS_THAN, token.LESS_THAN_OR_EQUAL, token.GREATER_THAN, token.GREATER_THAN_OR_EQUAL:
+			default:
+				return true
+			}
+			other := n.Right
+			if !isSelfBalance(n.Left) {
+				if !isSelfBalance(n.Right) {
+					return true
+				}
+				other = n.Left
+			}
+			if n.Operator != token.EQUAL && n.Operator != token.NOT_EQUAL && !s.readsState(doc, other) {
+				return true
+			}
+			report(n, lsp.SeverityInformation, "balance-invariant",
+				fmt.Sprintf("`%s` relies on the balance of the contract, but ether can be sent to it without calling it, with selfdestruct or as the block reward; "+
+					"track the deposited amount in a state variable", ast.ExprString(n)))
+		}
+		return true
+	})
+	return res
+}
+
+// addressActions offers to convert the receivers of `transfer` and `send`
+// reported by addressDiagnostics with `payable(...)`. The conversion needs
+// 0.6.0, so the files allowing the older compilers get no fix.
+func (s *State) addressActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	if pragma.AllowsBelow(doc.File, payableConversionVersion) {
+		return actions
+	}
+	for _, d := range s.addressDiagnostics(doc) {
+		if d.Code != "non-payable-transfer" {
+			continue
+		}
+		r := toTokenRange(doc.Handle, d.Range)
+		if !touches(selected, r) {
+			continue
+		}
+		access, ok := ast.PathEnclosingPos(doc.File, r.Start)[1].(*ast.MemberAccessExpression)
+		if !ok {
+			continue
+		}
+		receiver := ast.NodeRange(access.Expression)
+		actions = append(actions, lsp.CodeAction{
+			Title:       fmt.Sprintf("Convert `%s` with `payable(...)`", ast.ExprString(access.Expression)),
+			Kind:        lsp.CodeActionQuickFix,
+			Diagnostics: []lsp.Diagnostic{d},
+			Edit: &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+				doc.URI: {
+					{Range: toLspRange(doc.Handle, token.Range{Start: receiver.Start, End: receiver.Start}), NewText: "payable("},
+					{Range: toLspRange(doc.Handle, token.Range{Start: receiver.End, End: receiver.End}), NewText: ")"},
+				},
+			}},
+			IsPreferred: true,
+		})
+	}
+	return actions
+}
+
+// addressMemberHover shows the declaration of the address member at the
+// position e.g. `function send(uint256 amount) returns (bool)`; or returns
+// an empty string if there's none.
+func (s *State) addressMemberHover(uri string, position lsp.Position, markdown bool) string {
+	doc, ok := s.document(uri)
+	if !ok {
+		return ""
+	}
+	path := ast.PathEnclosingPos(doc.File, toTokenPos(doc.Handle, position))
+	if len(path) < 2 {
+		return ""
+	}
+	access, ok := path[1].(*ast.MemberAccessExpression)
+	if !ok || access.Member != path[0] {
+		return ""
+	}
+	member, ok := s.addressMemberOf(doc, path, access)
+	if !ok {
+		return ""
+	}
+	owner := "address"
+	if member.payable {
+		owner = "address payable"
+	}
+	if markdown {
+		return fmt.Sprintf("```solidity\n%s\n```\n\nmember of `%s`", member.header, owner)
+	}
+	return fmt.Sprintf("%s\n\nmember of %s", member.header, owner)
+}
+
+// isSelfBalance reports whether the expression is `address(this).balance`.
+func isSelfBalance(x ast.Expression) bool {
+	access, ok := x.(*ast.MemberAccessExpression)
+	if !ok || access.Member.Name != "balance" {
+		return false
+	}
+	call, ok := access.Expression.(*ast.CallExpression)
+	if !ok || len(call.Args) != 1 {
+		return false
+	}
+	conversion, ok := call.Function.(*ast.ElementaryType)
+	self, isIdent := call.Args[0].(*ast.Identifier)
+	return ok && conversion.Value == "address" && isIdent && self.Name == "this"
+}
+
+// readsState reports whether the expression reads the storage e.g.
+// `totalDeposits` or `deposits[user] + fees`.
+func (s *State) readsState(doc *Document, x ast.Expression) bool {
+	found := false
+	ast.Inspect(x, func(n ast.Node) bool {
+		if e, ok := n.(ast.Expression); ok && !found && s.isStorageRef(doc, e) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,189 @@
+package analysis
+
+import (
+	"context"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+const walletSrc = `pragma solidity %s;
+
+contract Wallet {
+    uint256 public deposited;
+
+    function pay(address to, address payable owner, uint256 amount) external {
+        to.transfer(amount);
+        bool sent = owner.send(amount);
+        (bool ok, ) = owner.call{value: amount}("");
+        require(sent && ok);
+        require(address(this).balance == deposited);
+        require(address(this).balance >= amount);
+        uint256 size = to.code.length;
+        bytes32 hash = to.codehash;
+        (ok, ) = to.delegatecall("");
+        (ok, ) = to.staticcall("");
+    }
+}
+`
+
+func walletDiagnostics(t *testing.T, s *State, version string) []lsp.Diagnostic {
+	t.Helper()
+	uri := "file:///ws/src/Wallet.sol"
+	s.OpenDocument(uri, 1, strings.Replace(walletSrc, "%s", version, 1))
+	return s.Diagnostics(context.Background(), uri).Params.Diagnostics
+}
+
+func Test_NonPayableTransfer(t *testing.T) {
+	s := NewState()
+	diagnostics := walletDiagnostics(t, s, "^0.8.0")
+	expected := []struct {
+		code string
+		line uint
+	}{
+		{"non-payable-transfer", 6},
+		{"transfer-gas-stipend", 6},
+		{"transfer-gas-stipend", 7},
+		{"balance-invariant", 10},
+		{"unused-variable", 12},
+		{"unused-variable", 13},
+		{"dead-store", 14},
+		{"dead-store", 15},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.Code != expected[i].code || d.Range.Start.Line != expected[i].line {
+			t.Errorf("Expected %s at line %d, got %s at line %d", expected[i].code, expected[i].line, d.Code, d.Range.Start.Line)
+		}
+	}
+	if d := diagnostics[0]; d.Severity != lsp.SeverityError ||
+		d.Message != "`transfer` is only available on `address payable`, but `to` is not payable; convert it with `payable(to)`" {
+		t.Errorf("Expected the error on `to.transfer`, got %d %q", d.Severity, d.Message)
+	}
+
+	doc := s.Documents["file:///ws/src/Wallet.sol"]
+	actions := s.CodeAction(lsp.IntID(2), doc.URI, diagnostics[0].Range).Result
+	if len(actions) != 1 || actions[0].Title != "Convert `to` with `payable(...)`" {
+		t.Fatalf("Expected the payable conversion, got %v", actions)
+	}
+	fixed := applyEdits(doc, actions[0].Edit.Changes[doc.URI])
+	if !strings.Contains(fixed, "        payable(to).transfer(amount);\n") {
+		t.Fatalf("Expected the receiver converted with payable, got:\n%s", fixed)
+	}
+	s.UpdateDocument(doc.URI, 2, fixed)
+	for _, d := range s.Diagnostics(context.Background(), doc.URI).Params.Diagnostics {
+		if d.Code == "non-payable-transfer" {
+			t.Errorf("Expected no error after the fix, got %q", d.Message)
+		}
+	}
+}
+
+func Test_TransferGasStipend(t *testing.T) {
+	s := NewState()
+	stipends := 0
+	for _, d := range walletDiagnostics(t, s, "^0.8.0") {
+		if d.Code == "transfer-gas-stipend" {
+			stipends++
+			if d.Severity != lsp.SeverityInformation {
+				t.Errorf("Expected an information, got %d", d.Severity)
+			}
+		}
+	}
+	if stipends != 2 {
+		t.Errorf("Expected the stipend of `transfer` and `send` on 0.8, got %d", stipends)
+	}
+
+	s = NewState()
+	for _, d := range walletDiagnostics(t, s, "^0.6.0") {
+		if d.Code == "transfer-gas-stipend" {
+			t.Errorf("Expected no stipend on 0.6, got %q", d.Message)
+		}
+	}
+}
+
+func Test_AddressMemberTypes(t *testing.T) {
+	s := NewState()
+	walletDiagnostics(t, s, "^0.8.0")
+	uri := "file:///ws/src/Wallet.sol"
+
+	for _, tt := range []struct {
+		position lsp.Position
+		header   string
+		owner    string
+	}{
+		{lsp.Position{Line: 6, Character: 12}, "function transfer(uint256 amount)", "address payable"},
+		{lsp.Position{Line: 7, Character: 27}, "function send(uint256 amount) returns (bool)", "address payable"},
+		{lsp.Position{Line: 8, Character: 29}, "function call(bytes memory) payable returns (bool, bytes memory)", "address"},
+		{lsp.Position{Line: 10, Character: 31}, "uint256 balance", "address"},
+		{lsp.Position{Line: 12, Character: 28}, "bytes memory code", "address"},
+		{lsp.Position{Line: 13, Character: 27}, "bytes32 codehash", "address"},
+		{lsp.Position{Line: 14, Character: 21}, "function delegatecall(bytes memory) returns (bool, bytes memory)", "address"},
+		{lsp.Position{Line: 15, Character: 21}, "function staticcall(bytes memory) view returns (bool, bytes memory)", "address"},
+	} {
+		expected := "```solidity\n" + tt.header + "\n```\n\nmember of `" + tt.owner + "`"
+		if hover := s.Hover(lsp.IntID(1), uri, tt.position).Result.Contents.Value; hover != expected {
+			t.Errorf("Expected the hover at %v:\n%s\ngot:\n%s", tt.position, expected, hover)
+		}
+	}
+
+	doc := s.Documents[uri]
+	for _, tt := range []struct {
+		expr, expected string
+	}{
+		{"to.code", "bytes"},
+		{"to.codehash", "bytes32"},
+		{"address(this).balance", "uint256"},
+		{"owner.send(amount)", "bool"},
+	} {
+		path := ast.PathEnclosingPos(doc.File, token.Pos(strings.Index(doc.Handle.Src(), tt.expr)))
+		got := ""
+		for _, n := range path {
+			if x, ok := n.(ast.Expression); ok && ast.ExprString(x) == tt.expr {
+				got = s.argumentType(doc, path, x)
+			}
+		}
+		if got != tt.expected {
+			t.Errorf("Expected %s to be %s, got %q", tt.expr, tt.expected, got)
+		}
+	}
+}
+
+func Test_DestructuringDiagnostics(t *testing.T) {
+	for _, tt := range []struct {
+		stmt     string
+		expected string
+	}{
+		{`(bool ok, ) = to.call{value: 1}("");`, ""},
+		{`(bool ok, bytes memory data) = to.staticcall("");`, ""},
+		{`(bool ok, uint256 x, ) = to.call("");`, "`to.call` returns 2 values, but the tuple has 3 components"},
+		{`(uint256 ok, ) = to.call("");`, "Component 1 of the tuple is `uint256`, but `to.call` returns `bool`"},
+		{`(uint8 a, uint256 b) = amounts();`, "Component 1 of the tuple is `uint8`, but `amounts` returns `uint256`"},
+		{`(uint256 a, ) = amounts();`, ""},
+	} {
+		s := NewState()
+		uri := "file:///ws/src/Split.sol"
+		s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+contract Split {
+    function amounts() internal pure returns (uint256, uint256) {
+        return (1, 2);
+    }
+
+    function run(address to) external {
+        `+tt.stmt+`
+    }
+}
+`)
+		diagnostics := s.destructuringDiagnostics(s.Documents[uri])
+		switch {
+		case tt.expected == "" && len(diagnostics) != 0:
+			t.Errorf("Expected no diagnostics for %s, got %q", tt.stmt, diagnostics[0].Message)
+		case tt.expected != "" && (len(diagnostics) != 1 || diagnostics[0].Message != tt.expected):
+			t.Errorf("Expected %q for %s, got %v", tt.expected, tt.stmt, diagnostics)
+		}
+	}
+}
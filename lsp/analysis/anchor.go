@@ -0,0 +1,238 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strconv"
+	"strings"
+)
+
+// The results of the analyses refer to the document by their positions,
+// which become stale as soon as the document is edited. Each declaration
+// gets an anchor: an ID derived from its kind, qualified name and signature,
+// that stays the same when the code around it moves. Together with the log
+// of the edits, the anchors let the server move the stale ranges to the
+// current version of the document, or drop them if their declaration is
+// gone.
+
+// maxEdits is the number of the edits kept in the log of a document. The
+// results computed for the older versions can't be moved anymore.
+const maxEdits = 64
+
+// edit replaces the Removed bytes at the Offset with the Inserted ones,
+// changing the document from version From to version To.
+type edit struct {
+	From, To int
+	Offset   int
+	Removed  int
+	Inserted int
+}
+
+// diffEdit returns the single edit changing the old text into the new one:
+// everything between their common prefix and suffix is replaced. The client
+// sends the full content on every change, so the edit covers at least the
+// changed bytes.
+func diffEdit(from, to int, old, new string) edit {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix &&
+		old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	return edit{
+		From:     from,
+		To:       to,
+		Offset:   prefix,
+		Removed:  len(old) - prefix - suffix,
+		Inserted: len(new) - prefix - suffix,
+	}
+}
+
+// logEdit appends the edit from the previous version of the document to
+// the log.
+func (doc *Document) logEdit(prev *Document) {
+	edits := prev.Edits
+	if len(edits) >= maxEdits {
+		edits = edits[len(edits)-maxEdits+1:]
+	}
+	e := diffEdit(prev.Version, doc.Version, prev.Handle.Src(), doc.Handle.Src())
+	doc.Edits = append(edits[:len(edits):len(edits)], e)
+}
+
+// translate moves the range in the given version of the document to the
+// current one. It returns false if some edit since then touched the range,
+// or the log doesn't reach back to the version.
+func (doc *Document) translate(version int, r token.Range) (token.Range, bool) {
+	if version == doc.Version {
+		return r, true
+	}
+	first := -1
+	for i, e := range doc.Edits {
+		if e.From == version {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return token.Range{}, false
+	}
+	for _, e := range doc.Edits[first:] {
+		switch {
+		case int(r.End) <= e.Offset:
+		case e.Offset+e.Removed <= int(r.Start):
+			delta := token.Pos(e.Inserted - e.Removed)
+			r = token.Range{Start: r.Start + delta, End: r.End + delta}
+		default:
+			return token.Range{}, false
+		}
+	}
+	return r, true
+}
+
+// anchors returns the ranges of the declarations of the file by their
+// anchors e.g. "function Vault.deposit(uint256,address)". Overloads differ
+// in their signatures, while the duplicates get the "#2", "#3" suffixes in
+// the order of their appearance.
+func anchors(file *ast.File) map[string]token.Range {
+	res := map[string]token.Range{}
+	next := map[string]int{} // anchor -> suffix of its next duplicate
+	add := func(id string, node ast.Node) {
+		unique := id
+		if _, ok := res[unique]; ok {
+			n := max(next[id], 2)
+			for ; ; n++ {
+				unique = id + "#" + strconv.Itoa(n)
+				if _, ok := res[unique]; !ok {
+					break
+				}
+			}
+			next[id] = n + 1
+		}
+		res[unique] = ast.NodeRange(node)
+	}
+
+	var visit func(decls []ast.Declaration, container string)
+	visit = func(decls []ast.Declaration, container string) {
+		for _, decl := range decls {
+			id := anchorID(decl, container)
+			if id == "" {
+				continue
+			}
+			add(id, decl)
+			if c, ok := decl.(*ast.ContractDeclaration); ok {
+				visit(c.Body, c.Name.Name+".")
+			}
+		}
+	}
+	visit(file.Declarations, "")
+	return res
+}
+
+// anchorID returns the anchor of the declaration in the container e.g.
+// "Vault."; or an empty string for the directives.
+func anchorID(decl ast.Declaration, container string) string {
+	switch d := decl.(type) {
+	case *ast.ContractDeclaration:
+		return d.Kind.String() + " " + container + d.Name.Name
+	case *ast.FunctionDeclaration:
+		name := d.Kind.String()
+		if d.Name != nil {
+			name = d.Name.Name
+		}
+		return "function " + container + name + paramTypes(d.Type.Params)
+	case *ast.ModifierDeclaration:
+		return "modifier " + container + d.Name.Name + paramTypes(d.Params)
+	case *ast.EventDeclaration:
+		return "event " + container + d.Name.Name + paramTypes(d.Params)
+	case *ast.ErrorDeclaration:
+		return "error " + container + d.Name.Name + paramTypes(d.Params)
+	case *ast.StructDeclaration:
+		return "struct " + container + d.Name.Name
+	case *ast.EnumDeclaration:
+		return "enum " + container + d.Name.Name
+	case *ast.TypeDeclaration:
+		return "type " + container + d.Name.Name
+	case *ast.VariableDeclaration:
+		return "variable " + container + d.Name.Name
+	}
+	return ""
+}
+
+// paramTypes returns the parameter types as written e.g. "(uint, Vault)".
+// Unlike the canonical signature, it doesn't need the names to resolve.
+func paramTypes(params *ast.ParamList) string {
+	types := []string{}
+	if params != nil {
+		for _, p := range params.List {
+			types = append(types, ast.ExprString(p.Type))
+		}
+	}
+	return "(" + strings.Join(types, ",") + ")"
+}
+
+// anchorOf returns the anchor of the innermost declaration enclosing the
+// range; or an empty string if it's outside of all of the declarations.
+func anchorOf(doc *Document, r token.Range) string {
+	id, size := "", token.Pos(-1)
+	for candidate, decl := range doc.Anchors {
+		if !encloses(decl, r) {
+			continue
+		}
+		if size < 0 || decl.End-decl.Start < size || decl.End-decl.Start == size && candidate < id {
+			id, size = candidate, decl.End-decl.Start
+		}
+	}
+	return id
+}
+
+// reanchor moves the range in the given version of the document to the
+// current one. The range must still lie within its anchor, unless it lies
+// outside of all of the declarations e.g. on the pragma.
+func reanchor(doc *Document, version int, anchor string, r token.Range) (token.Range, bool) {
+	moved, ok := doc.translate(version, r)
+	if !ok {
+		return token.Range{}, false
+	}
+	if anchor != "" {
+		decl, ok := doc.Anchors[anchor]
+		if !ok || !encloses(decl, moved) {
+			return token.Range{}, false
+		}
+	}
+	return moved, true
+}
+
+// encloses reports whether the declaration encloses the range. The end of a
+// node is its last character, while the range may end right after it.
+func encloses(decl, r token.Range) bool {
+	return decl.Start <= r.Start && r.End <= decl.End+1
+}
+
+// Reanchor moves the diagnostics computed for the snapshot of a document
+// to its current version, so that a slow analysis finishing after further
+// edits doesn't publish them in the wrong places. The diagnostics touched by
+// the edits, or whose declaration is gone, are dropped.
+func (s *State) Reanchor(snapshot *Document, diagnostics []lsp.Diagnostic) lsp.PublishDiagnosticsNotification {
+	doc, ok := s.document(snapshot.URI)
+	if !ok {
+		return lsp.NewPublishDiagnosticsNotification(snapshot.URI, nil, []lsp.Diagnostic{})
+	}
+
+	res := []lsp.Diagnostic{}
+	for _, d := range diagnostics {
+		r := token.Range{Start: toTokenPos(snapshot.Handle, d.Range.Start), End: toTokenPos(snapshot.Handle, d.Range.End)}
+		moved, ok := reanchor(doc, snapshot.Version, anchorOf(snapshot, r), r)
+		if !ok {
+			continue
+		}
+		d.Range = toLspRange(doc.Handle, moved)
+		res = append(res, d)
+	}
+	// The moved declarations can change the order of the diagnostics.
+	sortDiagnostics(res)
+	return lsp.NewPublishDiagnosticsNotification(doc.URI, publishedVersion(doc), res)
+}
@@ -0,0 +1,148 @@
+package analysis
+
+import (
+	"context"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const anchorSrc = `pragma solidity ^0.8.0;
+
+contract Staking {
+    struct Position {
+        uint256 amount;
+        uint256 rewards;
+    }
+
+    Position[] positions;
+
+    function claim(uint256 i) external {
+        Position memory p = positions[i];
+        p.rewards = 0;
+    }
+}
+`
+
+func Test_Anchors(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/src/Staking.sol", 1, anchorSrc+`
+function fee(uint a) pure returns (uint) { return a; }
+function fee(uint a, uint b) pure returns (uint) { return a + b; }
+`)
+
+	expected := []string{
+		"contract Staking",
+		"struct Staking.Position",
+		"variable Staking.positions",
+		"function Staking.claim(uint256)",
+		"function fee(uint)",
+		"function fee(uint,uint)",
+	}
+	doc := s.Documents["file:///ws/src/Staking.sol"]
+	if len(doc.Anchors) != len(expected) {
+		t.Fatalf("Expected %d anchors, got %v", len(expected), doc.Anchors)
+	}
+	for _, id := range expected {
+		if _, ok := doc.Anchors[id]; !ok {
+			t.Errorf("Expected the anchor %q, got %v", id, doc.Anchors)
+		}
+	}
+}
+
+func Test_DuplicateAnchors(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, "contract Vault {}\ncontract Vault {}\ncontract Vault {}\n")
+
+	doc := s.Documents["file:///ws/src/Vault.sol"]
+	for i, id := range []string{"contract Vault", "contract Vault#2", "contract Vault#3"} {
+		r, ok := doc.Anchors[id]
+		if !ok {
+			t.Fatalf("Expected the anchor %q, got %v", id, doc.Anchors)
+		}
+		if line := doc.Handle.Position(r.Start).Line; line != i+1 {
+			t.Errorf("Expected %q at line %d, got %d", id, i+1, line)
+		}
+	}
+}
+
+func Test_ReanchorDelayedDiagnostics(t *testing.T) {
+	uri := "file:///ws/src/Staking.sol"
+	s := NewState()
+	s.Config.Disabled = []string{"could-be-view"}
+	s.OpenDocument(uri, 1, anchorSrc)
+
+	// The analysis of the version 1 finishes only after the two edits
+	// below, both above the finding.
+	snapshot := s.Documents[uri]
+	diagnostics := s.Diagnostics(context.Background(), uri).Params.Diagnostics
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start.Line != 11 {
+		t.Fatalf("Expected 1 diagnostic at line 11, got %v", diagnostics)
+	}
+
+	src := "// SPDX-License-Identifier: MIT\n" + anchorSrc
+	s.UpdateDocument(uri, 2, src)
+	src = strings.Replace(src, "    Position[] positions;\n", "    Position[] positions;\n    uint256 fee;\n", 1)
+	s.UpdateDocument(uri, 3, src)
+
+	published := s.Reanchor(snapshot, diagnostics).Params
+	if published.Version == nil || *published.Version != 3 {
+		t.Errorf("Expected the diagnostics of the version 3, got %v", published.Version)
+	}
+	if len(published.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %d", len(published.Diagnostics))
+	}
+	expected := diagnostics[0].Range
+	expected.Start.Line += 2
+	expected.End.Line += 2
+	if r := published.Diagnostics[0].Range; r != expected {
+		t.Errorf("Expected %v, got %v", expected, r)
+	}
+
+	// Once the function is gone, so is the finding.
+	start := strings.Index(src, "    function claim")
+	end := strings.Index(src, "    }\n}") + len("    }\n")
+	s.UpdateDocument(uri, 4, src[:start]+src[end:])
+	if published := s.Reanchor(snapshot, diagnostics).Params; len(published.Diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", published.Diagnostics)
+	}
+}
+
+func Test_ResolveCodeAction(t *testing.T) {
+	uri := "file:///ws/src/Staking.sol"
+	s := NewState()
+	s.Capabilities.TextDocument = &lsp.TextDocumentClientCapabilities{
+		CodeAction: &lsp.CodeActionClientCapabilities{
+			ResolveSupport: &lsp.CodeActionResolveSupport{Properties: []string{"edit"}},
+		},
+	}
+	s.OpenDocument(uri, 1, anchorSrc)
+
+	cursor := lsp.Position{Line: 11, Character: 20}
+	actions := s.CodeAction(lsp.IntID(1), uri, lsp.Range{Start: cursor, End: cursor}).Result
+	if len(actions) != 1 {
+		t.Fatalf("Expected 1 action, got %d", len(actions))
+	}
+	action := actions[0]
+	if action.Edit != nil || action.Data == nil || action.Data.Anchor != "function Staking.claim(uint256)" {
+		t.Fatalf("Expected the action to be resolved later, got %+v", action)
+	}
+
+	src := "// SPDX-License-Identifier: MIT\n" + anchorSrc
+	s.UpdateDocument(uri, 2, src)
+	response := s.ResolveCodeAction(lsp.IntID(2), action)
+	if response.Error != nil || response.Result == nil || response.Result.Edit == nil {
+		t.Fatalf("Expected the resolved action, got %+v", response)
+	}
+	edits := response.Result.Edit.Changes[uri]
+	if len(edits) != 1 || edits[0].Range.Start.Line != 12 || edits[0].NewText != "storage" {
+		t.Errorf("Expected `storage` at line 12, got %v", edits)
+	}
+
+	// The anchor is gone once the function is renamed.
+	s.UpdateDocument(uri, 3, strings.Replace(src, "function claim(", "function claimAll(", 1))
+	response = s.ResolveCodeAction(lsp.IntID(3), action)
+	if response.Error == nil || response.Error.Code != lsp.RequestFailed {
+		t.Errorf("Expected the action to be refused, got %+v", response)
+	}
+}
@@ -0,0 +1,19 @@
+package analysis
+
+import (
+	"solbot/audit"
+)
+
+// PathSnapshot returns the hashes of the declarations of the documents in
+// the file or directory, with the paths relative to the workspace, see
+// audit.Declarations and documentsUnder.
+func (s *State) PathSnapshot(path string) audit.Snapshot {
+	snapshot := audit.Snapshot{Version: audit.Version, Declarations: []audit.Declaration{}}
+	for _, doc := range s.documentsUnder(path) {
+		for _, d := range audit.Declarations(doc.File, doc.tokens()) {
+			d.File = s.RelativePath(doc.URI)
+			snapshot.Declarations = append(snapshot.Declarations, d)
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,119 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"solbot/analyzer"
+	"solbot/baseline"
+	"solbot/lsp"
+	"time"
+)
+
+// baselinedPrefix starts the messages of the diagnostics in the baseline.
+const baselinedPrefix = "[baselined] "
+
+// loadBaseline reads the baseline of the project at the root, see
+// baseline.DefaultPath. A project without one has no baselined
+// diagnostics, while an invalid one is only logged.
+func (s *State) loadBaseline() {
+	s.Baseline = nil
+	data, err := s.readFile(filepath.Join(s.Root, baseline.DefaultPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		s.Baseline, err = baseline.Parse(data)
+	}
+	if err != nil {
+		s.Logger.Warn("can't read the baseline", "path", baseline.DefaultPath, "err", err)
+	}
+}
+
+// BaselineFindings returns the findings of the document recorded in the
+// baselines: the errors and the warnings of the language server, and the
+// findings of the detectors of the analyzer of any severity. Their
+// fingerprints are set.
+func (s *State) BaselineFindings(uri string) []baseline.Finding {
+	doc, ok := s.document(uri)
+	if !ok {
+		return nil
+	}
+	res := s.diagnosticFindings(doc, withoutDetectorDiagnostics(s.analyze(context.Background(), doc)))
+	path := s.RelativePath(uri)
+	for _, f := range analyzer.AnalyzeFile(doc.File, s.Config.Disabled...) {
+		res = append(res, baseline.FromFinding(path, doc.Handle, f))
+	}
+	baseline.Fingerprint(res)
+	return res
+}
+
+// diagnosticFindings returns the findings of the errors and the warnings
+// among the diagnostics, in their order. The hints are faded anyway.
+func (s *State) diagnosticFindings(doc *Document, diagnostics []lsp.Diagnostic) []baseline.Finding {
+	res := []baseline.Finding{}
+	path := s.RelativePath(doc.URI)
+	for _, d := range diagnostics {
+		if d.Severity != lsp.SeverityError && d.Severity != lsp.SeverityWarning {
+			continue
+		}
+		start := toTokenPos(doc.Handle, d.Range.Start)
+		res = append(res, baseline.Finding{
+			Code:    d.Code,
+			Path:    path,
+			Line:    int(d.Range.Start.Line) + 1,
+			Message: d.Message,
+			Text:    baseline.LineAt(doc.Handle.Src(), int(start)),
+		})
+	}
+	return res
+}
+
+// published returns the diagnostics as they are published: with the
+// severities of the settings, the monikers of their declarations, and the
+// ones of the valid entries of the baseline turned into hints starting
+// with "[baselined]", so that the editors fade them rather than hide them.
+func (s *State) published(doc *Document, diagnostics []lsp.Diagnostic) []lsp.Diagnostic {
+	res := s.withMonikers(doc, s.overrideSeverities(diagnostics))
+	if s.Baseline == nil {
+		return res
+	}
+	findings := s.diagnosticFindings(doc, diagnostics)
+	baseline.Fingerprint(findings)
+	suppressed := map[string]bool{}
+	for _, f := range s.Baseline.Match(findings, func(string) bool { return false }, time.Now()).Suppressed {
+		suppressed[f.Fingerprint] = true
+	}
+	if len(suppressed) == 0 {
+		return res
+	}
+
+	// The fingerprints are computed from the diagnostics before the
+	// settings, which may turn some of them off.
+	baselined := map[string]bool{}
+	i := 0
+	for _, d := range diagnostics {
+		if d.Severity != lsp.SeverityError && d.Severity != lsp.SeverityWarning {
+			continue
+		}
+		if suppressed[findings[i].Fingerprint] {
+			baselined[diagnosticKey(d)] = true
+		}
+		i++
+	}
+	// The diagnostics are shared with the cache.
+	res = append([]lsp.Diagnostic{}, res...)
+	for i, d := range res {
+		if baselined[diagnosticKey(d)] {
+			res[i].Severity = lsp.SeverityHint
+			res[i].Message = baselinedPrefix + d.Message
+		}
+	}
+	return res
+}
+
+func diagnosticKey(d lsp.Diagnostic) string {
+	return fmt.Sprintf("%d:%d-%d:%d %s %s", d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Line, d.Range.End.Character, d.Code, d.Message)
+}
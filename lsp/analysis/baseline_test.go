@@ -0,0 +1,69 @@
+package analysis
+
+import (
+	"context"
+	"path/filepath"
+	"solbot/baseline"
+	"solbot/lsp"
+	"testing"
+)
+
+func Test_BaselinedDiagnostics(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    uint256 constant FEE = 1;
+
+    function deposit() external payable {
+        require(FEE > 0);
+        require(FEE != 0);
+    }
+}
+`
+	root := "/ws"
+	uri := PathToURI(filepath.Join(root, "src/Vault.sol"))
+
+	// The baseline has the first of the conditions only.
+	s := NewState()
+	s.FS = memoryWorkspace(root, map[string]string{"src/Vault.sol": src})
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatal(err)
+	}
+	findings := s.BaselineFindings(uri)
+	if len(findings) != 2 || findings[0].Path != "src/Vault.sol" || findings[0].Line != 7 || findings[0].Text != "        require(FEE > 0);" {
+		t.Fatalf("Expected the findings of both conditions, got %+v", findings)
+	}
+	content, err := baseline.New(findings[:1], nil, func(string) bool { return true }).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s = NewState()
+	s.FS = memoryWorkspace(root, map[string]string{"src/Vault.sol": src, baseline.DefaultPath: string(content)})
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatal(err)
+	}
+	s.OpenDocument(uri, 1, src)
+	diagnostics := s.Diagnostics(context.Background(), uri).Params.Diagnostics
+	expected := []lsp.Diagnostic{
+		{Severity: lsp.SeverityHint, Message: "[baselined] The condition is always true (`FEE > 0` is `1 > 0`); the check has no effect"},
+		{Severity: lsp.SeverityWarning, Message: "The condition is always true (`FEE != 0` is `1 != 0`); the check has no effect"},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %+v", len(expected), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.Severity != expected[i].Severity || d.Message != expected[i].Message {
+			t.Errorf("Expected %v %q, got %v %q", expected[i].Severity, expected[i].Message, d.Severity, d.Message)
+		}
+	}
+
+	// The cached diagnostics are published again the same way.
+	republished := s.Republish(context.Background(), false)
+	if len(republished) != 1 || republished[0].Params.Diagnostics[0].Message != expected[0].Message {
+		t.Errorf("Expected the baselined diagnostic republished, got %+v", republished)
+	}
+	if cached := s.analyzed[uri].diagnostics[0]; cached.Severity != lsp.SeverityWarning {
+		t.Errorf("Expected the cached diagnostic unchanged, got %+v", cached)
+	}
+}
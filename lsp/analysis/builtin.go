@@ -0,0 +1,324 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strconv"
+	"strings"
+)
+
+// builtinResults are the result types of the global functions taking the
+// arguments of any type e.g. `abi.encode` or `string.concat`.
+var builtinResults = map[string]string{
+	"abi.encode":              "bytes",
+	"abi.encodePacked":        "bytes",
+	"abi.encodeWithSelector":  "bytes",
+	"abi.encodeWithSignature": "bytes",
+	"abi.encodeCall":          "bytes",
+	"bytes.concat":            "bytes",
+	"string.concat":           "string",
+}
+
+// builtinName returns the name of the builtin function called by the call
+// e.g. "abi.encodeCall"; or an empty string if it's not one of the
+// builtinResults. The `abi` namespace can be shadowed by a declaration.
+func (s *State) builtinName(doc *Document, path []ast.Node, call *ast.CallExpression) string {
+	access, ok := call.Function.(*ast.MemberAccessExpression)
+	if !ok {
+		return ""
+	}
+	name := ""
+	switch x := access.Expression.(type) {
+	case *ast.Identifier:
+		if x.Name != "abi" || s.lookup(doc, path, x.Name, x.Start()) != nil {
+			return ""
+		}
+		name = "abi." + access.Member.Name
+	case *ast.ElementaryType:
+		name = x.Value + "." + access.Member.Name
+	}
+	if _, ok := builtinResults[name]; !ok {
+		return ""
+	}
+	return name
+}
+
+// builtinDiagnostics checks the arguments of the builtin functions:
+//
+//   - the selector of `abi.encodeWithSelector` must be a bytes4,
+//   - the signature of `abi.encodeWithSignature` must be a string,
+//   - the tuple of `abi.encodeCall` must match the parameters of the
+//     function it encodes the call of,
+//   - `bytes.concat` and `string.concat` take the bytes and the strings
+//     respectively.
+//
+// The arguments of an unknown type are assumed to be right. It also warns
+// about `abi.encodePacked` with the consecutive dynamic arguments, whose
+// different values can have the same encoding e.g. ("a", "bc") and ("ab",
+// "c"). A hash of such an encoding can be forged by moving the bytes from
+// one argument to the other.
+func (s *State) builtinDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(r token.Range, severity lsp.DiagnosticSeverity, code, message string) {
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, r),
+			Severity: severity,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+	invalid := func(node ast.Node, format string, args ...any) {
+		report(ast.NodeRange(node), lsp.SeverityError, "invalid-argument", fmt.Sprintf(format, args...))
+	}
+
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+		path := ast.PathEnclosingPos(doc.File, call.Lparen)
+		name := s.builtinName(doc, path, call)
+		argType := func(i int) string { return s.argumentType(doc, path, call.Args[i]) }
+
+		switch name {
+		case "abi.encodeWithSelector", "abi.encodeWithSignature":
+			if len(call.Args) == 0 {
+				invalid(call, "`%s` expects at least 1 argument, got 0", name)
+				break
+			}
+			t := argType(0)
+			switch {
+			case name == "abi.encodeWithSelector" && t != "" && !convertible(t, "bytes4"):
+				invalid(call.Args[0], "The selector of `%s` must be `bytes4`, got `%s`", name, t)
+			case name == "abi.encodeWithSignature" && t != "" && !convertible(t, "string"):
+				invalid(call.Args[0], "The signature of `%s` must be a `string`, got `%s`", name, t)
+			}
+		case "abi.encodeCall":
+			s.checkEncodeCall(doc, path, call, invalid)
+		case "bytes.concat", "string.concat":
+			for i := range call.Args {
+				t := argType(i)
+				if t == "" || name == "string.concat" && convertible(t, "string") ||
+					name == "bytes.concat" && (convertible(t, "bytes") || fixedBytesSize(t) > 0) {
+					continue
+				}
+				invalid(call.Args[i], "`%s` can't concatenate `%s`", name, t)
+			}
+		case "abi.encodePacked":
+			for i := 1; i < len(call.Args); i++ {
+				if !isDynamic(argType(i-1)) || !isDynamic(argType(i)) {
+					continue
+				}
+				r := token.Range{Start: call.Args[i-1].Start(), End: ast.NodeRange(call.Args[i]).End}
+				report(r, lsp.SeverityWarning, "encode-packed-collision",
+					fmt.Sprintf("`abi.encodePacked` of the consecutive dynamic arguments `%s` and `%s` is ambiguous: "+
+						"different values can have the same encoding; use `abi.encode`",
+						ast.ExprString(call.Args[i-1]), ast.ExprString(call.Args[i])))
+				// A run of the dynamic arguments is reported once.
+				for i < len(call.Args) && isDynamic(argType(i)) {
+					i++
+				}
+			}
+		}
+		return true
+	})
+	return res
+}
+
+// checkEncodeCall checks that the first argument of `abi.encodeCall` is a
+// function and the tuple in the second one has an argument of a matching
+// type for each of its parameters.
+func (s *State) checkEncodeCall(doc *Document, path []ast.Node, call *ast.CallExpression, invalid func(ast.Node, string, ...any)) {
+	if len(call.Args) != 2 {
+		invalid(call, "`abi.encodeCall` expects a function and a tuple of its arguments, got %d %s",
+			len(call.Args), plural(len(call.Args), "argument"))
+		return
+	}
+	sym := s.follow(s.resolveExpr(doc, path, call.Args[0]))
+	if sym == nil {
+		return
+	}
+	fn, ok := sym.Node.(*ast.FunctionDeclaration)
+	if !ok {
+		invalid(call.Args[0], "The first argument of `abi.encodeCall` must be a function, got `%s`", ast.ExprString(call.Args[0]))
+		return
+	}
+
+	args := []ast.Expression{call.Args[1]}
+	if tuple, ok := call.Args[1].(*ast.TupleExpression); ok {
+		args = tuple.Elements
+	}
+	params := []*ast.Param{}
+	if fn.Type.Params != nil {
+		params = fn.Type.Params.List
+	}
+	name := fn.Name.Name
+	if sig, ok := s.functionSignature(sym.Doc, fn); ok {
+		name = sig
+	}
+	if len(args) != len(params) {
+		invalid(call.Args[1], "`%s` takes %d %s, but the tuple has %d %s",
+			name, len(params), plural(len(params), "argument"), len(args), plural(len(args), "element"))
+		return
+	}
+
+	for i, arg := range args {
+		if arg == nil {
+			continue
+		}
+		t := s.argumentType(doc, path, arg)
+		param, ok := params[i].Type.(*ast.ElementaryType)
+		if t == "" || !ok || convertible(t, param.Value) {
+			continue
+		}
+		invalid(arg, "Argument %d of `%s` must be `%s`, got `%s`", i+1, name, param.Value, t)
+	}
+}
+
+// argumentType returns the type of the argument passed to a builtin
+// function: an elementary type e.g. "uint256", "literal_string" or
+// "int_const" for the literals, and the type name as written for the other
+// types; or an empty string if it's unknown.
+func (s *State) argumentType(doc *Document, path []ast.Node, x ast.Expression) string {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		switch x.Kind {
+		case token.STRING_LITERAL, token.UNICODE_STRING_LITERAL, token.HEX_STRING_LITERAL:
+			return "literal_string"
+		case token.DECIMAL_NUMBER:
+			return "int_const"
+		case token.HEX_NUMBER:
+			// The hex numbers of the right size are fixed bytes e.g. 0x12345678.
+			digits := strings.ReplaceAll(strings.TrimPrefix(x.Value, "0x"), "_", "")
+			if x.Unit == nil && len(digits)%2 == 0 && len(digits) <= 64 {
+				return "hex_const" + strconv.Itoa(len(digits)/2)
+			}
+			return "int_const"
+		case token.TRUE_LITERAL, token.FALSE_LITERAL:
+			return "bool"
+		}
+		return ""
+	case *ast.MemberAccessExpression:
+		if x.Member.Name == "selector" {
+			return "bytes4"
+		}
+	case *ast.CallExpression:
+		if conversion, ok := x.Function.(*ast.ElementaryType); ok {
+			return conversion.Value
+		}
+		if name := s.builtinName(doc, path, x); name != "" {
+			return builtinResults[name]
+		}
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 && x.Elements[0] != nil {
+			return s.argumentType(doc, path, x.Elements[0])
+		}
+		return ""
+	}
+	_, t := s.typeOf(doc, path, x)
+	if t == nil {
+		return ""
+	}
+	if e, ok := t.(*ast.ElementaryType); ok {
+		return e.Value
+	}
+	return ast.ExprString(t)
+}
+
+// convertible reports whether the value of the type converts implicitly to
+// the elementary type. Only the elementary types are compared, the others
+// are assumed to be convertible.
+func convertible(from, to string) bool {
+	switch to {
+	case "uint":
+		to = "uint256"
+	case "int":
+		to = "int256"
+	case "byte":
+		to = "bytes1"
+	}
+	switch from {
+	case "uint":
+		from = "uint256"
+	case "int":
+		from = "int256"
+	case "byte":
+		from = "bytes1"
+	case "literal_string":
+		return to == "string" || to == "bytes" || fixedBytesSize(to) > 0
+	case "int_const":
+		return intSize(to) > 0
+	}
+	if size, ok := strings.CutPrefix(from, "hex_const"); ok {
+		n, _ := strconv.Atoi(size)
+		return intSize(to) > 0 || fixedBytesSize(to) == n
+	}
+	if from == to {
+		return true
+	}
+
+	switch {
+	case intSize(from) > 0 && intSize(to) > 0:
+		// The wider types of the same signedness, and the signed types
+		// wide enough for all of the unsigned values.
+		fromUnsigned, toUnsigned := strings.HasPrefix(from, "u"), strings.HasPrefix(to, "u")
+		if fromUnsigned == toUnsigned {
+			return intSize(from) <= intSize(to)
+		}
+		return fromUnsigned && intSize(from) < intSize(to)
+	case fixedBytesSize(from) > 0 && fixedBytesSize(to) > 0:
+		return fixedBytesSize(from) <= fixedBytesSize(to)
+	}
+	if elementary(from) && elementary(to) {
+		return false
+	}
+	return true
+}
+
+// intSize returns the number of the bits of the integer type; or 0 if it's
+// not one.
+func intSize(t string) int {
+	bits, ok := strings.CutPrefix(strings.TrimPrefix(t, "u"), "int")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(bits)
+	if err != nil || n%8 != 0 || n < 8 || n > 256 {
+		return 0
+	}
+	return n
+}
+
+// fixedBytesSize returns the size of the fixed bytes type e.g. 4 for
+// "bytes4"; or 0 if it's not one.
+func fixedBytesSize(t string) int {
+	size, ok := strings.CutPrefix(t, "bytes")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(size)
+	if err != nil || n < 1 || n > 32 {
+		return 0
+	}
+	return n
+}
+
+// elementary reports whether the type is a value or a dynamic elementary
+// type, whose conversions convertible knows.
+func elementary(t string) bool {
+	switch t {
+	case "address", "bool", "string", "bytes":
+		return true
+	}
+	return intSize(t) > 0 || fixedBytesSize(t) > 0
+}
+
+// isDynamic reports whether the type is encoded with its length by
+// `abi.encodePacked`: the strings, the bytes and the dynamic arrays. The
+// literals are left out, since the caller can't change them.
+func isDynamic(t string) bool {
+	return t == "string" || t == "bytes" || strings.HasSuffix(t, "[]")
+}
@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const routerSrc = `pragma solidity ^0.8.0;
+
+interface IERC20 {
+    function transfer(address to, uint256 amount) external returns (bool);
+}
+
+contract Router {
+    function encode(address to, uint256 amount, string memory name, bytes memory data) external pure returns (bytes memory) {
+        %s;
+    }
+}
+`
+
+func builtinDiagnosticsOf(t *testing.T, stmt string) []lsp.Diagnostic {
+	t.Helper()
+	uri := "file:///ws/src/Router.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, strings.Replace(routerSrc, "%s", stmt, 1))
+	return s.builtinDiagnostics(s.Documents[uri])
+}
+
+func Test_EncodeCall(t *testing.T) {
+	if diagnostics := builtinDiagnosticsOf(t, "return abi.encodeCall(IERC20.transfer, (to, amount))"); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	diagnostics := builtinDiagnosticsOf(t, "return abi.encodeCall(IERC20.transfer, (to))")
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "invalid-argument" || d.Severity != lsp.SeverityError {
+		t.Errorf("Expected an invalid argument error, got %s %v", d.Code, d.Severity)
+	}
+	expected := lsp.Range{Start: lsp.Position{Line: 8, Character: 47}, End: lsp.Position{Line: 8, Character: 51}}
+	if d.Range != expected {
+		t.Errorf("Expected the error at the tuple %v, got %v", expected, d.Range)
+	}
+	if d.Message != "`transfer(address,uint256)` takes 2 arguments, but the tuple has 1 element" {
+		t.Errorf("Expected the arity mismatch, got %q", d.Message)
+	}
+
+	diagnostics = builtinDiagnosticsOf(t, "return abi.encodeCall(IERC20.transfer, (name, amount))")
+	if len(diagnostics) != 1 || diagnostics[0].Message != "Argument 1 of `transfer(address,uint256)` must be `address`, got `string`" {
+		t.Errorf("Expected the type mismatch of the first argument, got %v", diagnostics)
+	}
+}
+
+func Test_EncodeWithSelectorAndSignature(t *testing.T) {
+	for _, stmt := range []string{
+		"return abi.encodeWithSelector(IERC20.transfer.selector, to, amount)",
+		"return abi.encodeWithSelector(0xa9059cbb, to, amount)",
+		`return abi.encodeWithSignature("transfer(address,uint256)", to, amount)`,
+		"return bytes.concat(data, bytes4(0), \"x\")",
+		"return bytes(string.concat(name, \"-\", name))",
+	} {
+		if diagnostics := builtinDiagnosticsOf(t, stmt); len(diagnostics) != 0 {
+			t.Errorf("Expected no diagnostics for %s, got %v", stmt, diagnostics)
+		}
+	}
+
+	for _, stmt := range []string{
+		"return abi.encodeWithSelector(name, to, amount)",
+		"return abi.encodeWithSignature(IERC20.transfer.selector, to, amount)",
+		"return string.concat(name, data)",
+	} {
+		diagnostics := builtinDiagnosticsOf(t, stmt)
+		if len(diagnostics) != 1 || diagnostics[0].Code != "invalid-argument" {
+			t.Errorf("Expected an invalid argument for %s, got %v", stmt, diagnostics)
+		}
+	}
+}
+
+func Test_EncodePackedDynamicArguments(t *testing.T) {
+	diagnostics := builtinDiagnosticsOf(t, "return abi.encodePacked(to, name, data, amount)")
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "encode-packed-collision" || d.Severity != lsp.SeverityWarning {
+		t.Errorf("Expected the packed encoding warning, got %s %v", d.Code, d.Severity)
+	}
+	expected := lsp.Range{Start: lsp.Position{Line: 8, Character: 36}, End: lsp.Position{Line: 8, Character: 46}}
+	if d.Range != expected {
+		t.Errorf("Expected the warning at `name, data` %v, got %v", expected, d.Range)
+	}
+
+	if diagnostics := builtinDiagnosticsOf(t, `return abi.encodePacked(name, "-", amount)`); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics for a literal separator, got %v", diagnostics)
+	}
+}
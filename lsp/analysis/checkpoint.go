@@ -0,0 +1,23 @@
+package analysis
+
+import "context"
+
+type checkpointKey struct{}
+
+// WithCheckpoint returns the context whose long loops of the analysis e.g.
+// indexing the workspace or running the detectors call the function in
+// between their steps. The server uses it to handle the interactive
+// requests while the background work runs, and to cancel the work.
+func WithCheckpoint(ctx context.Context, checkpoint func(ctx context.Context) error) context.Context {
+	return context.WithValue(ctx, checkpointKey{}, checkpoint)
+}
+
+// Checkpoint lets the server handle the waiting requests, see
+// WithCheckpoint. It returns an error if the work was cancelled, then the
+// loop stops.
+func Checkpoint(ctx context.Context) error {
+	if checkpoint, ok := ctx.Value(checkpointKey{}).(func(ctx context.Context) error); ok {
+		return checkpoint(ctx)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,126 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/clones"
+	"solbot/lsp"
+	"solbot/metrics"
+	"solbot/token"
+)
+
+// CloneGroup is a set of the functions whose bodies are copies of each
+// other, see clones.Find.
+type CloneGroup struct {
+	ID         string
+	Similarity float64
+	Members    []CloneMember // ordered by the file and by the position
+}
+
+// CloneMember is a function of a clone group.
+type CloneMember struct {
+	metrics.Function
+	Doc   *Document
+	Lines int // lines spanned by the body
+}
+
+// PathClones finds the clones among the functions of the documents in the
+// file or directory, see documentsUnder.
+func (s *State) PathClones(path string, opts clones.Options) []CloneGroup {
+	return s.clones(s.documentsUnder(path), opts)
+}
+
+// clones groups the functions with the bodies of the documents.
+func (s *State) clones(docs []*Document, opts clones.Options) []CloneGroup {
+	members := []CloneMember{}
+	fragments := []clones.Fragment{}
+	for _, doc := range docs {
+		for _, f := range s.Metrics(doc) {
+			body := f.Decl.Body
+			if body == nil {
+				continue
+			}
+			first, last := doc.Handle.Position(body.Start()), doc.Handle.Position(body.End()-1)
+			member := CloneMember{Function: f, Doc: doc, Lines: last.Line - first.Line + 1}
+			members = append(members, member)
+			fragments = append(fragments, clones.Fragment{
+				Shape: clones.Normalize(tokensIn(doc.tokens(), ast.NodeRange(body))),
+				Lines: member.Lines,
+			})
+		}
+	}
+
+	res := []CloneGroup{}
+	for _, g := range clones.Find(fragments, opts) {
+		group := CloneGroup{ID: g.ID, Similarity: g.Similarity}
+		for _, i := range g.Members {
+			group.Members = append(group.Members, members[i])
+		}
+		res = append(res, group)
+	}
+	return res
+}
+
+// tokensIn returns the tokens starting in the range.
+func tokensIn(tokens []token.Token, r token.Range) []token.Token {
+	from, to := len(tokens), len(tokens)
+	for i, tkn := range tokens {
+		if tkn.Pos >= r.Start && from == len(tokens) {
+			from = i
+		}
+		if tkn.Pos >= r.End {
+			to = i
+			break
+		}
+	}
+	return tokens[from:max(from, to)]
+}
+
+// cloneDiagnostics reports the functions of the document whose bodies are
+// copied in the other files of the workspace or in the same one, if the
+// [clones] section of solbot.toml turns them on. The counterparts are in
+// the related information. The dependencies are neither reported nor
+// compared.
+func (s *State) cloneDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	if !s.Config.Clones.Diagnostics || s.isDependency(doc.URI) {
+		return res
+	}
+	docs := []*Document{}
+	for _, d := range s.sortedDocuments() {
+		if !s.isDependency(d.URI) {
+			docs = append(docs, d)
+		}
+	}
+
+	for _, group := range s.clones(docs, s.Config.Clones.Options()) {
+		for _, member := range group.Members {
+			if member.Doc.URI != doc.URI {
+				continue
+			}
+			related := []lsp.DiagnosticRelatedInformation{}
+			for _, other := range group.Members {
+				if other.Decl == member.Decl {
+					continue
+				}
+				related = append(related, lsp.DiagnosticRelatedInformation{
+					Location: lsp.Location{URI: other.Doc.URI, Range: toLspRange(other.Doc.Handle, other.NameRange())},
+					Message:  fmt.Sprintf("clone: %s", other.Name()),
+				})
+			}
+			others := "another function"
+			if len(related) > 1 {
+				others = fmt.Sprintf("%d other functions", len(related))
+			}
+			res = append(res, lsp.Diagnostic{
+				Range:              toLspRange(doc.Handle, member.NameRange()),
+				Severity:           lsp.SeverityHint,
+				Code:               "code-clone",
+				Source:             "solbot",
+				Message:            fmt.Sprintf("The body of %s is a copy of %s (%.0f%% similar, clone group %s)", member.Name(), others, 100*group.Similarity, group.ID),
+				RelatedInformation: related,
+			})
+		}
+	}
+	return res
+}
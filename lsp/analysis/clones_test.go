@@ -0,0 +1,53 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const cloneBody = `{
+        require(%[1]s > 0, "zero");
+        uint256 shares = %[1]s * totalShares / totalAssets;
+        if (shares == 0) {
+            revert ZeroShares();
+        }
+        balances[to] += shares;
+        totalShares += shares;
+        totalAssets += %[1]s;
+        token.transferFrom(msg.sender, address(this), %[1]s);
+        emit Deposit(msg.sender, to, %[1]s, shares);
+    }`
+
+func Test_CloneDiagnostics(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	vault := "file:///ws/src/Vault.sol"
+	router := "file:///ws/src/Router.sol"
+	s.OpenDocument(vault, 1, "contract Vault {\n    function deposit(uint256 amount, address to) external "+fmt.Sprintf(cloneBody, "amount")+"\n\n    function owner() external view returns (address) {\n        return msg.sender;\n    }\n}\n")
+	s.OpenDocument(router, 1, "contract Router {\n    function mint(uint256 assets, address to) external "+fmt.Sprintf(cloneBody, "assets")+"\n}\n")
+	s.OpenDocument("file:///ws/lib/Vault.sol", 1, "contract Copy {\n    function deposit(uint256 amount, address to) external "+fmt.Sprintf(cloneBody, "amount")+"\n}\n")
+
+	if diagnostics := s.cloneDiagnostics(s.Documents[vault]); len(diagnostics) != 0 {
+		t.Errorf("Expected the clone diagnostics to be off by default, got %v", diagnostics)
+	}
+
+	s.Config.Clones.Diagnostics = true
+	diagnostics := s.cloneDiagnostics(s.Documents[vault])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected a single diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Range.Start.Line != 1 || !strings.HasPrefix(d.Message, "The body of Vault.deposit is a copy of another function (100% similar, clone group ") {
+		t.Errorf("Expected the clone of Vault.deposit, got %d: %s", d.Range.Start.Line, d.Message)
+	}
+	if len(d.RelatedInformation) != 1 || d.RelatedInformation[0].Location.URI != router || d.RelatedInformation[0].Message != "clone: Router.mint" {
+		t.Errorf("Expected Router.mint as the counterpart, got %v", d.RelatedInformation)
+	}
+
+	// The CLI reports the same group.
+	groups := s.PathClones("/ws", s.Config.Clones.Options())
+	if len(groups) != 1 || len(groups[0].Members) != 2 || !strings.Contains(d.Message, groups[0].ID) {
+		t.Errorf("Expected the group of the diagnostic, got %v", groups)
+	}
+}
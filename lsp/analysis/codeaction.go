@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"slices"
+	"solbot/lsp"
+	"solbot/token"
+)
+
+// CodeAction returns the actions available in the selected range: the
+// quick fixes of the diagnostics, including the suggestions for the
+// misspelled names, the migration actions and the extraction of the
+// selected statements, together with organizing the imports of the whole
+// document.
+//
+// The actions with edits carry the data identifying them. If the client
+// resolves the edits lazily, they are left out and computed again by
+// ResolveCodeAction, against the version of the document the client is
+// about to edit.
+//
+// The refactorings refused for the selection are offered disabled, with
+// the reason. The clients which can't show them, but resolve the edits,
+// get the reason as the error of the resolution instead.
+func (s *State) CodeAction(id lsp.ID, uri string, r lsp.Range) lsp.CodeActionResponse {
+	doc, ok := s.document(uri)
+	if !ok {
+		return lsp.NewCodeActionResponse(id, []lsp.CodeAction{})
+	}
+
+	selected := toTokenRange(doc.Handle, r)
+	actions := s.codeActions(doc, selected)
+	if !s.showsDisabled() {
+		actions = slices.DeleteFunc(actions, func(a lsp.CodeAction) bool {
+			return a.Disabled != nil && !s.resolvesEdits()
+		})
+	}
+	for i := range actions {
+		if actions[i].Edit == nil && actions[i].Disabled == nil {
+			continue
+		}
+		if !s.showsDisabled() {
+			actions[i].Disabled = nil
+		}
+		actions[i].Data = &lsp.CodeActionData{
+			URI:     uri,
+			Version: doc.Version,
+			Start:   int(selected.Start),
+			End:     int(selected.End),
+			Anchor:  anchorOf(doc, selected),
+		}
+		if s.resolvesEdits() {
+			actions[i].Edit = nil
+		}
+	}
+	return lsp.NewCodeActionResponse(id, actions)
+}
+
+func (s *State) codeActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	actions = append(actions, s.suggestionActions(doc, selected)...)
+	actions = append(actions, s.dataLocationActions(doc, selected)...)
+	actions = append(actions, s.memoryCopyActions(doc, selected)...)
+	actions = append(actions, s.addressActions(doc, selected)...)
+	actions = append(actions, s.implementationActions(doc, selected)...)
+	actions = append(actions, s.natSpecActions(doc, selected)...)
+	actions = append(actions, s.abicoderActions(doc, selected)...)
+	actions = append(actions, s.missingRemappingActions(doc, selected)...)
+	actions = append(actions, s.whitespaceActions(doc, selected)...)
+	actions = append(actions, s.migrationActions(doc, selected)...)
+	actions = append(actions, s.pragmaRangeActions(doc, selected)...)
+	actions = append(actions, s.extractActions(doc, selected)...)
+	actions = append(actions, s.organizeImportsActions(doc)...)
+	return actions
+}
+
+// ResolveCodeAction computes the edit of the action offered by CodeAction.
+// If the document was edited since then, the selection is moved to the
+// current version and the action is offered again there. The action is
+// refused if the edits touched the selection, its declaration is gone or
+// the action is no longer available.
+func (s *State) ResolveCodeAction(id lsp.ID, action lsp.CodeAction) lsp.CodeActionResolveResponse {
+	data := action.Data
+	if data == nil {
+		return lsp.NewCodeActionResolveResponse(id, &action)
+	}
+	doc, ok := s.document(data.URI)
+	if !ok {
+		return lsp.NewCodeActionResolveErrorResponse(id, lsp.RequestFailed, "the document is no longer available")
+	}
+
+	selected := token.Range{Start: token.Pos(data.Start), End: token.Pos(data.End)}
+	selected, ok = reanchor(doc, data.Version, data.Anchor, selected)
+	if !ok {
+		return lsp.NewCodeActionResolveErrorResponse(id, lsp.RequestFailed, "the code action is out of date")
+	}
+	actions := s.codeActions(doc, selected)
+	i := slices.IndexFunc(actions, func(a lsp.CodeAction) bool {
+		return a.Title == action.Title && (a.Edit != nil || a.Disabled != nil)
+	})
+	if i < 0 {
+		return lsp.NewCodeActionResolveErrorResponse(id, lsp.RequestFailed, "the code action is out of date")
+	}
+	if disabled := actions[i].Disabled; disabled != nil {
+		return lsp.NewCodeActionResolveErrorResponse(id, lsp.RequestFailed, disabled.Reason)
+	}
+	return lsp.NewCodeActionResolveResponse(id, &actions[i])
+}
+
+// resolvesEdits reports whether the client resolves the edits of the code
+// actions with the codeAction/resolve request.
+func (s *State) resolvesEdits() bool {
+	textDocument := s.Capabilities.TextDocument
+	if textDocument == nil || textDocument.CodeAction == nil || textDocument.CodeAction.ResolveSupport == nil {
+		return false
+	}
+	return slices.Contains(textDocument.CodeAction.ResolveSupport.Properties, "edit")
+}
+
+// showsDisabled reports whether the client shows the disabled code actions.
+func (s *State) showsDisabled() bool {
+	textDocument := s.Capabilities.TextDocument
+	return textDocument != nil && textDocument.CodeAction != nil && textDocument.CodeAction.DisabledSupport
+}
+
+// touches reports whether the ranges overlap or the selection is right
+// next to the other range e.g. the cursor is placed after a word.
+func touches(selected, other token.Range) bool {
+	return other.Start <= selected.End && selected.Start <= other.End
+}
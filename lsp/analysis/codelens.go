@@ -0,0 +1,211 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// CodeLens returns the lenses above the declarations of the document, as
+// configured in the [code_lens] section: the number of the references to
+// the contracts, functions, state variables, structs, enums and events,
+// the selectors of the external and public functions, and the "run" and
+// "debug" commands of the Foundry tests, see Tests. Counting the
+// references needs all of the documents, so the reference lenses come
+// without their commands, filled in by ResolveCodeLens once the client
+// shows them. The dependency files get no lenses.
+func (s *State) CodeLens(id lsp.ID, uri string) lsp.CodeLensResponse {
+	lenses := []lsp.CodeLens{}
+	doc, ok := s.document(uri)
+	if !ok || s.isDependency(uri) {
+		return lsp.NewCodeLensResponse(id, lenses)
+	}
+
+	add := func(decl ast.Declaration, name *ast.Identifier, external bool) {
+		r := ast.NodeRange(name)
+		if s.Config.CodeLens.References {
+			lenses = append(lenses, lsp.CodeLens{
+				Range: toLspRange(doc.Handle, r),
+				Data: &lsp.CodeLensData{
+					URI:     uri,
+					Version: doc.Version,
+					Start:   int(r.Start),
+					End:     int(r.End),
+					Anchor:  anchorOf(doc, r),
+				},
+			})
+		}
+		fn, ok := decl.(*ast.FunctionDeclaration)
+		if !ok || !external || !s.Config.CodeLens.Selectors {
+			return
+		}
+		if signature, ok := s.functionSignature(doc, fn); ok {
+			lenses = append(lenses, lsp.CodeLens{
+				Range:   toLspRange(doc.Handle, r),
+				Command: &lsp.Command{Title: fmt.Sprintf("%s %s", formatSelector(selector(signature)), signature)},
+			})
+		}
+	}
+
+	for _, decl := range doc.File.Declarations {
+		switch d := decl.(type) {
+		case *ast.ContractDeclaration:
+			add(d, d.Name, false)
+			for _, member := range d.Body {
+				switch m := member.(type) {
+				case *ast.FunctionDeclaration:
+					if m.Kind == token.FUNCTION {
+						visibility := m.Type.Visibility
+						add(m, m.Name, d.Kind == token.INTERFACE || visibility == ast.External || visibility == ast.Public)
+					}
+				case *ast.VariableDeclaration, *ast.StructDeclaration, *ast.EnumDeclaration, *ast.EventDeclaration:
+					add(m, declaredName(m), false)
+				}
+			}
+		case *ast.FunctionDeclaration:
+			if d.Name != nil {
+				add(d, d.Name, false)
+			}
+		case *ast.StructDeclaration, *ast.EnumDeclaration, *ast.EventDeclaration:
+			add(d, declaredName(d), false)
+		}
+	}
+	if s.Config.CodeLens.Tests {
+		lenses = append(lenses, s.testLenses(doc)...)
+	}
+	return lsp.NewCodeLensResponse(id, lenses)
+}
+
+// ResolveCodeLens counts the references to the declaration of the lens
+// offered by CodeLens, in all of the indexed files. Clicking the lens lists
+// them with the references request. If the document was edited since the
+// lens was offered, the name is moved to the current version; the lens is
+// refused if the edits touched it or its declaration is gone.
+func (s *State) ResolveCodeLens(id lsp.ID, lens lsp.CodeLens) lsp.CodeLensResolveResponse {
+	data := lens.Data
+	if data == nil {
+		return lsp.NewCodeLensResolveResponse(id, &lens)
+	}
+	doc, ok := s.document(data.URI)
+	if !ok {
+		return lsp.NewCodeLensResolveErrorResponse(id, lsp.RequestFailed, "the document is no longer available")
+	}
+
+	r := token.Range{Start: token.Pos(data.Start), End: token.Pos(data.End)}
+	r, ok = reanchor(doc, data.Version, data.Anchor, r)
+	if !ok {
+		return lsp.NewCodeLensResolveErrorResponse(id, lsp.RequestFailed, "the code lens is out of date")
+	}
+	path := ast.PathEnclosingPos(doc.File, r.Start)
+	ident, ok := path[0].(*ast.Identifier)
+	if !ok || ast.NodeRange(ident) != r {
+		return lsp.NewCodeLensResolveErrorResponse(id, lsp.RequestFailed, "the code lens is out of date")
+	}
+	sym := s.resolve(doc, path)
+	if sym == nil || sym.Name != ident {
+		return lsp.NewCodeLensResolveErrorResponse(id, lsp.RequestFailed, "the code lens is out of date")
+	}
+
+	title := "1 reference"
+	if n := len(s.referencesTo(sym)); n != 1 {
+		title = fmt.Sprintf("%d references", n)
+	}
+	position := toLspPosition(doc.Handle, r.Start)
+	lens.Range = toLspRange(doc.Handle, r)
+	lens.Command = &lsp.Command{
+		Title:     title,
+		Command:   lsp.FindReferencesCommand,
+		Arguments: []any{data.URI, position},
+	}
+	return lsp.NewCodeLensResolveResponse(id, &lens)
+}
+
+// References returns the identifiers referring to the declaration at the
+// position in all of the indexed files, and the declared name itself if
+// the client asks for it. The arguments of the NatSpec tags naming it
+// follow the code, unless the client excludes them.
+func (s *State) References(id lsp.ID, uri string, position lsp.Position, context lsp.ReferenceContext) lsp.ReferencesResponse {
+	locations := []lsp.Location{}
+	sym := s.symbolAt(uri, position)
+	if sym == nil {
+		return lsp.NewReferencesResponse(id, locations)
+	}
+	if context.IncludeDeclaration {
+		locations = append(locations, lsp.Location{URI: sym.Doc.URI, Range: toLspRange(sym.Doc.Handle, ast.NodeRange(sym.Name))})
+	}
+	refs := s.referencesTo(sym)
+	if context.IncludeDocumentation == nil || *context.IncludeDocumentation {
+		refs = append(refs, s.natSpecReferencesTo(map[*ast.Identifier]bool{sym.Name: true}, sym.Name.Name)...)
+		slices.SortStableFunc(refs, func(a, b reference) int {
+			if a.doc.URI != b.doc.URI {
+				return strings.Compare(a.doc.URI, b.doc.URI)
+			}
+			return cmp.Compare(a.ident.Start(), b.ident.Start())
+		})
+	}
+	for _, ref := range refs {
+		locations = append(locations, lsp.Location{URI: ref.doc.URI, Range: toLspRange(ref.doc.Handle, ast.NodeRange(ref.ident))})
+	}
+	return lsp.NewReferencesResponse(id, locations)
+}
+
+// referencesTo returns the identifiers referring to the declaration, other
+// than its name, ordered by the file and by the position. The imported
+// names are followed to their declarations.
+func (s *State) referencesTo(sym *Symbol) []reference {
+	refs := []reference{}
+	for _, doc := range s.sortedDocuments() {
+		inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+			if ident.Name != sym.Name.Name || ident == sym.Name {
+				return
+			}
+			ref := s.resolve(doc, path)
+			if ref == nil {
+				return
+			}
+			if ref.Name != sym.Name {
+				if ref = s.follow(ref); ref == nil || ref.Name != sym.Name {
+					return
+				}
+			}
+			refs = append(refs, reference{doc: doc, ident: ident, path: path})
+		})
+	}
+	return refs
+}
+
+// RefreshesCodeLenses reports whether the client asks for the lenses again
+// when the server sends the workspace/codeLens/refresh request.
+func (s *State) RefreshesCodeLenses() bool {
+	workspace := s.Capabilities.Workspace
+	return workspace != nil && workspace.CodeLens != nil && workspace.CodeLens.RefreshSupport
+}
+
+// ReferencesChanged reports whether an opened or edited document changed
+// the identifiers since the last call, so that the reference counts may be
+// different. The edits of the comments, the literals and the whitespace
+// leave the counts as they are.
+func (s *State) ReferencesChanged() bool {
+	changed := s.referencesChanged
+	s.referencesChanged = false
+	return changed
+}
+
+// identifiersHash returns the hash of the names of all of the identifiers
+// of the file in the source order, see ReferencesChanged.
+func identifiersHash(file *ast.File) uint64 {
+	h := fnv.New64a()
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Identifier); ok {
+			h.Write([]byte(ident.Name))
+			h.Write([]byte{0})
+		}
+		return true
+	})
+	return h.Sum64()
+}
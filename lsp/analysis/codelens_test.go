@@ -0,0 +1,117 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func Test_CodeLens(t *testing.T) {
+	token := `pragma solidity ^0.8.0;
+
+contract Token {
+    uint256 public totalSupply;
+
+    event Minted(uint256 amount);
+
+    function mint(uint256 amount) public {
+        totalSupply += amount;
+        emit Minted(amount);
+    }
+
+    function mintTwice(uint256 amount) internal {
+        mint(amount * 2);
+    }
+}
+`
+	vault := `pragma solidity ^0.8.0;
+
+import {Token} from "./Token.sol";
+
+contract Vault {
+    Token token;
+
+    function deposit(uint256 amount) external {
+        token.mint(amount);
+        token.mint(amount * 2);
+    }
+}
+`
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument("file:///ws/src/Token.sol", 1, token)
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, vault)
+	s.OpenDocument("file:///ws/lib/oz/Token.sol", 1, token)
+
+	lenses := s.CodeLens(lsp.IntID(1), "file:///ws/src/Token.sol").Result
+	expected := []struct {
+		line     uint
+		resolved bool
+		title    string
+	}{
+		{line: 2}, // Token
+		{line: 3}, // totalSupply
+		{line: 5}, // Minted
+		{line: 7}, // mint
+		{line: 7, resolved: true, title: "0xa0712d68 mint(uint256)"},
+		{line: 12}, // mintTwice
+	}
+	if len(lenses) != len(expected) {
+		t.Fatalf("Expected %d lenses, got %d: %+v", len(expected), len(lenses), lenses)
+	}
+	for i, e := range expected {
+		lens := lenses[i]
+		if lens.Range.Start.Line != e.line {
+			t.Errorf("Expected lens %d on line %d, got %d", i, e.line, lens.Range.Start.Line)
+		}
+		if (lens.Command != nil) != e.resolved {
+			t.Errorf("Expected lens %d to be resolved %v, got %+v", i, e.resolved, lens.Command)
+		}
+		if e.resolved && lens.Command.Title != e.title {
+			t.Errorf("Expected lens %d titled %q, got %q", i, e.title, lens.Command.Title)
+		}
+	}
+
+	tests := []struct {
+		lens  int
+		title string
+	}{
+		{lens: 0, title: "2 references"}, // the import and the type of `token`
+		{lens: 1, title: "1 reference"},
+		{lens: 3, title: "3 references"}, // once in Token.sol and twice in Vault.sol
+		{lens: 5, title: "0 references"},
+	}
+	for _, tt := range tests {
+		resolved := s.ResolveCodeLens(lsp.IntID(2), lenses[tt.lens]).Result
+		if resolved == nil || resolved.Command == nil {
+			t.Fatalf("Expected lens %d to be resolved, got %+v", tt.lens, resolved)
+		}
+		if resolved.Command.Title != tt.title {
+			t.Errorf("Expected lens %d titled %q, got %q", tt.lens, tt.title, resolved.Command.Title)
+		}
+		if resolved.Command.Command != lsp.FindReferencesCommand {
+			t.Errorf("Expected the %s command, got %s", lsp.FindReferencesCommand, resolved.Command.Command)
+		}
+	}
+
+	if lenses := s.CodeLens(lsp.IntID(3), "file:///ws/lib/oz/Token.sol").Result; len(lenses) != 0 {
+		t.Errorf("Expected no lenses in the dependency, got %+v", lenses)
+	}
+
+	// A comment moves the lens offered for the previous version, without
+	// changing the counts.
+	s.ReferencesChanged()
+	s.UpdateDocument("file:///ws/src/Token.sol", 2, "// Mintable token.\n"+token)
+	if s.ReferencesChanged() {
+		t.Errorf("Expected the comment to leave the references as they are")
+	}
+	resolved := s.ResolveCodeLens(lsp.IntID(4), lenses[3]).Result
+	if resolved == nil || resolved.Range.Start.Line != 8 || resolved.Command.Title != "3 references" {
+		t.Errorf("Expected the lens moved to line 8 with 3 references, got %+v", resolved)
+	}
+
+	s.UpdateDocument("file:///ws/src/Vault.sol", 2, strings.Replace(vault, "token.mint(amount * 2);", "", 1))
+	if !s.ReferencesChanged() {
+		t.Errorf("Expected the removed call to change the references")
+	}
+}
@@ -0,0 +1,220 @@
+package analysis
+
+import (
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// Completion lists the members of the expression before the period at the
+// position e.g. the errors declared in Errors.sol after `revert Errors.`.
+// The document is usually incomplete while typing, so the expression is
+// read from the source rather than from the AST. The members of `block`,
+// `msg` and `tx` are ranked by their use and checked against the pragma,
+// see globalCompletions. In the NatSpec, the arguments of @param and
+// @inheritdoc are listed, see natSpecCompletions. Elsewhere, the names are
+// filtered and ranked by the context, see contextCompletions. In the
+// Foundry tests, the cheatcodes and the logging functions missing from
+// forge-std, or all of them if it's not installed, are listed from the
+// table, see foundryLibraries. In remappings.txt, the directories of the
+// target are listed, see projectFileCompletions.
+func (s *State) Completion(id lsp.ID, uri string, position lsp.Position) lsp.CompletionResponse {
+	if f, ok := s.projectFiles[uri]; ok {
+		return lsp.NewCompletionResponse(id, s.projectFileCompletions(f, position))
+	}
+	items := []lsp.CompletionItem{}
+	doc, ok := s.document(uri)
+	if !ok {
+		return lsp.NewCompletionResponse(id, items)
+	}
+
+	pos := toTokenPos(doc.Handle, position)
+	if items, ok := s.natSpecCompletions(doc, pos); ok {
+		return lsp.NewCompletionResponse(id, items)
+	}
+	src := doc.Handle.Src()
+	start := int(pos)
+	for start > 0 && isIdentifierChar(src[start-1]) {
+		start--
+	}
+	if start == 0 || src[start-1] != '.' {
+		return lsp.NewCompletionResponse(id, s.contextCompletions(doc, token.Pos(start)))
+	}
+	// The qualified name before the period e.g. `Lib.Errors`.
+	qualifierEnd := start - 1
+	qualifierStart := qualifierEnd
+	for qualifierStart > 0 && (isIdentifierChar(src[qualifierStart-1]) || src[qualifierStart-1] == '.') {
+		qualifierStart--
+	}
+	qualifier := strings.Split(string(src[qualifierStart:qualifierEnd]), ".")
+
+	path := ast.PathEnclosingPos(doc.File, token.Pos(qualifierStart))
+	var scope *Symbol
+	for i, name := range qualifier {
+		switch {
+		case name == "":
+			return lsp.NewCompletionResponse(id, items)
+		case i == 0 && (name == "this" || name == "super"):
+			scope = enclosingContract(doc, path)
+		case i == 0:
+			scope = s.typeScope(s.lookup(doc, path, name, token.Pos(qualifierStart)))
+		default:
+			scope = s.typeScope(s.member(scope, name))
+		}
+		if scope == nil {
+			if i == 0 && len(qualifier) == 1 && isGlobalReceiver(name) && s.lookup(doc, path, name, token.Pos(qualifierStart)) == nil {
+				return lsp.NewCompletionResponse(id, globalCompletions(doc, name))
+			}
+			// The cheatcodes of the Foundry tests without forge-std.
+			if lib := s.foundryLibraryOf(doc, name, nil); lib != nil && len(qualifier) == 1 {
+				items = lib.completions(nil)
+			}
+			return lsp.NewCompletionResponse(id, items)
+		}
+	}
+
+	members := s.members(scope)
+	for _, sym := range members {
+		items = append(items, lsp.CompletionItem{
+			Label:  sym.Name.Name,
+			Kind:   completionKind(sym),
+			Detail: declarationHeader(sym),
+		})
+	}
+	if lib := s.foundryLibraryOf(doc, "", scope); lib != nil {
+		items = append(items, lib.completions(members)...)
+	}
+	return lsp.NewCompletionResponse(id, items)
+}
+
+// members returns all of the members of a scope returned by scopeOf,
+// sorted by name. The members of the bases are included, unless they are
+// shadowed, and so are the symbols imported into the file of a unit alias.
+func (s *State) members(scope *Symbol) []*Symbol {
+	res := []*Symbol{}
+	seen := map[string]bool{}
+	add := func(sym *Symbol) {
+		if !seen[sym.Name.Name] {
+			seen[sym.Name.Name] = true
+			res = append(res, sym)
+		}
+	}
+
+	switch n := scope.Node.(type) {
+	case *ast.ContractDeclaration:
+		visited := map[*ast.ContractDeclaration]bool{}
+		var visit func(doc *Document, c *ast.ContractDeclaration)
+		visit = func(doc *Document, c *ast.ContractDeclaration) {
+			if visited[c] {
+				return
+			}
+			visited[c] = true
+			for _, decl := range c.Body {
+				if id := declaredName(decl); id != nil {
+					add(&Symbol{Doc: doc, Name: id, Node: decl})
+				}
+			}
+			// The most derived base comes last in the inheritance list.
+			bases := s.bases(doc, c)
+			for i := len(bases) - 1; i >= 0; i-- {
+				visit(bases[i].Doc, bases[i].Node.(*ast.ContractDeclaration))
+			}
+		}
+		visit(scope.Doc, n)
+	case *ast.StructDeclaration:
+		for _, member := range n.Members {
+			add(&Symbol{Doc: scope.Doc, Name: member.Name, Node: member})
+		}
+	case *ast.EnumDeclaration:
+		for _, member := range n.Members {
+			add(&Symbol{Doc: scope.Doc, Name: member, Node: n})
+		}
+	case *ast.ImportDirective:
+		if target := s.ImportTarget(scope.Doc, n); target != nil {
+			for _, sym := range s.fileSymbols(target, map[*Document]bool{}) {
+				add(sym)
+			}
+		}
+	}
+
+	slices.SortFunc(res, func(a, b *Symbol) int { return strings.Compare(a.Name.Name, b.Name.Name) })
+	return res
+}
+
+// fileSymbols returns the top-level declarations of the file and the
+// symbols imported into it, the same ones that lookupFile can find.
+func (s *State) fileSymbols(doc *Document, visited map[*Document]bool) []*Symbol {
+	if visited[doc] {
+		return nil
+	}
+	visited[doc] = true
+
+	res := []*Symbol{}
+	for _, decl := range doc.File.Declarations {
+		if id := declaredName(decl); id != nil {
+			res = append(res, &Symbol{Doc: doc, Name: id, Node: decl})
+		}
+	}
+	for _, decl := range doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok {
+			continue
+		}
+		switch {
+		case imp.Alias != nil:
+			res = append(res, &Symbol{Doc: doc, Name: imp.Alias, Node: imp})
+		case imp.Symbols != nil:
+			for _, symbol := range imp.Symbols {
+				if symbol.Alias != nil {
+					res = append(res, &Symbol{Doc: doc, Name: symbol.Alias, Node: symbol})
+				} else if sym := s.follow(&Symbol{Doc: doc, Name: symbol.Name, Node: symbol}); sym != nil {
+					res = append(res, sym)
+				}
+			}
+		default:
+			if target := s.ImportTarget(doc, imp); target != nil {
+				res = append(res, s.fileSymbols(target, visited)...)
+			}
+		}
+	}
+	return res
+}
+
+func completionKind(sym *Symbol) lsp.CompletionItemKind {
+	switch n := sym.Node.(type) {
+	case *ast.ContractDeclaration:
+		switch n.Kind {
+		case token.INTERFACE:
+			return lsp.CompletionItemInterface
+		case token.LIBRARY:
+			return lsp.CompletionItemModule
+		}
+		return lsp.CompletionItemClass
+	case *ast.FunctionDeclaration:
+		return lsp.CompletionItemFunction
+	case *ast.ModifierDeclaration:
+		return lsp.CompletionItemMethod
+	case *ast.EventDeclaration:
+		return lsp.CompletionItemEvent
+	case *ast.ErrorDeclaration, *ast.TypeDeclaration:
+		return lsp.CompletionItemClass
+	case *ast.StructDeclaration:
+		return lsp.CompletionItemStruct
+	case *ast.EnumDeclaration:
+		if sym.Name != n.Name {
+			return lsp.CompletionItemEnumMember
+		}
+		return lsp.CompletionItemEnum
+	case *ast.VariableDeclaration:
+		return lsp.CompletionItemField
+	case *ast.ImportDirective:
+		return lsp.CompletionItemModule
+	}
+	return 0
+}
+
+func isIdentifierChar(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || ch == '_' || ch == '$'
+}
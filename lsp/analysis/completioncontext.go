@@ -0,0 +1,516 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// CompletionContext is the kind of code expected at the position of a
+// completion, see classifyCompletion.
+type CompletionContext int
+
+const (
+	ContextGeneral      CompletionContext = iota // anything, the context isn't known
+	ContextNone                                  // in a comment or a string literal
+	ContextType                                  // the type of a declaration, the key or value of a mapping, after `new`
+	ContextInheritance                           // the inheritance list after `is`
+	ContextOverride                              // after `override` starting a contract member
+	ContextOverrideList                          // in the parentheses of `override(...)`
+	ContextEmit                                  // after `emit`
+	ContextRevert                                // after `revert`
+	ContextModifier                              // the modifier invocations of a function header
+)
+
+// completionContext is the context of a completion with its payload.
+type completionContext struct {
+	kind     CompletionContext
+	function string // the name of the overriding function in ContextOverrideList; or "" if it's not known
+	member   bool   // does the declaration start a contract member? In ContextType.
+	creation bool   // is it after `new`? In ContextType.
+}
+
+// classifyCompletion finds the context of the completion of the name
+// starting at the position from the tokens before it. The document is
+// usually incomplete while typing, so the tokens are more reliable than
+// the AST here.
+func classifyCompletion(tokens []token.Token, pos token.Pos) completionContext {
+	before := []token.Token{}
+	for _, tkn := range tokens {
+		if tkn.Pos >= pos {
+			break
+		}
+		switch tkn.Type {
+		case token.COMMENT_LITERAL, token.STRING_LITERAL, token.HEX_STRING_LITERAL, token.UNICODE_STRING_LITERAL:
+			if pos < tkn.Pos+token.Pos(len(tkn.Literal)) {
+				return completionContext{kind: ContextNone}
+			}
+			if tkn.Type == token.COMMENT_LITERAL {
+				continue
+			}
+		case token.ILLEGAL:
+			continue
+		}
+		before = append(before, tkn)
+	}
+
+	at := func(i int) token.TokenType {
+		if i < 0 || i >= len(before) {
+			return token.ILLEGAL
+		}
+		return before[i].Type
+	}
+	last := len(before) - 1
+	switch {
+	case at(last) == token.EMIT:
+		return completionContext{kind: ContextEmit}
+	case at(last) == token.IDENTIFIER && before[last].Literal == "revert" && at(last-1) != token.PERIOD:
+		return completionContext{kind: ContextRevert}
+	case at(last) == token.NEW:
+		return completionContext{kind: ContextType, creation: true}
+	case at(last) == token.LPAREN && at(last-1) == token.MAPPING, at(last) == token.DOUBLE_ARROW:
+		return completionContext{kind: ContextType}
+	}
+	if function, ok := overrideList(before); ok {
+		return completionContext{kind: ContextOverrideList, function: function}
+	}
+	if inheritanceList(before) {
+		return completionContext{kind: ContextInheritance}
+	}
+	if at(last) == token.OVERRIDE && memberStart(before[:last]) {
+		return completionContext{kind: ContextOverride}
+	}
+	if functionHeader(before) {
+		return completionContext{kind: ContextModifier}
+	}
+	if parameterStart(before) {
+		return completionContext{kind: ContextType}
+	}
+	if memberStart(before) {
+		return completionContext{kind: ContextType, member: true}
+	}
+	return completionContext{kind: ContextGeneral}
+}
+
+// overrideList reports whether the tokens end in the parentheses of an
+// override specifier and returns the name of the function declaring it.
+func overrideList(before []token.Token) (string, bool) {
+	for i := len(before) - 1; i >= 0; i-- {
+		switch before[i].Type {
+		case token.IDENTIFIER, token.PERIOD, token.COMMA:
+			continue
+		case token.LPAREN:
+			if i == 0 || before[i-1].Type != token.OVERRIDE {
+				return "", false
+			}
+			for j := i - 2; j >= 0 && !separator(before[j].Type); j-- {
+				if before[j].Type == token.FUNCTION && j+1 < len(before) && before[j+1].Type == token.IDENTIFIER {
+					return before[j+1].Literal, true
+				}
+			}
+			return "", true
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// inheritanceList reports whether the tokens end in the list of the bases
+// of a contract, the arguments of the base constructors included.
+func inheritanceList(before []token.Token) bool {
+	for i := len(before) - 1; i >= 0; i-- {
+		switch before[i].Type {
+		case token.IDENTIFIER, token.PERIOD, token.COMMA:
+		case token.RPAREN:
+			i = matchingParen(before, i)
+		case token.IS:
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// functionHeader reports whether the tokens end in the header of a
+// function after its parameters, where the modifiers are invoked. The
+// visibility, the mutability, the override specifier and the other
+// modifiers may come before.
+func functionHeader(before []token.Token) bool {
+	for i := len(before) - 1; i >= 0; i-- {
+		switch before[i].Type {
+		case token.IDENTIFIER, token.PERIOD, token.PUBLIC, token.EXTERNAL, token.INTERNAL, token.PRIVATE,
+			token.VIEW, token.PURE, token.PAYABLE, token.VIRTUAL, token.OVERRIDE:
+		case token.RPAREN:
+			i = matchingParen(before, i)
+			if i <= 0 {
+				return false
+			}
+			switch before[i-1].Type {
+			case token.CONSTRUCTOR, token.FALLBACK, token.RECEIVE:
+				return true
+			case token.IDENTIFIER:
+				if i >= 2 && before[i-2].Type == token.FUNCTION {
+					return true
+				}
+			case token.OVERRIDE:
+			default:
+				// The parameters of a function type or of a modifier.
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// parameterStart reports whether the tokens end at the start of a
+// parameter of a function, a modifier, an event or an error.
+func parameterStart(before []token.Token) bool {
+	last := len(before) - 1
+	if last < 0 || before[last].Type != token.LPAREN && before[last].Type != token.COMMA {
+		return false
+	}
+	depth := 0
+	for i := last; i >= 0; i-- {
+		switch typ := before[i].Type; {
+		case typ == token.RPAREN:
+			depth++
+		case typ == token.LPAREN && depth > 0:
+			depth--
+		case typ == token.LPAREN:
+			if i == 0 {
+				return false
+			}
+			switch before[i-1].Type {
+			case token.RETURNS, token.CONSTRUCTOR, token.FALLBACK, token.RECEIVE, token.FUNCTION:
+				return true
+			case token.IDENTIFIER:
+				if i < 2 {
+					return false
+				}
+				prev := before[i-2]
+				return prev.Type == token.FUNCTION || prev.Type == token.EVENT || prev.Type == token.MODIFIER ||
+					prev.Type == token.IDENTIFIER && prev.Literal == "error"
+			}
+			return false
+		case separator(typ):
+			return false
+		}
+	}
+	return false
+}
+
+// memberStart reports whether the tokens end where a member of a contract
+// starts.
+func memberStart(before []token.Token) bool {
+	last := len(before) - 1
+	if last < 0 || !separator(before[last].Type) {
+		return false
+	}
+	// The innermost brace not closed is the body of a contract.
+	depth := 0
+	for i := last; i >= 0; i-- {
+		switch before[i].Type {
+		case token.RBRACE:
+			depth++
+		case token.LBRACE:
+			if depth > 0 {
+				depth--
+				continue
+			}
+			start := i
+			for start > 0 && !separator(before[start-1].Type) {
+				start--
+			}
+			switch before[start].Type {
+			case token.ABSTRACT, token.CONTRACT, token.INTERFACE, token.LIBRARY:
+				return true
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// matchingParen returns the index of the parenthesis opening the one
+// closed at the index; or -1 if it's not opened.
+func matchingParen(tokens []token.Token, i int) int {
+	depth := 0
+	for ; i >= 0; i-- {
+		switch tokens[i].Type {
+		case token.RPAREN:
+			depth++
+		case token.LPAREN:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func separator(typ token.TokenType) bool {
+	return typ == token.SEMICOLON || typ == token.LBRACE || typ == token.RBRACE
+}
+
+// elementaryCompletions are the elementary types offered by the completion,
+// the other sizes are typed out.
+var elementaryCompletions = []string{
+	"address", "bool", "bytes", "bytes32", "bytes4", "int256", "string",
+	"uint128", "uint16", "uint256", "uint32", "uint64", "uint8",
+}
+
+// memberKeywords start the contract members other than the variables.
+var memberKeywords = []string{
+	"constructor", "enum", "error", "event", "fallback", "function",
+	"modifier", "receive", "struct", "using",
+}
+
+// generalKeywords are offered where the context isn't known.
+var generalKeywords = []string{
+	"abstract", "assembly", "break", "calldata", "constant", "constructor",
+	"continue", "contract", "delete", "do", "else", "emit", "enum", "event",
+	"external", "for", "function", "if", "immutable", "import", "interface",
+	"internal", "library", "mapping", "memory", "modifier", "new", "override",
+	"payable", "pragma", "private", "public", "pure", "return", "returns",
+	"storage", "struct", "try", "unchecked", "using", "view", "virtual",
+	"while",
+}
+
+// contextCompletions lists the names that fit the context of the
+// completion of the name starting at the position. The sortText ranks the
+// names by their relevance in the context, the most relevant ones come
+// first e.g. the local variables before the state variables and the
+// builtins in the statements.
+func (s *State) contextCompletions(doc *Document, pos token.Pos) []lsp.CompletionItem {
+	ctx := classifyCompletion(doc.tokens(), pos)
+	path := ast.PathEnclosingPos(doc.File, pos)
+	contract := enclosingContract(doc, path)
+
+	items := []lsp.CompletionItem{}
+	seen := map[string]bool{}
+	add := func(label string, kind lsp.CompletionItemKind, detail string, rank int) {
+		if seen[label] {
+			return
+		}
+		seen[label] = true
+		items = append(items, lsp.CompletionItem{
+			Label:    label,
+			Kind:     kind,
+			Detail:   detail,
+			SortText: fmt.Sprintf("%d_%s", rank, label),
+		})
+	}
+	addSymbol := func(sym *Symbol, rank int) {
+		add(sym.Name.Name, completionKind(sym), declarationHeader(sym), rank)
+	}
+	addKeywords := func(keywords []string, rank int) {
+		for _, keyword := range keywords {
+			add(keyword, lsp.CompletionItemKeyword, "", rank)
+		}
+	}
+
+	switch ctx.kind {
+	case ContextNone:
+	case ContextOverride:
+		if contract != nil {
+			for _, fn := range s.overridableFunctions(contract) {
+				// The functions not implemented yet are the ones to override.
+				rank := 1
+				if fn.Node.(*ast.FunctionDeclaration).Body == nil {
+					rank = 0
+				}
+				addSymbol(fn, rank)
+			}
+		}
+	case ContextOverrideList:
+		if contract != nil {
+			for _, base := range s.overriddenBases(contract, ctx.function) {
+				addSymbol(base, 0)
+			}
+		}
+	default:
+		for _, sym := range s.visibleSymbols(doc, path, pos) {
+			if rank, ok := contextRank(ctx, doc, sym, path, contract); ok {
+				addSymbol(sym, rank)
+			}
+		}
+		switch ctx.kind {
+		case ContextType:
+			if !ctx.creation {
+				addKeywords([]string{"mapping"}, 1)
+			}
+			addKeywords(elementaryCompletions, 1)
+			if ctx.member {
+				addKeywords(memberKeywords, 3)
+			}
+		case ContextGeneral:
+			for _, name := range sortedKeys(builtins) {
+				add(name, 0, "", 3)
+			}
+			addKeywords(elementaryCompletions, 4)
+			addKeywords(generalKeywords, 4)
+		}
+	}
+
+	slices.SortFunc(items, func(a, b lsp.CompletionItem) int { return strings.Compare(a.SortText, b.SortText) })
+	return items
+}
+
+// contextRank returns the rank of the symbol in the context of the
+// completion; or false if it doesn't fit the context.
+func contextRank(ctx completionContext, doc *Document, sym *Symbol, path []ast.Node, contract *Symbol) (int, bool) {
+	switch ctx.kind {
+	case ContextEmit:
+		_, ok := sym.Node.(*ast.EventDeclaration)
+		return 0, ok
+	case ContextRevert:
+		_, ok := sym.Node.(*ast.ErrorDeclaration)
+		return 0, ok
+	case ContextModifier:
+		_, ok := sym.Node.(*ast.ModifierDeclaration)
+		return 0, ok
+	case ContextInheritance:
+		switch n := sym.Node.(type) {
+		case *ast.ContractDeclaration:
+			self := contract != nil && contract.Node == ast.Node(n)
+			return 0, n.Kind != token.LIBRARY && !self
+		case *ast.ImportDirective:
+			// The qualified names of the bases e.g. `is Tokens.ERC20`.
+			return 1, true
+		}
+		return 0, false
+	case ContextType:
+		switch n := sym.Node.(type) {
+		case *ast.ContractDeclaration:
+			switch {
+			case n.Kind == token.LIBRARY:
+				// The qualified names of the types declared in the library.
+				return 2, true
+			case ctx.creation && (n.Kind == token.INTERFACE || n.Abstract):
+				return 0, false
+			}
+			return 0, true
+		case *ast.StructDeclaration, *ast.TypeDeclaration:
+		case *ast.EnumDeclaration:
+			if sym.Name != n.Name {
+				return 0, false
+			}
+		case *ast.ImportDirective:
+			return 2, true
+		default:
+			return 0, false
+		}
+		// The arrays of the other types can be created too.
+		if ctx.creation {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	// The general context, the local variables and the parameters first,
+	// then the members of the contracts.
+	switch sym.Node.(type) {
+	case *ast.VariableDeclaration, *ast.Param:
+		for _, node := range path {
+			switch node.(type) {
+			case *ast.FunctionDeclaration, *ast.ModifierDeclaration:
+				if sym.Doc == doc && node.Start() <= sym.Name.Start() && sym.Name.End() <= node.End() {
+					return 0, true
+				}
+			}
+		}
+		return 1, true
+	case *ast.FunctionDeclaration, *ast.ModifierDeclaration:
+		return 1, true
+	}
+	return 2, true
+}
+
+// visibleSymbols returns the declarations of the names visible at the
+// position, see visibleNames, with the import aliases followed. The
+// builtins are left out.
+func (s *State) visibleSymbols(doc *Document, path []ast.Node, pos token.Pos) []*Symbol {
+	res := []*Symbol{}
+	seen := map[string]bool{}
+	for _, name := range s.visibleNames(doc, path, pos) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if sym := s.lookup(doc, path, name, pos); sym != nil {
+			// The aliases of the units are kept, they qualify the names.
+			if _, ok := sym.Node.(*ast.ImportSymbol); ok {
+				sym = s.follow(sym)
+			}
+			if sym != nil && sym.Name.Name != name {
+				// The alias of the symbol e.g. `V` for `{IVault as V}`.
+				sym = &Symbol{Doc: sym.Doc, Name: &ast.Identifier{NamePos: sym.Name.NamePos, Name: name}, Node: sym.Node}
+			}
+			if sym != nil {
+				res = append(res, sym)
+			}
+		}
+	}
+	return res
+}
+
+// overridableFunctions returns the virtual functions of the bases of the
+// contract which it doesn't declare, the most derived ones first. The
+// functions of the interfaces are virtual too.
+func (s *State) overridableFunctions(contract *Symbol) []*Symbol {
+	res := []*Symbol{}
+	seen := map[string]bool{}
+	for _, decl := range contract.Node.(*ast.ContractDeclaration).Body {
+		if id := declaredName(decl); id != nil {
+			seen[id.Name] = true
+		}
+	}
+	linearized := s.linearize(contract)
+	if len(linearized) == 0 {
+		return res
+	}
+	for _, base := range linearized[1:] {
+		c := base.Node.(*ast.ContractDeclaration)
+		for _, decl := range c.Body {
+			fn, ok := decl.(*ast.FunctionDeclaration)
+			if !ok || fn.Kind != token.FUNCTION || fn.Name == nil || seen[fn.Name.Name] {
+				continue
+			}
+			if fn.Virtual || c.Kind == token.INTERFACE {
+				seen[fn.Name.Name] = true
+				res = append(res, &Symbol{Doc: base.Doc, Name: fn.Name, Node: fn})
+			}
+		}
+	}
+	return res
+}
+
+// overriddenBases returns the bases of the contract declaring the function,
+// which its override specifier may list; or all of the bases if the name
+// of the function is not known.
+func (s *State) overriddenBases(contract *Symbol, function string) []*Symbol {
+	res := []*Symbol{}
+	linearized := s.linearize(contract)
+	if len(linearized) == 0 {
+		return res
+	}
+	for _, base := range linearized[1:] {
+		declares := function == ""
+		for _, decl := range base.Node.(*ast.ContractDeclaration).Body {
+			if id := declaredName(decl); id != nil && id.Name == function {
+				declares = true
+			}
+		}
+		if declares {
+			res = append(res, base)
+		}
+	}
+	return res
+}
@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const completionContextSrc = `pragma solidity ^0.8.0;
+
+interface IVault {
+    function deposit(uint256 amount) external;
+    function withdraw(uint256 amount) external;
+}
+
+library Math {
+    function min(uint256 a, uint256 b) internal pure returns (uint256) {
+        return a < b ? a : b;
+    }
+}
+
+abstract contract Ownable {
+    address owner;
+    error NotOwner(address caller);
+    modifier onlyOwner() {
+        if (msg.sender != owner) revert NotOwner(msg.sender);
+        _;
+    }
+    function transferOwnership(address to) public virtual onlyOwner {
+        owner = to;
+    }
+}
+
+contract Pausable {
+    bool paused;
+    event Paused();
+    modifier whenNotPaused() {
+        _;
+    }
+    function pause() external virtual {
+        paused = true;
+        emit Paused();
+    }
+}
+
+contract Vault is IVault, Ownable, Pausable {
+    struct Position {
+        uint256 amount;
+    }
+    mapping(address => Position) positions;
+    uint256 total;
+    event Deposited(address from, uint256 amount);
+    error ZeroAmount();
+
+    function deposit(uint256 amount) external override(IVault) whenNotPaused {
+        if (amount == 0) revert ZeroAmount();
+        uint256 fee = Math.min(amount, 100);
+        positions[msg.sender].amount += amount - fee;
+        emit Deposited(msg.sender, amount);
+    }
+
+    function pause() external override(Pausable) onlyOwner {
+        Vault v = new Vault();
+    }
+}
+`
+
+// completeAfter returns the completions at the end of the first occurrence
+// of the text in the source.
+func completeAfter(t *testing.T, src, text string) []lsp.CompletionItem {
+	t.Helper()
+	i := strings.Index(src, text)
+	if i < 0 {
+		t.Fatalf("Expected %q in the source", text)
+	}
+	i += len(text)
+	line := strings.Count(src[:i], "\n")
+	character := i - strings.LastIndex(src[:i], "\n") - 1
+	s := NewState()
+	uri := "file:///ws/Vault.sol"
+	s.OpenDocument(uri, 1, src)
+	return s.Completion(lsp.IntID(1), uri, lsp.Position{Line: uint(line), Character: uint(character)}).Result
+}
+
+func completionLabels(items []lsp.CompletionItem) string {
+	labels := []string{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	return strings.Join(labels, ",")
+}
+
+func Test_CompletionInheritance(t *testing.T) {
+	items := completeAfter(t, completionContextSrc, "contract Vault is IVault, ")
+	// Neither the library nor the contract itself.
+	if got := completionLabels(items); got != "IVault,Ownable,Pausable" {
+		t.Errorf("Expected IVault,Ownable,Pausable, got %s", got)
+	}
+}
+
+func Test_CompletionOverride(t *testing.T) {
+	src := strings.Replace(completionContextSrc, "new Vault();\n    }\n", "new Vault();\n    }\n    override \n", 1)
+	items := completeAfter(t, src, "    override ")
+	// The function not implemented comes first, the ones declared by Vault
+	// are left out.
+	if got := completionLabels(items); got != "withdraw,transferOwnership" {
+		t.Errorf("Expected withdraw,transferOwnership, got %s", got)
+	}
+	if len(items) > 0 && items[0].Detail != "function withdraw(uint256 amount) external" {
+		t.Errorf("Expected the header of withdraw, got %q", items[0].Detail)
+	}
+}
+
+func Test_CompletionOverrideList(t *testing.T) {
+	if got := completionLabels(completeAfter(t, completionContextSrc, "deposit(uint256 amount) external override(")); got != "IVault" {
+		t.Errorf("Expected only IVault declaring deposit, got %s", got)
+	}
+	if got := completionLabels(completeAfter(t, completionContextSrc, "pause() external override(")); got != "Pausable" {
+		t.Errorf("Expected only Pausable declaring pause, got %s", got)
+	}
+}
+
+func Test_CompletionEmitAndRevert(t *testing.T) {
+	if got := completionLabels(completeAfter(t, completionContextSrc, "emit Deposited")); got != "Deposited,Paused" {
+		t.Errorf("Expected the events, got %s", got)
+	}
+	if got := completionLabels(completeAfter(t, completionContextSrc, "if (amount == 0) revert ")); got != "NotOwner,ZeroAmount" {
+		t.Errorf("Expected the errors, got %s", got)
+	}
+
+	// The statement is incomplete while typing.
+	src := strings.Replace(completionContextSrc, "emit Deposited(msg.sender, amount);", "emit ", 1)
+	if got := completionLabels(completeAfter(t, src, "amount - fee;\n        emit ")); got != "Deposited,Paused" {
+		t.Errorf("Expected the events in the incomplete statement, got %s", got)
+	}
+}
+
+func Test_CompletionModifier(t *testing.T) {
+	if got := completionLabels(completeAfter(t, completionContextSrc, "override(IVault) ")); got != "onlyOwner,whenNotPaused" {
+		t.Errorf("Expected the modifiers, got %s", got)
+	}
+}
+
+func Test_CompletionType(t *testing.T) {
+	tests := []struct {
+		name     string
+		after    string
+		top      string
+		included []string
+		excluded []string
+	}{
+		{"mapping key", "    mapping(", "IVault", []string{"Position", "address", "uint256", "mapping", "Math"}, []string{"total", "deposit", "msg", "if", "function"}},
+		{"mapping value", "mapping(address => ", "IVault", []string{"Position", "uint256"}, []string{"positions", "onlyOwner"}},
+		{"parameter", "event Deposited(", "IVault", []string{"Position", "uint256"}, []string{"amount", "total", "Deposited"}},
+		{"member", "positions;\n    ", "IVault", []string{"Position", "uint256", "function", "event"}, []string{"total", "pause"}},
+		// The contracts that can be created come first.
+		{"creation", "= new ", "Pausable", []string{"Vault", "Position", "bytes"}, []string{"IVault", "Ownable", "mapping", "v"}},
+	}
+	for _, tt := range tests {
+		items := completeAfter(t, completionContextSrc, tt.after)
+		if len(items) == 0 || items[0].Label != tt.top {
+			t.Errorf("%s: expected %s first, got %s", tt.name, tt.top, completionLabels(items))
+			continue
+		}
+		labels := map[string]bool{}
+		for _, item := range items {
+			labels[item.Label] = true
+		}
+		for _, label := range tt.included {
+			if !labels[label] {
+				t.Errorf("%s: expected %s, got %s", tt.name, label, completionLabels(items))
+			}
+		}
+		for _, label := range tt.excluded {
+			if labels[label] {
+				t.Errorf("%s: expected no %s, got %s", tt.name, label, completionLabels(items))
+			}
+		}
+	}
+}
+
+func Test_CompletionGeneral(t *testing.T) {
+	items := completeAfter(t, completionContextSrc, "uint256 fee = Math.min(amount, 100);\n        ")
+	// The parameter and the local variable first, then the members.
+	if got := completionLabels(items); !strings.HasPrefix(got, "amount,fee,") {
+		t.Errorf("Expected amount and fee first, got %s", got)
+	}
+	labels := map[string]bool{}
+	for _, item := range items {
+		labels[item.Label] = true
+	}
+	for _, label := range []string{"total", "positions", "pause", "onlyOwner", "Math", "Position", "msg", "require", "uint256", "if", "return"} {
+		if !labels[label] {
+			t.Errorf("Expected %s in the general completions, got %s", label, completionLabels(items))
+		}
+	}
+
+	if items := completeAfter(t, completionContextSrc, "pragma solidity ^0.8.0;\n\ninterface IVault {\n    function deposit(uint256 amount) external;\n    function withdraw(uint256 amount) external;\n}\n"); len(items) == 0 {
+		t.Errorf("Expected the general completions at the file level, got none")
+	}
+}
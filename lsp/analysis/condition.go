@@ -0,0 +1,229 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// conditionDiagnostics reports the conditions whose value is known at the
+// analysis time: the literals and the constants folded together, the
+// comparisons of an expression with itself and the comparisons of an
+// unsigned value with zero e.g. `amount >= 0`. The checks can be disabled
+// one by one with their codes in the [detectors] section:
+//
+//   - always-true-condition: the check of `require` or `assert` never
+//     fails, so it has no effect;
+//   - always-false-condition: the check always fails, so the code always
+//     reverts. `require(false, "not implemented")` is a deliberate stub,
+//     so it's only an information;
+//   - unreachable-code: the branch of an `if` statement is never executed.
+//
+// The messages show the values of the constants in the condition.
+func (s *State) conditionDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(node ast.Node, severity lsp.DiagnosticSeverity, code, message string, tags ...lsp.DiagnosticTag) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(node)),
+			Severity: severity,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+			Tags:     tags,
+		})
+	}
+
+	consts := s.constantsOf(doc, nil)
+	ast.Inspect(doc.File, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpression:
+			fn, ok := n.Function.(*ast.Identifier)
+			if !ok || fn.Name != "require" && fn.Name != "assert" || len(n.Args) == 0 {
+				return true
+			}
+			if s.lookup(doc, ast.PathEnclosingPos(doc.File, fn.Start()), fn.Name, fn.Start()) != nil {
+				return true
+			}
+			value, detail, ok := s.evalCondition(doc, n.Args[0], consts)
+			switch {
+			case !ok:
+			case value:
+				report(n.Args[0], lsp.SeverityWarning, "always-true-condition",
+					fmt.Sprintf("The condition is always true%s; the check has no effect", detail))
+			case len(n.Args) > 1 && isStringLiteral(n.Args[1]):
+				report(n.Args[0], lsp.SeverityInformation, "always-false-condition",
+					fmt.Sprintf("The condition is always false%s; this code always reverts with %s", detail, n.Args[1].(*ast.BasicLit).Value))
+			default:
+				report(n.Args[0], lsp.SeverityError, "always-false-condition",
+					fmt.Sprintf("The condition is always false%s; this code always reverts", detail))
+			}
+		case *ast.IfStatement:
+			if branch, message := s.unreachableBranch(doc, n, consts); branch != nil {
+				report(branch, lsp.SeverityHint, "unreachable-code", message, lsp.TagUnnecessary)
+			}
+		}
+		return true
+	})
+	return res
+}
+
+// unreachableBranch returns the branch of the `if` statement that is never
+// executed, because the value of the condition is known at the analysis
+// time, and the message explaining it; or nil.
+func (s *State) unreachableBranch(doc *Document, n *ast.IfStatement, consts constants) (ast.Statement, string) {
+	value, detail, ok := s.evalCondition(doc, n.Condition, consts)
+	switch {
+	case !ok:
+	case !value:
+		return n.Consequence, fmt.Sprintf("Unreachable code: the condition is always false%s", detail)
+	case n.Alternative != nil:
+		return n.Alternative, fmt.Sprintf("Unreachable code: the condition is always true%s", detail)
+	}
+	return nil, ""
+}
+
+// evalCondition returns the value of the condition if it's known at the
+// analysis time, and the detail explaining it e.g. " (`MAX_FEE > 10000`
+// is `500 > 10000`)"; or an empty detail if the condition is a literal.
+func (s *State) evalCondition(doc *Document, x ast.Expression, consts constants) (value bool, detail string, ok bool) {
+	switch x := x.(type) {
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 {
+			return s.evalCondition(doc, x.Elements[0], consts)
+		}
+	case *ast.UnaryExpression:
+		if x.Operator == token.NOT {
+			value, detail, ok := s.evalCondition(doc, x.Operand, consts)
+			return !value, detail, ok
+		}
+	case *ast.BinaryExpression:
+		if x.Operator == token.AND || x.Operator == token.OR {
+			// Either side decides the value if it's false for `&&` or true
+			// for `||`, otherwise both sides have to be known.
+			decisive := x.Operator == token.OR
+			left, ldetail, lok := s.evalCondition(doc, x.Left, consts)
+			right, rdetail, rok := s.evalCondition(doc, x.Right, consts)
+			switch {
+			case lok && left == decisive:
+				return decisive, ldetail, true
+			case rok && right == decisive:
+				return decisive, rdetail, true
+			case lok && rok:
+				return !decisive, ldetail + rdetail, true
+			}
+			return false, "", false
+		}
+	}
+
+	if value, ok := foldBool(x, consts); ok {
+		src, folded := ast.ExprString(x), foldedString(x, consts)
+		if folded != src {
+			detail = fmt.Sprintf(" (`%s` is `%s`)", src, folded)
+		}
+		return value, detail, true
+	}
+
+	comparison, ok := x.(*ast.BinaryExpression)
+	if !ok {
+		return false, "", false
+	}
+	switch comparison.Operator {
+	case token.EQUAL, token.LESS_THAN_OR_EQUAL, token.GREATER_THAN_OR_EQUAL,
+		token.NOT_EQUAL, token.LESS_THAN, token.GREATER_THAN:
+	default:
+		return false, "", false
+	}
+	if ast.ExprString(comparison.Left) == ast.ExprString(comparison.Right) && !hasSideEffects(comparison.Left) {
+		value := comparison.Operator == token.EQUAL || comparison.Operator == token.LESS_THAN_OR_EQUAL ||
+			comparison.Operator == token.GREATER_THAN_OR_EQUAL
+		return value, fmt.Sprintf(" (both sides are `%s`)", ast.ExprString(comparison.Left)), true
+	}
+
+	// An unsigned value is never below zero, whichever side it's on.
+	unsigned, operator := comparison.Left, comparison.Operator
+	if zero, ok := foldInt(comparison.Left, consts); ok && zero.Sign() == 0 {
+		unsigned = comparison.Right
+		switch operator {
+		case token.LESS_THAN_OR_EQUAL:
+			operator = token.GREATER_THAN_OR_EQUAL
+		case token.GREATER_THAN:
+			operator = token.LESS_THAN
+		}
+	} else if zero, ok := foldInt(comparison.Right, consts); !ok || zero.Sign() != 0 {
+		return false, "", false
+	}
+	if operator != token.GREATER_THAN_OR_EQUAL && operator != token.LESS_THAN || !s.isUnsigned(doc, unsigned) {
+		return false, "", false
+	}
+	return operator == token.GREATER_THAN_OR_EQUAL, fmt.Sprintf(" (`%s` is unsigned, so it's never negative)", ast.ExprString(unsigned)), true
+}
+
+// isUnsigned reports whether the expression is an unsigned integer: a
+// value declared as `uint`, the length of an array or the balance of an
+// address.
+func (s *State) isUnsigned(doc *Document, x ast.Expression) bool {
+	if access, ok := x.(*ast.MemberAccessExpression); ok {
+		if name := access.Member.Name; name == "length" || name == "balance" {
+			return true
+		}
+	}
+	_, t := s.typeOf(doc, ast.PathEnclosingPos(doc.File, x.Start()), x)
+	elementary, ok := t.(*ast.ElementaryType)
+	return ok && strings.HasPrefix(elementary.Value, "uint")
+}
+
+// foldedString returns the source of the expression with the constants
+// replaced by their values e.g. `500 > 10000` for `MAX_FEE > 10000`.
+func foldedString(x ast.Expression, consts constants) string {
+	if value, ok := foldInt(x, consts); ok {
+		if _, literal := x.(*ast.BasicLit); !literal {
+			return value.String()
+		}
+	}
+	switch x := x.(type) {
+	case *ast.Identifier, *ast.MemberAccessExpression:
+		if value, ok := foldBool(x, consts); ok {
+			return fmt.Sprint(value)
+		}
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 {
+			return "(" + foldedString(x.Elements[0], consts) + ")"
+		}
+	case *ast.UnaryExpression:
+		if x.Operator == token.NOT {
+			return "!" + foldedString(x.Operand, consts)
+		}
+	case *ast.BinaryExpression:
+		return foldedString(x.Left, consts) + " " + x.Operator.String() + " " + foldedString(x.Right, consts)
+	}
+	return ast.ExprString(x)
+}
+
+// hasSideEffects reports whether evaluating the expression may change the
+// state, so that evaluating it twice may give different values.
+func hasSideEffects(x ast.Expression) bool {
+	found := false
+	ast.Inspect(x, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpression, *ast.AssignmentExpression, *ast.NewExpression:
+			found = true
+		case *ast.UnaryExpression:
+			if n.Operator == token.INC || n.Operator == token.DEC {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+func isStringLiteral(x ast.Expression) bool {
+	lit, ok := x.(*ast.BasicLit)
+	return ok && lit.Kind == token.STRING_LITERAL
+}
@@ -0,0 +1,155 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"testing"
+)
+
+func Test_ConditionDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		code     string
+		severity lsp.DiagnosticSeverity
+		message  string
+	}{
+		{
+			"unsigned is never negative",
+			"require(amount >= 0);",
+			"always-true-condition", lsp.SeverityWarning,
+			"The condition is always true (`amount` is unsigned, so it's never negative); the check has no effect",
+		},
+		{
+			"length below zero",
+			"assert(0 > shares.length);",
+			"always-false-condition", lsp.SeverityError,
+			"The condition is always false (`shares.length` is unsigned, so it's never negative); this code always reverts",
+		},
+		{
+			"same operands",
+			"require(amount == amount);",
+			"always-true-condition", lsp.SeverityWarning,
+			"The condition is always true (both sides are `amount`); the check has no effect",
+		},
+		{
+			"literal",
+			"require(true);",
+			"always-true-condition", lsp.SeverityWarning,
+			"The condition is always true; the check has no effect",
+		},
+		{
+			"constants",
+			"require(MAX_FEE > LIMIT);",
+			"always-false-condition", lsp.SeverityError,
+			"The condition is always false (`MAX_FEE > LIMIT` is `500 > 10000`); this code always reverts",
+		},
+		{
+			"decisive side",
+			"require(amount > 1 && MAX_FEE * 2 >= LIMIT);",
+			"always-false-condition", lsp.SeverityError,
+			"The condition is always false (`MAX_FEE * 2 >= LIMIT` is `1000 >= 10000`); this code always reverts",
+		},
+		{
+			"deliberate stub",
+			`require(false, "not implemented");`,
+			"always-false-condition", lsp.SeverityInformation,
+			`The condition is always false; this code always reverts with "not implemented"`,
+		},
+		{
+			"statically false branch",
+			"if (DEBUG) {\n            amount = 0;\n        }",
+			"unreachable-code", lsp.SeverityHint,
+			"Unreachable code: the condition is always false (`DEBUG` is `false`)",
+		},
+		{"unknown value", "require(amount > 0 && amount < LIMIT);", "", 0, ""},
+		{"side effects", "require(next() == next());", "", 0, ""},
+		{"signed", "require(delta >= 0);", "", 0, ""},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		s.OpenDocument("file:///ws/src/Config.sol", 1, "pragma solidity ^0.8.0;\n\nuint256 constant LIMIT = 10_000;\n")
+		uri := "file:///ws/src/Vault.sol"
+		s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+import {LIMIT} from "./Config.sol";
+
+contract Vault {
+    uint256 constant MAX_FEE = 500;
+    bool constant DEBUG = false;
+    uint256[] shares;
+    int256 delta;
+
+    function pay(uint256 amount) external {
+        `+tt.body+`
+    }
+
+    function next() internal returns (uint256) {}
+}
+`)
+
+		diagnostics := s.conditionDiagnostics(s.Documents[uri])
+		if tt.code == "" {
+			if len(diagnostics) != 0 {
+				t.Errorf("Expected no diagnostics for %s, got %v", tt.name, diagnostics)
+			}
+			continue
+		}
+		if len(diagnostics) != 1 {
+			t.Errorf("Expected 1 diagnostic for %s, got %v", tt.name, diagnostics)
+			continue
+		}
+		d := diagnostics[0]
+		if d.Code != tt.code || d.Range.Start.Line != 11 || d.Severity != tt.severity {
+			t.Errorf("Expected %s of severity %d at line 11 for %s, got %s of severity %d at line %d",
+				tt.code, tt.severity, tt.name, d.Code, d.Severity, d.Range.Start.Line)
+		}
+		if d.Message != tt.message {
+			t.Errorf("Expected %q, got %q", tt.message, d.Message)
+		}
+
+		s.Config.Disabled = []string{tt.code}
+		if diagnostics := s.conditionDiagnostics(s.Documents[uri]); len(diagnostics) != 0 {
+			t.Errorf("Expected %s to be disabled, got %v", tt.code, diagnostics)
+		}
+	}
+}
+
+func Test_ConstantsAcrossFiles(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/src/Fees.sol", 1, `pragma solidity ^0.8.0;
+
+import "./Config.sol";
+
+uint256 constant FEE = 100;
+uint256 constant TWICE = FEE + FEE;
+uint256 constant CYCLE_B = CYCLE_A * 2;
+`)
+	uri := "file:///ws/src/Config.sol"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+import "./Fees.sol";
+
+uint256 constant LIMIT = TWICE * 2;
+uint256 constant CYCLE_A = CYCLE_B + 1;
+`)
+
+	doc := s.Documents[uri]
+	tests := []struct {
+		name  string
+		value int64
+		ok    bool
+	}{
+		{"LIMIT", 400, true},
+		{"CYCLE_A", 0, false},
+	}
+	for _, tt := range tests {
+		sym := s.lookupFile(doc, tt.name, map[*Document]bool{})
+		if sym == nil {
+			t.Fatalf("Expected %s to be declared", tt.name)
+		}
+		value, ok := foldInt(sym.Name, s.constantsOf(doc, nil))
+		if ok != tt.ok || ok && value.Int64() != tt.value {
+			t.Errorf("Expected %s to fold to %d (%t), got %v (%t)", tt.name, tt.value, tt.ok, value, ok)
+		}
+	}
+}
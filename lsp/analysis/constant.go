@@ -0,0 +1,287 @@
+package analysis
+
+import (
+	"math/big"
+	"slices"
+	"solbot/ast"
+	"solbot/token"
+	"strconv"
+	"strings"
+)
+
+// constBool folds the boolean expression made of literals; ok is false if
+// the value is not known at compile time.
+func constBool(x ast.Expression) (value, ok bool) {
+	return foldBool(x, nil)
+}
+
+// constants resolves the name of a constant in a folded expression e.g.
+// `MAX_FEE` or `Config.MAX_FEE` to its value, and the constants of the
+// document of the value; or returns nil if the expression is not a
+// constant.
+type constants func(x ast.Expression) (ast.Expression, constants)
+
+// maxConstDepth limits the chains of the constants referring to the other
+// constants.
+const maxConstDepth = 32
+
+// constantsOf returns the resolver of the constants referred to in the
+// document, see foldBool. The chain holds the constants whose values are
+// being folded, in the documents they're declared in: a constant referring
+// to one of them, even through the constants of the other files, is part
+// of a cycle and has no value.
+//
+// The state variables whose values are known, see knownValues, are
+// resolved the same as the constants.
+func (s *State) constantsOf(doc *Document, chain []*ast.VariableDeclaration) constants {
+	known := map[*ast.ContractDeclaration]map[*ast.VariableDeclaration]ast.Expression{}
+	return func(x ast.Expression) (ast.Expression, constants) {
+		switch x.(type) {
+		case *ast.Identifier, *ast.MemberAccessExpression:
+		default:
+			return nil, nil
+		}
+		if len(chain) >= maxConstDepth {
+			return nil, nil
+		}
+		path := ast.PathEnclosingPos(doc.File, x.Start())
+		sym := s.follow(s.resolveExpr(doc, path, x))
+		if sym == nil {
+			return nil, nil
+		}
+		decl, ok := sym.Node.(*ast.VariableDeclaration)
+		if !ok || slices.Contains(chain, decl) {
+			return nil, nil
+		}
+		value := decl.Value
+		if !decl.Constant {
+			value = s.knownValue(sym, path, known)
+		}
+		if value == nil {
+			return nil, nil
+		}
+		return value, s.constantsOf(sym.Doc, append(chain[:len(chain):len(chain)], decl))
+	}
+}
+
+// foldBool folds the boolean expression made of literals and, if the
+// resolver is given, the constants.
+func foldBool(x ast.Expression, consts constants) (value, ok bool) {
+	if consts != nil {
+		if value, resolve := consts(x); value != nil {
+			return foldBool(value, resolve)
+		}
+	}
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		switch x.Kind {
+		case token.TRUE_LITERAL:
+			return true, true
+		case token.FALSE_LITERAL:
+			return false, true
+		}
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 {
+			return foldBool(x.Elements[0], consts)
+		}
+	case *ast.UnaryExpression:
+		if x.Operator == token.NOT {
+			value, ok := foldBool(x.Operand, consts)
+			return !value, ok
+		}
+	case *ast.BinaryExpression:
+		switch x.Operator {
+		case token.AND, token.OR:
+			left, lok := foldBool(x.Left, consts)
+			right, rok := foldBool(x.Right, consts)
+			// Short-circuiting makes the result known e.g. `false && x`.
+			if x.Operator == token.AND {
+				if (lok && !left) || (rok && !right) {
+					return false, true
+				}
+				return left && right, lok && rok
+			}
+			if (lok && left) || (rok && right) {
+				return true, true
+			}
+			return left || right, lok && rok
+		case token.EQUAL, token.NOT_EQUAL, token.LESS_THAN, token.GREATER_THAN,
+			token.LESS_THAN_OR_EQUAL, token.GREATER_THAN_OR_EQUAL:
+			left, lok := foldInt(x.Left, consts)
+			right, rok := foldInt(x.Right, consts)
+			if !lok || !rok {
+				return false, false
+			}
+			return compareInts(x.Operator, left, right), true
+		}
+	}
+	return false, false
+}
+
+// constInt folds the integer expression made of literals e.g. `10**18` or
+// `2 ether`; ok is false if the value is not known at compile time. The
+// fractional literals are allowed as long as the result is an integer, the
+// same as in Solidity.
+func constInt(x ast.Expression) (*big.Int, bool) {
+	return foldInt(x, nil)
+}
+
+// foldInt folds the integer expression made of literals and, if the
+// resolver is given, the constants.
+func foldInt(x ast.Expression, consts constants) (*big.Int, bool) {
+	if consts != nil {
+		if value, resolve := consts(x); value != nil {
+			return foldInt(value, resolve)
+		}
+	}
+	switch x := x.(type) {
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 {
+			return foldInt(x.Elements[0], consts)
+		}
+	case *ast.BasicLit:
+		return literalValue(x)
+	case *ast.UnaryExpression:
+		if x.Operator != token.SUB {
+			return nil, false
+		}
+		value, ok := foldInt(x.Operand, consts)
+		if !ok {
+			return nil, false
+		}
+		return value.Neg(value), true
+	case *ast.BinaryExpression:
+		left, lok := foldInt(x.Left, consts)
+		right, rok := foldInt(x.Right, consts)
+		if !lok || !rok {
+			return nil, false
+		}
+		return intOperation(x.Operator, left, right)
+	}
+	return nil, false
+}
+
+// intOperation applies the arithmetic or the bitwise operator to the
+// integers; ok is false if the operator is not one of them, or the result
+// is not defined e.g. a division by zero.
+func intOperation(op token.TokenType, left, right *big.Int) (*big.Int, bool) {
+	res := new(big.Int)
+	switch op {
+	case token.ADD:
+		res.Add(left, right)
+	case token.SUB:
+		res.Sub(left, right)
+	case token.MUL:
+		res.Mul(left, right)
+	case token.DIV, token.MOD:
+		if right.Sign() == 0 {
+			return nil, false
+		}
+		// Solidity truncates towards zero, like Quo and Rem.
+		if op == token.DIV {
+			res.Quo(left, right)
+		} else {
+			res.Rem(left, right)
+		}
+	case token.EXP:
+		if right.Sign() < 0 || right.BitLen() > 16 || left.BitLen()*int(right.Int64()) > maxConstBits {
+			return nil, false
+		}
+		res.Exp(left, right, nil)
+	case token.SHL:
+		if right.Sign() < 0 || right.BitLen() > 16 || left.BitLen()+int(right.Int64()) > maxConstBits {
+			return nil, false
+		}
+		res.Lsh(left, uint(right.Int64()))
+	case token.SAR:
+		if right.Sign() < 0 || right.BitLen() > 16 {
+			return nil, false
+		}
+		res.Rsh(left, uint(right.Int64()))
+	case token.BIT_AND:
+		res.And(left, right)
+	case token.BIT_OR:
+		res.Or(left, right)
+	case token.BIT_XOR:
+		res.Xor(left, right)
+	default:
+		return nil, false
+	}
+	return res, true
+}
+
+// compareInts applies the comparison operator to the integers.
+func compareInts(op token.TokenType, left, right *big.Int) bool {
+	cmp := left.Cmp(right)
+	switch op {
+	case token.EQUAL:
+		return cmp == 0
+	case token.NOT_EQUAL:
+		return cmp != 0
+	case token.LESS_THAN:
+		return cmp < 0
+	case token.GREATER_THAN:
+		return cmp > 0
+	case token.LESS_THAN_OR_EQUAL:
+		return cmp <= 0
+	}
+	return cmp >= 0
+}
+
+// maxConstBits limits the size of the folded values, the compiler rejects
+// the literals that don't fit in 256 bits anyway.
+const maxConstBits = 1024
+
+// subdenominations are the multipliers of the ether and time units.
+var subdenominations = map[string]int64{
+	"wei":     1,
+	"gwei":    1e9,
+	"szabo":   1e12,
+	"finney":  1e15,
+	"ether":   1e18,
+	"seconds": 1,
+	"minutes": 60,
+	"hours":   60 * 60,
+	"days":    24 * 60 * 60,
+	"weeks":   7 * 24 * 60 * 60,
+	"years":   365 * 24 * 60 * 60,
+}
+
+// literalValue returns the value of the number literal e.g. `0xff`, `1e18`
+// or `0.5 ether`.
+func literalValue(lit *ast.BasicLit) (*big.Int, bool) {
+	digits := strings.ReplaceAll(lit.Value, "_", "")
+	switch lit.Kind {
+	case token.HEX_NUMBER:
+		// The base prefix is detected by SetString.
+		value, ok := new(big.Int).SetString(digits, 0)
+		if !ok || lit.Unit != nil {
+			return nil, false
+		}
+		return value, true
+	case token.DECIMAL_NUMBER:
+		if i := strings.IndexAny(digits, "eE"); i >= 0 {
+			// Rat would accept any exponent, even a huge one.
+			exp, err := strconv.Atoi(digits[i+1:])
+			if err != nil || exp > maxConstBits/3 || exp < -maxConstBits/3 {
+				return nil, false
+			}
+		}
+		value, ok := new(big.Rat).SetString(digits)
+		if !ok {
+			return nil, false
+		}
+		if lit.Unit != nil {
+			multiplier, ok := subdenominations[lit.Unit.Name]
+			if !ok {
+				return nil, false
+			}
+			value.Mul(value, new(big.Rat).SetInt64(multiplier))
+		}
+		if !value.IsInt() {
+			return nil, false
+		}
+		return new(big.Int).Set(value.Num()), true
+	}
+	return nil, false
+}
@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+)
+
+// Two contracts of the same name in different files get the same artifact
+// name in Foundry and Hardhat, and so are ambiguous wherever the tools take
+// a contract by its name e.g. `solbot proxy-check --impl Vault`. The name
+// qualified with the path, like the compiler's e.g. `src/Vault.sol:Vault`,
+// tells them apart, see ResolveContract.
+
+// contractsNamed returns the contracts, the interfaces and the libraries
+// of the workspace with the name, ordered by their files. The callers get
+// all of them, and decide what to do when there are more than one.
+func (s *State) contractsNamed(name string) []*Symbol {
+	return s.contractsByName()[name]
+}
+
+// contractsByName indexes the contracts, the interfaces and the libraries
+// of the workspace by their names, see contractsNamed, for the callers
+// looking up many names.
+func (s *State) contractsByName() map[string][]*Symbol {
+	res := map[string][]*Symbol{}
+	for _, doc := range s.sortedDocuments() {
+		for _, decl := range doc.File.Declarations {
+			if c, ok := decl.(*ast.ContractDeclaration); ok && c.Name != nil {
+				res[c.Name.Name] = append(res[c.Name.Name], &Symbol{Doc: doc, Name: c.Name, Node: c})
+			}
+		}
+	}
+	return res
+}
+
+// QualifiedName returns the name of the contract qualified with the path of
+// its file relative to the workspace e.g. "src/Vault.sol:Vault".
+func (s *State) QualifiedName(contract *Symbol) string {
+	return s.RelativePath(contract.Doc.URI) + ":" + contract.Name.Name
+}
+
+// ResolveContract returns the contract the reference names, either by its
+// name alone e.g. "Vault", or qualified with the path of its file e.g.
+// "src/Vault.sol:Vault", relative to the workspace unless it's absolute. A
+// name declared in more than one file is an error listing the qualified
+// names to choose from.
+func (s *State) ResolveContract(ref string) (*Symbol, error) {
+	file, name, qualified := "", ref, false
+	if i := strings.LastIndex(ref, ":"); i >= 0 {
+		file, name, qualified = ref[:i], ref[i+1:], true
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(s.Root, file)
+		}
+		file = filepath.Clean(file)
+	}
+
+	candidates := []*Symbol{}
+	for _, c := range s.contractsNamed(name) {
+		if !qualified || URIToPath(c.Doc.URI) == file {
+			candidates = append(candidates, c)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		if qualified {
+			return nil, fmt.Errorf("contract `%s` not found in %s", name, s.RelativePath(PathToURI(file)))
+		}
+		return nil, fmt.Errorf("contract `%s` not found", name)
+	case 1:
+		return candidates[0], nil
+	}
+	names := []string{}
+	for _, c := range candidates {
+		names = append(names, s.QualifiedName(c))
+	}
+	return nil, fmt.Errorf("contract `%s` is declared in more than one file, qualify it with the path: %s", name, strings.Join(names, ", "))
+}
+
+// duplicateContractDiagnostics reports the contracts of the document whose
+// names other files of the workspace declare too, with the other
+// declarations in the related information. It's a warning if both are the
+// sources of the workspace, and an information if one of them is a
+// dependency, whose names the project can't change.
+func (s *State) duplicateContractDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	type declaredElsewhere struct {
+		severity lsp.DiagnosticSeverity
+		related  []lsp.DiagnosticRelatedInformation
+		others   []string
+	}
+	// The contracts of the document with the same name share the other
+	// declarations, which are collected once.
+	index := s.contractsByName()
+	elsewhere := map[string]*declaredElsewhere{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Name == nil {
+			continue
+		}
+		e := elsewhere[c.Name.Name]
+		if e == nil {
+			e = &declaredElsewhere{severity: lsp.SeverityInformation, related: []lsp.DiagnosticRelatedInformation{}, others: []string{}}
+			for _, other := range index[c.Name.Name] {
+				if other.Doc.URI == doc.URI {
+					continue
+				}
+				if !s.isDependency(doc.URI) && !s.isDependency(other.Doc.URI) {
+					e.severity = lsp.SeverityWarning
+				}
+				e.related = append(e.related, lsp.DiagnosticRelatedInformation{
+					Location: lsp.Location{URI: other.Doc.URI, Range: toLspRange(other.Doc.Handle, ast.NodeRange(other.Name))},
+					Message:  s.QualifiedName(other),
+				})
+				e.others = append(e.others, s.RelativePath(other.Doc.URI))
+			}
+			elsewhere[c.Name.Name] = e
+		}
+		if len(e.related) == 0 {
+			continue
+		}
+		severity, related, others := e.severity, e.related, e.others
+		contract := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(c.Name)),
+			Severity: severity,
+			Code:     "duplicate-contract",
+			Source:   "solbot",
+			Message: fmt.Sprintf("`%s` is declared in %s too, so the build artifacts and the tools taking the contract by its name can't tell them apart; refer to this one as %s",
+				c.Name.Name, strings.Join(others, ", "), s.QualifiedName(contract)),
+			RelatedInformation: related,
+		})
+	}
+	return res
+}
@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func newDuplicateVaults() *State {
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, "contract Vault {\n    uint256 total;\n}\n")
+	s.OpenDocument("file:///ws/test/mocks/Vault.sol", 1, "contract Vault {\n    address owner;\n}\n\ncontract VaultProxy {\n    address admin;\n}\n")
+	s.OpenDocument("file:///ws/lib/oz/Ownable.sol", 1, "abstract contract Ownable {}\n")
+	s.OpenDocument("file:///ws/src/Ownable.sol", 1, "contract Ownable {}\n")
+	return s
+}
+
+func Test_DuplicateContractDiagnostics(t *testing.T) {
+	s := newDuplicateVaults()
+
+	got := []string{}
+	for _, uri := range []string{"file:///ws/src/Vault.sol", "file:///ws/src/Ownable.sol", "file:///ws/lib/oz/Ownable.sol"} {
+		for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+			if d.Code != "duplicate-contract" {
+				continue
+			}
+			related := []string{}
+			for _, r := range d.RelatedInformation {
+				related = append(related, fmt.Sprintf("%s@%d", r.Message, r.Location.Range.Start.Line))
+			}
+			got = append(got, fmt.Sprintf("%s %d:%d %d: %s [%s]", s.RelativePath(uri), d.Range.Start.Line, d.Range.Start.Character, d.Severity, d.Message, strings.Join(related, " ")))
+		}
+	}
+	expected := []string{
+		"src/Vault.sol 0:9 2: `Vault` is declared in test/mocks/Vault.sol too, so the build artifacts and the tools taking the contract by its name can't tell them apart; refer to this one as src/Vault.sol:Vault [test/mocks/Vault.sol:Vault@0]",
+		"src/Ownable.sol 0:9 3: `Ownable` is declared in lib/oz/Ownable.sol too, so the build artifacts and the tools taking the contract by its name can't tell them apart; refer to this one as src/Ownable.sol:Ownable [lib/oz/Ownable.sol:Ownable@0]",
+		"lib/oz/Ownable.sol 0:18 3: `Ownable` is declared in src/Ownable.sol too, so the build artifacts and the tools taking the contract by its name can't tell them apart; refer to this one as lib/oz/Ownable.sol:Ownable [src/Ownable.sol:Ownable@0]",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_ResolveContract(t *testing.T) {
+	s := newDuplicateVaults()
+
+	if _, err := s.ResolveContract("Vault"); err == nil || err.Error() != "contract `Vault` is declared in more than one file, qualify it with the path: src/Vault.sol:Vault, test/mocks/Vault.sol:Vault" {
+		t.Errorf("Expected the ambiguous name to list the qualified names, got %v", err)
+	}
+	for _, ref := range []string{"test/mocks/Vault.sol:Vault", "./test/mocks/Vault.sol:Vault", "/ws/test/mocks/Vault.sol:Vault"} {
+		c, err := s.ResolveContract(ref)
+		if err != nil || c.Doc.URI != "file:///ws/test/mocks/Vault.sol" {
+			t.Errorf("Expected %s to resolve to the mock, got %v, %v", ref, c, err)
+		}
+	}
+	if c, err := s.ResolveContract("VaultProxy"); err != nil || s.QualifiedName(c) != "test/mocks/Vault.sol:VaultProxy" {
+		t.Errorf("Expected the unique name to resolve, got %v", err)
+	}
+	if _, err := s.ResolveContract("src/Other.sol:Vault"); err == nil || err.Error() != "contract `Vault` not found in src/Other.sol" {
+		t.Errorf("Expected the contract to be missing from the file, got %v", err)
+	}
+
+	// The proxy check takes the qualified names too.
+	if _, err := s.CheckProxy("VaultProxy", "Vault"); err == nil || !strings.Contains(err.Error(), "qualify it with the path") {
+		t.Errorf("Expected the ambiguous implementation to be refused, got %v", err)
+	}
+	collisions, err := s.CheckProxy("VaultProxy", "test/mocks/Vault.sol:Vault")
+	if err != nil || len(collisions) != 1 || collisions[0].Implementation.Name != "owner" {
+		t.Errorf("Expected the collision with the mock, got %v, %v", collisions, err)
+	}
+}
+
+func Test_WorkspaceSymbolQualifiedNames(t *testing.T) {
+	s := newDuplicateVaults()
+
+	got := []string{}
+	for _, symbol := range s.WorkspaceSymbol(lsp.IntID(1), "vault").Result {
+		got = append(got, fmt.Sprintf("%s %s %s", symbol.Name, s.RelativePath(symbol.Location.URI), symbol.ContainerName))
+	}
+	expected := []string{
+		"Vault src/Vault.sol src/Vault.sol:Vault",
+		"Vault test/mocks/Vault.sol test/mocks/Vault.sol:Vault",
+		"VaultProxy test/mocks/Vault.sol test/mocks/Vault.sol:VaultProxy",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	members := s.WorkspaceSymbol(lsp.IntID(2), "OWNER").Result
+	if len(members) != 1 || members[0].Name != "owner" || members[0].ContainerName != "test/mocks/Vault.sol:Vault" {
+		t.Errorf("Expected the member contained by the qualified contract, got %v", members)
+	}
+}
@@ -0,0 +1,111 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/metrics"
+	"solbot/token"
+	"strings"
+)
+
+// ContractSizeMetrics is the estimated size of a contract in the document.
+type ContractSizeMetrics struct {
+	metrics.Size
+	Doc *Document
+}
+
+// ContractSizes estimates the bytecode sizes of the deployable contracts
+// and libraries of the document, with the code inherited from the bases
+// declared in other files. The contracts whose bases can't be resolved are
+// left out.
+func (s *State) ContractSizes(doc *Document) []metrics.Size {
+	res := []metrics.Size{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Abstract || c.Kind == token.INTERFACE {
+			continue
+		}
+		linearized := s.linearize(&Symbol{Doc: doc, Name: c.Name, Node: c})
+		if linearized == nil {
+			continue
+		}
+		contracts := []*ast.ContractDeclaration{}
+		for _, sym := range linearized {
+			contracts = append(contracts, sym.Node.(*ast.ContractDeclaration))
+		}
+		res = append(res, metrics.EstimateSize(contracts))
+	}
+	return res
+}
+
+// PathContractSizes estimates the sizes of the contracts of the documents
+// in the file or directory, see documentsUnder.
+func (s *State) PathContractSizes(path string) []ContractSizeMetrics {
+	res := []ContractSizeMetrics{}
+	for _, doc := range s.documentsUnder(path) {
+		for _, size := range s.ContractSizes(doc) {
+			res = append(res, ContractSizeMetrics{Size: size, Doc: doc})
+		}
+	}
+	return res
+}
+
+// contractSizeDiagnostics reports the contracts whose estimated size is
+// above the threshold configured in solbot.toml, the EIP-170 limit by
+// default, with the three largest factors.
+func (s *State) contractSizeDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	threshold := s.Config.ContractSize
+	if threshold <= 0 {
+		return res
+	}
+	for _, size := range s.ContractSizes(doc) {
+		if size.Estimate <= threshold {
+			continue
+		}
+		factors := []string{}
+		for _, f := range size.Top(3) {
+			factors = append(factors, f.Detail)
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(size.Contract.Name)),
+			Severity: lsp.SeverityInformation,
+			Code:     "contract-size",
+			Source:   "solbot",
+			Message: fmt.Sprintf("Estimated bytecode size of `%s` is %d bytes, above the threshold of %d bytes "+
+				"(the EIP-170 limit is %d bytes). The largest factors: %s.",
+				size.Name(), size.Estimate, threshold, metrics.EIP170Limit, strings.Join(factors, ". ")),
+		})
+	}
+	return res
+}
+
+// ContractSize returns the estimated sizes of the contracts of the document
+// with their factors.
+func (s *State) ContractSize(id lsp.ID, uri string) lsp.ContractSizeResponse {
+	res := []lsp.ContractSize{}
+	doc, ok := s.document(uri)
+	if !ok {
+		return lsp.NewContractSizeResponse(id, res)
+	}
+	for _, size := range s.ContractSizes(doc) {
+		factors := []lsp.ContractSizeFactor{}
+		for _, f := range size.Factors {
+			factors = append(factors, lsp.ContractSizeFactor{Name: f.Name, Count: f.Count, Bytes: f.Bytes, Detail: f.Detail})
+		}
+		sources := []lsp.ContractSizeSource{}
+		for _, source := range size.Sources {
+			sources = append(sources, lsp.ContractSizeSource{Contract: source.Contract.Name.Name, Bytes: source.Bytes})
+		}
+		res = append(res, lsp.ContractSize{
+			Name:      size.Name(),
+			Range:     toLspRange(doc.Handle, ast.NodeRange(size.Contract.Name)),
+			Estimate:  size.Estimate,
+			Threshold: max(s.Config.ContractSize, 0),
+			Factors:   factors,
+			Sources:   sources,
+		})
+	}
+	return lsp.NewContractSizeResponse(id, res)
+}
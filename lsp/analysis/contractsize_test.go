@@ -0,0 +1,102 @@
+package analysis
+
+import (
+	"context"
+	"path/filepath"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func Test_ContractSizeDiagnostics(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "contractsize"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s := NewState()
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sizeDiagnostics := func(name string) []string {
+		res := []string{}
+		for _, d := range s.Diagnostics(context.Background(), PathToURI(filepath.Join(root, name))).Params.Diagnostics {
+			if d.Code == "contract-size" {
+				res = append(res, d.Message)
+			}
+		}
+		return res
+	}
+
+	// The long revert strings take the contract over the limit.
+	messages := sizeDiagnostics("Registry.sol")
+	if len(messages) != 1 {
+		t.Fatalf("Expected a contract-size diagnostic, got %v", messages)
+	}
+	expected := "Estimated bytecode size of `Registry` is "
+	if !strings.HasPrefix(messages[0], expected) {
+		t.Errorf("Expected the message to start with %q, got %q", expected, messages[0])
+	}
+	expected = "The largest factors: 161 revert strings totaling 10138 bytes; consider custom errors. "
+	if !strings.Contains(messages[0], expected) {
+		t.Errorf("Expected the revert strings to be the largest factor in %q", messages[0])
+	}
+
+	// The same contract with the custom errors fits.
+	if messages := sizeDiagnostics("RegistryWithErrors.sol"); len(messages) != 0 {
+		t.Errorf("Expected no contract-size diagnostic with the custom errors, got %v", messages)
+	}
+
+	// The threshold is configurable, and the abstract base is never
+	// reported.
+	s.Config.ContractSize = 10000
+	if messages := sizeDiagnostics("RegistryWithErrors.sol"); len(messages) != 1 {
+		t.Errorf("Expected a contract-size diagnostic below the lowered threshold, got %v", messages)
+	}
+	if messages := sizeDiagnostics("Pausable.sol"); len(messages) != 0 {
+		t.Errorf("Expected no contract-size diagnostic of the abstract contract, got %v", messages)
+	}
+}
+
+func Test_ContractSizeRequest(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "contractsize"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s := NewState()
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sizes := s.ContractSize(lsp.IntID(1), PathToURI(filepath.Join(root, "RegistryWithErrors.sol"))).Result
+	if len(sizes) != 1 {
+		t.Fatalf("Expected 1 contract, got %d", len(sizes))
+	}
+	size := sizes[0]
+	if size.Name != "RegistryWithErrors" || size.Threshold != 24576 || size.Range.Start.Line != 10 {
+		t.Errorf("Expected RegistryWithErrors on line 10 with the EIP-170 threshold, got %s on line %d with %d",
+			size.Name, size.Range.Start.Line, size.Threshold)
+	}
+
+	// The contributions of the contract and of its base add up to the
+	// estimate, and so do the factors.
+	if len(size.Sources) != 2 || size.Sources[0].Contract != "RegistryWithErrors" || size.Sources[1].Contract != "Pausable" {
+		t.Fatalf("Expected the sources RegistryWithErrors and Pausable, got %v", size.Sources)
+	}
+	total := 0
+	for _, source := range size.Sources {
+		total += source.Bytes
+	}
+	if total != size.Estimate {
+		t.Errorf("Expected the sources to add up to %d, got %d", size.Estimate, total)
+	}
+	total = 120
+	for i, f := range size.Factors {
+		total += f.Bytes
+		if i > 0 && f.Bytes > size.Factors[i-1].Bytes {
+			t.Errorf("Expected the largest factors first, got %s after %s", f.Name, size.Factors[i-1].Name)
+		}
+	}
+	if total != size.Estimate {
+		t.Errorf("Expected the factors and the overhead to add up to %d, got %d", size.Estimate, total)
+	}
+}
@@ -0,0 +1,494 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/semver"
+	"solbot/token"
+	"strings"
+)
+
+var (
+	// explicitLocationVersion requires the data location of all of the
+	// variables of the reference types.
+	explicitLocationVersion = semver.MustParse("0.5.0")
+	// calldataAnywhereVersion allows `calldata` in all of the functions and
+	// `memory` for the parameters of the external functions.
+	calldataAnywhereVersion = semver.MustParse("0.6.9")
+)
+
+// locationIssue is a data location rejected by the compiler, together with
+// its fix if the right location is clear.
+type locationIssue struct {
+	r       token.Range // the location keyword; or the insertion point of a missing one
+	code    string
+	message string
+	fix     *locationFix
+}
+
+type locationFix struct {
+	title string
+	edit  lsp.TextEdit
+}
+
+// dataLocationDiagnostics reports the data locations the compiler rejects:
+//   - the variables of the reference types without a location,
+//   - the locations not allowed for the kind of the function e.g. `storage`
+//     parameters of the external functions,
+//   - the locations of the value types,
+//   - the writes to the calldata, which is read-only,
+//   - the storage pointers pointing to the memory or the calldata.
+//
+// The rules follow the compilers allowed by the pragma of the file, a
+// location is reported only if none of them accepts it. The storage copied
+// to the memory is valid, the memory copy detector reports it if the
+// changes are lost.
+func (s *State) dataLocationDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, issue := range s.locationIssues(doc) {
+		res = append(res, issue.diagnostic(doc))
+	}
+	return res
+}
+
+func (issue locationIssue) diagnostic(doc *Document) lsp.Diagnostic {
+	return lsp.Diagnostic{
+		Range:    toLspRange(doc.Handle, issue.r),
+		Severity: lsp.SeverityError,
+		Code:     issue.code,
+		Source:   "solbot",
+		Message:  issue.message,
+	}
+}
+
+// dataLocationActions returns the quick fixes of the data locations in the
+// selected range.
+func (s *State) dataLocationActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	for _, issue := range s.locationIssues(doc) {
+		if issue.fix == nil || !touches(selected, issue.r) {
+			continue
+		}
+		actions = append(actions, lsp.CodeAction{
+			Title:       issue.fix.title,
+			Kind:        lsp.CodeActionQuickFix,
+			Diagnostics: []lsp.Diagnostic{issue.diagnostic(doc)},
+			Edit:        &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{doc.URI: {issue.fix.edit}}},
+			IsPreferred: true,
+		})
+	}
+	return actions
+}
+
+// locationIssues returns the problems with the data locations of the
+// document, in the order of the declarations.
+func (s *State) locationIssues(doc *Document) []locationIssue {
+	explicit := !pragma.AllowsBelow(doc.File, explicitLocationVersion)
+	anywhere := pragma.AllowsAtLeast(doc.File, calldataAnywhereVersion)
+	res := []locationIssue{}
+
+	checkFunction := func(contract *ast.ContractDeclaration, fn *ast.FunctionDeclaration) {
+		kind, allowed := functionLocations(contract, fn, false, anywhere)
+		_, results := functionLocations(contract, fn, true, anywhere)
+		if fn.Type.Params != nil {
+			for _, p := range fn.Type.Params.List {
+				res = append(res, s.checkLocation(doc, p.Type, p.Name, p.Location, "parameter", "of the "+kind, allowed, explicit, true)...)
+			}
+		}
+		if fn.Type.Results != nil {
+			for _, p := range fn.Type.Results.List {
+				res = append(res, s.checkLocation(doc, p.Type, p.Name, p.Location, "return variable", "of the "+kind, results, explicit, true)...)
+			}
+		}
+	}
+	for _, decl := range doc.File.Declarations {
+		switch d := decl.(type) {
+		case *ast.FunctionDeclaration:
+			checkFunction(nil, d)
+		case *ast.ContractDeclaration:
+			for _, member := range d.Body {
+				switch m := member.(type) {
+				case *ast.FunctionDeclaration:
+					checkFunction(d, m)
+				case *ast.ModifierDeclaration:
+					if m.Params == nil {
+						continue
+					}
+					for _, p := range m.Params.List {
+						res = append(res, s.checkLocation(doc, p.Type, p.Name, p.Location, "parameter", "of the modifier", internalLocations(anywhere), explicit, true)...)
+					}
+				}
+			}
+		}
+	}
+
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.VariableDeclarationStatement:
+			for _, decl := range n.Declarations {
+				if decl == nil {
+					continue
+				}
+				// The local storage pointers can't be initialized with a
+				// copy, the right location depends on the intent.
+				fixable := len(n.Declarations) != 1 || n.Value == nil || s.locationOf(doc, n.Value) != ast.Storage
+				res = append(res, s.checkLocation(doc, decl.Type, decl.Name, decl.Location, "local variable", "", internalLocations(anywhere), explicit, fixable)...)
+			}
+			if len(n.Declarations) == 1 && n.Declarations[0] != nil && n.Value != nil {
+				res = append(res, s.checkPointer(doc, n.Declarations[0], n.Value)...)
+			}
+		case *ast.AssignmentExpression:
+			ident, ok := n.Left.(*ast.Identifier)
+			if !ok || n.Operator != token.ASSIGN {
+				return true
+			}
+			sym := s.resolve(doc, ast.PathEnclosingPos(doc.File, ident.Start()))
+			if sym == nil {
+				return true
+			}
+			if decl, ok := sym.Node.(*ast.VariableDeclaration); ok && s.declaringContract(sym) == nil {
+				res = append(res, s.checkPointer(doc, decl, n.Right)...)
+			}
+		}
+		return true
+	})
+	return append(res, s.calldataWrites(doc)...)
+}
+
+// functionLocations returns the description of the function e.g. "external
+// function", together with the locations allowed for its parameters or
+// results, the preferred one first.
+func functionLocations(contract *ast.ContractDeclaration, fn *ast.FunctionDeclaration, results, anywhere bool) (string, []ast.DataLocation) {
+	visibility := fn.Type.Visibility
+	switch {
+	case contract == nil:
+		visibility = ast.Internal
+	case contract.Kind == token.INTERFACE:
+		visibility = ast.External
+	case visibility == 0:
+		// The functions were public by default before 0.5.0.
+		visibility = ast.Public
+	}
+	library := contract != nil && contract.Kind == token.LIBRARY
+
+	var kind string
+	var allowed []ast.DataLocation
+	switch {
+	case fn.Kind == token.CONSTRUCTOR:
+		kind = "constructor"
+		allowed = []ast.DataLocation{ast.Memory}
+	case visibility == ast.Internal || visibility == ast.Private:
+		return "internal function", internalLocations(anywhere)
+	case visibility == ast.External && !results:
+		kind = "external function"
+		allowed = []ast.DataLocation{ast.Calldata}
+		if anywhere {
+			allowed = append(allowed, ast.Memory)
+		}
+	default:
+		kind = "public function"
+		if visibility == ast.External {
+			kind = "external function"
+		}
+		allowed = []ast.DataLocation{ast.Memory}
+		if anywhere {
+			allowed = append(allowed, ast.Calldata)
+		}
+	}
+	if library {
+		allowed = append(allowed, ast.Storage)
+	}
+	return kind, allowed
+}
+
+// internalLocations returns the locations allowed for the local variables
+// and the parameters of the internal functions.
+func internalLocations(anywhere bool) []ast.DataLocation {
+	if anywhere {
+		return []ast.DataLocation{ast.Memory, ast.Storage, ast.Calldata}
+	}
+	return []ast.DataLocation{ast.Memory, ast.Storage}
+}
+
+// checkLocation checks the location of the variable against the allowed
+// ones. The missing location is reported only if the compiler requires it.
+// The variable is described by its role and owner e.g. "parameter" and "of
+// the external function".
+func (s *State) checkLocation(doc *Document, typ ast.Expression, name *ast.Identifier, location ast.DataLocation, role, owner string, allowed []ast.DataLocation, explicit, fixable bool) []locationIssue {
+	if typ == nil {
+		return nil
+	}
+	ref, ok := s.isReferenceType(doc, typ)
+	if !ok {
+		return nil
+	}
+	what := role
+	if name != nil {
+		what += " `" + name.Name + "`"
+	}
+	if owner != "" {
+		what += " " + owner
+	}
+	keyword, hasKeyword := locationKeyword(doc.Handle.Src(), typ.End())
+
+	switch {
+	case !ref && location != 0:
+		if !hasKeyword {
+			return nil
+		}
+		return []locationIssue{{
+			r:       keyword,
+			code:    "invalid-data-location",
+			message: fmt.Sprintf("Data location can only be given for the array, struct or mapping types, `%s` of the %s is not one", ast.ExprString(typ), what),
+			fix: &locationFix{
+				title: fmt.Sprintf("Remove `%s`", locationName(location)),
+				edit:  lsp.TextEdit{Range: toLspRange(doc.Handle, token.Range{Start: typ.End(), End: keyword.End}), NewText: ""},
+			},
+		}}
+	case ref && location == 0:
+		if !explicit {
+			return nil
+		}
+		at := token.Range{Start: typ.End(), End: typ.End()}
+		issue := locationIssue{
+			r:       at,
+			code:    "missing-data-location",
+			message: fmt.Sprintf("Data location must be %s for the %s, but none was given", locationList(allowed), what),
+		}
+		if fixable {
+			issue.fix = &locationFix{
+				title: fmt.Sprintf("Add `%s`", locationName(allowed[0])),
+				edit:  lsp.TextEdit{Range: toLspRange(doc.Handle, at), NewText: " " + locationName(allowed[0])},
+			}
+		}
+		return []locationIssue{issue}
+	case ref && !slices.Contains(allowed, location):
+		if !hasKeyword {
+			return nil
+		}
+		return []locationIssue{{
+			r:       keyword,
+			code:    "invalid-data-location",
+			message: fmt.Sprintf("Data location must be %s for the %s, but `%s` was given", locationList(allowed), what, locationName(location)),
+			fix: &locationFix{
+				title: fmt.Sprintf("Change `%s` to `%s`", locationName(location), locationName(allowed[0])),
+				edit:  lsp.TextEdit{Range: toLspRange(doc.Handle, keyword), NewText: locationName(allowed[0])},
+			},
+		}}
+	}
+	return nil
+}
+
+// checkPointer reports the local storage pointer set to a value in the
+// memory or the calldata, which has to be copied instead.
+func (s *State) checkPointer(doc *Document, decl *ast.VariableDeclaration, value ast.Expression) []locationIssue {
+	if decl.Location != ast.Storage {
+		return nil
+	}
+	from := s.locationOf(doc, value)
+	if from != ast.Memory && from != ast.Calldata {
+		return nil
+	}
+	return []locationIssue{{
+		r:    ast.NodeRange(value),
+		code: "invalid-storage-pointer",
+		message: fmt.Sprintf("`%s` is a storage pointer, it can't point to the %s; declare it `memory` to copy the value",
+			decl.Name.Name, locationName(from)),
+	}}
+}
+
+// calldataWrites reports the writes to the members and the elements of the
+// calldata parameters and variables e.g. `data[0] = 1` or `ids.push(id)`.
+func (s *State) calldataWrites(doc *Document) []locationIssue {
+	res := []locationIssue{}
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		if len(path) < 2 {
+			return
+		}
+		if _, ok := path[1].(*ast.MemberAccessExpression); !ok {
+			if _, ok := path[1].(*ast.IndexAccessExpression); !ok {
+				return
+			}
+		}
+		sym := s.resolve(doc, path)
+		if sym == nil || sym.Name == ident {
+			return
+		}
+		switch n := sym.Node.(type) {
+		case *ast.Param:
+			if n.Location != ast.Calldata {
+				return
+			}
+		case *ast.VariableDeclaration:
+			if n.Location != ast.Calldata {
+				return
+			}
+		default:
+			return
+		}
+
+		top := accessedBy(path)
+		if top == 0 || top+1 >= len(path) {
+			return
+		}
+		written := false
+		switch parent := path[top+1].(type) {
+		case *ast.AssignmentExpression:
+			written = parent.Left == path[top]
+		case *ast.UnaryExpression:
+			switch parent.Operator {
+			case token.INC, token.DEC, token.DELETE:
+				written = true
+			}
+		case *ast.CallExpression:
+			if member, ok := path[top].(*ast.MemberAccessExpression); ok && parent.Function == path[top] {
+				written = member.Member.Name == "push" || member.Member.Name == "pop"
+			}
+		}
+		if !written {
+			return
+		}
+		res = append(res, locationIssue{
+			r:       ast.NodeRange(path[top]),
+			code:    "calldata-write",
+			message: fmt.Sprintf("`%s` is read-only, it's in the calldata; copy it to the memory to modify it", ident.Name),
+		})
+	})
+	return res
+}
+
+// accessedBy returns the index of the outermost member or index access
+// based on path[0] e.g. `p.amounts[i]` for `p`; or 0 if there is none.
+func accessedBy(path []ast.Node) int {
+	top := 0
+	for top+1 < len(path) {
+		switch parent := path[top+1].(type) {
+		case *ast.MemberAccessExpression:
+			if parent.Expression != path[top] {
+				return top
+			}
+		case *ast.IndexAccessExpression:
+			if parent.Expression != path[top] {
+				return top
+			}
+		default:
+			return top
+		}
+		top++
+	}
+	return top
+}
+
+// locationOf returns the data location of the value of the expression; or
+// the zero value if it's unknown or it's not a reference.
+func (s *State) locationOf(doc *Document, x ast.Expression) ast.DataLocation {
+	for {
+		switch e := x.(type) {
+		case *ast.MemberAccessExpression:
+			x = e.Expression
+			continue
+		case *ast.IndexAccessExpression:
+			x = e.Expression
+			continue
+		case *ast.TupleExpression:
+			if len(e.Elements) == 1 {
+				x = e.Elements[0]
+				continue
+			}
+		case *ast.CallExpression:
+			if _, ok := e.Function.(*ast.NewExpression); ok {
+				return ast.Memory
+			}
+			return 0
+		}
+		break
+	}
+	ident, ok := x.(*ast.Identifier)
+	if !ok {
+		return 0
+	}
+	sym := s.resolve(doc, ast.PathEnclosingPos(doc.File, ident.Start()))
+	if sym == nil {
+		return 0
+	}
+	switch n := sym.Node.(type) {
+	case *ast.Param:
+		return n.Location
+	case *ast.VariableDeclaration:
+		if n.Location != 0 || n.Constant || n.Immutable || s.declaringContract(sym) == nil {
+			return n.Location
+		}
+		return ast.Storage
+	}
+	return 0
+}
+
+// isReferenceType reports whether the values of the type are kept in a
+// data location: the arrays, `bytes`, `string` and the structs. The second
+// result is false if the type can't be resolved. The mappings are left
+// out, they are only ever in the storage.
+func (s *State) isReferenceType(doc *Document, typ ast.Expression) (bool, bool) {
+	switch t := typ.(type) {
+	case *ast.ArrayType:
+		return true, true
+	case *ast.MappingType:
+		return false, false
+	case *ast.ElementaryType:
+		return t.Value == "string" || t.Value == "bytes", true
+	case *ast.FunctionType:
+		return false, true
+	}
+	sym := s.follow(s.resolveExpr(doc, ast.PathEnclosingPos(doc.File, typ.Start()), typ))
+	if sym == nil {
+		return false, false
+	}
+	_, ok := sym.Node.(*ast.StructDeclaration)
+	return ok, true
+}
+
+// locationKeyword returns the range of the data location keyword following
+// the type e.g. `memory` in `string memory name`.
+func locationKeyword(src string, after token.Pos) (token.Range, bool) {
+	start := int(after)
+	for start < len(src) && strings.ContainsRune(" \t\r\n", rune(src[start])) {
+		start++
+	}
+	for _, keyword := range []string{"storage", "memory", "calldata"} {
+		end := start + len(keyword)
+		if strings.HasPrefix(src[start:], keyword) && (end == len(src) || !isIdentifierByte(src[end])) {
+			return token.Range{Start: token.Pos(start), End: token.Pos(end)}, true
+		}
+	}
+	return token.Range{}, false
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b == '$' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+func locationName(location ast.DataLocation) string {
+	switch location {
+	case ast.Storage:
+		return "storage"
+	case ast.Memory:
+		return "memory"
+	case ast.Calldata:
+		return "calldata"
+	}
+	return ""
+}
+
+// locationList returns the locations e.g. "`memory` or `calldata`".
+func locationList(locations []ast.DataLocation) string {
+	names := []string{}
+	for _, l := range locations {
+		names = append(names, "`"+locationName(l)+"`")
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
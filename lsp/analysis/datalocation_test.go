@@ -0,0 +1,165 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"solbot/lsp"
+	"testing"
+)
+
+func Test_DataLocationDiagnostics(t *testing.T) {
+	uri := "file:///ws/src/Registry.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+contract Registry {
+    struct Entry {
+        string name;
+        uint256[] ids;
+    }
+
+    Entry[] entries;
+
+    function register(string name, uint256[] calldata ids) external {
+        ids[0] = 1;
+        Entry storage e = Entry(name, ids);
+        e.ids.push(ids[0]);
+    }
+
+    function replace(Entry storage entry, uint256 memory index) public {
+        uint256[] ids;
+        Entry last = entries[0];
+    }
+}
+`)
+	doc := s.Documents[uri]
+
+	expected := []struct {
+		code    string
+		line    uint
+		message string
+	}{
+		{"missing-data-location", 10, "Data location must be `calldata` or `memory` for the parameter `name` of the external function, but none was given"},
+		{"invalid-data-location", 16, "Data location must be `memory` or `calldata` for the parameter `entry` of the public function, but `storage` was given"},
+		{"invalid-data-location", 16, "Data location can only be given for the array, struct or mapping types, `uint256` of the parameter `index` of the public function is not one"},
+		{"missing-data-location", 17, "Data location must be `memory`, `storage` or `calldata` for the local variable `ids`, but none was given"},
+		{"missing-data-location", 18, "Data location must be `memory`, `storage` or `calldata` for the local variable `last`, but none was given"},
+		{"calldata-write", 11, "`ids` is read-only, it's in the calldata; copy it to the memory to modify it"},
+	}
+	diagnostics := s.dataLocationDiagnostics(doc)
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for i, e := range expected {
+		d := diagnostics[i]
+		if d.Code != e.code || d.Range.Start.Line != e.line || d.Severity != lsp.SeverityError {
+			t.Errorf("Expected %s at line %d, got %s at line %d", e.code, e.line, d.Code, d.Range.Start.Line)
+		}
+		if d.Message != e.message {
+			t.Errorf("Expected %q, got %q", e.message, d.Message)
+		}
+	}
+	if r := diagnostics[0].Range; r.Start != r.End || r.Start.Character != 28 {
+		t.Errorf("Expected the insertion point after `string`, got %v", r)
+	}
+
+	// The location of the variable initialized with the storage is left
+	// out, it's either a copy or a pointer.
+	fixes := s.Fixes(uri)
+	titles := map[string]string{}
+	for _, fix := range fixes {
+		if len(fix.Edits) == 1 {
+			titles[fix.Title] = fix.Edits[0].NewText
+		}
+	}
+	if titles["Add `calldata`"] != " calldata" || titles["Change `storage` to `memory`"] != "memory" || titles["Remove `memory`"] != "" {
+		t.Errorf("Expected the location fixes, got %v", titles)
+	}
+	res := s.ApplyFixes(uri, fixes)
+	if len(res.Errors) != 0 || len(res.Conflicts) != 0 {
+		t.Fatalf("Expected the fixes to apply, got %v and %v", res.Errors, res.Conflicts)
+	}
+	remaining := []string{}
+	for _, d := range s.dataLocationDiagnostics(s.Documents[uri]) {
+		remaining = append(remaining, d.Code)
+	}
+	if !slices.Equal(remaining, []string{"missing-data-location", "calldata-write"}) {
+		t.Errorf("Expected `last` and the write to remain, got %v", remaining)
+	}
+}
+
+func Test_DataLocationVersions(t *testing.T) {
+	src := `pragma solidity %s;
+
+contract Registry {
+    function register(string name, uint256[] memory ids) external {}
+    function check(bytes calldata data) internal {}
+}
+`
+	tests := []struct {
+		pragma   string
+		expected []string
+	}{
+		// The locations were optional, but the external parameters were
+		// always in the calldata.
+		{"^0.4.24", []string{"invalid-data-location", "invalid-data-location"}},
+		{"^0.5.0", []string{"missing-data-location", "invalid-data-location", "invalid-data-location"}},
+		// Some of the versions allow the memory and the calldata anywhere.
+		{"^0.6.0", []string{"missing-data-location"}},
+		{">=0.6.9", []string{"missing-data-location"}},
+	}
+	for _, tt := range tests {
+		uri := "file:///ws/src/Registry.sol"
+		s := NewState()
+		s.OpenDocument(uri, 1, fmt.Sprintf(src, tt.pragma))
+		codes := []string{}
+		for _, d := range s.dataLocationDiagnostics(s.Documents[uri]) {
+			codes = append(codes, d.Code)
+		}
+		if !slices.Equal(codes, tt.expected) {
+			t.Errorf("Expected %v for %s, got %v", tt.expected, tt.pragma, codes)
+		}
+	}
+}
+
+func Test_DataLocationMemoryCopy(t *testing.T) {
+	uri := "file:///ws/src/Staking.sol"
+	s := NewState()
+	s.Config.Disabled = []string{"could-be-view", "dead-store"}
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+contract Staking {
+    struct Position {
+        uint256 amount;
+    }
+
+    Position[] positions;
+
+    function claim(uint256 i) external {
+        Position memory p = positions[i];
+        p.amount = 0;
+    }
+
+    function reset(uint256 i) external {
+        Position memory copy = Position(0);
+        Position storage p = copy;
+        p = positions[i];
+    }
+}
+`)
+
+	diagnostics := s.Diagnostics(context.Background(), uri).Params.Diagnostics
+	codes := []string{}
+	for _, d := range diagnostics {
+		codes = append(codes, d.Code)
+	}
+	// The copy to the memory is valid, only the lost write is reported.
+	if !slices.Equal(codes, []string{"lost-memory-write", "invalid-storage-pointer"}) {
+		t.Fatalf("Expected the lost write and the pointer to the memory, got %v", diagnostics)
+	}
+	message := "`p` is a storage pointer, it can't point to the memory; declare it `memory` to copy the value"
+	if diagnostics[1].Message != message || diagnostics[1].Range.Start.Line != 16 {
+		t.Errorf("Expected %q at line 16, got %q at line %d", message, diagnostics[1].Message, diagnostics[1].Range.Start.Line)
+	}
+}
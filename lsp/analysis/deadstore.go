@@ -0,0 +1,274 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+)
+
+// deadStore is a value written to a variable that is never read.
+type deadStore struct {
+	name       *ast.Identifier // the written variable
+	decl       ast.Node        // *ast.VariableDeclaration or *ast.Param
+	node       int             // the node of the flow graph writing it
+	value      ast.Expression  // the value assigned; or nil e.g. for `x++`
+	overwrites []*ast.Identifier
+	always     bool // is the value overwritten on every path?
+}
+
+// deadStoreDiagnostics reports the values written to the variables of the
+// functions that are never read, with the code dead-store: overwritten
+// before they are read e.g. the first assignment in `amount = calculate();
+// amount = 0;`, or never read until the variable goes out of scope. The
+// state variables persist after the function, so only the values
+// overwritten on every path are reported. The values of the calls that may
+// change the state are reported as the information instead, the call
+// itself is to be kept.
+func (s *State) deadStoreDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	if slices.Contains(s.Config.Disabled, "dead-store") {
+		return res
+	}
+	ast.Inspect(doc.File, func(n ast.Node) bool {
+		fn, ok := n.(*ast.FunctionDeclaration)
+		if !ok {
+			return true
+		}
+		if fn.Body != nil {
+			for _, store := range s.deadStores(doc, fn) {
+				res = append(res, s.deadStoreDiagnostic(doc, fn, store))
+			}
+		}
+		return false
+	})
+	return res
+}
+
+func (s *State) deadStoreDiagnostic(doc *Document, fn *ast.FunctionDeclaration, store deadStore) lsp.Diagnostic {
+	message := fmt.Sprintf("The value assigned to `%s` is never read", store.name.Name)
+	if store.always {
+		message = fmt.Sprintf("The value assigned to `%s` is overwritten before it's read", store.name.Name)
+	}
+	d := lsp.Diagnostic{
+		Range:    toLspRange(doc.Handle, ast.NodeRange(store.name)),
+		Severity: lsp.SeverityHint,
+		Code:     "dead-store",
+		Source:   "solbot",
+		Message:  message,
+		Tags:     []lsp.DiagnosticTag{lsp.TagUnnecessary},
+	}
+	if store.value != nil {
+		r := ast.NodeRange(store.value)
+		effects := s.effectsWithCallees(callable{doc: doc, node: fn}, func(node ast.Node) bool {
+			return r.Contains(node.Start())
+		})
+		if effects&changesState != 0 {
+			d.Severity, d.Tags = lsp.SeverityInformation, nil
+			d.Message += "; the call may change the state, keep it without the assignment"
+		}
+	}
+	for _, ident := range store.overwrites {
+		d.RelatedInformation = append(d.RelatedInformation, lsp.DiagnosticRelatedInformation{
+			Location: lsp.Location{URI: doc.URI, Range: toLspRange(doc.Handle, ast.NodeRange(ident))},
+			Message:  fmt.Sprintf("`%s` is overwritten here", ident.Name),
+		})
+	}
+	return d
+}
+
+// deadStores returns the values written in the function that are never
+// read, by a backward liveness analysis of its local variables and of its
+// state variables of the value types. The state variables are read at the
+// end of the function and at its returns, and any of them may be read by
+// the inline assembly, the calls of the other contracts and the functions
+// reading the state. The writes after a return or a revert, or in the
+// branches of the conditions known at the analysis time, are not reported,
+// they are never executed, see unreachableBranch. Neither are the
+// declarations initialized with zero or of the variables never used, see
+// unusedDiagnostics.
+func (s *State) deadStores(doc *Document, fn *ast.FunctionDeclaration) []deadStore {
+	accesses := s.variableAccesses(doc, fn)
+	state := s.stateVariableAccesses(doc, fn)
+	accesses = append(accesses, state...)
+	g := buildFlowGraph(fn, accesses)
+	if len(state) > 0 {
+		s.readState(doc, fn, g, state)
+	}
+	_, live := g.liveness()
+	reached := g.reachable()
+	consts := s.constantsOf(doc, nil)
+	unreachable := []token.Range{}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if n, ok := n.(*ast.IfStatement); ok {
+			if branch, _ := s.unreachableBranch(doc, n, consts); branch != nil {
+				unreachable = append(unreachable, ast.NodeRange(branch))
+			}
+		}
+		return true
+	})
+
+	used := map[ast.Node]bool{}
+	for _, a := range accesses {
+		used[a.decl] = true
+	}
+	stores := []deadStore{}
+	for _, a := range accesses {
+		i, ok := g.accessed[a.ident]
+		if !ok || !a.written {
+			continue
+		}
+		if _, ok := g.nodes[i].node.(*ast.AssemblyStatement); ok {
+			continue
+		}
+		stores = append(stores, deadStore{name: a.ident, decl: a.decl, node: i, value: assignedValue(doc, a.ident)})
+	}
+	for i, n := range g.nodes {
+		stmt, ok := n.node.(*ast.VariableDeclarationStatement)
+		if !ok || stmt.Lparen != 0 || len(stmt.Declarations) != 1 || stmt.Value == nil || isDefaultLiteral(stmt.Value) {
+			continue
+		}
+		if decl := stmt.Declarations[0]; decl != nil && decl.Name != nil && used[decl] {
+			stores = append(stores, deadStore{name: decl.Name, decl: decl, node: i, value: stmt.Value})
+		}
+	}
+
+	res := []deadStore{}
+	for _, store := range stores {
+		if !reached[store.node] || live[store.node][store.decl] {
+			continue
+		}
+		if slices.ContainsFunc(unreachable, func(r token.Range) bool { return r.Contains(store.name.NamePos) }) {
+			continue
+		}
+		nodes, always := g.overwrites(store.node, store.decl)
+		// The state variables are declared outside of the function.
+		if _, ok := store.decl.(*ast.VariableDeclaration); ok && !always && !ast.NodeRange(fn).Contains(store.decl.Start()) {
+			continue
+		}
+		store.always = always
+		for _, i := range nodes {
+			if i == store.node {
+				continue
+			}
+			for _, a := range accesses {
+				if a.written && a.decl == store.decl && g.accessed[a.ident] == i {
+					store.overwrites = append(store.overwrites, a.ident)
+				}
+			}
+			if name := declaredName(store.decl); ast.NodeRange(g.nodes[i].node).Contains(name.NamePos) {
+				store.overwrites = append(store.overwrites, name)
+			}
+		}
+		slices.SortFunc(store.overwrites, func(x, y *ast.Identifier) int {
+			return int(x.NamePos) - int(y.NamePos)
+		})
+		res = append(res, store)
+	}
+	slices.SortFunc(res, func(x, y deadStore) int {
+		return int(x.name.NamePos) - int(y.name.NamePos)
+	})
+	return res
+}
+
+// readState adds the reads of the state variables to the nodes of the
+// flow graph that may read all of them: the end of the function, the
+// returns, the assembly blocks and the calls of the other contracts and of
+// the functions reading the state.
+func (s *State) readState(doc *Document, fn *ast.FunctionDeclaration, g *flowGraph, accesses []variableAccess) {
+	readAll := func(n *flowNode) {
+		for _, a := range accesses {
+			n.uses[a.decl] = true
+		}
+	}
+	readAll(g.nodes[g.exit])
+	calls := map[int][]effectCall{}
+	roots := []callable{}
+	for i, n := range g.nodes {
+		switch n.node.(type) {
+		case nil:
+			continue
+		case *ast.ReturnStatement, *ast.AssemblyStatement:
+			readAll(n)
+			continue
+		}
+		r := ast.NodeRange(n.node)
+		f := s.effectsWithin(callable{doc: doc, node: fn}, func(node ast.Node) bool {
+			return r.Contains(node.Start())
+		})
+		if f.effects&(CallsView|CallsExternal|CallsUnknown) != 0 {
+			readAll(n)
+		}
+		calls[i] = f.calls
+		for _, call := range f.calls {
+			roots = append(roots, call.callee)
+		}
+	}
+	if len(roots) == 0 {
+		return
+	}
+	inferred := s.inferEffects(roots)
+	for i, calls := range calls {
+		for _, call := range calls {
+			if inferred[call.callee.node].effects&(ReadsState|CallsView|CallsExternal|CallsUnknown) != 0 {
+				readAll(g.nodes[i])
+			}
+		}
+	}
+}
+
+// overwrites returns the nodes first writing the variable again after the
+// node, and whether it's written again on every path to the end of the
+// function.
+func (g *flowGraph) overwrites(from int, decl ast.Node) ([]int, bool) {
+	res := []int{}
+	always := true
+	seen := map[int]bool{}
+	queue := slices.Clone(g.nodes[from].succs)
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		n := g.nodes[i]
+		if n.defs[decl] {
+			res = append(res, i)
+			continue
+		}
+		if len(n.succs) == 0 {
+			always = false
+		}
+		queue = append(queue, n.succs...)
+	}
+	return res, always
+}
+
+// assignedValue returns the value assigned to the variable at the
+// identifier, the right side of `x = value` or `x += value`; or nil e.g.
+// for `x++`.
+func assignedValue(doc *Document, ident *ast.Identifier) ast.Expression {
+	for _, node := range ast.PathEnclosingPos(doc.File, ident.NamePos) {
+		if assign, ok := node.(*ast.AssignmentExpression); ok && ast.NodeRange(assign.Left).Contains(ident.NamePos) {
+			return assign.Right
+		}
+	}
+	return nil
+}
+
+// isDefaultLiteral reports whether the expression is the literal default
+// value e.g. `0`, `false` or `""`. The declarations initialized with it
+// only spell out the default.
+func isDefaultLiteral(expr ast.Expression) bool {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok {
+		return false
+	}
+	switch lit.Value {
+	case "0", "false", `""`, "''":
+		return true
+	}
+	return false
+}
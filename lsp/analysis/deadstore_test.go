@@ -0,0 +1,140 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const deadStoreSrc = `pragma solidity ^0.8.0;
+
+interface IToken {
+    function transfer(address to, uint256 amount) external returns (bool);
+}
+
+contract Vault {
+    IToken token;
+    uint256 total;
+    uint256 fee;
+
+    function calculate(uint256 x) internal pure returns (uint256) {
+        return x * 2;
+    }
+
+    function local(uint256 x) external pure returns (uint256) {
+        uint256 amount = calculate(x);
+        amount = 0;
+        return amount;
+    }
+
+    function state(uint256 x) external {
+        total = x;
+        if (x > 10) {
+            total = 10;
+        } else {
+            total = x + 1;
+        }
+    }
+
+    function branch(uint256 x) external pure returns (uint256 y) {
+        y = x;
+        if (x > 10) {
+            y = 10;
+        }
+    }
+
+    function sideEffect(address to) external {
+        bool ok = token.transfer(to, 1);
+        ok = true;
+        require(ok);
+    }
+
+    function call(uint256 x) external {
+        fee = x;
+        _sync();
+        fee = 0;
+    }
+
+    function _sync() internal {
+        total = fee;
+    }
+
+    function loop(uint256[] memory xs) external pure {
+        uint256 last;
+        for (uint256 i = 0; i < xs.length; i++) {
+            last = xs[i];
+        }
+    }
+
+    function disabled() external {
+        if (false) {
+            total = 1;
+        }
+        total = 2;
+    }
+}
+`
+
+func Test_DeadStoreDiagnostics(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Vault.sol"
+	s.OpenDocument(uri, 1, deadStoreSrc)
+
+	// `y = x` is overwritten on one branch only, `fee = x` is read by _sync
+	// before it's overwritten, and `total = 1` is never executed.
+	expected := []string{
+		"16 Hint: The value assigned to `amount` is overwritten before it's read (17)",
+		"22 Hint: The value assigned to `total` is overwritten before it's read (24, 26)",
+		"38 Information: The value assigned to `ok` is overwritten before it's read; the call may change the state, keep it without the assignment (39)",
+		"56 Hint: The value assigned to `last` is never read",
+	}
+	got := []string{}
+	for _, d := range s.deadStoreDiagnostics(s.Documents[uri]) {
+		severity := map[lsp.DiagnosticSeverity]string{lsp.SeverityHint: "Hint", lsp.SeverityInformation: "Information"}[d.Severity]
+		line := fmt.Sprintf("%d %s: %s", d.Range.Start.Line, severity, d.Message)
+		related := []string{}
+		for _, info := range d.RelatedInformation {
+			related = append(related, fmt.Sprint(info.Location.Range.Start.Line))
+		}
+		if len(related) > 0 {
+			line += " (" + strings.Join(related, ", ") + ")"
+		}
+		got = append(got, line)
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_Reachable(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Vault.sol"
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    function f(uint256 x) external pure returns (uint256) {
+        while (x > 0) {
+            x--;
+        }
+        return x;
+        x = 1;
+    }
+}
+`
+	s.OpenDocument(uri, 1, src)
+	doc := s.Documents[uri]
+	fn := doc.File.Declarations[1].(*ast.ContractDeclaration).Body[0].(*ast.FunctionDeclaration)
+	g := buildFlowGraph(fn, s.variableAccesses(doc, fn))
+	reached := g.reachable()
+	for i, n := range g.nodes {
+		if n.node == nil {
+			continue
+		}
+		text := src[n.node.Start():n.node.End()]
+		if expected := !strings.HasPrefix(text, "x = 1"); reached[i] != expected {
+			t.Errorf("Expected `%s` to be reached: %t, got %t", text, expected, reached[i])
+		}
+	}
+}
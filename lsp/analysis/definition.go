@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+)
+
+// symbolAt returns the declaration that the identifier at the position
+// refers to. Import aliases are followed to the imported declarations.
+// Outside of the code, the arguments of the NatSpec tags refer to the
+// declarations too e.g. the parameter of `@param amount`, see natSpecName.
+func (s *State) symbolAt(uri string, position lsp.Position) *Symbol {
+	doc, ok := s.document(uri)
+	if !ok {
+		return nil
+	}
+	pos := toTokenPos(doc.Handle, position)
+	path := ast.PathEnclosingPos(doc.File, pos)
+	if _, ok := path[0].(*ast.Identifier); !ok {
+		if name, ok := natSpecNameAt(doc, pos); ok {
+			return s.resolveNatSpecName(doc, name)
+		}
+		return nil
+	}
+	sym := s.resolve(doc, path)
+	if followed := s.follow(sym); followed != nil {
+		return followed
+	}
+	return sym
+}
+
+func (s *State) Definition(id lsp.ID, uri string, position lsp.Position) lsp.DefinitionResponse {
+	locations := []lsp.Location{}
+	sym := s.symbolAt(uri, position)
+	if sym == nil {
+		return lsp.NewDefinitionResponse(id, &locations)
+	}
+
+	// The unit alias e.g. `Errors` in `import "./Errors.sol" as Errors;`
+	// is defined by the whole imported file.
+	if imp, ok := sym.Node.(*ast.ImportDirective); ok {
+		if target := s.ImportTarget(sym.Doc, imp); target != nil {
+			locations = append(locations, lsp.Location{URI: target.URI})
+			return lsp.NewDefinitionResponse(id, &locations)
+		}
+	}
+
+	locations = append(locations, lsp.Location{
+		URI:   sym.Doc.URI,
+		Range: toLspRange(sym.Doc.Handle, ast.NodeRange(sym.Name)),
+	})
+	return lsp.NewDefinitionResponse(id, &locations)
+}
+
+// Hover shows the header of the declaration under the cursor, the override
+// chain of a function, the ERC-165 identifier of an interface, the topics
+// and the data of the logs of an event and the panic codes for the
+// parameter of a `catch Panic` clause. The members of the
+// address type show their builtin declarations, the builtin globals e.g.
+// `block.timestamp` their replacements if they're deprecated, the NatSpec
+// tags e.g. `@inheritdoc` what they mean, and the cheatcodes of the Foundry
+// tests, see foundryLibraries. The content is Markdown, unless the client
+// renders the plain text only.
+func (s *State) Hover(id lsp.ID, uri string, position lsp.Position) lsp.HoverResponse {
+	markdown := s.rendersMarkdown()
+	contents := lsp.MarkupContent{Kind: lsp.PlainText}
+	if markdown {
+		contents.Kind = lsp.Markdown
+	}
+	if block, p, ok := s.blockAt(uri, position); ok {
+		uri, position = block.Doc.URI, p
+	}
+	sym := s.symbolAt(uri, position)
+	if sym == nil {
+		contents.Value = s.addressMemberHover(uri, position, markdown)
+		if contents.Value == "" {
+			contents.Value = s.legacyBuiltinHover(uri, position, markdown)
+		}
+		if contents.Value == "" {
+			contents.Value = s.globalHover(uri, position, markdown)
+		}
+		if contents.Value == "" {
+			contents.Value = s.natSpecTagHover(uri, position, markdown)
+		}
+		if contents.Value == "" {
+			contents.Value = s.foundryHover(uri, position, markdown)
+		}
+		return lsp.NewHoverResponse(id, contents)
+	}
+
+	content := declarationHeader(sym)
+	if markdown {
+		content = fmt.Sprintf("```solidity\n%s\n```", content)
+	}
+	if s.isGetterCall(uri, position) {
+		if sig, ok := s.getterSignature(s.getterOf(sym)); ok {
+			content += fmt.Sprintf("\n\npublic state variable (implicit getter) `%s`", sig)
+		} else {
+			content += "\n\npublic state variable (implicit getter)"
+		}
+	}
+	if chain := s.overrideChainHover(uri, position, sym, markdown); chain != "" {
+		content += "\n\n" + chain
+	}
+	if id := s.interfaceIDHover(sym, markdown); id != "" {
+		content += "\n\n" + id
+	}
+	if layout := s.eventLayoutHover(sym, markdown); layout != "" {
+		content += "\n\n" + layout
+	}
+	if codes := panicCodesHover(sym, markdown); codes != "" {
+		content += "\n\n" + codes
+	}
+	if inferred := s.inferredTypeHover(sym, markdown); inferred != "" {
+		content += "\n\n" + inferred
+	}
+	if effects := s.effectsHover(sym); effects != "" {
+		content += "\n\n" + effects
+	}
+	if slot := s.transientSlotHover(sym); slot != "" {
+		content += "\n\n" + slot
+	}
+	if deployment := s.deploymentHover(sym, markdown); deployment != "" {
+		content += "\n\n" + deployment
+	}
+	if doc := s.foundryDoc(uri, sym); doc != "" {
+		content += "\n\n" + doc
+	}
+	if sym.Doc.URI != uri {
+		content += fmt.Sprintf("\n\nDeclared in %s", s.RelativePath(sym.Doc.URI))
+	}
+	contents.Value = content
+	return lsp.NewHoverResponse(id, contents)
+}
+
+// rendersMarkdown reports whether the client renders the hover content as
+// Markdown. The clients that don't say otherwise are assumed to do.
+func (s *State) rendersMarkdown() bool {
+	textDocument := s.Capabilities.TextDocument
+	if textDocument == nil || textDocument.Hover == nil || len(textDocument.Hover.ContentFormat) == 0 {
+		return true
+	}
+	return slices.Contains(textDocument.Hover.ContentFormat, lsp.Markdown)
+}
+
+// declarationHeader returns the source of the declaration without its body
+// e.g. `function deposit(uint256 amount) external` or `error NotOwner()`.
+func declarationHeader(sym *Symbol) string {
+	src := sym.Doc.Handle.Src()
+	node := sym.Node
+	end := node.End()
+	if v, ok := node.(*ast.VariableDeclaration); ok && v.Type == nil {
+		return "var " + v.Name.Name
+	}
+	switch n := node.(type) {
+	case *ast.ContractDeclaration:
+		end = n.LeftBrace
+	case *ast.FunctionDeclaration:
+		if n.Body != nil {
+			end = n.Body.Start()
+		}
+	case *ast.ModifierDeclaration:
+		if n.Body != nil {
+			end = n.Body.Start()
+		}
+	case *ast.StructDeclaration, *ast.EnumDeclaration:
+		// The members are a part of the header.
+	case *ast.ImportDirective:
+		if n.Alias != nil {
+			return "import " + n.Path.Value + " as " + n.Alias.Name
+		}
+	case *ast.EventDeclaration, *ast.ErrorDeclaration, *ast.VariableDeclaration, *ast.TypeDeclaration,
+		*ast.Param, *ast.ImportSymbol:
+	default:
+		return sym.Name.Name
+	}
+	return strings.TrimRight(strings.TrimSpace(string(src[node.Start():end])), ";")
+}
+
+// isGetterCall reports whether the identifier at the position is the called
+// member of an external call to the getter of a public state variable e.g.
+// `totalSupply` in `vault.totalSupply()`.
+func (s *State) isGetterCall(uri string, position lsp.Position) bool {
+	doc, ok := s.document(uri)
+	if !ok {
+		return false
+	}
+	path := ast.PathEnclosingPos(doc.File, toTokenPos(doc.Handle, position))
+	if len(path) < 3 {
+		return false
+	}
+	access, ok := path[1].(*ast.MemberAccessExpression)
+	if !ok || access.Member != path[0] {
+		return false
+	}
+	call, ok := path[2].(*ast.CallExpression)
+	return ok && call.Function == ast.Expression(access) && s.getterOf(s.resolve(doc, path)) != nil
+}
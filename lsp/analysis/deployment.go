@@ -0,0 +1,396 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/analyzer/taint"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// The constructor parameters are what the deployer decides: DeploymentOf
+// follows each of them into the state variables it's stored in, through
+// the local variables and the arithmetic, see taint.From, and through the
+// arguments of the base constructors, both in the constructor header e.g.
+// `constructor(address admin) Ownable(admin)` and in the inheritance list,
+// into the constructors of the bases, whichever files they're in.
+
+// Deployment is what the deployer of a contract decides.
+type Deployment struct {
+	Contract   *Symbol
+	Parameters []DeploymentParameter
+	Unassigned []*Symbol // the immutables without a value their constructors never assign, in the contract and its bases
+}
+
+// DeploymentParameter is a constructor parameter of a contract, with the
+// state variables its value is stored in.
+type DeploymentParameter struct {
+	Contract *Symbol
+	Param    *ast.Param
+	Flows    []ParameterFlow
+}
+
+// ParameterFlow is a state variable a constructor parameter is stored in.
+type ParameterFlow struct {
+	Variable *Symbol        // the state variable, declared in the contract or one of its bases
+	Derived  bool           // is a value computed from the parameter stored, rather than the parameter itself?
+	Via      []ParameterHop // the parameters of the base constructors the value is passed through, in order
+}
+
+// ParameterHop is a parameter of a base constructor a value is passed to.
+type ParameterHop struct {
+	Contract *Symbol
+	Param    *ast.Param
+}
+
+// Name returns the qualified name of the parameter e.g. "Ownable.admin".
+func (h ParameterHop) Name() string {
+	return h.Contract.Name.Name + "." + h.Param.Name.Name
+}
+
+// VariableName returns the name of the state variable qualified with the
+// name of its contract e.g. "Ownable.owner".
+func (s *State) VariableName(v *Symbol) string {
+	if c := s.declaringContract(v); c != nil {
+		return c.Name.Name + "." + v.Name.Name
+	}
+	return v.Name.Name
+}
+
+// DeploymentOf returns the constructor parameters of the contract, see
+// ResolveContract, with the state variables their values are stored in.
+func (s *State) DeploymentOf(ref string) (*Deployment, error) {
+	contract, err := s.resolveConcreteContract(ref)
+	if err != nil {
+		return nil, err
+	}
+	res := &Deployment{Contract: contract, Parameters: s.deploymentParameters(contract), Unassigned: []*Symbol{}}
+	for _, c := range s.linearize(contract) {
+		res.Unassigned = append(res.Unassigned, s.unassignedImmutables(c)...)
+	}
+	return res, nil
+}
+
+// deploymentParameters returns the named parameters of the constructor of
+// the contract with their flows; or none if it has no constructor.
+func (s *State) deploymentParameters(contract *Symbol) []DeploymentParameter {
+	res := []DeploymentParameter{}
+	ctor := constructorOf(contract.Node.(*ast.ContractDeclaration))
+	if ctor == nil || ctor.Type.Params == nil {
+		return res
+	}
+	for i, param := range ctor.Type.Params.List {
+		if param.Name == nil {
+			continue
+		}
+		res = append(res, DeploymentParameter{
+			Contract: contract,
+			Param:    param,
+			Flows:    s.parameterFlows(contract, i, map[ast.Node]bool{}),
+		})
+	}
+	return res
+}
+
+func constructorOf(c *ast.ContractDeclaration) *ast.FunctionDeclaration {
+	for _, member := range c.Body {
+		if fn, ok := member.(*ast.FunctionDeclaration); ok && fn.Kind == token.CONSTRUCTOR {
+			return fn
+		}
+	}
+	return nil
+}
+
+// parameterFlows returns the state variables the i-th parameter of the
+// constructor of the contract is stored in. The constructors being
+// followed are visited, since the inheritance may be cyclic.
+func (s *State) parameterFlows(contract *Symbol, i int, visited map[ast.Node]bool) []ParameterFlow {
+	res := []ParameterFlow{}
+	doc, c := contract.Doc, contract.Node.(*ast.ContractDeclaration)
+	ctor := constructorOf(c)
+	if ctor == nil || visited[ctor] || ctor.Type.Params == nil || i >= len(ctor.Type.Params.List) {
+		return res
+	}
+	param := ctor.Type.Params.List[i].Name
+	if param == nil {
+		return res
+	}
+	visited[ctor] = true
+	defer delete(visited, ctor)
+
+	tainted := taint.From(ctor, param.Name)
+	add := func(flow ParameterFlow) {
+		if !slices.ContainsFunc(res, func(f ParameterFlow) bool {
+			return f.Variable.Node == flow.Variable.Node && f.Derived == flow.Derived && slices.Equal(f.Via, flow.Via)
+		}) {
+			res = append(res, flow)
+		}
+	}
+	if ctor.Body != nil {
+		ast.Inspect(ctor.Body, func(n ast.Node) bool {
+			assign, ok := n.(*ast.AssignmentExpression)
+			if !ok || !tainted.IsTainted(assign.Right) {
+				return true
+			}
+			derived := assign.Operator != token.ASSIGN || !s.isParameter(doc, assign.Right, param.Name)
+			for _, target := range s.assignedStateVariables(doc, assign.Left) {
+				add(ParameterFlow{Variable: target, Derived: derived})
+			}
+			return true
+		})
+	}
+
+	// The arguments of the base constructors.
+	path := ast.PathEnclosingPos(doc.File, ctor.Start())
+	calls := []struct {
+		base *Symbol
+		args []ast.Expression
+	}{}
+	for _, m := range ctor.Modifiers {
+		if base := s.follow(s.resolveExpr(doc, path, m.Name)); base != nil {
+			if _, ok := base.Node.(*ast.ContractDeclaration); ok {
+				calls = append(calls, struct {
+					base *Symbol
+					args []ast.Expression
+				}{base, m.Args})
+			}
+		}
+	}
+	for _, spec := range c.Bases {
+		if base := s.follow(s.resolveExpr(doc, []ast.Node{doc.File}, spec.Name)); base != nil && spec.Args != nil {
+			if _, ok := base.Node.(*ast.ContractDeclaration); ok {
+				calls = append(calls, struct {
+					base *Symbol
+					args []ast.Expression
+				}{base, spec.Args})
+			}
+		}
+	}
+	for _, call := range calls {
+		baseCtor := constructorOf(call.base.Node.(*ast.ContractDeclaration))
+		if baseCtor == nil || baseCtor.Type.Params == nil {
+			continue
+		}
+		for j, arg := range call.args {
+			if j >= len(baseCtor.Type.Params.List) || baseCtor.Type.Params.List[j].Name == nil || !tainted.IsTainted(arg) {
+				continue
+			}
+			hop := ParameterHop{Contract: call.base, Param: baseCtor.Type.Params.List[j]}
+			for _, flow := range s.parameterFlows(call.base, j, visited) {
+				flow.Derived = flow.Derived || !s.isParameter(doc, arg, param.Name)
+				flow.Via = append([]ParameterHop{hop}, flow.Via...)
+				add(flow)
+			}
+		}
+	}
+	return res
+}
+
+// isParameter reports whether the expression is the parameter itself, or
+// a conversion of it e.g. `IERC20(token)` or `address(vault)`.
+func (s *State) isParameter(doc *Document, x ast.Expression, name string) bool {
+	for {
+		switch e := x.(type) {
+		case *ast.Identifier:
+			return e.Name == name
+		case *ast.TupleExpression:
+			if len(e.Elements) != 1 {
+				return false
+			}
+			x = e.Elements[0]
+		case *ast.CallExpression:
+			if len(e.Args) != 1 || e.Names != nil {
+				return false
+			}
+			if _, ok := e.Function.(*ast.ElementaryType); !ok && s.scopeOfType(doc, e.Function) == nil {
+				return false
+			}
+			x = e.Args[0]
+		default:
+			return false
+		}
+	}
+}
+
+// assignedStateVariables returns the state variables the left side of an
+// assignment writes e.g. `owner` in `owner = admin` or `(a, b) = f()`.
+func (s *State) assignedStateVariables(doc *Document, left ast.Expression) []*Symbol {
+	switch x := left.(type) {
+	case *ast.Identifier:
+		sym := s.resolve(doc, ast.PathEnclosingPos(doc.File, x.Start()))
+		if sym == nil {
+			return nil
+		}
+		if decl, ok := sym.Node.(*ast.VariableDeclaration); !ok || decl.Constant || s.declaringContract(sym) == nil {
+			return nil
+		}
+		return []*Symbol{sym}
+	case *ast.TupleExpression:
+		res := []*Symbol{}
+		for _, elem := range x.Elements {
+			if elem != nil {
+				res = append(res, s.assignedStateVariables(doc, elem)...)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// deploymentDiagnostics reports the mistakes of the constructors. The
+// checks can be disabled one by one with their codes in the [detectors]
+// section:
+//
+//   - unassigned-immutable: an immutable without a value is never assigned
+//     in the constructor of its contract. The compilers older than 0.8.21
+//     reject it, and the later ones leave it zero, which is as likely a
+//     mistake;
+//   - unused-constructor-parameter: the value the deployer chooses is only
+//     checked: the parameter is not stored, not passed to a base
+//     constructor nor to any call other than `require` and `assert`. The
+//     parameters never read are unused-variable instead.
+func (s *State) deploymentDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(node ast.Node, severity lsp.DiagnosticSeverity, code, message string) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(node)),
+			Severity: severity,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Kind != token.CONTRACT || c.Name == nil {
+			continue
+		}
+		contract := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		for _, v := range s.unassignedImmutables(contract) {
+			report(v.Name, lsp.SeverityError, "unassigned-immutable",
+				fmt.Sprintf("The immutable `%s` is never assigned in the constructor of `%s`", v.Name.Name, c.Name.Name))
+		}
+
+		ctor := constructorOf(c)
+		for _, p := range s.deploymentParameters(contract) {
+			if len(p.Flows) > 0 || !isRead(ctor, p.Param.Name.Name) || passedToCall(ctor, p.Param.Name.Name) {
+				continue
+			}
+			report(p.Param.Name, lsp.SeverityWarning, "unused-constructor-parameter",
+				fmt.Sprintf("The constructor parameter `%s` is only checked: it's not stored, nor passed to a base constructor or a call", p.Param.Name.Name))
+		}
+	}
+	return res
+}
+
+// unassignedImmutables returns the immutables of the contract without a
+// value which its constructor never assigns.
+func (s *State) unassignedImmutables(contract *Symbol) []*Symbol {
+	res := []*Symbol{}
+	assigned := map[ast.Node]bool{}
+	if ctor := constructorOf(contract.Node.(*ast.ContractDeclaration)); ctor != nil && ctor.Body != nil {
+		ast.Inspect(ctor.Body, func(n ast.Node) bool {
+			if assign, ok := n.(*ast.AssignmentExpression); ok {
+				for _, sym := range s.assignedStateVariables(contract.Doc, assign.Left) {
+					assigned[sym.Node] = true
+				}
+			}
+			return true
+		})
+	}
+	for _, member := range contract.Node.(*ast.ContractDeclaration).Body {
+		if v, ok := member.(*ast.VariableDeclaration); ok && v.Immutable && v.Value == nil && !assigned[v] {
+			res = append(res, &Symbol{Doc: contract.Doc, Name: v.Name, Node: v})
+		}
+	}
+	return res
+}
+
+// isRead reports whether the constructor refers to the parameter, in its
+// body or in the arguments of its modifiers.
+func isRead(ctor *ast.FunctionDeclaration, name string) bool {
+	found := false
+	inspect := func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Identifier); ok && ident.Name == name {
+			found = true
+		}
+		return !found
+	}
+	for _, m := range ctor.Modifiers {
+		for _, arg := range m.Args {
+			ast.Inspect(arg, inspect)
+		}
+	}
+	if ctor.Body != nil {
+		ast.Inspect(ctor.Body, inspect)
+	}
+	return found
+}
+
+// passedToCall reports whether the value of the parameter reaches a call
+// other than `require` and `assert`, as an argument or as the called
+// contract e.g. `token.approve(spender, amount)`, or an emitted event.
+func passedToCall(ctor *ast.FunctionDeclaration, name string) bool {
+	if ctor.Body == nil {
+		return false
+	}
+	tainted := taint.From(ctor, name)
+	found := false
+	ast.Inspect(ctor.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpression)
+		if !ok || found {
+			return !found
+		}
+		if fn, ok := call.Function.(*ast.Identifier); ok && (fn.Name == "require" || fn.Name == "assert") {
+			return true
+		}
+		found = tainted.IsTainted(call.Function) || slices.ContainsFunc(call.Args, tainted.IsTainted)
+		return !found
+	})
+	return found
+}
+
+// deploymentHover returns the constructor parameters the immutable is set
+// from e.g. "Set from the constructor parameter `_owner`"; or an empty
+// string if it's not an immutable, or no parameter flows into it.
+func (s *State) deploymentHover(sym *Symbol, markdown bool) string {
+	v, ok := sym.Node.(*ast.VariableDeclaration)
+	if !ok || !v.Immutable {
+		return ""
+	}
+	contract := s.declaringContract(sym)
+	if contract == nil {
+		return ""
+	}
+	code := func(s string) string { return s }
+	if markdown {
+		code = func(s string) string { return "`" + s + "`" }
+	}
+	set, derived := []string{}, []string{}
+	for _, p := range s.deploymentParameters(contract) {
+		for _, flow := range p.Flows {
+			if flow.Variable.Node != sym.Node || len(flow.Via) > 0 {
+				continue
+			}
+			if flow.Derived {
+				derived = append(derived, code(p.Param.Name.Name))
+			} else {
+				set = append(set, code(p.Param.Name.Name))
+			}
+		}
+	}
+	lines := []string{}
+	if len(set) > 0 {
+		lines = append(lines, fmt.Sprintf("Set from the constructor parameter %s", strings.Join(set, ", ")))
+	}
+	if len(derived) > 0 {
+		lines = append(lines, fmt.Sprintf("Derived from the constructor parameter %s", strings.Join(derived, ", ")))
+	}
+	return strings.Join(lines, "\n\n")
+}
@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const deploymentCore = `pragma solidity ^0.8.20;
+
+contract Core {
+    address public immutable owner;
+    uint256 public immutable cap;
+
+    constructor(address _owner) {
+        owner = _owner;
+    }
+}
+`
+
+const deploymentFees = `pragma solidity ^0.8.20;
+
+import "./Core.sol";
+
+contract Fees is Core {
+    uint256 public immutable fee;
+
+    constructor(address _admin, uint256 _fee) Core(_admin) {
+        fee = _fee;
+    }
+}
+`
+
+const deploymentVault = `pragma solidity ^0.8.20;
+
+import "./Fees.sol";
+
+contract Vault is Fees {
+    IERC20 public immutable token;
+
+    constructor(address _owner, uint256 _fee, address _token, uint256 _deadline) Fees(_owner, _fee * 2) {
+        require(_deadline > block.timestamp);
+        token = IERC20(_token);
+    }
+}
+
+interface IERC20 {}
+`
+
+func openDeployment() *State {
+	s := NewState()
+	s.OpenDocument("file:///ws/src/Core.sol", 1, deploymentCore)
+	s.OpenDocument("file:///ws/src/Fees.sol", 1, deploymentFees)
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, deploymentVault)
+	return s
+}
+
+func Test_DeploymentOf(t *testing.T) {
+	s := openDeployment()
+	deployment, err := s.DeploymentOf("Vault")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	flows := map[string][]string{}
+	for _, p := range deployment.Parameters {
+		flows[p.Param.Name.Name] = []string{}
+		for _, flow := range p.Flows {
+			via := []string{}
+			for _, hop := range flow.Via {
+				via = append(via, hop.Name())
+			}
+			text := fmt.Sprintf("%s derived=%t via=%s", s.VariableName(flow.Variable), flow.Derived, strings.Join(via, ","))
+			flows[p.Param.Name.Name] = append(flows[p.Param.Name.Name], text)
+		}
+	}
+	expected := map[string][]string{
+		"_owner":    {"Core.owner derived=false via=Fees._admin,Core._owner"},
+		"_fee":      {"Fees.fee derived=true via=Fees._fee"},
+		"_token":    {"Vault.token derived=false via="},
+		"_deadline": {},
+	}
+	if fmt.Sprint(flows) != fmt.Sprint(expected) {
+		t.Errorf("Expected the flows\n%v\ngot\n%v", expected, flows)
+	}
+	if len(deployment.Unassigned) != 1 || deployment.Unassigned[0].Name.Name != "cap" {
+		t.Errorf("Expected `cap` unassigned, got %v", deployment.Unassigned)
+	}
+
+	if _, err := s.DeploymentOf("IERC20"); err == nil {
+		t.Errorf("Expected an error for an interface")
+	}
+}
+
+func Test_DeploymentDiagnostics(t *testing.T) {
+	s := openDeployment()
+	diagnostics := s.deploymentDiagnostics(s.Documents["file:///ws/src/Core.sol"])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected the unassigned immutable, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "unassigned-immutable" || d.Severity != lsp.SeverityError || d.Range.Start.Line != 4 {
+		t.Errorf("Expected the unassigned-immutable error at line 4, got %s of severity %d at line %d", d.Code, d.Severity, d.Range.Start.Line)
+	}
+	if expected := "The immutable `cap` is never assigned in the constructor of `Core`"; d.Message != expected {
+		t.Errorf("Expected %q, got %q", expected, d.Message)
+	}
+
+	diagnostics = s.deploymentDiagnostics(s.Documents["file:///ws/src/Vault.sol"])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected the parameter only checked, got %v", diagnostics)
+	}
+	d = diagnostics[0]
+	if d.Code != "unused-constructor-parameter" || d.Severity != lsp.SeverityWarning || d.Range.Start.Line != 7 {
+		t.Errorf("Expected the unused-constructor-parameter warning at line 7, got %s of severity %d at line %d", d.Code, d.Severity, d.Range.Start.Line)
+	}
+	if !strings.HasPrefix(d.Message, "The constructor parameter `_deadline` is only checked") {
+		t.Errorf("Expected the message about `_deadline`, got %q", d.Message)
+	}
+	if diagnostics := s.deploymentDiagnostics(s.Documents["file:///ws/src/Fees.sol"]); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics in Fees, got %v", diagnostics)
+	}
+
+	s.Config.Disabled = []string{"unassigned-immutable", "unused-constructor-parameter"}
+	for _, uri := range []string{"file:///ws/src/Core.sol", "file:///ws/src/Vault.sol"} {
+		if diagnostics := s.deploymentDiagnostics(s.Documents[uri]); len(diagnostics) != 0 {
+			t.Errorf("Expected the checks to be disabled, got %v", diagnostics)
+		}
+	}
+}
+
+func Test_DeploymentHover(t *testing.T) {
+	s := openDeployment()
+	hover := s.Hover(lsp.IntID(1), "file:///ws/src/Core.sol", lsp.Position{Line: 3, Character: 30}).Result.Contents.Value
+	if !strings.Contains(hover, "Set from the constructor parameter `_owner`") {
+		t.Errorf("Expected the parameter `owner` is set from, got\n%s", hover)
+	}
+	hover = s.Hover(lsp.IntID(2), "file:///ws/src/Vault.sol", lsp.Position{Line: 5, Character: 30}).Result.Contents.Value
+	if !strings.Contains(hover, "Set from the constructor parameter `_token`") {
+		t.Errorf("Expected the conversion of the parameter to set `token`, got\n%s", hover)
+	}
+	hover = s.Hover(lsp.IntID(3), "file:///ws/src/Core.sol", lsp.Position{Line: 4, Character: 30}).Result.Contents.Value
+	if strings.Contains(hover, "constructor parameter") {
+		t.Errorf("Expected no parameter for `cap`, got\n%s", hover)
+	}
+}
@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+)
+
+// destructuringDiagnostics checks the tuples assigned the results of a call
+// e.g. `(bool ok, ) = to.call{value: v}("")`: the tuple must have as many
+// components as the call returns values, and the types of the components
+// must match the results. The results of an unknown call, and the
+// components of an unknown type, are assumed to be right.
+func (s *State) destructuringDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	invalid := func(r token.Range, format string, args ...any) {
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, r),
+			Severity: lsp.SeverityError,
+			Code:     "invalid-destructuring",
+			Source:   "solbot",
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	check := func(tuple token.Range, components []ast.Node, types func(i int) string, value ast.Expression) {
+		call, ok := value.(*ast.CallExpression)
+		if !ok {
+			return
+		}
+		path := ast.PathEnclosingPos(doc.File, call.Start())
+		results, ok := s.resultTypes(doc, path, call)
+		if !ok {
+			return
+		}
+		if len(results) != len(components) {
+			invalid(tuple, "`%s` returns %d %s, but the tuple has %d %s", ast.ExprString(call.Function),
+				len(results), plural(len(results), "value"), len(components), plural(len(components), "component"))
+			return
+		}
+		for i, component := range components {
+			if component == nil {
+				continue
+			}
+			if t := types(i); t != "" && !convertible(results[i], t) {
+				invalid(ast.NodeRange(component), "Component %d of the tuple is `%s`, but `%s` returns `%s`", i+1, t, ast.ExprString(call.Function), results[i])
+			}
+		}
+	}
+
+	ast.Inspect(doc.File, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.VariableDeclarationStatement:
+			if n.Lparen == 0 {
+				return true
+			}
+			components := make([]ast.Node, len(n.Declarations))
+			for i, decl := range n.Declarations {
+				if decl != nil {
+					components[i] = decl
+				}
+			}
+			types := func(i int) string {
+				if t, ok := n.Declarations[i].Type.(*ast.ElementaryType); ok {
+					return t.Value
+				}
+				return ""
+			}
+			check(token.Range{Start: n.Lparen, End: n.Rparen + 1}, components, types, n.Value)
+		case *ast.AssignmentExpression:
+			tuple, ok := n.Left.(*ast.TupleExpression)
+			if !ok || n.Operator != token.ASSIGN {
+				return true
+			}
+			path := ast.PathEnclosingPos(doc.File, tuple.Start())
+			components := make([]ast.Node, len(tuple.Elements))
+			for i, element := range tuple.Elements {
+				if element != nil {
+					components[i] = element
+				}
+			}
+			types := func(i int) string { return s.argumentType(doc, path, tuple.Elements[i]) }
+			check(ast.NodeRange(tuple), components, types, n.Right)
+		}
+		return true
+	})
+	return res
+}
+
+// resultTypes returns the types of the values returned by the call e.g.
+// "bool" and "bytes" for `to.call(data)`: elementary types, and the type
+// names as written for the other types; or false if they're unknown.
+func (s *State) resultTypes(doc *Document, path []ast.Node, call *ast.CallExpression) ([]string, bool) {
+	if results, ok := s.addressCallResults(doc, path, call); ok {
+		return results, true
+	}
+	if name := s.builtinName(doc, path, call); name != "" {
+		return []string{builtinResults[name]}, true
+	}
+	x := call.Function
+	if options, ok := x.(*ast.CallOptionsExpression); ok {
+		x = options.Expression
+	}
+	fn := s.follow(s.resolveExpr(doc, path, x))
+	if fn == nil {
+		return nil, false
+	}
+	decl, ok := fn.Node.(*ast.FunctionDeclaration)
+	if !ok {
+		return nil, false
+	}
+	res := []string{}
+	if decl.Type.Results != nil {
+		for _, result := range decl.Type.Results.List {
+			if t, ok := result.Type.(*ast.ElementaryType); ok {
+				res = append(res, t.Value)
+			} else {
+				res = append(res, ast.ExprString(result.Type))
+			}
+		}
+	}
+	return res, true
+}
@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"solbot/analyzer"
+	"solbot/lsp"
+	"solbot/render"
+	"solbot/reporter"
+	"strings"
+	"sync"
+)
+
+// detectorDiagnostics reports the findings of the detectors of the
+// analyzer, the same as `solbot analyze`, with the codes of their rules.
+// The code links to the writeup of the rule, see ruleHref. The detectors
+// disabled in the settings are skipped.
+func (s *State) detectorDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, f := range analyzer.AnalyzeFile(doc.File, s.Config.Disabled...) {
+		if len(f.Locations) == 0 {
+			continue
+		}
+		d := lsp.Diagnostic{
+			Severity: findingSeverity(f.Severity),
+			Code:     f.Code,
+			Source:   "solbot",
+			Message:  f.Title,
+		}
+		if rule, ok := analyzer.LookupRule(f.Code); ok {
+			if href := ruleHref(rule); href != "" {
+				d.CodeDescription = &lsp.CodeDescription{Href: href}
+			}
+		}
+		for i, loc := range f.Locations {
+			r := toLspRange(doc.Handle, render.WordRange(doc.Handle.Src(), loc.Position.Offset))
+			if i == 0 {
+				d.Range = r
+				continue
+			}
+			d.RelatedInformation = append(d.RelatedInformation, lsp.DiagnosticRelatedInformation{
+				Location: lsp.Location{URI: doc.URI, Range: r},
+				Message:  loc.Context,
+			})
+		}
+		res = append(res, d)
+	}
+	return res
+}
+
+// findingSeverity returns the severity of the diagnostic of a finding e.g.
+// an error for "High". The severities below medium are informations.
+func findingSeverity(severity string) lsp.DiagnosticSeverity {
+	switch strings.ToLower(severity) {
+	case "error", "high":
+		return lsp.SeverityError
+	case "warning", "medium":
+		return lsp.SeverityWarning
+	}
+	return lsp.SeverityInformation
+}
+
+var (
+	ruleHrefs   = map[string]string{}
+	ruleHrefsMu sync.Mutex
+)
+
+// ruleHref returns the URI of the writeup of the rule, the same as `solbot
+// explain` prints, rendered as Markdown to the temporary directory the
+// first time it's needed; or an empty string if it can't be written. The
+// editors open it from the code of the diagnostic.
+func ruleHref(rule reporter.Rule) string {
+	ruleHrefsMu.Lock()
+	defer ruleHrefsMu.Unlock()
+	if href, ok := ruleHrefs[rule.Code]; ok {
+		return href
+	}
+	path := filepath.Join(os.TempDir(), "solbot-rules", rule.Code+".md")
+	href := ""
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		if err := os.WriteFile(path, []byte(rule.Markdown()), 0644); err == nil {
+			href = PathToURI(path)
+		}
+	}
+	ruleHrefs[rule.Code] = href
+	return href
+}
+
+// withoutDetectorDiagnostics returns the diagnostics other than the
+// findings of the detectors, see detectorDiagnostics.
+func withoutDetectorDiagnostics(diagnostics []lsp.Diagnostic) []lsp.Diagnostic {
+	return slices.DeleteFunc(slices.Clone(diagnostics), func(d lsp.Diagnostic) bool {
+		_, ok := analyzer.LookupRule(d.Code)
+		return ok
+	})
+}
@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func Test_DetectorDiagnostics(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    function pay(address[] calldata to) external payable {
+        for (uint256 i = 0; i < to.length; i++) {
+            payable(to[i]).transfer(msg.value);
+        }
+    }
+}
+`
+	s := NewState()
+	s.OpenDocument(uri, 1, src)
+	var found *lsp.Diagnostic
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "msg-value-loop" {
+			found = &d
+		}
+	}
+	if found == nil {
+		t.Fatalf("Expected the msg-value-loop finding")
+	}
+	if found.Range.Start.Line != 5 || found.Severity != lsp.SeverityWarning || found.Source != "solbot" {
+		t.Errorf("Expected the warning at line 5, got %+v", found)
+	}
+	if found.CodeDescription == nil || !strings.HasPrefix(found.CodeDescription.Href, "file://") {
+		t.Fatalf("Expected the link to the writeup, got %+v", found.CodeDescription)
+	}
+	writeup, err := os.ReadFile(URIToPath(found.CodeDescription.Href))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(writeup), "## Exploit scenario") {
+		t.Errorf("Expected the writeup of the rule, got %q", writeup)
+	}
+
+	// The findings are counted once in the baseline.
+	count := 0
+	for _, f := range s.BaselineFindings(uri) {
+		if f.Code == "msg-value-loop" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected the finding once in the baseline, got %d", count)
+	}
+
+	s.Config.Disabled = []string{"msg-value-loop"}
+	for _, d := range s.detectorDiagnostics(s.Documents[uri]) {
+		if d.Code == "msg-value-loop" {
+			t.Errorf("Expected the detector to be disabled, got %+v", d)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/parser"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+// pipelineOutput runs everything solbot computes for the files of the
+// directory and returns the outputs as they reach the users: the syntax
+// errors, the published diagnostics, the code actions, the inlay hints, the
+// hovers, the definitions and the completions at every identifier, the
+// metrics and the answers of the session.
+func pipelineOutput(t *testing.T, dir string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	write := func(label string, v any) {
+		out, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("Expected %s to marshal, got %v", label, err)
+		}
+		fmt.Fprintf(&b, "%s: %s\n", label, out)
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s := NewState()
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sess := NewSession()
+	for _, doc := range s.sortedDocuments() {
+		name := s.RelativePath(doc.URI)
+		sess.AddFile(filepath.Join(dir, name), doc.Handle.Src())
+
+		p := parser.Parser{}
+		p.Init(token.NewFile(name, doc.Handle.Src()))
+		p.ParseFile()
+		write(name+" errors", p.Errors())
+
+		write(name+" diagnostics", s.Diagnostics(context.Background(), doc.URI))
+		whole := toLspRange(doc.Handle, token.Range{Start: 0, End: token.Pos(len(doc.Handle.Src()))})
+		write(name+" code actions", s.CodeAction(lsp.IntID(1), doc.URI, whole))
+		write(name+" inlay hints", s.InlayHint(lsp.IntID(1), doc.URI, whole))
+		organized, _ := s.OrganizeImports(doc.URI)
+		write(name+" organized imports", organized)
+
+		inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+			position := toLspPosition(doc.Handle, ident.Start())
+			at := fmt.Sprintf("%s:%d:%d", name, position.Line, position.Character)
+			write(at+" hover", s.Hover(lsp.IntID(1), doc.URI, position))
+			write(at+" definition", s.Definition(lsp.IntID(1), doc.URI, position))
+			write(at+" completion", s.Completion(lsp.IntID(1), doc.URI, position))
+		})
+	}
+	for _, f := range s.PathMetrics(root) {
+		write(s.RelativePath(f.Doc.URI)+" "+f.Name()+" metrics",
+			[]int{f.Complexity, f.Statements, f.Parameters, f.Nesting})
+	}
+
+	graph := sess.CallGraph()
+	for _, fn := range sess.Functions() {
+		pos := sess.Position(fn)
+		label := fmt.Sprintf("%s:%d:%d %s", pos.Filename, pos.Line, pos.Column, fn.Name.Name)
+		refs := []token.Position{}
+		for _, ref := range sess.ReferencesTo(fn) {
+			refs = append(refs, ref.Doc.Handle.Position(ref.Ident.Start()))
+		}
+		write(label+" references", refs)
+		callees := []token.Position{}
+		for _, callee := range graph[fn.Node] {
+			callees = append(callees, sess.Position(callee))
+		}
+		write(label+" callees", callees)
+	}
+	for _, c := range sess.Contracts() {
+		layout, _ := sess.StorageLayout(c)
+		pos := sess.Position(c)
+		write(fmt.Sprintf("%s:%d:%d %s layout", pos.Filename, pos.Line, pos.Column, c.Name.Name), layout)
+	}
+	return b.Bytes()
+}
+
+// Test_Determinism runs the pipeline over the fixtures with a single
+// thread, then again in many parallel tests with more threads, and expects
+// the same bytes every time. A map iteration or a goroutine leaking into
+// the order of an output fails it.
+func Test_Determinism(t *testing.T) {
+	dirs, _ := filepath.Glob("testdata/*")
+	if len(dirs) == 0 {
+		t.Fatalf("Expected the fixtures in testdata")
+	}
+
+	expected := map[string][]byte{}
+	procs := runtime.GOMAXPROCS(1)
+	for _, dir := range dirs {
+		expected[dir] = pipelineOutput(t, dir)
+	}
+	runtime.GOMAXPROCS(max(4, 2*procs))
+	defer runtime.GOMAXPROCS(procs)
+
+	t.Run("parallel", func(t *testing.T) {
+		for _, dir := range dirs {
+			for i := 0; i < 8; i++ {
+				dir := dir
+				t.Run(fmt.Sprintf("%s/%d", filepath.Base(dir), i), func(t *testing.T) {
+					t.Parallel()
+					if got := pipelineOutput(t, dir); !bytes.Equal(got, expected[dir]) {
+						t.Errorf("Expected the same output for %s, got a difference at:\n%s", dir, firstDifference(expected[dir], got))
+					}
+				})
+			}
+		}
+	})
+}
+
+// firstDifference returns the first line of the outputs that differs.
+func firstDifference(a, b []byte) string {
+	aLines, bLines := strings.Split(string(a), "\n"), strings.Split(string(b), "\n")
+	for i := 0; i < min(len(aLines), len(bLines)); i++ {
+		if aLines[i] != bLines[i] {
+			return fmt.Sprintf("- %s\n+ %s", aLines[i], bLines[i])
+		}
+	}
+	return fmt.Sprintf("%d lines, then %d lines", len(aLines), len(bLines))
+}
@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"solbot/ast"
+	"solbot/lsp"
+)
+
+// PullsDiagnostics reports whether the client pulls the diagnostics with
+// textDocument/diagnostic, so that they must not be pushed.
+func (s *State) PullsDiagnostics() bool {
+	textDocument := s.Capabilities.TextDocument
+	return textDocument != nil && textDocument.Diagnostic != nil
+}
+
+// RefreshesDiagnostics reports whether the client pulls the diagnostics
+// again when the server sends the workspace/diagnostic/refresh request.
+func (s *State) RefreshesDiagnostics() bool {
+	workspace := s.Capabilities.Workspace
+	return s.PullsDiagnostics() && workspace != nil && workspace.Diagnostics != nil && workspace.Diagnostics.RefreshSupport
+}
+
+// DocumentDiagnostic answers the textDocument/diagnostic request with the
+// same diagnostics Diagnostics publishes. If the result ID the client got
+// last is still the current one, the report is unchanged and nothing is
+// analyzed; the diagnostics computed for the same document, imports and
+// configuration are reused too. An unknown document, e.g. one just closed,
+// has no diagnostics.
+func (s *State) DocumentDiagnostic(ctx context.Context, id lsp.ID, params lsp.DocumentDiagnosticParams) lsp.DocumentDiagnosticResponse {
+	if f, ok := s.projectFiles[params.TextDocument.URI]; ok {
+		items := s.overrideSeverities(s.projectFileDiagnostics(f))
+		return lsp.NewDocumentDiagnosticResponse(id, lsp.DocumentDiagnosticReport{Kind: lsp.ReportFull, Items: items})
+	}
+	doc, ok := s.document(params.TextDocument.URI)
+	if !ok {
+		return lsp.NewDocumentDiagnosticResponse(id, lsp.DocumentDiagnosticReport{Kind: lsp.ReportFull, Items: []lsp.Diagnostic{}})
+	}
+	report, ok := s.diagnosticReport(ctx, doc, params.PreviousResultID)
+	if !ok {
+		return lsp.NewDocumentDiagnosticErrorResponse(id, lsp.ServerCancelled, "the diagnostics were cancelled")
+	}
+	return lsp.NewDocumentDiagnosticResponse(id, report)
+}
+
+// WorkspaceDiagnostic reports the diagnostics of all of the documents of
+// the workspace except the dependencies, one document at a time, skipping
+// the analysis of the ones whose previous result ID is still the current
+// one. It stops with the error of the context at the checkpoint after it's
+// cancelled.
+func (s *State) WorkspaceDiagnostic(ctx context.Context, previous []lsp.PreviousResultID, report func(lsp.WorkspaceDocumentDiagnosticReport)) error {
+	previousIDs := map[string]string{}
+	for _, p := range previous {
+		previousIDs[p.URI] = p.Value
+	}
+	for _, doc := range s.sortedDocuments() {
+		if err := Checkpoint(ctx); err != nil {
+			return err
+		}
+		if s.isDependency(doc.URI) {
+			continue
+		}
+		r, ok := s.diagnosticReport(ctx, s.use(doc), previousIDs[doc.URI])
+		if !ok {
+			return ctx.Err()
+		}
+		report(lsp.WorkspaceDocumentDiagnosticReport{DocumentDiagnosticReport: r, URI: doc.URI, Version: publishedVersion(doc)})
+	}
+	return nil
+}
+
+// diagnosticReport returns the full report of the document's diagnostics,
+// or the unchanged one if the previous result ID is the current one. It
+// reports false if the context was cancelled during the analysis.
+func (s *State) diagnosticReport(ctx context.Context, doc *Document, previousResultID string) (lsp.DocumentDiagnosticReport, bool) {
+	key := s.diagnosticsKey(doc)
+	resultID := s.resultID(key)
+	if previousResultID == resultID {
+		return lsp.DocumentDiagnosticReport{Kind: lsp.ReportUnchanged, ResultID: resultID}, true
+	}
+
+	analyzed, ok := s.analyzed[doc.URI]
+	if !ok || analyzed.key != key {
+		diagnostics := s.analyze(ctx, doc)
+		if ctx.Err() != nil {
+			return lsp.DocumentDiagnosticReport{}, false
+		}
+		analyzed = analyzedDiagnostics{version: doc.Version, key: key, diagnostics: diagnostics}
+		s.analyzed[doc.URI] = analyzed
+	}
+	return lsp.DocumentDiagnosticReport{Kind: lsp.ReportFull, ResultID: resultID, Items: s.published(doc, analyzed.diagnostics)}, true
+}
+
+// resultID identifies the diagnostics computed for the key, as published
+// with the severities of the current settings.
+func (s *State) resultID(key uint64) string {
+	severities, _ := json.Marshal(s.Settings.Severity)
+	h := fnv.New64a()
+	h.Write(severities)
+	return fmt.Sprintf("%016x-%016x", key, h.Sum64())
+}
+
+// diagnosticsKey returns the hash of everything the diagnostics of the
+// document are computed from: its source, the sources of the documents it
+// imports, directly or not, and which ones couldn't be resolved, the
+// configuration, the previewed migration and the renamed signatures. The
+// documents looked up by name across the workspace without an import, like
+// the implementations of the proxies and the duplicates of the contract
+// names, and the clones of the functions are not a part of it.
+func (s *State) diagnosticsKey(doc *Document) uint64 {
+	h := fnv.New64a()
+	config, _ := json.Marshal(s.Config)
+	h.Write(config)
+	fmt.Fprintf(h, "\n%s\n%d\n", s.Migrations[doc.URI], len(s.renamedSignatures))
+
+	visited := map[*Document]bool{}
+	var visit func(doc *Document)
+	visit = func(doc *Document) {
+		if visited[doc] {
+			return
+		}
+		visited[doc] = true
+		fmt.Fprintf(h, "%s %016x\n", doc.URI, doc.sourceHash())
+		for _, decl := range doc.File.Declarations {
+			imp, ok := decl.(*ast.ImportDirective)
+			if !ok {
+				continue
+			}
+			if target := s.ImportTarget(doc, imp); target != nil {
+				visit(target)
+			} else if imp.Path != nil {
+				fmt.Fprintf(h, "unresolved %s\n", imp.Path.Value)
+			}
+		}
+	}
+	visit(doc)
+	return h.Sum64()
+}
+
+// sourceHash returns the hash of the document's source, computed once.
+func (doc *Document) sourceHash() uint64 {
+	if doc.hash == 0 {
+		h := fnv.New64a()
+		io.WriteString(h, doc.Handle.Src())
+		doc.hash = h.Sum64()
+	}
+	return doc.hash
+}
@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"context"
+	"slices"
+	"solbot/lsp"
+	"testing"
+)
+
+const (
+	pullVaultSrc = `pragma solidity ^0.8.0;
+
+import {Token} from "./Token.sol";
+
+contract Vault {
+    Token token;
+    address owner;
+
+    function withdraw() external {
+        uint256 amount = 1;
+        require(msg.sender == owner);
+        token.mint(1);
+    }
+}
+`
+	pullTokenSrc = `pragma solidity ^0.8.0;
+
+contract Token {
+    function mint(uint256 amount) public {}
+}
+`
+)
+
+func newPullState() *State {
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, pullVaultSrc)
+	s.OpenDocument("file:///ws/src/Token.sol", 1, pullTokenSrc)
+	s.OpenDocument("file:///ws/lib/oz/Token.sol", 1, pullTokenSrc)
+	return s
+}
+
+func Test_DocumentDiagnostic(t *testing.T) {
+	s := newPullState()
+	const uri = "file:///ws/src/Vault.sol"
+	pull := func(previous string) lsp.DocumentDiagnosticReport {
+		params := lsp.DocumentDiagnosticParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}, PreviousResultID: previous}
+		res := s.DocumentDiagnostic(context.Background(), lsp.IntID(1), params)
+		if res.Result == nil {
+			t.Fatalf("Expected a report, got the error %+v", res.Error)
+		}
+		return *res.Result
+	}
+
+	first := pull("")
+	if first.Kind != lsp.ReportFull || first.ResultID == "" {
+		t.Fatalf("Expected a full report with a result ID, got %+v", first)
+	}
+	pushed := s.Diagnostics(context.Background(), uri).Params.Diagnostics
+	if len(first.Items) == 0 || len(first.Items) != len(pushed) {
+		t.Fatalf("Expected the pulled diagnostics to be the pushed ones, got %d and %d", len(first.Items), len(pushed))
+	}
+
+	analyses := s.Stats.Analyses
+	second := pull(first.ResultID)
+	if second.Kind != lsp.ReportUnchanged || second.ResultID != first.ResultID || second.Items != nil {
+		t.Errorf("Expected an unchanged report with the same result ID, got %+v", second)
+	}
+	if s.Stats.Analyses != analyses {
+		t.Errorf("Expected no analysis for the unchanged report, got %d", s.Stats.Analyses-analyses)
+	}
+
+	// The comment changes the source of the import.
+	s.UpdateDocument("file:///ws/src/Token.sol", 2, pullTokenSrc+"// edited\n")
+	third := pull(first.ResultID)
+	if third.Kind != lsp.ReportFull || third.ResultID == first.ResultID {
+		t.Errorf("Expected a full report with a new result ID after editing the import, got %+v", third)
+	}
+
+	// The documents the Vault doesn't import don't change its result.
+	s.UpdateDocument("file:///ws/lib/oz/Token.sol", 2, pullTokenSrc+"// edited\n")
+	if r := pull(third.ResultID); r.Kind != lsp.ReportUnchanged {
+		t.Errorf("Expected an unchanged report after editing an unrelated document, got %+v", r)
+	}
+
+	// The severities are applied to the cached diagnostics.
+	analyses = s.Stats.Analyses
+	s.Settings.Severity = map[string]string{first.Items[0].Code: "off"}
+	r := pull(third.ResultID)
+	if r.Kind != lsp.ReportFull || r.ResultID == third.ResultID || len(r.Items) != 0 {
+		t.Errorf("Expected a full report without the disabled diagnostic, got %+v", r)
+	}
+	if s.Stats.Analyses != analyses {
+		t.Errorf("Expected the severities not to analyze the document again, got %d analyses", s.Stats.Analyses-analyses)
+	}
+
+	params := lsp.DocumentDiagnosticParams{TextDocument: lsp.TextDocumentIdentifier{URI: "file:///ws/src/Closed.sol"}}
+	if r := s.DocumentDiagnostic(context.Background(), lsp.IntID(1), params).Result; r == nil || r.Kind != lsp.ReportFull || len(r.Items) != 0 {
+		t.Errorf("Expected an empty full report for an unknown document, got %+v", r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.UpdateDocument(uri, 2, pullVaultSrc+"// edited\n")
+	res := s.DocumentDiagnostic(ctx, lsp.IntID(1), lsp.DocumentDiagnosticParams{TextDocument: lsp.TextDocumentIdentifier{URI: uri}})
+	if res.Error == nil || res.Error.Code != lsp.ServerCancelled {
+		t.Errorf("Expected the ServerCancelled error, got %+v", res.Error)
+	}
+}
+
+func Test_WorkspaceDiagnostic(t *testing.T) {
+	s := newPullState()
+	collect := func(previous []lsp.PreviousResultID) []lsp.WorkspaceDocumentDiagnosticReport {
+		reports := []lsp.WorkspaceDocumentDiagnosticReport{}
+		err := s.WorkspaceDiagnostic(context.Background(), previous, func(r lsp.WorkspaceDocumentDiagnosticReport) {
+			reports = append(reports, r)
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		return reports
+	}
+
+	reports := collect(nil)
+	uris := []string{}
+	previous := []lsp.PreviousResultID{}
+	for _, r := range reports {
+		uris = append(uris, r.URI)
+		if r.Kind != lsp.ReportFull || r.Version == nil || *r.Version != 1 {
+			t.Errorf("Expected a full report of version 1 for %s, got %+v", r.URI, r)
+		}
+		previous = append(previous, lsp.PreviousResultID{URI: r.URI, Value: r.ResultID})
+	}
+	expected := []string{"file:///ws/src/Token.sol", "file:///ws/src/Vault.sol"}
+	if !slices.Equal(uris, expected) {
+		t.Fatalf("Expected the reports of %v, got %v", expected, uris)
+	}
+
+	analyses := s.Stats.Analyses
+	for _, r := range collect(previous) {
+		if r.Kind != lsp.ReportUnchanged {
+			t.Errorf("Expected an unchanged report for %s, got %+v", r.URI, r)
+		}
+	}
+	if s.Stats.Analyses != analyses {
+		t.Errorf("Expected no analysis for the unchanged reports, got %d", s.Stats.Analyses-analyses)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.WorkspaceDiagnostic(ctx, nil, func(lsp.WorkspaceDocumentDiagnosticReport) {}); err == nil {
+		t.Errorf("Expected the cancellation error, got nil")
+	}
+}
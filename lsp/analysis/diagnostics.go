@@ -0,0 +1,173 @@
+package analysis
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"solbot/lsp"
+)
+
+// Diagnostics returns the diagnostics of the document, the findings of the
+// detectors run by analyze; each of them documents what it reports, and
+// their codes are listed in knownCodes.
+//
+// The diagnostics are ordered by their ranges, so that the clients and the
+// tools diffing them see the same array for the same sources. A document
+// larger than the limit gets a single diagnostic explaining why it's not
+// analyzed. The severities set in the editor settings are applied last.
+// The open remappings.txt and foundry.toml get their own diagnostics, see
+// projectFileDiagnostics.
+//
+// The detectors stop at the checkpoint after the context is cancelled, see
+// Checkpoint; the incomplete diagnostics are not cached then, and the
+// caller is expected to drop them.
+func (s *State) Diagnostics(ctx context.Context, uri string) lsp.PublishDiagnosticsNotification {
+	if f, ok := s.projectFiles[uri]; ok {
+		return lsp.NewPublishDiagnosticsNotification(uri, &f.Version, s.overrideSeverities(s.projectFileDiagnostics(f)))
+	}
+	if md, ok := s.markdown[uri]; ok {
+		var version *int
+		if md.Open {
+			version = &md.Version
+		}
+		return lsp.NewPublishDiagnosticsNotification(uri, version, s.overrideSeverities(s.markdownDiagnostics(ctx, md)))
+	}
+	doc, ok := s.document(uri)
+	if !ok {
+		return lsp.NewPublishDiagnosticsNotification(uri, nil, []lsp.Diagnostic{})
+	}
+	diagnostics := s.analyze(ctx, doc)
+	if ctx.Err() == nil {
+		s.analyzed[uri] = analyzedDiagnostics{version: doc.Version, key: s.diagnosticsKey(doc), diagnostics: diagnostics}
+	}
+	return lsp.NewPublishDiagnosticsNotification(uri, publishedVersion(doc), s.published(doc, diagnostics))
+}
+
+func (s *State) analyze(ctx context.Context, doc *Document) []lsp.Diagnostic {
+	s.Stats.Analyses++
+	if doc.TooLarge {
+		return s.tooLargeDiagnostics(doc)
+	}
+
+	detectors := []func(*Document) []lsp.Diagnostic{
+		s.limitDiagnostics,
+		s.referenceDiagnostics,
+		s.importDiagnostics,
+		s.missingRemappingDiagnostics,
+		s.modifierDiagnostics,
+		s.implementationDiagnostics,
+		s.overrideDiagnostics,
+		s.cyclicInheritanceDiagnostics,
+		s.duplicateContractDiagnostics,
+		s.builtinDiagnostics,
+		s.destructuringDiagnostics,
+		s.addressDiagnostics,
+		s.tryDiagnostics,
+		s.selectorDiagnostics,
+		s.eventDiagnostics,
+		s.dataLocationDiagnostics,
+		s.memoryCopyDiagnostics,
+		s.metricDiagnostics,
+		s.cloneDiagnostics,
+		s.contractSizeDiagnostics,
+		s.proxyDiagnostics,
+		s.upgradeableDiagnostics,
+		s.erc20Diagnostics,
+		s.conditionDiagnostics,
+		s.mistakeDiagnostics,
+		s.deploymentDiagnostics,
+		s.signatureDiagnostics,
+		s.unusedDiagnostics,
+		s.deadStoreDiagnostics,
+		s.purityDiagnostics,
+		s.natSpecDiagnostics,
+		s.transientDiagnostics,
+		s.abicoderDiagnostics,
+		s.legacyDiagnostics,
+		s.migrationDiagnostics,
+		s.pragmaRangeDiagnostics,
+		s.whitespaceDiagnostics,
+		s.detectorDiagnostics,
+	}
+	diagnostics := []lsp.Diagnostic{}
+	for _, detect := range detectors {
+		if err := Checkpoint(ctx); err != nil {
+			return diagnostics
+		}
+		diagnostics = append(diagnostics, detect(doc)...)
+	}
+	sortDiagnostics(diagnostics)
+	s.Logger.DebugContext(ctx, "computed the diagnostics", "diagnostics", len(diagnostics))
+	return diagnostics
+}
+
+// analyzedDiagnostics are the diagnostics computed for a version of a
+// document, before the severities of the settings are applied.
+type analyzedDiagnostics struct {
+	version     int
+	key         uint64 // the document, its imports and the configuration they were computed for, see diagnosticsKey
+	diagnostics []lsp.Diagnostic
+}
+
+// Republish returns the diagnostics of the open documents again, after the
+// settings changed. The ones computed for the current versions are reused
+// with the new severities, unless reanalyze is set e.g. a detector was
+// turned on, so the documents are neither parsed nor analyzed again. The
+// documents republished before the context was cancelled are returned.
+func (s *State) Republish(ctx context.Context, reanalyze bool) []lsp.PublishDiagnosticsNotification {
+	res := []lsp.PublishDiagnosticsNotification{}
+	for _, uri := range sortedKeys(s.Documents) {
+		if err := Checkpoint(ctx); err != nil {
+			return res
+		}
+		doc := s.Documents[uri]
+		if !doc.Open {
+			continue
+		}
+		if analyzed, ok := s.analyzed[uri]; ok && !reanalyze && analyzed.version == doc.Version {
+			res = append(res, lsp.NewPublishDiagnosticsNotification(uri, publishedVersion(doc), s.published(doc, analyzed.diagnostics)))
+			continue
+		}
+		notification := s.Diagnostics(ctx, uri)
+		if ctx.Err() != nil {
+			return res
+		}
+		res = append(res, notification)
+	}
+	return res
+}
+
+// publishedVersion returns the version of the document the diagnostics
+// are published for; or nil if the document is not open.
+func publishedVersion(doc *Document) *int {
+	if !doc.Open {
+		return nil
+	}
+	v := doc.Version
+	return &v
+}
+
+// sortDiagnostics orders the diagnostics by the start and the end of their
+// ranges, then by their codes and messages. The diagnostics of the same
+// range, code and message keep the order they were reported in.
+func sortDiagnostics(diagnostics []lsp.Diagnostic) {
+	slices.SortStableFunc(diagnostics, func(a, b lsp.Diagnostic) int {
+		if c := comparePositions(a.Range.Start, b.Range.Start); c != 0 {
+			return c
+		}
+		if c := comparePositions(a.Range.End, b.Range.End); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Code, b.Code); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Message, b.Message)
+	})
+}
+
+func comparePositions(a, b lsp.Position) int {
+	if c := cmp.Compare(a.Line, b.Line); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Character, b.Character)
+}
@@ -0,0 +1,272 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// DocumentSymbol returns the outline of the document: the declarations at
+// the top level of the file, with the members of the contracts, structs
+// and enums nested under them. The overloaded functions are listed one by
+// one, told apart by their parameters in the detail, and the public state
+// variables show the signature of their getter. The constructors the
+// contracts don't declare are not listed.
+func (s *State) DocumentSymbol(id lsp.ID, uri string) lsp.DocumentSymbolResponse {
+	symbols := []lsp.DocumentSymbol{}
+	doc, ok := s.document(uri)
+	if !ok {
+		return lsp.NewDocumentSymbolResponse(id, symbols)
+	}
+	for _, decl := range doc.File.Declarations {
+		if symbol, ok := s.documentSymbol(doc, decl, nil, false); ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return lsp.NewDocumentSymbolResponse(id, symbols)
+}
+
+// WorkspaceSymbol finds the declarations of the workspace whose names
+// contain the query, ignoring the case: the ones at the top level of the
+// files and the members of the contracts, in the order of the files. The
+// container of a contract and of its members is the contract qualified with
+// the path of its file e.g. "src/Vault.sol:Vault", which tells apart the
+// contracts of the same name; the other declarations at the top level are
+// contained by their files.
+func (s *State) WorkspaceSymbol(id lsp.ID, query string) lsp.WorkspaceSymbolResponse {
+	symbols := []lsp.SymbolInformation{}
+	query = strings.ToLower(query)
+	add := func(doc *Document, symbol lsp.DocumentSymbol, container string) {
+		if !strings.Contains(strings.ToLower(symbol.Name), query) {
+			return
+		}
+		symbols = append(symbols, lsp.SymbolInformation{
+			Name:          symbol.Name,
+			Kind:          symbol.Kind,
+			Tags:          symbol.Tags,
+			Location:      lsp.Location{URI: doc.URI, Range: symbol.SelectionRange},
+			ContainerName: container,
+		})
+	}
+	for _, doc := range s.sortedDocuments() {
+		for _, decl := range doc.File.Declarations {
+			symbol, ok := s.documentSymbol(doc, decl, nil, false)
+			if !ok {
+				continue
+			}
+			c, ok := decl.(*ast.ContractDeclaration)
+			if !ok {
+				add(doc, symbol, s.RelativePath(doc.URI))
+				continue
+			}
+			container := s.QualifiedName(&Symbol{Doc: doc, Name: c.Name, Node: c})
+			add(doc, symbol, container)
+			for _, member := range symbol.Children {
+				add(doc, member, container)
+			}
+		}
+	}
+	return lsp.NewWorkspaceSymbolResponse(id, symbols)
+}
+
+// documentSymbol returns the entry of the declaration in the outline; or
+// false if it's not shown e.g. a pragma or an import. The contract is the
+// one declaring the member; or nil at the top level. The members of a
+// deprecated declaration are deprecated as well.
+func (s *State) documentSymbol(doc *Document, decl ast.Node, contract *ast.ContractDeclaration, deprecated bool) (lsp.DocumentSymbol, bool) {
+	deprecated = deprecated || isDeprecated(doc, decl)
+	symbol := lsp.DocumentSymbol{Range: toLspRange(doc.Handle, ast.NodeRange(decl))}
+	named := func(name *ast.Identifier) bool {
+		if name == nil {
+			return false
+		}
+		symbol.Name = name.Name
+		symbol.SelectionRange = toLspRange(doc.Handle, ast.NodeRange(name))
+		return true
+	}
+
+	switch d := decl.(type) {
+	case *ast.ContractDeclaration:
+		if !named(d.Name) {
+			return symbol, false
+		}
+		switch {
+		case d.Kind == token.INTERFACE:
+			symbol.Kind = lsp.SymbolKindInterface
+		case d.Kind == token.LIBRARY:
+			symbol.Kind = lsp.SymbolKindModule
+		default:
+			symbol.Kind = lsp.SymbolKindClass
+		}
+		symbol.Detail = contractDetail(d)
+		for _, member := range d.Body {
+			if child, ok := s.documentSymbol(doc, member, d, deprecated); ok {
+				symbol.Children = append(symbol.Children, child)
+			}
+		}
+	case *ast.FunctionDeclaration:
+		if d.Kind == token.FUNCTION {
+			if !named(d.Name) {
+				return symbol, false
+			}
+		} else {
+			// The constructor, fallback and receive functions are named
+			// by their keyword.
+			symbol.Name = d.Kind.String()
+			keyword := token.Range{Start: d.Type.Func, End: d.Type.Func + token.Pos(len(symbol.Name))}
+			symbol.SelectionRange = toLspRange(doc.Handle, keyword)
+		}
+		switch {
+		case d.Kind == token.CONSTRUCTOR:
+			symbol.Kind = lsp.SymbolKindConstructor
+		case contract == nil:
+			symbol.Kind = lsp.SymbolKindFunction
+		default:
+			symbol.Kind = lsp.SymbolKindMethod
+		}
+		symbol.Detail = paramsDetail(d.Type.Params)
+		if d.Type.Results != nil {
+			symbol.Detail += " returns " + paramsDetail(d.Type.Results)
+		}
+	case *ast.ModifierDeclaration:
+		if !named(d.Name) {
+			return symbol, false
+		}
+		symbol.Kind = lsp.SymbolKindMethod
+		symbol.Detail = strings.TrimSpace("modifier " + paramsDetail(d.Params))
+	case *ast.EventDeclaration:
+		if !named(d.Name) {
+			return symbol, false
+		}
+		symbol.Kind = lsp.SymbolKindEvent
+		symbol.Detail = "event " + paramsDetail(d.Params)
+	case *ast.ErrorDeclaration:
+		if !named(d.Name) {
+			return symbol, false
+		}
+		symbol.Kind = lsp.SymbolKindEvent
+		symbol.Detail = "error " + paramsDetail(d.Params)
+	case *ast.StructDeclaration:
+		if !named(d.Name) {
+			return symbol, false
+		}
+		symbol.Kind = lsp.SymbolKindStruct
+		for _, member := range d.Members {
+			if child, ok := s.documentSymbol(doc, member, nil, deprecated); ok {
+				child.Kind = lsp.SymbolKindField
+				symbol.Children = append(symbol.Children, child)
+			}
+		}
+	case *ast.EnumDeclaration:
+		if !named(d.Name) {
+			return symbol, false
+		}
+		symbol.Kind = lsp.SymbolKindEnum
+		for _, member := range d.Members {
+			child := lsp.DocumentSymbol{
+				Name:           member.Name,
+				Kind:           lsp.SymbolKindEnumMember,
+				Range:          toLspRange(doc.Handle, ast.NodeRange(member)),
+				SelectionRange: toLspRange(doc.Handle, ast.NodeRange(member)),
+			}
+			if deprecated {
+				child.Tags = []lsp.SymbolTag{lsp.SymbolTagDeprecated}
+			}
+			symbol.Children = append(symbol.Children, child)
+		}
+	case *ast.TypeDeclaration:
+		if !named(d.Name) {
+			return symbol, false
+		}
+		symbol.Kind = lsp.SymbolKindTypeParameter
+		symbol.Detail = ast.ExprString(d.Underlying)
+	case *ast.VariableDeclaration:
+		if !named(d.Name) {
+			return symbol, false
+		}
+		symbol.Detail = ast.ExprString(d.Type)
+		switch {
+		case d.Constant:
+			symbol.Kind = lsp.SymbolKindConstant
+		case contract == nil:
+			symbol.Kind = lsp.SymbolKindVariable
+		case d.Visibility == ast.Public:
+			symbol.Kind = lsp.SymbolKindProperty
+		default:
+			symbol.Kind = lsp.SymbolKindField
+		}
+		if g := s.getterOf(&Symbol{Doc: doc, Name: d.Name, Node: d}); g != nil {
+			symbol.Detail = getterDetail(g)
+		}
+	default:
+		return symbol, false
+	}
+	if deprecated {
+		symbol.Tags = []lsp.SymbolTag{lsp.SymbolTagDeprecated}
+	}
+	return symbol, true
+}
+
+// contractDetail returns the detail of the contract in the outline e.g.
+// "abstract contract is ERC20, Ownable".
+func contractDetail(c *ast.ContractDeclaration) string {
+	detail := c.Kind.String()
+	if c.Abstract {
+		detail = "abstract " + detail
+	}
+	if len(c.Bases) > 0 {
+		bases := make([]string, 0, len(c.Bases))
+		for _, base := range c.Bases {
+			bases = append(bases, ast.ExprString(base.Name))
+		}
+		detail += " is " + strings.Join(bases, ", ")
+	}
+	return detail
+}
+
+// paramsDetail returns the parameters without the comments and the line
+// breaks e.g. "(address to, bytes memory data)"; or an empty string if
+// there are no parentheses.
+func paramsDetail(params *ast.ParamList) string {
+	if params == nil {
+		return ""
+	}
+	list := make([]string, 0, len(params.List))
+	for _, param := range params.List {
+		words := []string{ast.ExprString(param.Type)}
+		if param.Indexed {
+			words = append(words, "indexed")
+		}
+		if location := locationName(param.Location); location != "" {
+			words = append(words, location)
+		}
+		if param.Name != nil {
+			words = append(words, param.Name.Name)
+		}
+		list = append(list, strings.Join(words, " "))
+	}
+	return "(" + strings.Join(list, ", ") + ")"
+}
+
+// getterDetail returns the signature of the getter in the outline e.g.
+// "(address) returns (uint256)" for `mapping(address => uint256) public
+// balances`.
+func getterDetail(g *getter) string {
+	params := make([]string, 0, len(g.Params))
+	for _, param := range g.Params {
+		params = append(params, ast.ExprString(param))
+	}
+	return "(" + strings.Join(params, ", ") + ") returns (" + ast.ExprString(g.Result) + ")"
+}
+
+// isDeprecated reports whether the NatSpec of the declaration has the
+// `@custom:deprecated` or the `@deprecated` tag.
+func isDeprecated(doc *Document, decl ast.Node) bool {
+	for _, line := range strings.Split(natSpec(doc, decl), "\n") {
+		if strings.HasPrefix(line, "@custom:deprecated") || strings.HasPrefix(line, "@deprecated") {
+			return true
+		}
+	}
+	return false
+}
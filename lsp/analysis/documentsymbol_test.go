@@ -0,0 +1,138 @@
+package analysis
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+var symbolKindNames = map[lsp.SymbolKind]string{
+	lsp.SymbolKindModule:        "Module",
+	lsp.SymbolKindClass:         "Class",
+	lsp.SymbolKindMethod:        "Method",
+	lsp.SymbolKindProperty:      "Property",
+	lsp.SymbolKindField:         "Field",
+	lsp.SymbolKindConstructor:   "Constructor",
+	lsp.SymbolKindEnum:          "Enum",
+	lsp.SymbolKindInterface:     "Interface",
+	lsp.SymbolKindFunction:      "Function",
+	lsp.SymbolKindVariable:      "Variable",
+	lsp.SymbolKindConstant:      "Constant",
+	lsp.SymbolKindEnumMember:    "EnumMember",
+	lsp.SymbolKindStruct:        "Struct",
+	lsp.SymbolKindEvent:         "Event",
+	lsp.SymbolKindTypeParameter: "TypeParameter",
+}
+
+// renderSymbols writes the tree of the symbols, a line per symbol with its
+// kind, detail, tags and ranges.
+func renderSymbols(b *strings.Builder, symbols []lsp.DocumentSymbol, indent string) {
+	position := func(r lsp.Range) string {
+		return fmt.Sprintf("%d:%d-%d:%d", r.Start.Line, r.Start.Character, r.End.Line, r.End.Character)
+	}
+	for _, symbol := range symbols {
+		fmt.Fprintf(b, "%s%s %s", indent, symbolKindNames[symbol.Kind], symbol.Name)
+		if symbol.Detail != "" {
+			fmt.Fprintf(b, " %q", symbol.Detail)
+		}
+		for _, tag := range symbol.Tags {
+			if tag == lsp.SymbolTagDeprecated {
+				b.WriteString(" deprecated")
+			}
+		}
+		fmt.Fprintf(b, " %s %s\n", position(symbol.Range), position(symbol.SelectionRange))
+		renderSymbols(b, symbol.Children, indent+"  ")
+	}
+}
+
+func Test_DocumentSymbolGolden(t *testing.T) {
+	src, err := os.ReadFile("testdata/symbols/Kitchen.sol")
+	if err != nil {
+		t.Fatalf("Cannot read the fixture: %s", err)
+	}
+	s := NewState()
+	uri := "file:///ws/src/Kitchen.sol"
+	s.OpenDocument(uri, 1, string(src))
+
+	var b strings.Builder
+	renderSymbols(&b, s.DocumentSymbol(lsp.IntID(1), uri).Result, "")
+	golden := filepath.Join("testdata", "symbols", "Kitchen.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(b.String()), 0644); err != nil {
+			t.Fatalf("Cannot update %s: %s", golden, err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Cannot read %s: %s", golden, err)
+	}
+	if b.String() != string(expected) {
+		t.Errorf("Expected %s:\n%s\ngot:\n%s", golden, expected, b.String())
+	}
+}
+
+func Test_DocumentSymbolOverloads(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Router.sol"
+	s.OpenDocument(uri, 1, `contract Router {
+    function swap(uint256 amount) external {}
+    function swap(uint256 amount, address to) external returns (uint256) {}
+    function swap(bytes calldata path) external {}
+}
+`)
+	symbols := s.DocumentSymbol(lsp.IntID(1), uri).Result
+	if len(symbols) != 1 || len(symbols[0].Children) != 3 {
+		t.Fatalf("Expected the contract with 3 functions, got %v", symbols)
+	}
+	for i, expected := range []string{"(uint256 amount)", "(uint256 amount, address to) returns (uint256)", "(bytes calldata path)"} {
+		child := symbols[0].Children[i]
+		if child.Name != "swap" || child.Detail != expected {
+			t.Errorf("Expected swap %s, got %s %s", expected, child.Name, child.Detail)
+		}
+		if child.SelectionRange.Start.Line != uint(i+1) {
+			t.Errorf("Expected swap %s at line %d, got %d", expected, i+1, child.SelectionRange.Start.Line)
+		}
+	}
+}
+
+func Test_DocumentSymbolDeprecated(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Legacy.sol"
+	s.OpenDocument(uri, 1, `/// @custom:deprecated Use Vault.
+contract Legacy {
+    struct Entry {
+        uint256 amount;
+    }
+
+    function withdraw() external {}
+}
+
+contract Vault {
+    /// @deprecated
+    function withdraw() external {}
+
+    /// @notice Not deprecated, see @deprecated.
+    function deposit() external {}
+}
+`)
+	deprecated := func(symbol lsp.DocumentSymbol) bool {
+		return len(symbol.Tags) == 1 && symbol.Tags[0] == lsp.SymbolTagDeprecated
+	}
+	symbols := s.DocumentSymbol(lsp.IntID(1), uri).Result
+	if len(symbols) != 2 {
+		t.Fatalf("Expected 2 contracts, got %v", symbols)
+	}
+	legacy, vault := symbols[0], symbols[1]
+	if !deprecated(legacy) || !deprecated(legacy.Children[0]) || !deprecated(legacy.Children[0].Children[0]) || !deprecated(legacy.Children[1]) {
+		t.Errorf("Expected Legacy and all of its members to be deprecated, got %v", legacy)
+	}
+	if deprecated(vault) || !deprecated(vault.Children[0]) || deprecated(vault.Children[1]) {
+		t.Errorf("Expected only Vault.withdraw to be deprecated, got %v", vault)
+	}
+}
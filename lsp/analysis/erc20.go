@@ -0,0 +1,204 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// safeERC20Functions map the ERC20 functions returning a bool to their
+// SafeERC20 replacements.
+var safeERC20Functions = map[string]string{
+	"transfer":     "safeTransfer",
+	"transferFrom": "safeTransferFrom",
+	"approve":      "forceApprove",
+}
+
+// erc20Diagnostics reports the calls of the ERC20 tokens which break on the
+// non-standard tokens. The calls are checked if the type of the token is
+// ERC20-like, it declares `transfer`, `transferFrom` and `approve`
+// returning a bool, e.g. IERC20. The checks can be disabled one by one with
+// their codes in the [detectors] section:
+//
+//   - unchecked-erc20-call: the bool returned by `transfer`, `transferFrom`
+//     or `approve` is discarded, so the tokens returning false instead of
+//     reverting fail silently, while the tokens returning nothing, like
+//     USDT, revert when the result is decoded. The SafeERC20 functions,
+//     called through using-for or on the library, handle both;
+//   - erc20-approve-race: `approve` changes a non-zero allowance without
+//     resetting it to zero first, so the spender can front-run the change
+//     and spend both allowances. Approving zero, and approving the spenders
+//     trusted in the [erc20] section, is left alone.
+//
+// The bodies of the SafeERC20-like libraries, whose names start with
+// "Safe", are skipped, since they wrap the raw calls on purpose.
+func (s *State) erc20Diagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(call *ast.CallExpression, severity lsp.DiagnosticSeverity, code, message string) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(call.Function.(*ast.MemberAccessExpression).Member)),
+			Severity: severity,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+
+	bodies := []*ast.BlockStatement{}
+	for _, decl := range doc.File.Declarations {
+		switch decl := decl.(type) {
+		case *ast.FunctionDeclaration:
+			bodies = append(bodies, decl.Body)
+		case *ast.ContractDeclaration:
+			if decl.Kind == token.LIBRARY && strings.HasPrefix(decl.Name.Name, "Safe") {
+				continue
+			}
+			for _, member := range decl.Body {
+				switch member := member.(type) {
+				case *ast.FunctionDeclaration:
+					bodies = append(bodies, member.Body)
+				case *ast.ModifierDeclaration:
+					bodies = append(bodies, member.Body)
+				}
+			}
+		}
+	}
+
+	for _, body := range bodies {
+		if body == nil {
+			continue
+		}
+		// The zero approvals seen so far, by the token and the spender.
+		reset := map[string]bool{}
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ExpressionStatement:
+				call, name := s.erc20Call(doc, n.Expression)
+				if call != nil {
+					report(call, lsp.SeverityWarning, "unchecked-erc20-call",
+						fmt.Sprintf("The bool returned by `%s` is discarded, so the tokens returning false instead of reverting fail silently; "+
+							"use `%s` of SafeERC20 or require() the result", ast.ExprString(call.Function), safeERC20Functions[name]))
+				}
+			case *ast.CallExpression:
+				call, name := s.erc20Call(doc, n)
+				if call == nil || name != "approve" || len(call.Args) != 2 {
+					return true
+				}
+				approval := ast.ExprString(call.Function.(*ast.MemberAccessExpression).Expression) + "," + ast.ExprString(call.Args[0])
+				if isZero(call.Args[1]) {
+					reset[approval] = true
+					return true
+				}
+				if reset[approval] || s.trustedSpender(doc, call.Args[0]) {
+					return true
+				}
+				report(call, lsp.SeverityInformation, "erc20-approve-race",
+					fmt.Sprintf("`%s` changes the allowance without resetting it to zero first, so the spender can front-run it and spend both allowances; "+
+						"approve zero first or use `forceApprove` or `increaseAllowance`", ast.ExprString(call.Function)))
+			}
+			return true
+		})
+	}
+	return res
+}
+
+// erc20Call returns the call of the expression if it calls `transfer`,
+// `transferFrom` or `approve` of an ERC20-like token, and the name of the
+// called function; or nil if it doesn't.
+func (s *State) erc20Call(doc *Document, x ast.Expression) (*ast.CallExpression, string) {
+	call, ok := x.(*ast.CallExpression)
+	if !ok {
+		return nil, ""
+	}
+	member, ok := call.Function.(*ast.MemberAccessExpression)
+	if !ok {
+		return nil, ""
+	}
+	if _, ok := safeERC20Functions[member.Member.Name]; !ok {
+		return nil, ""
+	}
+	path := ast.PathEnclosingPos(doc.File, member.Expression.Start())
+	if !s.isERC20Like(s.scopeOf(doc, path, member.Expression)) {
+		return nil, ""
+	}
+	return call, member.Member.Name
+}
+
+// isERC20Like reports whether the contract declares or inherits
+// `transfer`, `transferFrom` and `approve` returning a single bool.
+func (s *State) isERC20Like(scope *Symbol) bool {
+	if scope == nil {
+		return false
+	}
+	if _, ok := scope.Node.(*ast.ContractDeclaration); !ok {
+		return false
+	}
+	for name := range safeERC20Functions {
+		fn := s.follow(s.member(scope, name))
+		if fn == nil {
+			return false
+		}
+		decl, ok := fn.Node.(*ast.FunctionDeclaration)
+		if !ok || decl.Type.Results == nil || len(decl.Type.Results.List) != 1 {
+			return false
+		}
+		if t, ok := decl.Type.Results.List[0].Type.(*ast.ElementaryType); !ok || t.Value != "bool" {
+			return false
+		}
+	}
+	return true
+}
+
+// trustedSpender reports whether the spender is a constant listed in the
+// [erc20] section of solbot.toml, by its name or its address, the address
+// itself e.g. `address(0x7a25...)`, or a state variable known to hold it,
+// see knownValues.
+func (s *State) trustedSpender(doc *Document, x ast.Expression) bool {
+	trusted := func(value string) bool {
+		return slices.ContainsFunc(s.Config.TrustedSpenders, func(spender string) bool {
+			return strings.EqualFold(spender, value)
+		})
+	}
+	if trusted(addressLiteral(x)) {
+		return true
+	}
+	path := ast.PathEnclosingPos(doc.File, x.Start())
+	sym := s.follow(s.resolveExpr(doc, path, x))
+	if sym == nil {
+		return false
+	}
+	v, ok := sym.Node.(*ast.VariableDeclaration)
+	if !ok {
+		return false
+	}
+	if !v.Constant {
+		value := s.knownValue(sym, path, map[*ast.ContractDeclaration]map[*ast.VariableDeclaration]ast.Expression{})
+		return value != nil && trusted(addressLiteral(value))
+	}
+	return trusted(v.Name.Name) || v.Value != nil && trusted(addressLiteral(v.Value))
+}
+
+// addressLiteral returns the source of the address, with the conversions
+// to `address` and the contracts stripped e.g. "0x7a25..." for
+// `IRouter(address(0x7a25...))`.
+func addressLiteral(x ast.Expression) string {
+	for {
+		call, ok := x.(*ast.CallExpression)
+		if !ok || len(call.Args) != 1 {
+			return ast.ExprString(x)
+		}
+		x = call.Args[0]
+	}
+}
+
+// isZero reports whether the expression is the zero literal.
+func isZero(x ast.Expression) bool {
+	lit, ok := x.(*ast.BasicLit)
+	return ok && lit.Value == "0"
+}
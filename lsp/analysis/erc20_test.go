@@ -0,0 +1,107 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"testing"
+)
+
+const erc20Src = `pragma solidity ^0.8.20;
+
+interface IERC20 {
+    function transfer(address to, uint256 amount) external returns (bool);
+    function transferFrom(address from, address to, uint256 amount) external returns (bool);
+    function approve(address spender, uint256 amount) external returns (bool);
+}
+
+library SafeERC20 {
+    function safeTransfer(IERC20 token, address to, uint256 amount) internal {
+        token.transfer(to, amount);
+    }
+
+    function forceApprove(IERC20 token, address spender, uint256 amount) internal {
+        token.approve(spender, amount);
+    }
+}
+`
+
+func Test_ERC20Diagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		code     string
+		severity lsp.DiagnosticSeverity
+		message  string
+	}{
+		{
+			"discarded transfer",
+			"token.transfer(to, amount);",
+			"unchecked-erc20-call", lsp.SeverityWarning,
+			"The bool returned by `token.transfer` is discarded, so the tokens returning false instead of reverting fail silently; " +
+				"use `safeTransfer` of SafeERC20 or require() the result",
+		},
+		{"SafeERC20", "token.safeTransfer(to, amount);\n        SafeERC20.forceApprove(token, to, amount);", "", 0, ""},
+		{"require", "require(token.transferFrom(msg.sender, to, amount));", "", 0, ""},
+		{"checked result", "bool ok = token.transfer(to, amount);\n        require(ok);", "", 0, ""},
+		{
+			"approve race",
+			"require(token.approve(to, amount));",
+			"erc20-approve-race", lsp.SeverityInformation,
+			"`token.approve` changes the allowance without resetting it to zero first, so the spender can front-run it and spend both allowances; " +
+				"approve zero first or use `forceApprove` or `increaseAllowance`",
+		},
+		{"approve zero first", "require(token.approve(to, 0));\n        require(token.approve(to, amount));", "", 0, ""},
+		{"trusted spender", "require(token.approve(ROUTER, amount));", "", 0, ""},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		s.Config.TrustedSpenders = []string{"ROUTER"}
+		s.OpenDocument("file:///ws/src/IERC20.sol", 1, erc20Src)
+		uri := "file:///ws/src/Vault.sol"
+		s.OpenDocument(uri, 1, `pragma solidity ^0.8.20;
+
+import "./IERC20.sol";
+
+contract Vault {
+    using SafeERC20 for IERC20;
+
+    address constant ROUTER = address(0x1);
+    IERC20 token;
+
+    function pay(address to, uint256 amount) external {
+        `+tt.body+`
+    }
+}
+`)
+
+		diagnostics := s.erc20Diagnostics(s.Documents[uri])
+		if tt.code == "" {
+			if len(diagnostics) != 0 {
+				t.Errorf("Expected no diagnostics for %s, got %v", tt.name, diagnostics)
+			}
+			continue
+		}
+		if len(diagnostics) != 1 {
+			t.Errorf("Expected 1 diagnostic for %s, got %v", tt.name, diagnostics)
+			continue
+		}
+		d := diagnostics[0]
+		if d.Code != tt.code || d.Range.Start.Line != 11 || d.Severity != tt.severity {
+			t.Errorf("Expected %s at line 11, got %s at line %d", tt.code, d.Code, d.Range.Start.Line)
+		}
+		if d.Message != tt.message {
+			t.Errorf("Expected %q, got %q", tt.message, d.Message)
+		}
+
+		s.Config.Disabled = []string{tt.code}
+		if diagnostics := s.erc20Diagnostics(s.Documents[uri]); len(diagnostics) != 0 {
+			t.Errorf("Expected %s to be disabled, got %v", tt.code, diagnostics)
+		}
+	}
+
+	// The SafeERC20 library wraps the raw calls on purpose.
+	s := NewState()
+	s.OpenDocument("file:///ws/src/IERC20.sol", 1, erc20Src)
+	if diagnostics := s.erc20Diagnostics(s.Documents["file:///ws/src/IERC20.sol"]); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics in SafeERC20, got %v", diagnostics)
+	}
+}
@@ -0,0 +1,963 @@
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// Evaluation is the trace of a function executed with the given values of
+// its parameters, see Evaluate.
+type Evaluation struct {
+	Steps   []EvaluationStep // executed statements in order
+	Returns []string         // returned values if the function returned
+	Stop    *EvaluationStop  // where the evaluation stopped; or nil if the function returned
+}
+
+// EvaluationStep is an executed statement, or the condition of a branch or
+// of a loop, with the values of the variables after it.
+type EvaluationStep struct {
+	Range     token.Range
+	Note      string // e.g. "true" for a condition or "returns 3000"; or empty
+	Variables []EvaluatedVariable
+}
+
+type EvaluatedVariable struct {
+	Name  string // e.g. "fee" or "balances[to]"
+	Value string // e.g. "3000" or "true"
+}
+
+// EvaluationStop is the statement or the expression the evaluation didn't
+// go past.
+type EvaluationStop struct {
+	Range    token.Range
+	Reason   string
+	Reverted bool // does the function revert there, as opposed to doing something not evaluated?
+}
+
+// Limits of the evaluation, so that a long loop doesn't run forever.
+const (
+	maxEvaluationSteps = 10000
+	maxIterations      = 1000
+)
+
+// Evaluate executes the function of the document with the values of the
+// arguments, as far as it's made of the arithmetic on the integers and the
+// booleans: the constants are folded, the branches whose conditions are
+// known are followed, and the loops run as long as their conditions are
+// known. The evaluation stops at the first operation it can't evaluate
+// e.g. a call, or a read of a state variable without a value, and at the
+// checks failing e.g. `require` or an overflow.
+//
+// The function is named e.g. "computeFee" or "Vault.computeFee". The
+// arguments are the values of the parameters in the decimal or the hex
+// notation e.g. "1000000", "1e18", "1 ether" or "0xff", or "true" and
+// "false". The values of the state variables and the globals can be given
+// too e.g. "totalSupply", "msg.value" or "balances[to]"; the elements of
+// the mappings and the arrays are told apart by their source. The
+// modifiers of the function are not evaluated.
+func (s *State) Evaluate(uri, function string, args map[string]string) (*Evaluation, error) {
+	doc, ok := s.document(uri)
+	if !ok {
+		return nil, fmt.Errorf("unknown document %s", uri)
+	}
+	fn, err := functionNamed(doc, function)
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(doc, fn, args)
+}
+
+// EvaluatePath evaluates the function named in the request, or the one at
+// the position if there is no name, see Evaluate.
+func (s *State) EvaluatePath(id lsp.ID, params lsp.EvaluatePathParams) lsp.EvaluatePathResponse {
+	doc, ok := s.document(params.TextDocument.URI)
+	if !ok {
+		return lsp.NewEvaluatePathErrorResponse(id, lsp.InvalidParams, "unknown document "+params.TextDocument.URI)
+	}
+	var fn *ast.FunctionDeclaration
+	if params.Function != "" {
+		var err error
+		if fn, err = functionNamed(doc, params.Function); err != nil {
+			return lsp.NewEvaluatePathErrorResponse(id, lsp.InvalidParams, err.Error())
+		}
+	} else {
+		for _, node := range ast.PathEnclosingPos(doc.File, toTokenPos(doc.Handle, params.Position)) {
+			if f, ok := node.(*ast.FunctionDeclaration); ok {
+				fn = f
+				break
+			}
+		}
+		if fn == nil {
+			return lsp.NewEvaluatePathErrorResponse(id, lsp.InvalidParams, "no function at the position")
+		}
+	}
+	evaluation, err := s.evaluate(doc, fn, params.Arguments)
+	if err != nil {
+		return lsp.NewEvaluatePathErrorResponse(id, lsp.InvalidParams, err.Error())
+	}
+
+	result := &lsp.EvaluatePathResult{Steps: []lsp.EvaluationStep{}, Returns: evaluation.Returns}
+	for _, step := range evaluation.Steps {
+		variables := []lsp.EvaluatedVariable{}
+		for _, v := range step.Variables {
+			variables = append(variables, lsp.EvaluatedVariable{Name: v.Name, Value: v.Value})
+		}
+		result.Steps = append(result.Steps, lsp.EvaluationStep{
+			Range:     toLspRange(doc.Handle, step.Range),
+			Note:      step.Note,
+			Variables: variables,
+		})
+	}
+	if stop := evaluation.Stop; stop != nil {
+		result.Stop = &lsp.EvaluationStop{Range: toLspRange(doc.Handle, stop.Range), Reason: stop.Reason, Reverted: stop.Reverted}
+	}
+	return lsp.NewEvaluatePathResponse(id, result)
+}
+
+// functionNamed returns the function of the document with the name e.g.
+// "computeFee", or the name prefixed with the contract e.g.
+// "Vault.computeFee".
+func functionNamed(doc *Document, name string) (*ast.FunctionDeclaration, error) {
+	contract, function, qualified := strings.Cut(name, ".")
+	if !qualified {
+		contract, function = "", name
+	}
+	matches := []string{}
+	var res *ast.FunctionDeclaration
+	add := func(fn *ast.FunctionDeclaration, c *ast.ContractDeclaration) {
+		if fn.Name == nil || fn.Name.Name != function || fn.Body == nil {
+			return
+		}
+		if c != nil && contract != "" && c.Name.Name != contract || c == nil && contract != "" {
+			return
+		}
+		res = fn
+		if c != nil {
+			matches = append(matches, c.Name.Name+"."+function)
+		} else {
+			matches = append(matches, function)
+		}
+	}
+	for _, decl := range doc.File.Declarations {
+		switch d := decl.(type) {
+		case *ast.FunctionDeclaration:
+			add(d, nil)
+		case *ast.ContractDeclaration:
+			for _, member := range d.Body {
+				if fn, ok := member.(*ast.FunctionDeclaration); ok {
+					add(fn, d)
+				}
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no function `%s` with a body in the file", name)
+	case 1:
+		return res, nil
+	}
+	return nil, fmt.Errorf("`%s` is ambiguous: %s", name, strings.Join(matches, ", "))
+}
+
+// value is an evaluated integer or boolean.
+type value struct {
+	int  *big.Int // or nil for the booleans
+	bool bool
+	typ  string // type e.g. "uint256", "bool" or "address"; or empty if not known e.g. for the literals
+}
+
+func (v value) String() string {
+	switch {
+	case v.int == nil:
+		return fmt.Sprint(v.bool)
+	case v.typ == "address":
+		return fmt.Sprintf("0x%040x", v.int)
+	}
+	return v.int.String()
+}
+
+// evaluator is the state of an evaluation: the values of the variables and
+// the trace so far.
+type evaluator struct {
+	s         *State
+	doc       *Document
+	consts    constants
+	env       map[string]value
+	names     []string // variables in the order of their declaration
+	results   []*ast.Param
+	unchecked bool // is the arithmetic unchecked?
+	steps     []EvaluationStep
+	returns   []string
+}
+
+// stop ends the evaluation at the node.
+type stop struct {
+	node     ast.Node
+	reason   string
+	reverted bool
+}
+
+// flow tells how the execution continues after a statement.
+type flow int
+
+const (
+	next flow = iota
+	breakLoop
+	continueLoop
+	returned
+)
+
+func (s *State) evaluate(doc *Document, fn *ast.FunctionDeclaration, args map[string]string) (*Evaluation, error) {
+	e := &evaluator{s: s, doc: doc, consts: s.constantsOf(doc, nil), env: map[string]value{}}
+	params := map[string]bool{}
+	if fn.Type.Params != nil {
+		for _, param := range fn.Type.Params.List {
+			if param.Name == nil {
+				continue
+			}
+			params[param.Name.Name] = true
+			text, ok := args[param.Name.Name]
+			if !ok {
+				// The evaluation stops where it's used.
+				continue
+			}
+			v, err := parseValue(text, typeName(param.Type))
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of `%s`: %s", param.Name.Name, err)
+			}
+			e.set(param.Name.Name, v)
+		}
+	}
+	path := ast.PathEnclosingPos(doc.File, fn.Body.LeftBrace)
+	for _, name := range sortedKeys(args) {
+		if params[name] {
+			continue
+		}
+		typ := ""
+		if sym := s.follow(s.lookup(doc, path, name, fn.Body.LeftBrace)); sym != nil {
+			if decl, ok := sym.Node.(*ast.VariableDeclaration); ok {
+				typ = typeName(decl.Type)
+			}
+		}
+		v, err := parseValue(args[name], typ)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of `%s`: %s", name, err)
+		}
+		e.set(name, v)
+	}
+	if fn.Type.Results != nil {
+		e.results = fn.Type.Results.List
+		for _, result := range e.results {
+			if result.Name != nil {
+				e.set(result.Name.Name, zeroValue(typeName(result.Type)))
+			}
+		}
+	}
+
+	res := &Evaluation{}
+	f, st := e.exec(fn.Body)
+	if st != nil {
+		res.Stop = &EvaluationStop{Range: ast.NodeRange(st.node), Reason: st.reason, Reverted: st.reverted}
+	} else if f != returned {
+		// The function returns its named results at the end.
+		for _, result := range e.results {
+			if result.Name != nil {
+				e.returns = append(e.returns, e.env[result.Name.Name].String())
+			} else {
+				e.returns = append(e.returns, zeroValue(typeName(result.Type)).String())
+			}
+		}
+	}
+	res.Steps = e.steps
+	if res.Stop == nil {
+		res.Returns = e.returns
+	}
+	return res, nil
+}
+
+// step records the executed node with the values of the variables.
+func (e *evaluator) step(node ast.Node, note string) {
+	variables := []EvaluatedVariable{}
+	for _, name := range e.names {
+		variables = append(variables, EvaluatedVariable{Name: name, Value: e.env[name].String()})
+	}
+	e.steps = append(e.steps, EvaluationStep{Range: ast.NodeRange(node), Note: note, Variables: variables})
+}
+
+// set assigns the variable, which is declared if it's new.
+func (e *evaluator) set(name string, v value) {
+	if _, ok := e.env[name]; !ok {
+		e.names = append(e.names, name)
+	}
+	e.env[name] = v
+}
+
+func (e *evaluator) exec(stmt ast.Statement) (flow, *stop) {
+	if len(e.steps) >= maxEvaluationSteps {
+		return next, &stop{node: stmt, reason: fmt.Sprintf("the evaluation takes over %d steps", maxEvaluationSteps)}
+	}
+	switch n := stmt.(type) {
+	case *ast.BlockStatement:
+		for _, stmt := range n.Statements {
+			if f, st := e.exec(stmt); f != next || st != nil {
+				return f, st
+			}
+		}
+		return next, nil
+	case *ast.UncheckedBlockStatement:
+		unchecked := e.unchecked
+		e.unchecked = true
+		f, st := e.exec(n.Body)
+		e.unchecked = unchecked
+		return f, st
+	case *ast.VariableDeclarationStatement:
+		return next, e.declare(n)
+	case *ast.ExpressionStatement:
+		if call, ok := n.Expression.(*ast.CallExpression); ok {
+			if name := e.checkName(call); name != "" {
+				return next, e.check(n, call, name)
+			}
+		}
+		if _, st := e.eval(n.Expression); st != nil {
+			return next, st
+		}
+		e.step(n, "")
+		return next, nil
+	case *ast.IfStatement:
+		condition, st := e.condition(n.Condition)
+		if st != nil {
+			return next, st
+		}
+		e.step(n.Condition, fmt.Sprint(condition))
+		if condition {
+			return e.exec(n.Consequence)
+		}
+		if n.Alternative != nil {
+			return e.exec(n.Alternative)
+		}
+		return next, nil
+	case *ast.ForStatement:
+		if n.Init != nil {
+			if _, st := e.exec(n.Init); st != nil {
+				return next, st
+			}
+		}
+		return e.loop(n.Condition, n.Body, n.Post, false)
+	case *ast.WhileStatement:
+		return e.loop(n.Condition, n.Body, nil, false)
+	case *ast.DoWhileStatement:
+		return e.loop(n.Condition, n.Body, nil, true)
+	case *ast.BreakStatement:
+		return breakLoop, nil
+	case *ast.ContinueStatement:
+		return continueLoop, nil
+	case *ast.ReturnStatement:
+		if n.Result != nil {
+			results := []ast.Expression{n.Result}
+			if tuple, ok := n.Result.(*ast.TupleExpression); ok && len(tuple.Elements) > 1 {
+				results = tuple.Elements
+			}
+			for _, x := range results {
+				if x == nil {
+					return next, &stop{node: n, reason: "the return statement has a missing value"}
+				}
+				v, st := e.eval(x)
+				if st != nil {
+					return next, st
+				}
+				e.returns = append(e.returns, v.String())
+			}
+		} else {
+			for _, result := range e.results {
+				if result.Name != nil {
+					e.returns = append(e.returns, e.env[result.Name.Name].String())
+				}
+			}
+		}
+		e.step(n, "returns "+strings.Join(e.returns, ", "))
+		return returned, nil
+	case *ast.EmitStatement:
+		// The events don't change the variables.
+		e.step(n, "emits `"+ast.ExprString(n.Call.Function)+"`")
+		return next, nil
+	case *ast.RevertStatement:
+		if n.Call == nil {
+			return next, &stop{node: n, reason: "reverts with `throw`", reverted: true}
+		}
+		return next, &stop{node: n, reason: "reverts with `" + ast.ExprString(n.Call) + "`", reverted: true}
+	case *ast.AssemblyStatement:
+		return next, &stop{node: n, reason: "the assembly blocks are not evaluated"}
+	case *ast.TryStatement:
+		return next, &stop{node: n, reason: "the try statements make external calls, which are not evaluated"}
+	}
+	return next, &stop{node: stmt, reason: "the statement is not evaluated"}
+}
+
+// loop runs the loop as long as its condition is known. The condition of
+// a do-while loop is checked after the body.
+func (e *evaluator) loop(condition ast.Expression, body ast.Statement, post ast.Expression, do bool) (flow, *stop) {
+	for i := 0; ; i++ {
+		if i == maxIterations {
+			return next, &stop{node: condition, reason: fmt.Sprintf("the loop runs over %d iterations", maxIterations)}
+		}
+		if condition != nil && (!do || i > 0) {
+			value, st := e.condition(condition)
+			if st != nil {
+				if !st.reverted {
+					st = &stop{node: condition, reason: fmt.Sprintf("the bound of the loop `%s` is not known: %s", ast.ExprString(condition), st.reason)}
+				}
+				return next, st
+			}
+			e.step(condition, fmt.Sprint(value))
+			if !value {
+				return next, nil
+			}
+		}
+		f, st := e.exec(body)
+		switch {
+		case st != nil:
+			return next, st
+		case f == breakLoop:
+			return next, nil
+		case f == returned:
+			return f, nil
+		}
+		if post != nil {
+			if _, st := e.eval(post); st != nil {
+				return next, st
+			}
+			e.step(post, "")
+		}
+	}
+}
+
+// declare evaluates the declaration of the local variables. The variables
+// without a value are zero.
+func (e *evaluator) declare(n *ast.VariableDeclarationStatement) *stop {
+	var values []ast.Expression
+	switch {
+	case n.Value == nil:
+	case n.Lparen == 0:
+		values = []ast.Expression{n.Value}
+	default:
+		tuple, ok := n.Value.(*ast.TupleExpression)
+		if !ok || len(tuple.Elements) != len(n.Declarations) {
+			// e.g. a call returning several values
+			if _, st := e.eval(n.Value); st != nil {
+				return st
+			}
+			return &stop{node: n.Value, reason: fmt.Sprintf("`%s` is not evaluated", ast.ExprString(n.Value))}
+		}
+		values = tuple.Elements
+	}
+
+	evaluated := make([]value, len(n.Declarations))
+	for i, decl := range n.Declarations {
+		if decl == nil {
+			continue
+		}
+		typ := typeName(decl.Type)
+		if values == nil {
+			evaluated[i] = zeroValue(typ)
+			continue
+		}
+		if values[i] == nil {
+			return &stop{node: n, reason: "the declaration has a missing value"}
+		}
+		v, st := e.eval(values[i])
+		if st != nil {
+			return st
+		}
+		if typ != "" {
+			v.typ = typ
+		}
+		evaluated[i] = v
+	}
+	for i, decl := range n.Declarations {
+		if decl != nil {
+			e.set(decl.Name.Name, evaluated[i])
+		}
+	}
+	e.step(n, "")
+	return nil
+}
+
+// checkName returns the name of the builtin check called e.g. "require",
+// "assert" or "revert"; or empty if it's another call.
+func (e *evaluator) checkName(call *ast.CallExpression) string {
+	fn, ok := call.Function.(*ast.Identifier)
+	if !ok || fn.Name != "require" && fn.Name != "assert" && fn.Name != "revert" {
+		return ""
+	}
+	if e.s.lookup(e.doc, ast.PathEnclosingPos(e.doc.File, fn.Start()), fn.Name, fn.Start()) != nil {
+		return ""
+	}
+	return fn.Name
+}
+
+// check evaluates the call of `require`, `assert` or `revert`.
+func (e *evaluator) check(stmt ast.Statement, call *ast.CallExpression, name string) *stop {
+	if name == "revert" {
+		reason := "reverts"
+		if len(call.Args) > 0 {
+			reason += " with " + ast.ExprString(call.Args[0])
+		}
+		return &stop{node: stmt, reason: reason, reverted: true}
+	}
+	if len(call.Args) == 0 {
+		return &stop{node: stmt, reason: fmt.Sprintf("`%s` has no condition", name)}
+	}
+	condition, st := e.condition(call.Args[0])
+	if st != nil {
+		return st
+	}
+	if !condition {
+		reason := fmt.Sprintf("`%s` fails", ast.ExprString(call.Args[0]))
+		if len(call.Args) > 1 {
+			reason += ", reverting with " + ast.ExprString(call.Args[1])
+		}
+		return &stop{node: stmt, reason: reason, reverted: true}
+	}
+	e.step(stmt, "passes")
+	return nil
+}
+
+func (e *evaluator) condition(x ast.Expression) (bool, *stop) {
+	v, st := e.eval(x)
+	if st != nil {
+		return false, st
+	}
+	if v.int != nil {
+		return false, &stop{node: x, reason: fmt.Sprintf("`%s` is not a boolean", ast.ExprString(x))}
+	}
+	return v.bool, nil
+}
+
+func (e *evaluator) eval(x ast.Expression) (value, *stop) {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		switch x.Kind {
+		case token.TRUE_LITERAL:
+			return value{bool: true}, nil
+		case token.FALSE_LITERAL:
+			return value{}, nil
+		}
+		if v, ok := literalValue(x); ok {
+			return value{int: v}, nil
+		}
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 && x.Elements[0] != nil {
+			return e.eval(x.Elements[0])
+		}
+	case *ast.Identifier, *ast.MemberAccessExpression, *ast.IndexAccessExpression:
+		return e.variable(x)
+	case *ast.UnaryExpression:
+		return e.unary(x)
+	case *ast.BinaryExpression:
+		return e.binary(x)
+	case *ast.AssignmentExpression:
+		return e.assignment(x)
+	case *ast.ConditionalExpression:
+		condition, st := e.condition(x.Condition)
+		if st != nil {
+			return value{}, st
+		}
+		if condition {
+			return e.eval(x.True)
+		}
+		return e.eval(x.False)
+	case *ast.CallExpression:
+		if t, ok := x.Function.(*ast.ElementaryType); ok && len(x.Args) == 1 {
+			return e.conversion(x, typeName(t))
+		}
+		return value{}, &stop{node: x, reason: fmt.Sprintf("`%s` calls a function, which is not evaluated", ast.ExprString(x))}
+	}
+	return value{}, &stop{node: x, reason: fmt.Sprintf("`%s` is not evaluated", ast.ExprString(x))}
+}
+
+// variable returns the value of the variable, the constant or the bound
+// of a type e.g. `type(uint128).max`.
+func (e *evaluator) variable(x ast.Expression) (value, *stop) {
+	if v, ok := e.env[ast.ExprString(x)]; ok {
+		return v, nil
+	}
+	if v, ok := foldInt(x, e.consts); ok {
+		return value{int: v}, nil
+	}
+	if v, ok := foldBool(x, e.consts); ok {
+		return value{bool: v}, nil
+	}
+	if access, ok := x.(*ast.MemberAccessExpression); ok {
+		if v, ok := typeBound(access); ok {
+			return v, nil
+		}
+	}
+
+	// Explain what is missing.
+	base := x
+	for {
+		switch b := base.(type) {
+		case *ast.MemberAccessExpression:
+			base = b.Expression
+			continue
+		case *ast.IndexAccessExpression:
+			base = b.Expression
+			continue
+		}
+		break
+	}
+	name := ast.ExprString(x)
+	if ident, ok := base.(*ast.Identifier); ok {
+		sym := e.s.follow(e.s.lookup(e.doc, ast.PathEnclosingPos(e.doc.File, ident.Start()), ident.Name, ident.Start()))
+		if sym != nil {
+			switch sym.Node.(type) {
+			case *ast.VariableDeclaration:
+				return value{}, &stop{node: x, reason: fmt.Sprintf("`%s` is read from the storage, and the arguments have no value of it", name)}
+			case *ast.Param:
+				if base != x {
+					break
+				}
+				return value{}, &stop{node: x, reason: fmt.Sprintf("the arguments have no value of the parameter `%s`", name)}
+			}
+		}
+	}
+	return value{}, &stop{node: x, reason: fmt.Sprintf("the arguments have no value of `%s`", name)}
+}
+
+// typeBound returns the value of `type(T).min` or `type(T).max` of an
+// integer type.
+func typeBound(access *ast.MemberAccessExpression) (value, bool) {
+	call, ok := access.Expression.(*ast.CallExpression)
+	if !ok || len(call.Args) != 1 {
+		return value{}, false
+	}
+	if fn, ok := call.Function.(*ast.Identifier); !ok || fn.Name != "type" {
+		return value{}, false
+	}
+	t, ok := call.Args[0].(*ast.ElementaryType)
+	if !ok {
+		return value{}, false
+	}
+	typ := typeName(t)
+	min, max, ok := intRange(typ)
+	switch {
+	case !ok:
+		return value{}, false
+	case access.Member.Name == "min":
+		return value{int: min, typ: typ}, true
+	case access.Member.Name == "max":
+		return value{int: max, typ: typ}, true
+	}
+	return value{}, false
+}
+
+func (e *evaluator) unary(x *ast.UnaryExpression) (value, *stop) {
+	switch x.Operator {
+	case token.INC, token.DEC:
+		old, st := e.eval(x.Operand)
+		if st != nil {
+			return value{}, st
+		}
+		if old.int == nil {
+			return value{}, &stop{node: x, reason: fmt.Sprintf("`%s` is not evaluated", ast.ExprString(x))}
+		}
+		op := token.ADD
+		if x.Operator == token.DEC {
+			op = token.SUB
+		}
+		res, _ := intOperation(op, old.int, big.NewInt(1))
+		v, st := e.fit(x, res, old.typ, false)
+		if st != nil {
+			return value{}, st
+		}
+		if st := e.assign(x.Operand, v); st != nil {
+			return value{}, st
+		}
+		if x.Postfix {
+			return old, nil
+		}
+		return v, nil
+	case token.DELETE:
+		old, st := e.eval(x.Operand)
+		if st != nil {
+			return value{}, st
+		}
+		return value{}, e.assign(x.Operand, zeroValue(old.typ))
+	}
+
+	v, st := e.eval(x.Operand)
+	if st != nil {
+		return value{}, st
+	}
+	switch {
+	case x.Operator == token.NOT && v.int == nil:
+		return value{bool: !v.bool, typ: v.typ}, nil
+	case x.Operator == token.SUB && v.int != nil:
+		return e.fit(x, new(big.Int).Neg(v.int), v.typ, false)
+	case x.Operator == token.BIT_NOT && v.int != nil:
+		// The complement wraps around in the unsigned types.
+		return e.fit(x, new(big.Int).Not(v.int), v.typ, true)
+	}
+	return value{}, &stop{node: x, reason: fmt.Sprintf("`%s` is not evaluated", ast.ExprString(x))}
+}
+
+func (e *evaluator) binary(x *ast.BinaryExpression) (value, *stop) {
+	left, st := e.eval(x.Left)
+	if st != nil {
+		return value{}, st
+	}
+	if (x.Operator == token.AND || x.Operator == token.OR) && left.int == nil {
+		// The right side is not evaluated if the left one decides.
+		if left.bool == (x.Operator == token.OR) {
+			return left, nil
+		}
+		return e.eval(x.Right)
+	}
+	right, st := e.eval(x.Right)
+	if st != nil {
+		return value{}, st
+	}
+	return e.operation(x, x.Operator, left, right)
+}
+
+// operation applies the binary operator of the node to the values.
+func (e *evaluator) operation(node ast.Node, op token.TokenType, left, right value) (value, *stop) {
+	if left.int == nil || right.int == nil {
+		if left.int == nil && right.int == nil {
+			switch op {
+			case token.EQUAL:
+				return value{bool: left.bool == right.bool}, nil
+			case token.NOT_EQUAL:
+				return value{bool: left.bool != right.bool}, nil
+			}
+		}
+		return value{}, &stop{node: node, reason: fmt.Sprintf("`%s` is not evaluated", nodeString(node))}
+	}
+	switch op {
+	case token.EQUAL, token.NOT_EQUAL, token.LESS_THAN, token.GREATER_THAN,
+		token.LESS_THAN_OR_EQUAL, token.GREATER_THAN_OR_EQUAL:
+		return value{bool: compareInts(op, left.int, right.int)}, nil
+	}
+	res, ok := intOperation(op, left.int, right.int)
+	if !ok {
+		if (op == token.DIV || op == token.MOD) && right.int.Sign() == 0 {
+			return value{}, &stop{node: node, reason: fmt.Sprintf("`%s` divides by zero", nodeString(node)), reverted: true}
+		}
+		return value{}, &stop{node: node, reason: fmt.Sprintf("`%s` is not evaluated", nodeString(node))}
+	}
+	// The type of a shift or a power is the type of the left operand.
+	typ := left.typ
+	if typ == "" && op != token.EXP && op != token.SHL && op != token.SAR {
+		typ = right.typ
+	}
+	return e.fit(node, res, typ, op == token.SHL)
+}
+
+// fit returns the result of the arithmetic in the integer type: it wraps
+// around if the arithmetic is unchecked, otherwise an overflow reverts.
+// The values of the unknown types are not checked.
+func (e *evaluator) fit(node ast.Node, res *big.Int, typ string, wrap bool) (value, *stop) {
+	min, max, ok := intRange(typ)
+	if !ok || res.Cmp(min) >= 0 && res.Cmp(max) <= 0 {
+		return value{int: res, typ: typ}, nil
+	}
+	if e.unchecked || wrap {
+		size := new(big.Int).Lsh(big.NewInt(1), uint(intSize(typ)))
+		res = new(big.Int).Mod(res, size)
+		if res.Cmp(max) > 0 {
+			res.Sub(res, size)
+		}
+		return value{int: res, typ: typ}, nil
+	}
+	what := "overflows"
+	if res.Cmp(min) < 0 {
+		what = "underflows"
+	}
+	return value{}, &stop{
+		node:     node,
+		reason:   fmt.Sprintf("`%s` %s %s, it would be %s", nodeString(node), what, typ, res),
+		reverted: true,
+	}
+}
+
+func (e *evaluator) assignment(x *ast.AssignmentExpression) (value, *stop) {
+	if left, ok := x.Left.(*ast.TupleExpression); ok && x.Operator == token.ASSIGN {
+		// e.g. `(a, b) = (b, a)`, the values are evaluated first.
+		right, ok := x.Right.(*ast.TupleExpression)
+		if !ok || len(right.Elements) != len(left.Elements) {
+			return value{}, &stop{node: x, reason: fmt.Sprintf("`%s` is not evaluated", ast.ExprString(x))}
+		}
+		values := make([]value, len(right.Elements))
+		for i, element := range right.Elements {
+			if left.Elements[i] == nil || element == nil {
+				continue
+			}
+			v, st := e.eval(element)
+			if st != nil {
+				return value{}, st
+			}
+			values[i] = v
+		}
+		for i, element := range left.Elements {
+			if element != nil {
+				if st := e.assign(element, values[i]); st != nil {
+					return value{}, st
+				}
+			}
+		}
+		return value{}, nil
+	}
+
+	v, st := e.eval(x.Right)
+	if st != nil {
+		return value{}, st
+	}
+	if op, ok := compoundOperators[x.Operator]; ok {
+		left, st := e.eval(x.Left)
+		if st != nil {
+			return value{}, st
+		}
+		if v, st = e.operation(x, op, left, v); st != nil {
+			return value{}, st
+		}
+	}
+	return v, e.assign(x.Left, v)
+}
+
+// compoundOperators are the binary operators of the compound assignments.
+var compoundOperators = map[token.TokenType]token.TokenType{
+	token.ASSIGN_ADD:     token.ADD,
+	token.ASSIGN_SUB:     token.SUB,
+	token.ASSIGN_MUL:     token.MUL,
+	token.ASSIGN_DIV:     token.DIV,
+	token.ASSIGN_MOD:     token.MOD,
+	token.ASSIGN_BIT_OR:  token.BIT_OR,
+	token.ASSIGN_BIT_XOR: token.BIT_XOR,
+	token.ASSIGN_BIT_AND: token.BIT_AND,
+	token.ASSIGN_SHL:     token.SHL,
+	token.ASSIGN_SAR:     token.SAR,
+}
+
+// assign writes the value to the variable, or to the element or the member
+// of a state variable. The value takes the type of the variable.
+func (e *evaluator) assign(x ast.Expression, v value) *stop {
+	switch x.(type) {
+	case *ast.Identifier, *ast.MemberAccessExpression, *ast.IndexAccessExpression:
+	default:
+		return &stop{node: x, reason: fmt.Sprintf("`%s` can't be assigned in the evaluation", ast.ExprString(x))}
+	}
+	name := ast.ExprString(x)
+	if old, ok := e.env[name]; ok && old.typ != "" {
+		v.typ = old.typ
+	} else if _, t := e.s.typeOf(e.doc, ast.PathEnclosingPos(e.doc.File, x.Start()), x); t != nil {
+		if typ := typeName(t); typ != "" {
+			v.typ = typ
+		}
+	}
+	e.set(name, v)
+	return nil
+}
+
+// conversion evaluates the explicit conversion to the elementary type e.g.
+// `uint128(x)`, which truncates the integers.
+func (e *evaluator) conversion(call *ast.CallExpression, typ string) (value, *stop) {
+	v, st := e.eval(call.Args[0])
+	if st != nil {
+		return value{}, st
+	}
+	switch {
+	case v.int != nil && intSize(typ) > 0:
+		return e.fit(call, v.int, typ, true)
+	case v.int != nil && typ == "address":
+		return value{int: v.int, typ: typ}, nil
+	}
+	return value{}, &stop{node: call, reason: fmt.Sprintf("`%s` is not evaluated", ast.ExprString(call))}
+}
+
+// typeName returns the name of the elementary type e.g. "uint256" for
+// `uint`; or empty if it's not one.
+func typeName(t ast.Expression) string {
+	elementary, ok := t.(*ast.ElementaryType)
+	if !ok {
+		return ""
+	}
+	switch elementary.Value {
+	case "uint":
+		return "uint256"
+	case "int":
+		return "int256"
+	}
+	return elementary.Value
+}
+
+// zeroValue returns the default value of the type.
+func zeroValue(typ string) value {
+	if typ == "bool" {
+		return value{typ: typ}
+	}
+	return value{int: new(big.Int), typ: typ}
+}
+
+// intRange returns the bounds of the integer type.
+func intRange(typ string) (min, max *big.Int, ok bool) {
+	bits := intSize(typ)
+	if bits == 0 {
+		return nil, nil, false
+	}
+	if strings.HasPrefix(typ, "u") {
+		max = new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		return new(big.Int), max.Sub(max, big.NewInt(1)), true
+	}
+	max = new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	min = new(big.Int).Neg(max)
+	return min, max.Sub(max, big.NewInt(1)), true
+}
+
+// parseValue parses the value of an argument of the type e.g. "1000",
+// "1e18", "1 ether", "0xff", "-5" or "true".
+func parseValue(text, typ string) (value, error) {
+	text = strings.TrimSpace(text)
+	if text == "true" || text == "false" {
+		if typ != "" && typ != "bool" {
+			return value{}, fmt.Errorf("expected a value of %s, got %s", typ, text)
+		}
+		return value{bool: text == "true", typ: "bool"}, nil
+	}
+	if typ == "bool" {
+		return value{}, fmt.Errorf("expected true or false, got %q", text)
+	}
+
+	digits, unit, _ := strings.Cut(text, " ")
+	negative := strings.HasPrefix(digits, "-")
+	lit := &ast.BasicLit{Kind: token.DECIMAL_NUMBER, Value: strings.TrimPrefix(digits, "-")}
+	if strings.HasPrefix(lit.Value, "0x") {
+		lit.Kind = token.HEX_NUMBER
+	}
+	if unit = strings.TrimSpace(unit); unit != "" {
+		lit.Unit = &ast.Identifier{Name: unit}
+	}
+	v, ok := literalValue(lit)
+	if !ok {
+		return value{}, fmt.Errorf(`expected a number e.g. "1000", "1e18", "1 ether" or "0xff", or true or false, got %q`, text)
+	}
+	if negative {
+		v.Neg(v)
+	}
+	if min, max, ok := intRange(typ); ok && (v.Cmp(min) < 0 || v.Cmp(max) > 0) {
+		return value{}, fmt.Errorf("%s doesn't fit in %s", v, typ)
+	}
+	return value{int: v, typ: typ}, nil
+}
+
+// nodeString returns the source of the expression node e.g. `a - b` for
+// the stops.
+func nodeString(node ast.Node) string {
+	if x, ok := node.(ast.Expression); ok {
+		return ast.ExprString(x)
+	}
+	return ""
+}
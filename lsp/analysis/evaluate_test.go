@@ -0,0 +1,232 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const feeVaultSrc = `pragma solidity ^0.8.0;
+
+contract Vault {
+    uint256 constant BPS = 10_000;
+    uint256 public totalAssets;
+
+    function computeFee(uint256 amount, uint256 feeBps) public pure returns (uint256 fee, uint256 net) {
+        uint256 gross = amount * feeBps;
+        fee = gross / BPS;
+        if (fee == 0 && amount > 0) {
+            fee = 1;
+        }
+        net = amount - fee;
+    }
+
+    function tier(uint256 amount, bool vip) public pure returns (uint256) {
+        require(amount > 0, "zero");
+        if (vip) {
+            return amount / 2;
+        } else if (amount >= 1 ether) {
+            return amount * 3 / 4;
+        }
+        return amount;
+    }
+
+    function sum(uint256 n) public pure returns (uint256 total) {
+        for (uint256 i = 1; i <= n; i++) {
+            unchecked { total += i; }
+        }
+    }
+
+    function distribute(address[] memory holders) public {
+        for (uint256 i = 0; i < holders.length; i++) {
+            totalAssets -= 1;
+        }
+    }
+
+    function shares(uint256 amount) public view returns (uint256) {
+        return amount * 1e18 / totalAssets;
+    }
+}
+`
+
+// traceString returns the steps of the evaluation as lines e.g.
+// "8 `uint256 gross = amount * feeBps` amount=1000, gross=30000".
+func traceString(doc *Document, evaluation *Evaluation) []string {
+	res := []string{}
+	for _, step := range evaluation.Steps {
+		line := fmt.Sprintf("%d `%s`", doc.Handle.Position(step.Range.Start).Line, doc.Handle.Src()[step.Range.Start:step.Range.End])
+		if step.Note != "" {
+			line += " " + step.Note + ":"
+		}
+		variables := []string{}
+		for _, v := range step.Variables {
+			variables = append(variables, v.Name+"="+v.Value)
+		}
+		res = append(res, line+" "+strings.Join(variables, ", "))
+	}
+	return res
+}
+
+func Test_EvaluateFeeMath(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Vault.sol"
+	s.OpenDocument(uri, 1, feeVaultSrc)
+	doc := s.Documents[uri]
+
+	tests := []struct {
+		args    map[string]string
+		steps   []string
+		returns []string
+	}{
+		{
+			map[string]string{"amount": "1000000", "feeBps": "30"},
+			[]string{
+				"8 `uint256 gross = amount * feeBps` amount=1000000, feeBps=30, fee=0, net=0, gross=30000000",
+				"9 `fee = gross / BPS` amount=1000000, feeBps=30, fee=3000, net=0, gross=30000000",
+				"10 `fee == 0 && amount > 0` false: amount=1000000, feeBps=30, fee=3000, net=0, gross=30000000",
+				"13 `net = amount - fee` amount=1000000, feeBps=30, fee=3000, net=997000, gross=30000000",
+			},
+			[]string{"3000", "997000"},
+		},
+		{
+			// The fee rounds down to zero, so the minimum fee is charged.
+			map[string]string{"amount": "100", "feeBps": "30"},
+			[]string{
+				"8 `uint256 gross = amount * feeBps` amount=100, feeBps=30, fee=0, net=0, gross=3000",
+				"9 `fee = gross / BPS` amount=100, feeBps=30, fee=0, net=0, gross=3000",
+				"10 `fee == 0 && amount > 0` true: amount=100, feeBps=30, fee=0, net=0, gross=3000",
+				"11 `fee = 1` amount=100, feeBps=30, fee=1, net=0, gross=3000",
+				"13 `net = amount - fee` amount=100, feeBps=30, fee=1, net=99, gross=3000",
+			},
+			[]string{"1", "99"},
+		},
+	}
+	for _, tt := range tests {
+		evaluation, err := s.Evaluate(uri, "computeFee", tt.args)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		if evaluation.Stop != nil {
+			t.Fatalf("Expected the function to return, got a stop: %s", evaluation.Stop.Reason)
+		}
+		if got := traceString(doc, evaluation); strings.Join(got, "\n") != strings.Join(tt.steps, "\n") {
+			t.Errorf("Expected the steps:\n%s\ngot:\n%s", strings.Join(tt.steps, "\n"), strings.Join(got, "\n"))
+		}
+		if strings.Join(evaluation.Returns, ", ") != strings.Join(tt.returns, ", ") {
+			t.Errorf("Expected the returned values %v, got %v", tt.returns, evaluation.Returns)
+		}
+	}
+}
+
+func Test_EvaluateBranches(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Vault.sol"
+	s.OpenDocument(uri, 1, feeVaultSrc)
+	doc := s.Documents[uri]
+
+	tests := []struct {
+		function string
+		args     map[string]string
+		returns  string
+		last     string // the last step
+	}{
+		{"tier", map[string]string{"amount": "100", "vip": "true"}, "50", "19 `return amount / 2` returns 50: amount=100, vip=true"},
+		{"tier", map[string]string{"amount": "2 ether", "vip": "false"}, "1500000000000000000",
+			"21 `return amount * 3 / 4` returns 1500000000000000000: amount=2000000000000000000, vip=false"},
+		{"tier", map[string]string{"amount": "100", "vip": "false"}, "100", "23 `return amount` returns 100: amount=100, vip=false"},
+		{"Vault.sum", map[string]string{"n": "4"}, "10", "27 `i <= n` false: n=4, total=10, i=5"},
+		{"shares", map[string]string{"amount": "1", "totalAssets": "4e18"}, "0", "39 `return amount * 1e18 / totalAssets` returns 0: amount=1, totalAssets=4000000000000000000"},
+	}
+	for _, tt := range tests {
+		evaluation, err := s.Evaluate(uri, tt.function, tt.args)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %s", tt.function, err)
+		}
+		if evaluation.Stop != nil {
+			t.Fatalf("%s: expected the function to return, got a stop: %s", tt.function, evaluation.Stop.Reason)
+		}
+		if got := strings.Join(evaluation.Returns, ", "); got != tt.returns {
+			t.Errorf("%s: expected to return %s, got %s", tt.function, tt.returns, got)
+		}
+		trace := traceString(doc, evaluation)
+		if last := trace[len(trace)-1]; last != tt.last {
+			t.Errorf("%s: expected the last step %q, got %q", tt.function, tt.last, last)
+		}
+	}
+}
+
+func Test_EvaluateStops(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Vault.sol"
+	s.OpenDocument(uri, 1, feeVaultSrc)
+	doc := s.Documents[uri]
+
+	tests := []struct {
+		function string
+		args     map[string]string
+		line     int
+		reason   string
+		reverted bool
+	}{
+		{"tier", map[string]string{"amount": "0", "vip": "true"}, 17, "`amount > 0` fails, reverting with \"zero\"", true},
+		{"computeFee", map[string]string{"amount": "0x" + strings.Repeat("f", 64), "feeBps": "30"}, 8,
+			"`amount * feeBps` overflows uint256, it would be 3473762677119485862707129550260637235598099539969216921183727520237393889198050", true},
+		{"computeFee", map[string]string{"amount": "1000"}, 8, "the arguments have no value of the parameter `feeBps`", false},
+		{"distribute", map[string]string{}, 33,
+			"the bound of the loop `i < holders.length` is not known: the arguments have no value of `holders.length`", false},
+		{"shares", map[string]string{"amount": "1"}, 39, "`totalAssets` is read from the storage, and the arguments have no value of it", false},
+		{"shares", map[string]string{"amount": "1", "totalAssets": "0"}, 39, "`amount * 1e18 / totalAssets` divides by zero", true},
+	}
+	for _, tt := range tests {
+		evaluation, err := s.Evaluate(uri, tt.function, tt.args)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %s", tt.function, err)
+		}
+		stop := evaluation.Stop
+		if stop == nil {
+			t.Fatalf("%s: expected a stop, got the returned values %v", tt.function, evaluation.Returns)
+		}
+		if line := doc.Handle.Position(stop.Range.Start).Line; line != tt.line || stop.Reason != tt.reason || stop.Reverted != tt.reverted {
+			t.Errorf("%s: expected a stop at line %d with %q (reverted: %t), got line %d with %q (reverted: %t)",
+				tt.function, tt.line, tt.reason, tt.reverted, line, stop.Reason, stop.Reverted)
+		}
+	}
+
+	errors := []struct {
+		function string
+		args     map[string]string
+		err      string
+	}{
+		{"withdraw", nil, "no function `withdraw` with a body in the file"},
+		{"tier", map[string]string{"amount": "100", "vip": "1"}, "invalid value of `vip`: expected true or false, got \"1\""},
+		{"computeFee", map[string]string{"amount": "-1"}, "invalid value of `amount`: -1 doesn't fit in uint256"},
+	}
+	for _, tt := range errors {
+		if _, err := s.Evaluate(uri, tt.function, tt.args); err == nil || err.Error() != tt.err {
+			t.Errorf("%s: expected the error %q, got %v", tt.function, tt.err, err)
+		}
+	}
+}
+
+func Test_EvaluatePathAtPosition(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Vault.sol"
+	s.OpenDocument(uri, 1, feeVaultSrc)
+
+	response := s.EvaluatePath(lsp.IntID(1), lsp.EvaluatePathParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+		Position:     lsp.Position{Line: 17, Character: 12},
+		Arguments:    map[string]string{"amount": "100", "vip": "true"},
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %s", response.Error.Message)
+	}
+	result := response.Result
+	if len(result.Steps) != 3 || result.Stop != nil || strings.Join(result.Returns, ", ") != "50" {
+		t.Fatalf("Expected 3 steps returning 50, got %+v", result)
+	}
+	if start := result.Steps[1].Range.Start; start.Line != 17 || start.Character != 12 {
+		t.Errorf("Expected the condition at 17:12, got %d:%d", start.Line, start.Character)
+	}
+}
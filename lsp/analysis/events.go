@@ -0,0 +1,235 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/keccak"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// maxIndexed is the number of the topics the EVM logs beyond the first one,
+// the hash of the signature. The anonymous events have no such hash, so they
+// can index one more parameter.
+const maxIndexed = 3
+
+// addressNames are the names of the address parameters the off-chain
+// consumers usually filter the logs by.
+var addressNames = []string{"owner", "from", "to", "sender", "account"}
+
+// eventDiagnostics checks the events of the document, the interface of the
+// contracts to the off-chain consumers. The checks can be disabled one by
+// one with their codes in the [detectors] section:
+//
+//   - too-many-indexed: the indexed parameters beyond the ones the EVM has
+//     the topics for, which the compiler rejects;
+//   - indexed-dynamic-type: an indexed string, bytes, array or struct. The
+//     topic holds the hash of its encoding, so the value can't be read back
+//     from the log;
+//   - unindexed-address: an address parameter named e.g. `from` or `owner`
+//     of an event without indexed parameters, which the consumers can't
+//     filter the logs by;
+//   - event-collision: two events of the same contract whose names differ
+//     only by case, or whose signatures have the same hash e.g. `uint` and
+//     `uint256` parameters, which the consumers can't tell apart.
+func (s *State) eventDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(r token.Range, severity lsp.DiagnosticSeverity, code, message string, related ...*ast.EventDeclaration) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		d := lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, r),
+			Severity: severity,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		}
+		for _, e := range related {
+			d.RelatedInformation = append(d.RelatedInformation, lsp.DiagnosticRelatedInformation{
+				Location: lsp.Location{URI: doc.URI, Range: toLspRange(doc.Handle, ast.NodeRange(e.Name))},
+				Message:  "event " + e.Name.Name,
+			})
+		}
+		res = append(res, d)
+	}
+
+	// The events are compared with the ones declared next to them, in the
+	// same contract or at the file level.
+	scopes := [][]ast.Declaration{doc.File.Declarations}
+	for _, decl := range doc.File.Declarations {
+		if c, ok := decl.(*ast.ContractDeclaration); ok {
+			scopes = append(scopes, c.Body)
+		}
+	}
+	for _, scope := range scopes {
+		events := []*ast.EventDeclaration{}
+		for _, node := range scope {
+			if e, ok := node.(*ast.EventDeclaration); ok && e.Name != nil && e.Params != nil {
+				events = append(events, e)
+			}
+		}
+
+		signatures := map[*ast.EventDeclaration]string{}
+		for _, e := range events {
+			limit, indexed := maxIndexed, 0
+			if e.Anonymous {
+				limit++
+			}
+			addresses := []*ast.Param{}
+			for _, param := range e.Params.List {
+				canonical, known := s.canonicalType(doc, param.Type, map[ast.Node]bool{})
+				if !param.Indexed {
+					if known && canonical == "address" && param.Name != nil && isAddressName(param.Name.Name) {
+						addresses = append(addresses, param)
+					}
+					continue
+				}
+				indexed++
+				if indexed == limit+1 {
+					report(ast.NodeRange(param), lsp.SeverityError, "too-many-indexed",
+						fmt.Sprintf("`%s` has %d indexed parameters, but at most %d can be indexed", e.Name.Name, countIndexed(e), limit))
+				}
+				if known && isHashedTopic(canonical) {
+					name := ""
+					if param.Name != nil {
+						name = fmt.Sprintf(" `%s`", param.Name.Name)
+					}
+					report(ast.NodeRange(param), lsp.SeverityWarning, "indexed-dynamic-type",
+						fmt.Sprintf("The indexed `%s` parameter%s is logged as the keccak256 hash of its value, which the off-chain consumers can't recover", canonical, name))
+				}
+			}
+			if indexed == 0 {
+				for _, param := range addresses {
+					report(ast.NodeRange(param.Name), lsp.SeverityHint, "unindexed-address",
+						fmt.Sprintf("`%s` has no indexed parameters; indexing `%s` lets the off-chain consumers filter the logs by it", e.Name.Name, param.Name.Name))
+				}
+			}
+			if signature, ok := s.eventSignature(doc, e); ok && !e.Anonymous {
+				signatures[e] = signature
+			}
+		}
+
+		for i, e := range events {
+			for _, other := range events[:i] {
+				switch {
+				case e.Name.Name != other.Name.Name && strings.EqualFold(e.Name.Name, other.Name.Name):
+					report(ast.NodeRange(e.Name), lsp.SeverityWarning, "event-collision",
+						fmt.Sprintf("The events `%s` and `%s` differ only by case, which the off-chain consumers matching the names can confuse", other.Name.Name, e.Name.Name), other)
+				case signatures[e] != "" && signatures[e] == signatures[other]:
+					report(ast.NodeRange(e.Name), lsp.SeverityWarning, "event-collision",
+						fmt.Sprintf("The events `%s` and `%s` have the same topic0 %s, the off-chain consumers can't tell their logs apart", writtenSignature(other), writtenSignature(e), formatTopic(signatures[e])), other)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// eventSignature returns the canonical signature of the event e.g.
+// "Transfer(address,address,uint256)", whose hash is the first topic of its
+// logs.
+func (s *State) eventSignature(doc *Document, e *ast.EventDeclaration) (string, bool) {
+	types := []ast.Expression{}
+	for _, param := range e.Params.List {
+		types = append(types, param.Type)
+	}
+	return s.signature(doc, e.Name.Name, types)
+}
+
+// formatTopic returns the hash of the signature as it's shown in the logs.
+func formatTopic(signature string) string {
+	return fmt.Sprintf("0x%x", keccak.Sum256([]byte(signature)))
+}
+
+// isHashedTopic reports whether the indexed parameter of the canonical type
+// is logged as the hash of its encoding: the strings, the bytes, the arrays
+// and the structs.
+func isHashedTopic(canonical string) bool {
+	return canonical == "string" || canonical == "bytes" || strings.HasSuffix(canonical, "]") || strings.HasPrefix(canonical, "(")
+}
+
+// isAddressName reports whether the name is one of addressNames, with the
+// underscores and the case ignored e.g. `_owner` or `Account`.
+func isAddressName(name string) bool {
+	return slices.Contains(addressNames, strings.ToLower(strings.Trim(name, "_")))
+}
+
+// writtenSignature returns the signature of the event with the types as
+// they're written e.g. "Deposit(uint)".
+func writtenSignature(e *ast.EventDeclaration) string {
+	types := []string{}
+	for _, param := range e.Params.List {
+		types = append(types, ast.ExprString(param.Type))
+	}
+	return e.Name.Name + "(" + strings.Join(types, ",") + ")"
+}
+
+func countIndexed(e *ast.EventDeclaration) int {
+	n := 0
+	for _, param := range e.Params.List {
+		if param.Indexed {
+			n++
+		}
+	}
+	return n
+}
+
+// eventLayoutHover returns how the logs of the event are laid out: the
+// first topic, the hash of the signature, the indexed parameters in the
+// other topics and the rest in the data; or an empty string if it's not an
+// event or some of its types are unknown.
+func (s *State) eventLayoutHover(sym *Symbol, markdown bool) string {
+	e, ok := sym.Node.(*ast.EventDeclaration)
+	if !ok || e.Params == nil {
+		return ""
+	}
+	signature, ok := s.eventSignature(sym.Doc, e)
+	if !ok {
+		return ""
+	}
+	code := func(s string) string {
+		if markdown {
+			return "`" + s + "`"
+		}
+		return s
+	}
+
+	var b strings.Builder
+	if markdown {
+		b.WriteString("**Log layout**:\n")
+	} else {
+		b.WriteString("Log layout:\n")
+	}
+	topic := 0
+	if e.Anonymous {
+		b.WriteString("- topic0: none, the event is anonymous\n")
+	} else {
+		fmt.Fprintf(&b, "- topic0: %s, the hash of %s\n", code(formatTopic(signature)), code(signature))
+		topic++
+	}
+	data := []string{}
+	for _, param := range e.Params.List {
+		canonical, _ := s.canonicalType(sym.Doc, param.Type, map[ast.Node]bool{})
+		name := canonical
+		if param.Name != nil {
+			name = param.Name.Name
+		}
+		if !param.Indexed {
+			data = append(data, code(name))
+			continue
+		}
+		fmt.Fprintf(&b, "- topic%d: %s", topic, code(name))
+		if isHashedTopic(canonical) {
+			b.WriteString(", the hash of the value")
+		}
+		b.WriteString("\n")
+		topic++
+	}
+	if len(data) > 0 {
+		fmt.Fprintf(&b, "- data: %s\n", strings.Join(data, ", "))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,85 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const eventsToken = `pragma solidity ^0.8.0;
+
+contract Token {
+    struct Order { uint256 id; address maker; }
+
+    event Transfer(address indexed from, address indexed to, uint256 value);
+    event Approval(address owner, address spender, uint256 value);
+    event Named(string indexed name, bytes data, Order indexed order);
+    event Crowded(uint256 indexed a, uint256 indexed b, uint256 indexed c, uint256 indexed d);
+    event Hidden(uint256 indexed a, uint256 indexed b, uint256 indexed c, uint256 indexed d) anonymous;
+    event Paid(uint amount);
+    event Paid(uint256 amount);
+    event approval(address account);
+}
+`
+
+func Test_EventDiagnostics(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/Token.sol"
+	s.OpenDocument(uri, 1, eventsToken)
+
+	got := []string{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		switch d.Code {
+		case "too-many-indexed", "indexed-dynamic-type", "unindexed-address", "event-collision":
+			got = append(got, fmt.Sprintf("%d:%d %d %s: %s", d.Range.Start.Line, d.Range.Start.Character, d.Severity, d.Code, d.Message))
+		}
+	}
+	expected := []string{
+		"6:27 4 unindexed-address: `Approval` has no indexed parameters; indexing `owner` lets the off-chain consumers filter the logs by it",
+		"7:16 2 indexed-dynamic-type: The indexed `string` parameter `name` is logged as the keccak256 hash of its value, which the off-chain consumers can't recover",
+		"7:49 2 indexed-dynamic-type: The indexed `(uint256,address)` parameter `order` is logged as the keccak256 hash of its value, which the off-chain consumers can't recover",
+		"8:75 1 too-many-indexed: `Crowded` has 4 indexed parameters, but at most 3 can be indexed",
+		"11:10 2 event-collision: The events `Paid(uint)` and `Paid(uint256)` have the same topic0 " + formatTopic("Paid(uint256)") + ", the off-chain consumers can't tell their logs apart",
+		"12:10 2 event-collision: The events `Approval` and `approval` differ only by case, which the off-chain consumers matching the names can confuse",
+		"12:27 4 unindexed-address: `approval` has no indexed parameters; indexing `account` lets the off-chain consumers filter the logs by it",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d:\n%v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected\n%s\ngot\n%s", expected[i], got[i])
+		}
+	}
+
+	s.Config.Disabled = []string{"unindexed-address", "event-collision"}
+	for _, d := range s.eventDiagnostics(s.Documents[uri]) {
+		if d.Code == "unindexed-address" || d.Code == "event-collision" {
+			t.Errorf("Expected the disabled checks to be skipped, got %s", d.Message)
+		}
+	}
+}
+
+func Test_EventLayoutHover(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/Token.sol"
+	s.OpenDocument(uri, 1, eventsToken)
+
+	hover := s.Hover(lsp.IntID(1), uri, lsp.Position{Line: 5, Character: 12}).Result.Contents.Value
+	expected := "```solidity\nevent Transfer(address indexed from, address indexed to, uint256 value)\n```\n\n" +
+		"**Log layout**:\n" +
+		"- topic0: `0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef`, the hash of `Transfer(address,address,uint256)`\n" +
+		"- topic1: `from`\n" +
+		"- topic2: `to`\n" +
+		"- data: `value`"
+	if hover != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, hover)
+	}
+
+	hover = s.Hover(lsp.IntID(2), uri, lsp.Position{Line: 9, Character: 12}).Result.Contents.Value
+	if want := "- topic0: none, the event is anonymous\n- topic0: `a`"; !strings.Contains(hover, want) {
+		t.Errorf("Expected the anonymous event to start with the first parameter, got\n%s", hover)
+	}
+}
@@ -0,0 +1,35 @@
+package analysis_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"solbot/ast"
+	"solbot/lsp/analysis"
+)
+
+// Lists the external and public functions without the NatSpec comments.
+func ExampleSession() {
+	sess := analysis.NewSession()
+	paths, _ := filepath.Glob("testdata/natspec/*.sol")
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sess.AddFile(path, string(src))
+	}
+
+	for _, fn := range sess.Functions() {
+		visibility := fn.Node.(*ast.FunctionDeclaration).Type.Visibility
+		if (visibility == ast.External || visibility == ast.Public) && sess.NatSpec(fn) == "" {
+			pos := sess.Position(fn)
+			fmt.Printf("%s:%d: %s\n", pos.Filename, pos.Line, fn.Name.Name)
+		}
+	}
+	// Output:
+	// testdata/natspec/IVault.sol:8: withdraw
+	// testdata/natspec/Vault.sol:17: withdraw
+	// testdata/natspec/Vault.sol:21: sweep
+}
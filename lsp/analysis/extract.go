@@ -0,0 +1,490 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// extractTitle is the title of the action extracting the selected
+// statements into a new function.
+const extractTitle = "Extract to internal function"
+
+// extraction is the statements of a block of a function body selected to
+// be extracted.
+type extraction struct {
+	doc   *Document
+	fn    *ast.FunctionDeclaration
+	path  []ast.Node      // the path of the block with the statements
+	stmts []ast.Statement // contiguous statements of the block
+	r     token.Range     // from the start of the first statement to the end of the last one, with its semicolon
+}
+
+// extractActions returns the action extracting the selected statements
+// into a new internal function, inserted after the function with them.
+// The variables declared before the statements and read by them become
+// the parameters, and the ones written by them and read after them are
+// returned and assigned at the call site. The selection is refused if
+// it straddles a block boundary, or if it jumps out of the statements
+// with a return, a break or a continue.
+func (s *State) extractActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	e, reason := selectedStatements(doc, selected)
+	if e == nil && reason == "" {
+		return []lsp.CodeAction{}
+	}
+	action := lsp.CodeAction{Title: extractTitle, Kind: lsp.CodeActionRefactorExtract}
+	if reason == "" {
+		action.Edit, reason = s.extractEdit(e)
+	}
+	if reason != "" {
+		action.Edit = nil
+		action.Disabled = &lsp.CodeActionDisabled{Reason: reason}
+	}
+	return []lsp.CodeAction{action}
+}
+
+// selectedStatements returns the statements of a function body covered by
+// the selection, or the reason they can't be extracted. Both are empty if
+// the selection is not about the statements e.g. it's a part of an
+// expression, so the action isn't offered.
+func selectedStatements(doc *Document, selected token.Range) (*extraction, string) {
+	src := doc.Handle.Src()
+	start, end := int(selected.Start), min(int(selected.End), len(src))
+	for start < end && isSpace(src[start]) {
+		start++
+	}
+	for end > start && isSpace(src[end-1]) {
+		end--
+	}
+	if start >= end {
+		return nil, ""
+	}
+	r := token.Range{Start: token.Pos(start), End: token.Pos(end)}
+
+	path := ast.PathEnclosingPos(doc.File, r.Start)
+	var fn *ast.FunctionDeclaration
+	block := -1
+	for i, node := range path {
+		switch n := node.(type) {
+		case *ast.BlockStatement:
+			if block < 0 && n.LeftBrace < r.Start && r.End <= n.RightBrace {
+				block = i
+			}
+		case *ast.FunctionDeclaration:
+			fn = n
+		}
+		if fn != nil {
+			break
+		}
+	}
+	if fn == nil || fn.Body == nil || block < 0 {
+		return nil, ""
+	}
+
+	stmts := []ast.Statement{}
+	for _, stmt := range path[block].(*ast.BlockStatement).Statements {
+		full := statementRange(src, stmt)
+		if full.Start < r.End && r.Start < full.End {
+			stmts = append(stmts, stmt)
+		}
+	}
+	if len(stmts) == 0 {
+		return nil, ""
+	}
+	e := &extraction{
+		doc:   doc,
+		fn:    fn,
+		path:  path[block:],
+		stmts: stmts,
+		r:     token.Range{Start: stmts[0].Start(), End: statementRange(src, stmts[len(stmts)-1]).End},
+	}
+	if !r.ContainsRange(e.r) {
+		switch {
+		case straddlesBlock(stmts, r):
+			return nil, "The selection straddles a block boundary"
+		case len(stmts) == 1:
+			return nil, ""
+		}
+		return nil, "The selection covers the statements partially"
+	}
+	for _, stmt := range stmts {
+		if reason := escapingJump(stmt); reason != "" {
+			return nil, reason
+		}
+	}
+	return e, ""
+}
+
+// statementRange returns the range of the statement including its
+// semicolon, which isn't part of the node.
+func statementRange(src string, stmt ast.Statement) token.Range {
+	r := ast.NodeRange(stmt)
+	i := int(r.End)
+	for i < len(src) && isSpace(src[i]) {
+		i++
+	}
+	if i < len(src) && src[i] == ';' {
+		r.End = token.Pos(i + 1)
+	}
+	return r
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// straddlesBlock reports whether the range has one of the braces of a
+// block of the statements, but not the other.
+func straddlesBlock(stmts []ast.Statement, r token.Range) bool {
+	res := false
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			if block, ok := node.(*ast.BlockStatement); ok && r.Contains(block.LeftBrace) != r.Contains(block.RightBrace) {
+				res = true
+			}
+			return !res
+		})
+	}
+	return res
+}
+
+// escapingJump returns the reason the statement can't be moved into
+// another function: it returns, or breaks out of or continues a loop
+// around it; or "" if it can.
+func escapingJump(stmt ast.Statement) string {
+	reason := ""
+	loops := 0
+	stack := []ast.Node{}
+	ast.Inspect(stmt, func(node ast.Node) bool {
+		if node == nil {
+			switch stack[len(stack)-1].(type) {
+			case *ast.ForStatement, *ast.WhileStatement, *ast.DoWhileStatement:
+				loops--
+			}
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if reason != "" {
+			return false
+		}
+		switch node.(type) {
+		case *ast.ReturnStatement:
+			reason = "The selection contains a return statement, it would return from the extracted function only"
+		case *ast.BreakStatement:
+			if loops == 0 {
+				reason = "The selection contains a break out of the loop around it"
+			}
+		case *ast.ContinueStatement:
+			if loops == 0 {
+				reason = "The selection contains a continue of the loop around it"
+			}
+		case *ast.ForStatement, *ast.WhileStatement, *ast.DoWhileStatement:
+			loops++
+		}
+		stack = append(stack, node)
+		return true
+	})
+	return reason
+}
+
+// environmentParams are the members of `msg` passed to the extracted
+// function as the parameters, by their names.
+var environmentParams = []struct {
+	member, name, typ string
+}{
+	{"sender", "msgSender", "address"},
+	{"value", "msgValue", "uint256"},
+}
+
+// extractEdit returns the edit replacing the statements with the call of
+// the new function; or the reason they can't be extracted.
+func (s *State) extractEdit(e *extraction) (*lsp.WorkspaceEdit, string) {
+	doc, fn := e.doc, e.fn
+	src := doc.Handle.Src()
+
+	// The dataflow of the local variables around the statements.
+	accesses := s.variableAccesses(doc, fn)
+	g := buildFlowGraph(fn, accesses)
+	live, _ := g.liveness()
+	liveIn := live[g.entry[e.stmts[0]]]
+	liveOut := live[g.next[e.stmts[len(e.stmts)-1]]]
+
+	defined := map[ast.Node]bool{}
+	for _, n := range g.nodes {
+		if n.node != nil && e.r.ContainsRange(ast.NodeRange(n.node)) {
+			for decl := range n.defs {
+				defined[decl] = true
+			}
+		}
+	}
+	outside := map[ast.Node]bool{}
+	for _, a := range accesses {
+		if e.r.Contains(a.ident.NamePos) && !e.r.Contains(declaredName(a.decl).NamePos) {
+			outside[a.decl] = true
+		}
+	}
+	outputs := []ast.Node{}
+	for decl := range defined {
+		if liveOut[decl] {
+			outputs = append(outputs, decl)
+		}
+	}
+	// The variables assigned before they are read become the named results,
+	// the others the parameters even if their values aren't used.
+	results := map[ast.Node]bool{}
+	inputs := []ast.Node{}
+	for decl := range outside {
+		if !liveIn[decl] && liveOut[decl] && defined[decl] {
+			results[decl] = true
+		} else {
+			inputs = append(inputs, decl)
+		}
+	}
+	byPosition := func(x, y ast.Node) int {
+		return int(declaredName(x).NamePos) - int(declaredName(y).NamePos)
+	}
+	slices.SortFunc(outputs, byPosition)
+	slices.SortFunc(inputs, byPosition)
+
+	// The members of `msg` become the parameters, so that the function
+	// doesn't read the environment itself.
+	taken := map[string]bool{}
+	for _, a := range accesses {
+		taken[a.ident.Name] = true
+	}
+	replaced := map[ast.Node]bool{}
+	replacements := map[token.Range]string{}
+	params, args := []string{}, []string{}
+	for _, decl := range inputs {
+		typ, ok := variableType(src, decl)
+		if !ok {
+			return nil, fmt.Sprintf("The type of `%s` is not declared", declaredName(decl).Name)
+		}
+		params = append(params, typ+" "+declaredName(decl).Name)
+		args = append(args, declaredName(decl).Name)
+	}
+	for _, p := range environmentParams {
+		name := ""
+		for _, stmt := range e.stmts {
+			ast.Inspect(stmt, func(node ast.Node) bool {
+				access, ok := node.(*ast.MemberAccessExpression)
+				if !ok || access.Member.Name != p.member {
+					return true
+				}
+				if ident, ok := access.Expression.(*ast.Identifier); ok && ident.Name == "msg" {
+					if name == "" {
+						name = uniqueName(p.name, taken)
+					}
+					replaced[ident], replaced[access.Member] = true, true
+					replacements[ast.NodeRange(access)] = name
+				}
+				return true
+			})
+		}
+		if name != "" {
+			params = append(params, p.typ+" "+name)
+			args = append(args, "msg."+p.member)
+		}
+	}
+
+	// The mutability of the statements, with the calls of the other
+	// functions.
+	effects := s.effectsWithCallees(callable{doc: doc, node: fn}, func(node ast.Node) bool {
+		return e.r.Contains(node.Start()) && !replaced[node]
+	})
+	mutability := ""
+	switch {
+	case effects&changesState != 0:
+	case effects == 0:
+		mutability = "pure"
+	default:
+		mutability = "view"
+	}
+
+	returns, names, declared := []string{}, []string{}, []string{}
+	for _, decl := range outputs {
+		typ, ok := variableType(src, decl)
+		if !ok {
+			return nil, fmt.Sprintf("The type of `%s` is not declared", declaredName(decl).Name)
+		}
+		name := declaredName(decl).Name
+		if results[decl] {
+			returns = append(returns, typ+" "+name)
+		} else {
+			returns = append(returns, typ)
+		}
+		if e.r.Contains(declaredName(decl).NamePos) {
+			declared = append(declared, typ+" "+name)
+		}
+		names = append(names, name)
+	}
+
+	contract := enclosingContract(doc, e.path)
+	name := uniqueName("_extracted", s.takenNames(doc, contract))
+	indent := lineIndent(src, fn.Start())
+	unit := "    "
+	if first := lineIndent(src, fn.Body.Statements[0].Start()); len(first) > len(indent) && strings.HasPrefix(first, indent) {
+		unit = first[len(indent):]
+	}
+	base := lineIndent(src, e.r.Start)
+
+	// The new function.
+	specifiers := []string{}
+	if contract != nil {
+		specifiers = append(specifiers, "internal")
+	}
+	if mutability != "" {
+		specifiers = append(specifiers, mutability)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\n%s%s {\n", indent, renderFunctionHeader(name, params, specifiers, returns))
+	body := replaceRanges(src, e.r, replacements)
+	for i, line := range strings.Split(body, "\n") {
+		switch {
+		case strings.TrimSpace(line) == "":
+			b.WriteString("\n")
+		case i == 0:
+			fmt.Fprintf(&b, "%s%s%s\n", indent, unit, line)
+		default:
+			fmt.Fprintf(&b, "%s%s%s\n", indent, unit, strings.TrimPrefix(line, base))
+		}
+	}
+	switch len(names) {
+	case 0:
+	case 1:
+		fmt.Fprintf(&b, "%s%sreturn %s;\n", indent, unit, names[0])
+	default:
+		fmt.Fprintf(&b, "%s%sreturn (%s);\n", indent, unit, strings.Join(names, ", "))
+	}
+	fmt.Fprintf(&b, "%s}", indent)
+
+	// The call, declaring the variables declared by the statements, or
+	// assigning the others.
+	call := fmt.Sprintf("%s(%s);", name, strings.Join(args, ", "))
+	switch {
+	case len(names) == 0:
+	case len(declared) == 1 && len(names) == 1:
+		call = declared[0] + " = " + call
+	case len(declared) == len(names):
+		call = "(" + strings.Join(declared, ", ") + ") = " + call
+	default:
+		// The declarations can't be mixed with the assignments in a tuple.
+		lines := []string{}
+		for _, d := range declared {
+			lines = append(lines, d+";")
+		}
+		target := names[0]
+		if len(names) > 1 {
+			target = "(" + strings.Join(names, ", ") + ")"
+		}
+		call = strings.Join(append(lines, target+" = "+call), "\n"+base)
+	}
+
+	return &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{doc.URI: {
+		{Range: toLspRange(doc.Handle, e.r), NewText: call},
+		{Range: toLspRange(doc.Handle, token.Range{Start: fn.End(), End: fn.End()}), NewText: b.String()},
+	}}}, ""
+}
+
+// variableType returns the type of the variable with its data location
+// e.g. "uint256[] memory"; or false if it has no type e.g. after `var`.
+func variableType(src string, decl ast.Node) (string, bool) {
+	var typ ast.Expression
+	var location ast.DataLocation
+	switch n := decl.(type) {
+	case *ast.VariableDeclaration:
+		typ, location = n.Type, n.Location
+	case *ast.Param:
+		typ, location = n.Type, n.Location
+	}
+	if typ == nil {
+		return "", false
+	}
+	res := src[typ.Start():typ.End()]
+	if name := locationName(location); name != "" {
+		res += " " + name
+	}
+	return res, true
+}
+
+// renderFunctionHeader returns the header of the function with the parameters
+// and the results as they're written, and the specifiers in their order
+// e.g. "function f(uint256 a) external view returns (uint256)".
+func renderFunctionHeader(name string, params, specifiers, returns []string) string {
+	res := fmt.Sprintf("function %s(%s)", name, strings.Join(params, ", "))
+	for _, specifier := range specifiers {
+		res += " " + specifier
+	}
+	if len(returns) > 0 {
+		res += fmt.Sprintf(" returns (%s)", strings.Join(returns, ", "))
+	}
+	return res
+}
+
+// takenNames returns the names declared in the contract and its bases;
+// or at the top level of the document for the free functions.
+func (s *State) takenNames(doc *Document, contract *Symbol) map[string]bool {
+	res := map[string]bool{}
+	add := func(decls []ast.Declaration) {
+		for _, decl := range decls {
+			if name := declaredName(decl); name != nil {
+				res[name.Name] = true
+			}
+		}
+	}
+	if contract == nil {
+		add(doc.File.Declarations)
+		return res
+	}
+	contracts := s.linearize(contract)
+	if contracts == nil {
+		contracts = []*Symbol{contract}
+	}
+	for _, c := range contracts {
+		add(c.Node.(*ast.ContractDeclaration).Body)
+	}
+	return res
+}
+
+// uniqueName returns the name, with a number appended if it's taken, and
+// takes it.
+func uniqueName(name string, taken map[string]bool) string {
+	res := name
+	for i := 2; taken[res]; i++ {
+		res = fmt.Sprintf("%s%d", name, i)
+	}
+	taken[res] = true
+	return res
+}
+
+// lineIndent returns the indentation of the line at the position.
+func lineIndent(src string, pos token.Pos) string {
+	start := strings.LastIndexByte(src[:pos], '\n') + 1
+	end := start
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return src[start:end]
+}
+
+// replaceRanges returns the source in the range with the ranges inside it
+// replaced by the texts.
+func replaceRanges(src string, r token.Range, replacements map[token.Range]string) string {
+	ranges := []token.Range{}
+	for rr := range replacements {
+		ranges = append(ranges, rr)
+	}
+	slices.SortFunc(ranges, func(x, y token.Range) int { return int(x.Start) - int(y.Start) })
+	var b strings.Builder
+	pos := r.Start
+	for _, rr := range ranges {
+		b.WriteString(src[pos:rr.Start])
+		b.WriteString(replacements[rr])
+		pos = rr.End
+	}
+	b.WriteString(src[pos:r.End])
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+const extractSrc = `pragma solidity ^0.8.0;
+
+contract Vault {
+    mapping(address => uint256) balances;
+    uint256 feeRate;
+
+    function deposit(uint256 amount) external {
+        uint256 fee = amount * feeRate / 10000;
+        balances[msg.sender] += amount - fee;
+    }
+
+    function withdraw(uint256 amount) external {
+        if (balances[msg.sender] < amount) {
+            return;
+        }
+        balances[msg.sender] -= amount;
+    }
+
+    function total(address[] memory holders) external view returns (uint256 sum) {
+        for (uint256 i = 0; i < holders.length; i++) {
+            sum += balances[holders[i]];
+        }
+    }
+}
+`
+
+// extractAction returns the action extracting the lines of the source
+// starting with the first and the last text.
+func extractAction(t *testing.T, s *State, uri, first, last string) lsp.CodeAction {
+	t.Helper()
+	doc, _ := s.document(uri)
+	start := strings.Index(extractSrc, first)
+	end := strings.Index(extractSrc[start:], last) + start + len(last)
+	startOfLine := func(offset int) token.Pos {
+		return token.Pos(strings.LastIndexByte(extractSrc[:offset], '\n') + 1)
+	}
+	r := lsp.Range{Start: toLspPosition(doc.Handle, startOfLine(start)), End: toLspPosition(doc.Handle, startOfLine(end+1))}
+	for _, action := range s.CodeAction(lsp.IntID(1), uri, r).Result {
+		if action.Title == extractTitle {
+			return action
+		}
+	}
+	t.Fatalf("Expected the extract action for %q", first)
+	return lsp.CodeAction{}
+}
+
+func Test_ExtractFunction(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, extractSrc)
+
+	// The selection is the whole line, from its indentation to the next.
+	action := extractAction(t, s, uri, "        uint256 fee =", "10000;")
+	if action.Disabled != nil || action.Edit == nil {
+		t.Fatalf("Expected the action with an edit, got %+v", action)
+	}
+	edits := action.Edit.Changes[uri]
+	if len(edits) != 2 {
+		t.Fatalf("Expected 2 edits, got %+v", edits)
+	}
+	if expected := "uint256 fee = _extracted(amount);"; edits[0].NewText != expected {
+		t.Errorf("Expected the call %q, got %q", expected, edits[0].NewText)
+	}
+	expected := `
+
+    function _extracted(uint256 amount) internal view returns (uint256) {
+        uint256 fee = amount * feeRate / 10000;
+        return fee;
+    }`
+	if edits[1].NewText != expected {
+		t.Errorf("Expected the function\n%s\ngot\n%s", expected, edits[1].NewText)
+	}
+	if edits[1].Range.Start.Line != 9 || edits[1].Range.Start.Character != 5 {
+		t.Errorf("Expected the function inserted after deposit, got %v", edits[1].Range)
+	}
+
+	// The members of msg become the parameters.
+	action = extractAction(t, s, uri, "        balances[msg.sender] +=", "- fee;")
+	edits = action.Edit.Changes[uri]
+	if expected := "_extracted(amount, fee, msg.sender);"; edits[0].NewText != expected {
+		t.Errorf("Expected the call %q, got %q", expected, edits[0].NewText)
+	}
+	if !strings.Contains(edits[1].NewText, "function _extracted(uint256 amount, uint256 fee, address msgSender) internal {\n        balances[msgSender] += amount - fee;\n    }") {
+		t.Errorf("Expected msg.sender passed as msgSender, got\n%s", edits[1].NewText)
+	}
+}
+
+func Test_ExtractFunctionRefused(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.Capabilities.TextDocument = &lsp.TextDocumentClientCapabilities{
+		CodeAction: &lsp.CodeActionClientCapabilities{
+			DisabledSupport: true,
+			ResolveSupport:  &lsp.CodeActionResolveSupport{Properties: []string{"edit"}},
+		},
+	}
+	s.OpenDocument(uri, 1, extractSrc)
+
+	tests := []struct {
+		first, last, reason string
+	}{
+		{"        if (balances[msg.sender] < amount)", "        }", "The selection contains a return statement, it would return from the extracted function only"},
+		{"            return;", "-= amount;", "The selection straddles a block boundary"},
+	}
+	for _, tt := range tests {
+		action := extractAction(t, s, uri, tt.first, tt.last)
+		if action.Disabled == nil || action.Disabled.Reason != tt.reason {
+			t.Errorf("Expected the action disabled with %q, got %+v", tt.reason, action.Disabled)
+			continue
+		}
+		response := s.ResolveCodeAction(lsp.IntID(2), action)
+		if response.Error == nil || response.Error.Message != tt.reason {
+			t.Errorf("Expected the resolution refused with %q, got %+v", tt.reason, response)
+		}
+	}
+}
+
+func Test_ExtractFunctionLoopVariable(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, extractSrc)
+
+	// The sum is read by the next iteration and returned.
+	action := extractAction(t, s, uri, "            sum +=", "[i]];")
+	edits := action.Edit.Changes[uri]
+	if expected := "sum = _extracted(holders, sum, i);"; edits[0].NewText != expected {
+		t.Errorf("Expected the call %q, got %q", expected, edits[0].NewText)
+	}
+	expected := `
+
+    function _extracted(address[] memory holders, uint256 sum, uint256 i) internal view returns (uint256) {
+        sum += balances[holders[i]];
+        return sum;
+    }`
+	if edits[1].NewText != expected {
+		t.Errorf("Expected the function\n%s\ngot\n%s", expected, edits[1].NewText)
+	}
+}
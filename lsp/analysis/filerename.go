@@ -0,0 +1,166 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/project"
+	"strings"
+)
+
+// WillRenameFiles returns the edits of the import paths broken by renaming
+// or moving the files, which the client applies before the renames: the
+// imports of the renamed files, and the relative imports of the renamed
+// files themselves. The dependency files are never edited; the second
+// result is the warning listing their broken imports, or empty if there
+// are none.
+func (s *State) WillRenameFiles(id lsp.ID, renames []lsp.FileRename) (lsp.WillRenameFilesResponse, string) {
+	moved := map[string]string{} // old path -> new path
+	for _, r := range renames {
+		moved[URIToPath(r.OldURI)] = URIToPath(r.NewURI)
+	}
+	newPath := func(p string) string {
+		if n, ok := moved[p]; ok {
+			return n
+		}
+		return p
+	}
+
+	edits := map[*Document][]lsp.TextEdit{}
+	skipped := []string{}
+	for _, doc := range s.sortedDocuments() {
+		importer := URIToPath(doc.URI)
+		for _, decl := range doc.File.Declarations {
+			imp, ok := decl.(*ast.ImportDirective)
+			if !ok {
+				continue
+			}
+			target := s.ImportTarget(doc, imp)
+			if target == nil {
+				continue
+			}
+			targetPath := URIToPath(target.URI)
+			if newPath(importer) == importer && newPath(targetPath) == targetPath {
+				continue
+			}
+			value := imp.Path.Value
+			quote, oldImport := value[:1], value[1:len(value)-1]
+			newImport := s.importPathTo(newPath(importer), oldImport, targetPath, newPath(targetPath))
+			if newImport == oldImport {
+				continue
+			}
+			if s.isDependency(doc.URI) {
+				skipped = append(skipped, fmt.Sprintf("%s imports %s", s.RelativePath(doc.URI), s.RelativePath(target.URI)))
+				continue
+			}
+			edits[doc] = append(edits[doc], lsp.TextEdit{
+				Range:   toLspRange(doc.Handle, ast.NodeRange(imp.Path)),
+				NewText: quote + newImport + quote,
+			})
+		}
+	}
+
+	warning := ""
+	if len(skipped) > 0 {
+		warning = "The imports of the dependency files are not edited, so they are broken now: " + strings.Join(skipped, ", ")
+	}
+	if len(edits) == 0 {
+		return lsp.NewWillRenameFilesResponse(id, nil), warning
+	}
+	return lsp.NewWillRenameFilesResponse(id, s.documentEdits(edits)), warning
+}
+
+// importPathTo returns the import path of the file moved from oldTarget to
+// newTarget, in the same form as its old import path: a relative path stays
+// relative, a remapped path stays remapped while the file stays in the
+// directory it's remapped to, and a path from the root or a library
+// directory stays such while the file stays in that directory. Otherwise
+// the new path is relative. The importer is the new path of the importing
+// file. The quotes of the literal are not a part of the paths.
+func (s *State) importPathTo(importer, oldImport, oldTarget, newTarget string) string {
+	if !IsRelativeImport(oldImport) && s.Root != "" {
+		importerRel := s.RelativePath(PathToURI(importer))
+		if _, ok := project.Remap(s.Config.Remappings, importerRel, oldImport); ok {
+			if importPath, ok := project.Unmap(s.Config.Remappings, importerRel, s.RelativePath(PathToURI(newTarget))); ok {
+				return importPath
+			}
+		} else if base, ok := strings.CutSuffix(filepath.ToSlash(oldTarget), "/"+oldImport); ok {
+			if importPath, ok := strings.CutPrefix(filepath.ToSlash(newTarget), base+"/"); ok {
+				return importPath
+			}
+		}
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(importer), newTarget)
+	if err != nil {
+		return filepath.ToSlash(newTarget)
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// RenameFiles moves the renamed documents to their new URIs, after the
+// client renamed the files and applied the edits of WillRenameFiles. The
+// closed documents that were edited are read from the disk again. It
+// returns the URIs whose diagnostics changed: the old ones, which have
+// none now, the new ones and the open documents, whose imports may resolve
+// differently.
+func (s *State) RenameFiles(renames []lsp.FileRename) []string {
+	// The importers are found before the documents move.
+	stale := map[string]bool{}
+	for _, doc := range s.sortedDocuments() {
+		for _, decl := range doc.File.Declarations {
+			imp, ok := decl.(*ast.ImportDirective)
+			if !ok {
+				continue
+			}
+			if target := s.ImportTarget(doc, imp); target != nil {
+				for _, r := range renames {
+					if target.URI == r.OldURI {
+						stale[doc.URI] = true
+					}
+				}
+			}
+		}
+	}
+
+	res := []string{}
+	for _, r := range renames {
+		doc, ok := s.Documents[r.OldURI]
+		if !ok {
+			continue
+		}
+		delete(s.Documents, r.OldURI)
+		delete(s.analyzed, r.OldURI)
+		if target, ok := s.Migrations[r.OldURI]; ok {
+			delete(s.Migrations, r.OldURI)
+			s.Migrations[r.NewURI] = target
+		}
+		s.setDocument(s.newDocument(r.NewURI, doc.Version, doc.Open, string(doc.Handle.Src())))
+		// The moved file may have had its relative imports edited.
+		delete(stale, r.OldURI)
+		stale[r.NewURI] = true
+		res = append(res, r.OldURI, r.NewURI)
+	}
+	for _, uri := range sortedKeys(stale) {
+		if doc, ok := s.Documents[uri]; ok && !doc.Open {
+			if src, err := s.readFile(URIToPath(uri)); err == nil {
+				s.setDocument(s.newDocument(uri, 0, false, string(src)))
+			}
+		}
+	}
+	// The imports of the documents resolve to the new URIs now.
+	s.referencesChanged = true
+
+	for _, doc := range s.sortedDocuments() {
+		if doc.Open && !slices.Contains(res, doc.URI) {
+			res = append(res, doc.URI)
+		}
+	}
+	return res
+}
@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"solbot/lsp"
+	"solbot/project"
+	"strings"
+	"testing"
+)
+
+func Test_ImportPathTo(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	s.Config.Remappings = []project.Remapping{{Prefix: "@oz/", Target: "lib/oz/contracts/"}}
+
+	tests := []struct {
+		name      string
+		importer  string // new path of the importer
+		oldImport string
+		oldTarget string
+		newTarget string
+		expected  string
+	}{
+		{"into a directory", "/ws/src/Vault.sol", "./IVault.sol", "/ws/src/IVault.sol", "/ws/src/interfaces/IVault.sol", "./interfaces/IVault.sol"},
+		{"to a sibling directory", "/ws/src/interfaces/IPool.sol", "../Vault.sol", "/ws/src/Vault.sol", "/ws/src/core/Vault.sol", "../core/Vault.sol"},
+		{"out of a directory", "/ws/src/Vault.sol", "./interfaces/IVault.sol", "/ws/src/interfaces/IVault.sol", "/ws/src/IVault.sol", "./IVault.sol"},
+		{"moved importer", "/ws/src/core/Router.sol", "./IVault.sol", "/ws/src/IVault.sol", "/ws/src/IVault.sol", "../IVault.sol"},
+		{"both moved together", "/ws/src/core/Router.sol", "./IVault.sol", "/ws/src/IVault.sol", "/ws/src/core/IVault.sol", "./IVault.sol"},
+		{"deep importer", "/ws/test/unit/Vault.t.sol", "../../src/Vault.sol", "/ws/src/Vault.sol", "/ws/src/vaults/Vault.sol", "../../src/vaults/Vault.sol"},
+		{"normalized", "/ws/src/Vault.sol", "./lib/../IVault.sol", "/ws/src/IVault.sol", "/ws/IVault.sol", "../IVault.sol"},
+		{"remapped within the tree", "/ws/src/Vault.sol", "@oz/access/Ownable.sol", "/ws/lib/oz/contracts/access/Ownable.sol", "/ws/lib/oz/contracts/auth/Ownable.sol", "@oz/auth/Ownable.sol"},
+		{"remapped out of the tree", "/ws/src/Vault.sol", "@oz/access/Ownable.sol", "/ws/lib/oz/contracts/access/Ownable.sol", "/ws/src/access/Ownable.sol", "./access/Ownable.sol"},
+		{"from the root", "/ws/src/Vault.sol", "src/IVault.sol", "/ws/src/IVault.sol", "/ws/src/interfaces/IVault.sol", "src/interfaces/IVault.sol"},
+		{"from a library directory", "/ws/test/Vault.t.sol", "forge-std/Test.sol", "/ws/lib/forge-std/Test.sol", "/ws/lib/forge-std/src/Test.sol", "forge-std/src/Test.sol"},
+	}
+	for _, tt := range tests {
+		if got := s.importPathTo(tt.importer, tt.oldImport, tt.oldTarget, tt.newTarget); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func Test_RenameFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"src/Types.sol": `pragma solidity ^0.8.0;
+
+struct Deposit {
+    uint256 amount;
+}
+`,
+		"src/IVault.sol": `pragma solidity ^0.8.0;
+
+import "./Types.sol";
+
+interface IVault {
+    function deposit(Deposit calldata d) external;
+}
+`,
+		"src/Vault.sol": `pragma solidity ^0.8.0;
+
+import "./IVault.sol";
+
+contract Vault is IVault {
+    function deposit(Deposit calldata d) external {}
+}
+`,
+		"test/unit/Vault.t.sol": `pragma solidity ^0.8.0;
+
+import {IVault} from '../../src/IVault.sol';
+
+contract VaultTest {
+    IVault vault;
+}
+`,
+		"lib/adapter/Adapter.sol": `pragma solidity ^0.8.0;
+
+import "../../src/IVault.sol";
+
+contract Adapter {}
+`,
+	}
+	for name, src := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewState()
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	oldURI := PathToURI(filepath.Join(root, "src/IVault.sol"))
+	newURI := PathToURI(filepath.Join(root, "src/interfaces/IVault.sol"))
+	renames := []lsp.FileRename{{OldURI: oldURI, NewURI: newURI}}
+	response, warning := s.WillRenameFiles(lsp.IntID(1), renames)
+	if !strings.Contains(warning, "lib/adapter/Adapter.sol imports src/IVault.sol") {
+		t.Errorf("Expected a warning about the dependency, got %q", warning)
+	}
+	if response.Result == nil {
+		t.Fatalf("Expected the import edits, got none")
+	}
+
+	expected := map[string]string{
+		"src/Vault.sol":         `import "./interfaces/IVault.sol";`,
+		"test/unit/Vault.t.sol": `import {IVault} from '../../src/interfaces/IVault.sol';`,
+		"src/IVault.sol":        `import "../Types.sol";`,
+	}
+	if len(response.Result.Changes) != len(expected) {
+		t.Errorf("Expected edits in %d files, got %v", len(expected), response.Result.Changes)
+	}
+	for name, line := range expected {
+		uri := PathToURI(filepath.Join(root, name))
+		edited := applyEdits(s.Documents[uri], response.Result.Changes[uri])
+		if !strings.Contains(edited, line) {
+			t.Errorf("Expected %s to contain %q, got:\n%s", name, line, edited)
+		}
+		if err := os.WriteFile(URIToPath(uri), []byte(edited), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The client renames the file after applying the edits.
+	if err := os.MkdirAll(filepath.Dir(URIToPath(newURI)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(URIToPath(oldURI), URIToPath(newURI)); err != nil {
+		t.Fatal(err)
+	}
+	changed := s.RenameFiles(renames)
+	if len(changed) != 2 || changed[0] != oldURI || changed[1] != newURI {
+		t.Errorf("Expected the diagnostics of the old and the new URIs to change, got %v", changed)
+	}
+	if _, ok := s.Documents[oldURI]; ok {
+		t.Errorf("Expected the old document to be removed")
+	}
+	for _, doc := range s.sortedDocuments() {
+		if s.isDependency(doc.URI) {
+			continue
+		}
+		if unresolved := s.unresolvedIdentifiers(doc); len(unresolved) > 0 {
+			t.Errorf("Expected no unresolved identifiers in %s, got %s", doc.URI, unresolved[0].Name)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"solbot/lsp"
+	"solbot/parser"
+	"solbot/textedit"
+	"solbot/token"
+)
+
+// OrganizeImportsCode identifies organizing the imports among the fixes,
+// which has no diagnostic of its own.
+const OrganizeImportsCode = "organize-imports"
+
+// Fix is a quick fix of a diagnostic applied without a client, see
+// ApplyFixes.
+type Fix struct {
+	Code  string      // code of the fixed diagnostic
+	Range token.Range // range of the fixed diagnostic
+	Title string
+	Edits []textedit.Edit
+	Safe  bool // false if the detector only suggests the edit, it has to be reviewed
+}
+
+// Fixes returns the quick fixes offered by the code actions for the whole
+// document, together with organizing its imports, ordered by the ranges of
+// the diagnostics. The fixes editing the other documents are left out, and
+// the dependencies are never fixed.
+func (s *State) Fixes(uri string) []Fix {
+	doc, ok := s.document(uri)
+	if !ok || s.isDependency(uri) {
+		return nil
+	}
+	whole := token.Range{Start: 0, End: token.Pos(len(doc.Handle.Src()))}
+	res := []Fix{}
+	for _, action := range s.codeActions(doc, whole) {
+		if action.Edit == nil || len(action.Edit.Changes) != 1 || action.Edit.Changes[uri] == nil {
+			continue
+		}
+		var fix Fix
+		switch {
+		case action.Kind == lsp.CodeActionQuickFix && len(action.Diagnostics) == 1:
+			d := action.Diagnostics[0]
+			fix = Fix{Code: d.Code, Range: toTokenRange(doc.Handle, d.Range), Safe: action.IsPreferred}
+		case action.Kind == lsp.CodeActionSourceOrganizeImports:
+			fix = Fix{Code: OrganizeImportsCode, Safe: true}
+		default:
+			continue
+		}
+		fix.Title = action.Title
+		for _, e := range action.Edit.Changes[uri] {
+			fix.Edits = append(fix.Edits, textedit.Edit{Range: toTokenRange(doc.Handle, e.Range), NewText: e.NewText})
+		}
+		if fix.Code == OrganizeImportsCode && len(fix.Edits) > 0 {
+			fix.Range = fix.Edits[0].Range
+		}
+		res = append(res, fix)
+	}
+	slices.SortStableFunc(res, func(a, b Fix) int { return int(a.Range.Start - b.Range.Start) })
+	return res
+}
+
+// FixResult is the outcome of applying the fixes to a document.
+type FixResult struct {
+	Src       string   // content with the applied fixes; or the original one if they were rolled back
+	Applied   []Fix    // fixes applied to the content
+	Conflicts []Fix    // fixes skipped, since they overlap one applied before
+	Errors    []string // errors introduced by the fixes, which were rolled back
+}
+
+// ApplyFixes applies the fixes to the document, in their order. A fix
+// touching the range edited by one applied before is skipped, since the
+// result would depend on the order.
+//
+// The document is analyzed again afterwards and the fixes are rolled back
+// all together if they introduced new syntax errors or diagnostics of the
+// error severity. Otherwise the document of the state is replaced with the
+// fixed content.
+func (s *State) ApplyFixes(uri string, fixes []Fix) FixResult {
+	doc, ok := s.document(uri)
+	if !ok {
+		return FixResult{}
+	}
+	src := doc.Handle.Src()
+	res := FixResult{Src: src}
+	edits := []textedit.Edit{}
+	for _, fix := range fixes {
+		if textedit.Conflicts(edits, fix.Edits) {
+			res.Conflicts = append(res.Conflicts, fix)
+			continue
+		}
+		edits = append(edits, fix.Edits...)
+		res.Applied = append(res.Applied, fix)
+	}
+	if len(res.Applied) == 0 {
+		return res
+	}
+
+	before := map[string]int{}
+	for _, e := range s.errors(doc) {
+		before[e.message]++
+	}
+	fixed := parsedDocument(uri, doc.Version, doc.Open, textedit.Apply(src, edits), s.parserLimits())
+	s.Documents[uri] = fixed
+	for _, e := range s.errors(fixed) {
+		if before[e.message] > 0 {
+			before[e.message]--
+			continue
+		}
+		position := fixed.Handle.Position(e.pos)
+		res.Errors = append(res.Errors, fmt.Sprintf("%d:%d: %s", position.Line, position.Column, e.message))
+	}
+	if len(res.Errors) > 0 {
+		s.Documents[uri] = doc
+		return res
+	}
+	res.Src = fixed.Handle.Src()
+	return res
+}
+
+type documentError struct {
+	pos     token.Pos
+	message string
+}
+
+// errors returns the syntax errors of the document together with its
+// diagnostics of the error severity.
+func (s *State) errors(doc *Document) []documentError {
+	res := []documentError{}
+	p := parser.Parser{}
+	p.Init(token.NewFile(doc.URI, doc.Handle.Src()))
+	p.ParseFile()
+	for _, e := range p.Errors() {
+		res = append(res, documentError{pos: e.Pos, message: e.Message()})
+	}
+	for _, d := range s.Diagnostics(context.Background(), doc.URI).Params.Diagnostics {
+		if d.Severity == lsp.SeverityError {
+			res = append(res, documentError{pos: toTokenPos(doc.Handle, d.Range.Start), message: d.Message})
+		}
+	}
+	return res
+}
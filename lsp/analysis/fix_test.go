@@ -0,0 +1,75 @@
+package analysis
+
+import (
+	"solbot/textedit"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+const fixSrc = `pragma solidity ^0.8.0;
+
+contract Vault {
+    uint256 total;
+
+    function deposit(uint256 amount) external {
+        total += amount;
+    }
+}
+`
+
+// replace returns the fix replacing the first occurrence of the old text.
+func replace(code, old, new string) Fix {
+	start := token.Pos(strings.Index(fixSrc, old))
+	r := token.Range{Start: start, End: start + token.Pos(len(old))}
+	return Fix{Code: code, Range: r, Title: "Replace " + old, Edits: []textedit.Edit{{Range: r, NewText: new}}, Safe: true}
+}
+
+func Test_ApplyFixesConflict(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, fixSrc)
+
+	fixes := []Fix{
+		replace("decrement", "total += amount", "total -= amount"),
+		replace("increment", "+= amount", "+= amount + 1"),
+		replace("visibility", "uint256 total;", "uint256 public total;"),
+	}
+	res := s.ApplyFixes(uri, fixes)
+	if len(res.Errors) != 0 {
+		t.Fatalf("Expected no errors, got %v", res.Errors)
+	}
+	if len(res.Applied) != 2 || len(res.Conflicts) != 1 || res.Conflicts[0].Code != "increment" {
+		t.Fatalf("Expected the second fix to conflict, got %v applied and %v skipped", res.Applied, res.Conflicts)
+	}
+	expected := strings.NewReplacer("total += amount", "total -= amount", "uint256 total;", "uint256 public total;").Replace(fixSrc)
+	if res.Src != expected {
+		t.Errorf("Expected %q, got %q", expected, res.Src)
+	}
+	if src := s.Documents[uri].Handle.Src(); src != expected {
+		t.Errorf("Expected the document to be fixed, got %q", src)
+	}
+}
+
+func Test_ApplyFixesRollback(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, fixSrc)
+	doc := s.Documents[uri]
+
+	// The rigged detector drops the semicolon.
+	fixes := []Fix{
+		replace("visibility", "uint256 total;", "uint256 public total;"),
+		replace("semicolon", "total += amount;", "total += amount"),
+	}
+	res := s.ApplyFixes(uri, fixes)
+	if len(res.Errors) == 0 {
+		t.Fatalf("Expected the syntax error to be reported")
+	}
+	if !strings.HasPrefix(res.Errors[0], "8:5: ") {
+		t.Errorf("Expected the error at the closing brace, got %q", res.Errors[0])
+	}
+	if res.Src != fixSrc || s.Documents[uri] != doc {
+		t.Errorf("Expected the document to be rolled back, got %q", res.Src)
+	}
+}
@@ -0,0 +1,405 @@
+package analysis
+
+import (
+	"maps"
+	"slices"
+	"solbot/ast"
+	"solbot/token"
+)
+
+// flowGraph is the control flow graph of the body of a function, for the
+// dataflow of its local variables. Its nodes are the simple statements and
+// the conditions of the branches and the loops, so that the compound
+// statements are the edges between them.
+type flowGraph struct {
+	nodes    []*flowNode
+	start    int                     // the first node of the body
+	exit     int                     // the end of the function, returning the named results
+	entry    map[ast.Statement]int   // the first node of the statement
+	next     map[ast.Statement]int   // the node following the statement
+	accessed map[*ast.Identifier]int // the node of every variable access
+}
+
+// flowNode reads and writes the local variables, by their declarations:
+// *ast.VariableDeclaration or *ast.Param.
+type flowNode struct {
+	node  ast.Node // the statement or the expression; or nil for the ends of the function
+	uses  map[ast.Node]bool
+	defs  map[ast.Node]bool
+	succs []int
+}
+
+// variableAccess is a read or a write of a local variable of a function.
+type variableAccess struct {
+	ident   *ast.Identifier
+	decl    ast.Node // *ast.VariableDeclaration or *ast.Param
+	read    bool
+	written bool // assigned as a whole, not e.g. one of its elements
+}
+
+// loopTargets are the nodes the break and the continue statements of a
+// loop jump to.
+type loopTargets struct {
+	breakTo, continueTo int
+}
+
+type flowBuilder struct {
+	graph *flowGraph
+	exit  int // the end of the function, returning the named results
+	end   int // after the reverts and the returns of values, nothing is read
+}
+
+// buildFlowGraph returns the control flow graph of the body of the
+// function, with the accesses of its local variables.
+func buildFlowGraph(fn *ast.FunctionDeclaration, accesses []variableAccess) *flowGraph {
+	g := &flowGraph{entry: map[ast.Statement]int{}, next: map[ast.Statement]int{}, accessed: map[*ast.Identifier]int{}}
+	b := &flowBuilder{graph: g}
+	b.exit = b.add(nil)
+	b.end = b.add(nil)
+	if fn.Type.Results != nil {
+		for _, result := range fn.Type.Results.List {
+			g.nodes[b.exit].uses[result] = true
+		}
+	}
+	g.start, g.exit = b.statement(fn.Body, b.exit, nil), b.exit
+
+	for i, n := range g.nodes {
+		if n.node == nil {
+			continue
+		}
+		r := ast.NodeRange(n.node)
+		for _, access := range accesses {
+			if !r.Contains(access.ident.NamePos) {
+				continue
+			}
+			g.accessed[access.ident] = i
+			if access.read {
+				n.uses[access.decl] = true
+			}
+			if access.written {
+				n.defs[access.decl] = true
+			}
+		}
+	}
+	return g
+}
+
+func (b *flowBuilder) add(node ast.Node, succs ...int) int {
+	b.graph.nodes = append(b.graph.nodes, &flowNode{node: node, uses: map[ast.Node]bool{}, defs: map[ast.Node]bool{}, succs: succs})
+	return len(b.graph.nodes) - 1
+}
+
+// statement adds the nodes of the statement followed by the node next, and
+// returns its first node. The graph is built backwards, so that the
+// successors are known.
+func (b *flowBuilder) statement(stmt ast.Statement, next int, loop *loopTargets) int {
+	entry := next
+	switch n := stmt.(type) {
+	case *ast.BlockStatement:
+		for i := len(n.Statements) - 1; i >= 0; i-- {
+			entry = b.statement(n.Statements[i], entry, loop)
+		}
+	case *ast.UncheckedBlockStatement:
+		entry = b.statement(n.Body, next, loop)
+	case *ast.IfStatement:
+		entry = b.add(n.Condition, b.statement(n.Consequence, next, loop), next)
+		if n.Alternative != nil {
+			b.graph.nodes[entry].succs[1] = b.statement(n.Alternative, next, loop)
+		}
+	case *ast.WhileStatement:
+		entry = b.add(n.Condition)
+		body := b.statement(n.Body, entry, &loopTargets{breakTo: next, continueTo: entry})
+		b.graph.nodes[entry].succs = []int{body, next}
+	case *ast.DoWhileStatement:
+		condition := b.add(n.Condition)
+		entry = b.statement(n.Body, condition, &loopTargets{breakTo: next, continueTo: condition})
+		b.graph.nodes[condition].succs = []int{entry, next}
+	case *ast.ForStatement:
+		var condition, post int
+		if n.Condition != nil {
+			condition = b.add(n.Condition)
+		} else {
+			condition = b.add(nil)
+		}
+		if n.Post != nil {
+			post = b.add(n.Post, condition)
+		} else {
+			post = b.add(nil, condition)
+		}
+		body := b.statement(n.Body, post, &loopTargets{breakTo: next, continueTo: post})
+		b.graph.nodes[condition].succs = []int{body}
+		if n.Condition != nil {
+			b.graph.nodes[condition].succs = append(b.graph.nodes[condition].succs, next)
+		}
+		entry = condition
+		if n.Init != nil {
+			entry = b.statement(n.Init, condition, loop)
+		}
+	case *ast.TryStatement:
+		entry = b.add(n.Expression, b.statement(n.Body, next, loop))
+		node := b.graph.nodes[entry]
+		if n.Returns != nil {
+			for _, p := range n.Returns.List {
+				node.defs[p] = true
+			}
+		}
+		for _, c := range n.Catches {
+			if c.Params != nil {
+				for _, p := range c.Params.List {
+					node.defs[p] = true
+				}
+			}
+			node.succs = append(node.succs, b.statement(c.Body, next, loop))
+		}
+	case *ast.BreakStatement:
+		entry = b.add(n, next)
+		if loop != nil {
+			b.graph.nodes[entry].succs = []int{loop.breakTo}
+		}
+	case *ast.ContinueStatement:
+		entry = b.add(n, next)
+		if loop != nil {
+			b.graph.nodes[entry].succs = []int{loop.continueTo}
+		}
+	case *ast.ReturnStatement:
+		// A return without a value returns the named results.
+		if n.Result == nil {
+			entry = b.add(n, b.exit)
+		} else {
+			entry = b.add(n, b.end)
+		}
+	case *ast.RevertStatement:
+		entry = b.add(n, b.end)
+	case *ast.VariableDeclarationStatement:
+		entry = b.add(n, next)
+		for _, decl := range n.Declarations {
+			if decl != nil {
+				b.graph.nodes[entry].defs[decl] = true
+			}
+		}
+	case nil:
+	default:
+		entry = b.add(stmt, next)
+	}
+	if stmt != nil {
+		b.graph.entry[stmt] = entry
+		b.graph.next[stmt] = next
+	}
+	return entry
+}
+
+// direction is the way the facts of a dataflow analysis flow through the
+// graph.
+type direction int
+
+const (
+	forward  direction = iota // from the start of the body to its ends
+	backward                  // from the ends of the body to its start
+)
+
+// dataflow is an analysis of the flow graph computing the facts F at every
+// node, see solve.
+type dataflow[F any] struct {
+	direction direction
+	boundary  F                            // the facts at the start of the body, or at its ends if backward
+	bottom    func() F                     // the facts before anything is known
+	join      func(x, y F) F               // the facts of the paths meeting at a node
+	transfer  func(n *flowNode, facts F) F // the facts after the node; must not modify facts
+	equal     func(x, y F) bool
+}
+
+// solve returns the facts of the analysis at the start and at the end of
+// every node. The nodes are visited from a worklist until their facts don't
+// change, so the transfer must be monotone and the facts of a finite height
+// e.g. the sets of the variables.
+func solve[F any](g *flowGraph, a dataflow[F]) (start, end []F) {
+	preds := make([][]int, len(g.nodes))
+	succs := make([][]int, len(g.nodes))
+	for i, n := range g.nodes {
+		succs[i] = n.succs
+		for _, succ := range n.succs {
+			preds[succ] = append(preds[succ], i)
+		}
+	}
+	start, end = make([]F, len(g.nodes)), make([]F, len(g.nodes))
+	for i := range g.nodes {
+		start[i], end[i] = a.bottom(), a.bottom()
+	}
+	// The facts flow from the sources of a node into it, and out of it to
+	// its dependents.
+	in, out, sources, dependents := start, end, preds, succs
+	boundary := func(i int) bool { return i == g.start }
+	if a.direction == backward {
+		in, out, sources, dependents = end, start, succs, preds
+		boundary = func(i int) bool { return len(g.nodes[i].succs) == 0 }
+	}
+
+	queue := make([]int, len(g.nodes))
+	queued := make([]bool, len(g.nodes))
+	for i := range queue {
+		queue[i], queued[i] = i, true
+	}
+	if a.direction == backward {
+		slices.Reverse(queue)
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue, queued[i] = queue[1:], false
+		facts := a.bottom()
+		if boundary(i) {
+			facts = a.join(facts, a.boundary)
+		}
+		for _, source := range sources[i] {
+			facts = a.join(facts, out[source])
+		}
+		in[i] = facts
+		facts = a.transfer(g.nodes[i], facts)
+		if a.equal(facts, out[i]) {
+			continue
+		}
+		out[i] = facts
+		for _, dependent := range dependents[i] {
+			if !queued[dependent] {
+				queue, queued[dependent] = append(queue, dependent), true
+			}
+		}
+	}
+	return start, end
+}
+
+// variables is a set of the variables, by their declarations.
+type variables map[ast.Node]bool
+
+func unionVariables(x, y variables) variables {
+	res := maps.Clone(x)
+	maps.Copy(res, y)
+	return res
+}
+
+// liveness returns the variables live at the start and at the end of every
+// node: read later on some path without being written before.
+func (g *flowGraph) liveness() (start, end []variables) {
+	return solve(g, dataflow[variables]{
+		direction: backward,
+		boundary:  variables{},
+		bottom:    func() variables { return variables{} },
+		join:      unionVariables,
+		transfer: func(n *flowNode, live variables) variables {
+			res := variables{}
+			for decl := range live {
+				if !n.defs[decl] {
+					res[decl] = true
+				}
+			}
+			for decl := range n.uses {
+				res[decl] = true
+			}
+			return res
+		},
+		equal: maps.Equal[variables, variables],
+	})
+}
+
+// reachable returns whether every node is reached from the start of the
+// body e.g. not the statements after a return.
+func (g *flowGraph) reachable() []bool {
+	reached, _ := solve(g, dataflow[bool]{
+		direction: forward,
+		boundary:  true,
+		bottom:    func() bool { return false },
+		join:      func(x, y bool) bool { return x || y },
+		transfer:  func(_ *flowNode, reached bool) bool { return reached },
+		equal:     func(x, y bool) bool { return x == y },
+	})
+	return reached
+}
+
+// variableAccesses returns the reads and the writes of the local variables
+// of the function, including its parameters and its named results, in the
+// order of their positions. The variables of the inline assembly are
+// assumed to be both read and written.
+func (s *State) variableAccesses(doc *Document, fn *ast.FunctionDeclaration) []variableAccess {
+	res := []variableAccess{}
+	r := ast.NodeRange(fn)
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		if !r.Contains(ident.NamePos) {
+			return
+		}
+		sym := s.resolve(doc, path)
+		if sym == nil || sym.Doc != doc || sym.Name == ident || !r.Contains(sym.Name.NamePos) {
+			return
+		}
+		switch sym.Node.(type) {
+		case *ast.VariableDeclaration, *ast.Param:
+		default:
+			return
+		}
+		access := variableAccess{ident: ident, decl: sym.Node}
+		if _, ok := path[1].(*ast.AssemblyStatement); ok {
+			access.read, access.written = true, true
+			res = append(res, access)
+			return
+		}
+		top, written := writtenAt(path)
+		access.written = written && top == 0
+		access.read = !access.written || readsWritten(path)
+		res = append(res, access)
+	})
+	return res
+}
+
+// stateVariableAccesses returns the reads and the writes of the state
+// variables of the value types in the function, by their names, in the
+// order of their positions. The storage pointers can't alias them, but the
+// inline assembly and the calls may access them, see deadStores.
+func (s *State) stateVariableAccesses(doc *Document, fn *ast.FunctionDeclaration) []variableAccess {
+	res := []variableAccess{}
+	r := ast.NodeRange(fn)
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		if !r.Contains(ident.NamePos) {
+			return
+		}
+		switch parent := path[1].(type) {
+		case *ast.MemberAccessExpression:
+			if parent.Member == ident {
+				return
+			}
+		case *ast.AssemblyStatement:
+			return
+		}
+		sym := s.resolve(doc, path)
+		if sym == nil || sym.Name == ident {
+			return
+		}
+		decl, ok := sym.Node.(*ast.VariableDeclaration)
+		if !ok || decl.Constant || decl.Immutable || s.declaringContract(sym) == nil {
+			return
+		}
+		if _, ok := decl.Type.(*ast.ElementaryType); !ok {
+			return
+		}
+		access := variableAccess{ident: ident, decl: decl}
+		top, written := writtenAt(path)
+		access.written = written && top == 0
+		access.read = !access.written || readsWritten(path)
+		res = append(res, access)
+	})
+	return res
+}
+
+// readsWritten reports whether the variable at path[0] is read before it's
+// written e.g. by `x += 1` or `x++`, but not by `x = 1` or `delete x`.
+func readsWritten(path []ast.Node) bool {
+	if unary, ok := path[1].(*ast.UnaryExpression); ok {
+		return unary.Operator != token.DELETE
+	}
+	i := 1
+	for i < len(path) {
+		if _, ok := path[i].(*ast.TupleExpression); !ok {
+			break
+		}
+		i++
+	}
+	assign, ok := path[i].(*ast.AssignmentExpression)
+	return ok && assign.Operator != token.ASSIGN
+}
@@ -0,0 +1,274 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+)
+
+//go:generate go run solbot/internal/foundrygen -in foundry.json -out foundry_gen.go
+
+// The Foundry tests call the cheatcodes on `vm` e.g. `vm.prank(alice)` and
+// log with `console.log`. They're declared in lib/forge-std, which is not
+// there until the dependencies are installed, and resolving it only to
+// hover `vm.warp` is slow, so the tests fall back on the table of them
+// built into the server, see foundryLibraries. The real declarations come
+// first where they resolve, the table only adds what they're missing.
+
+// foundryLibrary is a contract of forge-std in the table.
+type foundryLibrary struct {
+	name       string   // e.g. "Vm"
+	receivers  []string // names the functions are called on e.g. "vm"
+	contracts  []string // names of the real declarations e.g. "Vm" and "VmSafe"
+	visibility string   // "external" or "internal"
+	functions  []foundryFunction
+}
+
+// foundryFunction is an overload of a function of a foundryLibrary.
+type foundryFunction struct {
+	name       string
+	params     []string // e.g. "address msgSender"
+	returns    []string // e.g. "uint256 forkId"
+	mutability string   // "pure", "view" or empty
+	doc        string
+}
+
+// header returns the declaration of the function e.g.
+// `function prank(address msgSender) external`, and the offsets of the
+// parameters in it.
+func (l *foundryLibrary) header(fn foundryFunction) (string, [][2]int) {
+	header := "function " + fn.name + "("
+	offsets := [][2]int{}
+	for i, param := range fn.params {
+		if i > 0 {
+			header += ", "
+		}
+		offsets = append(offsets, [2]int{len(header), len(header) + len(param)})
+		header += param
+	}
+	header += ") " + l.visibility
+	if fn.mutability != "" {
+		header += " " + fn.mutability
+	}
+	if len(fn.returns) > 0 {
+		header += " returns (" + strings.Join(fn.returns, ", ") + ")"
+	}
+	return header, offsets
+}
+
+// overloads returns the functions of the name, in the order of the table.
+func (l *foundryLibrary) overloads(name string) []foundryFunction {
+	res := []foundryFunction{}
+	for _, fn := range l.functions {
+		if fn.name == name {
+			res = append(res, fn)
+		}
+	}
+	return res
+}
+
+// paramTypes returns the types of the parameters of the function e.g.
+// "address,uint256", the same as the ones of the real declarations, see
+// declaredParamTypes.
+func (fn foundryFunction) paramTypes() string {
+	types := []string{}
+	for _, param := range fn.params {
+		types = append(types, strings.Fields(param)[0])
+	}
+	return strings.Join(types, ",")
+}
+
+// declaredParamTypes returns the types of the parameters of the declared
+// function e.g. "address,uint256".
+func declaredParamTypes(fn *ast.FunctionDeclaration) string {
+	types := []string{}
+	if fn.Type.Params != nil {
+		for _, param := range fn.Type.Params.List {
+			types = append(types, ast.ExprString(param.Type))
+		}
+	}
+	return strings.Join(types, ",")
+}
+
+// isFoundryTest reports whether the document is a Foundry test or script:
+// it's in the test directory of the project or named e.g. Vault.t.sol, it
+// imports forge-std, or one of its contracts inherits from `Test`.
+func (s *State) isFoundryTest(doc *Document) bool {
+	rel := s.RelativePath(doc.URI)
+	if strings.HasPrefix(rel, path.Clean(s.Config.Test)+"/") || strings.HasSuffix(rel, ".t.sol") || strings.HasSuffix(rel, ".s.sol") {
+		return true
+	}
+	for _, decl := range doc.File.Declarations {
+		switch n := decl.(type) {
+		case *ast.ImportDirective:
+			if n.Path != nil && strings.HasPrefix(strings.Trim(n.Path.Value, `"'`), "forge-std/") {
+				return true
+			}
+		case *ast.ContractDeclaration:
+			for _, base := range n.Bases {
+				if ast.ExprString(base.Name) == "Test" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// foundryLibraryOf returns the library of the table whose functions are
+// called on the receiver in the Foundry test: the one the real declaration
+// of the receiver is, if it resolves e.g. `Vm`, or the one of the name of
+// the receiver e.g. `vm`; or nil if there's none.
+func (s *State) foundryLibraryOf(doc *Document, receiver string, scope *Symbol) *foundryLibrary {
+	if !s.isFoundryTest(doc) {
+		return nil
+	}
+	for i := range foundryLibraries {
+		lib := &foundryLibraries[i]
+		if scope == nil && slices.Contains(lib.receivers, receiver) {
+			return lib
+		}
+		if scope != nil {
+			if c, ok := scope.Node.(*ast.ContractDeclaration); ok && slices.Contains(lib.contracts, c.Name.Name) {
+				return lib
+			}
+		}
+	}
+	return nil
+}
+
+// completions returns the functions of the library not declared in
+// the real one, an item for every name with the header of its first
+// overload.
+func (l *foundryLibrary) completions(declared []*Symbol) []lsp.CompletionItem {
+	items := []lsp.CompletionItem{}
+	seen := map[string]bool{}
+	for _, sym := range declared {
+		seen[sym.Name.Name] = true
+	}
+	for _, fn := range l.functions {
+		if seen[fn.name] {
+			continue
+		}
+		seen[fn.name] = true
+		detail, _ := l.header(fn)
+		if n := len(l.overloads(fn.name)) - 1; n == 1 {
+			detail += " (+1 overload)"
+		} else if n > 1 {
+			detail += fmt.Sprintf(" (+%d overloads)", n)
+		}
+		items = append(items, lsp.CompletionItem{
+			Label:         fn.name,
+			Kind:          lsp.CompletionItemFunction,
+			Detail:        detail,
+			Documentation: &lsp.MarkupContent{Kind: lsp.PlainText, Value: fn.doc},
+		})
+	}
+	return items
+}
+
+// signatures returns the signatures of the overloads of the function
+// missing from the real declarations, if any.
+func (l *foundryLibrary) signatures(name string, declared []*ast.FunctionDeclaration) []lsp.SignatureInformation {
+	res := []lsp.SignatureInformation{}
+	for _, fn := range l.overloads(name) {
+		if slices.ContainsFunc(declared, func(d *ast.FunctionDeclaration) bool {
+			return declaredParamTypes(d) == fn.paramTypes()
+		}) {
+			continue
+		}
+		label, offsets := l.header(fn)
+		info := lsp.SignatureInformation{
+			Label:         label,
+			Documentation: &lsp.MarkupContent{Kind: lsp.PlainText, Value: fn.doc},
+			Parameters:    []lsp.ParameterInformation{},
+		}
+		for _, offset := range offsets {
+			info.Parameters = append(info.Parameters, lsp.ParameterInformation{Label: offset})
+		}
+		res = append(res, info)
+	}
+	return res
+}
+
+// foundryReceiver returns the library and the receiver scope of the
+// member access in a Foundry test e.g. `vm.prank`; or nil if the
+// expression before the period is not one of the receivers of the
+// table. The scope is nil if the receiver doesn't resolve.
+func (s *State) foundryReceiver(doc *Document, path []ast.Node, access *ast.MemberAccessExpression) (*foundryLibrary, *Symbol) {
+	receiver, ok := access.Expression.(*ast.Identifier)
+	if !ok {
+		return nil, nil
+	}
+	scope := s.typeScope(s.lookup(doc, path, receiver.Name, receiver.Start()))
+	return s.foundryLibraryOf(doc, receiver.Name, scope), scope
+}
+
+// foundryHover shows the overloads of the function of the table at the
+// position which has no real declaration e.g. `warp` in `vm.warp(1)`
+// without forge-std; or returns an empty string.
+func (s *State) foundryHover(uri string, position lsp.Position, markdown bool) string {
+	doc, ok := s.document(uri)
+	if !ok {
+		return ""
+	}
+	path := ast.PathEnclosingPos(doc.File, toTokenPos(doc.Handle, position))
+	if len(path) < 2 {
+		return ""
+	}
+	access, ok := path[1].(*ast.MemberAccessExpression)
+	if !ok || access.Member != path[0] {
+		return ""
+	}
+	lib, _ := s.foundryReceiver(doc, path[2:], access)
+	if lib == nil {
+		return ""
+	}
+	overloads := lib.overloads(access.Member.Name)
+	if len(overloads) == 0 {
+		return ""
+	}
+	headers, docs := []string{}, []string{}
+	for _, fn := range overloads {
+		header, _ := lib.header(fn)
+		headers = append(headers, header)
+		if !slices.Contains(docs, fn.doc) {
+			docs = append(docs, fn.doc)
+		}
+	}
+	if markdown {
+		return fmt.Sprintf("```solidity\n%s\n```\n\n%s\n\nmember of `%s` (built in)", strings.Join(headers, "\n"), strings.Join(docs, "\n\n"), lib.name)
+	}
+	return fmt.Sprintf("%s\n\n%s\n\nmember of %s (built in)", strings.Join(headers, "\n"), strings.Join(docs, "\n\n"), lib.name)
+}
+
+// foundryDoc returns the documentation of the table for the real
+// declaration of a function of forge-std e.g. `Vm.warp`, the overload
+// with the same parameter types; or an empty string.
+func (s *State) foundryDoc(uri string, sym *Symbol) string {
+	fn, ok := sym.Node.(*ast.FunctionDeclaration)
+	if !ok || fn.Name == nil {
+		return ""
+	}
+	doc, ok := s.document(uri)
+	if !ok {
+		return ""
+	}
+	c := enclosingContract(sym.Doc, ast.PathEnclosingPos(sym.Doc.File, fn.Start()))
+	if c == nil {
+		return ""
+	}
+	lib := s.foundryLibraryOf(doc, "", c)
+	if lib == nil {
+		return ""
+	}
+	for _, overload := range lib.overloads(fn.Name.Name) {
+		if overload.paramTypes() == declaredParamTypes(fn) {
+			return overload.doc
+		}
+	}
+	return ""
+}
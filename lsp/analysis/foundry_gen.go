@@ -0,0 +1,106 @@
+// Code generated by foundrygen from foundry.json; DO NOT EDIT.
+
+package analysis
+
+// foundryLibraries are the cheatcodes and the logging functions of forge-std, see foundry.json.
+var foundryLibraries = []foundryLibrary{
+	{
+		name:       "Vm",
+		receivers:  []string{"vm"},
+		contracts:  []string{"Vm", "VmSafe"},
+		visibility: "external",
+		functions: []foundryFunction{
+			{name: "accesses", params: []string{"address target"}, returns: []string{"bytes32[] memory readSlots", "bytes32[] memory writeSlots"}, doc: "Gets all the storage slots of the target read and written since `record` was called."},
+			{name: "addr", params: []string{"uint256 privateKey"}, returns: []string{"address keyAddr"}, mutability: "pure", doc: "Computes the address of the private key."},
+			{name: "allowCheatcodes", params: []string{"address account"}, doc: "Lets the account call the cheatcodes on a fork."},
+			{name: "assume", params: []string{"bool condition"}, mutability: "pure", doc: "Discards the fuzzer inputs for which the condition is false."},
+			{name: "broadcast", doc: "Signs the next call made from the script and sends it on-chain."},
+			{name: "broadcast", params: []string{"address signer"}, doc: "Signs the next call made from the script with the signer and sends it on-chain."},
+			{name: "broadcast", params: []string{"uint256 privateKey"}, doc: "Signs the next call made from the script with the private key and sends it on-chain."},
+			{name: "chainId", params: []string{"uint256 newChainId"}, doc: "Sets `block.chainid`."},
+			{name: "clearMockedCalls", doc: "Clears all the mocked calls."},
+			{name: "coinbase", params: []string{"address newCoinbase"}, doc: "Sets `block.coinbase`."},
+			{name: "createFork", params: []string{"string calldata urlOrAlias"}, returns: []string{"uint256 forkId"}, doc: "Creates a fork of the chain at the RPC URL or the alias from foundry.toml, without selecting it."},
+			{name: "createFork", params: []string{"string calldata urlOrAlias", "uint256 blockNumber"}, returns: []string{"uint256 forkId"}, doc: "Creates a fork of the chain at the block, without selecting it."},
+			{name: "createSelectFork", params: []string{"string calldata urlOrAlias"}, returns: []string{"uint256 forkId"}, doc: "Creates a fork of the chain at the RPC URL or the alias from foundry.toml and selects it."},
+			{name: "createSelectFork", params: []string{"string calldata urlOrAlias", "uint256 blockNumber"}, returns: []string{"uint256 forkId"}, doc: "Creates a fork of the chain at the block and selects it."},
+			{name: "deal", params: []string{"address account", "uint256 newBalance"}, doc: "Sets the ether balance of the account."},
+			{name: "envAddress", params: []string{"string calldata name"}, returns: []string{"address value"}, mutability: "view", doc: "Reads the environment variable as an address."},
+			{name: "envBool", params: []string{"string calldata name"}, returns: []string{"bool value"}, mutability: "view", doc: "Reads the environment variable as a boolean."},
+			{name: "envString", params: []string{"string calldata name"}, returns: []string{"string memory value"}, mutability: "view", doc: "Reads the environment variable as a string."},
+			{name: "envUint", params: []string{"string calldata name"}, returns: []string{"uint256 value"}, mutability: "view", doc: "Reads the environment variable as an unsigned integer."},
+			{name: "etch", params: []string{"address target", "bytes calldata newRuntimeBytecode"}, doc: "Sets the runtime bytecode of the target."},
+			{name: "expectCall", params: []string{"address callee", "bytes calldata data"}, doc: "Expects a call to the callee with the calldata, or its prefix, before the end of the test."},
+			{name: "expectCall", params: []string{"address callee", "uint256 msgValue", "bytes calldata data"}, doc: "Expects a call to the callee with the value and the calldata before the end of the test."},
+			{name: "expectEmit", doc: "Expects the next emitted event to have the same topics and data as the one emitted right after this call."},
+			{name: "expectEmit", params: []string{"address emitter"}, doc: "Expects the next event emitted by the emitter to match the one emitted right after this call."},
+			{name: "expectEmit", params: []string{"bool checkTopic1", "bool checkTopic2", "bool checkTopic3", "bool checkData"}, doc: "Expects the next emitted event to match the one emitted right after this call, in the checked topics and data."},
+			{name: "expectEmit", params: []string{"bool checkTopic1", "bool checkTopic2", "bool checkTopic3", "bool checkData", "address emitter"}, doc: "Expects the next event emitted by the emitter to match the one emitted right after this call, in the checked topics and data."},
+			{name: "expectRevert", doc: "Expects the next call to revert."},
+			{name: "expectRevert", params: []string{"bytes4 revertData"}, doc: "Expects the next call to revert with the custom error selector."},
+			{name: "expectRevert", params: []string{"bytes calldata revertData"}, doc: "Expects the next call to revert with the data e.g. `abi.encodeWithSelector(Vault.Paused.selector)` or a reason string."},
+			{name: "fee", params: []string{"uint256 newBasefee"}, doc: "Sets `block.basefee`."},
+			{name: "ffi", params: []string{"string[] calldata commandInput"}, returns: []string{"bytes memory result"}, doc: "Runs the command and returns its output; needs `ffi = true` in foundry.toml."},
+			{name: "getCode", params: []string{"string calldata artifactPath"}, returns: []string{"bytes memory creationBytecode"}, mutability: "view", doc: "Gets the creation bytecode of the artifact e.g. \"Vault.sol:Vault\"."},
+			{name: "getNonce", params: []string{"address account"}, returns: []string{"uint64 nonce"}, mutability: "view", doc: "Gets the nonce of the account."},
+			{name: "label", params: []string{"address account", "string calldata newLabel"}, doc: "Names the address in the traces."},
+			{name: "load", params: []string{"address target", "bytes32 slot"}, returns: []string{"bytes32 data"}, mutability: "view", doc: "Reads the storage slot of the target."},
+			{name: "mockCall", params: []string{"address callee", "bytes calldata data", "bytes calldata returnData"}, doc: "Returns the data from the calls to the callee with the calldata, or its prefix, instead of calling it."},
+			{name: "mockCall", params: []string{"address callee", "uint256 msgValue", "bytes calldata data", "bytes calldata returnData"}, doc: "Returns the data from the calls to the callee with the value and the calldata instead of calling it."},
+			{name: "prank", params: []string{"address msgSender"}, doc: "Sets `msg.sender` of the next call to the address."},
+			{name: "prank", params: []string{"address msgSender", "address txOrigin"}, doc: "Sets `msg.sender` and `tx.origin` of the next call to the addresses."},
+			{name: "prevrandao", params: []string{"bytes32 newPrevrandao"}, doc: "Sets `block.prevrandao`."},
+			{name: "readFile", params: []string{"string calldata path"}, returns: []string{"string memory data"}, mutability: "view", doc: "Reads the file, relative to the project root; needs `fs_permissions` in foundry.toml."},
+			{name: "record", doc: "Starts recording the storage reads and writes, see `accesses`."},
+			{name: "recordLogs", doc: "Starts recording the emitted events, see `getRecordedLogs`."},
+			{name: "revertTo", params: []string{"uint256 snapshotId"}, returns: []string{"bool success"}, doc: "Reverts the state of the chain to the snapshot."},
+			{name: "roll", params: []string{"uint256 newHeight"}, doc: "Sets `block.number`."},
+			{name: "selectFork", params: []string{"uint256 forkId"}, doc: "Selects the fork created before."},
+			{name: "setNonce", params: []string{"address account", "uint64 newNonce"}, doc: "Sets the nonce of the account, which can only grow."},
+			{name: "sign", params: []string{"uint256 privateKey", "bytes32 digest"}, returns: []string{"uint8 v", "bytes32 r", "bytes32 s"}, mutability: "pure", doc: "Signs the digest with the private key."},
+			{name: "skip", params: []string{"bool skipTest"}, doc: "Marks the test as skipped."},
+			{name: "snapshot", returns: []string{"uint256 snapshotId"}, doc: "Takes a snapshot of the state of the chain, see `revertTo`."},
+			{name: "startBroadcast", doc: "Signs all the following calls made from the script and sends them on-chain."},
+			{name: "startBroadcast", params: []string{"address signer"}, doc: "Signs all the following calls made from the script with the signer and sends them on-chain."},
+			{name: "startBroadcast", params: []string{"uint256 privateKey"}, doc: "Signs all the following calls made from the script with the private key and sends them on-chain."},
+			{name: "startPrank", params: []string{"address msgSender"}, doc: "Sets `msg.sender` of all the following calls to the address, until `stopPrank`."},
+			{name: "startPrank", params: []string{"address msgSender", "address txOrigin"}, doc: "Sets `msg.sender` and `tx.origin` of all the following calls to the addresses, until `stopPrank`."},
+			{name: "stopBroadcast", doc: "Stops sending the calls on-chain."},
+			{name: "stopPrank", doc: "Resets `msg.sender` and `tx.origin` of the following calls."},
+			{name: "store", params: []string{"address target", "bytes32 slot", "bytes32 value"}, doc: "Writes the storage slot of the target."},
+			{name: "toString", params: []string{"address value"}, returns: []string{"string memory stringifiedValue"}, mutability: "pure", doc: "Converts the address to its checksummed hex string."},
+			{name: "toString", params: []string{"uint256 value"}, returns: []string{"string memory stringifiedValue"}, mutability: "pure", doc: "Converts the integer to its decimal string."},
+			{name: "txGasPrice", params: []string{"uint256 newGasPrice"}, doc: "Sets `tx.gasprice`."},
+			{name: "warp", params: []string{"uint256 newTimestamp"}, doc: "Sets `block.timestamp`."},
+		},
+	},
+	{
+		name:       "console",
+		receivers:  []string{"console", "console2"},
+		contracts:  []string{"console", "console2"},
+		visibility: "internal",
+		functions: []foundryFunction{
+			{name: "log", mutability: "pure", doc: "Prints an empty line in the test traces."},
+			{name: "log", params: []string{"string memory p0"}, mutability: "pure", doc: "Prints the string; it can be a format string e.g. \"%s is %d\"."},
+			{name: "log", params: []string{"uint256 p0"}, mutability: "pure", doc: "Prints the unsigned integer."},
+			{name: "log", params: []string{"int256 p0"}, mutability: "pure", doc: "Prints the signed integer."},
+			{name: "log", params: []string{"bool p0"}, mutability: "pure", doc: "Prints the boolean."},
+			{name: "log", params: []string{"address p0"}, mutability: "pure", doc: "Prints the address."},
+			{name: "log", params: []string{"string memory p0", "uint256 p1"}, mutability: "pure", doc: "Prints the format string with the unsigned integer, or the two values separated by a space."},
+			{name: "log", params: []string{"string memory p0", "int256 p1"}, mutability: "pure", doc: "Prints the format string with the signed integer, or the two values separated by a space."},
+			{name: "log", params: []string{"string memory p0", "string memory p1"}, mutability: "pure", doc: "Prints the format string with the string, or the two values separated by a space."},
+			{name: "log", params: []string{"string memory p0", "bool p1"}, mutability: "pure", doc: "Prints the format string with the boolean, or the two values separated by a space."},
+			{name: "log", params: []string{"string memory p0", "address p1"}, mutability: "pure", doc: "Prints the format string with the address, or the two values separated by a space."},
+			{name: "log", params: []string{"string memory p0", "uint256 p1", "uint256 p2"}, mutability: "pure", doc: "Prints the format string with the two unsigned integers, or the values separated by spaces."},
+			{name: "log", params: []string{"string memory p0", "address p1", "uint256 p2"}, mutability: "pure", doc: "Prints the format string with the address and the unsigned integer, or the values separated by spaces."},
+			{name: "log", params: []string{"string memory p0", "string memory p1", "uint256 p2"}, mutability: "pure", doc: "Prints the format string with the string and the unsigned integer, or the values separated by spaces."},
+			{name: "logAddress", params: []string{"address p0"}, mutability: "pure", doc: "Prints the address."},
+			{name: "logBool", params: []string{"bool p0"}, mutability: "pure", doc: "Prints the boolean."},
+			{name: "logBytes", params: []string{"bytes memory p0"}, mutability: "pure", doc: "Prints the bytes in hex."},
+			{name: "logBytes32", params: []string{"bytes32 p0"}, mutability: "pure", doc: "Prints the 32 bytes in hex."},
+			{name: "logInt", params: []string{"int256 p0"}, mutability: "pure", doc: "Prints the signed integer."},
+			{name: "logString", params: []string{"string memory p0"}, mutability: "pure", doc: "Prints the string."},
+			{name: "logUint", params: []string{"uint256 p0"}, mutability: "pure", doc: "Prints the unsigned integer."},
+		},
+	},
+}
@@ -0,0 +1,213 @@
+package analysis
+
+import (
+	"encoding/json"
+	"os"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const vaultTestSrc = `pragma solidity ^0.8.20;
+
+import {Test, console} from "forge-std/Test.sol";
+
+contract VaultTest is Test {
+    function test_withdraw() public {
+        vm.warp(1 days);
+        vm.expectRevert(bytes4(0x1234));
+        console.log("balance", 1);
+        vm.
+    }
+}
+`
+
+func completionItem(items []lsp.CompletionItem, label string) (lsp.CompletionItem, bool) {
+	for _, item := range items {
+		if item.Label == label {
+			return item, true
+		}
+	}
+	return lsp.CompletionItem{}, false
+}
+
+func Test_FoundryCompletionWithoutForgeStd(t *testing.T) {
+	uri := "file:///ws/test/Vault.t.sol"
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument(uri, 1, vaultTestSrc)
+
+	items := s.Completion(lsp.IntID(1), uri, lsp.Position{Line: 9, Character: 11}).Result
+	prank, ok := completionItem(items, "prank")
+	if !ok {
+		t.Fatalf("Expected `prank` in the completions, got %v", items)
+	}
+	if prank.Detail != "function prank(address msgSender) external (+1 overload)" || prank.Kind != lsp.CompletionItemFunction {
+		t.Errorf("Expected the signature of `prank`, got %q", prank.Detail)
+	}
+	if prank.Documentation == nil || prank.Documentation.Value != "Sets `msg.sender` of the next call to the address." {
+		t.Errorf("Expected the documentation of `prank`, got %v", prank.Documentation)
+	}
+	if _, ok := completionItem(items, "log"); ok {
+		t.Errorf("Expected only the cheatcodes after `vm.`, got `log`")
+	}
+	names := map[string]bool{}
+	for _, item := range items {
+		if names[item.Label] {
+			t.Errorf("Expected every name once, got %s twice", item.Label)
+		}
+		names[item.Label] = true
+	}
+
+	items = s.Completion(lsp.IntID(2), uri, lsp.Position{Line: 8, Character: 16}).Result
+	if log, ok := completionItem(items, "log"); !ok || log.Detail != "function log() internal pure (+13 overloads)" {
+		t.Errorf("Expected `log` after `console.`, got %v", items)
+	}
+
+	// The same code outside of the tests is not a Foundry test.
+	uri = "file:///ws/src/Vault.sol"
+	s.OpenDocument(uri, 1, strings.Replace(vaultTestSrc, `import {Test, console} from "forge-std/Test.sol";`, "", 1))
+	s.UpdateDocument(uri, 2, strings.Replace(s.Documents[uri].Handle.Src(), "is Test ", "", 1))
+	if items := s.Completion(lsp.IntID(3), uri, lsp.Position{Line: 9, Character: 11}).Result; len(items) != 0 {
+		t.Errorf("Expected no completions outside of the tests, got %v", items)
+	}
+}
+
+func Test_FoundryHoverWithoutForgeStd(t *testing.T) {
+	uri := "file:///ws/test/Vault.t.sol"
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument(uri, 1, vaultTestSrc)
+
+	hover := s.Hover(lsp.IntID(1), uri, lsp.Position{Line: 6, Character: 12}).Result.Contents.Value
+	expected := "```solidity\nfunction warp(uint256 newTimestamp) external\n```\n\nSets `block.timestamp`.\n\nmember of `Vm` (built in)"
+	if hover != expected {
+		t.Errorf("Expected the hover of `warp`:\n%s\ngot:\n%s", expected, hover)
+	}
+}
+
+func Test_FoundrySignatureHelpOverloads(t *testing.T) {
+	uri := "file:///ws/test/Vault.t.sol"
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument(uri, 1, vaultTestSrc)
+
+	help := s.SignatureHelp(lsp.IntID(1), uri, lsp.Position{Line: 7, Character: 24}).Result
+	if help == nil || len(help.Signatures) != 3 {
+		t.Fatalf("Expected the 3 overloads of `expectRevert`, got %v", help)
+	}
+	if help.ActiveSignature != 1 || help.Signatures[1].Label != "function expectRevert(bytes4 revertData) external" {
+		t.Errorf("Expected the overload taking an argument to be active, got %d: %v", help.ActiveSignature, help.Signatures)
+	}
+	label := help.Signatures[1].Label
+	if p := help.Signatures[1].Parameters; len(p) != 1 || label[p[0].Label[0]:p[0].Label[1]] != "bytes4 revertData" {
+		t.Errorf("Expected the parameter `bytes4 revertData`, got %v", p)
+	}
+}
+
+func Test_FoundryMergedWithForgeStd(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument("file:///ws/lib/forge-std/src/Vm.sol", 1, `pragma solidity ^0.8.20;
+
+interface Vm {
+    function warp(uint256 newTimestamp) external;
+    function prank(address sender) external;
+    function pauseTracing() external view;
+}
+`)
+	uri := "file:///ws/test/Vault.t.sol"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.20;
+
+import {Vm} from "../lib/forge-std/src/Vm.sol";
+
+contract VaultTest {
+    Vm constant vm = Vm(address(0));
+
+    function test_withdraw() public {
+        vm.warp(1 days);
+        vm.prank(address(this));
+        vm.roll(2);
+        vm.
+    }
+}
+`)
+
+	items := s.Completion(lsp.IntID(1), uri, lsp.Position{Line: 11, Character: 11}).Result
+	if item, ok := completionItem(items, "pauseTracing"); !ok || item.Detail != "function pauseTracing() external view" {
+		t.Errorf("Expected the real `pauseTracing`, got %v", item)
+	}
+	if item, ok := completionItem(items, "prank"); !ok || item.Detail != "function prank(address sender) external" {
+		t.Errorf("Expected the real `prank` not overridden, got %q", item.Detail)
+	}
+	if _, ok := completionItem(items, "deal"); !ok {
+		t.Errorf("Expected `deal` missing from the real Vm to be added, got %v", items)
+	}
+	count := 0
+	for _, item := range items {
+		if item.Label == "warp" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected `warp` once, got %d times", count)
+	}
+
+	hover := s.Hover(lsp.IntID(2), uri, lsp.Position{Line: 8, Character: 12}).Result.Contents.Value
+	if !strings.HasPrefix(hover, "```solidity\nfunction warp(uint256 newTimestamp) external\n```") || !strings.Contains(hover, "\n\nSets `block.timestamp`.") {
+		t.Errorf("Expected the real declaration of `warp` with the builtin documentation, got:\n%s", hover)
+	}
+	hover = s.Hover(lsp.IntID(3), uri, lsp.Position{Line: 10, Character: 12}).Result.Contents.Value
+	if !strings.Contains(hover, "function roll(uint256 newHeight) external") {
+		t.Errorf("Expected the builtin `roll` missing from the real Vm, got:\n%s", hover)
+	}
+
+	help := s.SignatureHelp(lsp.IntID(4), uri, lsp.Position{Line: 9, Character: 17}).Result
+	// The builtin `prank(address)` is the real one.
+	if help == nil || len(help.Signatures) != 2 {
+		t.Fatalf("Expected the real `prank` and the builtin overload, got %v", help)
+	}
+	labels := []string{}
+	for _, info := range help.Signatures {
+		labels = append(labels, info.Label)
+	}
+	expected := "function prank(address sender) external; function prank(address msgSender, address txOrigin) external"
+	if strings.Join(labels, "; ") != expected {
+		t.Errorf("Expected the signatures %s, got %s", expected, strings.Join(labels, "; "))
+	}
+}
+
+func Test_FoundryTableMatchesManifest(t *testing.T) {
+	src, err := os.ReadFile("foundry.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := []struct {
+		Name      string
+		Functions []struct {
+			Name string
+			Doc  string
+		}
+	}{}
+	if err := json.Unmarshal(src, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != len(foundryLibraries) {
+		t.Fatalf("Expected %d libraries, got %d; run go generate", len(manifest), len(foundryLibraries))
+	}
+	for i, lib := range manifest {
+		if len(lib.Functions) != len(foundryLibraries[i].functions) {
+			t.Errorf("Expected %d functions of %s, got %d; run go generate", len(lib.Functions), lib.Name, len(foundryLibraries[i].functions))
+			continue
+		}
+		docs := map[string]bool{}
+		for _, fn := range foundryLibraries[i].functions {
+			docs[fn.name+fn.doc] = true
+		}
+		for _, fn := range lib.Functions {
+			if !docs[fn.Name+fn.Doc] {
+				t.Errorf("Expected %s.%s documented as %q; run go generate", lib.Name, fn.Name, fn.Doc)
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/parser"
+	"solbot/token"
+	"strings"
+)
+
+// Fragment is a piece of code checked without the surrounding contract,
+// see CheckFragment.
+type Fragment struct {
+	Statements  []ast.Statement
+	Diagnostics []lsp.Diagnostic     // ranges in the fragment source
+	Expressions []FragmentExpression // top-level expression statements
+}
+
+// FragmentExpression is a top-level expression of a fragment together with
+// its inferred type.
+type FragmentExpression struct {
+	Expression ast.Expression
+	Type       string // e.g. "uint256" or "int_const 86400"; or empty if unknown
+}
+
+// fragmentHeader starts the synthetic source of the fragment. The comment
+// keeps the positions of the synthetic nodes away from the code.
+const fragmentHeader = "// solbot fragment\n"
+
+// CheckFragment checks the statements as if they were the body of a
+// function in a contract with the given variables e.g. "x" -> "uint256".
+// The variables can refer to the elementary types only, since the
+// fragment has no imports. It returns the parser errors and the unresolved
+// names, together with the types of the top-level expressions.
+func CheckFragment(src string, vars map[string]string) *Fragment {
+	// The variables are declared as the locals before the fragment, so the
+	// whole thing parses as a single list of statements.
+	var header strings.Builder
+	header.WriteString(fragmentHeader)
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	declared := map[string]token.Range{}
+	for _, name := range names {
+		start := header.Len()
+		fmt.Fprintf(&header, "%s %s;\n", vars[name], name)
+		declared[name] = token.Range{Start: token.Pos(start), End: token.Pos(header.Len())}
+	}
+	offset := token.Pos(header.Len())
+	text := header.String() + src
+
+	res := &Fragment{
+		Statements:  []ast.Statement{},
+		Diagnostics: []lsp.Diagnostic{},
+		Expressions: []FragmentExpression{},
+	}
+	handle := token.NewFile("fragment", src)
+	report := func(r token.Range, code, message string) {
+		res.Diagnostics = append(res.Diagnostics, lsp.Diagnostic{
+			Range:    toLspRange(handle, token.Range{Start: r.Start - offset, End: r.End - offset}),
+			Severity: lsp.SeverityError,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+
+	stmts, errs := parser.ParseStatements(text)
+	for _, err := range errs {
+		if err.Pos >= offset {
+			// The offsets in the messages are the ones in the synthetic
+			// source, the range is enough.
+			report(token.Range{Start: err.Pos, End: err.Pos}, "syntax-error", err.Message())
+			continue
+		}
+		for _, name := range names {
+			if declared[name].Contains(err.Pos) {
+				report(token.Range{Start: offset, End: offset}, "invalid-variable",
+					fmt.Sprintf("`%s` can't be declared as `%s`", name, vars[name]))
+			}
+		}
+	}
+	for _, stmt := range stmts {
+		if stmt.Start() >= offset {
+			res.Statements = append(res.Statements, stmt)
+		}
+	}
+
+	doc := fragmentDocument(text, stmts)
+	s := NewState()
+	s.Documents[doc.URI] = doc
+	for _, ident := range s.unresolvedIdentifiers(doc) {
+		if ident.NamePos < offset {
+			continue
+		}
+		report(ast.NodeRange(ident), "undeclared-identifier", fmt.Sprintf("Undeclared identifier `%s`", ident.Name))
+	}
+
+	for _, stmt := range res.Statements {
+		x, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			continue
+		}
+		path := ast.PathEnclosingPos(doc.File, x.Expression.Start())
+		res.Expressions = append(res.Expressions, FragmentExpression{
+			Expression: x.Expression,
+			Type:       s.fragmentType(doc, path, x.Expression),
+		})
+	}
+	return res
+}
+
+// fragmentDocument wraps the statements in a synthetic function of a
+// synthetic contract, both spanning the whole text. Their names are placed
+// over the header comment, so that no path to the code passes them.
+func fragmentDocument(text string, stmts []ast.Statement) *Document {
+	end := token.Pos(len(text))
+	fn := &ast.FunctionDeclaration{
+		Kind: token.FUNCTION,
+		Name: &ast.Identifier{Name: "fragment"},
+		Type: &ast.FunctionType{
+			Params:     &ast.ParamList{},
+			Visibility: ast.External,
+		},
+		Body: &ast.BlockStatement{Statements: stmts, RightBrace: end},
+	}
+	contract := &ast.ContractDeclaration{
+		Kind:       token.CONTRACT,
+		Name:       &ast.Identifier{Name: "Fragment"},
+		Body:       []ast.Declaration{fn},
+		RightBrace: end,
+	}
+	return &Document{
+		URI:    "fragment",
+		Handle: token.NewFile("fragment", text),
+		File:   &ast.File{Name: "fragment", Declarations: []ast.Declaration{contract}},
+	}
+}
+
+// fragmentType returns the type of the expression as solc prints it; or an
+// empty string if it's unknown. The literals and the expressions made of
+// them are folded e.g. `1 days` is "int_const 86400".
+func (s *State) fragmentType(doc *Document, path []ast.Node, x ast.Expression) string {
+	if value, ok := constInt(x); ok {
+		return "int_const " + value.String()
+	}
+	if _, ok := constBool(x); ok {
+		return "bool"
+	}
+
+	switch x := x.(type) {
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 && x.Elements[0] != nil {
+			return s.fragmentType(doc, path, x.Elements[0])
+		}
+		return ""
+	case *ast.UnaryExpression:
+		if x.Operator == token.NOT {
+			return "bool"
+		}
+		return s.fragmentType(doc, path, x.Operand)
+	case *ast.BinaryExpression:
+		switch x.Operator {
+		case token.AND, token.OR, token.EQUAL, token.NOT_EQUAL, token.LESS_THAN,
+			token.GREATER_THAN, token.LESS_THAN_OR_EQUAL, token.GREATER_THAN_OR_EQUAL:
+			return "bool"
+		case token.SHL, token.SAR, token.EXP:
+			// The result has the type of the left operand.
+			return s.fragmentType(doc, path, x.Left)
+		}
+		// The literals take the type of the other operand.
+		if _, ok := constInt(x.Left); ok {
+			return s.fragmentType(doc, path, x.Right)
+		}
+		left := s.fragmentType(doc, path, x.Left)
+		if _, ok := constInt(x.Right); ok {
+			return left
+		}
+		if right := s.fragmentType(doc, path, x.Right); right != left {
+			return ""
+		}
+		return left
+	case *ast.AssignmentExpression:
+		return s.fragmentType(doc, path, x.Left)
+	case *ast.BasicLit:
+		if x.Kind == token.STRING_LITERAL {
+			return "literal_string"
+		}
+		return ""
+	}
+
+	_, t := s.typeOf(doc, path, x)
+	if t == nil {
+		return ""
+	}
+	return ast.ExprString(t)
+}
@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"testing"
+)
+
+func Test_CheckFragmentExpression(t *testing.T) {
+	fragment := CheckFragment("1 days + 2 hours", nil)
+	if len(fragment.Diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", fragment.Diagnostics)
+	}
+	if len(fragment.Expressions) != 1 {
+		t.Fatalf("Expected 1 expression, got %d", len(fragment.Expressions))
+	}
+	if typ := fragment.Expressions[0].Type; typ != "int_const 93600" {
+		t.Errorf("Expected int_const 93600, got %q", typ)
+	}
+
+	fragment = CheckFragment("x + 1 days", map[string]string{"x": "uint256"})
+	if len(fragment.Diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", fragment.Diagnostics)
+	}
+	if typ := fragment.Expressions[0].Type; typ != "uint256" {
+		t.Errorf("Expected uint256, got %q", typ)
+	}
+}
+
+func Test_CheckFragmentStatements(t *testing.T) {
+	src := `uint256 fee = amount * 30 / 10_000;
+fee >= minFee`
+
+	fragment := CheckFragment(src, map[string]string{"amount": "uint256", "minFee": "uint128"})
+	if len(fragment.Diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", fragment.Diagnostics)
+	}
+	if len(fragment.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(fragment.Statements))
+	}
+	if _, ok := fragment.Statements[0].(*ast.VariableDeclarationStatement); !ok {
+		t.Errorf("Expected a variable declaration, got %T", fragment.Statements[0])
+	}
+	if len(fragment.Expressions) != 1 || fragment.Expressions[0].Type != "bool" {
+		t.Errorf("Expected a bool expression, got %v", fragment.Expressions)
+	}
+
+	fragment = CheckFragment("uint256 fee = 1; fee", nil)
+	if len(fragment.Expressions) != 1 || fragment.Expressions[0].Type != "uint256" {
+		t.Errorf("Expected `fee` of type uint256, got %v", fragment.Expressions)
+	}
+}
+
+func Test_CheckFragmentUndeclared(t *testing.T) {
+	fragment := CheckFragment("x + y", map[string]string{"x": "uint256"})
+	if len(fragment.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", fragment.Diagnostics)
+	}
+	d := fragment.Diagnostics[0]
+	if d.Message != "Undeclared identifier `y`" || d.Range.Start.Line != 0 || d.Range.Start.Character != 4 {
+		t.Errorf("Expected `y` undeclared at 0:4, got %q at %d:%d", d.Message, d.Range.Start.Line, d.Range.Start.Character)
+	}
+	if typ := fragment.Expressions[0].Type; typ != "" {
+		t.Errorf("Expected an unknown type, got %q", typ)
+	}
+}
@@ -0,0 +1,209 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"strings"
+)
+
+// getter is the external function that the compiler generates for a public
+// state variable. The keys of the mappings and the indices of the arrays
+// become its parameters, e.g. `balances(address)` for
+// `mapping(address => uint256) public balances`, and it returns the value
+// stored under them.
+type getter struct {
+	Var    *Symbol          // the public state variable
+	Params []ast.Expression // types of the keys and the indices
+	Result ast.Expression   // type of the returned value
+}
+
+// getterOf returns the implicit getter of the symbol; or nil if it's not
+// a public state variable.
+func (s *State) getterOf(sym *Symbol) *getter {
+	if sym == nil {
+		return nil
+	}
+	decl, ok := sym.Node.(*ast.VariableDeclaration)
+	if !ok || decl.Visibility != ast.Public || s.declaringContract(sym) == nil {
+		return nil
+	}
+
+	g := &getter{Var: sym}
+	t := decl.Type
+	for {
+		switch typ := t.(type) {
+		case *ast.MappingType:
+			g.Params = append(g.Params, typ.Key)
+			t = typ.Value
+			continue
+		case *ast.ArrayType:
+			g.Params = append(g.Params, &ast.ElementaryType{ValuePos: typ.Lbracket, Value: "uint256"})
+			t = typ.Elem
+			continue
+		}
+		break
+	}
+	g.Result = t
+	return g
+}
+
+// getterSignature returns the canonical signature of the getter e.g.
+// `balances(address)`; or false if some of the key types are unknown.
+func (s *State) getterSignature(g *getter) (string, bool) {
+	return s.signature(g.Var.Doc, g.Var.Name.Name, g.Params)
+}
+
+// functionSignature returns the canonical signature of the function e.g.
+// `transfer(address,uint256)`; or false if some of the parameter types are
+// unknown.
+func (s *State) functionSignature(doc *Document, fn *ast.FunctionDeclaration) (string, bool) {
+	if fn.Name == nil {
+		return "", false
+	}
+	types := []ast.Expression{}
+	if fn.Type.Params != nil {
+		for _, param := range fn.Type.Params.List {
+			types = append(types, param.Type)
+		}
+	}
+	return s.signature(doc, fn.Name.Name, types)
+}
+
+func (s *State) signature(doc *Document, name string, types []ast.Expression) (string, bool) {
+	params := make([]string, 0, len(types))
+	for _, t := range types {
+		canonical, ok := s.canonicalType(doc, t, map[ast.Node]bool{})
+		if !ok {
+			return "", false
+		}
+		params = append(params, canonical)
+	}
+	return name + "(" + strings.Join(params, ",") + ")", true
+}
+
+// canonicalType returns the type as it's written in the function selectors
+// e.g. `uint256` for `uint`, `address` for contracts and `uint8` for enums.
+// Structs are written as the tuples of their members. The visited structs
+// guard against the recursive ones.
+func (s *State) canonicalType(doc *Document, t ast.Expression, visited map[ast.Node]bool) (string, bool) {
+	switch t := t.(type) {
+	case *ast.ElementaryType:
+		switch t.Value {
+		case "uint", "int":
+			return t.Value + "256", true
+		case "byte":
+			return "bytes1", true
+		case "fixed", "ufixed":
+			return t.Value + "128x18", true
+		}
+		return t.Value, t.Value != ""
+	case *ast.FunctionType:
+		return "function", true
+	case *ast.ArrayType:
+		elem, ok := s.canonicalType(doc, t.Elem, visited)
+		if !ok {
+			return "", false
+		}
+		if t.Len == nil {
+			return elem + "[]", true
+		}
+		if length, ok := constInt(t.Len); ok {
+			return elem + "[" + length.String() + "]", true
+		}
+		return "", false
+	case *ast.Identifier, *ast.MemberAccessExpression:
+	default:
+		return "", false
+	}
+
+	sym := s.follow(s.resolveExpr(doc, ast.PathEnclosingPos(doc.File, t.Start()), t))
+	if sym == nil {
+		// `byte` is not a keyword, it's an alias removed in 0.8.0.
+		if ident, ok := t.(*ast.Identifier); ok && ident.Name == "byte" {
+			return "bytes1", true
+		}
+		return "", false
+	}
+	switch n := sym.Node.(type) {
+	case *ast.ContractDeclaration:
+		return "address", true
+	case *ast.EnumDeclaration:
+		return "uint8", true
+	case *ast.TypeDeclaration:
+		return s.canonicalType(sym.Doc, n.Underlying, visited)
+	case *ast.StructDeclaration:
+		if visited[n] {
+			return "", false
+		}
+		visited[n] = true
+		defer delete(visited, n)
+		members := []string{}
+		for _, member := range n.Members {
+			canonical, ok := s.canonicalType(sym.Doc, member.Type, visited)
+			if !ok {
+				return "", false
+			}
+			members = append(members, canonical)
+		}
+		return "(" + strings.Join(members, ",") + ")", true
+	}
+	return "", false
+}
+
+// needsABICoderV2 reports whether only the ABI coder v2 can encode the
+// canonical type, see canonicalType: a struct, written as a tuple e.g.
+// `(uint256,address)`, or an array of a dynamic type e.g. `string[]` or
+// `uint256[][2]`, nested at any depth.
+func needsABICoderV2(canonical string) bool {
+	if strings.HasPrefix(canonical, "(") {
+		return true
+	}
+	i := strings.LastIndexByte(canonical, '[')
+	if i < 0 {
+		return false
+	}
+	elem := canonical[:i]
+	return abiDynamic(elem) || needsABICoderV2(elem)
+}
+
+// abiDynamic reports whether the values of the canonical type are encoded
+// after the static part, at their offsets e.g. `bytes` or `uint256[]`.
+func abiDynamic(canonical string) bool {
+	switch {
+	case canonical == "string", canonical == "bytes", strings.HasSuffix(canonical, "[]"):
+		return true
+	case strings.HasSuffix(canonical, "]"):
+		return abiDynamic(canonical[:strings.LastIndexByte(canonical, '[')])
+	case strings.HasPrefix(canonical, "("):
+		for _, member := range tupleMembers(canonical) {
+			if abiDynamic(member) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tupleMembers returns the members of the canonical tuple type e.g.
+// `uint256` and `(bytes,bool)[]` of `(uint256,(bytes,bool)[])`.
+func tupleMembers(canonical string) []string {
+	res := []string{}
+	inner := canonical[1:strings.LastIndexByte(canonical, ')')]
+	depth, start := 0, 0
+	for i, c := range inner {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, inner[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if inner != "" {
+		res = append(res, inner[start:])
+	}
+	return res
+}
@@ -0,0 +1,148 @@
+package analysis
+
+import (
+	"context"
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+var getterWorkspace = map[string]string{
+	"file:///ws/src/IVault.sol": `pragma solidity ^0.8.0;
+
+interface IVault {
+    function balances(address owner) external view returns (uint256);
+    function totalSupply() external view returns (uint);
+}
+`,
+	"file:///ws/src/Vault.sol": `pragma solidity ^0.8.0;
+
+import "./IVault.sol";
+
+contract Vault is IVault {
+    mapping(address => uint256) public balances;
+    uint256 public override totalSupply;
+}
+
+contract Partial is IVault {
+    uint256 public totalSupply;
+}
+`,
+	"file:///ws/src/Router.sol": `pragma solidity ^0.8.0;
+
+import "./Vault.sol";
+
+contract Router {
+    Vault vault;
+
+    function balanceOf(address user) external view returns (uint256) {
+        return vault.balances(user);
+    }
+}
+`,
+}
+
+func newGetterState() *State {
+	s := NewState()
+	s.Root = "/ws"
+	for uri, src := range getterWorkspace {
+		s.Documents[uri] = newDocument(uri, 0, false, src)
+	}
+	return s
+}
+
+func Test_GetterCall(t *testing.T) {
+	s := newGetterState()
+
+	// `balances` in `vault.balances(user)`
+	position := lsp.Position{Line: 8, Character: 23}
+	response := s.Definition(lsp.IntID(1), "file:///ws/src/Router.sol", position)
+	if response.Result == nil || len(*response.Result) != 1 {
+		t.Fatalf("Expected 1 location, got %v", response.Result)
+	}
+	expected := lsp.Location{
+		URI:   "file:///ws/src/Vault.sol",
+		Range: lsp.Range{Start: lsp.Position{Line: 5, Character: 39}, End: lsp.Position{Line: 5, Character: 47}},
+	}
+	if location := (*response.Result)[0]; location != expected {
+		t.Errorf("Expected %v, got %v", expected, location)
+	}
+
+	hover := s.Hover(lsp.IntID(2), "file:///ws/src/Router.sol", position)
+	if !strings.Contains(hover.Result.Contents.Value, "public state variable (implicit getter) `balances(address)`") {
+		t.Errorf("Expected the getter in the hover, got %q", hover.Result.Contents.Value)
+	}
+	hover = s.Hover(lsp.IntID(3), "file:///ws/src/Vault.sol", lsp.Position{Line: 5, Character: 40})
+	if strings.Contains(hover.Result.Contents.Value, "implicit getter") {
+		t.Errorf("Expected no getter on the declaration, got %q", hover.Result.Contents.Value)
+	}
+}
+
+func Test_RenameGetter(t *testing.T) {
+	s := newGetterState()
+
+	edit, err := s.rename("file:///ws/src/Router.sol", lsp.Position{Line: 8, Character: 23}, "deposits")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	expected := map[string]string{
+		"file:///ws/src/IVault.sol": "function deposits(address owner)",
+		"file:///ws/src/Vault.sol":  "mapping(address => uint256) public deposits;",
+		"file:///ws/src/Router.sol": "return vault.deposits(user);",
+	}
+	if len(edit.Changes) != len(expected) {
+		t.Fatalf("Expected edits in %d files, got %d", len(expected), len(edit.Changes))
+	}
+	for uri, line := range expected {
+		renamed := applyEdits(s.Documents[uri], edit.Changes[uri])
+		if !strings.Contains(renamed, line) {
+			t.Errorf("Expected %q in %s, got:\n%s", line, uri, renamed)
+		}
+	}
+}
+
+func Test_ImplementationDiagnostics(t *testing.T) {
+	s := newGetterState()
+
+	diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Vault.sol").Params.Diagnostics
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %d", len(diagnostics))
+	}
+	d := diagnostics[0]
+	if d.Code != "missing-implementation" || d.Range.Start.Line != 9 {
+		t.Errorf("Expected missing-implementation at line 9, got %s at line %d", d.Code, d.Range.Start.Line)
+	}
+	message := "`Partial` doesn't implement `balances(address)` of `IVault`"
+	if !strings.HasPrefix(d.Message, message) {
+		t.Errorf("Expected message starting with %q, got %q", message, d.Message)
+	}
+}
+
+func Test_CanonicalType(t *testing.T) {
+	s := NewState()
+	src := `pragma solidity ^0.8.0;
+
+contract Types {
+    enum Kind { A, B }
+    struct Pair { uint a; Kind kind; }
+    type Price is uint128;
+
+    function f(uint a, int8 b, byte c, Types t, Kind k, Pair[] memory p, Price q, address payable r, bytes32[2] calldata s) external {}
+}
+`
+	s.OpenDocument("file:///ws/Types.sol", 1, src)
+	doc := s.Documents["file:///ws/Types.sol"]
+
+	contract := doc.File.Declarations[1].(*ast.ContractDeclaration)
+	fn := contract.Body[len(contract.Body)-1].(*ast.FunctionDeclaration)
+	signature, ok := s.functionSignature(doc, fn)
+	if !ok {
+		t.Fatalf("Expected the signature to be known")
+	}
+	expected := "f(uint256,int8,bytes1,address,uint8,(uint256,uint8)[],uint128,address,bytes32[2])"
+	if signature != expected {
+		t.Errorf("Expected %s, got %s", expected, signature)
+	}
+}
@@ -0,0 +1,186 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/semver"
+	"strings"
+)
+
+//go:generate go run solbot/internal/globalsgen -in globals.json -out globals_gen.go
+
+// builtinGlobal is a member of the builtin globals `block`, `msg` and `tx`
+// e.g. `block.timestamp`, or a global name e.g. `now`, in the table of
+// builtinGlobals. The versions of the compiler introducing, deprecating
+// and removing it decide how it's completed under the pragma of a file.
+type builtinGlobal struct {
+	receiver    string // e.g. "block"; or empty for a global name
+	name        string
+	typ         string // e.g. "uint256"
+	rank        int    // 0 for the most used member of the receiver, see globals.json
+	since       string // the version introducing it e.g. "0.8.18"; or empty
+	deprecated  string // the version deprecating it; or empty
+	removed     string // the version removing it; or empty
+	replacement string // the code to use instead of the deprecated or removed one e.g. "block.prevrandao"
+	doc         string
+}
+
+// qualifiedName returns the name with the receiver e.g. "block.timestamp".
+func (g builtinGlobal) qualifiedName() string {
+	if g.receiver == "" {
+		return g.name
+	}
+	return g.receiver + "." + g.name
+}
+
+// introduced returns the versions with the global, removed or not.
+func (g builtinGlobal) introduced() semver.Constraint {
+	if g.since == "" {
+		return semver.MustParseConstraint(">=0.0.0")
+	}
+	return semver.MustParseConstraint(">=" + g.since)
+}
+
+// available returns the versions with the global, introduced and not yet
+// removed.
+func (g builtinGlobal) available() semver.Constraint {
+	if g.removed == "" {
+		return g.introduced()
+	}
+	return g.introduced().Intersect(semver.MustParseConstraint("<" + g.removed))
+}
+
+// obsolete returns the versions deprecating or removing the global. The
+// constraint is empty if it's still current.
+func (g builtinGlobal) obsolete() semver.Constraint {
+	switch {
+	case g.deprecated != "":
+		return semver.MustParseConstraint(">=" + g.deprecated)
+	case g.removed != "":
+		return semver.MustParseConstraint(">=" + g.removed)
+	}
+	return semver.Constraint{}
+}
+
+// note returns the replacement of the deprecated or removed global e.g.
+// "removed in Solidity 0.7.0; use `block.timestamp` instead"; or an empty
+// string if it's still current.
+func (g builtinGlobal) note(markdown bool) string {
+	replacement := g.replacement
+	if markdown {
+		replacement = "`" + replacement + "`"
+	}
+	switch {
+	case g.removed != "":
+		return fmt.Sprintf("removed in Solidity %s; use %s instead", g.removed, replacement)
+	case g.deprecated != "":
+		return fmt.Sprintf("deprecated since Solidity %s; use %s instead", g.deprecated, replacement)
+	}
+	return ""
+}
+
+// builtinGlobalOf returns the global of the table with the receiver and
+// the name.
+func builtinGlobalOf(receiver, name string) (builtinGlobal, bool) {
+	i := slices.IndexFunc(builtinGlobals, func(g builtinGlobal) bool {
+		return g.receiver == receiver && g.name == name
+	})
+	if i < 0 {
+		return builtinGlobal{}, false
+	}
+	return builtinGlobals[i], true
+}
+
+// isGlobalReceiver reports whether the name is one of the builtin globals
+// with the members in the table e.g. `block`.
+func isGlobalReceiver(name string) bool {
+	return name != "" && slices.ContainsFunc(builtinGlobals, func(g builtinGlobal) bool { return g.receiver == name })
+}
+
+// globalCompletions returns the members of the builtin global under the
+// solidity pragma of the document, the most used ones first. The members
+// introduced after the versions the pragma allows, or removed before them,
+// are left out. The ones
+// deprecated or removed in any of them are tagged as deprecated and come
+// last, and the ones introduced after the lowest of them name the version
+// they require.
+func globalCompletions(doc *Document, receiver string) []lsp.CompletionItem {
+	c, ok := pragma.Solidity(doc.File)
+	if !ok {
+		c = semver.MustParseConstraint(">=0.0.0")
+	}
+	minimum, _ := c.Min()
+	items := []lsp.CompletionItem{}
+	for _, g := range builtinGlobals {
+		introduced := g.introduced()
+		if g.receiver != receiver || !g.available().AllowsAny(c) {
+			continue
+		}
+		item := lsp.CompletionItem{
+			Label:  g.name,
+			Kind:   lsp.CompletionItemField,
+			Detail: g.typ + " " + g.qualifiedName(),
+			Documentation: &lsp.MarkupContent{
+				Kind:  lsp.PlainText,
+				Value: g.doc,
+			},
+			SortText: fmt.Sprintf("0%02d", g.rank),
+		}
+		if !introduced.AllowsAll(c) {
+			item.Detail += fmt.Sprintf("; requires >=%s but pragma allows %s", g.since, minimum)
+		}
+		if g.obsolete().AllowsAny(c) {
+			item.Tags = []lsp.CompletionItemTag{lsp.CompletionItemTagDeprecated}
+			item.SortText = fmt.Sprintf("1%02d", g.rank)
+			item.Documentation.Value += "\n\n" + g.note(false)
+		}
+		items = append(items, item)
+	}
+	slices.SortStableFunc(items, func(a, b lsp.CompletionItem) int {
+		return strings.Compare(a.SortText, b.SortText)
+	})
+	return items
+}
+
+// globalHover shows the type and the documentation of the builtin global
+// at the position e.g. `block.timestamp` or `now`, and the replacement of
+// the deprecated and removed ones; or returns an empty string. The names
+// declared in the source shadow them.
+func (s *State) globalHover(uri string, position lsp.Position, markdown bool) string {
+	doc, ok := s.document(uri)
+	if !ok {
+		return ""
+	}
+	path := ast.PathEnclosingPos(doc.File, toTokenPos(doc.Handle, position))
+	if len(path) < 2 {
+		return ""
+	}
+	ident, ok := path[0].(*ast.Identifier)
+	if !ok {
+		return ""
+	}
+	var g builtinGlobal
+	if access, isMember := path[1].(*ast.MemberAccessExpression); isMember && access.Member == ident {
+		receiver, ok := access.Expression.(*ast.Identifier)
+		if !ok || !isGlobalReceiver(receiver.Name) || s.lookup(doc, path[2:], receiver.Name, receiver.Start()) != nil {
+			return ""
+		}
+		if g, ok = builtinGlobalOf(receiver.Name, ident.Name); !ok {
+			return ""
+		}
+	} else if g, ok = builtinGlobalOf("", ident.Name); !ok || s.resolve(doc, path) != nil {
+		return ""
+	}
+
+	content := fmt.Sprintf("%s %s\n\n%s", g.typ, g.qualifiedName(), g.doc)
+	if markdown {
+		content = fmt.Sprintf("```solidity\n%s %s\n```\n\n%s", g.typ, g.qualifiedName(), g.doc)
+	}
+	if note := g.note(markdown); note != "" {
+		content += "\n\n" + note
+	}
+	return content
+}
@@ -0,0 +1,25 @@
+// Code generated by globalsgen from globals.json; DO NOT EDIT.
+
+package analysis
+
+// builtinGlobals are the members of the builtin globals and the global names, see globals.json.
+var builtinGlobals = []builtinGlobal{
+	{receiver: "block", name: "timestamp", typ: "uint256", rank: 0, doc: "The timestamp of the current block, in seconds since the Unix epoch."},
+	{receiver: "block", name: "number", typ: "uint256", rank: 1, doc: "The number of the current block."},
+	{receiver: "block", name: "chainid", typ: "uint256", rank: 2, since: "0.8.0", doc: "The identifier of the chain."},
+	{receiver: "block", name: "basefee", typ: "uint256", rank: 3, since: "0.8.7", doc: "The base fee of the current block, EIP-1559."},
+	{receiver: "block", name: "coinbase", typ: "address payable", rank: 4, doc: "The address of the validator of the current block."},
+	{receiver: "block", name: "prevrandao", typ: "uint256", rank: 5, since: "0.8.18", doc: "The random number provided by the beacon chain, EIP-4399."},
+	{receiver: "block", name: "gaslimit", typ: "uint256", rank: 6, doc: "The gas limit of the current block."},
+	{receiver: "block", name: "blobbasefee", typ: "uint256", rank: 7, since: "0.8.24", doc: "The blob base fee of the current block, EIP-7516."},
+	{receiver: "block", name: "difficulty", typ: "uint256", rank: 8, deprecated: "0.8.18", replacement: "block.prevrandao", doc: "The difficulty of the current block; the random number of the beacon chain since the merge."},
+	{receiver: "block", name: "blockhash", typ: "function (uint256 blockNumber) returns (bytes32)", rank: 9, deprecated: "0.4.22", removed: "0.5.0", replacement: "blockhash(blockNumber)", doc: "The hash of one of the 256 most recent blocks."},
+	{receiver: "msg", name: "sender", typ: "address", rank: 0, doc: "The sender of the current call."},
+	{receiver: "msg", name: "value", typ: "uint256", rank: 1, doc: "The wei sent with the current call."},
+	{receiver: "msg", name: "data", typ: "bytes calldata", rank: 2, doc: "The complete calldata of the current call."},
+	{receiver: "msg", name: "sig", typ: "bytes4", rank: 3, doc: "The first four bytes of the calldata, the function selector."},
+	{receiver: "msg", name: "gas", typ: "uint256", rank: 4, deprecated: "0.4.21", removed: "0.5.0", replacement: "gasleft()", doc: "The remaining gas."},
+	{receiver: "tx", name: "origin", typ: "address", rank: 0, doc: "The sender of the transaction, an externally owned account."},
+	{receiver: "tx", name: "gasprice", typ: "uint256", rank: 1, doc: "The gas price of the transaction."},
+	{receiver: "", name: "now", typ: "uint256", rank: 0, removed: "0.7.0", replacement: "block.timestamp", doc: "The timestamp of the current block, an alias of `block.timestamp`."},
+}
@@ -0,0 +1,94 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func Test_GlobalCompletions(t *testing.T) {
+	src := "pragma solidity %s;\n\ncontract Clock {\n    function time() external view returns (uint256) {\n        return block.\n    }\n}\n"
+	tests := []struct {
+		pragma     string
+		labels     string
+		deprecated []string
+		requires   map[string]string
+	}{
+		{
+			pragma:     "^0.4.24",
+			labels:     "timestamp number coinbase gaslimit difficulty blockhash",
+			deprecated: []string{"blockhash"},
+		},
+		{
+			pragma:     "^0.8.0",
+			labels:     "timestamp number chainid basefee coinbase prevrandao gaslimit blobbasefee difficulty",
+			deprecated: []string{"difficulty"},
+			requires: map[string]string{
+				"basefee":     "; requires >=0.8.7 but pragma allows 0.8.0",
+				"prevrandao":  "; requires >=0.8.18 but pragma allows 0.8.0",
+				"blobbasefee": "; requires >=0.8.24 but pragma allows 0.8.0",
+			},
+		},
+		{
+			pragma:     ">=0.7.0 <0.8.10",
+			labels:     "timestamp number chainid basefee coinbase gaslimit difficulty",
+			deprecated: nil,
+			requires: map[string]string{
+				"chainid": "; requires >=0.8.0 but pragma allows 0.7.0",
+				"basefee": "; requires >=0.8.7 but pragma allows 0.7.0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		uri := "file:///Clock.sol"
+		s := NewState()
+		s.OpenDocument(uri, 1, fmt.Sprintf(src, tt.pragma))
+		items := s.Completion(lsp.IntID(1), uri, lsp.Position{Line: 4, Character: 21}).Result
+
+		labels := []string{}
+		for i, item := range items {
+			labels = append(labels, item.Label)
+			if i > 0 && item.SortText <= items[i-1].SortText {
+				t.Errorf("Expected the items of %s sorted by their sort text, got %q after %q", tt.pragma, item.SortText, items[i-1].SortText)
+			}
+			deprecated := slices.Contains(item.Tags, lsp.CompletionItemTagDeprecated)
+			if deprecated != slices.Contains(tt.deprecated, item.Label) {
+				t.Errorf("Expected `%s` under %s to be deprecated: %v, got %v", item.Label, tt.pragma, !deprecated, deprecated)
+			}
+			if !strings.HasSuffix(item.Detail, tt.requires[item.Label]) || tt.requires[item.Label] == "" && strings.Contains(item.Detail, "requires") {
+				t.Errorf("Expected the detail of `%s` under %s to end with %q, got %q", item.Label, tt.pragma, tt.requires[item.Label], item.Detail)
+			}
+		}
+		if strings.Join(labels, " ") != tt.labels {
+			t.Errorf("Expected the members of `block` under %s:\n%s\ngot:\n%s", tt.pragma, tt.labels, strings.Join(labels, " "))
+		}
+	}
+}
+
+func Test_GlobalCompletionsShadowed(t *testing.T) {
+	uri := "file:///Clock.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, "pragma solidity ^0.8.0;\n\ncontract Clock {\n    struct Info { uint256 height; }\n\n    function time(Info memory block) external view {\n        block.\n    }\n}\n")
+	items := s.Completion(lsp.IntID(1), uri, lsp.Position{Line: 6, Character: 14}).Result
+	if len(items) != 1 || items[0].Label != "height" {
+		t.Errorf("Expected the members of the declared `block`, got %v", items)
+	}
+}
+
+func Test_GlobalHover(t *testing.T) {
+	uri := "file:///Clock.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, "pragma solidity ^0.6.0;\n\ncontract Clock {\n    function time() external view returns (uint256, uint256) {\n        return (now, block.difficulty);\n    }\n}\n")
+
+	hover := s.Hover(lsp.IntID(1), uri, lsp.Position{Line: 4, Character: 17}).Result.Contents.Value
+	expected := "```solidity\nuint256 now\n```\n\nThe timestamp of the current block, an alias of `block.timestamp`.\n\nremoved in Solidity 0.7.0; use `block.timestamp` instead"
+	if hover != expected {
+		t.Errorf("Expected the hover of `now`:\n%s\ngot:\n%s", expected, hover)
+	}
+	hover = s.Hover(lsp.IntID(2), uri, lsp.Position{Line: 4, Character: 30}).Result.Contents.Value
+	if !strings.HasSuffix(hover, "deprecated since Solidity 0.8.18; use `block.prevrandao` instead") {
+		t.Errorf("Expected the replacement of `block.difficulty`, got:\n%s", hover)
+	}
+}
@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"solbot/gitignore"
+	"solbot/vfs"
+	"strings"
+)
+
+// ignoreFilter decides which paths under the root of the workspace are
+// left out of it. The [files] section of solbot.toml decides first: the
+// excluded paths are always left out and the included ones are kept, even
+// if git ignores them. The other paths are left out if they're ignored by
+// the .git/info/exclude file or the .gitignore files of their directories.
+type ignoreFilter struct {
+	root    string
+	git     gitignore.Matcher
+	include gitignore.Matcher
+	exclude gitignore.Matcher
+	loaded  map[string]bool // relative directory -> whether its .gitignore was read, see load
+}
+
+// ignoreFilter returns the filter of the workspace, made on the first use
+// after the project was loaded.
+func (s *State) ignoreFilter() *ignoreFilter {
+	if s.ignore != nil {
+		return s.ignore
+	}
+	f := &ignoreFilter{root: s.Root, loaded: map[string]bool{}}
+	for _, pattern := range s.Config.Files.Exclude {
+		f.exclude.Add(gitignore.Parse(pattern, "")...)
+	}
+	for _, pattern := range s.Config.Files.Include {
+		f.include.Add(gitignore.Parse(pattern, "")...)
+	}
+	if src, err := vfs.ReadFile(s.files(), filepath.Join(s.Root, ".git", "info", "exclude")); err == nil {
+		f.git.Add(gitignore.Parse(string(src), "")...)
+	}
+	s.ignore = f
+	return f
+}
+
+// ignored reports whether the file or the directory at the path is left
+// out of the workspace. The paths outside of the root are never ignored.
+func (s *State) ignored(p string, isDir bool) bool {
+	if s.Root == "" {
+		return false
+	}
+	f := s.ignoreFilter()
+	rel, err := filepath.Rel(f.root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if f.exclude.Ignored(rel, isDir) {
+		return true
+	}
+	if f.include.Ignored(rel, isDir) {
+		return false
+	}
+	// The .gitignore files of the parent directories, from the root down.
+	// The hidden directories are never walked, e.g. .git.
+	dir := "."
+	s.loadGitignore(f, dir)
+	for _, name := range strings.Split(path.Dir(rel), "/") {
+		if name == "." {
+			break
+		}
+		if strings.HasPrefix(name, ".") {
+			return true
+		}
+		dir = path.Join(dir, name)
+		s.loadGitignore(f, dir)
+	}
+	if !f.git.Ignored(rel, isDir) {
+		return false
+	}
+	// The ignored directory is walked for the included paths under it.
+	return !isDir || !f.include.MayMatchUnder(rel)
+}
+
+// loadGitignore adds the patterns of the .gitignore file in the directory,
+// relative to the root, unless it was read already.
+func (s *State) loadGitignore(f *ignoreFilter, dir string) {
+	if f.loaded[dir] {
+		return
+	}
+	f.loaded[dir] = true
+	src, err := vfs.ReadFile(s.files(), filepath.Join(f.root, filepath.FromSlash(dir), ".gitignore"))
+	if err != nil {
+		return
+	}
+	base := dir
+	if base == "." {
+		base = ""
+	}
+	f.git.Add(gitignore.Parse(string(src), base)...)
+}
+
+// walkFiles calls visit with the path of every file with the extension
+// under the root, skipping the hidden directories and the paths left out
+// of the workspace, see ignored.
+func (s *State) walkFiles(root, ext string, visit func(p string) error) error {
+	return vfs.WalkDir(s.files(), root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && (strings.HasPrefix(d.Name(), ".") || s.ignored(p, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(p) != ext || s.ignored(p, false) {
+			return nil
+		}
+		return visit(p)
+	})
+}
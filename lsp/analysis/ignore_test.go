@@ -0,0 +1,129 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+// newIgnoreWorkspace writes the workspace with the ignore files to a
+// temporary directory.
+func newIgnoreWorkspace(t *testing.T) (*State, string) {
+	root := t.TempDir()
+	contract := func(name string) string {
+		return "pragma solidity ^0.8.0;\n\ncontract " + name + " {}\n"
+	}
+	writeWorkspace(t, root, map[string]string{
+		".git/info/exclude":           "scratch/\n",
+		".gitignore":                  "out/\ncache/\n*.tmp.sol\n",
+		"solbot.toml":                 "[files]\nexclude = [\"script/\"]\ninclude = [\"out/Flat.sol\"]\n",
+		"src/Vault.sol":               contract("Vault"),
+		"src/Vault.tmp.sol":           contract("VaultDraft"),
+		"src/generated/.gitignore":    "*.sol\n!Keep.sol\n",
+		"src/generated/Keep.sol":      contract("Keep"),
+		"src/generated/Bindings.sol":  contract("Bindings"),
+		"out/Vault.sol/Vault.sol":     contract("Vault"),
+		"out/Flat.sol":                contract("Flat"),
+		"cache/Vault.sol":             contract("Vault"),
+		"scratch/Try.sol":             contract("Try"),
+		"script/Deploy.s.sol":         contract("Deploy"),
+		"lib/token/.gitignore":        "/build\n",
+		"lib/token/src/Token.sol":     contract("Token"),
+		"lib/token/build/Token.sol":   contract("Token"),
+		"lib/token/src/build/Fee.sol": contract("Fee"),
+	})
+	return NewState(), root
+}
+
+func indexedPaths(s *State) []string {
+	res := []string{}
+	for _, doc := range s.sortedDocuments() {
+		res = append(res, s.RelativePath(doc.URI))
+	}
+	slices.Sort(res)
+	return res
+}
+
+func Test_IndexWorkspaceIgnored(t *testing.T) {
+	s, root := newIgnoreWorkspace(t)
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	// The generated duplicate of Vault in out/ is ignored, while the
+	// flattened file is included by solbot.toml.
+	expected := []string{
+		"lib/token/src/Token.sol",
+		"lib/token/src/build/Fee.sol",
+		"out/Flat.sol",
+		"src/Vault.sol",
+		"src/generated/Keep.sol",
+	}
+	if got := indexedPaths(s); !slices.Equal(got, expected) {
+		t.Errorf("Expected the indexed files %v, got %v", expected, got)
+	}
+
+	w, err := s.NewWarmup(root, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if _, total := w.Progress(); total != 0 {
+		t.Errorf("Expected no files left to warm up, got %d", total)
+	}
+}
+
+func Test_WatchedFilesChanged(t *testing.T) {
+	s, root := newIgnoreWorkspace(t)
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	uri := func(name string) string {
+		return PathToURI(filepath.Join(root, name))
+	}
+	write := func(name, src string) {
+		writeWorkspace(t, root, map[string]string{name: src})
+	}
+
+	// The events of the ignored paths are dropped.
+	write("out/Vault.sol/Copy.sol", "pragma solidity ^0.8.0;\n\ncontract Vault {}\n")
+	write("src/Bank.sol", "pragma solidity ^0.8.0;\n\ncontract Bank {}\n")
+	if err := os.Remove(filepath.Join(root, "src/Vault.sol")); err != nil {
+		t.Fatal(err)
+	}
+	changed := s.WatchedFilesChanged([]lsp.FileEvent{
+		{URI: uri("out/Vault.sol/Copy.sol"), Type: lsp.FileCreated},
+		{URI: uri("src/Bank.sol"), Type: lsp.FileCreated},
+		{URI: uri("src/Vault.sol"), Type: lsp.FileDeleted},
+	})
+	if expected := []string{uri("src/Bank.sol"), uri("src/Vault.sol")}; !slices.Equal(changed, expected) {
+		t.Errorf("Expected the changed documents %v, got %v", expected, changed)
+	}
+	if _, ok := s.Documents[uri("out/Vault.sol/Copy.sol")]; ok {
+		t.Errorf("Expected the ignored file not to be indexed")
+	}
+	if _, ok := s.Documents[uri("src/Vault.sol")]; ok {
+		t.Errorf("Expected the deleted file to be dropped")
+	}
+
+	// The changed ignore file leaves the generated bindings out no more.
+	write("src/generated/.gitignore", "*.sol\n!Keep.sol\n!Bindings.sol\n")
+	write(".gitignore", "out/\ncache/\n*.tmp.sol\nlib/\n")
+	changed = s.WatchedFilesChanged([]lsp.FileEvent{
+		{URI: uri("src/generated/.gitignore"), Type: lsp.FileChanged},
+		{URI: uri(".gitignore"), Type: lsp.FileChanged},
+	})
+	got := []string{}
+	for _, u := range changed {
+		got = append(got, s.RelativePath(u))
+	}
+	expected := "lib/token/src/Token.sol lib/token/src/build/Fee.sol src/generated/Bindings.sol"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected the changed documents %q, got %q", expected, strings.Join(got, " "))
+	}
+	if _, ok := s.Documents[uri("src/generated/Bindings.sol")]; !ok {
+		t.Errorf("Expected the re-included file to be indexed")
+	}
+}
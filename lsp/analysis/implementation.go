@@ -0,0 +1,322 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// implementationDiagnostics reports the contracts that are not abstract, but
+// don't implement some of the functions declared in their interfaces and
+// abstract bases, with a single error listing all of them. A public state
+// variable implements the function with the signature of its getter e.g.
+// `uint256 public totalSupply` implements
+// `function totalSupply() external view returns (uint256)`.
+//
+// The inverse, a contract marked as abstract that implements everything it
+// inherits, is noted as needless-abstract: it could be made concrete unless
+// it's abstract only so that it's never deployed on its own.
+//
+// The functions are matched by their canonical signatures. Nothing is
+// reported for a contract whose bases can't be resolved, and a function is
+// assumed to be implemented if a same-named member has a parameter of an
+// unknown type.
+func (s *State) implementationDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, m := range s.missingImplementations(doc) {
+		res = append(res, m.diagnostic)
+	}
+	if s.isDependency(doc.URI) || slices.Contains(s.Config.Disabled, "needless-abstract") {
+		return res
+	}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Kind != token.CONTRACT || !c.Abstract || c.Name == nil {
+			continue
+		}
+		missing, complete := s.unimplemented(&Symbol{Doc: doc, Name: c.Name, Node: c})
+		if !complete || len(missing) > 0 {
+			continue
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(c.Name)),
+			Severity: lsp.SeverityInformation,
+			Code:     "needless-abstract",
+			Source:   "solbot",
+			Message:  fmt.Sprintf("`%s` is marked as abstract, but implements all the functions it declares and inherits; it could be made concrete", c.Name.Name),
+		})
+	}
+	return res
+}
+
+// missingImplementation is a contract that is not abstract, with the
+// functions it doesn't implement and the diagnostic listing them.
+type missingImplementation struct {
+	contract   *Symbol
+	functions  []declaredFunction
+	diagnostic lsp.Diagnostic
+}
+
+// missingImplementations returns the contracts of the document that don't
+// implement some of the functions they declare or inherit.
+func (s *State) missingImplementations(doc *Document) []missingImplementation {
+	res := []missingImplementation{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Kind != token.CONTRACT || c.Abstract || c.Name == nil {
+			continue
+		}
+		contract := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		missing, _ := s.unimplemented(contract)
+		if len(missing) == 0 {
+			continue
+		}
+		listed := []string{}
+		related := []lsp.DiagnosticRelatedInformation{}
+		for _, fn := range missing {
+			listed = append(listed, fmt.Sprintf("`%s` of `%s`", fn.signature, fn.contract.Name.Name))
+			related = append(related, lsp.DiagnosticRelatedInformation{
+				Location: lsp.Location{URI: fn.contract.Doc.URI, Range: toLspRange(fn.contract.Doc.Handle, ast.NodeRange(fn.fn.Name))},
+				Message:  fn.contract.Name.Name + "." + fn.signature,
+			})
+		}
+		pronoun := "it"
+		if len(missing) > 1 {
+			pronoun = "them"
+		}
+		res = append(res, missingImplementation{
+			contract:  contract,
+			functions: missing,
+			diagnostic: lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, ast.NodeRange(c.Name)),
+				Severity: lsp.SeverityError,
+				Code:     "missing-implementation",
+				Source:   "solbot",
+				Message: fmt.Sprintf("`%s` doesn't implement %s; implement %s or mark the contract as abstract",
+					c.Name.Name, strings.Join(listed, ", "), pronoun),
+				RelatedInformation: related,
+			},
+		})
+	}
+	return res
+}
+
+// implementationActions returns the quick fixes adding the stubs of the
+// functions the reported contracts don't implement before their closing
+// braces, see stubsEdit.
+func (s *State) implementationActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	for _, m := range s.missingImplementations(doc) {
+		if !touches(selected, toTokenRange(doc.Handle, m.diagnostic.Range)) {
+			continue
+		}
+		edit, ok := s.stubsEdit(m)
+		if !ok {
+			continue
+		}
+		actions = append(actions, lsp.CodeAction{
+			Title:       fmt.Sprintf("Add the stubs of the functions `%s` doesn't implement", m.contract.Name.Name),
+			Kind:        lsp.CodeActionQuickFix,
+			Diagnostics: []lsp.Diagnostic{m.diagnostic},
+			Edit:        &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{doc.URI: {edit}}},
+			IsPreferred: true,
+		})
+	}
+	return actions
+}
+
+// stubsEdit returns the edit inserting the stubs of the inherited functions
+// the contract doesn't implement after its last member: the signatures as
+// the bases declare them, overriding the most derived of the bases, with
+// the NatSpec inherited from the first of them and the bodies reverting.
+// The functions declared without a body by the contract itself are left
+// to the user. It returns false if there's nothing to insert.
+func (s *State) stubsEdit(m missingImplementation) (lsp.TextEdit, bool) {
+	doc := m.contract.Doc
+	c := m.contract.Node.(*ast.ContractDeclaration)
+	src := doc.Handle.Src()
+	if c.RightBrace <= c.LeftBrace || int(c.RightBrace) >= len(src) || src[c.RightBrace] != '}' {
+		return lsp.TextEdit{}, false
+	}
+
+	outer := lineIndent(src, c.Start())
+	indent, unit := outer+"    ", "    "
+	if len(c.Body) > 0 {
+		if member := lineIndent(src, c.Body[0].Start()); len(member) > len(outer) && strings.HasPrefix(member, outer) {
+			indent, unit = member, member[len(outer):]
+		}
+	}
+
+	stubs := []string{}
+	for _, fn := range m.functions {
+		if fn.contract.Node == m.contract.Node {
+			continue
+		}
+		fnSrc := fn.contract.Doc.Handle.Src()
+		params, returns := []string{}, []string{}
+		for _, list := range []struct {
+			params *ast.ParamList
+			res    *[]string
+		}{{fn.fn.Type.Params, &params}, {fn.fn.Type.Results, &returns}} {
+			if list.params == nil {
+				continue
+			}
+			for _, p := range list.params.List {
+				typ, ok := variableType(fnSrc, p)
+				if !ok {
+					return lsp.TextEdit{}, false
+				}
+				if p.Name != nil {
+					typ += " " + p.Name.Name
+				}
+				*list.res = append(*list.res, typ)
+			}
+		}
+
+		specifiers := []string{}
+		if visibility := visibilityName(fn.fn.Type.Visibility); visibility != "" {
+			specifiers = append(specifiers, visibility)
+		} else {
+			specifiers = append(specifiers, "public")
+		}
+		switch {
+		case fn.fn.Type.Mutability != 0:
+			specifiers = append(specifiers, mutabilityName(fn.fn.Type.Mutability))
+		case fn.fn.Type.Constant != 0:
+			specifiers = append(specifiers, "view")
+		}
+		bases := s.mostDerived(fn.declarers)
+		if len(bases) == 1 {
+			specifiers = append(specifiers, "override")
+		} else {
+			names := []string{}
+			for _, base := range bases {
+				names = append(names, base.Name.Name)
+			}
+			specifiers = append(specifiers, fmt.Sprintf("override(%s)", strings.Join(names, ", ")))
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s/// @inheritdoc %s\n", indent, bases[0].Name.Name)
+		fmt.Fprintf(&b, "%s%s {\n", indent, renderFunctionHeader(fn.fn.Name.Name, params, specifiers, returns))
+		fmt.Fprintf(&b, "%s%srevert(\"not implemented\");\n", indent, unit)
+		fmt.Fprintf(&b, "%s}", indent)
+		stubs = append(stubs, b.String())
+	}
+	if len(stubs) == 0 {
+		return lsp.TextEdit{}, false
+	}
+
+	// The stubs replace the blank space before the closing brace.
+	end := int(c.RightBrace)
+	start := end
+	for start > 0 && isSpace(src[start-1]) {
+		start--
+	}
+	prefix := "\n\n"
+	if src[start-1] == '{' {
+		prefix = "\n"
+	}
+	return lsp.TextEdit{
+		Range:   toLspRange(doc.Handle, token.Range{Start: token.Pos(start), End: token.Pos(end)}),
+		NewText: prefix + strings.Join(stubs, "\n\n") + "\n" + outer,
+	}, true
+}
+
+// mostDerived returns the contracts declaring the function that none of
+// the others inherits from, the ones an implementation has to name in its
+// override specifier. They are in the order of the declarers.
+func (s *State) mostDerived(declarers []*Symbol) []*Symbol {
+	res := []*Symbol{}
+	for _, base := range declarers {
+		derived := false
+		for _, other := range declarers {
+			if other.Node == base.Node {
+				continue
+			}
+			for _, ancestor := range s.ancestors(other)[1:] {
+				if ancestor.Node == base.Node {
+					derived = true
+				}
+			}
+		}
+		if !derived {
+			res = append(res, base)
+		}
+	}
+	return res
+}
+
+type declaredFunction struct {
+	contract  *Symbol
+	fn        *ast.FunctionDeclaration
+	signature string
+	declarers []*Symbol // contracts declaring the function without a body, the first one included
+}
+
+// unimplemented returns the functions without a body declared in the
+// contract or its bases, which are not implemented by any of them. They are
+// ordered from the contract to its most distant bases. The result is
+// complete if all the bases are resolved and all the signatures are known;
+// otherwise it only has the functions surely not implemented.
+func (s *State) unimplemented(contract *Symbol) ([]declaredFunction, bool) {
+	ancestors := s.ancestors(contract)
+	for _, c := range ancestors {
+		if len(s.bases(c.Doc, c.Node.(*ast.ContractDeclaration))) != len(c.Node.(*ast.ContractDeclaration).Bases) {
+			return nil, false
+		}
+	}
+
+	implemented := map[string]bool{}
+	unknown := map[string]bool{} // names of the members with unknown signatures
+	required := []declaredFunction{}
+	for _, c := range ancestors {
+		for _, decl := range c.Node.(*ast.ContractDeclaration).Body {
+			switch n := decl.(type) {
+			case *ast.FunctionDeclaration:
+				if n.Name == nil {
+					continue
+				}
+				sig, ok := s.functionSignature(c.Doc, n)
+				switch {
+				case !ok:
+					unknown[n.Name.Name] = true
+				case n.Body != nil:
+					implemented[sig] = true
+				default:
+					required = append(required, declaredFunction{contract: c, fn: n, signature: sig})
+				}
+			case *ast.VariableDeclaration:
+				g := s.getterOf(&Symbol{Doc: c.Doc, Name: n.Name, Node: n})
+				if g == nil {
+					continue
+				}
+				if sig, ok := s.getterSignature(g); ok {
+					implemented[sig] = true
+				} else {
+					unknown[n.Name.Name] = true
+				}
+			}
+		}
+	}
+
+	res := []declaredFunction{}
+	seen := map[string]int{}
+	for _, fn := range required {
+		name, _, _ := strings.Cut(fn.signature, "(")
+		if implemented[fn.signature] || unknown[name] {
+			continue
+		}
+		if i, ok := seen[fn.signature]; ok {
+			res[i].declarers = append(res[i].declarers, fn.contract)
+			continue
+		}
+		seen[fn.signature] = len(res)
+		fn.declarers = []*Symbol{fn.contract}
+		res = append(res, fn)
+	}
+	return res, len(unknown) == 0
+}
@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"solbot/lsp"
+	"testing"
+)
+
+func Test_ImplementationStubs(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/src/IVault.sol", 1, `pragma solidity ^0.8.0;
+
+interface IVault {
+    function deposit(uint256 amount, bytes calldata data) external payable;
+    function balanceOf(address account) external view returns (uint256);
+}
+`)
+	s.OpenDocument("file:///ws/src/IToken.sol", 1, `pragma solidity ^0.8.0;
+
+interface IToken {
+    function name() external view returns (string memory);
+}
+`)
+	uri := "file:///ws/src/Vault.sol"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+import "./IVault.sol";
+import "./IToken.sol";
+
+contract Vault is IVault, IToken {
+    mapping(address => uint256) public balanceOf;
+}
+`)
+
+	diagnostics := s.Diagnostics(context.Background(), uri).Params.Diagnostics
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	expected := "`Vault` doesn't implement `deposit(uint256,bytes)` of `IVault`, `name()` of `IToken`; implement them or mark the contract as abstract"
+	if d.Code != "missing-implementation" || d.Range.Start.Line != 5 || d.Message != expected {
+		t.Errorf("Expected %q at line 5, got %s %q at line %d", expected, d.Code, d.Message, d.Range.Start.Line)
+	}
+	if len(d.RelatedInformation) != 2 || d.RelatedInformation[1].Location.URI != "file:///ws/src/IToken.sol" {
+		t.Errorf("Expected the declarations in the related information, got %+v", d.RelatedInformation)
+	}
+
+	var stubs *lsp.CodeAction
+	actions := s.CodeAction(lsp.IntID(1), uri, d.Range).Result
+	for i := range actions {
+		if actions[i].Title == "Add the stubs of the functions `Vault` doesn't implement" {
+			stubs = &actions[i]
+		}
+	}
+	if stubs == nil || !stubs.IsPreferred {
+		t.Fatalf("Expected the preferred quick fix adding the stubs, got %v", stubs)
+	}
+	doc := s.Documents[uri]
+	fixed := applyEdits(doc, stubs.Edit.Changes[uri])
+	expected = `pragma solidity ^0.8.0;
+
+import "./IVault.sol";
+import "./IToken.sol";
+
+contract Vault is IVault, IToken {
+    mapping(address => uint256) public balanceOf;
+
+    /// @inheritdoc IVault
+    function deposit(uint256 amount, bytes calldata data) external payable override {
+        revert("not implemented");
+    }
+
+    /// @inheritdoc IToken
+    function name() external view override returns (string memory) {
+        revert("not implemented");
+    }
+}
+`
+	if fixed != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, fixed)
+	}
+
+	s.UpdateDocument(uri, 2, fixed)
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "missing-implementation" {
+			t.Errorf("Expected the stubs to implement the interfaces, got %s", d.Message)
+		}
+	}
+}
+
+func Test_ImplementationDiamond(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Diamond.sol"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+interface IPausable {
+    function pause() external;
+}
+
+abstract contract Pausable is IPausable {
+    function pause() external virtual override {}
+}
+
+abstract contract Guarded is IPausable {
+    function guard() internal view virtual;
+}
+
+contract Vault is Pausable, Guarded {
+    function guard() internal view override {}
+}
+
+abstract contract Base {
+    function owner() public pure returns (address) {
+        return address(0);
+    }
+}
+`)
+
+	got := []string{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code != "missing-implementation" && d.Code != "needless-abstract" {
+			continue
+		}
+		got = append(got, fmt.Sprintf("%d %d %s: %s", d.Range.Start.Line, d.Severity, d.Code, d.Message))
+	}
+	expected := []string{
+		"6 3 needless-abstract: `Pausable` is marked as abstract, but implements all the functions it declares and inherits; it could be made concrete",
+		"18 3 needless-abstract: `Base` is marked as abstract, but implements all the functions it declares and inherits; it could be made concrete",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected\n%v\ngot\n%v", expected, got)
+	}
+
+	s.Config.Disabled = []string{"needless-abstract"}
+	if diagnostics := s.implementationDiagnostics(s.Documents[uri]); len(diagnostics) != 0 {
+		t.Errorf("Expected the disabled check to be skipped, got %v", diagnostics)
+	}
+}
@@ -0,0 +1,168 @@
+package analysis
+
+import (
+	"slices"
+	"solbot/ast"
+)
+
+// ImportGraph is the graph of the imports between the indexed documents,
+// split into its strongly connected components. Import cycles are legal in
+// Solidity, so the analyses following the imports must either stop at the
+// documents they have already visited, or work on the components.
+type ImportGraph struct {
+	Imports    map[string][]string // file URI -> URIs of the indexed documents it imports, in the order of the directives
+	Components [][]string          // strongly connected components, each imported before its importers; the URIs of each are sorted
+
+	component map[string]int // file URI -> index of its component
+}
+
+// ImportGraph returns the import graph of the indexed documents. The
+// imports of the documents that are not indexed are left out.
+func (s *State) ImportGraph() *ImportGraph {
+	g := &ImportGraph{Imports: map[string][]string{}, component: map[string]int{}}
+	for _, doc := range s.sortedDocuments() {
+		imports := []string{}
+		if doc.File != nil {
+			for _, decl := range doc.File.Declarations {
+				imp, ok := decl.(*ast.ImportDirective)
+				if !ok {
+					continue
+				}
+				if target := s.ImportTarget(doc, imp); target != nil && !slices.Contains(imports, target.URI) {
+					imports = append(imports, target.URI)
+				}
+			}
+		}
+		g.Imports[doc.URI] = imports
+	}
+	g.Components = stronglyConnected(sortedKeys(g.Imports), g.Imports)
+	for i, component := range g.Components {
+		for _, uri := range component {
+			g.component[uri] = i
+		}
+	}
+	return g
+}
+
+// Component returns the documents importing each other with the document,
+// including itself; or nil if it's not indexed.
+func (g *ImportGraph) Component(uri string) []string {
+	i, ok := g.component[uri]
+	if !ok {
+		return nil
+	}
+	return g.Components[i]
+}
+
+// Cyclic reports whether the document imports itself, directly or through
+// the documents it imports.
+func (g *ImportGraph) Cyclic(uri string) bool {
+	return len(g.Component(uri)) > 1 || slices.Contains(g.Imports[uri], uri)
+}
+
+// Dependents returns the documents importing the document, directly or
+// through the other documents, in the order of the components: every
+// document comes after the ones it imports. The document itself is left
+// out, even if it's on a cycle.
+func (g *ImportGraph) Dependents(uri string) []string {
+	importers := map[string][]string{}
+	for importer, imports := range g.Imports {
+		for _, imported := range imports {
+			importers[imported] = append(importers[imported], importer)
+		}
+	}
+	affected := map[string]bool{uri: true}
+	queue := []string{uri}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, importer := range importers[next] {
+			if !affected[importer] {
+				affected[importer] = true
+				queue = append(queue, importer)
+			}
+		}
+	}
+	res := []string{}
+	for _, component := range g.Components {
+		for _, dependent := range component {
+			if affected[dependent] && dependent != uri {
+				res = append(res, dependent)
+			}
+		}
+	}
+	return res
+}
+
+// Cycles returns the components of the documents importing each other.
+func (g *ImportGraph) Cycles() [][]string {
+	res := [][]string{}
+	for _, component := range g.Components {
+		if g.Cyclic(component[0]) {
+			res = append(res, component)
+		}
+	}
+	return res
+}
+
+// stronglyConnected returns the strongly connected components of the graph
+// with Tarjan's algorithm, in the reverse topological order: every component
+// comes after the ones it has edges to. The nodes are visited in the given
+// order, and the search keeps its own stack, so that the long chains of the
+// imports don't grow the goroutine's one.
+func stronglyConnected(nodes []string, edges map[string][]string) [][]string {
+	type frame struct {
+		node string
+		next int // index of the next edge to follow
+	}
+	index, low := map[string]int{}, map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	res := [][]string{}
+	for _, root := range nodes {
+		if _, ok := index[root]; ok {
+			continue
+		}
+		frames := []frame{{node: root}}
+		index[root], low[root] = len(index), len(index)
+		stack, onStack[root] = append(stack, root), true
+		for len(frames) > 0 {
+			f := &frames[len(frames)-1]
+			if f.next < len(edges[f.node]) {
+				to := edges[f.node][f.next]
+				f.next++
+				if _, ok := index[to]; !ok {
+					index[to], low[to] = len(index), len(index)
+					stack, onStack[to] = append(stack, to), true
+					frames = append(frames, frame{node: to})
+				} else if onStack[to] {
+					low[f.node] = min(low[f.node], index[to])
+				}
+				continue
+			}
+
+			node := f.node
+			frames = frames[:len(frames)-1]
+			if len(frames) > 0 {
+				parent := frames[len(frames)-1].node
+				low[parent] = min(low[parent], low[node])
+			}
+			if low[node] != index[node] {
+				continue
+			}
+			component := []string{}
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == node {
+					break
+				}
+			}
+			slices.Sort(component)
+			res = append(res, component)
+		}
+	}
+	return res
+}
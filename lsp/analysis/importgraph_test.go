@@ -0,0 +1,166 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"solbot/lsp"
+	"testing"
+	"time"
+)
+
+func Test_MutuallyImportingFiles(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	s.Documents["file:///ws/src/IPool.sol"] = newDocument("file:///ws/src/IPool.sol", 0, false, `pragma solidity ^0.8.0;
+
+import "./IRouter.sol";
+
+struct Reserve {
+    uint256 amount;
+    Route route;
+}
+
+interface IPool {
+    function reserve() external view returns (Reserve memory);
+    function router() external view returns (IRouter);
+}
+`)
+	s.Documents["file:///ws/src/IRouter.sol"] = newDocument("file:///ws/src/IRouter.sol", 0, false, `pragma solidity ^0.8.0;
+
+import {IPool, Reserve} from "./IPool.sol";
+
+struct Route {
+    IPool[] pools;
+}
+
+interface IRouter {
+    function route(Reserve calldata reserve) external returns (Route memory);
+    function pool(uint256 i) external view returns (IPool);
+}
+`)
+
+	for _, uri := range []string{"file:///ws/src/IPool.sol", "file:///ws/src/IRouter.sol"} {
+		doc := s.Documents[uri]
+		if unresolved := s.unresolvedIdentifiers(doc); len(unresolved) != 0 {
+			t.Errorf("Expected no unresolved identifiers in %s, got %d e.g. `%s`", uri, len(unresolved), unresolved[0].Name)
+		}
+		for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+			if d.Severity == lsp.SeverityError {
+				t.Errorf("Expected no errors in %s, got %s: %s", uri, d.Code, d.Message)
+			}
+		}
+	}
+
+	// `Route` in the struct of IPool.sol is declared in IRouter.sol.
+	response := s.Definition(lsp.IntID(1), "file:///ws/src/IPool.sol", lsp.Position{Line: 6, Character: 4})
+	if response.Result == nil || len(*response.Result) != 1 || (*response.Result)[0].URI != "file:///ws/src/IRouter.sol" {
+		t.Errorf("Expected `Route` to be declared in IRouter.sol, got %v", response.Result)
+	}
+
+	g := s.ImportGraph()
+	expected := []string{"file:///ws/src/IPool.sol", "file:///ws/src/IRouter.sol"}
+	if !slices.Equal(g.Component("file:///ws/src/IPool.sol"), expected) || !g.Cyclic("file:///ws/src/IRouter.sol") {
+		t.Errorf("Expected the files to import each other, got %v", g.Components)
+	}
+}
+
+func Test_ImportGraphComponents(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	for name, src := range map[string]string{
+		"A.sol": `import "./B.sol";`,
+		"B.sol": `import "./C.sol"; import "./A.sol";`,
+		"C.sol": `import "./D.sol";`,
+		"D.sol": `import "./D.sol";`,
+		"E.sol": `import "./Missing.sol";`,
+	} {
+		uri := "file:///ws/" + name
+		s.Documents[uri] = newDocument(uri, 0, false, src)
+	}
+
+	g := s.ImportGraph()
+	expected := [][]string{
+		{"file:///ws/D.sol"},
+		{"file:///ws/C.sol"},
+		{"file:///ws/A.sol", "file:///ws/B.sol"},
+		{"file:///ws/E.sol"},
+	}
+	if len(g.Components) != len(expected) {
+		t.Fatalf("Expected %d components, got %v", len(expected), g.Components)
+	}
+	for i := range expected {
+		if !slices.Equal(g.Components[i], expected[i]) {
+			t.Errorf("Expected the component %d to be %v, got %v", i, expected[i], g.Components[i])
+		}
+	}
+
+	if dependents := g.Dependents("file:///ws/D.sol"); !slices.Equal(dependents, []string{"file:///ws/C.sol", "file:///ws/A.sol", "file:///ws/B.sol"}) {
+		t.Errorf("Expected C.sol, then A.sol and B.sol to depend on D.sol, got %v", dependents)
+	}
+	if dependents := g.Dependents("file:///ws/A.sol"); !slices.Equal(dependents, []string{"file:///ws/B.sol"}) {
+		t.Errorf("Expected only B.sol to depend on A.sol, got %v", dependents)
+	}
+
+	cycles := g.Cycles()
+	if len(cycles) != 2 || !slices.Equal(cycles[0], []string{"file:///ws/D.sol"}) {
+		t.Errorf("Expected the self-import and the cycle of A and B, got %v", cycles)
+	}
+	if g.Cyclic("file:///ws/C.sol") || g.Cyclic("file:///ws/E.sol") {
+		t.Errorf("Expected C.sol and E.sol not to be on a cycle")
+	}
+}
+
+func Test_LongImportCycle(t *testing.T) {
+	const n = 50
+	s := NewState()
+	s.Root = "/ws"
+	for i := 0; i < n; i++ {
+		uri := fmt.Sprintf("file:///ws/src/F%d.sol", i)
+		src := fmt.Sprintf(`pragma solidity ^0.8.0;
+
+import "./F%d.sol";
+
+struct S%d {
+    S%d next;
+}
+
+contract C%d is C%d {
+    function f(S%d memory s) external returns (uint256) {
+        return UNKNOWN;
+    }
+}
+`, (i+1)%n, i, (i+1)%n, i, (i+1)%n, (i+n-1)%n)
+		s.Documents[uri] = newDocument(uri, 0, false, src)
+	}
+
+	done := make(chan []lsp.Diagnostic)
+	go func() {
+		res := []lsp.Diagnostic{}
+		for i := 0; i < n; i++ {
+			res = append(res, s.Diagnostics(context.Background(), fmt.Sprintf("file:///ws/src/F%d.sol", i)).Params.Diagnostics...)
+		}
+		done <- res
+	}()
+	select {
+	case <-time.After(30 * time.Second):
+		t.Fatalf("Expected the diagnostics of the cycle of %d files to complete", n)
+	case diagnostics := <-done:
+		cyclic := 0
+		for _, d := range diagnostics {
+			if d.Code == "cyclic-inheritance" {
+				cyclic++
+			}
+		}
+		if cyclic != 1 {
+			t.Errorf("Expected the inheritance cycle to be reported once, got %d", cyclic)
+		}
+	}
+
+	if unresolved := s.unresolvedIdentifiers(s.Documents["file:///ws/src/F0.sol"]); len(unresolved) != 1 || unresolved[0].Name != "UNKNOWN" {
+		t.Errorf("Expected only `UNKNOWN` to be unresolved, got %d identifiers", len(unresolved))
+	}
+	if cycles := s.ImportGraph().Cycles(); len(cycles) != 1 || len(cycles[0]) != n {
+		t.Errorf("Expected one cycle of %d files, got %v", n, cycles)
+	}
+}
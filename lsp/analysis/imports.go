@@ -0,0 +1,428 @@
+package analysis
+
+import (
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// importEntry is an import directive of the file together with the
+// comments attached to it.
+type importEntry struct {
+	imp      *ast.ImportDirective
+	leading  []*ast.Comment // comments on the lines above the directive
+	trailing *ast.Comment   // comment after the directive on the same line; or nil
+}
+
+// importBlock returns the import directives of the file and the range they
+// span, including their comments. The comments between two directives are
+// attached to the second one, while the first directive gets only the
+// comments right above it, without a blank line in between. It returns
+// false if there are no imports or some other declaration is placed among
+// them.
+func importBlock(doc *Document) ([]importEntry, token.Range, bool) {
+	first, last := -1, -1
+	for i, decl := range doc.File.Declarations {
+		if _, ok := decl.(*ast.ImportDirective); ok {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return nil, token.Range{}, false
+	}
+
+	src := doc.Handle.Src()
+	entries := []importEntry{}
+	prevEnd := token.Pos(-1) // end of the previous directive and its trailing comment
+	for _, decl := range doc.File.Declarations[first : last+1] {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok {
+			return nil, token.Range{}, false
+		}
+		e := importEntry{imp: imp}
+		for i := len(doc.File.Comments) - 1; i >= 0; i-- {
+			c := doc.File.Comments[i]
+			if c.End() > imp.Start() {
+				continue
+			}
+			if prevEnd >= 0 {
+				if c.Start() < prevEnd {
+					break
+				}
+			} else if between := src[c.End():leadingEnd(e, imp)]; strings.Count(between, "\n") > 1 || strings.TrimSpace(between) != "" {
+				break
+			}
+			e.leading = append(e.leading, c)
+		}
+		slices.Reverse(e.leading)
+
+		end := imp.Semicolon + 1
+		for _, c := range doc.File.Comments {
+			if c.Start() >= end && !strings.Contains(src[end:c.Start()], "\n") && strings.TrimSpace(src[end:c.Start()]) == "" {
+				e.trailing = c
+				end = c.End()
+				break
+			}
+		}
+		prevEnd = end
+		entries = append(entries, e)
+	}
+
+	start := entries[0].imp.Start()
+	if len(entries[0].leading) > 0 {
+		start = entries[0].leading[0].Start()
+	}
+	return entries, token.Range{Start: start, End: prevEnd}, true
+}
+
+// leadingEnd returns the start of the directive or of its first leading
+// comment found so far.
+func leadingEnd(e importEntry, imp *ast.ImportDirective) token.Pos {
+	if len(e.leading) > 0 {
+		return e.leading[len(e.leading)-1].Start()
+	}
+	return imp.Start()
+}
+
+// organizedImport is a directive of the organized block.
+type organizedImport struct {
+	path     string // imported path without the quotes
+	quoted   string // imported path as written first
+	kind     int    // plainImport, unitImport or namedImport
+	text     string // source of the unit imports and the unresolved ones
+	symbols  []*ast.ImportSymbol
+	comments []*ast.Comment // leading comments
+	trailing []*ast.Comment
+}
+
+// The kinds of the organized imports, in the order they are sorted in for
+// the same path.
+const (
+	plainImport = iota // import "./Vault.sol";
+	unitImport         // import "./Vault.sol" as V; or import * as V from "./Vault.sol";
+	namedImport        // import {Vault} from "./Vault.sol";
+)
+
+// organizeImports rewrites the import block of the document: the duplicate
+// directives are removed, the named imports of the same path are merged,
+// the unused imports are dropped and the directives are sorted by their
+// paths, the remapped paths before the relative ones. With the named
+// imports enabled in solbot.toml, the plain imports are converted to the
+// named ones listing the used symbols. It returns the new block and the
+// range it replaces; or false if there is nothing to change.
+//
+// The imports whose target is not indexed are kept as they are. A file
+// imported by another one without naming the symbols re-exports everything
+// it imports, so none of its imports is dropped or converted then. Neither
+// are the plain imports of a file with unresolved names, since any of them
+// could be the one declaring them.
+func (s *State) organizeImports(doc *Document) (string, token.Range, bool) {
+	entries, block, ok := importBlock(doc)
+	if !ok {
+		return "", token.Range{}, false
+	}
+	uses := s.importUses(doc)
+	reexportsAll, reexported := s.importersOf(doc)
+	provable := !reexportsAll && len(s.unresolvedIdentifiers(doc)) == 0
+
+	imports := []*organizedImport{}
+	find := func(path string, kind int, text string) *organizedImport {
+		for _, o := range imports {
+			if o.path == path && o.kind == kind && o.text == text {
+				return o
+			}
+		}
+		return nil
+	}
+	add := func(e importEntry, kind int, text string, symbols []*ast.ImportSymbol) {
+		path := e.imp.Path.Value[1 : len(e.imp.Path.Value)-1]
+		o := find(path, kind, text)
+		if o == nil {
+			o = &organizedImport{path: path, quoted: e.imp.Path.Value, kind: kind, text: text}
+			imports = append(imports, o)
+		}
+		o.comments = append(o.comments, e.leading...)
+		if e.trailing != nil {
+			o.trailing = append(o.trailing, e.trailing)
+		}
+		for _, symbol := range symbols {
+			duplicate := slices.ContainsFunc(o.symbols, func(other *ast.ImportSymbol) bool {
+				return other.Name.Name == symbol.Name.Name && localName(other) == localName(symbol)
+			})
+			if !duplicate {
+				o.symbols = append(o.symbols, symbol)
+			}
+		}
+	}
+
+	src := doc.Handle.Src()
+	for _, e := range entries {
+		imp := e.imp
+		if imp.Path == nil || len(imp.Path.Value) < 2 {
+			return "", token.Range{}, false
+		}
+		target := s.ImportTarget(doc, imp)
+		text := src[imp.Start() : imp.Semicolon+1]
+		switch {
+		case target == nil:
+			add(e, unitImport, text, nil)
+		case imp.Alias != nil:
+			if len(uses[imp]) > 0 || reexportsAll || reexported[imp.Alias.Name] {
+				add(e, unitImport, text, nil)
+			}
+		case imp.Symbols == nil:
+			names := uses[imp]
+			switch {
+			case !provable:
+				add(e, plainImport, "", nil)
+			case len(names) == 0:
+				// Unused.
+			case s.Config.Imports.Named && s.declaresAll(target, names):
+				symbols := []*ast.ImportSymbol{}
+				for name := range names {
+					symbols = append(symbols, &ast.ImportSymbol{Name: &ast.Identifier{Name: name}})
+				}
+				add(e, namedImport, "", symbols)
+			default:
+				add(e, plainImport, "", nil)
+			}
+		default:
+			symbols := []*ast.ImportSymbol{}
+			for _, symbol := range imp.Symbols {
+				name := localName(symbol)
+				if uses[imp][name] || reexportsAll || reexported[name] {
+					symbols = append(symbols, symbol)
+				}
+			}
+			if len(symbols) > 0 {
+				add(e, namedImport, "", symbols)
+			}
+		}
+	}
+
+	// The plain import of a path makes its named imports redundant, unless
+	// they rename the symbols.
+	for _, o := range imports {
+		if o.kind != namedImport || find(o.path, plainImport, "") == nil {
+			continue
+		}
+		o.symbols = slices.DeleteFunc(o.symbols, func(symbol *ast.ImportSymbol) bool { return symbol.Alias == nil })
+	}
+	imports = slices.DeleteFunc(imports, func(o *organizedImport) bool {
+		return o.kind == namedImport && len(o.symbols) == 0
+	})
+
+	slices.SortStableFunc(imports, func(a, b *organizedImport) int {
+		if s.Config.Imports.Groups && IsRelativeImport(a.path) != IsRelativeImport(b.path) {
+			if IsRelativeImport(b.path) {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(a.path, b.path); c != 0 {
+			return c
+		}
+		return a.kind - b.kind
+	})
+
+	var b strings.Builder
+	for i, o := range imports {
+		if i > 0 {
+			b.WriteString("\n")
+			if s.Config.Imports.Groups && IsRelativeImport(o.path) != IsRelativeImport(imports[i-1].path) {
+				b.WriteString("\n")
+			}
+		}
+		o.write(&b)
+	}
+	if b.String() == src[block.Start:block.End] {
+		return "", token.Range{}, false
+	}
+	return b.String(), block, true
+}
+
+// write writes the directive with its comments.
+func (o *organizedImport) write(b *strings.Builder) {
+	for _, c := range o.comments {
+		b.WriteString(c.Text + "\n")
+	}
+	switch o.kind {
+	case plainImport:
+		b.WriteString("import " + o.quoted + ";")
+	case unitImport:
+		b.WriteString(o.text)
+	case namedImport:
+		slices.SortFunc(o.symbols, func(a, b *ast.ImportSymbol) int {
+			if c := strings.Compare(a.Name.Name, b.Name.Name); c != 0 {
+				return c
+			}
+			return strings.Compare(localName(a), localName(b))
+		})
+		names := []string{}
+		for _, symbol := range o.symbols {
+			if symbol.Alias != nil {
+				names = append(names, symbol.Name.Name+" as "+symbol.Alias.Name)
+			} else {
+				names = append(names, symbol.Name.Name)
+			}
+		}
+		b.WriteString("import {" + strings.Join(names, ", ") + "} from " + o.quoted + ";")
+	}
+	for _, c := range o.trailing {
+		b.WriteString(" " + c.Text)
+	}
+}
+
+// localName returns the name the imported symbol is known by in the file.
+func localName(symbol *ast.ImportSymbol) string {
+	if symbol.Alias != nil {
+		return symbol.Alias.Name
+	}
+	return symbol.Name.Name
+}
+
+// importUses returns the names each import directive brings into the
+// document that are used by its code.
+func (s *State) importUses(doc *Document) map[*ast.ImportDirective]map[string]bool {
+	uses := map[*ast.ImportDirective]map[string]bool{}
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		for _, node := range path {
+			if _, ok := node.(*ast.ImportDirective); ok {
+				return
+			}
+		}
+		if access, ok := path[1].(*ast.MemberAccessExpression); ok && access.Member == ident {
+			return
+		}
+		// Only the names found in the file scope come from the imports, the
+		// others are the locals or the members.
+		sym := s.resolve(doc, path)
+		global := s.lookupFile(doc, ident.Name, map[*Document]bool{})
+		if sym == nil || global == nil || sym.Node != global.Node {
+			return
+		}
+		if imp := s.importOf(doc, ident.Name); imp != nil {
+			if uses[imp] == nil {
+				uses[imp] = map[string]bool{}
+			}
+			uses[imp][ident.Name] = true
+		}
+	})
+	return uses
+}
+
+// importOf returns the directive the name of the file scope is imported
+// with, in the order lookupFile searches them; or nil if the name is
+// declared in the document itself or not found.
+func (s *State) importOf(doc *Document, name string) *ast.ImportDirective {
+	for _, decl := range doc.File.Declarations {
+		if id := declaredName(decl); id != nil && id.Name == name {
+			return nil
+		}
+	}
+	plain := []*ast.ImportDirective{}
+	for _, decl := range doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok {
+			continue
+		}
+		if imp.Alias != nil {
+			if imp.Alias.Name == name {
+				return imp
+			}
+			continue
+		}
+		for _, symbol := range imp.Symbols {
+			if localName(symbol) == name {
+				return imp
+			}
+		}
+		if imp.Symbols == nil {
+			plain = append(plain, imp)
+		}
+	}
+	for _, imp := range plain {
+		target := s.ImportTarget(doc, imp)
+		if target != nil && s.lookupFile(target, name, map[*Document]bool{doc: true}) != nil {
+			return imp
+		}
+	}
+	return nil
+}
+
+// declaresAll reports whether all of the names are declared by the target
+// itself. The names it imports from the other files can't be proven to be
+// importable by name e.g. if it renames them.
+func (s *State) declaresAll(target *Document, names map[string]bool) bool {
+	for name := range names {
+		sym := s.lookupFile(target, name, map[*Document]bool{})
+		if sym == nil || sym.Doc != target {
+			return false
+		}
+		switch sym.Node.(type) {
+		case *ast.ImportDirective, *ast.ImportSymbol:
+			return false
+		}
+	}
+	return true
+}
+
+// importersOf returns the names the other documents import from the
+// document by name, and whether any of them imports all of its names with
+// a plain or a unit import.
+func (s *State) importersOf(doc *Document) (bool, map[string]bool) {
+	all, names := false, map[string]bool{}
+	for _, other := range s.sortedDocuments() {
+		if other == doc {
+			continue
+		}
+		for _, decl := range other.File.Declarations {
+			imp, ok := decl.(*ast.ImportDirective)
+			if !ok || s.ImportTarget(other, imp) != doc {
+				continue
+			}
+			if imp.Symbols == nil {
+				all = true
+			}
+			for _, symbol := range imp.Symbols {
+				names[symbol.Name.Name] = true
+			}
+		}
+	}
+	return all, names
+}
+
+// organizeImportsActions offers to organize the imports of the document,
+// wherever the selection is.
+func (s *State) organizeImportsActions(doc *Document) []lsp.CodeAction {
+	text, r, ok := s.organizeImports(doc)
+	if !ok {
+		return []lsp.CodeAction{}
+	}
+	edit := lsp.TextEdit{Range: toLspRange(doc.Handle, r), NewText: text}
+	return []lsp.CodeAction{{
+		Title: "Organize imports",
+		Kind:  lsp.CodeActionSourceOrganizeImports,
+		Edit:  &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{doc.URI: {edit}}},
+	}}
+}
+
+// OrganizeImports returns the content of the document with its imports
+// organized, see organizeImports; or false if there is nothing to change.
+func (s *State) OrganizeImports(uri string) (string, bool) {
+	doc, ok := s.document(uri)
+	if !ok {
+		return "", false
+	}
+	text, r, ok := s.organizeImports(doc)
+	if !ok {
+		return "", false
+	}
+	src := doc.Handle.Src()
+	return src[:r.Start] + text + src[r.End:], true
+}
@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"solbot/project"
+	"strings"
+	"testing"
+)
+
+// importsState indexes a project with a remapped dependency and the local
+// files the fixtures import.
+func importsState() *State {
+	s := NewState()
+	s.Root = "/ws"
+	s.Config.Remappings = []project.Remapping{{Prefix: "@oz/", Target: "lib/openzeppelin/"}}
+	s.OpenDocument("file:///ws/lib/openzeppelin/token/IERC20.sol", 1, "interface IERC20 {}\n")
+	s.OpenDocument("file:///ws/lib/openzeppelin/access/Ownable.sol", 1, "abstract contract Ownable {}\n")
+	s.OpenDocument("file:///ws/src/Math.sol", 1, "library Math {\n    uint256 constant MAX = 1;\n}\nlibrary SafeCast {}\n")
+	s.OpenDocument("file:///ws/src/Errors.sol", 1, "error Unauthorized();\nerror Expired();\n")
+	s.OpenDocument("file:///ws/src/Unused.sol", 1, "contract Unused {}\n")
+	return s
+}
+
+const messyImports = `// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+import {SafeCast} from "./Math.sol";
+import "./Errors.sol";
+// The token standard.
+import {IERC20} from "@oz/token/IERC20.sol"; // dependency
+import {Math} from "./Math.sol";
+import "./Unused.sol";
+import {Ownable} from "@oz/access/Ownable.sol";
+import "./Errors.sol";
+import {Math} from "./Math.sol";
+
+contract Vault is Ownable {
+    using SafeCast for uint256;
+
+    IERC20 token;
+
+    function pay() external {
+        if (Math.MAX == 0) revert Unauthorized();
+    }
+}
+`
+
+func Test_OrganizeImports(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := importsState()
+	s.OpenDocument(uri, 1, messyImports)
+	unresolved := len(s.unresolvedIdentifiers(s.Documents[uri]))
+
+	src, ok := s.OrganizeImports(uri)
+	if !ok {
+		t.Fatalf("Expected the imports to be organized")
+	}
+	expected := `import {Ownable} from "@oz/access/Ownable.sol";
+// The token standard.
+import {IERC20} from "@oz/token/IERC20.sol"; // dependency
+
+import "./Errors.sol";
+import {Math, SafeCast} from "./Math.sol";
+
+contract Vault is Ownable {`
+	if !strings.Contains(src, "pragma solidity ^0.8.0;\n\n"+expected) {
+		t.Errorf("Expected the imports:\n%s\ngot:\n%s", expected, src)
+	}
+
+	// The organized file resolves the same names.
+	s.UpdateDocument(uri, 2, src)
+	if n := len(s.unresolvedIdentifiers(s.Documents[uri])); n != unresolved {
+		t.Errorf("Expected %d unresolved names, got %d", unresolved, n)
+	}
+	if _, ok := s.OrganizeImports(uri); ok {
+		t.Errorf("Expected the organized imports to stay as they are")
+	}
+}
+
+func Test_OrganizeImportsToNamed(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := importsState()
+	s.Config.Imports = project.Imports{Groups: false, Named: true}
+	s.OpenDocument(uri, 1, messyImports)
+
+	src, ok := s.OrganizeImports(uri)
+	if !ok {
+		t.Fatalf("Expected the imports to be organized")
+	}
+	expected := `import {Unauthorized} from "./Errors.sol";
+import {Math, SafeCast} from "./Math.sol";
+import {Ownable} from "@oz/access/Ownable.sol";
+// The token standard.
+import {IERC20} from "@oz/token/IERC20.sol"; // dependency
+`
+	if !strings.Contains(src, expected) {
+		t.Errorf("Expected the imports:\n%s\ngot:\n%s", expected, src)
+	}
+
+	// A file re-exporting its imports keeps them all.
+	s.OpenDocument("file:///ws/src/Router.sol", 1, `import "./Vault.sol";`)
+	src, _ = s.OrganizeImports(uri)
+	if !strings.Contains(src, `import "./Unused.sol";`) || !strings.Contains(src, `import "./Errors.sol";`) {
+		t.Errorf("Expected the plain imports to be kept, got:\n%s", src)
+	}
+}
+
+func Test_OrganizeImportsAction(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := importsState()
+	s.OpenDocument(uri, 1, messyImports)
+
+	cursor := lsp.Position{Line: 20, Character: 0}
+	actions := s.CodeAction(lsp.IntID(1), uri, lsp.Range{Start: cursor, End: cursor}).Result
+	if len(actions) != 1 || actions[0].Kind != lsp.CodeActionSourceOrganizeImports {
+		t.Fatalf("Expected the organize imports action, got %v", actions)
+	}
+	edits := actions[0].Edit.Changes[uri]
+	if len(edits) != 1 || edits[0].Range.Start.Line != 3 || edits[0].Range.End.Line != 11 {
+		t.Errorf("Expected the edit of the lines 3 to 11, got %v", edits)
+	}
+}
@@ -0,0 +1,106 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+)
+
+// inheritanceLink is a contract on a path of the inheritance graph together
+// with the specifier naming its next base on the path.
+type inheritanceLink struct {
+	contract  *Symbol
+	specifier *ast.InheritanceSpecifier
+}
+
+// cyclicInheritanceDiagnostics reports the contracts inheriting from
+// themselves through their bases e.g. `A is B` in A.sol and `B is A` in
+// B.sol. The compiler rejects them, and the analyses linearizing the bases
+// skip them. A cycle is reported once, on the specifier of the first of its
+// contracts by their files and positions, with the specifiers of the other
+// ones as the related information.
+func (s *State) cyclicInheritanceDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || len(c.Bases) == 0 {
+			continue
+		}
+		contract := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		cycle := s.inheritanceCycle(contract)
+		if cycle == nil || !firstOfCycle(contract, cycle) {
+			continue
+		}
+
+		names := []string{}
+		related := []lsp.DiagnosticRelatedInformation{}
+		for i, link := range cycle {
+			names = append(names, link.contract.Name.Name)
+			if i == 0 {
+				continue
+			}
+			next := cycle[(i+1)%len(cycle)].contract.Name.Name
+			related = append(related, lsp.DiagnosticRelatedInformation{
+				Location: lsp.Location{URI: link.contract.Doc.URI, Range: toLspRange(link.contract.Doc.Handle, ast.NodeRange(link.specifier.Name))},
+				Message:  fmt.Sprintf("`%s` inherits from `%s`", link.contract.Name.Name, next),
+			})
+		}
+		names = append(names, c.Name.Name)
+		res = append(res, lsp.Diagnostic{
+			Range:              toLspRange(doc.Handle, ast.NodeRange(cycle[0].specifier.Name)),
+			Severity:           lsp.SeverityError,
+			Code:               "cyclic-inheritance",
+			Source:             "solbot",
+			Message:            "cyclic inheritance: " + strings.Join(names, " -> "),
+			RelatedInformation: related,
+		})
+	}
+	return res
+}
+
+// inheritanceCycle returns the path of the inheritance graph leading from
+// the contract back to itself, starting with the contract; or nil if it
+// doesn't inherit from itself. The bases are searched in the order of the
+// inheritance lists, every contract once.
+func (s *State) inheritanceCycle(contract *Symbol) []inheritanceLink {
+	visited := map[ast.Node]bool{}
+	var search func(c *Symbol, path []inheritanceLink) []inheritanceLink
+	search = func(c *Symbol, path []inheritanceLink) []inheritanceLink {
+		decl := c.Node.(*ast.ContractDeclaration)
+		for _, spec := range decl.Bases {
+			base := s.follow(s.resolveExpr(c.Doc, []ast.Node{c.Doc.File}, spec.Name))
+			if base == nil {
+				continue
+			}
+			if _, ok := base.Node.(*ast.ContractDeclaration); !ok {
+				continue
+			}
+			next := append(path[:len(path):len(path)], inheritanceLink{contract: c, specifier: spec})
+			if base.Node == contract.Node {
+				return next
+			}
+			if visited[base.Node] {
+				continue
+			}
+			visited[base.Node] = true
+			if cycle := search(base, next); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return search(contract, nil)
+}
+
+// firstOfCycle reports whether the contract comes first of the contracts
+// on the cycle, by the URIs of their documents and their positions.
+func firstOfCycle(contract *Symbol, cycle []inheritanceLink) bool {
+	for _, link := range cycle {
+		other := link.contract
+		if other.Doc.URI < contract.Doc.URI || other.Doc.URI == contract.Doc.URI && other.Name.Start() < contract.Name.Start() {
+			return false
+		}
+	}
+	return true
+}
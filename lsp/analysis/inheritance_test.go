@@ -0,0 +1,77 @@
+package analysis
+
+import (
+	"context"
+	"solbot/lsp"
+	"testing"
+)
+
+func Test_CyclicInheritance(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	for uri, src := range map[string]string{
+		"file:///ws/src/A.sol": `pragma solidity ^0.8.0;
+
+import "./C.sol";
+
+contract A is C {}
+`,
+		"file:///ws/src/B.sol": `pragma solidity ^0.8.0;
+
+import "./A.sol";
+
+contract B is A {}
+
+contract D is B {}
+`,
+		"file:///ws/src/C.sol": `pragma solidity ^0.8.0;
+
+import "./B.sol";
+
+contract Base {}
+
+contract C is Base, B {}
+`,
+	} {
+		s.Documents[uri] = newDocument(uri, 0, false, src)
+	}
+
+	errors := []lsp.Diagnostic{}
+	uris := []string{}
+	for _, uri := range []string{"file:///ws/src/A.sol", "file:///ws/src/B.sol", "file:///ws/src/C.sol"} {
+		for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+			if d.Code == "cyclic-inheritance" {
+				errors = append(errors, d)
+				uris = append(uris, uri)
+			}
+		}
+	}
+	if len(errors) != 1 {
+		t.Fatalf("Expected 1 cyclic inheritance error, got %d: %v", len(errors), errors)
+	}
+	d := errors[0]
+	if uris[0] != "file:///ws/src/A.sol" || d.Severity != lsp.SeverityError || d.Message != "cyclic inheritance: A -> C -> B -> A" {
+		t.Errorf("Expected the cycle to be reported in A.sol, got %q in %s", d.Message, uris[0])
+	}
+	if expected := (lsp.Range{Start: lsp.Position{Line: 4, Character: 14}, End: lsp.Position{Line: 4, Character: 15}}); d.Range != expected {
+		t.Errorf("Expected the range %v, got %v", expected, d.Range)
+	}
+	expected := []lsp.DiagnosticRelatedInformation{
+		{
+			Location: lsp.Location{URI: "file:///ws/src/C.sol", Range: lsp.Range{Start: lsp.Position{Line: 6, Character: 20}, End: lsp.Position{Line: 6, Character: 21}}},
+			Message:  "`C` inherits from `B`",
+		},
+		{
+			Location: lsp.Location{URI: "file:///ws/src/B.sol", Range: lsp.Range{Start: lsp.Position{Line: 4, Character: 14}, End: lsp.Position{Line: 4, Character: 15}}},
+			Message:  "`B` inherits from `A`",
+		},
+	}
+	if len(d.RelatedInformation) != len(expected) {
+		t.Fatalf("Expected %d related locations, got %v", len(expected), d.RelatedInformation)
+	}
+	for i := range expected {
+		if d.RelatedInformation[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], d.RelatedInformation[i])
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// InlayHint returns the hints in the visible range of the document. The
+// categories are turned on and off in solbot.toml. The lines longer than
+// Limits.MaxLineLength have no hints.
+func (s *State) InlayHint(id lsp.ID, uri string, r lsp.Range) lsp.InlayHintResponse {
+	hints := []lsp.InlayHint{}
+	doc, ok := s.document(uri)
+	if !ok {
+		return lsp.NewInlayHintResponse(id, hints)
+	}
+
+	visible := token.Range{Start: toTokenPos(doc.Handle, r.Start), End: toTokenPos(doc.Handle, r.End)}
+	if s.Config.InlayHints.Numbers {
+		skip := func(pos token.Pos) bool { return s.isLongLine(doc, pos, "inlay hints") }
+		for _, hint := range numberHints(doc.File, skip) {
+			if visible.Start <= hint.pos && hint.pos <= visible.End {
+				hints = append(hints, lsp.InlayHint{
+					Position:    toLspPosition(doc.Handle, hint.pos),
+					Label:       "= " + hint.label,
+					PaddingLeft: true,
+				})
+			}
+		}
+	}
+	return lsp.NewInlayHintResponse(id, hints)
+}
+
+type numberHint struct {
+	pos   token.Pos // end of the number
+	label string
+}
+
+// numberHints returns the readable forms of the large number literals and
+// of the constant expressions e.g. "1 ether" for 1000000000000000000 and
+// for 10**18. The numbers already written in a readable way, with the
+// underscores, the units or the exponents, are skipped together with the
+// expressions containing them. So are the hex literals of the addresses,
+// and the numbers at the positions skipped by the function.
+func numberHints(file *ast.File, skip func(token.Pos) bool) []numberHint {
+	res := []numberHint{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.BasicLit, *ast.BinaryExpression:
+			if skip(node.Start()) {
+				return false
+			}
+		}
+		switch n := node.(type) {
+		case *ast.BasicLit:
+			if !isPlainNumber(n) {
+				return false
+			}
+			value, ok := literalValue(n)
+			if !ok {
+				return false
+			}
+			decimal := n.Kind == token.DECIMAL_NUMBER
+			if !decimal && len(n.Value) == len("0x")+40 {
+				return false
+			}
+			if label := readableNumber(value, true, decimal); label != "" {
+				res = append(res, numberHint{pos: n.End(), label: label})
+			}
+			return false
+		case *ast.BinaryExpression:
+			value, ok := constInt(n)
+			if !ok {
+				return true
+			}
+			if hasReadableNumbers(n) {
+				return false
+			}
+			if label := readableNumber(value, false, false); label != "" {
+				res = append(res, numberHint{pos: n.End(), label: label})
+			}
+			return false
+		}
+		return true
+	})
+	return res
+}
+
+// isPlainNumber reports whether the literal is a number written without
+// the underscores, the unit, the fraction and the exponent.
+func isPlainNumber(lit *ast.BasicLit) bool {
+	switch lit.Kind {
+	case token.DECIMAL_NUMBER:
+		return lit.Unit == nil && !strings.ContainsAny(lit.Value, "_.eE")
+	case token.HEX_NUMBER:
+		return lit.Unit == nil && !strings.Contains(lit.Value, "_")
+	}
+	return false
+}
+
+// hasReadableNumbers reports whether some of the numbers in the expression
+// are not plain.
+func hasReadableNumbers(x ast.Expression) bool {
+	found := false
+	ast.Inspect(x, func(node ast.Node) bool {
+		if lit, ok := node.(*ast.BasicLit); ok && !isPlainNumber(lit) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+var (
+	bigGwei    = big.NewInt(1e9)
+	bigFinney  = big.NewInt(1e15)
+	bigEther   = big.NewInt(1e18)
+	bigMillion = big.NewInt(1e6)
+)
+
+// readableNumber chooses the best human form of the value: a multiple of
+// ether or gwei, a power of ten, a power of two with a small offset or the
+// digits grouped with underscores. It returns an empty string for the small
+// values and for the ones without a better form.
+func readableNumber(value *big.Int, powersOfTwo, grouped bool) string {
+	if value.Cmp(bigMillion) < 0 {
+		return ""
+	}
+
+	// Multiples of 0.001 ether, below a million ether.
+	if new(big.Int).Rem(value, bigFinney).Sign() == 0 && value.Cmp(new(big.Int).Mul(bigEther, bigMillion)) < 0 {
+		whole, frac := new(big.Int).QuoRem(value, bigEther, new(big.Int))
+		if frac.Sign() == 0 {
+			return whole.String() + " ether"
+		}
+		digits := fmt.Sprintf("%03d", new(big.Int).Quo(frac, bigFinney).Int64())
+		return whole.String() + "." + strings.TrimRight(digits, "0") + " ether"
+	}
+	if new(big.Int).Rem(value, bigGwei).Sign() == 0 && value.Cmp(bigFinney) < 0 {
+		return new(big.Int).Quo(value, bigGwei).String() + " gwei"
+	}
+
+	digits := value.String()
+	if strings.TrimRight(digits, "0") == "1" {
+		return fmt.Sprintf("1e%d", len(digits)-1)
+	}
+
+	if powersOfTwo && value.BitLen() > 24 {
+		// The nearest power of two is either below or above the value.
+		for _, n := range []int{value.BitLen() - 1, value.BitLen()} {
+			power := new(big.Int).Lsh(big.NewInt(1), uint(n))
+			offset := new(big.Int).Sub(value, power)
+			switch {
+			case offset.Sign() == 0:
+				return fmt.Sprintf("2^%d", n)
+			case offset.CmpAbs(big.NewInt(1<<16)) <= 0 && offset.Sign() < 0:
+				return fmt.Sprintf("2^%d - %s", n, offset.Neg(offset))
+			case offset.CmpAbs(big.NewInt(1<<16)) <= 0:
+				return fmt.Sprintf("2^%d + %s", n, offset)
+			}
+		}
+	}
+
+	if !grouped {
+		return ""
+	}
+	var b strings.Builder
+	for i, ch := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"math/big"
+	"solbot/lsp"
+	"testing"
+)
+
+func Test_NumberInlayHints(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+contract Units {
+    uint256 constant RAW = 1000000000000000000;
+    uint256 constant POWER = 10**18;
+    uint256 constant GROUPED = 1_000_000;
+    uint256 constant UNIT = 3 ether;
+}
+`
+
+	s := NewState()
+	s.OpenDocument("file:///ws/src/Units.sol", 1, src)
+	visible := lsp.Range{End: lsp.Position{Line: 8}}
+
+	hints := s.InlayHint(lsp.IntID(1), "file:///ws/src/Units.sol", visible).Result
+	expected := []lsp.InlayHint{
+		{Position: lsp.Position{Line: 3, Character: 46}, Label: "= 1 ether", PaddingLeft: true},
+		{Position: lsp.Position{Line: 4, Character: 35}, Label: "= 1 ether", PaddingLeft: true},
+	}
+	if len(hints) != len(expected) {
+		t.Fatalf("Expected %d hints, got %v", len(expected), hints)
+	}
+	for i, hint := range hints {
+		if hint != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], hint)
+		}
+	}
+
+	s.Config.InlayHints.Numbers = false
+	if hints := s.InlayHint(lsp.IntID(2), "file:///ws/src/Units.sol", visible).Result; len(hints) != 0 {
+		t.Errorf("Expected no hints with the category disabled, got %v", hints)
+	}
+}
+
+func Test_ReadableNumber(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"999999", ""},
+		{"2500000000000000000", "2.5 ether"},
+		{"50000000000000000", "0.05 ether"},
+		{"30000000000", "30 gwei"},
+		{"100000000", "1e8"},
+		{"57896044618658097711785492504343953926634992332820282019728792003956564819949", "2^255 - 19"},
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639935", "2^256 - 1"},
+		{"12345678", "12_345_678"},
+	}
+
+	for _, tt := range tests {
+		value, _ := new(big.Int).SetString(tt.value, 10)
+		if got := readableNumber(value, true, true); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.value, tt.expected, got)
+		}
+	}
+}
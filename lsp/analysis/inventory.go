@@ -0,0 +1,143 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"path/filepath"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/metrics"
+	"strings"
+)
+
+// Inventory returns the source files in the file or directory for the
+// scope section of the audit reports, grouped by directory in the order
+// of the paths. The dependencies are listed separately, if they're
+// included, and left out of the totals and of the licenses.
+func (s *State) Inventory(root string, includeDependencies bool) lsp.Inventory {
+	inventory := lsp.Inventory{Directories: []lsp.InventoryDirectory{}, Licenses: []lsp.InventoryLicense{}}
+	licenses := map[string]int{}
+	for _, doc := range s.sortedDocuments() {
+		rel, err := filepath.Rel(root, URIToPath(doc.URI))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		file := s.sourceFile(doc)
+		if !s.isDependency(doc.URI) {
+			inventory.Directories = addToDirectory(inventory.Directories, file)
+			addToTotals(&inventory.Totals, file)
+			licenses[file.License]++
+		} else if includeDependencies {
+			inventory.Dependencies = addToDirectory(inventory.Dependencies, file)
+		}
+	}
+	for _, dirs := range [][]lsp.InventoryDirectory{inventory.Directories, inventory.Dependencies} {
+		slices.SortFunc(dirs, func(a, b lsp.InventoryDirectory) int { return strings.Compare(a.Path, b.Path) })
+		for _, dir := range dirs {
+			slices.SortFunc(dir.Files, func(a, b lsp.InventoryFile) int { return strings.Compare(a.Path, b.Path) })
+		}
+	}
+	for license, n := range licenses {
+		inventory.Licenses = append(inventory.Licenses, lsp.InventoryLicense{License: license, Files: n})
+	}
+	slices.SortFunc(inventory.Licenses, func(a, b lsp.InventoryLicense) int {
+		if a.Files != b.Files {
+			return b.Files - a.Files
+		}
+		return strings.Compare(a.License, b.License)
+	})
+	return inventory
+}
+
+// InventoryReport returns the inventory of the whole workspace.
+func (s *State) InventoryReport(id lsp.ID, params lsp.InventoryParams) lsp.InventoryResponse {
+	return lsp.NewInventoryResponse(id, s.Inventory(s.Root, params.IncludeDependencies))
+}
+
+// addToDirectory adds the file to its directory, which is added if it's
+// not there yet.
+func addToDirectory(dirs []lsp.InventoryDirectory, file lsp.InventoryFile) []lsp.InventoryDirectory {
+	dir := path.Dir(file.Path)
+	i := slices.IndexFunc(dirs, func(d lsp.InventoryDirectory) bool { return d.Path == dir })
+	if i < 0 {
+		dirs, i = append(dirs, lsp.InventoryDirectory{Path: dir}), len(dirs)
+	}
+	dirs[i].Files = append(dirs[i].Files, file)
+	addToTotals(&dirs[i].Totals, file)
+	return dirs
+}
+
+func addToTotals(totals *lsp.InventoryTotals, file lsp.InventoryFile) {
+	totals.Files++
+	totals.Lines += file.Lines
+	totals.SLOC += file.SLOC
+	totals.CommentLines += file.CommentLines
+}
+
+// sourceFile describes the document for the inventory. The lines are
+// counted from the tokens with the comments, so that the comments and the
+// blank lines are not counted as the code.
+func (s *State) sourceFile(doc *Document) lsp.InventoryFile {
+	src := doc.Handle.Src()
+	hash := sha256.Sum256([]byte(src))
+	lines := metrics.CountLines(doc.Handle, doc.tokens())
+	file := lsp.InventoryFile{
+		URI:          doc.URI,
+		Path:         s.RelativePath(doc.URI),
+		License:      doc.File.License(),
+		Lines:        lines.Total,
+		SLOC:         lines.Code,
+		CommentLines: lines.Comment,
+		Hash:         hex.EncodeToString(hash[:]),
+	}
+	if p := doc.File.Pragma("solidity"); p != nil {
+		file.Pragma = p.Value
+	}
+	authors := []string{}
+	for _, tag := range parseNatSpec(natSpecText(fileNatSpecComments(doc))) {
+		text := strings.ReplaceAll(tag.Text, "\n", " ")
+		switch tag.Kind {
+		case "title":
+			file.Title = text
+		case "author":
+			authors = append(authors, text)
+		case "notice", "":
+			file.Notice = strings.TrimSpace(file.Notice + " " + text)
+		}
+	}
+	file.Author = strings.Join(authors, ", ")
+	return file
+}
+
+// fileNatSpecComments returns the NatSpec comments of the file itself: the
+// `///` and `/** */` comments of the header, above the pragma or between
+// it and the imports, which the first declaration doesn't take, see
+// natSpecComments.
+func fileNatSpecComments(doc *Document) []*ast.Comment {
+	var first ast.Declaration
+	for _, decl := range doc.File.Declarations {
+		switch decl.(type) {
+		case *ast.PragmaDirective, *ast.ImportDirective:
+			continue
+		}
+		first = decl
+		break
+	}
+	var attached []*ast.Comment
+	if first != nil {
+		attached = natSpecComments(doc, first)
+	}
+	res := []*ast.Comment{}
+	for _, c := range doc.File.Comments {
+		if first != nil && c.End() > first.Start() {
+			break
+		}
+		isNatSpec := strings.HasPrefix(c.Text, "///") || strings.HasPrefix(c.Text, "/**") && c.Text != "/**/"
+		if isNatSpec && !slices.Contains(attached, c) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
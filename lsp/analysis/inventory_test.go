@@ -0,0 +1,101 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func Test_FileNatSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string // title, author and notice
+	}{
+		{"above the pragma", `// SPDX-License-Identifier: MIT
+/// @title Vault
+/// @author Alice
+pragma solidity ^0.8.0;
+
+contract Vault {}
+`, "Vault|Alice|"},
+		{"below the pragma", `pragma solidity ^0.8.0;
+
+/**
+ * @title Vault
+ * @notice Holds the deposits
+ *         of the users.
+ */
+
+import "./IVault.sol";
+
+contract Vault {}
+`, "Vault||Holds the deposits of the users."},
+		{"taken by the contract", `pragma solidity ^0.8.0;
+
+/// @title Vault
+contract Vault {}
+`, "||"},
+		{"split by the contract's", `/// @author Alice
+pragma solidity ^0.8.0;
+
+/// @title Vault
+/// @author Bob
+contract Vault {}
+`, "|Alice|"},
+		{"implicit notice and authors", `/// The vault of the protocol.
+/// @author Alice
+/// @author Bob
+pragma solidity ^0.8.0;
+`, "|Alice, Bob|The vault of the protocol."},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		s.Root = "/ws"
+		s.OpenDocument("file:///ws/src/Vault.sol", 1, tt.src)
+		f := s.sourceFile(s.Documents["file:///ws/src/Vault.sol"])
+		if got := fmt.Sprintf("%s|%s|%s", f.Title, f.Author, f.Notice); got != tt.expected {
+			t.Errorf("%s: Expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func Test_Inventory(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, "// SPDX-License-Identifier: MIT\npragma solidity ^0.8.20;\n\ncontract Vault {}\n")
+	s.OpenDocument("file:///ws/src/tokens/Token.sol", 1, "// SPDX-License-Identifier: BUSL-1.1\npragma solidity ^0.8.20;\n\ncontract Token {\n    // the supply\n    uint256 supply;\n}\n")
+	s.OpenDocument("file:///ws/src/Auth.sol", 1, "// SPDX-License-Identifier: MIT\npragma solidity ^0.8.20;\n\ncontract Auth {}\n")
+	s.OpenDocument("file:///ws/lib/forge-std/src/Test.sol", 1, "// SPDX-License-Identifier: MIT\npragma solidity >=0.6.2;\n\ncontract Test {}\n")
+
+	inventory := s.InventoryReport(lsp.IntID(1), lsp.InventoryParams{}).Result
+	got := []string{}
+	for _, dir := range inventory.Directories {
+		got = append(got, fmt.Sprintf("%s %+v", dir.Path, dir.Totals))
+		for _, f := range dir.Files {
+			got = append(got, fmt.Sprintf("  %s %s %s %d/%d/%d", f.Path, f.License, f.Pragma, f.Lines, f.SLOC, f.CommentLines))
+		}
+	}
+	expected := []string{
+		"src {Files:2 Lines:8 SLOC:4 CommentLines:2}",
+		"  src/Auth.sol MIT ^0.8.20 4/2/1",
+		"  src/Vault.sol MIT ^0.8.20 4/2/1",
+		"src/tokens {Files:1 Lines:7 SLOC:4 CommentLines:2}",
+		"  src/tokens/Token.sol BUSL-1.1 ^0.8.20 7/4/2",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the directories\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if fmt.Sprintf("%+v", inventory.Licenses) != "[{License:MIT Files:2} {License:BUSL-1.1 Files:1}]" {
+		t.Errorf("Expected 2 MIT files and 1 BUSL-1.1 file, got %+v", inventory.Licenses)
+	}
+	if inventory.Totals.Files != 3 || inventory.Totals.SLOC != 8 || len(inventory.Dependencies) != 0 {
+		t.Errorf("Expected 3 files with 8 SLOC without the dependencies, got %+v and %d dependencies", inventory.Totals, len(inventory.Dependencies))
+	}
+
+	inventory = s.InventoryReport(lsp.IntID(2), lsp.InventoryParams{IncludeDependencies: true}).Result
+	if len(inventory.Dependencies) != 1 || inventory.Dependencies[0].Path != "lib/forge-std/src" || inventory.Totals.Files != 3 {
+		t.Errorf("Expected forge-std in a separate section, got %+v with totals %+v", inventory.Dependencies, inventory.Totals)
+	}
+}
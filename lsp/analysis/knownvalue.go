@@ -0,0 +1,214 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"solbot/token"
+	"strconv"
+	"strings"
+)
+
+// Besides the constants, the values of some state variables are known at
+// the analysis time, since nothing but a literal is ever assigned to them
+// e.g. `maxHolders = 100` in the constructor. The diagnostics folding the
+// constants fold these too, see constantsOf, so that e.g. the loop bounded
+// by an immutable is evaluated and the trusted router stored in one is
+// still trusted.
+
+// knownValues returns the state variables of the contract whose values are
+// known at the analysis time, other than the constants, with their values:
+//
+//   - the immutables whose every assignment, the initial value or the ones
+//     in the constructor, is the same literal e.g. `100` or
+//     `IRouter(0x7a25...)`, an expression of the literals and the
+//     constants included;
+//   - the private variables of the elementary types initialized with a
+//     literal and never written afterwards.
+//
+// It's conservative: a value which is not a literal, an assignment outside
+// of the constructor or in a branch of it, a write in the inline assembly,
+// or a delegatecall in the contract or its bases, which runs the code of
+// another contract on its storage, leaves the variable unknown.
+func (s *State) knownValues(doc *Document, c *ast.ContractDeclaration) map[*ast.VariableDeclaration]ast.Expression {
+	res := map[*ast.VariableDeclaration]ast.Expression{}
+	if s.delegatesIn(doc, c, map[*ast.ContractDeclaration]bool{}) {
+		return res
+	}
+
+	assigned := map[*ast.VariableDeclaration][]ast.Expression{}
+	names := map[string]bool{}
+	for _, member := range c.Body {
+		decl, ok := member.(*ast.VariableDeclaration)
+		if !ok || decl.Constant {
+			continue
+		}
+		_, elementary := decl.Type.(*ast.ElementaryType)
+		if !decl.Immutable && (decl.Visibility != ast.Private || !elementary || decl.Value == nil) {
+			continue
+		}
+		assigned[decl] = []ast.Expression{}
+		if decl.Value != nil {
+			assigned[decl] = append(assigned[decl], decl.Value)
+		}
+		names[decl.Name.Name] = true
+	}
+	if len(names) == 0 {
+		return res
+	}
+
+	invalid := map[*ast.VariableDeclaration]bool{}
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		if !names[ident.Name] || ident.Start() < c.Start() || ident.End() > c.End() {
+			return
+		}
+		sym := s.resolve(doc, path)
+		if sym == nil {
+			return
+		}
+		decl, ok := sym.Node.(*ast.VariableDeclaration)
+		if _, candidate := assigned[decl]; !ok || !candidate || decl.Name == ident {
+			return
+		}
+		if _, ok := path[1].(*ast.AssemblyStatement); ok {
+			invalid[decl] = true
+			return
+		}
+		if _, written := writtenAt(path); !written {
+			return
+		}
+		if value := constructorAssignment(path); value != nil {
+			assigned[decl] = append(assigned[decl], value)
+			return
+		}
+		invalid[decl] = true
+	})
+	// The slots can be written by the inline assembly without naming the
+	// variables; the immutables are not in the storage.
+	ast.Inspect(c, func(node ast.Node) bool {
+		if n, ok := node.(*ast.AssemblyStatement); ok && strings.Contains(n.Body, "sstore(") {
+			for decl := range assigned {
+				invalid[decl] = invalid[decl] || !decl.Immutable
+			}
+		}
+		return true
+	})
+
+	consts := s.constantsOf(doc, nil)
+	for decl, values := range assigned {
+		if invalid[decl] || len(values) == 0 {
+			continue
+		}
+		known := true
+		first, _ := s.literalKey(doc, values[0], consts)
+		for _, value := range values {
+			key, ok := s.literalKey(doc, value, consts)
+			known = known && ok && key == first
+		}
+		if known {
+			res[decl] = values[len(values)-1]
+		}
+	}
+	return res
+}
+
+// constructorAssignment returns the value assigned to the identifier at
+// path[0] by a statement of the constructor body itself e.g. `fee = 30;`,
+// which is executed exactly once; or nil if it's written in any other way.
+func constructorAssignment(path []ast.Node) ast.Expression {
+	if len(path) < 5 {
+		return nil
+	}
+	assign, ok := path[1].(*ast.AssignmentExpression)
+	if !ok || assign.Operator != token.ASSIGN || assign.Left != path[0] {
+		return nil
+	}
+	if _, ok := path[2].(*ast.ExpressionStatement); !ok {
+		return nil
+	}
+	fn, ok := path[4].(*ast.FunctionDeclaration)
+	if !ok || fn.Kind != token.CONSTRUCTOR || path[3] != ast.Node(fn.Body) {
+		return nil
+	}
+	return assign.Right
+}
+
+// delegatesIn reports whether the contract or any of its bases makes a
+// delegatecall.
+func (s *State) delegatesIn(doc *Document, c *ast.ContractDeclaration, visited map[*ast.ContractDeclaration]bool) bool {
+	if visited[c] {
+		return false
+	}
+	visited[c] = true
+	if delegates(c) {
+		return true
+	}
+	for _, base := range s.bases(doc, c) {
+		if s.delegatesIn(base.Doc, base.Node.(*ast.ContractDeclaration), visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// literalKey returns the value of the expression made of the literals and
+// the constants, in a form the values can be compared by e.g. "100",
+// "true" or "0x7a25..." for `IRouter(0x7a25...)`; ok is false if it's not
+// made of them.
+func (s *State) literalKey(doc *Document, x ast.Expression, consts constants) (string, bool) {
+	if value, ok := foldInt(x, consts); ok {
+		return value.String(), true
+	}
+	if value, ok := foldBool(x, consts); ok {
+		return strconv.FormatBool(value), true
+	}
+	// The conversions to the address and the contract types.
+	call, ok := x.(*ast.CallExpression)
+	if !ok || len(call.Args) != 1 || len(call.Names) != 0 {
+		return "", false
+	}
+	if _, ok := call.Function.(*ast.ElementaryType); !ok {
+		sym := s.follow(s.resolveExpr(doc, ast.PathEnclosingPos(doc.File, call.Function.Start()), call.Function))
+		if sym == nil {
+			return "", false
+		}
+		if _, ok := sym.Node.(*ast.ContractDeclaration); !ok {
+			return "", false
+		}
+	}
+	return s.literalKey(doc, call.Args[0], consts)
+}
+
+// knownValue returns the value of the state variable known at the analysis
+// time, see knownValues, as it's read at the path; or nil if it's not
+// known. The values assigned in the constructor are only known once it
+// has run, so they're taken in the bodies of the functions, but not in the
+// constructor, the modifiers it may run or the initial values of the state
+// variables.
+func (s *State) knownValue(sym *Symbol, path []ast.Node, known map[*ast.ContractDeclaration]map[*ast.VariableDeclaration]ast.Expression) ast.Expression {
+	decl, ok := sym.Node.(*ast.VariableDeclaration)
+	if !ok || decl.Constant {
+		return nil
+	}
+	read := false
+	for _, node := range path {
+		if fn, ok := node.(*ast.FunctionDeclaration); ok {
+			read = fn.Kind != token.CONSTRUCTOR
+			break
+		}
+	}
+	if !read {
+		return nil
+	}
+	for _, node := range sym.Doc.File.Declarations {
+		c, ok := node.(*ast.ContractDeclaration)
+		if !ok || decl.Start() < c.Start() || decl.End() > c.End() {
+			continue
+		}
+		values, ok := known[c]
+		if !ok {
+			values = s.knownValues(sym.Doc, c)
+			known[c] = values
+		}
+		return values[decl]
+	}
+	return nil
+}
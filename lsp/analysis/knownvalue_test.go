@@ -0,0 +1,230 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const knownValuesSrc = `pragma solidity ^0.8.0;
+
+interface IRouter {}
+
+contract Airdrop {
+    uint256 constant BPS = 10_000;
+    uint256 immutable maxHolders;
+    uint256 immutable feeBps;
+    IRouter immutable router;
+    uint256 immutable cap;
+    uint256 immutable floor;
+    uint256 immutable limit = 10;
+    uint256 private divisor = 100;
+    uint256 private rate = 5;
+    uint256 private scale = 1e18;
+    uint256 private total = 1;
+    uint256 public price = 1;
+
+    constructor(uint256 _cap) {
+        maxHolders = 100;
+        feeBps = BPS / 100;
+        router = IRouter(0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D);
+        cap = _cap;
+        if (_cap > 0) {
+            floor = 1;
+        }
+        require(limit > 0);
+    }
+
+    function setRate(uint256 r) external {
+        rate = r;
+    }
+
+    function count() public view returns (uint256 n) {
+        for (uint256 i = 0; i < maxHolders; i++) {
+            n += 1;
+        }
+    }
+
+    function share(uint256 amount) public view returns (uint256) {
+        require(divisor != 0);
+        return amount / divisor;
+    }
+
+    function accrue() external {
+        total++;
+    }
+}
+
+contract Ledger {
+    uint256 private fee = 30;
+
+    function reset() external {
+        assembly {
+            sstore(0, 1)
+        }
+    }
+}
+
+contract Forwarder {
+    uint256 immutable maxHolders;
+
+    constructor() {
+        maxHolders = 100;
+    }
+
+    function forward(address target) external {
+        (bool ok, ) = target.delegatecall("");
+        require(ok);
+    }
+}
+`
+
+func knownValueStrings(s *State, doc *Document, name string) map[string]string {
+	res := map[string]string{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Name.Name != name {
+			continue
+		}
+		for v, value := range s.knownValues(doc, c) {
+			res[v.Name.Name] = ast.ExprString(value)
+		}
+	}
+	return res
+}
+
+func Test_KnownValues(t *testing.T) {
+	uri := "file:///ws/src/Airdrop.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, knownValuesSrc)
+
+	expected := map[string]string{
+		"maxHolders": "100",
+		"feeBps":     "BPS / 100",
+		"router":     "IRouter(0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D)",
+		"limit":      "10",
+		"divisor":    "100",
+		"scale":      "1e18",
+	}
+	known := knownValueStrings(s, s.Documents[uri], "Airdrop")
+	if len(known) != len(expected) {
+		t.Errorf("Expected %d known values, got %v", len(expected), known)
+	}
+	for name, value := range expected {
+		if known[name] != value {
+			t.Errorf("Expected `%s` to be known as `%s`, got `%s`", name, value, known[name])
+		}
+	}
+}
+
+func Test_KnownValueInvalidation(t *testing.T) {
+	uri := "file:///ws/src/Airdrop.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, knownValuesSrc)
+	known := knownValueStrings(s, s.Documents[uri], "Airdrop")
+
+	tests := []struct {
+		name   string
+		reason string
+	}{
+		{"cap", "assigned a parameter"},
+		{"floor", "assigned in a branch of the constructor"},
+		{"rate", "assigned outside of the constructor"},
+		{"total", "incremented"},
+		{"price", "public"},
+	}
+	for _, tt := range tests {
+		if value, ok := known[tt.name]; ok {
+			t.Errorf("Expected `%s` not to be known, since it's %s, got `%s`", tt.name, tt.reason, value)
+		}
+	}
+	if known := knownValueStrings(s, s.Documents[uri], "Ledger"); len(known) != 0 {
+		t.Errorf("Expected no known values in the contract writing the storage in the assembly, got %v", known)
+	}
+	if known := knownValueStrings(s, s.Documents[uri], "Forwarder"); len(known) != 0 {
+		t.Errorf("Expected no known values in the contract making a delegatecall, got %v", known)
+	}
+}
+
+func Test_KnownValuesEvaluated(t *testing.T) {
+	uri := "file:///ws/src/Airdrop.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, knownValuesSrc)
+
+	// The loop bounded by the immutable runs to the end.
+	evaluation, err := s.Evaluate(uri, "count", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if evaluation.Stop != nil {
+		t.Fatalf("Expected the loop to be evaluated, got a stop: %s", evaluation.Stop.Reason)
+	}
+	if got := strings.Join(evaluation.Returns, ", "); got != "100" {
+		t.Errorf("Expected 100 to be returned, got %s", got)
+	}
+
+	evaluation, err = s.Evaluate(uri, "share", map[string]string{"amount": "1000"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if evaluation.Stop != nil || strings.Join(evaluation.Returns, ", ") != "10" {
+		t.Errorf("Expected the division by the private variable to return 10, got %v %v", evaluation.Returns, evaluation.Stop)
+	}
+}
+
+func Test_KnownValueConditions(t *testing.T) {
+	uri := "file:///ws/src/Airdrop.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, knownValuesSrc)
+
+	diagnostics := s.conditionDiagnostics(s.Documents[uri])
+	// `require(limit > 0)` in the constructor is left alone, the value
+	// is only known once the constructor has run.
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	expected := "The condition is always true (`divisor != 0` is `100 != 0`); the check has no effect"
+	if d.Code != "always-true-condition" || d.Range.Start.Line != 40 || d.Message != expected {
+		t.Errorf("Expected %q at line 40, got %s %q at line %d", expected, d.Code, d.Message, d.Range.Start.Line)
+	}
+}
+
+func Test_TrustedSpenderInImmutable(t *testing.T) {
+	s := NewState()
+	s.Config.TrustedSpenders = []string{"0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D"}
+	s.OpenDocument("file:///ws/src/IERC20.sol", 1, erc20Src)
+	uri := "file:///ws/src/Swapper.sol"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.20;
+
+import "./IERC20.sol";
+
+contract Swapper {
+    IERC20 token;
+    address immutable router;
+    address immutable spender;
+
+    constructor(address _spender) {
+        router = address(0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D);
+        spender = _spender;
+    }
+
+    function approveRouter(uint256 amount) external {
+        require(token.approve(router, amount));
+    }
+
+    function approveSpender(uint256 amount) external {
+        require(token.approve(spender, amount));
+    }
+}
+`)
+
+	diagnostics := s.erc20Diagnostics(s.Documents[uri])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	if d := diagnostics[0]; d.Code != "erc20-approve-race" || d.Range.Start.Line != 19 || d.Severity != lsp.SeverityInformation {
+		t.Errorf("Expected erc20-approve-race of the spender at line 19, got %s at line %d", d.Code, d.Range.Start.Line)
+	}
+}
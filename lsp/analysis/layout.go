@@ -0,0 +1,330 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"strconv"
+	"strings"
+)
+
+// StorageSlot is the place of a state variable in the contract storage.
+type StorageSlot struct {
+	Contract string // contract declaring the variable
+	Name     string
+	Type     string // e.g. "mapping(address => uint256)"
+	Slot     int
+	Offset   int // offset in bytes within the slot
+	Size     int // size in bytes; a multiple of 32 for the types taking whole slots
+}
+
+// storageLayout returns the storage slots of the state variables of the
+// contract and its bases, following the rules of the compiler: the
+// variables of the most base contract come first, the value types smaller
+// than 32 bytes share the slots, while the structs, the arrays and the
+// mappings start new ones. The transient variables are left out, see
+// transientLayout. It returns false if the inheritance can't be linearized
+// or the size of some of the types is unknown e.g. an array length is not
+// a literal.
+func (s *State) storageLayout(contract *Symbol) ([]StorageSlot, bool) {
+	return slotsOf(s.stateVariables(contract, false))
+}
+
+// transientLayout returns the transient storage slots of the transient
+// state variables of the contract and its bases. They are numbered from
+// zero apart from the storage, with the same rules.
+func (s *State) transientLayout(contract *Symbol) ([]StorageSlot, bool) {
+	return slotsOf(s.stateVariables(contract, true))
+}
+
+func slotsOf(vars []storageVariable, ok bool) ([]StorageSlot, bool) {
+	if !ok {
+		return nil, false
+	}
+	res := []StorageSlot{}
+	for _, v := range vars {
+		res = append(res, v.slot)
+	}
+	return res, true
+}
+
+// storageVariable is a state variable placed in the storage.
+type storageVariable struct {
+	slot StorageSlot
+	decl *Symbol
+}
+
+// storageVariables returns the state variables in the order of the
+// storageLayout.
+func (s *State) storageVariables(contract *Symbol) ([]storageVariable, bool) {
+	return s.stateVariables(contract, false)
+}
+
+// stateVariables returns the state variables placed in the storage, or in
+// the transient storage.
+func (s *State) stateVariables(contract *Symbol, transient bool) ([]storageVariable, bool) {
+	linearized := s.linearize(contract)
+	if linearized == nil {
+		return nil, false
+	}
+
+	res := []storageVariable{}
+	var l slotAllocator
+	for i := len(linearized) - 1; i >= 0; i-- {
+		c := linearized[i]
+		for _, decl := range c.Node.(*ast.ContractDeclaration).Body {
+			v, ok := decl.(*ast.VariableDeclaration)
+			if !ok || v.Constant || v.Immutable || (v.Transient != 0) != transient {
+				continue
+			}
+			size, ok := s.storageSize(c.Doc, v.Type, map[ast.Node]bool{})
+			if !ok {
+				return nil, false
+			}
+			slot, offset := l.place(size)
+			res = append(res, storageVariable{
+				slot: StorageSlot{
+					Contract: c.Name.Name,
+					Name:     v.Name.Name,
+					Type:     ast.ExprString(v.Type),
+					Slot:     slot,
+					Offset:   offset,
+					Size:     size.bytes(),
+				},
+				decl: &Symbol{Doc: c.Doc, Name: v.Name, Node: v},
+			})
+		}
+	}
+	return res, true
+}
+
+// storageSize is the size of a value type in bytes, or the number of the
+// slots taken by the other types.
+type storageSize struct {
+	value int // size of a value type; or 0
+	slots int // number of the whole slots; or 0 for the value types
+}
+
+func (size storageSize) bytes() int {
+	if size.slots > 0 {
+		return size.slots * 32
+	}
+	return size.value
+}
+
+// slotAllocator places the variables one after another in the storage.
+type slotAllocator struct {
+	slot   int
+	offset int
+}
+
+// place returns the slot and the offset of the next variable of the size.
+func (l *slotAllocator) place(size storageSize) (slot, offset int) {
+	if size.slots > 0 {
+		if l.offset > 0 {
+			l.slot++
+			l.offset = 0
+		}
+		slot = l.slot
+		l.slot += size.slots
+		return slot, 0
+	}
+	if l.offset+size.value > 32 {
+		l.slot++
+		l.offset = 0
+	}
+	slot, offset = l.slot, l.offset
+	l.offset += size.value
+	return slot, offset
+}
+
+// slots returns the number of the slots used so far.
+func (l *slotAllocator) slots() int {
+	if l.offset > 0 {
+		return l.slot + 1
+	}
+	return l.slot
+}
+
+// storageSize returns the size of the type in the storage. The visited
+// structs guard against the recursive ones.
+func (s *State) storageSize(doc *Document, t ast.Expression, visited map[ast.Node]bool) (storageSize, bool) {
+	switch t := t.(type) {
+	case *ast.ElementaryType:
+		return elementarySize(t.Value)
+	case *ast.FunctionType:
+		if t.Visibility == ast.External {
+			return storageSize{value: 24}, true
+		}
+		return storageSize{value: 8}, true
+	case *ast.MappingType:
+		return storageSize{slots: 1}, true
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return storageSize{slots: 1}, true
+		}
+		length, ok := constInt(t.Len)
+		if !ok || !length.IsInt64() || length.Int64() <= 0 {
+			return storageSize{}, false
+		}
+		elem, ok := s.storageSize(doc, t.Elem, visited)
+		if !ok {
+			return storageSize{}, false
+		}
+		n := int(length.Int64())
+		if elem.slots > 0 {
+			return storageSize{slots: n * elem.slots}, true
+		}
+		perSlot := 32 / elem.value
+		return storageSize{slots: (n + perSlot - 1) / perSlot}, true
+	case *ast.Identifier, *ast.MemberAccessExpression:
+	default:
+		return storageSize{}, false
+	}
+
+	sym := s.follow(s.resolveExpr(doc, ast.PathEnclosingPos(doc.File, t.Start()), t))
+	if sym == nil {
+		if ident, ok := t.(*ast.Identifier); ok && ident.Name == "byte" {
+			return storageSize{value: 1}, true
+		}
+		return storageSize{}, false
+	}
+	switch n := sym.Node.(type) {
+	case *ast.ContractDeclaration:
+		return storageSize{value: 20}, true
+	case *ast.EnumDeclaration:
+		return storageSize{value: 1}, true
+	case *ast.TypeDeclaration:
+		return s.storageSize(sym.Doc, n.Underlying, visited)
+	case *ast.StructDeclaration:
+		if visited[n] {
+			return storageSize{}, false
+		}
+		visited[n] = true
+		defer delete(visited, n)
+		var l slotAllocator
+		for _, member := range n.Members {
+			size, ok := s.storageSize(sym.Doc, member.Type, visited)
+			if !ok {
+				return storageSize{}, false
+			}
+			l.place(size)
+		}
+		return storageSize{slots: max(l.slots(), 1)}, true
+	}
+	return storageSize{}, false
+}
+
+// elementarySize returns the size of the elementary type e.g. 20 bytes for
+// `address` or a slot for `string`.
+func elementarySize(name string) (storageSize, bool) {
+	switch name {
+	case "bool":
+		return storageSize{value: 1}, true
+	case "address":
+		return storageSize{value: 20}, true
+	case "string", "bytes":
+		return storageSize{slots: 1}, true
+	case "uint", "int":
+		return storageSize{value: 32}, true
+	case "fixed", "ufixed":
+		return storageSize{value: 16}, true
+	}
+
+	for _, prefix := range []string{"uint", "int", "bytes", "ufixed", "fixed"} {
+		digits, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		if prefix == "ufixed" || prefix == "fixed" {
+			digits, _, _ = strings.Cut(digits, "x")
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil || n <= 0 {
+			return storageSize{}, false
+		}
+		if prefix == "bytes" {
+			return storageSize{value: n}, n <= 32
+		}
+		return storageSize{value: n / 8}, n%8 == 0 && n <= 256
+	}
+	return storageSize{}, false
+}
+
+// linearize returns the C3 linearization of the contract and its bases, from
+// the most derived contract to the most base one; or nil if the inheritance
+// graph is invalid or some of the bases can't be resolved.
+func (s *State) linearize(contract *Symbol) []*Symbol {
+	return s.linearizeVisiting(contract, map[ast.Node]bool{})
+}
+
+func (s *State) linearizeVisiting(contract *Symbol, visiting map[ast.Node]bool) []*Symbol {
+	c := contract.Node.(*ast.ContractDeclaration)
+	if visiting[c] {
+		return nil
+	}
+	visiting[c] = true
+	defer delete(visiting, c)
+
+	bases := s.bases(contract.Doc, c)
+	if len(bases) != len(c.Bases) {
+		return nil
+	}
+
+	// The most derived base comes last in the inheritance list, so it's
+	// merged first.
+	lists := [][]*Symbol{}
+	direct := []*Symbol{}
+	for i := len(bases) - 1; i >= 0; i-- {
+		l := s.linearizeVisiting(bases[i], visiting)
+		if l == nil {
+			return nil
+		}
+		lists = append(lists, l)
+		direct = append(direct, bases[i])
+	}
+	lists = append(lists, direct)
+
+	res := []*Symbol{contract}
+	for {
+		// Drop the exhausted lists.
+		rest := lists[:0]
+		for _, l := range lists {
+			if len(l) > 0 {
+				rest = append(rest, l)
+			}
+		}
+		lists = rest
+		if len(lists) == 0 {
+			return res
+		}
+
+		// The next contract is the first head that is not in the tail
+		// of any of the lists.
+		var next *Symbol
+		for _, l := range lists {
+			if !inTail(lists, l[0]) {
+				next = l[0]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		res = append(res, next)
+		for i, l := range lists {
+			if l[0].Node == next.Node {
+				lists[i] = l[1:]
+			}
+		}
+	}
+}
+
+func inTail(lists [][]*Symbol, sym *Symbol) bool {
+	for _, l := range lists {
+		for _, other := range l[1:] {
+			if other.Node == sym.Node {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+)
+
+// legacyBuiltins are the global functions removed in Solidity 0.5.0, by
+// their names, mapped to the modern ones. They are builtins only in the
+// documents whose pragma targets an older compiler, see pragma.Legacy.
+var legacyBuiltins = map[string]string{
+	"sha3":    "keccak256",
+	"suicide": "selfdestruct",
+}
+
+// removedBuiltin returns the modern name of the legacy builtin the
+// identifier refers to; or an empty string if it's not one of the
+// legacyBuiltins or it's a declared name.
+func (s *State) removedBuiltin(doc *Document, path []ast.Node) string {
+	ident, ok := path[0].(*ast.Identifier)
+	if !ok || legacyBuiltins[ident.Name] == "" {
+		return ""
+	}
+	if access, ok := path[1].(*ast.MemberAccessExpression); ok && access.Member == ident {
+		return ""
+	}
+	if s.resolve(doc, path) != nil {
+		return ""
+	}
+	return legacyBuiltins[ident.Name]
+}
+
+// legacyDiagnostics notes the constructs of the legacy documents which were
+// removed in Solidity 0.5.0: the `constant` functions, `throw`, `var`, the
+// constructors named after the contract, the unnamed fallback functions,
+// `years`, `suicide` and `sha3`, and the functions public by default. The
+// parser accepts them only in the legacy documents and reports the syntax
+// errors in the others, except for the removed builtins, which are errors
+// here then.
+func (s *State) legacyDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	legacy := pragma.Legacy(doc.File)
+	report := func(r token.Range, severity lsp.DiagnosticSeverity, message string) {
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, r),
+			Severity: severity,
+			Code:     "legacy-construct",
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+	note := func(r token.Range, format string, args ...any) {
+		if legacy {
+			report(r, lsp.SeverityInformation, "Legacy construct: "+fmt.Sprintf(format, args...))
+		}
+	}
+	keyword := func(pos token.Pos, word string) token.Range {
+		return token.Range{Start: pos, End: pos + token.Pos(len(word))}
+	}
+
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FunctionDeclaration:
+			switch {
+			case n.Legacy && n.Kind == token.CONSTRUCTOR:
+				note(ast.NodeRange(n.Name), "the constructor named after the contract, it's `constructor` since Solidity 0.5.0")
+			case n.Legacy && n.Kind == token.FALLBACK:
+				note(keyword(n.Type.Func, "function"), "the unnamed fallback function, it's `fallback` since Solidity 0.6.0")
+			}
+			if n.Type.Constant != 0 {
+				note(keyword(n.Type.Constant, "constant"), "the `constant` function, it's `view` since Solidity 0.5.0")
+			}
+			if n.Type.Implicit && n.Name != nil && !n.Legacy {
+				note(ast.NodeRange(n.Name), "`%s` is public by default, the visibility is required since Solidity 0.5.0", n.Name.Name)
+			}
+		case *ast.RevertStatement:
+			if n.Legacy {
+				note(ast.NodeRange(n), "`throw`, it's `revert()` since Solidity 0.5.0")
+			}
+		case *ast.VariableDeclarationStatement:
+			if n.Var != 0 {
+				note(keyword(n.Var, "var"), "`var`, the type is required since Solidity 0.5.0")
+			}
+		case *ast.BasicLit:
+			if n.Unit != nil && n.Unit.Name == "years" {
+				note(ast.NodeRange(n.Unit), "`years`, it was removed in Solidity 0.5.0")
+			}
+		}
+		return true
+	})
+
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		modern := s.removedBuiltin(doc, path)
+		switch {
+		case modern == "":
+		case legacy:
+			note(ast.NodeRange(ident), "`%s`, it's `%s` since Solidity 0.5.0", ident.Name, modern)
+		default:
+			report(ast.NodeRange(ident), lsp.SeverityError, fmt.Sprintf("`%s` was removed in Solidity 0.5.0, use `%s` instead", ident.Name, modern))
+		}
+	})
+	return res
+}
+
+// legacyBuiltinHover returns the hover of the legacy builtin at the
+// position; or an empty string if there is none.
+func (s *State) legacyBuiltinHover(uri string, position lsp.Position, markdown bool) string {
+	doc, ok := s.document(uri)
+	if !ok {
+		return ""
+	}
+	path := ast.PathEnclosingPos(doc.File, toTokenPos(doc.Handle, position))
+	if len(path) < 2 || !pragma.Legacy(doc.File) {
+		return ""
+	}
+	modern := s.removedBuiltin(doc, path)
+	if modern == "" {
+		return ""
+	}
+	name := path[0].(*ast.Identifier).Name
+	if markdown {
+		return fmt.Sprintf("`%s`\n\nlegacy builtin, `%s` since Solidity 0.5.0", name, modern)
+	}
+	return fmt.Sprintf("%s\n\nlegacy builtin, %s since Solidity 0.5.0", name, modern)
+}
+
+// inferredTypeHover returns the type inferred for the legacy `var`
+// declaration; or an empty string if it's not one or the type can't be
+// computed.
+func (s *State) inferredTypeHover(sym *Symbol, markdown bool) string {
+	v, ok := sym.Node.(*ast.VariableDeclaration)
+	if !ok || v.Type != nil {
+		return ""
+	}
+	_, t := s.inferredType(sym.Doc, v)
+	if t == nil {
+		return ""
+	}
+	if markdown {
+		return fmt.Sprintf("inferred type `%s`", ast.ExprString(t))
+	}
+	return "inferred type " + ast.ExprString(t)
+}
@@ -0,0 +1,107 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+// legacyNotes returns the legacy-construct diagnostics of the document as
+// "line:character severity message".
+func legacyNotes(s *State, uri string) []string {
+	res := []string{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "legacy-construct" {
+			res = append(res, fmt.Sprintf("%d:%d %d %s", d.Range.Start.Line, d.Range.Start.Character, d.Severity, d.Message))
+		}
+	}
+	return res
+}
+
+func Test_LegacyDiagnostics(t *testing.T) {
+	src, err := os.ReadFile("../../internal/corpus/testdata/corpus/legacy/StandardToken.sol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewState()
+	s.OpenDocument("file:///StandardToken.sol", 1, string(src))
+
+	expected := []string{
+		"50:11 3 Legacy construct: the constructor named after the contract, it's `constructor` since Solidity 0.5.0",
+		"225:2 3 Legacy construct: the unnamed fallback function, it's `fallback` since Solidity 0.6.0",
+		"226:4 3 Legacy construct: `throw`, it's `revert()` since Solidity 0.5.0",
+		"230:4 3 Legacy construct: `suicide`, it's `selfdestruct` since Solidity 0.5.0",
+		"233:42 3 Legacy construct: the `constant` function, it's `view` since Solidity 0.5.0",
+		"234:11 3 Legacy construct: `sha3`, it's `keccak256` since Solidity 0.5.0",
+	}
+	if got := legacyNotes(s, "file:///StandardToken.sol"); !slices.Equal(got, expected) {
+		t.Errorf("Expected the notes:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// The removed builtins are errors under a modern pragma, unless they
+	// are declared.
+	s.OpenDocument("file:///Modern.sol", 1, `pragma solidity ^0.8.0;
+
+contract Modern {
+    function hash(bytes memory data) external pure returns (bytes32) {
+        return sha3(data);
+    }
+
+    function destroy() external {
+        suicide(payable(msg.sender));
+    }
+}
+
+contract Shadowed {
+    function sha3(bytes memory data) internal pure returns (bytes32) {}
+
+    function hash() external pure returns (bytes32) {
+        return sha3("");
+    }
+}
+`)
+	expected = []string{
+		"4:15 1 `sha3` was removed in Solidity 0.5.0, use `keccak256` instead",
+		"8:8 1 `suicide` was removed in Solidity 0.5.0, use `selfdestruct` instead",
+	}
+	if got := legacyNotes(s, "file:///Modern.sol"); !slices.Equal(got, expected) {
+		t.Errorf("Expected the errors:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_LegacyTypes(t *testing.T) {
+	src, err := os.ReadFile("testdata/legacy/Crowdsale.sol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewState()
+	s.OpenDocument("file:///Crowdsale.sol", 1, string(src))
+
+	tests := []struct {
+		position lsp.Position
+		expected string
+	}{
+		// `previous` in `contributions[beneficiary] = previous + ...;`
+		{lsp.Position{Line: 21, Character: 38}, "```solidity\nvar previous\n```\n\ninferred type `uint256`"},
+		// `until` in `sha3(tokens, until)`, the second result of quote.
+		{lsp.Position{Line: 30, Character: 28}, "```solidity\nvar until\n```\n\ninferred type `uint256`"},
+		{lsp.Position{Line: 30, Character: 15}, "`sha3`\n\nlegacy builtin, `keccak256` since Solidity 0.5.0"},
+		// The legacy constructor keeps its name.
+		{lsp.Position{Line: 8, Character: 13}, "```solidity\nfunction Crowdsale(uint256 _rate)\n```"},
+	}
+	for _, tt := range tests {
+		got := s.Hover(lsp.IntID(1), "file:///Crowdsale.sol", tt.position).Result.Contents.Value
+		if got != tt.expected {
+			t.Errorf("Expected the hover at %d:%d to be %q, got %q", tt.position.Line, tt.position.Character, tt.expected, got)
+		}
+	}
+
+	notes := legacyNotes(s, "file:///Crowdsale.sol")
+	if len(notes) != 14 {
+		t.Errorf("Expected 14 notes, got:\n%s", strings.Join(notes, "\n"))
+	}
+}
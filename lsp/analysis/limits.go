@@ -0,0 +1,181 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/parser"
+	"solbot/token"
+	"time"
+)
+
+// Limits bound the memory used by the documents. A zero limit is no limit.
+type Limits struct {
+	// MaxDocumentSize is the size in bytes above which a document is
+	// stored, but neither parsed nor analyzed e.g. a generated file.
+	MaxDocumentSize int
+	// MaxParsedSize is the total size in bytes of the parsed documents
+	// above which the syntax trees of the least recently used ones are
+	// unloaded, see Unload. Their text is always kept.
+	MaxParsedSize int
+	// MaxLineLength is the length in bytes above which the inlay hints of
+	// a line are not computed e.g. a line of a minified file.
+	MaxLineLength int
+	// Parser bounds the parsing of a document, see parser.Limits. The
+	// [parser] section of solbot.toml overrides them.
+	Parser parser.Limits
+}
+
+// Default limits, configurable with the initialization options and the
+// flags of the lsp command.
+const (
+	DefaultMaxDocumentSize = 4 << 20
+	DefaultMaxParsedSize   = 256 << 20
+	DefaultMaxLineLength   = 10000
+)
+
+// DefaultParserLimits returns the limits of the parser in the language
+// server, tighter than the ones of the command line tools, since the
+// documents are parsed again on every edit.
+func DefaultParserLimits() parser.Limits {
+	return parser.Limits{
+		MaxExpressionDepth: 256,
+		MaxStatementDepth:  128,
+		MaxDeclarations:    20_000,
+		MaxNodes:           2_000_000,
+		Timeout:            5 * time.Second,
+	}
+}
+
+// parserLimits returns the limits of the parser, with the ones set in
+// solbot.toml.
+func (s *State) parserLimits() parser.Limits {
+	return s.Config.Parser.Apply(s.Limits.Parser)
+}
+
+// newDocument parses the document, unless it's larger than the limit.
+func (s *State) newDocument(uri string, version int, open bool, src string) *Document {
+	if s.Limits.MaxDocumentSize > 0 && len(src) > s.Limits.MaxDocumentSize {
+		return &Document{
+			URI:      uri,
+			Version:  version,
+			Open:     open,
+			Handle:   token.NewFile(uri, src),
+			File:     &ast.File{},
+			Anchors:  map[string]token.Range{},
+			TooLarge: true,
+		}
+	}
+	s.Stats.Parses++
+	return parsedDocument(uri, version, open, src, s.parserLimits())
+}
+
+// Document returns the document with the URI, with its syntax tree
+// loaded, for the methods the embedders add to the server; or false if
+// there is none.
+func (s *State) Document(uri string) (*Document, bool) {
+	return s.document(uri)
+}
+
+// document returns the document with the URI, parsed again if its syntax
+// tree was unloaded, and marks it as used.
+func (s *State) document(uri string) (*Document, bool) {
+	doc, ok := s.Documents[uri]
+	if !ok {
+		if block, ok := s.codeBlock(uri); ok {
+			return block.Doc, true
+		}
+		return nil, false
+	}
+	return s.use(doc), true
+}
+
+// use parses the document again if its syntax tree was unloaded, and marks
+// it as used, so that it's the last one to be unloaded.
+func (s *State) use(doc *Document) *Document {
+	if doc.unloaded {
+		doc.Handle, doc.File, doc.Limited = parseDocument(doc.URI, string(doc.Handle.Src()), s.parserLimits())
+		doc.unloaded = false
+		s.Stats.Parses++
+	}
+	s.clock++
+	doc.used = s.clock
+	return doc
+}
+
+// Unload drops the syntax trees of the least recently used documents while
+// the parsed documents are larger than the limit. The text of the documents
+// is kept, and the trees are parsed again when the documents are used; the
+// server unloads them after every message, so the limit bounds the memory
+// in between the messages.
+func (s *State) Unload() {
+	if s.Limits.MaxParsedSize <= 0 {
+		return
+	}
+	parsed, size := []*Document{}, 0
+	for _, doc := range s.Documents {
+		if !doc.TooLarge && !doc.unloaded {
+			parsed = append(parsed, doc)
+			size += len(doc.Handle.Src())
+		}
+	}
+	slices.SortFunc(parsed, func(a, b *Document) int { return int(a.used) - int(b.used) })
+	for _, doc := range parsed {
+		if size <= s.Limits.MaxParsedSize {
+			break
+		}
+		doc.File, doc.scope, doc.lexed = &ast.File{}, nil, nil
+		doc.unloaded = true
+		size -= len(doc.Handle.Src())
+	}
+}
+
+// tooLargeDiagnostics explains why the document larger than the limit has
+// no other diagnostics.
+func (s *State) tooLargeDiagnostics(doc *Document) []lsp.Diagnostic {
+	return []lsp.Diagnostic{{
+		Severity: lsp.SeverityInformation,
+		Code:     "file-too-large",
+		Source:   "solbot",
+		Message: fmt.Sprintf("The file has %d bytes, more than the limit of %d bytes, so it's not analyzed; "+
+			"raise maxDocumentSize in the initialization options or --max-document-size to analyze it",
+			len(doc.Handle.Src()), s.Limits.MaxDocumentSize),
+	}}
+}
+
+// limitDiagnostics report the limits of the parser the document hit, at
+// the positions where the parsing of an expression, of a statement or of
+// the rest of the file stopped.
+func (s *State) limitDiagnostics(doc *Document) []lsp.Diagnostic {
+	diagnostics := []lsp.Diagnostic{}
+	for _, e := range doc.Limited {
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, token.Range{Start: e.Pos, End: e.Pos}),
+			Severity: lsp.SeverityWarning,
+			Code:     "parse-limit",
+			Source:   "solbot",
+			Message:  e.Message() + "; raise the limit in the [parser] section of solbot.toml to parse it",
+		})
+	}
+	return diagnostics
+}
+
+// isLongLine reports whether the offset is on a line longer than the limit.
+// The first time a document has one, it's noted in the log, so that the
+// missing hints can be told from a bug.
+func (s *State) isLongLine(doc *Document, pos token.Pos, feature string) bool {
+	if s.Limits.MaxLineLength <= 0 {
+		return false
+	}
+	line := doc.Handle.Position(pos).Line
+	if doc.Handle.LineLength(line) <= s.Limits.MaxLineLength {
+		return false
+	}
+	if !s.longLinesLogged[doc.URI] {
+		s.longLinesLogged[doc.URI] = true
+		s.Logger.Info("skipped the lines longer than the limit", "uri", doc.URI, "line", line,
+			"feature", feature, "maxLineLength", s.Limits.MaxLineLength)
+	}
+	return true
+}
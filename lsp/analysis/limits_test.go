@@ -0,0 +1,176 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+func Test_DocumentTooLarge(t *testing.T) {
+	s := NewState()
+	s.Limits.MaxDocumentSize = 100
+	uri := "file:///ws/src/Generated.sol"
+	src := "contract Generated {\n" + strings.Repeat("    uint256 x;\n", 20) + "}\n"
+	s.OpenDocument(uri, 3, src)
+
+	doc := s.Documents[uri]
+	if !doc.TooLarge || len(doc.File.Declarations) != 0 || string(doc.Handle.Src()) != src {
+		t.Fatalf("Expected the text to be stored without parsing it, got %d declarations", len(doc.File.Declarations))
+	}
+	diagnostics := s.Diagnostics(context.Background(), uri).Params.Diagnostics
+	if len(diagnostics) != 1 || diagnostics[0].Code != "file-too-large" || diagnostics[0].Severity != lsp.SeverityInformation {
+		t.Fatalf("Expected the single file-too-large diagnostic, got %v", diagnostics)
+	}
+	if hover := s.Hover(lsp.IntID(1), uri, lsp.Position{Line: 0, Character: 10}).Result.Contents.Value; hover != "" {
+		t.Errorf("Expected no hover, got %q", hover)
+	}
+
+	// The document is analyzed again once it's small enough.
+	s.UpdateDocument(uri, 4, "contract Generated {}\n")
+	if s.Documents[uri].TooLarge {
+		t.Errorf("Expected the smaller document to be parsed")
+	}
+}
+
+func Test_ParseLimit(t *testing.T) {
+	s := NewState()
+	s.Limits.Parser = DefaultParserLimits()
+	uri := "file:///ws/src/Generated.sol"
+	src := "contract Generated {\n    function f() public {\n        x = " + strings.Repeat("(", 1000) + "1" + strings.Repeat(")", 1000) + ";\n    }\n}\n"
+	s.OpenDocument(uri, 1, src)
+
+	diagnostics := []lsp.Diagnostic{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "parse-limit" {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	expected := "expression nested deeper than 256 levels, the rest of it is not parsed; raise the limit in the [parser] section of solbot.toml to parse it"
+	if len(diagnostics) != 1 || diagnostics[0].Message != expected || diagnostics[0].Range.Start != (lsp.Position{Line: 2, Character: 266}) {
+		t.Fatalf("Expected the single parse-limit diagnostic, got %v", diagnostics)
+	}
+
+	// The limit set in solbot.toml applies to the next parse.
+	depth := 2000
+	s.Config.Parser.MaxExpressionDepth = &depth
+	s.UpdateDocument(uri, 2, src)
+	if limited := s.Documents[uri].Limited; len(limited) != 0 {
+		t.Errorf("Expected the document to be parsed within the raised limit, got %v", limited)
+	}
+}
+
+func Test_UnloadLeastRecentlyUsed(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	sources := map[string]string{
+		"file:///ws/src/A.sol": "contract A {}\n",
+		"file:///ws/src/B.sol": "contract B {}\n",
+		"file:///ws/src/Vault.sol": `import {A} from "./A.sol";
+
+contract Vault is A {}
+`,
+	}
+	for _, uri := range []string{"file:///ws/src/A.sol", "file:///ws/src/B.sol", "file:///ws/src/Vault.sol"} {
+		s.OpenDocument(uri, 1, sources[uri])
+	}
+	// Only the last document fits.
+	s.Limits.MaxParsedSize = len(sources["file:///ws/src/Vault.sol"])
+	s.Unload()
+
+	for uri, unloaded := range map[string]bool{"file:///ws/src/A.sol": true, "file:///ws/src/B.sol": true, "file:///ws/src/Vault.sol": false} {
+		doc := s.Documents[uri]
+		if doc.unloaded != unloaded {
+			t.Errorf("Expected unloaded %t for %s, got %t", unloaded, uri, doc.unloaded)
+		}
+		if string(doc.Handle.Src()) != sources[uri] {
+			t.Errorf("Expected the text of %s to be kept, got %q", uri, doc.Handle.Src())
+		}
+	}
+
+	// The import is parsed again when it's resolved.
+	vault, _ := s.document("file:///ws/src/Vault.sol")
+	if unresolved := s.unresolvedIdentifiers(vault); len(unresolved) != 0 {
+		t.Errorf("Expected no unresolved identifiers, got %s", unresolved[0].Name)
+	}
+	if a := s.Documents["file:///ws/src/A.sol"]; a.unloaded || len(a.File.Declarations) != 1 {
+		t.Errorf("Expected A.sol to be parsed again")
+	}
+
+	// The recently used ones stay.
+	s.Unload()
+	if !s.Documents["file:///ws/src/Vault.sol"].unloaded || s.Documents["file:///ws/src/A.sol"].unloaded {
+		t.Errorf("Expected Vault.sol to be unloaded before A.sol, which was used last")
+	}
+}
+
+func Test_LongLine(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "minified", "Flattened.sol"))
+	if err != nil {
+		t.Fatalf("Cannot read the fixture: %s", err)
+	}
+	var log bytes.Buffer
+	s := NewState()
+	s.Logger = slog.New(slog.NewTextHandler(&log, nil))
+	s.Limits.MaxLineLength = DefaultMaxLineLength
+	uri := "file:///ws/src/Flattened.sol"
+	s.OpenDocument(uri, 1, string(src))
+	doc := s.Documents[uri]
+
+	// The positions near the end of the line count the UTF-16 units of the
+	// characters before them.
+	line := strings.Split(string(src), "\n")[3]
+	offset := strings.LastIndex(line, "FEE")
+	character := uint(len([]rune(line[:offset]))) + 1 // 日本 are one unit each, 🚀 is two
+	pos := token.Pos(strings.Index(string(src), line) + offset)
+	if got := toLspPosition(doc.Handle, pos); got.Line != 3 || got.Character != character {
+		t.Fatalf("Expected 3:%d, got %d:%d", character, got.Line, got.Character)
+	}
+	if got := toTokenPos(doc.Handle, lsp.Position{Line: 3, Character: character}); got != pos {
+		t.Fatalf("Expected the offset %d, got %d", pos, got)
+	}
+	hover := s.Hover(lsp.IntID(1), uri, lsp.Position{Line: 3, Character: character + 1}).Result.Contents.Value
+	if !strings.Contains(hover, "uint256 constant FEE") {
+		t.Errorf("Expected the hover of FEE, got %q", hover)
+	}
+
+	// The inlay hints of the long line are skipped, and it's logged once.
+	whole := lsp.Range{End: lsp.Position{Line: 5}}
+	for i := 0; i < 2; i++ {
+		if hints := s.InlayHint(lsp.IntID(2), uri, whole).Result; len(hints) != 0 {
+			t.Fatalf("Expected no inlay hints on the long line, got %d", len(hints))
+		}
+	}
+	if got := strings.Count(log.String(), "skipped the lines longer than the limit"); got != 1 {
+		t.Errorf("Expected the long line to be logged once, got %d times:\n%s", got, log.String())
+	}
+
+	s.Limits.MaxLineLength = 0
+	if hints := s.InlayHint(lsp.IntID(3), uri, whole).Result; len(hints) == 0 {
+		t.Errorf("Expected the inlay hints without the limit")
+	}
+}
+
+// BenchmarkHoverLongLine hovers near the end of the 50 KB line of a
+// minified file.
+func BenchmarkHoverLongLine(b *testing.B) {
+	src, err := os.ReadFile(filepath.Join("testdata", "minified", "Flattened.sol"))
+	if err != nil {
+		b.Fatalf("Cannot read the fixture: %s", err)
+	}
+	s := NewState()
+	uri := "file:///ws/src/Flattened.sol"
+	s.OpenDocument(uri, 1, string(src))
+	line := strings.Split(string(src), "\n")[3]
+	character := uint(len([]rune(line[:strings.LastIndex(line, "FEE")]))) + 2
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Hover(lsp.IntID(i), uri, lsp.Position{Line: 3, Character: character})
+	}
+}
@@ -0,0 +1,324 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"solbot/lsp"
+	"solbot/parser"
+	"solbot/token"
+	"solbot/vfs"
+	"strings"
+)
+
+// The protocols keep examples of their code in the fenced ```solidity
+// blocks of the Markdown docs, and the examples rot as the code changes.
+// With the [docs] analysis of solbot.toml on, every block of a Markdown
+// file is parsed as a document of its own, kept apart from the workspace
+// documents, so that the references, the renames and the reports don't see
+// it. The diagnostics and the hovers are mapped between the lines of the
+// block and the ones of the Markdown file, see codeBlock.
+
+// markdownDocument is a Markdown file with the Solidity code blocks.
+type markdownDocument struct {
+	URI     string
+	Version int  // version sent by the client; or 0 if the document is not open
+	Open    bool // is the document open in the editor?
+	Blocks  []*codeBlock
+}
+
+// blockMode is how the code of a block is parsed.
+type blockMode int
+
+const (
+	fileBlock      blockMode = iota // with a pragma, an import or a contract, parsed as a file
+	memberBlock                     // functions, events etc. parsed as the members of a contract
+	statementBlock                  // parsed as the body of a function
+)
+
+// blockWrappers start and end the source of the member and the statement
+// blocks. The wrapped blocks stand for a part of the code, so the names
+// they don't declare are not reported, see markdownDiagnostics.
+var blockWrappers = map[blockMode][2]string{
+	memberBlock:    {"contract Example {\n", "\n}\n"},
+	statementBlock: {"contract Example {\nfunction example() external {\n", "\n}\n}\n"},
+}
+
+// codeBlock is a fenced Solidity block of a Markdown file. Its document
+// has the URI of the file with the line of the fence e.g.
+// file:///ws/docs/guide.md#L12, so that the imports are resolved from the
+// directory of the file. The lines of the document are the ones of the
+// code after the lines of the wrapper, without the indentation of the
+// fence.
+type codeBlock struct {
+	Doc    *Document
+	Mode   blockMode
+	Line   uint             // 0-based line of the first line of the code in the Markdown file
+	Lines  uint             // lines of the code
+	Indent uint             // characters of the indentation of the fence, removed from the lines of the code
+	Header uint             // lines of the wrapper before the code
+	Errors parser.ErrorList // syntax errors, at the offsets of Doc
+}
+
+// fencedBlock is a fenced code block of the Markdown text.
+type fencedBlock struct {
+	line   int // 0-based line of the first line of the code
+	indent int
+	info   []string // words after the fence e.g. ["solidity", "ignore"]
+	lines  []string // lines of the code without the indentation
+}
+
+// fencedBlocks returns the code blocks fenced with ``` or ~~~. A block that
+// is never closed runs until the end of the text.
+func fencedBlocks(src string) []fencedBlock {
+	res := []fencedBlock{}
+	lines := strings.Split(src, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\r")
+		trimmed := strings.TrimLeft(line, " \t")
+		fence := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, "`"))]
+		if len(fence) < 3 {
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, "~"))]
+		}
+		if len(fence) < 3 {
+			continue
+		}
+		block := fencedBlock{line: i + 1, indent: len(line) - len(trimmed), info: strings.Fields(trimmed[len(fence):])}
+		for i++; i < len(lines); i++ {
+			code := strings.TrimSuffix(lines[i], "\r")
+			closing := strings.TrimSpace(code)
+			if strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+				break
+			}
+			// The indentation of the fence is removed from the code, but
+			// not more than the line has.
+			n := 0
+			for n < block.indent && n < len(code) && (code[n] == ' ' || code[n] == '\t') {
+				n++
+			}
+			block.lines = append(block.lines, code[n:])
+		}
+		res = append(res, block)
+	}
+	return res
+}
+
+// isSolidity reports whether the block is a Solidity one to be analyzed:
+// ```solidity or ```sol, without the ignore word e.g. ```solidity ignore,
+// and without a solbot-ignore comment in the code.
+func (b fencedBlock) isSolidity() bool {
+	if len(b.info) == 0 || b.info[0] != "solidity" && b.info[0] != "sol" || slices.Contains(b.info[1:], "ignore") {
+		return false
+	}
+	for _, line := range b.lines {
+		if strings.Contains(line, "solbot-ignore") {
+			return false
+		}
+	}
+	return true
+}
+
+// mode returns how the code of the block is parsed: as a file if it has a
+// pragma, an import or a contract, as the members of a contract if it
+// starts with one, and as the statements of a function otherwise.
+func (b fencedBlock) mode() blockMode {
+	first := ""
+	for _, line := range b.lines {
+		words := strings.Fields(line)
+		if len(words) == 0 || strings.HasPrefix(words[0], "//") || strings.HasPrefix(words[0], "/*") || strings.HasPrefix(words[0], "*") {
+			continue
+		}
+		switch words[0] {
+		case "pragma", "import", "contract", "abstract", "interface", "library":
+			return fileBlock
+		}
+		if first == "" {
+			first = strings.FieldsFunc(words[0], func(r rune) bool { return r == '(' })[0]
+		}
+	}
+	switch first {
+	case "function", "modifier", "event", "error", "struct", "enum", "constructor", "receive", "fallback", "using", "mapping":
+		return memberBlock
+	}
+	return statementBlock
+}
+
+// newCodeBlock parses the code of the fenced block of the Markdown file.
+func newCodeBlock(uri string, version int, open bool, b fencedBlock) *codeBlock {
+	block := &codeBlock{Mode: b.mode(), Line: uint(b.line), Lines: uint(len(b.lines)), Indent: uint(b.indent)}
+	src := strings.Join(b.lines, "\n")
+	if wrapper, ok := blockWrappers[block.Mode]; ok {
+		src = wrapper[0] + src + wrapper[1]
+		block.Header = uint(strings.Count(wrapper[0], "\n"))
+	}
+	blockURI := fmt.Sprintf("%s#L%d", uri, b.line+1)
+	p := parser.Parser{}
+	handle := token.NewFile(blockURI, src)
+	p.Init(handle)
+	file := p.ParseFile()
+	block.Errors = p.Errors()
+	block.Doc = &Document{
+		URI:     blockURI,
+		Version: version,
+		Open:    open,
+		Handle:  handle,
+		File:    file,
+		Anchors: anchors(file),
+		names:   identifiersHash(file),
+	}
+	return block
+}
+
+// toMarkdown returns the position in the Markdown file of the position in
+// the block. The positions in the wrapper are moved to the start or to the
+// end of the code.
+func (b *codeBlock) toMarkdown(p lsp.Position) lsp.Position {
+	switch {
+	case p.Line < b.Header:
+		return lsp.Position{Line: b.Line, Character: b.Indent}
+	case p.Line >= b.Header+b.Lines:
+		last := b.Lines
+		if last > 0 {
+			last--
+		}
+		line := int(b.Header+last) + 1
+		end := toLspPosition(b.Doc.Handle, b.Doc.Handle.Offset(line, b.Doc.Handle.LineLength(line)+1))
+		return lsp.Position{Line: b.Line + last, Character: end.Character + b.Indent}
+	}
+	return lsp.Position{Line: b.Line + p.Line - b.Header, Character: p.Character + b.Indent}
+}
+
+// inCode reports whether the range of the block is in the code, not in the
+// wrapper.
+func (b *codeBlock) inCode(r lsp.Range) bool {
+	return r.Start.Line >= b.Header && r.End.Line < b.Header+b.Lines
+}
+
+// isMarkdown reports whether the URI is the one of a Markdown file.
+func isMarkdown(uri string) bool {
+	return strings.HasSuffix(URIToPath(uri), ".md")
+}
+
+// setMarkdown parses the code blocks of the Markdown file, if the analysis
+// of the docs is on.
+func (s *State) setMarkdown(uri string, version int, open bool, src string) {
+	if !s.Config.Docs.Analysis {
+		delete(s.markdown, uri)
+		return
+	}
+	md := &markdownDocument{URI: uri, Version: version, Open: open, Blocks: []*codeBlock{}}
+	for _, b := range fencedBlocks(src) {
+		if b.isSolidity() {
+			md.Blocks = append(md.Blocks, newCodeBlock(uri, version, open, b))
+		}
+	}
+	s.markdown[uri] = md
+}
+
+// IndexMarkdown reads the Markdown files under the directory, the ones of
+// the dependencies excepted, if the analysis of the docs is on. The files
+// already open are kept as they are.
+func (s *State) IndexMarkdown(ctx context.Context, root string) error {
+	if !s.Config.Docs.Analysis {
+		return nil
+	}
+	return s.walkFiles(root, ".md", func(p string) error {
+		if err := Checkpoint(ctx); err != nil {
+			return err
+		}
+		uri := PathToURI(p)
+		if md, ok := s.markdown[uri]; (ok && md.Open) || s.isDependency(uri) {
+			return nil
+		}
+		src, err := vfs.ReadFile(s.files(), p)
+		if err != nil {
+			return err
+		}
+		s.setMarkdown(uri, 0, false, string(src))
+		return nil
+	})
+}
+
+// MarkdownURIs returns the URIs of the Markdown files with the Solidity
+// code blocks, in order.
+func (s *State) MarkdownURIs() []string {
+	return sortedKeys(s.markdown)
+}
+
+// codeBlock returns the block with the URI e.g.
+// file:///ws/docs/guide.md#L12, see document.
+func (s *State) codeBlock(uri string) (*codeBlock, bool) {
+	mdURI, _, ok := strings.Cut(uri, "#L")
+	if !ok || s.markdown[mdURI] == nil {
+		return nil, false
+	}
+	for _, b := range s.markdown[mdURI].Blocks {
+		if b.Doc.URI == uri {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// blockAt returns the block of the Markdown file at the position, with the
+// position in the block.
+func (s *State) blockAt(uri string, position lsp.Position) (*codeBlock, lsp.Position, bool) {
+	md, ok := s.markdown[uri]
+	if !ok {
+		return nil, position, false
+	}
+	for _, b := range md.Blocks {
+		if position.Line >= b.Line && position.Line < b.Line+b.Lines {
+			p := lsp.Position{Line: position.Line - b.Line + b.Header}
+			if position.Character > b.Indent {
+				p.Character = position.Character - b.Indent
+			}
+			return b, p, true
+		}
+	}
+	return nil, position, false
+}
+
+// markdownDiagnostics returns the syntax errors of the code blocks of the
+// Markdown file, and their diagnostics of the codes listed in the [docs]
+// section, at the lines of the file. The wrapped blocks are parts of some
+// code, so the names they don't declare are not reported, and neither are
+// the diagnostics of the wrapper itself.
+func (s *State) markdownDiagnostics(ctx context.Context, md *markdownDocument) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, b := range md.Blocks {
+		for _, err := range b.Errors {
+			p := b.toMarkdown(toLspPosition(b.Doc.Handle, err.Pos))
+			res = append(res, lsp.Diagnostic{
+				Range:    lsp.Range{Start: p, End: p},
+				Severity: lsp.SeverityError,
+				Code:     "syntax-error",
+				Source:   "solbot",
+				Message:  err.Message(),
+			})
+		}
+		if len(s.Config.Docs.Diagnostics) == 0 {
+			continue
+		}
+		for _, d := range s.analyze(ctx, b.Doc) {
+			if !slices.Contains(s.Config.Docs.Diagnostics, d.Code) || !b.inCode(d.Range) {
+				continue
+			}
+			if b.Mode != fileBlock && (d.Code == "undeclared-identifier" || d.Code == "unresolved-member") {
+				continue
+			}
+			d.Range = lsp.Range{Start: b.toMarkdown(d.Range.Start), End: b.toMarkdown(d.Range.End)}
+			related := []lsp.DiagnosticRelatedInformation{}
+			for _, info := range d.RelatedInformation {
+				if info.Location.URI == b.Doc.URI {
+					info.Location = lsp.Location{URI: md.URI, Range: lsp.Range{Start: b.toMarkdown(info.Location.Range.Start), End: b.toMarkdown(info.Location.Range.End)}}
+				}
+				related = append(related, info)
+			}
+			d.RelatedInformation = related
+			res = append(res, d)
+		}
+	}
+	sortDiagnostics(res)
+	return res
+}
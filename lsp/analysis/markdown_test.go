@@ -0,0 +1,104 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func openGuide(t *testing.T) (*State, string) {
+	t.Helper()
+	src, err := os.ReadFile("testdata/docs/guide.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewState()
+	s.Root = "/ws"
+	s.Config.Docs.Analysis = true
+	uri := "file:///ws/docs/guide.md"
+	s.OpenDocument(uri, 1, string(src))
+	return s, uri
+}
+
+func Test_MarkdownDiagnostics(t *testing.T) {
+	s, uri := openGuide(t)
+	if len(s.markdown[uri].Blocks) != 2 {
+		t.Fatalf("Expected the 2 blocks not ignored, got %d", len(s.markdown[uri].Blocks))
+	}
+
+	diagnostics := s.Diagnostics(context.Background(), uri).Params.Diagnostics
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", diagnostics)
+	}
+	// The missing parenthesis of the indented block, at its line of the
+	// file and after the indentation.
+	d := diagnostics[0]
+	if d.Code != "syntax-error" || d.Range.Start != (lsp.Position{Line: 23, Character: 24}) {
+		t.Errorf("Expected the syntax error at 23:24, got %s at %v: %s", d.Code, d.Range.Start, d.Message)
+	}
+	if len(s.Documents) != 0 {
+		t.Errorf("Expected the blocks to be kept apart from the workspace documents, got %d documents", len(s.Documents))
+	}
+
+	// The diagnostics of the listed codes are reported too, but not the
+	// names the wrapped blocks don't declare.
+	s.Config.Docs.Diagnostics = []string{"undeclared-identifier", "unused-variable"}
+	s.UpdateDocument(uri, 2, strings.Replace(readGuide(t), "msg.value;", "msg.value + fee;", 1))
+	codes := []string{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		codes = append(codes, d.Code)
+		if d.Code == "undeclared-identifier" && d.Range.Start != (lsp.Position{Line: 12, Character: 44}) {
+			t.Errorf("Expected the undeclared fee at 12:44, got %v", d.Range.Start)
+		}
+	}
+	if strings.Join(codes, ",") != "undeclared-identifier,syntax-error" {
+		t.Errorf("Expected the undeclared fee and the syntax error, got %v", codes)
+	}
+
+	s.Config.Docs.Analysis = false
+	s.UpdateDocument(uri, 3, readGuide(t))
+	if diagnostics := s.Diagnostics(context.Background(), uri).Params.Diagnostics; len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics with the analysis of the docs off, got %d", len(diagnostics))
+	}
+}
+
+func readGuide(t *testing.T) string {
+	t.Helper()
+	src, err := os.ReadFile("testdata/docs/guide.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(src)
+}
+
+func Test_MarkdownHover(t *testing.T) {
+	s, uri := openGuide(t)
+
+	// `balances` in the body of deposit.
+	hover := s.Hover(lsp.IntID(1), uri, lsp.Position{Line: 12, Character: 10}).Result.Contents.Value
+	if !strings.Contains(hover, "mapping(address => uint256) public balances") {
+		t.Errorf("Expected the hover of balances, got %q", hover)
+	}
+	// The prose between the blocks has no hover.
+	if hover := s.Hover(lsp.IntID(2), uri, lsp.Position{Line: 2, Character: 3}).Result.Contents.Value; hover != "" {
+		t.Errorf("Expected no hover outside of the blocks, got %q", hover)
+	}
+}
+
+func Test_FencedBlocks(t *testing.T) {
+	blocks := fencedBlocks(readGuide(t))
+	got := []string{}
+	for _, b := range blocks {
+		mode := map[blockMode]string{fileBlock: "file", memberBlock: "members", statementBlock: "statements"}[b.mode()]
+		got = append(got, strings.Join(b.info, " ")+" "+mode)
+	}
+	expected := "solidity file|solidity statements|solidity ignore members|sol statements"
+	if strings.Join(got, "|") != expected {
+		t.Errorf("Expected the blocks %s, got %s", expected, strings.Join(got, "|"))
+	}
+	if blocks[1].line != 22 || blocks[1].indent != 3 || blocks[1].lines[0] != "uint256 amount = vault.balances(msg.sender);" {
+		t.Errorf("Expected the indented block at line 22, got %d with the indentation %d and %q", blocks[1].line, blocks[1].indent, blocks[1].lines[0])
+	}
+}
@@ -0,0 +1,228 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// memoryCopy is a local variable initialized with a memory copy of a
+// storage struct or array e.g. `Position memory p = positions[i];`.
+type memoryCopy struct {
+	decl    *ast.VariableDeclaration
+	keyword token.Range // the `memory` keyword
+	inLoop  bool
+	isArray bool
+
+	writes int                 // writes to the members or the elements
+	reads  map[string]struct{} // names of the members read
+	others int                 // other uses e.g. passing it to a function
+}
+
+// memoryCopyDiagnostics reports the inefficient or wrong copies of the
+// storage to the memory:
+//   - the copy is modified, but it's never used as a whole e.g. written
+//     back to the storage or returned, so the changes are lost,
+//   - a whole struct is copied in every iteration of a loop to read a
+//     single member.
+func (s *State) memoryCopyDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, c := range s.memoryCopies(doc) {
+		if d, ok := c.diagnostic(doc); ok {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func (c *memoryCopy) diagnostic(doc *Document) (lsp.Diagnostic, bool) {
+	d := lsp.Diagnostic{
+		Range:  toLspRange(doc.Handle, ast.NodeRange(c.decl)),
+		Source: "solbot",
+	}
+	name := c.decl.Name.Name
+	switch {
+	case c.lostWrite():
+		d.Severity = lsp.SeverityWarning
+		d.Code = "lost-memory-write"
+		d.Message = fmt.Sprintf("Changes to `%s` are not persisted, it's a memory copy of the storage; did you mean `storage`?", name)
+	case c.inLoop && !c.isArray && c.writes == 0 && c.others == 0 && len(c.reads) == 1:
+		member := ""
+		for name := range c.reads {
+			member = name
+		}
+		d.Severity = lsp.SeverityHint
+		d.Code = "memory-copy-in-loop"
+		d.Message = fmt.Sprintf("The whole struct is copied to the memory in every iteration to read only `%s.%s`, consider reading the member directly or using a `storage` pointer", name, member)
+	default:
+		return d, false
+	}
+	return d, true
+}
+
+func (c *memoryCopy) lostWrite() bool {
+	return c.writes > 0 && c.others == 0
+}
+
+// memoryCopyActions returns the quick fixes changing the data location of
+// the copies with the lost writes to `storage`.
+func (s *State) memoryCopyActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	for _, c := range s.memoryCopies(doc) {
+		if !c.lostWrite() || !touches(selected, ast.NodeRange(c.decl)) {
+			continue
+		}
+		d, _ := c.diagnostic(doc)
+		actions = append(actions, lsp.CodeAction{
+			Title:       fmt.Sprintf("Change `%s` to a storage pointer", c.decl.Name.Name),
+			Kind:        lsp.CodeActionQuickFix,
+			Diagnostics: []lsp.Diagnostic{d},
+			Edit: &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+				doc.URI: {{Range: toLspRange(doc.Handle, c.keyword), NewText: "storage"}},
+			}},
+		})
+	}
+	return actions
+}
+
+// memoryCopies returns the local variables of the document initialized with
+// a memory copy of a struct or an array in the storage, together with their
+// uses.
+func (s *State) memoryCopies(doc *Document) []*memoryCopy {
+	copies := map[*ast.VariableDeclaration]*memoryCopy{}
+	res := []*memoryCopy{}
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		stmt, ok := node.(*ast.VariableDeclarationStatement)
+		if !ok || len(stmt.Declarations) != 1 || stmt.Declarations[0] == nil || stmt.Value == nil {
+			return true
+		}
+		decl := stmt.Declarations[0]
+		if decl.Location != ast.Memory {
+			return true
+		}
+
+		c := &memoryCopy{decl: decl, reads: map[string]struct{}{}}
+		switch typ := decl.Type.(type) {
+		case *ast.ArrayType:
+			c.isArray = true
+		default:
+			sym := s.follow(s.resolveExpr(doc, ast.PathEnclosingPos(doc.File, typ.Start()), typ))
+			if sym == nil {
+				return true
+			}
+			if _, ok := sym.Node.(*ast.StructDeclaration); !ok {
+				return true
+			}
+		}
+		if !s.isStorageRef(doc, stmt.Value) {
+			return true
+		}
+
+		src := doc.Handle.Src()
+		i := strings.Index(src[decl.Type.End():decl.Name.Start()], "memory")
+		if i < 0 {
+			return true
+		}
+		start := decl.Type.End() + token.Pos(i)
+		c.keyword = token.Range{Start: start, End: start + token.Pos(len("memory"))}
+
+		for _, node := range ast.PathEnclosingPos(doc.File, decl.Name.Start()) {
+			switch node.(type) {
+			case *ast.ForStatement, *ast.WhileStatement, *ast.DoWhileStatement:
+				c.inLoop = true
+			}
+		}
+
+		copies[decl] = c
+		res = append(res, c)
+		return true
+	})
+	if len(res) == 0 {
+		return res
+	}
+
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		sym := s.resolve(doc, path)
+		if sym == nil {
+			return
+		}
+		decl, ok := sym.Node.(*ast.VariableDeclaration)
+		if !ok || copies[decl] == nil || decl.Name == ident {
+			return
+		}
+		copies[decl].addUse(path)
+	})
+	return res
+}
+
+// addUse classifies the use of the copy at path[0]: a write or a read of a
+// member or an element, or some other use of the whole value.
+func (c *memoryCopy) addUse(path []ast.Node) {
+	top := accessedBy(path)
+	if top == 0 {
+		c.others++
+		return
+	}
+
+	if top+1 < len(path) {
+		switch parent := path[top+1].(type) {
+		case *ast.AssignmentExpression:
+			if parent.Left == path[top] {
+				c.writes++
+				return
+			}
+		case *ast.UnaryExpression:
+			switch parent.Operator {
+			case token.INC, token.DEC, token.DELETE:
+				c.writes++
+				return
+			}
+		}
+	}
+	if member, ok := path[1].(*ast.MemberAccessExpression); ok {
+		c.reads[member.Member.Name] = struct{}{}
+	} else {
+		c.reads[""] = struct{}{}
+	}
+}
+
+// isStorageRef reports whether the expression refers to the storage: a
+// state variable, a storage pointer or their members and elements.
+func (s *State) isStorageRef(doc *Document, x ast.Expression) bool {
+	for {
+		switch e := x.(type) {
+		case *ast.MemberAccessExpression:
+			x = e.Expression
+			continue
+		case *ast.IndexAccessExpression:
+			x = e.Expression
+			continue
+		case *ast.TupleExpression:
+			if len(e.Elements) == 1 {
+				x = e.Elements[0]
+				continue
+			}
+		}
+		break
+	}
+	ident, ok := x.(*ast.Identifier)
+	if !ok {
+		return false
+	}
+	sym := s.resolve(doc, ast.PathEnclosingPos(doc.File, ident.Start()))
+	if sym == nil {
+		return false
+	}
+	switch n := sym.Node.(type) {
+	case *ast.VariableDeclaration:
+		if n.Location == ast.Storage {
+			return true
+		}
+		return !n.Constant && !n.Immutable && s.declaringContract(sym) != nil
+	case *ast.Param:
+		return n.Location == ast.Storage
+	}
+	return false
+}
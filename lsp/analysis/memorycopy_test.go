@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"context"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func Test_MemoryCopyDiagnostics(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+contract Staking {
+    struct Position {
+        uint256 amount;
+        uint256 rewards;
+        uint64 since;
+    }
+
+    Position[] positions;
+    mapping(address => Position) byOwner;
+
+    function claim(uint256 i) external {
+        Position memory p = positions[i];
+        p.rewards = 0;
+        p.since = uint64(block.timestamp);
+    }
+
+    function rewardsOf(address owner) external view returns (uint256) {
+        Position memory p = byOwner[owner];
+        return p.rewards + p.amount;
+    }
+
+    function total() external view returns (uint256 sum) {
+        for (uint256 i = 0; i < positions.length; i++) {
+            Position memory p = positions[i];
+            sum += p.amount;
+        }
+    }
+
+    function reset(uint256 i) external {
+        Position memory p = positions[i];
+        p.rewards = 0;
+        positions[i] = p;
+    }
+}
+`
+
+	s := NewState()
+	s.Config.Disabled = []string{"could-be-view"}
+	s.OpenDocument("file:///ws/src/Staking.sol", 1, src)
+
+	expected := []struct {
+		code     string
+		line     uint
+		severity lsp.DiagnosticSeverity
+	}{
+		{"lost-memory-write", 13, lsp.SeverityWarning},
+		{"memory-copy-in-loop", 25, lsp.SeverityHint},
+	}
+	diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Staking.sol").Params.Diagnostics
+	if len(diagnostics) != len(expected) {
+		for _, d := range diagnostics {
+			t.Logf("%d: %s: %s", d.Range.Start.Line, d.Code, d.Message)
+		}
+		t.Fatalf("Expected %d diagnostics, got %d", len(expected), len(diagnostics))
+	}
+	for i, d := range diagnostics {
+		if d.Code != expected[i].code || d.Range.Start.Line != expected[i].line || d.Severity != expected[i].severity {
+			t.Errorf("Expected %s at line %d with severity %d, got %s at line %d with severity %d",
+				expected[i].code, expected[i].line, expected[i].severity, d.Code, d.Range.Start.Line, d.Severity)
+		}
+	}
+	expectedMessage := "Changes to `p` are not persisted, it's a memory copy of the storage; did you mean `storage`?"
+	if diagnostics[0].Message != expectedMessage {
+		t.Errorf("Expected message %q, got %q", expectedMessage, diagnostics[0].Message)
+	}
+	if !strings.Contains(diagnostics[1].Message, "`p.amount`") {
+		t.Errorf("Expected the message to mention `p.amount`, got %q", diagnostics[1].Message)
+	}
+
+	actions := s.CodeAction(lsp.IntID(1), "file:///ws/src/Staking.sol", diagnostics[0].Range).Result
+	if len(actions) != 1 {
+		t.Fatalf("Expected 1 quick fix, got %d", len(actions))
+	}
+	doc := s.Documents["file:///ws/src/Staking.sol"]
+	fixed := applyEdits(doc, actions[0].Edit.Changes[doc.URI])
+	if !strings.Contains(fixed, "        Position storage p = positions[i];\n        p.rewards = 0;\n        p.since") {
+		t.Errorf("Expected the data location to be changed to storage, got:\n%s", fixed)
+	}
+}
@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/metrics"
+)
+
+// FunctionMetrics are the metrics of a function in the document.
+type FunctionMetrics struct {
+	metrics.Function
+	Doc *Document
+}
+
+// Metrics measures the functions of the document. The modifiers are
+// resolved across the workspace, so the ones inherited from the bases
+// declared in other files are included too.
+func (s *State) Metrics(doc *Document) []metrics.Function {
+	return metrics.File(doc.File, func(c *ast.ContractDeclaration, name string) *ast.ModifierDeclaration {
+		sym := s.lookupMember(doc, c, name, map[*ast.ContractDeclaration]bool{})
+		if sym == nil {
+			return nil
+		}
+		mod, _ := sym.Node.(*ast.ModifierDeclaration)
+		return mod
+	})
+}
+
+// PathMetrics measures the functions of the documents in the file or
+// directory, see documentsUnder.
+func (s *State) PathMetrics(path string) []FunctionMetrics {
+	res := []FunctionMetrics{}
+	for _, doc := range s.documentsUnder(path) {
+		for _, f := range s.Metrics(doc) {
+			res = append(res, FunctionMetrics{Function: f, Doc: doc})
+		}
+	}
+	return res
+}
+
+// metricDiagnostics reports the functions whose metrics exceed the
+// thresholds configured in solbot.toml.
+func (s *State) metricDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	if !s.Config.Metrics.Enabled() {
+		return res
+	}
+
+	severity := lsp.SeverityWarning
+	if s.Config.Metrics.Severity == "hint" {
+		severity = lsp.SeverityHint
+	}
+	for _, f := range s.Metrics(doc) {
+		for _, v := range f.Check(s.Config.Metrics) {
+			res = append(res, lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, f.NameRange()),
+				Severity: severity,
+				Code:     v.Metric,
+				Source:   "solbot",
+				Message:  v.Message(f),
+			})
+		}
+	}
+	return res
+}
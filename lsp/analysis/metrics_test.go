@@ -0,0 +1,72 @@
+package analysis
+
+import (
+	"context"
+	"solbot/lsp"
+	"solbot/project"
+	"testing"
+)
+
+func Test_MetricsDiagnostics(t *testing.T) {
+	s := NewState()
+	// The test is about the metrics, not the stipend of `transfer`.
+	s.Config.Disabled = []string{"transfer-gas-stipend"}
+	s.Root = "/ws"
+	s.Documents["file:///ws/src/Pausable.sol"] = newDocument("file:///ws/src/Pausable.sol", 0, false, `pragma solidity ^0.8.0;
+
+contract Pausable {
+    bool paused;
+
+    modifier whenNotPaused() {
+        require(!paused || msg.sender == address(0));
+        _;
+    }
+}
+`)
+	s.OpenDocument("file:///ws/src/Vault.sol", 3, `pragma solidity ^0.8.0;
+
+import "./Pausable.sol";
+
+contract Vault is Pausable {
+    function withdraw(uint256 amount) external whenNotPaused {
+        if (amount > 0) {
+            payable(msg.sender).transfer(amount);
+        }
+    }
+}
+`)
+
+	diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Vault.sol").Params.Diagnostics
+	if len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics with the default thresholds, got %d", len(diagnostics))
+	}
+
+	// 1 + the if in the body + the || in the modifier from the other file
+	s.Config.Metrics = project.Metrics{Complexity: 2, Parameters: 3, Severity: "hint"}
+	notification := s.Diagnostics(context.Background(), "file:///ws/src/Vault.sol")
+	diagnostics = notification.Params.Diagnostics
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %d", len(diagnostics))
+	}
+
+	d := diagnostics[0]
+	expectedMessage := "Cyclomatic complexity of `Vault.withdraw` is 3, above the threshold of 2"
+	if d.Message != expectedMessage {
+		t.Errorf("Expected message %q, got %q", expectedMessage, d.Message)
+	}
+	if d.Severity != lsp.SeverityHint {
+		t.Errorf("Expected severity hint, got %d", d.Severity)
+	}
+	expectedRange := lsp.Range{Start: lsp.Position{Line: 5, Character: 13}, End: lsp.Position{Line: 5, Character: 21}}
+	if d.Range != expectedRange {
+		t.Errorf("Expected the range of the function name %v, got %v", expectedRange, d.Range)
+	}
+	if v := notification.Params.Version; v == nil || *v != 3 {
+		t.Errorf("Expected version 3 of the open document, got %v", v)
+	}
+
+	s.Config.Metrics.Complexity = 3
+	if diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Vault.sol").Params.Diagnostics; len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics at the threshold, got %d", len(diagnostics))
+	}
+}
@@ -0,0 +1,144 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"solbot/lsp"
+	"solbot/migration"
+	"solbot/semver"
+	"solbot/token"
+)
+
+// migrationTarget returns the pragma the document is checked against: the
+// one previewed with the code action or the one set in solbot.toml. It's
+// empty if the migration mode is off.
+func (s *State) migrationTarget(doc *Document) string {
+	if target, ok := s.Migrations[doc.URI]; ok {
+		return target
+	}
+	return s.Config.Migration
+}
+
+// migrationIssues returns the code of the document that breaks after its
+// pragma is raised to the migration target. The dependencies are not
+// migrated.
+func (s *State) migrationIssues(doc *Document) []migration.Issue {
+	target := s.migrationTarget(doc)
+	if target == "" || s.isDependency(doc.URI) {
+		return nil
+	}
+	c, err := semver.ParseConstraint(target)
+	if err != nil {
+		return nil
+	}
+	return migration.Check(doc.File, doc.Handle.Src(), c)
+}
+
+func (s *State) migrationDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, issue := range s.migrationIssues(doc) {
+		res = append(res, migrationDiagnostic(doc, issue))
+	}
+	return res
+}
+
+func migrationDiagnostic(doc *Document, issue migration.Issue) lsp.Diagnostic {
+	return lsp.Diagnostic{
+		Range:    toLspRange(doc.Handle, issue.Range),
+		Severity: lsp.SeverityInformation,
+		Code:     issue.Rule,
+		Source:   "solbot-migration",
+		Message:  issue.Message,
+	}
+}
+
+// migrationActions returns the migration actions in the selected range.
+// Without the migration mode, the solidity pragma offers to preview the
+// migrations applicable to it. In the migration mode, the issues offer
+// their fixes and the pragma offers to be raised to the target.
+func (s *State) migrationActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	p := doc.File.Pragma("solidity")
+	onPragma := p != nil && touches(selected, token.Range{Start: p.Start(), End: p.Semicolon + 1})
+
+	target := s.migrationTarget(doc)
+	if target == "" {
+		if !onPragma || s.isDependency(doc.URI) {
+			return actions
+		}
+		for _, m := range migration.Migrations {
+			if len(migration.Applicable(doc.File, m.To)) == 0 {
+				continue
+			}
+			title := "Preview migration to " + m.Target
+			actions = append(actions, lsp.CodeAction{
+				Title: title,
+				Kind:  lsp.CodeActionRefactor,
+				Command: &lsp.Command{
+					Title:     title,
+					Command:   lsp.PreviewMigrationCommand,
+					Arguments: []any{doc.URI, m.Target},
+				},
+			})
+		}
+		return actions
+	}
+
+	issues := s.migrationIssues(doc)
+	for _, issue := range issues {
+		if issue.Fix == nil || !touches(selected, issue.Range) {
+			continue
+		}
+		actions = append(actions, lsp.CodeAction{
+			Title:       issue.Fix.Title,
+			Kind:        lsp.CodeActionQuickFix,
+			Diagnostics: []lsp.Diagnostic{migrationDiagnostic(doc, issue)},
+			Edit:        migrationEdit(doc, issue.Fix.Edits...),
+			IsPreferred: true,
+		})
+	}
+	if onPragma && len(issues) > 0 {
+		if bump := migration.BumpPragma(doc.File, target); bump != nil {
+			actions = append(actions, lsp.CodeAction{
+				Title: "Update the pragma to " + target,
+				Kind:  lsp.CodeActionRefactor,
+				Edit:  migrationEdit(doc, *bump),
+			})
+		}
+	}
+	return actions
+}
+
+func migrationEdit(doc *Document, edits ...migration.Edit) *lsp.WorkspaceEdit {
+	textEdits := []lsp.TextEdit{}
+	for _, edit := range edits {
+		textEdits = append(textEdits, lsp.TextEdit{
+			Range:   toLspRange(doc.Handle, edit.Range),
+			NewText: edit.NewText,
+		})
+	}
+	return &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{doc.URI: textEdits}}
+}
+
+// ExecuteCommand runs the command and returns the URI of the document whose
+// diagnostics changed; or an empty string.
+func (s *State) ExecuteCommand(id lsp.ID, params lsp.ExecuteCommandParams) (lsp.ExecuteCommandResponse, string) {
+	switch params.Command {
+	case lsp.PreviewMigrationCommand:
+		var uri, target string
+		if len(params.Arguments) != 2 ||
+			json.Unmarshal(params.Arguments[0], &uri) != nil ||
+			json.Unmarshal(params.Arguments[1], &target) != nil {
+			return lsp.NewExecuteCommandErrorResponse(id, lsp.InvalidParams, "expected the document URI and the target pragma"), ""
+		}
+		if _, ok := s.Documents[uri]; !ok {
+			return lsp.NewExecuteCommandErrorResponse(id, lsp.InvalidParams, fmt.Sprintf("unknown document %s", uri)), ""
+		}
+		if _, err := semver.ParseConstraint(target); err != nil {
+			return lsp.NewExecuteCommandErrorResponse(id, lsp.InvalidParams, fmt.Sprintf("invalid target %s: %s", target, err)), ""
+		}
+		s.Migrations[uri] = target
+		return lsp.NewExecuteCommandResponse(id), uri
+	}
+	return lsp.NewExecuteCommandErrorResponse(id, lsp.InvalidParams, fmt.Sprintf("unknown command %s", params.Command)), ""
+}
@@ -0,0 +1,160 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"solbot/lsp"
+	"testing"
+)
+
+const legacyBank = `pragma solidity >=0.6.12 <0.8.0;
+pragma experimental ABIEncoderV2;
+
+import "./SafeMath.sol";
+
+contract Bank {
+    using SafeMath for uint256;
+
+    enum Status { Active, Frozen }
+
+    mapping(address => uint256) balances;
+    uint256 lastWithdrawal;
+    byte flags;
+    Status status;
+
+    function deposit(int256 bonus) external payable {
+        balances[msg.sender] = balances[msg.sender].add(msg.value).add(uint256(int8(bonus)));
+    }
+
+    function withdraw(uint256 amount, uint256 fee) external {
+        balances[msg.sender] = balances[msg.sender].sub(amount.mul(fee.add(1)));
+        lastWithdrawal = now;
+        msg.sender.transfer(amount);
+    }
+
+    function setStatus(uint8 code) external {
+        status = Status(code);
+    }
+}
+`
+
+const migratedBank = `pragma solidity ^0.8.0;
+
+contract Bank {
+    enum Status { Active, Frozen }
+
+    mapping(address => uint256) balances;
+    uint256 lastWithdrawal;
+    bytes1 flags;
+    Status status;
+
+    function deposit(int256 bonus) external payable {
+        balances[msg.sender] = balances[msg.sender] + msg.value + uint256(int256(int8(bonus)));
+    }
+
+    function withdraw(uint256 amount, uint256 fee) external {
+        balances[msg.sender] = balances[msg.sender] - amount * (fee + 1);
+        lastWithdrawal = block.timestamp;
+        payable(msg.sender).transfer(amount);
+    }
+
+    function setStatus(uint8 code) external {
+        status = Status(code);
+    }
+}
+`
+
+func Test_MigrationPreview(t *testing.T) {
+	s := NewState()
+	// The pragma allows 0.7, which removed `now`.
+	s.Config.Disabled = []string{"pragma-range"}
+	s.OpenDocument("file:///ws/src/Bank.sol", 1, legacyBank)
+	s.Documents["file:///ws/src/SafeMath.sol"] = newDocument("file:///ws/src/SafeMath.sol", 0, false, `pragma solidity >=0.6.0 <0.8.0;
+
+library SafeMath {
+    function add(uint256 a, uint256 b) internal pure returns (uint256) {
+        uint256 c = a + b;
+        require(c >= a, "SafeMath: addition overflow");
+        return c;
+    }
+}
+`)
+
+	if diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Bank.sol").Params.Diagnostics; len(diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics before the preview, got %q", diagnostics[0].Message)
+	}
+
+	actions := s.CodeAction(lsp.IntID(1), "file:///ws/src/Bank.sol", lsp.Range{}).Result
+	if len(actions) != 1 || actions[0].Command == nil {
+		t.Fatalf("Expected the preview action on the pragma, got %v", actions)
+	}
+	if actions[0].Title != "Preview migration to ^0.8.0" {
+		t.Errorf("Expected title %q, got %q", "Preview migration to ^0.8.0", actions[0].Title)
+	}
+
+	var params lsp.ExecuteCommandParams
+	command, _ := json.Marshal(actions[0].Command)
+	if err := json.Unmarshal(command, &params); err != nil {
+		t.Fatalf("Expected the command to be decoded, got %s", err)
+	}
+	response, uri := s.ExecuteCommand(lsp.IntID(2), params)
+	if response.Error != nil || uri != "file:///ws/src/Bank.sol" {
+		t.Fatalf("Expected the preview to be turned on for Bank.sol, got %q and error %v", uri, response.Error)
+	}
+
+	expected := []struct {
+		code string
+		line uint
+	}{
+		{"abicoder-v2", 1},
+		{"safemath", 6},
+		{"byte", 12},
+		{"explicit-conversion", 16}, // uint256(int8(bonus))
+		{"now", 21},
+		{"payable-sender", 22},
+		{"enum-conversion", 26},
+	}
+	diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Bank.sol").Params.Diagnostics
+	if len(diagnostics) != len(expected) {
+		for _, d := range diagnostics {
+			t.Logf("%d: %s: %s", d.Range.Start.Line, d.Code, d.Message)
+		}
+		t.Fatalf("Expected %d diagnostics, got %d", len(expected), len(diagnostics))
+	}
+	for i, d := range diagnostics {
+		if d.Code != expected[i].code || d.Range.Start.Line != expected[i].line {
+			t.Errorf("Expected %s at line %d, got %s at line %d", expected[i].code, expected[i].line, d.Code, d.Range.Start.Line)
+		}
+		if d.Severity != lsp.SeverityInformation || d.Source != "solbot-migration" {
+			t.Errorf("Expected an info from solbot-migration, got severity %d from %s", d.Severity, d.Source)
+		}
+	}
+
+	// Apply all of the fixes offered in the file together with the pragma
+	// update.
+	doc := s.Documents["file:///ws/src/Bank.sol"]
+	whole := lsp.Range{End: toLspPosition(doc.Handle, doc.File.End())}
+	edits := []lsp.TextEdit{}
+	fixes := 0
+	for _, action := range s.CodeAction(lsp.IntID(3), doc.URI, whole).Result {
+		if action.Kind == lsp.CodeActionQuickFix {
+			fixes++
+		}
+		edits = append(edits, action.Edit.Changes[doc.URI]...)
+	}
+	// The enum conversion is the only issue without a mechanical fix.
+	if fixes != len(expected)-1 {
+		t.Errorf("Expected %d quick fixes, got %d", len(expected)-1, fixes)
+	}
+
+	migrated := applyEdits(doc, edits)
+	if migrated != migratedBank {
+		t.Fatalf("Expected the migrated file:\n%s\ngot:\n%s", migratedBank, migrated)
+	}
+	// The migrated `transfer` is left for the stipend detector to report.
+	s.Config.Disabled = []string{"transfer-gas-stipend"}
+	s.UpdateDocument(doc.URI, 2, migrated)
+	if diagnostics := s.Diagnostics(context.Background(), doc.URI).Params.Diagnostics; len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics after the migration, got %q", diagnostics[0].Message)
+	}
+}
@@ -0,0 +1,321 @@
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+)
+
+// mistakeDiagnostics reports the expressions that look like copy-paste
+// slips: they compile, but they're almost never what was meant. The checks
+// can be disabled one by one with their codes in the [detectors] section:
+//
+//   - same-operands: both sides of a comparison or of `&&` and `||` are
+//     the same e.g. `balances[from] >= balances[from]`;
+//   - self-assignment: a variable is assigned to itself e.g.
+//     `owner = owner;`. It's an error if the variable is a parameter
+//     shadowing a state variable of the same name, the classic bug of a
+//     constructor, since the state variable is never assigned;
+//   - inverted-range: two bounds of the same value make an empty range
+//     with `&&` e.g. `x > MAX && x < MIN`, or one covering every value
+//     with `||`, once the constants are folded;
+//   - swapped-arguments: two arguments are named after each other's
+//     parameters e.g. `transferFrom(to, from, amount)` of
+//     `transferFrom(address from, address to, uint256 amount)`, and the
+//     parameters are of the same type.
+//
+// The expressions with side effects e.g. `next() == next()` are skipped,
+// as evaluating them twice may give different values. The tests are not
+// exempted.
+func (s *State) mistakeDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(r token.Range, severity lsp.DiagnosticSeverity, code, message string, related ...lsp.DiagnosticRelatedInformation) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:              toLspRange(doc.Handle, r),
+			Severity:           severity,
+			Code:               code,
+			Source:             "solbot",
+			Message:            message,
+			RelatedInformation: related,
+		})
+	}
+
+	consts := s.constantsOf(doc, nil)
+	chained := map[ast.Node]bool{}
+	ast.Inspect(doc.File, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BinaryExpression:
+			if !isComparison(n.Operator) && n.Operator != token.AND && n.Operator != token.OR {
+				return true
+			}
+			if ast.ExprString(n.Left) == ast.ExprString(n.Right) && !hasSideEffects(n.Left) {
+				report(ast.NodeRange(n), lsp.SeverityWarning, "same-operands",
+					fmt.Sprintf("Both sides of `%s` are `%s`; one of them is likely meant to be another expression", n.Operator, ast.ExprString(n.Left)))
+			}
+			if (n.Operator == token.AND || n.Operator == token.OR) && !chained[n] {
+				if message, ok := invertedRange(n, consts, chained); ok {
+					report(ast.NodeRange(n), lsp.SeverityWarning, "inverted-range", message)
+				}
+			}
+		case *ast.AssignmentExpression:
+			if n.Operator != token.ASSIGN || ast.ExprString(n.Left) != ast.ExprString(n.Right) || hasSideEffects(n.Left) {
+				return true
+			}
+			if shadowed := s.shadowedStateVariable(doc, n.Left); shadowed != nil {
+				report(ast.NodeRange(n), lsp.SeverityError, "self-assignment",
+					fmt.Sprintf("The parameter `%[1]s` is assigned to itself, it shadows the state variable `%[1]s`, which is never assigned", shadowed.Name.Name),
+					lsp.DiagnosticRelatedInformation{
+						Location: lsp.Location{URI: shadowed.Doc.URI, Range: toLspRange(shadowed.Doc.Handle, ast.NodeRange(shadowed.Name))},
+						Message:  "The shadowed state variable",
+					})
+				return true
+			}
+			report(ast.NodeRange(n), lsp.SeverityWarning, "self-assignment",
+				fmt.Sprintf("`%s` is assigned to itself; the assignment has no effect", ast.ExprString(n.Left)))
+		case *ast.CallExpression:
+			if r, message, related, ok := s.swappedArguments(doc, n); ok {
+				report(r, lsp.SeverityWarning, "swapped-arguments", message, related...)
+			}
+		}
+		return true
+	})
+	return res
+}
+
+func isComparison(op token.TokenType) bool {
+	switch op {
+	case token.EQUAL, token.NOT_EQUAL, token.LESS_THAN, token.GREATER_THAN,
+		token.LESS_THAN_OR_EQUAL, token.GREATER_THAN_OR_EQUAL:
+		return true
+	}
+	return false
+}
+
+// shadowedStateVariable returns the state variable of the same name as the
+// parameter the expression refers to; or nil if it's not a parameter, or
+// the parameter shadows nothing.
+func (s *State) shadowedStateVariable(doc *Document, x ast.Expression) *Symbol {
+	ident, ok := x.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	path := ast.PathEnclosingPos(doc.File, ident.Start())
+	if sym := s.resolve(doc, path); sym == nil {
+		return nil
+	} else if _, ok := sym.Node.(*ast.Param); !ok {
+		return nil
+	}
+	contract := enclosingContract(doc, path)
+	if contract == nil {
+		return nil
+	}
+	shadowed := s.lookupMember(contract.Doc, contract.Node.(*ast.ContractDeclaration), ident.Name, map[*ast.ContractDeclaration]bool{})
+	if shadowed == nil {
+		return nil
+	}
+	if _, ok := shadowed.Node.(*ast.VariableDeclaration); !ok {
+		return nil
+	}
+	return shadowed
+}
+
+// bound is the lowest or the highest value a comparison allows e.g. `x > 5`
+// is the lower bound 6.
+type bound struct {
+	lower bool
+	value *big.Int
+}
+
+// invertedRange explains why the chain of the `&&` or the `||` operators
+// is never or always true: two of its comparisons bound the same value from
+// both sides, with the bounds making an empty range for `&&` e.g. `x > 100
+// && x < 10`, or one covering every value for `||` e.g. `x >= 10 || x <
+// 100`. The nested operators of the chain are marked, so that they're not
+// checked again.
+func invertedRange(x *ast.BinaryExpression, consts constants, chained map[ast.Node]bool) (string, bool) {
+	operands := []ast.Expression{}
+	var flatten func(e ast.Expression)
+	flatten = func(e ast.Expression) {
+		if b, ok := e.(*ast.BinaryExpression); ok && b.Operator == x.Operator {
+			chained[b] = true
+			flatten(b.Left)
+			flatten(b.Right)
+			return
+		}
+		operands = append(operands, e)
+	}
+	flatten(x)
+
+	type comparison struct {
+		x      ast.Expression
+		bounds bound
+	}
+	comparisons := []comparison{}
+	for _, operand := range operands {
+		for {
+			tuple, ok := operand.(*ast.TupleExpression)
+			if !ok || len(tuple.Elements) != 1 {
+				break
+			}
+			operand = tuple.Elements[0]
+		}
+		if c, ok := operand.(*ast.BinaryExpression); ok {
+			if value, b, ok := comparisonBound(c, consts); ok {
+				comparisons = append(comparisons, comparison{value, b})
+			}
+		}
+	}
+
+	for i, a := range comparisons {
+		for _, b := range comparisons[i+1:] {
+			if a.bounds.lower == b.bounds.lower || ast.ExprString(a.x) != ast.ExprString(b.x) {
+				continue
+			}
+			lower, upper := a.bounds.value, b.bounds.value
+			if !a.bounds.lower {
+				lower, upper = upper, lower
+			}
+			detail := ""
+			if src, folded := ast.ExprString(x), foldedString(x, consts); folded != src {
+				detail = fmt.Sprintf(" (`%s` is `%s`)", src, folded)
+			}
+			switch {
+			case x.Operator == token.AND && lower.Cmp(upper) > 0:
+				return fmt.Sprintf("The range of `%s` is empty, the condition is never true%s; the bounds or the operators are likely inverted", ast.ExprString(a.x), detail), true
+			case x.Operator == token.OR && lower.Cmp(new(big.Int).Add(upper, big.NewInt(1))) <= 0:
+				return fmt.Sprintf("The range of `%s` covers every value, the condition is always true%s; the bounds or the operators are likely inverted", ast.ExprString(a.x), detail), true
+			}
+		}
+	}
+	return "", false
+}
+
+// comparisonBound returns the value compared with a constant, and the
+// bound the comparison sets; or false if neither or both sides are
+// constant, or the operator is not an inequality.
+func comparisonBound(c *ast.BinaryExpression, consts constants) (ast.Expression, bound, bool) {
+	x, op := c.Left, c.Operator
+	constant, ok := foldInt(c.Right, consts)
+	if !ok {
+		// The value is on the right e.g. `10 < x`, flip the operator.
+		x = c.Right
+		if constant, ok = foldInt(c.Left, consts); !ok {
+			return nil, bound{}, false
+		}
+		switch op {
+		case token.LESS_THAN:
+			op = token.GREATER_THAN
+		case token.GREATER_THAN:
+			op = token.LESS_THAN
+		case token.LESS_THAN_OR_EQUAL:
+			op = token.GREATER_THAN_OR_EQUAL
+		case token.GREATER_THAN_OR_EQUAL:
+			op = token.LESS_THAN_OR_EQUAL
+		}
+	}
+	if _, ok := foldInt(x, consts); ok || hasSideEffects(x) {
+		return nil, bound{}, false
+	}
+	one := big.NewInt(1)
+	switch op {
+	case token.GREATER_THAN:
+		return x, bound{lower: true, value: new(big.Int).Add(constant, one)}, true
+	case token.GREATER_THAN_OR_EQUAL:
+		return x, bound{lower: true, value: constant}, true
+	case token.LESS_THAN:
+		return x, bound{value: new(big.Int).Sub(constant, one)}, true
+	case token.LESS_THAN_OR_EQUAL:
+		return x, bound{value: constant}, true
+	}
+	return nil, bound{}, false
+}
+
+// swappedArguments returns the range of two arguments passed in the order
+// contradicting the names of the parameters: each of them is an identifier
+// named exactly like the parameter of the other one, and the parameters
+// are of the same type. The callee has to be resolved, and the overloads
+// of the same arity told apart by the arity alone.
+func (s *State) swappedArguments(doc *Document, call *ast.CallExpression) (token.Range, string, []lsp.DiagnosticRelatedInformation, bool) {
+	if len(call.Args) < 2 || call.Names != nil {
+		return token.Range{}, "", nil, false
+	}
+	path := ast.PathEnclosingPos(doc.File, call.Lparen)
+	i := slices.Index(path, ast.Node(call))
+	if i < 0 {
+		return token.Range{}, "", nil, false
+	}
+	path = path[i+1:]
+	x := call.Function
+	if options, ok := x.(*ast.CallOptionsExpression); ok {
+		x = options.Expression
+	}
+	callee := s.follow(s.resolveExpr(doc, path, x))
+	if access, ok := x.(*ast.MemberAccessExpression); ok && callee == nil {
+		callee = s.attachedFunction(doc, path, access.Member.Name)
+	}
+	if callee == nil || callee.Name == nil {
+		return token.Range{}, "", nil, false
+	}
+
+	attached := s.isAttachedCall(doc, path, call, callee)
+	arity := len(call.Args)
+	if attached {
+		arity++
+	}
+	var params *ast.ParamList
+	switch n := callee.Node.(type) {
+	case *ast.FunctionDeclaration:
+		candidates := []*ast.FunctionDeclaration{}
+		for _, overload := range declaredOverloads(callee) {
+			if fn := overload.Node.(*ast.FunctionDeclaration); paramCount(fn) == arity {
+				candidates = append(candidates, fn)
+			}
+		}
+		if len(candidates) != 1 {
+			return token.Range{}, "", nil, false
+		}
+		params = candidates[0].Type.Params
+	case *ast.EventDeclaration:
+		params = n.Params
+	case *ast.ErrorDeclaration:
+		params = n.Params
+	}
+	if params == nil || len(params.List) != arity {
+		return token.Range{}, "", nil, false
+	}
+	list := params.List
+	if attached {
+		list = list[1:]
+	}
+
+	named := func(arg ast.Expression, param *ast.Param) bool {
+		ident, ok := arg.(*ast.Identifier)
+		return ok && param.Name != nil && ident.Name == param.Name.Name
+	}
+	for i := range call.Args {
+		for j := i + 1; j < len(call.Args); j++ {
+			if !named(call.Args[i], list[j]) || !named(call.Args[j], list[i]) || list[i].Name.Name == list[j].Name.Name {
+				continue
+			}
+			first, ok := s.canonicalType(callee.Doc, list[i].Type, map[ast.Node]bool{})
+			second, sok := s.canonicalType(callee.Doc, list[j].Type, map[ast.Node]bool{})
+			if !ok || !sok || first != second {
+				continue
+			}
+			message := fmt.Sprintf("`%s` and `%s` are passed as the `%s` and `%s` parameters of `%s`; the arguments are likely swapped",
+				list[j].Name.Name, list[i].Name.Name, list[i].Name.Name, list[j].Name.Name, callee.Name.Name)
+			related := []lsp.DiagnosticRelatedInformation{{
+				Location: lsp.Location{URI: callee.Doc.URI, Range: toLspRange(callee.Doc.Handle, token.Range{Start: list[i].Start(), End: list[j].End()})},
+				Message:  "The parameters",
+			}}
+			return token.Range{Start: call.Args[i].Start(), End: call.Args[j].End()}, message, related, true
+		}
+	}
+	return token.Range{}, "", nil, false
+}
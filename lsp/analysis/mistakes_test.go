@@ -0,0 +1,167 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"testing"
+)
+
+func Test_MistakeDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		code     string
+		severity lsp.DiagnosticSeverity
+		message  string
+	}{
+		{
+			"same comparison operands",
+			"require(balances[from] >= balances[from]);",
+			"same-operands", lsp.SeverityWarning,
+			"Both sides of `>=` are `balances[from]`; one of them is likely meant to be another expression",
+		},
+		{
+			"same logical operands",
+			"paused = paused || paused;",
+			"same-operands", lsp.SeverityWarning,
+			"Both sides of `||` are `paused`; one of them is likely meant to be another expression",
+		},
+		{
+			"self-assignment",
+			"limit = limit;",
+			"self-assignment", lsp.SeverityWarning,
+			"`limit` is assigned to itself; the assignment has no effect",
+		},
+		{
+			"shadowing parameter",
+			"owner = owner;",
+			"self-assignment", lsp.SeverityError,
+			"The parameter `owner` is assigned to itself, it shadows the state variable `owner`, which is never assigned",
+		},
+		{
+			"empty range",
+			"require(amount > MAX && amount < MIN);",
+			"inverted-range", lsp.SeverityWarning,
+			"The range of `amount` is empty, the condition is never true (`amount > MAX && amount < MIN` is `amount > 1000 && amount < 10`); the bounds or the operators are likely inverted",
+		},
+		{
+			"universal range",
+			"if (MIN <= amount || amount <= MAX) {}",
+			"inverted-range", lsp.SeverityWarning,
+			"The range of `amount` covers every value, the condition is always true (`MIN <= amount || amount <= MAX` is `10 <= amount || amount <= 1000`); the bounds or the operators are likely inverted",
+		},
+		{
+			"range in a chain",
+			"require(from != to && (amount >= 5) && amount <= 4);",
+			"inverted-range", lsp.SeverityWarning,
+			"The range of `amount` is empty, the condition is never true; the bounds or the operators are likely inverted",
+		},
+		{
+			"swapped arguments",
+			"transferFrom(to, from, amount);",
+			"swapped-arguments", lsp.SeverityWarning,
+			"`to` and `from` are passed as the `from` and `to` parameters of `transferFrom`; the arguments are likely swapped",
+		},
+		{
+			"swapped event arguments",
+			"emit Transfer(to, from, amount);",
+			"swapped-arguments", lsp.SeverityWarning,
+			"`to` and `from` are passed as the `from` and `to` parameters of `Transfer`; the arguments are likely swapped",
+		},
+		{"different operands", "require(balances[from] >= balances[to]);", "", 0, ""},
+		{"side effects", "require(next() == next());", "", 0, ""},
+		{"compound assignment", "limit += limit;", "", 0, ""},
+		{"valid range", "require(amount >= MIN && amount <= MAX);", "", 0, ""},
+		{"unknown bounds", "require(amount > limit && amount < MIN);", "", 0, ""},
+		{"bounds of other values", "require(amount > MAX && limit < MIN);", "", 0, ""},
+		{"arguments in order", "transferFrom(from, to, amount);", "", 0, ""},
+		{"partial match", "transferFrom(to, owner, amount);", "", 0, ""},
+		{"different types", "move(amount, to);", "", 0, ""},
+		{"named arguments", "transferFrom({from: from, to: to, amount: amount});", "", 0, ""},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		// The tests are not exempted.
+		uri := "file:///ws/test/Vault.t.sol"
+		s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+contract Vault {
+    uint256 constant MIN = 10;
+    uint256 constant MAX = 1_000;
+    address owner;
+    uint256 limit;
+    bool paused;
+    mapping(address => uint256) balances;
+
+    event Transfer(address indexed from, address indexed to, uint256 amount);
+
+    function check(address from, address to, uint256 amount, address owner) external {
+        `+tt.body+`
+    }
+
+    function transferFrom(address from, address to, uint256 amount) public {}
+
+    function move(address amount, uint256 to) internal {}
+
+    function next() internal returns (uint256) {}
+}
+`)
+
+		diagnostics := s.mistakeDiagnostics(s.Documents[uri])
+		if tt.code == "" {
+			if len(diagnostics) != 0 {
+				t.Errorf("Expected no diagnostics for %s, got %v", tt.name, diagnostics)
+			}
+			continue
+		}
+		if len(diagnostics) != 1 {
+			t.Errorf("Expected 1 diagnostic for %s, got %v", tt.name, diagnostics)
+			continue
+		}
+		d := diagnostics[0]
+		if d.Code != tt.code || d.Range.Start.Line != 13 || d.Severity != tt.severity {
+			t.Errorf("Expected %s of severity %d at line 13 for %s, got %s of severity %d at line %d",
+				tt.code, tt.severity, tt.name, d.Code, d.Severity, d.Range.Start.Line)
+		}
+		if d.Message != tt.message {
+			t.Errorf("Expected %q, got %q", tt.message, d.Message)
+		}
+
+		s.Config.Disabled = []string{tt.code}
+		if diagnostics := s.mistakeDiagnostics(s.Documents[uri]); len(diagnostics) != 0 {
+			t.Errorf("Expected %s to be disabled, got %v", tt.code, diagnostics)
+		}
+	}
+}
+
+func Test_SwappedArgumentsRange(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Token.sol"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+library Balances {
+    function move(mapping(address => uint256) storage self, address from, address to) internal {}
+}
+
+contract Token {
+    using Balances for mapping(address => uint256);
+    mapping(address => uint256) balances;
+
+    function transfer(address from, address to) external {
+        balances.move(to, from);
+    }
+}
+`)
+	diagnostics := s.mistakeDiagnostics(s.Documents[uri])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected the swapped arguments of the attached function, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	expected := lsp.Range{Start: lsp.Position{Line: 11, Character: 22}, End: lsp.Position{Line: 11, Character: 30}}
+	if d.Range != expected {
+		t.Errorf("Expected the range %v of the two arguments, got %v", expected, d.Range)
+	}
+	params := lsp.Range{Start: lsp.Position{Line: 3, Character: 60}, End: lsp.Position{Line: 3, Character: 84}}
+	if len(d.RelatedInformation) != 1 || d.RelatedInformation[0].Location.Range != params {
+		t.Errorf("Expected the parameters %v in the related information, got %+v", params, d.RelatedInformation)
+	}
+}
@@ -0,0 +1,359 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// modifierDiagnostics checks the modifiers declared and applied in the
+// document:
+//   - a modifier without a reachable placeholder `_;` never executes the
+//     body of the modified function,
+//   - a modifier with multiple placeholders executes the body multiple
+//     times, which is surprising if the modifier makes external calls,
+//   - a modifier that reaches itself through the functions it calls,
+//   - an applied modifier that doesn't exist or gets a wrong number of
+//     arguments.
+func (s *State) modifierDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(node ast.Node, severity lsp.DiagnosticSeverity, code, message string) {
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(node)),
+			Severity: severity,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+	// The modifiers can be declared anywhere in the bases, so the missing
+	// ones are reported only if all of the imports can be followed.
+	resolvable := s.importsResolve(doc, map[*Document]bool{})
+
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok {
+			continue
+		}
+		for _, member := range c.Body {
+			switch member := member.(type) {
+			case *ast.ModifierDeclaration:
+				if member.Body == nil {
+					continue
+				}
+				if found, _ := reachesPlaceholder(member.Body); !found {
+					report(member.Name, lsp.SeverityError, "missing-placeholder",
+						fmt.Sprintf("Modifier `%s` has no reachable placeholder `_;`, the body of the modified function never executes", member.Name.Name))
+				}
+				if n := countPlaceholders(member.Body); n > 1 && s.makesExternalCall(doc, member.Body) {
+					report(member.Name, lsp.SeverityInformation, "multiple-placeholders",
+						fmt.Sprintf("Modifier `%s` makes external calls and has %d placeholders `_;`, the body of the modified function executes %d times", member.Name.Name, n, n))
+				}
+				if cycle := s.modifierCycle(doc, member); cycle != nil {
+					report(member.Name, lsp.SeverityError, "recursive-modifier",
+						fmt.Sprintf("Modifier `%s` is recursive: %s", member.Name.Name, strings.Join(cycle, " -> ")))
+				}
+			case *ast.FunctionDeclaration:
+				for _, inv := range member.Modifiers {
+					if code, message := s.checkInvocation(doc, member, inv, resolvable); message != "" {
+						report(inv, lsp.SeverityError, code, message)
+					}
+				}
+			}
+		}
+	}
+	return res
+}
+
+// checkInvocation returns the problem with the modifier invocation; or an
+// empty message if there is none.
+func (s *State) checkInvocation(doc *Document, fn *ast.FunctionDeclaration,
+	inv *ast.ModifierInvocation, resolvable bool) (code, message string) {
+	name := ast.ExprString(inv.Name)
+	sym := s.follow(s.resolveExpr(doc, ast.PathEnclosingPos(doc.File, inv.Start()), inv.Name))
+	if sym == nil {
+		if !resolvable {
+			return "", ""
+		}
+		suggestions := []string{}
+		if ident, ok := inv.Name.(*ast.Identifier); ok {
+			suggestions = suggest(ident.Name, s.declaredNames(doc, ast.PathEnclosingPos(doc.File, inv.Start()), "modifier"))
+		}
+		return "undefined-modifier", fmt.Sprintf("Undefined modifier `%s`", name) + didYouMean(suggestions)
+	}
+
+	switch node := sym.Node.(type) {
+	case *ast.ModifierDeclaration:
+		expected := 0
+		if node.Params != nil {
+			expected = len(node.Params.List)
+		}
+		if len(inv.Args) != expected {
+			return "modifier-arity", fmt.Sprintf("Modifier `%s` expects %d %s, got %d",
+				name, expected, plural(expected, "argument"), len(inv.Args))
+		}
+	case *ast.ContractDeclaration:
+		// The base constructor call e.g. `constructor() Ownable(msg.sender)`.
+		if fn.Kind != token.CONSTRUCTOR {
+			return "undefined-modifier", fmt.Sprintf("`%s` is not a modifier, the base constructors can be called only by constructors", name)
+		}
+	default:
+		return "undefined-modifier", fmt.Sprintf("`%s` is not a modifier", name)
+	}
+	return "", ""
+}
+
+// importsResolve reports whether all of the imports of the document and of
+// the documents it imports can be resolved.
+func (s *State) importsResolve(doc *Document, visited map[*Document]bool) bool {
+	if visited[doc] {
+		return true
+	}
+	visited[doc] = true
+	for _, decl := range doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok {
+			continue
+		}
+		target := s.ImportTarget(doc, imp)
+		if target == nil || !s.importsResolve(target, visited) {
+			return false
+		}
+	}
+	return true
+}
+
+// reachesPlaceholder reports whether a placeholder can be reached when the
+// statement executes, and whether the execution can continue after it. The
+// branches whose conditions are always false are skipped.
+func reachesPlaceholder(stmt ast.Statement) (found, fallsThrough bool) {
+	switch stmt := stmt.(type) {
+	case nil:
+		return false, true
+	case *ast.PlaceholderStatement:
+		return true, true
+	case *ast.BlockStatement:
+		for _, s := range stmt.Statements {
+			f, ft := reachesPlaceholder(s)
+			found = found || f
+			if !ft {
+				return found, false
+			}
+		}
+		return found, true
+	case *ast.UncheckedBlockStatement:
+		return reachesPlaceholder(stmt.Body)
+	case *ast.ReturnStatement, *ast.RevertStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return false, false
+	case *ast.ExpressionStatement:
+		return false, !alwaysReverts(stmt.Expression)
+	case *ast.IfStatement:
+		if value, ok := constBool(stmt.Condition); ok {
+			if value {
+				return reachesPlaceholder(stmt.Consequence)
+			}
+			return reachesPlaceholder(stmt.Alternative)
+		}
+		f1, ft1 := reachesPlaceholder(stmt.Consequence)
+		f2, ft2 := reachesPlaceholder(stmt.Alternative)
+		return f1 || f2, ft1 || ft2
+	case *ast.ForStatement:
+		if value, ok := constBool(stmt.Condition); ok && !value {
+			return false, true
+		}
+		found, _ = reachesPlaceholder(stmt.Body)
+		return found, true
+	case *ast.WhileStatement:
+		if value, ok := constBool(stmt.Condition); ok && !value {
+			return false, true
+		}
+		found, _ = reachesPlaceholder(stmt.Body)
+		return found, true
+	case *ast.DoWhileStatement:
+		found, _ = reachesPlaceholder(stmt.Body)
+		return found, true
+	case *ast.TryStatement:
+		found, _ = reachesPlaceholder(stmt.Body)
+		for _, c := range stmt.Catches {
+			if f, _ := reachesPlaceholder(c.Body); f {
+				found = true
+			}
+		}
+		return found, true
+	}
+	return false, true
+}
+
+// alwaysReverts reports whether the expression is a call that always
+// reverts e.g. `revert("paused")` or `require(false)`.
+func alwaysReverts(x ast.Expression) bool {
+	call, ok := x.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	fn, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return false
+	}
+	switch fn.Name {
+	case "revert":
+		return true
+	case "require", "assert":
+		if len(call.Args) > 0 {
+			value, ok := constBool(call.Args[0])
+			return ok && !value
+		}
+	}
+	return false
+}
+
+func countPlaceholders(body *ast.BlockStatement) int {
+	count := 0
+	ast.Inspect(body, func(node ast.Node) bool {
+		if _, ok := node.(*ast.PlaceholderStatement); ok {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// makesExternalCall reports whether the body calls a member of another
+// contract or an address e.g. `token.transfer(to, amount)` or
+// `to.call{value: amount}("")`. The calls of the library functions and of
+// the builtins like `abi.encode` are internal.
+func (s *State) makesExternalCall(doc *Document, body *ast.BlockStatement) bool {
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || found {
+			return !found
+		}
+		fn := call.Function
+		if opts, ok := fn.(*ast.CallOptionsExpression); ok {
+			fn = opts.Expression
+		}
+		member, ok := fn.(*ast.MemberAccessExpression)
+		if !ok {
+			return true
+		}
+		if member.Member.Name == "push" || member.Member.Name == "pop" {
+			return true // array operations
+		}
+		switch base := member.Expression.(type) {
+		case *ast.Identifier:
+			switch base.Name {
+			case "abi", "super", "msg", "block", "tx", "string", "bytes":
+				return true
+			}
+		case *ast.CallExpression:
+			if ident, ok := base.Function.(*ast.Identifier); ok && ident.Name == "type" {
+				return true // e.g. `type(uint256).max`
+			}
+		}
+		path := ast.PathEnclosingPos(doc.File, member.Expression.Start())
+		if sym := s.follow(s.resolveExpr(doc, path, member.Expression)); sym != nil {
+			if c, ok := sym.Node.(*ast.ContractDeclaration); ok && c.Kind == token.LIBRARY {
+				return true
+			}
+		}
+		found = true
+		return false
+	})
+	return found
+}
+
+// callable is a function or a modifier with the document declaring it.
+type callable struct {
+	doc  *Document
+	node ast.Node // *ast.FunctionDeclaration or *ast.ModifierDeclaration
+}
+
+func (c callable) name() string {
+	switch node := c.node.(type) {
+	case *ast.ModifierDeclaration:
+		return node.Name.Name
+	case *ast.FunctionDeclaration:
+		if node.Name != nil {
+			return node.Name.Name
+		}
+		return node.Kind.String()
+	}
+	return ""
+}
+
+// modifierCycle returns the names on the path from the modifier back to
+// itself e.g. ["lock", "sync", "lock"] if the modifier calls the function
+// `sync` which applies the modifier; or nil if there is no such path.
+func (s *State) modifierCycle(doc *Document, mod *ast.ModifierDeclaration) []string {
+	start := callable{doc: doc, node: mod}
+	visited := map[ast.Node]bool{}
+
+	var visit func(c callable, path []string) []string
+	visit = func(c callable, path []string) []string {
+		path = append(path, c.name())
+		for _, next := range s.callees(c) {
+			if next.node == start.node {
+				return append(path, next.name())
+			}
+			if visited[next.node] {
+				continue
+			}
+			visited[next.node] = true
+			if cycle := visit(next, path); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return visit(start, nil)
+}
+
+// callees returns the modifiers applied by the function and the functions
+// called by name in its body, or in the body of the modifier.
+func (s *State) callees(c callable) []callable {
+	res := []callable{}
+	add := func(doc *Document, x ast.Expression) {
+		sym := s.follow(s.resolveExpr(doc, ast.PathEnclosingPos(doc.File, x.Start()), x))
+		if sym == nil {
+			return
+		}
+		switch sym.Node.(type) {
+		case *ast.FunctionDeclaration, *ast.ModifierDeclaration:
+			res = append(res, callable{doc: sym.Doc, node: sym.Node})
+		}
+	}
+
+	var body *ast.BlockStatement
+	switch node := c.node.(type) {
+	case *ast.FunctionDeclaration:
+		for _, inv := range node.Modifiers {
+			add(c.doc, inv.Name)
+		}
+		body = node.Body
+	case *ast.ModifierDeclaration:
+		body = node.Body
+	}
+	if body == nil {
+		return res
+	}
+
+	ast.Inspect(body, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpression); ok {
+			if ident, ok := call.Function.(*ast.Identifier); ok {
+				add(c.doc, ident)
+			}
+		}
+		return true
+	})
+	return res
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
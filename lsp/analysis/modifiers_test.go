@@ -0,0 +1,138 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_ModifierDiagnostics(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+interface IERC20 {
+    function transfer(address to, uint256 amount) external returns (bool);
+}
+
+contract Base {
+    constructor(uint256 x) {}
+}
+
+contract Vault is Base {
+    bool paused;
+    bool locked;
+    IERC20 token;
+
+    modifier whenNotPaused() {
+        require(!paused, "paused");
+    }
+
+    modifier whenPaused() {
+        require(paused);
+        if (1 > 2 || false) {
+            _;
+        }
+    }
+
+    modifier nonReentrant() {
+        require(!locked);
+        locked = true;
+        _;
+        locked = false;
+    }
+
+    modifier twice() {
+        token.transfer(msg.sender, 1);
+        _;
+        _;
+    }
+
+    modifier lock() {
+        sync();
+        _;
+    }
+
+    modifier syncing() {
+        refresh();
+        _;
+    }
+
+    modifier onlyRole(uint256 role) {
+        _;
+    }
+
+    constructor() Base(1) {}
+
+    function sync() internal syncing {}
+
+    function refresh() internal lock {}
+
+    function withdraw() external nonReentrant onlyRole(1, 2) {}
+
+    function deposit() external whenNotPaused onlyOwner {}
+
+    function pay() external Base(1) {}
+}
+`
+
+	s := NewState()
+	// The always false condition of whenPaused is reported on its own, and
+	// the parameters of the stubs are unused.
+	s.Config.Disabled = []string{"unreachable-code", "unused-variable", "could-be-view"}
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, src)
+
+	expected := []struct {
+		code string
+		line uint
+	}{
+		{"missing-placeholder", 15},   // whenNotPaused
+		{"missing-placeholder", 19},   // whenPaused, behind an always false condition
+		{"multiple-placeholders", 33}, // twice
+		{"recursive-modifier", 39},    // lock -> sync -> syncing -> refresh -> lock
+		{"recursive-modifier", 44},    // syncing -> refresh -> lock -> sync -> syncing
+		{"modifier-arity", 59},        // onlyRole(1, 2)
+		{"undefined-modifier", 61},    // onlyOwner
+		{"undefined-modifier", 63},    // Base(1) in a function
+	}
+
+	diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Vault.sol").Params.Diagnostics
+	if len(diagnostics) != len(expected) {
+		for _, d := range diagnostics {
+			t.Logf("%d: %s: %s", d.Range.Start.Line, d.Code, d.Message)
+		}
+		t.Fatalf("Expected %d diagnostics, got %d", len(expected), len(diagnostics))
+	}
+	for i, d := range diagnostics {
+		if d.Code != expected[i].code || d.Range.Start.Line != expected[i].line {
+			t.Errorf("Expected %s at line %d, got %s at line %d: %s",
+				expected[i].code, expected[i].line, d.Code, d.Range.Start.Line, d.Message)
+		}
+	}
+
+	expectedMessage := "Modifier `lock` is recursive: lock -> sync -> syncing -> refresh -> lock"
+	if diagnostics[3].Message != expectedMessage {
+		t.Errorf("Expected message %q, got %q", expectedMessage, diagnostics[3].Message)
+	}
+	expectedMessage = "Modifier `onlyRole` expects 1 argument, got 2"
+	if diagnostics[5].Message != expectedMessage {
+		t.Errorf("Expected message %q, got %q", expectedMessage, diagnostics[5].Message)
+	}
+}
+
+func Test_UndefinedModifierWithUnresolvedImport(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+import {Ownable} from "@openzeppelin/contracts/access/Ownable.sol";
+
+contract Vault is Ownable {
+    function withdraw() external onlyOwner {}
+}
+`
+
+	s := NewState()
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, src)
+
+	// The modifier can be declared in the missing dependency.
+	diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Vault.sol").Params.Diagnostics
+	if len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %q", diagnostics[0].Message)
+	}
+}
@@ -0,0 +1,188 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+)
+
+// The monikers identify the declarations for the tools outside of the
+// editor e.g. a code search across the repositories, or a knowledge base
+// linking the findings of the audits to the functions. The identifier is
+// the path of the file relative to the workspace, then the names of the
+// declarations from the outermost one, joined by dots, with the canonical
+// parameter types of the functions, the modifiers, the events and the
+// errors, so the overloads get their own e.g.
+//
+//	solbot:src/Vault.sol:Vault.deposit(uint256,address)
+//	solbot:src/Vault.sol:Vault.Order.maker
+//	solbot:src/Math.sol:mulDiv(uint256,uint256,uint256)
+//	solbot:src/Vault.sol:Vault.deposit(uint256,address).amount
+//
+// The path tells apart the contracts of the same name, like QualifiedName.
+// The monikers are made of the names and the types only, never of the
+// positions, so they don't change when the code is reformatted or moved
+// within its file, and a clone of the repository at another path gets the
+// same ones. Moving the file changes them, as its path is a part of them.
+//
+// The declarations of the workspace are unique in the project, and the
+// ones of the dependencies in the scheme, since the projects vendoring the
+// same library at the same path share them. The parameters and the local
+// variables are unique in the document only, as two blocks of a function
+// may declare the same name.
+const monikerScheme = "solbot"
+
+// Moniker returns the moniker of the declaration the identifier at the
+// position refers to; or none if it's not a declaration of a document e.g.
+// a builtin.
+func (s *State) Moniker(id lsp.ID, uri string, position lsp.Position) lsp.MonikerResponse {
+	monikers := []lsp.Moniker{}
+	if sym := s.symbolAt(uri, position); sym != nil {
+		if m, ok := s.moniker(sym); ok {
+			monikers = append(monikers, m)
+		}
+	}
+	return lsp.NewMonikerResponse(id, monikers)
+}
+
+// moniker returns the moniker of the declaration; or false if it's not
+// one of the named declarations e.g. an import alias.
+func (s *State) moniker(sym *Symbol) (lsp.Moniker, bool) {
+	// The constructors, the fallback and the receive functions are found
+	// by their keywords.
+	pos := sym.Node.Start()
+	if sym.Name != nil {
+		pos = sym.Name.Start()
+	}
+	path := ast.PathEnclosingPos(sym.Doc.File, pos)
+	segments := []string{}
+	local, found := false, false
+	for i := len(path) - 1; i >= 0 && !found; i-- {
+		node := path[i]
+		segment, ok := s.monikerSegment(sym.Doc, node)
+		if node == sym.Node {
+			if !ok {
+				return lsp.Moniker{}, false
+			}
+			segments, found = append(segments, segment), true
+			break
+		}
+		if !ok {
+			continue
+		}
+		switch node.(type) {
+		case *ast.FunctionDeclaration, *ast.ModifierDeclaration, *ast.EventDeclaration, *ast.ErrorDeclaration:
+			local = true
+		}
+		segments = append(segments, segment)
+	}
+	if !found {
+		return lsp.Moniker{}, false
+	}
+	// The values of an enum resolve to the enum.
+	if e, ok := sym.Node.(*ast.EnumDeclaration); ok && sym.Name != nil && sym.Name != e.Name {
+		segments = append(segments, sym.Name.Name)
+	}
+
+	m := lsp.Moniker{
+		Scheme:     monikerScheme,
+		Identifier: s.RelativePath(sym.Doc.URI) + ":" + strings.Join(segments, "."),
+		Unique:     lsp.UniqueProject,
+		Kind:       lsp.MonikerExport,
+	}
+	switch {
+	case local:
+		m.Unique, m.Kind = lsp.UniqueDocument, lsp.MonikerLocal
+	case s.isDependency(sym.Doc.URI):
+		m.Unique, m.Kind = lsp.UniqueScheme, lsp.MonikerImport
+	}
+	return m, true
+}
+
+// monikerSegment returns the part of the moniker the declaration adds: its
+// name, with the parameter types of the callables e.g. "deposit(uint256)";
+// or false if the node is not a declaration.
+func (s *State) monikerSegment(doc *Document, node ast.Node) (string, bool) {
+	var name *ast.Identifier
+	var params *ast.ParamList
+	callable := false
+	switch n := node.(type) {
+	case *ast.ContractDeclaration:
+		name = n.Name
+	case *ast.FunctionDeclaration:
+		if n.Name == nil {
+			// The constructor, the fallback and the receive functions.
+			return n.Kind.String() + s.monikerTypes(doc, n.Type.Params), true
+		}
+		name, params, callable = n.Name, n.Type.Params, true
+	case *ast.ModifierDeclaration:
+		name, params, callable = n.Name, n.Params, true
+	case *ast.EventDeclaration:
+		name, params, callable = n.Name, n.Params, true
+	case *ast.ErrorDeclaration:
+		name, params, callable = n.Name, n.Params, true
+	case *ast.StructDeclaration:
+		name = n.Name
+	case *ast.EnumDeclaration:
+		name = n.Name
+	case *ast.TypeDeclaration:
+		name = n.Name
+	case *ast.VariableDeclaration:
+		name = n.Name
+	case *ast.Param:
+		name = n.Name
+	}
+	if name == nil {
+		return "", false
+	}
+	if callable {
+		return name.Name + s.monikerTypes(doc, params), true
+	}
+	return name.Name, true
+}
+
+// monikerTypes returns the canonical types of the parameters in
+// parentheses, or the types as they're written if they can't be resolved.
+func (s *State) monikerTypes(doc *Document, params *ast.ParamList) string {
+	types := []string{}
+	if params != nil {
+		for _, param := range params.List {
+			canonical, ok := s.canonicalType(doc, param.Type, map[ast.Node]bool{})
+			if !ok {
+				canonical = ast.ExprString(param.Type)
+			}
+			types = append(types, canonical)
+		}
+	}
+	return "(" + strings.Join(types, ",") + ")"
+}
+
+// withMonikers returns the diagnostics with the monikers of the
+// declarations they're in, the innermost function or the like, or its
+// contract, in their data. The diagnostics outside of the declarations
+// e.g. of the pragmas have none.
+func (s *State) withMonikers(doc *Document, diagnostics []lsp.Diagnostic) []lsp.Diagnostic {
+	res := make([]lsp.Diagnostic, len(diagnostics))
+	for i, d := range diagnostics {
+		res[i] = d
+		path := ast.PathEnclosingPos(doc.File, toTokenPos(doc.Handle, d.Range.Start))
+		for j, node := range path {
+			if j+1 >= len(path) {
+				break
+			}
+			switch path[j+1].(type) {
+			case *ast.File, *ast.ContractDeclaration:
+			default:
+				continue
+			}
+			if _, ok := s.monikerSegment(doc, node); !ok {
+				continue
+			}
+			if m, ok := s.moniker(&Symbol{Doc: doc, Name: declaredName(node), Node: node}); ok {
+				res[i].Data = &lsp.DiagnosticData{Moniker: m.String()}
+				break
+			}
+		}
+	}
+	return res
+}
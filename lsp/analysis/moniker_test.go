@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"context"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+const monikerVault = `pragma solidity ^0.8.0;
+
+contract Vault {
+    struct Order { uint256 id; address maker; }
+    enum Kind { Spot, Limit }
+
+    mapping(address => uint256) public balances;
+
+    function deposit(uint256 amount) external {
+        balances[msg.sender] += amount;
+    }
+
+    function deposit(uint256 amount, address to) external {
+        require(amount >= 0);
+        balances[to] += amount;
+    }
+
+    function cancel(Order calldata order, Kind kind) external {}
+}
+`
+
+// monikerAt returns the moniker of the symbol at the n-th occurrence of
+// the text in the document, counting from 0.
+func monikerAt(t *testing.T, s *State, uri, text string, n int) lsp.Moniker {
+	t.Helper()
+	doc := s.Documents[uri]
+	offset := -1
+	for i := 0; i <= n; i++ {
+		next := strings.Index(doc.Handle.Src()[offset+1:], text)
+		if next < 0 {
+			t.Fatalf("%q not found in %s", text, uri)
+		}
+		offset += next + 1
+	}
+	monikers := s.Moniker(lsp.IntID(1), uri, toLspPosition(doc.Handle, token.Pos(offset))).Result
+	if len(monikers) != 1 {
+		t.Fatalf("Expected a moniker for %q, got %v", text, monikers)
+	}
+	return monikers[0]
+}
+
+func Test_Moniker(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	uri := "file:///ws/src/Vault.sol"
+	s.OpenDocument(uri, 1, monikerVault)
+
+	tests := []struct {
+		text     string
+		n        int
+		expected lsp.Moniker
+	}{
+		{"Vault", 0, lsp.Moniker{Scheme: "solbot", Identifier: "src/Vault.sol:Vault", Unique: lsp.UniqueProject, Kind: lsp.MonikerExport}},
+		{"deposit", 0, lsp.Moniker{Scheme: "solbot", Identifier: "src/Vault.sol:Vault.deposit(uint256)", Unique: lsp.UniqueProject, Kind: lsp.MonikerExport}},
+		{"deposit", 1, lsp.Moniker{Scheme: "solbot", Identifier: "src/Vault.sol:Vault.deposit(uint256,address)", Unique: lsp.UniqueProject, Kind: lsp.MonikerExport}},
+		{"cancel", 0, lsp.Moniker{Scheme: "solbot", Identifier: "src/Vault.sol:Vault.cancel((uint256,address),uint8)", Unique: lsp.UniqueProject, Kind: lsp.MonikerExport}},
+		{"maker", 0, lsp.Moniker{Scheme: "solbot", Identifier: "src/Vault.sol:Vault.Order.maker", Unique: lsp.UniqueProject, Kind: lsp.MonikerExport}},
+		{"Limit", 0, lsp.Moniker{Scheme: "solbot", Identifier: "src/Vault.sol:Vault.Kind.Limit", Unique: lsp.UniqueProject, Kind: lsp.MonikerExport}},
+		// A reference gets the moniker of its declaration.
+		{"balances", 2, lsp.Moniker{Scheme: "solbot", Identifier: "src/Vault.sol:Vault.balances", Unique: lsp.UniqueProject, Kind: lsp.MonikerExport}},
+		{"to] +=", 0, lsp.Moniker{Scheme: "solbot", Identifier: "src/Vault.sol:Vault.deposit(uint256,address).to", Unique: lsp.UniqueDocument, Kind: lsp.MonikerLocal}},
+	}
+	for _, test := range tests {
+		if got := monikerAt(t, s, uri, test.text, test.n); got != test.expected {
+			t.Errorf("Expected the moniker of %q to be %+v, got %+v", test.text, test.expected, got)
+		}
+	}
+
+	// The diagnostics carry the monikers of their functions.
+	found := false
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code != "always-true-condition" {
+			continue
+		}
+		found = true
+		if d.Data == nil || d.Data.Moniker != "solbot:src/Vault.sol:Vault.deposit(uint256,address)" {
+			t.Errorf("Expected the moniker of the function in the data, got %+v", d.Data)
+		}
+	}
+	if !found {
+		t.Error("Expected the always true condition reported")
+	}
+}
+
+func Test_MonikerStability(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	uri := "file:///ws/src/Vault.sol"
+	s.OpenDocument(uri, 1, monikerVault)
+	before := monikerAt(t, s, uri, "deposit", 1)
+
+	// The reformatted and reordered functions keep their monikers.
+	reformatted := strings.Replace(monikerVault, `    function deposit(uint256 amount, address to) external {
+        require(amount >= 0);
+        balances[to] += amount;
+    }
+`, "", 1)
+	reformatted = strings.Replace(reformatted, "contract Vault {\n", `/// @title Vault
+contract Vault
+{
+    function deposit(
+        uint256 amount,
+        address to
+    )
+        external
+    {
+        balances[to] += amount;
+    }
+
+`, 1)
+	s.UpdateDocument(uri, 2, reformatted)
+	if after := monikerAt(t, s, uri, "deposit", 0); after != before {
+		t.Errorf("Expected the moniker %+v after the reformatting, got %+v", before, after)
+	}
+
+	// A moved file changes the path of the monikers, by design.
+	moved := NewState()
+	moved.Root = "/ws"
+	moved.OpenDocument("file:///ws/src/core/Vault.sol", 1, reformatted)
+	if after := monikerAt(t, moved, "file:///ws/src/core/Vault.sol", "deposit", 0); after.Identifier != "src/core/Vault.sol:Vault.deposit(uint256,address)" {
+		t.Errorf("Expected the moniker with the new path, got %+v", after)
+	}
+}
+
+func Test_MonikerDuplicateContracts(t *testing.T) {
+	s := newDuplicateVaults()
+
+	src := monikerAt(t, s, "file:///ws/src/Vault.sol", "total", 0)
+	mock := monikerAt(t, s, "file:///ws/test/mocks/Vault.sol", "owner", 0)
+	if src.Identifier != "src/Vault.sol:Vault.total" || mock.Identifier != "test/mocks/Vault.sol:Vault.owner" {
+		t.Errorf("Expected the members qualified with the paths of their contracts, got %s and %s", src.Identifier, mock.Identifier)
+	}
+	first := monikerAt(t, s, "file:///ws/src/Vault.sol", "Vault", 0)
+	second := monikerAt(t, s, "file:///ws/test/mocks/Vault.sol", "Vault", 0)
+	if first == second || first.Identifier != "src/Vault.sol:Vault" {
+		t.Errorf("Expected distinct monikers for the duplicate contracts, got %+v and %+v", first, second)
+	}
+
+	// The dependencies are shared by the projects vendoring them.
+	dep := monikerAt(t, s, "file:///ws/lib/oz/Ownable.sol", "Ownable", 0)
+	if dep != (lsp.Moniker{Scheme: "solbot", Identifier: "lib/oz/Ownable.sol:Ownable", Unique: lsp.UniqueScheme, Kind: lsp.MonikerImport}) {
+		t.Errorf("Expected the moniker of the dependency unique in the scheme, got %+v", dep)
+	}
+}
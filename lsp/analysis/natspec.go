@@ -0,0 +1,601 @@
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/metrics"
+	"solbot/token"
+	"strings"
+)
+
+// natSpecTag is a tag of the NatSpec e.g. `@param amount The amount to
+// withdraw`. The text before the first tag is the implicit @notice, with an
+// empty kind.
+type natSpecTag struct {
+	Kind string // e.g. "notice", "param", "return" or "custom:deprecated"
+	Name string // first word of @param, @return and @inheritdoc e.g. "amount"
+	Text string // rest of the text, the continuation lines separated by "\n"
+
+	// The ranges in the source of the tags parsed from the comments, see
+	// natSpecTags; or zero.
+	KindRange token.Range // e.g. `@param`
+	NameRange token.Range // e.g. `amount`
+}
+
+// natSpecLine is a line of the NatSpec without the comment markers, and
+// the position of its first character in the source; or zero.
+type natSpecLine struct {
+	text string
+	pos  token.Pos
+}
+
+// parseNatSpec splits the text returned by natSpec into the tags.
+func parseNatSpec(text string) []natSpecTag {
+	lines := []natSpecLine{}
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, natSpecLine{text: line})
+	}
+	return parseNatSpecLines(lines)
+}
+
+// natSpecTags returns the tags of the NatSpec comments, with their ranges.
+func natSpecTags(comments []*ast.Comment) []natSpecTag {
+	lines := []natSpecLine{}
+	add := func(text string, pos token.Pos) {
+		trimmed := strings.TrimLeft(text, " \t")
+		pos += token.Pos(len(text) - len(trimmed))
+		lines = append(lines, natSpecLine{text: strings.TrimRight(trimmed, " \t\r"), pos: pos})
+	}
+	for _, c := range comments {
+		if strings.HasPrefix(c.Text, "///") {
+			add(c.Text[3:], c.Start()+3)
+			continue
+		}
+		text := strings.TrimSuffix(c.Text[3:], "*/")
+		pos := c.Start() + 3
+		for _, line := range strings.Split(text, "\n") {
+			trimmed := strings.TrimLeft(line, " \t")
+			if strings.HasPrefix(trimmed, "*") {
+				add(trimmed[1:], pos+token.Pos(len(line)-len(trimmed)+1))
+			} else {
+				add(line, pos)
+			}
+			pos += token.Pos(len(line) + 1)
+		}
+	}
+	return parseNatSpecLines(lines)
+}
+
+// parseNatSpecLines splits the lines into the tags. The ranges are set if
+// the positions of the lines are.
+func parseNatSpecLines(lines []natSpecLine) []natSpecTag {
+	tags := []natSpecTag{}
+	for _, l := range lines {
+		line := l.text
+		switch {
+		case line == "":
+			continue
+		case !strings.HasPrefix(line, "@"):
+			if len(tags) == 0 {
+				tags = append(tags, natSpecTag{Text: line})
+				continue
+			}
+			last := &tags[len(tags)-1]
+			if last.Text == "" {
+				last.Text = line
+			} else {
+				last.Text += "\n" + line
+			}
+			continue
+		}
+		kind, rest, _ := strings.Cut(strings.Replace(line[1:], "\t", " ", 1), " ")
+		tag := natSpecTag{Kind: kind, Text: strings.TrimSpace(rest)}
+		if l.pos > 0 {
+			tag.KindRange = token.Range{Start: l.pos, End: l.pos + token.Pos(1+len(kind))}
+		}
+		if kind == "param" || kind == "return" || kind == "inheritdoc" {
+			textPos := l.pos + token.Pos(len(line)-len(strings.TrimLeft(rest, " \t")))
+			tag.Name, tag.Text, _ = strings.Cut(tag.Text, " ")
+			tag.Text = strings.TrimSpace(tag.Text)
+			if l.pos > 0 && tag.Name != "" {
+				tag.NameRange = token.Range{Start: textPos, End: textPos + token.Pos(len(tag.Name))}
+			}
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// lines returns the lines of the tag without the comment markers.
+func (tag natSpecTag) lines() []string {
+	text := strings.Split(tag.Text, "\n")
+	if tag.Kind == "" {
+		return text
+	}
+	words := []string{"@" + tag.Kind}
+	if tag.Name != "" {
+		words = append(words, tag.Name)
+	}
+	if text[0] != "" {
+		words = append(words, text[0])
+	}
+	return append([]string{strings.Join(words, " ")}, text[1:]...)
+}
+
+// functionDocs is a function of the document with its NatSpec and the
+// differences between them.
+type functionDocs struct {
+	fn          *ast.FunctionDeclaration
+	comments    []*ast.Comment // NatSpec comments; or nil if there are none
+	tags        []natSpecTag
+	diagnostics []lsp.Diagnostic
+}
+
+// natSpecDiagnostics reports the NatSpec of the functions which no longer
+// matches them:
+//   - the @param tags of the parameters missing from the signature, and the
+//     parameters without one,
+//   - the number of the @return tags different from the number of the
+//     returned values,
+//   - the units of the parameters contradicted by the body, see unitRules,
+//   - if the documentation coverage is requested in solbot.toml, the
+//     external and public functions without any NatSpec.
+//
+// The parameters and the returned values are only checked if the NatSpec
+// documents at least one of them, and the functions inheriting their
+// documentation with @inheritdoc are left out. The dependencies are never
+// reported.
+func (s *State) natSpecDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, docs := range s.functionDocs(doc) {
+		res = append(res, docs.diagnostics...)
+	}
+	return res
+}
+
+// natSpecActions returns the quick fixes writing the skeleton of the
+// NatSpec of the reported functions: the @param and the @return tags named
+// after the signature, with the descriptions of the ones still matching.
+func (s *State) natSpecActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	for _, docs := range s.functionDocs(doc) {
+		if len(docs.diagnostics) == 0 || !touches(selected, toTokenRange(doc.Handle, docs.diagnostics[0].Range)) {
+			continue
+		}
+		edit, ok := docs.edit(doc)
+		if !ok {
+			continue
+		}
+		title := fmt.Sprintf("Update the NatSpec of `%s`", callable{doc: doc, node: docs.fn}.name())
+		if docs.comments == nil {
+			title = fmt.Sprintf("Add the NatSpec of `%s`", callable{doc: doc, node: docs.fn}.name())
+		}
+		actions = append(actions, lsp.CodeAction{
+			Title:       title,
+			Kind:        lsp.CodeActionQuickFix,
+			Diagnostics: docs.diagnostics,
+			Edit:        &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{doc.URI: {edit}}},
+		})
+	}
+	return actions
+}
+
+// functionDocs returns the functions of the document with their NatSpec,
+// checked against their signatures.
+func (s *State) functionDocs(doc *Document) []*functionDocs {
+	res := []*functionDocs{}
+	if s.isDependency(doc.URI) {
+		return res
+	}
+	ast.Inspect(doc.File, func(n ast.Node) bool {
+		fn, ok := n.(*ast.FunctionDeclaration)
+		if !ok {
+			return true
+		}
+		docs := &functionDocs{fn: fn, comments: natSpecComments(doc, fn)}
+		if len(docs.comments) == 0 {
+			docs.comments = nil
+		}
+		docs.tags = parseNatSpec(natSpec(doc, fn))
+		docs.check(s, doc)
+		res = append(res, docs)
+		return false
+	})
+	return res
+}
+
+func (docs *functionDocs) check(s *State, doc *Document) {
+	name := callable{doc: doc, node: docs.fn}.name()
+	report := func(code, message string) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		docs.diagnostics = append(docs.diagnostics, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, metrics.Function{Decl: docs.fn}.NameRange()),
+			Severity: lsp.SeverityHint,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+
+	if docs.comments == nil {
+		if s.Config.Documentation.Coverage && isExternalFunction(docs.fn) && docs.fn.Override == nil {
+			report("natspec-missing", fmt.Sprintf("`%s` is %s, but it has no NatSpec", name, visibilityName(docs.fn.Type.Visibility)))
+		}
+		return
+	}
+	if slices.ContainsFunc(docs.tags, func(tag natSpecTag) bool { return tag.Kind == "inheritdoc" }) {
+		return
+	}
+
+	params := paramNames(docs.fn.Type.Params)
+	documented := docs.tagged("param")
+	if len(documented) > 0 {
+		problems := []string{}
+		extra := []string{}
+		for _, tag := range documented {
+			if !slices.Contains(params, tag.Name) {
+				extra = append(extra, "`"+tag.Name+"`")
+			}
+		}
+		missing := []string{}
+		for _, param := range params {
+			if !slices.ContainsFunc(documented, func(tag natSpecTag) bool { return tag.Name == param }) {
+				missing = append(missing, "`"+param+"`")
+			}
+		}
+		switch len(extra) {
+		case 0:
+		case 1:
+			problems = append(problems, fmt.Sprintf("documents @param %s, which is not a parameter", extra[0]))
+		default:
+			problems = append(problems, fmt.Sprintf("documents @param %s, which are not parameters", strings.Join(extra, ", ")))
+		}
+		if len(missing) > 0 {
+			problems = append(problems, "has no @param for "+strings.Join(missing, ", "))
+		}
+		if len(problems) > 0 {
+			report("natspec-params", fmt.Sprintf("The NatSpec of `%s` %s", name, strings.Join(problems, " and ")))
+		}
+	}
+
+	var results []*ast.Param
+	if docs.fn.Type.Results != nil {
+		results = docs.fn.Type.Results.List
+	}
+	returns := docs.tagged("return")
+	if len(returns) > 0 && len(returns) != len(results) {
+		message := fmt.Sprintf("The NatSpec of `%s` documents %d return %s, but it returns %d", name, len(returns), plural(len(returns), "value"), len(results))
+		undocumented := []string{}
+		for i, j := range associateReturns(results, returns) {
+			if j >= 0 {
+				continue
+			}
+			if results[i].Name != nil {
+				undocumented = append(undocumented, "`"+results[i].Name.Name+"`")
+			} else {
+				undocumented = append(undocumented, fmt.Sprintf("#%d (%s)", i+1, ast.ExprString(results[i].Type)))
+			}
+		}
+		if len(undocumented) > 0 {
+			message += "; no @return for " + strings.Join(undocumented, ", ")
+		}
+		report("natspec-returns", message)
+	}
+
+	if docs.fn.Body == nil {
+		return
+	}
+	for _, tag := range documented {
+		if !slices.Contains(params, tag.Name) {
+			continue
+		}
+		for _, rule := range unitRules {
+			if rule.documented.MatchString(tag.Text) && dividesBy(docs.fn.Body, tag.Name, rule.divisor) {
+				report("natspec-units", fmt.Sprintf("@param `%s` of `%s` is documented %s", tag.Name, name, rule.contradiction))
+				break
+			}
+		}
+	}
+}
+
+// tagged returns the tags of the kind.
+func (docs *functionDocs) tagged(kind string) []natSpecTag {
+	res := []natSpecTag{}
+	for _, tag := range docs.tags {
+		if tag.Kind == kind {
+			res = append(res, tag)
+		}
+	}
+	return res
+}
+
+// edit returns the edit writing the skeleton of the NatSpec above the
+// function, or replacing the NatSpec it has. The tags other than @param
+// and @return are kept. The descriptions of the parameters are kept by
+// the name; if as many parameters are undocumented as there are stale
+// tags, they're renames, and the descriptions are moved over in the order.
+// ok is false if the NatSpec wouldn't change.
+func (docs *functionDocs) edit(doc *Document) (edit lsp.TextEdit, ok bool) {
+	before, after := []natSpecTag{}, []natSpecTag{}
+	documented := false
+	for _, tag := range docs.tags {
+		switch {
+		case tag.Kind == "param" || tag.Kind == "return":
+			documented = true
+		case documented:
+			after = append(after, tag)
+		default:
+			before = append(before, tag)
+		}
+	}
+	if docs.comments == nil {
+		before = append(before, natSpecTag{Kind: "notice"})
+	}
+
+	tags := before
+	if docs.comments == nil || len(docs.tagged("param")) > 0 {
+		tags = append(tags, docs.params()...)
+	}
+	if docs.comments == nil || len(docs.tagged("return")) > 0 {
+		tags = append(tags, docs.returns()...)
+	}
+	tags = append(tags, after...)
+	lines := []string{}
+	for _, tag := range tags {
+		lines = append(lines, tag.lines()...)
+	}
+
+	src := doc.Handle.Src()
+	start := docs.fn.Start()
+	lineStart := start - token.Pos(doc.Handle.Position(start).Column-1)
+	indent := src[lineStart:start]
+	if strings.TrimSpace(indent) != "" {
+		indent = ""
+	}
+
+	var b strings.Builder
+	switch {
+	case docs.comments == nil:
+		for _, line := range lines {
+			b.WriteString(indent + strings.TrimRight("/// "+line, " ") + "\n")
+		}
+		return lsp.TextEdit{Range: toLspRange(doc.Handle, token.Range{Start: lineStart, End: lineStart}), NewText: b.String()}, true
+	case strings.HasPrefix(docs.comments[0].Text, "/**"):
+		b.WriteString("/**\n")
+		for _, line := range lines {
+			b.WriteString(indent + strings.TrimRight(" * "+line, " ") + "\n")
+		}
+		b.WriteString(indent + " */")
+	default:
+		for i, line := range lines {
+			if i > 0 {
+				b.WriteString("\n" + indent)
+			}
+			b.WriteString(strings.TrimRight("/// "+line, " "))
+		}
+	}
+	r := token.Range{Start: docs.comments[0].Start(), End: docs.comments[len(docs.comments)-1].End()}
+	if src[r.Start:r.End] == b.String() {
+		return lsp.TextEdit{}, false
+	}
+	return lsp.TextEdit{Range: toLspRange(doc.Handle, r), NewText: b.String()}, true
+}
+
+// params returns the @param tags of the named parameters, in their order.
+func (docs *functionDocs) params() []natSpecTag {
+	names := paramNames(docs.fn.Type.Params)
+	documented := docs.tagged("param")
+	stale := []natSpecTag{}
+	for _, tag := range documented {
+		if !slices.Contains(names, tag.Name) {
+			stale = append(stale, tag)
+		}
+	}
+	undocumented := 0
+	for _, name := range names {
+		if !slices.ContainsFunc(documented, func(tag natSpecTag) bool { return tag.Name == name }) {
+			undocumented++
+		}
+	}
+
+	res := []natSpecTag{}
+	for _, name := range names {
+		tag := natSpecTag{Kind: "param", Name: name}
+		if i := slices.IndexFunc(documented, func(tag natSpecTag) bool { return tag.Name == name }); i >= 0 {
+			tag.Text = documented[i].Text
+		} else if undocumented == len(stale) {
+			tag.Text, stale = stale[0].Text, stale[1:]
+		}
+		res = append(res, tag)
+	}
+	return res
+}
+
+// returns returns the @return tags of the returned values, in their order.
+// The tags of the named values start with the name.
+func (docs *functionDocs) returns() []natSpecTag {
+	var results []*ast.Param
+	if docs.fn.Type.Results != nil {
+		results = docs.fn.Type.Results.List
+	}
+	documented := docs.tagged("return")
+	res := []natSpecTag{}
+	for i, j := range associateReturns(results, documented) {
+		tag := natSpecTag{Kind: "return"}
+		if j >= 0 {
+			tag.Text = strings.TrimSpace(documented[j].Name + " " + documented[j].Text)
+		}
+		if name := results[i].Name; name != nil {
+			tag.Name = name.Name
+			tag.Text = strings.TrimSpace(strings.TrimPrefix(tag.Text, name.Name))
+		}
+		res = append(res, tag)
+	}
+	return res
+}
+
+// associateReturns returns the index of the @return tag of every returned
+// value; or -1 if it has none. A tag starting with the name of a named
+// value belongs to it, the rest are associated by their positions.
+func associateReturns(results []*ast.Param, tags []natSpecTag) []int {
+	res := make([]int, len(results))
+	claimed := make([]bool, len(tags))
+	for i, result := range results {
+		res[i] = -1
+		if result.Name == nil {
+			continue
+		}
+		for j, tag := range tags {
+			if !claimed[j] && tag.Name == result.Name.Name {
+				res[i], claimed[j] = j, true
+				break
+			}
+		}
+	}
+	j := 0
+	for i := range results {
+		if res[i] >= 0 {
+			continue
+		}
+		for j < len(tags) && claimed[j] {
+			j++
+		}
+		if j < len(tags) {
+			res[i], claimed[j] = j, true
+		}
+	}
+	return res
+}
+
+// unitRule is a unit named in the description of a parameter, contradicted
+// by the body dividing the parameter by the scale of another unit.
+type unitRule struct {
+	documented    *regexp.Regexp
+	divisor       *big.Int
+	contradiction string
+}
+
+// unitRules are kept to the units mixed up in practice, so that the
+// descriptions mentioning the units in passing are not reported.
+var unitRules = []unitRule{
+	{
+		documented:    regexp.MustCompile(`(?i)\b(in|as) (basis points|bps|bips)\b`),
+		divisor:       big.NewInt(1e18),
+		contradiction: "in basis points, but it's divided by 1e18 as if it had 18 decimals",
+	},
+	{
+		documented:    regexp.MustCompile(`(?i)\b(in|as) (wad|18 decimals)\b|\bwith 18 decimals\b`),
+		divisor:       big.NewInt(10_000),
+		contradiction: "with 18 decimals, but it's divided by 10000 as if it were in basis points",
+	},
+}
+
+// dividesBy reports whether the body divides the parameter, or a product
+// of it, by the constant e.g. `amount * fee / 1e18`.
+func dividesBy(body *ast.BlockStatement, param string, divisor *big.Int) bool {
+	var factor func(x ast.Expression) bool
+	factor = func(x ast.Expression) bool {
+		switch x := x.(type) {
+		case *ast.Identifier:
+			return x.Name == param
+		case *ast.TupleExpression:
+			return len(x.Elements) == 1 && factor(x.Elements[0])
+		case *ast.BinaryExpression:
+			return x.Operator == token.MUL && (factor(x.Left) || factor(x.Right))
+		}
+		return false
+	}
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		x, ok := n.(*ast.BinaryExpression)
+		if found || !ok || x.Operator != token.DIV && x.Operator != token.ASSIGN_DIV {
+			return !found
+		}
+		if value, ok := constInt(x.Right); ok && value.Cmp(divisor) == 0 && factor(x.Left) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func paramNames(params *ast.ParamList) []string {
+	names := []string{}
+	if params == nil {
+		return names
+	}
+	for _, param := range params.List {
+		if param.Name != nil && param.Name.Name != "" {
+			names = append(names, param.Name.Name)
+		}
+	}
+	return names
+}
+
+// isExternalFunction reports whether the function can be called from the
+// outside of the contract.
+func isExternalFunction(fn *ast.FunctionDeclaration) bool {
+	return fn.Kind == token.FUNCTION && fn.Name != nil &&
+		(fn.Type.Visibility == ast.External || fn.Type.Visibility == ast.Public)
+}
+
+// DocCoverage is the share of the external and public functions of a
+// contract documented with NatSpec. The overrides count as documented,
+// since they inherit the NatSpec of the functions they override.
+type DocCoverage struct {
+	Doc        *Document
+	Contract   *ast.ContractDeclaration
+	Functions  int // external and public functions declared in the contract
+	Documented int // the ones with NatSpec
+}
+
+// Percent returns the percentage of the documented functions.
+func (c DocCoverage) Percent() float64 {
+	if c.Functions == 0 {
+		return 100
+	}
+	return 100 * float64(c.Documented) / float64(c.Functions)
+}
+
+// DocCoverage returns the NatSpec coverage of the contracts of the
+// document with any external or public functions.
+func (s *State) DocCoverage(doc *Document) []DocCoverage {
+	res := []DocCoverage{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok {
+			continue
+		}
+		coverage := DocCoverage{Doc: doc, Contract: c}
+		for _, member := range c.Body {
+			fn, ok := member.(*ast.FunctionDeclaration)
+			if !ok || !isExternalFunction(fn) {
+				continue
+			}
+			coverage.Functions++
+			if fn.Override != nil || len(natSpecComments(doc, fn)) > 0 {
+				coverage.Documented++
+			}
+		}
+		if coverage.Functions > 0 {
+			res = append(res, coverage)
+		}
+	}
+	return res
+}
+
+// PathDocCoverage returns the NatSpec coverage of the contracts of the
+// documents in the file or directory, see documentsUnder.
+func (s *State) PathDocCoverage(path string) []DocCoverage {
+	res := []DocCoverage{}
+	for _, doc := range s.documentsUnder(path) {
+		res = append(res, s.DocCoverage(doc)...)
+	}
+	return res
+}
@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const natSpecVault = `pragma solidity ^0.8.0;
+
+contract Vault {
+    /// @notice Withdraws the shares of the caller.
+    /// @param amount The shares to burn.
+    /// @param to The receiver of the assets.
+    /// @return The assets sent.
+    function withdraw(uint256 shares, address to) external returns (uint256 assets, uint256 fee) {
+        return (shares, 0);
+    }
+
+    /// @notice Takes the fee.
+    /// @param amount The amount charged.
+    /// @param feeBps The fee in basis points.
+    function charge(uint256 amount, uint256 feeBps) public returns (uint256) {
+        return amount * feeBps / 1e18;
+    }
+
+    function deposit(uint256 assets, address receiver) external returns (uint256 shares) {
+        return assets;
+    }
+
+    /** @notice The assets held by the vault. */
+    function totalAssets() public view returns (uint256) {}
+
+    function pause() external {}
+
+    function _mint() internal {}
+}
+`
+
+func newNatSpecState(coverage bool) *State {
+	s := NewState()
+	s.Root = "/ws"
+	s.Config.Documentation.Coverage = coverage
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, natSpecVault)
+	return s
+}
+
+func natSpecDiagnosticsOf(s *State) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, d := range s.Diagnostics(context.Background(), "file:///ws/src/Vault.sol").Params.Diagnostics {
+		if strings.HasPrefix(d.Code, "natspec-") {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func Test_NatSpecDiagnostics(t *testing.T) {
+	got := []string{}
+	for _, d := range natSpecDiagnosticsOf(newNatSpecState(false)) {
+		got = append(got, fmt.Sprintf("%d %s %d: %s", d.Range.Start.Line, d.Code, d.Severity, d.Message))
+	}
+	// The renamed parameter is reported with both names, and the return
+	// values are associated by the name, then by the position.
+	expected := []string{
+		"7 natspec-params 4: The NatSpec of `withdraw` documents @param `amount`, which is not a parameter and has no @param for `shares`",
+		"7 natspec-returns 4: The NatSpec of `withdraw` documents 1 return value, but it returns 2; no @return for `fee`",
+		"14 natspec-units 4: @param `feeBps` of `charge` is documented in basis points, but it's divided by 1e18 as if it had 18 decimals",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// The functions without NatSpec are reported only if the coverage is
+	// requested.
+	got = []string{}
+	for _, d := range natSpecDiagnosticsOf(newNatSpecState(true)) {
+		if d.Code == "natspec-missing" {
+			got = append(got, fmt.Sprintf("%d %s", d.Range.Start.Line, d.Message))
+		}
+	}
+	expected = []string{
+		"18 `deposit` is external, but it has no NatSpec",
+		"25 `pause` is external, but it has no NatSpec",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_NatSpecQuickFix(t *testing.T) {
+	s := newNatSpecState(true)
+	doc := s.Documents["file:///ws/src/Vault.sol"]
+
+	// The stale tag is renamed with its description kept, and the missing
+	// return value gets an empty tag.
+	actions := s.CodeAction(lsp.IntID(1), doc.URI, lsp.Range{Start: lsp.Position{Line: 7, Character: 14}, End: lsp.Position{Line: 7, Character: 14}}).Result
+	if len(actions) != 1 || actions[0].Title != "Update the NatSpec of `withdraw`" || len(actions[0].Diagnostics) != 2 {
+		t.Fatalf("Expected the update of the NatSpec for both diagnostics, got %+v", actions)
+	}
+	fixed := applyEdits(doc, actions[0].Edit.Changes[doc.URI])
+	expected := `    /// @notice Withdraws the shares of the caller.
+    /// @param shares The shares to burn.
+    /// @param to The receiver of the assets.
+    /// @return assets The assets sent.
+    /// @return fee
+    function withdraw(`
+	if !strings.Contains(fixed, "contract Vault {\n"+expected) {
+		t.Errorf("Expected the NatSpec:\n%s\ngot:\n%s", expected, fixed)
+	}
+
+	// The skeleton of the undocumented function.
+	actions = s.CodeAction(lsp.IntID(1), doc.URI, lsp.Range{Start: lsp.Position{Line: 18, Character: 14}, End: lsp.Position{Line: 18, Character: 14}}).Result
+	if len(actions) != 1 || actions[0].Title != "Add the NatSpec of `deposit`" {
+		t.Fatalf("Expected the skeleton of the NatSpec, got %+v", actions)
+	}
+	edits := actions[0].Edit.Changes[doc.URI]
+	expected = "    /// @notice\n    /// @param assets\n    /// @param receiver\n    /// @return shares\n"
+	if len(edits) != 1 || edits[0].NewText != expected || edits[0].Range.Start != (lsp.Position{Line: 18, Character: 0}) {
+		t.Errorf("Expected %q inserted at the start of the line 18, got %+v", expected, edits)
+	}
+}
+
+func Test_DocCoverage(t *testing.T) {
+	s := newNatSpecState(false)
+
+	coverage := s.PathDocCoverage("/ws")
+	if len(coverage) != 1 {
+		t.Fatalf("Expected the coverage of 1 contract, got %d", len(coverage))
+	}
+	c := coverage[0]
+	if c.Contract.Name.Name != "Vault" || c.Documented != 3 || c.Functions != 5 || c.Percent() != 60 {
+		t.Errorf("Expected 3 of the 5 functions of Vault documented, got %d of %d (%.0f%%)", c.Documented, c.Functions, c.Percent())
+	}
+}
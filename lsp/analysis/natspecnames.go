@@ -0,0 +1,268 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// natSpecName is the argument of a NatSpec tag naming a declaration: the
+// parameter of @param, the named return value of @return or the base
+// contract of @inheritdoc. The identifier is made up for the argument, with
+// its range in the comment, so the references to it can be edited like the
+// ones in the code.
+type natSpecName struct {
+	ident *ast.Identifier
+	tag   natSpecTag
+	path  []ast.Node // from the documented declaration up to the file
+}
+
+// documentedDeclarations returns the paths from the declarations which can
+// have the NatSpec, the top-level ones and the members of the contracts, up
+// to the file, in the source order.
+func documentedDeclarations(doc *Document) [][]ast.Node {
+	res := [][]ast.Node{}
+	for _, decl := range doc.File.Declarations {
+		res = append(res, []ast.Node{decl, doc.File})
+		if c, ok := decl.(*ast.ContractDeclaration); ok {
+			for _, member := range c.Body {
+				res = append(res, []ast.Node{member, c, doc.File})
+			}
+		}
+	}
+	return res
+}
+
+// natSpecNames returns the arguments of the NatSpec tags of the declaration
+// naming the other declarations, see natSpecName.
+func natSpecNames(doc *Document, path []ast.Node) []natSpecName {
+	res := []natSpecName{}
+	for _, tag := range natSpecTags(natSpecComments(doc, path[0])) {
+		if tag.NameRange.Start == 0 {
+			continue
+		}
+		res = append(res, natSpecName{ident: &ast.Identifier{NamePos: tag.NameRange.Start, Name: tag.Name}, tag: tag, path: path})
+	}
+	return res
+}
+
+// resolveNatSpecName returns the declaration the argument of the tag names;
+// or nil if it names none e.g. a parameter which was renamed.
+func (s *State) resolveNatSpecName(doc *Document, name natSpecName) *Symbol {
+	var params *ast.ParamList
+	switch name.tag.Kind {
+	case "param":
+		params = documentedParams(name.path[0])
+	case "return":
+		if fn, ok := name.path[0].(*ast.FunctionDeclaration); ok {
+			params = fn.Type.Results
+		}
+	case "inheritdoc":
+		sym := s.follow(s.lookup(doc, name.path[1:], name.ident.Name, name.path[0].Start()))
+		if sym == nil {
+			return nil
+		}
+		if _, ok := sym.Node.(*ast.ContractDeclaration); !ok {
+			return nil
+		}
+		return sym
+	}
+	if params == nil {
+		return nil
+	}
+	for _, param := range params.List {
+		if param.Name != nil && param.Name.Name == name.ident.Name {
+			return &Symbol{Doc: doc, Name: param.Name, Node: param}
+		}
+	}
+	return nil
+}
+
+// documentedParams returns the parameters the @param tags of the
+// declaration document; or nil if it has none.
+func documentedParams(node ast.Node) *ast.ParamList {
+	switch n := node.(type) {
+	case *ast.FunctionDeclaration:
+		return n.Type.Params
+	case *ast.ModifierDeclaration:
+		return n.Params
+	case *ast.EventDeclaration:
+		return n.Params
+	case *ast.ErrorDeclaration:
+		return n.Params
+	}
+	return nil
+}
+
+// natSpecCommentAt returns the NatSpec comment at the position; or nil.
+// The position right after the comment is a part of it, since the cursor
+// is there while typing.
+func natSpecCommentAt(doc *Document, pos token.Pos) *ast.Comment {
+	for _, c := range doc.File.Comments {
+		if c.Start() < pos && pos <= c.End() && (strings.HasPrefix(c.Text, "///") || strings.HasPrefix(c.Text, "/**")) {
+			return c
+		}
+	}
+	return nil
+}
+
+// documentedBy returns the path from the declaration the NatSpec comment
+// documents up to the file; or nil if it documents none.
+func documentedBy(doc *Document, c *ast.Comment) []ast.Node {
+	for _, path := range documentedDeclarations(doc) {
+		if path[0].Start() >= c.End() && slices.Contains(natSpecComments(doc, path[0]), c) {
+			return path
+		}
+	}
+	return nil
+}
+
+// natSpecNameAt returns the argument of the NatSpec tag at the position.
+func natSpecNameAt(doc *Document, pos token.Pos) (natSpecName, bool) {
+	c := natSpecCommentAt(doc, pos)
+	if c == nil {
+		return natSpecName{}, false
+	}
+	path := documentedBy(doc, c)
+	if path == nil {
+		return natSpecName{}, false
+	}
+	for _, name := range natSpecNames(doc, path) {
+		if ast.NodeRange(name.ident).Contains(pos) {
+			return name, true
+		}
+	}
+	return natSpecName{}, false
+}
+
+// natSpecReferencesTo returns the arguments of the NatSpec tags naming one
+// of the declarations, as the references of the docReference kind, ordered
+// by the file and by the position.
+func (s *State) natSpecReferencesTo(targets map[*ast.Identifier]bool, name string) []reference {
+	refs := []reference{}
+	for _, doc := range s.sortedDocuments() {
+		for _, path := range documentedDeclarations(doc) {
+			for _, arg := range natSpecNames(doc, path) {
+				if arg.ident.Name != name {
+					continue
+				}
+				if sym := s.resolveNatSpecName(doc, arg); sym != nil && targets[sym.Name] {
+					refs = append(refs, reference{doc: doc, ident: arg.ident, path: path, kind: docReference})
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// natSpecTagDocs explain the NatSpec tags on hover.
+var natSpecTagDocs = map[string]string{
+	"title":      "A title that should describe the contract or the interface.",
+	"author":     "The name of the author.",
+	"notice":     "Explains to an end user what this does.",
+	"dev":        "Explains to a developer any extra details.",
+	"param":      "Documents a parameter, named by the first word.",
+	"return":     "Documents the return variables of a function.",
+	"inheritdoc": "Copies all of the missing tags from the base function, named by the first word.",
+	"custom":     "A custom tag, its meaning is up to the application e.g. `@custom:security-contact`.",
+}
+
+// natSpecTagHover explains the NatSpec tag whose keyword e.g. `@param` is
+// at the position; or returns an empty string.
+func (s *State) natSpecTagHover(uri string, position lsp.Position, markdown bool) string {
+	doc, ok := s.document(uri)
+	if !ok {
+		return ""
+	}
+	pos := toTokenPos(doc.Handle, position)
+	c := natSpecCommentAt(doc, pos)
+	if c == nil {
+		return ""
+	}
+	for _, tag := range natSpecTags([]*ast.Comment{c}) {
+		if !tag.KindRange.Contains(pos) {
+			continue
+		}
+		kind, _, custom := strings.Cut(tag.Kind, ":")
+		if custom {
+			kind = "custom"
+		}
+		text, ok := natSpecTagDocs[kind]
+		if !ok {
+			return ""
+		}
+		if markdown {
+			return fmt.Sprintf("**NatSpec** `@%s`\n\n%s", tag.Kind, text)
+		}
+		return fmt.Sprintf("NatSpec @%s\n\n%s", tag.Kind, strings.ReplaceAll(text, "`", ""))
+	}
+	return ""
+}
+
+// natSpecArgument matches the line of the NatSpec up to the cursor typing
+// the argument of @param or @inheritdoc.
+var natSpecArgument = regexp.MustCompile(`@(param|inheritdoc)[ \t]+([A-Za-z0-9_$]*)$`)
+
+// natSpecCompletions lists the arguments of the NatSpec tag being typed at
+// the position: the parameters of the documented declaration without a
+// @param yet after `@param`, and the bases of the contract after
+// `@inheritdoc`. ok is false if the position is not in such a tag.
+func (s *State) natSpecCompletions(doc *Document, pos token.Pos) (items []lsp.CompletionItem, ok bool) {
+	c := natSpecCommentAt(doc, pos)
+	if c == nil {
+		return nil, false
+	}
+	src := doc.Handle.Src()
+	lineStart := max(int(c.Start()), strings.LastIndexByte(string(src[:pos]), '\n')+1)
+	match := natSpecArgument.FindStringSubmatch(string(src[lineStart:pos]))
+	if match == nil {
+		return nil, false
+	}
+	items = []lsp.CompletionItem{}
+	path := documentedBy(doc, c)
+	if path == nil {
+		return items, true
+	}
+
+	switch match[1] {
+	case "param":
+		documented := map[string]bool{}
+		for _, name := range natSpecNames(doc, path) {
+			// The argument being typed doesn't document anything yet.
+			if name.tag.Kind == "param" && (pos < name.ident.Start() || pos > name.ident.End()) {
+				documented[name.ident.Name] = true
+			}
+		}
+		params := documentedParams(path[0])
+		if params == nil {
+			return items, true
+		}
+		for _, param := range params.List {
+			if param.Name == nil || documented[param.Name.Name] {
+				continue
+			}
+			items = append(items, lsp.CompletionItem{
+				Label:  param.Name.Name,
+				Kind:   lsp.CompletionItemVariable,
+				Detail: declarationHeader(&Symbol{Doc: doc, Name: param.Name, Node: param}),
+			})
+		}
+	case "inheritdoc":
+		contract := enclosingContract(doc, path[1:])
+		if contract == nil {
+			return items, true
+		}
+		for _, base := range s.ancestors(contract)[1:] {
+			items = append(items, lsp.CompletionItem{
+				Label:  base.Name.Name,
+				Kind:   completionKind(base),
+				Detail: declarationHeader(base),
+			})
+		}
+	}
+	return items, true
+}
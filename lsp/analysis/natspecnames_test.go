@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const natSpecNamesSrc = `pragma solidity ^0.8.0;
+
+interface IVault {
+    function deposit(uint256 amount, address to) external;
+}
+
+contract Base {}
+
+contract Other {}
+
+contract Vault is Base, IVault {
+    /// @notice Deposits the amount.
+    /// @param amount The amount to deposit.
+    /// @param t
+    function deposit(uint256 amount, address to) external {
+        to;
+        amount;
+    }
+
+    /// @inheritdoc I
+    function withdraw() external {}
+}
+`
+
+func Test_NatSpecNames(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument(uri, 1, natSpecNamesSrc)
+
+	// `amount` in `/// @param amount The amount to deposit.`
+	position := lsp.Position{Line: 12, Character: 16}
+	response := s.Definition(lsp.IntID(1), uri, position)
+	expected := lsp.Location{
+		URI:   uri,
+		Range: lsp.Range{Start: lsp.Position{Line: 14, Character: 29}, End: lsp.Position{Line: 14, Character: 35}},
+	}
+	if response.Result == nil || len(*response.Result) != 1 || (*response.Result)[0] != expected {
+		t.Errorf("Expected the definition of the parameter %v, got %v", expected, response.Result)
+	}
+
+	// Renaming the parameter renames its @param too.
+	edit, err := s.rename(uri, lsp.Position{Line: 14, Character: 31}, "assets")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	renamed := applyEdits(s.Documents[uri], edit.Changes[uri])
+	if !strings.Contains(renamed, "/// @param assets The amount to deposit.") || !strings.Contains(renamed, "        assets;") {
+		t.Errorf("Expected the parameter renamed in the code and in the NatSpec, got:\n%s", renamed)
+	}
+
+	// The references include the @param unless the client asks otherwise.
+	references := s.References(lsp.IntID(2), uri, position, lsp.ReferenceContext{IncludeDeclaration: true}).Result
+	if len(references) != 3 {
+		t.Errorf("Expected 3 references, got %v", references)
+	}
+	exclude := false
+	references = s.References(lsp.IntID(3), uri, position, lsp.ReferenceContext{IncludeDeclaration: true, IncludeDocumentation: &exclude}).Result
+	if len(references) != 2 {
+		t.Errorf("Expected 2 references without the documentation, got %v", references)
+	}
+
+	// After `@param` the parameters not documented yet are offered.
+	items := s.Completion(lsp.IntID(4), uri, lsp.Position{Line: 13, Character: 16}).Result
+	if len(items) != 1 || items[0].Label != "to" {
+		t.Errorf("Expected only the parameter `to`, got %v", items)
+	}
+
+	// After `@inheritdoc` the bases of the contract are offered.
+	items = s.Completion(lsp.IntID(5), uri, lsp.Position{Line: 19, Character: 21}).Result
+	labels := []string{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	if strings.Join(labels, " ") != "Base IVault" {
+		t.Errorf("Expected the bases of Vault, got %v", labels)
+	}
+
+	// The hover of a tag explains it.
+	hover := s.Hover(lsp.IntID(6), uri, lsp.Position{Line: 11, Character: 10}).Result.Contents.Value
+	if hover != "**NatSpec** `@notice`\n\nExplains to an end user what this does." {
+		t.Errorf("Expected the hover of @notice, got %q", hover)
+	}
+}
@@ -0,0 +1,255 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// chainFunction is an implementation of a function in the linearization of
+// a contract.
+type chainFunction struct {
+	contract   *Symbol
+	fn         *ast.FunctionDeclaration
+	callsSuper bool // does it call the function of the next base with super?
+}
+
+// overrideChain returns the functions with the signature declared by the
+// contract and its bases, in the order of the linearization: the first one
+// is the function that executes for the contract, the next ones are reached
+// with the super calls. It returns nil if the inheritance can't be
+// linearized.
+func (s *State) overrideChain(contract *Symbol, signature string) []chainFunction {
+	res := []chainFunction{}
+	for _, c := range s.linearize(contract) {
+		for _, decl := range c.Node.(*ast.ContractDeclaration).Body {
+			fn, ok := decl.(*ast.FunctionDeclaration)
+			if !ok || fn.Kind != token.FUNCTION || fn.Name == nil || fn.Name.Name != signatureName(signature) {
+				continue
+			}
+			if sig, ok := s.functionSignature(c.Doc, fn); !ok || sig != signature {
+				continue
+			}
+			res = append(res, chainFunction{contract: c, fn: fn, callsSuper: callsSuper(fn)})
+			break
+		}
+	}
+	return res
+}
+
+// signatureName returns the name of the function of the signature e.g.
+// "transfer" for "transfer(address,uint256)".
+func signatureName(signature string) string {
+	name, _, _ := strings.Cut(signature, "(")
+	return name
+}
+
+// callsSuper reports whether the function calls its namesake with super
+// e.g. `super._update(from, to, value)`.
+func callsSuper(fn *ast.FunctionDeclaration) bool {
+	found := false
+	if fn.Body == nil {
+		return false
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if access, ok := n.(*ast.MemberAccessExpression); ok {
+			ident, ok := access.Expression.(*ast.Identifier)
+			found = found || ok && ident.Name == "super" && access.Member.Name == fn.Name.Name
+		}
+		return !found
+	})
+	return found
+}
+
+// overrideChainHover renders the override chain of the hovered function as
+// a list: every implementation with a link to it, whether it declares the
+// function virtual or overrides it, whether it calls super and which one of
+// them executes. The chain is the one of the contract the cursor is in, if
+// it inherits the function, and of the contract declaring the function
+// otherwise. It returns an empty string if the function is neither
+// overridden nor overrides anything.
+func (s *State) overrideChainHover(uri string, position lsp.Position, sym *Symbol, markdown bool) string {
+	fn, ok := sym.Node.(*ast.FunctionDeclaration)
+	if !ok || fn.Kind != token.FUNCTION {
+		return ""
+	}
+	declaring := enclosingContract(sym.Doc, ast.PathEnclosingPos(sym.Doc.File, sym.Name.Start()))
+	if declaring == nil {
+		return ""
+	}
+	signature, ok := s.functionSignature(sym.Doc, fn)
+	if !ok {
+		return ""
+	}
+
+	contract := declaring
+	doc, ok := s.document(uri)
+	if !ok {
+		return ""
+	}
+	if c := enclosingContract(doc, ast.PathEnclosingPos(doc.File, toTokenPos(doc.Handle, position))); c != nil {
+		for _, ancestor := range s.ancestors(c) {
+			if ancestor.Node == declaring.Node {
+				contract = c
+				break
+			}
+		}
+	}
+	chain := s.overrideChain(contract, signature)
+	if len(chain) < 2 {
+		return ""
+	}
+
+	var b strings.Builder
+	if markdown {
+		fmt.Fprintf(&b, "**Override chain** of `%s` in `%s`:\n", signature, contract.Name.Name)
+	} else {
+		fmt.Fprintf(&b, "Override chain of %s in %s:\n", signature, contract.Name.Name)
+	}
+	for i, link := range chain {
+		name := link.contract.Name.Name + "." + link.fn.Name.Name
+		if markdown {
+			fmt.Fprintf(&b, "- [`%s`](%s)", name, functionLink(link))
+		} else {
+			fmt.Fprintf(&b, "- %s (%s)", name, s.location(link.contract.Doc, link.fn.Name))
+		}
+
+		notes := []string{}
+		switch {
+		case link.fn.Override != nil:
+			notes = append(notes, "overrides")
+		case link.fn.Virtual:
+			notes = append(notes, "declares it virtual")
+		}
+		if link.fn.Body == nil {
+			notes = append(notes, "not implemented")
+		}
+		if link.callsSuper {
+			notes = append(notes, "calls super")
+		}
+		if i == 0 {
+			notes = append(notes, "executes")
+		}
+		if len(notes) > 0 {
+			b.WriteString(": " + strings.Join(notes, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// functionLink returns the link to the name of the function the clients can
+// navigate to e.g. "file:///ws/src/Vault.sol#L12,14".
+func functionLink(link chainFunction) string {
+	pos := link.contract.Doc.Handle.Position(link.fn.Name.Start())
+	return fmt.Sprintf("%s#L%d,%d", link.contract.Doc.URI, pos.Line, pos.Column)
+}
+
+// overrideDiagnostics reports the super calls without a target: no base
+// after the contract in its linearization implements the called function,
+// so the call doesn't compile. The calls to the functions unknown to the
+// contract and its bases are left to the reference diagnostics.
+//
+// It also notes the overrides that don't call super in the chains where
+// the other overrides, at least two of them, all do. Skipping the super
+// call skips the base implementations too, which is often a forgotten hook
+// call e.g. in the `_beforeTokenTransfer` chains of ERC721.
+func (s *State) overrideDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok {
+			continue
+		}
+		contract := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		linearized := s.linearize(contract)
+		if linearized == nil {
+			continue
+		}
+		for _, member := range c.Body {
+			fn, ok := member.(*ast.FunctionDeclaration)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			res = append(res, s.superCallDiagnostics(doc, linearized, fn)...)
+			if d, ok := s.missingSuperDiagnostic(doc, contract, fn); ok {
+				res = append(res, d)
+			}
+		}
+	}
+	return res
+}
+
+// superCallDiagnostics reports the super calls of the function without a
+// target in the linearization.
+func (s *State) superCallDiagnostics(doc *Document, linearized []*Symbol, fn *ast.FunctionDeclaration) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		access, ok := n.(*ast.MemberAccessExpression)
+		if !ok {
+			return true
+		}
+		if ident, ok := access.Expression.(*ast.Identifier); !ok || ident.Name != "super" {
+			return true
+		}
+		name := access.Member.Name
+		declared, implemented := false, false
+		for i, c := range linearized {
+			for _, decl := range c.Node.(*ast.ContractDeclaration).Body {
+				other, ok := decl.(*ast.FunctionDeclaration)
+				if !ok || other.Name == nil || other.Name.Name != name {
+					continue
+				}
+				declared = true
+				implemented = implemented || i > 0 && other.Body != nil
+			}
+		}
+		if declared && !implemented {
+			res = append(res, lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, ast.NodeRange(access.Member)),
+				Severity: lsp.SeverityError,
+				Code:     "missing-super-target",
+				Source:   "solbot",
+				Message: fmt.Sprintf("`super.%s` has no target: no base of `%s` implements `%s`",
+					name, linearized[0].Name.Name, name),
+			})
+		}
+		return true
+	})
+	return res
+}
+
+// missingSuperDiagnostic notes the override of the function which doesn't
+// call super, while all of the other overrides in its chain do.
+func (s *State) missingSuperDiagnostic(doc *Document, contract *Symbol, fn *ast.FunctionDeclaration) (lsp.Diagnostic, bool) {
+	if fn.Kind != token.FUNCTION || fn.Override == nil || callsSuper(fn) {
+		return lsp.Diagnostic{}, false
+	}
+	signature, ok := s.functionSignature(doc, fn)
+	if !ok {
+		return lsp.Diagnostic{}, false
+	}
+	others := 0
+	for _, link := range s.overrideChain(contract, signature)[1:] {
+		if link.fn.Override == nil || link.fn.Body == nil {
+			continue
+		}
+		if !link.callsSuper {
+			return lsp.Diagnostic{}, false
+		}
+		others++
+	}
+	if others < 2 {
+		return lsp.Diagnostic{}, false
+	}
+	return lsp.Diagnostic{
+		Range:    toLspRange(doc.Handle, ast.NodeRange(fn.Name)),
+		Severity: lsp.SeverityInformation,
+		Code:     "missing-super-call",
+		Source:   "solbot",
+		Message: fmt.Sprintf("`%s` doesn't call `super.%s`, while the other overrides do; the base implementations are skipped",
+			fn.Name.Name, fn.Name.Name),
+	}, true
+}
@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"testing"
+)
+
+func Test_HoverOverrideChain(t *testing.T) {
+	uri := "file:///ws/src/Token.sol"
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+contract Base {
+    function _update(address to) internal virtual {}
+}
+
+contract Pausable is Base {
+    function _update(address to) internal virtual override {
+        super._update(to);
+    }
+}
+
+contract Token is Pausable {
+    function _update(address to) internal override {
+        super._update(to);
+    }
+}
+`)
+
+	hover := s.Hover(lsp.IntID(1), uri, lsp.Position{Line: 13, Character: 14})
+	expected := "```solidity\nfunction _update(address to) internal override\n```\n\n" +
+		"**Override chain** of `_update(address)` in `Token`:\n" +
+		"- [`Token._update`](file:///ws/src/Token.sol#L14,14): overrides, calls super, executes\n" +
+		"- [`Pausable._update`](file:///ws/src/Token.sol#L8,14): overrides, calls super\n" +
+		"- [`Base._update`](file:///ws/src/Token.sol#L4,14): declares it virtual\n\n" +
+		"inferred effects: none"
+	if hover.Result.Contents.Kind != lsp.Markdown || hover.Result.Contents.Value != expected {
+		t.Errorf("Expected %q, got %q", expected, hover.Result.Contents.Value)
+	}
+
+	// The clients rendering the plain text get the locations instead.
+	s.Capabilities.TextDocument = &lsp.TextDocumentClientCapabilities{
+		Hover: &lsp.HoverClientCapabilities{ContentFormat: []string{lsp.PlainText}},
+	}
+	hover = s.Hover(lsp.IntID(2), uri, lsp.Position{Line: 7, Character: 14})
+	expected = "function _update(address to) internal virtual override\n\n" +
+		"Override chain of _update(address) in Pausable:\n" +
+		"- Pausable._update (src/Token.sol:8:14): overrides, calls super, executes\n" +
+		"- Base._update (src/Token.sol:4:14): declares it virtual\n\n" +
+		"inferred effects: none"
+	if hover.Result.Contents.Kind != lsp.PlainText || hover.Result.Contents.Value != expected {
+		t.Errorf("Expected %q, got %q", expected, hover.Result.Contents.Value)
+	}
+}
+
+func Test_SuperCallWithoutTarget(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+abstract contract Hooks {
+    function _afterDeposit(uint256 amount) internal virtual;
+}
+
+contract Vault is Hooks {
+    function _afterDeposit(uint256 amount) internal override {
+        super._afterDeposit(amount);
+    }
+}
+`)
+
+	diagnostics := s.overrideDiagnostics(s.Documents[uri])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "missing-super-target" || d.Severity != lsp.SeverityError || d.Range.Start.Line != 8 {
+		t.Errorf("Expected the missing super target at line 8, got %s %v at %v", d.Code, d.Severity, d.Range)
+	}
+	expected := "`super._afterDeposit` has no target: no base of `Vault` implements `_afterDeposit`"
+	if d.Message != expected {
+		t.Errorf("Expected %q, got %q", expected, d.Message)
+	}
+}
+
+func Test_MissingSuperCallInChain(t *testing.T) {
+	uri := "file:///ws/src/Token.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+contract ERC721 {
+    function _beforeTokenTransfer(address from, address to) internal virtual {}
+}
+
+contract ERC721Enumerable is ERC721 {
+    function _beforeTokenTransfer(address from, address to) internal virtual override {
+        super._beforeTokenTransfer(from, to);
+    }
+}
+
+contract ERC721Pausable is ERC721 {
+    function _beforeTokenTransfer(address from, address to) internal virtual override {
+        super._beforeTokenTransfer(from, to);
+    }
+}
+
+contract Token is ERC721Enumerable, ERC721Pausable {
+    function _beforeTokenTransfer(address from, address to) internal override(ERC721Enumerable, ERC721Pausable) {
+        require(from != to);
+    }
+}
+`)
+
+	diagnostics := s.overrideDiagnostics(s.Documents[uri])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "missing-super-call" || d.Severity != lsp.SeverityInformation || d.Range.Start.Line != 19 {
+		t.Errorf("Expected the missing super call at line 19, got %s %v at %v", d.Code, d.Severity, d.Range)
+	}
+}
@@ -0,0 +1,34 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"solbot/token"
+)
+
+// The LSP counts lines and characters starting from 0, while token.Position
+// is 1-based. The LSP characters are the UTF-16 code units, while the token
+// columns are the bytes. These helpers are the only place where the two are
+// converted.
+
+func toTokenPos(file *token.File, position lsp.Position) token.Pos {
+	return file.OffsetUTF16(int(position.Line)+1, int(position.Character)+1)
+}
+
+func toLspPosition(file *token.File, pos token.Pos) lsp.Position {
+	position := file.Position(pos)
+	return lsp.Position{
+		Line:      uint(position.Line - 1),
+		Character: uint(file.UTF16Column(pos) - 1),
+	}
+}
+
+func toLspRange(file *token.File, r token.Range) lsp.Range {
+	return lsp.Range{
+		Start: toLspPosition(file, r.Start),
+		End:   toLspPosition(file, r.End),
+	}
+}
+
+func toTokenRange(file *token.File, r lsp.Range) token.Range {
+	return token.Range{Start: toTokenPos(file, r.Start), End: toTokenPos(file, r.End)}
+}
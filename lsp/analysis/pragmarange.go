@@ -0,0 +1,145 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/features"
+	"solbot/lsp"
+	"solbot/semver"
+	"solbot/token"
+)
+
+// pragmaRangeDiagnostics reports the constructs the compilers allowed by
+// the solidity pragma can't all compile e.g. a custom error under
+// `pragma solidity >=0.6.0 <0.9.0;`, which needs 0.8.4. The lowest version
+// that fails is named, so that the pragma can be narrowed, see
+// pragmaRangeActions. The dependencies are not checked, and neither are
+// the transient state variables, see transientDiagnostics, nor the
+// removed constructs no version allowed by the pragma compiles, which are
+// the legacy constructs then, see legacyDiagnostics.
+func (s *State) pragmaRangeDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	if slices.Contains(s.Config.Disabled, "pragma-range") || s.isDependency(doc.URI) {
+		return res
+	}
+	p := doc.File.Pragma("solidity")
+	c, ok := pragma.Solidity(doc.File)
+	if !ok {
+		return res
+	}
+	for _, use := range features.Find(doc.File) {
+		f := use.Feature
+		if f == features.TransientVariables {
+			continue
+		}
+		var message string
+		if f.Removed == (semver.Version{}) {
+			failing, ok := c.Intersect(semver.MustParseConstraint("<" + f.Since.String())).Min()
+			if !ok {
+				continue
+			}
+			message = fmt.Sprintf("%s requires >=%s but pragma allows %s; narrow the pragma or remove the construct", f.Name, f.Since, failing)
+		} else {
+			failing, ok := c.Intersect(semver.MustParseConstraint(">=" + f.Removed.String())).Min()
+			if !ok || c.Intersect(f.Constraint()).IsEmpty() {
+				continue
+			}
+			message = fmt.Sprintf("%s was removed in %s but pragma allows %s; narrow the pragma or remove the construct", f.Name, f.Removed, failing)
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, use.Range),
+			Severity: lsp.SeverityWarning,
+			Code:     "pragma-range",
+			Source:   "solbot",
+			Message:  message,
+			RelatedInformation: []lsp.DiagnosticRelatedInformation{{
+				Location: lsp.Location{URI: doc.URI, Range: toLspRange(doc.Handle, ast.NodeRange(p))},
+				Message:  "pragma allows " + p.Value,
+			}},
+		})
+	}
+	return res
+}
+
+// pragmaRangeActions offers to narrow the solidity pragma to the versions
+// compiling all of the constructs reported by pragmaRangeDiagnostics, on
+// the pragma or on any of the constructs. It's not offered if no version
+// allowed by the pragma compiles them all.
+func (s *State) pragmaRangeActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	diagnostics := s.pragmaRangeDiagnostics(doc)
+	if len(diagnostics) == 0 {
+		return actions
+	}
+	p := doc.File.Pragma("solidity")
+	onPragma := touches(selected, token.Range{Start: p.Start(), End: p.Semicolon + 1})
+	onUse := slices.ContainsFunc(diagnostics, func(d lsp.Diagnostic) bool {
+		return touches(selected, toTokenRange(doc.Handle, d.Range))
+	})
+	if !onPragma && !onUse {
+		return actions
+	}
+	c, _ := pragma.Solidity(doc.File)
+	narrowed := features.Required(features.Find(doc.File)).Intersect(c)
+	if narrowed.IsEmpty() {
+		return actions
+	}
+	text := narrowed.Format()
+	actions = append(actions, lsp.CodeAction{
+		Title:       "Narrow the pragma to " + text,
+		Kind:        lsp.CodeActionQuickFix,
+		Diagnostics: diagnostics,
+		Edit: &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{doc.URI: {{
+			Range:   toLspRange(doc.Handle, token.Range{Start: p.Name.End(), End: p.Semicolon}),
+			NewText: " " + text,
+		}}}},
+		IsPreferred: true,
+	})
+	return actions
+}
+
+// MinimumVersion is the lowest compiler version that can compile a file,
+// given its solidity pragma and the constructs it uses.
+type MinimumVersion struct {
+	Doc     *Document
+	Pragma  string            // the value of the pragma; or empty if there's none
+	Minimum semver.Version    // zero if no version compiles the file
+	Reason  *features.Feature // the construct requiring the minimum; or nil if it's the pragma
+}
+
+// MinimumVersion returns the lowest compiler version that can compile the
+// document.
+func (s *State) MinimumVersion(doc *Document) MinimumVersion {
+	res := MinimumVersion{Doc: doc}
+	uses := features.Find(doc.File)
+	required := features.Required(uses)
+	c, ok := pragma.Solidity(doc.File)
+	if ok {
+		res.Pragma = doc.File.Pragma("solidity").Value
+		required = required.Intersect(c)
+	}
+	res.Minimum, _ = required.Min()
+	var pragmaMin semver.Version
+	if ok {
+		pragmaMin, _ = c.Min()
+	}
+	for _, use := range uses {
+		if use.Feature.Since == res.Minimum && pragmaMin.Less(res.Minimum) {
+			res.Reason = use.Feature
+			break
+		}
+	}
+	return res
+}
+
+// PathMinimumVersions returns the lowest compiler versions of the
+// documents in the file or directory, see documentsUnder.
+func (s *State) PathMinimumVersions(path string) []MinimumVersion {
+	res := []MinimumVersion{}
+	for _, doc := range s.documentsUnder(path) {
+		res = append(res, s.MinimumVersion(doc))
+	}
+	return res
+}
@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"context"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const pragmaRangeSrc = `pragma solidity >=0.6.0 <0.9.0;
+
+contract Vault {
+    error Unauthorized();
+
+    address immutable owner;
+    uint256 seed;
+
+    constructor() {
+        owner = msg.sender;
+    }
+
+    function reseed() external {
+        if (msg.sender != owner) revert Unauthorized();
+        seed = block.prevrandao;
+    }
+}
+`
+
+func Test_PragmaRangeDiagnostics(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, pragmaRangeSrc)
+
+	messages := []string{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "pragma-range" {
+			if d.Severity != lsp.SeverityWarning {
+				t.Errorf("Expected a warning, got %v", d.Severity)
+			}
+			messages = append(messages, d.Message)
+		}
+	}
+	expected := []string{
+		"custom error requires >=0.8.4 but pragma allows 0.6.0; narrow the pragma or remove the construct",
+		"immutable variable requires >=0.6.5 but pragma allows 0.6.0; narrow the pragma or remove the construct",
+		"`block.prevrandao` requires >=0.8.18 but pragma allows 0.6.0; narrow the pragma or remove the construct",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected the diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+
+	// The single fix narrows the pragma to the newest of the three.
+	r := lsp.Range{Start: lsp.Position{Line: 0, Character: 3}, End: lsp.Position{Line: 0, Character: 3}}
+	actions := []lsp.CodeAction{}
+	for _, action := range s.CodeAction(lsp.IntID(1), uri, r).Result {
+		if action.Kind == lsp.CodeActionQuickFix && strings.HasPrefix(action.Title, "Narrow the pragma") {
+			actions = append(actions, action)
+		}
+	}
+	if len(actions) != 1 {
+		t.Fatalf("Expected 1 action narrowing the pragma, got %d", len(actions))
+	}
+	if actions[0].Title != "Narrow the pragma to >=0.8.18 <0.9.0" || len(actions[0].Diagnostics) != 3 {
+		t.Errorf("Expected the action fixing the 3 diagnostics, got %q with %d", actions[0].Title, len(actions[0].Diagnostics))
+	}
+	edits := actions[0].Edit.Changes[uri]
+	if len(edits) != 1 || edits[0].NewText != " >=0.8.18 <0.9.0" {
+		t.Fatalf("Expected the edit replacing the pragma value, got %+v", edits)
+	}
+	if expected := (lsp.Range{Start: lsp.Position{Line: 0, Character: 15}, End: lsp.Position{Line: 0, Character: 30}}); edits[0].Range != expected {
+		t.Errorf("Expected the edit at %v, got %v", expected, edits[0].Range)
+	}
+
+	// The narrowed pragma allows only the versions compiling the file.
+	s.UpdateDocument(uri, 2, strings.Replace(pragmaRangeSrc, ">=0.6.0 <0.9.0", ">=0.8.18 <0.9.0", 1))
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "pragma-range" {
+			t.Errorf("Expected no pragma-range diagnostics, got %q", d.Message)
+		}
+	}
+	if v := s.MinimumVersion(s.Documents[uri]); v.Minimum.String() != "0.8.18" || v.Reason != nil {
+		t.Errorf("Expected the minimum 0.8.18 set by the pragma, got %s", v.Minimum)
+	}
+}
+
+func Test_PragmaRangeSkipped(t *testing.T) {
+	s := NewState()
+	codes := func(uri, src string) []string {
+		s.OpenDocument(uri, 1, src)
+		res := []string{}
+		for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+			if d.Code == "pragma-range" || d.Code == "transient-version" {
+				res = append(res, string(d.Code)+": "+d.Message)
+			}
+		}
+		return res
+	}
+
+	// The transient variables are reported by transient-version only.
+	got := codes("file:///ws/src/Lock.sol", `pragma solidity ^0.8.20;
+
+contract Lock {
+    uint256 transient locked;
+}
+`)
+	expected := []string{"transient-version: `transient` state variables require Solidity 0.8.28, but the pragma `^0.8.20` allows older compilers"}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// No version allowed by the pragma has `now`, narrowing won't help.
+	src := `pragma solidity ^0.8.0;
+
+contract Clock {
+    function time() external view returns (uint256) {
+        return now;
+    }
+}
+`
+	if got := codes("file:///ws/src/Clock.sol", src); len(got) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", got)
+	}
+	got = codes("file:///ws/src/Clock.sol", strings.Replace(src, "^0.8.0", ">=0.6.0 <0.8.0", 1))
+	expected = []string{"pragma-range: `now` was removed in 0.7.0 but pragma allows 0.7.0; narrow the pragma or remove the construct"}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,443 @@
+package analysis
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/project"
+	"solbot/token"
+	"solbot/vfs"
+	"strings"
+)
+
+// The imports are resolved with the remappings of remappings.txt and the
+// directories of foundry.toml, so the server helps editing the two files
+// at the root of the workspace too: the remappings leading nowhere or
+// conflicting and the directories that don't exist are reported, the
+// targets of the remappings are completed from the disk, and an import
+// that doesn't resolve gets a quick fix adding the missing remapping. The
+// files open in the editor take part in the project model as they're
+// typed, see reloadProject.
+
+// projectFile is remappings.txt or foundry.toml open in the editor.
+type projectFile struct {
+	URI     string
+	Version int
+	Handle  *token.File
+}
+
+// IsProjectFile reports whether the file is remappings.txt or foundry.toml
+// at the root of the workspace.
+func (s *State) IsProjectFile(uri string) bool {
+	if s.Root == "" {
+		return false
+	}
+	p := URIToPath(uri)
+	name := filepath.Base(p)
+	return filepath.Dir(p) == s.Root && (name == "remappings.txt" || name == "foundry.toml")
+}
+
+// setProjectFile stores the content of the open project file and reloads
+// the project model with it.
+func (s *State) setProjectFile(uri string, version int, src string) {
+	s.projectFiles[uri] = &projectFile{URI: uri, Version: version, Handle: token.NewFile(uri, src)}
+	s.reloadProject()
+}
+
+// reloadProject reads the configuration of the project again, with the
+// content of the open project files in place of the one on the disk. The
+// imports are resolved against the configuration when they're followed,
+// so they resolve with the edited remappings right away. A configuration
+// that doesn't parse keeps the last one, its mistakes are reported in the
+// project files, see projectFileDiagnostics.
+func (s *State) reloadProject() {
+	files := map[string]string{}
+	for uri, f := range s.projectFiles {
+		files[URIToPath(uri)] = f.Handle.Src()
+	}
+	cfg, err := project.LoadFS(overlay{FS: s.files(), files: files}, s.Root)
+	if err != nil {
+		s.Logger.Warn("cannot reload the project configuration", "error", err)
+		return
+	}
+	s.projectConfig = cfg
+	s.Config = s.Settings.apply(cfg)
+	s.restrict()
+	s.referencesChanged = true
+}
+
+// overlay is the file system with the content of the files open in the
+// editor in place of the one on the disk.
+type overlay struct {
+	vfs.FS
+	files map[string]string // clean absolute path -> content
+}
+
+func (o overlay) Open(name string) (fs.File, error) {
+	if src, ok := o.files[filepath.Clean(name)]; ok {
+		return vfs.NewMemory(map[string]string{name: src}).Open(name)
+	}
+	return o.FS.Open(name)
+}
+
+func (o overlay) Stat(name string) (fs.FileInfo, error) {
+	if src, ok := o.files[filepath.Clean(name)]; ok {
+		return vfs.NewMemory(map[string]string{name: src}).Stat(name)
+	}
+	return o.FS.Stat(name)
+}
+
+// projectFileDiagnostics returns the diagnostics of the open project file.
+func (s *State) projectFileDiagnostics(f *projectFile) []lsp.Diagnostic {
+	if filepath.Base(URIToPath(f.URI)) == "remappings.txt" {
+		return s.remappingsDiagnostics(f)
+	}
+	return s.foundryTomlDiagnostics(f)
+}
+
+// remappingLine is a remapping of remappings.txt.
+type remappingLine struct {
+	project.Remapping
+	Line   int         // 1-based line of the remapping
+	Range  token.Range // range of the remapping without the surrounding whitespace
+	Target token.Range // range of the target
+}
+
+// remappingLines returns the remappings of the file, and the ranges of the
+// lines that aren't remappings.
+func remappingLines(handle *token.File) ([]remappingLine, []token.Range) {
+	remappings, invalid := []remappingLine{}, []token.Range{}
+	offset := 0
+	for i, line := range strings.Split(handle.Src(), "\n") {
+		start := offset
+		offset += len(line) + 1
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		from := token.Pos(start + strings.Index(line, trimmed))
+		r := token.Range{Start: from, End: from + token.Pos(len(trimmed))}
+		remapping, err := project.ParseRemapping(trimmed)
+		if err != nil {
+			invalid = append(invalid, r)
+			continue
+		}
+		target := r.Start + token.Pos(strings.Index(trimmed, "=")+1)
+		remappings = append(remappings, remappingLine{
+			Remapping: remapping,
+			Line:      i + 1,
+			Range:     r,
+			Target:    token.Range{Start: target, End: r.End},
+		})
+	}
+	return remappings, invalid
+}
+
+// remappingsDiagnostics reports the lines of remappings.txt that aren't
+// remappings, the targets that don't exist, the prefixes remapped twice to
+// different targets, and the prefixes taking over the imports another
+// remapping leads somewhere too.
+func (s *State) remappingsDiagnostics(f *projectFile) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(r token.Range, severity lsp.DiagnosticSeverity, code, message string, related ...remappingLine) {
+		d := lsp.Diagnostic{
+			Range:    toLspRange(f.Handle, r),
+			Severity: severity,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		}
+		for _, other := range related {
+			d.RelatedInformation = append(d.RelatedInformation, lsp.DiagnosticRelatedInformation{
+				Location: lsp.Location{URI: f.URI, Range: toLspRange(f.Handle, other.Range)},
+				Message:  other.String(),
+			})
+		}
+		res = append(res, d)
+	}
+
+	remappings, invalid := remappingLines(f.Handle)
+	for _, r := range invalid {
+		report(r, lsp.SeverityError, "invalid-remapping", "Expected a remapping e.g. `forge-std/=lib/forge-std/src/`")
+	}
+	for i, r := range remappings {
+		if r.Remapping.Target != "" && !s.isDirectory(r.Remapping.Target) {
+			report(r.Target, lsp.SeverityWarning, "missing-remapping-target",
+				fmt.Sprintf("The target `%s` of the prefix `%s` doesn't exist", r.Remapping.Target, r.Prefix))
+		}
+		for _, other := range remappings[:i] {
+			if other.Context != r.Context {
+				continue
+			}
+			switch {
+			case other.Prefix == r.Prefix && other.Remapping.Target != r.Remapping.Target:
+				report(r.Range, lsp.SeverityWarning, "conflicting-remapping",
+					fmt.Sprintf("The prefix `%s` is remapped to `%s` here and to `%s` on line %d", r.Prefix, r.Remapping.Target, other.Remapping.Target, other.Line), other)
+			case other.Prefix != r.Prefix && (strings.HasPrefix(r.Prefix, other.Prefix) || strings.HasPrefix(other.Prefix, r.Prefix)):
+				short, long := other, r
+				if len(short.Prefix) > len(long.Prefix) {
+					short, long = long, short
+				}
+				if message, ok := s.shadowing(short, long); ok {
+					report(r.Range, lsp.SeverityWarning, "shadowed-remapping", message, other)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// shadowing explains how the longer prefix takes the imports the shorter
+// one would resolve otherwise, if it's not an override of a subdirectory
+// meant by the user: the shorter prefix lacks the trailing slash, so it
+// matches a part of a name e.g. `oz` of `ozv4/`, or the longer prefix hides
+// a directory the shorter one leads to.
+func (s *State) shadowing(short, long remappingLine) (string, bool) {
+	rest := long.Prefix[len(short.Prefix):]
+	if !strings.HasSuffix(short.Prefix, "/") && !strings.HasPrefix(rest, "/") {
+		return fmt.Sprintf("The prefix `%s` without the trailing slash matches the imports of `%s` too, the longer prefix wins", short.Prefix, long.Prefix), true
+	}
+	hidden := short.Remapping.Target + rest
+	if short.Remapping.Target != "" && s.isDirectory(hidden) {
+		return fmt.Sprintf("The prefix `%s` hides `%s`, where the prefix `%s` leads too", long.Prefix, hidden, short.Prefix), true
+	}
+	return "", false
+}
+
+// isDirectory reports whether the path, relative to the root of the
+// workspace unless it's absolute, is a directory.
+func (s *State) isDirectory(p string) bool {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(s.Root, p)
+	}
+	info, err := s.files().Stat(p)
+	return err == nil && info.IsDir()
+}
+
+// quotedString matches the strings of foundry.toml.
+var quotedString = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+
+// foundryTomlDiagnostics reports the src, test and libs directories of the
+// default profile of foundry.toml that don't exist.
+func (s *State) foundryTomlDiagnostics(f *projectFile) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	section, key := "", ""
+	offset := 0
+	for _, line := range strings.Split(f.Handle.Src(), "\n") {
+		start := offset
+		offset += len(line) + 1
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && key == "" {
+			section = strings.Trim(trimmed, "[] ")
+			continue
+		}
+		value := line
+		if key == "" {
+			name, rest, found := strings.Cut(line, "=")
+			if !found || section != "profile.default" {
+				continue
+			}
+			switch key = strings.TrimSpace(name); key {
+			case "src", "test", "libs":
+			default:
+				key = ""
+				continue
+			}
+			value = rest
+		}
+		// The arrays can span multiple lines.
+		from := start + len(line) - len(value)
+		for _, loc := range quotedString.FindAllStringIndex(value, -1) {
+			dir := value[loc[0]+1 : loc[1]-1]
+			if s.isDirectory(dir) {
+				continue
+			}
+			res = append(res, lsp.Diagnostic{
+				Range:    toLspRange(f.Handle, token.Range{Start: token.Pos(from + loc[0]), End: token.Pos(from + loc[1])}),
+				Severity: lsp.SeverityWarning,
+				Code:     "missing-project-path",
+				Source:   "solbot",
+				Message:  fmt.Sprintf("The directory `%s` of `%s` doesn't exist", dir, key),
+			})
+		}
+		if !strings.HasPrefix(strings.TrimSpace(value), "[") || strings.Contains(value, "]") {
+			key = ""
+		}
+	}
+	return res
+}
+
+// projectFileCompletions lists the directories of the workspace in the
+// target of the remapping at the position e.g. the subdirectories of lib/
+// after `forge-std/=lib/`. The items replace the last segment of the path
+// typed so far.
+func (s *State) projectFileCompletions(f *projectFile, position lsp.Position) []lsp.CompletionItem {
+	items := []lsp.CompletionItem{}
+	if filepath.Base(URIToPath(f.URI)) != "remappings.txt" {
+		return items
+	}
+	pos := toTokenPos(f.Handle, position)
+	src := f.Handle.Src()
+	lineStart := strings.LastIndex(src[:pos], "\n") + 1
+	_, typed, found := strings.Cut(src[lineStart:pos], "=")
+	if !found {
+		return items
+	}
+	dir, partial := path.Split(typed)
+	entries, err := vfs.ReadDir(s.files(), filepath.Join(s.Root, filepath.FromSlash(dir)))
+	if err != nil {
+		return items
+	}
+	r := toLspRange(f.Handle, token.Range{Start: pos - token.Pos(len(partial)), End: pos})
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(name, partial) || strings.HasPrefix(name, ".") && !strings.HasPrefix(partial, ".") {
+			continue
+		}
+		items = append(items, lsp.CompletionItem{
+			Label:    name + "/",
+			Kind:     lsp.CompletionItemFolder,
+			TextEdit: &lsp.TextEdit{Range: r, NewText: name + "/"},
+		})
+	}
+	return items
+}
+
+// missingRemapping returns the remapping resolving the import that doesn't
+// resolve, if its first segment names a dependency, and the file it would
+// resolve to e.g. `forge-std/=lib/forge-std/src/` for
+// `import "forge-std/Test.sol"` with lib/forge-std/src/Test.sol indexed.
+// The scoped packages are looked up by their directories in the
+// dependencies e.g. lib/openzeppelin-contracts for `@openzeppelin/contracts/`.
+func (s *State) missingRemapping(doc *Document, imp *ast.ImportDirective) (project.Remapping, string, bool) {
+	if s.Root == "" || imp.Path == nil || len(imp.Path.Value) < 2 || s.ImportTarget(doc, imp) != nil {
+		return project.Remapping{}, "", false
+	}
+	importPath := imp.Path.Value[1 : len(imp.Path.Value)-1]
+	if IsRelativeImport(importPath) {
+		return project.Remapping{}, "", false
+	}
+	segments := strings.Split(importPath, "/")
+	type candidate struct {
+		segments int    // segments of the prefix
+		dir      string // directory of the dependency
+	}
+	candidates := []candidate{{1, strings.TrimPrefix(segments[0], "@")}}
+	if strings.HasPrefix(segments[0], "@") && len(segments) > 2 {
+		candidates = slices.Insert(candidates, 0, candidate{2, segments[0][1:] + "-" + segments[1]})
+	}
+	for _, c := range candidates {
+		if len(segments) <= c.segments {
+			continue
+		}
+		prefix := strings.Join(segments[:c.segments], "/") + "/"
+		rest := strings.Join(segments[c.segments:], "/")
+		for _, lib := range s.Config.Libs {
+			for _, sub := range []string{"", "src/", "contracts/"} {
+				target := path.Join(filepath.ToSlash(lib), c.dir) + "/" + sub
+				file := path.Join(target, rest)
+				if _, ok := s.document(PathToURI(filepath.Join(s.Root, file))); ok {
+					return project.Remapping{Prefix: prefix, Target: target}, file, true
+				}
+			}
+		}
+	}
+	return project.Remapping{}, "", false
+}
+
+// missingRemappingDiagnostics reports the imports that don't resolve, but
+// would with a remapping to a dependency, see missingRemapping.
+func (s *State) missingRemappingDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, decl := range doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok {
+			continue
+		}
+		if remapping, file, ok := s.missingRemapping(doc, imp); ok {
+			res = append(res, lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, ast.NodeRange(imp.Path)),
+				Severity: lsp.SeverityError,
+				Code:     "missing-remapping",
+				Source:   "solbot",
+				Message:  fmt.Sprintf("The import doesn't resolve; the remapping `%s` would resolve it to %s", remapping, file),
+			})
+		}
+	}
+	return res
+}
+
+// missingRemappingActions add the missing remapping of the selected import
+// to remappings.txt, see missingRemapping. The file is created if it
+// doesn't exist and the client can create the files.
+func (s *State) missingRemappingActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	diagnostics := s.missingRemappingDiagnostics(doc)
+	for _, decl := range doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok || imp.Path == nil || !touches(selected, ast.NodeRange(imp.Path)) {
+			continue
+		}
+		remapping, _, ok := s.missingRemapping(doc, imp)
+		if !ok {
+			continue
+		}
+		edit, ok := s.addRemappingEdit(remapping)
+		if !ok {
+			continue
+		}
+		r := toLspRange(doc.Handle, ast.NodeRange(imp.Path))
+		i := slices.IndexFunc(diagnostics, func(d lsp.Diagnostic) bool { return d.Range == r })
+		actions = append(actions, lsp.CodeAction{
+			Title:       fmt.Sprintf("Add the remapping `%s` to remappings.txt", remapping),
+			Kind:        lsp.CodeActionQuickFix,
+			Diagnostics: diagnostics[i : i+1],
+			Edit:        edit,
+			IsPreferred: true,
+		})
+	}
+	return actions
+}
+
+// addRemappingEdit returns the edit appending the remapping to
+// remappings.txt, the open one or the one on the disk.
+func (s *State) addRemappingEdit(remapping project.Remapping) (*lsp.WorkspaceEdit, bool) {
+	uri := PathToURI(filepath.Join(s.Root, "remappings.txt"))
+	var handle *token.File
+	if f, ok := s.projectFiles[uri]; ok {
+		handle = f.Handle
+	} else if src, err := vfs.ReadFile(s.files(), URIToPath(uri)); err == nil {
+		handle = token.NewFile(uri, string(src))
+	}
+
+	line := remapping.String() + "\n"
+	if handle == nil {
+		caps := s.Capabilities.Workspace
+		if caps == nil || caps.WorkspaceEdit == nil || !caps.WorkspaceEdit.DocumentChanges || !slices.Contains(caps.WorkspaceEdit.ResourceOperations, "create") {
+			return nil, false
+		}
+		return &lsp.WorkspaceEdit{DocumentChanges: []any{
+			lsp.CreateFile{Kind: "create", URI: uri, Options: &lsp.CreateFileOptions{IgnoreIfExists: true}},
+			lsp.TextDocumentEdit{
+				TextDocument: lsp.OptionalVersionedTextDocumentIdentifier{TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: uri}},
+				Edits:        []lsp.TextEdit{{NewText: line}},
+			},
+		}}, true
+	}
+	src := handle.Src()
+	if src != "" && !strings.HasSuffix(src, "\n") {
+		line = "\n" + line
+	}
+	end := toLspPosition(handle, token.Pos(len(src)))
+	return &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+		uri: {{Range: lsp.Range{Start: end, End: end}, NewText: line}},
+	}}, true
+}
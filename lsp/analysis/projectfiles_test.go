@@ -0,0 +1,130 @@
+package analysis
+
+import (
+	"context"
+	"path/filepath"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+func newProjectFilesWorkspace(t *testing.T, remappings string) (*State, string) {
+	t.Helper()
+	root := "/ws"
+	s := NewState()
+	s.FS = memoryWorkspace(root, map[string]string{
+		"remappings.txt":                   "ds-test/=lib/ds-test/src/\n",
+		"src/Vault.sol":                    "pragma solidity ^0.8.0;\n\nimport \"forge-std/Test.sol\";\n\ncontract Vault {}\n",
+		"lib/ds-test/src/test.sol":         "pragma solidity ^0.8.0;\n\ncontract DSTest {}\n",
+		"lib/forge-std/src/Test.sol":       "pragma solidity ^0.8.0;\n\ncontract Test {}\n",
+		"lib/solmate/src/tokens/ERC20.sol": "pragma solidity ^0.8.0;\n\ncontract ERC20 {}\n",
+	})
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	s.OpenDocument(PathToURI(filepath.Join(root, "remappings.txt")), 1, remappings)
+	return s, root
+}
+
+func Test_RemappingsDiagnostics(t *testing.T) {
+	remappings := "ds-test/=lib/ds-test/src/\nsolady/=lib/solady/src/\nds-test/=lib/forge-std/lib/ds-test/src/\nnot a remapping\n"
+	s, root := newProjectFilesWorkspace(t, remappings)
+	uri := PathToURI(filepath.Join(root, "remappings.txt"))
+
+	diagnostics := s.Diagnostics(context.Background(), uri).Params.Diagnostics
+	expected := []struct {
+		line uint
+		code string
+	}{
+		{1, "missing-remapping-target"},
+		{2, "conflicting-remapping"},
+		{2, "missing-remapping-target"},
+		{3, "invalid-remapping"},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	sortDiagnostics(diagnostics)
+	for i, e := range expected {
+		if d := diagnostics[i]; d.Range.Start.Line != e.line || d.Code != e.code {
+			t.Errorf("Expected %s on line %d, got %s on line %d: %s", e.code, e.line, d.Code, d.Range.Start.Line, d.Message)
+		}
+	}
+	broken := diagnostics[0]
+	if broken.Message != "The target `lib/solady/src/` of the prefix `solady/` doesn't exist" || broken.Range.Start.Character != 8 {
+		t.Errorf("Expected the broken target, got %d: %s", broken.Range.Start.Character, broken.Message)
+	}
+	if related := diagnostics[1].RelatedInformation; len(related) != 1 || related[0].Location.Range.Start.Line != 0 {
+		t.Errorf("Expected the first remapping of the prefix as related, got %v", related)
+	}
+
+	s.UpdateDocument(uri, 2, "oz=lib/openzeppelin/\nozv4/=lib/ozv4/\n")
+	diagnostics = s.Diagnostics(context.Background(), uri).Params.Diagnostics
+	codes := []string{}
+	for _, d := range diagnostics {
+		codes = append(codes, d.Code)
+	}
+	if !strings.Contains(strings.Join(codes, " "), "shadowed-remapping") {
+		t.Errorf("Expected the prefix without the trailing slash to be shadowed, got %v", diagnostics)
+	}
+}
+
+func Test_MissingRemappingQuickFix(t *testing.T) {
+	s, root := newProjectFilesWorkspace(t, "ds-test/=lib/ds-test/src/")
+	vault, _ := s.document(PathToURI(filepath.Join(root, "src/Vault.sol")))
+
+	diagnostics := s.missingRemappingDiagnostics(vault)
+	if len(diagnostics) != 1 || diagnostics[0].Code != "missing-remapping" {
+		t.Fatalf("Expected the missing remapping, got %v", diagnostics)
+	}
+	actions := s.missingRemappingActions(vault, token.Range{Start: 34, End: 34})
+	if len(actions) != 1 {
+		t.Fatalf("Expected a quick fix, got %v", actions)
+	}
+	action := actions[0]
+	if action.Title != "Add the remapping `forge-std/=lib/forge-std/src/` to remappings.txt" || !action.IsPreferred {
+		t.Errorf("Expected the preferred remapping, got %s", action.Title)
+	}
+
+	// The edit goes to the other document, appended after the missing
+	// newline of the last line.
+	uri := PathToURI(filepath.Join(root, "remappings.txt"))
+	edits := action.Edit.Changes[uri]
+	if len(edits) != 1 || edits[0].NewText != "\nforge-std/=lib/forge-std/src/\n" || edits[0].Range.Start != (lsp.Position{Line: 0, Character: 25}) {
+		t.Fatalf("Expected the remapping appended to remappings.txt, got %v", action.Edit.Changes)
+	}
+	s.UpdateDocument(uri, 2, "ds-test/=lib/ds-test/src/"+edits[0].NewText)
+
+	imp := vault.File.Declarations[1].(*ast.ImportDirective)
+	if target := s.ImportTarget(vault, imp); target == nil || !strings.HasSuffix(target.URI, "lib/forge-std/src/Test.sol") {
+		t.Errorf("Expected the import to resolve to forge-std, got %v", target)
+	}
+	if diagnostics := s.missingRemappingDiagnostics(vault); len(diagnostics) != 0 {
+		t.Errorf("Expected no missing remapping, got %v", diagnostics)
+	}
+}
+
+func Test_RemappingTargetCompletion(t *testing.T) {
+	s, root := newProjectFilesWorkspace(t, "solmate/=lib/")
+	uri := PathToURI(filepath.Join(root, "remappings.txt"))
+
+	items := s.Completion(lsp.IntID(1), uri, lsp.Position{Line: 0, Character: 13}).Result
+	labels := []string{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	if strings.Join(labels, " ") != "ds-test/ forge-std/ solmate/" {
+		t.Fatalf("Expected the directories of lib/, got %v", labels)
+	}
+
+	s.UpdateDocument(uri, 2, "solmate/=lib/sol")
+	items = s.Completion(lsp.IntID(2), uri, lsp.Position{Line: 0, Character: 16}).Result
+	if len(items) != 1 || items[0].Kind != lsp.CompletionItemFolder || items[0].TextEdit == nil {
+		t.Fatalf("Expected solmate/, got %v", items)
+	}
+	if r := items[0].TextEdit.Range; r.Start.Character != 13 || r.End.Character != 16 || items[0].TextEdit.NewText != "solmate/" {
+		t.Errorf("Expected the edit replacing `sol`, got %v", items[0].TextEdit)
+	}
+}
@@ -0,0 +1,306 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// StorageCollision is a pair of different variables of a proxy and its
+// implementation sharing the storage bytes. Since the implementation runs
+// on the storage of the proxy, writing one of them overwrites the other.
+type StorageCollision struct {
+	Proxy                  StorageSlot
+	Implementation         StorageSlot
+	ProxyVariable          *Symbol
+	ImplementationVariable *Symbol
+}
+
+// proxyDiagnostics reports the state variables of the proxies that collide
+// with the ones of their implementations. The implementation of a proxy is
+// the contract named by the `@custom:implementation` NatSpec tag, or the
+// contract type of the constructor parameter stored in an immutable
+// variable. The proxies with the state variables, but without a known
+// implementation, get an information instead of a guess.
+//
+// The proxies following EIP-1967 keep their state at the hashed slots,
+// written and read with the inline assembly. Those slots are not part of the
+// layout of the declared variables, so they never collide.
+func (s *State) proxyDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Kind != token.CONTRACT || c.Abstract {
+			continue
+		}
+		proxy := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		if !s.isProxy(proxy) {
+			continue
+		}
+		vars, ok := s.storageVariables(proxy)
+		if !ok || len(vars) == 0 {
+			continue
+		}
+
+		impl := s.implementationOf(proxy)
+		if impl == nil {
+			res = append(res, lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, ast.NodeRange(c.Name)),
+				Severity: lsp.SeverityInformation,
+				Code:     "unknown-implementation",
+				Source:   "solbot",
+				Message: fmt.Sprintf("The implementation of the proxy `%s` could not be determined, so its storage is not checked; "+
+					"name it with `/// @custom:implementation ContractName`", c.Name.Name),
+			})
+			continue
+		}
+
+		collisions, ok := s.storageCollisions(proxy, impl)
+		if !ok {
+			continue
+		}
+		for _, collision := range collisions {
+			// The variable can be declared by a base in another file.
+			r := ast.NodeRange(c.Name)
+			if collision.ProxyVariable.Doc == doc {
+				r = ast.NodeRange(collision.ProxyVariable.Name)
+			}
+			res = append(res, lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, r),
+				Severity: lsp.SeverityError,
+				Code:     "storage-collision",
+				Source:   "solbot",
+				Message:  collision.Message(),
+			})
+		}
+	}
+	return res
+}
+
+// Message describes the collision e.g. "Slot 0 of the proxy `Proxy` holds
+// `admin` (address), which collides with `owner` (address) of the
+// implementation `Vault`".
+func (c StorageCollision) Message() string {
+	return fmt.Sprintf("Slot %d of the proxy `%s` holds `%s` (%s), which collides with `%s` (%s) of the implementation `%s`",
+		c.Proxy.Slot, c.Proxy.Contract, c.Proxy.Name, c.Proxy.Type,
+		c.Implementation.Name, c.Implementation.Type, c.Implementation.Contract)
+}
+
+// isProxy reports whether the contract delegates the calls it doesn't
+// implement: its fallback function makes a delegatecall, or it inherits one
+// of the proxy bases configured in solbot.toml.
+func (s *State) isProxy(contract *Symbol) bool {
+	for _, c := range s.ancestors(contract) {
+		decl := c.Node.(*ast.ContractDeclaration)
+		if c != contract && slices.Contains(s.Config.ProxyBases, decl.Name.Name) {
+			return true
+		}
+		for _, member := range decl.Body {
+			fn, ok := member.(*ast.FunctionDeclaration)
+			if ok && fn.Kind == token.FALLBACK && fn.Body != nil && delegates(fn.Body) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// delegates reports whether the node makes a delegatecall, either with the
+// member of an address or with the inline assembly.
+func delegates(node ast.Node) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.MemberAccessExpression:
+			found = found || n.Member.Name == "delegatecall"
+		case *ast.AssemblyStatement:
+			found = found || strings.Contains(n.Body, "delegatecall(")
+		}
+		return !found
+	})
+	return found
+}
+
+// implementationOf returns the implementation of the proxy: the contract
+// named by its `/// @custom:implementation ContractName` tag or, without
+// the tag, the contract type of the constructor parameter assigned to an
+// immutable variable e.g. `implementation = address(_vault)`. It returns
+// nil if there is neither.
+func (s *State) implementationOf(proxy *Symbol) *Symbol {
+	doc, c := proxy.Doc, proxy.Node.(*ast.ContractDeclaration)
+	for _, line := range strings.Split(natSpec(doc, c), "\n") {
+		rest, ok := strings.CutPrefix(line, "@custom:implementation")
+		if !ok || len(strings.Fields(rest)) == 0 {
+			continue
+		}
+		sym := s.follow(s.lookup(doc, []ast.Node{doc.File}, strings.Fields(rest)[0], c.Start()))
+		if sym == nil {
+			return nil
+		}
+		if impl, ok := sym.Node.(*ast.ContractDeclaration); ok && impl.Kind == token.CONTRACT {
+			return sym
+		}
+		return nil
+	}
+
+	immutables := map[string]bool{}
+	var constructor *ast.FunctionDeclaration
+	for _, member := range c.Body {
+		switch n := member.(type) {
+		case *ast.VariableDeclaration:
+			if n.Immutable {
+				immutables[n.Name.Name] = true
+			}
+		case *ast.FunctionDeclaration:
+			if n.Kind == token.CONSTRUCTOR && n.Body != nil {
+				constructor = n
+			}
+		}
+	}
+	if constructor == nil || len(immutables) == 0 {
+		return nil
+	}
+
+	var res *Symbol
+	ast.Inspect(constructor.Body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignmentExpression)
+		if !ok || res != nil {
+			return res == nil
+		}
+		left, ok := assign.Left.(*ast.Identifier)
+		if !ok || assign.Operator != token.ASSIGN || !immutables[left.Name] {
+			return true
+		}
+		res = s.implementationArg(doc, constructor, assign.Right)
+		return false
+	})
+	return res
+}
+
+// implementationArg returns the contract passed to the constructor as the
+// parameter the expression refers to, unwrapping the conversions e.g.
+// `address(_vault)` or `Vault(_vault)`.
+func (s *State) implementationArg(doc *Document, constructor *ast.FunctionDeclaration, x ast.Expression) *Symbol {
+	var conversion *Symbol
+	for {
+		call, ok := x.(*ast.CallExpression)
+		if !ok || len(call.Args) != 1 {
+			break
+		}
+		switch fn := call.Function.(type) {
+		case *ast.ElementaryType:
+		case *ast.Identifier, *ast.MemberAccessExpression:
+			conversion = s.scopeOfType(doc, fn)
+			if conversion == nil {
+				return nil
+			}
+		default:
+			return nil
+		}
+		x = call.Args[0]
+	}
+
+	ident, ok := x.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	param := findParam(doc, constructor.Type.Params, ident.Name)
+	if param == nil {
+		return nil
+	}
+	impl := s.scopeOfType(doc, param.Node.(*ast.Param).Type)
+	if impl == nil {
+		// An address parameter converted to the contract type.
+		impl = conversion
+	}
+	if impl == nil {
+		return nil
+	}
+	if c, ok := impl.Node.(*ast.ContractDeclaration); !ok || c.Kind != token.CONTRACT {
+		return nil
+	}
+	return impl
+}
+
+// storageCollisions compares the storage layouts of the proxy and the
+// implementation byte by byte, and returns the pairs of the different
+// variables sharing some of them. The variables declared by a common base,
+// or declared the same way at the same place, are the same variable. It
+// returns false if either of the layouts is unknown.
+func (s *State) storageCollisions(proxy, impl *Symbol) ([]StorageCollision, bool) {
+	proxyVars, ok := s.storageVariables(proxy)
+	if !ok {
+		return nil, false
+	}
+	implVars, ok := s.storageVariables(impl)
+	if !ok {
+		return nil, false
+	}
+
+	res := []StorageCollision{}
+	for _, p := range proxyVars {
+		for _, i := range implVars {
+			if p.decl.Node == i.decl.Node || !overlaps(p.slot, i.slot) {
+				continue
+			}
+			if p.slot.Name == i.slot.Name && p.slot.Type == i.slot.Type &&
+				p.slot.Slot == i.slot.Slot && p.slot.Offset == i.slot.Offset {
+				continue
+			}
+			res = append(res, StorageCollision{
+				Proxy:                  p.slot,
+				Implementation:         i.slot,
+				ProxyVariable:          p.decl,
+				ImplementationVariable: i.decl,
+			})
+			break
+		}
+	}
+	return res, true
+}
+
+// overlaps reports whether the variables share some of the storage bytes.
+func overlaps(a, b StorageSlot) bool {
+	aStart, bStart := a.Slot*32+a.Offset, b.Slot*32+b.Offset
+	return aStart < bStart+b.Size && bStart < aStart+a.Size
+}
+
+// CheckProxy compares the storage layouts of the proxy and the
+// implementation contracts of the given names, see proxyDiagnostics. The
+// names can be qualified with the paths of the files e.g.
+// "src/Vault.sol:Vault", see ResolveContract. The pairing is not checked,
+// the contracts can be any two contracts of the workspace.
+func (s *State) CheckProxy(proxy, implementation string) ([]StorageCollision, error) {
+	p, err := s.resolveConcreteContract(proxy)
+	if err != nil {
+		return nil, err
+	}
+	impl, err := s.resolveConcreteContract(implementation)
+	if err != nil {
+		return nil, err
+	}
+	collisions, ok := s.storageCollisions(p, impl)
+	if !ok {
+		return nil, fmt.Errorf("the storage layout of `%s` or `%s` can't be computed", proxy, implementation)
+	}
+	return collisions, nil
+}
+
+// resolveConcreteContract resolves the reference to a contract, see
+// ResolveContract, rejecting the interfaces and the libraries.
+func (s *State) resolveConcreteContract(ref string) (*Symbol, error) {
+	c, err := s.ResolveContract(ref)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Node.(*ast.ContractDeclaration).Kind {
+	case token.INTERFACE:
+		return nil, fmt.Errorf("`%s` is an interface, not a contract", ref)
+	case token.LIBRARY:
+		return nil, fmt.Errorf("`%s` is a library, not a contract", ref)
+	}
+	return c, nil
+}
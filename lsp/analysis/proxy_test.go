@@ -0,0 +1,136 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"testing"
+)
+
+const vaultSrc = `pragma solidity ^0.8.0;
+
+contract Vault {
+    address owner;
+    uint256 total;
+
+    function deposit() external payable {
+        total += msg.value;
+    }
+}
+`
+
+func Test_ProxyStorageCollision(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, vaultSrc)
+	uri := "file:///ws/src/VaultProxy.sol"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+import {Vault} from "./Vault.sol";
+
+contract VaultProxy {
+    address admin;
+    address immutable implementation;
+
+    constructor(Vault _vault) {
+        admin = msg.sender;
+        implementation = address(_vault);
+    }
+
+    fallback() external payable {
+        (bool ok, ) = implementation.delegatecall(msg.data);
+        require(ok);
+    }
+}
+`)
+
+	diagnostics := s.proxyDiagnostics(s.Documents[uri])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "storage-collision" || d.Severity != lsp.SeverityError {
+		t.Errorf("Expected a storage collision error, got %s %v", d.Code, d.Severity)
+	}
+	if d.Range.Start.Line != 5 || d.Range.Start.Character != 12 {
+		t.Errorf("Expected the diagnostic at `admin`, got %v", d.Range)
+	}
+	expected := "Slot 0 of the proxy `VaultProxy` holds `admin` (address), which collides with `owner` (address) of the implementation `Vault`"
+	if d.Message != expected {
+		t.Errorf("Expected %q, got %q", expected, d.Message)
+	}
+
+	collisions, err := s.CheckProxy("VaultProxy", "Vault")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(collisions) != 1 || collisions[0].ImplementationVariable.Name.Name != "owner" {
+		t.Errorf("Expected the collision with `owner`, got %v", collisions)
+	}
+	if _, err := s.CheckProxy("VaultProxy", "Token"); err == nil {
+		t.Errorf("Expected an error for an unknown contract, got nil")
+	}
+}
+
+func Test_ProxyWithEIP1967Slots(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, vaultSrc)
+	uri := "file:///ws/src/VaultProxy.sol"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+import {Vault} from "./Vault.sol";
+
+/// @custom:implementation Vault
+contract VaultProxy {
+    bytes32 constant IMPLEMENTATION_SLOT = 0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc;
+
+    constructor(Vault _vault) {
+        assembly {
+            sstore(IMPLEMENTATION_SLOT, _vault)
+        }
+    }
+
+    fallback() external payable {
+        assembly {
+            calldatacopy(0, 0, calldatasize())
+            let ok := delegatecall(gas(), sload(IMPLEMENTATION_SLOT), 0, calldatasize(), 0, 0)
+            returndatacopy(0, 0, returndatasize())
+            switch ok
+            case 0 { revert(0, returndatasize()) }
+            default { return(0, returndatasize()) }
+        }
+    }
+}
+`)
+
+	if diagnostics := s.proxyDiagnostics(s.Documents[uri]); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diagnostics)
+	}
+	collisions, err := s.CheckProxy("VaultProxy", "Vault")
+	if err != nil || len(collisions) != 0 {
+		t.Errorf("Expected no collisions, got %v and error %v", collisions, err)
+	}
+}
+
+func Test_ProxyWithUnknownImplementation(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, vaultSrc)
+	uri := "file:///ws/src/VaultProxy.sol"
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+abstract contract ERC1967Proxy {}
+
+contract VaultProxy is ERC1967Proxy {
+    address admin;
+
+    constructor(address implementation) {
+        admin = msg.sender;
+    }
+}
+`)
+
+	diagnostics := s.proxyDiagnostics(s.Documents[uri])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	if d := diagnostics[0]; d.Code != "unknown-implementation" || d.Severity != lsp.SeverityInformation {
+		t.Errorf("Expected the information about the unknown implementation, got %s %v", d.Code, d.Severity)
+	}
+}
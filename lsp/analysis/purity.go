@@ -0,0 +1,792 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"solbot/yul"
+	"strings"
+)
+
+// Effect is a set of the effects of a function on the blockchain, which
+// decide the state mutability it can be declared with.
+type Effect uint
+
+const (
+	ReadsState       Effect = 1 << iota // reads a state variable or the storage
+	ReadsAccount                        // reads the balance or the code of an account
+	ReadsEnvironment                    // reads `msg`, `block`, `tx`, `this` or the like
+	CallsView                           // calls a view function of another contract
+	WritesState                         // writes a state variable or the storage
+	Emits                               // emits an event
+	CallsExternal                       // calls a function of another contract that can change the state, or creates one
+	CallsUnknown                        // calls a function that can't be resolved
+)
+
+var effectNames = []struct {
+	effect Effect
+	name   string
+}{
+	{ReadsState, "reads the state"},
+	{ReadsAccount, "reads an account"},
+	{ReadsEnvironment, "reads the environment"},
+	{CallsView, "calls a view function"},
+	{WritesState, "writes the state"},
+	{Emits, "emits an event"},
+	{CallsExternal, "calls a function that can change the state"},
+	{CallsUnknown, "calls a function that can't be resolved"},
+}
+
+// changesState are the effects not allowed in the view functions. The
+// unresolved calls are assumed to change the state.
+const changesState = WritesState | Emits | CallsExternal | CallsUnknown
+
+// String returns the effects separated by commas e.g. "reads the state,
+// emits an event"; or "none".
+func (e Effect) String() string {
+	names := []string{}
+	for _, n := range effectNames {
+		if e&n.effect != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// disallowed returns the effects that the mutability doesn't allow. The
+// unresolved calls are never reported, they may well be allowed.
+func disallowed(mutability ast.Mutability) Effect {
+	switch mutability {
+	case ast.Pure:
+		return ReadsState | ReadsAccount | ReadsEnvironment | CallsView | WritesState | Emits | CallsExternal
+	case ast.View:
+		return WritesState | Emits | CallsExternal
+	}
+	return 0
+}
+
+// functionEffects are the effects of a function or a modifier, its own
+// and the ones of the functions and the modifiers it calls.
+type functionEffects struct {
+	callable
+	effects Effect
+	sources map[Effect]effectSource // the first cause of every effect
+	calls   []effectCall            // calls of the implemented functions and modifiers
+	slots   []slotAccess            // reads and writes of the storage in the inline assembly, its own
+}
+
+// effectSource is the statement causing an effect, directly or through
+// the callee.
+type effectSource struct {
+	node   ast.Node
+	callee *functionEffects // nil if the effect is caused by the statement itself
+	call   *yul.Call        // the builtin causing it, if node is an assembly block
+}
+
+// Range returns the range of the builtin causing the effect, or of the
+// statement.
+func (e effectSource) Range() token.Range {
+	if e.call != nil {
+		return token.Range{Start: e.call.Pos, End: e.call.Pos + token.Pos(len(e.call.Name))}
+	}
+	return ast.NodeRange(e.node)
+}
+
+// slotAccess is a call of sload, sstore, tload or tstore in the inline
+// assembly. The variable is known if the slot is its `.slot` e.g. in
+// `sstore(total.slot, 1)`; otherwise any slot may be accessed.
+type slotAccess struct {
+	call   yul.Call
+	effect yul.Effect
+	ident  *ast.Identifier          // the variable in the Yul code; or nil
+	decl   *ast.VariableDeclaration // the state variable; or nil if the slot is not known
+}
+
+type effectCall struct {
+	node   ast.Node
+	callee callable
+}
+
+func (f *functionEffects) add(effect Effect, source effectSource) bool {
+	if f.effects&effect == effect {
+		return false
+	}
+	for _, n := range effectNames {
+		if effect&n.effect != 0 && f.effects&n.effect == 0 {
+			f.effects |= n.effect
+			f.sources[n.effect] = source
+		}
+	}
+	return true
+}
+
+// inferEffects returns the effects of the functions and the modifiers
+// reachable from the roots, by their declarations. The effects of the
+// callees are propagated to the callers until nothing changes, so the
+// recursive calls add nothing on their own. The calls that can't be
+// resolved are assumed to change the state, see CallsUnknown, and the
+// calls of the unimplemented functions have the effects their mutability
+// allows.
+func (s *State) inferEffects(roots []callable) map[ast.Node]*functionEffects {
+	res := map[ast.Node]*functionEffects{}
+	order := []*functionEffects{}
+	queue := roots
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if res[c.node] != nil {
+			continue
+		}
+		f := s.localEffects(c)
+		res[c.node] = f
+		order = append(order, f)
+		for _, call := range f.calls {
+			queue = append(queue, call.callee)
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, f := range order {
+			for _, call := range f.calls {
+				callee := res[call.callee.node]
+				if f.add(callee.effects, effectSource{node: call.node, callee: callee}) {
+					changed = true
+				}
+			}
+		}
+	}
+	return res
+}
+
+// localEffects returns the effects of the statements of the function or
+// the modifier, with the calls of the others left to inferEffects.
+func (s *State) localEffects(c callable) *functionEffects {
+	return s.effectsWithin(c, func(ast.Node) bool { return true })
+}
+
+// effectsWithin returns the local effects of the identifiers and the
+// assembly blocks of the function or the modifier for which within is
+// true e.g. the ones in the selected statements.
+func (s *State) effectsWithin(c callable, within func(ast.Node) bool) *functionEffects {
+	f := &functionEffects{callable: c, sources: map[Effect]effectSource{}}
+	outer := ast.PathEnclosingPos(c.doc.File, c.node.Start())
+	for len(outer) > 0 && outer[0] != c.node {
+		outer = outer[1:]
+	}
+	if len(outer) == 0 {
+		return f
+	}
+	outer = outer[1:]
+
+	stack := []ast.Node{}
+	ast.Inspect(c.node, func(node ast.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, node)
+		path := func() []ast.Node {
+			path := make([]ast.Node, 0, len(stack)+len(outer))
+			for i := len(stack) - 1; i >= 0; i-- {
+				path = append(path, stack[i])
+			}
+			return append(path, outer...)
+		}
+		if !within(node) {
+			return true
+		}
+		switch node.(type) {
+		case *ast.Identifier:
+			// The variables of the inline assembly are only slots and
+			// offsets, the builtins access them.
+			if _, ok := stack[len(stack)-2].(*ast.AssemblyStatement); !ok {
+				s.identifierEffects(f, path())
+			}
+		case *ast.AssemblyStatement:
+			s.assemblyEffects(f, path())
+		}
+		return true
+	})
+	return f
+}
+
+// effectsWithCallees returns the effects of the statements of the function
+// or the modifier for which within is true, with the ones of the functions
+// and the modifiers they call.
+func (s *State) effectsWithCallees(c callable, within func(ast.Node) bool) Effect {
+	f := s.effectsWithin(c, within)
+	effects := f.effects
+	if len(f.calls) > 0 {
+		roots := []callable{}
+		for _, call := range f.calls {
+			roots = append(roots, call.callee)
+		}
+		inferred := s.inferEffects(roots)
+		for _, call := range f.calls {
+			effects |= inferred[call.callee.node].effects
+		}
+	}
+	return effects
+}
+
+// yulEffects are the effects of the builtins of the inline assembly.
+var yulEffects = map[yul.Effect]Effect{
+	yul.StorageRead:     ReadsState,
+	yul.StorageWrite:    WritesState,
+	yul.TransientRead:   ReadsState,
+	yul.TransientWrite:  WritesState,
+	yul.AccountRead:     ReadsAccount,
+	yul.EnvironmentRead: ReadsEnvironment,
+	yul.StaticCall:      CallsView,
+	yul.ExternalCall:    CallsExternal,
+	yul.Emission:        Emits,
+	yul.SelfDestruct:    CallsExternal,
+}
+
+// assemblyEffects adds the effects of the builtins called in the assembly
+// block at path[0], and its accesses of the storage. The slots given as
+// the `.slot` of a state variable are accessed through the variable, the
+// others may be any. The Yul code that doesn't parse is assumed to change
+// the state.
+func (s *State) assemblyEffects(f *functionEffects, path []ast.Node) {
+	n := path[0].(*ast.AssemblyStatement)
+	calls, err := yul.Calls(n.Body, n.LeftBrace+1)
+	if err != nil {
+		f.add(CallsUnknown, effectSource{node: n})
+		return
+	}
+	for i := range calls {
+		call := &calls[i]
+		effect := yul.BuiltinEffect(call.Name)
+		if effect == yul.NoEffect {
+			continue
+		}
+		f.add(yulEffects[effect], effectSource{node: n, call: call})
+		switch effect {
+		case yul.StorageRead, yul.StorageWrite, yul.TransientRead, yul.TransientWrite:
+		default:
+			continue
+		}
+		access := slotAccess{call: *call, effect: effect}
+		if call.Slot != nil {
+			for _, ident := range n.Identifiers {
+				if ident.NamePos != call.Slot.Pos {
+					continue
+				}
+				access.ident = ident
+				sym := s.follow(s.resolve(f.doc, append([]ast.Node{ident}, path...)))
+				if sym == nil {
+					break
+				}
+				if decl, ok := sym.Node.(*ast.VariableDeclaration); ok && s.declaringContract(sym) != nil {
+					access.decl = decl
+				}
+			}
+		}
+		f.slots = append(f.slots, access)
+	}
+}
+
+// environment are the builtins reading the environment, by their names.
+var environment = map[string]bool{
+	"msg": true, "block": true, "tx": true, "now": true, "this": true,
+	"gasleft": true, "blockhash": true, "blobhash": true,
+}
+
+// identifierEffects adds the effects of the identifier at path[0]: the
+// reads and the writes of the state variables and of the storage pointers,
+// the events, the calls and the builtins reading the environment.
+func (s *State) identifierEffects(f *functionEffects, path []ast.Node) {
+	ident := path[0].(*ast.Identifier)
+	doc := f.doc
+	source := effectSource{node: effectStatement(path)}
+	call := callAt(path)
+	called := call != nil
+	access, isMember := path[1].(*ast.MemberAccessExpression)
+	isMember = isMember && access.Member == ident
+
+	sym := s.follow(s.resolve(doc, path))
+	if inv, ok := path[1].(*ast.ModifierInvocation); ok && inv.Name == ident {
+		// The modifiers that can't be resolved and the base constructors
+		// invoked by mistake.
+		if sym == nil {
+			f.add(CallsUnknown, source)
+			return
+		}
+		if _, ok := sym.Node.(*ast.ModifierDeclaration); !ok {
+			f.add(CallsUnknown, source)
+			return
+		}
+	}
+	if sym == nil {
+		switch {
+		case isMember:
+			if member, ok := s.addressMemberOf(doc, path[2:], access); ok {
+				switch {
+				case !member.function:
+					f.add(ReadsAccount, source)
+				case ident.Name == "staticcall":
+					f.add(CallsView, source)
+				default:
+					f.add(CallsExternal, source)
+				}
+				return
+			}
+			if called && !s.builtinMember(doc, path[2:], access) {
+				f.add(CallsUnknown, source)
+			}
+		case ident.Name == "selfdestruct" || ident.Name == "suicide":
+			f.add(CallsExternal, source)
+		case environment[ident.Name]:
+			if parent, ok := path[1].(*ast.MemberAccessExpression); ok && parent.Expression == ident &&
+				ident.Name == "msg" && (parent.Member.Name == "sig" || parent.Member.Name == "data") {
+				return
+			}
+			f.add(ReadsEnvironment, source)
+		case called && !builtins[ident.Name] && legacyBuiltins[ident.Name] == "":
+			f.add(CallsUnknown, source)
+		}
+		return
+	}
+	if sym.Name == ident {
+		return
+	}
+
+	// Members of the other contracts, except for the inherited ones
+	// accessed by the name of the base or with `super`.
+	external := false
+	if isMember {
+		if base, ok := access.Expression.(*ast.Identifier); ok && base.Name == "super" {
+			if called {
+				s.superEffects(f, path, source)
+			}
+			return
+		}
+		scope := s.follow(s.resolveExpr(doc, path[2:], access.Expression))
+		external = true
+		if scope != nil {
+			switch scope.Node.(type) {
+			case *ast.ContractDeclaration, *ast.ImportDirective:
+				external = false
+				path = path[1:]
+			}
+		}
+	}
+
+	switch n := sym.Node.(type) {
+	case *ast.FunctionDeclaration:
+		if !called {
+			return
+		}
+		contract := s.declaringContract(sym)
+		library := contract != nil && contract.Node.(*ast.ContractDeclaration).Kind == token.LIBRARY
+		// The value is the first argument of the attached functions.
+		args := len(call.Args)
+		if external && library {
+			args++
+		}
+		if sym = s.overload(sym, contract, args); sym == nil {
+			f.add(CallsUnknown, source)
+			return
+		}
+		n = sym.Node.(*ast.FunctionDeclaration)
+		if external && !library && contract != nil {
+			switch {
+			case n.Type.Mutability == ast.Pure:
+			case n.Type.Mutability == ast.View:
+				f.add(CallsView, source)
+			default:
+				f.add(CallsExternal, source)
+			}
+			return
+		}
+		if n.Body == nil {
+			switch n.Type.Mutability {
+			case ast.Pure:
+			case ast.View:
+				f.add(ReadsState, source)
+			default:
+				f.add(WritesState, source)
+			}
+			return
+		}
+		f.calls = append(f.calls, effectCall{node: source.node, callee: callable{doc: sym.Doc, node: n}})
+	case *ast.ModifierDeclaration:
+		if inv, ok := path[1].(*ast.ModifierInvocation); !ok || inv.Name != ident {
+			return
+		}
+		if n.Body == nil {
+			f.add(CallsUnknown, source)
+			return
+		}
+		f.calls = append(f.calls, effectCall{node: source.node, callee: callable{doc: sym.Doc, node: n}})
+	case *ast.EventDeclaration:
+		if called {
+			f.add(Emits, source)
+		}
+	case *ast.ContractDeclaration:
+		if _, ok := path[1].(*ast.NewExpression); ok {
+			f.add(CallsExternal, source)
+		}
+	case *ast.VariableDeclaration:
+		switch {
+		case external:
+			// The getter of a public state variable.
+			if called {
+				f.add(CallsView, source)
+			}
+		case n.Constant:
+		case n.Location == ast.Storage:
+			s.storageEffects(f, path, source, true)
+		case s.declaringContract(sym) != nil:
+			if n.Immutable {
+				f.add(ReadsState, source)
+				return
+			}
+			s.storageEffects(f, path, source, false)
+		case called:
+			s.functionTypeEffects(f, n.Type, source)
+		}
+	case *ast.Param:
+		switch {
+		case n.Location == ast.Storage:
+			s.storageEffects(f, path, source, true)
+		case called:
+			s.functionTypeEffects(f, n.Type, source)
+		}
+	}
+}
+
+// superEffects adds the call of the function with the name of the member
+// at path[0] in the first base of the enclosing contract implementing it,
+// in the order of the linearization.
+func (s *State) superEffects(f *functionEffects, path []ast.Node, source effectSource) {
+	name := path[0].(*ast.Identifier).Name
+	contract := enclosingContract(f.doc, path)
+	if contract == nil {
+		return
+	}
+	linearized := s.linearize(contract)
+	for i := 1; i < len(linearized); i++ {
+		for _, member := range linearized[i].Node.(*ast.ContractDeclaration).Body {
+			fn, ok := member.(*ast.FunctionDeclaration)
+			if ok && fn.Name != nil && fn.Name.Name == name && fn.Body != nil {
+				f.calls = append(f.calls, effectCall{node: source.node, callee: callable{doc: linearized[i].Doc, node: fn}})
+				return
+			}
+		}
+	}
+	f.add(CallsUnknown, source)
+}
+
+// storageEffects adds the read or the write of the state variable or of
+// the storage pointer at path[0]. Assigning the pointer itself is neither.
+func (s *State) storageEffects(f *functionEffects, path []ast.Node, source effectSource, pointer bool) {
+	top, written := writtenAt(path)
+	switch {
+	case pointer && top == 0 && written:
+	case written:
+		f.add(WritesState, source)
+	default:
+		f.add(ReadsState, source)
+	}
+}
+
+// functionTypeEffects adds the effects of calling a variable of a function
+// type, the ones its mutability allows.
+func (s *State) functionTypeEffects(f *functionEffects, typ ast.Expression, source effectSource) {
+	fn, ok := typ.(*ast.FunctionType)
+	if !ok {
+		f.add(CallsUnknown, source)
+		return
+	}
+	switch {
+	case fn.Mutability == ast.Pure:
+	case fn.Mutability == ast.View && fn.Visibility == ast.External:
+		f.add(CallsView, source)
+	case fn.Mutability == ast.View:
+		f.add(ReadsState, source)
+	case fn.Visibility == ast.External:
+		f.add(CallsExternal, source)
+	default:
+		f.add(WritesState, source)
+	}
+}
+
+// builtinMember reports whether the called member is one of the builtins
+// e.g. `abi.encode` or `string.concat`, or a member of a value which is
+// not a contract e.g. `push` of an array.
+func (s *State) builtinMember(doc *Document, path []ast.Node, access *ast.MemberAccessExpression) bool {
+	switch base := access.Expression.(type) {
+	case *ast.Identifier:
+		switch base.Name {
+		case "abi", "string", "bytes", "msg", "block", "tx":
+			return true
+		}
+	case *ast.ElementaryType:
+		return true
+	case *ast.CallExpression:
+		if ident, ok := base.Function.(*ast.Identifier); ok && ident.Name == "type" {
+			return true
+		}
+	}
+	typeDoc, t := s.typeOf(doc, path, access.Expression)
+	if t == nil {
+		return false
+	}
+	scope := s.scopeOfType(typeDoc, t)
+	if scope == nil {
+		_, ok := t.(*ast.ElementaryType)
+		_, array := t.(*ast.ArrayType)
+		return ok || array
+	}
+	_, ok := scope.Node.(*ast.ContractDeclaration)
+	return !ok
+}
+
+// writtenAt returns the index of the outermost access based on path[0],
+// see accessedBy, and whether it's written: assigned, incremented,
+// decremented, deleted, pushed to or popped from.
+func writtenAt(path []ast.Node) (int, bool) {
+	top := accessedBy(path)
+	if top+1 >= len(path) {
+		return top, false
+	}
+	switch parent := path[top+1].(type) {
+	case *ast.UnaryExpression:
+		switch parent.Operator {
+		case token.INC, token.DEC, token.DELETE:
+			return top, true
+		}
+	case *ast.CallExpression:
+		if access, ok := path[top].(*ast.MemberAccessExpression); ok && parent.Function == access {
+			return top, access.Member.Name == "push" || access.Member.Name == "pop"
+		}
+	}
+	i := top + 1
+	for i < len(path) {
+		if _, ok := path[i].(*ast.TupleExpression); !ok {
+			break
+		}
+		i++
+	}
+	if i < len(path) {
+		if assign, ok := path[i].(*ast.AssignmentExpression); ok && assign.Left == path[i-1] {
+			return top, true
+		}
+	}
+	return top, false
+}
+
+// overload returns the function with the name of the resolved one taking
+// the number of the arguments, since the overloads resolve to the first
+// declaration; or nil if there is none. The arguments are not type checked,
+// so the overloads of the same arity are not told apart.
+func (s *State) overload(fn *Symbol, contract *Symbol, args int) *Symbol {
+	if paramCount(fn.Node.(*ast.FunctionDeclaration)) == args {
+		return fn
+	}
+	if contract == nil {
+		return nil
+	}
+	for _, member := range contract.Node.(*ast.ContractDeclaration).Body {
+		other, ok := member.(*ast.FunctionDeclaration)
+		if ok && other.Name != nil && other.Name.Name == fn.Name.Name && paramCount(other) == args {
+			return &Symbol{Doc: contract.Doc, Name: other.Name, Node: other}
+		}
+	}
+	return nil
+}
+
+func paramCount(fn *ast.FunctionDeclaration) int {
+	if fn.Type.Params == nil {
+		return 0
+	}
+	return len(fn.Type.Params.List)
+}
+
+// callAt returns the call of the function at path[0] e.g. of `f` in `f(x)`
+// or in `token.f{value: 1}(x)`; or nil if it's not called.
+func callAt(path []ast.Node) *ast.CallExpression {
+	i := 0
+	if access, ok := path[1].(*ast.MemberAccessExpression); ok && access.Member == path[0] {
+		i = 1
+	}
+	if i+1 < len(path) {
+		if options, ok := path[i+1].(*ast.CallOptionsExpression); ok && options.Expression == path[i] {
+			i++
+		}
+	}
+	if i+1 < len(path) {
+		if call, ok := path[i+1].(*ast.CallExpression); ok && call.Function == path[i] {
+			return call
+		}
+	}
+	return nil
+}
+
+// effectStatement returns the statement or the modifier invocation
+// enclosing the identifier at path[0].
+func effectStatement(path []ast.Node) ast.Node {
+	for _, node := range path {
+		switch node.(type) {
+		case *ast.BlockStatement, *ast.UncheckedBlockStatement:
+		case *ast.ModifierInvocation:
+			return node
+		case ast.Statement:
+			return node
+		case *ast.FunctionDeclaration, *ast.ModifierDeclaration:
+			return path[0]
+		}
+	}
+	return path[0]
+}
+
+// documentEffects returns the effects of the functions of the document.
+func (s *State) documentEffects(doc *Document) ([]*ast.FunctionDeclaration, map[ast.Node]*functionEffects) {
+	fns := []*ast.FunctionDeclaration{}
+	roots := []callable{}
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		if fn, ok := node.(*ast.FunctionDeclaration); ok {
+			if fn.Body != nil {
+				fns = append(fns, fn)
+				roots = append(roots, callable{doc: doc, node: fn})
+			}
+			return false
+		}
+		return true
+	})
+	return fns, s.inferEffects(roots)
+}
+
+// purityDiagnostics checks the mutability of the functions against their
+// inferred effects. The checks can be disabled one by one with their codes
+// in the [detectors] section:
+//
+//   - mutability-violation: the function is declared view or pure, but it
+//     or one of the functions and the modifiers it calls has an effect the
+//     mutability doesn't allow. The chain of the calls leading to the
+//     statement causing it is in the related information;
+//   - could-be-view: the function could be declared view or pure. The
+//     virtual functions are left for their overrides, and the calls that
+//     can't be resolved are assumed to change the state.
+//
+// The legacy documents are left out, the compilers before 0.5.0 only warn
+// about the mutability.
+func (s *State) purityDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	if pragma.Legacy(doc.File) {
+		return res
+	}
+	fns, effects := s.documentEffects(doc)
+	for _, fn := range fns {
+		f := effects[fn]
+		if violations := f.effects & disallowed(fn.Type.Mutability); violations != 0 {
+			if !slices.Contains(s.Config.Disabled, "mutability-violation") {
+				res = append(res, s.violationDiagnostics(f, fn, violations)...)
+			}
+			continue
+		}
+		if slices.Contains(s.Config.Disabled, "could-be-view") || fn.Kind != token.FUNCTION || fn.Virtual || fn.Type.Mutability == ast.Payable || fn.Type.Mutability == ast.Pure {
+			continue
+		}
+		suggested := ast.View
+		if f.effects == 0 {
+			suggested = ast.Pure
+		}
+		if f.effects&changesState != 0 || suggested == fn.Type.Mutability {
+			continue
+		}
+		message := fmt.Sprintf("`%s` doesn't change the state, it can be declared view", fn.Name.Name)
+		if suggested == ast.Pure {
+			message = fmt.Sprintf("`%s` doesn't read or change the state, it can be declared pure", fn.Name.Name)
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(fn.Name)),
+			Severity: lsp.SeverityInformation,
+			Code:     "could-be-view",
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+	return res
+}
+
+// violationDiagnostics returns the diagnostics of the effects the function
+// is not allowed to have, one per statement causing them.
+func (s *State) violationDiagnostics(f *functionEffects, fn *ast.FunctionDeclaration, violations Effect) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	byRange := map[token.Range]int{}
+	for _, n := range effectNames {
+		if violations&n.effect == 0 {
+			continue
+		}
+		source := f.sources[n.effect]
+		if i, ok := byRange[source.Range()]; ok {
+			res[i].Message += " and " + effectReason(n.name, source)
+			continue
+		}
+		byRange[source.Range()] = len(res)
+		res = append(res, lsp.Diagnostic{
+			Range:              toLspRange(f.doc.Handle, source.Range()),
+			Severity:           lsp.SeverityError,
+			Code:               "mutability-violation",
+			Source:             "solbot",
+			Message:            fmt.Sprintf("`%s` is declared %s but it %s", f.name(), mutabilityName(fn.Type.Mutability), effectReason(n.name, source)),
+			RelatedInformation: effectChain(source, n.effect),
+		})
+	}
+	return res
+}
+
+func effectReason(name string, source effectSource) string {
+	if source.call != nil && source.call.Slot != nil {
+		return fmt.Sprintf("%s with `%s` of `%s`", name, source.call.Name, source.call.Slot.Name)
+	}
+	if source.call != nil {
+		return fmt.Sprintf("%s with `%s`", name, source.call.Name)
+	}
+	if source.callee == nil {
+		return name
+	}
+	return fmt.Sprintf("%s through `%s`", name, source.callee.name())
+}
+
+// effectChain returns the calls through which the effect reaches the
+// statement, down to the one causing it.
+func effectChain(source effectSource, effect Effect) []lsp.DiagnosticRelatedInformation {
+	res := []lsp.DiagnosticRelatedInformation{}
+	seen := map[*functionEffects]bool{}
+	for f := source.callee; f != nil && !seen[f]; f = f.sources[effect].callee {
+		seen[f] = true
+		next := f.sources[effect]
+		message := fmt.Sprintf("`%s` %s here", f.name(), effect)
+		if next.callee != nil {
+			message = fmt.Sprintf("`%s` calls `%s`", f.name(), next.callee.name())
+		}
+		res = append(res, lsp.DiagnosticRelatedInformation{
+			Location: lsp.Location{URI: f.doc.URI, Range: toLspRange(f.doc.Handle, next.Range())},
+			Message:  message,
+		})
+	}
+	return res
+}
+
+// effectsHover returns the inferred effects of the internal or private
+// function declared without a mutability; or an empty string if it's not
+// one.
+func (s *State) effectsHover(sym *Symbol) string {
+	fn, ok := sym.Node.(*ast.FunctionDeclaration)
+	if !ok || fn.Body == nil || fn.Type.Mutability != 0 || fn.Type.Visibility != ast.Internal && fn.Type.Visibility != ast.Private {
+		return ""
+	}
+	effects := s.inferEffects([]callable{{doc: sym.Doc, node: fn}})
+	return "inferred effects: " + effects[fn].effects.String()
+}
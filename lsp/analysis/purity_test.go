@@ -0,0 +1,241 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const purityLedger = `pragma solidity ^0.8.0;
+
+abstract contract Ledger {
+    mapping(address => uint256) balances;
+    uint256 total;
+
+    event Synced(uint256 total);
+
+    function _credit(address to, uint256 amount) internal {
+        balances[to] += amount;
+        _sync();
+    }
+
+    function _sync() internal {
+        emit Synced(total);
+    }
+}
+`
+
+const purityVault = `pragma solidity ^0.8.0;
+
+import "./Ledger.sol";
+
+contract Vault is Ledger {
+    function preview(address to) external view returns (uint256) {
+        _credit(to, 1);
+        return balances[to];
+    }
+
+    function isEven(uint256 n) public pure returns (bool) {
+        return n == 0 || isOdd(n - 1);
+    }
+
+    function isOdd(uint256 n) public pure returns (bool) {
+        return n != 0 && isEven(n - 1);
+    }
+
+    function balanceOf(address owner) external returns (uint256) {
+        return balances[owner];
+    }
+
+    function double(uint256 n) external returns (uint256) {
+        return n * 2;
+    }
+
+    function price(address oracle) external returns (uint256) {
+        return IOracle(oracle).latest();
+    }
+
+    function _quote(address oracle) internal returns (uint256) {
+        return IOracle(oracle).latest() + total;
+    }
+}
+`
+
+func newPurityState() *State {
+	s := NewState()
+	s.Root = "/ws"
+	s.OpenDocument("file:///ws/src/Ledger.sol", 1, purityLedger)
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, purityVault)
+	return s
+}
+
+func Test_PurityDiagnostics(t *testing.T) {
+	s := newPurityState()
+
+	got := []string{}
+	var violation lsp.Diagnostic
+	for _, d := range s.Diagnostics(context.Background(), "file:///ws/src/Vault.sol").Params.Diagnostics {
+		if d.Code != "mutability-violation" && d.Code != "could-be-view" {
+			continue
+		}
+		if d.Code == "mutability-violation" {
+			violation = d
+		}
+		got = append(got, fmt.Sprintf("%d %s %d: %s", d.Range.Start.Line, d.Code, d.Severity, d.Message))
+	}
+	// The write of the helper is reported on the call, the recursive pure
+	// functions stay pure, and the call of the unknown oracle may change
+	// the state.
+	expected := []string{
+		"6 mutability-violation 1: `preview` is declared view but it writes the state through `_credit` and emits an event through `_credit`",
+		"18 could-be-view 3: `balanceOf` doesn't change the state, it can be declared view",
+		"22 could-be-view 3: `double` doesn't read or change the state, it can be declared pure",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// The chain leads to the statement writing the state in the other file.
+	chain := []string{}
+	for _, info := range violation.RelatedInformation {
+		chain = append(chain, fmt.Sprintf("%s:%d %s", info.Location.URI, info.Location.Range.Start.Line, info.Message))
+	}
+	expectedChain := "file:///ws/src/Ledger.sol:9 `_credit` writes the state here"
+	if strings.Join(chain, "\n") != expectedChain {
+		t.Errorf("Expected %q, got %q", expectedChain, strings.Join(chain, "\n"))
+	}
+}
+
+func Test_InferEffects(t *testing.T) {
+	s := newPurityState()
+	doc := s.Documents["file:///ws/src/Vault.sol"]
+
+	fns, effects := s.documentEffects(doc)
+	got := map[string]Effect{}
+	for _, fn := range fns {
+		got[fn.Name.Name] = effects[fn].effects
+	}
+	expected := map[string]Effect{
+		"preview":   ReadsState | WritesState | Emits,
+		"isEven":    0,
+		"isOdd":     0,
+		"balanceOf": ReadsState,
+		"double":    0,
+		"price":     CallsUnknown,
+		"_quote":    ReadsState | CallsUnknown,
+	}
+	for name, effect := range expected {
+		if got[name] != effect {
+			t.Errorf("Expected the effects of %s to be %q, got %q", name, effect, got[name])
+		}
+	}
+
+	// The emit is two calls away, the chain goes through both helpers.
+	var preview *ast.FunctionDeclaration
+	for _, fn := range fns {
+		if fn.Name.Name == "preview" {
+			preview = fn
+		}
+	}
+	chain := []string{}
+	for _, info := range effectChain(effects[preview].sources[Emits], Emits) {
+		chain = append(chain, info.Message)
+	}
+	if expected := "`_credit` calls `_sync`, `_sync` emits an event here"; strings.Join(chain, ", ") != expected {
+		t.Errorf("Expected %q, got %q", expected, strings.Join(chain, ", "))
+	}
+}
+
+func Test_HoverInferredEffects(t *testing.T) {
+	s := newPurityState()
+
+	hover := s.Hover(lsp.IntID(1), "file:///ws/src/Vault.sol", lsp.Position{Line: 30, Character: 14}).Result.Contents.Value
+	if expected := "inferred effects: reads the state, calls a function that can't be resolved"; !strings.HasSuffix(hover, "\n\n"+expected) {
+		t.Errorf("Expected the hover of _quote to end with %q, got %q", expected, hover)
+	}
+	// The hover of the call shows the effects of the helper in the other
+	// file, its own write and the read and the emit of the helper it calls.
+	hover = s.Hover(lsp.IntID(1), "file:///ws/src/Vault.sol", lsp.Position{Line: 6, Character: 9}).Result.Contents.Value
+	if expected := "inferred effects: reads the state, writes the state, emits an event\n\nDeclared in src/Ledger.sol"; !strings.HasSuffix(hover, "\n\n"+expected) {
+		t.Errorf("Expected the hover of _credit to end with %q, got %q", expected, hover)
+	}
+	hover = s.Hover(lsp.IntID(1), "file:///ws/src/Vault.sol", lsp.Position{Line: 10, Character: 14}).Result.Contents.Value
+	if strings.Contains(hover, "inferred effects") {
+		t.Errorf("Expected no inferred effects for the pure isEven, got %q", hover)
+	}
+}
+
+const purityAssembly = `pragma solidity ^0.8.0;
+
+contract Counter {
+    uint256 count;
+    mapping(address => uint256) balances;
+
+    event Bumped(uint256 count);
+
+    function peek() external view returns (uint256 n) {
+        assembly {
+            n := sload(count.slot)
+            sstore(count.slot, add(n, 1))
+        }
+    }
+
+    function poke(bytes32 slot) external view {
+        assembly {
+            sstore(slot, 1)
+        }
+    }
+
+    function bump() external {
+        assembly {
+            log2(0, 0, 0x01, sload(count.slot))
+            pop(call(gas(), caller(), 0, 0, 0, 0, 0))
+        }
+    }
+
+    function slotOf() external pure returns (uint256 s) {
+        assembly {
+            s := balances.slot
+        }
+    }
+}
+`
+
+func Test_AssemblyEffects(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/Counter.sol"
+	s.OpenDocument(uri, 1, purityAssembly)
+
+	// The violations are reported on the builtins, with the variable of
+	// the slot if it's known.
+	got := []string{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "mutability-violation" || d.Code == "could-be-view" {
+			got = append(got, fmt.Sprintf("%d:%d %s: %s", d.Range.Start.Line, d.Range.Start.Character, d.Code, d.Message))
+		}
+	}
+	expected := []string{
+		"11:12 mutability-violation: `peek` is declared view but it writes the state with `sstore` of `count`",
+		"17:12 mutability-violation: `poke` is declared view but it writes the state with `sstore`",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	fns, effects := s.documentEffects(s.Documents[uri])
+	for _, fn := range fns {
+		if fn.Name.Name != "bump" {
+			continue
+		}
+		f := effects[fn]
+		if expected := ReadsState | ReadsEnvironment | Emits | CallsExternal; f.effects != expected {
+			t.Errorf("Expected the effects of bump to be %q, got %q", expected, f.effects)
+		}
+		if len(f.slots) != 1 || f.slots[0].decl == nil || f.slots[0].decl.Name.Name != "count" {
+			t.Errorf("Expected bump to read the slot of count, got %v", f.slots)
+		}
+	}
+}
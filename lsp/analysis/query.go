@@ -0,0 +1,331 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"solbot/ast"
+	"solbot/query"
+	"solbot/token"
+)
+
+// QueryMatch is a node matching the selector of a query.
+type QueryMatch struct {
+	Kind  string // kind of the node e.g. "call"
+	Node  ast.Node
+	Range token.Range
+}
+
+// Query returns the nodes of the document matching the selector e.g.
+// `function[visibility=external] > call[callee=*.delegatecall]`, in the
+// source order. See the query package for the syntax.
+func (s *State) Query(uri, selector string) ([]QueryMatch, error) {
+	sel, err := query.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := s.document(uri)
+	if !ok {
+		return nil, fmt.Errorf("unknown document %s", uri)
+	}
+	res := []QueryMatch{}
+	for _, n := range s.selectAll(doc, doc.File, sel, false) {
+		res = append(res, QueryMatch{Kind: nodeKind(n), Node: n, Range: ast.NodeRange(n)})
+	}
+	return res, nil
+}
+
+// selectAll returns the nodes under the root matching the selector. The
+// selector of :has is relative to the root, which is not matched itself.
+func (s *State) selectAll(doc *Document, root ast.Node, sel *query.Selector, relative bool) []ast.Node {
+	res := []ast.Node{}
+	open := []ast.Node{}
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil {
+			open = open[:len(open)-1]
+			return false
+		}
+		ancestors := []ast.Node{}
+		for _, a := range open {
+			if nodeKind(a) != "" || relative && a == root {
+				ancestors = append(ancestors, a)
+			}
+		}
+		open = append(open, n)
+		if (!relative || n != root) && s.matches(doc, sel, len(sel.Steps)-1, n, ancestors, relative) {
+			res = append(res, n)
+		}
+		return true
+	})
+	return res
+}
+
+// matches reports whether the node matches the steps of the selector up to
+// the ith one. The ancestors are the nodes with a kind enclosing the node,
+// from the outermost one. For the relative selectors, they start with the
+// node of :has.
+func (s *State) matches(doc *Document, sel *query.Selector, i int, n ast.Node, ancestors []ast.Node, relative bool) bool {
+	step := sel.Steps[i]
+	if !s.matchesStep(doc, step, n, ancestors) {
+		return false
+	}
+	lo := 0
+	if relative {
+		lo = 1
+	}
+	if i == 0 {
+		return !relative || step.Combinator == query.Descendant || len(ancestors) == 1
+	}
+	if step.Combinator == query.Child {
+		last := len(ancestors) - 1
+		return last >= lo && s.matches(doc, sel, i-1, ancestors[last], ancestors[:last], relative)
+	}
+	for j := len(ancestors) - 1; j >= lo; j-- {
+		if s.matches(doc, sel, i-1, ancestors[j], ancestors[:j], relative) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *State) matchesStep(doc *Document, step query.Step, n ast.Node, ancestors []ast.Node) bool {
+	kind := nodeKind(n)
+	if kind == "" || step.Kind != "*" && step.Kind != kind {
+		return false
+	}
+	for _, f := range step.Filters {
+		switch f.Pseudo {
+		case "has":
+			if len(s.selectAll(doc, n, f.Selector, true)) == 0 {
+				return false
+			}
+		case "not":
+			if s.matches(doc, f.Selector, len(f.Selector.Steps)-1, n, ancestors, false) {
+				return false
+			}
+		default:
+			found := false
+			for _, value := range s.attribute(doc, n, f.Attribute) {
+				ok, _ := path.Match(f.Value, value)
+				found = found || ok
+			}
+			if found == f.Negated {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// nodeKind returns the kind of the node in the queries; or an empty string
+// if the node can't be selected e.g. a block.
+func nodeKind(n ast.Node) string {
+	switch n.(type) {
+	case *ast.ContractDeclaration:
+		return "contract"
+	case *ast.FunctionDeclaration:
+		return "function"
+	case *ast.ModifierDeclaration:
+		return "modifier"
+	case *ast.EventDeclaration:
+		return "event"
+	case *ast.ErrorDeclaration:
+		return "error"
+	case *ast.StructDeclaration:
+		return "struct"
+	case *ast.EnumDeclaration:
+		return "enum"
+	case *ast.VariableDeclaration:
+		return "variable"
+	case *ast.Param:
+		return "param"
+	case *ast.CallExpression:
+		return "call"
+	case *ast.AssignmentExpression:
+		return "assignment"
+	case *ast.BinaryExpression:
+		return "binary"
+	case *ast.UnaryExpression:
+		return "unary"
+	case *ast.ConditionalExpression:
+		return "conditional"
+	case *ast.MemberAccessExpression:
+		return "member"
+	case *ast.IndexAccessExpression:
+		return "index"
+	case *ast.Identifier:
+		return "identifier"
+	case *ast.BasicLit:
+		return "literal"
+	case *ast.NewExpression:
+		return "new"
+	case *ast.IfStatement:
+		return "if"
+	case *ast.ForStatement:
+		return "for"
+	case *ast.WhileStatement:
+		return "while"
+	case *ast.DoWhileStatement:
+		return "do"
+	case *ast.ReturnStatement:
+		return "return"
+	case *ast.EmitStatement:
+		return "emit"
+	case *ast.RevertStatement:
+		return "revert"
+	case *ast.TryStatement:
+		return "try"
+	case *ast.CatchClause:
+		return "catch"
+	case *ast.UncheckedBlockStatement:
+		return "unchecked"
+	case *ast.AssemblyStatement:
+		return "assembly"
+	case *ast.ImportDirective:
+		return "import"
+	case *ast.PragmaDirective:
+		return "pragma"
+	case *ast.UsingForDirective:
+		return "using"
+	case *ast.TypeDeclaration:
+		return "type"
+	}
+	return ""
+}
+
+// attribute returns the values of the attribute of the node; or nil if the
+// node has none. The callee of a call is both its source text without the
+// call options e.g. `vault.deposit` and its resolved name e.g.
+// `IVault.deposit`, since the builtins like `delegatecall` don't resolve.
+func (s *State) attribute(doc *Document, n ast.Node, attr string) []string {
+	switch attr {
+	case "name":
+		if name := declaredName(n); name != nil {
+			return []string{name.Name}
+		}
+		switch n := n.(type) {
+		case *ast.Identifier:
+			return []string{n.Name}
+		case *ast.MemberAccessExpression:
+			return []string{n.Member.Name}
+		case *ast.CallExpression:
+			if callee := calleeName(n); callee != nil {
+				return []string{callee.Name}
+			}
+		}
+	case "kind":
+		switch n := n.(type) {
+		case *ast.ContractDeclaration:
+			return []string{n.Kind.String()}
+		case *ast.FunctionDeclaration:
+			return []string{n.Kind.String()}
+		}
+	case "visibility":
+		var visibility ast.Visibility
+		switch n := n.(type) {
+		case *ast.FunctionDeclaration:
+			visibility = n.Type.Visibility
+		case *ast.VariableDeclaration:
+			visibility = n.Visibility
+		}
+		if name := visibilityName(visibility); name != "" {
+			return []string{name}
+		}
+	case "mutability":
+		switch n := n.(type) {
+		case *ast.FunctionDeclaration:
+			return []string{mutabilityName(n.Type.Mutability)}
+		case *ast.VariableDeclaration:
+			switch {
+			case n.Constant:
+				return []string{"constant"}
+			case n.Immutable:
+				return []string{"immutable"}
+			}
+			return []string{"mutable"}
+		}
+	case "location":
+		var location ast.DataLocation
+		switch n := n.(type) {
+		case *ast.Param:
+			location = n.Location
+		case *ast.VariableDeclaration:
+			location = n.Location
+		}
+		if name := locationName(location); name != "" {
+			return []string{name}
+		}
+	case "operator":
+		switch n := n.(type) {
+		case *ast.BinaryExpression:
+			return []string{n.Operator.String()}
+		case *ast.UnaryExpression:
+			return []string{n.Operator.String()}
+		case *ast.AssignmentExpression:
+			return []string{n.Operator.String()}
+		}
+	case "callee":
+		call, ok := n.(*ast.CallExpression)
+		if !ok {
+			return nil
+		}
+		x := call.Function
+		if options, ok := x.(*ast.CallOptionsExpression); ok {
+			x = options.Expression
+		}
+		res := []string{ast.ExprString(x)}
+		if callee := calleeName(call); callee != nil {
+			enclosing := ast.PathEnclosingPos(doc.File, callee.Start())
+			if sym := s.follow(s.resolve(doc, enclosing)); sym != nil {
+				name := sym.Name.Name
+				if c := s.declaringContract(sym); c != nil {
+					name = c.Name.Name + "." + name
+				}
+				res = append(res, name)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// calleeName returns the name of the called function e.g. `deposit` in
+// `vault.deposit{value: 1}(x)`; or nil if the callee is not named.
+func calleeName(call *ast.CallExpression) *ast.Identifier {
+	x := call.Function
+	if options, ok := x.(*ast.CallOptionsExpression); ok {
+		x = options.Expression
+	}
+	switch x := x.(type) {
+	case *ast.Identifier:
+		return x
+	case *ast.MemberAccessExpression:
+		return x.Member
+	}
+	return nil
+}
+
+func visibilityName(visibility ast.Visibility) string {
+	switch visibility {
+	case ast.Internal:
+		return "internal"
+	case ast.External:
+		return "external"
+	case ast.Private:
+		return "private"
+	case ast.Public:
+		return "public"
+	}
+	return ""
+}
+
+func mutabilityName(mutability ast.Mutability) string {
+	switch mutability {
+	case ast.Pure:
+		return "pure"
+	case ast.View:
+		return "view"
+	case ast.Payable:
+		return "payable"
+	}
+	return "nonpayable"
+}
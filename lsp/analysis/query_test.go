@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+const querySrc = `pragma solidity ^0.8.0;
+
+interface IVault {
+    function deposit(uint256 amount) external payable;
+}
+
+library Math {
+    function max(uint256 a, uint256 b) internal pure returns (uint256) {
+        return a > b ? a : b;
+    }
+}
+
+contract Router {
+    IVault vault;
+    address implementation;
+    uint256 constant FEE = 5;
+    uint256 total;
+
+    function route(uint256 amount) external payable {
+        vault.deposit{value: msg.value}(amount);
+        total += amount;
+        if (amount > FEE) {
+            (bool ok, ) = implementation.delegatecall(msg.data);
+            require(ok);
+        }
+    }
+
+    function balance() external view returns (uint256) {
+        return total;
+    }
+
+    function _fee(bytes memory data) internal pure returns (uint256) {
+        return data.length * FEE;
+    }
+
+    fallback() external payable {
+        (bool ok, ) = implementation.delegatecall(msg.data);
+        require(ok);
+    }
+}
+`
+
+func Test_Query(t *testing.T) {
+	uri := "file:///ws/src/Router.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, querySrc)
+	handle := s.Documents[uri].Handle
+
+	tests := []struct {
+		selector string
+		expected []string // kind and line:column of the matches
+	}{
+		{"contract", []string{"contract 3:1", "contract 7:1", "contract 13:1"}},
+		{"contract[kind=library]", []string{"contract 7:1"}},
+		// The call in the if statement is not a child of the function.
+		{"function[visibility=external][mutability!=view] > call[callee=*.delegatecall]", []string{"call 37:23"}},
+		{"function[kind=fallback] call[name=require]", []string{"call 38:9"}},
+		{"call[callee=IVault.deposit]", []string{"call 20:9"}},
+		{"call[callee=vault.deposit]", []string{"call 20:9"}},
+		{"assignment[operator=+=]", []string{"assignment 21:9"}},
+		{"function > if > call", []string{"call 23:27", "call 24:13"}},
+		{"function > call", []string{"call 20:9", "call 37:23", "call 38:9"}},
+		{"variable[mutability=constant]", []string{"variable 16:5"}},
+		{"param[location=memory]", []string{"param 32:19"}},
+		{"function[name=_*]", []string{"function 32:5"}},
+		{"function:has(> if)", []string{"function 19:5"}},
+		{"function:has(member[name=delegatecall]):not(function[kind=fallback])", []string{"function 19:5"}},
+		{"contract > function:not([visibility=external])", []string{"function 8:5", "function 32:5"}},
+		{"binary[operator=>] identifier[name=FEE]", []string{"identifier 22:22"}},
+		{"conditional", []string{"conditional 9:16"}},
+		{"contract:has(variable[name=FEE]) > function[mutability=payable]", []string{"function 19:5", "function 36:5"}},
+	}
+	for _, tt := range tests {
+		matches, err := s.Query(uri, tt.selector)
+		if err != nil {
+			t.Errorf("Expected no error for %q, got %s", tt.selector, err)
+			continue
+		}
+		got := []string{}
+		for _, m := range matches {
+			p := handle.Position(m.Range.Start)
+			got = append(got, fmt.Sprintf("%s %d:%d", m.Kind, p.Line, p.Column))
+		}
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("Expected %v for %q, got %v", tt.expected, tt.selector, got)
+		}
+	}
+
+	if _, err := s.Query(uri, "function >"); err == nil || err.Error() != "column 11: unexpected end of the selector, expected a node kind, `*`, `[` or `:`" {
+		t.Errorf("Expected the syntax error, got %v", err)
+	}
+}
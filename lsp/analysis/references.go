@@ -0,0 +1,158 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+)
+
+// referenceDiagnostics checks the references and the targets of the revert
+// and emit statements:
+//   - an identifier that isn't declared e.g. `blanaces`,
+//   - a member of an import unit alias, of a contract accessed by its name,
+//     of an enum or of a struct that doesn't exist e.g. `Errors.NotOwnr`,
+//     also in a struct constructor with the named arguments,
+//   - a revert statement with something else than an error,
+//   - an emit statement with something else than an event,
+//   - a name declared differently by two files brought in with the plain
+//     imports e.g. `Math` of both "./Math.sol" and "./lib/Math.sol". Like
+//     solc, the name is reported where it's used, not at the imports.
+//
+// The names that don't resolve come with the names they are likely typos
+// of, see misspellings. Nothing is reported if some of the imports can't be
+// followed, since the declarations can be in the missing files.
+func (s *State) referenceDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	if !s.importsResolve(doc, map[*Document]bool{}) {
+		return res
+	}
+	report := func(node ast.Node, code, message string) {
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(node)),
+			Severity: lsp.SeverityError,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+
+	for _, m := range s.misspellings(doc) {
+		report(m.ident, m.code, m.message+didYouMean(m.suggestions))
+	}
+
+	ambiguous := map[string][]*Symbol{}
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		for _, node := range path {
+			if _, ok := node.(*ast.ImportDirective); ok {
+				return
+			}
+		}
+		if access, ok := path[1].(*ast.MemberAccessExpression); ok && access.Member == ident {
+			return
+		}
+		decls, ok := ambiguous[ident.Name]
+		if !ok {
+			decls = s.importedDeclarations(doc, ident.Name, map[*Document]bool{})
+			ambiguous[ident.Name] = decls
+		}
+		if len(decls) < 2 {
+			return
+		}
+		// Only the names found in the file scope come from the imports.
+		sym := s.resolve(doc, path)
+		global := s.lookupFile(doc, ident.Name, map[*Document]bool{})
+		if sym == nil || global == nil || sym.Node != global.Node {
+			return
+		}
+		origins := []string{}
+		for _, decl := range decls {
+			origins = append(origins, s.RelativePath(decl.Doc.URI))
+		}
+		report(ident, "ambiguous-import", fmt.Sprintf("`%s` is ambiguous: it is declared in %s and %s",
+			ident.Name, strings.Join(origins[:len(origins)-1], ", "), origins[len(origins)-1]))
+	})
+
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		var call *ast.CallExpression
+		var kind, code string
+		switch n := node.(type) {
+		case *ast.RevertStatement:
+			call, kind, code = n.Call, "error", "invalid-revert"
+		case *ast.EmitStatement:
+			call, kind, code = n.Call, "event", "invalid-emit"
+		default:
+			return true
+		}
+		if call == nil {
+			return true
+		}
+
+		path := ast.PathEnclosingPos(doc.File, call.Function.Start())
+		sym := s.follow(s.resolveExpr(doc, path, call.Function))
+		name := ast.ExprString(call.Function)
+		if sym == nil {
+			// The unresolved members are already reported.
+			if ident, ok := call.Function.(*ast.Identifier); ok {
+				suggestions := suggest(ident.Name, s.declaredNames(doc, path, kind))
+				report(call.Function, code, fmt.Sprintf("Undefined %s `%s`", kind, name)+didYouMean(suggestions))
+			}
+			return true
+		}
+		switch sym.Node.(type) {
+		case *ast.ErrorDeclaration:
+			if kind == "error" {
+				return true
+			}
+		case *ast.EventDeclaration:
+			if kind == "event" {
+				return true
+			}
+		}
+		report(call.Function, code, fmt.Sprintf("`%s` is not an %s", name, kind))
+		return true
+	})
+	return res
+}
+
+// namespace returns the scope of the member access if all of its members
+// are known: an import unit alias, a contract accessed by its name, an enum
+// or a value of a struct type; or nil otherwise. The members of the other
+// values e.g. `vault.deposit` are not checked, since their types are
+// resolved only partially.
+func (s *State) namespace(doc *Document, path []ast.Node, x ast.Expression) *Symbol {
+	sym := s.follow(s.resolveExpr(doc, path, x))
+	if sym == nil {
+		return nil
+	}
+	switch sym.Node.(type) {
+	case *ast.ImportDirective, *ast.ContractDeclaration, *ast.EnumDeclaration:
+		// Enum members resolve to the enum declaration too.
+		if enum, ok := sym.Node.(*ast.EnumDeclaration); ok && sym.Name != enum.Name {
+			return nil
+		}
+		return sym
+	case *ast.StructDeclaration:
+		// A member of a struct type e.g. `Position.amount` is not a value.
+		return nil
+	}
+	// The functions attached to the struct type everywhere are declared
+	// with it, see attachedFunction.
+	if scope := s.scopeOf(doc, path, x); scope != nil {
+		if _, ok := scope.Node.(*ast.StructDeclaration); ok && !hasGlobalUsing(scope.Doc) {
+			return scope
+		}
+	}
+	return nil
+}
+
+// hasGlobalUsing reports whether the file attaches functions to its types
+// globally e.g. `using {add} for Fixed global;`.
+func hasGlobalUsing(doc *Document) bool {
+	for _, decl := range doc.File.Declarations {
+		if using, ok := decl.(*ast.UsingForDirective); ok && using.Global {
+			return true
+		}
+	}
+	return false
+}
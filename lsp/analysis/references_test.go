@@ -0,0 +1,276 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+var namespaceWorkspace = map[string]string{
+	"file:///ws/src/Errors.sol": `pragma solidity ^0.8.4;
+
+error NotOwner(address caller);
+error Paused();
+`,
+	"file:///ws/src/Events.sol": `pragma solidity ^0.8.4;
+
+event Deposited(address indexed from, uint256 amount);
+`,
+	"file:///ws/src/Vault.sol": `pragma solidity ^0.8.4;
+
+import "./Errors.sol" as Errors;
+import * as Events from "./Events.sol";
+
+contract Vault {
+    address owner;
+
+    function deposit(uint256 amount) external {
+        if (msg.sender != owner) {
+            revert Errors.NotOwner(msg.sender);
+        }
+        emit Events.Deposited(msg.sender, amount);
+    }
+
+    function pause() external {
+        revert Errors.Pausd();
+    }
+
+    function withdraw() external {
+        revert Events.Deposited(msg.sender, 0);
+    }
+}
+`,
+}
+
+func newNamespaceState() *State {
+	s := NewState()
+	s.Root = "/ws"
+	for uri, src := range namespaceWorkspace {
+		s.Documents[uri] = newDocument(uri, 0, false, src)
+	}
+	return s
+}
+
+func Test_DefinitionThroughNamespace(t *testing.T) {
+	s := newNamespaceState()
+
+	// `NotOwner` in `revert Errors.NotOwner(msg.sender);`
+	response := s.Definition(lsp.IntID(1), "file:///ws/src/Vault.sol", lsp.Position{Line: 10, Character: 28})
+	if response.Result == nil || len(*response.Result) != 1 {
+		t.Fatalf("Expected 1 location, got %v", response.Result)
+	}
+	location := (*response.Result)[0]
+	expected := lsp.Location{
+		URI:   "file:///ws/src/Errors.sol",
+		Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 6}, End: lsp.Position{Line: 2, Character: 14}},
+	}
+	if location != expected {
+		t.Errorf("Expected %v, got %v", expected, location)
+	}
+
+	hover := s.Hover(lsp.IntID(2), "file:///ws/src/Vault.sol", lsp.Position{Line: 12, Character: 22})
+	if !strings.Contains(hover.Result.Contents.Value, "event Deposited(address indexed from, uint256 amount)") {
+		t.Errorf("Expected the event declaration in the hover, got %q", hover.Result.Contents.Value)
+	}
+}
+
+func Test_CompletionAfterNamespace(t *testing.T) {
+	s := newNamespaceState()
+	src := strings.Replace(namespaceWorkspace["file:///ws/src/Vault.sol"], "revert Errors.Pausd();", "revert Errors.", 1)
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, src)
+
+	response := s.Completion(lsp.IntID(1), "file:///ws/src/Vault.sol", lsp.Position{Line: 16, Character: 22})
+	labels := []string{}
+	for _, item := range response.Result {
+		labels = append(labels, item.Label)
+	}
+	if strings.Join(labels, ",") != "NotOwner,Paused" {
+		t.Errorf("Expected NotOwner and Paused, got %v", labels)
+	}
+	if len(response.Result) > 0 && response.Result[0].Detail != "error NotOwner(address caller)" {
+		t.Errorf("Expected the detail `error NotOwner(address caller)`, got %q", response.Result[0].Detail)
+	}
+}
+
+func Test_ReferenceDiagnostics(t *testing.T) {
+	s := newNamespaceState()
+
+	diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Vault.sol").Params.Diagnostics
+	expected := []lsp.Diagnostic{
+		{
+			// Only the member token is highlighted.
+			Range:   lsp.Range{Start: lsp.Position{Line: 16, Character: 22}, End: lsp.Position{Line: 16, Character: 27}},
+			Code:    "unresolved-member",
+			Message: "`Errors` has no member `Pausd`, did you mean `Paused`?",
+		},
+		{
+			Range:   lsp.Range{Start: lsp.Position{Line: 20, Character: 15}, End: lsp.Position{Line: 20, Character: 31}},
+			Code:    "invalid-revert",
+			Message: "`Events.Deposited` is not an error",
+		},
+	}
+
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d", len(expected), len(diagnostics))
+	}
+	for i, d := range diagnostics {
+		if d.Range != expected[i].Range || d.Code != expected[i].Code || d.Message != expected[i].Message {
+			t.Errorf("Expected %s %q at %v, got %s %q at %v",
+				expected[i].Code, expected[i].Message, expected[i].Range, d.Code, d.Message, d.Range)
+		}
+	}
+}
+
+var reexportWorkspace = map[string]string{
+	"file:///ws/src/Math.sol": `pragma solidity ^0.8.0;
+
+library Math {
+    function one() internal pure returns (uint256) { return 1; }
+}
+`,
+	"file:///ws/lib/Math.sol": `pragma solidity ^0.8.0;
+
+library Math {
+    function two() internal pure returns (uint256) { return 2; }
+}
+`,
+	"file:///ws/src/Base.sol": `pragma solidity ^0.8.0;
+
+import "./Math.sol";
+
+contract Base {}
+`,
+	// Math.sol is imported directly and re-exported by Base.sol.
+	"file:///ws/src/Token.sol": `pragma solidity ^0.8.0;
+
+import "./Base.sol";
+import "./Math.sol";
+
+contract Token is Base {
+    function f() external pure returns (uint256) {
+        return Math.one();
+    }
+}
+`,
+	"file:///ws/src/Counter.sol": `pragma solidity ^0.8.0;
+
+import "./Base.sol";
+// The only use of Base.sol is the Math library it re-exports.
+
+contract Counter {
+    function f() external pure returns (uint256) {
+        return Math.one();
+    }
+}
+`,
+	"file:///ws/src/Clash.sol": `pragma solidity ^0.8.0;
+
+import "./Math.sol";
+import "../lib/Math.sol";
+
+contract Clash {
+    function f() external pure returns (uint256) {
+        return Math.one();
+    }
+}
+`,
+}
+
+func Test_ReExportedImports(t *testing.T) {
+	s := NewState()
+	s.Root = "/ws"
+	for uri, src := range reexportWorkspace {
+		s.Documents[uri] = newDocument(uri, 0, false, src)
+	}
+
+	for _, uri := range []string{"file:///ws/src/Token.sol", "file:///ws/src/Counter.sol"} {
+		if diagnostics := s.Diagnostics(context.Background(), uri).Params.Diagnostics; len(diagnostics) != 0 {
+			t.Errorf("Expected no diagnostics in %s, got %v", uri, diagnostics)
+		}
+		definition := s.Definition(lsp.IntID(1), uri, lsp.Position{Line: 7, Character: 16}).Result
+		if definition == nil || len(*definition) != 1 || (*definition)[0].URI != "file:///ws/src/Math.sol" {
+			t.Errorf("Expected Math of %s to be defined in src/Math.sol, got %v", uri, definition)
+		}
+	}
+	if _, ok := s.OrganizeImports("file:///ws/src/Counter.sol"); ok {
+		t.Errorf("Expected the re-exporting import to be kept")
+	}
+
+	// The ambiguous name is reported where it's used, not at the imports.
+	diagnostics := s.Diagnostics(context.Background(), "file:///ws/src/Clash.sol").Params.Diagnostics
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	expected := lsp.Range{Start: lsp.Position{Line: 7, Character: 15}, End: lsp.Position{Line: 7, Character: 19}}
+	message := "`Math` is ambiguous: it is declared in src/Math.sol and lib/Math.sol"
+	if d := diagnostics[0]; d.Range != expected || d.Code != "ambiguous-import" || d.Message != message {
+		t.Errorf("Expected ambiguous-import %q at %v, got %s %q at %v", message, expected, d.Code, d.Message, d.Range)
+	}
+}
+
+// Test_ArgumentPositions resolves the identifiers in the positions outside
+// of the plain expressions: the arguments of the emit and revert
+// statements, of the modifier invocations and of the inheritance
+// specifiers, the array sizes and the values of the constants.
+func Test_ArgumentPositions(t *testing.T) {
+	src, err := os.ReadFile("testdata/positions/Vault.sol")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, string(src))
+	doc := s.Documents[uri]
+
+	tests := []struct {
+		name  string
+		at    string // source around the identifier under the cursor
+		ident string
+		line  uint // line of the declaration
+	}{
+		{"emit argument", "emit Deposited(msg.sender, amount)", "amount", 23},
+		{"revert argument", "revert TooLarge(amount, LIMIT)", "LIMIT", 4},
+		{"modifier argument", "atMost(amount, LIMIT)", "LIMIT", 4},
+		// The constructor parameters are not visible in the inheritance
+		// specifiers, even if they shadow the name in the contract.
+		{"inheritance argument", "is Ownable(initialOwner)", "initialOwner", 5},
+		{"array size", "uint256[SIZE] slots", "SIZE", 3},
+		{"constant value", "LIMIT = SIZE * 2", "SIZE", 3},
+	}
+	for _, tt := range tests {
+		at := strings.Index(string(src), tt.at)
+		if at < 0 {
+			t.Fatalf("Expected %q in the fixture", tt.at)
+		}
+		position := toLspPosition(doc.Handle, token.Pos(at+strings.Index(tt.at, tt.ident)))
+
+		if hover := s.Hover(lsp.IntID(1), uri, position).Result.Contents.Value; hover == "" {
+			t.Errorf("Expected the hover of the %s, got nothing", tt.name)
+		}
+		definition := s.Definition(lsp.IntID(2), uri, position).Result
+		if definition == nil || len(*definition) != 1 {
+			t.Errorf("Expected the definition of the %s, got %v", tt.name, definition)
+			continue
+		}
+		if line := (*definition)[0].Range.Start.Line; line != tt.line {
+			t.Errorf("Expected the %s to be declared at line %d, got %d", tt.name, tt.line, line)
+		}
+	}
+
+	// `SIZE` in `uint256[SIZE] slots`
+	at := strings.Index(string(src), "[SIZE]") + 1
+	edit, err := s.rename(uri, toLspPosition(doc.Handle, token.Pos(at)), "LENGTH")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(edit.Changes[uri]) != 3 {
+		t.Fatalf("Expected 3 edits, got %d", len(edit.Changes[uri]))
+	}
+	renamed := applyEdits(doc, edit.Changes[uri])
+	if !strings.Contains(renamed, "uint256[LENGTH] slots;") || !strings.Contains(renamed, "LIMIT = LENGTH * 2;") {
+		t.Errorf("Expected the array size and the constant value to be renamed, got:\n%s", renamed)
+	}
+}
@@ -0,0 +1,456 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// reference is an identifier that refers to one of the renamed declarations.
+type reference struct {
+	doc   *Document
+	ident *ast.Identifier
+	path  []ast.Node // path from the identifier up to the file; from the documented declaration for a docReference
+	kind  referenceKind
+}
+
+type referenceKind int
+
+const (
+	codeReference referenceKind = iota
+	docReference                // the argument of a NatSpec tag, see natSpecName
+)
+
+// Rename renames the symbol at the position in every indexed file. The
+// rename fails as a whole if the new name would conflict with an existing
+// declaration in any of the files, or if a dependency file would have to
+// be edited. The arguments of the NatSpec tags naming the symbol e.g.
+// `@param amount` are renamed with it. The string literals with the
+// signatures of the renamed functions and events e.g. in
+// abi.encodeWithSignature are updated in a separate group, see
+// renameSignatureStrings.
+func (s *State) Rename(id lsp.ID, uri string, position lsp.Position, newName string) lsp.RenameResponse {
+	edit, err := s.rename(uri, position, newName)
+	if err != nil {
+		return lsp.NewRenameErrorResponse(id, lsp.RequestFailed, err.Error())
+	}
+	return lsp.NewRenameResponse(id, edit)
+}
+
+func (s *State) rename(uri string, position lsp.Position, newName string) (*lsp.WorkspaceEdit, error) {
+	if !isValidIdentifier(newName) {
+		return nil, fmt.Errorf("`%s` is not a valid identifier", newName)
+	}
+
+	doc, ok := s.document(uri)
+	if !ok {
+		return nil, fmt.Errorf("unknown document %s", uri)
+	}
+	pos := toTokenPos(doc.Handle, position)
+	path := ast.PathEnclosingPos(doc.File, pos)
+	ident, ok := path[0].(*ast.Identifier)
+	var sym *Symbol
+	switch {
+	case ok:
+		sym = s.resolve(doc, path)
+	default:
+		name, ok := natSpecNameAt(doc, pos)
+		if !ok {
+			return nil, fmt.Errorf("there is no symbol to rename at the position")
+		}
+		ident, sym = name.ident, s.resolveNatSpecName(doc, name)
+	}
+	if sym == nil {
+		return nil, fmt.Errorf("cannot rename `%s`: the declaration can't be found", ident.Name)
+	}
+	oldName := sym.Name.Name
+	if newName == oldName {
+		return &lsp.WorkspaceEdit{}, nil
+	}
+
+	group := s.renameGroup(sym)
+	targets := map[*ast.Identifier]bool{}
+	for _, decl := range group {
+		targets[decl.Name] = true
+	}
+
+	refs := []reference{}
+	for _, doc := range s.sortedDocuments() {
+		inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+			if ident.Name != oldName {
+				return
+			}
+			if ref := s.resolve(doc, path); ref != nil && targets[ref.Name] {
+				refs = append(refs, reference{doc: doc, ident: ident, path: path})
+			}
+		})
+	}
+	refs = append(refs, s.natSpecReferencesTo(targets, oldName)...)
+
+	for _, ref := range refs {
+		if s.isDependency(ref.doc.URI) {
+			return nil, fmt.Errorf("cannot rename `%s`: it is used in the dependency file %s, which must not be edited",
+				oldName, s.location(ref.doc, ref.ident))
+		}
+	}
+	if conflict := s.findConflict(group, refs, newName); conflict != nil {
+		return nil, fmt.Errorf("cannot rename `%s` to `%s`: it conflicts with `%s` declared at %s",
+			oldName, newName, newName, s.location(conflict.Doc, conflict.Name))
+	}
+
+	edits := map[*Document][]lsp.TextEdit{}
+	for _, ref := range refs {
+		edits[ref.doc] = append(edits[ref.doc], lsp.TextEdit{
+			Range:   toLspRange(ref.doc.Handle, ast.NodeRange(ref.ident)),
+			NewText: newName,
+		})
+	}
+	signatures := s.renameSignatureStrings(group, newName, edits)
+	edit := s.workspaceEdit(sym, newName, edits)
+	if signatures {
+		if edit.ChangeAnnotations == nil {
+			edit.ChangeAnnotations = map[string]lsp.ChangeAnnotation{}
+		}
+		edit.ChangeAnnotations[signatureStringAnnotation] = lsp.ChangeAnnotation{
+			Label:             fmt.Sprintf("Update the signature strings of `%s`", oldName),
+			NeedsConfirmation: true,
+			Description:       "The string literals like \"" + oldName + "(...)\" match the renamed signature, but they may refer to something else.",
+		}
+	}
+	return edit, nil
+}
+
+// renameGroup returns the declarations that have to be renamed together
+// with the symbol. Functions, modifiers and public state variables are
+// overridden by name, so all of the same-named members of the base and the
+// derived contracts are renamed as well. It includes the overloads, since
+// the references to them can't be told apart.
+func (s *State) renameGroup(sym *Symbol) []*Symbol {
+	switch sym.Node.(type) {
+	case *ast.FunctionDeclaration, *ast.ModifierDeclaration, *ast.VariableDeclaration:
+	default:
+		return []*Symbol{sym}
+	}
+
+	contract := s.declaringContract(sym)
+	if contract == nil {
+		return []*Symbol{sym}
+	}
+
+	group := []*Symbol{}
+	for _, c := range s.relatedContracts(contract) {
+		for _, decl := range c.Node.(*ast.ContractDeclaration).Body {
+			switch decl.(type) {
+			case *ast.FunctionDeclaration, *ast.ModifierDeclaration, *ast.VariableDeclaration:
+				if id := declaredName(decl); id != nil && id.Name == sym.Name.Name {
+					group = append(group, &Symbol{Doc: c.Doc, Name: id, Node: decl})
+				}
+			}
+		}
+	}
+	return group
+}
+
+// findConflict returns an existing declaration of the new name that would
+// clash with the renamed symbols; or nil if there is none. The new name is
+// looked up at every reference, so that it's not captured by a different
+// declaration, and in the scopes that will contain the renamed declarations.
+func (s *State) findConflict(group []*Symbol, refs []reference, newName string) *Symbol {
+	for _, ref := range refs {
+		// The NatSpec names only what the code can see.
+		if ref.kind == docReference {
+			continue
+		}
+		if sym := s.lookupAt(ref, newName); sym != nil {
+			return sym
+		}
+	}
+
+	for _, decl := range group {
+		switch decl.Node.(type) {
+		case *ast.FunctionDeclaration, *ast.ModifierDeclaration, *ast.VariableDeclaration,
+			*ast.EventDeclaration, *ast.ErrorDeclaration, *ast.StructDeclaration,
+			*ast.EnumDeclaration, *ast.TypeDeclaration, *ast.ContractDeclaration:
+		default:
+			continue
+		}
+
+		// Members of the derived contracts are not visible from the base,
+		// but they would still clash with the renamed member.
+		if contract := s.declaringContract(decl); contract != nil {
+			for _, c := range s.relatedContracts(contract) {
+				for _, member := range c.Node.(*ast.ContractDeclaration).Body {
+					if id := declaredName(member); id != nil && id.Name == newName {
+						return &Symbol{Doc: c.Doc, Name: id, Node: member}
+					}
+				}
+			}
+			continue
+		}
+
+		// Top-level declarations clash in every file that can see them.
+		for _, doc := range s.sortedDocuments() {
+			if sym := s.lookupFile(doc, decl.Name.Name, map[*Document]bool{}); sym == nil || sym.Name != decl.Name {
+				continue
+			}
+			if sym := s.lookupFile(doc, newName, map[*Document]bool{}); sym != nil {
+				return sym
+			}
+		}
+	}
+	return nil
+}
+
+// lookupAt returns the declaration of the name that would be visible at the
+// reference if it was renamed.
+func (s *State) lookupAt(ref reference, name string) *Symbol {
+	doc, path := ref.doc, ref.path
+	switch parent := path[1].(type) {
+	case *ast.MemberAccessExpression:
+		if parent.Member == ref.ident {
+			if sym := s.member(s.scopeOf(doc, path[2:], parent.Expression), name); sym != nil {
+				return sym
+			}
+			return s.attachedFunction(doc, path[2:], name)
+		}
+	case *ast.ImportSymbol:
+		if parent.Name == ref.ident && parent.Alias != nil {
+			// The alias is the local name, the imported name only has to
+			// be unique in the imported file, which is checked separately.
+			return nil
+		}
+		return s.lookupFile(doc, name, map[*Document]bool{})
+	case *ast.CallExpression:
+		for _, arg := range parent.Names {
+			if arg == ref.ident {
+				return s.namedArgument(doc, path[2:], parent.Function, name)
+			}
+		}
+	}
+
+	// A declaration is looked up in the scope that contains it.
+	if declaredName(path[1]) == ref.ident {
+		return s.lookup(doc, path[2:], name, ref.ident.Start())
+	}
+	return s.lookup(doc, path[1:], name, ref.ident.Start())
+}
+
+// workspaceEdit builds the edit in the form supported by the client.
+func (s *State) workspaceEdit(sym *Symbol, newName string, edits map[*Document][]lsp.TextEdit) *lsp.WorkspaceEdit {
+	if !s.workspaceEditCapabilities().DocumentChanges {
+		return s.documentEdits(edits)
+	}
+
+	// Offering the file rename adds the import path edits.
+	renameFile := s.offerFileRename(sym, newName, edits)
+	res := s.documentEdits(edits)
+
+	// The file is renamed after its content was edited.
+	if renameFile != nil {
+		res.DocumentChanges = append(res.DocumentChanges, *renameFile)
+		res.ChangeAnnotations = map[string]lsp.ChangeAnnotation{
+			renameFileAnnotation: {
+				Label:             fmt.Sprintf("Rename %s to %s", path.Base(URIToPath(renameFile.OldURI)), path.Base(URIToPath(renameFile.NewURI))),
+				NeedsConfirmation: true,
+				Description:       "The file name matches the renamed contract.",
+			},
+		}
+	}
+	return res
+}
+
+// documentEdits builds the edit of the documents in the form supported by
+// the client, ordered by their URIs.
+func (s *State) documentEdits(edits map[*Document][]lsp.TextEdit) *lsp.WorkspaceEdit {
+	if !s.workspaceEditCapabilities().DocumentChanges {
+		res := &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{}}
+		for doc, docEdits := range edits {
+			res.Changes[doc.URI] = docEdits
+		}
+		return res
+	}
+
+	docs := make([]*Document, 0, len(edits))
+	for doc := range edits {
+		docs = append(docs, doc)
+	}
+	slices.SortFunc(docs, func(a, b *Document) int { return strings.Compare(a.URI, b.URI) })
+
+	res := &lsp.WorkspaceEdit{}
+	for _, doc := range docs {
+		var version *int
+		if doc.Open {
+			v := doc.Version
+			version = &v
+		}
+		res.DocumentChanges = append(res.DocumentChanges, lsp.TextDocumentEdit{
+			TextDocument: lsp.OptionalVersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: doc.URI},
+				Version:                version,
+			},
+			Edits: edits[doc],
+		})
+	}
+	return res
+}
+
+const renameFileAnnotation = "renameFile"
+
+// offerFileRename returns the operation renaming the file of the contract
+// if the file is named after it e.g. IVault.sol for IVault. The import
+// paths pointing to the file are added to the edits. The operation needs
+// a confirmation from the user, so it's only offered if the client
+// supports both the resource operations and the change annotations.
+func (s *State) offerFileRename(sym *Symbol, newName string, edits map[*Document][]lsp.TextEdit) *lsp.RenameFile {
+	if _, ok := sym.Node.(*ast.ContractDeclaration); !ok {
+		return nil
+	}
+	caps := s.workspaceEditCapabilities()
+	if !slices.Contains(caps.ResourceOperations, "rename") || caps.ChangeAnnotationSupport == nil {
+		return nil
+	}
+
+	oldPath := URIToPath(sym.Doc.URI)
+	if path.Base(oldPath) != sym.Name.Name+".sol" {
+		return nil
+	}
+	newPath := path.Join(path.Dir(oldPath), newName+".sol")
+	newURI := PathToURI(newPath)
+	if _, exists := s.Documents[newURI]; exists {
+		return nil
+	}
+
+	for _, doc := range s.sortedDocuments() {
+		for _, decl := range doc.File.Declarations {
+			imp, ok := decl.(*ast.ImportDirective)
+			if !ok || s.ImportTarget(doc, imp) != sym.Doc {
+				continue
+			}
+			value := imp.Path.Value
+			quote, importPath := value[:1], value[1:len(value)-1]
+			importPath = s.importPathTo(URIToPath(doc.URI), importPath, oldPath, newPath)
+			edits[doc] = append(edits[doc], lsp.TextEdit{
+				Range:        toLspRange(doc.Handle, ast.NodeRange(imp.Path)),
+				NewText:      quote + importPath + quote,
+				AnnotationID: renameFileAnnotation,
+			})
+		}
+	}
+
+	return &lsp.RenameFile{
+		Kind:         "rename",
+		OldURI:       sym.Doc.URI,
+		NewURI:       newURI,
+		AnnotationID: renameFileAnnotation,
+	}
+}
+
+func (s *State) workspaceEditCapabilities() lsp.WorkspaceEditClientCapabilities {
+	workspace := s.Capabilities.Workspace
+	if workspace == nil || workspace.WorkspaceEdit == nil {
+		return lsp.WorkspaceEditClientCapabilities{}
+	}
+	return *workspace.WorkspaceEdit
+}
+
+// declaringContract returns the contract of which the symbol is a direct
+// member; or nil if it's declared elsewhere.
+func (s *State) declaringContract(sym *Symbol) *Symbol {
+	path := ast.PathEnclosingPos(sym.Doc.File, sym.Name.Start())
+	for i, node := range path {
+		if node != sym.Node {
+			continue
+		}
+		if i+1 < len(path) {
+			if c, ok := path[i+1].(*ast.ContractDeclaration); ok {
+				return &Symbol{Doc: sym.Doc, Name: c.Name, Node: c}
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// relatedContracts returns the contract together with all of its bases and
+// all of the contracts in the workspace that derive from it.
+func (s *State) relatedContracts(contract *Symbol) []*Symbol {
+	res := []*Symbol{}
+	seen := map[*ast.ContractDeclaration]bool{}
+	add := func(c *Symbol) {
+		if decl := c.Node.(*ast.ContractDeclaration); !seen[decl] {
+			seen[decl] = true
+			res = append(res, c)
+		}
+	}
+
+	for _, c := range s.ancestors(contract) {
+		add(c)
+	}
+	for _, doc := range s.sortedDocuments() {
+		for _, decl := range doc.File.Declarations {
+			c, ok := decl.(*ast.ContractDeclaration)
+			if !ok {
+				continue
+			}
+			derived := &Symbol{Doc: doc, Name: c.Name, Node: c}
+			for _, base := range s.ancestors(derived) {
+				if base.Node == contract.Node {
+					add(derived)
+					break
+				}
+			}
+		}
+	}
+	return res
+}
+
+// ancestors returns the contract and all of its direct and indirect bases.
+func (s *State) ancestors(contract *Symbol) []*Symbol {
+	res := []*Symbol{contract}
+	seen := map[ast.Node]bool{contract.Node: true}
+	for i := 0; i < len(res); i++ {
+		c := res[i]
+		for _, base := range s.bases(c.Doc, c.Node.(*ast.ContractDeclaration)) {
+			if !seen[base.Node] {
+				seen[base.Node] = true
+				res = append(res, base)
+			}
+		}
+	}
+	return res
+}
+
+// sortedDocuments returns the documents ordered by their URIs, so that the
+// results don't depend on the map iteration order.
+func (s *State) sortedDocuments() []*Document {
+	docs := make([]*Document, 0, len(s.Documents))
+	for _, doc := range s.Documents {
+		docs = append(docs, s.use(doc))
+	}
+	slices.SortFunc(docs, func(a, b *Document) int { return strings.Compare(a.URI, b.URI) })
+	return docs
+}
+
+// location formats the position of the identifier for the messages shown
+// to the user e.g. src/Vault.sol:12:9.
+func (s *State) location(doc *Document, ident *ast.Identifier) string {
+	pos := doc.Handle.Position(ident.Start())
+	return fmt.Sprintf("%s:%d:%d", s.RelativePath(doc.URI), pos.Line, pos.Column)
+}
+
+func isValidIdentifier(name string) bool {
+	if name == "" || token.LookupIdent(name) != token.IDENTIFIER {
+		return false
+	}
+	for i, ch := range name {
+		isLetter := 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch == '$'
+		if !isLetter && (i == 0 || ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return true
+}
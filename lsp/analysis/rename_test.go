@@ -0,0 +1,297 @@
+package analysis
+
+import (
+	"slices"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+var renameWorkspace = map[string]string{
+	"file:///ws/src/IVault.sol": `pragma solidity ^0.8.0;
+
+interface IVault {
+    function deposit(uint256 amount) external;
+}
+`,
+	"file:///ws/src/Vault.sol": `pragma solidity ^0.8.0;
+
+import {IVault} from "./IVault.sol";
+import {Ownable} from "../lib/oz/Ownable.sol";
+
+contract Vault is IVault, Ownable {
+    uint256 total;
+
+    function deposit(uint256 amount) external override(IVault) {
+        total += amount;
+    }
+}
+`,
+	"file:///ws/src/Router.sol": `pragma solidity ^0.8.0;
+
+import {IVault as Target} from "./IVault.sol";
+import "./Vault.sol";
+
+library VaultLib {
+    function depositAll(IVault vault, uint256 amount) internal {
+        vault.deposit(amount);
+    }
+}
+
+contract Router {
+    using VaultLib for IVault;
+
+    Target target;
+
+    function route(uint256 amount) external {
+        target.deposit(amount);
+        IVault(address(target)).depositAll(amount);
+    }
+}
+`,
+	"file:///ws/lib/oz/Ownable.sol": `pragma solidity ^0.8.0;
+
+contract Ownable {
+    address owner;
+}
+`,
+}
+
+func newRenameState(t *testing.T) *State {
+	s := NewState()
+	s.Root = "/ws"
+	for uri, src := range renameWorkspace {
+		s.Documents[uri] = newDocument(uri, 0, false, src)
+	}
+	// One of the documents is open in the editor.
+	s.OpenDocument("file:///ws/src/Vault.sol", 7, renameWorkspace["file:///ws/src/Vault.sol"])
+
+	for _, doc := range s.sortedDocuments() {
+		if unresolved := s.unresolvedIdentifiers(doc); len(unresolved) > 0 {
+			t.Fatalf("Expected no unresolved identifiers in %s, got %s", doc.URI, unresolved[0].Name)
+		}
+	}
+	return s
+}
+
+func Test_RenameAcrossWorkspace(t *testing.T) {
+	s := newRenameState(t)
+	s.Capabilities.Workspace = &lsp.WorkspaceClientCapabilities{
+		WorkspaceEdit: &lsp.WorkspaceEditClientCapabilities{
+			DocumentChanges:         true,
+			ResourceOperations:      []string{"create", "rename", "delete"},
+			ChangeAnnotationSupport: &lsp.ChangeAnnotationSupport{},
+		},
+	}
+
+	// `IVault` in `contract Vault is IVault`
+	edit, err := s.rename("file:///ws/src/Vault.sol", lsp.Position{Line: 5, Character: 20}, "IVaultV2")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	expectedEdits := map[string]int{
+		"file:///ws/src/IVault.sol": 1,
+		"file:///ws/src/Router.sol": 5, // import symbol, param, using-for, conversion and the import path
+		"file:///ws/src/Vault.sol":  4, // import symbol, base, override and the import path
+	}
+
+	var renameFile *lsp.RenameFile
+	files := map[string]string{}
+	for uri, src := range renameWorkspace {
+		files[uri] = src
+	}
+	for _, change := range edit.DocumentChanges {
+		switch change := change.(type) {
+		case lsp.TextDocumentEdit:
+			uri := change.TextDocument.URI
+			if len(change.Edits) != expectedEdits[uri] {
+				t.Errorf("Expected %d edits in %s, got %d", expectedEdits[uri], uri, len(change.Edits))
+			}
+			if uri == "file:///ws/src/Vault.sol" {
+				if v := change.TextDocument.Version; v == nil || *v != 7 {
+					t.Errorf("Expected version 7 of the open document, got %v", v)
+				}
+			} else if change.TextDocument.Version != nil {
+				t.Errorf("Expected no version for %s, got %d", uri, *change.TextDocument.Version)
+			}
+			files[uri] = applyEdits(s.Documents[uri], change.Edits)
+		case lsp.RenameFile:
+			renameFile = &change
+		}
+	}
+
+	if len(edit.DocumentChanges) != len(expectedEdits)+1 {
+		t.Fatalf("Expected %d document changes, got %d", len(expectedEdits)+1, len(edit.DocumentChanges))
+	}
+	if renameFile == nil {
+		t.Fatalf("Expected the file rename to be offered, got nil")
+	}
+	if renameFile.NewURI != "file:///ws/src/IVaultV2.sol" {
+		t.Errorf("Expected new URI file:///ws/src/IVaultV2.sol, got %s", renameFile.NewURI)
+	}
+	if !edit.ChangeAnnotations[renameFile.AnnotationID].NeedsConfirmation {
+		t.Errorf("Expected the file rename to need a confirmation")
+	}
+	files[renameFile.NewURI] = files[renameFile.OldURI]
+	delete(files, renameFile.OldURI)
+
+	if !strings.Contains(files["file:///ws/src/Router.sol"], `import {IVaultV2 as Target} from "./IVaultV2.sol";`) {
+		t.Errorf("Expected the aliased import to be renamed, got:\n%s", files["file:///ws/src/Router.sol"])
+	}
+
+	// The whole workspace has to resolve after the rename.
+	renamed := NewState()
+	renamed.Root = "/ws"
+	for uri, src := range files {
+		renamed.Documents[uri] = newDocument(uri, 0, false, src)
+	}
+	for _, doc := range renamed.sortedDocuments() {
+		if unresolved := renamed.unresolvedIdentifiers(doc); len(unresolved) > 0 {
+			t.Errorf("Expected no unresolved identifiers in %s, got %s", doc.URI, unresolved[0].Name)
+		}
+		if strings.Contains(files[doc.URI], "IVault ") {
+			t.Errorf("Expected no references to IVault in %s, got:\n%s", doc.URI, files[doc.URI])
+		}
+	}
+}
+
+func Test_RenameWithoutDocumentChanges(t *testing.T) {
+	s := newRenameState(t)
+
+	// `deposit` in the interface is implemented by the Vault contract.
+	edit, err := s.rename("file:///ws/src/IVault.sol", lsp.Position{Line: 3, Character: 14}, "deposit2")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	expectedEdits := map[string]int{
+		"file:///ws/src/IVault.sol": 1,
+		"file:///ws/src/Router.sol": 2,
+		"file:///ws/src/Vault.sol":  1,
+	}
+
+	if len(edit.DocumentChanges) != 0 {
+		t.Errorf("Expected no document changes, got %d", len(edit.DocumentChanges))
+	}
+	for uri, count := range expectedEdits {
+		if len(edit.Changes[uri]) != count {
+			t.Errorf("Expected %d edits in %s, got %d", count, uri, len(edit.Changes[uri]))
+		}
+	}
+}
+
+func Test_RenameImportAlias(t *testing.T) {
+	s := newRenameState(t)
+	router := "file:///ws/src/Router.sol"
+
+	// The hover of the alias shows the aliased interface.
+	hover := s.Hover(lsp.IntID(1), router, lsp.Position{Line: 14, Character: 4}).Result.Contents.Value
+	if !strings.Contains(hover, "interface IVault") || !strings.Contains(hover, "Declared in src/IVault.sol") {
+		t.Errorf("Expected the hover of IVault, got %q", hover)
+	}
+
+	// `Target` in `Target target;` is renamed in the importing file only.
+	edit, err := s.rename(router, lsp.Position{Line: 14, Character: 4}, "Destination")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(edit.Changes) != 1 || len(edit.Changes[router]) != 2 {
+		t.Fatalf("Expected 2 edits in %s only, got %v", router, edit.Changes)
+	}
+	renamed := applyEdits(s.Documents[router], edit.Changes[router])
+	for _, line := range []string{`import {IVault as Destination} from "./IVault.sol";`, "Destination target;"} {
+		if !strings.Contains(renamed, line) {
+			t.Errorf("Expected the renamed source to contain %q, got:\n%s", line, renamed)
+		}
+	}
+
+	// The aliased interface keeps the alias, only its imported name changes.
+	edit, err = s.rename(router, lsp.Position{Line: 2, Character: 9}, "IPool")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	renamed = applyEdits(s.Documents[router], edit.Changes[router])
+	if !strings.Contains(renamed, `import {IPool as Target} from "./IVault.sol";`) || !strings.Contains(renamed, "Target target;") {
+		t.Errorf("Expected the alias to be kept, got:\n%s", renamed)
+	}
+	if len(edit.Changes["file:///ws/src/IVault.sol"]) != 1 {
+		t.Errorf("Expected the interface to be renamed, got %v", edit.Changes["file:///ws/src/IVault.sol"])
+	}
+}
+
+func Test_RenameFailures(t *testing.T) {
+	s := newRenameState(t)
+
+	tests := []struct {
+		name     string
+		uri      string
+		position lsp.Position
+		newName  string
+		expected string
+	}{
+		{
+			"conflict in another file",
+			"file:///ws/src/IVault.sol", lsp.Position{Line: 2, Character: 10}, "Router",
+			"conflicts with `Router` declared at src/Router.sol:12:10",
+		},
+		{
+			"conflict with a state variable",
+			"file:///ws/src/Vault.sol", lsp.Position{Line: 8, Character: 30}, "total",
+			"conflicts with `total` declared at src/Vault.sol:7:13",
+		},
+		{
+			"declared in a dependency",
+			"file:///ws/src/Vault.sol", lsp.Position{Line: 5, Character: 28}, "Owned",
+			"dependency file lib/oz/Ownable.sol:3:10",
+		},
+		{
+			"invalid identifier",
+			"file:///ws/src/Vault.sol", lsp.Position{Line: 6, Character: 13}, "uint256",
+			"not a valid identifier",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := s.rename(tt.uri, tt.position, tt.newName)
+		if err == nil {
+			t.Errorf("%s: expected an error, got nil", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %q", tt.name, tt.expected, err)
+		}
+	}
+}
+
+// applyEdits returns the content of the document with the edits applied.
+// The insertions at the same position end up in the order of the edits,
+// before the text replacing the range starting there.
+func applyEdits(doc *Document, edits []lsp.TextEdit) string {
+	src := doc.Handle.Src()
+	order := make([]int, len(edits))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		a, b := edits[i].Range, edits[j].Range
+		if a.Start != b.Start {
+			return int(toTokenPos(doc.Handle, b.Start) - toTokenPos(doc.Handle, a.Start))
+		}
+		if (a.Start == a.End) != (b.Start == b.End) {
+			// The replacement is applied first.
+			if a.Start == a.End {
+				return 1
+			}
+			return -1
+		}
+		return j - i
+	})
+	for _, i := range order {
+		edit := edits[i]
+		start := toTokenPos(doc.Handle, edit.Range.Start)
+		end := toTokenPos(doc.Handle, edit.Range.End)
+		src = src[:start] + edit.NewText + src[end:]
+	}
+	return src
+}
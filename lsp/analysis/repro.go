@@ -0,0 +1,476 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/textedit"
+	"solbot/token"
+	"strings"
+)
+
+// The bug reports and the findings shared with a teammate need the sources
+// reproducing a diagnostic, without the rest of the workspace. Repro
+// flattens the file of the diagnostic and the ones it imports into a single
+// directory, and reduces them declaration by declaration, analyzing the
+// reduced sources after every step to keep only the reductions that don't
+// lose the diagnostic.
+
+// reproRoot is the directory the reduced sources are analyzed in.
+const reproRoot = "/repro"
+
+// Repro is a reduced set of the sources reproducing a diagnostic.
+type Repro struct {
+	Files      map[string]string // the reduced sources by their names in the output directory e.g. "Vault.sol"
+	Origins    map[string]string // the paths of the files relative to the workspace root, by their names
+	Target     string            // the name of the file with the diagnostic
+	Diagnostic lsp.Diagnostic    // the diagnostic in the reduced sources
+	Removed    []string          // the reductions kept, in order e.g. "the function `Vault.withdraw` of src/Vault.sol"
+	BackedOut  []string          // the reductions backed out, they lose the diagnostic
+	Runs       int               // the analyses of the reduced sources
+}
+
+// reduction removes a declaration or replaces the body of a function with a
+// stub, in one of the files.
+type reduction struct {
+	uri         string
+	edit        textedit.Edit
+	description string
+}
+
+type reducer struct {
+	s       *State
+	ctx     context.Context
+	docs    []*Document       // the file of the diagnostic and the ones it imports, transitively
+	names   map[string]string // the names of the files in the output directory, by their URIs
+	target  lsp.Diagnostic    // the diagnostic in the original sources
+	kept    []reduction
+	repro   *Repro
+	matched lsp.Diagnostic // the diagnostic found by the last successful run
+}
+
+// Repro returns the sources reproducing the diagnostic with the code at the
+// line of the document: the document and the ones it imports, with their
+// imports rewritten to the flat layout e.g. `import "./Types.sol";`. They
+// are reduced in the steps below, and each step is analyzed again: if the
+// diagnostic is lost, the step is split in halves that are tried on their
+// own, down to the single reductions, which are backed out.
+//
+//  1. The declarations of the imported files that the document doesn't
+//     reference, directly or through the declarations it references.
+//  2. The declarations and the members of the contracts.
+//  3. The imports, with the files imported only by them.
+//  4. The bodies of the functions and of the modifiers, replaced with a
+//     revert and a placeholder, so that the signatures still resolve.
+//
+// The diagnostic is found in the reduced sources by its code and its
+// message, its line changes as the code is removed.
+func (s *State) Repro(ctx context.Context, uri string, line uint, code string) (*Repro, error) {
+	doc, ok := s.document(uri)
+	if !ok {
+		return nil, fmt.Errorf("%s is not indexed", s.RelativePath(uri))
+	}
+	r := &reducer{s: s, ctx: ctx, names: map[string]string{}, repro: &Repro{Origins: map[string]string{}}}
+	found := false
+	for _, d := range s.Diagnostics(ctx, uri).Params.Diagnostics {
+		if d.Code == code && d.Range.Start.Line == line {
+			r.target, found = d, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("No %s diagnostic at %s:%d", code, s.RelativePath(uri), line+1)
+	}
+
+	// The imported files, in the order they are found.
+	r.docs = []*Document{doc}
+	for i := 0; i < len(r.docs); i++ {
+		for _, decl := range r.docs[i].File.Declarations {
+			if imp, ok := decl.(*ast.ImportDirective); ok {
+				if target := s.ImportTarget(r.docs[i], imp); target != nil && !slices.Contains(r.docs, target) {
+					r.docs = append(r.docs, target)
+				}
+			}
+		}
+	}
+	taken := map[string]bool{}
+	for _, d := range r.docs {
+		base := path.Base(URIToPath(d.URI))
+		name := base
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(base, ".sol"), n, path.Ext(base))
+		}
+		taken[name] = true
+		r.names[d.URI] = name
+		r.repro.Origins[name] = s.RelativePath(d.URI)
+	}
+	r.repro.Target = r.names[doc.URI]
+	if !r.verify(nil) {
+		return nil, fmt.Errorf("The %s diagnostic is not reproduced by the flattened sources", code)
+	}
+
+	r.reduce(r.unreferenced())
+	r.reduce(r.pending(r.declarations()))
+	r.reduce(r.pending(r.imports()))
+	r.reduce(r.pending(r.stubs()))
+
+	r.repro.Files = r.render(r.kept)
+	for _, d := range r.docs {
+		if _, ok := r.repro.Files[r.names[d.URI]]; !ok {
+			r.repro.Removed = append(r.repro.Removed, "the file "+s.RelativePath(d.URI))
+			delete(r.repro.Origins, r.names[d.URI])
+		}
+	}
+	r.verify(r.kept)
+	r.repro.Diagnostic = r.matched
+	return r.repro, nil
+}
+
+// reduce applies the reductions if the diagnostic is still reproduced;
+// otherwise it tries both halves of them on their own.
+func (r *reducer) reduce(reductions []reduction) {
+	if len(reductions) == 0 || r.ctx.Err() != nil {
+		return
+	}
+	candidate := append(slices.Clone(r.kept), reductions...)
+	if r.verify(candidate) {
+		r.kept = candidate
+		for _, red := range reductions {
+			r.repro.Removed = append(r.repro.Removed, red.description)
+		}
+		return
+	}
+	if len(reductions) == 1 {
+		r.repro.BackedOut = append(r.repro.BackedOut, reductions[0].description)
+		return
+	}
+	mid := len(reductions) / 2
+	r.reduce(reductions[:mid])
+	r.reduce(r.pending(reductions[mid:]))
+}
+
+// verify reports whether the sources reduced by the reductions still have
+// the diagnostic.
+func (r *reducer) verify(reductions []reduction) bool {
+	r.repro.Runs++
+	state := NewState()
+	state.Root = reproRoot
+	state.Config = r.s.Config
+	for name, src := range r.render(reductions) {
+		state.OpenDocument(PathToURI(path.Join(reproRoot, name)), 1, src)
+	}
+	for _, d := range state.Diagnostics(r.ctx, PathToURI(path.Join(reproRoot, r.repro.Target))).Params.Diagnostics {
+		if d.Code == r.target.Code && d.Message == r.target.Message {
+			r.matched = d
+			return true
+		}
+	}
+	return false
+}
+
+// render returns the sources with the reductions applied and the imports
+// rewritten, by their names. The files no longer imported from the file of
+// the diagnostic are left out.
+func (r *reducer) render(reductions []reduction) map[string]string {
+	res := map[string]string{}
+	removed := map[string][]token.Range{}
+	for _, red := range reductions {
+		if red.edit.NewText == "" {
+			removed[red.uri] = append(removed[red.uri], red.edit.Range)
+		}
+	}
+	// The edits inside the removals are left out, except for the removals
+	// themselves.
+	isRemoved := func(uri string, rng, except token.Range) bool {
+		for _, other := range removed[uri] {
+			if other != except && other.ContainsRange(rng) {
+				return true
+			}
+		}
+		return false
+	}
+
+	reached := map[string]bool{r.docs[0].URI: true}
+	queue := []*Document{r.docs[0]}
+	for len(queue) > 0 {
+		doc := queue[0]
+		queue = queue[1:]
+		edits := []textedit.Edit{}
+		for _, red := range reductions {
+			if red.uri == doc.URI && !isRemoved(doc.URI, red.edit.Range, red.edit.Range) {
+				edits = append(edits, red.edit)
+			}
+		}
+		for _, decl := range doc.File.Declarations {
+			imp, ok := decl.(*ast.ImportDirective)
+			if !ok || imp.Path == nil || isRemoved(doc.URI, ast.NodeRange(imp), token.Range{}) {
+				continue
+			}
+			target := r.s.ImportTarget(doc, imp)
+			if target == nil || r.names[target.URI] == "" {
+				continue
+			}
+			edits = append(edits, textedit.Edit{Range: ast.NodeRange(imp.Path), NewText: fmt.Sprintf("%q", "./"+r.names[target.URI])})
+			if !reached[target.URI] {
+				reached[target.URI] = true
+				queue = append(queue, target)
+			}
+		}
+		res[r.names[doc.URI]] = collapseBlankLines(textedit.Apply(doc.Handle.Src(), edits))
+	}
+	return res
+}
+
+// collapseBlankLines returns the source with the runs of the blank lines
+// left by the removals collapsed into one, and without the blank lines at
+// the end.
+func collapseBlankLines(src string) string {
+	lines := strings.Split(strings.TrimRight(src, " \t\r\n"), "\n")
+	res := []string{}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" && i > 0 && strings.TrimSpace(lines[i-1]) == "" {
+			continue
+		}
+		res = append(res, line)
+	}
+	return strings.Join(res, "\n") + "\n"
+}
+
+// pending returns the reductions not inside the removals kept e.g. not the
+// members of a removed contract.
+func (r *reducer) pending(reductions []reduction) []reduction {
+	res := []reduction{}
+	for _, red := range reductions {
+		inside := false
+		for _, kept := range r.kept {
+			if kept.uri == red.uri && kept.edit.NewText == "" && kept.edit.Range.ContainsRange(red.edit.Range) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			res = append(res, red)
+		}
+	}
+	return res
+}
+
+// unreferenced returns the removals of the declarations of the imported
+// files that the file of the diagnostic doesn't reference, directly or
+// through the declarations it references.
+func (r *reducer) unreferenced() []reduction {
+	topLevel := func(sym *Symbol) ast.Declaration {
+		for _, decl := range sym.Doc.File.Declarations {
+			if ast.NodeRange(decl).Contains(sym.Name.NamePos) {
+				return decl
+			}
+		}
+		return nil
+	}
+	references := map[ast.Node][]ast.Declaration{}
+	for _, doc := range r.docs {
+		inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+			sym := r.s.follow(r.s.resolve(doc, path))
+			if sym == nil || sym.Name == ident {
+				return
+			}
+			from, to := path[len(path)-2], topLevel(sym)
+			if to != nil && from != to {
+				references[from] = append(references[from], to)
+			}
+		})
+	}
+	referenced := map[ast.Node]bool{}
+	queue := slices.Clone(r.docs[0].File.Declarations)
+	for len(queue) > 0 {
+		decl := queue[0]
+		queue = queue[1:]
+		if referenced[decl] {
+			continue
+		}
+		referenced[decl] = true
+		queue = append(queue, references[decl]...)
+	}
+
+	res := []reduction{}
+	for _, doc := range r.docs[1:] {
+		for _, decl := range doc.File.Declarations {
+			if !referenced[decl] && isReducible(decl) {
+				res = append(res, r.removal(doc, decl, nil))
+			}
+		}
+	}
+	return res
+}
+
+// declarations returns the removals of the declarations of the files and
+// of the members of their contracts.
+func (r *reducer) declarations() []reduction {
+	res := []reduction{}
+	for _, doc := range r.docs {
+		for _, decl := range doc.File.Declarations {
+			if isReducible(decl) {
+				res = append(res, r.removal(doc, decl, nil))
+			}
+		}
+	}
+	for _, doc := range r.docs {
+		for _, decl := range doc.File.Declarations {
+			if contract, ok := decl.(*ast.ContractDeclaration); ok {
+				for _, member := range contract.Body {
+					res = append(res, r.removal(doc, member, contract))
+				}
+			}
+		}
+	}
+	return res
+}
+
+// imports returns the removals of the import directives.
+func (r *reducer) imports() []reduction {
+	res := []reduction{}
+	for _, doc := range r.docs {
+		for _, decl := range doc.File.Declarations {
+			if imp, ok := decl.(*ast.ImportDirective); ok {
+				res = append(res, r.removal(doc, imp, nil))
+			}
+		}
+	}
+	return res
+}
+
+// stubs returns the replacements of the bodies of the functions and of the
+// modifiers with a revert and a placeholder.
+func (r *reducer) stubs() []reduction {
+	res := []reduction{}
+	stub := func(doc *Document, decl ast.Node, contract *ast.ContractDeclaration) {
+		var body *ast.BlockStatement
+		text := "{ revert(); }"
+		switch n := decl.(type) {
+		case *ast.FunctionDeclaration:
+			body = n.Body
+		case *ast.ModifierDeclaration:
+			body, text = n.Body, "{ _; }"
+		}
+		if body != nil && len(body.Statements) > 0 {
+			res = append(res, reduction{
+				uri:         doc.URI,
+				edit:        textedit.Edit{Range: ast.NodeRange(body), NewText: text},
+				description: "the body of " + r.describe(doc, decl, contract),
+			})
+		}
+	}
+	for _, doc := range r.docs {
+		for _, decl := range doc.File.Declarations {
+			contract, ok := decl.(*ast.ContractDeclaration)
+			if !ok {
+				stub(doc, decl, nil)
+				continue
+			}
+			for _, member := range contract.Body {
+				stub(doc, member, contract)
+			}
+		}
+	}
+	return res
+}
+
+// removal returns the removal of the declaration with its NatSpec, its
+// semicolon and the rest of its lines, if it's alone on them.
+func (r *reducer) removal(doc *Document, decl ast.Node, contract *ast.ContractDeclaration) reduction {
+	src := doc.Handle.Src()
+	rng := ast.NodeRange(decl)
+	if comments := natSpecComments(doc, decl); len(comments) > 0 {
+		rng.Start = comments[0].Start()
+	}
+	end := int(rng.End)
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	if end < len(src) && src[end] == ';' {
+		rng.End = token.Pos(end + 1)
+	}
+	start := int(rng.Start)
+	for start > 0 && (src[start-1] == ' ' || src[start-1] == '\t') {
+		start--
+	}
+	end = int(rng.End)
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t' || src[end] == '\r') {
+		end++
+	}
+	if (start == 0 || src[start-1] == '\n') && (end == len(src) || src[end] == '\n') {
+		rng.Start = token.Pos(start)
+		rng.End = token.Pos(min(end+1, len(src)))
+	}
+	return reduction{uri: doc.URI, edit: textedit.Edit{Range: rng}, description: r.describe(doc, decl, contract)}
+}
+
+// describe returns the declaration for the manifest e.g. "the function
+// `Vault.withdraw` of src/Vault.sol".
+func (r *reducer) describe(doc *Document, decl ast.Node, contract *ast.ContractDeclaration) string {
+	file := r.s.RelativePath(doc.URI)
+	if imp, ok := decl.(*ast.ImportDirective); ok && imp.Path != nil {
+		return fmt.Sprintf("the import of %s in %s", imp.Path.Value, file)
+	}
+	kind := declarationKind(decl)
+	name := declaredName(decl)
+	if name == nil {
+		return fmt.Sprintf("the %s at %s:%d", kind, file, doc.Handle.Position(decl.Start()).Line)
+	}
+	qualified := name.Name
+	if contract != nil {
+		qualified = contract.Name.Name + "." + qualified
+		if kind == "variable" {
+			kind = "state variable"
+		}
+	}
+	return fmt.Sprintf("the %s `%s` of %s", kind, qualified, file)
+}
+
+// declarationKind returns the kind of the declaration e.g. "function" or
+// "state variable".
+func declarationKind(decl ast.Node) string {
+	switch n := decl.(type) {
+	case *ast.ContractDeclaration:
+		switch n.Kind {
+		case token.INTERFACE:
+			return "interface"
+		case token.LIBRARY:
+			return "library"
+		}
+		return "contract"
+	case *ast.FunctionDeclaration:
+		if n.Name == nil {
+			return "special function"
+		}
+		return "function"
+	case *ast.ModifierDeclaration:
+		return "modifier"
+	case *ast.EventDeclaration:
+		return "event"
+	case *ast.ErrorDeclaration:
+		return "error"
+	case *ast.StructDeclaration:
+		return "struct"
+	case *ast.EnumDeclaration:
+		return "enum"
+	case *ast.TypeDeclaration:
+		return "type"
+	case *ast.VariableDeclaration:
+		return "variable"
+	}
+	return "declaration"
+}
+
+// isReducible reports whether the top-level declaration can be removed:
+// not the pragmas and not the imports, which are removed in their own step.
+func isReducible(decl ast.Node) bool {
+	switch decl.(type) {
+	case *ast.PragmaDirective, *ast.ImportDirective:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package analysis
+
+import (
+	"context"
+	"path"
+	"strings"
+	"testing"
+)
+
+var reproFiles = map[string]string{
+	"src/Types.sol": `pragma solidity ^0.8.0;
+
+struct Position {
+    uint256 amount;
+}
+
+/// @notice Not used by the staking.
+struct Reward {
+    uint256 rate;
+}
+
+enum Kind {
+    Fixed,
+    Flexible
+}
+`,
+	"src/Math.sol": `pragma solidity ^0.8.0;
+
+library Math {
+    function double(uint256 x) internal pure returns (uint256) {
+        return x * 2;
+    }
+}
+`,
+	"src/Staking.sol": `pragma solidity ^0.8.0;
+
+import {Position} from "./Types.sol";
+import {Math} from "./Math.sol";
+
+contract Staking {
+    Position[] positions;
+    uint256 total;
+
+    event Staked(uint256 amount);
+
+    function stake(uint256 amount) external {
+        positions.push(Position(Math.double(amount)));
+        total += amount;
+        emit Staked(amount);
+    }
+
+    function claim(uint256 i) external {
+        Position memory p = positions[i];
+        p.amount = 0;
+    }
+}
+`,
+}
+
+func reproState() *State {
+	s := NewState()
+	s.Root = "/ws"
+	for name, src := range reproFiles {
+		s.OpenDocument(PathToURI(path.Join("/ws", name)), 1, src)
+	}
+	return s
+}
+
+func Test_Repro(t *testing.T) {
+	s := reproState()
+	repro, err := s.Repro(context.Background(), "file:///ws/src/Staking.sol", 18, "lost-memory-write")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"Staking.sol": `pragma solidity ^0.8.0;
+
+import {Position} from "./Types.sol";
+
+contract Staking {
+    Position[] positions;
+
+    function claim(uint256 i) external {
+        Position memory p = positions[i];
+        p.amount = 0;
+    }
+}
+`,
+		"Types.sol": `pragma solidity ^0.8.0;
+
+struct Position {
+    uint256 amount;
+}
+`,
+	}
+	for name, src := range expected {
+		if repro.Files[name] != src {
+			t.Errorf("Expected %s:\n%s\ngot:\n%s", name, src, repro.Files[name])
+		}
+	}
+	if len(repro.Files) != len(expected) {
+		t.Errorf("Expected %d files, got %v", len(expected), sortedKeys(repro.Files))
+	}
+	if repro.Target != "Staking.sol" || repro.Origins["Types.sol"] != "src/Types.sol" {
+		t.Errorf("Expected the target Staking.sol and the origins of the files, got %s and %v", repro.Target, repro.Origins)
+	}
+	removed := strings.Join(repro.Removed, "\n")
+	for _, r := range []string{"the struct `Reward` of src/Types.sol", "the function `Staking.stake` of src/Staking.sol", "the file src/Math.sol"} {
+		if !strings.Contains(removed, r) {
+			t.Errorf("Expected %s to be removed, got:\n%s", r, removed)
+		}
+	}
+
+	// The diagnostic fires on the output.
+	out := NewState()
+	out.Root = "/out"
+	for name, src := range repro.Files {
+		out.OpenDocument(PathToURI(path.Join("/out", name)), 1, src)
+	}
+	found := false
+	for _, d := range out.Diagnostics(context.Background(), "file:///out/Staking.sol").Params.Diagnostics {
+		found = found || d.Code == "lost-memory-write" && d.Range.Start.Line == repro.Diagnostic.Range.Start.Line
+	}
+	if !found || repro.Diagnostic.Range.Start.Line != 8 {
+		t.Errorf("Expected the diagnostic at line 8 of the output, got %v", repro.Diagnostic)
+	}
+}
+
+func Test_ReproBacksOut(t *testing.T) {
+	s := reproState()
+	repro, err := s.Repro(context.Background(), "file:///ws/src/Staking.sol", 18, "lost-memory-write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Removing the struct, the storage array or stubbing the function
+	// loses the diagnostic, so the driver backs them out, after trying them
+	// along with the others.
+	expected := []string{
+		"the contract `Staking` of src/Staking.sol",
+		"the struct `Position` of src/Types.sol",
+		"the state variable `Staking.positions` of src/Staking.sol",
+		"the function `Staking.claim` of src/Staking.sol",
+		"the import of \"./Types.sol\" in src/Staking.sol",
+		"the body of the function `Staking.claim` of src/Staking.sol",
+	}
+	if strings.Join(repro.BackedOut, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected to back out:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(repro.BackedOut, "\n"))
+	}
+	if !strings.Contains(repro.Files["Types.sol"], "struct Position") {
+		t.Errorf("Expected the struct to be kept, got:\n%s", repro.Files["Types.sol"])
+	}
+}
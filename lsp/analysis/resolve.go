@@ -0,0 +1,752 @@
+package analysis
+
+import (
+	"slices"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/token"
+)
+
+// Symbol is a named declaration that identifiers can refer to.
+type Symbol struct {
+	Doc  *Document       // document with the declaration
+	Name *ast.Identifier // declared name
+	Node ast.Node        // declaring node e.g. *ast.ContractDeclaration, *ast.Param, or *ast.ImportSymbol for import aliases
+}
+
+// The resolution is purely syntactic. It follows the Solidity scoping rules
+// for blocks, functions, contracts with their bases and imports, but it
+// doesn't type check anything. Member accesses are resolved only if the type
+// of the accessed expression can be read from a declaration e.g. a variable
+// of a contract type, a type conversion like `IVault(addr)`, or an import
+// alias. Overloaded functions resolve to the first declaration.
+
+// resolve returns the declaration that the identifier at path[0] refers to;
+// or nil if it can't be resolved e.g. it's a builtin like `msg`. The path
+// starts with the innermost node, the same as in ast.PathEnclosingPos.
+func (s *State) resolve(doc *Document, path []ast.Node) *Symbol {
+	ident, ok := path[0].(*ast.Identifier)
+	if !ok || len(path) < 2 {
+		return nil
+	}
+
+	switch parent := path[1].(type) {
+	case *ast.MemberAccessExpression:
+		if parent.Member == ident {
+			if sym := s.member(s.scopeOf(doc, path[2:], parent.Expression), ident.Name); sym != nil {
+				return sym
+			}
+			return s.attachedFunction(doc, path[2:], ident.Name)
+		}
+	case *ast.ImportSymbol:
+		if parent.Alias == ident {
+			return &Symbol{Doc: doc, Name: ident, Node: parent}
+		}
+		target := s.ImportTarget(doc, path[2].(*ast.ImportDirective))
+		if target == nil {
+			return nil
+		}
+		return s.lookupFile(target, ident.Name, map[*Document]bool{})
+	case *ast.ImportDirective:
+		// Unit alias e.g. `Lib` in `import "./Lib.sol" as Lib;`
+		return &Symbol{Doc: doc, Name: ident, Node: parent}
+	case *ast.CallExpression:
+		for _, name := range parent.Names {
+			if name == ident {
+				return s.namedArgument(doc, path[2:], parent.Function, ident.Name)
+			}
+		}
+	case *ast.CallOptionsExpression:
+		// Options like `value` and `gas` in `to.call{value: 1}("")`.
+		return nil
+	case *ast.CatchClause, *ast.PragmaDirective, *ast.BasicLit:
+		// `Error` and `Panic` in catch clauses, pragma names and units.
+		return nil
+	case *ast.EnumDeclaration:
+		for _, member := range parent.Members {
+			if member == ident {
+				return &Symbol{Doc: doc, Name: ident, Node: parent}
+			}
+		}
+	case *ast.MappingType:
+		if parent.KeyName == ident || parent.ValueName == ident {
+			return &Symbol{Doc: doc, Name: ident, Node: parent}
+		}
+	}
+
+	if declaredName(path[1]) == ident {
+		return &Symbol{Doc: doc, Name: ident, Node: path[1]}
+	}
+	return s.lookup(doc, path[1:], ident.Name, ident.Start())
+}
+
+// lookup finds the declaration of the name visible at the position. The
+// path starts with the innermost node enclosing the position.
+func (s *State) lookup(doc *Document, path []ast.Node, name string, pos token.Pos) *Symbol {
+	for i, node := range path {
+		var sym *Symbol
+		switch n := node.(type) {
+		case *ast.BlockStatement:
+			// Local variables are visible only after they are declared.
+			for _, stmt := range n.Statements {
+				if stmt.End() > pos {
+					break
+				}
+				if decl, ok := stmt.(*ast.VariableDeclarationStatement); ok {
+					sym = findLocal(doc, decl, name)
+				}
+				if sym != nil {
+					break
+				}
+			}
+		case *ast.ForStatement:
+			if decl, ok := n.Init.(*ast.VariableDeclarationStatement); ok {
+				sym = findLocal(doc, decl, name)
+			}
+		case *ast.TryStatement:
+			if i > 0 && path[i-1] == ast.Node(n.Body) {
+				sym = findParam(doc, n.Returns, name)
+			}
+		case *ast.CatchClause:
+			sym = findParam(doc, n.Params, name)
+		case *ast.FunctionDeclaration:
+			sym = findParam(doc, n.Type.Params, name)
+			if sym == nil {
+				sym = findParam(doc, n.Type.Results, name)
+			}
+		case *ast.ModifierDeclaration:
+			sym = findParam(doc, n.Params, name)
+		case *ast.ContractDeclaration:
+			sym = s.lookupMember(doc, n, name, map[*ast.ContractDeclaration]bool{})
+		case *ast.File:
+			sym = s.lookupFile(doc, name, map[*Document]bool{})
+		}
+		if sym != nil {
+			return sym
+		}
+	}
+	return nil
+}
+
+// lookupMember finds the member of the contract or one of its bases.
+func (s *State) lookupMember(doc *Document, c *ast.ContractDeclaration,
+	name string, visited map[*ast.ContractDeclaration]bool) *Symbol {
+	if visited[c] {
+		return nil
+	}
+	visited[c] = true
+
+	for _, decl := range c.Body {
+		if id := declaredName(decl); id != nil && id.Name == name {
+			return &Symbol{Doc: doc, Name: id, Node: decl}
+		}
+	}
+
+	// The most derived base comes last in the inheritance list.
+	bases := s.bases(doc, c)
+	for i := len(bases) - 1; i >= 0; i-- {
+		base := bases[i]
+		if sym := s.lookupMember(base.Doc, base.Node.(*ast.ContractDeclaration), name, visited); sym != nil {
+			return sym
+		}
+	}
+	return nil
+}
+
+// The names of the file scope are resolved in two phases, so that the
+// files importing each other see each other's declarations whichever of
+// them is resolved first. The first phase reads the names every file
+// declares itself: its top-level declarations, the unit aliases and the
+// symbols it imports by name, see declare. The second one, lookupFile,
+// follows the plain imports and the symbols imported by name to the files
+// declaring them, stopping at the files already visited, since the imports
+// may form a cycle.
+
+// fileScope is the first phase of the resolution of the file scope.
+type fileScope struct {
+	file      *ast.File
+	names     map[string]scopeEntry
+	wildcards []*ast.ImportDirective // plain imports e.g. `import "./Vault.sol";`
+}
+
+// scopeEntry is a name declared by the file, or the directive importing it
+// by name from another file.
+type scopeEntry struct {
+	sym *Symbol              // declaration, or the alias of the unit or the symbol
+	imp *ast.ImportDirective // directive importing the symbol without an alias; or nil
+}
+
+// declare returns the names the document declares in its file scope. The
+// first declaration of a name wins, the top-level declarations before the
+// imports. It's computed once per syntax tree.
+func (doc *Document) declare() *fileScope {
+	if doc.scope != nil && doc.scope.file == doc.File {
+		return doc.scope
+	}
+	scope := &fileScope{file: doc.File, names: map[string]scopeEntry{}}
+	add := func(name string, e scopeEntry) {
+		if _, ok := scope.names[name]; !ok {
+			scope.names[name] = e
+		}
+	}
+	for _, decl := range doc.File.Declarations {
+		if id := declaredName(decl); id != nil {
+			add(id.Name, scopeEntry{sym: &Symbol{Doc: doc, Name: id, Node: decl}})
+		}
+	}
+	for _, decl := range doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok {
+			continue
+		}
+		if imp.Alias != nil {
+			add(imp.Alias.Name, scopeEntry{sym: &Symbol{Doc: doc, Name: imp.Alias, Node: imp}})
+			continue
+		}
+		for _, symbol := range imp.Symbols {
+			if symbol.Alias != nil {
+				add(symbol.Alias.Name, scopeEntry{sym: &Symbol{Doc: doc, Name: symbol.Alias, Node: symbol}})
+			} else {
+				add(symbol.Name.Name, scopeEntry{imp: imp})
+			}
+		}
+		if imp.Symbols == nil {
+			scope.wildcards = append(scope.wildcards, imp)
+		}
+	}
+	doc.scope = scope
+	return scope
+}
+
+// lookupFile finds a top-level declaration of the file or a symbol imported
+// into the file.
+func (s *State) lookupFile(doc *Document, name string, visited map[*Document]bool) *Symbol {
+	if visited[doc] {
+		return nil
+	}
+	visited[doc] = true
+
+	scope := doc.declare()
+	if e, ok := scope.names[name]; ok {
+		if e.imp == nil {
+			return e.sym
+		}
+		if target := s.ImportTarget(doc, e.imp); target != nil {
+			return s.lookupFile(target, name, visited)
+		}
+		return nil
+	}
+
+	wildcards := []*Document{}
+	for _, imp := range scope.wildcards {
+		if target := s.ImportTarget(doc, imp); target != nil {
+			wildcards = append(wildcards, target)
+		}
+	}
+	for _, target := range wildcards {
+		if sym := s.lookupFile(target, name, visited); sym != nil {
+			return sym
+		}
+	}
+	return nil
+}
+
+// importedDeclarations returns the distinct declarations of the name that
+// the plain imports bring into the file scope of the document, through the
+// files re-exporting them too. The same declaration imported along two
+// paths is not ambiguous. A name declared in the document or imported by
+// name hides the plain imports, so only its declaration is returned then.
+func (s *State) importedDeclarations(doc *Document, name string, visited map[*Document]bool) []*Symbol {
+	if visited[doc] {
+		return nil
+	}
+	visited[doc] = true
+
+	if imp := s.importOf(doc, name); imp == nil || imp.Symbols != nil || imp.Alias != nil {
+		if sym := s.follow(s.lookupFile(doc, name, map[*Document]bool{})); sym != nil {
+			return []*Symbol{sym}
+		}
+		return nil
+	}
+	res := []*Symbol{}
+	for _, decl := range doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok || imp.Symbols != nil || imp.Alias != nil {
+			continue
+		}
+		target := s.ImportTarget(doc, imp)
+		if target == nil {
+			continue
+		}
+		for _, sym := range s.importedDeclarations(target, name, visited) {
+			if !slices.ContainsFunc(res, func(other *Symbol) bool { return other.Node == sym.Node }) {
+				res = append(res, sym)
+			}
+		}
+	}
+	return res
+}
+
+// bases returns the resolved base contracts in the order of the inheritance
+// list. Bases that can't be resolved are skipped.
+func (s *State) bases(doc *Document, c *ast.ContractDeclaration) []*Symbol {
+	res := []*Symbol{}
+	for _, base := range c.Bases {
+		sym := s.follow(s.resolveExpr(doc, []ast.Node{doc.File}, base.Name))
+		if sym == nil {
+			continue
+		}
+		if _, ok := sym.Node.(*ast.ContractDeclaration); ok {
+			res = append(res, sym)
+		}
+	}
+	return res
+}
+
+// resolveExpr resolves a name or a qualified name e.g. `IVault` or
+// `Lib.IVault`. The path encloses the expression.
+func (s *State) resolveExpr(doc *Document, path []ast.Node, x ast.Expression) *Symbol {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		return s.lookup(doc, path, x.Name, x.Start())
+	case *ast.MemberAccessExpression:
+		return s.member(s.scopeOf(doc, path, x.Expression), x.Member.Name)
+	}
+	return nil
+}
+
+// scopeOf returns the declaration whose members are accessed through the
+// expression e.g. the IVault interface in `IVault(addr).deposit` or in
+// `vault.deposit` if `vault` is of the IVault type. The returned symbol is a
+// contract, struct, enum or an import unit alias; or nil if it's unknown.
+func (s *State) scopeOf(doc *Document, path []ast.Node, x ast.Expression) *Symbol {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		if x.Name == "this" || x.Name == "super" {
+			return enclosingContract(doc, path)
+		}
+		return s.typeScope(s.lookup(doc, path, x.Name, x.Start()))
+	case *ast.MemberAccessExpression:
+		return s.typeScope(s.member(s.scopeOf(doc, path, x.Expression), x.Member.Name))
+	case *ast.CallExpression:
+		// Type conversions e.g. IVault(addr) and struct constructors.
+		fn := s.follow(s.resolveExpr(doc, path, x.Function))
+		if fn == nil {
+			return nil
+		}
+		switch fn.Node.(type) {
+		case *ast.ContractDeclaration, *ast.StructDeclaration:
+			return fn
+		}
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 && x.Elements[0] != nil {
+			return s.scopeOf(doc, path, x.Elements[0])
+		}
+		return nil
+	}
+	return s.scopeOfType(s.typeOf(doc, path, x))
+}
+
+// typeScope returns the declaration whose members are accessed through the
+// symbol. It's the symbol itself for contracts, structs, enums and import
+// units, and the declared type for variables.
+func (s *State) typeScope(sym *Symbol) *Symbol {
+	sym = s.follow(sym)
+	if sym == nil {
+		return nil
+	}
+	switch n := sym.Node.(type) {
+	case *ast.ContractDeclaration, *ast.StructDeclaration, *ast.EnumDeclaration, *ast.ImportDirective:
+		return sym
+	case *ast.VariableDeclaration:
+		return s.scopeOfType(sym.Doc, n.Type)
+	case *ast.Param:
+		return s.scopeOfType(sym.Doc, n.Type)
+	}
+	return nil
+}
+
+// typeOf returns the declared type of the expression and the document in
+// which the type is written; or nil if it's unknown.
+func (s *State) typeOf(doc *Document, path []ast.Node, x ast.Expression) (*Document, ast.Expression) {
+	var sym *Symbol
+	switch x := x.(type) {
+	case *ast.Identifier, *ast.MemberAccessExpression:
+		sym = s.follow(s.resolveExpr(doc, path, x))
+		if access, ok := x.(*ast.MemberAccessExpression); ok && sym == nil {
+			// The members of the address type e.g. `to.balance`.
+			if member, ok := s.addressMemberOf(doc, path, access); ok && !member.function {
+				return doc, &ast.ElementaryType{Value: member.results[0]}
+			}
+		}
+	case *ast.IndexAccessExpression:
+		d, t := s.typeOf(doc, path, x.Expression)
+		switch t := t.(type) {
+		case *ast.MappingType:
+			return d, t.Value
+		case *ast.ArrayType:
+			return d, t.Elem
+		}
+		return nil, nil
+	case *ast.CallExpression:
+		if name := s.builtinName(doc, path, x); name != "" {
+			return doc, &ast.ElementaryType{Value: builtinResults[name]}
+		}
+		if results, ok := s.addressCallResults(doc, path, x); ok {
+			if len(results) != 1 {
+				return nil, nil
+			}
+			return doc, &ast.ElementaryType{Value: results[0]}
+		}
+		fn := s.follow(s.resolveExpr(doc, path, x.Function))
+		if fn == nil {
+			return nil, nil
+		}
+		if decl, ok := fn.Node.(*ast.FunctionDeclaration); ok {
+			results := decl.Type.Results
+			if results != nil && len(results.List) == 1 {
+				return fn.Doc, results.List[0].Type
+			}
+		}
+		// External calls of the getters e.g. `vault.balances(user)`.
+		if g := s.getterOf(fn); g != nil {
+			return fn.Doc, g.Result
+		}
+		return nil, nil
+	case *ast.TupleExpression:
+		if len(x.Elements) == 1 && x.Elements[0] != nil {
+			return s.typeOf(doc, path, x.Elements[0])
+		}
+	}
+
+	if sym == nil {
+		return nil, nil
+	}
+	switch n := sym.Node.(type) {
+	case *ast.VariableDeclaration:
+		if n.Type == nil {
+			return s.inferredType(sym.Doc, n)
+		}
+		return sym.Doc, n.Type
+	case *ast.Param:
+		return sym.Doc, n.Type
+	}
+	return nil, nil
+}
+
+// inferredType returns the type of the legacy `var` declaration, which is
+// the type of its value: the component of the tuple or the result of the
+// function it's assigned from in the tuple form e.g. `var (a, b) = f()`.
+func (s *State) inferredType(doc *Document, decl *ast.VariableDeclaration) (*Document, ast.Expression) {
+	path := ast.PathEnclosingPos(doc.File, decl.Name.Start())
+	for i, node := range path {
+		stmt, ok := node.(*ast.VariableDeclarationStatement)
+		if !ok {
+			continue
+		}
+		index := slices.Index(stmt.Declarations, decl)
+		if stmt.Value == nil || index < 0 {
+			return nil, nil
+		}
+		path = path[i:]
+		if len(stmt.Declarations) == 1 && stmt.Lparen == 0 {
+			return s.typeOf(doc, path, stmt.Value)
+		}
+		switch value := stmt.Value.(type) {
+		case *ast.TupleExpression:
+			if len(value.Elements) == len(stmt.Declarations) && value.Elements[index] != nil {
+				return s.typeOf(doc, path, value.Elements[index])
+			}
+		case *ast.CallExpression:
+			fn := s.follow(s.resolveExpr(doc, path, value.Function))
+			if fn == nil {
+				return nil, nil
+			}
+			if f, ok := fn.Node.(*ast.FunctionDeclaration); ok && f.Type.Results != nil && len(f.Type.Results.List) == len(stmt.Declarations) {
+				return fn.Doc, f.Type.Results.List[index].Type
+			}
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// scopeOfType returns the contract, struct or enum named by the type
+// expression; or nil for elementary types, mappings and arrays.
+func (s *State) scopeOfType(doc *Document, t ast.Expression) *Symbol {
+	var name *ast.Identifier
+	switch t := t.(type) {
+	case *ast.Identifier:
+		name = t
+	case *ast.MemberAccessExpression:
+		name = t.Member
+	default:
+		return nil
+	}
+
+	path := ast.PathEnclosingPos(doc.File, name.Start())
+	if path[0] != ast.Node(name) {
+		return nil
+	}
+	sym := s.follow(s.resolve(doc, path))
+	if sym == nil {
+		return nil
+	}
+	switch sym.Node.(type) {
+	case *ast.ContractDeclaration, *ast.StructDeclaration, *ast.EnumDeclaration:
+		return sym
+	}
+	return nil
+}
+
+// member finds the member of a scope returned by scopeOf.
+func (s *State) member(scope *Symbol, name string) *Symbol {
+	if scope == nil {
+		return nil
+	}
+	switch n := scope.Node.(type) {
+	case *ast.ContractDeclaration:
+		return s.lookupMember(scope.Doc, n, name, map[*ast.ContractDeclaration]bool{})
+	case *ast.StructDeclaration:
+		for _, member := range n.Members {
+			if member.Name.Name == name {
+				return &Symbol{Doc: scope.Doc, Name: member.Name, Node: member}
+			}
+		}
+	case *ast.EnumDeclaration:
+		for _, member := range n.Members {
+			if member.Name == name {
+				return &Symbol{Doc: scope.Doc, Name: member, Node: n}
+			}
+		}
+	case *ast.ImportDirective:
+		if target := s.ImportTarget(scope.Doc, n); target != nil {
+			return s.lookupFile(target, name, map[*Document]bool{})
+		}
+	}
+	return nil
+}
+
+// attachedFunction finds the library function attached to a type with
+// a using-for directive e.g. `add` in `x.add(y)` after `using SafeMath for
+// uint256;`. The type of the value is not checked, the first function with
+// the name wins.
+func (s *State) attachedFunction(doc *Document, path []ast.Node, name string) *Symbol {
+	directives := []*ast.UsingForDirective{}
+	if contract := enclosingContract(doc, path); contract != nil {
+		for _, decl := range contract.Node.(*ast.ContractDeclaration).Body {
+			if using, ok := decl.(*ast.UsingForDirective); ok {
+				directives = append(directives, using)
+			}
+		}
+	}
+	for _, decl := range doc.File.Declarations {
+		if using, ok := decl.(*ast.UsingForDirective); ok {
+			directives = append(directives, using)
+		}
+	}
+
+	file := []ast.Node{doc.File}
+	for _, using := range directives {
+		if using.Library != nil {
+			lib := s.follow(s.resolveExpr(doc, file, using.Library))
+			if lib == nil {
+				continue
+			}
+			if c, ok := lib.Node.(*ast.ContractDeclaration); ok {
+				for _, decl := range c.Body {
+					if fn, ok := decl.(*ast.FunctionDeclaration); ok && fn.Name != nil && fn.Name.Name == name {
+						return &Symbol{Doc: lib.Doc, Name: fn.Name, Node: fn}
+					}
+				}
+			}
+			continue
+		}
+		for _, fn := range using.Functions {
+			if sym := s.follow(s.resolveExpr(doc, file, fn)); sym != nil && sym.Name.Name == name {
+				return sym
+			}
+		}
+	}
+	return nil
+}
+
+// namedArgument resolves the name of an argument in a call with named
+// arguments e.g. `amount` in `deposit({amount: 1})`. It refers to the
+// parameter of the called function, event or error, or to a struct member.
+func (s *State) namedArgument(doc *Document, path []ast.Node, fn ast.Expression, name string) *Symbol {
+	callee := s.follow(s.resolveExpr(doc, path, fn))
+	if callee == nil {
+		return nil
+	}
+	switch n := callee.Node.(type) {
+	case *ast.FunctionDeclaration:
+		return findParam(callee.Doc, n.Type.Params, name)
+	case *ast.EventDeclaration:
+		return findParam(callee.Doc, n.Params, name)
+	case *ast.ErrorDeclaration:
+		return findParam(callee.Doc, n.Params, name)
+	case *ast.StructDeclaration:
+		return s.member(callee, name)
+	}
+	return nil
+}
+
+// follow returns the declaration that an import alias refers to e.g.
+// IVault for `V` in `import {IVault as V} from "./IVault.sol";`. Other
+// symbols are returned as they are.
+func (s *State) follow(sym *Symbol) *Symbol {
+	if sym == nil {
+		return nil
+	}
+	symbol, ok := sym.Node.(*ast.ImportSymbol)
+	if !ok {
+		return sym
+	}
+	for _, decl := range sym.Doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok {
+			continue
+		}
+		for _, other := range imp.Symbols {
+			if other != symbol {
+				continue
+			}
+			if target := s.ImportTarget(sym.Doc, imp); target != nil {
+				return s.lookupFile(target, symbol.Name.Name, map[*Document]bool{})
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// ImportTarget returns the document imported by the directive; or nil if
+// it's not indexed.
+func (s *State) ImportTarget(doc *Document, imp *ast.ImportDirective) *Document {
+	if imp.Path == nil || len(imp.Path.Value) < 2 {
+		return nil
+	}
+	// Strip the quotes; both "..." and '...' are allowed.
+	return s.resolveImport(doc.URI, imp.Path.Value[1:len(imp.Path.Value)-1])
+}
+
+func enclosingContract(doc *Document, path []ast.Node) *Symbol {
+	for _, node := range path {
+		if c, ok := node.(*ast.ContractDeclaration); ok {
+			return &Symbol{Doc: doc, Name: c.Name, Node: c}
+		}
+	}
+	return nil
+}
+
+func findLocal(doc *Document, stmt *ast.VariableDeclarationStatement, name string) *Symbol {
+	for _, decl := range stmt.Declarations {
+		if decl != nil && decl.Name.Name == name {
+			return &Symbol{Doc: doc, Name: decl.Name, Node: decl}
+		}
+	}
+	return nil
+}
+
+func findParam(doc *Document, params *ast.ParamList, name string) *Symbol {
+	if params == nil {
+		return nil
+	}
+	for _, param := range params.List {
+		if param.Name != nil && param.Name.Name == name {
+			return &Symbol{Doc: doc, Name: param.Name, Node: param}
+		}
+	}
+	return nil
+}
+
+// declaredName returns the name introduced by the declaration; or nil if
+// the node doesn't declare a name.
+func declaredName(node ast.Node) *ast.Identifier {
+	switch n := node.(type) {
+	case *ast.ContractDeclaration:
+		return n.Name
+	case *ast.FunctionDeclaration:
+		return n.Name
+	case *ast.ModifierDeclaration:
+		return n.Name
+	case *ast.EventDeclaration:
+		return n.Name
+	case *ast.ErrorDeclaration:
+		return n.Name
+	case *ast.StructDeclaration:
+		return n.Name
+	case *ast.EnumDeclaration:
+		return n.Name
+	case *ast.TypeDeclaration:
+		return n.Name
+	case *ast.VariableDeclaration:
+		return n.Name
+	case *ast.Param:
+		return n.Name
+	}
+	return nil
+}
+
+// builtins are the global names that are not declared in the source. The
+// legacyBuiltins are added in the legacy documents.
+var builtins = map[string]bool{
+	"msg": true, "block": true, "tx": true, "abi": true, "this": true,
+	"super": true, "now": true, "require": true, "assert": true,
+	"revert": true, "keccak256": true, "sha256": true,
+	"ripemd160": true, "ecrecover": true, "addmod": true, "mulmod": true,
+	"selfdestruct": true, "gasleft": true,
+	"blockhash": true, "blobhash": true, "type": true,
+}
+
+// unresolvedIdentifiers returns the identifiers in the document that refer
+// to a declaration which can't be found. Builtins and members of values of
+// an unknown type are not reported.
+func (s *State) unresolvedIdentifiers(doc *Document) []*ast.Identifier {
+	res := []*ast.Identifier{}
+	legacy := pragma.Legacy(doc.File)
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		if builtins[ident.Name] || legacy && legacyBuiltins[ident.Name] != "" || s.resolve(doc, path) != nil {
+			return
+		}
+		switch parent := path[1].(type) {
+		case *ast.MemberAccessExpression:
+			if parent.Member == ident && s.scopeOf(doc, path[2:], parent.Expression) == nil {
+				return
+			}
+		case *ast.CallExpression:
+			// Named arguments of calls to unknown functions.
+			for _, name := range parent.Names {
+				if name == ident && s.follow(s.resolveExpr(doc, path[2:], parent.Function)) == nil {
+					return
+				}
+			}
+		case *ast.CallOptionsExpression, *ast.CatchClause, *ast.PragmaDirective, *ast.BasicLit:
+			return
+		}
+		res = append(res, ident)
+	})
+	return res
+}
+
+// inspectIdentifiers calls f for every identifier in the file together with
+// its path, which starts with the identifier and ends with the file.
+func inspectIdentifiers(file *ast.File, f func(ident *ast.Identifier, path []ast.Node)) {
+	stack := []ast.Node{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, node)
+		if ident, ok := node.(*ast.Identifier); ok {
+			path := make([]ast.Node, len(stack))
+			for i, n := range stack {
+				path[len(stack)-1-i] = n
+			}
+			f(ident, path)
+		}
+		return true
+	})
+}
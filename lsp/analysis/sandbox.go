@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/vfs"
+)
+
+// The workspace is read and written through the sandbox, see vfs.Sandbox:
+// the root, the libraries and node_modules, and the directories allowed
+// with `allow_paths` in foundry.toml. A remapping or a symbolic link
+// leading elsewhere e.g. to /etc is refused, and the import which tried
+// is reported, see importDiagnostics.
+
+// restrict builds the sandbox of the workspace for the root and the
+// configuration.
+func (s *State) restrict() {
+	if s.Root == "" {
+		s.sandbox = nil
+		return
+	}
+	dirs := []string{s.Root}
+	for _, dir := range append(append(slices.Clip(s.Config.Libs), "node_modules"), s.Config.AllowPaths...) {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(s.Root, dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	s.sandbox = vfs.NewSandbox(s.FS, s.Logger, dirs...)
+}
+
+// files returns the file system of the workspace: the sandbox, once the
+// root is known, or the whole FS.
+func (s *State) files() vfs.FS {
+	if s.sandbox != nil {
+		return s.sandbox
+	}
+	return s.FS
+}
+
+// readFile reads the file of the workspace. The paths the sandbox refused
+// are kept for importDiagnostics.
+func (s *State) readFile(p string) ([]byte, error) {
+	src, err := vfs.ReadFile(s.files(), p)
+	if errors.Is(err, vfs.ErrOutside) {
+		s.refused[filepath.Clean(p)] = true
+	}
+	return src, err
+}
+
+// WriteFile writes the source of the document to the disk, within the
+// workspace e.g. after the fixes were applied.
+func (s *State) WriteFile(uri string, src []byte) error {
+	return s.files().WriteFile(URIToPath(uri), src, 0o644)
+}
+
+// indexExternalImports reads the files outside of the root imported by
+// the document, directly or not, e.g. of a library allowed with
+// `allow_paths`. The files under the root are indexed with the workspace.
+func (s *State) indexExternalImports(doc *Document) {
+	if s.Root == "" {
+		return
+	}
+	stack := []*Document{doc}
+	for len(stack) > 0 {
+		doc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, decl := range doc.File.Declarations {
+			imp, ok := decl.(*ast.ImportDirective)
+			if !ok || imp.Path == nil || len(imp.Path.Value) < 2 || s.ImportTarget(doc, imp) != nil {
+				continue
+			}
+			for _, candidate := range s.importCandidates(doc.URI, imp.Path.Value[1:len(imp.Path.Value)-1]) {
+				if rel, err := filepath.Rel(s.Root, candidate); err == nil && filepath.IsLocal(rel) || s.external[candidate] {
+					continue
+				}
+				s.external[candidate] = true
+				src, err := s.readFile(candidate)
+				if err != nil {
+					continue
+				}
+				imported := s.newDocument(PathToURI(candidate), 0, false, string(src))
+				s.Documents[imported.URI] = imported
+				stack = append(stack, imported)
+				break
+			}
+		}
+	}
+}
+
+// importDiagnostics reports the imports which don't resolve since they
+// lead outside of the workspace, with a remapping, a relative path or a
+// symbolic link, see restrict.
+func (s *State) importDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	if s.sandbox == nil {
+		return res
+	}
+	for _, decl := range doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok || imp.Path == nil || len(imp.Path.Value) < 2 || s.ImportTarget(doc, imp) != nil {
+			continue
+		}
+		for _, candidate := range s.importCandidates(doc.URI, imp.Path.Value[1:len(imp.Path.Value)-1]) {
+			if s.sandbox.Contains(candidate) && !s.refused[filepath.Clean(candidate)] {
+				continue
+			}
+			res = append(res, lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, ast.NodeRange(imp.Path)),
+				Severity: lsp.SeverityError,
+				Code:     "import-outside-workspace",
+				Source:   "solbot",
+				Message:  fmt.Sprintf("The import resolves to %s, outside of the workspace, so it's not read; allow the directory with `allow_paths` in foundry.toml", candidate),
+			})
+			break
+		}
+	}
+	return res
+}
@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const sandboxVault = `pragma solidity ^0.8.0;
+
+import "shared/Math.sol";
+
+contract Vault {
+    function double(uint256 x) external pure returns (uint256) {
+        return Math.twice(x);
+    }
+}
+`
+
+const sandboxMath = `pragma solidity ^0.8.0;
+
+library Math {
+    function twice(uint256 x) internal pure returns (uint256) {
+        return 2 * x;
+    }
+}
+`
+
+func outsideDiagnostics(s *State, uri string) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "import-outside-workspace" {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func Test_RemappingOutsideWorkspace(t *testing.T) {
+	s := NewState()
+	s.FS = memoryWorkspace("/", map[string]string{
+		"ws/remappings.txt": "shared/=../etc/\n",
+		"ws/src/Vault.sol":  sandboxVault,
+		"etc/Math.sol":      sandboxMath,
+	})
+	if err := s.IndexWorkspace(context.Background(), "/ws"); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if _, ok := s.Documents["file:///etc/Math.sol"]; ok {
+		t.Fatalf("Expected the file outside of the workspace not to be read")
+	}
+
+	uri := "file:///ws/src/Vault.sol"
+	diagnostics := outsideDiagnostics(s, uri)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Range.Start.Line != 2 || d.Severity != lsp.SeverityError || !strings.Contains(d.Message, "resolves to /etc/Math.sol, outside of the workspace") {
+		t.Errorf("Expected the import at line 2 to be refused, got %q at line %d", d.Message, d.Range.Start.Line)
+	}
+}
+
+func Test_AllowedPathOutsideWorkspace(t *testing.T) {
+	s := NewState()
+	s.FS = memoryWorkspace("/", map[string]string{
+		"ws/foundry.toml":   "[profile.default]\nallow_paths = [\"../shared\"]\nremappings = [\"shared/=../shared/\"]\n",
+		"ws/src/Vault.sol":  sandboxVault,
+		"shared/Math.sol":   sandboxMath,
+		"secrets/Token.sol": "pragma solidity ^0.8.0;\n\ncontract Token {}\n",
+	})
+	if err := s.IndexWorkspace(context.Background(), "/ws"); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if _, ok := s.Documents["file:///secrets/Token.sol"]; ok {
+		t.Errorf("Expected only the imported files outside of the workspace to be read")
+	}
+
+	uri := "file:///ws/src/Vault.sol"
+	if diagnostics := outsideDiagnostics(s, uri); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diagnostics)
+	}
+	locations := *s.Definition(lsp.IntID(1), uri, lsp.Position{Line: 6, Character: 16}).Result
+	if len(locations) != 1 || locations[0].URI != "file:///shared/Math.sol" {
+		t.Errorf("Expected the definition in /shared/Math.sol, got %v", locations)
+	}
+}
+
+func Test_SymlinkOutsideWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "ws")
+	writeWorkspace(t, dir, map[string]string{
+		"ws/src/Vault.sol": strings.Replace(sandboxVault, "shared/Math.sol", "./Math.sol", 1),
+		"outside/Math.sol": sandboxMath,
+	})
+	if err := os.Symlink(filepath.Join(dir, "outside", "Math.sol"), filepath.Join(root, "src", "Math.sol")); err != nil {
+		t.Skipf("Cannot create the symbolic link: %s", err)
+	}
+	s := NewState()
+	if err := s.IndexWorkspace(context.Background(), root); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if _, ok := s.Documents[PathToURI(filepath.Join(root, "src", "Math.sol"))]; ok {
+		t.Fatalf("Expected the link outside of the workspace not to be read")
+	}
+	diagnostics := outsideDiagnostics(s, PathToURI(filepath.Join(root, "src", "Vault.sol")))
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start.Line != 2 {
+		t.Errorf("Expected the import of the link to be refused, got %v", diagnostics)
+	}
+
+	// The fixes aren't written through the link either.
+	if err := s.WriteFile(PathToURI(filepath.Join(root, "src", "Math.sol")), []byte("")); err == nil {
+		t.Errorf("Expected the write through the link to be refused")
+	}
+	if src, _ := os.ReadFile(filepath.Join(dir, "outside", "Math.sol")); string(src) != sandboxMath {
+		t.Errorf("Expected the file outside of the workspace intact, got %q", src)
+	}
+}
@@ -0,0 +1,242 @@
+package analysis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"solbot/ast"
+	"solbot/keccak"
+	"solbot/lsp"
+	"solbot/token"
+	"strconv"
+	"strings"
+)
+
+// selector returns the first 4 bytes of the hash of the canonical signature
+// e.g. 0xa9059cbb for "transfer(address,uint256)".
+func selector(signature string) uint32 {
+	sum := keccak.Sum256([]byte(signature))
+	return binary.BigEndian.Uint32(sum[:4])
+}
+
+func formatSelector(sel uint32) string {
+	return fmt.Sprintf("0x%08x", sel)
+}
+
+// externalFunction is a function callable from the outside of a contract,
+// either declared or the getter of a public state variable.
+type externalFunction struct {
+	signature string
+	doc       *Document
+	name      *ast.Identifier
+}
+
+// externalFunctions returns the functions of the contract and its bases
+// callable from the outside, with the overridden ones left out. The ones
+// whose signature is unknown are left out too.
+func (s *State) externalFunctions(contract *Symbol) []externalFunction {
+	res := []externalFunction{}
+	seen := map[string]bool{}
+	for _, c := range s.linearize(contract) {
+		decl := c.Node.(*ast.ContractDeclaration)
+		for _, member := range decl.Body {
+			var signature string
+			var name *ast.Identifier
+			ok := false
+			switch m := member.(type) {
+			case *ast.FunctionDeclaration:
+				visibility := m.Type.Visibility
+				if m.Kind != token.FUNCTION || decl.Kind != token.INTERFACE && visibility != ast.External && visibility != ast.Public {
+					continue
+				}
+				signature, ok = s.functionSignature(c.Doc, m)
+				name = m.Name
+			case *ast.VariableDeclaration:
+				g := s.getterOf(&Symbol{Doc: c.Doc, Name: m.Name, Node: m})
+				if g == nil {
+					continue
+				}
+				signature, ok = s.getterSignature(g)
+				name = m.Name
+			}
+			if !ok || seen[signature] {
+				continue
+			}
+			seen[signature] = true
+			res = append(res, externalFunction{signature: signature, doc: c.Doc, name: name})
+		}
+	}
+	return res
+}
+
+// interfaceID returns the ERC-165 identifier of the interface: the XOR of
+// the selectors of the functions it declares, without the inherited ones;
+// or false if some of the signatures are unknown.
+func (s *State) interfaceID(iface *Symbol) (uint32, bool) {
+	id := uint32(0)
+	for _, member := range iface.Node.(*ast.ContractDeclaration).Body {
+		fn, ok := member.(*ast.FunctionDeclaration)
+		if !ok || fn.Kind != token.FUNCTION {
+			continue
+		}
+		signature, ok := s.functionSignature(iface.Doc, fn)
+		if !ok {
+			return 0, false
+		}
+		id ^= selector(signature)
+	}
+	return id, true
+}
+
+// wellKnownInterfaceIDs are the identifiers every implementation of
+// `supportsInterface` can check: the one of ERC-165 itself and the invalid
+// one, which must not be supported.
+var wellKnownInterfaceIDs = map[uint32]bool{0x01ffc9a7: true, 0xffffffff: true}
+
+// selectorDiagnostics reports the externally visible functions of the
+// contracts sharing a selector, which the compiler rejects, since a call
+// can't tell them apart.
+//
+// It also warns about the interface IDs hard-coded in `supportsInterface`
+// that are not the ID of any interface the contract inherits, e.g. left
+// behind after the interface changed. They are checked only if all of the
+// inherited interfaces are known.
+func (s *State) selectorDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok {
+			continue
+		}
+		contract := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		if s.linearize(contract) == nil {
+			continue
+		}
+
+		first := map[uint32]externalFunction{}
+		for _, fn := range s.externalFunctions(contract) {
+			sel := selector(fn.signature)
+			other, ok := first[sel]
+			if !ok {
+				first[sel] = fn
+				continue
+			}
+			// The function can be declared by a base in another file.
+			r := ast.NodeRange(c.Name)
+			if fn.doc == doc {
+				r = ast.NodeRange(fn.name)
+			}
+			res = append(res, lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, r),
+				Severity: lsp.SeverityError,
+				Code:     "selector-collision",
+				Source:   "solbot",
+				Message: fmt.Sprintf("Functions `%s` and `%s` of `%s` have the same selector `%s`",
+					other.signature, fn.signature, c.Name.Name, formatSelector(sel)),
+			})
+		}
+
+		res = append(res, s.interfaceIDDiagnostics(doc, contract)...)
+	}
+	return res
+}
+
+// interfaceIDDiagnostics warns about the hard-coded interface IDs in the
+// `supportsInterface` function of the contract that none of its interfaces
+// have.
+func (s *State) interfaceIDDiagnostics(doc *Document, contract *Symbol) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	var fn *ast.FunctionDeclaration
+	for _, member := range contract.Node.(*ast.ContractDeclaration).Body {
+		if f, ok := member.(*ast.FunctionDeclaration); ok && f.Name != nil && f.Name.Name == "supportsInterface" && f.Body != nil {
+			fn = f
+		}
+	}
+	if fn == nil || !s.importsResolve(doc, map[*Document]bool{}) {
+		return res
+	}
+
+	known := map[uint32]bool{}
+	names := []string{}
+	for _, c := range s.ancestors(contract) {
+		if c.Node.(*ast.ContractDeclaration).Kind != token.INTERFACE {
+			continue
+		}
+		id, ok := s.interfaceID(c)
+		if !ok {
+			return res
+		}
+		known[id] = true
+		names = append(names, fmt.Sprintf("`%s` (`%s`)", c.Name.Name, formatSelector(id)))
+	}
+
+	implemented := "it implements no interfaces"
+	if len(names) > 0 {
+		implemented = "the implemented ones are " + strings.Join(names, ", ")
+	}
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		x, ok := node.(ast.Expression)
+		if !ok {
+			return true
+		}
+		id, ok := s.constInterfaceID(doc, x)
+		if !ok || known[id] || wellKnownInterfaceIDs[id] {
+			return true
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(x)),
+			Severity: lsp.SeverityWarning,
+			Code:     "unknown-interface-id",
+			Source:   "solbot",
+			Message: fmt.Sprintf("The interface ID `%s` is not the ID of any interface `%s` inherits; %s",
+				formatSelector(id), contract.Name.Name, implemented),
+		})
+		return false
+	})
+	return res
+}
+
+// constInterfaceID returns the value of the 4 bytes hex literal e.g.
+// `0x80ac58cd`, used directly or through a constant.
+func (s *State) constInterfaceID(doc *Document, x ast.Expression) (uint32, bool) {
+	if ident, ok := x.(*ast.Identifier); ok {
+		sym := s.follow(s.resolve(doc, ast.PathEnclosingPos(doc.File, ident.Start())))
+		if sym == nil {
+			return 0, false
+		}
+		decl, ok := sym.Node.(*ast.VariableDeclaration)
+		if !ok || !decl.Constant || decl.Value == nil {
+			return 0, false
+		}
+		x = decl.Value
+	}
+	lit, ok := x.(*ast.BasicLit)
+	if !ok || lit.Kind != token.HEX_NUMBER || lit.Unit != nil {
+		return 0, false
+	}
+	digits, _ := strings.CutPrefix(strings.ReplaceAll(lit.Value, "_", ""), "0x")
+	if len(digits) != 8 {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(id), true
+}
+
+// interfaceIDHover shows the ERC-165 identifier of an interface; or returns
+// an empty string for the other symbols.
+func (s *State) interfaceIDHover(sym *Symbol, markdown bool) string {
+	c, ok := sym.Node.(*ast.ContractDeclaration)
+	if !ok || c.Kind != token.INTERFACE {
+		return ""
+	}
+	id, ok := s.interfaceID(sym)
+	if !ok {
+		return ""
+	}
+	if markdown {
+		return fmt.Sprintf("interfaceId: `%s`", formatSelector(id))
+	}
+	return "interfaceId: " + formatSelector(id)
+}
@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const erc721Src = `pragma solidity ^0.8.0;
+
+interface IERC165 {
+    function supportsInterface(bytes4 interfaceId) external view returns (bool);
+}
+
+interface IERC721 is IERC165 {
+    function balanceOf(address owner) external view returns (uint256 balance);
+    function ownerOf(uint256 tokenId) external view returns (address owner);
+    function safeTransferFrom(address from, address to, uint256 tokenId, bytes calldata data) external;
+    function safeTransferFrom(address from, address to, uint256 tokenId) external;
+    function transferFrom(address from, address to, uint256 tokenId) external;
+    function approve(address to, uint256 tokenId) external;
+    function setApprovalForAll(address operator, bool approved) external;
+    function getApproved(uint256 tokenId) external view returns (address operator);
+    function isApprovedForAll(address owner, address operator) external view returns (bool);
+}
+`
+
+func Test_SelectorCollision(t *testing.T) {
+	uri := "file:///ws/src/Token.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+contract Token {
+    function burn(uint256 amount) external {}
+    function collate_propagate_storage(bytes16 data) public {}
+    function transfer(address to, uint256 amount) internal {}
+}
+`)
+
+	diagnostics := s.selectorDiagnostics(s.Documents[uri])
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "selector-collision" || d.Severity != lsp.SeverityError || d.Range.Start.Line != 4 {
+		t.Errorf("Expected selector-collision at line 4, got %s at line %d", d.Code, d.Range.Start.Line)
+	}
+	message := "Functions `burn(uint256)` and `collate_propagate_storage(bytes16)` of `Token` have the same selector `0x42966c68`"
+	if d.Message != message {
+		t.Errorf("Expected %q, got %q", message, d.Message)
+	}
+}
+
+func Test_HoverInterfaceID(t *testing.T) {
+	uri := "file:///ws/src/IERC721.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, erc721Src)
+
+	expected := map[lsp.Position]string{
+		{Line: 2, Character: 10}: "interfaceId: `0x01ffc9a7`",
+		// The inherited functions are not part of the ID.
+		{Line: 6, Character: 10}: "interfaceId: `0x80ac58cd`",
+	}
+	for position, want := range expected {
+		if hover := s.Hover(lsp.IntID(1), uri, position).Result.Contents.Value; !strings.HasSuffix(hover, want) {
+			t.Errorf("Expected %q at %v, got %q", want, position, hover)
+		}
+	}
+
+	sess := NewSession()
+	sess.AddFile("IERC721.sol", erc721Src)
+	ids := []string{}
+	for _, c := range sess.Contracts() {
+		if id, ok := sess.InterfaceID(c); ok {
+			ids = append(ids, id)
+		}
+	}
+	if strings.Join(ids, " ") != "0x01ffc9a7 0x80ac58cd" {
+		t.Errorf("Expected 0x01ffc9a7 0x80ac58cd, got %v", ids)
+	}
+}
+
+func Test_UnknownInterfaceID(t *testing.T) {
+	uri := "file:///ws/src/NFT.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, erc721Src+`
+contract NFT is IERC721 {
+    bytes4 constant STALE_ID = 0x80ac58ce;
+
+    function supportsInterface(bytes4 interfaceId) external pure returns (bool) {
+        return interfaceId == 0x80ac58cd || interfaceId == 0x01ffc9a7 || interfaceId == STALE_ID || interfaceId == 0x5b5e139f;
+    }
+}
+`)
+
+	var diagnostics []lsp.Diagnostic
+	for _, d := range s.selectorDiagnostics(s.Documents[uri]) {
+		if d.Code == "unknown-interface-id" {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diagnostics)
+	}
+	message := "The interface ID `0x80ac58ce` is not the ID of any interface `NFT` inherits; " +
+		"the implemented ones are `IERC721` (`0x80ac58cd`), `IERC165` (`0x01ffc9a7`)"
+	if diagnostics[0].Message != message || diagnostics[0].Severity != lsp.SeverityWarning {
+		t.Errorf("Expected %q, got %q", message, diagnostics[0].Message)
+	}
+	if !strings.Contains(diagnostics[1].Message, "`0x5b5e139f`") {
+		t.Errorf("Expected the metadata ID to be reported, got %q", diagnostics[1].Message)
+	}
+}
@@ -0,0 +1,545 @@
+package analysis
+
+import (
+	"path/filepath"
+	"slices"
+	"solbot/ast"
+	"solbot/token"
+	"strings"
+	"sync"
+)
+
+// Session answers the questions about a set of Solidity files without the
+// language server protocol, for the tools embedding solbot e.g.
+//
+//	sess := analysis.NewSession()
+//	sess.AddFile("src/Vault.sol", src)
+//	for _, fn := range sess.Functions() {
+//		fmt.Println(fn.Name.Name, len(sess.ReferencesTo(fn)))
+//	}
+//
+// The files are parsed and resolved lazily by the first query that needs
+// them, and every pass runs at most once until the next file is added. The
+// queries are safe for the concurrent use once all of the files are added.
+type Session struct {
+	mu    sync.Mutex
+	files map[string]string // URI -> source
+	names map[string]string // URI -> name passed to AddFile
+	snap  *snapshot         // results of the passes over the current files; or nil
+	runs  map[string]int    // pass name -> number of runs, for the tests
+}
+
+func NewSession() *Session {
+	return &Session{
+		files: map[string]string{},
+		names: map[string]string{},
+		runs:  map[string]int{},
+	}
+}
+
+// AddFile adds the file or replaces its source. The name is a path; the
+// relative imports are resolved against it.
+func (sess *Session) AddFile(name, src string) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		abs = name
+	}
+	uri := PathToURI(abs)
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.files[uri] = src
+	sess.names[uri] = name
+	sess.snap = nil
+}
+
+// Reference is an identifier referring to a declaration.
+type Reference struct {
+	Doc   *Document
+	Ident *ast.Identifier
+}
+
+// CallGraph maps the functions and the modifiers to the functions they call
+// and the modifiers they apply, in the source order. Only the callees that
+// can be resolved are included.
+type CallGraph map[ast.Node][]*Symbol
+
+// File returns the syntax tree of the file; or nil if it wasn't added.
+func (sess *Session) File(name string) *ast.File {
+	if doc := sess.document(name); doc != nil {
+		return doc.File
+	}
+	return nil
+}
+
+// Contracts returns the contracts, interfaces and libraries of all of the
+// files, ordered by the file and by the position.
+func (sess *Session) Contracts() []*Symbol {
+	return sess.result(passDeclarations).(*declarations).contracts
+}
+
+// Functions returns the functions of all of the files, including the free
+// functions, the constructors, the fallback and the receive functions.
+func (sess *Session) Functions() []*Symbol {
+	return sess.result(passDeclarations).(*declarations).functions
+}
+
+// SymbolAt returns the declaration referred to by the identifier at the
+// byte offset of the file; or nil if there is no identifier or it can't be
+// resolved. Import aliases are followed to the imported declarations.
+func (sess *Session) SymbolAt(name string, offset int) *Symbol {
+	index := sess.result(passResolve).(*resolution)
+	doc := sess.document(name)
+	if doc == nil {
+		return nil
+	}
+	ident, ok := ast.PathEnclosingPos(doc.File, token.Pos(offset))[0].(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	return index.symbols[ident]
+}
+
+// ReferencesTo returns the identifiers referring to the declaration, except
+// for its name, ordered by the file and by the position.
+func (sess *Session) ReferencesTo(sym *Symbol) []Reference {
+	if sym == nil {
+		return nil
+	}
+	return sess.result(passResolve).(*resolution).references[sym.Name]
+}
+
+// CallGraph returns the calls between the functions and the modifiers of
+// all of the files.
+func (sess *Session) CallGraph() CallGraph {
+	return sess.result(passCalls).(CallGraph)
+}
+
+// TypeOf returns the declared type of the expression in the file e.g. the
+// type of the variable for an identifier or the value type for an index
+// access into a mapping; or nil if it's unknown.
+func (sess *Session) TypeOf(name string, x ast.Expression) ast.Expression {
+	types := sess.result(passTypes).(map[ast.Expression]ast.Expression)
+	if sess.document(name) == nil {
+		return nil
+	}
+	return types[x]
+}
+
+// StorageLayout returns the storage slots of the state variables of the
+// contract, including the inherited ones; or false if the layout can't be
+// computed e.g. a base is missing.
+func (sess *Session) StorageLayout(contract *Symbol) ([]StorageSlot, bool) {
+	layout, ok := sess.result(passLayout).(*layouts).storage[contract.Node]
+	return layout, ok
+}
+
+// TransientLayout returns the transient storage slots of the transient
+// state variables of the contract, numbered apart from the storage ones.
+func (sess *Session) TransientLayout(contract *Symbol) ([]StorageSlot, bool) {
+	layout, ok := sess.result(passLayout).(*layouts).transient[contract.Node]
+	return layout, ok
+}
+
+// Effects returns the inferred effects of the function, its own and the
+// ones of the functions and the modifiers it calls; or false if the symbol
+// is not an implemented function.
+func (sess *Session) Effects(fn *Symbol) (Effect, bool) {
+	effects := sess.result(passEffects).(map[ast.Node]Effect)
+	effect, ok := effects[fn.Node]
+	return effect, ok
+}
+
+// Position returns the position of the declared name of the symbol, with
+// the file name passed to AddFile.
+func (sess *Session) Position(sym *Symbol) token.Position {
+	pos := sym.Doc.Handle.Position(sym.Name.Start())
+	sess.mu.Lock()
+	pos.Filename = sess.names[sym.Doc.URI]
+	sess.mu.Unlock()
+	return pos
+}
+
+// NatSpec returns the text of the documentation comment right above the
+// declaration: a run of the `///` lines or a `/** */` block; or an empty
+// string if there is none.
+func (sess *Session) NatSpec(sym *Symbol) string {
+	return natSpec(sym.Doc, sym.Node)
+}
+
+// InterfaceID returns the ERC-165 identifier of the interface e.g.
+// "0x80ac58cd"; or false if the symbol is not an interface or some of the
+// signatures of its functions are unknown.
+func (sess *Session) InterfaceID(iface *Symbol) (string, bool) {
+	if c, ok := iface.Node.(*ast.ContractDeclaration); !ok || c.Kind != token.INTERFACE {
+		return "", false
+	}
+	snap := sess.current()
+	state := sess.runPass(snap, passParse).(*State)
+	snap.state.Lock()
+	id, ok := state.interfaceID(iface)
+	snap.state.Unlock()
+	if !ok {
+		return "", false
+	}
+	return formatSelector(id), true
+}
+
+func natSpec(doc *Document, node ast.Node) string {
+	return natSpecText(natSpecComments(doc, node))
+}
+
+// natSpecText returns the text of the NatSpec comments without the comment
+// markers, one line of the comments per line.
+func natSpecText(comments []*ast.Comment) string {
+	lines := []string{}
+	for _, c := range comments {
+		if strings.HasPrefix(c.Text, "///") {
+			lines = append(lines, strings.TrimSpace(strings.TrimPrefix(c.Text, "///")))
+			continue
+		}
+		text := strings.TrimSuffix(strings.TrimPrefix(c.Text, "/**"), "*/")
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// natSpecComments returns the comments of the NatSpec right above the
+// declaration in the source order: the run of the `///` lines or the single
+// `/** */` block.
+func natSpecComments(doc *Document, node ast.Node) []*ast.Comment {
+	src := doc.Handle.Src()
+	res := []*ast.Comment{}
+	end := node.Start()
+	comments := doc.File.Comments
+	for i := len(comments) - 1; i >= 0; i-- {
+		c := comments[i]
+		if c.End() > end {
+			continue
+		}
+		if strings.TrimSpace(src[c.End():end]) != "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(c.Text, "///"):
+			res = append(res, c)
+			end = c.Start()
+			continue
+		case strings.HasPrefix(c.Text, "/**") && len(res) == 0:
+			return []*ast.Comment{c}
+		}
+		break
+	}
+	slices.Reverse(res)
+	return res
+}
+
+// document returns the document of the file name passed to AddFile.
+func (sess *Session) document(name string) *Document {
+	state := sess.result(passParse).(*State)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for uri, other := range sess.names {
+		if other == name {
+			return state.Documents[uri]
+		}
+	}
+	return nil
+}
+
+// The passes form a small graph: every pass runs once per snapshot of the
+// files, after the passes it depends on.
+const (
+	passParse        = "parse"
+	passDeclarations = "declarations"
+	passResolve      = "resolve"
+	passTypes        = "types"
+	passCalls        = "calls"
+	passLayout       = "layout"
+	passEffects      = "effects"
+)
+
+type pass struct {
+	deps []string
+	run  func(snap *snapshot) any
+}
+
+var passes = map[string]pass{
+	passParse:        {run: parseFiles},
+	passDeclarations: {deps: []string{passParse}, run: collectDeclarations},
+	passResolve:      {deps: []string{passParse}, run: resolveIdentifiers},
+	passTypes:        {deps: []string{passResolve}, run: resolveTypes},
+	passCalls:        {deps: []string{passDeclarations, passResolve}, run: buildCallGraph},
+	passLayout:       {deps: []string{passDeclarations}, run: computeLayouts},
+	passEffects:      {deps: []string{passDeclarations}, run: inferFunctionEffects},
+}
+
+// snapshot keeps the results of the passes over one version of the files.
+type snapshot struct {
+	files   map[string]string
+	results map[string]*passResult
+
+	// The State of the parse pass fills its caches as it's queried e.g. the
+	// recently used documents, so the passes and the queries using it take
+	// turns.
+	state sync.Mutex
+}
+
+type passResult struct {
+	once  sync.Once
+	value any
+}
+
+// value returns the result of a pass that has already run.
+func (snap *snapshot) value(name string) any {
+	return snap.results[name].value
+}
+
+// result runs the pass and its dependencies unless they already ran on the
+// current files, and returns its result.
+func (sess *Session) result(name string) any {
+	return sess.runPass(sess.current(), name)
+}
+
+// current returns the snapshot of the current files.
+func (sess *Session) current() *snapshot {
+	sess.mu.Lock()
+	if sess.snap == nil {
+		files := make(map[string]string, len(sess.files))
+		for uri, src := range sess.files {
+			files[uri] = src
+		}
+		sess.snap = &snapshot{files: files, results: map[string]*passResult{}}
+		for name := range passes {
+			sess.snap.results[name] = &passResult{}
+		}
+	}
+	snap := sess.snap
+	sess.mu.Unlock()
+	return snap
+}
+
+func (sess *Session) runPass(snap *snapshot, name string) any {
+	r := snap.results[name]
+	r.once.Do(func() {
+		p := passes[name]
+		for _, dep := range p.deps {
+			sess.runPass(snap, dep)
+		}
+		sess.mu.Lock()
+		sess.runs[name]++
+		sess.mu.Unlock()
+		snap.state.Lock()
+		defer snap.state.Unlock()
+		r.value = p.run(snap)
+	})
+	return r.value
+}
+
+func parseFiles(snap *snapshot) any {
+	state := NewState()
+	for uri, src := range snap.files {
+		state.Documents[uri] = newDocument(uri, 0, false, src)
+	}
+	return state
+}
+
+type declarations struct {
+	contracts []*Symbol
+	functions []*Symbol
+}
+
+func collectDeclarations(snap *snapshot) any {
+	state := snap.value(passParse).(*State)
+	res := &declarations{contracts: []*Symbol{}, functions: []*Symbol{}}
+	for _, doc := range state.sortedDocuments() {
+		ast.Inspect(doc.File, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.ContractDeclaration:
+				res.contracts = append(res.contracts, &Symbol{Doc: doc, Name: n.Name, Node: n})
+			case *ast.FunctionDeclaration:
+				name := n.Name
+				if name == nil {
+					// Constructors, fallback and receive functions are
+					// named by their keyword.
+					name = &ast.Identifier{NamePos: n.Start(), Name: n.Kind.String()}
+				}
+				res.functions = append(res.functions, &Symbol{Doc: doc, Name: name, Node: n})
+				return false
+			}
+			return true
+		})
+	}
+	return res
+}
+
+// resolution maps every identifier of the files to its declaration, and the
+// names of the declarations back to the identifiers referring to them.
+type resolution struct {
+	symbols    map[*ast.Identifier]*Symbol
+	references map[*ast.Identifier][]Reference
+}
+
+func resolveIdentifiers(snap *snapshot) any {
+	state := snap.value(passParse).(*State)
+	res := &resolution{
+		symbols:    map[*ast.Identifier]*Symbol{},
+		references: map[*ast.Identifier][]Reference{},
+	}
+	for _, doc := range state.sortedDocuments() {
+		inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+			sym := state.resolve(doc, path)
+			if followed := state.follow(sym); followed != nil {
+				sym = followed
+			}
+			if sym == nil {
+				return
+			}
+			res.symbols[ident] = sym
+			if sym.Name != ident {
+				res.references[sym.Name] = append(res.references[sym.Name], Reference{Doc: doc, Ident: ident})
+			}
+		})
+	}
+	return res
+}
+
+func resolveTypes(snap *snapshot) any {
+	state := snap.value(passParse).(*State)
+	res := map[ast.Expression]ast.Expression{}
+	for _, doc := range state.sortedDocuments() {
+		stack := []ast.Node{}
+		ast.Inspect(doc.File, func(node ast.Node) bool {
+			if node == nil {
+				stack = stack[:len(stack)-1]
+				return true
+			}
+			if x, ok := node.(ast.Expression); ok {
+				path := make([]ast.Node, 0, len(stack))
+				for i := len(stack) - 1; i >= 0; i-- {
+					path = append(path, stack[i])
+				}
+				if _, t := state.typeOf(doc, path, x); t != nil {
+					res[x] = t
+				}
+			}
+			stack = append(stack, node)
+			return true
+		})
+	}
+	return res
+}
+
+func buildCallGraph(snap *snapshot) any {
+	index := snap.value(passResolve).(*resolution)
+	state := snap.value(passParse).(*State)
+	graph := CallGraph{}
+	callers := snap.value(passDeclarations).(*declarations).functions
+	for _, doc := range state.sortedDocuments() {
+		for _, decl := range doc.File.Declarations {
+			c, ok := decl.(*ast.ContractDeclaration)
+			if !ok {
+				continue
+			}
+			for _, member := range c.Body {
+				if mod, ok := member.(*ast.ModifierDeclaration); ok {
+					callers = append(callers, &Symbol{Doc: doc, Name: mod.Name, Node: mod})
+				}
+			}
+		}
+	}
+
+	for _, caller := range callers {
+		callees := []*Symbol{}
+		add := func(x ast.Expression) {
+			var ident *ast.Identifier
+			switch x := x.(type) {
+			case *ast.Identifier:
+				ident = x
+			case *ast.MemberAccessExpression:
+				ident = x.Member
+			default:
+				return
+			}
+			sym := index.symbols[ident]
+			if sym == nil {
+				return
+			}
+			switch sym.Node.(type) {
+			case *ast.FunctionDeclaration, *ast.ModifierDeclaration:
+				callees = append(callees, sym)
+			}
+		}
+
+		var body *ast.BlockStatement
+		switch n := caller.Node.(type) {
+		case *ast.FunctionDeclaration:
+			for _, inv := range n.Modifiers {
+				add(inv.Name)
+			}
+			body = n.Body
+		case *ast.ModifierDeclaration:
+			body = n.Body
+		}
+		if body != nil {
+			ast.Inspect(body, func(node ast.Node) bool {
+				if call, ok := node.(*ast.CallExpression); ok {
+					fn := call.Function
+					if opts, ok := fn.(*ast.CallOptionsExpression); ok {
+						fn = opts.Expression
+					}
+					add(fn)
+				}
+				return true
+			})
+		}
+		graph[caller.Node] = callees
+	}
+	return graph
+}
+
+// layouts are the storage and the transient storage layouts of the
+// contracts, by their declarations.
+type layouts struct {
+	storage   map[ast.Node][]StorageSlot
+	transient map[ast.Node][]StorageSlot
+}
+
+func computeLayouts(snap *snapshot) any {
+	state := snap.value(passParse).(*State)
+	res := &layouts{storage: map[ast.Node][]StorageSlot{}, transient: map[ast.Node][]StorageSlot{}}
+	for _, c := range snap.value(passDeclarations).(*declarations).contracts {
+		if c.Node.(*ast.ContractDeclaration).Kind != token.CONTRACT {
+			continue
+		}
+		if layout, ok := state.storageLayout(c); ok {
+			res.storage[c.Node] = layout
+		}
+		if layout, ok := state.transientLayout(c); ok {
+			res.transient[c.Node] = layout
+		}
+	}
+	return res
+}
+
+func inferFunctionEffects(snap *snapshot) any {
+	state := snap.value(passParse).(*State)
+	roots := []callable{}
+	for _, fn := range snap.value(passDeclarations).(*declarations).functions {
+		if fn.Node.(*ast.FunctionDeclaration).Body != nil {
+			roots = append(roots, callable{doc: fn.Doc, node: fn.Node})
+		}
+	}
+	res := map[ast.Node]Effect{}
+	for node, f := range state.inferEffects(roots) {
+		if _, ok := node.(*ast.FunctionDeclaration); ok {
+			res[node] = f.effects
+		}
+	}
+	return res
+}
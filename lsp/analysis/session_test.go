@@ -0,0 +1,186 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const sessionBase = `pragma solidity ^0.8.0;
+
+contract Owned {
+    address owner;
+    bool paused;
+
+    modifier onlyOwner() {
+        require(msg.sender == owner);
+        _;
+    }
+}
+
+contract Fees is Owned {
+    uint16 feeBps;
+    uint256 collected;
+}
+
+contract Limits is Owned {
+    uint128 limit;
+}
+`
+
+const sessionVault = `pragma solidity ^0.8.0;
+
+import "./Base.sol";
+
+contract Vault is Fees, Limits {
+    struct Position {
+        uint128 amount;
+        uint64 since;
+    }
+
+    uint8 constant VERSION = 1;
+    mapping(address => Position) positions;
+    uint32[3] checkpoints;
+    bool locked;
+
+    function deposit(uint128 amount) external onlyOwner {
+        positions[msg.sender].amount += amount;
+        collect(amount);
+    }
+
+    function collect(uint256 amount) internal {
+        collected += amount;
+    }
+}
+`
+
+func newSession() *Session {
+	sess := NewSession()
+	sess.AddFile("src/Base.sol", sessionBase)
+	sess.AddFile("src/Vault.sol", sessionVault)
+	return sess
+}
+
+func Test_SessionPassesRunOnce(t *testing.T) {
+	sess := newSession()
+
+	queries := []func(){
+		func() { sess.Contracts() },
+		func() { sess.Functions() },
+		func() { sess.SymbolAt("src/Vault.sol", strings.Index(sessionVault, "collected")) },
+		func() { sess.ReferencesTo(sess.Functions()[0]) },
+		func() { sess.CallGraph() },
+		func() { sess.TypeOf("src/Vault.sol", nil) },
+		func() { sess.StorageLayout(sess.Contracts()[0]) },
+		func() { sess.Effects(sess.Functions()[0]) },
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		for _, query := range queries {
+			wg.Add(1)
+			go func(query func()) {
+				defer wg.Done()
+				query()
+			}(query)
+		}
+	}
+	wg.Wait()
+
+	for name := range passes {
+		if sess.runs[name] != 1 {
+			t.Errorf("Expected the %s pass to run once, got %d", name, sess.runs[name])
+		}
+	}
+
+	// A new file starts a new snapshot, the passes run again on demand.
+	sess.AddFile("src/Empty.sol", "pragma solidity ^0.8.0;\n")
+	if contracts := sess.Contracts(); len(contracts) != 4 {
+		t.Errorf("Expected 4 contracts, got %d", len(contracts))
+	}
+	if sess.runs[passParse] != 2 || sess.runs[passDeclarations] != 2 || sess.runs[passResolve] != 1 {
+		t.Errorf("Expected parse and declarations to run again, got %v", sess.runs)
+	}
+}
+
+func Test_SessionQueries(t *testing.T) {
+	sess := newSession()
+
+	sym := sess.SymbolAt("src/Vault.sol", strings.Index(sessionVault, "collected +="))
+	if sym == nil || sym.Name.Name != "collected" || !strings.HasSuffix(sym.Doc.URI, "/src/Base.sol") {
+		t.Fatalf("Expected `collected` declared in Base.sol, got %v", sym)
+	}
+	if refs := sess.ReferencesTo(sym); len(refs) != 1 || !strings.HasSuffix(refs[0].Doc.URI, "/src/Vault.sol") {
+		t.Errorf("Expected 1 reference in Vault.sol, got %v", refs)
+	}
+
+	graph := sess.CallGraph()
+	var deposit *Symbol
+	for _, fn := range sess.Functions() {
+		if fn.Name.Name == "deposit" {
+			deposit = fn
+		}
+	}
+	if deposit == nil {
+		t.Fatalf("Expected the deposit function")
+	}
+	callees := []string{}
+	for _, callee := range graph[deposit.Node] {
+		callees = append(callees, callee.Name.Name)
+	}
+	if strings.Join(callees, ",") != "onlyOwner,collect" {
+		t.Errorf("Expected deposit to call onlyOwner and collect, got %v", callees)
+	}
+	if effects, ok := sess.Effects(deposit); !ok || effects != ReadsState|ReadsEnvironment|WritesState {
+		t.Errorf("Expected deposit to read the state and the environment and write the state, got %q", effects)
+	}
+
+	var access *ast.IndexAccessExpression
+	ast.Inspect(sess.File("src/Vault.sol"), func(node ast.Node) bool {
+		if x, ok := node.(*ast.IndexAccessExpression); ok && access == nil {
+			access = x
+		}
+		return access == nil
+	})
+	if typ := sess.TypeOf("src/Vault.sol", access); ast.ExprString(typ) != "Position" {
+		t.Errorf("Expected `positions[msg.sender]` of type Position, got %q", ast.ExprString(typ))
+	}
+}
+
+func Test_StorageLayout(t *testing.T) {
+	sess := newSession()
+
+	var vault *Symbol
+	for _, c := range sess.Contracts() {
+		if c.Name.Name == "Vault" {
+			vault = c
+		}
+	}
+	layout, ok := sess.StorageLayout(vault)
+	if !ok {
+		t.Fatalf("Expected the layout of Vault")
+	}
+
+	// The linearization is Vault, Limits, Fees, Owned.
+	expected := []StorageSlot{
+		{Contract: "Owned", Name: "owner", Type: "address", Slot: 0, Offset: 0, Size: 20},
+		{Contract: "Owned", Name: "paused", Type: "bool", Slot: 0, Offset: 20, Size: 1},
+		{Contract: "Fees", Name: "feeBps", Type: "uint16", Slot: 0, Offset: 21, Size: 2},
+		{Contract: "Fees", Name: "collected", Type: "uint256", Slot: 1, Offset: 0, Size: 32},
+		{Contract: "Limits", Name: "limit", Type: "uint128", Slot: 2, Offset: 0, Size: 16},
+		{Contract: "Vault", Name: "positions", Type: "mapping(address => Position)", Slot: 3, Offset: 0, Size: 32},
+		{Contract: "Vault", Name: "checkpoints", Type: "uint32[3]", Slot: 4, Offset: 0, Size: 32},
+		{Contract: "Vault", Name: "locked", Type: "bool", Slot: 5, Offset: 0, Size: 1},
+	}
+	if len(layout) != len(expected) {
+		t.Fatalf("Expected %d slots, got %d: %v", len(expected), len(layout), layout)
+	}
+	for i, slot := range layout {
+		if slot != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], slot)
+		}
+	}
+	if transient, ok := sess.TransientLayout(vault); !ok || len(transient) != 0 {
+		t.Errorf("Expected an empty transient layout, got %v", transient)
+	}
+}
@@ -0,0 +1,242 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"solbot/analyzer"
+	"solbot/lsp"
+	"solbot/migration"
+	"solbot/project"
+)
+
+// Settings are the editor settings of the "solbot" section, fetched with
+// the workspace/configuration request or pushed with
+// workspace/didChangeConfiguration. They take precedence over solbot.toml:
+//
+//	"solbot": {
+//	    "severity": {"always-true-condition": "error", "erc20-approve-race": "off"},
+//	    "detectors": {"screaming-snake-const": true, "metric-complexity": false},
+//	    "inlayHints": {"numbers": false}
+//	}
+//
+// The severities override the ones of the diagnostics with the codes when
+// they're published, "off" hides them. The detectors turned on or off
+// replace the [detectors] section of solbot.toml, and the inlay hint
+// categories the [inlay_hints] section.
+type Settings struct {
+	Severity   map[string]string `json:"severity"`  // diagnostic code -> "error", "warning", "information", "hint" or "off"
+	Detectors  map[string]bool   `json:"detectors"` // diagnostic code -> is the detector enabled?
+	InlayHints InlayHintSettings `json:"inlayHints"`
+}
+
+type InlayHintSettings struct {
+	Numbers *bool `json:"numbers"` // or nil to keep the value of solbot.toml
+}
+
+// severities are the values of the severity overrides. Zero hides the
+// diagnostics.
+var severities = map[string]lsp.DiagnosticSeverity{
+	"error":       lsp.SeverityError,
+	"warning":     lsp.SeverityWarning,
+	"information": lsp.SeverityInformation,
+	"hint":        lsp.SeverityHint,
+	"off":         0,
+}
+
+// ParseSettings decodes the "solbot" section of the editor settings. The
+// unknown keys and the invalid values are skipped and returned as the
+// problems e.g. "solbot.inlayHints.types is unknown", so that the rest of
+// the settings still applies. So are the diagnostic codes which are typos
+// of the known ones, see misspelledCode. Null settings are the defaults.
+func ParseSettings(raw json.RawMessage) (Settings, []string) {
+	settings := Settings{}
+	problems := []string{}
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return settings, problems
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return settings, append(problems, "solbot is not an object")
+	}
+	for _, key := range sortedKeys(fields) {
+		value := fields[key]
+		switch key {
+		case "severity":
+			var overrides map[string]string
+			if err := json.Unmarshal(value, &overrides); err != nil {
+				problems = append(problems, "solbot.severity is not an object of strings")
+				continue
+			}
+			settings.Severity = map[string]string{}
+			for _, code := range sortedKeys(overrides) {
+				if suggestions := misspelledCode(code); len(suggestions) > 0 {
+					problems = append(problems, fmt.Sprintf("solbot.severity.%s is unknown%s", code, didYouMean(suggestions)))
+					continue
+				}
+				if _, ok := severities[overrides[code]]; !ok {
+					problems = append(problems, fmt.Sprintf("solbot.severity.%s has an invalid value %q, expected \"error\", \"warning\", \"information\", \"hint\" or \"off\"", code, overrides[code]))
+					continue
+				}
+				settings.Severity[code] = overrides[code]
+			}
+		case "detectors":
+			if err := json.Unmarshal(value, &settings.Detectors); err != nil {
+				problems = append(problems, "solbot.detectors is not an object of booleans")
+				continue
+			}
+			for _, code := range sortedKeys(settings.Detectors) {
+				if suggestions := misspelledCode(code); len(suggestions) > 0 {
+					problems = append(problems, fmt.Sprintf("solbot.detectors.%s is unknown%s", code, didYouMean(suggestions)))
+					delete(settings.Detectors, code)
+				}
+			}
+		case "inlayHints":
+			var categories map[string]json.RawMessage
+			if err := json.Unmarshal(value, &categories); err != nil {
+				problems = append(problems, "solbot.inlayHints is not an object")
+				continue
+			}
+			for _, category := range sortedKeys(categories) {
+				if category != "numbers" {
+					problems = append(problems, fmt.Sprintf("solbot.inlayHints.%s is unknown", category))
+					continue
+				}
+				if err := json.Unmarshal(categories[category], &settings.InlayHints.Numbers); err != nil {
+					problems = append(problems, "solbot.inlayHints.numbers is not a boolean")
+				}
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("solbot.%s is unknown", key))
+		}
+	}
+	return settings, problems
+}
+
+// diagnosticCodes are the codes of the diagnostics of the language server,
+// other than the ones of the metrics, of the migrations and of the
+// detectors of the analyzer, see knownCodes.
+var diagnosticCodes = []string{
+	"abicoder-v1-type", "always-false-condition", "always-true-condition",
+	"ambiguous-import", "balance-invariant", "calldata-write",
+	"code-clone", "conflicting-abicoder", "conflicting-remapping",
+	"contract-size", "could-be-view",
+	"cyclic-inheritance", "dead-store", "duplicate-catch", "duplicate-contract",
+	"encode-packed-collision",
+	"erc20-approve-race", "event-collision", "file-too-large",
+	"import-outside-workspace", "inconsistent-indentation",
+	"indexed-dynamic-type", "invalid-argument", "invalid-catch",
+	"invalid-data-location", "invalid-destructuring", "invalid-emit",
+	"invalid-remapping", "invalid-revert", "invalid-storage-pointer", "invalid-try",
+	"inverted-range",
+	"legacy-construct", "line-too-long", "lost-memory-write", "low-level",
+	"memory-copy-in-loop", "missing-data-location", "missing-final-newline",
+	"missing-implementation", "missing-initializer-modifier",
+	"missing-parent-init", "missing-placeholder", "missing-project-path",
+	"missing-remapping", "missing-remapping-target", "missing-super-call",
+	"missing-super-target", "mixed-indentation", "modifier-arity",
+	"multiple-placeholders", "mutability-violation", "natspec-missing",
+	"natspec-params", "natspec-returns", "natspec-units", "needless-abstract",
+	"non-payable-transfer", "parse-limit", "pragma-range", "recursive-modifier",
+	"redundant-abicoder", "same-operands", "selector-collision", "self-assignment",
+	"shadowed-remapping", "stale-signature-string", "storage-collision",
+	"swapped-arguments", "syntax-error", "too-many-indexed", "trailing-whitespace",
+	"transfer-gas-stipend",
+	"transient-read", "transient-type", "transient-version",
+	"unassigned-immutable", "unchecked-erc20-call", "undeclared-identifier", "undefined-modifier",
+	"unindexed-address", "unknown-implementation", "unknown-interface-id", "unreachable-code",
+	"unresolved-member", "unused-constructor-parameter", "unused-variable", "upgradeable-constructor",
+	"upgradeable-state-initializer", "yul-evm-version",
+}
+
+// knownCodes returns the codes the settings can refer to: the ones of the
+// diagnostics, of the metrics e.g. "complexity", of the migration rules
+// and of the detectors of the analyzer.
+func knownCodes() []string {
+	codes := append([]string{}, diagnosticCodes...)
+	codes = append(codes, "complexity", "statements", "parameters", "nesting")
+	for _, m := range migration.Migrations {
+		for _, rule := range m.Rules {
+			codes = append(codes, rule.Name)
+		}
+	}
+	for _, rule := range analyzer.Rules() {
+		codes = append(codes, rule.Code)
+	}
+	return codes
+}
+
+// misspelledCode returns the known codes the code is likely a typo of e.g.
+// "unused-variable" for "unused-varaible"; or nil if the code is known or
+// not close to any of them. The codes not close to any are kept, they may
+// come from the detectors this version doesn't know about.
+func misspelledCode(code string) []string {
+	codes := knownCodes()
+	if slices.Contains(codes, code) {
+		return nil
+	}
+	return suggest(code, codes)
+}
+
+// ApplySettings replaces the editor settings. It reports whether the
+// documents have to be analyzed again, since a detector was turned on or
+// off; the severities alone are applied when the diagnostics are
+// published, see Republish.
+func (s *State) ApplySettings(settings Settings) bool {
+	s.Settings = settings
+	disabled := s.Config.Disabled
+	s.Config = settings.apply(s.projectConfig)
+	s.restrict()
+	return !slices.Equal(disabled, s.Config.Disabled)
+}
+
+// apply returns the configuration of the project with the settings
+// overriding it.
+func (settings Settings) apply(cfg project.Config) project.Config {
+	disabled := []string{}
+	for _, code := range cfg.Disabled {
+		if enabled, ok := settings.Detectors[code]; !ok || !enabled {
+			disabled = append(disabled, code)
+		}
+	}
+	for _, code := range sortedKeys(settings.Detectors) {
+		if !settings.Detectors[code] && !slices.Contains(disabled, code) {
+			disabled = append(disabled, code)
+		}
+	}
+	cfg.Disabled = disabled
+	if settings.InlayHints.Numbers != nil {
+		cfg.InlayHints.Numbers = *settings.InlayHints.Numbers
+	}
+	return cfg
+}
+
+// overrideSeverities returns the diagnostics with the severities set in the
+// editor settings, without the ones turned off.
+func (s *State) overrideSeverities(diagnostics []lsp.Diagnostic) []lsp.Diagnostic {
+	if len(s.Settings.Severity) == 0 {
+		return diagnostics
+	}
+	res := []lsp.Diagnostic{}
+	for _, d := range diagnostics {
+		if name, ok := s.Settings.Severity[d.Code]; ok {
+			if severities[name] == 0 {
+				continue
+			}
+			d.Severity = severities[name]
+		}
+		res = append(res, d)
+	}
+	return res
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
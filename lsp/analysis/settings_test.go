@@ -0,0 +1,52 @@
+package analysis
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_ApplySettings(t *testing.T) {
+	s := NewState()
+	s.projectConfig.Disabled = []string{"erc20-approve-race", "screaming-snake-const"}
+	s.Config = s.projectConfig
+
+	settings, problems := ParseSettings([]byte(`{"detectors":{"erc20-approve-race":true,"unchecked-erc20-call":false},"inlayHints":{"numbers":false}}`))
+	if len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v", problems)
+	}
+	if !s.ApplySettings(settings) {
+		t.Errorf("Expected the detectors turned on and off to need the analysis")
+	}
+	// The editor settings take precedence over solbot.toml.
+	expected := []string{"screaming-snake-const", "unchecked-erc20-call"}
+	if !slices.Equal(s.Config.Disabled, expected) {
+		t.Errorf("Expected the disabled detectors %v, got %v", expected, s.Config.Disabled)
+	}
+	if s.Config.InlayHints.Numbers {
+		t.Errorf("Expected the number hints to be turned off")
+	}
+
+	settings, _ = ParseSettings([]byte(`{"severity":{"screaming-snake-const":"hint"},"detectors":{"erc20-approve-race":true,"unchecked-erc20-call":false}}`))
+	if s.ApplySettings(settings) {
+		t.Errorf("Expected the severities alone not to need the analysis")
+	}
+	if !s.Config.InlayHints.Numbers {
+		t.Errorf("Expected the number hints of solbot.toml back")
+	}
+}
+
+func Test_MisspelledCodes(t *testing.T) {
+	settings, problems := ParseSettings([]byte(`{"detectors":{"unused-varaible":false,"my-detector":false},"severity":{"erc20-aprove-race":"off"}}`))
+	expected := []string{
+		"solbot.detectors.unused-varaible is unknown, did you mean `unused-variable`?",
+		"solbot.severity.erc20-aprove-race is unknown, did you mean `erc20-approve-race`?",
+	}
+	if !slices.Equal(problems, expected) {
+		t.Errorf("Expected the problems %v, got %v", expected, problems)
+	}
+	// The codes far from the known ones may be of the detectors solbot
+	// doesn't know about.
+	if len(settings.Detectors) != 1 || len(settings.Severity) != 0 {
+		t.Errorf("Expected only my-detector to be kept, got %v and %v", settings.Detectors, settings.Severity)
+	}
+}
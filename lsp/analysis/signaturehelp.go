@@ -0,0 +1,205 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// SignatureHelp shows the signature of the function, event, error or struct
+// called at the position, with the parameter of the argument under the
+// cursor. The calls being typed e.g. `transfer(to, ` count too, their
+// missing arguments are parsed as ast.BadExpression. The first parameter of
+// a function attached with a using-for directive is the value before the
+// period, so it's not shown as an argument. The cheatcodes of the Foundry
+// tests show all of their overloads, the real ones and the ones of the
+// table missing from forge-std, see foundryLibraries.
+func (s *State) SignatureHelp(id lsp.ID, uri string, position lsp.Position) lsp.SignatureHelpResponse {
+	doc, ok := s.document(uri)
+	if !ok {
+		return lsp.NewSignatureHelpResponse(id, nil)
+	}
+	pos := toTokenPos(doc.Handle, position)
+	path := ast.PathEnclosingPos(doc.File, pos)
+	for i, node := range path {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || pos <= call.Lparen || pos > call.Rparen {
+			continue
+		}
+		callee := s.follow(s.resolveExpr(doc, path[i+1:], call.Function))
+		var lib *foundryLibrary
+		if access, ok := call.Function.(*ast.MemberAccessExpression); ok {
+			if callee == nil {
+				callee = s.attachedFunction(doc, path[i+1:], access.Member.Name)
+			}
+			lib, _ = s.foundryReceiver(doc, path[i+1:], access)
+		}
+		active := activeArgument(doc.Handle.Src(), call, pos)
+		if callee == nil {
+			if lib == nil {
+				return lsp.NewSignatureHelpResponse(id, nil)
+			}
+			return foundrySignatureHelp(id, lib.signatures(call.Function.(*ast.MemberAccessExpression).Member.Name, nil), active)
+		}
+		info, ok := signatureInformation(callee)
+		if !ok {
+			return lsp.NewSignatureHelpResponse(id, nil)
+		}
+		if len(info.Parameters) > 0 && s.isAttachedCall(doc, path[i+1:], call, callee) {
+			info.Parameters = info.Parameters[1:]
+		}
+		if fn, ok := callee.Node.(*ast.FunctionDeclaration); ok && lib != nil {
+			// The real overloads come first, the ones of the table only
+			// fill in what's missing.
+			signatures, declared := []lsp.SignatureInformation{}, []*ast.FunctionDeclaration{}
+			for _, overload := range declaredOverloads(callee) {
+				if info, ok := signatureInformation(overload); ok {
+					signatures = append(signatures, info)
+					declared = append(declared, overload.Node.(*ast.FunctionDeclaration))
+				}
+			}
+			return foundrySignatureHelp(id, append(signatures, lib.signatures(fn.Name.Name, declared)...), active)
+		}
+		return lsp.NewSignatureHelpResponse(id, &lsp.SignatureHelp{
+			Signatures:      []lsp.SignatureInformation{info},
+			ActiveParameter: active,
+		})
+	}
+	return lsp.NewSignatureHelpResponse(id, nil)
+}
+
+// foundrySignatureHelp returns the overloads of a function of forge-std,
+// with the first one taking the argument under the cursor active e.g.
+// `expectRevert(bytes4 revertData)` rather than `expectRevert()`.
+func foundrySignatureHelp(id lsp.ID, signatures []lsp.SignatureInformation, active int) lsp.SignatureHelpResponse {
+	if len(signatures) == 0 {
+		return lsp.NewSignatureHelpResponse(id, nil)
+	}
+	help := &lsp.SignatureHelp{Signatures: signatures, ActiveParameter: active}
+	for i, info := range signatures {
+		if len(info.Parameters) > active {
+			help.ActiveSignature = i
+			break
+		}
+	}
+	return lsp.NewSignatureHelpResponse(id, help)
+}
+
+// declaredOverloads returns the functions of the contract declaring the
+// function with the same name, the function included, in the order of
+// their declaration.
+func declaredOverloads(fn *Symbol) []*Symbol {
+	res := []*Symbol{}
+	c := enclosingContract(fn.Doc, ast.PathEnclosingPos(fn.Doc.File, fn.Node.Start()))
+	if c == nil {
+		return []*Symbol{fn}
+	}
+	for _, decl := range c.Node.(*ast.ContractDeclaration).Body {
+		if overload, ok := decl.(*ast.FunctionDeclaration); ok && overload.Name != nil && overload.Name.Name == fn.Name.Name {
+			res = append(res, &Symbol{Doc: fn.Doc, Name: overload.Name, Node: overload})
+		}
+	}
+	return res
+}
+
+// signatureInformation returns the signature of the callable declaration
+// with the offsets of its parameters in the label; or false if the
+// declaration is not callable.
+func signatureInformation(sym *Symbol) (lsp.SignatureInformation, bool) {
+	src := sym.Doc.Handle.Src()
+	info := lsp.SignatureInformation{Parameters: []lsp.ParameterInformation{}}
+	var params *ast.ParamList
+	switch n := sym.Node.(type) {
+	case *ast.FunctionDeclaration:
+		params = n.Type.Params
+	case *ast.EventDeclaration:
+		params = n.Params
+	case *ast.ErrorDeclaration:
+		params = n.Params
+	case *ast.StructDeclaration:
+		// The struct constructor takes the members in order e.g.
+		// `Position(address owner, uint256 amount)`.
+		label := sym.Name.Name + "("
+		for i, member := range n.Members {
+			if i > 0 {
+				label += ", "
+			}
+			text := strings.TrimRight(strings.TrimSpace(src[member.Start():member.End()]), ";")
+			info.Parameters = append(info.Parameters, lsp.ParameterInformation{
+				Label: [2]int{len(label), len(label) + len(text)},
+			})
+			label += text
+		}
+		info.Label = label + ")"
+		addDocumentation(&info, sym)
+		return info, true
+	default:
+		return info, false
+	}
+
+	info.Label = declarationHeader(sym)
+	start := int(sym.Node.Start())
+	if params != nil {
+		for _, param := range params.List {
+			from, to := int(param.Start())-start, int(param.End())-start
+			if from < 0 || to > len(info.Label) {
+				break
+			}
+			info.Parameters = append(info.Parameters, lsp.ParameterInformation{Label: [2]int{from, to}})
+		}
+	}
+	addDocumentation(&info, sym)
+	return info, true
+}
+
+// addDocumentation adds the NatSpec comment of the declaration, if any.
+func addDocumentation(info *lsp.SignatureInformation, sym *Symbol) {
+	if doc := natSpec(sym.Doc, sym.Node); doc != "" {
+		info.Documentation = &lsp.MarkupContent{Kind: lsp.PlainText, Value: doc}
+	}
+}
+
+// isAttachedCall reports whether the call is to a library or a free
+// function attached to the type of the value before the period e.g.
+// `amount.add(fee)`, as opposed to `SafeMath.add(amount, fee)`.
+func (s *State) isAttachedCall(doc *Document, path []ast.Node, call *ast.CallExpression, callee *Symbol) bool {
+	access, ok := call.Function.(*ast.MemberAccessExpression)
+	if !ok {
+		return false
+	}
+	if _, ok := callee.Node.(*ast.FunctionDeclaration); !ok {
+		return false
+	}
+	if base := s.follow(s.resolveExpr(doc, path, access.Expression)); base != nil {
+		if _, ok := base.Node.(*ast.ContractDeclaration); ok {
+			return false
+		}
+	}
+	for _, decl := range callee.Doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if ok && c.Start() <= callee.Node.Start() && callee.Node.End() <= c.End() {
+			return c.Kind == token.LIBRARY
+		}
+	}
+	return true // a free function
+}
+
+// activeArgument returns the index of the argument under the cursor. The
+// cursor after a comma is in the next argument, even if it's not typed yet.
+func activeArgument(src string, call *ast.CallExpression, pos token.Pos) int {
+	active := 0
+	for i, arg := range call.Args {
+		if pos <= arg.End() {
+			break
+		}
+		end := pos
+		if i+1 < len(call.Args) {
+			end = min(pos, call.Args[i+1].Start())
+		}
+		if arg.End() <= end && strings.Contains(src[arg.End():end], ",") {
+			active = i + 1
+		}
+	}
+	return active
+}
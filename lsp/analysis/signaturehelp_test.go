@@ -0,0 +1,125 @@
+package analysis
+
+import (
+	"context"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const payerSrc = `pragma solidity ^0.8.0;
+
+interface IERC20 {
+    /// Moves the tokens to the recipient.
+    function transfer(address to, uint256 amount) external returns (bool);
+}
+
+library Math {
+    function add(uint256 a, uint256 b) internal pure returns (uint256) {
+        return a + b;
+    }
+}
+
+contract Payer {
+    using Math for uint256;
+
+    struct Payment {
+        address to;
+        uint256 amount;
+    }
+
+    IERC20 token;
+
+    function pay(address recipient, uint256 amount) external {
+        token.transfer(recipient, amount.add(1));
+        Payment memory p = Payment(recipient, amount);
+    }
+}
+`
+
+func Test_SignatureHelp(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Payer.sol"
+	s.OpenDocument(uri, 1, payerSrc)
+
+	tests := []struct {
+		name     string
+		position lsp.Position
+		label    string
+		params   []string
+		active   int
+	}{
+		{"first argument", lsp.Position{Line: 24, Character: 23}, "function transfer(address to, uint256 amount) external returns (bool)",
+			[]string{"address to", "uint256 amount"}, 0},
+		{"second argument", lsp.Position{Line: 24, Character: 34}, "function transfer(address to, uint256 amount) external returns (bool)",
+			[]string{"address to", "uint256 amount"}, 1},
+		{"attached function", lsp.Position{Line: 24, Character: 45}, "function add(uint256 a, uint256 b) internal pure returns (uint256)",
+			[]string{"uint256 b"}, 0},
+		{"struct", lsp.Position{Line: 25, Character: 46}, "Payment(address to, uint256 amount)",
+			[]string{"address to", "uint256 amount"}, 1},
+	}
+	for _, tt := range tests {
+		help := s.SignatureHelp(lsp.IntID(1), uri, tt.position).Result
+		if help == nil || len(help.Signatures) != 1 {
+			t.Errorf("%s: expected a signature, got %v", tt.name, help)
+			continue
+		}
+		sig := help.Signatures[0]
+		if sig.Label != tt.label {
+			t.Errorf("%s: expected the label %q, got %q", tt.name, tt.label, sig.Label)
+			continue
+		}
+		params := []string{}
+		for _, p := range sig.Parameters {
+			params = append(params, sig.Label[p.Label[0]:p.Label[1]])
+		}
+		if strings.Join(params, ", ") != strings.Join(tt.params, ", ") {
+			t.Errorf("%s: expected the parameters %v, got %v", tt.name, tt.params, params)
+		}
+		if help.ActiveParameter != tt.active {
+			t.Errorf("%s: expected the active parameter %d, got %d", tt.name, tt.active, help.ActiveParameter)
+		}
+	}
+
+	// After the closing parenthesis of the call.
+	if help := s.SignatureHelp(lsp.IntID(1), uri, lsp.Position{Line: 24, Character: 48}).Result; help != nil {
+		t.Errorf("Expected no signature outside of the calls, got %v", help)
+	}
+}
+
+func Test_SignatureHelpWhileTyping(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Payer.sol"
+	typed := strings.Replace(payerSrc, "        token.transfer(recipient, amount.add(1));\n        Payment memory p = Payment(recipient, amount);\n",
+		"        token.transfer(recipient, \n", 1)
+	s.OpenDocument(uri, 1, typed)
+
+	help := s.SignatureHelp(lsp.IntID(1), uri, lsp.Position{Line: 24, Character: 34}).Result
+	if help == nil {
+		t.Fatalf("Expected a signature, got none")
+	}
+	if help.ActiveParameter != 1 {
+		t.Errorf("Expected the active parameter 1, got %d", help.ActiveParameter)
+	}
+	doc := help.Signatures[0].Documentation
+	if doc == nil || doc.Value != "Moves the tokens to the recipient." {
+		t.Errorf("Expected the NatSpec documentation, got %v", doc)
+	}
+
+	// The missing arguments don't crash the resolver, nor are they reported
+	// by the detectors.
+	nested := strings.Replace(payerSrc, "token.transfer(recipient, amount.add(1));", "token.transfer(Payment(recipient, , amount.add(, 1)), );", 1)
+	s.UpdateDocument(uri, 2, nested)
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Range.Start.Line == 24 {
+			t.Errorf("Expected no diagnostics for the incomplete call, got %s", d.Message)
+		}
+	}
+	for character := uint(0); character < 70; character++ {
+		position := lsp.Position{Line: 24, Character: character}
+		s.Hover(lsp.IntID(1), uri, position)
+		s.Definition(lsp.IntID(1), uri, position)
+		s.Completion(lsp.IntID(1), uri, position)
+		s.SignatureHelp(lsp.IntID(1), uri, position)
+	}
+}
@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// signaturePattern matches the string literals written like a signature
+// e.g. "transfer(address,uint256)" in abi.encodeWithSignature.
+var signaturePattern = regexp.MustCompile(`^"([A-Za-z_$][A-Za-z0-9_$]*)\(([A-Za-z0-9_$\[\](), ]*)\)"$`)
+
+// signatureString is a string literal written like a signature.
+type signatureString struct {
+	lit    *ast.BasicLit
+	name   string
+	params string // parameter types without the spaces e.g. "address,uint256"
+}
+
+// nameRange returns the range of the name inside of the quotes.
+func (sig signatureString) nameRange() token.Range {
+	start := sig.lit.Start() + 1
+	return token.Range{Start: start, End: start + token.Pos(len(sig.name))}
+}
+
+func signatureStrings(doc *Document) []signatureString {
+	res := []signatureString{}
+	ast.Inspect(doc.File, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		// The name is edited in place, which the parts of a concatenated
+		// literal don't allow.
+		if !ok || lit.Kind != token.STRING_LITERAL || lit.Parts != nil {
+			return true
+		}
+		if m := signaturePattern.FindStringSubmatch(lit.Value); m != nil {
+			res = append(res, signatureString{lit: lit, name: m[1], params: strings.ReplaceAll(m[2], " ", "")})
+		}
+		return true
+	})
+	return res
+}
+
+// renamedSignature is a function or an event renamed by the client. The
+// string literals keeping its old name are reported until they're updated.
+type renamedSignature struct {
+	kind    string // "function" or "event"
+	oldName string
+	newName string
+	params  []string // parameter types of the renamed declarations e.g. "address,uint256"
+}
+
+const signatureStringAnnotation = "signatureStrings"
+
+// renameSignatureStrings adds the edits of the string literals matching the
+// signatures of the renamed functions or events, both the name and the
+// parameter types. Since the match is heuristic, the edits need the
+// confirmation of the user, so they're only added if the client supports
+// the change annotations. The strings left behind, and the ones with the
+// same name but other parameters, are reported by signatureDiagnostics.
+// It reports whether any edit was added.
+func (s *State) renameSignatureStrings(group []*Symbol, newName string, edits map[*Document][]lsp.TextEdit) bool {
+	sig := renamedSignature{oldName: group[0].Name.Name, newName: newName}
+	for _, decl := range group {
+		var params *ast.ParamList
+		switch n := decl.Node.(type) {
+		case *ast.FunctionDeclaration:
+			sig.kind, params = "function", n.Type.Params
+		case *ast.EventDeclaration:
+			sig.kind, params = "event", n.Params
+		default:
+			return false
+		}
+		types := []ast.Expression{}
+		if params != nil {
+			for _, param := range params.List {
+				types = append(types, param.Type)
+			}
+		}
+		if signature, ok := s.signature(decl.Doc, sig.oldName, types); ok {
+			sig.params = append(sig.params, strings.TrimSuffix(strings.TrimPrefix(signature, sig.oldName+"("), ")"))
+		}
+	}
+	s.renamedSignatures = append(s.renamedSignatures, sig)
+
+	if s.workspaceEditCapabilities().ChangeAnnotationSupport == nil {
+		return false
+	}
+	added := false
+	for _, doc := range s.sortedDocuments() {
+		if s.isDependency(doc.URI) {
+			continue
+		}
+		for _, str := range signatureStrings(doc) {
+			if str.name == sig.oldName && slices.Contains(sig.params, str.params) {
+				edits[doc] = append(edits[doc], lsp.TextEdit{
+					Range:        toLspRange(doc.Handle, str.nameRange()),
+					NewText:      newName,
+					AnnotationID: signatureStringAnnotation,
+				})
+				added = true
+			}
+		}
+	}
+	return added
+}
+
+// signatureDiagnostics warns about the string literals which may still
+// refer to the renamed functions and events by their old names.
+func (s *State) signatureDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	if len(s.renamedSignatures) == 0 {
+		return res
+	}
+	for _, str := range signatureStrings(doc) {
+		for _, sig := range s.renamedSignatures {
+			if str.name != sig.oldName {
+				continue
+			}
+			value := strings.Trim(str.lit.Value, `"`)
+			message := fmt.Sprintf("String signature '%s' refers to the %s renamed to `%s`, but it wasn't updated", value, sig.kind, sig.newName)
+			if !slices.Contains(sig.params, str.params) {
+				message = fmt.Sprintf("String signature '%s' may refer to the %s renamed to `%s`, but the parameters differ", value, sig.kind, sig.newName)
+			}
+			res = append(res, lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, str.nameRange()),
+				Severity: lsp.SeverityWarning,
+				Code:     "stale-signature-string",
+				Source:   "solbot",
+				Message:  message,
+			})
+			break
+		}
+	}
+	return res
+}
@@ -0,0 +1,95 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+func Test_RenameSignatureStrings(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+contract Token {
+    function transfer(address to, uint256 amount) external {}
+}
+
+contract Caller {
+    function call(address token) external {
+        token.call(abi.encodeWithSignature("transfer(address,uint256)", msg.sender, 1));
+        token.call(abi.encodeWithSignature("transfer(address)", msg.sender));
+    }
+}
+`
+	tests := []struct {
+		name        string
+		annotations bool
+		edits       int
+		messages    []string
+	}{
+		{
+			name:        "with the change annotations",
+			annotations: true,
+			edits:       2,
+			messages: []string{
+				"String signature 'transfer(address)' may refer to the function renamed to `send`, but the parameters differ",
+			},
+		},
+		{
+			name:  "without the change annotations",
+			edits: 1,
+			messages: []string{
+				"String signature 'transfer(address,uint256)' refers to the function renamed to `send`, but it wasn't updated",
+				"String signature 'transfer(address)' may refer to the function renamed to `send`, but the parameters differ",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri := "file:///ws/src/Token.sol"
+			s := NewState()
+			s.Root = "/ws"
+			s.OpenDocument(uri, 1, src)
+			s.Capabilities.Workspace = &lsp.WorkspaceClientCapabilities{
+				WorkspaceEdit: &lsp.WorkspaceEditClientCapabilities{DocumentChanges: true},
+			}
+			if tt.annotations {
+				s.Capabilities.Workspace.WorkspaceEdit.ChangeAnnotationSupport = &lsp.ChangeAnnotationSupport{}
+			}
+
+			edit, err := s.rename(uri, lsp.Position{Line: 3, Character: 14}, "send")
+			if err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+			if len(edit.DocumentChanges) != 1 {
+				t.Fatalf("Expected 1 document change, got %d", len(edit.DocumentChanges))
+			}
+			change := edit.DocumentChanges[0].(lsp.TextDocumentEdit)
+			if len(change.Edits) != tt.edits {
+				t.Fatalf("Expected %d edits, got %d", tt.edits, len(change.Edits))
+			}
+			renamed := applyEdits(s.Documents[uri], change.Edits)
+			if tt.annotations {
+				if !strings.Contains(renamed, `"send(address,uint256)"`) || !strings.Contains(renamed, `"transfer(address)"`) {
+					t.Errorf("Expected only the matching signature to be renamed, got:\n%s", renamed)
+				}
+				if !edit.ChangeAnnotations[signatureStringAnnotation].NeedsConfirmation {
+					t.Errorf("Expected the signature strings to need a confirmation")
+				}
+			} else if _, ok := edit.ChangeAnnotations[signatureStringAnnotation]; ok {
+				t.Errorf("Expected no annotation of the signature strings")
+			}
+
+			s.UpdateDocument(uri, 2, renamed)
+			diagnostics := s.signatureDiagnostics(s.Documents[uri])
+			if len(diagnostics) != len(tt.messages) {
+				t.Fatalf("Expected %d diagnostics, got %d", len(tt.messages), len(diagnostics))
+			}
+			for i, message := range tt.messages {
+				if diagnostics[i].Message != message {
+					t.Errorf("Expected message %q, got %q", message, diagnostics[i].Message)
+				}
+			}
+		})
+	}
+}
@@ -1,67 +1,134 @@
 package analysis
 
 import (
-	"fmt"
+	"io"
+	"log/slog"
+	"solbot/ast"
+	"solbot/baseline"
 	"solbot/lsp"
+	"solbot/parser"
+	"solbot/project"
+	"solbot/token"
+	"solbot/vfs"
 )
 
 type State struct {
-	Documents map[string]string // file name -> file content
-}
+	Documents    map[string]*Document   // file URI -> parsed document
+	Root         string                 // workspace root directory; or empty
+	Config       project.Config         // project configuration e.g. remappings
+	Capabilities lsp.ClientCapabilities // capabilities announced by the client
+	Logger       *slog.Logger           // logs the analysis work; discards everything by default
+	Migrations   map[string]string      // file URI -> migration target previewed with the code action
+	Limits       Limits                 // bounds of the memory used by the documents
+	Settings     Settings               // editor settings overriding the configuration, see ApplySettings
+	Stats        Stats                  // counters of the work done
+	FS           vfs.FS                 // disk the workspace is read from and written to; vfs.OS by default
+	Baseline     *baseline.Baseline     // findings accepted by the team, see published; or nil
 
-func NewState() State {
-	return State{
-		Documents: map[string]string{},
-	}
+	projectConfig     project.Config                 // configuration read from the project files, before the settings
+	analyzed          map[string]analyzedDiagnostics // file URI -> diagnostics last computed, see Republish
+	renamedSignatures []renamedSignature             // renamed functions and events, see signatureDiagnostics
+	clock             uint64                         // number of the document uses, see use
+	referencesChanged bool                           // see ReferencesChanged
+	longLinesLogged   map[string]bool                // file URI -> whether its long lines were noted in the log, see isLongLine
+	sandbox           *vfs.Sandbox                   // FS restricted to the workspace; or nil until the root is known, see restrict
+	refused           map[string]bool                // paths the sandbox refused to read, see importDiagnostics
+	external          map[string]bool                // paths outside of the root already tried, see indexExternalImports
+	markdown          map[string]*markdownDocument   // file URI -> Markdown file with the Solidity code blocks, see setMarkdown
+	projectFiles      map[string]*projectFile        // file URI -> remappings.txt or foundry.toml open in the editor, see setProjectFile
+	ignore            *ignoreFilter                  // paths left out of the workspace; or nil until it's needed, see ignoreFilter
 }
 
-func (s *State) OpenDocument(uri, text string) {
-	s.Documents[uri] = text
+// Stats count the work done by the analysis, e.g. to check that applying
+// the settings doesn't parse the documents again.
+type Stats struct {
+	Parses   int // documents parsed, including the ones parsed again after Unload
+	Analyses int // diagnostics computed for a document
 }
 
-func (s *State) UpdateDocument(uri, text string) {
-	s.Documents[uri] = text
+// NewState returns the state with the limits of the parser of the command
+// line tools; the language server sets its own, see Limits.
+func NewState() *State {
+	return &State{
+		Documents:       map[string]*Document{},
+		Limits:          Limits{Parser: parser.DefaultLimits()},
+		Config:          project.DefaultConfig(),
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Migrations:      map[string]string{},
+		projectConfig:   project.DefaultConfig(),
+		analyzed:        map[string]analyzedDiagnostics{},
+		longLinesLogged: map[string]bool{},
+		FS:              vfs.OS{},
+		refused:         map[string]bool{},
+		external:        map[string]bool{},
+		markdown:        map[string]*markdownDocument{},
+		projectFiles:    map[string]*projectFile{},
+	}
 }
 
-func (s *State) Hover(id int, uri string, position lsp.Position) lsp.HoverResponse {
-	// @TODO: This should look up the type etc.
-
-	_, ok := s.Documents[uri]
-	if !ok {
-		return lsp.NewHoverResponse(id, "")
+// Initialize stores the client capabilities. It returns the root directory
+// of the workspace, to be indexed with IndexWorkspace or NewWarmup; or an
+// empty string if the client opened no folder.
+func (s *State) Initialize(params lsp.InitializeParams) string {
+	s.Capabilities = params.Capabilities
+	if params.RootURI == "" {
+		return ""
 	}
-
-	content := fmt.Sprintf("Hover in file: %s, line: %d, character: %d", uri, position.Line, position.Character)
-
-	return lsp.NewHoverResponse(id, content)
+	return URIToPath(params.RootURI)
 }
 
-func (s *State) Definition(
-	id int,
-	uri string,
-	position lsp.Position) lsp.DefinitionResponse {
-	_, ok := s.Documents[uri]
-	if !ok {
-		return lsp.NewDefinitionResponse(id, nil)
+func (s *State) OpenDocument(uri string, version int, text string) {
+	if s.IsProjectFile(uri) {
+		s.setProjectFile(uri, version, text)
+		return
 	}
+	if isMarkdown(uri) {
+		s.setMarkdown(uri, version, true, text)
+		return
+	}
+	s.setDocument(s.use(s.newDocument(uri, version, true, text)))
+}
 
-	locations := []lsp.Location{}
-
-	loc1 := lsp.Location{
-		URI: uri,
-		Range: lsp.Range{
-			Start: lsp.Position{
-				Line:      position.Line + 3,
-				Character: 0,
-			},
-			End: lsp.Position{
-				Line:      position.Line + 3,
-				Character: 0,
-			},
-		},
+// UpdateDocument parses the new content of the document and logs the edit
+// from the previous version, see Reanchor.
+func (s *State) UpdateDocument(uri string, version int, text string) {
+	if s.IsProjectFile(uri) {
+		s.setProjectFile(uri, version, text)
+		return
+	}
+	if isMarkdown(uri) {
+		s.setMarkdown(uri, version, true, text)
+		return
 	}
+	doc := s.use(s.newDocument(uri, version, true, text))
+	if prev, ok := s.Documents[uri]; ok {
+		doc.logEdit(prev)
+	}
+	s.setDocument(doc)
+}
 
-	locations = append(locations, loc1)
+// setDocument stores the opened or edited document, noting whether its
+// identifiers changed, see ReferencesChanged.
+func (s *State) setDocument(doc *Document) {
+	if prev, ok := s.Documents[doc.URI]; !ok || prev.names != doc.names {
+		s.referencesChanged = true
+	}
+	s.Documents[doc.URI] = doc
+}
 
-	return lsp.NewDefinitionResponse(id, &locations)
+// parseDocument parses the source within the limits, and returns the
+// errors of the limits it hit.
+func parseDocument(uri, src string, limits parser.Limits) (*token.File, *ast.File, []parser.Error) {
+	p := parser.Parser{}
+	handle := token.NewFile(uri, src)
+	p.Init(handle)
+	p.SetLimits(limits)
+	file := p.ParseFile()
+	var limited []parser.Error
+	for _, e := range p.Errors() {
+		if e.Limit {
+			limited = append(limited, e)
+		}
+	}
+	return handle, file, limited
 }
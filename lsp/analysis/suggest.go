@@ -0,0 +1,376 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// The names that can't be resolved are usually typos of the names in
+// scope e.g. `blanaces` for `balances`. The suggestions are the names
+// closest to the typo by the Damerau-Levenshtein distance, counting the
+// swapped neighbours as a single edit. They are computed for every
+// unresolved name of the code being typed, so the candidates are bounded
+// and the distance gives up as soon as it's above the threshold.
+
+// maxSuggestions is the number of the suggestions offered for a name.
+const maxSuggestions = 3
+
+// maxCandidates limits the candidates compared with a name e.g. the
+// members of a contract inheriting from a large library of bases.
+const maxCandidates = 1000
+
+// suggest returns up to maxSuggestions candidates closest to the name,
+// within the distance allowed for the length of the name. The closest come
+// first; of the equally close ones, those closer when the case is ignored
+// e.g. `Owner` for `owner`, then by the name.
+func suggest(name string, candidates []string) []string {
+	limit := suggestionDistance(name)
+	type match struct {
+		name     string
+		distance int
+		folded   int // distance with the case ignored
+	}
+	matches := []match{}
+	seen := map[string]bool{name: true}
+	for i, candidate := range candidates {
+		if i == maxCandidates {
+			break
+		}
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		d := editDistance(name, candidate, limit)
+		if d > limit {
+			continue
+		}
+		matches = append(matches, match{
+			name:     candidate,
+			distance: d,
+			folded:   editDistance(strings.ToLower(name), strings.ToLower(candidate), limit),
+		})
+	}
+
+	slices.SortFunc(matches, func(a, b match) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		if a.folded != b.folded {
+			return a.folded - b.folded
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	res := []string{}
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		res = append(res, matches[i].name)
+	}
+	return res
+}
+
+// suggestionDistance is the largest distance of a suggestion from the
+// name: a single edit for the short names, where any two of them are a few
+// edits apart, and one more for every four characters after that, up to
+// three edits.
+func suggestionDistance(name string) int {
+	return min(3, 1+max(0, len(name)-4)/4)
+}
+
+// editDistance returns the optimal string alignment distance of the two
+// names: the insertions, the deletions, the substitutions and the
+// transpositions of the neighbouring characters turning one into the
+// other. It returns limit+1 as soon as the distance is known to be larger
+// than the limit.
+func editDistance(a, b string, limit int) int {
+	if abs(len(a)-len(b)) > limit {
+		return limit + 1
+	}
+	// Three rows of the matrix: the one before the previous one is
+	// needed by the transpositions.
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		lowest := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			lowest = min(lowest, curr[j])
+		}
+		if lowest > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return min(prev[len(b)], limit+1)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// didYouMean returns the suggestions in a sentence to be appended to a
+// message e.g. ", did you mean `balances`?"; or an empty string if there
+// are none.
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	quoted := []string{}
+	for _, s := range suggestions {
+		quoted = append(quoted, "`"+s+"`")
+	}
+	if len(quoted) == 1 {
+		return fmt.Sprintf(", did you mean %s?", quoted[0])
+	}
+	return fmt.Sprintf(", did you mean %s or %s?", strings.Join(quoted[:len(quoted)-1], ", "), quoted[len(quoted)-1])
+}
+
+// visibleNames returns the names visible at the position: the local
+// variables declared before it, the parameters, the members of the
+// enclosing contract and its bases, the names of the file scope and the
+// builtins. The path starts with the innermost node enclosing the position,
+// the same as in lookup.
+func (s *State) visibleNames(doc *Document, path []ast.Node, pos token.Pos) []string {
+	res := []string{}
+	params := func(list *ast.ParamList) {
+		if list == nil {
+			return
+		}
+		for _, param := range list.List {
+			if param.Name != nil {
+				res = append(res, param.Name.Name)
+			}
+		}
+	}
+	locals := func(stmt *ast.VariableDeclarationStatement) {
+		for _, decl := range stmt.Declarations {
+			if decl != nil {
+				res = append(res, decl.Name.Name)
+			}
+		}
+	}
+
+	for i, node := range path {
+		switch n := node.(type) {
+		case *ast.BlockStatement:
+			for _, stmt := range n.Statements {
+				if stmt.End() > pos {
+					break
+				}
+				if decl, ok := stmt.(*ast.VariableDeclarationStatement); ok {
+					locals(decl)
+				}
+			}
+		case *ast.ForStatement:
+			if decl, ok := n.Init.(*ast.VariableDeclarationStatement); ok {
+				locals(decl)
+			}
+		case *ast.TryStatement:
+			if i > 0 && path[i-1] == ast.Node(n.Body) {
+				params(n.Returns)
+			}
+		case *ast.CatchClause:
+			params(n.Params)
+		case *ast.FunctionDeclaration:
+			params(n.Type.Params)
+			params(n.Type.Results)
+		case *ast.ModifierDeclaration:
+			params(n.Params)
+		case *ast.ContractDeclaration:
+			for _, member := range s.members(&Symbol{Doc: doc, Name: n.Name, Node: n}) {
+				res = append(res, member.Name.Name)
+			}
+		case *ast.File:
+			for _, sym := range s.fileSymbols(doc, map[*Document]bool{}) {
+				res = append(res, sym.Name.Name)
+			}
+		}
+	}
+	return append(res, sortedKeys(builtins)...)
+}
+
+// memberNames returns the names of the members of a scope returned by
+// scopeOf.
+func (s *State) memberNames(scope *Symbol) []string {
+	res := []string{}
+	for _, member := range s.members(scope) {
+		res = append(res, member.Name.Name)
+	}
+	return res
+}
+
+// misspelling is an unresolved name together with the names it's likely a
+// typo of.
+type misspelling struct {
+	ident       *ast.Identifier
+	code        string
+	message     string // without the suggestions
+	suggestions []string
+}
+
+// misspellings returns the names that can't be resolved although all of
+// the names they can refer to are known: the undeclared identifiers, the
+// missing members of the import unit aliases, of the contracts accessed by
+// their names, of the enums and of the values of the struct types, and the
+// unknown members in the struct constructors with the named arguments
+// e.g. `Position({amout: 1})`. The targets of the revert and emit
+// statements are left to referenceDiagnostics, and the names of the
+// modifiers to modifierDiagnostics.
+func (s *State) misspellings(doc *Document) []misspelling {
+	res := []misspelling{}
+	if !s.importsResolve(doc, map[*Document]bool{}) {
+		return res
+	}
+	targets := map[*ast.Identifier]bool{}
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.RevertStatement:
+			if n.Call != nil {
+				if ident, ok := n.Call.Function.(*ast.Identifier); ok {
+					targets[ident] = true
+				}
+			}
+		case *ast.EmitStatement:
+			if n.Call != nil {
+				if ident, ok := n.Call.Function.(*ast.Identifier); ok {
+					targets[ident] = true
+				}
+			}
+		}
+		return true
+	})
+
+	for _, ident := range s.unresolvedIdentifiers(doc) {
+		path := ast.PathEnclosingPos(doc.File, ident.Start())
+		if len(path) < 2 || path[0] != ast.Node(ident) {
+			continue
+		}
+		switch parent := path[1].(type) {
+		case *ast.MemberAccessExpression:
+			scope := s.namespace(doc, path[2:], parent.Expression)
+			if scope == nil {
+				continue
+			}
+			res = append(res, misspelling{
+				ident:       ident,
+				code:        "unresolved-member",
+				message:     fmt.Sprintf("`%s` has no member `%s`", ast.ExprString(parent.Expression), ident.Name),
+				suggestions: suggest(ident.Name, s.memberNames(scope)),
+			})
+		case *ast.CallExpression:
+			if !slices.Contains(parent.Names, ident) {
+				continue
+			}
+			callee := s.follow(s.resolveExpr(doc, path[2:], parent.Function))
+			if callee == nil {
+				continue
+			}
+			if _, ok := callee.Node.(*ast.StructDeclaration); !ok {
+				// The overloaded functions resolve to the first one.
+				continue
+			}
+			res = append(res, misspelling{
+				ident:       ident,
+				code:        "unresolved-member",
+				message:     fmt.Sprintf("`%s` has no member `%s`", ast.ExprString(parent.Function), ident.Name),
+				suggestions: suggest(ident.Name, s.memberNames(callee)),
+			})
+		case *ast.ModifierInvocation:
+			// Left to modifierDiagnostics.
+			continue
+		default:
+			// `byte` is the alias of bytes1 before 0.8.0, the migration
+			// reports it afterwards.
+			if targets[ident] || ident.Name == "byte" || slices.ContainsFunc(path, func(node ast.Node) bool {
+				_, ok := node.(*ast.ImportDirective)
+				return ok
+			}) {
+				continue
+			}
+			res = append(res, misspelling{
+				ident:       ident,
+				code:        "undeclared-identifier",
+				message:     fmt.Sprintf("Undeclared identifier `%s`", ident.Name),
+				suggestions: suggest(ident.Name, s.visibleNames(doc, path[1:], ident.Start())),
+			})
+		}
+	}
+	return res
+}
+
+// declaredNames returns the names visible at the path which are declared as
+// the errors, the events or the modifiers, by the kind "error", "event" or
+// "modifier".
+func (s *State) declaredNames(doc *Document, path []ast.Node, kind string) []string {
+	res := []string{}
+	pos := path[0].Start()
+	for _, name := range s.visibleNames(doc, path, pos) {
+		sym := s.follow(s.lookup(doc, path, name, pos))
+		if sym == nil {
+			continue
+		}
+		switch sym.Node.(type) {
+		case *ast.ErrorDeclaration:
+			if kind == "error" {
+				res = append(res, name)
+			}
+		case *ast.EventDeclaration:
+			if kind == "event" {
+				res = append(res, name)
+			}
+		case *ast.ModifierDeclaration:
+			if kind == "modifier" {
+				res = append(res, name)
+			}
+		}
+	}
+	return res
+}
+
+// suggestionActions replaces the misspelled names in the selection with
+// the suggestions, an action for each one. They are never preferred, the
+// right one is for the user to pick.
+func (s *State) suggestionActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	for _, m := range s.misspellings(doc) {
+		r := ast.NodeRange(m.ident)
+		if len(m.suggestions) == 0 || !touches(selected, r) {
+			continue
+		}
+		d := lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, r),
+			Severity: lsp.SeverityError,
+			Code:     m.code,
+			Source:   "solbot",
+			Message:  m.message + didYouMean(m.suggestions),
+		}
+		for _, suggestion := range m.suggestions {
+			actions = append(actions, lsp.CodeAction{
+				Title:       fmt.Sprintf("Change `%s` to `%s`", m.ident.Name, suggestion),
+				Kind:        lsp.CodeActionQuickFix,
+				Diagnostics: []lsp.Diagnostic{d},
+				Edit: &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+					doc.URI: {{Range: d.Range, NewText: suggestion}},
+				}},
+			})
+		}
+	}
+	return actions
+}
@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"context"
+	"slices"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const typoSrc = `pragma solidity ^0.8.0;
+
+contract Bank {
+    struct Position {
+        uint256 amount;
+        address owner;
+    }
+
+    mapping(address => uint256) balances;
+    Position[] positions;
+
+    function deposit() external payable {
+        blanaces[msg.sender] += msg.value;
+        positions.push(Position({amout: msg.value, owner: msg.sender}));
+    }
+
+    function total(uint256 i) external view returns (uint256) {
+        Position memory pos = positions[i];
+        return pos.amonut + qwerty;
+    }
+}
+`
+
+func typoDiagnostics(t *testing.T, s *State, uri string) []lsp.Diagnostic {
+	t.Helper()
+	res := []lsp.Diagnostic{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		if d.Code == "undeclared-identifier" || d.Code == "unresolved-member" {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func Test_SuggestDeclaredName(t *testing.T) {
+	uri := "file:///ws/src/Bank.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, typoSrc)
+
+	diagnostics := typoDiagnostics(t, s, uri)
+	if len(diagnostics) != 4 {
+		t.Fatalf("Expected 4 diagnostics, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != "undeclared-identifier" || d.Range.Start.Line != 12 || d.Message != "Undeclared identifier `blanaces`, did you mean `balances`?" {
+		t.Fatalf("Expected the typo of `balances`, got %s %q at line %d", d.Code, d.Message, d.Range.Start.Line)
+	}
+
+	actions := s.CodeAction(lsp.IntID(2), uri, d.Range).Result
+	if len(actions) != 1 || actions[0].Title != "Change `blanaces` to `balances`" || actions[0].IsPreferred {
+		t.Fatalf("Expected the action replacing the typo, got %v", actions)
+	}
+	fixed := applyEdits(s.Documents[uri], actions[0].Edit.Changes[uri])
+	if !strings.Contains(fixed, "        balances[msg.sender] += msg.value;\n") {
+		t.Fatalf("Expected the typo replaced, got:\n%s", fixed)
+	}
+	s.UpdateDocument(uri, 2, fixed)
+	if diagnostics := typoDiagnostics(t, s, uri); len(diagnostics) != 3 || strings.Contains(diagnostics[0].Message, "blanaces") {
+		t.Errorf("Expected the typo fixed, got %v", diagnostics)
+	}
+}
+
+func Test_SuggestStructMember(t *testing.T) {
+	uri := "file:///ws/src/Bank.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, typoSrc)
+
+	expected := []struct {
+		line    uint
+		message string
+	}{
+		{13, "`Position` has no member `amout`, did you mean `amount`?"},
+		{18, "`pos` has no member `amonut`, did you mean `amount`?"},
+	}
+	diagnostics := typoDiagnostics(t, s, uri)[1:]
+	for i, e := range expected {
+		if i >= len(diagnostics) {
+			t.Fatalf("Expected %q, got no diagnostic", e.message)
+		}
+		d := diagnostics[i]
+		if d.Code != "unresolved-member" || d.Range.Start.Line != e.line || d.Message != e.message {
+			t.Errorf("Expected %q at line %d, got %s %q at line %d", e.message, e.line, d.Code, d.Message, d.Range.Start.Line)
+		}
+	}
+}
+
+func Test_NoSuggestionsOutOfReach(t *testing.T) {
+	uri := "file:///ws/src/Bank.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, typoSrc)
+
+	diagnostics := typoDiagnostics(t, s, uri)
+	d := diagnostics[len(diagnostics)-1]
+	if d.Message != "Undeclared identifier `qwerty`" {
+		t.Fatalf("Expected `qwerty` without suggestions, got %q", d.Message)
+	}
+	if actions := s.CodeAction(lsp.IntID(2), uri, d.Range).Result; len(actions) != 0 {
+		t.Errorf("Expected no actions, got %v", actions)
+	}
+}
+
+func Test_Suggest(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		expected   []string
+	}{
+		// The swapped characters are a single edit.
+		{"trasnfer", []string{"transfer", "transferFrom", "approve"}, []string{"transfer"}},
+		// The ones differing only in the case come first.
+		{"owner", []string{"owners", "Owner", "owned"}, []string{"Owner", "owned", "owners"}},
+		{"totalSuply", []string{"totalSupply", "totalSupplyCap", "_totalSupply", "supply"}, []string{"totalSupply", "_totalSupply"}},
+		// A single edit for the short names.
+		{"fee", []string{"fees", "free", "foo", "x"}, []string{"fees", "free"}},
+		{"x", []string{"y"}, []string{"y"}},
+		{"amount", []string{"total", "value"}, []string{}},
+	}
+	for _, tt := range tests {
+		if got := suggest(tt.name, tt.candidates); !slices.Equal(got, tt.expected) {
+			t.Errorf("Expected the suggestions %v for %s, got %v", tt.expected, tt.name, got)
+		}
+	}
+}
+
+func Test_EditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		limit    int
+		expected int
+	}{
+		{"balances", "blanaces", 3, 2},
+		{"transfer", "trasnfer", 3, 1},
+		{"", "abc", 3, 3},
+		{"kitten", "sitting", 3, 3},
+		// The distance above the limit is cut off at limit+1.
+		{"kitten", "sitting", 2, 3},
+		{"a", "abcdef", 2, 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.limit); got != tt.expected {
+			t.Errorf("Expected the distance of %s and %s to be %d, got %d", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+error Paused();
+
+abstract contract Pausable {
+    bool public paused;
+    address public owner;
+
+    modifier whenNotPaused() {
+        require(!paused, "Pausable: the registry is paused");
+        _;
+    }
+
+    modifier whenNotPausedCustom() {
+        if (paused) revert Paused();
+        _;
+    }
+
+    function pause() external {
+        require(msg.sender == owner, "Pausable: only the owner can pause");
+        paused = true;
+    }
+}
@@ -0,0 +1,413 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+import {Pausable} from "./Pausable.sol";
+
+contract Registry is Pausable {
+    uint256 public constant MAX_VALUE = 1e30;
+    uint256 public count;
+    mapping(uint256 => address) public owners;
+
+    mapping(uint256 => uint256) public price0s;
+    mapping(uint256 => uint256) public limit0s;
+    mapping(uint256 => uint256) public fee0s;
+    mapping(uint256 => uint256) public delay0s;
+    mapping(uint256 => uint256) public weight0s;
+    mapping(uint256 => uint256) public quota0s;
+    mapping(uint256 => uint256) public bonus0s;
+    mapping(uint256 => uint256) public penalty0s;
+    mapping(uint256 => uint256) public cap0s;
+    mapping(uint256 => uint256) public floor0s;
+    mapping(uint256 => uint256) public price1s;
+    mapping(uint256 => uint256) public limit1s;
+    mapping(uint256 => uint256) public fee1s;
+    mapping(uint256 => uint256) public delay1s;
+    mapping(uint256 => uint256) public weight1s;
+    mapping(uint256 => uint256) public quota1s;
+    mapping(uint256 => uint256) public bonus1s;
+    mapping(uint256 => uint256) public penalty1s;
+    mapping(uint256 => uint256) public cap1s;
+    mapping(uint256 => uint256) public floor1s;
+    mapping(uint256 => uint256) public price2s;
+    mapping(uint256 => uint256) public limit2s;
+    mapping(uint256 => uint256) public fee2s;
+    mapping(uint256 => uint256) public delay2s;
+    mapping(uint256 => uint256) public weight2s;
+    mapping(uint256 => uint256) public quota2s;
+    mapping(uint256 => uint256) public bonus2s;
+    mapping(uint256 => uint256) public penalty2s;
+    mapping(uint256 => uint256) public cap2s;
+    mapping(uint256 => uint256) public floor2s;
+    mapping(uint256 => uint256) public price3s;
+    mapping(uint256 => uint256) public limit3s;
+    mapping(uint256 => uint256) public fee3s;
+    mapping(uint256 => uint256) public delay3s;
+    mapping(uint256 => uint256) public weight3s;
+    mapping(uint256 => uint256) public quota3s;
+    mapping(uint256 => uint256) public bonus3s;
+    mapping(uint256 => uint256) public penalty3s;
+    mapping(uint256 => uint256) public cap3s;
+    mapping(uint256 => uint256) public floor3s;
+
+    event Updated(uint256 indexed id, string field, uint256 value);
+
+    function setPrice0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the price0 must be positive, use clearPrice0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the price0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its price0");
+        price0s[id] = value;
+        emit Updated(id, "price0", value);
+    }
+
+    function setLimit0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the limit0 must be positive, use clearLimit0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the limit0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its limit0");
+        limit0s[id] = value;
+        emit Updated(id, "limit0", value);
+    }
+
+    function setFee0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the fee0 must be positive, use clearFee0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the fee0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its fee0");
+        fee0s[id] = value;
+        emit Updated(id, "fee0", value);
+    }
+
+    function setDelay0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the delay0 must be positive, use clearDelay0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the delay0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its delay0");
+        delay0s[id] = value;
+        emit Updated(id, "delay0", value);
+    }
+
+    function setWeight0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the weight0 must be positive, use clearWeight0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the weight0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its weight0");
+        weight0s[id] = value;
+        emit Updated(id, "weight0", value);
+    }
+
+    function setQuota0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the quota0 must be positive, use clearQuota0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the quota0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its quota0");
+        quota0s[id] = value;
+        emit Updated(id, "quota0", value);
+    }
+
+    function setBonus0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the bonus0 must be positive, use clearBonus0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the bonus0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its bonus0");
+        bonus0s[id] = value;
+        emit Updated(id, "bonus0", value);
+    }
+
+    function setPenalty0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the penalty0 must be positive, use clearPenalty0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the penalty0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its penalty0");
+        penalty0s[id] = value;
+        emit Updated(id, "penalty0", value);
+    }
+
+    function setCap0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the cap0 must be positive, use clearCap0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the cap0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its cap0");
+        cap0s[id] = value;
+        emit Updated(id, "cap0", value);
+    }
+
+    function setFloor0(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the floor0 must be positive, use clearFloor0 to remove it");
+        require(value <= MAX_VALUE, "Registry: the floor0 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its floor0");
+        floor0s[id] = value;
+        emit Updated(id, "floor0", value);
+    }
+
+    function setPrice1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the price1 must be positive, use clearPrice1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the price1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its price1");
+        price1s[id] = value;
+        emit Updated(id, "price1", value);
+    }
+
+    function setLimit1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the limit1 must be positive, use clearLimit1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the limit1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its limit1");
+        limit1s[id] = value;
+        emit Updated(id, "limit1", value);
+    }
+
+    function setFee1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the fee1 must be positive, use clearFee1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the fee1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its fee1");
+        fee1s[id] = value;
+        emit Updated(id, "fee1", value);
+    }
+
+    function setDelay1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the delay1 must be positive, use clearDelay1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the delay1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its delay1");
+        delay1s[id] = value;
+        emit Updated(id, "delay1", value);
+    }
+
+    function setWeight1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the weight1 must be positive, use clearWeight1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the weight1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its weight1");
+        weight1s[id] = value;
+        emit Updated(id, "weight1", value);
+    }
+
+    function setQuota1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the quota1 must be positive, use clearQuota1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the quota1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its quota1");
+        quota1s[id] = value;
+        emit Updated(id, "quota1", value);
+    }
+
+    function setBonus1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the bonus1 must be positive, use clearBonus1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the bonus1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its bonus1");
+        bonus1s[id] = value;
+        emit Updated(id, "bonus1", value);
+    }
+
+    function setPenalty1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the penalty1 must be positive, use clearPenalty1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the penalty1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its penalty1");
+        penalty1s[id] = value;
+        emit Updated(id, "penalty1", value);
+    }
+
+    function setCap1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the cap1 must be positive, use clearCap1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the cap1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its cap1");
+        cap1s[id] = value;
+        emit Updated(id, "cap1", value);
+    }
+
+    function setFloor1(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the floor1 must be positive, use clearFloor1 to remove it");
+        require(value <= MAX_VALUE, "Registry: the floor1 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its floor1");
+        floor1s[id] = value;
+        emit Updated(id, "floor1", value);
+    }
+
+    function setPrice2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the price2 must be positive, use clearPrice2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the price2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its price2");
+        price2s[id] = value;
+        emit Updated(id, "price2", value);
+    }
+
+    function setLimit2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the limit2 must be positive, use clearLimit2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the limit2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its limit2");
+        limit2s[id] = value;
+        emit Updated(id, "limit2", value);
+    }
+
+    function setFee2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the fee2 must be positive, use clearFee2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the fee2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its fee2");
+        fee2s[id] = value;
+        emit Updated(id, "fee2", value);
+    }
+
+    function setDelay2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the delay2 must be positive, use clearDelay2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the delay2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its delay2");
+        delay2s[id] = value;
+        emit Updated(id, "delay2", value);
+    }
+
+    function setWeight2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the weight2 must be positive, use clearWeight2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the weight2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its weight2");
+        weight2s[id] = value;
+        emit Updated(id, "weight2", value);
+    }
+
+    function setQuota2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the quota2 must be positive, use clearQuota2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the quota2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its quota2");
+        quota2s[id] = value;
+        emit Updated(id, "quota2", value);
+    }
+
+    function setBonus2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the bonus2 must be positive, use clearBonus2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the bonus2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its bonus2");
+        bonus2s[id] = value;
+        emit Updated(id, "bonus2", value);
+    }
+
+    function setPenalty2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the penalty2 must be positive, use clearPenalty2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the penalty2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its penalty2");
+        penalty2s[id] = value;
+        emit Updated(id, "penalty2", value);
+    }
+
+    function setCap2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the cap2 must be positive, use clearCap2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the cap2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its cap2");
+        cap2s[id] = value;
+        emit Updated(id, "cap2", value);
+    }
+
+    function setFloor2(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the floor2 must be positive, use clearFloor2 to remove it");
+        require(value <= MAX_VALUE, "Registry: the floor2 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its floor2");
+        floor2s[id] = value;
+        emit Updated(id, "floor2", value);
+    }
+
+    function setPrice3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the price3 must be positive, use clearPrice3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the price3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its price3");
+        price3s[id] = value;
+        emit Updated(id, "price3", value);
+    }
+
+    function setLimit3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the limit3 must be positive, use clearLimit3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the limit3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its limit3");
+        limit3s[id] = value;
+        emit Updated(id, "limit3", value);
+    }
+
+    function setFee3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the fee3 must be positive, use clearFee3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the fee3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its fee3");
+        fee3s[id] = value;
+        emit Updated(id, "fee3", value);
+    }
+
+    function setDelay3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the delay3 must be positive, use clearDelay3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the delay3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its delay3");
+        delay3s[id] = value;
+        emit Updated(id, "delay3", value);
+    }
+
+    function setWeight3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the weight3 must be positive, use clearWeight3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the weight3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its weight3");
+        weight3s[id] = value;
+        emit Updated(id, "weight3", value);
+    }
+
+    function setQuota3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the quota3 must be positive, use clearQuota3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the quota3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its quota3");
+        quota3s[id] = value;
+        emit Updated(id, "quota3", value);
+    }
+
+    function setBonus3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the bonus3 must be positive, use clearBonus3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the bonus3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its bonus3");
+        bonus3s[id] = value;
+        emit Updated(id, "bonus3", value);
+    }
+
+    function setPenalty3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the penalty3 must be positive, use clearPenalty3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the penalty3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its penalty3");
+        penalty3s[id] = value;
+        emit Updated(id, "penalty3", value);
+    }
+
+    function setCap3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the cap3 must be positive, use clearCap3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the cap3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its cap3");
+        cap3s[id] = value;
+        emit Updated(id, "cap3", value);
+    }
+
+    function setFloor3(uint256 id, uint256 value) external whenNotPaused {
+        require(id < count, "Registry: the entry id is out of range, register the entry first");
+        require(value > 0, "Registry: the floor3 must be positive, use clearFloor3 to remove it");
+        require(value <= MAX_VALUE, "Registry: the floor3 is above the maximum value of the registry");
+        require(msg.sender == owners[id], "Registry: only the owner of the entry can update its floor3");
+        floor3s[id] = value;
+        emit Updated(id, "floor3", value);
+    }
+}
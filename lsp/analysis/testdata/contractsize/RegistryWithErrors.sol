@@ -0,0 +1,418 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+import {Pausable} from "./Pausable.sol";
+
+error EntryOutOfRange(uint256 id);
+error ZeroValue(uint256 id);
+error ValueTooLarge(uint256 id, uint256 value);
+error NotEntryOwner(uint256 id, address sender);
+
+contract RegistryWithErrors is Pausable {
+    uint256 public constant MAX_VALUE = 1e30;
+    uint256 public count;
+    mapping(uint256 => address) public owners;
+
+    mapping(uint256 => uint256) public price0s;
+    mapping(uint256 => uint256) public limit0s;
+    mapping(uint256 => uint256) public fee0s;
+    mapping(uint256 => uint256) public delay0s;
+    mapping(uint256 => uint256) public weight0s;
+    mapping(uint256 => uint256) public quota0s;
+    mapping(uint256 => uint256) public bonus0s;
+    mapping(uint256 => uint256) public penalty0s;
+    mapping(uint256 => uint256) public cap0s;
+    mapping(uint256 => uint256) public floor0s;
+    mapping(uint256 => uint256) public price1s;
+    mapping(uint256 => uint256) public limit1s;
+    mapping(uint256 => uint256) public fee1s;
+    mapping(uint256 => uint256) public delay1s;
+    mapping(uint256 => uint256) public weight1s;
+    mapping(uint256 => uint256) public quota1s;
+    mapping(uint256 => uint256) public bonus1s;
+    mapping(uint256 => uint256) public penalty1s;
+    mapping(uint256 => uint256) public cap1s;
+    mapping(uint256 => uint256) public floor1s;
+    mapping(uint256 => uint256) public price2s;
+    mapping(uint256 => uint256) public limit2s;
+    mapping(uint256 => uint256) public fee2s;
+    mapping(uint256 => uint256) public delay2s;
+    mapping(uint256 => uint256) public weight2s;
+    mapping(uint256 => uint256) public quota2s;
+    mapping(uint256 => uint256) public bonus2s;
+    mapping(uint256 => uint256) public penalty2s;
+    mapping(uint256 => uint256) public cap2s;
+    mapping(uint256 => uint256) public floor2s;
+    mapping(uint256 => uint256) public price3s;
+    mapping(uint256 => uint256) public limit3s;
+    mapping(uint256 => uint256) public fee3s;
+    mapping(uint256 => uint256) public delay3s;
+    mapping(uint256 => uint256) public weight3s;
+    mapping(uint256 => uint256) public quota3s;
+    mapping(uint256 => uint256) public bonus3s;
+    mapping(uint256 => uint256) public penalty3s;
+    mapping(uint256 => uint256) public cap3s;
+    mapping(uint256 => uint256) public floor3s;
+
+    event Updated(uint256 indexed id, string field, uint256 value);
+
+    function setPrice0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        price0s[id] = value;
+        emit Updated(id, "price0", value);
+    }
+
+    function setLimit0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        limit0s[id] = value;
+        emit Updated(id, "limit0", value);
+    }
+
+    function setFee0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        fee0s[id] = value;
+        emit Updated(id, "fee0", value);
+    }
+
+    function setDelay0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        delay0s[id] = value;
+        emit Updated(id, "delay0", value);
+    }
+
+    function setWeight0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        weight0s[id] = value;
+        emit Updated(id, "weight0", value);
+    }
+
+    function setQuota0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        quota0s[id] = value;
+        emit Updated(id, "quota0", value);
+    }
+
+    function setBonus0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        bonus0s[id] = value;
+        emit Updated(id, "bonus0", value);
+    }
+
+    function setPenalty0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        penalty0s[id] = value;
+        emit Updated(id, "penalty0", value);
+    }
+
+    function setCap0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        cap0s[id] = value;
+        emit Updated(id, "cap0", value);
+    }
+
+    function setFloor0(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        floor0s[id] = value;
+        emit Updated(id, "floor0", value);
+    }
+
+    function setPrice1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        price1s[id] = value;
+        emit Updated(id, "price1", value);
+    }
+
+    function setLimit1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        limit1s[id] = value;
+        emit Updated(id, "limit1", value);
+    }
+
+    function setFee1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        fee1s[id] = value;
+        emit Updated(id, "fee1", value);
+    }
+
+    function setDelay1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        delay1s[id] = value;
+        emit Updated(id, "delay1", value);
+    }
+
+    function setWeight1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        weight1s[id] = value;
+        emit Updated(id, "weight1", value);
+    }
+
+    function setQuota1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        quota1s[id] = value;
+        emit Updated(id, "quota1", value);
+    }
+
+    function setBonus1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        bonus1s[id] = value;
+        emit Updated(id, "bonus1", value);
+    }
+
+    function setPenalty1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        penalty1s[id] = value;
+        emit Updated(id, "penalty1", value);
+    }
+
+    function setCap1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        cap1s[id] = value;
+        emit Updated(id, "cap1", value);
+    }
+
+    function setFloor1(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        floor1s[id] = value;
+        emit Updated(id, "floor1", value);
+    }
+
+    function setPrice2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        price2s[id] = value;
+        emit Updated(id, "price2", value);
+    }
+
+    function setLimit2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        limit2s[id] = value;
+        emit Updated(id, "limit2", value);
+    }
+
+    function setFee2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        fee2s[id] = value;
+        emit Updated(id, "fee2", value);
+    }
+
+    function setDelay2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        delay2s[id] = value;
+        emit Updated(id, "delay2", value);
+    }
+
+    function setWeight2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        weight2s[id] = value;
+        emit Updated(id, "weight2", value);
+    }
+
+    function setQuota2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        quota2s[id] = value;
+        emit Updated(id, "quota2", value);
+    }
+
+    function setBonus2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        bonus2s[id] = value;
+        emit Updated(id, "bonus2", value);
+    }
+
+    function setPenalty2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        penalty2s[id] = value;
+        emit Updated(id, "penalty2", value);
+    }
+
+    function setCap2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        cap2s[id] = value;
+        emit Updated(id, "cap2", value);
+    }
+
+    function setFloor2(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        floor2s[id] = value;
+        emit Updated(id, "floor2", value);
+    }
+
+    function setPrice3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        price3s[id] = value;
+        emit Updated(id, "price3", value);
+    }
+
+    function setLimit3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        limit3s[id] = value;
+        emit Updated(id, "limit3", value);
+    }
+
+    function setFee3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        fee3s[id] = value;
+        emit Updated(id, "fee3", value);
+    }
+
+    function setDelay3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        delay3s[id] = value;
+        emit Updated(id, "delay3", value);
+    }
+
+    function setWeight3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        weight3s[id] = value;
+        emit Updated(id, "weight3", value);
+    }
+
+    function setQuota3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        quota3s[id] = value;
+        emit Updated(id, "quota3", value);
+    }
+
+    function setBonus3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        bonus3s[id] = value;
+        emit Updated(id, "bonus3", value);
+    }
+
+    function setPenalty3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        penalty3s[id] = value;
+        emit Updated(id, "penalty3", value);
+    }
+
+    function setCap3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        cap3s[id] = value;
+        emit Updated(id, "cap3", value);
+    }
+
+    function setFloor3(uint256 id, uint256 value) external whenNotPausedCustom {
+        if (id >= count) revert EntryOutOfRange(id);
+        if (value == 0) revert ZeroValue(id);
+        if (value > MAX_VALUE) revert ValueTooLarge(id, value);
+        if (msg.sender != owners[id]) revert NotEntryOwner(id, msg.sender);
+        floor3s[id] = value;
+        emit Updated(id, "floor3", value);
+    }
+}
@@ -0,0 +1,10 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+contract Broken {
+    uint256 x
+
+    function set(uint256 value) external {
+        x = value
+    }
+}
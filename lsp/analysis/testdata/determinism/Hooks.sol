@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+abstract contract Base {
+    function _update(address from, address to, uint256 amount) internal virtual {}
+}
+
+abstract contract Paused is Base {
+    bool paused;
+
+    function _update(address from, address to, uint256 amount) internal virtual override {
+        require(!paused);
+        super._update(from, to, amount);
+    }
+}
+
+abstract contract Capped is Base {
+    uint256 cap;
+
+    function _update(address from, address to, uint256 amount) internal virtual override {
+        require(amount <= cap);
+        super._update(from, to, amount);
+    }
+}
+
+abstract contract Hooks is Paused, Capped {
+    modifier whenNotPaused() {
+        require(!paused, "paused");
+    }
+}
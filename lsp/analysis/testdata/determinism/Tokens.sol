@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+import {Vault} from "./Vault.sol";
+import "./Hooks.sol";
+
+contract Token is Hooks {
+    mapping(address => uint256) balances;
+    string name;
+
+    function _update(address from, address to, uint256 amount) internal override {
+        balances[from] -= amount;
+        balances[to] += amount;
+    }
+
+    function digest(string memory a, bytes memory b) external view returns (bytes32) {
+        return keccak256(abi.encodePacked(a, b, name));
+    }
+
+    function call(address to) external pure returns (bytes memory) {
+        return abi.encodeWithSelector(true, to);
+    }
+}
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+contract Vault {
+    struct Position {
+        uint256 amount;
+        uint256 rewards;
+    }
+
+    address owner;
+    Position[] positions;
+
+    function claim(uint256 i) external {
+        Position memory p = positions[i];
+        p.rewards = 0;
+    }
+
+    function total() external view returns (uint256 sum) {
+        for (uint256 i = 0; i < positions.length; i++) {
+            Position memory p = positions[i];
+            sum += p.amount + Vault.unknown;
+        }
+    }
+}
+
+contract VaultProxy {
+    address admin;
+    address immutable implementation;
+
+    constructor(Vault _vault) {
+        admin = msg.sender;
+        implementation = address(_vault);
+    }
+
+    fallback() external payable {
+        (bool ok, ) = implementation.delegatecall(msg.data);
+        require(ok);
+    }
+}
@@ -0,0 +1,38 @@
+pragma solidity ^0.4.24;
+
+contract Crowdsale {
+    address owner;
+    uint256 public rate;
+    uint256 public closingTime;
+    mapping(address => uint256) contributions;
+
+    function Crowdsale(uint256 _rate) {
+        owner = msg.sender;
+        rate = _rate;
+        closingTime = now + 1 years;
+    }
+
+    function () payable {
+        buy(msg.sender);
+    }
+
+    function buy(address beneficiary) payable {
+        if (now > closingTime) throw;
+        var previous = contributions[beneficiary];
+        contributions[beneficiary] = previous + msg.value * rate;
+    }
+
+    function quote(uint256 value) constant returns (uint256, uint256) {
+        return (value * rate, closingTime);
+    }
+
+    function preview() constant returns (bytes32) {
+        var (tokens, until) = quote(1 ether);
+        return sha3(tokens, until);
+    }
+
+    function close() {
+        require(msg.sender == owner);
+        suicide(owner);
+    }
+}
@@ -0,0 +1,4 @@
+// SPDX-License-Identifier: MIT
+// Generated: a flattened contract on a single line.
+pragma solidity ^0.8.0;
+contract Flattened { uint256 constant FEE = 1000000000000000000; function f0(uint256 amount) external pure returns (uint256) { return amount * FEE + 0; } function f1(uint256 amount) external pure returns (uint256) { return amount * FEE + 1; } function f2(uint256 amount) external pure returns (uint256) { return amount * FEE + 2; } function f3(uint256 amount) external pure returns (uint256) { return amount * FEE + 3; } function f4(uint256 amount) external pure returns (uint256) { return amount * FEE + 4; } function f5(uint256 amount) external pure returns (uint256) { return amount * FEE + 5; } function f6(uint256 amount) external pure returns (uint256) { return amount * FEE + 6; } function f7(uint256 amount) external pure returns (uint256) { return amount * FEE + 7; } function f8(uint256 amount) external pure returns (uint256) { return amount * FEE + 8; } function f9(uint256 amount) external pure returns (uint256) { return amount * FEE + 9; } function f10(uint256 amount) external pure returns (uint256) { return amount * FEE + 10; } function f11(uint256 amount) external pure returns (uint256) { return amount * FEE + 11; } function f12(uint256 amount) external pure returns (uint256) { return amount * FEE + 12; } function f13(uint256 amount) external pure returns (uint256) { return amount * FEE + 13; } function f14(uint256 amount) external pure returns (uint256) { return amount * FEE + 14; } function f15(uint256 amount) external pure returns (uint256) { return amount * FEE + 15; } function f16(uint256 amount) external pure returns (uint256) { return amount * FEE + 16; } function f17(uint256 amount) external pure returns (uint256) { return amount * FEE + 17; } function f18(uint256 amount) external pure returns (uint256) { return amount * FEE + 18; } function f19(uint256 amount) external pure returns (uint256) { return amount * FEE + 19; } function f20(uint256 amount) external pure returns (uint256) { return amount * FEE + 20; } function f21(uint256 amount) external pure returns (uint256) { return amount * FEE + 21; } function f22(uint256 amount) external pure returns (uint256) { return amount * FEE + 22; } function f23(uint256 amount) external pure returns (uint256) { return amount * FEE + 23; } function f24(uint256 amount) external pure returns (uint256) { return amount * FEE + 24; } function f25(uint256 amount) external pure returns (uint256) { return amount * FEE + 25; } function f26(uint256 amount) external pure returns (uint256) { return amount * FEE + 26; } function f27(uint256 amount) external pure returns (uint256) { return amount * FEE + 27; } function f28(uint256 amount) external pure returns (uint256) { return amount * FEE + 28; } function f29(uint256 amount) external pure returns (uint256) { return amount * FEE + 29; } function f30(uint256 amount) external pure returns (uint256) { return amount * FEE + 30; } function f31(uint256 amount) external pure returns (uint256) { return amount * FEE + 31; } function f32(uint256 amount) external pure returns (uint256) { return amount * FEE + 32; } function f33(uint256 amount) external pure returns (uint256) { return amount * FEE + 33; } function f34(uint256 amount) external pure returns (uint256) { return amount * FEE + 34; } function f35(uint256 amount) external pure returns (uint256) { return amount * FEE + 35; } function f36(uint256 amount) external pure returns (uint256) { return amount * FEE + 36; } function f37(uint256 amount) external pure returns (uint256) { return amount * FEE + 37; } function f38(uint256 amount) external pure returns (uint256) { return amount * FEE + 38; } function f39(uint256 amount) external pure returns (uint256) { return amount * FEE + 39; } function f40(uint256 amount) external pure returns (uint256) { return amount * FEE + 40; } function f41(uint256 amount) external pure returns (uint256) { return amount * FEE + 41; } function f42(uint256 amount) external pure returns (uint256) { return amount * FEE + 42; } function f43(uint256 amount) external pure returns (uint256) { return amount * FEE + 43; } function f44(uint256 amount) external pure returns (uint256) { return amount * FEE + 44; } function f45(uint256 amount) external pure returns (uint256) { return amount * FEE + 45; } function f46(uint256 amount) external pure returns (uint256) { return amount * FEE + 46; } function f47(uint256 amount) external pure returns (uint256) { return amount * FEE + 47; } function f48(uint256 amount) external pure returns (uint256) { return amount * FEE + 48; } function f49(uint256 amount) external pure returns (uint256) { return amount * FEE + 49; } function f50(uint256 amount) external pure returns (uint256) { return amount * FEE + 50; } function f51(uint256 amount) external pure returns (uint256) { return amount * FEE + 51; } function f52(uint256 amount) external pure returns (uint256) { return amount * FEE + 52; } function f53(uint256 amount) external pure returns (uint256) { return amount * FEE + 53; } function f54(uint256 amount) external pure returns (uint256) { return amount * FEE + 54; } function f55(uint256 amount) external pure returns (uint256) { return amount * FEE + 55; } function f56(uint256 amount) external pure returns (uint256) { return amount * FEE + 56; } function f57(uint256 amount) external pure returns (uint256) { return amount * FEE + 57; } function f58(uint256 amount) external pure returns (uint256) { return amount * FEE + 58; } function f59(uint256 amount) external pure returns (uint256) { return amount * FEE + 59; } function f60(uint256 amount) external pure returns (uint256) { return amount * FEE + 60; } function f61(uint256 amount) external pure returns (uint256) { return amount * FEE + 61; } function f62(uint256 amount) external pure returns (uint256) { return amount * FEE + 62; } function f63(uint256 amount) external pure returns (uint256) { return amount * FEE + 63; } function f64(uint256 amount) external pure returns (uint256) { return amount * FEE + 64; } function f65(uint256 amount) external pure returns (uint256) { return amount * FEE + 65; } function f66(uint256 amount) external pure returns (uint256) { return amount * FEE + 66; } function f67(uint256 amount) external pure returns (uint256) { return amount * FEE + 67; } function f68(uint256 amount) external pure returns (uint256) { return amount * FEE + 68; } function f69(uint256 amount) external pure returns (uint256) { return amount * FEE + 69; } function f70(uint256 amount) external pure returns (uint256) { return amount * FEE + 70; } function f71(uint256 amount) external pure returns (uint256) { return amount * FEE + 71; } function f72(uint256 amount) external pure returns (uint256) { return amount * FEE + 72; } function f73(uint256 amount) external pure returns (uint256) { return amount * FEE + 73; } function f74(uint256 amount) external pure returns (uint256) { return amount * FEE + 74; } function f75(uint256 amount) external pure returns (uint256) { return amount * FEE + 75; } function f76(uint256 amount) external pure returns (uint256) { return amount * FEE + 76; } function f77(uint256 amount) external pure returns (uint256) { return amount * FEE + 77; } function f78(uint256 amount) external pure returns (uint256) { return amount * FEE + 78; } function f79(uint256 amount) external pure returns (uint256) { return amount * FEE + 79; } function f80(uint256 amount) external pure returns (uint256) { return amount * FEE + 80; } function f81(uint256 amount) external pure returns (uint256) { return amount * FEE + 81; } function f82(uint256 amount) external pure returns (uint256) { return amount * FEE + 82; } function f83(uint256 amount) external pure returns (uint256) { return amount * FEE + 83; } function f84(uint256 amount) external pure returns (uint256) { return amount * FEE + 84; } function f85(uint256 amount) external pure returns (uint256) { return amount * FEE + 85; } function f86(uint256 amount) external pure returns (uint256) { return amount * FEE + 86; } function f87(uint256 amount) external pure returns (uint256) { return amount * FEE + 87; } function f88(uint256 amount) external pure returns (uint256) { return amount * FEE + 88; } function f89(uint256 amount) external pure returns (uint256) { return amount * FEE + 89; } function f90(uint256 amount) external pure returns (uint256) { return amount * FEE + 90; } function f91(uint256 amount) external pure returns (uint256) { return amount * FEE + 91; } function f92(uint256 amount) external pure returns (uint256) { return amount * FEE + 92; } function f93(uint256 amount) external pure returns (uint256) { return amount * FEE + 93; } function f94(uint256 amount) external pure returns (uint256) { return amount * FEE + 94; } function f95(uint256 amount) external pure returns (uint256) { return amount * FEE + 95; } function f96(uint256 amount) external pure returns (uint256) { return amount * FEE + 96; } function f97(uint256 amount) external pure returns (uint256) { return amount * FEE + 97; } function f98(uint256 amount) external pure returns (uint256) { return amount * FEE + 98; } function f99(uint256 amount) external pure returns (uint256) { return amount * FEE + 99; } function f100(uint256 amount) external pure returns (uint256) { return amount * FEE + 100; } function f101(uint256 amount) external pure returns (uint256) { return amount * FEE + 101; } function f102(uint256 amount) external pure returns (uint256) { return amount * FEE + 102; } function f103(uint256 amount) external pure returns (uint256) { return amount * FEE + 103; } function f104(uint256 amount) external pure returns (uint256) { return amount * FEE + 104; } function f105(uint256 amount) external pure returns (uint256) { return amount * FEE + 105; } function f106(uint256 amount) external pure returns (uint256) { return amount * FEE + 106; } function f107(uint256 amount) external pure returns (uint256) { return amount * FEE + 107; } function f108(uint256 amount) external pure returns (uint256) { return amount * FEE + 108; } function f109(uint256 amount) external pure returns (uint256) { return amount * FEE + 109; } function f110(uint256 amount) external pure returns (uint256) { return amount * FEE + 110; } function f111(uint256 amount) external pure returns (uint256) { return amount * FEE + 111; } function f112(uint256 amount) external pure returns (uint256) { return amount * FEE + 112; } function f113(uint256 amount) external pure returns (uint256) { return amount * FEE + 113; } function f114(uint256 amount) external pure returns (uint256) { return amount * FEE + 114; } function f115(uint256 amount) external pure returns (uint256) { return amount * FEE + 115; } function f116(uint256 amount) external pure returns (uint256) { return amount * FEE + 116; } function f117(uint256 amount) external pure returns (uint256) { return amount * FEE + 117; } function f118(uint256 amount) external pure returns (uint256) { return amount * FEE + 118; } function f119(uint256 amount) external pure returns (uint256) { return amount * FEE + 119; } function f120(uint256 amount) external pure returns (uint256) { return amount * FEE + 120; } function f121(uint256 amount) external pure returns (uint256) { return amount * FEE + 121; } function f122(uint256 amount) external pure returns (uint256) { return amount * FEE + 122; } function f123(uint256 amount) external pure returns (uint256) { return amount * FEE + 123; } function f124(uint256 amount) external pure returns (uint256) { return amount * FEE + 124; } function f125(uint256 amount) external pure returns (uint256) { return amount * FEE + 125; } function f126(uint256 amount) external pure returns (uint256) { return amount * FEE + 126; } function f127(uint256 amount) external pure returns (uint256) { return amount * FEE + 127; } function f128(uint256 amount) external pure returns (uint256) { return amount * FEE + 128; } function f129(uint256 amount) external pure returns (uint256) { return amount * FEE + 129; } function f130(uint256 amount) external pure returns (uint256) { return amount * FEE + 130; } function f131(uint256 amount) external pure returns (uint256) { return amount * FEE + 131; } function f132(uint256 amount) external pure returns (uint256) { return amount * FEE + 132; } function f133(uint256 amount) external pure returns (uint256) { return amount * FEE + 133; } function f134(uint256 amount) external pure returns (uint256) { return amount * FEE + 134; } function f135(uint256 amount) external pure returns (uint256) { return amount * FEE + 135; } function f136(uint256 amount) external pure returns (uint256) { return amount * FEE + 136; } function f137(uint256 amount) external pure returns (uint256) { return amount * FEE + 137; } function f138(uint256 amount) external pure returns (uint256) { return amount * FEE + 138; } function f139(uint256 amount) external pure returns (uint256) { return amount * FEE + 139; } function f140(uint256 amount) external pure returns (uint256) { return amount * FEE + 140; } function f141(uint256 amount) external pure returns (uint256) { return amount * FEE + 141; } function f142(uint256 amount) external pure returns (uint256) { return amount * FEE + 142; } function f143(uint256 amount) external pure returns (uint256) { return amount * FEE + 143; } function f144(uint256 amount) external pure returns (uint256) { return amount * FEE + 144; } function f145(uint256 amount) external pure returns (uint256) { return amount * FEE + 145; } function f146(uint256 amount) external pure returns (uint256) { return amount * FEE + 146; } function f147(uint256 amount) external pure returns (uint256) { return amount * FEE + 147; } function f148(uint256 amount) external pure returns (uint256) { return amount * FEE + 148; } function f149(uint256 amount) external pure returns (uint256) { return amount * FEE + 149; } function f150(uint256 amount) external pure returns (uint256) { return amount * FEE + 150; } function f151(uint256 amount) external pure returns (uint256) { return amount * FEE + 151; } function f152(uint256 amount) external pure returns (uint256) { return amount * FEE + 152; } function f153(uint256 amount) external pure returns (uint256) { return amount * FEE + 153; } function f154(uint256 amount) external pure returns (uint256) { return amount * FEE + 154; } function f155(uint256 amount) external pure returns (uint256) { return amount * FEE + 155; } function f156(uint256 amount) external pure returns (uint256) { return amount * FEE + 156; } function f157(uint256 amount) external pure returns (uint256) { return amount * FEE + 157; } function f158(uint256 amount) external pure returns (uint256) { return amount * FEE + 158; } function f159(uint256 amount) external pure returns (uint256) { return amount * FEE + 159; } function f160(uint256 amount) external pure returns (uint256) { return amount * FEE + 160; } function f161(uint256 amount) external pure returns (uint256) { return amount * FEE + 161; } function f162(uint256 amount) external pure returns (uint256) { return amount * FEE + 162; } function f163(uint256 amount) external pure returns (uint256) { return amount * FEE + 163; } function f164(uint256 amount) external pure returns (uint256) { return amount * FEE + 164; } function f165(uint256 amount) external pure returns (uint256) { return amount * FEE + 165; } function f166(uint256 amount) external pure returns (uint256) { return amount * FEE + 166; } function f167(uint256 amount) external pure returns (uint256) { return amount * FEE + 167; } function f168(uint256 amount) external pure returns (uint256) { return amount * FEE + 168; } function f169(uint256 amount) external pure returns (uint256) { return amount * FEE + 169; } function f170(uint256 amount) external pure returns (uint256) { return amount * FEE + 170; } function f171(uint256 amount) external pure returns (uint256) { return amount * FEE + 171; } function f172(uint256 amount) external pure returns (uint256) { return amount * FEE + 172; } function f173(uint256 amount) external pure returns (uint256) { return amount * FEE + 173; } function f174(uint256 amount) external pure returns (uint256) { return amount * FEE + 174; } function f175(uint256 amount) external pure returns (uint256) { return amount * FEE + 175; } function f176(uint256 amount) external pure returns (uint256) { return amount * FEE + 176; } function f177(uint256 amount) external pure returns (uint256) { return amount * FEE + 177; } function f178(uint256 amount) external pure returns (uint256) { return amount * FEE + 178; } function f179(uint256 amount) external pure returns (uint256) { return amount * FEE + 179; } function f180(uint256 amount) external pure returns (uint256) { return amount * FEE + 180; } function f181(uint256 amount) external pure returns (uint256) { return amount * FEE + 181; } function f182(uint256 amount) external pure returns (uint256) { return amount * FEE + 182; } function f183(uint256 amount) external pure returns (uint256) { return amount * FEE + 183; } function f184(uint256 amount) external pure returns (uint256) { return amount * FEE + 184; } function f185(uint256 amount) external pure returns (uint256) { return amount * FEE + 185; } function f186(uint256 amount) external pure returns (uint256) { return amount * FEE + 186; } function f187(uint256 amount) external pure returns (uint256) { return amount * FEE + 187; } function f188(uint256 amount) external pure returns (uint256) { return amount * FEE + 188; } function f189(uint256 amount) external pure returns (uint256) { return amount * FEE + 189; } function f190(uint256 amount) external pure returns (uint256) { return amount * FEE + 190; } function f191(uint256 amount) external pure returns (uint256) { return amount * FEE + 191; } function f192(uint256 amount) external pure returns (uint256) { return amount * FEE + 192; } function f193(uint256 amount) external pure returns (uint256) { return amount * FEE + 193; } function f194(uint256 amount) external pure returns (uint256) { return amount * FEE + 194; } function f195(uint256 amount) external pure returns (uint256) { return amount * FEE + 195; } function f196(uint256 amount) external pure returns (uint256) { return amount * FEE + 196; } function f197(uint256 amount) external pure returns (uint256) { return amount * FEE + 197; } function f198(uint256 amount) external pure returns (uint256) { return amount * FEE + 198; } function f199(uint256 amount) external pure returns (uint256) { return amount * FEE + 199; } function f200(uint256 amount) external pure returns (uint256) { return amount * FEE + 200; } function f201(uint256 amount) external pure returns (uint256) { return amount * FEE + 201; } function f202(uint256 amount) external pure returns (uint256) { return amount * FEE + 202; } function f203(uint256 amount) external pure returns (uint256) { return amount * FEE + 203; } function f204(uint256 amount) external pure returns (uint256) { return amount * FEE + 204; } function f205(uint256 amount) external pure returns (uint256) { return amount * FEE + 205; } function f206(uint256 amount) external pure returns (uint256) { return amount * FEE + 206; } function f207(uint256 amount) external pure returns (uint256) { return amount * FEE + 207; } function f208(uint256 amount) external pure returns (uint256) { return amount * FEE + 208; } function f209(uint256 amount) external pure returns (uint256) { return amount * FEE + 209; } function f210(uint256 amount) external pure returns (uint256) { return amount * FEE + 210; } function f211(uint256 amount) external pure returns (uint256) { return amount * FEE + 211; } function f212(uint256 amount) external pure returns (uint256) { return amount * FEE + 212; } function f213(uint256 amount) external pure returns (uint256) { return amount * FEE + 213; } function f214(uint256 amount) external pure returns (uint256) { return amount * FEE + 214; } function f215(uint256 amount) external pure returns (uint256) { return amount * FEE + 215; } function f216(uint256 amount) external pure returns (uint256) { return amount * FEE + 216; } function f217(uint256 amount) external pure returns (uint256) { return amount * FEE + 217; } function f218(uint256 amount) external pure returns (uint256) { return amount * FEE + 218; } function f219(uint256 amount) external pure returns (uint256) { return amount * FEE + 219; } function f220(uint256 amount) external pure returns (uint256) { return amount * FEE + 220; } function f221(uint256 amount) external pure returns (uint256) { return amount * FEE + 221; } function f222(uint256 amount) external pure returns (uint256) { return amount * FEE + 222; } function f223(uint256 amount) external pure returns (uint256) { return amount * FEE + 223; } function f224(uint256 amount) external pure returns (uint256) { return amount * FEE + 224; } function f225(uint256 amount) external pure returns (uint256) { return amount * FEE + 225; } function f226(uint256 amount) external pure returns (uint256) { return amount * FEE + 226; } function f227(uint256 amount) external pure returns (uint256) { return amount * FEE + 227; } function f228(uint256 amount) external pure returns (uint256) { return amount * FEE + 228; } function f229(uint256 amount) external pure returns (uint256) { return amount * FEE + 229; } function f230(uint256 amount) external pure returns (uint256) { return amount * FEE + 230; } function f231(uint256 amount) external pure returns (uint256) { return amount * FEE + 231; } function f232(uint256 amount) external pure returns (uint256) { return amount * FEE + 232; } function f233(uint256 amount) external pure returns (uint256) { return amount * FEE + 233; } function f234(uint256 amount) external pure returns (uint256) { return amount * FEE + 234; } function f235(uint256 amount) external pure returns (uint256) { return amount * FEE + 235; } function f236(uint256 amount) external pure returns (uint256) { return amount * FEE + 236; } function f237(uint256 amount) external pure returns (uint256) { return amount * FEE + 237; } function f238(uint256 amount) external pure returns (uint256) { return amount * FEE + 238; } function f239(uint256 amount) external pure returns (uint256) { return amount * FEE + 239; } function f240(uint256 amount) external pure returns (uint256) { return amount * FEE + 240; } function f241(uint256 amount) external pure returns (uint256) { return amount * FEE + 241; } function f242(uint256 amount) external pure returns (uint256) { return amount * FEE + 242; } function f243(uint256 amount) external pure returns (uint256) { return amount * FEE + 243; } function f244(uint256 amount) external pure returns (uint256) { return amount * FEE + 244; } function f245(uint256 amount) external pure returns (uint256) { return amount * FEE + 245; } function f246(uint256 amount) external pure returns (uint256) { return amount * FEE + 246; } function f247(uint256 amount) external pure returns (uint256) { return amount * FEE + 247; } function f248(uint256 amount) external pure returns (uint256) { return amount * FEE + 248; } function f249(uint256 amount) external pure returns (uint256) { return amount * FEE + 249; } function f250(uint256 amount) external pure returns (uint256) { return amount * FEE + 250; } function f251(uint256 amount) external pure returns (uint256) { return amount * FEE + 251; } function f252(uint256 amount) external pure returns (uint256) { return amount * FEE + 252; } function f253(uint256 amount) external pure returns (uint256) { return amount * FEE + 253; } function f254(uint256 amount) external pure returns (uint256) { return amount * FEE + 254; } function f255(uint256 amount) external pure returns (uint256) { return amount * FEE + 255; } function f256(uint256 amount) external pure returns (uint256) { return amount * FEE + 256; } function f257(uint256 amount) external pure returns (uint256) { return amount * FEE + 257; } function f258(uint256 amount) external pure returns (uint256) { return amount * FEE + 258; } function f259(uint256 amount) external pure returns (uint256) { return amount * FEE + 259; } function f260(uint256 amount) external pure returns (uint256) { return amount * FEE + 260; } function f261(uint256 amount) external pure returns (uint256) { return amount * FEE + 261; } function f262(uint256 amount) external pure returns (uint256) { return amount * FEE + 262; } function f263(uint256 amount) external pure returns (uint256) { return amount * FEE + 263; } function f264(uint256 amount) external pure returns (uint256) { return amount * FEE + 264; } function f265(uint256 amount) external pure returns (uint256) { return amount * FEE + 265; } function f266(uint256 amount) external pure returns (uint256) { return amount * FEE + 266; } function f267(uint256 amount) external pure returns (uint256) { return amount * FEE + 267; } function f268(uint256 amount) external pure returns (uint256) { return amount * FEE + 268; } function f269(uint256 amount) external pure returns (uint256) { return amount * FEE + 269; } function f270(uint256 amount) external pure returns (uint256) { return amount * FEE + 270; } function f271(uint256 amount) external pure returns (uint256) { return amount * FEE + 271; } function f272(uint256 amount) external pure returns (uint256) { return amount * FEE + 272; } function f273(uint256 amount) external pure returns (uint256) { return amount * FEE + 273; } function f274(uint256 amount) external pure returns (uint256) { return amount * FEE + 274; } function f275(uint256 amount) external pure returns (uint256) { return amount * FEE + 275; } function f276(uint256 amount) external pure returns (uint256) { return amount * FEE + 276; } function f277(uint256 amount) external pure returns (uint256) { return amount * FEE + 277; } function f278(uint256 amount) external pure returns (uint256) { return amount * FEE + 278; } function f279(uint256 amount) external pure returns (uint256) { return amount * FEE + 279; } function f280(uint256 amount) external pure returns (uint256) { return amount * FEE + 280; } function f281(uint256 amount) external pure returns (uint256) { return amount * FEE + 281; } function f282(uint256 amount) external pure returns (uint256) { return amount * FEE + 282; } function f283(uint256 amount) external pure returns (uint256) { return amount * FEE + 283; } function f284(uint256 amount) external pure returns (uint256) { return amount * FEE + 284; } function f285(uint256 amount) external pure returns (uint256) { return amount * FEE + 285; } function f286(uint256 amount) external pure returns (uint256) { return amount * FEE + 286; } function f287(uint256 amount) external pure returns (uint256) { return amount * FEE + 287; } function f288(uint256 amount) external pure returns (uint256) { return amount * FEE + 288; } function f289(uint256 amount) external pure returns (uint256) { return amount * FEE + 289; } function f290(uint256 amount) external pure returns (uint256) { return amount * FEE + 290; } function f291(uint256 amount) external pure returns (uint256) { return amount * FEE + 291; } function f292(uint256 amount) external pure returns (uint256) { return amount * FEE + 292; } function f293(uint256 amount) external pure returns (uint256) { return amount * FEE + 293; } function f294(uint256 amount) external pure returns (uint256) { return amount * FEE + 294; } function f295(uint256 amount) external pure returns (uint256) { return amount * FEE + 295; } function f296(uint256 amount) external pure returns (uint256) { return amount * FEE + 296; } function f297(uint256 amount) external pure returns (uint256) { return amount * FEE + 297; } function f298(uint256 amount) external pure returns (uint256) { return amount * FEE + 298; } function f299(uint256 amount) external pure returns (uint256) { return amount * FEE + 299; } function f300(uint256 amount) external pure returns (uint256) { return amount * FEE + 300; } function f301(uint256 amount) external pure returns (uint256) { return amount * FEE + 301; } function f302(uint256 amount) external pure returns (uint256) { return amount * FEE + 302; } function f303(uint256 amount) external pure returns (uint256) { return amount * FEE + 303; } function f304(uint256 amount) external pure returns (uint256) { return amount * FEE + 304; } function f305(uint256 amount) external pure returns (uint256) { return amount * FEE + 305; } function f306(uint256 amount) external pure returns (uint256) { return amount * FEE + 306; } function f307(uint256 amount) external pure returns (uint256) { return amount * FEE + 307; } function f308(uint256 amount) external pure returns (uint256) { return amount * FEE + 308; } function f309(uint256 amount) external pure returns (uint256) { return amount * FEE + 309; } function f310(uint256 amount) external pure returns (uint256) { return amount * FEE + 310; } function f311(uint256 amount) external pure returns (uint256) { return amount * FEE + 311; } function f312(uint256 amount) external pure returns (uint256) { return amount * FEE + 312; } function f313(uint256 amount) external pure returns (uint256) { return amount * FEE + 313; } function f314(uint256 amount) external pure returns (uint256) { return amount * FEE + 314; } function f315(uint256 amount) external pure returns (uint256) { return amount * FEE + 315; } function f316(uint256 amount) external pure returns (uint256) { return amount * FEE + 316; } function f317(uint256 amount) external pure returns (uint256) { return amount * FEE + 317; } function f318(uint256 amount) external pure returns (uint256) { return amount * FEE + 318; } function f319(uint256 amount) external pure returns (uint256) { return amount * FEE + 319; } function f320(uint256 amount) external pure returns (uint256) { return amount * FEE + 320; } function f321(uint256 amount) external pure returns (uint256) { return amount * FEE + 321; } function f322(uint256 amount) external pure returns (uint256) { return amount * FEE + 322; } function f323(uint256 amount) external pure returns (uint256) { return amount * FEE + 323; } function f324(uint256 amount) external pure returns (uint256) { return amount * FEE + 324; } function f325(uint256 amount) external pure returns (uint256) { return amount * FEE + 325; } function f326(uint256 amount) external pure returns (uint256) { return amount * FEE + 326; } function f327(uint256 amount) external pure returns (uint256) { return amount * FEE + 327; } function f328(uint256 amount) external pure returns (uint256) { return amount * FEE + 328; } function f329(uint256 amount) external pure returns (uint256) { return amount * FEE + 329; } function f330(uint256 amount) external pure returns (uint256) { return amount * FEE + 330; } function f331(uint256 amount) external pure returns (uint256) { return amount * FEE + 331; } function f332(uint256 amount) external pure returns (uint256) { return amount * FEE + 332; } function f333(uint256 amount) external pure returns (uint256) { return amount * FEE + 333; } function f334(uint256 amount) external pure returns (uint256) { return amount * FEE + 334; } function f335(uint256 amount) external pure returns (uint256) { return amount * FEE + 335; } function f336(uint256 amount) external pure returns (uint256) { return amount * FEE + 336; } function f337(uint256 amount) external pure returns (uint256) { return amount * FEE + 337; } function f338(uint256 amount) external pure returns (uint256) { return amount * FEE + 338; } function f339(uint256 amount) external pure returns (uint256) { return amount * FEE + 339; } function f340(uint256 amount) external pure returns (uint256) { return amount * FEE + 340; } function f341(uint256 amount) external pure returns (uint256) { return amount * FEE + 341; } function f342(uint256 amount) external pure returns (uint256) { return amount * FEE + 342; } function f343(uint256 amount) external pure returns (uint256) { return amount * FEE + 343; } function f344(uint256 amount) external pure returns (uint256) { return amount * FEE + 344; } function f345(uint256 amount) external pure returns (uint256) { return amount * FEE + 345; } function f346(uint256 amount) external pure returns (uint256) { return amount * FEE + 346; } function f347(uint256 amount) external pure returns (uint256) { return amount * FEE + 347; } function f348(uint256 amount) external pure returns (uint256) { return amount * FEE + 348; } function f349(uint256 amount) external pure returns (uint256) { return amount * FEE + 349; } function f350(uint256 amount) external pure returns (uint256) { return amount * FEE + 350; } function f351(uint256 amount) external pure returns (uint256) { return amount * FEE + 351; } function f352(uint256 amount) external pure returns (uint256) { return amount * FEE + 352; } function f353(uint256 amount) external pure returns (uint256) { return amount * FEE + 353; } function f354(uint256 amount) external pure returns (uint256) { return amount * FEE + 354; } function f355(uint256 amount) external pure returns (uint256) { return amount * FEE + 355; } function f356(uint256 amount) external pure returns (uint256) { return amount * FEE + 356; } function f357(uint256 amount) external pure returns (uint256) { return amount * FEE + 357; } function f358(uint256 amount) external pure returns (uint256) { return amount * FEE + 358; } function f359(uint256 amount) external pure returns (uint256) { return amount * FEE + 359; } function f360(uint256 amount) external pure returns (uint256) { return amount * FEE + 360; } function f361(uint256 amount) external pure returns (uint256) { return amount * FEE + 361; } function f362(uint256 amount) external pure returns (uint256) { return amount * FEE + 362; } function f363(uint256 amount) external pure returns (uint256) { return amount * FEE + 363; } function f364(uint256 amount) external pure returns (uint256) { return amount * FEE + 364; } function f365(uint256 amount) external pure returns (uint256) { return amount * FEE + 365; } function f366(uint256 amount) external pure returns (uint256) { return amount * FEE + 366; } function f367(uint256 amount) external pure returns (uint256) { return amount * FEE + 367; } function f368(uint256 amount) external pure returns (uint256) { return amount * FEE + 368; } function f369(uint256 amount) external pure returns (uint256) { return amount * FEE + 369; } function f370(uint256 amount) external pure returns (uint256) { return amount * FEE + 370; } function f371(uint256 amount) external pure returns (uint256) { return amount * FEE + 371; } function f372(uint256 amount) external pure returns (uint256) { return amount * FEE + 372; } function f373(uint256 amount) external pure returns (uint256) { return amount * FEE + 373; } function f374(uint256 amount) external pure returns (uint256) { return amount * FEE + 374; } function f375(uint256 amount) external pure returns (uint256) { return amount * FEE + 375; } function f376(uint256 amount) external pure returns (uint256) { return amount * FEE + 376; } function f377(uint256 amount) external pure returns (uint256) { return amount * FEE + 377; } function f378(uint256 amount) external pure returns (uint256) { return amount * FEE + 378; } function f379(uint256 amount) external pure returns (uint256) { return amount * FEE + 379; } function f380(uint256 amount) external pure returns (uint256) { return amount * FEE + 380; } function f381(uint256 amount) external pure returns (uint256) { return amount * FEE + 381; } function f382(uint256 amount) external pure returns (uint256) { return amount * FEE + 382; } function f383(uint256 amount) external pure returns (uint256) { return amount * FEE + 383; } function f384(uint256 amount) external pure returns (uint256) { return amount * FEE + 384; } function f385(uint256 amount) external pure returns (uint256) { return amount * FEE + 385; } function f386(uint256 amount) external pure returns (uint256) { return amount * FEE + 386; } function f387(uint256 amount) external pure returns (uint256) { return amount * FEE + 387; } function f388(uint256 amount) external pure returns (uint256) { return amount * FEE + 388; } function f389(uint256 amount) external pure returns (uint256) { return amount * FEE + 389; } function f390(uint256 amount) external pure returns (uint256) { return amount * FEE + 390; } function f391(uint256 amount) external pure returns (uint256) { return amount * FEE + 391; } function f392(uint256 amount) external pure returns (uint256) { return amount * FEE + 392; } function f393(uint256 amount) external pure returns (uint256) { return amount * FEE + 393; } function f394(uint256 amount) external pure returns (uint256) { return amount * FEE + 394; } function f395(uint256 amount) external pure returns (uint256) { return amount * FEE + 395; } function f396(uint256 amount) external pure returns (uint256) { return amount * FEE + 396; } function f397(uint256 amount) external pure returns (uint256) { return amount * FEE + 397; } function f398(uint256 amount) external pure returns (uint256) { return amount * FEE + 398; } function f399(uint256 amount) external pure returns (uint256) { return amount * FEE + 399; } function f400(uint256 amount) external pure returns (uint256) { return amount * FEE + 400; } function f401(uint256 amount) external pure returns (uint256) { return amount * FEE + 401; } function f402(uint256 amount) external pure returns (uint256) { return amount * FEE + 402; } function f403(uint256 amount) external pure returns (uint256) { return amount * FEE + 403; } function f404(uint256 amount) external pure returns (uint256) { return amount * FEE + 404; } function f405(uint256 amount) external pure returns (uint256) { return amount * FEE + 405; } function f406(uint256 amount) external pure returns (uint256) { return amount * FEE + 406; } function f407(uint256 amount) external pure returns (uint256) { return amount * FEE + 407; } function f408(uint256 amount) external pure returns (uint256) { return amount * FEE + 408; } function f409(uint256 amount) external pure returns (uint256) { return amount * FEE + 409; } function f410(uint256 amount) external pure returns (uint256) { return amount * FEE + 410; } function f411(uint256 amount) external pure returns (uint256) { return amount * FEE + 411; } function f412(uint256 amount) external pure returns (uint256) { return amount * FEE + 412; } function f413(uint256 amount) external pure returns (uint256) { return amount * FEE + 413; } function f414(uint256 amount) external pure returns (uint256) { return amount * FEE + 414; } function f415(uint256 amount) external pure returns (uint256) { return amount * FEE + 415; } function f416(uint256 amount) external pure returns (uint256) { return amount * FEE + 416; } function f417(uint256 amount) external pure returns (uint256) { return amount * FEE + 417; } function f418(uint256 amount) external pure returns (uint256) { return amount * FEE + 418; } function f419(uint256 amount) external pure returns (uint256) { return amount * FEE + 419; } function f420(uint256 amount) external pure returns (uint256) { return amount * FEE + 420; } function f421(uint256 amount) external pure returns (uint256) { return amount * FEE + 421; } function f422(uint256 amount) external pure returns (uint256) { return amount * FEE + 422; } function f423(uint256 amount) external pure returns (uint256) { return amount * FEE + 423; } function f424(uint256 amount) external pure returns (uint256) { return amount * FEE + 424; } function f425(uint256 amount) external pure returns (uint256) { return amount * FEE + 425; } function f426(uint256 amount) external pure returns (uint256) { return amount * FEE + 426; } function f427(uint256 amount) external pure returns (uint256) { return amount * FEE + 427; } function f428(uint256 amount) external pure returns (uint256) { return amount * FEE + 428; } function f429(uint256 amount) external pure returns (uint256) { return amount * FEE + 429; } function f430(uint256 amount) external pure returns (uint256) { return amount * FEE + 430; } function f431(uint256 amount) external pure returns (uint256) { return amount * FEE + 431; } function f432(uint256 amount) external pure returns (uint256) { return amount * FEE + 432; } function f433(uint256 amount) external pure returns (uint256) { return amount * FEE + 433; } function f434(uint256 amount) external pure returns (uint256) { return amount * FEE + 434; } function f435(uint256 amount) external pure returns (uint256) { return amount * FEE + 435; } function f436(uint256 amount) external pure returns (uint256) { return amount * FEE + 436; } function f437(uint256 amount) external pure returns (uint256) { return amount * FEE + 437; } function f438(uint256 amount) external pure returns (uint256) { return amount * FEE + 438; } function f439(uint256 amount) external pure returns (uint256) { return amount * FEE + 439; } function f440(uint256 amount) external pure returns (uint256) { return amount * FEE + 440; } function f441(uint256 amount) external pure returns (uint256) { return amount * FEE + 441; } function f442(uint256 amount) external pure returns (uint256) { return amount * FEE + 442; } function f443(uint256 amount) external pure returns (uint256) { return amount * FEE + 443; } function f444(uint256 amount) external pure returns (uint256) { return amount * FEE + 444; } function f445(uint256 amount) external pure returns (uint256) { return amount * FEE + 445; } function f446(uint256 amount) external pure returns (uint256) { return amount * FEE + 446; } function f447(uint256 amount) external pure returns (uint256) { return amount * FEE + 447; } function f448(uint256 amount) external pure returns (uint256) { return amount * FEE + 448; } function f449(uint256 amount) external pure returns (uint256) { return amount * FEE + 449; } function f450(uint256 amount) external pure returns (uint256) { return amount * FEE + 450; } function f451(uint256 amount) external pure returns (uint256) { return amount * FEE + 451; } function f452(uint256 amount) external pure returns (uint256) { return amount * FEE + 452; } function f453(uint256 amount) external pure returns (uint256) { return amount * FEE + 453; } function f454(uint256 amount) external pure returns (uint256) { return amount * FEE + 454; } function f455(uint256 amount) external pure returns (uint256) { return amount * FEE + 455; } function f456(uint256 amount) external pure returns (uint256) { return amount * FEE + 456; } function f457(uint256 amount) external pure returns (uint256) { return amount * FEE + 457; } function f458(uint256 amount) external pure returns (uint256) { return amount * FEE + 458; } function f459(uint256 amount) external pure returns (uint256) { return amount * FEE + 459; } function f460(uint256 amount) external pure returns (uint256) { return amount * FEE + 460; } function f461(uint256 amount) external pure returns (uint256) { return amount * FEE + 461; } function f462(uint256 amount) external pure returns (uint256) { return amount * FEE + 462; } function f463(uint256 amount) external pure returns (uint256) { return amount * FEE + 463; } function f464(uint256 amount) external pure returns (uint256) { return amount * FEE + 464; } function f465(uint256 amount) external pure returns (uint256) { return amount * FEE + 465; } function f466(uint256 amount) external pure returns (uint256) { return amount * FEE + 466; } function f467(uint256 amount) external pure returns (uint256) { return amount * FEE + 467; } function f468(uint256 amount) external pure returns (uint256) { return amount * FEE + 468; } function f469(uint256 amount) external pure returns (uint256) { return amount * FEE + 469; } function f470(uint256 amount) external pure returns (uint256) { return amount * FEE + 470; } function f471(uint256 amount) external pure returns (uint256) { return amount * FEE + 471; } function f472(uint256 amount) external pure returns (uint256) { return amount * FEE + 472; } function f473(uint256 amount) external pure returns (uint256) { return amount * FEE + 473; } function f474(uint256 amount) external pure returns (uint256) { return amount * FEE + 474; } function f475(uint256 amount) external pure returns (uint256) { return amount * FEE + 475; } function f476(uint256 amount) external pure returns (uint256) { return amount * FEE + 476; } function f477(uint256 amount) external pure returns (uint256) { return amount * FEE + 477; } function f478(uint256 amount) external pure returns (uint256) { return amount * FEE + 478; } function f479(uint256 amount) external pure returns (uint256) { return amount * FEE + 479; } function f480(uint256 amount) external pure returns (uint256) { return amount * FEE + 480; } function f481(uint256 amount) external pure returns (uint256) { return amount * FEE + 481; } function f482(uint256 amount) external pure returns (uint256) { return amount * FEE + 482; } function f483(uint256 amount) external pure returns (uint256) { return amount * FEE + 483; } function f484(uint256 amount) external pure returns (uint256) { return amount * FEE + 484; } function f485(uint256 amount) external pure returns (uint256) { return amount * FEE + 485; } function f486(uint256 amount) external pure returns (uint256) { return amount * FEE + 486; } function f487(uint256 amount) external pure returns (uint256) { return amount * FEE + 487; } function f488(uint256 amount) external pure returns (uint256) { return amount * FEE + 488; } function f489(uint256 amount) external pure returns (uint256) { return amount * FEE + 489; } function f490(uint256 amount) external pure returns (uint256) { return amount * FEE + 490; } function f491(uint256 amount) external pure returns (uint256) { return amount * FEE + 491; } function f492(uint256 amount) external pure returns (uint256) { return amount * FEE + 492; } function f493(uint256 amount) external pure returns (uint256) { return amount * FEE + 493; } function f494(uint256 amount) external pure returns (uint256) { return amount * FEE + 494; } function f495(uint256 amount) external pure returns (uint256) { return amount * FEE + 495; } function f496(uint256 amount) external pure returns (uint256) { return amount * FEE + 496; } function f497(uint256 amount) external pure returns (uint256) { return amount * FEE + 497; } function f498(uint256 amount) external pure returns (uint256) { return amount * FEE + 498; } function f499(uint256 amount) external pure returns (uint256) { return amount * FEE + 499; } function f500(uint256 amount) external pure returns (uint256) { return amount * FEE + 500; } function f501(uint256 amount) external pure returns (uint256) { return amount * FEE + 501; } function f502(uint256 amount) external pure returns (uint256) { return amount * FEE + 502; } function f503(uint256 amount) external pure returns (uint256) { return amount * FEE + 503; } function f504(uint256 amount) external pure returns (uint256) { return amount * FEE + 504; } function f505(uint256 amount) external pure returns (uint256) { return amount * FEE + 505; } function f506(uint256 amount) external pure returns (uint256) { return amount * FEE + 506; } function f507(uint256 amount) external pure returns (uint256) { return amount * FEE + 507; } function f508(uint256 amount) external pure returns (uint256) { return amount * FEE + 508; } function f509(uint256 amount) external pure returns (uint256) { return amount * FEE + 509; } function f510(uint256 amount) external pure returns (uint256) { return amount * FEE + 510; } function f511(uint256 amount) external pure returns (uint256) { return amount * FEE + 511; } function f512(uint256 amount) external pure returns (uint256) { return amount * FEE + 512; } function f513(uint256 amount) external pure returns (uint256) { return amount * FEE + 513; } function f514(uint256 amount) external pure returns (uint256) { return amount * FEE + 514; } function f515(uint256 amount) external pure returns (uint256) { return amount * FEE + 515; } function f516(uint256 amount) external pure returns (uint256) { return amount * FEE + 516; } function f517(uint256 amount) external pure returns (uint256) { return amount * FEE + 517; } function f518(uint256 amount) external pure returns (uint256) { return amount * FEE + 518; } function f519(uint256 amount) external pure returns (uint256) { return amount * FEE + 519; } function f520(uint256 amount) external pure returns (uint256) { return amount * FEE + 520; } function f521(uint256 amount) external pure returns (uint256) { return amount * FEE + 521; } function f522(uint256 amount) external pure returns (uint256) { return amount * FEE + 522; } function f523(uint256 amount) external pure returns (uint256) { return amount * FEE + 523; } function f524(uint256 amount) external pure returns (uint256) { return amount * FEE + 524; } function f525(uint256 amount) external pure returns (uint256) { return amount * FEE + 525; } function f526(uint256 amount) external pure returns (uint256) { return amount * FEE + 526; } function f527(uint256 amount) external pure returns (uint256) { return amount * FEE + 527; } function f528(uint256 amount) external pure returns (uint256) { return amount * FEE + 528; } function f529(uint256 amount) external pure returns (uint256) { return amount * FEE + 529; } function f530(uint256 amount) external pure returns (uint256) { return amount * FEE + 530; } function f531(uint256 amount) external pure returns (uint256) { return amount * FEE + 531; } function f532(uint256 amount) external pure returns (uint256) { return amount * FEE + 532; } function f533(uint256 amount) external pure returns (uint256) { return amount * FEE + 533; } function f534(uint256 amount) external pure returns (uint256) { return amount * FEE + 534; } function f535(uint256 amount) external pure returns (uint256) { return amount * FEE + 535; } function f536(uint256 amount) external pure returns (uint256) { return amount * FEE + 536; } function f537(uint256 amount) external pure returns (uint256) { return amount * FEE + 537; } function f538(uint256 amount) external pure returns (uint256) { return amount * FEE + 538; } function f539(uint256 amount) external pure returns (uint256) { return amount * FEE + 539; } string constant NAME = "Flattened 日本 🚀"; function last() external pure returns (uint256) { return FEE; } }
@@ -0,0 +1,9 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+interface IVault {
+    /// @notice Deposits the tokens of the caller.
+    function deposit(uint256 amount) external;
+
+    function withdraw(uint256 amount) external;
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+import "./IVault.sol";
+
+contract Vault is IVault {
+    mapping(address => uint256) public balances;
+
+    /**
+     * @notice Deposits the tokens of the caller.
+     */
+    function deposit(uint256 amount) external {
+        balances[msg.sender] += amount;
+    }
+
+    // Not NatSpec, a regular comment.
+    function withdraw(uint256 amount) external {
+        balances[msg.sender] -= amount;
+    }
+
+    function sweep() public {}
+
+    function _check() internal view {}
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+uint256 constant SIZE = 4;
+uint256 constant LIMIT = SIZE * 2;
+address constant initialOwner = address(1);
+
+contract Ownable {
+    constructor(address owner) {}
+}
+
+contract Vault is Ownable(initialOwner) {
+    uint256[SIZE] slots;
+
+    event Deposited(address user, uint256 amount);
+    error TooLarge(uint256 amount, uint256 limit);
+
+    modifier atMost(uint256 amount, uint256 limit) {
+        _;
+    }
+
+    constructor(address initialOwner) {}
+
+    function deposit(uint256 amount) external atMost(amount, LIMIT) {
+        if (amount > LIMIT) revert TooLarge(amount, LIMIT);
+        emit Deposited(msg.sender, amount);
+    }
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+import "./IERC20.sol";
+
+uint256 constant MAX_FEE = 1000;
+
+type Price is uint128;
+
+error Unauthorized(address caller);
+
+event Paused(address indexed by);
+
+function feeOf(uint256 amount) pure returns (uint256) {
+    return amount / MAX_FEE;
+}
+
+interface IVault {
+    function totalAssets() external view returns (uint256);
+
+    function balanceOf(address owner) external view returns (uint256);
+}
+
+/// @title A vault with everything in it.
+abstract contract Vault is IVault {
+    enum Status {
+        Open,
+        Closed
+    }
+
+    struct Position {
+        address owner;
+        uint256 shares;
+    }
+
+    using Math for uint256;
+
+    uint256 public override totalAssets;
+    mapping(address => uint256) public override balanceOf;
+    mapping(address => mapping(uint256 => Position)) public positions;
+    uint256[] public history;
+    address internal immutable owner;
+    uint256 private constant FEE = 5;
+
+    /// @custom:deprecated Use `Moved` instead.
+    event Deposited(address indexed user, uint256 amount);
+
+    modifier onlyOwner() {
+        _;
+    }
+
+    modifier whenNot {
+        _;
+    }
+
+    receive() external payable {}
+
+    fallback() external {}
+
+    function deposit(uint256 amount) external {}
+
+    function deposit(uint256 amount, address receiver) external {}
+
+    /// @notice Moves the shares.
+    /// @deprecated Use `deposit` instead.
+    function move(bytes memory data) internal virtual returns (bool ok, bytes memory result);
+}
+
+/**
+ * @custom:deprecated Use `Math` from the standard library.
+ */
+library Math {
+    struct Fraction {
+        uint256 numerator;
+        uint256 denominator;
+    }
+
+    function mulDiv(uint256 x, uint256 y, uint256 d) internal pure returns (uint256) {
+        return x * y / d;
+    }
+}
@@ -0,0 +1,10 @@
+pragma solidity ^0.8.0;
+
+contract Vault {
+    mapping(address => uint256) balances;
+
+    function withdraw(uint256 amount) external {
+        require(balances[msg.sender] >= amount, "Vault: the balance of the caller is lower than the amount being withdrawn");
+        balances[msg.sender] -= amount;
+    }
+}
@@ -0,0 +1,267 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// TestKind is the kind of a Foundry test: "unit", "fuzz" or "invariant".
+type TestKind string
+
+const (
+	UnitTest      TestKind = "unit"
+	FuzzTest      TestKind = "fuzz"
+	InvariantTest TestKind = "invariant"
+)
+
+// TestContract is a contract run by `forge test`, with its tests.
+type TestContract struct {
+	Contract *Symbol
+	SetUp    *Symbol // setUp function, the one of a base for the inherited one; or nil
+	Tests    []FoundryTest
+}
+
+// FoundryTest is a test function of a TestContract.
+type FoundryTest struct {
+	Doc           *Document // document declaring the function, the one of a base for the inherited tests
+	Decl          *ast.FunctionDeclaration
+	Kind          TestKind
+	ExpectsRevert bool // does the body call vm.expectRevert or is it a testFail one?
+}
+
+// Command returns the forge command line running the test alone e.g.
+// "forge test --match-contract ^VaultTest$ --match-test ^test_deposit$".
+func (c TestContract) Command(test FoundryTest) []string {
+	return []string{"forge", "test", "--match-contract", "^" + c.Contract.Name.Name + "$", "--match-test", "^" + test.Decl.Name.Name + "$"}
+}
+
+// Tests returns the contracts of the document run by `forge test`, the
+// way forge finds them: the concrete contracts inheriting forge-std's
+// `Test`, with their public and external functions named test*, testFuzz*
+// and testFork* or invariant*, the inherited ones included. The tests with
+// parameters are fuzzed. If the bases can't be resolved e.g. lib/forge-std
+// is not installed, the contracts of the Foundry test files named *Test or
+// listing `Test` among their bases are taken instead, with their own
+// functions. So the helpers named test* of the other contracts, and the
+// ones declared in the dependencies, are left out. The bases are resolved
+// with the symbol index, nothing is parsed again. The dependencies have no
+// tests.
+func (s *State) Tests(doc *Document) []TestContract {
+	res := []TestContract{}
+	if s.isDependency(doc.URI) {
+		return res
+	}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Kind != token.CONTRACT || c.Abstract {
+			continue
+		}
+		contract := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		linearized := s.linearize(contract)
+		if linearized == nil {
+			if !s.isFoundryTest(doc) || !namedTest(c) {
+				continue
+			}
+			linearized = []*Symbol{contract}
+		} else if !inheritsTest(linearized) {
+			continue
+		}
+
+		tc := TestContract{Contract: contract, Tests: []FoundryTest{}}
+		seen := map[string]bool{}
+		for _, base := range linearized {
+			if s.isDependency(base.Doc.URI) {
+				continue
+			}
+			for _, member := range base.Node.(*ast.ContractDeclaration).Body {
+				fn, ok := member.(*ast.FunctionDeclaration)
+				if !ok || fn.Kind != token.FUNCTION || fn.Type.Visibility != ast.External && fn.Type.Visibility != ast.Public {
+					continue
+				}
+				name := fn.Name.Name
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				if name == "setUp" {
+					tc.SetUp = &Symbol{Doc: base.Doc, Name: fn.Name, Node: fn}
+					continue
+				}
+				kind, ok := testKind(fn)
+				if !ok {
+					continue
+				}
+				tc.Tests = append(tc.Tests, FoundryTest{
+					Doc:           base.Doc,
+					Decl:          fn,
+					Kind:          kind,
+					ExpectsRevert: strings.HasPrefix(name, "testFail") || expectsRevert(fn),
+				})
+			}
+		}
+		if len(tc.Tests) > 0 {
+			res = append(res, tc)
+		}
+	}
+	return res
+}
+
+// inheritsTest reports whether one of the bases of the linearization is
+// forge-std's `Test`.
+func inheritsTest(linearized []*Symbol) bool {
+	for _, base := range linearized[1:] {
+		if base.Name.Name == "Test" {
+			return true
+		}
+	}
+	return false
+}
+
+// namedTest reports whether the contract looks like a test when its bases
+// can't be resolved: it's named e.g. VaultTest or lists `Test` among its
+// bases.
+func namedTest(c *ast.ContractDeclaration) bool {
+	if strings.HasSuffix(c.Name.Name, "Test") {
+		return true
+	}
+	for _, base := range c.Bases {
+		if ast.ExprString(base.Name) == "Test" {
+			return true
+		}
+	}
+	return false
+}
+
+// testKind returns the kind of the test function by its name and
+// parameters; or false if the function is not a test.
+func testKind(fn *ast.FunctionDeclaration) (TestKind, bool) {
+	name := fn.Name.Name
+	switch {
+	case strings.HasPrefix(name, "invariant"):
+		return InvariantTest, true
+	case !strings.HasPrefix(name, "test"):
+		return "", false
+	case strings.HasPrefix(name, "testFuzz") || fn.Type.Params != nil && len(fn.Type.Params.List) > 0:
+		return FuzzTest, true
+	}
+	return UnitTest, true
+}
+
+// expectsRevert reports whether the body of the function calls
+// `vm.expectRevert`.
+func expectsRevert(fn *ast.FunctionDeclaration) bool {
+	if fn.Body == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || found {
+			return !found
+		}
+		if callee, ok := call.Function.(*ast.MemberAccessExpression); ok && callee.Member.Name == "expectRevert" && ast.ExprString(callee.Expression) == "vm" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// FuzzParams returns the parameters of the fuzz test, the inputs forge
+// generates; or nil if it's not fuzzed.
+func (t FoundryTest) FuzzParams() []*ast.Param {
+	if t.Kind != FuzzTest || t.Decl.Type.Params == nil {
+		return nil
+	}
+	return t.Decl.Type.Params.List
+}
+
+// PathTests returns the test contracts of the documents in the file or
+// directory, see documentsUnder.
+func (s *State) PathTests(path string) []TestContract {
+	res := []TestContract{}
+	for _, doc := range s.documentsUnder(path) {
+		res = append(res, s.Tests(doc)...)
+	}
+	return res
+}
+
+// TestsReport returns the test contracts of the document with their tests.
+func (s *State) TestsReport(id lsp.ID, uri string) lsp.TestsResponse {
+	report := []lsp.TestContract{}
+	doc, ok := s.document(uri)
+	if !ok {
+		return lsp.NewTestsResponse(id, report)
+	}
+	for _, tc := range s.Tests(doc) {
+		report = append(report, tc.report())
+	}
+	return lsp.NewTestsResponse(id, report)
+}
+
+// report returns the test contract as sent to the clients and printed by
+// `solbot tests`.
+func (tc TestContract) report() lsp.TestContract {
+	location := func(sym *Symbol) lsp.Location {
+		return lsp.Location{URI: sym.Doc.URI, Range: toLspRange(sym.Doc.Handle, ast.NodeRange(sym.Name))}
+	}
+	res := lsp.TestContract{Contract: tc.Contract.Name.Name, Location: location(tc.Contract), Tests: []lsp.TestItem{}}
+	if tc.SetUp != nil {
+		l := location(tc.SetUp)
+		res.SetUp = &l
+	}
+	for _, test := range tc.Tests {
+		params := []lsp.TestParam{}
+		for _, param := range test.FuzzParams() {
+			name := ""
+			if param.Name != nil {
+				name = param.Name.Name
+			}
+			params = append(params, lsp.TestParam{Name: name, Type: ast.ExprString(param.Type)})
+		}
+		res.Tests = append(res.Tests, lsp.TestItem{
+			Name:          test.Decl.Name.Name,
+			Kind:          string(test.Kind),
+			Location:      location(&Symbol{Doc: test.Doc, Name: test.Decl.Name, Node: test.Decl}),
+			ExpectsRevert: test.ExpectsRevert,
+			FuzzParams:    params,
+			Command:       tc.Command(test),
+		})
+	}
+	return res
+}
+
+// testLenses returns the "run" and "debug" lenses above the tests declared
+// in the document, for each of the contracts running them. The tests of an
+// abstract base are run by the contracts of the other files, so all of the
+// Foundry test files are looked at.
+func (s *State) testLenses(doc *Document) []lsp.CodeLens {
+	lenses := []lsp.CodeLens{}
+	contracts := []TestContract{}
+	for _, other := range s.sortedDocuments() {
+		if other == doc || s.isFoundryTest(other) {
+			contracts = append(contracts, s.Tests(other)...)
+		}
+	}
+	for _, tc := range contracts {
+		for _, test := range tc.Tests {
+			if test.Doc != doc {
+				continue
+			}
+			r := toLspRange(doc.Handle, ast.NodeRange(test.Decl.Name))
+			command := tc.Command(test)
+			run := []any{}
+			for _, word := range command {
+				run = append(run, word)
+			}
+			debug := append(append([]any{}, run...), "--debug")
+			lenses = append(lenses,
+				lsp.CodeLens{Range: r, Command: &lsp.Command{Title: "run", Command: lsp.RunTestCommand, Arguments: run}},
+				lsp.CodeLens{Range: r, Command: &lsp.Command{Title: "debug", Command: lsp.DebugTestCommand, Arguments: debug}},
+			)
+		}
+	}
+	return lenses
+}
@@ -0,0 +1,149 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+// openFoundryTests opens a Foundry project whose test contract inherits
+// its setUp and a test from a base in another file.
+func openFoundryTests(s *State) {
+	s.Root = "/ws"
+	s.OpenDocument("file:///ws/lib/forge-std/src/Test.sol", 1, `pragma solidity ^0.8.0;
+
+abstract contract Test {
+    function testHelper() public {}
+}
+`)
+	s.OpenDocument("file:///ws/src/Vault.sol", 1, `pragma solidity ^0.8.0;
+
+contract Vault {
+    function deposit(uint256 amount) external {}
+
+    function testMode() external {}
+}
+`)
+	s.OpenDocument("file:///ws/test/Base.t.sol", 1, `pragma solidity ^0.8.0;
+
+import {Test} from "../lib/forge-std/src/Test.sol";
+import {Vault} from "../src/Vault.sol";
+
+abstract contract VaultTestBase is Test {
+    Vault vault;
+
+    function setUp() public virtual {
+        vault = new Vault();
+    }
+
+    function test_deposit() public {
+        vault.deposit(1);
+    }
+}
+`)
+	s.OpenDocument("file:///ws/test/Vault.t.sol", 1, `pragma solidity ^0.8.0;
+
+import {VaultTestBase} from "./Base.t.sol";
+
+contract VaultTest is VaultTestBase {
+    function test_withdraw() public {
+        vm.expectRevert();
+        vault.deposit(0);
+    }
+
+    function testFuzz_deposit(uint256 amount, address to) public {}
+
+    function test_fork(uint256[] memory ids) external {}
+
+    function invariant_solvency() public view {}
+
+    function testInternal() internal {}
+}
+
+contract OrphanTest is Missing {
+    function test_orphan() public {}
+}
+
+contract Handler is Missing {
+    function test_handler() public {}
+}
+`)
+}
+
+func Test_Tests(t *testing.T) {
+	s := NewState()
+	openFoundryTests(s)
+
+	if tests := s.TestsReport(lsp.IntID(1), "file:///ws/src/Vault.sol").Result; len(tests) != 0 {
+		t.Errorf("Expected no tests in the contract under test, got %d", len(tests))
+	}
+	if tests := s.TestsReport(lsp.IntID(1), "file:///ws/test/Base.t.sol").Result; len(tests) != 0 {
+		t.Errorf("Expected no tests run by the abstract base, got %d", len(tests))
+	}
+
+	got := []string{}
+	for _, c := range s.TestsReport(lsp.IntID(1), "file:///ws/test/Vault.t.sol").Result {
+		setUp := "none"
+		if c.SetUp != nil {
+			setUp = fmt.Sprintf("%s:%d", c.SetUp.URI, c.SetUp.Range.Start.Line)
+		}
+		got = append(got, fmt.Sprintf("%s setUp %s", c.Contract, setUp))
+		for _, test := range c.Tests {
+			params := []string{}
+			for _, p := range test.FuzzParams {
+				params = append(params, p.Type+" "+p.Name)
+			}
+			got = append(got, fmt.Sprintf("  %s %s at %s:%d reverts %t (%s)", test.Kind, test.Name, test.Location.URI, test.Location.Range.Start.Line, test.ExpectsRevert, strings.Join(params, ", ")))
+		}
+	}
+	// The helpers of forge-std and of the contract under test are left
+	// out, and so is Handler, which can't be resolved and isn't named
+	// like a test.
+	expected := []string{
+		"VaultTest setUp file:///ws/test/Base.t.sol:8",
+		"  unit test_withdraw at file:///ws/test/Vault.t.sol:5 reverts true ()",
+		"  fuzz testFuzz_deposit at file:///ws/test/Vault.t.sol:10 reverts false (uint256 amount, address to)",
+		"  fuzz test_fork at file:///ws/test/Vault.t.sol:12 reverts false (uint256[] ids)",
+		"  invariant invariant_solvency at file:///ws/test/Vault.t.sol:14 reverts false ()",
+		"  unit test_deposit at file:///ws/test/Base.t.sol:12 reverts false ()",
+		"OrphanTest setUp none",
+		"  unit test_orphan at file:///ws/test/Vault.t.sol:20 reverts false ()",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the tests\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_TestLenses(t *testing.T) {
+	s := NewState()
+	openFoundryTests(s)
+	s.Config.CodeLens.References = false
+	s.Config.CodeLens.Selectors = false
+
+	if lenses := s.CodeLens(lsp.IntID(1), "file:///ws/test/Vault.t.sol").Result; len(lenses) != 0 {
+		t.Fatalf("Expected no test lenses unless they're enabled, got %d", len(lenses))
+	}
+	s.Config.CodeLens.Tests = true
+
+	// The inherited test_deposit has its lenses in the base.
+	got := []string{}
+	for _, lens := range s.CodeLens(lsp.IntID(1), "file:///ws/test/Base.t.sol").Result {
+		words := []string{}
+		for _, arg := range lens.Command.Arguments {
+			words = append(words, arg.(string))
+		}
+		got = append(got, fmt.Sprintf("%d %s %s: %s", lens.Range.Start.Line, lens.Command.Title, lens.Command.Command, strings.Join(words, " ")))
+	}
+	expected := []string{
+		"12 run solbot.runTest: forge test --match-contract ^VaultTest$ --match-test ^test_deposit$",
+		"12 debug solbot.debugTest: forge test --match-contract ^VaultTest$ --match-test ^test_deposit$ --debug",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the lenses\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	if lenses := s.CodeLens(lsp.IntID(2), "file:///ws/test/Vault.t.sol").Result; len(lenses) != 10 {
+		t.Errorf("Expected 2 lenses for each of the 5 tests, got %d", len(lenses))
+	}
+}
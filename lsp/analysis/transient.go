@@ -0,0 +1,319 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/features"
+	"solbot/lsp"
+	"solbot/semver"
+	"solbot/token"
+	"solbot/yul"
+)
+
+var (
+	// transientVariablesVersion added the transient state variables.
+	transientVariablesVersion = features.TransientVariables.Since
+	// transientOpcodesVersion added `tload` and `tstore` to the inline
+	// assembly, for the cancun EVM version.
+	transientOpcodesVersion = semver.MustParse("0.8.24")
+)
+
+// transientDiagnostics checks the use of the transient storage. The checks
+// can be disabled one by one with their codes in the [detectors] section:
+//
+//   - transient-version: a `transient` state variable, or a `tload` or a
+//     `tstore` in the inline assembly, under a pragma allowing the compilers
+//     which don't support them e.g. `^0.8.20` for the variables;
+//   - transient-type: a transient state variable of a type the compiler
+//     can't keep in the transient storage, a mapping, an array, a struct, a
+//     string or bytes;
+//   - yul-evm-version: a builtin of the inline assembly missing from the EVM
+//     version set in foundry.toml e.g. `tstore` before cancun;
+//   - transient-read: a public or an external function reading a transient
+//     variable that neither it nor the functions and the modifiers it calls
+//     write. The transient storage is cleared at the end of every
+//     transaction, so the read gives zero unless an earlier call of the same
+//     transaction wrote the variable; the order of the reads and the writes
+//     is not checked. The `tload` and the `tstore` of the `.slot` of a
+//     variable in the inline assembly count too, and a `tstore` of any
+//     other slot may write all of them.
+func (s *State) transientDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(r token.Range, severity lsp.DiagnosticSeverity, code, message string) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, r),
+			Severity: severity,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+	version := ""
+	if p := doc.File.Pragma("solidity"); p != nil {
+		version = p.Value
+	}
+	// The pragma must not allow the compilers rejecting the code, even if
+	// it allows the newer ones too.
+	oldVariables := pragma.AllowsBelow(doc.File, transientVariablesVersion)
+	oldOpcodes := pragma.AllowsBelow(doc.File, transientOpcodesVersion)
+
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.ContractDeclaration:
+			for _, member := range n.Body {
+				v, ok := member.(*ast.VariableDeclaration)
+				if !ok || v.Transient == 0 {
+					continue
+				}
+				keyword := token.Range{Start: v.Transient, End: v.Transient + token.Pos(len("transient"))}
+				if oldVariables {
+					report(keyword, lsp.SeverityError, "transient-version", fmt.Sprintf("`transient` state variables require Solidity %s, but the pragma `%s` allows older compilers", transientVariablesVersion, version))
+				}
+				if !s.transientType(doc, v.Type) {
+					report(ast.NodeRange(v.Type), lsp.SeverityError, "transient-type", fmt.Sprintf("`%s` can't be transient, only the value types are supported in the transient storage", ast.ExprString(v.Type)))
+				}
+			}
+		case *ast.AssemblyStatement:
+			calls, err := yul.Calls(n.Body, n.LeftBrace+1)
+			if err != nil {
+				return true
+			}
+			for _, call := range calls {
+				r := token.Range{Start: call.Pos, End: call.Pos + token.Pos(len(call.Name))}
+				if oldOpcodes && (call.Name == "tload" || call.Name == "tstore") {
+					report(r, lsp.SeverityError, "transient-version", fmt.Sprintf("`%s` requires Solidity %s, but the pragma `%s` allows older compilers", call.Name, transientOpcodesVersion, version))
+				}
+				if !yul.BuiltinAvailable(call.Name, s.Config.EVMVersion) {
+					report(r, lsp.SeverityError, "yul-evm-version", fmt.Sprintf("`%s` requires the %s EVM version, but the project targets %s", call.Name, yul.BuiltinSince(call.Name), s.Config.EVMVersion))
+				}
+			}
+		}
+		return true
+	})
+
+	if pragma.AllowsAtLeast(doc.File, transientVariablesVersion) && !slices.Contains(s.Config.Disabled, "transient-read") {
+		res = append(res, s.transientReadDiagnostics(doc)...)
+	}
+	return res
+}
+
+// transientType reports whether the transient storage supports the type,
+// which is the case for the value types only. The types that can't be
+// resolved are assumed to be supported.
+func (s *State) transientType(doc *Document, t ast.Expression) bool {
+	switch t := t.(type) {
+	case *ast.ElementaryType:
+		return t.Value != "string" && t.Value != "bytes"
+	case *ast.MappingType, *ast.ArrayType:
+		return false
+	case *ast.Identifier, *ast.MemberAccessExpression:
+		sym := s.follow(s.resolveExpr(doc, ast.PathEnclosingPos(doc.File, t.Start()), t))
+		if sym == nil {
+			return true
+		}
+		_, isStruct := sym.Node.(*ast.StructDeclaration)
+		return !isStruct
+	}
+	return true
+}
+
+// transientRead is the first read of a transient state variable by a
+// function or a modifier.
+type transientRead struct {
+	doc   *Document
+	ident *ast.Identifier
+	decl  *ast.VariableDeclaration
+}
+
+// transientAccess are the reads and the writes of the transient state
+// variables by a function or a modifier, its own.
+type transientAccess struct {
+	reads  []transientRead // in the order of the code
+	writes map[*ast.VariableDeclaration]bool
+}
+
+// transientAccesses returns the accesses of the transient state variables
+// by the functions and the modifiers of the document.
+func (s *State) transientAccesses(doc *Document) map[ast.Node]*transientAccess {
+	res := map[ast.Node]*transientAccess{}
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		// The slots of the inline assembly are read and written with the
+		// opcodes.
+		if _, ok := path[1].(*ast.AssemblyStatement); ok {
+			return
+		}
+		sym := s.follow(s.resolve(doc, path))
+		if sym == nil || sym.Name == ident {
+			return
+		}
+		decl, ok := sym.Node.(*ast.VariableDeclaration)
+		if !ok || decl.Transient == 0 {
+			return
+		}
+		i := slices.IndexFunc(path, func(node ast.Node) bool {
+			switch node.(type) {
+			case *ast.FunctionDeclaration, *ast.ModifierDeclaration:
+				return true
+			}
+			return false
+		})
+		if i < 0 {
+			return
+		}
+		owner := path[i]
+		access := res[owner]
+		if access == nil {
+			access = &transientAccess{writes: map[*ast.VariableDeclaration]bool{}}
+			res[owner] = access
+		}
+		if _, written := writtenAt(path); written {
+			access.writes[decl] = true
+			return
+		}
+		if !slices.ContainsFunc(access.reads, func(r transientRead) bool { return r.decl == decl }) {
+			access.reads = append(access.reads, transientRead{doc: doc, ident: ident, decl: decl})
+		}
+	})
+	return res
+}
+
+// transientReadDiagnostics returns the transient-read diagnostics, see
+// transientDiagnostics.
+func (s *State) transientReadDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	accesses := map[*Document]map[ast.Node]*transientAccess{doc: s.transientAccesses(doc)}
+	accessOf := func(c callable) *transientAccess {
+		if accesses[c.doc] == nil {
+			accesses[c.doc] = s.transientAccesses(c.doc)
+		}
+		return accesses[c.doc][c.node]
+	}
+
+	entries := []callable{}
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		if c, ok := node.(*ast.ContractDeclaration); ok {
+			// Only the contracts with transient variables, their own or
+			// inherited, can read them.
+			return s.hasTransientVariables(&Symbol{Doc: doc, Name: c.Name, Node: c})
+		}
+		if fn, ok := node.(*ast.FunctionDeclaration); ok {
+			if fn.Body != nil && fn.Kind != token.CONSTRUCTOR && (fn.Type.Visibility == ast.Public || fn.Type.Visibility == ast.External) {
+				entries = append(entries, callable{doc: doc, node: fn})
+			}
+			return false
+		}
+		return true
+	})
+	if len(entries) == 0 {
+		return res
+	}
+	effects := s.inferEffects(entries)
+
+	for _, entry := range entries {
+		reads := []transientRead{}
+		writes := map[*ast.VariableDeclaration]bool{}
+		anyWrite := false // a tstore of a slot that is not known
+		visited := map[ast.Node]bool{}
+		var visit func(c callable)
+		visit = func(c callable) {
+			if visited[c.node] {
+				return
+			}
+			visited[c.node] = true
+			if access := accessOf(c); access != nil {
+				reads = append(reads, access.reads...)
+				for decl := range access.writes {
+					writes[decl] = true
+				}
+			}
+			if f := effects[c.node]; f != nil {
+				for _, slot := range f.slots {
+					switch {
+					case slot.effect == yul.TransientWrite && slot.decl == nil:
+						anyWrite = true
+					case slot.effect == yul.TransientWrite:
+						writes[slot.decl] = true
+					case slot.effect == yul.TransientRead && slot.decl != nil && slot.decl.Transient != 0:
+						reads = append(reads, transientRead{doc: c.doc, ident: slot.ident, decl: slot.decl})
+					}
+				}
+				for _, call := range f.calls {
+					visit(call.callee)
+				}
+			}
+		}
+		visit(entry)
+		if anyWrite {
+			continue
+		}
+
+		reported := map[*ast.VariableDeclaration]bool{}
+		for _, read := range reads {
+			if writes[read.decl] || reported[read.decl] {
+				continue
+			}
+			reported[read.decl] = true
+			d := lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, ast.NodeRange(entry.node.(*ast.FunctionDeclaration).Name)),
+				Severity: lsp.SeverityInformation,
+				Code:     "transient-read",
+				Source:   "solbot",
+				Message: fmt.Sprintf("`%s` reads the transient `%s`, which neither it nor the functions it calls write. "+
+					"The transient storage is cleared at the end of every transaction, so it reads zero unless an earlier call of the same transaction wrote it",
+					entry.name(), read.decl.Name.Name),
+			}
+			if read.doc == doc && entry.node.Start() <= read.ident.Start() && read.ident.End() <= entry.node.End() {
+				d.Range = toLspRange(doc.Handle, ast.NodeRange(read.ident))
+			} else {
+				d.RelatedInformation = []lsp.DiagnosticRelatedInformation{{
+					Location: lsp.Location{URI: read.doc.URI, Range: toLspRange(read.doc.Handle, ast.NodeRange(read.ident))},
+					Message:  fmt.Sprintf("`%s` is read here", read.decl.Name.Name),
+				}}
+			}
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+// hasTransientVariables reports whether the contract or one of its bases
+// declares a transient state variable.
+func (s *State) hasTransientVariables(contract *Symbol) bool {
+	for _, c := range s.linearize(contract) {
+		for _, member := range c.Node.(*ast.ContractDeclaration).Body {
+			if v, ok := member.(*ast.VariableDeclaration); ok && v.Transient != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// transientSlotHover returns the transient storage slot of the transient
+// state variable; or an empty string if it's not one or the layout can't be
+// computed.
+func (s *State) transientSlotHover(sym *Symbol) string {
+	v, ok := sym.Node.(*ast.VariableDeclaration)
+	if !ok || v.Transient == 0 {
+		return ""
+	}
+	contract := s.declaringContract(sym)
+	if contract == nil {
+		return ""
+	}
+	vars, ok := s.stateVariables(contract, true)
+	if !ok {
+		return ""
+	}
+	for _, sv := range vars {
+		if sv.decl.Node == sym.Node {
+			return fmt.Sprintf("transient storage slot %d, offset %d, cleared at the end of every transaction", sv.slot.Slot, sv.slot.Offset)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,234 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const transientGuard = `pragma solidity ^0.8.28;
+
+contract Guard {
+    uint256 transient locked;
+    uint128 transient depth;
+    uint256 balance;
+    address transient caller;
+
+    modifier nonReentrant() {
+        require(locked == 0, "reentrant");
+        locked = 1;
+        _;
+        locked = 0;
+    }
+
+    function deposit() external payable nonReentrant {
+        balance += msg.value;
+    }
+
+    function isLocked() external view returns (bool) {
+        return locked == 1;
+    }
+
+    function enter() external {
+        depth += 1;
+        caller = msg.sender;
+    }
+}
+`
+
+// transientDiagnostics returns the diagnostics of the transient storage of
+// the document as "line code: message".
+func transientDiagnostics(s *State, uri string) []string {
+	got := []string{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		switch d.Code {
+		case "transient-version", "transient-type", "transient-read", "yul-evm-version":
+			got = append(got, fmt.Sprintf("%d %s: %s", d.Range.Start.Line, d.Code, d.Message))
+		}
+	}
+	return got
+}
+
+func Test_TransientLayout(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/Guard.sol", 1, transientGuard)
+	doc := s.Documents["file:///ws/Guard.sol"]
+	c := doc.File.Declarations[1].(*ast.ContractDeclaration)
+	guard := &Symbol{Doc: doc, Name: c.Name, Node: c}
+
+	storage, ok := s.storageLayout(guard)
+	if !ok {
+		t.Fatalf("Expected the storage layout of Guard")
+	}
+	expected := []StorageSlot{{Contract: "Guard", Name: "balance", Type: "uint256", Slot: 0, Offset: 0, Size: 32}}
+	if fmt.Sprint(storage) != fmt.Sprint(expected) {
+		t.Errorf("Expected the storage layout %v, got %v", expected, storage)
+	}
+	// The transient variables are numbered apart, packed like the storage.
+	transient, ok := s.transientLayout(guard)
+	if !ok {
+		t.Fatalf("Expected the transient layout of Guard")
+	}
+	expected = []StorageSlot{
+		{Contract: "Guard", Name: "locked", Type: "uint256", Slot: 0, Offset: 0, Size: 32},
+		{Contract: "Guard", Name: "depth", Type: "uint128", Slot: 1, Offset: 0, Size: 16},
+		{Contract: "Guard", Name: "caller", Type: "address", Slot: 2, Offset: 0, Size: 20},
+	}
+	if fmt.Sprint(transient) != fmt.Sprint(expected) {
+		t.Errorf("Expected the transient layout %v, got %v", expected, transient)
+	}
+
+	hover := s.Hover(lsp.IntID(1), "file:///ws/Guard.sol", lsp.Position{Line: 6, Character: 24}).Result.Contents.Value
+	if expected := "transient storage slot 2, offset 0, cleared at the end of every transaction"; !strings.HasSuffix(hover, "\n\n"+expected) {
+		t.Errorf("Expected the hover of caller to end with %q, got %q", expected, hover)
+	}
+}
+
+func Test_TransientDiagnostics(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/Guard.sol", 1, transientGuard)
+
+	// The guard reads and writes the lock in the same transaction, but the
+	// view function reads it alone.
+	expected := []string{
+		"20 transient-read: `isLocked` reads the transient `locked`, which neither it nor the functions it calls write. " +
+			"The transient storage is cleared at the end of every transaction, so it reads zero unless an earlier call of the same transaction wrote it",
+	}
+	if got := transientDiagnostics(s, "file:///ws/Guard.sol"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_TransientAssemblyAccess(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/Guard.sol", 1, strings.Replace(transientGuard, `    function enter() external {`, `    function lock() external {
+        assembly {
+            tstore(locked.slot, 1)
+        }
+    }
+
+    function peek() external view returns (uint256 d) {
+        assembly {
+            d := tload(depth.slot)
+        }
+    }
+
+    function enter() external {`, 1))
+
+	// The read of the lock is satisfied by the write in the assembly of
+	// another function only if they run in the same transaction, so
+	// isLocked is still reported; the read of depth in the assembly is too.
+	got := transientDiagnostics(s, "file:///ws/Guard.sol")
+	if len(got) != 2 || !strings.HasPrefix(got[0], "20 transient-read: `isLocked` reads the transient `locked`") ||
+		!strings.HasPrefix(got[1], "31 transient-read: `peek` reads the transient `depth`") {
+		t.Fatalf("Expected the reads of locked and depth, got %v", got)
+	}
+
+	// A write of the slot of the read variable, or of an unknown slot,
+	// counts as the write.
+	s.OpenDocument("file:///ws/Guard.sol", 2, strings.Replace(transientGuard, `        return locked == 1;`, `        assembly {
+            tstore(locked.slot, 0)
+        }
+        return locked == 1;`, 1))
+	if got := transientDiagnostics(s, "file:///ws/Guard.sol"); len(got) != 0 {
+		t.Errorf("Expected no diagnostics with the write of the slot, got %v", got)
+	}
+	s.OpenDocument("file:///ws/Guard.sol", 3, strings.Replace(transientGuard, `        return locked == 1;`, `        assembly {
+            tstore(0x42, 0)
+        }
+        return locked == 1;`, 1))
+	if got := transientDiagnostics(s, "file:///ws/Guard.sol"); len(got) != 0 {
+		t.Errorf("Expected no diagnostics with the write of an unknown slot, got %v", got)
+	}
+}
+
+func Test_TransientVersion(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/Guard.sol", 1, strings.Replace(transientGuard, "^0.8.28", "^0.8.20", 1))
+	s.OpenDocument("file:///ws/Lock.sol", 1, `pragma solidity 0.8.20;
+
+contract Lock {
+    function lock() external {
+        assembly {
+            tstore(0, 1)
+        }
+    }
+}
+`)
+
+	expected := []string{
+		"3 transient-version: `transient` state variables require Solidity 0.8.28, but the pragma `^0.8.20` allows older compilers",
+		"4 transient-version: `transient` state variables require Solidity 0.8.28, but the pragma `^0.8.20` allows older compilers",
+		"6 transient-version: `transient` state variables require Solidity 0.8.28, but the pragma `^0.8.20` allows older compilers",
+		"20 transient-read: `isLocked` reads the transient `locked`, which neither it nor the functions it calls write. " +
+			"The transient storage is cleared at the end of every transaction, so it reads zero unless an earlier call of the same transaction wrote it",
+	}
+	if got := transientDiagnostics(s, "file:///ws/Guard.sol"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	expected = []string{"5 transient-version: `tstore` requires Solidity 0.8.24, but the pragma `0.8.20` allows older compilers"}
+	if got := transientDiagnostics(s, "file:///ws/Lock.sol"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// The opcodes need the cancun EVM version too.
+	s.OpenDocument("file:///ws/Lock.sol", 2, `pragma solidity ^0.8.24;
+
+contract Lock {
+    function lock() external {
+        assembly {
+            tstore(0, add(tload(0), 1))
+        }
+    }
+}
+`)
+	s.Config.EVMVersion = "shanghai"
+	expected = []string{
+		"5 yul-evm-version: `tstore` requires the cancun EVM version, but the project targets shanghai",
+		"5 yul-evm-version: `tload` requires the cancun EVM version, but the project targets shanghai",
+	}
+	if got := transientDiagnostics(s, "file:///ws/Lock.sol"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	s.Config.EVMVersion = "cancun"
+	if got := transientDiagnostics(s, "file:///ws/Lock.sol"); len(got) != 0 {
+		t.Errorf("Expected no diagnostics under cancun, got %v", got)
+	}
+}
+
+func Test_TransientType(t *testing.T) {
+	s := NewState()
+	s.OpenDocument("file:///ws/Types.sol", 1, `pragma solidity ^0.8.28;
+
+type Price is uint128;
+
+contract Types {
+    enum Phase { Open, Closed }
+    struct Point { uint256 x; }
+
+    mapping(address => uint256) transient balances;
+    uint256[] transient values;
+    Point transient point;
+    string transient name;
+    bytes transient data;
+    Phase transient phase;
+    Price transient price;
+    bytes32 transient hash;
+}
+`)
+
+	expected := []string{
+		"8 transient-type: `mapping(address => uint256)` can't be transient, only the value types are supported in the transient storage",
+		"9 transient-type: `uint256[]` can't be transient, only the value types are supported in the transient storage",
+		"10 transient-type: `Point` can't be transient, only the value types are supported in the transient storage",
+		"11 transient-type: `string` can't be transient, only the value types are supported in the transient storage",
+		"12 transient-type: `bytes` can't be transient, only the value types are supported in the transient storage",
+	}
+	if got := transientDiagnostics(s, "file:///ws/Types.sol"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,245 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// panicCodes are the codes of the `Panic(uint256)` errors raised by the
+// compiler generated checks.
+var panicCodes = []struct {
+	code        string
+	description string
+}{
+	{"0x00", "generic compiler inserted panic"},
+	{"0x01", "failed `assert`"},
+	{"0x11", "arithmetic overflow or underflow outside of an `unchecked` block"},
+	{"0x12", "division or modulo by zero"},
+	{"0x21", "conversion of a value out of range to an enum"},
+	{"0x22", "access to an incorrectly encoded storage byte array"},
+	{"0x31", "`pop` on an empty array"},
+	{"0x32", "array or `bytes` index out of bounds"},
+	{"0x41", "too much memory allocated or an array too large"},
+	{"0x51", "call of a zero-initialized internal function variable"},
+}
+
+// catchKind returns the kind of the catch clause: "Error" for the clause
+// catching the reverts with a reason, "Panic" for the failed assertions and
+// the arithmetic errors, and an empty string for the low-level clause
+// catching everything else.
+func catchKind(clause *ast.CatchClause) string {
+	if clause.Kind == nil {
+		return ""
+	}
+	return clause.Kind.Name
+}
+
+// tryDiagnostics checks the try statements of the document:
+//   - the expression must be an external call or a contract creation,
+//   - the clauses can only be `Error(string memory)`, `Panic(uint256)` and
+//     the low-level one taking nothing or `bytes memory`,
+//   - every kind of the clause can be used once.
+//
+// The calls whose callee can't be resolved are assumed to be external.
+func (s *State) tryDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	report := func(node ast.Node, code, message string) {
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(node)),
+			Severity: lsp.SeverityError,
+			Code:     code,
+			Source:   "solbot",
+			Message:  message,
+		})
+	}
+
+	ast.Inspect(doc.File, func(node ast.Node) bool {
+		stmt, ok := node.(*ast.TryStatement)
+		if !ok {
+			return true
+		}
+		path := ast.PathEnclosingPos(doc.File, stmt.Expression.Start())
+		if reason := s.notExternalCall(doc, path, stmt.Expression); reason != "" {
+			report(stmt.Expression, "invalid-try",
+				fmt.Sprintf("`try` expects an external function call or a contract creation, got %s", reason))
+		}
+
+		seen := map[string]bool{}
+		for _, clause := range stmt.Catches {
+			kind := catchKind(clause)
+			// The low-level clauses are reported at the keyword.
+			at := clause.Kind
+			if at == nil {
+				at = &ast.Identifier{NamePos: clause.Catch, Name: "catch"}
+			}
+			if seen[kind] {
+				name := "low-level"
+				if kind != "" {
+					name = "`" + kind + "`"
+				}
+				report(at, "duplicate-catch", fmt.Sprintf("The try statement already has a %s catch clause", name))
+				continue
+			}
+			seen[kind] = true
+			if message := checkCatchParams(kind, clause.Params); message != "" {
+				report(at, "invalid-catch", message)
+			}
+		}
+		return true
+	})
+	return res
+}
+
+// checkCatchParams returns the problem with the parameters of the catch
+// clause of the kind; or an empty string if there is none.
+func checkCatchParams(kind string, params *ast.ParamList) string {
+	var list []*ast.Param
+	if params != nil {
+		list = params.List
+	}
+	single := func(types ...string) bool {
+		if len(list) != 1 {
+			return false
+		}
+		t, ok := list[0].Type.(*ast.ElementaryType)
+		for _, want := range types {
+			if ok && t.Value == want {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch kind {
+	case "Error":
+		if !single("string") || list[0].Location != ast.Memory {
+			return "`catch Error` takes a single `string memory` parameter, the revert reason"
+		}
+	case "Panic":
+		if !single("uint256", "uint") {
+			return "`catch Panic` takes a single `uint256` parameter, the panic code"
+		}
+	case "":
+		if params != nil && (!single("bytes") || list[0].Location != ast.Memory) {
+			return "The low-level catch clause takes no parameters or a single `bytes memory` parameter, the return data"
+		}
+	default:
+		return fmt.Sprintf("Unknown catch clause `%s`; only `Error` and `Panic` can be caught by name, "+
+			"the custom errors are caught by the low-level clause", kind)
+	}
+	return ""
+}
+
+// notExternalCall describes the expression of a try statement which is
+// neither an external call nor a contract creation e.g. "the internal call
+// of `_transfer`"; or returns an empty string if it is one, or if it can't
+// be told.
+func (s *State) notExternalCall(doc *Document, path []ast.Node, x ast.Expression) string {
+	call, ok := x.(*ast.CallExpression)
+	if !ok {
+		return fmt.Sprintf("`%s`", ast.ExprString(x))
+	}
+	fn := call.Function
+	if opts, ok := fn.(*ast.CallOptionsExpression); ok {
+		fn = opts.Expression
+	}
+
+	switch fn := fn.(type) {
+	case *ast.NewExpression:
+		if _, ok := fn.Type.(*ast.ArrayType); ok {
+			return "the array allocation"
+		}
+		return ""
+	case *ast.Identifier:
+		sym := s.follow(s.lookup(doc, path, fn.Name, fn.Start()))
+		if sym == nil {
+			return ""
+		}
+		switch sym.Node.(type) {
+		case *ast.FunctionDeclaration:
+			return fmt.Sprintf("the internal call of `%s`", fn.Name)
+		case *ast.ContractDeclaration, *ast.StructDeclaration:
+			return fmt.Sprintf("the conversion `%s`", ast.ExprString(call))
+		}
+		return ""
+	case *ast.MemberAccessExpression:
+		name := ast.ExprString(fn)
+		if base, ok := fn.Expression.(*ast.Identifier); ok {
+			switch base.Name {
+			case "this":
+				return ""
+			case "super":
+				return fmt.Sprintf("the internal call of `%s`", name)
+			}
+		}
+		switch fn.Member.Name {
+		case "call", "delegatecall", "staticcall":
+			if _, t := s.typeOf(doc, path, fn.Expression); isAddress(t) {
+				return fmt.Sprintf("the low-level call `%s`", name)
+			}
+		}
+		// A call through the name of a contract is internal e.g.
+		// `Base.f()`, except for the external functions of the libraries.
+		base := s.follow(s.resolveExpr(doc, path, fn.Expression))
+		if base == nil {
+			return ""
+		}
+		c, ok := base.Node.(*ast.ContractDeclaration)
+		if !ok {
+			return ""
+		}
+		member := s.follow(s.member(base, fn.Member.Name))
+		if member == nil {
+			return ""
+		}
+		decl, ok := member.Node.(*ast.FunctionDeclaration)
+		if !ok {
+			return ""
+		}
+		if c.Kind == token.LIBRARY && (decl.Type.Visibility == ast.External || decl.Type.Visibility == ast.Public) {
+			return ""
+		}
+		return fmt.Sprintf("the internal call of `%s`", name)
+	}
+	return ""
+}
+
+// isAddress reports whether the type is `address` or `address payable`.
+func isAddress(t ast.Expression) bool {
+	e, ok := t.(*ast.ElementaryType)
+	return ok && e.Value == "address"
+}
+
+// panicCodesHover lists the standard panic codes for the parameter of a
+// `catch Panic` clause; or returns an empty string for the other symbols.
+func panicCodesHover(sym *Symbol, markdown bool) string {
+	if _, ok := sym.Node.(*ast.Param); !ok {
+		return ""
+	}
+	path := ast.PathEnclosingPos(sym.Doc.File, sym.Name.Start())
+	if len(path) < 4 {
+		return ""
+	}
+	clause, ok := path[3].(*ast.CatchClause)
+	if !ok || catchKind(clause) != "Panic" {
+		return ""
+	}
+
+	var b strings.Builder
+	if markdown {
+		b.WriteString("**Panic codes**:\n")
+	} else {
+		b.WriteString("Panic codes:\n")
+	}
+	for _, p := range panicCodes {
+		if markdown {
+			fmt.Fprintf(&b, "- `%s`: %s\n", p.code, p.description)
+		} else {
+			fmt.Fprintf(&b, "- %s: %s\n", p.code, strings.ReplaceAll(p.description, "`", ""))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,138 @@
+package analysis
+
+import (
+	"solbot/ast"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const tryCatchSrc = `pragma solidity ^0.8.0;
+
+interface IERC20 {
+    function transferFrom(address from, address to, uint256 value) external returns (bool);
+}
+
+contract Vault {
+    IERC20 token;
+    string lastReason;
+    uint256 lastCode;
+    bytes lastData;
+
+    function pull(address from, uint256 value) external returns (bool) {
+        try token.transferFrom(from, address(this), value) returns (bool ok) {
+            return ok;
+        } catch Error(string memory reason) {
+            lastReason = reason;
+        } catch Panic(uint code) {
+            lastCode = code;
+        } catch (bytes memory data) {
+            lastData = data;
+        }
+        return false;
+    }
+}
+`
+
+func Test_TryCatchBindings(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, tryCatchSrc)
+	doc := s.Documents[uri]
+
+	expected := map[string]string{"ok": "bool", "reason": "string", "code": "uint", "data": "bytes"}
+	resolved := map[string]bool{}
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		want, ok := expected[ident.Name]
+		if _, isParam := path[1].(*ast.Param); !ok || isParam {
+			return
+		}
+		sym := s.resolve(doc, path)
+		if sym == nil {
+			t.Errorf("Expected `%s` at %d to resolve, got nil", ident.Name, ident.Start())
+			return
+		}
+		if _, ok := sym.Node.(*ast.Param); !ok {
+			t.Errorf("Expected `%s` to resolve to a parameter, got %T", ident.Name, sym.Node)
+		}
+		if _, typ := s.typeOf(doc, path, ident); ast.ExprString(typ) != want {
+			t.Errorf("Expected `%s` of type %s, got %s", ident.Name, want, ast.ExprString(typ))
+		}
+		resolved[ident.Name] = true
+	})
+	if len(resolved) != len(expected) {
+		t.Errorf("Expected all of the bindings to be used, got %v", resolved)
+	}
+	if diagnostics := s.tryDiagnostics(doc); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diagnostics)
+	}
+}
+
+func Test_TryCatchDiagnostics(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, `pragma solidity ^0.8.0;
+
+contract Vault {
+    function _pull(uint256 value) internal returns (bool) {}
+
+    function pull(uint256 value) external {
+        try _pull(value) returns (bool ok) {
+        } catch Panic(uint8 code) {
+        } catch {
+        } catch (bytes memory data) {
+        } catch Failure(string memory reason) {
+        }
+        try this.pull(value) {
+        } catch Error(string memory reason) {
+        }
+    }
+}
+`)
+
+	expected := []struct {
+		code string
+		line uint
+	}{
+		{"invalid-try", 6},
+		{"invalid-catch", 7},
+		{"duplicate-catch", 9},
+		{"invalid-catch", 10},
+	}
+	diagnostics := s.tryDiagnostics(s.Documents[uri])
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for i, e := range expected {
+		d := diagnostics[i]
+		if d.Code != e.code || d.Range.Start.Line != e.line || d.Severity != lsp.SeverityError {
+			t.Errorf("Expected %s at line %d, got %s at line %d", e.code, e.line, d.Code, d.Range.Start.Line)
+		}
+	}
+	message := "`try` expects an external function call or a contract creation, got the internal call of `_pull`"
+	if diagnostics[0].Message != message {
+		t.Errorf("Expected %q, got %q", message, diagnostics[0].Message)
+	}
+}
+
+func Test_HoverPanicCode(t *testing.T) {
+	uri := "file:///ws/src/Vault.sol"
+	s := NewState()
+	s.OpenDocument(uri, 1, tryCatchSrc)
+
+	// The parameter and its use show the codes, the other parameters don't.
+	for _, position := range []lsp.Position{{Line: 17, Character: 27}, {Line: 18, Character: 23}} {
+		hover := s.Hover(lsp.IntID(1), uri, position).Result.Contents.Value
+		if !strings.HasPrefix(hover, "```solidity\nuint code\n```\n\n**Panic codes**:\n- `0x00`: generic compiler inserted panic\n") {
+			t.Errorf("Expected the panic codes, got %q", hover)
+		}
+		for _, code := range []string{"`0x01`: failed `assert`", "`0x11`: arithmetic overflow", "`0x12`: division or modulo by zero", "`0x32`: array or `bytes` index out of bounds"} {
+			if !strings.Contains(hover, code) {
+				t.Errorf("Expected %q in the hover, got %q", code, hover)
+			}
+		}
+	}
+	if hover := s.Hover(lsp.IntID(1), uri, lsp.Position{Line: 15, Character: 36}).Result.Contents.Value; strings.Contains(hover, "Panic codes") {
+		t.Errorf("Expected no panic codes for the revert reason, got %q", hover)
+	}
+}
@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+)
+
+// unusedDiagnostics reports the parameters and the local variables never
+// used in the body of their function or modifier, with the code
+// unused-variable. The uses in the assembly blocks count. The named results
+// are assigned by the return statements, the unnamed parameters can't be
+// used, and the parameters of the virtual functions are kept for their
+// overrides, so none of them is reported.
+func (s *State) unusedDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	if slices.Contains(s.Config.Disabled, "unused-variable") {
+		return res
+	}
+
+	declared := []*ast.Identifier{}
+	kinds := map[*ast.Identifier]string{}
+	names := map[string]bool{}
+	declare := func(name *ast.Identifier, kind string) {
+		if name == nil || name.Name == "" {
+			return
+		}
+		declared = append(declared, name)
+		kinds[name] = kind
+		names[name.Name] = true
+	}
+	declareParams := func(params *ast.ParamList) {
+		if params == nil {
+			return
+		}
+		for _, param := range params.List {
+			declare(param.Name, "parameter")
+		}
+	}
+	ast.Inspect(doc.File, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionDeclaration:
+			if n.Body != nil && !n.Virtual {
+				declareParams(n.Type.Params)
+			}
+		case *ast.ModifierDeclaration:
+			if n.Body != nil && !n.Virtual {
+				declareParams(n.Params)
+			}
+		case *ast.VariableDeclarationStatement:
+			for _, decl := range n.Declarations {
+				if decl != nil {
+					declare(decl.Name, "local variable")
+				}
+			}
+		}
+		return true
+	})
+	if len(declared) == 0 {
+		return res
+	}
+
+	used := map[*ast.Identifier]bool{}
+	inspectIdentifiers(doc.File, func(ident *ast.Identifier, path []ast.Node) {
+		if !names[ident.Name] || kinds[ident] != "" {
+			return
+		}
+		if sym := s.resolve(doc, path); sym != nil && sym.Doc == doc {
+			used[sym.Name] = true
+		}
+	})
+	for _, name := range declared {
+		if used[name] {
+			continue
+		}
+		res = append(res, lsp.Diagnostic{
+			Range:    toLspRange(doc.Handle, ast.NodeRange(name)),
+			Severity: lsp.SeverityHint,
+			Code:     "unused-variable",
+			Source:   "solbot",
+			Message:  fmt.Sprintf("The %s `%s` is never used", kinds[name], name.Name),
+			Tags:     []lsp.DiagnosticTag{lsp.TagUnnecessary},
+		})
+	}
+	return res
+}
@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"fmt"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+const assemblySrc = `pragma solidity ^0.8.0;
+
+contract Store {
+    uint256 total;
+
+    function put(bytes32 key, uint256 value, uint256 unused) external {
+        uint256 ptr = 0x40;
+        assembly {
+            let ptr := mload(0x40)
+            mstore(ptr, value)
+            sstore(key, mload(ptr))
+            sstore(total.slot, 1)
+        }
+    }
+}
+`
+
+func Test_UnusedDiagnostics(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Store.sol"
+	s.OpenDocument(uri, 1, assemblySrc)
+
+	// `key` and `value` are used in the assembly only, and `ptr` of Solidity
+	// is shadowed there by the Yul variable.
+	expected := []string{
+		"5: The parameter `unused` is never used",
+		"6: The local variable `ptr` is never used",
+	}
+	diagnostics := s.unusedDiagnostics(s.Documents[uri])
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for i, d := range diagnostics {
+		got := fmt.Sprintf("%d: %s", d.Range.Start.Line, d.Message)
+		if got != expected[i] || d.Code != "unused-variable" || d.Severity != lsp.SeverityHint {
+			t.Errorf("Expected %s, got %s (%s, severity %d)", expected[i], got, d.Code, d.Severity)
+		}
+	}
+
+	s.Config.Disabled = []string{"unused-variable"}
+	if diagnostics := s.unusedDiagnostics(s.Documents[uri]); len(diagnostics) != 0 {
+		t.Errorf("Expected unused-variable to be disabled, got %v", diagnostics)
+	}
+}
+
+func Test_ReferencesInAssembly(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/src/Store.sol"
+	s.OpenDocument(uri, 1, assemblySrc)
+
+	tests := []struct {
+		name     string
+		position lsp.Position
+		expected []lsp.Position
+	}{
+		{"parameter", lsp.Position{Line: 5, Character: 40}, []lsp.Position{{Line: 9, Character: 24}}},
+		{"state variable", lsp.Position{Line: 3, Character: 13}, []lsp.Position{{Line: 11, Character: 19}}},
+		{"shadowed local", lsp.Position{Line: 6, Character: 16}, []lsp.Position{}},
+	}
+	for _, tt := range tests {
+		locations := s.References(lsp.IntID(1), uri, tt.position, lsp.ReferenceContext{}).Result
+		if len(locations) != len(tt.expected) {
+			t.Errorf("%s: expected %d references, got %v", tt.name, len(tt.expected), locations)
+			continue
+		}
+		for i, l := range locations {
+			if l.Range.Start != tt.expected[i] {
+				t.Errorf("%s: expected a reference at %v, got %v", tt.name, tt.expected[i], l.Range.Start)
+			}
+		}
+	}
+
+	edit, err := s.rename(uri, lsp.Position{Line: 5, Character: 40}, "amount")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	renamed := applyEdits(s.Documents[uri], edit.Changes[uri])
+	for _, line := range []string{"uint256 amount, uint256 unused", "mstore(ptr, amount)"} {
+		if !strings.Contains(renamed, line) {
+			t.Errorf("Expected the renamed source to contain %q, got:\n%s", line, renamed)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/token"
+	"strings"
+)
+
+// upgradeableDiagnostics reports the bugs of the contracts deployed behind
+// a proxy. A contract is upgradeable if one of its bases matches the
+// patterns configured in solbot.toml, by default the OpenZeppelin
+// `Initializable` and `*Upgradeable` contracts. The checks can be disabled
+// one by one with their codes in the [detectors] section:
+//
+//   - upgradeable-constructor: the constructor runs on the storage of the
+//     implementation, so the state it sets is lost behind the proxy. The
+//     constructor only calling `_disableInitializers()` is the recommended
+//     pattern and it's left alone;
+//   - missing-initializer-modifier: `initialize` without the `initializer`
+//     modifier can be called again e.g. to take over the ownership;
+//   - missing-parent-init: the initializer doesn't call `__X_init` of a
+//     direct base declaring it, so the state of the base is never set;
+//   - upgradeable-state-initializer: the value given in the declaration of
+//     a state variable is set by the constructor, so it's lost as well,
+//     unless the variable is constant or immutable.
+//
+// The diagnostics point to the upgradeable base, and to the initializers of
+// the bases, with the related information.
+func (s *State) upgradeableDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, decl := range doc.File.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok || c.Kind != token.CONTRACT {
+			continue
+		}
+		contract := &Symbol{Doc: doc, Name: c.Name, Node: c}
+		base := s.upgradeableBase(contract)
+		if base == nil {
+			continue
+		}
+		upgradeable := relatedTo(base, fmt.Sprintf("`%s` is upgradeable, since it inherits `%s`", c.Name.Name, base.Name.Name))
+		report := func(r token.Range, code, message string, related ...lsp.DiagnosticRelatedInformation) {
+			if slices.Contains(s.Config.Disabled, code) {
+				return
+			}
+			res = append(res, lsp.Diagnostic{
+				Range:              toLspRange(doc.Handle, r),
+				Severity:           lsp.SeverityWarning,
+				Code:               code,
+				Source:             "solbot",
+				Message:            message,
+				RelatedInformation: related,
+			})
+		}
+
+		for _, member := range c.Body {
+			switch member := member.(type) {
+			case *ast.VariableDeclaration:
+				if member.Value != nil && !member.Constant && !member.Immutable {
+					report(ast.NodeRange(member.Name), "upgradeable-state-initializer",
+						fmt.Sprintf("`%s` is initialized by the constructor of the implementation, so it's not set behind the proxy; "+
+							"assign it in the initializer or make it constant", member.Name.Name), upgradeable)
+				}
+			case *ast.FunctionDeclaration:
+				if member.Body == nil {
+					continue
+				}
+				switch {
+				case member.Kind == token.CONSTRUCTOR && !onlyDisablesInitializers(member.Body):
+					start := member.Start()
+					report(token.Range{Start: start, End: start + token.Pos(len("constructor"))}, "upgradeable-constructor",
+						"The constructor of an upgradeable contract sets the state of the implementation, which is lost behind the proxy; "+
+							"move the logic to the initializer and only call `_disableInitializers()` in the constructor", upgradeable)
+				case member.Kind == token.FUNCTION && member.Name.Name == "initialize" &&
+					!hasModifier(member, "initializer", "reinitializer"):
+					report(ast.NodeRange(member.Name), "missing-initializer-modifier",
+						"`initialize` has no `initializer` modifier, so it can be called again after the deployment", upgradeable)
+				}
+				if !isInitializer(member) {
+					continue
+				}
+				for _, init := range s.missingParentInits(contract, member) {
+					report(ast.NodeRange(member.Name), "missing-parent-init",
+						fmt.Sprintf("`%s` doesn't call `%s` of the base `%s`, so the state of the base is never initialized",
+							member.Name.Name, init.Name.Name, init.base.Name.Name),
+						relatedTo(init.Symbol, fmt.Sprintf("`%s` initializes `%s`", init.Name.Name, init.base.Name.Name)))
+				}
+			}
+		}
+	}
+	return res
+}
+
+// upgradeableBase returns the nearest base of the contract matching the
+// upgradeable patterns; or nil if there is none.
+func (s *State) upgradeableBase(contract *Symbol) *Symbol {
+	for _, base := range s.ancestors(contract)[1:] {
+		for _, pattern := range s.Config.Upgradeable {
+			if ok, _ := path.Match(pattern, base.Name.Name); ok {
+				return base
+			}
+		}
+	}
+	return nil
+}
+
+// parentInit is the initializer of a base e.g. `__Ownable_init` of
+// `OwnableUpgradeable`.
+type parentInit struct {
+	*Symbol
+	base *Symbol
+}
+
+// missingParentInits returns the initializers of the direct bases of the
+// contract which the initializer doesn't call, neither their unchained
+// variants.
+func (s *State) missingParentInits(contract *Symbol, fn *ast.FunctionDeclaration) []parentInit {
+	called := map[string]bool{}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpression); ok {
+			if ident, ok := call.Function.(*ast.Identifier); ok {
+				called[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	res := []parentInit{}
+	for _, base := range s.bases(contract.Doc, contract.Node.(*ast.ContractDeclaration)) {
+		for _, member := range base.Node.(*ast.ContractDeclaration).Body {
+			init, ok := member.(*ast.FunctionDeclaration)
+			if !ok || init.Name == nil || !strings.HasPrefix(init.Name.Name, "__") || !strings.HasSuffix(init.Name.Name, "_init") {
+				continue
+			}
+			if !called[init.Name.Name] && !called[init.Name.Name+"_unchained"] {
+				res = append(res, parentInit{Symbol: &Symbol{Doc: base.Doc, Name: init.Name, Node: init}, base: base})
+			}
+		}
+	}
+	return res
+}
+
+// isInitializer reports whether the function initializes the state of an
+// upgradeable contract. The unchained initializers don't initialize the
+// bases on purpose.
+func isInitializer(fn *ast.FunctionDeclaration) bool {
+	if fn.Kind != token.FUNCTION || strings.HasSuffix(fn.Name.Name, "_unchained") {
+		return false
+	}
+	return fn.Name.Name == "initialize" || hasModifier(fn, "initializer", "reinitializer", "onlyInitializing")
+}
+
+// hasModifier reports whether the function invokes one of the modifiers.
+func hasModifier(fn *ast.FunctionDeclaration, names ...string) bool {
+	for _, inv := range fn.Modifiers {
+		if ident, ok := inv.Name.(*ast.Identifier); ok && slices.Contains(names, ident.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyDisablesInitializers reports whether the body does nothing but
+// calling `_disableInitializers()`, which locks the implementation.
+func onlyDisablesInitializers(body *ast.BlockStatement) bool {
+	for _, stmt := range body.Statements {
+		x, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			return false
+		}
+		call, ok := x.Expression.(*ast.CallExpression)
+		if !ok {
+			return false
+		}
+		if ident, ok := call.Function.(*ast.Identifier); !ok || ident.Name != "_disableInitializers" {
+			return false
+		}
+	}
+	return true
+}
+
+// relatedTo returns the related information pointing to the name of the
+// symbol.
+func relatedTo(sym *Symbol, message string) lsp.DiagnosticRelatedInformation {
+	return lsp.DiagnosticRelatedInformation{
+		Location: lsp.Location{URI: sym.Doc.URI, Range: toLspRange(sym.Doc.Handle, ast.NodeRange(sym.Name))},
+		Message:  message,
+	}
+}
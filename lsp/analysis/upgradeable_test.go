@@ -0,0 +1,160 @@
+package analysis
+
+import (
+	"solbot/lsp"
+	"testing"
+)
+
+const upgradeableSrc = `pragma solidity ^0.8.20;
+
+abstract contract Initializable {
+    modifier initializer() { _; }
+    modifier reinitializer(uint64 version) { _; }
+    modifier onlyInitializing() { _; }
+    function _disableInitializers() internal {}
+}
+
+abstract contract OwnableUpgradeable is Initializable {
+    address private _owner;
+
+    function __Ownable_init(address owner) internal onlyInitializing {
+        __Ownable_init_unchained(owner);
+    }
+
+    function __Ownable_init_unchained(address owner) internal onlyInitializing {
+        _owner = owner;
+    }
+}
+
+abstract contract UUPSUpgradeable is Initializable {
+    function __UUPSUpgradeable_init() internal onlyInitializing {}
+}
+`
+
+func Test_UpgradeableDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		code    string
+		line    uint
+		message string
+		related []lsp.Location
+	}{
+		{
+			"clean",
+			`pragma solidity ^0.8.20;
+
+import "./Upgradeable.sol";
+
+contract Vault is OwnableUpgradeable, UUPSUpgradeable {
+    uint256 constant FEE = 5;
+    uint256 total;
+
+    constructor() {
+        _disableInitializers();
+    }
+
+    function initialize(address owner) external initializer {
+        __Ownable_init(owner);
+        __UUPSUpgradeable_init();
+    }
+}
+`, "", 0, "", nil,
+		},
+		{
+			"missing parent init",
+			`pragma solidity ^0.8.20;
+
+import "./Upgradeable.sol";
+
+contract Vault is OwnableUpgradeable, UUPSUpgradeable {
+    function initialize(address owner) external initializer {
+        __UUPSUpgradeable_init();
+    }
+}
+`, "missing-parent-init", 5, "`initialize` doesn't call `__Ownable_init` of the base `OwnableUpgradeable`, so the state of the base is never initialized",
+			[]lsp.Location{{URI: "file:///ws/src/Upgradeable.sol", Range: lsp.Range{Start: lsp.Position{Line: 12, Character: 13}, End: lsp.Position{Line: 12, Character: 27}}}},
+		},
+		{
+			"constructor",
+			`pragma solidity ^0.8.20;
+
+import "./Upgradeable.sol";
+
+contract Vault is UUPSUpgradeable {
+    address owner;
+
+    constructor() {
+        owner = msg.sender;
+    }
+}
+`, "upgradeable-constructor", 7, "The constructor of an upgradeable contract sets the state of the implementation, which is lost behind the proxy; " +
+				"move the logic to the initializer and only call `_disableInitializers()` in the constructor",
+			[]lsp.Location{{URI: "file:///ws/src/Upgradeable.sol", Range: lsp.Range{Start: lsp.Position{Line: 21, Character: 18}, End: lsp.Position{Line: 21, Character: 33}}}},
+		},
+		{
+			"missing modifier",
+			`pragma solidity ^0.8.20;
+
+import "./Upgradeable.sol";
+
+contract Vault is Initializable {
+    address owner;
+
+    function initialize(address _owner) external {
+        owner = _owner;
+    }
+}
+`, "missing-initializer-modifier", 7, "`initialize` has no `initializer` modifier, so it can be called again after the deployment",
+			[]lsp.Location{{URI: "file:///ws/src/Upgradeable.sol", Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 18}, End: lsp.Position{Line: 2, Character: 31}}}},
+		},
+		{
+			"state initializer",
+			`pragma solidity ^0.8.20;
+
+import "./Upgradeable.sol";
+
+contract Vault is Initializable {
+    uint256 fee = 5;
+    address immutable token = address(0);
+}
+`, "upgradeable-state-initializer", 5, "`fee` is initialized by the constructor of the implementation, so it's not set behind the proxy; " +
+				"assign it in the initializer or make it constant",
+			[]lsp.Location{{URI: "file:///ws/src/Upgradeable.sol", Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 18}, End: lsp.Position{Line: 2, Character: 31}}}},
+		},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		s.OpenDocument("file:///ws/src/Upgradeable.sol", 1, upgradeableSrc)
+		uri := "file:///ws/src/Vault.sol"
+		s.OpenDocument(uri, 1, tt.src)
+
+		diagnostics := s.upgradeableDiagnostics(s.Documents[uri])
+		if tt.code == "" {
+			if len(diagnostics) != 0 {
+				t.Errorf("Expected no diagnostics for %s, got %v", tt.name, diagnostics)
+			}
+			continue
+		}
+		if len(diagnostics) != 1 {
+			t.Errorf("Expected 1 diagnostic for %s, got %v", tt.name, diagnostics)
+			continue
+		}
+		d := diagnostics[0]
+		if d.Code != tt.code || d.Range.Start.Line != tt.line || d.Severity != lsp.SeverityWarning {
+			t.Errorf("Expected %s at line %d, got %s at line %d", tt.code, tt.line, d.Code, d.Range.Start.Line)
+		}
+		if d.Message != tt.message {
+			t.Errorf("Expected %q, got %q", tt.message, d.Message)
+		}
+		if len(d.RelatedInformation) != len(tt.related) || d.RelatedInformation[0].Location != tt.related[0] {
+			t.Errorf("Expected the related locations %v, got %v", tt.related, d.RelatedInformation)
+		}
+
+		// The checks are disabled one by one.
+		s.Config.Disabled = []string{tt.code}
+		if diagnostics := s.upgradeableDiagnostics(s.Documents[uri]); len(diagnostics) != 0 {
+			t.Errorf("Expected %s to be disabled, got %v", tt.code, diagnostics)
+		}
+	}
+}
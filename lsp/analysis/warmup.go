@@ -0,0 +1,273 @@
+package analysis
+
+import (
+	"container/heap"
+	"context"
+	"solbot/ast"
+	"strings"
+	"time"
+)
+
+// Priorities of the files waiting to be indexed by the warmup, the lowest
+// first.
+const (
+	priorityOpen       = iota // open in the editor
+	priorityImport            // imported by an open file
+	prioritySource            // in the source directory e.g. src/ or contracts/
+	priorityTest              // the rest of the project e.g. the tests and the scripts
+	priorityDependency        // in lib/ or node_modules/
+)
+
+// Warmup indexes the files of the workspace in the order the user is most
+// likely to query them: the open files first, then the files they import,
+// then the sources, the tests and finally the dependencies. A request into
+// a file not indexed yet promotes the file and its imports to the front,
+// see Promote, so it waits for them alone, not for the whole workspace.
+//
+// The warmup is not safe for concurrent use, like the state.
+type Warmup struct {
+	state   *State
+	queue   warmupQueue
+	queued  map[string]*warmupFile // URI -> file waiting in the queue
+	imports map[string][]string    // URI -> URIs of the files imported by the indexed document
+	found   int                    // files found under the root
+	done    bool                   // was the end of the warmup logged?
+	start   time.Time
+}
+
+type warmupFile struct {
+	uri      string
+	priority int
+	order    int // order of the walk, which breaks the ties
+	index    int // index in the heap
+}
+
+// NewWarmup makes the directory the root of the workspace, loads the
+// configuration of the project and finds the Solidity files to be indexed
+// under it, like IndexWorkspace. The files listed as open are indexed
+// first, together with the documents opened already. Nothing is indexed
+// until Run or Promote.
+func (s *State) NewWarmup(root string, open []string) (*Warmup, error) {
+	root, err := s.loadProject(root)
+	if err != nil {
+		return nil, err
+	}
+	w := &Warmup{state: s, queued: map[string]*warmupFile{}, imports: map[string][]string{}, start: time.Now()}
+	err = s.walkFiles(root, ".sol", func(p string) error {
+		uri := PathToURI(p)
+		if _, ok := s.Documents[uri]; ok {
+			return nil
+		}
+		f := &warmupFile{uri: uri, priority: s.warmupPriority(uri), order: w.found}
+		w.found++
+		w.queued[uri] = f
+		heap.Push(&w.queue, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, uri := range open {
+		w.raise(uri, priorityOpen)
+	}
+	for _, doc := range s.sortedDocuments() {
+		if doc.Open {
+			w.Opened(doc.URI)
+		}
+	}
+	return w, nil
+}
+
+// ReportsProgress reports whether the client shows the progress of the
+// work of the server e.g. the warmup, see lsp.WorkDoneProgressCreateRequest.
+func (s *State) ReportsProgress() bool {
+	window := s.Capabilities.Window
+	return window != nil && window.WorkDoneProgress
+}
+
+// warmupPriority returns the priority of the file found under the root.
+func (s *State) warmupPriority(uri string) int {
+	if s.isDependency(uri) {
+		return priorityDependency
+	}
+	rel := s.RelativePath(uri)
+	for _, dir := range []string{s.Config.Src, "contracts"} {
+		if dir != "" && strings.HasPrefix(rel, strings.TrimSuffix(dir, "/")+"/") {
+			return prioritySource
+		}
+	}
+	return priorityTest
+}
+
+// Opened moves the files imported by the document the client opened right
+// behind the other open files.
+func (w *Warmup) Opened(uri string) {
+	if f := w.queued[uri]; f != nil {
+		heap.Remove(&w.queue, f.index)
+		delete(w.queued, uri)
+	}
+	for _, imported := range w.importsOf(uri) {
+		w.raise(imported, priorityImport)
+	}
+}
+
+// Run indexes up to n of the waiting files, the ones with the highest
+// priority first; or all of them if n is 0. It reports whether the warmup
+// is done. It stops with the error of the context at the checkpoint after
+// it's cancelled, and the next Run carries on from there.
+func (w *Warmup) Run(ctx context.Context, n int) (bool, error) {
+	for i := 0; n == 0 || i < n; i++ {
+		// The files may be promoted at the checkpoint, so the next one is
+		// taken after it.
+		if err := Checkpoint(ctx); err != nil {
+			return false, err
+		}
+		if w.queue.Len() == 0 {
+			break
+		}
+		f := heap.Pop(&w.queue).(*warmupFile)
+		delete(w.queued, f.uri)
+		w.index(f.uri)
+		if f.priority == priorityOpen {
+			for _, imported := range w.importsOf(f.uri) {
+				w.raise(imported, priorityImport)
+			}
+		}
+	}
+	if w.queue.Len() > 0 {
+		return false, nil
+	}
+	if !w.done {
+		w.done = true
+		w.state.Logger.InfoContext(ctx, "warmed up the workspace", "root", w.state.Root, "files", w.found, "duration", time.Since(w.start))
+	}
+	return true, nil
+}
+
+// Promote indexes the document and the files it imports, directly or
+// not, ahead of the rest of the queue, so that a request into it is
+// answered as if the whole workspace was indexed. It returns the number
+// of the files indexed.
+func (w *Warmup) Promote(uri string) int {
+	if w.queue.Len() == 0 {
+		return 0
+	}
+	promoted := 0
+	visited := map[string]bool{}
+	stack := []string{uri}
+	for len(stack) > 0 {
+		uri := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[uri] {
+			continue
+		}
+		visited[uri] = true
+		if f := w.queued[uri]; f != nil {
+			heap.Remove(&w.queue, f.index)
+			delete(w.queued, uri)
+			w.index(uri)
+			promoted++
+		}
+		stack = append(stack, w.importsOf(uri)...)
+	}
+	return promoted
+}
+
+// Progress returns the number of the files indexed by the warmup so far,
+// and the number of the files it found.
+func (w *Warmup) Progress() (indexed, total int) {
+	return w.found - w.queue.Len(), w.found
+}
+
+// index reads the file from the disk, unless the client opened it in the
+// meantime.
+func (w *Warmup) index(uri string) {
+	s := w.state
+	if _, ok := s.Documents[uri]; ok {
+		return
+	}
+	src, err := s.readFile(URIToPath(uri))
+	if err != nil {
+		s.Logger.Warn("cannot index the file", "uri", uri, "error", err)
+		return
+	}
+	doc := s.newDocument(uri, 0, false, string(src))
+	s.Documents[uri] = doc
+	s.indexExternalImports(doc)
+	w.imports[uri] = w.resolveImports(doc)
+}
+
+// importsOf returns the URIs of the indexed or waiting files imported by
+// the document; or nil if it's not indexed. The imports of the open
+// documents are resolved again, since they may have been edited.
+func (w *Warmup) importsOf(uri string) []string {
+	if uris, ok := w.imports[uri]; ok {
+		if doc := w.state.Documents[uri]; doc == nil || !doc.Open {
+			return uris
+		}
+	}
+	doc, ok := w.state.document(uri)
+	if !ok {
+		return nil
+	}
+	uris := w.resolveImports(doc)
+	w.imports[uri] = uris
+	return uris
+}
+
+func (w *Warmup) resolveImports(doc *Document) []string {
+	uris := []string{}
+	for _, decl := range doc.File.Declarations {
+		imp, ok := decl.(*ast.ImportDirective)
+		if !ok || imp.Path == nil || len(imp.Path.Value) < 2 {
+			continue
+		}
+		for _, candidate := range w.state.importCandidates(doc.URI, imp.Path.Value[1:len(imp.Path.Value)-1]) {
+			uri := PathToURI(candidate)
+			if _, ok := w.state.Documents[uri]; ok || w.queued[uri] != nil {
+				uris = append(uris, uri)
+				break
+			}
+		}
+	}
+	return uris
+}
+
+// raise moves the waiting file up to the priority.
+func (w *Warmup) raise(uri string, priority int) {
+	if f := w.queued[uri]; f != nil && f.priority > priority {
+		f.priority = priority
+		heap.Fix(&w.queue, f.index)
+	}
+}
+
+// warmupQueue is the heap of the waiting files, see container/heap.
+type warmupQueue []*warmupFile
+
+func (q warmupQueue) Len() int { return len(q) }
+
+func (q warmupQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return q[i].order < q[j].order
+}
+
+func (q warmupQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *warmupQueue) Push(x any) {
+	f := x.(*warmupFile)
+	f.index = len(*q)
+	*q = append(*q, f)
+}
+
+func (q *warmupQueue) Pop() any {
+	old := *q
+	f := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return f
+}
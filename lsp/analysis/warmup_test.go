@@ -0,0 +1,156 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"solbot/lsp"
+	"solbot/vfs"
+	"strings"
+	"testing"
+)
+
+const warmupVault = `pragma solidity ^0.8.0;
+
+import "./Math.sol";
+import "../lib/token/Token.sol";
+
+contract Vault is Token {
+    function double(uint256 x) external pure returns (uint256) {
+        return Math.twice(x);
+    }
+}
+`
+
+func writeWorkspace(t testing.TB, root string, files map[string]string) {
+	for name, src := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newWarmupWorkspace returns the state reading the workspace at /ws from
+// the memory.
+func newWarmupWorkspace() (*State, string) {
+	root := "/ws"
+	s := NewState()
+	s.FS = memoryWorkspace(root, map[string]string{
+		"src/Vault.sol":       warmupVault,
+		"src/Math.sol":        "pragma solidity ^0.8.0;\n\nlibrary Math {\n    function twice(uint256 x) internal pure returns (uint256) {\n        return 2 * x;\n    }\n}\n",
+		"src/Other.sol":       "pragma solidity ^0.8.0;\n\ncontract Other {}\n",
+		"test/Vault.t.sol":    "pragma solidity ^0.8.0;\n\nimport \"../src/Vault.sol\";\n\ncontract VaultTest {}\n",
+		"lib/token/Token.sol": "pragma solidity ^0.8.0;\n\ncontract Token {}\n",
+		"lib/other/Other.sol": "pragma solidity ^0.8.0;\n\ncontract Unused {}\n",
+	})
+	return s, root
+}
+
+// memoryWorkspace returns the file system with the files of the paths
+// relative to the root.
+func memoryWorkspace(root string, files map[string]string) *vfs.Memory {
+	abs := map[string]string{}
+	for name, src := range files {
+		abs[filepath.Join(root, name)] = src
+	}
+	return vfs.NewMemory(abs)
+}
+
+func Test_WarmupOrder(t *testing.T) {
+	s, root := newWarmupWorkspace()
+	w, err := s.NewWarmup(root, []string{PathToURI(filepath.Join(root, "src/Vault.sol"))})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if indexed, total := w.Progress(); indexed != 0 || total != 6 {
+		t.Fatalf("Expected 0/6 files indexed, got %d/%d", indexed, total)
+	}
+
+	// The imports of the open file come right after it, the dependency it
+	// imports included, and the unused dependency comes last.
+	got := []string{}
+	indexed := map[string]bool{}
+	for done := false; !done; {
+		if done, err = w.Run(context.Background(), 1); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		for _, doc := range s.sortedDocuments() {
+			if !indexed[doc.URI] {
+				indexed[doc.URI] = true
+				got = append(got, s.RelativePath(doc.URI))
+			}
+		}
+	}
+	expected := "src/Vault.sol lib/token/Token.sol src/Math.sol src/Other.sol test/Vault.t.sol lib/other/Other.sol"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected the order %q, got %q", expected, strings.Join(got, " "))
+	}
+}
+
+func Test_WarmupPromote(t *testing.T) {
+	s, root := newWarmupWorkspace()
+	uri := PathToURI(filepath.Join(root, "src/Vault.sol"))
+	w, err := s.NewWarmup(root, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	s.OpenDocument(uri, 1, warmupVault)
+	w.Opened(uri)
+
+	token := lsp.Position{Line: 5, Character: 19}
+	if locations := *s.Definition(lsp.IntID(1), uri, token).Result; len(locations) != 0 {
+		t.Fatalf("Expected no definition before the promotion, got %v", locations)
+	}
+	if promoted := w.Promote(uri); promoted != 2 {
+		t.Errorf("Expected the 2 imported files to be promoted, got %d", promoted)
+	}
+	locations := *s.Definition(lsp.IntID(1), uri, token).Result
+	if len(locations) != 1 || s.RelativePath(locations[0].URI) != "lib/token/Token.sol" || locations[0].Range.Start.Line != 2 {
+		t.Fatalf("Expected the definition in lib/token/Token.sol, got %v", locations)
+	}
+	// The opened file counts as indexed.
+	if indexed, total := w.Progress(); indexed != 3 || total != 6 {
+		t.Errorf("Expected 3/6 files indexed, got %d/%d", indexed, total)
+	}
+}
+
+// BenchmarkFirstHover measures the time to the first hover in a large
+// workspace, after indexing the whole of it and after the promotion of the
+// hovered file alone.
+func BenchmarkFirstHover(b *testing.B) {
+	root := b.TempDir()
+	files := map[string]string{}
+	for i := 0; i < 500; i++ {
+		files[fmt.Sprintf("lib/dep%d/Dep%d.sol", i%20, i)] = fmt.Sprintf("pragma solidity ^0.8.0;\n\ncontract Dep%d {\n    uint256 public value;\n\n    function set(uint256 v) external {\n        value = v;\n    }\n}\n", i)
+	}
+	files["src/Vault.sol"] = "pragma solidity ^0.8.0;\n\nimport \"../lib/dep0/Dep0.sol\";\n\ncontract Vault is Dep0 {}\n"
+	writeWorkspace(b, root, files)
+	uri := PathToURI(filepath.Join(root, "src/Vault.sol"))
+	position := lsp.Position{Line: 4, Character: 19}
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s := NewState()
+			if err := s.IndexWorkspace(context.Background(), root); err != nil {
+				b.Fatal(err)
+			}
+			s.Hover(lsp.IntID(1), uri, position)
+		}
+	})
+	b.Run("warmup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s := NewState()
+			w, err := s.NewWarmup(root, []string{uri})
+			if err != nil {
+				b.Fatal(err)
+			}
+			w.Promote(uri)
+			s.Hover(lsp.IntID(1), uri, position)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package analysis
+
+import (
+	"path"
+	"path/filepath"
+	"solbot/lsp"
+	"strings"
+)
+
+// WatchedFilesChanged updates the documents after the Solidity files were
+// changed on the disk, e.g. by git or by the build. The events of the
+// paths left out of the workspace are dropped, see ignored, and so are the
+// ones of the open documents, the editor's content is newer. When an
+// ignore file changes, the documents it now leaves out are dropped and the
+// ones it no longer does are read. When remappings.txt or foundry.toml
+// changes and it's not open, the project is reloaded, the imports of all
+// of the documents may resolve differently. It returns the URIs of the
+// changed documents, to publish their diagnostics.
+func (s *State) WatchedFilesChanged(events []lsp.FileEvent) []string {
+	changed := map[string]bool{}
+	reload, reloadProject := false, false
+	for _, e := range events {
+		p := URIToPath(e.URI)
+		if s.IsProjectFile(e.URI) {
+			_, open := s.projectFiles[e.URI]
+			reloadProject = reloadProject || !open
+			continue
+		}
+		if isIgnoreFile(p) {
+			reload = true
+			continue
+		}
+		if filepath.Ext(p) != ".sol" || s.ignored(p, false) {
+			continue
+		}
+		if doc, ok := s.Documents[e.URI]; ok && doc.Open {
+			continue
+		}
+		if e.Type == lsp.FileDeleted {
+			if _, ok := s.Documents[e.URI]; ok {
+				s.removeDocument(e.URI)
+				changed[e.URI] = true
+			}
+			continue
+		}
+		src, err := s.readFile(p)
+		if err != nil {
+			continue
+		}
+		s.setDocument(s.newDocument(e.URI, 0, false, string(src)))
+		changed[e.URI] = true
+	}
+	if reload && s.Root != "" {
+		s.ignore = nil
+		for _, doc := range s.sortedDocuments() {
+			if !doc.Open && s.ignored(URIToPath(doc.URI), false) {
+				s.removeDocument(doc.URI)
+				changed[doc.URI] = true
+			}
+		}
+		_ = s.walkFiles(s.Root, ".sol", func(p string) error {
+			uri := PathToURI(p)
+			if _, ok := s.Documents[uri]; ok {
+				return nil
+			}
+			if src, err := s.readFile(p); err == nil {
+				s.setDocument(s.newDocument(uri, 0, false, string(src)))
+				changed[uri] = true
+			}
+			return nil
+		})
+	}
+	if reloadProject {
+		s.reloadProject()
+		for uri := range s.Documents {
+			changed[uri] = true
+		}
+	}
+	return sortedKeys(changed)
+}
+
+// removeDocument forgets the document deleted from the disk.
+func (s *State) removeDocument(uri string) {
+	delete(s.Documents, uri)
+	delete(s.analyzed, uri)
+	delete(s.Migrations, uri)
+	// The imports of the other documents may no longer resolve.
+	s.referencesChanged = true
+}
+
+// isIgnoreFile reports whether the file lists the paths ignored by git.
+func isIgnoreFile(p string) bool {
+	p = filepath.ToSlash(p)
+	return path.Base(p) == ".gitignore" || strings.HasSuffix(p, "/.git/info/exclude")
+}
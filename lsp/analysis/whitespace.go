@@ -0,0 +1,390 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"solbot/lexer"
+	"solbot/lsp"
+	"solbot/migration"
+	"solbot/project"
+	"solbot/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// whitespaceDiagnostics checks the whitespace of the document, without
+// formatting it. The checks are off by default and turned on in the
+// [whitespace] section of solbot.toml, see project.Whitespace:
+//
+//   - mixed-indentation: tabs and spaces in the indentation of a line. The
+//     tabs followed by the spaces aligning a block comment are fine. The
+//     fix converts the indentation to the style of most of the lines;
+//   - trailing-whitespace: spaces or tabs at the end of a line, the fix
+//     removes them;
+//   - missing-final-newline: the file doesn't end with a newline, the fix
+//     adds it;
+//   - inconsistent-indentation: the indentation differs from the depth of
+//     the brackets by more than the tolerance. The lines continuing a
+//     statement may be indented by one more level. There is no fix, the
+//     right indentation is a matter of style;
+//   - line-too-long: the line has more characters than the maximum. If a
+//     string literal runs past it, the fix wraps the literal into the
+//     adjacent ones concatenated with it, see wrapString.
+//
+// The checks only need the tokens, so they work even if the document
+// doesn't parse. The lines in the inline assembly blocks and the ones
+// continuing a string literal are left alone.
+func (s *State) whitespaceDiagnostics(doc *Document) []lsp.Diagnostic {
+	res := []lsp.Diagnostic{}
+	for _, p := range s.whitespaceProblems(doc) {
+		res = append(res, p.diagnostic)
+	}
+	return res
+}
+
+// whitespaceActions offers the fixes of the whitespace diagnostics in the
+// selected range.
+func (s *State) whitespaceActions(doc *Document, selected token.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	for _, p := range s.whitespaceProblems(doc) {
+		if p.fix == nil || !touches(selected, p.fix.Range) {
+			continue
+		}
+		actions = append(actions, lsp.CodeAction{
+			Title:       p.title,
+			Kind:        lsp.CodeActionQuickFix,
+			Diagnostics: []lsp.Diagnostic{p.diagnostic},
+			Edit:        migrationEdit(doc, *p.fix),
+			IsPreferred: true,
+		})
+	}
+	return actions
+}
+
+// whitespaceProblem is a whitespace diagnostic with its fix; or without
+// one.
+type whitespaceProblem struct {
+	diagnostic lsp.Diagnostic
+	title      string
+	fix        *migration.Edit
+}
+
+func (s *State) whitespaceProblems(doc *Document) []whitespaceProblem {
+	res := []whitespaceProblem{}
+	cfg := s.Config.Whitespace
+	if cfg == (project.Whitespace{}) {
+		return res
+	}
+	report := func(r token.Range, code, message, title string, fix *migration.Edit) {
+		if slices.Contains(s.Config.Disabled, code) {
+			return
+		}
+		res = append(res, whitespaceProblem{
+			diagnostic: lsp.Diagnostic{
+				Range:    toLspRange(doc.Handle, r),
+				Severity: lsp.SeverityHint,
+				Code:     code,
+				Source:   "solbot",
+				Message:  message,
+			},
+			title: title,
+			fix:   fix,
+		})
+	}
+
+	src := doc.Handle.Src()
+	lines := sourceLines(src, doc.tokens())
+	width := cfg.Indentation
+	if width == 0 {
+		width = 4
+	}
+	tabs := cfg.MixedIndentation && tabsDominate(src, lines)
+	for _, line := range lines {
+		if line.skipped {
+			continue
+		}
+		indent := src[line.start:line.indent]
+		blank := line.indent == line.end
+		if cfg.MixedIndentation && !blank && strings.Contains(indent, " ") && strings.Contains(indent, "\t") && !(line.comment && aligned(indent)) {
+			r := token.Range{Start: line.start, End: line.indent}
+			cols := columns(indent, width)
+			style, text := "spaces", strings.Repeat(" ", cols)
+			if tabs {
+				style, text = "tabs", strings.Repeat("\t", cols/width)+strings.Repeat(" ", cols%width)
+			}
+			report(r, "mixed-indentation", fmt.Sprintf("The indentation mixes tabs and spaces, the file is mostly indented with %s", style),
+				"Indent with "+style, &migration.Edit{Range: r, NewText: text})
+		}
+		if cfg.Trailing && line.trailing < line.end {
+			r := token.Range{Start: line.trailing, End: line.end}
+			report(r, "trailing-whitespace", "Trailing whitespace at the end of the line", "Remove the trailing whitespace", &migration.Edit{Range: r})
+		}
+		if cfg.Indentation > 0 && !blank && !line.comment && line.level >= 0 {
+			cols := columns(indent, width)
+			off := func(level int) bool {
+				return cols < level*width-cfg.IndentationTolerance || cols > level*width+cfg.IndentationTolerance
+			}
+			if off(line.level) && (!line.continued || off(line.level+1)) {
+				expected := fmt.Sprintf("%d", line.level*width)
+				if line.continued {
+					expected = fmt.Sprintf("%d, or %d continuing the statement,", line.level*width, (line.level+1)*width)
+				}
+				report(token.Range{Start: line.indent, End: line.trailing}, "inconsistent-indentation",
+					fmt.Sprintf("The line is indented by %d columns, expected %s at the depth of %d brackets", cols, expected, line.level), "", nil)
+			}
+		}
+		if cfg.MaxLineLength > 0 {
+			text := src[line.start:line.end]
+			if n := utf8.RuneCountInString(text); n > cfg.MaxLineLength {
+				over := line.start
+				for i := 0; i < cfg.MaxLineLength; i++ {
+					_, size := utf8.DecodeRuneInString(src[over:])
+					over += token.Pos(size)
+				}
+				level := strings.Repeat(" ", width)
+				if tabs || strings.HasSuffix(indent, "\t") {
+					level = "\t"
+				}
+				report(token.Range{Start: over, End: line.end}, "line-too-long",
+					fmt.Sprintf("The line has %d characters, more than the maximum of %d", n, cfg.MaxLineLength),
+					"Wrap the string literal", wrapString(src, doc.tokens(), line, over, cfg.MaxLineLength, indent+level, width))
+			}
+		}
+	}
+
+	if cfg.FinalNewline && src != "" && !strings.HasSuffix(src, "\n") {
+		newline := "\n"
+		if strings.Contains(src, "\r\n") {
+			newline = "\r\n"
+		}
+		end := token.Range{Start: token.Pos(len(src)), End: token.Pos(len(src))}
+		report(end, "missing-final-newline", "The file doesn't end with a newline", "Add the final newline", &migration.Edit{Range: end, NewText: newline})
+	}
+	return res
+}
+
+// wrapString returns the edit wrapping the string literal running past the
+// maximum length of the line into the adjacent literals, which are
+// concatenated, e.g. `"a long message"` into `"a long "` and `"message"` on
+// the next line indented by the indentation. The literal is split after
+// the spaces, so that every line fits if the words do; or nil if there is
+// no such literal or it has no space to split it at.
+func wrapString(src string, tokens []token.Token, line sourceLine, over token.Pos, maxLength int, indent string, width int) *migration.Edit {
+	for i, tkn := range tokens {
+		end := tkn.Pos + token.Pos(len(tkn.Literal))
+		if tkn.Type != token.STRING_LITERAL || tkn.Pos < line.start || tkn.Pos >= over || end <= over {
+			continue
+		}
+		if i > 0 && (tokens[i-1].Type == token.HEX || tokens[i-1].Type == token.UNICODE) || strings.ContainsAny(tkn.Literal, "\r\n") {
+			return nil
+		}
+		quote, content := tkn.Literal[:1], tkn.Literal[1:len(tkn.Literal)-1]
+		words := strings.SplitAfter(content, " ")
+		capacity := maxLength - utf8.RuneCountInString(src[line.start:tkn.Pos]) - 2
+		parts := []string{}
+		part := ""
+		for _, word := range words {
+			if part != "" && utf8.RuneCountInString(part+word) > capacity {
+				parts = append(parts, part)
+				part = ""
+				capacity = maxLength - columns(indent, width) - 2
+			}
+			part += word
+		}
+		parts = append(parts, part)
+		if len(parts) == 1 {
+			return nil
+		}
+		for i, part := range parts {
+			parts[i] = quote + part + quote
+		}
+		newline := "\n"
+		if strings.HasPrefix(src[line.end:], "\r\n") {
+			newline = "\r\n"
+		}
+		return &migration.Edit{
+			Range:   token.Range{Start: tkn.Pos, End: end},
+			NewText: strings.Join(parts, newline+indent),
+		}
+	}
+	return nil
+}
+
+// sourceLine is a line of the document, without the line break.
+type sourceLine struct {
+	start, end token.Pos
+	indent     token.Pos // end of the leading whitespace
+	trailing   token.Pos // start of the trailing whitespace
+	skipped    bool      // is it in an assembly block or a string literal? It's not checked then.
+	comment    bool      // does it continue a block comment?
+	level      int       // the depth of the brackets it's in; or -1 if it's not known
+	continued  bool      // does it continue the statement of the line before?
+}
+
+// sourceLines splits the source into the lines and finds the depth of the
+// brackets of every one from the tokens. A line starting with a closing
+// bracket has the depth of the line opening it, and the brackets opened on
+// the same line nest their content by a single level. The depth after the
+// lexer gave up is not known.
+func sourceLines(src string, tokens []token.Token) []sourceLine {
+	skipped, comments := []token.Range{}, []token.Range{}
+	for i, tkn := range tokens {
+		r := token.Range{Start: tkn.Pos, End: tkn.Pos + token.Pos(len(tkn.Literal))}
+		switch tkn.Type {
+		case token.STRING_LITERAL, token.HEX_STRING_LITERAL, token.UNICODE_STRING_LITERAL:
+			if strings.ContainsAny(tkn.Literal, "\r\n") {
+				skipped = append(skipped, r)
+			}
+		case token.COMMENT_LITERAL:
+			comments = append(comments, r)
+		case token.ASSEMBLY:
+			skipped = append(skipped, assemblyBody(tokens[i+1:], token.Pos(len(src))))
+		}
+	}
+	// The lexer stops at an unrecognised character.
+	stop := token.Pos(len(src))
+	if n := len(tokens); n > 0 && tokens[n-1].Type == token.ILLEGAL {
+		stop = tokens[n-1].Pos
+	}
+
+	lines := []sourceLine{}
+	levels := []int{} // the depths of the lines opening the brackets
+	prev := token.SEMICOLON
+	next := 0 // the first token not on the lines before
+	for start := 0; start < len(src); {
+		end := strings.IndexByte(src[start:], '\n')
+		following := start + end + 1
+		if end < 0 {
+			end, following = len(src), len(src)
+		} else {
+			end += start
+		}
+		text := strings.TrimSuffix(src[start:end], "\r")
+		line := sourceLine{
+			start:    token.Pos(start),
+			end:      token.Pos(start + len(text)),
+			indent:   token.Pos(start + len(text) - len(strings.TrimLeft(text, " \t"))),
+			trailing: token.Pos(start + len(strings.TrimRight(text, " \t"))),
+			level:    -1,
+		}
+		// The closing brace of an assembly block is in it too.
+		line.skipped = slices.ContainsFunc(skipped, func(r token.Range) bool { return r.Start < line.start && line.start <= r.End })
+		line.comment = slices.ContainsFunc(comments, func(r token.Range) bool { return r.Start < line.start && line.start < r.End })
+
+		if line.start <= stop {
+			line.level = 0
+			if len(levels) > 0 {
+				line.level = levels[len(levels)-1] + 1
+			}
+			first := next < len(tokens) && tokens[next].Pos < token.Pos(following)
+			switch {
+			case first && closing(tokens[next].Type):
+				if len(levels) > 0 {
+					line.level = levels[len(levels)-1]
+				}
+			case prev != token.SEMICOLON && prev != token.LBRACE && prev != token.RBRACE:
+				line.continued = true
+			}
+		}
+		for ; next < len(tokens) && tokens[next].Pos < token.Pos(following); next++ {
+			switch typ := tokens[next].Type; {
+			case typ == token.COMMENT_LITERAL:
+				continue
+			case opening(typ):
+				levels = append(levels, max(line.level, 0))
+			case closing(typ) && len(levels) > 0:
+				levels = levels[:len(levels)-1]
+			}
+			prev = tokens[next].Type
+		}
+		lines = append(lines, line)
+		start = following
+	}
+	return lines
+}
+
+// assemblyBody returns the range between the braces of the assembly block
+// whose tokens follow the keyword, after the dialect and the flags. The
+// body of the block not closed runs to the end.
+func assemblyBody(tokens []token.Token, end token.Pos) token.Range {
+	i := 0
+	for i < len(tokens) && tokens[i].Type != token.LBRACE && tokens[i].Type != token.SEMICOLON && tokens[i].Type != token.RBRACE {
+		i++
+	}
+	if i == len(tokens) || tokens[i].Type != token.LBRACE {
+		return token.Range{}
+	}
+	body := token.Range{Start: tokens[i].Pos + 1, End: end}
+	depth := 0
+	for _, tkn := range tokens[i:] {
+		switch tkn.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+			if depth == 0 {
+				body.End = tkn.Pos
+				return body
+			}
+		}
+	}
+	return body
+}
+
+func opening(typ token.TokenType) bool {
+	return typ == token.LBRACE || typ == token.LPAREN || typ == token.LBRACKET
+}
+
+func closing(typ token.TokenType) bool {
+	return typ == token.RBRACE || typ == token.RPAREN || typ == token.RBRACKET
+}
+
+// columns returns the width of the indentation, the tabs advancing to the
+// next multiple of the width of a level.
+func columns(indent string, width int) int {
+	cols := 0
+	for _, c := range indent {
+		if c == '\t' {
+			cols = (cols/width + 1) * width
+		} else {
+			cols++
+		}
+	}
+	return cols
+}
+
+// aligned reports whether the indentation is made of tabs followed by the
+// spaces aligning the text e.g. the stars of a block comment.
+func aligned(indent string) bool {
+	return strings.Trim(strings.TrimLeft(indent, "\t"), " ") == ""
+}
+
+// tabsDominate reports whether more of the indented lines are indented
+// with the tabs only than with the spaces only.
+func tabsDominate(src string, lines []sourceLine) bool {
+	tabs, spaces := 0, 0
+	for _, line := range lines {
+		indent := src[line.start:line.indent]
+		switch {
+		case line.skipped || line.indent == line.end || indent == "":
+		case strings.Trim(indent, "\t") == "":
+			tabs++
+		case strings.Trim(indent, " ") == "":
+			spaces++
+		}
+	}
+	return tabs > spaces
+}
+
+// tokens returns the tokens of the document with the comments, lexed once
+// for its version.
+func (doc *Document) tokens() []token.Token {
+	if doc.lexed == nil {
+		l := lexer.Lex(doc.Handle)
+		doc.lexed = []token.Token{}
+		for tkn := l.NextToken(); tkn.Type != token.EOF; tkn = l.NextToken() {
+			doc.lexed = append(doc.lexed, tkn)
+		}
+	}
+	return doc.lexed
+}
@@ -0,0 +1,259 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/project"
+	"strings"
+	"testing"
+)
+
+// whitespaceMessy has every whitespace problem: the mixed indentation on
+// lines 5 and 6, the trailing whitespace on lines 4 and 6, the wrong
+// indentation on line 5, the long line 12 and no final newline. The
+// assembly block and the string continued on the next line are left alone.
+const whitespaceMessy = "pragma solidity ^0.8.0;\n" +
+	"\n" +
+	"contract Messy {\n" +
+	"    uint256 total; \n" +
+	"  \t function add(uint256 x)\n" +
+	" \t    external\t\n" +
+	"    {\n" +
+	"        total += x;\n" +
+	"        assembly {\n" +
+	"         \t let y := 1   \n" +
+	"          }\n" +
+	"        string memory s = \"a\\\n" +
+	"  \t b\";\n" +
+	"    }\n" +
+	"}"
+
+func whitespaceDiagnosticStrings(s *State, uri string) []string {
+	got := []string{}
+	for _, d := range s.Diagnostics(context.Background(), uri).Params.Diagnostics {
+		switch d.Code {
+		case "mixed-indentation", "trailing-whitespace", "missing-final-newline", "inconsistent-indentation", "line-too-long":
+			got = append(got, fmt.Sprintf("%d:%d-%d:%d %s: %s", d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Line, d.Range.End.Character, d.Code, d.Message))
+		}
+	}
+	return got
+}
+
+func Test_WhitespaceDiagnostics(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/Messy.sol"
+	s.OpenDocument(uri, 1, whitespaceMessy)
+	if got := whitespaceDiagnosticStrings(s, uri); len(got) != 0 {
+		t.Fatalf("Expected no whitespace diagnostics by default, got %v", got)
+	}
+
+	tests := []struct {
+		name       string
+		whitespace project.Whitespace
+		expected   []string
+	}{
+		{
+			"mixed indentation",
+			project.Whitespace{MixedIndentation: true},
+			[]string{
+				"4:0-4:4 mixed-indentation: The indentation mixes tabs and spaces, the file is mostly indented with spaces",
+				"5:0-5:6 mixed-indentation: The indentation mixes tabs and spaces, the file is mostly indented with spaces",
+			},
+		},
+		{
+			"trailing whitespace",
+			project.Whitespace{Trailing: true},
+			[]string{
+				"3:18-3:19 trailing-whitespace: Trailing whitespace at the end of the line",
+				"5:14-5:15 trailing-whitespace: Trailing whitespace at the end of the line",
+			},
+		},
+		{
+			"final newline",
+			project.Whitespace{FinalNewline: true},
+			[]string{"14:1-14:1 missing-final-newline: The file doesn't end with a newline"},
+		},
+		{
+			// The modifier continues the declaration, so it may be
+			// indented by one more level.
+			"indentation",
+			project.Whitespace{Indentation: 4},
+			[]string{"4:4-4:27 inconsistent-indentation: The line is indented by 5 columns, expected 4 at the depth of 1 brackets"},
+		},
+		{
+			"indentation tolerance",
+			project.Whitespace{Indentation: 4, IndentationTolerance: 2},
+			[]string{},
+		},
+		{
+			"line length",
+			project.Whitespace{MaxLineLength: 28},
+			[]string{"11:28-11:29 line-too-long: The line has 29 characters, more than the maximum of 28"},
+		},
+	}
+	for _, tt := range tests {
+		s.Config.Whitespace = tt.whitespace
+		got := whitespaceDiagnosticStrings(s, uri)
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", tt.name, strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func Test_WhitespaceIndentation(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    function withdraw(
+        uint256 amount,
+        address to
+    ) external {
+        if (amount > 0) {
+            emit Withdrawn({
+                to: to,
+                amount: amount
+            });
+        } else {
+          revert();
+        }
+        uint256 fee = amount
+            / 100;
+    }
+   }
+`
+	s := NewState()
+	uri := "file:///ws/Vault.sol"
+	s.OpenDocument(uri, 1, src)
+	s.Config.Whitespace = project.Whitespace{Indentation: 4}
+	expected := []string{
+		"13:10-13:19 inconsistent-indentation: The line is indented by 10 columns, expected 12 at the depth of 3 brackets",
+		"18:3-18:4 inconsistent-indentation: The line is indented by 3 columns, expected 0 at the depth of 0 brackets",
+	}
+	if got := whitespaceDiagnosticStrings(s, uri); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// The checks need the tokens only, so they work without a parse.
+	s.OpenDocument(uri, 2, "contract Broken {\n    function f() {\n      uint x = ;\n    }\n}\n")
+	expected = []string{"2:6-2:16 inconsistent-indentation: The line is indented by 6 columns, expected 8 at the depth of 2 brackets"}
+	if got := whitespaceDiagnosticStrings(s, uri); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_WhitespaceTabs(t *testing.T) {
+	src := "contract Tabs {\n\tuint256 a;\n\tuint256 b;\n  \tuint256 c;\n\t/**\n\t * aligned\n\t */\n}\n"
+	s := NewState()
+	uri := "file:///ws/Tabs.sol"
+	s.OpenDocument(uri, 1, src)
+	s.Config.Whitespace = project.Whitespace{MixedIndentation: true, Indentation: 4}
+
+	expected := []string{"3:0-3:3 mixed-indentation: The indentation mixes tabs and spaces, the file is mostly indented with tabs"}
+	if got := whitespaceDiagnosticStrings(s, uri); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	fixes := s.Fixes(uri)
+	if len(fixes) != 1 || fixes[0].Title != "Indent with tabs" || fixes[0].Edits[0].NewText != "\t" {
+		t.Errorf("Expected the fix indenting with a tab, got %+v", fixes)
+	}
+}
+
+func Test_WhitespaceFixes(t *testing.T) {
+	s := NewState()
+	uri := "file:///ws/Messy.sol"
+	s.OpenDocument(uri, 1, whitespaceMessy)
+	s.Config.Whitespace = project.Whitespace{MixedIndentation: true, Trailing: true, FinalNewline: true, Indentation: 4}
+
+	// Both fixes of line 6 apply, they edit the two ends of the line.
+	res := s.ApplyFixes(uri, s.Fixes(uri))
+	if len(res.Conflicts) != 0 || len(res.Errors) != 0 {
+		t.Fatalf("Expected no conflicts or errors, got %v and %v", res.Conflicts, res.Errors)
+	}
+	if len(res.Applied) != 5 {
+		t.Errorf("Expected 5 fixes, got %d", len(res.Applied))
+	}
+	expected := "pragma solidity ^0.8.0;\n" +
+		"\n" +
+		"contract Messy {\n" +
+		"    uint256 total;\n" +
+		"     function add(uint256 x)\n" +
+		"        external\n" +
+		"    {\n" +
+		"        total += x;\n" +
+		"        assembly {\n" +
+		"         \t let y := 1   \n" +
+		"          }\n" +
+		"        string memory s = \"a\\\n" +
+		"  \t b\";\n" +
+		"    }\n" +
+		"}\n"
+	if res.Src != expected {
+		t.Errorf("Expected:\n%q\ngot:\n%q", expected, res.Src)
+	}
+
+	// Only the indentation of line 5 is left, which has no fix.
+	expectedLeft := []string{"4:5-4:28 inconsistent-indentation: The line is indented by 5 columns, expected 4 at the depth of 1 brackets"}
+	if got := whitespaceDiagnosticStrings(s, uri); strings.Join(got, "\n") != strings.Join(expectedLeft, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expectedLeft, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func Test_WrapStringLiteral(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "wrap", "Vault.sol"))
+	if err != nil {
+		t.Fatalf("Cannot read the source: %s", err)
+	}
+	s := NewState()
+	s.Config.Whitespace = project.Whitespace{MaxLineLength: 80}
+	uri := "file:///ws/Vault.sol"
+	s.OpenDocument(uri, 1, string(src))
+	doc := s.Documents[uri]
+
+	r := lsp.Range{Start: lsp.Position{Line: 6, Character: 80}, End: lsp.Position{Line: 6, Character: 80}}
+	var wrap *lsp.CodeAction
+	for _, action := range s.CodeAction(lsp.IntID(1), uri, r).Result {
+		if action.Title == "Wrap the string literal" {
+			wrap = &action
+		}
+	}
+	if wrap == nil {
+		t.Fatalf("Expected the action wrapping the string literal")
+	}
+	wrapped := applyEdits(doc, wrap.Edit.Changes[uri])
+
+	golden := filepath.Join("testdata", "wrap", "Vault.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(wrapped), 0644); err != nil {
+			t.Fatalf("Cannot update %s: %s", golden, err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Cannot read %s: %s", golden, err)
+	}
+	if wrapped != string(expected) {
+		t.Errorf("Expected %s:\n%s\ngot:\n%s", golden, expected, wrapped)
+	}
+
+	// The parts are concatenated into the original message.
+	s.UpdateDocument(uri, 2, wrapped)
+	for _, line := range strings.Split(wrapped, "\n") {
+		if len(line) > 80 {
+			t.Errorf("Expected the lines to fit, got %q", line)
+		}
+	}
+	found := false
+	ast.Inspect(s.Documents[uri].File, func(node ast.Node) bool {
+		if lit, ok := node.(*ast.BasicLit); ok && len(lit.Parts) > 1 {
+			found = lit.Value == `"Vault: the balance of the caller is lower than the amount being withdrawn"`
+		}
+		return true
+	})
+	if !found {
+		t.Errorf("Expected the wrapped literal to concatenate into the message")
+	}
+}
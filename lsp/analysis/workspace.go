@@ -0,0 +1,228 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path"
+	"path/filepath"
+	"solbot/ast"
+	"solbot/parser"
+	"solbot/project"
+	"solbot/token"
+	"solbot/vfs"
+	"strings"
+	"time"
+)
+
+// Document is a Solidity source file known to the server. It's either open
+// in the editor, or it was read from the disk when the workspace was indexed.
+type Document struct {
+	URI     string
+	Version int  // version sent by the client; or 0 if the document is not open
+	Open    bool // is the document open in the editor?
+	Handle  *token.File
+	File    *ast.File
+	Anchors map[string]token.Range // anchor of the declarations -> current range, see anchors
+	Edits   []edit                 // log of the recent edits, see translate
+
+	TooLarge bool           // is the document larger than the limit? It's not parsed then, see Limits
+	Limited  []parser.Error // errors of the limits of the parser the document hit, see Limits.Parser
+	names    uint64         // hash of the identifiers, see ReferencesChanged
+	unloaded bool           // was the syntax tree unloaded to bound the memory? see Unload
+	used     uint64         // clock of the last use, see use
+	hash     uint64         // hash of the source; or 0 until it's needed, see sourceHash
+	scope    *fileScope     // names of the file scope declared by the file itself, see declare
+	lexed    []token.Token  // tokens with the comments; or nil until they're needed, see tokens
+}
+
+func newDocument(uri string, version int, open bool, src string) *Document {
+	return parsedDocument(uri, version, open, src, parser.Limits{})
+}
+
+// parsedDocument is newDocument parsed within the limits.
+func parsedDocument(uri string, version int, open bool, src string, limits parser.Limits) *Document {
+	handle, file, limited := parseDocument(uri, src, limits)
+	return &Document{
+		URI:     uri,
+		Version: version,
+		Open:    open,
+		Handle:  handle,
+		File:    file,
+		Anchors: anchors(file),
+		Limited: limited,
+		names:   identifiersHash(file),
+	}
+}
+
+// IndexWorkspace reads all of the Solidity files under the root directory.
+// The documents that are already open are kept as they are, since the
+// editor's content is newer than the one on the disk. It stops with the
+// error of the context at the checkpoint after it's cancelled, keeping the
+// files read so far.
+func (s *State) IndexWorkspace(ctx context.Context, root string) error {
+	start := time.Now()
+	root, err := s.loadProject(root)
+	if err != nil {
+		return err
+	}
+	indexed := 0
+	err = s.walkFiles(root, ".sol", func(p string) error {
+		if err := Checkpoint(ctx); err != nil {
+			return err
+		}
+
+		uri := PathToURI(p)
+		if doc, ok := s.Documents[uri]; ok && doc.Open {
+			return nil
+		}
+		src, err := s.readFile(p)
+		if errors.Is(err, vfs.ErrOutside) {
+			// A symbolic link leading outside of the workspace.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.Documents[uri] = s.newDocument(uri, 0, false, string(src))
+		indexed++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, doc := range s.sortedDocuments() {
+		s.indexExternalImports(doc)
+	}
+	if err := s.IndexMarkdown(ctx, root); err != nil {
+		return err
+	}
+	s.Unload()
+	s.Logger.InfoContext(ctx, "indexed the workspace", "root", root, "files", indexed, "duration", time.Since(start))
+	return nil
+}
+
+// loadProject makes the directory the root of the workspace and loads the
+// configuration of the project in it. It returns the absolute path of the
+// root. The files are accessed through the sandbox of the workspace from
+// then on, see restrict.
+func (s *State) loadProject(root string) (string, error) {
+	// The URIs of the relative paths would start with a host e.g.
+	// file://src/Vault.sol.
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := project.LoadFS(vfs.NewSandbox(s.FS, s.Logger, root), root)
+	if err != nil {
+		return "", err
+	}
+	s.Root = root
+	s.projectConfig = cfg
+	s.Config = s.Settings.apply(cfg)
+	s.ignore = nil
+	s.restrict()
+	s.loadBaseline()
+	return root, nil
+}
+
+// resolveImport returns the document imported by the path in the import
+// directive of the document with the given URI; or nil if it's not indexed.
+// Relative paths are resolved against the importing file, other paths are
+// remapped and resolved against the workspace root and the dependency
+// directories.
+func (s *State) resolveImport(uri, importPath string) *Document {
+	for _, candidate := range s.importCandidates(uri, importPath) {
+		if doc, ok := s.document(PathToURI(candidate)); ok {
+			return doc
+		}
+	}
+	return nil
+}
+
+// importCandidates returns the paths the import path can refer to, in the
+// order they're tried by resolveImport.
+func (s *State) importCandidates(uri, importPath string) []string {
+	from := URIToPath(uri)
+	candidates := []string{}
+	if IsRelativeImport(importPath) {
+		candidates = append(candidates, path.Join(path.Dir(from), importPath))
+	} else if s.Root != "" {
+		remapped, _ := project.Remap(s.Config.Remappings, s.RelativePath(uri), importPath)
+		candidates = append(candidates, path.Join(s.Root, remapped))
+		for _, lib := range append(s.Config.Libs, "node_modules") {
+			candidates = append(candidates, path.Join(s.Root, lib, remapped))
+		}
+	}
+	return candidates
+}
+
+// IsRelativeImport reports whether the import path is relative to the
+// importing file e.g. "./IVault.sol" or "../interfaces/IVault.sol".
+func IsRelativeImport(importPath string) bool {
+	return strings.HasPrefix(importPath, "./") || strings.HasPrefix(importPath, "../")
+}
+
+// isDependency reports whether the document belongs to an installed
+// dependency e.g. a Foundry library in lib/ or an npm package. The server
+// must never edit those files.
+func (s *State) isDependency(uri string) bool {
+	p := URIToPath(uri)
+	if s.Root != "" {
+		if rel, err := filepath.Rel(s.Root, p); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(p), "/") {
+		if dir == "lib" || dir == "node_modules" {
+			return true
+		}
+	}
+	return false
+}
+
+// documentsUnder returns the documents in the file or directory, ordered
+// like sortedDocuments. The reports over a path e.g. PathMetrics use it.
+// The dependencies are skipped, so that the libraries don't drown the
+// results of the project, unless the path is inside of one, for the
+// reports over a library itself.
+func (s *State) documentsUnder(path string) []*Document {
+	res := []*Document{}
+	skipDependencies := !s.isDependency(PathToURI(path))
+	for _, doc := range s.sortedDocuments() {
+		rel, err := filepath.Rel(path, URIToPath(doc.URI))
+		if err != nil || strings.HasPrefix(rel, "..") || (skipDependencies && s.isDependency(doc.URI)) {
+			continue
+		}
+		res = append(res, doc)
+	}
+	return res
+}
+
+// RelativePath returns the path of the document relative to the workspace
+// root. It's used in the messages shown to the user.
+func (s *State) RelativePath(uri string) string {
+	p := URIToPath(uri)
+	if s.Root != "" {
+		if rel, err := filepath.Rel(s.Root, p); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return p
+}
+
+// URIToPath returns the path of the file URI; or the URI itself if it's not
+// a file URI.
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
+
+// PathToURI returns the file URI of the path e.g. file:///home/user/src/Vault.sol.
+func PathToURI(p string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(p)}
+	return u.String()
+}
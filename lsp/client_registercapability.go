@@ -0,0 +1,64 @@
+package lsp
+
+// RegistrationRequest is sent by the server to register the capabilities
+// the client announced the dynamic registration of, e.g. the documents
+// other than the Solidity ones the server wants to see.
+type RegistrationRequest struct {
+	Request
+	Params RegistrationParams `json:"params"`
+}
+
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+type Registration struct {
+	ID              string `json:"id"`
+	Method          string `json:"method"` // e.g. "textDocument/didOpen"
+	RegisterOptions any    `json:"registerOptions,omitempty"`
+}
+
+// TextDocumentRegistrationOptions select the documents a registered
+// text document method applies to.
+type TextDocumentRegistrationOptions struct {
+	DocumentSelector []DocumentFilter `json:"documentSelector"`
+}
+
+type DocumentFilter struct {
+	Language string `json:"language,omitempty"` // e.g. "solidity"
+	Scheme   string `json:"scheme,omitempty"`   // e.g. "file"
+	Pattern  string `json:"pattern,omitempty"`  // e.g. "**/remappings.txt"
+}
+
+// TextDocumentChangeRegistrationOptions select the documents of the
+// didChange notifications, and how they're synced.
+type TextDocumentChangeRegistrationOptions struct {
+	DocumentSelector []DocumentFilter `json:"documentSelector"`
+	SyncKind         int              `json:"syncKind"` // 1 for the full content
+}
+
+// CompletionRegistrationOptions select the documents of the completion
+// requests, and the characters triggering them.
+type CompletionRegistrationOptions struct {
+	DocumentSelector  []DocumentFilter `json:"documentSelector"`
+	TriggerCharacters []string         `json:"triggerCharacters,omitempty"`
+}
+
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+type FileSystemWatcher struct {
+	GlobPattern string `json:"globPattern"` // e.g. "**/foundry.toml"
+}
+
+func NewRegistrationRequest(id ID, registrations ...Registration) RegistrationRequest {
+	return RegistrationRequest{
+		Request: Request{
+			RPC:    "2.0",
+			ID:     id,
+			Method: "client/registerCapability",
+		},
+		Params: RegistrationParams{Registrations: registrations},
+	}
+}
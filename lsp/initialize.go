@@ -1,5 +1,7 @@
 package lsp
 
+import "encoding/json"
+
 type InitializeRequest struct {
 	Request
 	Params InitializeParams `json:"params"`
@@ -7,7 +9,89 @@ type InitializeRequest struct {
 
 type InitializeParams struct {
 	// Since this is optional we can do a pointer.
-	ClientInfo *ClientInfo `json:"clientInfo"`
+	ClientInfo   *ClientInfo        `json:"clientInfo"`
+	RootURI      string             `json:"rootUri"` // empty if no folder is open
+	Capabilities ClientCapabilities `json:"capabilities"`
+	Trace        string             `json:"trace"` // initial trace setting: "off", "messages" or "verbose"
+	// Settings of the server, decoded by the server itself since their
+	// shape is up to the client's configuration.
+	InitializationOptions json.RawMessage `json:"initializationOptions"`
+}
+
+// Only the capabilities that change the server's behaviour are decoded.
+type ClientCapabilities struct {
+	Workspace    *WorkspaceClientCapabilities    `json:"workspace"`
+	TextDocument *TextDocumentClientCapabilities `json:"textDocument"`
+	Window       *WindowClientCapabilities       `json:"window"`
+}
+
+type WindowClientCapabilities struct {
+	// Can the server report the progress of its own work with the tokens
+	// created by window/workDoneProgress/create?
+	WorkDoneProgress bool `json:"workDoneProgress"`
+}
+
+type TextDocumentClientCapabilities struct {
+	Synchronization *DynamicRegistrationCapabilities `json:"synchronization"`
+	Completion      *DynamicRegistrationCapabilities `json:"completion"`
+	CodeAction      *CodeActionClientCapabilities    `json:"codeAction"`
+	Hover           *HoverClientCapabilities         `json:"hover"`
+	// Does the client pull the diagnostics with textDocument/diagnostic?
+	// The diagnostics are pushed with textDocument/publishDiagnostics if
+	// it doesn't.
+	Diagnostic *DiagnosticClientCapabilities `json:"diagnostic"`
+}
+
+type HoverClientCapabilities struct {
+	// Formats of the hover content the client can render, in the order of
+	// preference e.g. ["markdown", "plaintext"].
+	ContentFormat []string `json:"contentFormat"`
+}
+
+type CodeActionClientCapabilities struct {
+	ResolveSupport *CodeActionResolveSupport `json:"resolveSupport"`
+	// Can the client show the disabled actions, together with the reasons
+	// they can't be applied?
+	DisabledSupport bool `json:"disabledSupport"`
+}
+
+type CodeActionResolveSupport struct {
+	// Properties of the actions the client can fill in lazily with the
+	// codeAction/resolve request e.g. "edit".
+	Properties []string `json:"properties"`
+}
+
+type WorkspaceClientCapabilities struct {
+	WorkspaceEdit *WorkspaceEditClientCapabilities     `json:"workspaceEdit"`
+	CodeLens      *CodeLensWorkspaceClientCapabilities `json:"codeLens"`
+	// Can the server fetch the settings with the workspace/configuration
+	// request?
+	Configuration bool                                   `json:"configuration"`
+	Diagnostics   *DiagnosticWorkspaceClientCapabilities `json:"diagnostics"`
+	// Can the server register the files it watches?
+	DidChangeWatchedFiles *DynamicRegistrationCapabilities `json:"didChangeWatchedFiles"`
+}
+
+// DynamicRegistrationCapabilities tell whether the server can register the
+// capability with the client/registerCapability request.
+type DynamicRegistrationCapabilities struct {
+	DynamicRegistration bool `json:"dynamicRegistration"`
+}
+
+type CodeLensWorkspaceClientCapabilities struct {
+	// Can the server ask for the lenses again with the
+	// workspace/codeLens/refresh request?
+	RefreshSupport bool `json:"refreshSupport"`
+}
+
+type WorkspaceEditClientCapabilities struct {
+	DocumentChanges         bool                     `json:"documentChanges"`
+	ResourceOperations      []string                 `json:"resourceOperations"` // e.g. "create", "rename", "delete"
+	ChangeAnnotationSupport *ChangeAnnotationSupport `json:"changeAnnotationSupport"`
+}
+
+type ChangeAnnotationSupport struct {
+	GroupsOnLabel bool `json:"groupsOnLabel"`
 }
 
 type ClientInfo struct {
@@ -26,10 +110,28 @@ type InitializeResult struct {
 }
 
 type ServerCapabilities struct {
-	TextDocumentSync   int  `json:"textDocumentSync"` // Sync kind: 1 = full content, 2 = incremental
-	HoverProvider      bool `json:"hoverProvider"`
-	DefinitionProvider bool `json:"definitionProvider"` // Go to implementation of code that will be executed.
-	CodeActionProvider bool `json:"codeActionProvider"`
+	TextDocumentSync        int  `json:"textDocumentSync"` // Sync kind: 1 = full content, 2 = incremental
+	HoverProvider           bool `json:"hoverProvider"`
+	DefinitionProvider      bool `json:"definitionProvider"` // Go to implementation of code that will be executed.
+	RenameProvider          bool `json:"renameProvider"`
+	InlayHintProvider       bool `json:"inlayHintProvider"`
+	ReferencesProvider      bool `json:"referencesProvider"`
+	DocumentSymbolProvider  bool `json:"documentSymbolProvider"`
+	WorkspaceSymbolProvider bool `json:"workspaceSymbolProvider"`
+	MonikerProvider         bool `json:"monikerProvider"`
+
+	CodeActionProvider     *CodeActionOptions     `json:"codeActionProvider,omitempty"`
+	CodeLensProvider       *CodeLensOptions       `json:"codeLensProvider,omitempty"`
+	CompletionProvider     *CompletionOptions     `json:"completionProvider,omitempty"`
+	SignatureHelpProvider  *SignatureHelpOptions  `json:"signatureHelpProvider,omitempty"`
+	ExecuteCommandProvider *ExecuteCommandOptions `json:"executeCommandProvider,omitempty"`
+	DiagnosticProvider     *DiagnosticOptions     `json:"diagnosticProvider,omitempty"`
+
+	Workspace *WorkspaceServerCapabilities `json:"workspace,omitempty"`
+
+	// Experimental holds the capabilities of the methods the embedders of
+	// the server add, by their names; or nil.
+	Experimental map[string]any `json:"experimental,omitempty"`
 }
 
 type ServerInfo struct {
@@ -37,19 +139,16 @@ type ServerInfo struct {
 	Version string `json:"version"`
 }
 
-func NewInitializeResponse(id int) InitializeResponse {
+// NewInitializeResponse returns the response advertising the capabilities,
+// which the server composes from its features.
+func NewInitializeResponse(id ID, capabilities ServerCapabilities) InitializeResponse {
 	return InitializeResponse{
 		Response: Response{
 			RPC: "2.0",
 			ID:  &id,
 		},
 		Result: InitializeResult{
-			Capabilities: ServerCapabilities{
-				TextDocumentSync:   1, // Sync by sending the full content.
-				HoverProvider:      true,
-				DefinitionProvider: true,
-				CodeActionProvider: true,
-			},
+			Capabilities: capabilities,
 			ServerInfo: ServerInfo{
 				Name:    "solbot_lsp",
 				Version: "0.0.0-alpha",
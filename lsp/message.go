@@ -1,17 +1,107 @@
 package lsp
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
 type Request struct {
 	RPC    string `json:"jsonrpc"` // Useless, but we have to send it either way.
-	ID     int    `json:"id"`
+	ID     ID     `json:"id"`
 	Method string `json:"method"`
 }
 
 type Response struct {
-	RPC string `json:"jsonrpc"` // Useless, but we have to send it either way.
-	ID  *int   `json:"id,omitempty"`
+	RPC   string         `json:"jsonrpc"` // Useless, but we have to send it either way.
+	ID    *ID            `json:"id,omitempty"`
+	Error *ResponseError `json:"error,omitempty"`
+}
+
+// ID identifies a request. JSON-RPC allows a number or a string, and the
+// response must echo the ID the way the request sent it, so the ID keeps
+// its representation. The IDs are comparable, e.g. the number 1 and the
+// string "1" are different IDs.
+type ID struct {
+	number int
+	text   string
+	isText bool
+}
+
+// IntID returns the numeric ID, like the ones of the requests the server
+// sends.
+func IntID(number int) ID {
+	return ID{number: number}
+}
+
+// StringID returns the string ID.
+func StringID(text string) ID {
+	return ID{text: text, isText: true}
+}
+
+// String returns the ID as it's written in JSON e.g. 1 or "abc".
+func (id ID) String() string {
+	if id.isText {
+		return strconv.Quote(id.text)
+	}
+	return strconv.Itoa(id.number)
+}
+
+func (id ID) MarshalJSON() ([]byte, error) {
+	if id.isText {
+		return json.Marshal(id.text)
+	}
+	return json.Marshal(id.number)
+}
+
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*id = StringID(text)
+		return nil
+	}
+	var number int
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("the ID must be an integer or a string, got %s", data)
+	}
+	*id = IntID(number)
+	return nil
+}
+
+// Error codes defined by JSON-RPC and the LSP specification.
+const (
+	InvalidRequest  = -32600
+	MethodNotFound  = -32601
+	InvalidParams   = -32602
+	InternalError   = -32603
+	ServerCancelled = -32802
+	RequestFailed   = -32803
+)
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
 }
 
 type Notification struct {
 	RPC    string `json:"jsonrpc"` // Useless, but we have to send it either way.
 	Method string `json:"method"`
 }
+
+func NewErrorResponse(id ID, code int, message string) Response {
+	return Response{
+		RPC: "2.0",
+		ID:  &id,
+		Error: &ResponseError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,283 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"solbot/lsp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultIgnore are the rules of the parts of the messages expected to
+// differ between the sessions: the refreshes of the code lenses are sent by
+// a timer, and the traces report how long the requests took.
+var DefaultIgnore = []string{
+	"workspace/codeLens/refresh",
+	"$/logTrace:params.message",
+}
+
+// Rule ignores a part of the messages in the comparison. It's written as
+// "method" to ignore the whole messages, or "method:path" to ignore a
+// field e.g. "textDocument/hover:result.contents". The method of a
+// response is the method of the request, and "*" matches any method. The
+// path is a list of the object keys and the array indices separated by
+// dots, where "*" matches any key or index.
+type Rule struct {
+	Method string
+	Path   []string // or nil for the whole message
+}
+
+// ParseRule parses the rule, see Rule.
+func ParseRule(s string) Rule {
+	method, path, found := strings.Cut(s, ":")
+	if !found || path == "" {
+		return Rule{Method: method}
+	}
+	return Rule{Method: method, Path: strings.Split(path, ".")}
+}
+
+func (r Rule) matchesMethod(method string) bool {
+	return r.Method == "*" || r.Method == method
+}
+
+func (r Rule) matchesPath(path []string) bool {
+	if len(path) != len(r.Path) {
+		return false
+	}
+	for i := range path {
+		if r.Path[i] != "*" && r.Path[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Divergence is a difference between the recorded and the replayed
+// messages of the server.
+type Divergence struct {
+	Message  string // e.g. "response 7 (textDocument/hover)" or "textDocument/publishDiagnostics file:///a.sol v2"
+	Path     string // field that differs e.g. "params.diagnostics.0.message"; or empty if the message is missing or unexpected
+	Expected string // recorded JSON value; or empty if it's unexpected
+	Got      string // replayed JSON value; or empty if it's missing
+}
+
+// String formats the divergence as a line of the report e.g.
+//
+//	response 7 (textDocument/hover): result.contents.value: expected "uint256 a", got "uint256 b"
+//	textDocument/publishDiagnostics file:///a.sol v3: missing
+func (d Divergence) String() string {
+	switch {
+	case d.Path == "" && d.Got == "":
+		return d.Message + ": missing"
+	case d.Path == "" && d.Expected == "":
+		return d.Message + ": unexpected"
+	}
+	expected, got := d.Expected, d.Got
+	if expected == "" {
+		expected = "nothing"
+	}
+	if got == "" {
+		got = "nothing"
+	}
+	return fmt.Sprintf("%s: %s: expected %s, got %s", d.Message, d.Path, expected, got)
+}
+
+// outbound is a message sent by the server, identified by a key that is the
+// same in both of the sessions, since the messages may be sent in a
+// different order: the responses by their IDs, the diagnostics by the
+// document and its version, and the other messages by their method and
+// the number of the messages of the method sent before.
+type outbound struct {
+	key     string
+	method  string // method of the message, or of the request of the response
+	uri     string // document of the diagnostics; or empty
+	version int    // version of the document of the diagnostics
+	value   any
+}
+
+// outboundMessages returns the messages sent by the server in the session.
+// The requests are the methods of the client's requests by their IDs.
+func outboundMessages(entries []Entry, requests map[lsp.ID]string) []outbound {
+	res := []outbound{}
+	sent := map[string]int{}
+	for _, e := range entries {
+		if e.Direction != Outbound {
+			continue
+		}
+		var message struct {
+			ID     *lsp.ID `json:"id"`
+			Method string  `json:"method"`
+			Params struct {
+				URI     string `json:"uri"`
+				Version *int   `json:"version"`
+			} `json:"params"`
+		}
+		decoder := json.NewDecoder(strings.NewReader(e.Content))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			value = e.Content
+		}
+		_ = json.Unmarshal([]byte(e.Content), &message)
+
+		m := outbound{method: message.Method, value: value}
+		switch {
+		case message.Method == "" && message.ID != nil:
+			m.method = requests[*message.ID]
+			m.key = fmt.Sprintf("response %s (%s)", message.ID, m.method)
+		case message.ID != nil:
+			m.key = fmt.Sprintf("%s request %s", message.Method, message.ID)
+		case message.Method == "textDocument/publishDiagnostics" && message.Params.Version != nil:
+			m.uri, m.version = message.Params.URI, *message.Params.Version
+			m.key = fmt.Sprintf("%s %s v%d", message.Method, m.uri, m.version)
+		default:
+			sent[message.Method]++
+			m.key = fmt.Sprintf("%s #%d", message.Method, sent[message.Method])
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// Compare compares the messages the server sent in the recorded session
+// with the ones it sent when replaying it, and returns the divergences in
+// the order of the recorded messages. The parts of the messages matched by
+// the rules are ignored. The diagnostics of a version of a document are not
+// required in both of the sessions if the document was edited again, since
+// the server skips the diagnostics of the versions edited before they were
+// computed.
+func Compare(recorded, replayed []Entry, rules []Rule) []Divergence {
+	requests := map[lsp.ID]string{}
+	for _, e := range recorded {
+		if e.Direction != Inbound {
+			continue
+		}
+		var message struct {
+			ID     *lsp.ID `json:"id"`
+			Method string  `json:"method"`
+		}
+		if json.Unmarshal([]byte(e.Content), &message) == nil && message.ID != nil && message.Method != "" {
+			requests[*message.ID] = message.Method
+		}
+	}
+	ignored := func(method string) bool {
+		return slices.ContainsFunc(rules, func(r Rule) bool { return r.Path == nil && r.matchesMethod(method) })
+	}
+	expected := slices.DeleteFunc(outboundMessages(recorded, requests), func(m outbound) bool { return ignored(m.method) })
+	got := slices.DeleteFunc(outboundMessages(replayed, requests), func(m outbound) bool { return ignored(m.method) })
+
+	index := func(messages []outbound) (map[string]outbound, map[string]int) {
+		byKey, latest := map[string]outbound{}, map[string]int{}
+		for _, m := range messages {
+			byKey[m.key] = m
+			if m.uri != "" {
+				latest[m.uri] = max(latest[m.uri], m.version)
+			}
+		}
+		return byKey, latest
+	}
+	superseded := func(m outbound, latest map[string]int) bool {
+		return m.uri != "" && m.version < latest[m.uri]
+	}
+	expectedByKey, expectedLatest := index(expected)
+	gotByKey, gotLatest := index(got)
+
+	res := []Divergence{}
+	for _, e := range expected {
+		g, ok := gotByKey[e.key]
+		if !ok {
+			if !superseded(e, expectedLatest) {
+				res = append(res, Divergence{Message: e.key, Expected: encode(e.value)})
+			}
+			continue
+		}
+		diff(nil, e.value, g.value, func(path []string) bool {
+			return slices.ContainsFunc(rules, func(r Rule) bool { return r.matchesMethod(e.method) && r.matchesPath(path) })
+		}, func(path []string, expected, got any) {
+			res = append(res, Divergence{Message: e.key, Path: strings.Join(path, "."), Expected: encode(expected), Got: encode(got)})
+		})
+	}
+	for _, g := range got {
+		if _, ok := expectedByKey[g.key]; !ok && !superseded(g, gotLatest) {
+			res = append(res, Divergence{Message: g.key, Got: encode(g.value)})
+		}
+	}
+	return res
+}
+
+// absent stands for the missing object keys and array elements.
+type absent struct{}
+
+// diff reports the differences between the JSON values, unless their paths
+// are ignored.
+func diff(path []string, expected, got any, ignored func(path []string) bool, report func(path []string, expected, got any)) {
+	if ignored(path) {
+		return
+	}
+	switch e := expected.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := []string{}
+		for key := range e {
+			keys = append(keys, key)
+		}
+		for key := range g {
+			if _, ok := e[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			ev, ok := e[key]
+			if !ok {
+				ev = absent{}
+			}
+			gv, ok := g[key]
+			if !ok {
+				gv = absent{}
+			}
+			diff(append(slices.Clip(path), key), ev, gv, ignored, report)
+		}
+		return
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(e), len(g)); i++ {
+			var ev, gv any = absent{}, absent{}
+			if i < len(e) {
+				ev = e[i]
+			}
+			if i < len(g) {
+				gv = g[i]
+			}
+			diff(append(slices.Clip(path), strconv.Itoa(i)), ev, gv, ignored, report)
+		}
+		return
+	}
+	if encode(expected) != encode(got) {
+		report(path, expected, got)
+	}
+}
+
+// encode returns the value as compact JSON; or an empty string if it's
+// absent.
+func encode(v any) string {
+	if _, ok := v.(absent); ok {
+		return ""
+	}
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,128 @@
+// Package replay records the LSP sessions and replays them through the
+// server, so that a bug report with a recording becomes a regression test:
+// the messages the server sends when replaying the recording are compared
+// with the recorded ones, see Compare.
+package replay
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Direction tells who sent a recorded message.
+type Direction string
+
+const (
+	Inbound  Direction = "in"  // sent by the client
+	Outbound Direction = "out" // sent by the server
+)
+
+// Entry is a line of the recording, a JSON object. The content is the
+// message as it was sent, without the header.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"direction"`
+	Content   string    `json:"content"`
+}
+
+// Recorder appends the messages to the recording. It's safe for concurrent
+// use.
+type Recorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	redact bool
+}
+
+// NewRecorder returns the recorder writing to w. If redact is set, the
+// texts of the documents sent by the client are replaced with their hashes,
+// see Redact.
+func NewRecorder(w io.Writer, redact bool) *Recorder {
+	return &Recorder{w: w, redact: redact}
+}
+
+// Record appends the message sent at the time. The errors are returned,
+// but the recording stays usable.
+func (r *Recorder) Record(t time.Time, direction Direction, content []byte) error {
+	if r.redact && direction == Inbound {
+		content = Redact(content)
+	}
+	line, err := json.Marshal(Entry{Time: t.UTC(), Direction: direction, Content: string(content)})
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.w.Write(append(line, '\n'))
+	return err
+}
+
+// Redact replaces the texts of the documents in the didOpen and didChange
+// notifications with their SHA-256 hashes e.g. "sha256:2c26b46b...", so
+// that the users who can't share the source can still share the sequence
+// of the edits. The other messages are returned as they are. The server
+// can't analyze the redacted documents, so the replay of such a recording
+// diverges wherever the diagnostics depend on the source.
+func Redact(content []byte) []byte {
+	var message map[string]any
+	if err := json.Unmarshal(content, &message); err != nil {
+		return content
+	}
+	params, _ := message["params"].(map[string]any)
+	hash := func(v any) any {
+		text, ok := v.(string)
+		if !ok {
+			return v
+		}
+		sum := sha256.Sum256([]byte(text))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+	switch message["method"] {
+	case "textDocument/didOpen":
+		if document, ok := params["textDocument"].(map[string]any); ok {
+			document["text"] = hash(document["text"])
+		}
+	case "textDocument/didChange":
+		changes, _ := params["contentChanges"].([]any)
+		for _, change := range changes {
+			if change, ok := change.(map[string]any); ok {
+				change["text"] = hash(change["text"])
+			}
+		}
+	default:
+		return content
+	}
+	redacted, err := json.Marshal(message)
+	if err != nil {
+		return content
+	}
+	return redacted
+}
+
+// ReadRecording reads the entries of the recording, in the order they were
+// recorded.
+func ReadRecording(r io.Reader) ([]Entry, error) {
+	entries := []Entry{}
+	scanner := bufio.NewScanner(r)
+	// The didOpen of a large file doesn't fit the default buffer.
+	scanner.Buffer(nil, 1<<30)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if e.Direction != Inbound && e.Direction != Outbound {
+			return nil, fmt.Errorf("line %d: unknown direction %q", line, e.Direction)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,73 @@
+package replay
+
+import (
+	"encoding/json"
+	"io"
+	"solbot/lsp/rpc"
+	"sync"
+	"time"
+)
+
+// Handler handles the messages of the client, like the Handle method of
+// the server.
+type Handler interface {
+	Handle(method string, content []byte)
+}
+
+// Replay feeds the inbound messages of the recording to the handler
+// returned by start, and returns the messages the handler wrote to the
+// writer passed to start. If paced is set, it waits between the messages as
+// long as the client did; otherwise they're fed at full speed.
+func Replay(entries []Entry, start func(w io.Writer) Handler, paced bool) []Entry {
+	w := &frameWriter{}
+	handler := start(w)
+	var last time.Time
+	for _, e := range entries {
+		if e.Direction != Inbound {
+			continue
+		}
+		if paced && !last.IsZero() && e.Time.After(last) {
+			time.Sleep(e.Time.Sub(last))
+		}
+		last = e.Time
+		var message struct {
+			Method string `json:"method"`
+		}
+		_ = json.Unmarshal([]byte(e.Content), &message)
+		handler.Handle(message.Method, []byte(e.Content))
+	}
+	return w.entries()
+}
+
+// frameWriter splits the written stream into the messages, stamped with
+// the time they were written at. It's written by the timers of the server
+// as well.
+type frameWriter struct {
+	mu       sync.Mutex
+	buf      []byte
+	messages []Entry
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		advance, frame, err := rpc.Split(w.buf, false)
+		if err != nil || frame == nil {
+			return len(p), err
+		}
+		w.buf = w.buf[advance:]
+		_, content, err := rpc.DecodeMessage(frame)
+		if err != nil {
+			return len(p), err
+		}
+		w.messages = append(w.messages, Entry{Time: time.Now().UTC(), Direction: Outbound, Content: string(content)})
+	}
+}
+
+func (w *frameWriter) entries() []Entry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Entry{}, w.messages...)
+}
@@ -0,0 +1,102 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func in(content string) Entry  { return Entry{Direction: Inbound, Content: content} }
+func out(content string) Entry { return Entry{Direction: Outbound, Content: content} }
+
+func diagnostics(version int, message string) Entry {
+	return out(fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///a.sol","version":%d,"diagnostics":[{"message":%q}]}}`, version, message))
+}
+
+func Test_Compare(t *testing.T) {
+	recorded := []Entry{
+		in(`{"jsonrpc":"2.0","id":1,"method":"textDocument/hover","params":{}}`),
+		diagnostics(1, "unused"),
+		diagnostics(3, "always true"),
+		out(`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"Processing request took 1ms."}}`),
+		out(`{"jsonrpc":"2.0","id":1,"result":{"contents":{"value":"uint256 a"}}}`),
+		out(`{"jsonrpc":"2.0","method":"window/showMessage","params":{"type":2,"message":"settings"}}`),
+	}
+	// The responses come in a different order, the diagnostics of the
+	// version 2 were skipped when recording and the trace took longer.
+	replayed := []Entry{
+		out(`{"jsonrpc":"2.0","id":1,"result":{"contents":{"value":"uint256 b"}}}`),
+		diagnostics(1, "unused"),
+		diagnostics(2, "unused"),
+		diagnostics(3, "always false"),
+		out(`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"Processing request took 2ms."}}`),
+		out(`{"jsonrpc":"2.0","method":"workspace/codeLens/refresh","id":4}`),
+	}
+
+	rules := []Rule{}
+	for _, rule := range DefaultIgnore {
+		rules = append(rules, ParseRule(rule))
+	}
+	expected := []string{
+		`textDocument/publishDiagnostics file:///a.sol v3: params.diagnostics.0.message: expected "always true", got "always false"`,
+		`response 1 (textDocument/hover): result.contents.value: expected "uint256 a", got "uint256 b"`,
+		`window/showMessage #1: missing`,
+	}
+	divergences := Compare(recorded, replayed, rules)
+	if len(divergences) != len(expected) {
+		t.Fatalf("Expected %d divergences, got %v", len(expected), divergences)
+	}
+	for i, d := range divergences {
+		if d.String() != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], d)
+		}
+	}
+
+	rules = append(rules, ParseRule("*:params.diagnostics.*.message"), ParseRule("textDocument/hover"), ParseRule("window/showMessage"))
+	if divergences := Compare(recorded, replayed, rules); len(divergences) != 0 {
+		t.Errorf("Expected the ignored parts not to diverge, got %v", divergences)
+	}
+
+	// The diagnostics of the last version are required.
+	replayed = append(replayed[:3], replayed[4:]...)
+	divergences = Compare(recorded, replayed, rules)
+	if len(divergences) != 2 || divergences[0].String() != "textDocument/publishDiagnostics file:///a.sol v3: missing" ||
+		divergences[1].String() != "textDocument/publishDiagnostics file:///a.sol v2: unexpected" {
+		t.Errorf("Expected the diagnostics of the version 2 instead of 3, got %v", divergences)
+	}
+}
+
+func Test_RecordAndRead(t *testing.T) {
+	var b bytes.Buffer
+	r := NewRecorder(&b, true)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	didOpen := `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.sol","version":1,"text":"contract A {}"}}}`
+	didChange := `{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///a.sol","version":2},"contentChanges":[{"text":"contract A {}"}]}}`
+	hover := "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"contents\":{\"value\":\"contract A\"}}}"
+	r.Record(start, Inbound, []byte(didOpen))
+	r.Record(start.Add(time.Second), Inbound, []byte(didChange))
+	r.Record(start.Add(2*time.Second), Outbound, []byte(hover))
+
+	entries, err := ReadRecording(&b)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %v", entries)
+	}
+	const hash = `"sha256:7ff3da8117bf263b90ac8fb9058d15c16b3ee70c02b7f7fe99f4df755b4a75c6"`
+	for _, e := range entries[:2] {
+		if strings.Contains(e.Content, "contract A {}") || !strings.Contains(e.Content, `"text":`+hash) {
+			t.Errorf("Expected the text to be redacted, got %s", e.Content)
+		}
+	}
+	if e := entries[2]; e.Content != hover || e.Direction != Outbound || !e.Time.Equal(start.Add(2*time.Second)) {
+		t.Errorf("Expected the response recorded verbatim, got %+v", e)
+	}
+
+	if _, err := ReadRecording(strings.NewReader(`{"direction":"sideways"}`)); err == nil || err.Error() != `line 1: unknown direction "sideways"` {
+		t.Errorf("Expected an error about the direction, got %v", err)
+	}
+}
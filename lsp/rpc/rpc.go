@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"slices"
+	"solbot/lsp"
 	"strconv"
 )
 
@@ -27,10 +30,12 @@ func DecodeMessage(msg []byte) (string, []byte, error) {
 		return "", nil, fmt.Errorf("Separator not found in message. Could not decode.")
 	}
 
-	contentLengthInBytes := header[len("Content-Length: "):]
-	contentLength, err := strconv.Atoi(string(contentLengthInBytes))
+	contentLength, err := ContentLength(header)
 	if err != nil {
-		return "", nil, fmt.Errorf("Could not parse Content-Length: %s", err)
+		return "", nil, err
+	}
+	if len(content) < contentLength {
+		return "", nil, fmt.Errorf("Content shorter than its Content-Length of %d", contentLength)
 	}
 
 	var message BaseMessage
@@ -42,6 +47,97 @@ func DecodeMessage(msg []byte) (string, []byte, error) {
 	return message.Method, content[:contentLength], nil
 }
 
+// Message is a message of a batch.
+type Message struct {
+	Method  string
+	Content []byte
+}
+
+// DecodeBatch decodes a batch: the array of messages some clients and
+// proxies send in a single frame. The second result is false if the
+// content is a single message, see DecodeMessage.
+func DecodeBatch(msg []byte) ([]Message, bool, error) {
+	_, content, found := bytes.Cut(msg, []byte("\r\n\r\n"))
+	if !found || !bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte("[")) {
+		return nil, false, nil
+	}
+	var elements []json.RawMessage
+	if err := json.Unmarshal(content, &elements); err != nil {
+		return nil, true, fmt.Errorf("Could not unmarshal batch: %s", err)
+	}
+	if len(elements) == 0 {
+		return nil, true, fmt.Errorf("Empty batch")
+	}
+	res := []Message{}
+	for i, element := range elements {
+		var message BaseMessage
+		if err := json.Unmarshal(element, &message); err != nil {
+			return nil, true, fmt.Errorf("Could not unmarshal message %d of the batch: %s", i, err)
+		}
+		res = append(res, Message{Method: message.Method, Content: element})
+	}
+	return res, true, nil
+}
+
+// knownFields are the top-level fields of the JSON-RPC messages.
+var knownFields = []string{"jsonrpc", "id", "method", "params", "result", "error"}
+
+// Normalize returns the content of the message with the omitted or null
+// params of a request or a notification replaced by an empty object, so
+// that the optional params are decoded like the empty ones. The second
+// result lists the top-level fields JSON-RPC doesn't define, which are
+// ignored.
+func Normalize(content []byte) ([]byte, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, nil, fmt.Errorf("Could not unmarshal message: %s", err)
+	}
+	unknown := []string{}
+	for name := range fields {
+		if !slices.Contains(knownFields, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	slices.Sort(unknown)
+
+	params, ok := fields["params"]
+	if _, isCall := fields["method"]; !isCall || ok && string(params) != "null" {
+		return content, unknown, nil
+	}
+	fields["params"] = json.RawMessage("{}")
+	normalized, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return normalized, unknown, nil
+}
+
+// ContentLength returns the value of the Content-Length field of the header,
+// without the separator. The header is read one field per line, and the
+// fields other than Content-Length, like Content-Type, are ignored. A
+// header without the length, or with a length below zero, is an error.
+func ContentLength(header []byte) (int, error) {
+	length, found := 0, false
+	for _, line := range bytes.Split(header, []byte("\r\n")) {
+		name, value, ok := bytes.Cut(line, []byte(":"))
+		if !ok || !bytes.EqualFold(bytes.TrimSpace(name), []byte("Content-Length")) {
+			continue
+		}
+		n, err := strconv.Atoi(string(bytes.TrimSpace(value)))
+		if err != nil {
+			return 0, fmt.Errorf("Could not parse Content-Length: %s", err)
+		}
+		if n < 0 {
+			return 0, fmt.Errorf("Invalid Content-Length: %d", n)
+		}
+		length, found = n, true
+	}
+	if !found {
+		return 0, fmt.Errorf("Content-Length not found in the header")
+	}
+	return length, nil
+}
+
 // Split is a function used for the bufio.Scanner to split the incoming data.
 // For the LSP it will just split it based on the Content-Length header.
 func Split(data []byte, _ bool) (advance int, token []byte, err error) {
@@ -53,8 +149,7 @@ func Split(data []byte, _ bool) (advance int, token []byte, err error) {
 		return 0, nil, nil
 	}
 
-	contentLengthInBytes := header[len("Content-Length: "):]
-	contentLength, err := strconv.Atoi(string(contentLengthInBytes))
+	contentLength, err := ContentLength(header)
 	// Here we return and error. If we can't get the actual number of bytes, something is messed up.
 	if err != nil {
 		return 0, nil, err
@@ -69,3 +164,111 @@ func Split(data []byte, _ bool) (advance int, token []byte, err error) {
 
 	return totalLength, data[:totalLength], nil
 }
+
+// DefaultMaxMessageSize is the default size in bytes of the largest content
+// of a message read by the server.
+const DefaultMaxMessageSize = 16 << 20
+
+// MaxHeaderSize is the size in bytes of the largest header. A stream without
+// the separator within it is corrupted.
+const MaxHeaderSize = 4096
+
+// Oversized describes a message whose content was larger than the limit
+// and was skipped. The ID and the method are read from the start of the
+// content, so they are missing if they don't come first in the JSON.
+type Oversized struct {
+	Length int     // declared content length in bytes
+	ID     *lsp.ID // ID of the request; or nil for a notification
+	Method string  // method; or empty if it's unknown
+}
+
+var (
+	oversizedID     = regexp.MustCompile(`^\s*\{.*?"id"\s*:\s*(-?\d+|"(?:[^"\\]|\\.)*")`)
+	oversizedMethod = regexp.MustCompile(`^\s*\{.*?"method"\s*:\s*"([^"]*)"`)
+)
+
+// Splitter splits the data the same as Split, but it bounds the memory: the
+// content of the messages larger than MaxSize is skipped as it arrives
+// rather than buffered, and the header must fit into MaxHeaderSize. The ID
+// and the method of a skipped message are looked for in its first KB. Every
+// skipped message produces an empty token, see Skipped.
+type Splitter struct {
+	MaxSize int // largest content length in bytes; or 0 for no limit
+
+	skip    int        // bytes of the skipped content still to come
+	skipped *Oversized // message being skipped
+	pending bool       // is a message read partially?
+}
+
+// Split is the bufio.SplitFunc of the splitter.
+func (s *Splitter) Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = s.split(data)
+	s.pending = advance < len(data)
+	return advance, token, err
+}
+
+func (s *Splitter) split(data []byte) (int, []byte, error) {
+	if s.skip > 0 {
+		n := min(s.skip, len(data))
+		s.skip -= n
+		if s.skip > 0 {
+			return n, nil, nil
+		}
+		return n, []byte{}, nil
+	}
+	s.skipped = nil
+
+	separator := []byte("\r\n\r\n")
+	header, content, found := bytes.Cut(data, separator)
+	if !found {
+		if len(data) > MaxHeaderSize {
+			return 0, nil, fmt.Errorf("header longer than %d bytes", MaxHeaderSize)
+		}
+		return 0, nil, nil
+	}
+	if len(header) > MaxHeaderSize {
+		return 0, nil, fmt.Errorf("header longer than %d bytes", MaxHeaderSize)
+	}
+	contentLength, err := ContentLength(header)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	headerLength := len(header) + len(separator)
+	if s.MaxSize > 0 && contentLength > s.MaxSize {
+		s.skipped = &Oversized{Length: contentLength}
+		start := content[:min(len(content), 1024)]
+		if m := oversizedID.FindSubmatch(start); m != nil {
+			var id lsp.ID
+			if err := json.Unmarshal(m[1], &id); err == nil {
+				s.skipped.ID = &id
+			}
+		}
+		if m := oversizedMethod.FindSubmatch(start); m != nil {
+			s.skipped.Method = string(m[1])
+		}
+		s.skip = contentLength
+		n, token, err := s.split(data[headerLength:])
+		return headerLength + n, token, err
+	}
+
+	if len(content) < contentLength {
+		return 0, nil, nil
+	}
+	totalLength := headerLength + contentLength
+	return totalLength, data[:totalLength], nil
+}
+
+// Skipped returns the message skipped by the last token; or nil if the
+// token is a message.
+func (s *Splitter) Skipped() *Oversized {
+	if s.skip > 0 {
+		return nil
+	}
+	return s.skipped
+}
+
+// Pending reports whether the data read so far ends with a partial message.
+func (s *Splitter) Pending() bool {
+	return s.pending || s.skip > 0
+}
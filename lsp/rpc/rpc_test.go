@@ -1,7 +1,13 @@
 package rpc
 
 import (
+	"bufio"
+	"fmt"
+	"slices"
+	"solbot/lsp"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 type ExampleEncoding struct {
@@ -38,3 +44,142 @@ func TestDecoding(t *testing.T) {
 		t.Fatalf("Expected method %s, got %s", methodStr, decodedMethod)
 	}
 }
+
+func TestSplitterSkipsOversized(t *testing.T) {
+	oversized := `{"jsonrpc":"2.0","id":3,"method":"textDocument/didOpen","params":{"text":"` + strings.Repeat("x", 5000) + `"}}`
+	notification := `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"text":"` + strings.Repeat("x", 5000) + `"}}`
+	small := `{"jsonrpc":"2.0","method":"initialized"}`
+	stream := fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(oversized), oversized) +
+		fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(notification), notification) +
+		fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(small), small)
+
+	splitter := &Splitter{MaxSize: 1024}
+	// The content arrives in small chunks, so it's skipped piece by piece.
+	scanner := bufio.NewScanner(iotest.HalfReader(strings.NewReader(stream)))
+	scanner.Buffer(make([]byte, 0, 256), 1024+MaxHeaderSize)
+	scanner.Split(splitter.Split)
+
+	skipped := []*Oversized{}
+	messages := []string{}
+	for scanner.Scan() {
+		if s := splitter.Skipped(); s != nil {
+			skipped = append(skipped, s)
+			continue
+		}
+		method, _, err := DecodeMessage(scanner.Bytes())
+		if err != nil {
+			t.Fatalf("Error decoding message: %s", err)
+		}
+		messages = append(messages, method)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if len(skipped) != 2 {
+		t.Fatalf("Expected 2 skipped messages, got %d", len(skipped))
+	}
+	if skipped[0].ID == nil || *skipped[0].ID != lsp.IntID(3) || skipped[0].Method != "textDocument/didOpen" || skipped[0].Length != len(oversized) {
+		t.Errorf("Expected the request 3 of %d bytes, got %+v", len(oversized), skipped[0])
+	}
+	if skipped[1].ID != nil {
+		t.Errorf("Expected a notification, got the ID %s", skipped[1].ID)
+	}
+	if len(messages) != 1 || messages[0] != "initialized" {
+		t.Errorf("Expected the message after the skipped ones, got %v", messages)
+	}
+}
+
+func TestSplitterRejectsLongHeader(t *testing.T) {
+	splitter := &Splitter{MaxSize: 1024}
+	scanner := bufio.NewScanner(strings.NewReader(strings.Repeat("Content-Length: 1", 1000)))
+	scanner.Split(splitter.Split)
+	for scanner.Scan() {
+	}
+	if err := scanner.Err(); err == nil || !strings.Contains(err.Error(), "header longer than") {
+		t.Errorf("Expected the header to be rejected, got %v", err)
+	}
+}
+
+func TestSplitterRejectsNegativeLength(t *testing.T) {
+	splitter := &Splitter{MaxSize: 1024}
+	scanner := bufio.NewScanner(strings.NewReader("Content-Length: -100\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"initialized\"}"))
+	scanner.Split(splitter.Split)
+	for scanner.Scan() {
+		t.Errorf("Expected no message, got %q", scanner.Text())
+	}
+	if err := scanner.Err(); err == nil || !strings.Contains(err.Error(), "Invalid Content-Length: -100") {
+		t.Errorf("Expected the negative length to be rejected, got %v", err)
+	}
+}
+
+func TestSplitterIgnoresOtherFields(t *testing.T) {
+	first := `{"jsonrpc":"2.0","method":"initialized"}`
+	second := `{"jsonrpc":"2.0","method":"exit"}`
+	stream := fmt.Sprintf("Content-Length: %d\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n%s", len(first), first) +
+		fmt.Sprintf("Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length:%d\r\n\r\n%s", len(second), second)
+
+	splitter := &Splitter{MaxSize: 1024}
+	scanner := bufio.NewScanner(strings.NewReader(stream))
+	scanner.Split(splitter.Split)
+	methods := []string{}
+	for scanner.Scan() {
+		method, _, err := DecodeMessage(scanner.Bytes())
+		if err != nil {
+			t.Fatalf("Error decoding message: %s", err)
+		}
+		methods = append(methods, method)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !slices.Equal(methods, []string{"initialized", "exit"}) {
+		t.Errorf("Expected both messages, got %v", methods)
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	tests := []struct {
+		header string
+		length int
+		err    string
+	}{
+		{"Content-Length: 25", 25, ""},
+		{"Content-Type: application/vscode-jsonrpc\r\nContent-Length: 7", 7, ""},
+		{"Content-Length: -1", 0, "Invalid Content-Length: -1"},
+		{"Content-Length: ten", 0, "Could not parse Content-Length"},
+		{"Content-Type: application/vscode-jsonrpc", 0, "Content-Length not found"},
+	}
+	for _, tt := range tests {
+		length, err := ContentLength([]byte(tt.header))
+		if tt.err == "" && (err != nil || length != tt.length) {
+			t.Errorf("Expected %d for %q, got %d, %v", tt.length, tt.header, length, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("Expected the error %q for %q, got %v", tt.err, tt.header, err)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		content, expected string
+		unknown           []string
+	}{
+		{`{"jsonrpc":"2.0","id":"a","method":"shutdown"}`, `{"id":"a","jsonrpc":"2.0","method":"shutdown","params":{}}`, []string{}},
+		{`{"jsonrpc":"2.0","method":"exit","params":null,"traceId":1}`, `{"jsonrpc":"2.0","method":"exit","params":{},"traceId":1}`, []string{"traceId"}},
+		{`{"jsonrpc":"2.0","id":1,"result":null}`, `{"jsonrpc":"2.0","id":1,"result":null}`, []string{}},
+	}
+	for _, tt := range tests {
+		content, unknown, err := Normalize([]byte(tt.content))
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		if string(content) != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, content)
+		}
+		if !slices.Equal(unknown, tt.unknown) {
+			t.Errorf("Expected the unknown fields %v, got %v", tt.unknown, unknown)
+		}
+	}
+}
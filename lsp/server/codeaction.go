@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"solbot/lsp"
+)
+
+func init() {
+	features.register(feature{
+		method: "textDocument/codeAction",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			if caps.CodeActionProvider == nil {
+				caps.CodeActionProvider = &lsp.CodeActionOptions{}
+			}
+			caps.CodeActionProvider.CodeActionKinds = []lsp.CodeActionKind{lsp.CodeActionQuickFix, lsp.CodeActionRefactor, lsp.CodeActionSourceOrganizeImports}
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.CodeActionRequest) {
+			response := s.state.CodeAction(request.ID, request.Params.TextDocument.URI, request.Params.Range)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "codeAction/resolve",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			if caps.CodeActionProvider == nil {
+				caps.CodeActionProvider = &lsp.CodeActionOptions{}
+			}
+			caps.CodeActionProvider.ResolveProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.CodeActionResolveRequest) {
+			response := s.state.ResolveCodeAction(request.ID, request.Params)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "workspace/executeCommand",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.ExecuteCommandProvider = &lsp.ExecuteCommandOptions{
+				Commands: []string{lsp.PreviewMigrationCommand},
+			}
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.ExecuteCommandRequest) {
+			response, uri := s.state.ExecuteCommand(request.ID, request.Params)
+			s.respond(ctx, response)
+			if uri != "" {
+				s.publishDiagnostics(ctx, uri)
+			}
+		}),
+	})
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"solbot/lsp"
+)
+
+func init() {
+	features.register(feature{
+		method: "textDocument/codeLens",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			if caps.CodeLensProvider == nil {
+				caps.CodeLensProvider = &lsp.CodeLensOptions{}
+			}
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.CodeLensRequest) {
+			response := s.state.CodeLens(request.ID, request.Params.TextDocument.URI)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "codeLens/resolve",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			if caps.CodeLensProvider == nil {
+				caps.CodeLensProvider = &lsp.CodeLensOptions{}
+			}
+			caps.CodeLensProvider.ResolveProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.CodeLensResolveRequest) {
+			response := s.state.ResolveCodeLens(request.ID, request.Params)
+			s.respond(ctx, response)
+		}),
+	})
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"solbot/lsp/rpc"
+	"time"
+)
+
+// deadliner is a reader with the deadlines e.g. a TCP connection.
+type deadliner interface {
+	Read(p []byte) (int, error)
+	SetReadDeadline(t time.Time) error
+}
+
+// deadlineReader sets the read deadline while a message is read partially,
+// so that a client stalling in the middle of a message doesn't hold the
+// buffered part forever. A client idle in between the messages is fine.
+type deadlineReader struct {
+	conn     deadliner
+	timeout  time.Duration
+	splitter *rpc.Splitter
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	deadline := time.Time{}
+	if r.splitter.Pending() {
+		deadline = time.Now().Add(r.timeout)
+	}
+	// The connections that can't set it are read without the deadline.
+	_ = r.conn.SetReadDeadline(deadline)
+	return r.conn.Read(p)
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"solbot/lsp"
+)
+
+// The pulled diagnostics are advertised to every client: the ones which
+// don't pull them ignore the capability and get the diagnostics published,
+// see publishDiagnostics.
+func init() {
+	features.register(feature{
+		method: "textDocument/diagnostic",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			if caps.DiagnosticProvider == nil {
+				caps.DiagnosticProvider = &lsp.DiagnosticOptions{}
+			}
+			caps.DiagnosticProvider.InterFileDependencies = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.DocumentDiagnosticRequest) {
+			response := s.state.DocumentDiagnostic(ctx, request.ID, request.Params)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "workspace/diagnostic",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			if caps.DiagnosticProvider == nil {
+				caps.DiagnosticProvider = &lsp.DiagnosticOptions{}
+			}
+			caps.DiagnosticProvider.WorkspaceDiagnostics = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.WorkspaceDiagnosticRequest) {
+			// With the partial result token, every report is streamed as
+			// soon as it's ready and the response has no items.
+			token := request.Params.PartialResultToken
+			reports := []lsp.WorkspaceDocumentDiagnosticReport{}
+			err := s.state.WorkspaceDiagnostic(ctx, request.Params.PreviousResultIDs, func(report lsp.WorkspaceDocumentDiagnosticReport) {
+				if len(token) > 0 {
+					s.notify(ctx, lsp.NewWorkspaceDiagnosticProgressNotification(token, []lsp.WorkspaceDocumentDiagnosticReport{report}))
+				} else {
+					reports = append(reports, report)
+				}
+			})
+			if err != nil {
+				s.respond(ctx, lsp.NewErrorResponse(request.ID, lsp.ServerCancelled, "the workspace diagnostics were cancelled"))
+				return
+			}
+			s.respond(ctx, lsp.NewWorkspaceDiagnosticResponse(request.ID, reports))
+		}),
+	})
+}
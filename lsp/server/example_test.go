@@ -0,0 +1,54 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"solbot/ast"
+	"solbot/lsp"
+	"solbot/lsp/analysis"
+	"solbot/lsp/rpc"
+	"solbot/lsp/server"
+	"strings"
+)
+
+// CountParams are the params of the acme/countExternal request.
+type CountParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+// Adds a request counting the external functions of a document.
+func ExampleWithMethod() {
+	count := server.NewMethod("acme/countExternal", func(ctx context.Context, state *analysis.State, params CountParams) (any, error) {
+		doc, ok := state.Document(params.TextDocument.URI)
+		if !ok {
+			return nil, fmt.Errorf("unknown document %s", params.TextDocument.URI)
+		}
+		n := 0
+		ast.Inspect(doc.File, func(node ast.Node) bool {
+			if fn, ok := node.(*ast.FunctionDeclaration); ok && fn.Type.Visibility == ast.External {
+				n++
+			}
+			return true
+		})
+		return n, nil
+	})
+	count.Interactive = true
+
+	var output bytes.Buffer
+	s := server.NewServer(&output, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), false, server.WithMethod(count))
+	s.Handle("textDocument/didOpen", []byte(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///ws/Vault.sol","version":1,"text":"contract Vault {\n    function deposit() external {}\n    function withdraw() external {}\n    function _sync() internal {}\n}\n"}}}`))
+	s.Handle("acme/countExternal", []byte(`{"jsonrpc":"2.0","id":1,"method":"acme/countExternal","params":{"textDocument":{"uri":"file:///ws/Vault.sol"}}}`))
+
+	scanner := bufio.NewScanner(&output)
+	scanner.Split(rpc.Split)
+	for scanner.Scan() {
+		if _, content, _ := strings.Cut(scanner.Text(), "\r\n\r\n"); strings.Contains(content, `"id":1`) {
+			fmt.Println(content)
+		}
+	}
+	// Output:
+	// {"jsonrpc":"2.0","id":1,"result":2}
+}
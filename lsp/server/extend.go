@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"solbot/lsp"
+	"solbot/lsp/analysis"
+)
+
+// The tools embedding the server add their own requests to it with the
+// options of NewServer, instead of forking it e.g.
+//
+//	count := server.NewMethod("acme/count", func(ctx context.Context, state *analysis.State, params CountParams) (any, error) {
+//		...
+//	})
+//	s := server.NewServer(os.Stdout, logger, false, server.WithMethod(count))
+//
+// The methods are handled like the built-in ones: on the same analysis
+// state, in the order of the messages, with the correlation ID of the
+// request in the context, and the panics turned into the InternalError.
+// Option, Method, NewMethod, WithMethod and WithCapabilities are the API
+// of the package kept compatible for the embedders; the rest of it may
+// change with the protocol.
+
+// Option customizes the server built by NewServer.
+type Option func(r *registry, interactive map[string]bool)
+
+// Method is a request an embedder adds to the server.
+type Method struct {
+	Name string // e.g. "acme/count", namespaced so that it can't collide with the future methods of the protocol
+
+	// Interactive methods are cheap and only read the state, like the
+	// hover: they're answered at the checkpoints of the background work
+	// instead of waiting for its end, see scheduler.
+	Interactive bool
+
+	handle func(s *Server, ctx context.Context, content []byte)
+}
+
+// NewMethod returns the request handled by the function, with the params
+// of the request decoded into P. The request which can't be decoded gets
+// the InvalidParams error, and the one the function fails gets the
+// RequestFailed error with its message; otherwise the result is the value
+// the function returns. The state must not be used after the function
+// returns, since the next messages change it.
+func NewMethod[P any](name string, handle func(ctx context.Context, state *analysis.State, params P) (any, error)) Method {
+	type request struct {
+		lsp.Request
+		Params P `json:"params"`
+	}
+	type response struct {
+		lsp.Response
+		Result any `json:"result"`
+	}
+	return Method{
+		Name: name,
+		handle: onRequest(func(s *Server, ctx context.Context, request request) {
+			result, err := handle(ctx, s.state, request.Params)
+			if err != nil {
+				s.respond(ctx, lsp.NewErrorResponse(request.ID, lsp.RequestFailed, err.Error()))
+				return
+			}
+			s.respond(ctx, response{Response: lsp.Response{RPC: "2.0", ID: &request.ID}, Result: result})
+		}),
+	}
+}
+
+// WithMethod adds the request to the server. A method of the same name as
+// a built-in one or another added method is a programming error, so
+// NewServer panics.
+func WithMethod(m Method) Option {
+	return func(r *registry, interactive map[string]bool) {
+		if m.handle == nil {
+			panic(fmt.Sprintf("the method %s has no handler, see NewMethod", m.Name))
+		}
+		r.register(feature{method: m.Name, handle: m.handle})
+		if m.Interactive {
+			interactive[m.Name] = true
+		}
+	}
+}
+
+// WithCapabilities adds to the capabilities the server advertises in the
+// initialize response, after the built-in ones, e.g. the experimental
+// capability telling the client of the embedder which methods it can send.
+func WithCapabilities(contribute func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities)) Option {
+	return func(r *registry, interactive map[string]bool) {
+		r.contributions = append(r.contributions, contribute)
+	}
+}
+
+// extend gives the server its own copy of the built-in features, with the
+// options applied.
+func (s *Server) extend(options []Option) {
+	s.features = features
+	if len(options) == 0 {
+		return
+	}
+	s.features = features.clone()
+	interactive := maps.Clone(interactiveMethods)
+	for _, option := range options {
+		option(s.features, interactive)
+	}
+	s.scheduler.interactive = interactive
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"solbot/lsp"
+	"solbot/lsp/analysis"
+	"strings"
+	"testing"
+)
+
+type uriParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+func Test_ServeAddedMethods(t *testing.T) {
+	count := NewMethod("acme/count", func(ctx context.Context, state *analysis.State, params uriParams) (any, error) {
+		doc, ok := state.Document(params.TextDocument.URI)
+		if !ok {
+			return nil, errors.New("unknown document")
+		}
+		return len(doc.File.Declarations), nil
+	})
+	count.Interactive = true
+	fail := NewMethod("acme/fail", func(ctx context.Context, state *analysis.State, params uriParams) (any, error) {
+		return nil, errors.New("not supported")
+	})
+	crash := NewMethod("acme/crash", func(ctx context.Context, state *analysis.State, params uriParams) (any, error) {
+		panic("out of range")
+	})
+	experimental := WithCapabilities(func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+		caps.Experimental = map[string]any{"acme": map[string]bool{"count": true}}
+	})
+
+	handler := newRecordHandler()
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(handler), false, WithMethod(count), WithMethod(fail), WithMethod(crash), experimental)
+	if !s.scheduler.interactive["acme/count"] || !s.scheduler.interactive["textDocument/hover"] || s.scheduler.interactive["acme/fail"] {
+		t.Errorf("Expected the added method in the interactive lane, got %v", s.scheduler.interactive)
+	}
+	input := frame(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{}}}`) +
+		frame(didOpen) +
+		frame(`{"jsonrpc":"2.0","id":2,"method":"acme/count","params":{"textDocument":{"uri":"file:///ws/Vault.sol"}}}`) +
+		frame(`{"jsonrpc":"2.0","id":3,"method":"acme/count","params":{"textDocument":{"uri":"file:///ws/Other.sol"}}}`) +
+		frame(`{"jsonrpc":"2.0","id":4,"method":"acme/fail","params":{"textDocument":1}}`) +
+		frame(`{"jsonrpc":"2.0","id":5,"method":"acme/crash","params":{}}`) +
+		frame(hover)
+	if err := s.Serve(strings.NewReader(input)); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	responses := map[string]string{}
+	for _, content := range messages(t, output.String()) {
+		if _, rest, ok := strings.Cut(content, `"id":`); ok {
+			id, _, _ := strings.Cut(rest, ",")
+			responses[id] = content
+		}
+	}
+	if !strings.Contains(responses["1"], `"experimental":{"acme":{"count":true}}`) || !strings.Contains(responses["1"], `"hoverProvider":true`) {
+		t.Errorf("Expected the added capability next to the built-in ones, got %s", responses["1"])
+	}
+	expected := map[string]string{
+		"2": `{"jsonrpc":"2.0","id":2,"result":1}`,
+		"3": `{"jsonrpc":"2.0","id":3,"error":{"code":-32803,"message":"unknown document"}}`,
+		"4": `{"jsonrpc":"2.0","id":4,"error":{"code":-32602,"message":"cannot decode the request: json: cannot unmarshal number`,
+		"5": `{"jsonrpc":"2.0","id":5,"error":{"code":-32603,"message":"acme/crash failed: out of range"}}`,
+	}
+	for id, response := range expected {
+		if !strings.HasPrefix(responses[id], response) {
+			t.Errorf("Expected the response %s, got %s", response, responses[id])
+		}
+	}
+	// The server carries on after the panic.
+	if !strings.HasPrefix(responses["7"], `{"jsonrpc":"2.0","id":7,"result":`) {
+		t.Errorf("Expected the hover answered after the panic, got %s", responses["7"])
+	}
+	panicked, ok := handler.find("the handler panicked")
+	if !ok || panicked["method"].String() != "acme/crash" || panicked["cid"].Int64() != 6 || !strings.Contains(panicked["stack"].String(), "extend_test.go") {
+		t.Errorf("Expected the panic logged with the correlation ID and the stack, got %v", panicked)
+	}
+}
+
+func Test_AddedMethodCollision(t *testing.T) {
+	hover := NewMethod("textDocument/hover", func(ctx context.Context, state *analysis.State, params uriParams) (any, error) {
+		return nil, nil
+	})
+	defer func() {
+		if r := recover(); r != "the method textDocument/hover is already registered" {
+			t.Errorf("Expected the server with a method colliding with a built-in one to panic, got %v", r)
+		}
+		// The built-in features are left as they were.
+		if features.lookup("acme/count") != nil {
+			t.Errorf("Expected the built-in features unchanged")
+		}
+	}()
+	count := NewMethod("acme/count", func(ctx context.Context, state *analysis.State, params uriParams) (any, error) {
+		return 0, nil
+	})
+	NewServer(&bytes.Buffer{}, slog.New(newRecordHandler()), false, WithMethod(count), WithMethod(hover))
+}
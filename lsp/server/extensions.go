@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"solbot/lsp"
+)
+
+// The solbot/ requests extend the protocol, so they have no capabilities;
+// the clients send them only to solbot.
+func init() {
+	features.register(feature{
+		method: "solbot/evaluatePath",
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.EvaluatePathRequest) {
+			response := s.state.EvaluatePath(request.ID, request.Params)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "solbot/contractSize",
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.ContractSizeRequest) {
+			response := s.state.ContractSize(request.ID, request.Params.TextDocument.URI)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "solbot/accessReport",
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.AccessReportRequest) {
+			response := s.state.AccessReport(request.ID, request.Params.TextDocument.URI)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "solbot/inventory",
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.InventoryRequest) {
+			response := s.state.InventoryReport(request.ID, request.Params)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "solbot/tests",
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.TestsRequest) {
+			response := s.state.TestsReport(request.ID, request.Params.TextDocument.URI)
+			s.respond(ctx, response)
+		}),
+	})
+}
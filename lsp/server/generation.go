@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"solbot/lsp"
+	"time"
+)
+
+// generation is the diagnostics of the documents affected by the edits up
+// to a revision of the workspace: the edited document and the open ones
+// importing it. They're published together once all of them are computed,
+// so the client never sees a document checked against the new version of
+// an interface while its importer still shows the diagnostics of the old
+// one.
+//
+// An edit made before the generation is published supersedes it: the new
+// generation takes over its documents, keeping the diagnostics already
+// computed for the ones the edit doesn't affect, and the diagnostics
+// computed for the superseded one later are dropped. A document analyzed
+// for longer than the holdback doesn't hold the others back: they're
+// published noting it's pending, and it's published on its own once it's
+// computed.
+type generation struct {
+	revision   int
+	uris       []string                                      // in the order of the imports
+	computed   map[string]lsp.PublishDiagnosticsNotification // URI -> diagnostics waiting for the rest of the generation
+	published  map[string]bool                               // URIs of the published diagnostics
+	flushed    bool                                          // published, as a whole or after the holdback
+	superseded bool
+	timer      *time.Timer // the holdback; or nil
+}
+
+// affected returns the document and the open documents importing it,
+// directly or not, in the order of the imports. The remappings of the
+// project files change how all of the imports resolve, so all of the open
+// documents are affected by their edits.
+func (s *Server) affected(uri string) []string {
+	uris := []string{uri}
+	if s.state.IsProjectFile(uri) {
+		open := []string{}
+		for _, doc := range s.state.Documents {
+			if doc.Open {
+				open = append(open, doc.URI)
+			}
+		}
+		slices.Sort(open)
+		return append(uris, open...)
+	}
+	for _, dependent := range s.state.ImportGraph().Dependents(uri) {
+		if doc := s.state.Documents[dependent]; doc != nil && doc.Open {
+			uris = append(uris, dependent)
+		}
+	}
+	return uris
+}
+
+// startGeneration starts the generation of the edit of the document, at the
+// next revision of the workspace, superseding the one not published yet. It
+// returns the documents whose diagnostics need to be computed.
+func (s *Server) startGeneration(ctx context.Context, uri string) (*generation, []string) {
+	affected := s.affected(uri)
+
+	s.genMu.Lock()
+	defer s.genMu.Unlock()
+	s.revision++
+	gen := &generation{
+		revision:  s.revision,
+		uris:      affected,
+		computed:  map[string]lsp.PublishDiagnosticsNotification{},
+		published: map[string]bool{},
+	}
+	if prev := s.generation; prev != nil && !prev.superseded {
+		prev.superseded = true
+		if prev.timer != nil {
+			prev.timer.Stop()
+		}
+		for _, uri := range prev.uris {
+			if prev.published[uri] || slices.Contains(affected, uri) {
+				continue
+			}
+			gen.uris = append(gen.uris, uri)
+			if notification, ok := prev.computed[uri]; ok {
+				gen.computed[uri] = notification
+			}
+		}
+	}
+	s.generation = gen
+	if s.holdback > 0 {
+		gen.timer = time.AfterFunc(s.holdback, func() { s.holdbackExpired(ctx, gen) })
+	}
+
+	missing := []string{}
+	for _, uri := range gen.uris {
+		if _, ok := gen.computed[uri]; !ok {
+			missing = append(missing, uri)
+		}
+	}
+	return gen, missing
+}
+
+// deliver adds the computed diagnostics of a document to the generation,
+// and publishes the generation once it's complete. The diagnostics of a
+// superseded generation are dropped, and the ones computed after the
+// holdback are published right away.
+func (s *Server) deliver(ctx context.Context, gen *generation, notification lsp.PublishDiagnosticsNotification) {
+	s.genMu.Lock()
+	defer s.genMu.Unlock()
+	uri := notification.Params.URI
+	switch {
+	case gen.superseded:
+		s.logger.InfoContext(ctx, "dropped the diagnostics of a superseded revision", "revision", gen.revision)
+	case gen.flushed:
+		gen.published[uri] = true
+		s.notify(ctx, tagged(notification, gen.revision, nil))
+	default:
+		gen.computed[uri] = notification
+		if len(gen.computed) == len(gen.uris) {
+			s.flush(ctx, gen)
+		}
+	}
+}
+
+// holdbackExpired publishes the diagnostics of the generation computed so
+// far, unless it's already published or superseded.
+func (s *Server) holdbackExpired(ctx context.Context, gen *generation) {
+	s.genMu.Lock()
+	defer s.genMu.Unlock()
+	if gen.flushed || gen.superseded {
+		return
+	}
+	s.logger.WarnContext(ctx, "published the diagnostics before the whole revision was analyzed",
+		"revision", gen.revision, "pending", len(gen.uris)-len(gen.computed))
+	s.flush(ctx, gen)
+}
+
+// flush publishes the computed diagnostics of the generation in the order
+// of the imports, tagged with the documents still pending. It's called with
+// the mutex held, so that the diagnostics computed later are published
+// after them.
+func (s *Server) flush(ctx context.Context, gen *generation) {
+	gen.flushed = true
+	if gen.timer != nil {
+		gen.timer.Stop()
+	}
+	var pending []string
+	for _, uri := range gen.uris {
+		if _, ok := gen.computed[uri]; !ok {
+			pending = append(pending, uri)
+		}
+	}
+	for _, uri := range gen.uris {
+		notification, ok := gen.computed[uri]
+		if !ok {
+			continue
+		}
+		gen.published[uri] = true
+		s.notify(ctx, tagged(notification, gen.revision, pending))
+	}
+	gen.computed = nil
+}
+
+// currentRevision returns the revision of the workspace the latest edit
+// raised it to.
+func (s *Server) currentRevision() int {
+	s.genMu.Lock()
+	defer s.genMu.Unlock()
+	return s.revision
+}
+
+// tagged returns the notification with its diagnostics tagged with the
+// revision and the documents of the revision still pending, keeping their
+// monikers. The diagnostics are copied, since the analysis caches them.
+func tagged(notification lsp.PublishDiagnosticsNotification, revision int, pending []string) lsp.PublishDiagnosticsNotification {
+	diagnostics := make([]lsp.Diagnostic, len(notification.Params.Diagnostics))
+	for i, d := range notification.Params.Diagnostics {
+		data := &lsp.DiagnosticData{Revision: revision, Pending: pending}
+		if d.Data != nil {
+			data.Moniker = d.Data.Moniker
+		}
+		d.Data = data
+		diagnostics[i] = d
+	}
+	notification.Params.Diagnostics = diagnostics
+	return notification
+}
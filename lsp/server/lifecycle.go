@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"solbot/lsp"
+	"solbot/lsp/replay"
+)
+
+func init() {
+	features.register(feature{
+		method: "initialize",
+		// The content is recorded once the options start the recording, so
+		// the request is decoded here.
+		handle: func(s *Server, ctx context.Context, content []byte) {
+			var request lsp.InitializeRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				s.logger.ErrorContext(ctx, "cannot decode the request", "error", err)
+				return
+			}
+
+			if request.Params.ClientInfo != nil {
+				s.logger.InfoContext(ctx, "connected", "client", request.Params.ClientInfo.Name, "version", request.Params.ClientInfo.Version)
+			}
+			if request.Params.Trace != "" {
+				s.trace = request.Params.Trace
+			}
+			if len(request.Params.InitializationOptions) > 0 {
+				recorded := s.recorder.Load() != nil
+				s.initializationOptions(ctx, request.Params.InitializationOptions)
+				if !recorded {
+					// The recording started by the options begins with them.
+					req, _ := requestFrom(ctx)
+					s.record(ctx, req.start, replay.Inbound, content)
+				}
+			}
+
+			s.root = s.state.Initialize(request.Params)
+			s.respond(ctx, lsp.NewInitializeResponse(request.ID, s.features.capabilities(request.Params.Capabilities)))
+		},
+	})
+	features.register(feature{
+		method:       "initialized",
+		notification: true,
+		handle: func(s *Server, ctx context.Context, content []byte) {
+			s.fetchSettings(ctx)
+			s.registerProjectFiles(ctx)
+			s.startWarmup(ctx)
+		},
+	})
+	// The server holds no resources to release, and it stops once the
+	// client closes the connection after the exit notification.
+	features.register(feature{
+		method: "shutdown",
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.ShutdownRequest) {
+			s.respond(ctx, lsp.NewShutdownResponse(request.ID))
+		}),
+	})
+	features.register(feature{
+		method:       "exit",
+		notification: true,
+		handle:       func(s *Server, ctx context.Context, content []byte) {},
+	})
+	features.register(feature{
+		method:       "$/setTrace",
+		notification: true,
+		handle: onNotification(func(s *Server, ctx context.Context, notification lsp.SetTraceNotification) {
+			s.trace = notification.Params.Value
+		}),
+	})
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type requestKey struct{}
+
+// request is the message being handled. It's stored in the context of all
+// of the work triggered by the message.
+type request struct {
+	id     int    // correlation ID, unique for the lifetime of the server
+	method string // e.g. "textDocument/hover"
+	uri    string // URI of the document the message is about; or empty
+	start  time.Time
+}
+
+func withRequest(ctx context.Context, req *request) context.Context {
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+func requestFrom(ctx context.Context) (*request, bool) {
+	req, ok := ctx.Value(requestKey{}).(*request)
+	return req, ok
+}
+
+// contextHandler adds the correlation ID, the method and the URI of the
+// request to every record logged with the request's context, so that the
+// interleaved lines can be grouped by the request that caused them.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if req, ok := requestFrom(ctx); ok {
+		r.AddAttrs(slog.Int("cid", req.id), slog.String("method", req.method))
+		if req.uri != "" {
+			r.AddAttrs(slog.String("uri", req.uri))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Latency sums up the time the responses to a method took, from the receipt
+// of the request to writing the response.
+type Latency struct {
+	Count int
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Mean returns the mean latency; or 0 if there were no responses.
+func (l Latency) Mean() time.Duration {
+	if l.Count == 0 {
+		return 0
+	}
+	return l.Total / time.Duration(l.Count)
+}
+
+func (s *Server) recordLatency(method string, duration time.Duration) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	l, ok := s.latencies[method]
+	if !ok {
+		l = &Latency{}
+		s.latencies[method] = l
+	}
+	l.Count++
+	l.Total += duration
+	l.Max = max(l.Max, duration)
+}
+
+// Latencies returns the latency of the responses, by the methods of the
+// requests. It's safe to call while the server is serving.
+func (s *Server) Latencies() map[string]Latency {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	res := make(map[string]Latency, len(s.latencies))
+	for method, l := range s.latencies {
+		res[method] = *l
+	}
+	return res
+}
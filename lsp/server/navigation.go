@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"solbot/lsp"
+)
+
+func init() {
+	features.register(feature{
+		method: "textDocument/hover",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.HoverProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.HoverRequest) {
+			response := s.state.Hover(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "textDocument/signatureHelp",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.SignatureHelpProvider = &lsp.SignatureHelpOptions{
+				TriggerCharacters: []string{"(", ","},
+			}
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.SignatureHelpRequest) {
+			response := s.state.SignatureHelp(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "textDocument/definition",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.DefinitionProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.DefinitionRequest) {
+			response := s.state.Definition(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "textDocument/completion",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.CompletionProvider = &lsp.CompletionOptions{
+				TriggerCharacters: []string{"."},
+			}
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.CompletionRequest) {
+			response := s.state.Completion(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "textDocument/inlayHint",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.InlayHintProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.InlayHintRequest) {
+			response := s.state.InlayHint(request.ID, request.Params.TextDocument.URI, request.Params.Range)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "textDocument/documentSymbol",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.DocumentSymbolProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.DocumentSymbolRequest) {
+			response := s.state.DocumentSymbol(request.ID, request.Params.TextDocument.URI)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "workspace/symbol",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.WorkspaceSymbolProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.WorkspaceSymbolRequest) {
+			response := s.state.WorkspaceSymbol(request.ID, request.Params.Query)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "textDocument/moniker",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.MonikerProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.MonikerRequest) {
+			response := s.state.Moniker(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "textDocument/references",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.ReferencesProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.ReferencesRequest) {
+			response := s.state.References(request.ID, request.Params.TextDocument.URI, request.Params.Position, request.Params.Context)
+			s.respond(ctx, response)
+		}),
+	})
+	features.register(feature{
+		method: "textDocument/rename",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.RenameProvider = true
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.RenameRequest) {
+			response := s.state.Rename(request.ID, request.Params.TextDocument.URI, request.Params.Position, request.Params.NewName)
+			s.respond(ctx, response)
+		}),
+	})
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"runtime/debug"
+	"slices"
+	"solbot/lsp"
+)
+
+// feature is a method the server handles. Every feature registers itself
+// with the registry from the init function of its file, so the capabilities
+// advertised in the initialize response and the routing of the messages
+// can't drift apart.
+type feature struct {
+	method       string
+	notification bool // is it a notification, which gets no response?
+
+	// requires reports whether the client supports the feature, given its
+	// capabilities; or nil if every client does. The capability of the
+	// feature is advertised only to the clients supporting it.
+	requires func(client lsp.ClientCapabilities) bool
+	// capability adds the part of the server capabilities advertising the
+	// feature; or nil if the protocol doesn't require one e.g. for the
+	// lifecycle messages and the solbot/ extensions.
+	capability func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities)
+	handle     func(s *Server, ctx context.Context, content []byte)
+}
+
+// registry holds the features by their methods.
+type registry struct {
+	features map[string]*feature
+	methods  []string // in the order of the registration
+
+	// contributions add the capabilities of the embedders after the ones
+	// of the features, see WithCapabilities.
+	contributions []func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities)
+}
+
+// features are the built-in features, registered by the init functions.
+// Every server starts with a copy of them, see NewServer.
+var features = &registry{features: map[string]*feature{}}
+
+// clone returns a copy of the registry the features can be added to.
+func (r *registry) clone() *registry {
+	return &registry{
+		features:      maps.Clone(r.features),
+		methods:       slices.Clone(r.methods),
+		contributions: slices.Clone(r.contributions),
+	}
+}
+
+// register adds the feature. Registering a method twice is a programming
+// error.
+func (r *registry) register(f feature) {
+	if _, ok := r.features[f.method]; ok {
+		panic(fmt.Sprintf("the method %s is already registered", f.method))
+	}
+	r.features[f.method] = &f
+	r.methods = append(r.methods, f.method)
+}
+
+// lookup returns the feature handling the method; or nil if there is none.
+func (r *registry) lookup(method string) *feature {
+	return r.features[method]
+}
+
+// capabilities composes the server capabilities from the features the
+// client supports.
+func (r *registry) capabilities(client lsp.ClientCapabilities) lsp.ServerCapabilities {
+	caps := lsp.ServerCapabilities{}
+	for _, method := range r.methods {
+		f := r.features[method]
+		if f.capability == nil || f.requires != nil && !f.requires(client) {
+			continue
+		}
+		f.capability(&caps, client)
+	}
+	for _, contribute := range r.contributions {
+		contribute(&caps, client)
+	}
+	return caps
+}
+
+// onRequest returns the handler decoding the request before handling it.
+// The request which can't be decoded gets the InvalidParams error, since
+// the client waits for the response.
+func onRequest[R any](handle func(s *Server, ctx context.Context, request R)) func(s *Server, ctx context.Context, content []byte) {
+	return func(s *Server, ctx context.Context, content []byte) {
+		var request R
+		if err := json.Unmarshal(content, &request); err != nil {
+			s.logger.ErrorContext(ctx, "cannot decode the request", "error", err)
+			var header lsp.Request
+			if json.Unmarshal(content, &header) == nil {
+				s.respond(ctx, lsp.NewErrorResponse(header.ID, lsp.InvalidParams, fmt.Sprintf("cannot decode the request: %s", err)))
+			}
+			return
+		}
+		handle(s, ctx, request)
+	}
+}
+
+// onNotification returns the handler decoding the notification before
+// handling it.
+func onNotification[N any](handle func(s *Server, ctx context.Context, notification N)) func(s *Server, ctx context.Context, content []byte) {
+	return func(s *Server, ctx context.Context, content []byte) {
+		var notification N
+		if err := json.Unmarshal(content, &notification); err != nil {
+			s.logger.ErrorContext(ctx, "cannot decode the notification", "error", err)
+			return
+		}
+		handle(s, ctx, notification)
+	}
+}
+
+// dispatch routes the message to the feature handling its method. The
+// requests of the methods without one get the MethodNotFound error, and so
+// do the requests sent with the method of a notification; the notifications
+// without a feature are ignored, like $/cancelRequest, and so are the ones
+// sent with the method of a request, since they can't be answered.
+//
+// A handler panicking is logged with its stack, and its request gets the
+// InternalError, so a bug in one feature doesn't stop the server.
+func (s *Server) dispatch(ctx context.Context, method string, id *lsp.ID, content []byte) {
+	f := s.features.lookup(method)
+	switch {
+	case f == nil && id != nil:
+		s.respond(ctx, lsp.NewErrorResponse(*id, lsp.MethodNotFound, fmt.Sprintf("unhandled method %s", method)))
+	case f == nil:
+		s.logger.DebugContext(ctx, "ignored the notification")
+	case f.notification && id != nil:
+		s.respond(ctx, lsp.NewErrorResponse(*id, lsp.MethodNotFound, fmt.Sprintf("%s is a notification, not a request", method)))
+	case !f.notification && id == nil:
+		s.logger.WarnContext(ctx, "ignored the request without an ID")
+	default:
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			s.logger.ErrorContext(ctx, "the handler panicked", "panic", r, "stack", string(debug.Stack()))
+			if id != nil {
+				s.respond(ctx, lsp.NewErrorResponse(*id, lsp.InternalError, fmt.Sprintf("%s failed: %v", method, r)))
+			}
+		}()
+		f.handle(s, ctx, content)
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"solbot/lsp"
+	"strings"
+	"testing"
+)
+
+// advertised reports whether the capabilities advertise the method, for
+// the methods the protocol requires a capability of.
+var advertised = map[string]func(caps lsp.ServerCapabilities) bool{
+	"textDocument/didOpen":        func(caps lsp.ServerCapabilities) bool { return caps.TextDocumentSync != 0 },
+	"textDocument/didChange":      func(caps lsp.ServerCapabilities) bool { return caps.TextDocumentSync != 0 },
+	"textDocument/hover":          func(caps lsp.ServerCapabilities) bool { return caps.HoverProvider },
+	"textDocument/signatureHelp":  func(caps lsp.ServerCapabilities) bool { return caps.SignatureHelpProvider != nil },
+	"textDocument/definition":     func(caps lsp.ServerCapabilities) bool { return caps.DefinitionProvider },
+	"textDocument/completion":     func(caps lsp.ServerCapabilities) bool { return caps.CompletionProvider != nil },
+	"textDocument/inlayHint":      func(caps lsp.ServerCapabilities) bool { return caps.InlayHintProvider },
+	"textDocument/documentSymbol": func(caps lsp.ServerCapabilities) bool { return caps.DocumentSymbolProvider },
+	"workspace/symbol":            func(caps lsp.ServerCapabilities) bool { return caps.WorkspaceSymbolProvider },
+	"textDocument/moniker":        func(caps lsp.ServerCapabilities) bool { return caps.MonikerProvider },
+	"textDocument/references":     func(caps lsp.ServerCapabilities) bool { return caps.ReferencesProvider },
+	"textDocument/rename":         func(caps lsp.ServerCapabilities) bool { return caps.RenameProvider },
+	"textDocument/codeAction":     func(caps lsp.ServerCapabilities) bool { return caps.CodeActionProvider != nil },
+	"codeAction/resolve": func(caps lsp.ServerCapabilities) bool {
+		return caps.CodeActionProvider != nil && caps.CodeActionProvider.ResolveProvider
+	},
+	"textDocument/codeLens": func(caps lsp.ServerCapabilities) bool { return caps.CodeLensProvider != nil },
+	"codeLens/resolve": func(caps lsp.ServerCapabilities) bool {
+		return caps.CodeLensProvider != nil && caps.CodeLensProvider.ResolveProvider
+	},
+	"workspace/executeCommand": func(caps lsp.ServerCapabilities) bool { return caps.ExecuteCommandProvider != nil },
+	"textDocument/diagnostic":  func(caps lsp.ServerCapabilities) bool { return caps.DiagnosticProvider != nil },
+	"workspace/diagnostic": func(caps lsp.ServerCapabilities) bool {
+		return caps.DiagnosticProvider != nil && caps.DiagnosticProvider.WorkspaceDiagnostics
+	},
+	"workspace/willRenameFiles": func(caps lsp.ServerCapabilities) bool {
+		return caps.Workspace != nil && caps.Workspace.FileOperations != nil && caps.Workspace.FileOperations.WillRename != nil
+	},
+	"workspace/didRenameFiles": func(caps lsp.ServerCapabilities) bool {
+		return caps.Workspace != nil && caps.Workspace.FileOperations != nil && caps.Workspace.FileOperations.DidRename != nil
+	},
+}
+
+// unadvertised are the methods the protocol requires no capability of.
+var unadvertised = map[string]bool{
+	"initialize":                       true,
+	"initialized":                      true,
+	"shutdown":                         true,
+	"exit":                             true,
+	"$/setTrace":                       true,
+	"workspace/didChangeConfiguration": true,
+	"workspace/didChangeWatchedFiles":  true,
+}
+
+func Test_RegistryInvariant(t *testing.T) {
+	for _, method := range features.methods {
+		f := features.lookup(method)
+		if f.handle == nil {
+			t.Errorf("Expected a handler of %s, got none", method)
+		}
+		if unadvertised[method] || strings.HasPrefix(method, "solbot/") {
+			if f.capability != nil {
+				t.Errorf("Expected no capability of %s, got one", method)
+			}
+			continue
+		}
+		isAdvertised, ok := advertised[method]
+		if !ok {
+			t.Errorf("Expected %s to be listed as advertised or not", method)
+			continue
+		}
+		if f.capability == nil {
+			t.Errorf("Expected a capability of %s, got none", method)
+			continue
+		}
+		// Its own contribution advertises the feature.
+		caps := lsp.ServerCapabilities{}
+		f.capability(&caps, lsp.ClientCapabilities{})
+		if !isAdvertised(caps) {
+			t.Errorf("Expected the capability of %s to advertise it, got %+v", method, caps)
+		}
+	}
+	// No capability is advertised without a handler.
+	caps := features.capabilities(lsp.ClientCapabilities{})
+	for method, isAdvertised := range advertised {
+		if features.lookup(method) == nil && isAdvertised(caps) {
+			t.Errorf("Expected no capability of %s, which has no handler", method)
+		}
+	}
+}
+
+func Test_RegistryCapabilities(t *testing.T) {
+	expected := lsp.ServerCapabilities{
+		TextDocumentSync:   1,
+		HoverProvider:      true,
+		DefinitionProvider: true,
+		CodeActionProvider: &lsp.CodeActionOptions{
+			CodeActionKinds: []lsp.CodeActionKind{lsp.CodeActionQuickFix, lsp.CodeActionRefactor, lsp.CodeActionSourceOrganizeImports},
+			ResolveProvider: true,
+		},
+		CodeLensProvider:        &lsp.CodeLensOptions{ResolveProvider: true},
+		RenameProvider:          true,
+		InlayHintProvider:       true,
+		ReferencesProvider:      true,
+		DocumentSymbolProvider:  true,
+		WorkspaceSymbolProvider: true,
+		MonikerProvider:         true,
+		CompletionProvider:      &lsp.CompletionOptions{TriggerCharacters: []string{"."}},
+		SignatureHelpProvider:   &lsp.SignatureHelpOptions{TriggerCharacters: []string{"(", ","}},
+		ExecuteCommandProvider:  &lsp.ExecuteCommandOptions{Commands: []string{lsp.PreviewMigrationCommand}},
+		DiagnosticProvider:      &lsp.DiagnosticOptions{InterFileDependencies: true, WorkspaceDiagnostics: true},
+		Workspace: &lsp.WorkspaceServerCapabilities{
+			FileOperations: &lsp.FileOperationOptions{DidRename: lsp.SolidityFiles, WillRename: lsp.SolidityFiles},
+		},
+	}
+	want, _ := json.Marshal(expected)
+	got, _ := json.Marshal(features.capabilities(lsp.ClientCapabilities{}))
+	if string(got) != string(want) {
+		t.Errorf("Expected the capabilities %s, got %s", want, got)
+	}
+}
+
+func Test_RegistryRequires(t *testing.T) {
+	r := &registry{features: map[string]*feature{}}
+	noop := func(s *Server, ctx context.Context, content []byte) {}
+	r.register(feature{
+		method: "textDocument/hover",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.HoverProvider = true
+		},
+		handle: noop,
+	})
+	r.register(feature{
+		method: "textDocument/diagnostic",
+		requires: func(client lsp.ClientCapabilities) bool {
+			return client.TextDocument != nil && client.TextDocument.Diagnostic != nil
+		},
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.DiagnosticProvider = &lsp.DiagnosticOptions{}
+		},
+		handle: noop,
+	})
+
+	caps := r.capabilities(lsp.ClientCapabilities{})
+	if !caps.HoverProvider || caps.DiagnosticProvider != nil {
+		t.Errorf("Expected the hover alone to be advertised, got %+v", caps)
+	}
+	caps = r.capabilities(lsp.ClientCapabilities{TextDocument: &lsp.TextDocumentClientCapabilities{Diagnostic: &lsp.DiagnosticClientCapabilities{}}})
+	if !caps.HoverProvider || caps.DiagnosticProvider == nil {
+		t.Errorf("Expected the hover and the diagnostics to be advertised, got %+v", caps)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering the hover twice to panic")
+		}
+	}()
+	r.register(feature{method: "textDocument/hover", handle: noop})
+}
+
+func Test_DispatchRouting(t *testing.T) {
+	tests := []struct {
+		method   string
+		content  string
+		expected string // the response; or empty if there is none
+	}{
+		{
+			"textDocument/unknown",
+			`{"jsonrpc":"2.0","id":1,"method":"textDocument/unknown","params":{}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"unhandled method textDocument/unknown"}}`,
+		},
+		{
+			"$/cancelRequest",
+			`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":1}}`,
+			"",
+		},
+		{
+			"textDocument/didOpen",
+			`{"jsonrpc":"2.0","id":2,"method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///ws/Vault.sol","version":1,"text":""}}}`,
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"textDocument/didOpen is a notification, not a request"}}`,
+		},
+		{
+			"textDocument/hover",
+			`{"jsonrpc":"2.0","method":"textDocument/hover","params":{"textDocument":{"uri":"file:///ws/Vault.sol"},"position":{"line":0,"character":0}}}`,
+			"",
+		},
+		{
+			"shutdown",
+			`{"jsonrpc":"2.0","id":3,"method":"shutdown"}`,
+			`{"jsonrpc":"2.0","id":3,"result":null}`,
+		},
+		{
+			"exit",
+			`{"jsonrpc":"2.0","method":"exit"}`,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			var output bytes.Buffer
+			s := NewServer(&output, slog.New(newRecordHandler()), false)
+			s.Handle(tt.method, []byte(tt.content))
+
+			got := ""
+			if _, content, ok := strings.Cut(output.String(), "\r\n\r\n"); ok {
+				got = content
+			}
+			if got != tt.expected {
+				t.Errorf("Expected the response %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"solbot/lsp/analysis"
+	"sync"
+)
+
+// interactiveMethods are the requests the user waits for while typing or
+// moving the cursor. They're cheap and only read the state, so they run at
+// the checkpoints of the background work instead of waiting for its end.
+var interactiveMethods = map[string]bool{
+	"textDocument/hover":                true,
+	"textDocument/completion":           true,
+	"textDocument/signatureHelp":        true,
+	"textDocument/definition":           true,
+	"textDocument/documentHighlight":    true,
+	"textDocument/semanticTokens/range": true,
+}
+
+// scheduler runs the work of the server on a single worker goroutine, since
+// the analysis state is not safe for concurrent use. It has two lanes:
+//
+//   - the messages, handled in the order they arrive, before any background
+//     task is started;
+//   - the background tasks e.g. indexing the workspace or computing the
+//     diagnostics, started in the order they were scheduled once no message
+//     is waiting.
+//
+// A running task calls analysis.Checkpoint in its long loops. At a
+// checkpoint the interactive requests at the head of the messages run right
+// away, so a hover doesn't wait for the whole workspace to be analyzed. The
+// other messages wait for the task to end, since they may change the state
+// the task is reading.
+type scheduler struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	messages []job
+	tasks    []*task
+	running  *task // the background task being run; or nil
+	closed   bool
+	done     chan struct{} // closed when the worker returns
+
+	// interactive are the methods run at the checkpoints, see
+	// interactiveMethods and Method.Interactive.
+	interactive map[string]bool
+
+	// observe is called on the scheduling events, for the tests: "run" and
+	// "preempt" with the method of a message run in order or at a
+	// checkpoint, "start", "end" and "cancel" with the key of a task.
+	observe func(event, name string)
+}
+
+// job is a message waiting to be handled.
+type job struct {
+	method      string
+	interactive bool
+	run         func()
+}
+
+// task is a background task. A task scheduled with the key of a waiting one
+// replaces it.
+type task struct {
+	key    string
+	ctx    context.Context
+	cancel context.CancelFunc
+	run    func(ctx context.Context)
+}
+
+func newScheduler() *scheduler {
+	sc := &scheduler{done: make(chan struct{}), interactive: interactiveMethods}
+	sc.cond = sync.NewCond(&sc.mu)
+	return sc
+}
+
+// enqueue adds the message to be handled after the ones already waiting.
+func (sc *scheduler) enqueue(method string, run func()) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.messages = append(sc.messages, job{method: method, interactive: sc.interactive[method], run: run})
+	sc.cond.Signal()
+}
+
+// schedule adds the background task, replacing the waiting task with the
+// same key. Its context is cancelled by cancel, and the long loops of the
+// analysis yield to the interactive requests through it.
+func (sc *scheduler) schedule(ctx context.Context, key string, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(ctx)
+	t := &task{key: key, cancel: cancel, run: run}
+	t.ctx = analysis.WithCheckpoint(ctx, sc.yield)
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.tasks = slices.DeleteFunc(sc.tasks, func(waiting *task) bool {
+		if waiting.key != key {
+			return false
+		}
+		waiting.cancel()
+		return true
+	})
+	sc.tasks = append(sc.tasks, t)
+	sc.cond.Signal()
+}
+
+// cancel cancels the task with the key, whether it's running or waiting
+// e.g. the diagnostics of a document edited again.
+func (sc *scheduler) cancel(key string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.tasks = slices.DeleteFunc(sc.tasks, func(waiting *task) bool {
+		if waiting.key != key {
+			return false
+		}
+		waiting.cancel()
+		return true
+	})
+	if sc.running != nil && sc.running.key == key {
+		sc.running.cancel()
+		sc.notify("cancel", key)
+	}
+}
+
+// close makes the worker return once the waiting work is done.
+func (sc *scheduler) close() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.closed = true
+	sc.cond.Signal()
+}
+
+// work runs the messages and the tasks until the scheduler is closed and
+// nothing is waiting.
+func (sc *scheduler) work() {
+	defer close(sc.done)
+	sc.mu.Lock()
+	for {
+		for len(sc.messages) == 0 && len(sc.tasks) == 0 && !sc.closed {
+			sc.cond.Wait()
+		}
+		switch {
+		case len(sc.messages) > 0:
+			j := sc.messages[0]
+			sc.messages = sc.messages[1:]
+			sc.notify("run", j.method)
+			sc.mu.Unlock()
+			j.run()
+			sc.mu.Lock()
+		case len(sc.tasks) > 0:
+			t := sc.tasks[0]
+			sc.tasks = sc.tasks[1:]
+			sc.running = t
+			sc.notify("start", t.key)
+			sc.mu.Unlock()
+			t.run(t.ctx)
+			t.cancel()
+			sc.mu.Lock()
+			sc.running = nil
+			sc.notify("end", t.key)
+		default:
+			sc.mu.Unlock()
+			return
+		}
+	}
+}
+
+// yield is the checkpoint of the running task: it runs the interactive
+// requests waiting at the head of the messages, then reports whether the
+// task was cancelled.
+func (sc *scheduler) yield(ctx context.Context) error {
+	for {
+		sc.mu.Lock()
+		if len(sc.messages) == 0 || !sc.messages[0].interactive {
+			sc.mu.Unlock()
+			return ctx.Err()
+		}
+		j := sc.messages[0]
+		sc.messages = sc.messages[1:]
+		sc.notify("preempt", j.method)
+		sc.mu.Unlock()
+		j.run()
+	}
+}
+
+// preempting reports whether a message is being run at a checkpoint of the
+// running task. It's only called by the worker.
+func (sc *scheduler) preempting() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.running != nil
+}
+
+// notify calls the observer, with the mutex held.
+func (sc *scheduler) notify(event, name string) {
+	if sc.observe != nil {
+		sc.observe(event, name)
+	}
+}
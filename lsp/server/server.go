@@ -0,0 +1,808 @@
+// server implements the language server: it reads the messages sent by the
+// client, dispatches them to the analysis and writes the responses back.
+//
+// Every message gets a correlation ID which is attached to the context of
+// the work it triggers. All of the lines logged with that context carry the
+// ID, the method and the URI of the document, see contextHandler.
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"solbot/lsp"
+	"solbot/lsp/analysis"
+	"solbot/lsp/replay"
+	"solbot/lsp/rpc"
+	"solbot/parser"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Server struct {
+	state         *analysis.State
+	features      *registry // the built-in features and the methods of the embedders
+	writer        io.Writer
+	logger        *slog.Logger
+	logPayloads   bool            // log the full content of every message, see --trace
+	trace         string          // trace setting of the client, see $/setTrace
+	lastID        int             // correlation ID of the last message
+	ignoredFields map[string]bool // unknown fields of the messages, logged once
+	limits        Limits
+	splitter      *rpc.Splitter // splits the messages being served; or nil
+	ceiling       int           // message size the buffer of Serve is bounded by; or 0 if it's not
+
+	// The messages are read on the goroutine of Serve and handled on the
+	// worker of the scheduler, which the limit of the splitter is passed
+	// from. The messages given to Handle are handled right away.
+	scheduler      *scheduler
+	serving        bool
+	maxMessageSize atomic.Int64
+
+	statsMu   sync.Mutex
+	latencies map[string]*Latency // method -> latency of the responses, see Latencies
+
+	recorder atomic.Pointer[replay.Recorder] // records the session; or nil, see SetRecorder
+
+	// The workspace is indexed by the warmup once the client is
+	// initialized, with the files the requests need promoted, see promote.
+	root      string           // root directory of the workspace; or empty if no folder is open
+	openFiles []string         // URIs of the files listed as open in the initialization options
+	warmup    *analysis.Warmup // or nil until the client is initialized
+
+	// The code lenses and the pulled diagnostics are refreshed from a
+	// timer, so the writes and the requests sent to the client are guarded.
+	mu           sync.Mutex
+	lastSentID   int               // ID of the last request sent to the client
+	pending      map[lsp.ID]string // ID -> method of the requests sent to the client, until they're answered
+	refresh      *time.Timer       // pending workspace/codeLens/refresh; or nil
+	refreshPull  *time.Timer       // pending workspace/diagnostic/refresh; or nil
+	refreshDelay time.Duration     // quiet period before the lenses or the pulled diagnostics are refreshed
+
+	// The diagnostics of the documents affected by an edit are published
+	// together, see generation. They're published from the timer of the
+	// holdback too, so the generations are guarded.
+	genMu      sync.Mutex
+	revision   int              // revision of the workspace, raised by every edit
+	generation *generation      // the latest generation; or nil
+	holdback   time.Duration    // how long the computed diagnostics wait for the rest of their generation
+	analyzed   func(uri string) // called once the diagnostics of a document are computed, for the tests
+}
+
+// Limits bound the resources a misbehaving client can make the server use.
+// A zero limit is no limit. They are set with the flags of the lsp command
+// and with the initialization options of the client, see SetLimits.
+type Limits struct {
+	MaxMessageSize  int           // largest content of a message in bytes; the larger ones are skipped
+	MaxDocumentSize int           // size in bytes above which a document is stored, but not analyzed
+	MaxParsedSize   int           // total size in bytes of the parsed documents, see analysis.State.Unload
+	MaxLineLength   int           // length in bytes of the lines above which the inlay hints are skipped
+	ReadTimeout     time.Duration // how long the rest of a started message can take to arrive over TCP
+	Parser          parser.Limits // bounds of the parsing of a document, see analysis.Limits
+}
+
+// DefaultLimits returns the limits used unless they're configured.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxMessageSize:  rpc.DefaultMaxMessageSize,
+		MaxDocumentSize: analysis.DefaultMaxDocumentSize,
+		MaxParsedSize:   analysis.DefaultMaxParsedSize,
+		MaxLineLength:   analysis.DefaultMaxLineLength,
+		ReadTimeout:     30 * time.Second,
+		Parser:          analysis.DefaultParserLimits(),
+	}
+}
+
+// initializationOptions are the settings the client sends with the
+// initialize request. The missing ones keep their values.
+type initializationOptions struct {
+	MaxMessageSize  *int `json:"maxMessageSize"`
+	MaxDocumentSize *int `json:"maxDocumentSize"`
+	MaxParsedSize   *int `json:"maxParsedSize"`
+	MaxLineLength   *int `json:"maxLineLength"`
+	ReadTimeout     *int `json:"readTimeout"` // in milliseconds
+
+	// The limits of the parser, see parser.Limits.
+	MaxExpressionDepth *int `json:"maxExpressionDepth"`
+	MaxStatementDepth  *int `json:"maxStatementDepth"`
+	MaxDeclarations    *int `json:"maxDeclarations"`
+	MaxNodes           *int `json:"maxNodes"`
+	ParseTimeout       *int `json:"parseTimeout"` // in milliseconds
+
+	// Record is the file the session is recorded to, unless it's already
+	// recorded with the --record flag; Redact hashes the documents in it.
+	Record string `json:"record"`
+	Redact bool   `json:"redact"`
+
+	// OpenFiles are the URIs of the files open in the editor, indexed
+	// first by the warmup.
+	OpenFiles []string `json:"openFiles"`
+}
+
+// NewServer returns the server writing its messages to the writer. Only the
+// metadata of the messages is logged, unless logPayloads is set or the
+// client turns the trace on. The options add the methods of the embedders,
+// see WithMethod.
+func NewServer(writer io.Writer, logger *slog.Logger, logPayloads bool, options ...Option) *Server {
+	logger = slog.New(contextHandler{logger.Handler()})
+	state := analysis.NewState()
+	state.Logger = logger
+	s := &Server{
+		state:         state,
+		writer:        writer,
+		logger:        logger,
+		logPayloads:   logPayloads,
+		trace:         lsp.TraceOff,
+		pending:       map[lsp.ID]string{},
+		ignoredFields: map[string]bool{},
+		refreshDelay:  500 * time.Millisecond,
+		holdback:      2 * time.Second,
+		scheduler:     newScheduler(),
+		latencies:     map[string]*Latency{},
+	}
+	s.extend(options)
+	s.SetLimits(DefaultLimits())
+	return s
+}
+
+// SetLimits replaces the limits. The message size can't be raised above the
+// one the messages are being served with, since the buffer is bounded by it.
+func (s *Server) SetLimits(limits Limits) {
+	if s.ceiling > 0 && (limits.MaxMessageSize <= 0 || limits.MaxMessageSize > s.ceiling) {
+		limits.MaxMessageSize = s.ceiling
+	}
+	s.maxMessageSize.Store(int64(limits.MaxMessageSize))
+	s.limits = limits
+	s.state.Limits = analysis.Limits{MaxDocumentSize: limits.MaxDocumentSize, MaxParsedSize: limits.MaxParsedSize, MaxLineLength: limits.MaxLineLength, Parser: limits.Parser}
+}
+
+// SetRecorder records the messages of the session from now on, see the
+// replay package.
+func (s *Server) SetRecorder(recorder *replay.Recorder) {
+	s.recorder.Store(recorder)
+}
+
+// record appends the message to the recording of the session, if it's
+// recorded.
+func (s *Server) record(ctx context.Context, t time.Time, direction replay.Direction, content []byte) {
+	recorder := s.recorder.Load()
+	if recorder == nil {
+		return
+	}
+	if err := recorder.Record(t, direction, content); err != nil {
+		s.logger.ErrorContext(ctx, "cannot record the message", "error", err)
+	}
+}
+
+// Serve handles the messages read from the reader until it's closed, and
+// returns once the work they triggered is done. The messages larger than
+// the limit are skipped: the requests get an error response and the
+// notifications are reported to the user. If the reader supports the
+// deadlines, like a TCP connection, the rest of a started message has to
+// arrive within the read timeout.
+//
+// The messages are handled by the scheduler, so that the interactive
+// requests are answered while the background work runs, see scheduler.
+func (s *Server) Serve(reader io.Reader) error {
+	s.splitter = &rpc.Splitter{MaxSize: s.limits.MaxMessageSize}
+	if conn, ok := reader.(deadliner); ok && s.limits.ReadTimeout > 0 {
+		reader = &deadlineReader{conn: conn, timeout: s.limits.ReadTimeout, splitter: s.splitter}
+	}
+	// The default buffer of the scanner holds 64KB, less than a didOpen of
+	// a large file, while the splitter never buffers more than the limit.
+	s.ceiling = s.limits.MaxMessageSize
+	maxBuffer := math.MaxInt
+	if s.ceiling > 0 {
+		maxBuffer = s.ceiling + rpc.MaxHeaderSize
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 4096), maxBuffer)
+	scanner.Split(s.splitter.Split)
+
+	s.serving = true
+	go s.scheduler.work()
+	defer func() {
+		s.scheduler.close()
+		<-s.scheduler.done
+	}()
+	for {
+		s.splitter.MaxSize = int(s.maxMessageSize.Load())
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		received := time.Now()
+		if skipped := s.splitter.Skipped(); skipped != nil {
+			s.scheduler.enqueue(skipped.Method, func() { s.skip(skipped) })
+			continue
+		}
+		// The buffer of the scanner is reused by the next message, and the
+		// batch is decoded from it.
+		messages, isBatch, err := rpc.DecodeBatch(scanner.Bytes())
+		if err != nil {
+			s.logger.Error("cannot decode the batch", "error", err)
+			continue
+		}
+		if isBatch {
+			for _, m := range messages {
+				s.cancelOutdated(m.Method, m.Content)
+			}
+			s.scheduler.enqueue("", func() { s.handleBatch(messages, received) })
+			continue
+		}
+		method, content, err := rpc.DecodeMessage(scanner.Bytes())
+		if err != nil {
+			s.logger.Error("cannot decode the message", "error", err)
+			continue
+		}
+		content = bytes.Clone(content)
+		s.cancelOutdated(method, content)
+		s.scheduler.enqueue(method, func() { s.handle(context.Background(), method, content, received) })
+	}
+}
+
+// cancelOutdated cancels the diagnostics of the document the edit makes
+// out of date.
+func (s *Server) cancelOutdated(method string, content []byte) {
+	if method == "textDocument/didChange" || method == "textDocument/didClose" {
+		s.scheduler.cancel(diagnosticsKey(documentURI(content)))
+	}
+}
+
+type batchKey struct{}
+
+// batch collects the responses to the requests of a batch, which are sent
+// back together as a batch once all of its messages are handled.
+type batch struct {
+	responses []json.RawMessage
+	sent      bool
+}
+
+// handleBatch handles the messages of the batch in order. The notifications
+// triggered by them are sent right away, like the ones of the single
+// messages.
+func (s *Server) handleBatch(messages []rpc.Message, received time.Time) {
+	b := &batch{}
+	ctx := context.WithValue(context.Background(), batchKey{}, b)
+	for _, m := range messages {
+		s.handle(ctx, m.Method, m.Content, received)
+	}
+	b.sent = true
+	if len(b.responses) > 0 {
+		s.write(ctx, b.responses)
+	}
+}
+
+// skip answers the message larger than the limit. The requests must be
+// answered, while the notifications, like didOpen of a huge file, are
+// lost, so the user is told about them.
+func (s *Server) skip(skipped *rpc.Oversized) {
+	s.lastID++
+	req := &request{id: s.lastID, method: skipped.Method, start: time.Now()}
+	ctx := withRequest(context.Background(), req)
+	s.logger.WarnContext(ctx, "skipped an oversized message", "length", skipped.Length, "limit", s.limits.MaxMessageSize)
+
+	message := fmt.Sprintf("The message has %d bytes, more than the limit of %d bytes", skipped.Length, s.limits.MaxMessageSize)
+	if skipped.ID != nil {
+		s.respond(ctx, lsp.NewErrorResponse(*skipped.ID, lsp.InvalidRequest, message))
+		return
+	}
+	method := "a message"
+	if skipped.Method != "" {
+		method = "`" + skipped.Method + "`"
+	}
+	s.notify(ctx, lsp.NewShowMessageNotification(lsp.MessageWarning,
+		fmt.Sprintf("solbot skipped %s of %d bytes, more than the limit of %d bytes; "+
+			"raise maxMessageSize in the initialization options or --max-message-size", method, skipped.Length, s.limits.MaxMessageSize)))
+}
+
+// Handle handles a single message, a request or a notification, together
+// with the background work it triggers.
+func (s *Server) Handle(method string, content []byte) {
+	s.handle(context.Background(), method, content, time.Now())
+}
+
+// message holds the fields common to all of the messages, the errors are
+// reported by the handlers.
+type message struct {
+	ID     *lsp.ID `json:"id"`
+	Params struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	} `json:"params"`
+}
+
+// documentURI returns the URI of the document the message is about; or an
+// empty string.
+func documentURI(content []byte) string {
+	var message message
+	_ = json.Unmarshal(content, &message)
+	return message.Params.TextDocument.URI
+}
+
+// handle handles the message received at the time, which the latency of
+// the response is measured from. The message is handled in the context of
+// its batch, if it's a part of one.
+func (s *Server) handle(parent context.Context, method string, content []byte, received time.Time) {
+	var message message
+	_ = json.Unmarshal(content, &message)
+
+	s.lastID++
+	req := &request{id: s.lastID, method: method, uri: message.Params.TextDocument.URI, start: received}
+	ctx := withRequest(parent, req)
+
+	s.record(ctx, received, replay.Inbound, content)
+	s.logger.InfoContext(ctx, "received", s.payload(content)...)
+	if normalized, unknown, err := rpc.Normalize(content); err == nil {
+		content = normalized
+		s.ignoreFields(ctx, unknown)
+	}
+	response := method == "" && message.ID != nil
+	switch {
+	case response:
+		s.logTrace(ctx, fmt.Sprintf("Received response '%s'.", message.ID), content)
+	case message.ID != nil:
+		s.logTrace(ctx, fmt.Sprintf("Received request '%s - (%s)'.", method, message.ID), content)
+	default:
+		s.logTrace(ctx, fmt.Sprintf("Received notification '%s'.", method), content)
+	}
+
+	switch {
+	case response:
+		s.handleResponse(ctx, *message.ID, content)
+	case message.ID != nil:
+		s.promote(ctx, method, req.uri)
+		s.dispatch(ctx, method, message.ID, content)
+	default:
+		s.dispatch(ctx, method, nil, content)
+	}
+	// The syntax trees the preempted task is using are unloaded once it
+	// ends, see background.
+	if !s.scheduler.preempting() {
+		s.state.Unload()
+	}
+
+	if message.ID == nil || response {
+		s.logger.InfoContext(ctx, "handled", "duration", time.Since(req.start))
+	}
+}
+
+// ignoreFields logs the top-level fields of a message JSON-RPC doesn't
+// define, once per field name, since a client sends them with every
+// message.
+func (s *Server) ignoreFields(ctx context.Context, fields []string) {
+	for _, field := range fields {
+		if s.ignoredFields[field] {
+			continue
+		}
+		s.ignoredFields[field] = true
+		s.logger.WarnContext(ctx, "ignored an unknown field of the message", "field", field)
+	}
+}
+
+// initializationOptions applies the limits set by the client. The options
+// of an unexpected shape are logged and ignored.
+func (s *Server) initializationOptions(ctx context.Context, raw json.RawMessage) {
+	var options initializationOptions
+	if err := json.Unmarshal(raw, &options); err != nil {
+		s.logger.WarnContext(ctx, "cannot decode the initialization options", "error", err)
+		return
+	}
+	limits := s.limits
+	if options.MaxMessageSize != nil {
+		limits.MaxMessageSize = *options.MaxMessageSize
+	}
+	if options.MaxDocumentSize != nil {
+		limits.MaxDocumentSize = *options.MaxDocumentSize
+	}
+	if options.MaxParsedSize != nil {
+		limits.MaxParsedSize = *options.MaxParsedSize
+	}
+	if options.MaxLineLength != nil {
+		limits.MaxLineLength = *options.MaxLineLength
+	}
+	if options.ReadTimeout != nil {
+		limits.ReadTimeout = time.Duration(*options.ReadTimeout) * time.Millisecond
+	}
+	if options.MaxExpressionDepth != nil {
+		limits.Parser.MaxExpressionDepth = *options.MaxExpressionDepth
+	}
+	if options.MaxStatementDepth != nil {
+		limits.Parser.MaxStatementDepth = *options.MaxStatementDepth
+	}
+	if options.MaxDeclarations != nil {
+		limits.Parser.MaxDeclarations = *options.MaxDeclarations
+	}
+	if options.MaxNodes != nil {
+		limits.Parser.MaxNodes = *options.MaxNodes
+	}
+	if options.ParseTimeout != nil {
+		limits.Parser.Timeout = time.Duration(*options.ParseTimeout) * time.Millisecond
+	}
+	if options.Record != "" && s.recorder.Load() == nil {
+		f, err := os.OpenFile(options.Record, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "cannot record the session", "error", err)
+		} else {
+			// The file stays open for the lifetime of the server.
+			s.SetRecorder(replay.NewRecorder(f, options.Redact))
+			s.logger.InfoContext(ctx, "recording the session", "path", options.Record, "redact", options.Redact)
+		}
+	}
+	s.openFiles = options.OpenFiles
+	s.SetLimits(limits)
+	s.logger.InfoContext(ctx, "set the limits", "maxMessageSize", s.limits.MaxMessageSize,
+		"maxDocumentSize", s.limits.MaxDocumentSize, "maxParsedSize", s.limits.MaxParsedSize, "maxLineLength", s.limits.MaxLineLength, "readTimeout", s.limits.ReadTimeout,
+		"maxExpressionDepth", s.limits.Parser.MaxExpressionDepth, "maxStatementDepth", s.limits.Parser.MaxStatementDepth,
+		"maxDeclarations", s.limits.Parser.MaxDeclarations, "maxNodes", s.limits.Parser.MaxNodes, "parseTimeout", s.limits.Parser.Timeout)
+}
+
+// fetchSettings asks the client for the "solbot" section of the settings
+// with the workspace/configuration request, if the client supports it. The
+// settings are applied once the client responds, see handleResponse.
+func (s *Server) fetchSettings(ctx context.Context) bool {
+	workspace := s.state.Capabilities.Workspace
+	if workspace == nil || !workspace.Configuration {
+		return false
+	}
+	s.request(ctx, "workspace/configuration", func(id lsp.ID) any {
+		return lsp.NewConfigurationRequest(id, "solbot")
+	})
+	return true
+}
+
+// registerProjectFiles asks the client to send remappings.txt and
+// foundry.toml of the workspace too, if it can register the methods
+// dynamically: they're validated and completed as they're edited, and the
+// imports resolve with their content, see analysis.State.IsProjectFile.
+// The changes on the disk are watched for the ones that aren't open.
+func (s *Server) registerProjectFiles(ctx context.Context) bool {
+	if s.root == "" {
+		return false
+	}
+	selector := []lsp.DocumentFilter{}
+	watchers := []lsp.FileSystemWatcher{}
+	for _, name := range []string{"remappings.txt", "foundry.toml"} {
+		pattern := filepath.ToSlash(filepath.Join(s.root, name))
+		selector = append(selector, lsp.DocumentFilter{Scheme: "file", Pattern: pattern})
+		watchers = append(watchers, lsp.FileSystemWatcher{GlobPattern: pattern})
+	}
+
+	registrations := []lsp.Registration{}
+	caps := s.state.Capabilities
+	if text := caps.TextDocument; text != nil && text.Synchronization != nil && text.Synchronization.DynamicRegistration {
+		registrations = append(registrations,
+			lsp.Registration{ID: "project-files/didOpen", Method: "textDocument/didOpen", RegisterOptions: lsp.TextDocumentRegistrationOptions{DocumentSelector: selector}},
+			lsp.Registration{ID: "project-files/didChange", Method: "textDocument/didChange", RegisterOptions: lsp.TextDocumentChangeRegistrationOptions{DocumentSelector: selector, SyncKind: 1}})
+		if text.Completion != nil && text.Completion.DynamicRegistration {
+			registrations = append(registrations, lsp.Registration{ID: "project-files/completion", Method: "textDocument/completion",
+				RegisterOptions: lsp.CompletionRegistrationOptions{DocumentSelector: selector, TriggerCharacters: []string{"=", "/"}}})
+		}
+	}
+	if workspace := caps.Workspace; workspace != nil && workspace.DidChangeWatchedFiles != nil && workspace.DidChangeWatchedFiles.DynamicRegistration {
+		registrations = append(registrations, lsp.Registration{ID: "project-files/watch", Method: "workspace/didChangeWatchedFiles",
+			RegisterOptions: lsp.DidChangeWatchedFilesRegistrationOptions{Watchers: watchers}})
+	}
+	if len(registrations) == 0 {
+		return false
+	}
+	s.request(ctx, "client/registerCapability", func(id lsp.ID) any {
+		return lsp.NewRegistrationRequest(id, registrations...)
+	})
+	return true
+}
+
+// applySettings applies the "solbot" section of the editor settings and
+// publishes the diagnostics of the open documents again, or asks the client
+// pulling them to pull them again. The unknown and invalid settings are
+// skipped and listed in a single warning.
+func (s *Server) applySettings(ctx context.Context, raw json.RawMessage) {
+	settings, problems := analysis.ParseSettings(raw)
+	if len(problems) > 0 {
+		s.notify(ctx, lsp.NewShowMessageNotification(lsp.MessageWarning,
+			"solbot ignored the settings: "+strings.Join(problems, "; ")))
+	}
+	reanalyze := s.state.ApplySettings(settings)
+	s.logger.InfoContext(ctx, "applied the settings", "problems", len(problems), "reanalyze", reanalyze)
+	if s.state.PullsDiagnostics() {
+		s.refreshDiagnostics()
+		return
+	}
+	s.background(ctx, "republish", func(ctx context.Context) {
+		revision := s.currentRevision()
+		for _, diagnostics := range s.state.Republish(ctx, reanalyze) {
+			s.notify(ctx, tagged(diagnostics, revision, nil))
+		}
+	})
+}
+
+// diagnosticsKey is the key of the background task computing the
+// diagnostics of the document.
+func diagnosticsKey(uri string) string {
+	return "diagnostics " + uri
+}
+
+// publishDiagnostics computes the diagnostics of the document and of the
+// open documents importing it in the background, and publishes them
+// together, tagged with the revision of the workspace, see generation. The
+// clients pulling the diagnostics are asked to pull them again instead.
+func (s *Server) publishDiagnostics(ctx context.Context, uri string) {
+	if s.state.PullsDiagnostics() {
+		s.refreshDiagnostics()
+		return
+	}
+	gen, missing := s.startGeneration(ctx, uri)
+	for _, uri := range missing {
+		s.background(ctx, diagnosticsKey(uri), func(ctx context.Context) {
+			s.promote(ctx, "textDocument/publishDiagnostics", uri)
+			notification := s.state.Diagnostics(ctx, uri)
+			if s.analyzed != nil {
+				s.analyzed(uri)
+			}
+			if ctx.Err() != nil {
+				s.logger.InfoContext(ctx, "cancelled the diagnostics")
+				return
+			}
+			s.deliver(ctx, gen, notification)
+		})
+	}
+}
+
+// warmupToken is the progress token of the warmup.
+const warmupToken = "solbot/warmup"
+
+// warmupBatch is the number of the files indexed by a warmup task. The
+// tasks scheduled in the meantime, like the diagnostics of the opened
+// documents, run before the next batch.
+const warmupBatch = 50
+
+// startWarmup starts indexing the workspace in the background, in batches,
+// reporting the progress if the client shows it.
+func (s *Server) startWarmup(ctx context.Context) {
+	if s.root == "" || s.warmup != nil {
+		return
+	}
+	warmup, err := s.state.NewWarmup(s.root, s.openFiles)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "cannot index the workspace", "error", err)
+		return
+	}
+	s.warmup = warmup
+	if s.state.ReportsProgress() {
+		s.request(ctx, "window/workDoneProgress/create", func(id lsp.ID) any {
+			return lsp.NewWorkDoneProgressCreateRequest(id, warmupToken)
+		})
+		_, total := warmup.Progress()
+		s.notify(ctx, lsp.NewWorkDoneProgressBegin(warmupToken, "Indexing the workspace", fmt.Sprintf("0/%d files", total)))
+	}
+	s.continueWarmup(ctx)
+}
+
+// continueWarmup schedules the next batch of the warmup. The batches are
+// scheduled with the context of the message that started the warmup, since
+// the one of the task ends with it.
+func (s *Server) continueWarmup(ctx context.Context) {
+	s.background(ctx, "warmup", func(taskCtx context.Context) {
+		done, err := s.warmup.Run(taskCtx, warmupBatch)
+		if err != nil {
+			s.logger.InfoContext(taskCtx, "cancelled the warmup")
+			return
+		}
+		indexed, total := s.warmup.Progress()
+		if done {
+			if s.state.ReportsProgress() {
+				s.notify(taskCtx, lsp.NewWorkDoneProgressEnd(warmupToken, fmt.Sprintf("Indexed %d files", total)))
+			}
+			return
+		}
+		if s.state.ReportsProgress() {
+			s.notify(taskCtx, lsp.NewWorkDoneProgressReport(warmupToken, fmt.Sprintf("%d/%d files", indexed, total), 100*indexed/total))
+		}
+		s.continueWarmup(ctx)
+	})
+}
+
+// wholeWorkspaceMethods are the requests depending on the files importing
+// the document, not only on the ones it imports, so they wait for the
+// whole warmup.
+var wholeWorkspaceMethods = map[string]bool{
+	"textDocument/references":   true,
+	"textDocument/rename":       true,
+	"textDocument/codeLens":     true,
+	"codeLens/resolve":          true,
+	"workspace/willRenameFiles": true,
+	"workspace/diagnostic":      true,
+	"solbot/inventory":          true,
+}
+
+// promote indexes the files the request needs before it's answered, while
+// the warmup is running: the document and the files it imports, or the
+// whole workspace for wholeWorkspaceMethods.
+func (s *Server) promote(ctx context.Context, method, uri string) {
+	if s.warmup == nil {
+		return
+	}
+	if wholeWorkspaceMethods[method] {
+		if _, err := s.warmup.Run(ctx, 0); err != nil {
+			s.logger.InfoContext(ctx, "cancelled the warmup")
+		}
+		return
+	}
+	if uri == "" {
+		return
+	}
+	if promoted := s.warmup.Promote(uri); promoted > 0 {
+		s.logger.InfoContext(ctx, "promoted the files", "files", promoted)
+	}
+}
+
+// background runs the work at a low priority, after the waiting messages,
+// see scheduler. The task scheduled with the key of a waiting one replaces
+// it. The messages given to Handle run their work right away instead.
+func (s *Server) background(ctx context.Context, key string, run func(ctx context.Context)) {
+	if !s.serving {
+		run(ctx)
+		return
+	}
+	s.scheduler.schedule(ctx, key, func(ctx context.Context) {
+		run(ctx)
+		s.state.Unload()
+	})
+}
+
+// request sends the request built for the next ID to the client. The
+// response is handled by handleResponse.
+func (s *Server) request(ctx context.Context, method string, build func(id lsp.ID) any) {
+	s.mu.Lock()
+	s.lastSentID++
+	id := lsp.IntID(s.lastSentID)
+	s.pending[id] = method
+	s.mu.Unlock()
+
+	content := s.write(ctx, build(id))
+	s.logger.InfoContext(ctx, "requested", append([]any{"method", method}, s.payload(content)...)...)
+}
+
+// handleResponse handles the response of the client to a request sent by
+// the server. Only the settings are used, the other requests, like the
+// refresh of the code lenses, need no answer.
+func (s *Server) handleResponse(ctx context.Context, id lsp.ID, content []byte) {
+	s.mu.Lock()
+	method, ok := s.pending[id]
+	delete(s.pending, id)
+	s.mu.Unlock()
+	if !ok {
+		s.logger.WarnContext(ctx, "received a response to an unknown request", "requestID", id.String())
+		return
+	}
+
+	switch method {
+	case "workspace/configuration":
+		var response lsp.ConfigurationResponse
+		if err := json.Unmarshal(content, &response); err != nil {
+			s.logger.ErrorContext(ctx, "cannot decode the response", "error", err)
+			return
+		}
+		if response.Error != nil {
+			s.logger.WarnContext(ctx, "cannot fetch the settings", "error", response.Error.Message)
+			return
+		}
+		var settings json.RawMessage
+		if len(response.Result) > 0 {
+			settings = response.Result[0]
+		}
+		s.applySettings(ctx, settings)
+	}
+}
+
+// refreshCodeLenses asks the client for the lenses again if the edit may
+// have changed the reference counts.
+func (s *Server) refreshCodeLenses() {
+	if !s.state.ReferencesChanged() || !s.state.RefreshesCodeLenses() {
+		return
+	}
+	s.refreshLater(&s.refresh, "workspace/codeLens/refresh", func(id lsp.ID) any {
+		return lsp.NewCodeLensRefreshRequest(id)
+	})
+}
+
+// refreshDiagnostics asks the client pulling the diagnostics to pull them
+// again, since an edit can change the diagnostics of the documents
+// importing the edited one. The documents whose diagnostics didn't change
+// are answered with the unchanged reports.
+func (s *Server) refreshDiagnostics() {
+	if !s.state.RefreshesDiagnostics() {
+		return
+	}
+	s.refreshLater(&s.refreshPull, "workspace/diagnostic/refresh", func(id lsp.ID) any {
+		return lsp.NewWorkspaceDiagnosticRefreshRequest(id)
+	})
+}
+
+// refreshLater sends the refresh request, debounced: it's sent once the
+// edits stop for the refresh delay, not on every keystroke. The timer is
+// the one of the pending request of the method.
+func (s *Server) refreshLater(timer **time.Timer, method string, build func(id lsp.ID) any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if *timer != nil {
+		(*timer).Stop()
+	}
+	*timer = time.AfterFunc(s.refreshDelay, func() {
+		s.mu.Lock()
+		*timer = nil
+		s.mu.Unlock()
+
+		ctx := withRequest(context.Background(), &request{method: method, start: time.Now()})
+		s.request(ctx, method, build)
+	})
+}
+
+// respond writes the response to the request handled in the context, or
+// adds it to the response of the batch the request is a part of.
+func (s *Server) respond(ctx context.Context, msg any) {
+	var content []byte
+	if b, ok := ctx.Value(batchKey{}).(*batch); ok && !b.sent {
+		content, _ = json.Marshal(msg)
+		b.responses = append(b.responses, content)
+	} else {
+		content = s.write(ctx, msg)
+	}
+	req, _ := requestFrom(ctx)
+	duration := time.Since(req.start)
+	s.recordLatency(req.method, duration)
+
+	s.logger.InfoContext(ctx, "responded", append([]any{"duration", duration}, s.payload(content)...)...)
+	s.logTrace(ctx, fmt.Sprintf("Sending response '%s'. Processing request took %s.", req.method, duration), content)
+}
+
+// notify writes the notification triggered by the message handled in the
+// context e.g. the diagnostics of the opened document.
+func (s *Server) notify(ctx context.Context, msg any) {
+	content := s.write(ctx, msg)
+	s.logger.InfoContext(ctx, "notified", s.payload(content)...)
+}
+
+// logTrace sends the $/logTrace notification, unless the client turned the
+// trace off. The content is included only if the trace is verbose.
+func (s *Server) logTrace(ctx context.Context, message string, content []byte) {
+	switch s.trace {
+	case lsp.TraceMessages:
+		s.write(ctx, lsp.NewLogTraceNotification(message, ""))
+	case lsp.TraceVerbose:
+		s.write(ctx, lsp.NewLogTraceNotification(message, string(content)))
+	}
+}
+
+// write encodes the message and writes it to the client. It returns the
+// content of the message without the header.
+func (s *Server) write(ctx context.Context, msg any) []byte {
+	encoded := rpc.EncodeMessage(msg)
+	_, content, _ := strings.Cut(encoded, "\r\n\r\n")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := io.WriteString(s.writer, encoded); err != nil {
+		s.logger.ErrorContext(ctx, "cannot write the message", "error", err)
+	}
+	// Recorded under the lock, in the order the messages were written.
+	s.record(ctx, time.Now(), replay.Outbound, []byte(content))
+	return []byte(content)
+}
+
+// payload returns the attributes with the content of the message, if the
+// payloads are logged.
+func (s *Server) payload(content []byte) []any {
+	if !s.logPayloads && s.trace == lsp.TraceOff {
+		return nil
+	}
+	return []any{"payload", string(content)}
+}
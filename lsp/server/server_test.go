@@ -0,0 +1,754 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"solbot/lsp"
+	"solbot/lsp/analysis"
+	"solbot/lsp/replay"
+	"solbot/lsp/rpc"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordHandler keeps the logged records in memory.
+type recordHandler struct {
+	mu      *sync.Mutex
+	records *[]slog.Record
+}
+
+func newRecordHandler() *recordHandler {
+	return &recordHandler{mu: &sync.Mutex{}, records: &[]slog.Record{}}
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.records = append(*h.records, r.Clone())
+	return nil
+}
+
+// The server doesn't use the attributes and groups of the loggers.
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler      { return h }
+
+// find returns the attributes of the first record with the message.
+func (h *recordHandler) find(message string) (map[string]slog.Value, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range *h.records {
+		if r.Message != message {
+			continue
+		}
+		attrs := map[string]slog.Value{}
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value
+			return true
+		})
+		return attrs, true
+	}
+	return nil, false
+}
+
+const (
+	didOpen = `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///ws/Vault.sol","languageId":"solidity","version":1,"text":"contract Vault {}"}}}`
+	hover   = `{"jsonrpc":"2.0","id":7,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///ws/Vault.sol"},"position":{"line":0,"character":10}}}`
+)
+
+func Test_LogCorrelationID(t *testing.T) {
+	handler := newRecordHandler()
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(handler), false)
+
+	s.Handle("textDocument/didOpen", []byte(didOpen))
+	s.Handle("textDocument/hover", []byte(hover))
+
+	received, ok := handler.find("received")
+	if !ok {
+		t.Fatalf("Expected the received line, got none")
+	}
+	responded, ok := handler.find("responded")
+	if !ok {
+		t.Fatalf("Expected the responded line, got none")
+	}
+
+	// The didOpen notification is the first message, the hover is the second.
+	if received["cid"].Int64() != 1 {
+		t.Errorf("Expected correlation ID 1 of the first message, got %s", received["cid"])
+	}
+	if responded["cid"].Int64() != 2 {
+		t.Errorf("Expected correlation ID 2 on the response to the hover, got %s", responded["cid"])
+	}
+	if responded["method"].String() != "textDocument/hover" {
+		t.Errorf("Expected method textDocument/hover, got %s", responded["method"])
+	}
+	if responded["uri"].String() != "file:///ws/Vault.sol" {
+		t.Errorf("Expected uri file:///ws/Vault.sol, got %s", responded["uri"])
+	}
+	if _, ok := responded["duration"]; !ok {
+		t.Errorf("Expected the duration on the responded line")
+	}
+
+	// The diagnostics are published as a part of handling the didOpen.
+	notified, ok := handler.find("notified")
+	if !ok {
+		t.Fatalf("Expected the notified line, got none")
+	}
+	if notified["cid"].Int64() != 1 {
+		t.Errorf("Expected the correlation ID 1 of the didOpen on the notified line, got %s", notified["cid"])
+	}
+
+	for _, message := range []string{"received", "responded", "notified"} {
+		attrs, _ := handler.find(message)
+		if _, ok := attrs["payload"]; ok {
+			t.Errorf("Expected no payload on the %s line without the trace", message)
+		}
+	}
+	if !strings.Contains(output.String(), `"id":7`) {
+		t.Errorf("Expected the response to the hover, got:\n%s", output.String())
+	}
+}
+
+func Test_LogPayloadsWithTrace(t *testing.T) {
+	handler := newRecordHandler()
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(handler), false)
+
+	s.Handle("$/setTrace", []byte(`{"jsonrpc":"2.0","method":"$/setTrace","params":{"value":"verbose"}}`))
+	s.Handle("textDocument/hover", []byte(hover))
+
+	responded, _ := handler.find("responded")
+	if !strings.Contains(responded["payload"].String(), `"id":7`) {
+		t.Errorf("Expected the payload of the response, got %q", responded["payload"])
+	}
+	if !strings.Contains(output.String(), `"method":"$/logTrace"`) {
+		t.Errorf("Expected the $/logTrace notifications with the verbose trace, got:\n%s", output.String())
+	}
+
+	// The --trace flag logs the payloads without notifying the client.
+	handler = newRecordHandler()
+	output.Reset()
+	s = NewServer(&output, slog.New(handler), true)
+	s.Handle("textDocument/hover", []byte(hover))
+
+	received, _ := handler.find("received")
+	if received["payload"].String() != hover {
+		t.Errorf("Expected the payload of the request, got %q", received["payload"])
+	}
+	if strings.Contains(output.String(), "$/logTrace") {
+		t.Errorf("Expected no $/logTrace notifications with the trace off, got:\n%s", output.String())
+	}
+}
+
+// frame adds the header to the content of a message.
+func frame(content string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+// didOpenOf returns the didOpen notification of the document with the text.
+func didOpenOf(text string) string {
+	return `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///ws/Vault.sol","languageId":"solidity","version":1,"text":"` + text + `"}}}`
+}
+
+func Test_ServeLargeDocument(t *testing.T) {
+	// The default buffer of bufio.Scanner holds 64KB.
+	text := "contract Vault {} /* " + strings.Repeat("x", 100_000) + " */"
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	if err := s.Serve(strings.NewReader(frame(didOpenOf(text)) + frame(hover))); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !strings.Contains(output.String(), `"method":"textDocument/publishDiagnostics"`) || !strings.Contains(output.String(), `"id":7`) {
+		t.Errorf("Expected the diagnostics and the hover, got:\n%.500s", output.String())
+	}
+}
+
+func Test_ServeMalformedHeaders(t *testing.T) {
+	// The other fields of the header are allowed by the spec.
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	typed := fmt.Sprintf("Content-Length: %d\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n%s", len(hover), hover)
+	if err := s.Serve(strings.NewReader(frame(didOpen) + typed)); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !strings.Contains(output.String(), `"id":7`) {
+		t.Errorf("Expected the hover with the Content-Type answered, got:\n%.500s", output.String())
+	}
+
+	// A negative length ends the stream rather than the process.
+	s = NewServer(&bytes.Buffer{}, slog.New(newRecordHandler()), false)
+	if err := s.Serve(strings.NewReader("Content-Length: -100\r\n\r\n" + hover)); err == nil || !strings.Contains(err.Error(), "Invalid Content-Length") {
+		t.Errorf("Expected the negative length to be rejected, got %v", err)
+	}
+}
+
+func Test_ServeOversizedMessages(t *testing.T) {
+	padding := strings.Repeat(" ", 2048)
+	request := `{"jsonrpc":"2.0","id":5,"method":"textDocument/hover","params":{"padding":"` + padding + `"}}`
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	s.SetLimits(Limits{MaxMessageSize: 1024})
+	if err := s.Serve(strings.NewReader(frame(request) + frame(didOpenOf(padding)) + frame(hover))); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	out := output.String()
+	if !strings.Contains(out, `"id":5,"error":{"code":-32600`) {
+		t.Errorf("Expected the error response to the oversized request, got:\n%s", out)
+	}
+	if !strings.Contains(out, `"method":"window/showMessage"`) || !strings.Contains(out, "skipped `textDocument/didOpen`") {
+		t.Errorf("Expected the skipped notification to be shown, got:\n%s", out)
+	}
+	if strings.Contains(out, "publishDiagnostics") {
+		t.Errorf("Expected the oversized didOpen to be skipped, got:\n%s", out)
+	}
+	if !strings.Contains(out, `"id":7`) {
+		t.Errorf("Expected the messages after the skipped ones to be handled, got:\n%s", out)
+	}
+}
+
+func Test_ServeReadTimeout(t *testing.T) {
+	client, conn := net.Pipe()
+	defer client.Close()
+	s := NewServer(io.Discard, slog.New(newRecordHandler()), false)
+	s.SetLimits(Limits{MaxMessageSize: 1024, ReadTimeout: 50 * time.Millisecond})
+
+	done := make(chan error)
+	go func() { done <- s.Serve(conn) }()
+	// A complete message, an idle client and then a stalled message.
+	if _, err := io.WriteString(client, frame(hover)); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := io.WriteString(client, "Content-Length: 100\r\n\r\n{"); err != nil {
+		t.Fatalf("Expected the idle client to stay connected, got %s", err)
+	}
+
+	select {
+	case err := <-done:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("Expected a timeout, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the stalled message to time out")
+	}
+}
+
+func Test_DocumentLimits(t *testing.T) {
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	s.Handle("initialize", []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{},"initializationOptions":{"maxDocumentSize":10}}}`))
+	s.Handle("textDocument/didOpen", []byte(didOpen))
+
+	if !strings.Contains(output.String(), `"code":"file-too-large"`) {
+		t.Errorf("Expected the diagnostic of the document larger than the limit, got:\n%s", output.String())
+	}
+	output.Reset()
+	s.Handle("textDocument/hover", []byte(hover))
+	if !strings.Contains(output.String(), `"id":7`) {
+		t.Errorf("Expected the hover response, got:\n%s", output.String())
+	}
+
+	// The options of another shape are ignored.
+	s.Handle("initialize", []byte(`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"capabilities":{},"initializationOptions":["x"]}}`))
+	if s.limits.MaxDocumentSize != 10 {
+		t.Errorf("Expected the limit 10 to stay, got %d", s.limits.MaxDocumentSize)
+	}
+}
+
+// syncBuffer is written by the timers of the server.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) count(s string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Count(b.buf.String(), s)
+}
+
+func Test_RefreshCodeLenses(t *testing.T) {
+	const initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"workspace":{"codeLens":{"refreshSupport":true}}}}}`
+	didChange := func(version int, text string) string {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///ws/Vault.sol","version":%d},"contentChanges":[{"text":"%s"}]}}`, version, text)
+	}
+	var output syncBuffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	s.refreshDelay = 20 * time.Millisecond
+	refreshes := func() int {
+		time.Sleep(100 * time.Millisecond)
+		return output.count(`"method":"workspace/codeLens/refresh"`)
+	}
+
+	s.Handle("initialize", []byte(initialize))
+	s.Handle("textDocument/didOpen", []byte(didOpen))
+	s.Handle("textDocument/didChange", []byte(didChange(2, "contract Vault { uint a; }")))
+	s.Handle("textDocument/didChange", []byte(didChange(3, "contract Vault { uint a; uint b; }")))
+	if n := refreshes(); n != 1 {
+		t.Fatalf("Expected a single refresh after the edits, got %d", n)
+	}
+	s.Handle("", []byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+
+	s.Handle("textDocument/didChange", []byte(didChange(4, "contract Vault { uint a; uint b; } // done")))
+	if n := refreshes(); n != 1 {
+		t.Errorf("Expected no refresh after editing a comment, got %d refreshes", n)
+	}
+}
+
+func Test_PullDiagnostics(t *testing.T) {
+	const (
+		initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"textDocument":{"diagnostic":{}},"workspace":{"diagnostics":{"refreshSupport":true}}}}}`
+		didChange  = `{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///ws/Vault.sol","version":2},"contentChanges":[{"text":"contract Vault { uint a; }"}]}}`
+		pull       = `{"jsonrpc":"2.0","id":2,"method":"workspace/diagnostic","params":{"previousResultIds":[],"partialResultToken":"p1"}}`
+	)
+	var output syncBuffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	s.refreshDelay = 20 * time.Millisecond
+
+	s.Handle("initialize", []byte(initialize))
+	s.Handle("textDocument/didOpen", []byte(didOpen))
+	s.Handle("textDocument/didChange", []byte(didChange))
+	time.Sleep(100 * time.Millisecond)
+	if n := output.count(`"method":"textDocument/publishDiagnostics"`); n != 0 {
+		t.Errorf("Expected no published diagnostics when the client pulls them, got %d", n)
+	}
+	if n := output.count(`"method":"workspace/diagnostic/refresh"`); n != 1 {
+		t.Errorf("Expected a single refresh after the edits, got %d", n)
+	}
+
+	s.Handle("workspace/diagnostic", []byte(pull))
+	if n := output.count(`"method":"$/progress","params":{"token":"p1"`); n != 1 {
+		t.Errorf("Expected the report to be streamed, got %d progress notifications", n)
+	}
+	if n := output.count(`"id":2,"result":{"items":[]}`); n != 1 {
+		t.Errorf("Expected the response without items, got %d", n)
+	}
+}
+
+func Test_SettingsSeverityOverrides(t *testing.T) {
+	const (
+		initialize       = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"workspace":{"configuration":true}}}}`
+		initialized      = `{"jsonrpc":"2.0","method":"initialized","params":{}}`
+		didChangeConfig  = `{"jsonrpc":"2.0","method":"workspace/didChangeConfiguration","params":{"settings":null}}`
+		alwaysTrue       = `"code":"always-true-condition"`
+		configRequestFmt = `"id":%d,"method":"workspace/configuration","params":{"items":[{"section":"solbot"}]}`
+	)
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	// configure answers the next workspace/configuration request with the
+	// settings and returns the messages written in between.
+	requests := 0
+	configure := func(trigger, settings string) string {
+		t.Helper()
+		output.Reset()
+		s.Handle(methodOf(trigger), []byte(trigger))
+		requests++
+		if !strings.Contains(output.String(), fmt.Sprintf(configRequestFmt, requests)) {
+			t.Fatalf("Expected the configuration request %d, got %s", requests, output.String())
+		}
+		output.Reset()
+		s.Handle("", []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":[%s]}`, requests, settings)))
+		return output.String()
+	}
+
+	s.Handle("initialize", []byte(initialize))
+	configure(initialized, "null")
+	output.Reset()
+	s.Handle("textDocument/didOpen", []byte(didOpenOf("contract Vault { function pay(uint a) external { require(a >= 0); } }")))
+	if !strings.Contains(output.String(), alwaysTrue+`,"source":"solbot"`) || !strings.Contains(output.String(), `"severity":2`) {
+		t.Fatalf("Expected the always true condition as a warning, got %s", output.String())
+	}
+	stats := s.state.Stats
+
+	published := configure(didChangeConfig, `{"severity":{"always-true-condition":"off"}}`)
+	if !strings.Contains(published, "textDocument/publishDiagnostics") || strings.Contains(published, alwaysTrue) {
+		t.Errorf("Expected the diagnostics published without the condition, got %s", published)
+	}
+	if s.state.Stats != stats {
+		t.Errorf("Expected the severity to be applied without parsing or analyzing, got %+v, was %+v", s.state.Stats, stats)
+	}
+
+	published = configure(didChangeConfig, `{"severity":{"always-true-condition":"error"}}`)
+	if !strings.Contains(published, `"severity":1,`+alwaysTrue) {
+		t.Errorf("Expected the condition published as an error, got %s", published)
+	}
+
+	published = configure(didChangeConfig, `{"severity":{"always-true-condition":"fatal"},"formatter":"default","inlayHints":{"types":true}}`)
+	if strings.Count(published, "window/showMessage") != 1 {
+		t.Fatalf("Expected a single warning about the settings, got %s", published)
+	}
+	for _, problem := range []string{"solbot.formatter is unknown", "solbot.inlayHints.types is unknown", `solbot.severity.always-true-condition has an invalid value \"fatal\"`} {
+		if !strings.Contains(published, problem) {
+			t.Errorf("Expected the warning to list %q, got %s", problem, published)
+		}
+	}
+	if !strings.Contains(published, `"severity":2,`+alwaysTrue) {
+		t.Errorf("Expected the condition published as a warning again, got %s", published)
+	}
+	if s.state.Stats.Parses != stats.Parses {
+		t.Errorf("Expected no document to be parsed again, got %d parses, was %d", s.state.Stats.Parses, stats.Parses)
+	}
+}
+
+// methodOf returns the method of the message.
+func methodOf(content string) string {
+	_, rest, _ := strings.Cut(content, `"method":"`)
+	method, _, _ := strings.Cut(rest, `"`)
+	return method
+}
+
+func Test_ServePrioritizesInteractiveRequests(t *testing.T) {
+	var output syncBuffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	var mu sync.Mutex
+	events := []string{}
+	s.scheduler.observe = func(event, name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event+" "+name)
+	}
+	observed := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(events)
+	}
+	// wait waits until the condition holds, or fails the test.
+	wait := func(what string, cond func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %s, got the events %v", what, observed())
+			}
+		}
+	}
+	happened := func(event string) func() bool {
+		return func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return slices.Contains(events, event)
+		}
+	}
+
+	conn, client := io.Pipe()
+	done := make(chan error)
+	go func() { done <- s.Serve(conn) }()
+	io.WriteString(client, frame(didOpen))
+	wait("the diagnostics", func() bool { return output.count("publishDiagnostics") == 1 })
+
+	// The whole-workspace work, which ends only when it's released.
+	release := make(chan struct{})
+	s.scheduler.schedule(context.Background(), "slow", func(ctx context.Context) {
+		for {
+			select {
+			case <-release:
+				return
+			default:
+			}
+			time.Sleep(time.Millisecond)
+			if err := analysis.Checkpoint(ctx); err != nil {
+				return
+			}
+		}
+	})
+	wait("the slow task to start", happened("start slow"))
+	io.WriteString(client, frame(hover))
+	wait("the hover response", func() bool { return output.count(`"id":7`) == 1 })
+	if !happened("preempt textDocument/hover")() || happened("end slow")() {
+		t.Errorf("Expected the hover to preempt the slow task, got the events %v", observed())
+	}
+
+	close(release)
+	client.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if latency := s.Latencies()["textDocument/hover"]; latency.Count != 1 || latency.Max > time.Second {
+		t.Errorf("Expected a single fast hover, got %+v", latency)
+	}
+}
+
+func Test_SchedulerCancel(t *testing.T) {
+	sc := newScheduler()
+	ran := []string{}
+	for _, key := range []string{"diagnostics a", "index", "diagnostics a", "diagnostics b"} {
+		key := key
+		sc.schedule(context.Background(), key, func(ctx context.Context) { ran = append(ran, key) })
+	}
+	sc.cancel("diagnostics b")
+	sc.close()
+	sc.work()
+	if expected := []string{"index", "diagnostics a"}; !slices.Equal(ran, expected) {
+		t.Errorf("Expected the tasks %v, got %v", expected, ran)
+	}
+}
+
+func Test_RecordSessionFromOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	s := NewServer(io.Discard, slog.New(newRecordHandler()), false)
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{},"initializationOptions":{"record":` + strconv.Quote(path) + `}}}`
+	s.Handle("initialize", []byte(initialize))
+	s.Handle("textDocument/didOpen", []byte(didOpen))
+	s.Handle("textDocument/hover", []byte(hover))
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected the recording, got %s", err)
+	}
+	defer f.Close()
+	entries, err := replay.ReadRecording(f)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	expected := []struct {
+		direction replay.Direction
+		method    string
+	}{
+		{replay.Inbound, "initialize"},
+		{replay.Outbound, ""},
+		{replay.Inbound, "textDocument/didOpen"},
+		{replay.Outbound, "textDocument/publishDiagnostics"},
+		{replay.Inbound, "textDocument/hover"},
+		{replay.Outbound, ""},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %v", len(expected), entries)
+	}
+	for i, e := range entries {
+		if e.Direction != expected[i].direction || methodOf(e.Content) != expected[i].method {
+			t.Errorf("Expected %s %q, got %s %s", expected[i].direction, expected[i].method, e.Direction, e.Content)
+		}
+	}
+	if entries[0].Content != initialize || entries[4].Content != hover {
+		t.Errorf("Expected the messages of the client verbatim, got %s and %s", entries[0].Content, entries[4].Content)
+	}
+}
+
+func Test_WarmupPromotesRequestedFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"src/Vault.sol":       "pragma solidity ^0.8.0;\n\nimport \"../lib/token/Token.sol\";\n\ncontract Vault is Token {}\n",
+		"lib/token/Token.sol": "pragma solidity ^0.8.0;\n\ncontract Token {}\n",
+	}
+	for name, src := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vault := analysis.PathToURI(filepath.Join(root, "src/Vault.sol"))
+	initialize := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":"%s","capabilities":{"window":{"workDoneProgress":true}}}}`, analysis.PathToURI(root))
+	definition := fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"textDocument/definition","params":{"textDocument":{"uri":"%s"},"position":{"line":4,"character":19}}}`, vault)
+
+	var output syncBuffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	// Without the worker, the batches of the warmup stay scheduled.
+	s.serving = true
+	s.Handle("initialize", []byte(initialize))
+	s.Handle("initialized", []byte(`{"jsonrpc":"2.0","method":"initialized","params":{}}`))
+	if n := output.count(`"method":"window/workDoneProgress/create"`); n != 1 {
+		t.Errorf("Expected the progress to be created, got %d requests", n)
+	}
+	if n := output.count(`"kind":"begin"`); n != 1 {
+		t.Errorf("Expected the progress to begin, got %d notifications", n)
+	}
+
+	s.Handle("textDocument/definition", []byte(definition))
+	if n := output.count(`lib/token/Token.sol","range":{"start":{"line":2,"character":9}`); n != 1 {
+		t.Errorf("Expected the definition in the promoted dependency, got %d", n)
+	}
+}
+
+// messages returns the contents of the messages written to the output.
+func messages(t *testing.T, output string) []string {
+	t.Helper()
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Split(rpc.Split)
+	res := []string{}
+	for scanner.Scan() {
+		_, content, _ := strings.Cut(scanner.Text(), "\r\n\r\n")
+		res = append(res, content)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Expected the messages, got %s", err)
+	}
+	return res
+}
+
+func Test_ServeBatch(t *testing.T) {
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	if err := s.Serve(strings.NewReader(frame("[" + didOpen + ",\n" + hover + "]"))); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	var batches, diagnostics int
+	for _, content := range messages(t, output.String()) {
+		switch {
+		case strings.HasPrefix(content, "["):
+			batches++
+			var responses []map[string]any
+			if err := json.Unmarshal([]byte(content), &responses); err != nil {
+				t.Fatalf("Expected the batch of the responses, got %s", content)
+			}
+			if len(responses) != 1 || responses[0]["id"] != float64(7) || responses[0]["result"] == nil {
+				t.Errorf("Expected the response to the hover alone, got %s", content)
+			}
+		case strings.Contains(content, `"id":7`):
+			t.Errorf("Expected the response to the hover in the batch, got %s", content)
+		case strings.Contains(content, "textDocument/publishDiagnostics"):
+			diagnostics++
+		}
+	}
+	if batches != 1 || diagnostics != 1 {
+		t.Errorf("Expected 1 batch and the diagnostics of the didOpen, got %d and %d:\n%s", batches, diagnostics, output.String())
+	}
+}
+
+func Test_StringRequestIDs(t *testing.T) {
+	handler := newRecordHandler()
+	var output bytes.Buffer
+	s := NewServer(&output, slog.New(handler), false)
+
+	// The initialize request has a string ID, an unknown field and no
+	// params.
+	s.Handle("initialize", []byte(`{"jsonrpc":"2.0","id":"init-1","method":"initialize","traceId":"a"}`))
+	s.Handle("textDocument/didOpen", []byte(didOpen))
+	s.Handle("textDocument/hover", []byte(strings.Replace(hover, `"jsonrpc":"2.0"`, `"jsonrpc":"2.0","traceId":"b"`, 1)))
+
+	responses := []string{}
+	for _, content := range messages(t, output.String()) {
+		if strings.Contains(content, `"id"`) {
+			responses = append(responses, content)
+		}
+	}
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %d:\n%s", len(responses), output.String())
+	}
+	if !strings.HasPrefix(responses[0], `{"jsonrpc":"2.0","id":"init-1","result":{"capabilities":`) {
+		t.Errorf("Expected the string ID echoed back, got %s", responses[0])
+	}
+	if !strings.HasPrefix(responses[1], `{"jsonrpc":"2.0","id":7,"result":`) {
+		t.Errorf("Expected the number ID echoed back, got %s", responses[1])
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	logged := 0
+	for _, r := range *handler.records {
+		if r.Message == "ignored an unknown field of the message" {
+			logged++
+		}
+	}
+	if logged != 1 {
+		t.Errorf("Expected the unknown field logged once, got %d", logged)
+	}
+}
+
+// published decodes the diagnostics published in the output, in order.
+func published(t *testing.T, output string) []lsp.PublishDiagnosticsParams {
+	t.Helper()
+	res := []lsp.PublishDiagnosticsParams{}
+	for _, content := range messages(t, output) {
+		if methodOf(content) != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var notification lsp.PublishDiagnosticsNotification
+		if err := json.Unmarshal([]byte(content), &notification); err != nil {
+			t.Fatalf("Expected the diagnostics, got %s", err)
+		}
+		res = append(res, notification.Params)
+	}
+	return res
+}
+
+// openVaults opens the interface and the two contracts implementing it,
+// in their own files, and changes the signature of the interface.
+func openVaults(s *Server) {
+	open := func(name, text string) {
+		s.Handle("textDocument/didOpen", []byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///ws/%s","languageId":"solidity","version":1,"text":%q}}}`, name, text)))
+	}
+	open("IVault.sol", "interface IVault {\n    function deposit(uint256 amount) external;\n}\n")
+	open("Vault.sol", "import \"./IVault.sol\";\n\ncontract Vault is IVault {\n    function deposit(uint256 amount) external {}\n}\n")
+	open("Pool.sol", "import \"./IVault.sol\";\n\ncontract Pool is IVault {\n    function deposit(uint256 amount) external {}\n}\n")
+	s.Handle("textDocument/didChange", []byte(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///ws/IVault.sol","version":2},"contentChanges":[{"text":"interface IVault {\n    function deposit(uint256 amount, address to) external;\n}\n"}]}}`))
+}
+
+func Test_PublishDependentsTogether(t *testing.T) {
+	var output syncBuffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	openVaults(s)
+
+	edit := published(t, output.buf.String())[3:]
+	uris := []string{}
+	for _, params := range edit {
+		uris = append(uris, params.URI)
+		if params.URI == "file:///ws/IVault.sol" {
+			continue
+		}
+		if len(params.Diagnostics) == 0 || !strings.Contains(params.Diagnostics[0].Message, "`deposit(uint256,address)` of `IVault`") {
+			t.Errorf("Expected %s checked against the new signature, got %v", params.URI, params.Diagnostics)
+			continue
+		}
+		for _, d := range params.Diagnostics {
+			if d.Data == nil || d.Data.Revision != 4 || len(d.Data.Pending) != 0 {
+				t.Errorf("Expected the diagnostics of %s tagged with the revision 4, got %+v", params.URI, d.Data)
+			}
+		}
+	}
+	if expected := []string{"file:///ws/IVault.sol", "file:///ws/Pool.sol", "file:///ws/Vault.sol"}; !slices.Equal(uris, expected) {
+		t.Errorf("Expected the diagnostics of %v published after the edit, got %v", expected, uris)
+	}
+}
+
+func Test_PublishDependentsAfterHoldback(t *testing.T) {
+	var output syncBuffer
+	s := NewServer(&output, slog.New(newRecordHandler()), false)
+	s.holdback = 20 * time.Millisecond
+	s.analyzed = func(uri string) {
+		if uri == "file:///ws/Vault.sol" && s.currentRevision() == 4 {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+	openVaults(s)
+
+	edit := published(t, output.buf.String())[3:]
+	if len(edit) != 3 || edit[2].URI != "file:///ws/Vault.sol" {
+		t.Fatalf("Expected Vault.sol published last, on its own, got %+v", edit)
+	}
+	pool, vault := edit[1], edit[2]
+	if len(pool.Diagnostics) == 0 || len(vault.Diagnostics) == 0 || pool.Diagnostics[0].Code != "missing-implementation" || vault.Diagnostics[0].Code != "missing-implementation" {
+		t.Fatalf("Expected Pool.sol and Vault.sol checked against the new signature, got %+v and %+v", pool, vault)
+	}
+	if data := pool.Diagnostics[0].Data; data == nil || data.Revision != 4 || !slices.Equal(data.Pending, []string{"file:///ws/Vault.sol"}) {
+		t.Errorf("Expected Pool.sol published noting Vault.sol is pending, got %+v", data)
+	}
+	if data := vault.Diagnostics[0].Data; data == nil || data.Revision != 4 || len(data.Pending) != 0 {
+		t.Errorf("Expected Vault.sol published with the same revision, got %+v", data)
+	}
+}
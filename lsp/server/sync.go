@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"solbot/lsp"
+)
+
+func init() {
+	features.register(feature{
+		method:       "textDocument/didOpen",
+		notification: true,
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.TextDocumentSync = 1 // Sync by sending the full content.
+		},
+		handle: onNotification(func(s *Server, ctx context.Context, request lsp.DidOpenTextDocumentNotification) {
+			s.state.OpenDocument(request.Params.TextDocument.URI, request.Params.TextDocument.Version, request.Params.TextDocument.Text)
+			if s.warmup != nil {
+				s.warmup.Opened(request.Params.TextDocument.URI)
+			}
+			s.publishDiagnostics(ctx, request.Params.TextDocument.URI)
+			s.refreshCodeLenses()
+		}),
+	})
+	features.register(feature{
+		method:       "textDocument/didChange",
+		notification: true,
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			caps.TextDocumentSync = 1
+		},
+		handle: onNotification(func(s *Server, ctx context.Context, request lsp.DidChangeTextDocumentNotification) {
+			for _, change := range request.Params.ContentChanges {
+				s.state.UpdateDocument(request.Params.TextDocument.URI, request.Params.TextDocument.Version, change.Text)
+			}
+			s.publishDiagnostics(ctx, request.Params.TextDocument.URI)
+			s.refreshCodeLenses()
+		}),
+	})
+}
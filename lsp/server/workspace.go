@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"solbot/lsp"
+)
+
+// fileOperations returns the file operations of the capabilities, added if
+// they're missing.
+func fileOperations(caps *lsp.ServerCapabilities) *lsp.FileOperationOptions {
+	if caps.Workspace == nil {
+		caps.Workspace = &lsp.WorkspaceServerCapabilities{}
+	}
+	if caps.Workspace.FileOperations == nil {
+		caps.Workspace.FileOperations = &lsp.FileOperationOptions{}
+	}
+	return caps.Workspace.FileOperations
+}
+
+func init() {
+	features.register(feature{
+		method:       "workspace/didChangeConfiguration",
+		notification: true,
+		handle: onNotification(func(s *Server, ctx context.Context, notification lsp.DidChangeConfigurationNotification) {
+			// The clients supporting the pull model may not send the
+			// settings, so they're fetched instead.
+			if s.fetchSettings(ctx) {
+				return
+			}
+			var sections map[string]json.RawMessage
+			if err := json.Unmarshal(notification.Params.Settings, &sections); err != nil {
+				s.logger.WarnContext(ctx, "cannot decode the settings", "error", err)
+				return
+			}
+			s.applySettings(ctx, sections["solbot"])
+		}),
+	})
+	features.register(feature{
+		method: "workspace/willRenameFiles",
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			fileOperations(caps).WillRename = lsp.SolidityFiles
+		},
+		handle: onRequest(func(s *Server, ctx context.Context, request lsp.WillRenameFilesRequest) {
+			response, warning := s.state.WillRenameFiles(request.ID, request.Params.Files)
+			s.respond(ctx, response)
+			if warning != "" {
+				s.notify(ctx, lsp.NewShowMessageNotification(lsp.MessageWarning, warning))
+			}
+		}),
+	})
+	features.register(feature{
+		method:       "workspace/didRenameFiles",
+		notification: true,
+		capability: func(caps *lsp.ServerCapabilities, client lsp.ClientCapabilities) {
+			fileOperations(caps).DidRename = lsp.SolidityFiles
+		},
+		handle: onNotification(func(s *Server, ctx context.Context, notification lsp.DidRenameFilesNotification) {
+			for _, uri := range s.state.RenameFiles(notification.Params.Files) {
+				s.publishDiagnostics(ctx, uri)
+			}
+			s.refreshCodeLenses()
+		}),
+	})
+	features.register(feature{
+		method:       "workspace/didChangeWatchedFiles",
+		notification: true,
+		handle: onNotification(func(s *Server, ctx context.Context, notification lsp.DidChangeWatchedFilesNotification) {
+			for _, uri := range s.state.WatchedFilesChanged(notification.Params.Changes) {
+				s.publishDiagnostics(ctx, uri)
+			}
+			s.refreshCodeLenses()
+		}),
+	})
+}
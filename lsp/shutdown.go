@@ -0,0 +1,21 @@
+package lsp
+
+// The client asks the server to shut down with the shutdown request, before
+// it sends the exit notification.
+type ShutdownRequest struct {
+	Request
+}
+
+type ShutdownResponse struct {
+	Response
+	Result any `json:"result"` // always null
+}
+
+func NewShutdownResponse(id ID) ShutdownResponse {
+	return ShutdownResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package lsp
+
+// AccessReportRequest is a request of solbot outside of the LSP
+// specification: it returns the guards of the external and public
+// functions of the contracts declared in the document, classified by who
+// can call them and in which states of the contract.
+type AccessReportRequest struct {
+	Request
+	Params AccessReportParams `json:"params"`
+}
+
+type AccessReportParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type AccessReportResponse struct {
+	Response
+	Result AccessReport `json:"result"`
+}
+
+type AccessReport struct {
+	Functions []AccessFunction `json:"functions"`
+	Totals    map[string]int   `json:"totals"` // category -> number of functions, counted in each of their categories
+}
+
+type AccessFunction struct {
+	Contract   string        `json:"contract"`   // contract the function is called on
+	Function   string        `json:"function"`   // signature e.g. "withdraw(uint256)"
+	Location   Location      `json:"location"`   // of the function name, in the base declaring it for the inherited ones
+	Categories []string      `json:"categories"` // e.g. ["owner-only", "pause-gated"] or ["unguarded"]
+	Guards     []AccessGuard `json:"guards"`
+}
+
+type AccessGuard struct {
+	Kind     string   `json:"kind"`               // "owner-only", "role-gated", "pause-gated" or "other"
+	Subject  string   `json:"subject"`            // e.g. the owner "owner()", the role "MINTER_ROLE", "not paused" or the condition text
+	Modifier string   `json:"modifier,omitempty"` // modifier with the check; or empty if the check is in the function
+	Location Location `json:"location"`           // of the modifier invocation or of the check
+}
+
+func NewAccessReportResponse(id ID, result AccessReport) AccessReportResponse {
+	return AccessReportResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: result,
+	}
+}
@@ -0,0 +1,52 @@
+package lsp
+
+// ContractSizeRequest is a request of solbot outside of the LSP
+// specification: it returns the estimated bytecode sizes of the contracts
+// declared in the document, with the features contributing to them, so
+// that a client extension can show what to trim.
+type ContractSizeRequest struct {
+	Request
+	Params ContractSizeParams `json:"params"`
+}
+
+type ContractSizeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type ContractSizeResponse struct {
+	Response
+	Result []ContractSize `json:"result"`
+}
+
+// ContractSize is the estimated size of the runtime bytecode of a contract,
+// computed from its syntax tree without compiling it.
+type ContractSize struct {
+	Name      string               `json:"name"`
+	Range     Range                `json:"range"`     // range of the contract name
+	Estimate  int                  `json:"estimate"`  // estimated size in bytes
+	Threshold int                  `json:"threshold"` // size above which the contract is reported; or 0 if it's not
+	Factors   []ContractSizeFactor `json:"factors"`   // the largest first
+	Sources   []ContractSizeSource `json:"sources"`   // the contract and its bases in the order of the linearization
+}
+
+type ContractSizeFactor struct {
+	Name   string `json:"name"` // e.g. "revert-strings"
+	Count  int    `json:"count"`
+	Bytes  int    `json:"bytes"`
+	Detail string `json:"detail"` // e.g. "12 revert strings totaling 840 bytes; consider custom errors"
+}
+
+type ContractSizeSource struct {
+	Contract string `json:"contract"`
+	Bytes    int    `json:"bytes"`
+}
+
+func NewContractSizeResponse(id ID, result []ContractSize) ContractSizeResponse {
+	return ContractSizeResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: result,
+	}
+}
@@ -0,0 +1,72 @@
+package lsp
+
+// EvaluatePathRequest is a request of solbot outside of the LSP
+// specification: it executes a function with the values of its parameters
+// as far as the values are known, and returns the variables after every
+// executed statement, for a client extension showing them inline.
+type EvaluatePathRequest struct {
+	Request
+	Params EvaluatePathParams `json:"params"`
+}
+
+type EvaluatePathParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`           // inside of the function, if it's not named
+	Function     string                 `json:"function,omitempty"` // e.g. "computeFee" or "Vault.computeFee"
+	// Values of the parameters, and of the state variables and the globals
+	// if the function reads them e.g. {"amount": "1000000", "msg.value": "1 ether"}.
+	Arguments map[string]string `json:"arguments"`
+}
+
+type EvaluatePathResponse struct {
+	Response
+	Result *EvaluatePathResult `json:"result"`
+}
+
+type EvaluatePathResult struct {
+	Steps   []EvaluationStep `json:"steps"`             // executed statements in order
+	Returns []string         `json:"returns,omitempty"` // returned values if the function returned
+	Stop    *EvaluationStop  `json:"stop,omitempty"`    // where the evaluation stopped; or null if the function returned
+}
+
+// EvaluationStep is an executed statement, or the condition of a branch or
+// of a loop, with the values of the variables after it.
+type EvaluationStep struct {
+	Range     Range               `json:"range"`
+	Note      string              `json:"note,omitempty"` // e.g. "true" for a condition or "returns 3000"
+	Variables []EvaluatedVariable `json:"variables"`
+}
+
+type EvaluatedVariable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type EvaluationStop struct {
+	Range    Range  `json:"range"`
+	Reason   string `json:"reason"`
+	Reverted bool   `json:"reverted"` // does the function revert there, as opposed to doing something not evaluated?
+}
+
+func NewEvaluatePathResponse(id ID, result *EvaluatePathResult) EvaluatePathResponse {
+	return EvaluatePathResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: result,
+	}
+}
+
+func NewEvaluatePathErrorResponse(id ID, code int, message string) EvaluatePathResponse {
+	return EvaluatePathResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+			Error: &ResponseError{
+				Code:    code,
+				Message: message,
+			},
+		},
+	}
+}
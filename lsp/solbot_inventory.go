@@ -0,0 +1,68 @@
+package lsp
+
+// InventoryRequest is a request of solbot outside of the LSP specification:
+// it returns the source files of the workspace for the scope section of
+// the audit reports, grouped by directory, with their licenses, file-level
+// NatSpec, pragmas, line counts and hashes.
+type InventoryRequest struct {
+	Request
+	Params InventoryParams `json:"params"`
+}
+
+type InventoryParams struct {
+	IncludeDependencies bool `json:"includeDependencies"` // list the files of lib and node_modules too
+}
+
+type InventoryResponse struct {
+	Response
+	Result Inventory `json:"result"`
+}
+
+type Inventory struct {
+	Directories  []InventoryDirectory `json:"directories"`
+	Dependencies []InventoryDirectory `json:"dependencies,omitempty"` // only if they're included
+	Totals       InventoryTotals      `json:"totals"`                 // of the directories, without the dependencies
+	Licenses     []InventoryLicense   `json:"licenses"`               // the most common first
+}
+
+type InventoryDirectory struct {
+	Path   string          `json:"path"` // relative to the workspace root e.g. "src/tokens"
+	Files  []InventoryFile `json:"files"`
+	Totals InventoryTotals `json:"totals"`
+}
+
+type InventoryFile struct {
+	URI          string `json:"uri"`
+	Path         string `json:"path"`              // relative to the workspace root
+	License      string `json:"license,omitempty"` // SPDX identifier e.g. "MIT"
+	Title        string `json:"title,omitempty"`   // file-level @title
+	Author       string `json:"author,omitempty"`  // file-level @author
+	Notice       string `json:"notice,omitempty"`  // file-level @notice
+	Pragma       string `json:"pragma,omitempty"`  // solidity pragma e.g. "^0.8.20"
+	Lines        int    `json:"lines"`
+	SLOC         int    `json:"sloc"`         // lines with code
+	CommentLines int    `json:"commentLines"` // lines with only comments
+	Hash         string `json:"hash"`         // SHA-256 of the content
+}
+
+type InventoryTotals struct {
+	Files        int `json:"files"`
+	Lines        int `json:"lines"`
+	SLOC         int `json:"sloc"`
+	CommentLines int `json:"commentLines"`
+}
+
+type InventoryLicense struct {
+	License string `json:"license"` // or empty for the files without one
+	Files   int    `json:"files"`
+}
+
+func NewInventoryResponse(id ID, result Inventory) InventoryResponse {
+	return InventoryResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: result,
+	}
+}
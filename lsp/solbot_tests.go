@@ -0,0 +1,58 @@
+package lsp
+
+// RunTestCommand and DebugTestCommand are the commands of the test lenses.
+// They're run by the client, the server doesn't execute them. The
+// arguments are the words of the forge command line e.g. "forge", "test",
+// "--match-contract", "^VaultTest$", "--match-test", "^test_deposit$".
+const (
+	RunTestCommand   = "solbot.runTest"
+	DebugTestCommand = "solbot.debugTest"
+)
+
+// TestsRequest is a request of solbot outside of the LSP specification: it
+// returns the Foundry tests run by the contracts declared in the document,
+// the inherited ones included, for the test explorers of the clients.
+type TestsRequest struct {
+	Request
+	Params TestsParams `json:"params"`
+}
+
+type TestsParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type TestsResponse struct {
+	Response
+	Result []TestContract `json:"result"`
+}
+
+type TestContract struct {
+	Contract string     `json:"contract"`
+	Location Location   `json:"location"`        // of the contract name
+	SetUp    *Location  `json:"setUp,omitempty"` // of the setUp function name, in the base declaring it for the inherited one
+	Tests    []TestItem `json:"tests"`
+}
+
+type TestItem struct {
+	Name          string      `json:"name"`
+	Kind          string      `json:"kind"`          // "unit", "fuzz" or "invariant"
+	Location      Location    `json:"location"`      // of the function name, in the base declaring it for the inherited tests
+	ExpectsRevert bool        `json:"expectsRevert"` // does the test call vm.expectRevert or is it a testFail one?
+	FuzzParams    []TestParam `json:"fuzzParams"`    // parameters of the fuzz tests
+	Command       []string    `json:"command"`       // forge command line running the test alone
+}
+
+type TestParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // e.g. "uint256" or "address[]"
+}
+
+func NewTestsResponse(id ID, result []TestContract) TestsResponse {
+	return TestsResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: result,
+	}
+}
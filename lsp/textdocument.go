@@ -37,11 +37,58 @@ type Range struct {
 	End   Position `json:"end"`
 }
 
+// OptionalVersionedTextDocumentIdentifier has a nil version for documents
+// that are not open in the editor, i.e. the server read them from the disk.
+type OptionalVersionedTextDocumentIdentifier struct {
+	TextDocumentIdentifier
+	Version *int `json:"version"`
+}
+
+// WorkspaceEdit uses either the Changes map or the DocumentChanges list,
+// depending on what the client supports. DocumentChanges carry the document
+// versions, so the client can reject edits computed for stale content.
 type WorkspaceEdit struct {
-	Changes map[string][]TextEdit `json:"changes"`
+	Changes           map[string][]TextEdit       `json:"changes,omitempty"`
+	DocumentChanges   []any                       `json:"documentChanges,omitempty"` // TextDocumentEdit, RenameFile or CreateFile
+	ChangeAnnotations map[string]ChangeAnnotation `json:"changeAnnotations,omitempty"`
 }
 
 type TextEdit struct {
-	Range   Range  `json:"range"`
-	NewText string `json:"newText"`
+	Range        Range  `json:"range"`
+	NewText      string `json:"newText"`
+	AnnotationID string `json:"annotationId,omitempty"` // makes it an AnnotatedTextEdit
+}
+
+type TextDocumentEdit struct {
+	TextDocument OptionalVersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                              `json:"edits"`
+}
+
+// RenameFile is a resource operation. Clients announce the support for it
+// with the workspace.workspaceEdit.resourceOperations capability.
+type RenameFile struct {
+	Kind         string `json:"kind"` // always "rename"
+	OldURI       string `json:"oldUri"`
+	NewURI       string `json:"newUri"`
+	AnnotationID string `json:"annotationId,omitempty"`
+}
+
+// CreateFile is a resource operation like RenameFile, announced with the
+// "create" resource operation.
+type CreateFile struct {
+	Kind    string             `json:"kind"` // always "create"
+	URI     string             `json:"uri"`
+	Options *CreateFileOptions `json:"options,omitempty"`
+}
+
+type CreateFileOptions struct {
+	IgnoreIfExists bool `json:"ignoreIfExists,omitempty"`
+}
+
+// ChangeAnnotation with NeedsConfirmation lets the user opt out of a part of
+// the workspace edit, e.g. renaming the file together with the contract.
+type ChangeAnnotation struct {
+	Label             string `json:"label"`
+	NeedsConfirmation bool   `json:"needsConfirmation,omitempty"`
+	Description       string `json:"description,omitempty"`
 }
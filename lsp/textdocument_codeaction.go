@@ -12,8 +12,8 @@ type CodeActionParams struct {
 }
 
 type CodeActionContext struct {
-	// @TODO: Add the diagnostics that are shown to the client right now.
-	// Diagnostics []Diagnostic `json:"diagnostics"`
+	// Diagnostics shown to the client in the range right now.
+	Diagnostics []Diagnostic `json:"diagnostics"`
 }
 
 type CodeActionResponse struct {
@@ -21,10 +21,98 @@ type CodeActionResponse struct {
 	Result []CodeAction `json:"result"`
 }
 
+type CodeActionKind string
+
+const (
+	CodeActionQuickFix              CodeActionKind = "quickfix"
+	CodeActionRefactor              CodeActionKind = "refactor"
+	CodeActionRefactorExtract       CodeActionKind = "refactor.extract"
+	CodeActionSourceOrganizeImports CodeActionKind = "source.organizeImports"
+)
+
 type CodeAction struct {
-	Title   string         `json:"title"`
-	Edit    *WorkspaceEdit `json:"edit,omitempty"`
-	Command *Command       `json:"command,omitempty"`
+	Title       string              `json:"title"`
+	Kind        CodeActionKind      `json:"kind,omitempty"`
+	Diagnostics []Diagnostic        `json:"diagnostics,omitempty"` // diagnostics resolved by the action
+	Edit        *WorkspaceEdit      `json:"edit,omitempty"`
+	Command     *Command            `json:"command,omitempty"`     // executed after the edit is applied
+	IsPreferred bool                `json:"isPreferred,omitempty"` // safe to apply without a review e.g. by the fix all command
+	Disabled    *CodeActionDisabled `json:"disabled,omitempty"`    // the action can't be applied to the selection
+	Data        *CodeActionData     `json:"data,omitempty"`        // kept by the client until the action is resolved
+}
+
+// CodeActionData identifies the action in the codeAction/resolve request:
+// the version of the document it was offered for, the selection and the
+// anchor of the declaration around it.
+type CodeActionData struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+	Start   int    `json:"start"` // offset of the selection in the version
+	End     int    `json:"end"`
+	Anchor  string `json:"anchor"` // or empty outside of the declarations
+}
+
+// CodeActionDisabled is the reason the action is offered but can't be
+// applied e.g. the selection can't be extracted. The clients show it when
+// the action is picked.
+type CodeActionDisabled struct {
+	Reason string `json:"reason"`
+}
+
+type CodeActionOptions struct {
+	CodeActionKinds []CodeActionKind `json:"codeActionKinds,omitempty"` // kinds of the offered actions
+	ResolveProvider bool             `json:"resolveProvider"`
+}
+
+// CodeActionResolveRequest asks for the edit of the action, right before
+// the client applies it.
+type CodeActionResolveRequest struct {
+	Request
+	Params CodeAction `json:"params"`
 }
 
-type Command struct{}
+type CodeActionResolveResponse struct {
+	Response
+	// The result is null if the request failed e.g. the action is out of date.
+	Result *CodeAction `json:"result"`
+}
+
+// Command is executed by the server through workspace/executeCommand.
+type Command struct {
+	Title     string `json:"title"`
+	Command   string `json:"command"`
+	Arguments []any  `json:"arguments,omitempty"`
+}
+
+func NewCodeActionResponse(id ID, actions []CodeAction) CodeActionResponse {
+	return CodeActionResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: actions,
+	}
+}
+
+func NewCodeActionResolveResponse(id ID, action *CodeAction) CodeActionResolveResponse {
+	return CodeActionResolveResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: action,
+	}
+}
+
+func NewCodeActionResolveErrorResponse(id ID, code int, message string) CodeActionResolveResponse {
+	return CodeActionResolveResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+			Error: &ResponseError{
+				Code:    code,
+				Message: message,
+			},
+		},
+	}
+}
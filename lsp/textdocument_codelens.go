@@ -0,0 +1,106 @@
+package lsp
+
+// FindReferencesCommand is the command of the reference lenses. It's run
+// by the client, which sends the textDocument/references request for the
+// position. The arguments are the document URI and the position.
+const FindReferencesCommand = "editor.action.findReferences"
+
+type CodeLensRequest struct {
+	Request
+	Params CodeLensParams `json:"params"`
+}
+
+type CodeLensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type CodeLensResponse struct {
+	Response
+	Result []CodeLens `json:"result"`
+}
+
+// CodeLens is shown above the line of its range. A lens without a command
+// is resolved with the codeLens/resolve request once it's visible.
+type CodeLens struct {
+	Range   Range         `json:"range"`
+	Command *Command      `json:"command,omitempty"`
+	Data    *CodeLensData `json:"data,omitempty"` // kept by the client until the lens is resolved
+}
+
+// CodeLensData identifies the declaration of the lens in the
+// codeLens/resolve request: the version of the document the lens was
+// offered for, the range of the declared name and its anchor.
+type CodeLensData struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+	Start   int    `json:"start"` // offset of the name in the version
+	End     int    `json:"end"`
+	Anchor  string `json:"anchor"`
+}
+
+type CodeLensOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+// CodeLensResolveRequest asks for the command of the lens, right before
+// the client shows it.
+type CodeLensResolveRequest struct {
+	Request
+	Params CodeLens `json:"params"`
+}
+
+type CodeLensResolveResponse struct {
+	Response
+	// The result is null if the request failed e.g. the lens is out of date.
+	Result *CodeLens `json:"result"`
+}
+
+// CodeLensRefreshRequest is sent by the server to ask the client to request
+// the lenses of the visible documents again e.g. after the references
+// changed.
+type CodeLensRefreshRequest struct {
+	Request
+}
+
+func NewCodeLensResponse(id ID, lenses []CodeLens) CodeLensResponse {
+	return CodeLensResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: lenses,
+	}
+}
+
+func NewCodeLensResolveResponse(id ID, lens *CodeLens) CodeLensResolveResponse {
+	return CodeLensResolveResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: lens,
+	}
+}
+
+func NewCodeLensResolveErrorResponse(id ID, code int, message string) CodeLensResolveResponse {
+	return CodeLensResolveResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+			Error: &ResponseError{
+				Code:    code,
+				Message: message,
+			},
+		},
+	}
+}
+
+func NewCodeLensRefreshRequest(id ID) CodeLensRefreshRequest {
+	return CodeLensRefreshRequest{
+		Request: Request{
+			RPC:    "2.0",
+			ID:     id,
+			Method: "workspace/codeLens/refresh",
+		},
+	}
+}
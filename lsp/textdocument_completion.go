@@ -0,0 +1,61 @@
+package lsp
+
+type CompletionRequest struct {
+	Request
+	Params CompletionParams `json:"params"`
+}
+
+type CompletionParams struct {
+	TextDocumentPositionParams
+}
+
+type CompletionResponse struct {
+	Response
+	Result []CompletionItem `json:"result"`
+}
+
+type CompletionItemKind int
+
+const (
+	CompletionItemMethod     CompletionItemKind = 2
+	CompletionItemFunction   CompletionItemKind = 3
+	CompletionItemField      CompletionItemKind = 5
+	CompletionItemVariable   CompletionItemKind = 6
+	CompletionItemClass      CompletionItemKind = 7
+	CompletionItemInterface  CompletionItemKind = 8
+	CompletionItemModule     CompletionItemKind = 9
+	CompletionItemEnum       CompletionItemKind = 13
+	CompletionItemKeyword    CompletionItemKind = 14
+	CompletionItemFolder     CompletionItemKind = 19
+	CompletionItemEnumMember CompletionItemKind = 20
+	CompletionItemStruct     CompletionItemKind = 22
+	CompletionItemEvent      CompletionItemKind = 23
+)
+
+type CompletionItem struct {
+	Label         string              `json:"label"`
+	Kind          CompletionItemKind  `json:"kind,omitempty"`
+	Detail        string              `json:"detail,omitempty"` // e.g. the signature
+	Documentation *MarkupContent      `json:"documentation,omitempty"`
+	SortText      string              `json:"sortText,omitempty"` // the order of the items, the label if it's empty
+	Tags          []CompletionItemTag `json:"tags,omitempty"`
+	TextEdit      *TextEdit           `json:"textEdit,omitempty"` // replaces the text typed so far e.g. the last segment of a path
+}
+
+type CompletionItemTag int
+
+const CompletionItemTagDeprecated CompletionItemTag = 1 // struck through by the clients
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+func NewCompletionResponse(id ID, items []CompletionItem) CompletionResponse {
+	return CompletionResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: items,
+	}
+}
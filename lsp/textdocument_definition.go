@@ -16,7 +16,7 @@ type DefinitionResponse struct {
 	Result *[]Location `json:"result,omitempty"`
 }
 
-func NewDefinitionResponse(id int, locations *[]Location) DefinitionResponse {
+func NewDefinitionResponse(id ID, locations *[]Location) DefinitionResponse {
 	return DefinitionResponse{
 		Response: Response{
 			RPC: "2.0",
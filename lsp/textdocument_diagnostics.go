@@ -0,0 +1,154 @@
+package lsp
+
+type PublishDiagnosticsNotification struct {
+	Notification
+	Params PublishDiagnosticsParams `json:"params"`
+}
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type DiagnosticSeverity int
+
+const (
+	SeverityError       DiagnosticSeverity = 1
+	SeverityWarning     DiagnosticSeverity = 2
+	SeverityInformation DiagnosticSeverity = 3
+	SeverityHint        DiagnosticSeverity = 4
+)
+
+type DiagnosticTag int
+
+const (
+	TagUnnecessary DiagnosticTag = 1 // unused or unreachable code, faded out by the clients
+	TagDeprecated  DiagnosticTag = 2
+)
+
+type Diagnostic struct {
+	Range              Range                          `json:"range"`
+	Severity           DiagnosticSeverity             `json:"severity,omitempty"`
+	Code               string                         `json:"code,omitempty"`
+	CodeDescription    *CodeDescription               `json:"codeDescription,omitempty"`
+	Source             string                         `json:"source,omitempty"`
+	Message            string                         `json:"message"`
+	Tags               []DiagnosticTag                `json:"tags,omitempty"`
+	RelatedInformation []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+	Data               *DiagnosticData                `json:"data,omitempty"` // set on the published diagnostics only
+}
+
+// CodeDescription links the code of a diagnostic to its documentation,
+// which the clients open from the code.
+type CodeDescription struct {
+	Href string `json:"href"`
+}
+
+// DiagnosticData tags a published diagnostic with the revision of the
+// workspace it was computed for. The diagnostics of the documents affected
+// by the same edits carry the same revision, so the tools can check that
+// the client never sees them computed from different sources. The moniker
+// of the declaration with the diagnostic lets the external tools join it
+// to the symbol across the runs and the clones of the repository.
+type DiagnosticData struct {
+	Revision int      `json:"revision,omitempty"`
+	Pending  []string `json:"pending,omitempty"` // URIs of the documents of the revision not analyzed in time, see the holdback of the server
+	Moniker  string   `json:"moniker,omitempty"` // e.g. "solbot:src/Vault.sol:Vault.deposit(uint256)"
+}
+
+// DiagnosticRelatedInformation points to the code causing the diagnostic
+// or explaining it e.g. the declaration of a base contract.
+type DiagnosticRelatedInformation struct {
+	Location Location `json:"location"`
+	Message  string   `json:"message"`
+}
+
+func NewPublishDiagnosticsNotification(uri string, version *int, diagnostics []Diagnostic) PublishDiagnosticsNotification {
+	return PublishDiagnosticsNotification{
+		Notification: Notification{
+			RPC:    "2.0",
+			Method: "textDocument/publishDiagnostics",
+		},
+		Params: PublishDiagnosticsParams{
+			URI:         uri,
+			Version:     version,
+			Diagnostics: diagnostics,
+		},
+	}
+}
+
+type DiagnosticClientCapabilities struct {
+	// Does the client use the relatedDocuments of the reports?
+	RelatedDocumentSupport bool `json:"relatedDocumentSupport"`
+}
+
+type DiagnosticWorkspaceClientCapabilities struct {
+	// Does the client pull the diagnostics again after the server sends
+	// the workspace/diagnostic/refresh request?
+	RefreshSupport bool `json:"refreshSupport"`
+}
+
+// DiagnosticOptions announce the pull model of the diagnostics.
+type DiagnosticOptions struct {
+	// Can an edit of a document change the diagnostics of the others e.g.
+	// of the ones importing it?
+	InterFileDependencies bool `json:"interFileDependencies"`
+	// Does the server answer the workspace/diagnostic request?
+	WorkspaceDiagnostics bool `json:"workspaceDiagnostics"`
+}
+
+// DocumentDiagnosticRequest pulls the diagnostics of a document, instead of
+// waiting for textDocument/publishDiagnostics.
+type DocumentDiagnosticRequest struct {
+	Request
+	Params DocumentDiagnosticParams `json:"params"`
+}
+
+type DocumentDiagnosticParams struct {
+	TextDocument     TextDocumentIdentifier `json:"textDocument"`
+	PreviousResultID string                 `json:"previousResultId"` // result ID of the last report the client got; or empty
+}
+
+type DocumentDiagnosticResponse struct {
+	Response
+	Result *DocumentDiagnosticReport `json:"result"`
+}
+
+// Kinds of the diagnostic reports.
+const (
+	ReportFull      = "full"
+	ReportUnchanged = "unchanged" // the diagnostics of the previous result ID are still valid
+)
+
+// DocumentDiagnosticReport is either a full report with all of the
+// diagnostics, or a report telling the client to keep the diagnostics of
+// the previous result ID. The items of an unchanged report are null.
+type DocumentDiagnosticReport struct {
+	Kind     string       `json:"kind"`
+	ResultID string       `json:"resultId,omitempty"`
+	Items    []Diagnostic `json:"items"`
+}
+
+func NewDocumentDiagnosticResponse(id ID, report DocumentDiagnosticReport) DocumentDiagnosticResponse {
+	return DocumentDiagnosticResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: &report,
+	}
+}
+
+func NewDocumentDiagnosticErrorResponse(id ID, code int, message string) DocumentDiagnosticResponse {
+	return DocumentDiagnosticResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+			Error: &ResponseError{
+				Code:    code,
+				Message: message,
+			},
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package lsp
+
+type DocumentSymbolRequest struct {
+	Request
+	Params DocumentSymbolParams `json:"params"`
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type DocumentSymbolResponse struct {
+	Response
+	Result []DocumentSymbol `json:"result"`
+}
+
+type SymbolKind int
+
+// Only the kinds the Solidity declarations are shown as.
+const (
+	SymbolKindModule        SymbolKind = 2
+	SymbolKindClass         SymbolKind = 5
+	SymbolKindMethod        SymbolKind = 6
+	SymbolKindProperty      SymbolKind = 7
+	SymbolKindField         SymbolKind = 8
+	SymbolKindConstructor   SymbolKind = 9
+	SymbolKindEnum          SymbolKind = 10
+	SymbolKindInterface     SymbolKind = 11
+	SymbolKindFunction      SymbolKind = 12
+	SymbolKindVariable      SymbolKind = 13
+	SymbolKindConstant      SymbolKind = 14
+	SymbolKindEnumMember    SymbolKind = 22
+	SymbolKindStruct        SymbolKind = 23
+	SymbolKindEvent         SymbolKind = 24
+	SymbolKindTypeParameter SymbolKind = 26
+)
+
+type SymbolTag int
+
+const (
+	SymbolTagDeprecated SymbolTag = 1 // struck through by the clients
+)
+
+// DocumentSymbol is an entry of the outline of the document. The range
+// spans the whole declaration, and the selection range its name.
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"` // e.g. the parameters of a function
+	Kind           SymbolKind       `json:"kind"`
+	Tags           []SymbolTag      `json:"tags,omitempty"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+func NewDocumentSymbolResponse(id ID, symbols []DocumentSymbol) DocumentSymbolResponse {
+	return DocumentSymbolResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: symbols,
+	}
+}
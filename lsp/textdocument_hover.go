@@ -15,11 +15,22 @@ type HoverResponse struct {
 }
 
 type HoverResult struct {
-	// @TODO: This should be changed to MarkupContent
-	Contents string `json:"contents"`
+	Contents MarkupContent `json:"contents"`
 }
 
-func NewHoverResponse(id int, contents string) HoverResponse {
+// MarkupContent is the text the client renders either as Markdown or as it
+// is, depending on its kind.
+type MarkupContent struct {
+	Kind  string `json:"kind"` // Markdown or PlainText
+	Value string `json:"value"`
+}
+
+const (
+	Markdown  = "markdown"
+	PlainText = "plaintext"
+)
+
+func NewHoverResponse(id ID, contents MarkupContent) HoverResponse {
 	return HoverResponse{
 		Response: Response{
 			RPC: "2.0",
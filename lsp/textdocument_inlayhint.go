@@ -0,0 +1,33 @@
+package lsp
+
+type InlayHintRequest struct {
+	Request
+	Params InlayHintParams `json:"params"`
+}
+
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"` // visible part of the document
+}
+
+type InlayHintResponse struct {
+	Response
+	Result []InlayHint `json:"result"`
+}
+
+type InlayHint struct {
+	Position    Position `json:"position"`
+	Label       string   `json:"label"`
+	Tooltip     string   `json:"tooltip,omitempty"`
+	PaddingLeft bool     `json:"paddingLeft,omitempty"`
+}
+
+func NewInlayHintResponse(id ID, hints []InlayHint) InlayHintResponse {
+	return InlayHintResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: hints,
+	}
+}
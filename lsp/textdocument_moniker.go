@@ -0,0 +1,63 @@
+package lsp
+
+type MonikerRequest struct {
+	Request
+	Params MonikerParams `json:"params"`
+}
+
+type MonikerParams struct {
+	TextDocumentPositionParams
+}
+
+type MonikerResponse struct {
+	Response
+	Result []Moniker `json:"result"`
+}
+
+// UniquenessLevel is the scope in which the identifier of a moniker is
+// unique.
+type UniquenessLevel string
+
+const (
+	UniqueDocument UniquenessLevel = "document"
+	UniqueProject  UniquenessLevel = "project"
+	UniqueGroup    UniquenessLevel = "group"
+	UniqueScheme   UniquenessLevel = "scheme"
+	UniqueGlobal   UniquenessLevel = "global"
+)
+
+// MonikerKind tells whether the symbol is declared by the project, by a
+// dependency or is local to a function.
+type MonikerKind string
+
+const (
+	MonikerImport MonikerKind = "import"
+	MonikerExport MonikerKind = "export"
+	MonikerLocal  MonikerKind = "local"
+)
+
+// Moniker identifies a symbol across the projects and their indexes e.g.
+// the scheme "solbot" and the identifier
+// "src/Vault.sol:Vault.deposit(uint256)".
+type Moniker struct {
+	Scheme     string          `json:"scheme"`
+	Identifier string          `json:"identifier"`
+	Unique     UniquenessLevel `json:"unique"`
+	Kind       MonikerKind     `json:"kind,omitempty"`
+}
+
+// String returns the scheme and the identifier joined by a colon, the form
+// the monikers take in the data of the diagnostics.
+func (m Moniker) String() string {
+	return m.Scheme + ":" + m.Identifier
+}
+
+func NewMonikerResponse(id ID, monikers []Moniker) MonikerResponse {
+	return MonikerResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: monikers,
+	}
+}
@@ -0,0 +1,34 @@
+package lsp
+
+type ReferencesRequest struct {
+	Request
+	Params ReferenceParams `json:"params"`
+}
+
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context ReferenceContext `json:"context"`
+}
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+
+	// IncludeDocumentation is an extension of solbot: the names in the
+	// NatSpec e.g. of `@param amount` are included unless it's false.
+	IncludeDocumentation *bool `json:"includeDocumentation,omitempty"`
+}
+
+type ReferencesResponse struct {
+	Response
+	Result []Location `json:"result"`
+}
+
+func NewReferencesResponse(id ID, locations []Location) ReferencesResponse {
+	return ReferencesResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: locations,
+	}
+}
@@ -0,0 +1,40 @@
+package lsp
+
+type RenameRequest struct {
+	Request
+	Params RenameParams `json:"params"`
+}
+
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+type RenameResponse struct {
+	Response
+	// The result is null if there is nothing to rename or the request failed.
+	Result *WorkspaceEdit `json:"result"`
+}
+
+func NewRenameResponse(id ID, edit *WorkspaceEdit) RenameResponse {
+	return RenameResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: edit,
+	}
+}
+
+func NewRenameErrorResponse(id ID, code int, message string) RenameResponse {
+	return RenameResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+			Error: &ResponseError{
+				Code:    code,
+				Message: message,
+			},
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package lsp
+
+type SignatureHelpRequest struct {
+	Request
+	Params SignatureHelpParams `json:"params"`
+}
+
+type SignatureHelpParams struct {
+	TextDocumentPositionParams
+}
+
+type SignatureHelpResponse struct {
+	Response
+	Result *SignatureHelp `json:"result"` // or null outside of the calls
+}
+
+// SignatureHelp shows the signature of the called function and the
+// parameter of the argument under the cursor.
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+type SignatureInformation struct {
+	Label         string                 `json:"label"` // e.g. "function transfer(address to, uint256 amount)"
+	Documentation *MarkupContent         `json:"documentation,omitempty"`
+	Parameters    []ParameterInformation `json:"parameters"`
+}
+
+type ParameterInformation struct {
+	// Start and end offsets of the parameter in the label of the signature.
+	Label [2]int `json:"label"`
+}
+
+type SignatureHelpOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+func NewSignatureHelpResponse(id ID, help *SignatureHelp) SignatureHelpResponse {
+	return SignatureHelpResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: help,
+	}
+}
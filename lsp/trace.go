@@ -0,0 +1,43 @@
+package lsp
+
+// Values of the trace setting.
+const (
+	TraceOff      = "off"
+	TraceMessages = "messages"
+	TraceVerbose  = "verbose"
+)
+
+// The client changes the trace setting with the $/setTrace notification.
+type SetTraceNotification struct {
+	Notification
+	Params SetTraceParams `json:"params"`
+}
+
+type SetTraceParams struct {
+	Value string `json:"value"`
+}
+
+// The server traces its execution with the $/logTrace notification. It's
+// sent only if the trace setting is not "off".
+type LogTraceNotification struct {
+	Notification
+	Params LogTraceParams `json:"params"`
+}
+
+type LogTraceParams struct {
+	Message string `json:"message"`
+	Verbose string `json:"verbose,omitempty"` // only if the trace setting is "verbose"
+}
+
+func NewLogTraceNotification(message, verbose string) LogTraceNotification {
+	return LogTraceNotification{
+		Notification: Notification{
+			RPC:    "2.0",
+			Method: "$/logTrace",
+		},
+		Params: LogTraceParams{
+			Message: message,
+			Verbose: verbose,
+		},
+	}
+}
@@ -0,0 +1,35 @@
+package lsp
+
+type MessageType int
+
+const (
+	MessageError   MessageType = 1
+	MessageWarning MessageType = 2
+	MessageInfo    MessageType = 3
+	MessageLog     MessageType = 4
+)
+
+// The server asks the client to show a message to the user with the
+// window/showMessage notification.
+type ShowMessageNotification struct {
+	Notification
+	Params ShowMessageParams `json:"params"`
+}
+
+type ShowMessageParams struct {
+	Type    MessageType `json:"type"`
+	Message string      `json:"message"`
+}
+
+func NewShowMessageNotification(messageType MessageType, message string) ShowMessageNotification {
+	return ShowMessageNotification{
+		Notification: Notification{
+			RPC:    "2.0",
+			Method: "window/showMessage",
+		},
+		Params: ShowMessageParams{
+			Type:    messageType,
+			Message: message,
+		},
+	}
+}
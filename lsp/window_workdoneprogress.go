@@ -0,0 +1,66 @@
+package lsp
+
+// WorkDoneProgressCreateRequest asks the client to create the progress
+// token, which the server then reports the progress of its own work with
+// e.g. indexing the workspace.
+type WorkDoneProgressCreateRequest struct {
+	Request
+	Params WorkDoneProgressCreateParams `json:"params"`
+}
+
+type WorkDoneProgressCreateParams struct {
+	Token string `json:"token"`
+}
+
+// WorkDoneProgressNotification reports the beginning, the progress or the
+// end of the work with the $/progress notification.
+type WorkDoneProgressNotification struct {
+	Notification
+	Params WorkDoneProgressParams `json:"params"`
+}
+
+type WorkDoneProgressParams struct {
+	Token string           `json:"token"`
+	Value WorkDoneProgress `json:"value"`
+}
+
+type WorkDoneProgress struct {
+	Kind       string `json:"kind"`            // "begin", "report" or "end"
+	Title      string `json:"title,omitempty"` // only in "begin"
+	Message    string `json:"message,omitempty"`
+	Percentage *int   `json:"percentage,omitempty"` // 0 to 100; not in "end"
+}
+
+func NewWorkDoneProgressCreateRequest(id ID, token string) WorkDoneProgressCreateRequest {
+	return WorkDoneProgressCreateRequest{
+		Request: Request{
+			RPC:    "2.0",
+			ID:     id,
+			Method: "window/workDoneProgress/create",
+		},
+		Params: WorkDoneProgressCreateParams{Token: token},
+	}
+}
+
+func NewWorkDoneProgressBegin(token, title, message string) WorkDoneProgressNotification {
+	percentage := 0
+	return newWorkDoneProgressNotification(token, WorkDoneProgress{Kind: "begin", Title: title, Message: message, Percentage: &percentage})
+}
+
+func NewWorkDoneProgressReport(token, message string, percentage int) WorkDoneProgressNotification {
+	return newWorkDoneProgressNotification(token, WorkDoneProgress{Kind: "report", Message: message, Percentage: &percentage})
+}
+
+func NewWorkDoneProgressEnd(token, message string) WorkDoneProgressNotification {
+	return newWorkDoneProgressNotification(token, WorkDoneProgress{Kind: "end", Message: message})
+}
+
+func newWorkDoneProgressNotification(token string, value WorkDoneProgress) WorkDoneProgressNotification {
+	return WorkDoneProgressNotification{
+		Notification: Notification{
+			RPC:    "2.0",
+			Method: "$/progress",
+		},
+		Params: WorkDoneProgressParams{Token: token, Value: value},
+	}
+}
@@ -0,0 +1,54 @@
+package lsp
+
+import "encoding/json"
+
+// ConfigurationRequest is sent by the server to fetch the settings of the
+// sections from the client e.g. the "solbot" section of the editor
+// settings.
+type ConfigurationRequest struct {
+	Request
+	Params ConfigurationParams `json:"params"`
+}
+
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+type ConfigurationItem struct {
+	ScopeURI string `json:"scopeUri,omitempty"`
+	Section  string `json:"section,omitempty"`
+}
+
+// ConfigurationResponse carries the settings in the order of the items;
+// null for the sections the client doesn't know.
+type ConfigurationResponse struct {
+	Response
+	Result []json.RawMessage `json:"result"`
+}
+
+// DidChangeConfigurationNotification tells the server that the settings
+// changed. The clients supporting workspace/configuration may send null
+// settings, so that the server fetches them itself.
+type DidChangeConfigurationNotification struct {
+	Notification
+	Params DidChangeConfigurationParams `json:"params"`
+}
+
+type DidChangeConfigurationParams struct {
+	Settings json.RawMessage `json:"settings"`
+}
+
+func NewConfigurationRequest(id ID, sections ...string) ConfigurationRequest {
+	items := []ConfigurationItem{}
+	for _, section := range sections {
+		items = append(items, ConfigurationItem{Section: section})
+	}
+	return ConfigurationRequest{
+		Request: Request{
+			RPC:    "2.0",
+			ID:     id,
+			Method: "workspace/configuration",
+		},
+		Params: ConfigurationParams{Items: items},
+	}
+}
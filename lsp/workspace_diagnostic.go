@@ -0,0 +1,93 @@
+package lsp
+
+import "encoding/json"
+
+// WorkspaceDiagnosticRequest pulls the diagnostics of all of the documents
+// in one round trip. With a partial result token the reports are streamed
+// with $/progress as the documents are analyzed, and the response itself
+// has no items.
+type WorkspaceDiagnosticRequest struct {
+	Request
+	Params WorkspaceDiagnosticParams `json:"params"`
+}
+
+type WorkspaceDiagnosticParams struct {
+	PreviousResultIDs  []PreviousResultID `json:"previousResultIds"`
+	PartialResultToken json.RawMessage    `json:"partialResultToken,omitempty"` // integer or string; or empty
+}
+
+// PreviousResultID is the result ID of the last report of a document the
+// client got.
+type PreviousResultID struct {
+	URI   string `json:"uri"`
+	Value string `json:"value"`
+}
+
+type WorkspaceDiagnosticResponse struct {
+	Response
+	Result *WorkspaceDiagnosticReport `json:"result"`
+}
+
+type WorkspaceDiagnosticReport struct {
+	Items []WorkspaceDocumentDiagnosticReport `json:"items"`
+}
+
+// WorkspaceDocumentDiagnosticReport is the report of a document in the
+// workspace diagnostics.
+type WorkspaceDocumentDiagnosticReport struct {
+	DocumentDiagnosticReport
+	URI     string `json:"uri"`
+	Version *int   `json:"version"` // version of the open document; or null
+}
+
+// WorkspaceDiagnosticProgressNotification streams a part of the reports of
+// the workspace/diagnostic request with the partial result token.
+type WorkspaceDiagnosticProgressNotification struct {
+	Notification
+	Params WorkspaceDiagnosticProgressParams `json:"params"`
+}
+
+type WorkspaceDiagnosticProgressParams struct {
+	Token json.RawMessage           `json:"token"`
+	Value WorkspaceDiagnosticReport `json:"value"`
+}
+
+// WorkspaceDiagnosticRefreshRequest is sent by the server to ask the client
+// to pull the diagnostics again e.g. after a document imported by the
+// others changed.
+type WorkspaceDiagnosticRefreshRequest struct {
+	Request
+}
+
+func NewWorkspaceDiagnosticResponse(id ID, reports []WorkspaceDocumentDiagnosticReport) WorkspaceDiagnosticResponse {
+	return WorkspaceDiagnosticResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: &WorkspaceDiagnosticReport{Items: reports},
+	}
+}
+
+func NewWorkspaceDiagnosticProgressNotification(token json.RawMessage, reports []WorkspaceDocumentDiagnosticReport) WorkspaceDiagnosticProgressNotification {
+	return WorkspaceDiagnosticProgressNotification{
+		Notification: Notification{
+			RPC:    "2.0",
+			Method: "$/progress",
+		},
+		Params: WorkspaceDiagnosticProgressParams{
+			Token: token,
+			Value: WorkspaceDiagnosticReport{Items: reports},
+		},
+	}
+}
+
+func NewWorkspaceDiagnosticRefreshRequest(id ID) WorkspaceDiagnosticRefreshRequest {
+	return WorkspaceDiagnosticRefreshRequest{
+		Request: Request{
+			RPC:    "2.0",
+			ID:     id,
+			Method: "workspace/diagnostic/refresh",
+		},
+	}
+}
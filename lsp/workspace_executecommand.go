@@ -0,0 +1,49 @@
+package lsp
+
+import "encoding/json"
+
+// PreviewMigrationCommand turns on the migration diagnostics of a document.
+// The arguments are the document URI and the target pragma e.g. "^0.8.0".
+const PreviewMigrationCommand = "solbot.previewMigration"
+
+type ExecuteCommandRequest struct {
+	Request
+	Params ExecuteCommandParams `json:"params"`
+}
+
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments"`
+}
+
+type ExecuteCommandResponse struct {
+	Response
+	Result any `json:"result"` // always null
+}
+
+// ExecuteCommandOptions lists the commands supported by the server.
+type ExecuteCommandOptions struct {
+	Commands []string `json:"commands"`
+}
+
+func NewExecuteCommandResponse(id ID) ExecuteCommandResponse {
+	return ExecuteCommandResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+	}
+}
+
+func NewExecuteCommandErrorResponse(id ID, code int, message string) ExecuteCommandResponse {
+	return ExecuteCommandResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+			Error: &ResponseError{
+				Code:    code,
+				Message: message,
+			},
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package lsp
+
+// The client asks the server for the edits to apply together with the
+// renames of the files with workspace/willRenameFiles, and tells it about
+// the applied renames with workspace/didRenameFiles.
+type WillRenameFilesRequest struct {
+	Request
+	Params RenameFilesParams `json:"params"`
+}
+
+type DidRenameFilesNotification struct {
+	Notification
+	Params RenameFilesParams `json:"params"`
+}
+
+type RenameFilesParams struct {
+	Files []FileRename `json:"files"`
+}
+
+type FileRename struct {
+	OldURI string `json:"oldUri"`
+	NewURI string `json:"newUri"`
+}
+
+type WillRenameFilesResponse struct {
+	Response
+	// The result is null if there is nothing to edit.
+	Result *WorkspaceEdit `json:"result"`
+}
+
+// WorkspaceServerCapabilities are the capabilities of the server for the
+// whole workspace.
+type WorkspaceServerCapabilities struct {
+	FileOperations *FileOperationOptions `json:"fileOperations,omitempty"`
+}
+
+// FileOperationOptions lists the files the server wants to hear about when
+// they're renamed.
+type FileOperationOptions struct {
+	DidRename  *FileOperationRegistrationOptions `json:"didRename,omitempty"`
+	WillRename *FileOperationRegistrationOptions `json:"willRename,omitempty"`
+}
+
+type FileOperationRegistrationOptions struct {
+	Filters []FileOperationFilter `json:"filters"`
+}
+
+type FileOperationFilter struct {
+	Scheme  string               `json:"scheme,omitempty"` // e.g. "file"
+	Pattern FileOperationPattern `json:"pattern"`
+}
+
+type FileOperationPattern struct {
+	Glob    string `json:"glob"`              // e.g. "**/*.sol"
+	Matches string `json:"matches,omitempty"` // "file" or "folder"; or empty for both
+}
+
+// SolidityFiles matches the Solidity files on the disk.
+var SolidityFiles = &FileOperationRegistrationOptions{
+	Filters: []FileOperationFilter{{Scheme: "file", Pattern: FileOperationPattern{Glob: "**/*.sol", Matches: "file"}}},
+}
+
+func NewWillRenameFilesResponse(id ID, edit *WorkspaceEdit) WillRenameFilesResponse {
+	return WillRenameFilesResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: edit,
+	}
+}
@@ -0,0 +1,36 @@
+package lsp
+
+type WorkspaceSymbolRequest struct {
+	Request
+	Params WorkspaceSymbolParams `json:"params"`
+}
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"` // e.g. "Vault"; or empty for all of the symbols
+}
+
+type WorkspaceSymbolResponse struct {
+	Response
+	Result []SymbolInformation `json:"result"`
+}
+
+// SymbolInformation is a declaration found in the workspace. The clients
+// show the container next to the name, which tells apart the declarations
+// of the same name in different files.
+type SymbolInformation struct {
+	Name          string      `json:"name"`
+	Kind          SymbolKind  `json:"kind"`
+	Tags          []SymbolTag `json:"tags,omitempty"`
+	Location      Location    `json:"location"`
+	ContainerName string      `json:"containerName,omitempty"` // e.g. "src/Vault.sol:Vault"
+}
+
+func NewWorkspaceSymbolResponse(id ID, symbols []SymbolInformation) WorkspaceSymbolResponse {
+	return WorkspaceSymbolResponse{
+		Response: Response{
+			RPC: "2.0",
+			ID:  &id,
+		},
+		Result: symbols,
+	}
+}
@@ -0,0 +1,25 @@
+package lsp
+
+// The client tells the server about the files changed on the disk, e.g. by
+// git or by the build, with workspace/didChangeWatchedFiles.
+type DidChangeWatchedFilesNotification struct {
+	Notification
+	Params DidChangeWatchedFilesParams `json:"params"`
+}
+
+type DidChangeWatchedFilesParams struct {
+	Changes []FileEvent `json:"changes"`
+}
+
+type FileEvent struct {
+	URI  string         `json:"uri"`
+	Type FileChangeType `json:"type"`
+}
+
+type FileChangeType int
+
+const (
+	FileCreated FileChangeType = 1
+	FileChanged FileChangeType = 2
+	FileDeleted FileChangeType = 3
+)
@@ -1,160 +1,2519 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
+	"solbot/access"
 	"solbot/analyzer"
+	"solbot/ast"
+	"solbot/audit"
+	"solbot/baseline"
+	"solbot/clones"
 	"solbot/lsp"
 	"solbot/lsp/analysis"
-	"solbot/lsp/rpc"
 	"solbot/parser"
+	"solbot/project"
+	"solbot/query"
+	"solbot/render"
 	"solbot/reporter"
+	"solbot/sarif"
+	"solbot/semver"
+	"solbot/standardjson"
+	"solbot/textedit"
 	"solbot/token"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 )
 
+// version is set at build time e.g.
+//
+//	go build -ldflags "-X main.version=v0.3.0"
+var version = "dev"
+
+const usage = `Usage: solbot <command> [flags]
+
+Commands:
+  lsp            Start the language server
+  parse          Check the syntax of a file
+  analyze        Analyze a file and write the report to solbot.md
+  baseline       Record the accepted findings, prune the stale ones or summarize them
+  compile-input  Write solc's standard JSON input for a file
+  verify-sources Check the local sources against the metadata of a verified contract
+  metrics        Print the functions with the highest complexity, the largest contracts, their NatSpec coverage or the minimum compiler versions
+  eval-check     Check a snippet and print the types of its expressions
+  proxy-check    Compare the storage layouts of a proxy and its implementation
+  access-report  Print who can call the functions and when e.g. owner-only, pause-gated
+  deploy-params  Print the constructor parameters of a contract and the state variables they set
+  clones         Print the groups of the functions whose bodies are copies of each other
+  audit-diff     Compare the declarations with the hashes recorded at the last audit
+  tests          List the Foundry tests and the forge commands running them
+  inventory      List the source files with their licenses, pragmas and SLOC for the audit scope
+  docs-check     Check the Solidity code blocks of the Markdown docs
+  fix            Apply the quick fixes to the files e.g. organize the imports
+  extract-repro  Reduce the workspace to the sources reproducing a diagnostic, for the bug reports
+  query          Print the nodes matching a selector e.g. 'function > call[callee=*.delegatecall]'
+  trace          Evaluate a function with the given arguments and print the variables
+  rules          List the detectors and whether they are enabled
+  explain        Explain the findings of a detector e.g. solbot explain msg-value-loop
+  new-detector   Write the skeleton of a detector, its fixture and its test
+  replay         Replay a recorded LSP session and compare the responses
+  difftest       Compare the parse trees with solc's and report where they diverge
+  version        Print the version
+
+Run 'solbot <command> --help' for the flags of a command.
+`
+
 func main() {
-	mode := flag.String("mode", "analyzer", "Operation mode: lsp or analyzer")
-	filePath := flag.String("file", "", "File path to analyze")
-	flag.Parse()
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command and returns the exit code: 0 on success, 1 on an
+// error and 2 on an invalid usage.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "lsp":
+		return startLanguageServer(args[1:], stdin, stdout, stderr)
+	case "parse":
+		return startParse(args[1:], stderr)
+	case "analyze":
+		return startAnalyze(args[1:], stdout, stderr)
+	case "baseline":
+		return startBaseline(args[1:], stdout, stderr)
+	case "compile-input":
+		return startCompileInput(args[1:], stdout, stderr)
+	case "verify-sources":
+		return startVerifySources(args[1:], stdout, stderr)
+	case "metrics":
+		return startMetrics(args[1:], stdout, stderr)
+	case "eval-check":
+		return startEvalCheck(args[1:], stdout, stderr)
+	case "proxy-check":
+		return startProxyCheck(args[1:], stdout, stderr)
+	case "access-report":
+		return startAccessReport(args[1:], stdout, stderr)
+	case "deploy-params":
+		return startDeployParams(args[1:], stdout, stderr)
+	case "clones":
+		return startClones(args[1:], stdout, stderr)
+	case "audit-diff":
+		return startAuditDiff(args[1:], stdout, stderr)
+	case "tests":
+		return startTests(args[1:], stdout, stderr)
+	case "inventory":
+		return startInventory(args[1:], stdout, stderr)
+	case "docs-check":
+		return startDocsCheck(args[1:], stdout, stderr)
+	case "fix":
+		return startFix(args[1:], stdout, stderr)
+	case "extract-repro":
+		return startExtractRepro(args[1:], stdout, stderr)
+	case "query":
+		return startQuery(args[1:], stdout, stderr)
+	case "trace":
+		return startTrace(args[1:], stdout, stderr)
+	case "rules":
+		return startRules(args[1:], stdout, stderr)
+	case "explain":
+		return startExplain(args[1:], stdout, stderr)
+	case "new-detector":
+		return startNewDetector(args[1:], stdout, stderr)
+	case "replay":
+		return startReplay(args[1:], stdout, stderr)
+	case "difftest":
+		return startDifftest(args[1:], stdout, stderr)
+	case "version", "-version", "--version":
+		fmt.Fprintln(stdout, versionString())
+		return 0
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	case "-stdio", "--stdio":
+		// Some editors launch the server with the transport flag only.
+		return startLanguageServer(args, stdin, stdout, stderr)
+	}
+	if strings.HasPrefix(args[0], "-") {
+		return runLegacy(args, stdin, stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "Unknown command: `%s`\n\n%s", args[0], usage)
+	return 2
+}
+
+// versionString returns the version of solbot together with the Solidity
+// versions it can parse e.g. "solbot v0.3.0 (Solidity >=0.6.0 <0.8.29)".
+func versionString() string {
+	return fmt.Sprintf("solbot %s (Solidity %s)", version, parser.Grammar)
+}
+
+// runLegacy supports the flags used before the subcommands e.g.
+// `solbot --mode lsp`, so that the existing editor configurations keep
+// working.
+func runLegacy(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("solbot", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	mode := fs.String("mode", "analyzer", "Operation mode: lsp or analyzer")
+	filePath := fs.String("file", "", "File path to analyze")
+	trace := fs.Bool("trace", false, "Log the full content of the LSP messages")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "Unexpected argument: `%s`\n\n%s", fs.Arg(0), usage)
+		return 2
+	}
 
 	switch *mode {
 	case "lsp":
-		startLanguageServer()
+		lspArgs := []string{}
+		if *trace {
+			lspArgs = append(lspArgs, "--trace")
+		}
+		return startLanguageServer(lspArgs, stdin, stdout, stderr)
 	case "analyzer":
 		if *filePath == "" {
-			log.Fatalf("File path is required in analyzer mode.\nUse --file path/to/file.sol to analyze a file.")
+			fmt.Fprintf(stderr, "File path is required in analyzer mode.\nUse `solbot analyze path/to/file.sol` to analyze a file.\n")
+			return 2
 		}
-		startAnalyzer(*filePath)
-	default:
-		log.Fatalf("Unknown mode: `%s` Available modes: `lsp` or `analyzer`", *mode)
-		os.Exit(1)
+		return startAnalyzer(*filePath, stdout, stderr, render.Options{}, reporter.Low, "", false, false)
 	}
+	fmt.Fprintf(stderr, "Unknown mode: `%s` Available modes: `lsp` or `analyzer`\n", *mode)
+	return 2
 }
 
-func startLanguageServer() {
-	logger := getLogger("log.txt")
-	logger.Println("Logger started.")
+// outputFlags registers the flags of the commands printing the
+// diagnostics.
+type outputFlags struct {
+	color  *string
+	format *string
+}
 
-	state := analysis.NewState()
-	scanner := bufio.NewScanner(os.Stdin)
-	writer := os.Stdout
+func newOutputFlags(fs *flag.FlagSet) outputFlags {
+	return outputFlags{
+		color:  fs.String("color", "auto", "Color the output: auto, always or never"),
+		format: fs.String("format", "pretty", "Output format: pretty or plain, one line per diagnostic"),
+	}
+}
 
-	scanner.Split(rpc.Split)
+func (f outputFlags) options(w io.Writer) (render.Options, error) {
+	format, err := render.ParseFormat(*f.format)
+	if err != nil {
+		return render.Options{}, err
+	}
+	color, err := render.UseColor(*f.color, w)
+	if err != nil {
+		return render.Options{}, err
+	}
+	return render.Options{Format: format, Color: color}, nil
+}
 
-	for scanner.Scan() {
-		msg := scanner.Bytes()
-		method, content, err := rpc.DecodeMessage(msg)
-		if err != nil {
-			logger.Printf("Error decoding message: %s\n", err)
-			continue
+// parseArgs parses the flags of the command taking a single file, which
+// can come before the flags. It returns the file path and the exit code
+// if the command should stop.
+func parseArgs(fs *flag.FlagSet, args []string) (string, int, bool) {
+	var filePath string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return "", 0, false
+			}
+			return "", 2, false
 		}
+		args = fs.Args()
+		if len(args) > 0 {
+			if filePath != "" {
+				fs.Usage()
+				return "", 2, false
+			}
+			filePath, args = args[0], args[1:]
+		}
+	}
+	if filePath == "" {
+		fs.Usage()
+		return "", 2, false
+	}
+	return filePath, 0, true
+}
+
+// startParse prints the syntax errors of the file e.g.
+//
+//	solbot parse src/Vault.sol --format plain
+//
+// It exits with 1 if there are any errors.
+func startParse(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot parse path/to/file.sol [--color auto] [--format pretty]")
+		fs.PrintDefaults()
+	}
+	output := newOutputFlags(fs)
+	filePath, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	opts, err := output.options(stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading file: %s\n", err)
+		return 1
+	}
+	p := parser.Parser{}
+	handle := token.NewFile(filePath, string(src))
+	p.Init(handle)
+	p.SetLimits(projectConfig(filePath, stderr).Parser.Apply(parser.DefaultLimits()))
+	p.ParseFile()
+
+	errs := p.Errors()
+	render.Render(stderr, render.FromParserErrors(handle, errs), opts)
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
 
-		handleMessage(logger, writer, state, method, content)
+// startAnalyze analyzes the file, prints the findings and writes the report
+// to solbot.md e.g.
+//
+//	solbot analyze src/Vault.sol --min-confidence medium
+//
+// The findings are written to stdout, one per line with `--format plain`,
+// so they can be grepped, or as a SARIF log with `--format sarif` for the
+// code scanning, see the sarif package. The detectors disabled in
+// solbot.toml of the project are skipped.
+func startAnalyze(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot analyze path/to/file.sol [--color auto] [--format pretty|plain|sarif] [--min-confidence low] [--baseline .solbot-baseline.json] [--trace]")
+		fs.PrintDefaults()
+	}
+	output := newOutputFlags(fs)
+	minConfidence := fs.String("min-confidence", "low", "Report only the findings of at least this confidence: low, medium or high")
+	baselinePath := fs.String("baseline", "", "Suppress the findings recorded with `solbot baseline create`, and fail on its expired and stale entries")
+	trace := fs.Bool("trace", false, "Print the trace of the parser, for debugging it")
+	filePath, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	var opts render.Options
+	var err error
+	sarifOutput := *output.format == "sarif"
+	if sarifOutput {
+		// The log goes to stdout, the syntax errors and the baseline to
+		// stderr.
+		opts.Color, err = render.UseColor(*output.color, stderr)
+	} else {
+		opts, err = output.options(stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	confidence, err := reporter.ParseConfidence(*minConfidence)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
 	}
+	return startAnalyzer(filePath, stdout, stderr, opts, confidence, *baselinePath, *trace, sarifOutput)
 }
 
-func startAnalyzer(filePath string) {
-	println("Solbot starts")
+// startAnalyzer reports the findings of the file. With a baseline, the
+// findings of its valid entries are left out, and it exits with 1 if some
+// of its entries of the file expired or are stale.
+func startAnalyzer(filePath string, stdout, stderr io.Writer, opts render.Options, minConfidence reporter.Confidence, baselinePath string, trace, sarifOutput bool) int {
 	src, err := os.ReadFile(filePath)
 	if err != nil {
-		log.Fatalf("Error reading file: %s\n", err)
+		fmt.Fprintf(stderr, "Error reading file: %s\n", err)
+		return 1
 	}
 
+	cfg := projectConfig(filePath, stderr)
+
 	p := parser.Parser{}
 	handle := token.NewFile(filePath, string(src))
 	p.Init(handle)
-	p.ToggleTracing()
+	p.SetLimits(cfg.Parser.Apply(parser.DefaultLimits()))
+	if trace {
+		p.ToggleTracing()
+	}
 
 	file := p.ParseFile()
 
-	println("Solbot is analyzing your file...")
-	findings := analyzer.AnalyzeFile(file)
-	for _, finding := range findings {
+	var matched baseline.Result
+	suppressed := map[string]bool{}
+	relativePath := filePath
+	if baselinePath != "" {
+		matched, relativePath, err = matchBaseline(baselinePath, filePath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		for _, f := range matched.Suppressed {
+			suppressed[f.Fingerprint] = true
+		}
+	}
+
+	findings := []reporter.Finding{}
+	diagnostics := render.FromParserErrors(handle, p.Errors())
+	analyzed := analyzer.AnalyzeFile(file, cfg.Disabled...)
+	fingerprints := []baseline.Finding{}
+	for _, finding := range analyzed {
+		fingerprints = append(fingerprints, baseline.FromFinding(relativePath, handle, finding))
+	}
+	baseline.Fingerprint(fingerprints)
+	for i, finding := range analyzed {
+		if finding.Confidence < minConfidence || suppressed[fingerprints[i].Fingerprint] {
+			continue
+		}
 		finding.CalculatePositions(handle)
+		findings = append(findings, finding)
+		if !sarifOutput {
+			diagnostics = append(diagnostics, render.FromFinding(handle, finding))
+		}
+	}
+	out := stdout
+	if sarifOutput {
+		out = stderr
+		if err := writeSARIF(stdout, filePath, findings); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
 	}
+	render.Sort(diagnostics)
+	render.Render(out, diagnostics, opts)
 
 	reporter.GenerateReport(findings, "solbot.md")
+	if baselinePath == "" {
+		return 0
+	}
+	printBaselineResult(out, matched)
+	if matched.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// writeSARIF writes the SARIF log of the findings of the file, with the
+// rules of all of the detectors. The path of the file is relative to the
+// project root, where the code scanning runs.
+func writeSARIF(w io.Writer, filePath string, findings []reporter.Finding) error {
+	path := filePath
+	if abs, err := filepath.Abs(filePath); err == nil {
+		if rel, err := filepath.Rel(findProjectRoot(filepath.Dir(abs)), abs); err == nil {
+			path = rel
+		}
+	}
+	log := sarif.New(version, analyzer.Rules())
+	for _, f := range findings {
+		log.Add(path, f)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(log); err != nil {
+		return fmt.Errorf("Error encoding the SARIF log: %s", err)
+	}
+	return nil
+}
+
+// startCompileInput writes solc's standard JSON input for the file and
+// everything it imports e.g.
+//
+//	solbot compile-input src/Vault.sol --output input.json
+func startCompileInput(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("compile-input", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot compile-input path/to/file.sol [--output input.json] [--root dir]")
+		fs.PrintDefaults()
+	}
+	output := fs.String("output", "", "Output file; stdout if empty")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	optimize := fs.Bool("optimize", false, "Enable the optimizer; overrides foundry.toml")
+	runs := fs.Int("optimizer-runs", 200, "Number of optimizer runs; overrides foundry.toml")
+	evmVersion := fs.String("evm-version", "", "Target EVM version; overrides foundry.toml and the pragma based default")
+	filePath, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading file: %s\n", err)
+		return 1
+	}
+	if *root == "" {
+		*root = findProjectRoot(filepath.Dir(absPath))
+	}
+
+	state := analysis.NewState()
+	if err := state.IndexWorkspace(context.Background(), *root); err != nil {
+		fmt.Fprintf(stderr, "Error indexing the project: %s\n", err)
+		return 1
+	}
+
+	opts := standardjson.Options{EVMVersion: *evmVersion}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "optimize":
+			opts.Optimizer = optimize
+		case "optimizer-runs":
+			opts.OptimizerRuns = runs
+		}
+	})
+
+	input, hint, err := standardjson.Build(state, analysis.PathToURI(absPath), opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %s\n", err)
+		return 1
+	}
+	if hint != nil {
+		fmt.Fprintf(stderr, "Compiler hint: solc %s (pragmas: %s), evmVersion %s\n", hint.Version, hint.Constraint, input.Settings.EVMVersion)
+	}
+
+	// The sources are kept readable, without escaping e.g. `<` and `>`.
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(input); err != nil {
+		fmt.Fprintf(stderr, "Error encoding the input: %s\n", err)
+		return 1
+	}
+	if *output == "" {
+		stdout.Write(content.Bytes())
+		return 0
+	}
+	if err := os.WriteFile(*output, content.Bytes(), 0644); err != nil {
+		fmt.Fprintf(stderr, "Error writing the input: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// startVerifySources checks that the local sources are the ones verified for
+// a deployed contract, given its metadata and the standard JSON input
+// downloaded from the block explorer or taken from the build output e.g.
+//
+//	solbot verify-sources --standard-json input.json --metadata metadata.json
+//
+// The compilation unit of the target named in the metadata is built from
+// the project the same way as by compile-input, its sources are hashed and
+// its settings compared. It exits with 1 if anything doesn't match.
+func startVerifySources(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("verify-sources", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot verify-sources --metadata metadata.json [--standard-json input.json] [--format text|json] [--root dir]")
+		fs.PrintDefaults()
+	}
+	metadataPath := fs.String("metadata", "", "solc metadata of the deployed contract")
+	inputPath := fs.String("standard-json", "", "Verified standard JSON input, for the diffs of the mismatching sources")
+	format := fs.String("format", "text", "Output format: text or json")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if *metadataPath == "" || fs.NArg() > 0 || *format != "text" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+
+	data, err := os.ReadFile(*metadataPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading the metadata: %s\n", err)
+		return 1
+	}
+	meta, err := standardjson.ParseMetadata(data)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	var verified *standardjson.Input
+	if *inputPath != "" {
+		data, err := os.ReadFile(*inputPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading the standard JSON input: %s\n", err)
+			return 1
+		}
+		verified = &standardjson.Input{}
+		if err := json.Unmarshal(data, verified); err != nil {
+			fmt.Fprintf(stderr, "Invalid standard JSON input: %s\n", err)
+			return 1
+		}
+	}
+
+	if *root == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading the working directory: %s\n", err)
+			return 1
+		}
+		*root = findProjectRoot(dir)
+	}
+	state := analysis.NewState()
+	if err := state.IndexWorkspace(context.Background(), *root); err != nil {
+		fmt.Fprintf(stderr, "Error indexing the project: %s\n", err)
+		return 1
+	}
+	target, _ := meta.Target()
+	local, _, err := standardjson.Build(state, analysis.PathToURI(filepath.Join(*root, filepath.FromSlash(target))), standardjson.Options{})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error building the compilation unit of %s: %s\n", target, err)
+		return 1
+	}
+	v := standardjson.Verify(local, verified, meta)
+
+	code := 0
+	if !v.OK() {
+		code = 1
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(v)
+		return code
+	}
+
+	fmt.Fprintf(stdout, "Sources of %s (%s):\n", v.Contract, v.Source)
+	mismatches := 0
+	for _, check := range v.Sources {
+		switch check.Status {
+		case standardjson.SourceMatch:
+			fmt.Fprintf(stdout, "  match     %s\n", check.Name)
+			continue
+		case standardjson.SourceMismatch:
+			fmt.Fprintf(stdout, "  mismatch  %s: keccak256 %s, the metadata has %s\n", check.Name, check.Actual, check.Expected)
+		case standardjson.SourceMissing:
+			fmt.Fprintf(stdout, "  missing   %s: not imported by the local sources\n", check.Name)
+		case standardjson.SourceExtra:
+			fmt.Fprintf(stdout, "  extra     %s: not in the metadata\n", check.Name)
+		}
+		mismatches++
+		if check.Diff != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(check.Diff, "\n"), "\n") {
+				fmt.Fprintf(stdout, "    %s\n", line)
+			}
+		}
+	}
+	if len(v.Settings) > 0 {
+		fmt.Fprintln(stdout, "Settings:")
+		for _, s := range v.Settings {
+			fmt.Fprintf(stdout, "  mismatch  %s: the metadata has %q, the project has %q\n", s.Name, s.Expected, s.Actual)
+		}
+	}
+	if code == 0 {
+		fmt.Fprintln(stdout, "The sources and the settings match the metadata")
+	} else {
+		fmt.Fprintf(stdout, "Mismatches: %d of %d sources, %d settings\n", mismatches, len(v.Sources), len(v.Settings))
+	}
+	return code
 }
 
-func handleMessage(logger *log.Logger, writer io.Writer, state analysis.State, method string, content []byte) {
-	logger.Printf("Received message with method: %s\n", method)
-	logger.Printf("Message content: %s\n", content)
+// startMetrics prints the functions of the file or directory with the
+// highest complexity first, or the contracts with the largest estimated
+// bytecode size first e.g.
+//
+//	solbot metrics src --limit 10
+//	solbot metrics src/Vault.sol --format json
+//	solbot metrics src --contracts
+//	solbot metrics src --versions
+func startMetrics(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("metrics", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot metrics [path] [--format table|json] [--limit n] [--contracts|--docs|--versions] [--root dir]")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "table", "Output format: table or json")
+	limit := fs.Int("limit", 20, "Number of the functions or contracts to print; all if 0")
+	contracts := fs.Bool("contracts", false, "Print the estimated bytecode sizes of the contracts instead of the function metrics")
+	docs := fs.Bool("docs", false, "Print the NatSpec coverage of the external and public functions of the contracts instead of the function metrics")
+	versions := fs.Bool("versions", false, "Print the lowest compiler version that can compile each file, given its pragma and the constructs it uses, instead of the function metrics")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+
+	// The path can come before the flags.
+	positional := []string{}
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional, args = append(positional, args[0]), args[1:]
+		}
+	}
+	if len(positional) > 1 || *format != "table" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+	path := "."
+	if len(positional) == 1 {
+		path = positional[0]
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading path: %s\n", err)
+		return 1
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading path: %s\n", err)
+		return 1
+	}
+	if *root == "" {
+		dir := absPath
+		if !info.IsDir() {
+			dir = filepath.Dir(absPath)
+		}
+		*root = findProjectRoot(dir)
+	}
+
+	state := analysis.NewState()
+	if err := state.IndexWorkspace(context.Background(), *root); err != nil {
+		fmt.Fprintf(stderr, "Error indexing the project: %s\n", err)
+		return 1
+	}
+	var printOther func(w io.Writer, state *analysis.State, path, format string, limit int) error
+	switch {
+	case *contracts:
+		printOther = printContractSizes
+	case *docs:
+		printOther = printDocCoverage
+	case *versions:
+		printOther = printMinimumVersions
+	}
+	if printOther != nil {
+		if err := printOther(stdout, state, absPath, *format, *limit); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	fns := state.PathMetrics(absPath)
+	slices.SortStableFunc(fns, func(a, b analysis.FunctionMetrics) int {
+		if a.Complexity != b.Complexity {
+			return cmp.Compare(b.Complexity, a.Complexity)
+		}
+		if a.Nesting != b.Nesting {
+			return cmp.Compare(b.Nesting, a.Nesting)
+		}
+		if a.Statements != b.Statements {
+			return cmp.Compare(b.Statements, a.Statements)
+		}
+		return cmp.Compare(b.Parameters, a.Parameters)
+	})
+	if *limit > 0 && len(fns) > *limit {
+		fns = fns[:*limit]
+	}
 
-	switch method {
-	case "initialize":
-		var request lsp.InitializeRequest
-		if err := json.Unmarshal(content, &request); err != nil {
-			logger.Printf("initialize: %s\n", err)
-			return
+	type functionMetrics struct {
+		Function   string `json:"function"`
+		Location   string `json:"location"`
+		Complexity int    `json:"complexity"`
+		Statements int    `json:"statements"`
+		Parameters int    `json:"parameters"`
+		Nesting    int    `json:"nesting"`
+	}
+	rows := []functionMetrics{}
+	for _, f := range fns {
+		pos := f.Doc.Handle.Position(f.NameRange().Start)
+		rows = append(rows, functionMetrics{
+			Function:   f.Name(),
+			Location:   fmt.Sprintf("%s:%d:%d", state.RelativePath(f.Doc.URI), pos.Line, pos.Column),
+			Complexity: f.Complexity,
+			Statements: f.Statements,
+			Parameters: f.Parameters,
+			Nesting:    f.Nesting,
+		})
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rows); err != nil {
+			fmt.Fprintf(stderr, "Error encoding the metrics: %s\n", err)
+			return 1
 		}
+		return 0
+	}
 
-		logger.Printf("Connected to: %s %s\n", request.Params.ClientInfo.Name, request.Params.ClientInfo.Version)
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPLEXITY\tNESTING\tSTATEMENTS\tPARAMETERS\tFUNCTION\tLOCATION")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\t%s\n",
+			row.Complexity, row.Nesting, row.Statements, row.Parameters, row.Function, row.Location)
+	}
+	w.Flush()
+	return 0
+}
+
+// printMinimumVersions prints the lowest compiler version that can compile
+// each file of the file or directory, the newest first, with the construct
+// requiring it.
+func printMinimumVersions(stdout io.Writer, state *analysis.State, path, format string, limit int) error {
+	versions := state.PathMinimumVersions(path)
+	slices.SortStableFunc(versions, func(a, b analysis.MinimumVersion) int {
+		return b.Minimum.Compare(a.Minimum)
+	})
+	if limit > 0 && len(versions) > limit {
+		versions = versions[:limit]
+	}
+
+	type minimumVersion struct {
+		File    string `json:"file"`
+		Pragma  string `json:"pragma"`
+		Minimum string `json:"minimum"`
+		Reason  string `json:"reason"`
+	}
+	rows := []minimumVersion{}
+	for _, v := range versions {
+		row := minimumVersion{File: state.RelativePath(v.Doc.URI), Pragma: v.Pragma, Minimum: v.Minimum.String()}
+		if v.Minimum == (semver.Version{}) {
+			row.Minimum = "none"
+		}
+		if v.Reason != nil {
+			row.Reason = v.Reason.Name
+		}
+		rows = append(rows, row)
+	}
 
-		msg := lsp.NewInitializeResponse(request.ID)
-		writeResponse(writer, logger, msg)
-	case "textDocument/didOpen":
-		var request lsp.DidOpenTextDocumentNotification
-		if err := json.Unmarshal(content, &request); err != nil {
-			logger.Printf("textDocument/didOpen: %s\n", err)
-			return
+	if format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rows); err != nil {
+			return fmt.Errorf("Error encoding the minimum versions: %s", err)
 		}
+		return nil
+	}
 
-		logger.Printf("Opened: %s\n", request.Params.TextDocument.URI)
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MINIMUM\tPRAGMA\tFILE\tREQUIRED BY")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.Minimum, row.Pragma, row.File, row.Reason)
+	}
+	return w.Flush()
+}
 
-		// @TODO: Here we can start the static analysis
+// printContractSizes prints the estimated bytecode sizes of the contracts
+// of the file or directory, the largest first, each with its factors and
+// the contributions of its bases.
+func printContractSizes(stdout io.Writer, state *analysis.State, path, format string, limit int) error {
+	sizes := state.PathContractSizes(path)
+	slices.SortStableFunc(sizes, func(a, b analysis.ContractSizeMetrics) int {
+		return cmp.Compare(b.Estimate, a.Estimate)
+	})
+	if limit > 0 && len(sizes) > limit {
+		sizes = sizes[:limit]
+	}
 
-		state.OpenDocument(request.Params.TextDocument.URI, request.Params.TextDocument.Text)
-	case "textDocument/didChange":
-		var request lsp.DidChangeTextDocumentNotification
-		if err := json.Unmarshal(content, &request); err != nil {
-			logger.Printf("textDocument/didChange: %s\n", err)
-			return
+	type factor struct {
+		Name   string `json:"name"`
+		Count  int    `json:"count"`
+		Bytes  int    `json:"bytes"`
+		Detail string `json:"detail"`
+	}
+	type source struct {
+		Contract string `json:"contract"`
+		Bytes    int    `json:"bytes"`
+	}
+	type contractSize struct {
+		Contract string   `json:"contract"`
+		Location string   `json:"location"`
+		Estimate int      `json:"estimate"`
+		Factors  []factor `json:"factors"`
+		Sources  []source `json:"sources"`
+	}
+	rows := []contractSize{}
+	for _, size := range sizes {
+		pos := size.Doc.Handle.Position(size.Contract.Name.NamePos)
+		row := contractSize{
+			Contract: size.Name(),
+			Location: fmt.Sprintf("%s:%d:%d", state.RelativePath(size.Doc.URI), pos.Line, pos.Column),
+			Estimate: size.Estimate,
+			Factors:  []factor{},
+			Sources:  []source{},
 		}
+		for _, f := range size.Factors {
+			row.Factors = append(row.Factors, factor{Name: f.Name, Count: f.Count, Bytes: f.Bytes, Detail: f.Detail})
+		}
+		for _, s := range size.Sources {
+			row.Sources = append(row.Sources, source{Contract: s.Contract.Name.Name, Bytes: s.Bytes})
+		}
+		rows = append(rows, row)
+	}
 
-		logger.Printf("Changed: %s\n", request.Params.TextDocument.URI)
+	if format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rows); err != nil {
+			return fmt.Errorf("Error encoding the contract sizes: %s", err)
+		}
+		return nil
+	}
 
-		for _, change := range request.Params.ContentChanges {
-			state.UpdateDocument(request.Params.TextDocument.URI, change.Text)
+	// The estimates are heuristic, so the sizes are approximate.
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ESTIMATED BYTES\tCONTRACT\tFACTOR\tDETAIL")
+	for _, row := range rows {
+		fmt.Fprintf(w, "~%d\t%s\t\t%s\n", row.Estimate, row.Contract, row.Location)
+		for _, f := range row.Factors {
+			fmt.Fprintf(w, "%d\t\t%s\t%s\n", f.Bytes, f.Name, f.Detail)
+		}
+		for _, s := range row.Sources[1:] {
+			fmt.Fprintf(w, "%d\t\tinherited\tof the above, declared in %s\n", s.Bytes, s.Contract)
 		}
-	case "textDocument/hover":
-		var request lsp.HoverRequest
-		if err := json.Unmarshal(content, &request); err != nil {
-			logger.Printf("textDocument/hover: %s\n", err)
-			return
+	}
+	return w.Flush()
+}
+
+// printDocCoverage prints the NatSpec coverage of the contracts of the file
+// or directory, the least documented first.
+func printDocCoverage(stdout io.Writer, state *analysis.State, path, format string, limit int) error {
+	coverage := state.PathDocCoverage(path)
+	slices.SortStableFunc(coverage, func(a, b analysis.DocCoverage) int {
+		return cmp.Compare(a.Percent(), b.Percent())
+	})
+	if limit > 0 && len(coverage) > limit {
+		coverage = coverage[:limit]
+	}
+
+	type docCoverage struct {
+		Contract   string  `json:"contract"`
+		Location   string  `json:"location"`
+		Documented int     `json:"documented"`
+		Functions  int     `json:"functions"`
+		Coverage   float64 `json:"coverage"`
+	}
+	rows := []docCoverage{}
+	for _, c := range coverage {
+		pos := c.Doc.Handle.Position(c.Contract.Name.NamePos)
+		rows = append(rows, docCoverage{
+			Contract:   c.Contract.Name.Name,
+			Location:   fmt.Sprintf("%s:%d:%d", state.RelativePath(c.Doc.URI), pos.Line, pos.Column),
+			Documented: c.Documented,
+			Functions:  c.Functions,
+			Coverage:   c.Percent(),
+		})
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rows); err != nil {
+			return fmt.Errorf("Error encoding the documentation coverage: %s", err)
 		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COVERAGE\tDOCUMENTED\tCONTRACT\tLOCATION")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%.0f%%\t%d/%d\t%s\t%s\n", row.Coverage, row.Documented, row.Functions, row.Contract, row.Location)
+	}
+	return w.Flush()
+}
+
+// letFlags collects the variables declared with the repeated --let flag
+// e.g. `--let x:uint256 --let owner:address`.
+type letFlags map[string]string
+
+func (l letFlags) String() string {
+	return fmt.Sprint(map[string]string(l))
+}
+
+func (l letFlags) Set(value string) error {
+	name, typ, ok := strings.Cut(value, ":")
+	if !ok || name == "" || typ == "" {
+		return fmt.Errorf("expected name:type, got %q", value)
+	}
+	l[name] = typ
+	return nil
+}
 
-		response := state.Hover(request.ID, request.Params.TextDocument.URI, request.Params.Position)
-		writeResponse(writer, logger, response)
-	case "textDocument/definition":
-		var request lsp.DefinitionRequest
-		if err := json.Unmarshal(content, &request); err != nil {
-			logger.Printf("textDocument/definition: %s\n", err)
-			return
+// startEvalCheck checks the statements or the expression outside of a
+// contract and prints the types of the top-level expressions e.g.
+//
+//	solbot eval-check 'x + 1 days' --let x:uint256
+//
+// It exits with 1 if there are any errors.
+func startEvalCheck(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("eval-check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot eval-check 'statements or expression' [--let name:type]...")
+		fs.PrintDefaults()
+	}
+	vars := letFlags{}
+	fs.Var(vars, "let", "Declare a variable of an elementary type e.g. x:uint256; repeatable")
+
+	// The snippet can come before the flags.
+	var src string
+	seen := false
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
 		}
+		args = fs.Args()
+		if len(args) > 0 {
+			if seen {
+				fmt.Fprintf(stderr, "Unexpected argument: `%s`\n", args[0])
+				return 2
+			}
+			src, args, seen = args[0], args[1:], true
+		}
+	}
+	if !seen {
+		fs.Usage()
+		return 2
+	}
 
-		response := state.Definition(request.ID, request.Params.TextDocument.URI, request.Params.Position)
-		writeResponse(writer, logger, response)
+	fragment := analysis.CheckFragment(src, vars)
+	for _, d := range fragment.Diagnostics {
+		fmt.Fprintf(stderr, "%d:%d: %s\n", d.Range.Start.Line+1, d.Range.Start.Character+1, d.Message)
+	}
+	for _, x := range fragment.Expressions {
+		typ := x.Type
+		if typ == "" {
+			typ = "unknown"
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", ast.ExprString(x.Expression), typ)
+	}
+	if len(fragment.Diagnostics) > 0 {
+		return 1
 	}
+	return 0
 }
 
-func writeResponse(writer io.Writer, logger *log.Logger, msg any) {
-	response := rpc.EncodeMessage(msg)
-	_, err := writer.Write([]byte(response))
+// startProxyCheck prints the state variables of the proxy colliding with
+// the ones of the implementation e.g.
+//
+//	solbot proxy-check --proxy VaultProxy --impl Vault
+//
+// The contracts whose names are declared in more than one file are named
+// with the paths of their files e.g. `--impl src/Vault.sol:Vault`. It exits
+// with 1 if there are any collisions.
+func startProxyCheck(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("proxy-check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot proxy-check --proxy [path:]ContractName --impl [path:]ContractName [--root dir]")
+		fs.PrintDefaults()
+	}
+	proxy := fs.String("proxy", "", "Name of the proxy contract, optionally qualified with its file e.g. src/VaultProxy.sol:VaultProxy")
+	impl := fs.String("impl", "", "Name of the implementation contract, optionally qualified with its file e.g. src/Vault.sol:Vault")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	output := newOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if *proxy == "" || *impl == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	opts, err := output.options(stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if *root == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading the working directory: %s\n", err)
+			return 1
+		}
+		*root = findProjectRoot(dir)
+	}
+	state := analysis.NewState()
+	if err := state.IndexWorkspace(context.Background(), *root); err != nil {
+		fmt.Fprintf(stderr, "Error indexing the project: %s\n", err)
+		return 1
+	}
+
+	collisions, err := state.CheckProxy(*proxy, *impl)
 	if err != nil {
-		logger.Printf("Error writing response: %s\n", err)
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if len(collisions) == 0 {
+		fmt.Fprintf(stdout, "No storage collisions between `%s` and `%s`\n", *proxy, *impl)
+		return 0
+	}
+
+	// The locations are printed relative to the project root.
+	files := map[*analysis.Document]*token.File{}
+	file := func(doc *analysis.Document) *token.File {
+		if files[doc] == nil {
+			files[doc] = token.NewFile(state.RelativePath(doc.URI), doc.Handle.Src())
+		}
+		return files[doc]
+	}
+	diagnostics := []render.Diagnostic{}
+	for _, c := range collisions {
+		diagnostics = append(diagnostics, render.Diagnostic{
+			Severity: "Error",
+			Message:  c.Message(),
+			File:     file(c.ProxyVariable.Doc),
+			Range:    ast.NodeRange(c.ProxyVariable.Name),
+			Label:    fmt.Sprintf("slot %d, offset %d", c.Proxy.Slot, c.Proxy.Offset),
+			Related: []render.Related{{
+				Message: fmt.Sprintf("`%s` is stored at slot %d, offset %d of the implementation", c.Implementation.Name, c.Implementation.Slot, c.Implementation.Offset),
+				File:    file(c.ImplementationVariable.Doc),
+				Range:   ast.NodeRange(c.ImplementationVariable.Name),
+			}},
+		})
 	}
+	render.Render(stderr, diagnostics, opts)
+	return 1
 }
 
-func getLogger(filename string) *log.Logger {
-	// Bitwise OR is used to combine the flags e.g. 001 | 010 | 100 is 111
-	logfile, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+// startAccessReport prints the guards of the external and public functions
+// of the contracts under the path, grouped by category, e.g.
+//
+//	solbot access-report src --format markdown
+//
+// A function with guards of several kinds is listed in each of their
+// categories. The Markdown output is meant for the audit reports.
+func startAccessReport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("access-report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot access-report <path> [--format table|json|markdown] [--root dir]")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "table", "Output format: table, json or markdown")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	positional := []string{}
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional, args = append(positional, args[0]), args[1:]
+		}
+	}
+	if len(positional) != 1 || *format != "table" && *format != "json" && *format != "markdown" {
+		fs.Usage()
+		return 2
+	}
+
+	state, _, err := loadDocuments(positional[0], *root)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	absPath, _ := filepath.Abs(positional[0])
+
+	type guard struct {
+		Kind     access.Kind `json:"kind"`
+		Subject  string      `json:"subject"`
+		Modifier string      `json:"modifier,omitempty"`
+		Location string      `json:"location"`
+	}
+	type function struct {
+		Contract   string        `json:"contract"`
+		Function   string        `json:"function"`
+		Location   string        `json:"location"`
+		Categories []access.Kind `json:"categories"`
+		Guards     []guard       `json:"guards"`
+	}
+	location := func(doc *analysis.Document, pos token.Pos) string {
+		p := doc.Handle.Position(pos)
+		return fmt.Sprintf("%s:%d:%d", state.RelativePath(doc.URI), p.Line, p.Column)
+	}
+	rows := []function{}
+	totals := access.Totals{}
+	for _, f := range state.PathAccess(absPath) {
+		totals.Add(f.Guards)
+		row := function{
+			Contract:   f.Contract.Name.Name,
+			Function:   f.Signature,
+			Location:   location(f.Doc, f.NameRange().Start),
+			Categories: access.Categories(f.Guards),
+			Guards:     []guard{},
+		}
+		for _, g := range f.Guards {
+			row.Guards = append(row.Guards, guard{Kind: g.Kind, Subject: g.Subject, Modifier: g.Modifier, Location: location(f.Doc, g.Range.Start)})
+		}
+		rows = append(rows, row)
+	}
+
+	if *format == "json" {
+		counts := map[access.Kind]int{}
+		for kind, n := range totals {
+			counts[kind] = n
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Functions []function          `json:"functions"`
+			Totals    map[access.Kind]int `json:"totals"`
+		}{rows, counts})
+		return 0
+	}
+
+	// guards returns the guards of the kind e.g. "`owner` via `onlyOwner`".
+	guards := func(row function, kind access.Kind, code func(string) string) string {
+		texts := []string{}
+		for _, g := range row.Guards {
+			if g.Kind != kind {
+				continue
+			}
+			text := code(g.Subject)
+			if g.Modifier != "" {
+				text += " via " + code(g.Modifier)
+			}
+			texts = append(texts, text)
+		}
+		if len(texts) == 0 {
+			return "-"
+		}
+		return strings.Join(texts, ", ")
 	}
 
-	return log.New(logfile, "[solbot_lsp] ", log.Ldate|log.Ltime|log.Lshortfile)
+	if *format == "markdown" {
+		// The pipes would end the cells of the tables, even in the code.
+		code := func(s string) string { return "`" + strings.ReplaceAll(s, "|", `\|`) + "`" }
+		fmt.Fprintf(stdout, "## Access control\n\n%s.\n", totals)
+		for _, kind := range access.Kinds {
+			if totals[kind] == 0 {
+				continue
+			}
+			fmt.Fprintf(stdout, "\n### %s\n\n| Contract | Function | Guards | Location |\n| --- | --- | --- | --- |\n", kind)
+			for _, row := range rows {
+				if slices.Contains(row.Categories, kind) {
+					fmt.Fprintf(stdout, "| %s | %s | %s | %s |\n", row.Contract, code(row.Function), guards(row, kind, code), row.Location)
+				}
+			}
+		}
+		return 0
+	}
+
+	plain := func(s string) string { return s }
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CATEGORY\tCONTRACT\tFUNCTION\tGUARDS\tLOCATION")
+	for _, kind := range access.Kinds {
+		for _, row := range rows {
+			if slices.Contains(row.Categories, kind) {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", kind, row.Contract, row.Function, guards(row, kind, plain), row.Location)
+			}
+		}
+	}
+	w.Flush()
+	fmt.Fprintln(stdout, totals)
+	return 0
+}
+
+// startDeployParams prints the constructor parameters of the contract and
+// the state variables their values are stored in, through the constructors
+// of the bases, e.g.
+//
+//	solbot deploy-params Vault --format json
+//
+// The parameters stored nowhere and the immutables no constructor assigns
+// are listed after them. It exits with 1 if there are unassigned immutables.
+func startDeployParams(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("deploy-params", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot deploy-params [path:]ContractName [--format table|json] [--root dir]")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "table", "Output format: table or json")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	positional := []string{}
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional, args = append(positional, args[0]), args[1:]
+		}
+	}
+	if len(positional) != 1 || *format != "table" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+
+	if *root == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading the working directory: %s\n", err)
+			return 1
+		}
+		*root = findProjectRoot(dir)
+	}
+	state := analysis.NewState()
+	if err := state.IndexWorkspace(context.Background(), *root); err != nil {
+		fmt.Fprintf(stderr, "Error indexing the project: %s\n", err)
+		return 1
+	}
+	deployment, err := state.DeploymentOf(positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	type flow struct {
+		Variable  string   `json:"variable"` // qualified with its contract e.g. "Ownable.owner"
+		Immutable bool     `json:"immutable"`
+		Derived   bool     `json:"derived"`
+		Via       []string `json:"via"`
+		Location  string   `json:"location"`
+	}
+	type parameter struct {
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Location string `json:"location"`
+		Flows    []flow `json:"flows"`
+	}
+	type immutable struct {
+		Variable string `json:"variable"`
+		Location string `json:"location"`
+	}
+	location := func(doc *analysis.Document, pos token.Pos) string {
+		p := doc.Handle.Position(pos)
+		return fmt.Sprintf("%s:%d:%d", state.RelativePath(doc.URI), p.Line, p.Column)
+	}
+	params := []parameter{}
+	for _, p := range deployment.Parameters {
+		param := parameter{
+			Name:     p.Param.Name.Name,
+			Type:     ast.ExprString(p.Param.Type),
+			Location: location(p.Contract.Doc, p.Param.Name.Start()),
+			Flows:    []flow{},
+		}
+		for _, f := range p.Flows {
+			via := []string{}
+			for _, hop := range f.Via {
+				via = append(via, hop.Name())
+			}
+			param.Flows = append(param.Flows, flow{
+				Variable:  state.VariableName(f.Variable),
+				Immutable: f.Variable.Node.(*ast.VariableDeclaration).Immutable,
+				Derived:   f.Derived,
+				Via:       via,
+				Location:  location(f.Variable.Doc, f.Variable.Name.Start()),
+			})
+		}
+		params = append(params, param)
+	}
+	unassigned := []immutable{}
+	for _, v := range deployment.Unassigned {
+		unassigned = append(unassigned, immutable{Variable: state.VariableName(v), Location: location(v.Doc, v.Name.Start())})
+	}
+	code := 0
+	if len(unassigned) > 0 {
+		code = 1
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Contract   string      `json:"contract"`
+			Location   string      `json:"location"`
+			Parameters []parameter `json:"parameters"`
+			Unassigned []immutable `json:"unassigned"`
+		}{deployment.Contract.Name.Name, location(deployment.Contract.Doc, deployment.Contract.Name.Start()), params, unassigned})
+		return code
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PARAMETER\tTYPE\tFLOWS INTO\tVIA\tLOCATION")
+	for _, p := range params {
+		if len(p.Flows) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t-\t-\t%s\n", p.Name, p.Type, p.Location)
+			continue
+		}
+		for _, f := range p.Flows {
+			into := f.Variable
+			if f.Derived {
+				into += " (derived)"
+			}
+			via := strings.Join(f.Via, " -> ")
+			if via == "" {
+				via = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.Type, into, via, f.Location)
+		}
+	}
+	w.Flush()
+	for _, p := range params {
+		if len(p.Flows) == 0 {
+			fmt.Fprintf(stdout, "warning: the constructor parameter `%s` is not stored (%s)\n", p.Name, p.Location)
+		}
+	}
+	for _, v := range unassigned {
+		fmt.Fprintf(stdout, "error: the immutable `%s` is never assigned (%s)\n", v.Variable, v.Location)
+	}
+	return code
+}
+
+// startClones prints the groups of the functions under the path whose
+// bodies are copies of each other, with the names and the literals
+// changed at most, e.g.
+//
+//	solbot clones src --min-lines 8 --min-similarity 0.9
+//
+// The thresholds default to the ones of the [clones] section of
+// solbot.toml. The group identities stay the same across the runs, so the
+// JSON output can be compared between the commits.
+func startClones(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("clones", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot clones <path> [--min-lines n] [--min-similarity s] [--format table|json] [--root dir]")
+		fs.PrintDefaults()
+	}
+	defaults := clones.DefaultOptions()
+	minLines := fs.Int("min-lines", defaults.MinLines, "Lines of the function bodies below which they're not compared")
+	minSimilarity := fs.Float64("min-similarity", defaults.MinSimilarity, "Similarity of the bodies, from 0 to 1, below which they're not clones")
+	format := fs.String("format", "table", "Output format: table or json")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	positional := []string{}
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional, args = append(positional, args[0]), args[1:]
+		}
+	}
+	if len(positional) != 1 || *format != "table" && *format != "json" || *minSimilarity < 0 || *minSimilarity > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	state, _, err := loadDocuments(positional[0], *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	absPath, _ := filepath.Abs(positional[0])
+	opts := state.Config.Clones.Options()
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "min-lines":
+			opts.MinLines = *minLines
+		case "min-similarity":
+			opts.MinSimilarity = *minSimilarity
+		}
+	})
+
+	type function struct {
+		Function string `json:"function"`
+		Location string `json:"location"`
+		Lines    int    `json:"lines"`
+	}
+	type group struct {
+		ID         string     `json:"id"`
+		Similarity float64    `json:"similarity"`
+		Functions  []function `json:"functions"`
+	}
+	groups := []group{}
+	for _, g := range state.PathClones(absPath, opts) {
+		row := group{ID: g.ID, Similarity: math.Round(g.Similarity*1000) / 1000}
+		for _, m := range g.Members {
+			p := m.Doc.Handle.Position(m.NameRange().Start)
+			location := fmt.Sprintf("%s:%d:%d", state.RelativePath(m.Doc.URI), p.Line, p.Column)
+			row.Functions = append(row.Functions, function{Function: m.Name(), Location: location, Lines: m.Lines})
+		}
+		groups = append(groups, row)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Groups []group `json:"groups"`
+		}{groups})
+		return 0
+	}
+
+	for _, g := range groups {
+		fmt.Fprintf(stdout, "Clone group %s: %d functions, %.0f%% similar\n", g.ID, len(g.Functions), 100*g.Similarity)
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		for _, f := range g.Functions {
+			fmt.Fprintf(w, "  %s\t%s\t%d lines\n", f.Function, f.Location, f.Lines)
+		}
+		w.Flush()
+		fmt.Fprintln(stdout)
+	}
+	switch len(groups) {
+	case 0:
+		fmt.Fprintln(stdout, "No clones found.")
+	case 1:
+		fmt.Fprintln(stdout, "1 clone group.")
+	default:
+		fmt.Fprintf(stdout, "%d clone groups.\n", len(groups))
+	}
+	return 0
+}
+
+// startAuditDiff records the hashes of the declarations under the path, or
+// compares them with the recorded ones, e.g.
+//
+//	solbot audit-diff --export audited.json src
+//	solbot audit-diff --baseline audited.json src --format markdown
+//
+// The hashes ignore the formatting and the comments, so the report lists
+// only the declarations whose code changed, and tells the ones moved to
+// another contract or renamed from the added and the removed ones.
+func startAuditDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("audit-diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot audit-diff (--baseline hashes.json | --export hashes.json) <path> [--format markdown|json] [--root dir]")
+		fs.PrintDefaults()
+	}
+	baselinePath := fs.String("baseline", "", "Compare the declarations with the snapshot written by --export")
+	exportPath := fs.String("export", "", "Write the snapshot of the hashes of the declarations to the file")
+	format := fs.String("format", "markdown", "Output format: markdown or json")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	positional := []string{}
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional, args = append(positional, args[0]), args[1:]
+		}
+	}
+	if len(positional) != 1 || (*baselinePath == "") == (*exportPath == "") || *format != "markdown" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+
+	state, _, err := loadDocuments(positional[0], *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	absPath, _ := filepath.Abs(positional[0])
+	current := state.PathSnapshot(absPath)
+
+	if *exportPath != "" {
+		var b bytes.Buffer
+		current.Write(&b)
+		if err := os.WriteFile(*exportPath, b.Bytes(), 0644); err != nil {
+			fmt.Fprintf(stderr, "Error writing the snapshot: %s\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Recorded %d declarations in %s.\n", len(current.Declarations), *exportPath)
+		return 0
+	}
+
+	f, err := os.Open(*baselinePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading the baseline: %s\n", err)
+		return 1
+	}
+	baseline, err := audit.ReadSnapshot(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading the baseline %s: %s\n", *baselinePath, err)
+		return 1
+	}
+	changes := audit.Compare(baseline.Declarations, current.Declarations)
+	counts := map[audit.Status]int{}
+	for _, c := range changes {
+		counts[c.Status]++
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Changes []audit.Change       `json:"changes"`
+			Totals  map[audit.Status]int `json:"totals"`
+		}{changes, counts})
+		return 0
+	}
+
+	// The pipes would end the cells of the tables, even in the code.
+	code := func(s string) string { return "`" + strings.ReplaceAll(s, "|", `\|`) + "`" }
+	totals := []string{}
+	for _, status := range audit.Statuses {
+		if counts[status] > 0 {
+			totals = append(totals, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	fmt.Fprintln(stdout, "## Changes since the audit")
+	fmt.Fprintln(stdout)
+	if len(totals) == 0 {
+		fmt.Fprintln(stdout, "No declarations.")
+		return 0
+	}
+	fmt.Fprintf(stdout, "%s.\n", strings.Join(totals, ", "))
+	if counts[audit.Unchanged] == len(changes) {
+		return 0
+	}
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "| Status | Kind | Declaration | Audited as | Change |")
+	fmt.Fprintln(stdout, "| --- | --- | --- | --- | --- |")
+	for _, c := range changes {
+		if c.Status == audit.Unchanged {
+			continue
+		}
+		declaration, audited, change := "-", "-", "-"
+		if c.Current != nil {
+			declaration = code(c.Current.String())
+		}
+		if c.Baseline != nil && (c.Current == nil || *c.Baseline != *c.Current) {
+			audited = code(c.Baseline.String())
+		}
+		if c.Status == audit.Modified {
+			change = fmt.Sprintf("+%d -%d tokens", c.Inserted, c.Deleted)
+		}
+		fmt.Fprintf(stdout, "| %s | %s | %s | %s | %s |\n", c.Status, c.Kind, declaration, audited, change)
+	}
+	return 0
+}
+
+// startTests prints the Foundry tests of the contracts under the path, the
+// inherited ones included, e.g.
+//
+//	solbot tests test --format json
+//
+// The JSON output lists the tests by contract with the forge command lines
+// running them, for the CI helpers.
+func startTests(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tests", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot tests <path> [--format table|json] [--root dir]")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "table", "Output format: table or json")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	positional := []string{}
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional, args = append(positional, args[0]), args[1:]
+		}
+	}
+	if len(positional) != 1 || *format != "table" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+
+	state, _, err := loadDocuments(positional[0], *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	absPath, _ := filepath.Abs(positional[0])
+
+	type param struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	type test struct {
+		Name          string   `json:"name"`
+		Kind          string   `json:"kind"`
+		Location      string   `json:"location"`
+		ExpectsRevert bool     `json:"expectsRevert"`
+		FuzzParams    []param  `json:"fuzzParams"`
+		Command       []string `json:"command"`
+	}
+	type contract struct {
+		Contract string `json:"contract"`
+		Location string `json:"location"`
+		SetUp    string `json:"setUp,omitempty"`
+		Tests    []test `json:"tests"`
+	}
+	location := func(doc *analysis.Document, pos token.Pos) string {
+		p := doc.Handle.Position(pos)
+		return fmt.Sprintf("%s:%d:%d", state.RelativePath(doc.URI), p.Line, p.Column)
+	}
+	contracts := []contract{}
+	total := 0
+	for _, tc := range state.PathTests(absPath) {
+		c := contract{Contract: tc.Contract.Name.Name, Location: location(tc.Contract.Doc, tc.Contract.Name.Start()), Tests: []test{}}
+		if tc.SetUp != nil {
+			c.SetUp = location(tc.SetUp.Doc, tc.SetUp.Name.Start())
+		}
+		for _, t := range tc.Tests {
+			params := []param{}
+			for _, p := range t.FuzzParams() {
+				name := ""
+				if p.Name != nil {
+					name = p.Name.Name
+				}
+				params = append(params, param{Name: name, Type: ast.ExprString(p.Type)})
+			}
+			c.Tests = append(c.Tests, test{
+				Name:          t.Decl.Name.Name,
+				Kind:          string(t.Kind),
+				Location:      location(t.Doc, t.Decl.Name.Start()),
+				ExpectsRevert: t.ExpectsRevert,
+				FuzzParams:    params,
+				Command:       tc.Command(t),
+			})
+		}
+		total += len(c.Tests)
+		contracts = append(contracts, c)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Contracts []contract `json:"contracts"`
+		}{contracts})
+		return 0
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTRACT\tTEST\tKIND\tREVERTS\tLOCATION")
+	for _, c := range contracts {
+		for _, t := range c.Tests {
+			reverts := "-"
+			if t.ExpectsRevert {
+				reverts = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Contract, t.Name, t.Kind, reverts, t.Location)
+		}
+	}
+	w.Flush()
+	fmt.Fprintf(stdout, "%s in %s\n", plural(total, "test"), plural(len(contracts), "contract"))
+	return 0
+}
+
+// plural returns the count with the noun e.g. "1 test" or "2 tests".
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// startInventory prints the source files under the path grouped by
+// directory, with their licenses, file-level NatSpec, pragmas, line counts
+// and hashes, e.g.
+//
+//	solbot inventory src --format markdown
+//
+// The Markdown output is the scope section of the audit reports, a table
+// per directory. The dependencies are listed only with --include-deps, in a
+// section of their own.
+func startInventory(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot inventory <path> [--format markdown|json|csv] [--include-deps] [--root dir]")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "markdown", "Output format: markdown, json or csv")
+	includeDeps := fs.Bool("include-deps", false, "List the files of the dependencies too, in a separate section")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	positional := []string{}
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional, args = append(positional, args[0]), args[1:]
+		}
+	}
+	if len(positional) != 1 || *format != "markdown" && *format != "json" && *format != "csv" {
+		fs.Usage()
+		return 2
+	}
+
+	state, _, err := loadDocuments(positional[0], *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	absPath, _ := filepath.Abs(positional[0])
+	inventory := state.Inventory(absPath, *includeDeps)
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(inventory)
+	case "csv":
+		w := csv.NewWriter(stdout)
+		w.Write([]string{"directory", "path", "dependency", "license", "title", "author", "notice", "pragma", "lines", "sloc", "comment_lines", "sha256"})
+		for _, section := range []struct {
+			dirs       []lsp.InventoryDirectory
+			dependency string
+		}{{inventory.Directories, "false"}, {inventory.Dependencies, "true"}} {
+			for _, dir := range section.dirs {
+				for _, f := range dir.Files {
+					w.Write([]string{dir.Path, f.Path, section.dependency, f.License, f.Title, f.Author, f.Notice, f.Pragma,
+						fmt.Sprint(f.Lines), fmt.Sprint(f.SLOC), fmt.Sprint(f.CommentLines), f.Hash})
+				}
+			}
+		}
+		w.Flush()
+	default:
+		printInventoryMarkdown(stdout, inventory)
+	}
+	return 0
+}
+
+// printInventoryMarkdown prints the inventory as the scope section of an
+// audit report.
+func printInventoryMarkdown(w io.Writer, inventory lsp.Inventory) {
+	// The pipes would end the cells of the tables.
+	cell := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return strings.ReplaceAll(s, "|", `\|`)
+	}
+	tables := func(dirs []lsp.InventoryDirectory) {
+		for _, dir := range dirs {
+			fmt.Fprintf(w, "\n### %s\n\n| File | Title | License | Pragma | SLOC | Comment lines | SHA-256 |\n| --- | --- | --- | --- | ---: | ---: | --- |\n", dir.Path)
+			for _, f := range dir.Files {
+				fmt.Fprintf(w, "| %s | %s | %s | %s | %d | %d | `%s` |\n", cell(f.Path), cell(f.Title), cell(f.License), cell(f.Pragma), f.SLOC, f.CommentLines, f.Hash)
+			}
+			fmt.Fprintf(w, "| **Total** | | | | %d | %d | |\n", dir.Totals.SLOC, dir.Totals.CommentLines)
+		}
+	}
+
+	totals := inventory.Totals
+	fmt.Fprintf(w, "## Scope\n\n%s, %d SLOC, %s.\n", plural(totals.Files, "file"), totals.SLOC, plural(totals.CommentLines, "comment line"))
+	if len(inventory.Licenses) > 0 {
+		licenses := []string{}
+		for _, l := range inventory.Licenses {
+			name := l.License
+			if name == "" {
+				name = "no license"
+			}
+			licenses = append(licenses, fmt.Sprintf("%s (%d)", name, l.Files))
+		}
+		fmt.Fprintf(w, "\nLicenses: %s.\n", strings.Join(licenses, ", "))
+	}
+	tables(inventory.Directories)
+	if len(inventory.Dependencies) > 0 {
+		fmt.Fprintln(w, "\n## Dependencies")
+		tables(inventory.Dependencies)
+	}
+}
+
+// startDocsCheck prints the problems of the fenced Solidity blocks of the
+// Markdown files under the path, at their lines in the files e.g.
+//
+//	solbot docs-check docs --format plain
+//
+// The blocks are checked even if the [docs] analysis of solbot.toml is off;
+// its diagnostics are reported along with the syntax errors. It exits with
+// 1 if there are any errors.
+func startDocsCheck(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("docs-check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot docs-check <path> [--root dir] [--color auto] [--format pretty]")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	output := newOutputFlags(fs)
+	path, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	opts, err := output.options(stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading path: %s\n", err)
+		return 1
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading path: %s\n", err)
+		return 1
+	}
+	if *root == "" {
+		dir := absPath
+		if !info.IsDir() {
+			dir = filepath.Dir(absPath)
+		}
+		*root = findProjectRoot(dir)
+	}
+	state := analysis.NewState()
+	ctx := context.Background()
+	if err := state.IndexWorkspace(ctx, *root); err != nil {
+		fmt.Fprintf(stderr, "Error indexing the project: %s\n", err)
+		return 1
+	}
+	state.Config.Docs.Analysis = true
+	if err := state.IndexMarkdown(ctx, absPath); err != nil {
+		fmt.Fprintf(stderr, "Error reading the docs: %s\n", err)
+		return 1
+	}
+
+	severities := map[lsp.DiagnosticSeverity]string{
+		lsp.SeverityError:       "Error",
+		lsp.SeverityWarning:     "Warning",
+		lsp.SeverityInformation: "Info",
+		lsp.SeverityHint:        "Hint",
+	}
+	base := analysis.PathToURI(absPath)
+	files, errors := 0, 0
+	diagnostics := []render.Diagnostic{}
+	for _, uri := range state.MarkdownURIs() {
+		if uri != base && !strings.HasPrefix(uri, strings.TrimSuffix(base, "/")+"/") {
+			continue
+		}
+		files++
+		src, err := os.ReadFile(analysis.URIToPath(uri))
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading file: %s\n", err)
+			return 1
+		}
+		file := token.NewFile(state.RelativePath(uri), string(src))
+		pos := func(p lsp.Position) token.Pos {
+			return file.OffsetUTF16(int(p.Line)+1, int(p.Character)+1)
+		}
+		for _, d := range state.Diagnostics(ctx, uri).Params.Diagnostics {
+			if d.Severity == lsp.SeverityError {
+				errors++
+			}
+			diagnostics = append(diagnostics, render.Diagnostic{
+				Severity: severities[d.Severity],
+				Message:  fmt.Sprintf("%s [%s]", d.Message, d.Code),
+				File:     file,
+				Range:    token.Range{Start: pos(d.Range.Start), End: pos(d.Range.End)},
+			})
+		}
+	}
+	if files == 0 {
+		fmt.Fprintf(stderr, "%s has no Markdown files with Solidity code blocks\n", path)
+		return 1
+	}
+	if len(diagnostics) == 0 {
+		fmt.Fprintf(stdout, "No problems in the code blocks of %s\n", plural(files, "file"))
+		return 0
+	}
+	render.Render(stderr, diagnostics, opts)
+	if errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+// startFix applies the quick fixes of the diagnostics to the files under
+// the path, in place e.g.
+//
+//	solbot fix src --only now,byte --dry-run
+//
+// Only the safe fixes are applied, unless --unsafe is passed, and only the
+// ones of the listed diagnostic codes, if any. The imports are organized
+// the way the code action does, following the [imports] section of
+// solbot.toml. The fixes of a file are rolled back if they introduce
+// errors, and the fixes overlapping the ones applied before are skipped;
+// both are reported. With --dry-run, the unified diffs are printed instead
+// of writing the files.
+func startFix(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fix", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot fix path [--only code1,code2] [--unsafe] [--dry-run] [--root dir]")
+		fs.PrintDefaults()
+	}
+	only := fs.String("only", "", "Comma-separated codes of the diagnostics to fix, e.g. now,organize-imports; all of them if empty")
+	unsafe := fs.Bool("unsafe", false, "Apply the fixes that are only suggested, too")
+	dryRun := fs.Bool("dry-run", false, "Print the unified diffs instead of writing the files")
+	organizeImports := fs.Bool("organize-imports", false, "Only sort, deduplicate and merge the imports, and drop the unused ones")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	path, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	codes := map[string]bool{}
+	if *only != "" {
+		for _, c := range strings.Split(*only, ",") {
+			codes[strings.TrimSpace(c)] = true
+		}
+	}
+	if *organizeImports {
+		codes[analysis.OrganizeImportsCode] = true
+	}
+
+	state, uris, err := loadDocuments(path, *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	exit := 0
+	for _, uri := range uris {
+		fixes := []analysis.Fix{}
+		for _, fix := range state.Fixes(uri) {
+			if (fix.Safe || *unsafe) && (len(codes) == 0 || codes[fix.Code]) {
+				fixes = append(fixes, fix)
+			}
+		}
+		if len(fixes) == 0 {
+			continue
+		}
+		name := state.RelativePath(uri)
+		handle := state.Documents[uri].Handle
+		src := handle.Src()
+		res := state.ApplyFixes(uri, fixes)
+		for _, fix := range res.Conflicts {
+			position := handle.Position(fix.Range.Start)
+			fmt.Fprintf(stderr, "%s:%d:%d: skipped %s: %s, it overlaps another fix\n", name, position.Line, position.Column, fix.Code, fix.Title)
+		}
+		if len(res.Errors) > 0 {
+			fmt.Fprintf(stderr, "%s: rolled back the fixes, they introduce errors:\n", name)
+			for _, e := range res.Errors {
+				fmt.Fprintf(stderr, "\t%s\n", e)
+			}
+			exit = 1
+			continue
+		}
+
+		if *dryRun {
+			fmt.Fprint(stdout, textedit.Diff("a/"+name, "b/"+name, src, res.Src))
+			continue
+		}
+		if err := state.WriteFile(uri, []byte(res.Src)); err != nil {
+			fmt.Fprintf(stderr, "Error writing file: %s\n", err)
+			return 1
+		}
+		for _, fix := range res.Applied {
+			position := handle.Position(fix.Range.Start)
+			fmt.Fprintf(stdout, "%s:%d:%d: fixed %s: %s\n", name, position.Line, position.Column, fix.Code, fix.Title)
+		}
+	}
+	return exit
+}
+
+const queryUsage = `Usage: solbot query <selector> path [--root dir]
+
+Prints the nodes of the files at the path, a file or a directory, matching
+the selector as JSON lines with their locations and source excerpts e.g.
+
+  solbot query 'function[visibility=external][mutability!=view] > call[callee=*.delegatecall]' src/
+
+Selectors:
+  kind           nodes of the kind e.g. function, call; * for any kind
+  a b            b inside of a
+  a > b          b directly inside of a; blocks and statements are skipped
+  [attr=glob]    the attribute matches the glob pattern e.g. [name=_*]
+  [attr!=glob]   the attribute doesn't match or the node has none
+  :has(b)        a node with b inside; :has(> b) for b directly inside
+  :not(b)        a node not matching b
+
+Kinds:
+  contract, function, modifier, event, error, struct, enum, variable, param,
+  call, assignment, binary, unary, conditional, member, index, identifier,
+  literal, new, if, for, while, do, return, emit, revert, try, catch,
+  unchecked, assembly, import, pragma, using, type
+
+Attributes:
+  name           declared name, identifier, accessed member or called function
+  kind           contract, interface, library; function, constructor, fallback, receive
+  visibility     external, public, internal or private, as written
+  mutability     pure, view, payable, nonpayable; constant, immutable, mutable
+  location       storage, memory or calldata
+  operator       operator of the binary, unary and assignment expressions e.g. +=
+  callee         called expression e.g. vault.deposit, or its resolved name e.g. IVault.deposit
+
+Flags:
+`
+
+// queryMatch is a line of the query output.
+type queryMatch struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Kind      string `json:"kind"`
+	Excerpt   string `json:"excerpt"` // first line of the node
+}
+
+// startExtractRepro writes the sources reproducing a diagnostic to the
+// output directory, reduced to the declarations it needs, with a manifest
+// of the reductions e.g.
+//
+//	solbot extract-repro --diagnostic src/Vault.sol:12:dead-store --output repro
+//
+// The line is the 1-based one of the diagnostic, as printed by the editors.
+func startExtractRepro(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("extract-repro", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot extract-repro --diagnostic <file:line:code> --output dir [--root dir]")
+		fs.PrintDefaults()
+	}
+	diagnostic := fs.String("diagnostic", "", "The diagnostic to reproduce e.g. src/Vault.sol:12:dead-store")
+	output := fs.String("output", "", "Directory to write the sources and the manifest to")
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	rest, code, ok := cutLast(*diagnostic, ":")
+	filePath, lineText, ok2 := cutLast(rest, ":")
+	line, err := strconv.Atoi(lineText)
+	if !ok || !ok2 || err != nil || line < 1 || code == "" || *output == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	state, _, err := loadDocuments(filePath, *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	absPath, _ := filepath.Abs(filePath)
+	repro, err := state.Repro(context.Background(), analysis.PathToURI(absPath), uint(line-1), code)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if err := os.MkdirAll(*output, 0755); err != nil {
+		fmt.Fprintf(stderr, "Error writing the output: %s\n", err)
+		return 1
+	}
+	files := []string{}
+	for name := range repro.Files {
+		files = append(files, name)
+	}
+	slices.Sort(files)
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(*output, name), []byte(repro.Files[name]), 0644); err != nil {
+			fmt.Fprintf(stderr, "Error writing the output: %s\n", err)
+			return 1
+		}
+	}
+	var manifest bytes.Buffer
+	printReproManifest(&manifest, repro, files)
+	if err := os.WriteFile(filepath.Join(*output, "MANIFEST.md"), manifest.Bytes(), 0644); err != nil {
+		fmt.Fprintf(stderr, "Error writing the output: %s\n", err)
+		return 1
+	}
+	start := repro.Diagnostic.Range.Start
+	fmt.Fprintf(stdout, "Wrote %s to %s, %s at %s:%d:%d\n", plural(len(files), "file"), *output, code, repro.Target, start.Line+1, start.Character+1)
+	fmt.Fprintf(stdout, "Applied %s and backed out %d, after %s\n", plural(len(repro.Removed), "reduction"), len(repro.BackedOut), plural(repro.Runs, "run"))
+	return 0
+}
+
+// cutLast slices s around the last instance of sep, see strings.Cut.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// printReproManifest prints the diagnostic, the files and the reductions
+// of the reproduction in Markdown.
+func printReproManifest(w io.Writer, repro *analysis.Repro, files []string) {
+	d := repro.Diagnostic
+	fmt.Fprintf(w, "# Reproduction of %s\n\n", d.Code)
+	fmt.Fprintf(w, "%s:%d:%d: %s\n\n", repro.Target, d.Range.Start.Line+1, d.Range.Start.Character+1, d.Message)
+	fmt.Fprintf(w, "## Files\n\n")
+	for _, name := range files {
+		fmt.Fprintf(w, "- %s, from %s\n", name, repro.Origins[name])
+	}
+	fmt.Fprintf(w, "\n## Removed\n\n")
+	for _, r := range repro.Removed {
+		fmt.Fprintf(w, "- %s\n", r)
+	}
+	if len(repro.BackedOut) > 0 {
+		fmt.Fprintf(w, "\n## Kept\n\nRemoving these loses the diagnostic:\n\n")
+		for _, r := range repro.BackedOut {
+			fmt.Fprintf(w, "- %s\n", r)
+		}
+	}
+}
+
+// startQuery prints the nodes matching the selector, see queryUsage.
+func startQuery(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, queryUsage)
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	positional := []string{}
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional, args = append(positional, args[0]), args[1:]
+		}
+	}
+	if len(positional) != 2 {
+		fs.Usage()
+		return 2
+	}
+	selector, path := positional[0], positional[1]
+	if _, err := query.Parse(selector); err != nil {
+		fmt.Fprintf(stderr, "Invalid selector: %s\n", err)
+		return 2
+	}
+
+	state, uris, err := loadDocuments(path, *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	enc := json.NewEncoder(stdout)
+	for _, uri := range uris {
+		matches, err := state.Query(uri, selector)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		handle := state.Documents[uri].Handle
+		for _, m := range matches {
+			start, end := handle.Position(m.Range.Start), handle.Position(m.Range.End)
+			excerpt, _, _ := strings.Cut(handle.Src()[m.Range.Start:m.Range.End], "\n")
+			enc.Encode(queryMatch{
+				File:      state.RelativePath(uri),
+				Line:      start.Line,
+				Column:    start.Column,
+				EndLine:   end.Line,
+				EndColumn: end.Column,
+				Kind:      m.Kind,
+				Excerpt:   strings.TrimSpace(excerpt),
+			})
+		}
+	}
+	return 0
+}
+
+// startTrace evaluates the function with the values of its parameters and
+// prints the variables after every executed statement e.g.
+//
+//	solbot trace src/Vault.sol --function computeFee --args '{"amount": "1000000", "feeBps": "30"}'
+//
+// The trace ends with the returned values, or where the evaluation stopped
+// and why, see analysis.Evaluate.
+func startTrace(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("trace", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot trace path/to/file.sol --function name [--args json] [--root dir]")
+		fs.PrintDefaults()
+	}
+	function := fs.String("function", "", "Evaluated function e.g. computeFee or Vault.computeFee")
+	argsJSON := fs.String("args", "{}", `Values of the parameters, and of the state variables read, as a JSON object e.g. '{"amount": "1e18", "paused": false}'`)
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	filePath, code, ok := parseArgs(fs, args)
+	if !ok {
+		return code
+	}
+	if *function == "" {
+		fs.Usage()
+		return 2
+	}
+	values, err := traceArguments(*argsJSON)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid arguments: %s\n", err)
+		return 2
+	}
+
+	state, uris, err := loadDocuments(filePath, *root)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if len(uris) != 1 {
+		fmt.Fprintf(stderr, "%s is not a Solidity file\n", filePath)
+		return 2
+	}
+	evaluation, err := state.Evaluate(uris[0], *function, values)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	handle := state.Documents[uris[0]].Handle
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tSTATEMENT\tNOTE\tVARIABLES")
+	for _, step := range evaluation.Steps {
+		excerpt, _, _ := strings.Cut(handle.Src()[step.Range.Start:step.Range.End], "\n")
+		variables := []string{}
+		for _, v := range step.Variables {
+			variables = append(variables, v.Name+" = "+v.Value)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", handle.Position(step.Range.Start).Line, strings.TrimSpace(excerpt), step.Note, strings.Join(variables, ", "))
+	}
+	w.Flush()
+	switch stop := evaluation.Stop; {
+	case stop == nil:
+		fmt.Fprintf(stdout, "Returns %s\n", strings.Join(evaluation.Returns, ", "))
+	case stop.Reverted:
+		fmt.Fprintf(stdout, "Reverts at line %d: %s\n", handle.Position(stop.Range.Start).Line, stop.Reason)
+	default:
+		fmt.Fprintf(stdout, "Stopped at line %d: %s\n", handle.Position(stop.Range.Start).Line, stop.Reason)
+	}
+	return 0
+}
+
+// traceArguments returns the values of the JSON object, which can be
+// strings, numbers or booleans.
+func traceArguments(src string) (map[string]string, error) {
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for name, v := range raw {
+		switch v := v.(type) {
+		case string:
+			values[name] = v
+		case json.Number, bool:
+			values[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("the value of `%s` is not a string, a number or a boolean", name)
+		}
+	}
+	return values, nil
+}
+
+// startRules lists the rules of the detectors with their confidence e.g.
+//
+//	solbot rules --root .
+//
+// The detectors disabled in solbot.toml of the project are marked.
+func startRules(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rules", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot rules [--root dir]")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "Project root; the nearest directory with foundry.toml, remappings.txt or .git if empty")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	if *root == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading the working directory: %s\n", err)
+			return 1
+		}
+		*root = findProjectRoot(cwd)
+	}
+	cfg, err := project.Load(*root)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading the project configuration: %s\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, rule := range analyzer.Rules() {
+		state := "enabled"
+		if slices.Contains(cfg.Disabled, rule.Code) {
+			state = "disabled"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", rule.Code, rule.Confidence, state, rule.Title)
+	}
+	w.Flush()
+	return 0
+}
+
+// startExplain prints the writeup of the rule with the code e.g.
+//
+//	solbot explain msg-value-loop
+func startExplain(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: solbot explain <code>\n\nRun 'solbot rules' for the codes.")
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	rule, ok := analyzer.LookupRule(fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown rule: `%s`\nRun 'solbot rules' for the codes.\n", fs.Arg(0))
+		return 1
+	}
+	fmt.Fprint(stdout, rule.Writeup())
+	return 0
+}
+
+// projectConfig returns the configuration of the project of the file; or
+// the defaults if it can't be read.
+func projectConfig(filePath string, stderr io.Writer) project.Config {
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return project.DefaultConfig()
+	}
+	cfg, err := project.Load(findProjectRoot(dir))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading the project configuration: %s\n", err)
+	}
+	return cfg
+}
+
+// loadDocuments indexes the project and returns the URIs of its documents
+// at the path, a file or a directory, in order. The root is found from the
+// path if it's empty.
+func loadDocuments(path, root string) (*analysis.State, []string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading path: %s", err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading path: %s", err)
+	}
+	if root == "" {
+		dir := absPath
+		if !info.IsDir() {
+			dir = filepath.Dir(absPath)
+		}
+		root = findProjectRoot(dir)
+	}
+	state := analysis.NewState()
+	if err := state.IndexWorkspace(context.Background(), root); err != nil {
+		return nil, nil, fmt.Errorf("Error indexing the project: %s", err)
+	}
+	base := analysis.PathToURI(absPath)
+	uris := []string{}
+	for uri := range state.Documents {
+		if uri == base || info.IsDir() && strings.HasPrefix(uri, strings.TrimSuffix(base, "/")+"/") {
+			uris = append(uris, uri)
+		}
+	}
+	if len(uris) == 0 {
+		return nil, nil, fmt.Errorf("%s has no Solidity files of the project at %s", path, root)
+	}
+	slices.Sort(uris)
+	return state, uris, nil
+}
+
+// findProjectRoot returns the nearest directory with the project
+// configuration or the git repository; or the start directory if there
+// is none.
+func findProjectRoot(dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		for _, marker := range []string{"foundry.toml", "remappings.txt", ".git"} {
+			if _, err := os.Stat(filepath.Join(d, marker)); err == nil {
+				return d
+			}
+		}
+		if filepath.Dir(d) == d {
+			return dir
+		}
+	}
+}
+
+// getLogger returns the logger writing to the file; or discarding the
+// records if the file name is empty.
+func getLogger(filename string, trace bool) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if trace {
+		level = slog.LevelDebug
+	}
+	if filename == "" {
+		return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: level})), nil
+	}
+
+	// Bitwise OR is used to combine the flags e.g. 001 | 010 | 100 is 111
+	logfile, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(slog.NewTextHandler(logfile, &slog.HandlerOptions{Level: level})), nil
 }
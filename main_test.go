@@ -0,0 +1,1652 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	goparser "go/parser"
+	gotoken "go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"solbot/analyzer"
+	"solbot/baseline"
+	"solbot/keccak"
+	"solbot/lsp/server"
+	"solbot/parser"
+	"solbot/sarif"
+	"solbot/token"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_LspFlags(t *testing.T) {
+	limits := server.DefaultLimits()
+	custom := server.Limits{MaxMessageSize: 1024, MaxDocumentSize: 0, MaxParsedSize: limits.MaxParsedSize, MaxLineLength: limits.MaxLineLength, ReadTimeout: 5 * time.Second, Parser: limits.Parser}
+	parsing := limits
+	parsing.Parser.MaxExpressionDepth, parsing.Parser.MaxNodes, parsing.Parser.Timeout = 64, 0, time.Second
+	tests := []struct {
+		args     []string
+		expected lspOptions
+		err      bool
+	}{
+		{nil, lspOptions{logPath: "log.txt", limits: limits}, false},
+		{[]string{"--stdio"}, lspOptions{logPath: "log.txt", limits: limits}, false},
+		{[]string{"--stdio", "--clientProcessId=1234"}, lspOptions{logPath: "log.txt", limits: limits}, false},
+		{[]string{"--listen", "localhost:9257", "--log", "", "--trace"}, lspOptions{listen: "localhost:9257", trace: true, limits: limits}, false},
+		{[]string{"--max-message-size", "1024", "--max-document-size", "0", "--read-timeout", "5s"}, lspOptions{logPath: "log.txt", limits: custom}, false},
+		{[]string{"--max-expression-depth", "64", "--max-nodes", "0", "--parse-timeout", "1s"}, lspOptions{logPath: "log.txt", limits: parsing}, false},
+		{[]string{"--record", "session.jsonl", "--redact"}, lspOptions{logPath: "log.txt", limits: limits, record: "session.jsonl", redact: true}, false},
+		{[]string{"--redact"}, lspOptions{}, true},
+		{[]string{"--stdio", "--listen", ":9257"}, lspOptions{}, true},
+		{[]string{"--socket=9257"}, lspOptions{}, true},
+		{[]string{"file.sol"}, lspOptions{}, true},
+	}
+
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+		opts, err := parseLspFlags(tt.args, &stderr)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Expected an error for %q, got %+v", tt.args, opts)
+			}
+			if !strings.Contains(stderr.String(), "Usage: solbot lsp") {
+				t.Errorf("Expected the usage for %q, got %q", tt.args, stderr.String())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected no error for %q, got %s", tt.args, err)
+			continue
+		}
+		if opts != tt.expected {
+			t.Errorf("Expected %+v for %q, got %+v", tt.expected, tt.args, opts)
+		}
+	}
+
+	if _, err := parseLspFlags([]string{"--help"}, io.Discard); err != flag.ErrHelp {
+		t.Errorf("Expected flag.ErrHelp for --help, got %v", err)
+	}
+}
+
+func Test_Version(t *testing.T) {
+	format := regexp.MustCompile(`^solbot \S+ \(Solidity ` + regexp.QuoteMeta(parser.Grammar) + `\)\n$`)
+	for _, args := range [][]string{{"version"}, {"--version"}} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("Expected exit code 0 for %q, got %d", args, code)
+		}
+		if !format.MatchString(stdout.String()) {
+			t.Errorf("Expected the version for %q, got %q", args, stdout.String())
+		}
+	}
+}
+
+func Test_UsageErrors(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"serve"},
+		{"lsp", "--bogus"},
+		{"--bogus"},
+		{"analyze"},
+		{"explain"},
+		{"query", "call"},
+		{"rules", "extra"},
+		{"baseline"},
+		{"baseline", "bogus"},
+		{"audit-diff", "src"},
+		{"deploy-params"},
+		{"deploy-params", "Vault", "--format", "markdown"},
+		{"compile-input"},
+		{"compile-input", "A.sol", "B.sol"},
+		{"metrics", "--format", "csv"},
+	}
+
+	for _, args := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, nil, &stdout, &stderr); code != 2 {
+			t.Errorf("Expected exit code 2 for %q, got %d", args, code)
+		}
+		if !strings.Contains(stderr.String(), "Usage:") {
+			t.Errorf("Expected the usage for %q, got %q", args, stderr.String())
+		}
+	}
+}
+
+func Test_Query(t *testing.T) {
+	root := t.TempDir()
+	src := `pragma solidity ^0.8.0;
+
+contract Router {
+    address implementation;
+
+    fallback() external payable {
+        (bool ok, ) = implementation.delegatecall(msg.data);
+        require(ok);
+    }
+}
+`
+	if err := os.WriteFile(filepath.Join(root, "Router.sol"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"query", "function[mutability=payable] > call[callee=*.delegatecall]", root}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := `{"file":"Router.sol","line":7,"column":23,"endLine":7,"endColumn":60,"kind":"call","excerpt":"implementation.delegatecall(msg.data)"}` + "\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+
+	stderr.Reset()
+	if code := run([]string{"query", "function[visibility]", root}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an invalid selector, got %d", code)
+	}
+	if expected := "Invalid selector: column 20: unexpected `]`, expected `=` or `!=` after the attribute\n"; stderr.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr.String())
+	}
+}
+
+func Test_RulesAndExplain(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "solbot.toml"), []byte("[detectors]\ndisabled = [\"msg-value-loop\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"rules", "--root", root}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	for _, line := range []string{
+		"msg-value-loop         high    disabled  `msg.value` used in a loop\n",
+		"missing-safemath       medium  enabled   Integer arithmetic is not protected against overflows before Solidity 0.8.0\n",
+	} {
+		if !strings.Contains(stdout.String(), line) {
+			t.Errorf("Expected %q in the rules, got %q", line, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"explain", "missing-safemath"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	for _, section := range []string{"missing-safemath: ", "Confidence: medium\n", "\nExploit scenario:\n", "\nRemediation:\n", "  - SWC-101\n"} {
+		if !strings.Contains(stdout.String(), section) {
+			t.Errorf("Expected %q in the writeup, got %q", section, stdout.String())
+		}
+	}
+
+	stderr.Reset()
+	if code := run([]string{"explain", "reentrancy"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1 for an unknown rule, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "Unknown rule: `reentrancy`") {
+		t.Errorf("Expected the unknown rule error, got %q", stderr.String())
+	}
+	if code := run([]string{"analyze", "Vault.sol", "--min-confidence", "certain"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an invalid confidence, got %d", code)
+	}
+}
+
+func Test_AnalyzePlain(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "Vault.sol")
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    function pay(address[] calldata to) external payable {
+        for (uint256 i = 0; i < to.length; i++) {
+            payable(to[i]).transfer(msg.value);
+        }
+    }
+}
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The report of analyze is written to the working directory.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"analyze", path, "--format", "plain"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	// Every line is a finding, nothing else.
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 findings, got %q", stdout.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, path+":6:") || !strings.Contains(line, ": warning: ") {
+			t.Errorf("Expected a finding at line 6, got %q", line)
+		}
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func Test_AnalyzeSARIF(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "Vault.sol")
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    function pay(address[] calldata to) external payable {
+        for (uint256 i = 0; i < to.length; i++) {
+            payable(to[i]).transfer(msg.value);
+        }
+    }
+}
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"analyze", path, "--format", "sarif"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var log sarif.Log
+	if err := json.Unmarshal(stdout.Bytes(), &log); err != nil {
+		t.Fatalf("Expected the SARIF log on stdout, got %s: %q", err, stdout.String())
+	}
+	r := log.Runs[0]
+	if len(r.Tool.Driver.Rules) != len(analyzer.Rules()) {
+		t.Errorf("Expected the rules of all of the detectors, got %d", len(r.Tool.Driver.Rules))
+	}
+	if len(r.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(r.Results))
+	}
+	for _, result := range r.Results {
+		loc := result.Locations[0].PhysicalLocation
+		if loc.ArtifactLocation.URI != "Vault.sol" || loc.Region.StartLine != 6 || result.Level != "warning" {
+			t.Errorf("Expected a warning at Vault.sol:6, got %s at %s:%d", result.Level, loc.ArtifactLocation.URI, loc.Region.StartLine)
+		}
+		rule := r.Tool.Driver.Rules[result.RuleIndex]
+		if rule.ID != result.RuleID || rule.FullDescription.Text == "" || !strings.Contains(rule.Help.Markdown, "## Remediation") {
+			t.Errorf("Expected the writeup of %s, got %+v", result.RuleID, rule)
+		}
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+// syncBuffer is written by the idle goroutine while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type fakeTerminal bool
+
+func (f fakeTerminal) IsTerminal(io.Reader) bool { return bool(f) }
+
+func Test_IdleWarning(t *testing.T) {
+	defer func(terminal terminal, delay time.Duration) {
+		stdinTerminal, idleDelay = terminal, delay
+	}(stdinTerminal, idleDelay)
+	stdinTerminal, idleDelay = fakeTerminal(true), 10*time.Millisecond
+
+	// Nothing is typed in until the message is printed.
+	stdin, input := io.Pipe()
+	var stdout, stderr syncBuffer
+	done := make(chan int)
+	go func() { done <- run([]string{"lsp", "--log", ""}, stdin, &stdout, &stderr) }()
+
+	deadline := time.Now().Add(time.Second)
+	for stderr.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stderr.String() != idleMessage {
+		t.Fatalf("Expected the idle message, got %q", stderr.String())
+	}
+	input.Close()
+	if code := <-done; code != 0 {
+		t.Errorf("Expected exit code 0 after the input is closed, got %d", code)
+	}
+
+	// The client sends a message right away.
+	var quiet syncBuffer
+	r := warnIfIdle(strings.NewReader("Content-Length: 2\r\n\r\n{}"), 10*time.Millisecond, &quiet)
+	if _, err := r.Read(make([]byte, 8)); err != nil {
+		t.Fatalf("Expected the message to be read, got %s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if quiet.String() != "" {
+		t.Errorf("Expected no message after the first read, got %q", quiet.String())
+	}
+}
+
+func Test_VerifySources(t *testing.T) {
+	root := t.TempDir()
+	fees := "contract Fees {\n    uint256 public constant FEE_BPS = 30;\n}\n"
+	vault := "import \"./Fees.sol\";\n\ncontract Vault is Fees {}\n"
+	for name, src := range map[string]string{"Fees.sol": fees, "Vault.sol": vault} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash := func(src string) string {
+		sum := keccak.Sum256([]byte(src))
+		return fmt.Sprintf("0x%x", sum)
+	}
+	metadata := fmt.Sprintf(`{
+  "settings": {"compilationTarget": {"Vault.sol": "Vault"}, "remappings": [], "optimizer": {"enabled": false, "runs": 200}},
+  "sources": {"Fees.sol": {"keccak256": %q}, "Vault.sol": {"keccak256": %q}}
+}`, hash(fees), hash(vault))
+	metadataPath := filepath.Join(root, "metadata.json")
+	if err := os.WriteFile(metadataPath, []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"verify-sources", "--metadata", metadataPath, "--root", root}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s%s", code, stdout.String(), stderr.String())
+	}
+	expected := "Sources of Vault (Vault.sol):\n  match     Fees.sol\n  match     Vault.sol\n" +
+		"The sources and the settings match the metadata\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+
+	// A one-character drift of the imported file, diffed against the
+	// verified input.
+	input := fmt.Sprintf(`{"language": "Solidity", "sources": {"Fees.sol": {"content": %q}}}`, fees)
+	inputPath := filepath.Join(root, "input.json")
+	if err := os.WriteFile(inputPath, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	drifted := strings.Replace(fees, "30", "31", 1)
+	if err := os.WriteFile(filepath.Join(root, "Fees.sol"), []byte(drifted), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	args = []string{"verify-sources", "--metadata", metadataPath, "--standard-json", inputPath, "--root", root}
+	if code := run(args, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	expected = "Sources of Vault (Vault.sol):\n" +
+		"  mismatch  Fees.sol: keccak256 " + hash(drifted) + ", the metadata has " + hash(fees) + "\n" +
+		"    --- Fees.sol (verified)\n    +++ Fees.sol (local)\n    @@ -1,3 +1,3 @@\n     contract Fees {\n" +
+		"    -    uint256 public constant FEE_BPS = 30;\n    +    uint256 public constant FEE_BPS = 31;\n     }\n" +
+		"  match     Vault.sol\n" +
+		"Mismatches: 1 of 2 sources, 0 settings\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	args = []string{"verify-sources", "--metadata", metadataPath, "--root", root, "--format", "json"}
+	if code := run(args, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	var report struct {
+		Sources []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("Expected a JSON report, got %s", err)
+	}
+	if len(report.Sources) != 2 || report.Sources[0].Status != "mismatch" || report.Sources[1].Status != "match" {
+		t.Errorf("Expected Fees.sol to mismatch, got %+v", report.Sources)
+	}
+
+	if code := run([]string{"verify-sources", "--root", root}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 without the metadata, got %d", code)
+	}
+}
+
+func Test_EvalCheck(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"eval-check", "x + 1 days", "--let", "x:uint256"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "x + 1 days: uint256\n" {
+		t.Errorf("Expected the type of the expression, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"eval-check", "--let", "x:uint256", "x + y"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d", code)
+	}
+	if stderr.String() != "1:5: Undeclared identifier `y`\n" {
+		t.Errorf("Expected the undeclared identifier, got %q", stderr.String())
+	}
+
+	if code := run([]string{"eval-check", "x", "--let", "x"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an invalid --let, got %d", code)
+	}
+}
+
+func Test_Trace(t *testing.T) {
+	root := t.TempDir()
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    function computeFee(uint256 amount, uint256 feeBps) public pure returns (uint256) {
+        uint256 fee = amount * feeBps / 10_000;
+        if (fee > 1_000) {
+            fee = 1_000;
+        }
+        return fee;
+    }
+}
+`
+	path := filepath.Join(root, "Vault.sol")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	args := []string{"trace", path, "--function", "computeFee", "--args", `{"amount": "1000000", "feeBps": 30}`}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := `LINE  STATEMENT                               NOTE          VARIABLES
+5     uint256 fee = amount * feeBps / 10_000                amount = 1000000, feeBps = 30, fee = 3000
+6     fee > 1_000                             true          amount = 1000000, feeBps = 30, fee = 3000
+7     fee = 1_000                                           amount = 1000000, feeBps = 30, fee = 1000
+9     return fee                              returns 1000  amount = 1000000, feeBps = 30, fee = 1000
+Returns 1000
+`
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"trace", path, "--function", "computeFee", "--args", "[1]"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for invalid arguments, got %d", code)
+	}
+	if code := run([]string{"trace", path}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 without a function, got %d", code)
+	}
+}
+
+func Test_ParseCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Bad.sol")
+	if err := os.WriteFile(path, []byte("contract A {\n    function f() public {\n        uint x = ;\n    }\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"parse", path, "--format", "plain"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d", code)
+	}
+	expected := path + ":3:18: error: no prefix parse function for ; found\n"
+	if stderr.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr.String())
+	}
+
+	stderr.Reset()
+	if code := run([]string{"parse", path, "--color", "sometimes"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an invalid color mode, got %d", code)
+	}
+}
+
+func Test_ProxyCheck(t *testing.T) {
+	root := t.TempDir()
+	src := `contract Vault {
+    address owner;
+}
+
+contract VaultProxy {
+    address admin;
+}
+`
+	if err := os.WriteFile(filepath.Join(root, "Vault.sol"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"proxy-check", "--proxy", "VaultProxy", "--impl", "Vault", "--root", root, "--format", "plain"}
+	if code := run(args, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d", code)
+	}
+	expected := "Vault.sol:6:13: error: Slot 0 of the proxy `VaultProxy` holds `admin` (address), " +
+		"which collides with `owner` (address) of the implementation `Vault`: slot 0, offset 0\n" +
+		"Vault.sol:2:13: note: `owner` is stored at slot 0, offset 0 of the implementation\n"
+	if stderr.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	args = []string{"proxy-check", "--proxy", "VaultProxy", "--impl", "VaultProxy", "--root", root}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if code := run([]string{"proxy-check", "--proxy", "VaultProxy"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 without the implementation, got %d", code)
+	}
+}
+
+func Test_ProxyCheckQualifiedNames(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"src/Vault.sol":        "contract Vault {\n    uint256 total;\n}\n",
+		"src/VaultProxy.sol":   "contract VaultProxy {\n    address admin;\n}\n",
+		"test/mocks/Vault.sol": "contract Vault {\n    address owner;\n}\n",
+	}
+	for name, src := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"proxy-check", "--proxy", "VaultProxy", "--impl", "Vault", "--root", root}
+	if code := run(args, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d", code)
+	}
+	expected := "contract `Vault` is declared in more than one file, qualify it with the path: src/Vault.sol:Vault, test/mocks/Vault.sol:Vault\n"
+	if stderr.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	args = []string{"proxy-check", "--proxy", "src/VaultProxy.sol:VaultProxy", "--impl", "src/Vault.sol:Vault", "--root", root, "--format", "plain"}
+	if code := run(args, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	expected = "src/VaultProxy.sol:2:13: error: Slot 0 of the proxy `VaultProxy` holds `admin` (address), " +
+		"which collides with `total` (uint256) of the implementation `Vault`: slot 0, offset 0\n" +
+		"src/Vault.sol:2:13: note: `total` is stored at slot 0, offset 0 of the implementation\n"
+	if stderr.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stderr.String())
+	}
+}
+
+func Test_DeployParams(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"src/Owned.sol": `contract Owned {
+    address immutable owner;
+    uint256 immutable cap;
+
+    constructor(address _owner) {
+        owner = _owner;
+    }
+}
+`,
+		"src/Vault.sol": `import "./Owned.sol";
+
+contract Vault is Owned {
+    uint256 immutable fee;
+
+    constructor(address _admin, uint256 _fee, uint256 _deadline) Owned(_admin) {
+        require(_deadline > block.timestamp);
+        fee = _fee * 2;
+    }
+}
+`,
+	}
+	for name, src := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"deploy-params", "Vault", "--root", root}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1 for the unassigned immutable, got %d: %s", code, stderr.String())
+	}
+	expected := "PARAMETER  TYPE     FLOWS INTO           VIA           LOCATION\n" +
+		"_admin     address  Owned.owner          Owned._owner  src/Owned.sol:2:23\n" +
+		"_fee       uint256  Vault.fee (derived)  -             src/Vault.sol:4:23\n" +
+		"_deadline  uint256  -                    -             src/Vault.sol:6:55\n" +
+		"warning: the constructor parameter `_deadline` is not stored (src/Vault.sol:6:55)\n" +
+		"error: the immutable `Owned.cap` is never assigned (src/Owned.sol:3:23)\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"deploy-params", "src/Vault.sol:Vault", "--format", "json", "--root", root}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	var report struct {
+		Contract   string
+		Parameters []struct {
+			Name  string
+			Flows []struct {
+				Variable  string
+				Immutable bool
+				Derived   bool
+				Via       []string
+			}
+		}
+		Unassigned []struct{ Variable string }
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("Expected JSON, got %s: %s", err, stdout.String())
+	}
+	if report.Contract != "Vault" || len(report.Parameters) != 3 || len(report.Unassigned) != 1 {
+		t.Fatalf("Expected the 3 parameters of Vault and 1 unassigned immutable, got %+v", report)
+	}
+	owner := report.Parameters[0].Flows
+	if len(owner) != 1 || owner[0].Variable != "Owned.owner" || !owner[0].Immutable || owner[0].Derived || len(owner[0].Via) != 1 || owner[0].Via[0] != "Owned._owner" {
+		t.Errorf("Expected `_admin` to set Owned.owner via Owned._owner, got %+v", owner)
+	}
+
+	stderr.Reset()
+	if code := run([]string{"deploy-params", "Missing", "--root", root}, nil, &stdout, &stderr); code != 1 || stderr.Len() == 0 {
+		t.Errorf("Expected an error for an unknown contract, got %d: %s", code, stderr.String())
+	}
+}
+
+func Test_AccessReport(t *testing.T) {
+	root := t.TempDir()
+	src, err := os.ReadFile("access/testdata/Treasury.sol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "Treasury.sol"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"access-report", root, "--root", root, "--format", "markdown"}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := `## Access control
+
+3 functions owner-only, 1 function role-gated, 2 functions pause-gated, 1 function other, 2 functions unguarded.
+
+### owner-only
+
+| Contract | Function | Guards | Location |
+| --- | --- | --- | --- |
+| Treasury | ` + "`setGuardian(address)` | `owner` via `onlyOwner`" + ` | Treasury.sol:36:14 |
+| Treasury | ` + "`pause()` | `guardian` via `onlyGuardian`" + ` | Treasury.sol:40:14 |
+| Treasury | ` + "`sweep(address)` | `owner` via `onlyOwner`" + ` | Treasury.sol:55:14 |
+
+### role-gated
+
+| Contract | Function | Guards | Location |
+| --- | --- | --- | --- |
+| Treasury | ` + "`harvest()` | `KEEPER_ROLE` via `onlyKeeper`" + ` | Treasury.sol:44:14 |
+
+### pause-gated
+
+| Contract | Function | Guards | Location |
+| --- | --- | --- | --- |
+| Treasury | ` + "`deposit(uint256)` | `not paused`" + ` | Treasury.sol:46:14 |
+| Treasury | ` + "`sweep(address)` | `not paused`" + ` | Treasury.sol:55:14 |
+
+### other
+
+| Contract | Function | Guards | Location |
+| --- | --- | --- | --- |
+| Treasury | ` + "`withdraw(uint256)` | `block.timestamp >= unlockTime`" + ` | Treasury.sol:51:14 |
+
+### unguarded
+
+| Contract | Function | Guards | Location |
+| --- | --- | --- | --- |
+| Treasury | ` + "`hasRole(bytes32,address)`" + ` | - | Treasury.sol:32:14 |
+| Treasury | ` + "`donate()`" + ` | - | Treasury.sol:59:14 |
+`
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	args = []string{"access-report", root, "--root", root, "--format", "json"}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var report struct {
+		Functions []struct {
+			Function string `json:"function"`
+		} `json:"functions"`
+		Totals map[string]int `json:"totals"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("Expected the JSON report, got %s", err)
+	}
+	if len(report.Functions) != 8 || report.Totals["unguarded"] != 2 {
+		t.Errorf("Expected 8 functions with 2 unguarded, got %d functions and totals %v", len(report.Functions), report.Totals)
+	}
+
+	if code := run([]string{"access-report", root, "--format", "html"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown format, got %d", code)
+	}
+}
+
+func Test_Clones(t *testing.T) {
+	root := t.TempDir()
+	body := `{
+        require(%[1]s > 0, "zero");
+        uint256 shares = %[1]s * totalShares / totalAssets;
+        if (shares == 0) {
+            revert ZeroShares();
+        }
+        balances[to] += shares;
+        totalShares += shares;
+        totalAssets += %[1]s;
+        token.transferFrom(msg.sender, address(this), %[1]s);
+        emit Deposit(msg.sender, to, %[1]s, shares);
+    }`
+	files := map[string]string{
+		"foundry.toml":          "[profile.default]\n",
+		"src/Vault.sol":         "contract Vault {\n    function deposit(uint256 amount, address to) external " + fmt.Sprintf(body, "amount") + "\n}\n",
+		"src/Router.sol":        "contract Router {\n    function mint(uint256 assets, address to) external " + fmt.Sprintf(body, "assets") + "\n}\n",
+		"src/Pool.sol":          "contract Pool {\n    function join(uint256 value, address to) external " + fmt.Sprintf(body, "value") + "\n}\n",
+		"lib/dep/src/Vault.sol": "contract Copy {\n    function deposit(uint256 amount, address to) external " + fmt.Sprintf(body, "amount") + "\n}\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"clones", root, "--root", root}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(stdout.String(), "\n")
+	if !regexp.MustCompile(`^Clone group [0-9a-f]{16}: 3 functions, 100% similar$`).MatchString(lines[0]) {
+		t.Errorf("Expected the header of the group, got %q", lines[0])
+	}
+	expected := "  Pool.join      src/Pool.sol:2:14    12 lines\n" +
+		"  Router.mint    src/Router.sol:2:14  12 lines\n" +
+		"  Vault.deposit  src/Vault.sol:2:14   12 lines\n\n1 clone group.\n"
+	if got := strings.Join(lines[1:], "\n"); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// The short bodies aren't compared.
+	stdout.Reset()
+	if code := run([]string{"clones", root, "--root", root, "--min-lines", "13", "--format", "json"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if got := stdout.String(); got != "{\n  \"groups\": []\n}\n" {
+		t.Errorf("Expected no groups, got %s", got)
+	}
+}
+
+func Test_Metrics(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"foundry.toml": "[profile.default]\n",
+		"src/Vault.sol": `pragma solidity ^0.8.0;
+
+contract Vault {
+    error Unauthorized();
+
+    /// @notice Withdraws the deposit.
+    function withdraw(uint256 amount) external {
+        if (amount == 0) revert Unauthorized();
+    }
+
+    function deposit() external {}
+}
+`,
+	}
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"src", "--limit", "1"}, "COMPLEXITY  NESTING  STATEMENTS  PARAMETERS  FUNCTION        LOCATION\n" +
+			"2           1        2           1           Vault.withdraw  src/Vault.sol:7:14\n"},
+		{[]string{"src", "--docs"}, "COVERAGE  DOCUMENTED  CONTRACT  LOCATION\n" +
+			"50%       1/2         Vault     src/Vault.sol:3:10\n"},
+		{[]string{"src", "--versions", "--format", "json"}, `[
+  {
+    "file": "src/Vault.sol",
+    "pragma": "^0.8.0",
+    "minimum": "0.8.4",
+    "reason": "custom error"
+  }
+]
+`},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"metrics", filepath.Join(root, test.args[0]), "--root", root}, test.args[1:]...)
+		if code := run(args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("Expected exit code 0 for %q, got %d: %s", test.args, code, stderr.String())
+		}
+		if got := stdout.String(); got != test.expected {
+			t.Errorf("Expected for %q:\n%s\ngot:\n%s", test.args, test.expected, got)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"metrics", filepath.Join(root, "missing")}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1 for a missing path, got %d", code)
+	}
+	if !strings.HasPrefix(stderr.String(), "Error reading path:") {
+		t.Errorf("Expected the error on stderr, got %q", stderr.String())
+	}
+}
+
+func Test_CompileInput(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"foundry.toml":  "[profile.default]\n",
+		"src/Auth.sol":  "pragma solidity ^0.8.20;\n\ncontract Auth {}\n",
+		"src/Vault.sol": "pragma solidity ^0.8.20;\n\nimport \"./Auth.sol\";\n\ncontract Vault is Auth {}\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"compile-input", filepath.Join(root, "src", "Vault.sol"), "--root", root}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var input struct {
+		Language string
+		Sources  map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &input); err != nil {
+		t.Fatalf("Expected the standard JSON input, got %s", err)
+	}
+	if input.Language != "Solidity" || len(input.Sources) != 2 {
+		t.Errorf("Expected the 2 sources, got %s", stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "Compiler hint: solc ") {
+		t.Errorf("Expected the compiler hint on stderr, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"compile-input", filepath.Join(root, "src", "Missing.sol"), "--root", root}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1 for a missing file, got %d", code)
+	}
+	if stdout.Len() != 0 || stderr.Len() == 0 {
+		t.Errorf("Expected the error on stderr only, got %q and %q", stdout.String(), stderr.String())
+	}
+}
+
+func Test_AuditDiff(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, src string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("foundry.toml", "[profile.default]\n")
+	write("src/Vault.sol", "contract Vault {\n    uint256 total;\n\n    function deposit(uint256 amount) external {\n        total += amount;\n    }\n\n    function sweep() external {}\n}\n")
+	write("src/Pool.sol", "contract Pool {}\n")
+	snapshot := filepath.Join(root, "audited.json")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"audit-diff", "--export", snapshot, filepath.Join(root, "src")}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if got := stdout.String(); got != fmt.Sprintf("Recorded 5 declarations in %s.\n", snapshot) {
+		t.Errorf("Expected the snapshot recorded, got %q", got)
+	}
+
+	write("src/Vault.sol", "/// @title Vault\ncontract Vault {\n    uint256 total;\n\n    function deposit(uint256 amount) external { total -= amount; }\n}\n")
+	write("src/Pool.sol", "contract Pool {\n    function sweep() external {}\n}\n")
+	stdout.Reset()
+	if code := run([]string{"audit-diff", "--baseline", snapshot, filepath.Join(root, "src")}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := "## Changes since the audit\n\n" +
+		"1 modified, 1 moved, 3 unchanged.\n\n" +
+		"| Status | Kind | Declaration | Audited as | Change |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| moved | function | `src/Pool.sol:Pool.sweep()` | `src/Vault.sol:Vault.sweep()` | - |\n" +
+		"| modified | function | `src/Vault.sol:Vault.deposit(uint256)` | - | +1 -1 tokens |\n"
+	if got := stdout.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"audit-diff", "--baseline", snapshot, filepath.Join(root, "src"), "--format", "json"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if got := stdout.String(); !strings.Contains(got, "\"totals\": {\n    \"modified\": 1,\n    \"moved\": 1,\n    \"unchanged\": 3\n  }") {
+		t.Errorf("Expected the totals in the JSON report, got:\n%s", got)
+	}
+}
+
+func Test_Tests(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"foundry.toml":               "[profile.default]\n",
+		"lib/forge-std/src/Test.sol": "pragma solidity ^0.8.0;\n\nabstract contract Test {}\n",
+		"test/Vault.t.sol": `pragma solidity ^0.8.0;
+
+import {Test} from "../lib/forge-std/src/Test.sol";
+
+contract VaultTest is Test {
+    function setUp() public {}
+
+    function test_withdraw() public {
+        vm.expectRevert();
+    }
+
+    function testFuzz_deposit(uint256 amount) public {}
+}
+`,
+	}
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"tests", filepath.Join(root, "test")}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := `CONTRACT   TEST              KIND  REVERTS  LOCATION
+VaultTest  test_withdraw     unit  yes      test/Vault.t.sol:8:14
+VaultTest  testFuzz_deposit  fuzz  -        test/Vault.t.sol:12:14
+2 tests in 1 contract
+`
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"tests", root, "--format", "json"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var report struct {
+		Contracts []struct {
+			Contract string `json:"contract"`
+			SetUp    string `json:"setUp"`
+			Tests    []struct {
+				Name       string `json:"name"`
+				FuzzParams []struct {
+					Name string `json:"name"`
+					Type string `json:"type"`
+				} `json:"fuzzParams"`
+				Command []string `json:"command"`
+			} `json:"tests"`
+		} `json:"contracts"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("Expected the JSON report, got %s", err)
+	}
+	if len(report.Contracts) != 1 || len(report.Contracts[0].Tests) != 2 || report.Contracts[0].SetUp != "test/Vault.t.sol:6:14" {
+		t.Fatalf("Expected VaultTest with its setUp and 2 tests, got %+v", report.Contracts)
+	}
+	fuzz := report.Contracts[0].Tests[1]
+	if len(fuzz.FuzzParams) != 1 || fuzz.FuzzParams[0].Name != "amount" || fuzz.FuzzParams[0].Type != "uint256" {
+		t.Errorf("Expected the fuzz parameter uint256 amount, got %+v", fuzz.FuzzParams)
+	}
+	if command := strings.Join(fuzz.Command, " "); command != "forge test --match-contract ^VaultTest$ --match-test ^testFuzz_deposit$" {
+		t.Errorf("Expected the forge command of the test, got %s", command)
+	}
+
+	if code := run([]string{"tests", root, "--format", "junit"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown format, got %d", code)
+	}
+}
+
+func Test_Inventory(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"foundry.toml": "[profile.default]\n",
+		"src/Vault.sol": `// SPDX-License-Identifier: MIT
+/// @title Vault
+/// @author Alice
+pragma solidity ^0.8.20;
+
+import {Auth} from "./Auth.sol";
+
+contract Vault is Auth {
+    uint256 total; // the deposits
+
+    function deposit() external {}
+}
+`,
+		"src/Auth.sol": `// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+contract Auth {}
+`,
+		"src/tokens/Token.sol": `// SPDX-License-Identifier: BUSL-1.1
+pragma solidity ^0.8.0;
+
+/**
+ * @title Token
+ */
+contract Token {}
+`,
+		"lib/forge-std/src/Test.sol": "pragma solidity >=0.6.2;\n\ncontract Test {}\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"inventory", root}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := `## Scope
+
+3 files, 10 SLOC, 8 comment lines.
+
+Licenses: MIT (2), BUSL-1.1 (1).
+
+### src
+
+| File | Title | License | Pragma | SLOC | Comment lines | SHA-256 |
+| --- | --- | --- | --- | ---: | ---: | --- |
+| src/Auth.sol | - | MIT | ^0.8.20 | 2 | 1 | ` + "`HASH_AUTH`" + ` |
+| src/Vault.sol | Vault | MIT | ^0.8.20 | 6 | 3 | ` + "`HASH_VAULT`" + ` |
+| **Total** | | | | 8 | 4 | |
+
+### src/tokens
+
+| File | Title | License | Pragma | SLOC | Comment lines | SHA-256 |
+| --- | --- | --- | --- | ---: | ---: | --- |
+| src/tokens/Token.sol | - | BUSL-1.1 | ^0.8.0 | 2 | 4 | ` + "`HASH_TOKEN`" + ` |
+| **Total** | | | | 2 | 4 | |
+`
+	for name, placeholder := range map[string]string{"src/Auth.sol": "HASH_AUTH", "src/Vault.sol": "HASH_VAULT", "src/tokens/Token.sol": "HASH_TOKEN"} {
+		hash := sha256.Sum256([]byte(files[name]))
+		expected = strings.Replace(expected, placeholder, hex.EncodeToString(hash[:]), 1)
+	}
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"inventory", root, "--format", "csv", "--include-deps"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[4], "lib/forge-std/src,lib/forge-std/src/Test.sol,true,,,,,>=0.6.2,3,2,0,") {
+		t.Errorf("Expected the header, 3 files and the dependency, got:\n%s", stdout.String())
+	}
+
+	if code := run([]string{"inventory", root, "--format", "html"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown format, got %d", code)
+	}
+}
+
+func Test_FixOrganizeImports(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"A.sol":     "contract A {}\n",
+		"B.sol":     "contract B {}\n",
+		"Vault.sol": "import \"./B.sol\";\nimport {A} from \"./A.sol\";\nimport {A} from \"./A.sol\";\n\ncontract Vault is A {}\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	path := filepath.Join(root, "Vault.sol")
+	if code := run([]string{"fix", path, "--organize-imports", "--root", root}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "import {A} from \"./A.sol\";\n\ncontract Vault is A {}\n"
+	if string(src) != expected {
+		t.Errorf("Expected %q, got %q", expected, src)
+	}
+
+	if code := run([]string{"fix", "--organize-imports"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 without a path, got %d", code)
+	}
+}
+
+func Test_Fix(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"solbot.toml": "[migration]\ntarget = \"^0.8.0\"\n",
+		"A.sol":       "pragma solidity ^0.7.0;\n\ncontract A {}\n",
+		"B.sol":       "pragma solidity ^0.7.0;\n\ncontract B {}\n",
+		"Vault.sol": `pragma solidity ^0.7.0;
+
+import {B} from "./B.sol";
+import {A} from "./A.sol";
+
+contract Vault is A, B {
+    struct Position {
+        uint256 amount;
+    }
+
+    Position[] positions;
+
+    function stamp() external view returns (uint256) {
+        return now;
+    }
+
+    function first(bytes memory data) external pure returns (byte) {
+        return data[0];
+    }
+
+    function limit() external pure returns (uint256) {
+        return uint256(-1);
+    }
+
+    function claim(uint256 i) external {
+        Position memory p = positions[i];
+        p.amount = 0;
+    }
+}
+`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(root, "Vault.sol")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"fix", root, "--dry-run"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if src, _ := os.ReadFile(path); string(src) != files["Vault.sol"] {
+		t.Errorf("Expected the dry run to keep the file, got %q", src)
+	}
+	for _, line := range []string{"--- a/Vault.sol\n+++ b/Vault.sol\n", "-        return now;\n+        return block.timestamp;\n"} {
+		if !strings.Contains(stdout.String(), line) {
+			t.Errorf("Expected %q in the diff, got %q", line, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"fix", root}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	expected := "Vault.sol:3:1: fixed organize-imports: Organize imports\n" +
+		"Vault.sol:14:16: fixed now: Replace `now` with `block.timestamp`\n" +
+		"Vault.sol:17:62: fixed byte: Replace `byte` with `bytes1`\n" +
+		"Vault.sol:22:16: fixed explicit-conversion: Replace with `type(uint256).max`\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The lost memory write is only suggested.
+	for _, fixed := range []string{"import {A} from \"./A.sol\";\nimport {B} from \"./B.sol\";\n", "return block.timestamp;", "returns (bytes1)", "return type(uint256).max;", "Position memory p"} {
+		if !strings.Contains(string(src), fixed) {
+			t.Errorf("Expected %q in the fixed file, got %q", fixed, src)
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"fix", path, "--only", "lost-memory-write"}, nil, &stdout, &stderr); code != 0 || stdout.Len() != 0 {
+		t.Errorf("Expected no fixes without --unsafe, got %d: %q", code, stdout.String())
+	}
+	if code := run([]string{"fix", path, "--only", "lost-memory-write", "--unsafe"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if src, _ := os.ReadFile(path); !strings.Contains(string(src), "Position storage p") {
+		t.Errorf("Expected the storage pointer, got %q", src)
+	}
+}
+
+func Test_Baseline(t *testing.T) {
+	root := t.TempDir()
+	// The report of analyze is written to the working directory.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    uint256 constant FEE = 1;
+    uint256 total;
+
+    function deposit(uint256[] memory amounts) external payable {
+        require(FEE > 0);
+        for (uint256 i = 0; i < amounts.length; i++) {
+            total += msg.value;
+        }
+    }
+}
+`
+	path := filepath.Join(root, "Vault.sol")
+	file := filepath.Join(root, ".solbot-baseline.json")
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, "foundry.toml"), "")
+	write(path, src)
+	analyze := func() (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"analyze", path, "--baseline", file, "--format", "plain"}, nil, &stdout, &stderr)
+		return code, stdout.String() + stderr.String()
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"baseline", "create", path, "--output", file}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if expected := "Recorded 2 findings of 1 files in " + file + "\n"; stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+
+	// The findings survive the lines moving.
+	write(path, strings.Replace(src, "contract Vault {", "/// @title Vault\ncontract Vault {\n", 1))
+	code, out := analyze()
+	if code != 0 || strings.Contains(out, "msg-value-loop]") || !strings.Contains(out, "Baseline: 2 findings suppressed, 0 entries expired, 0 entries stale") {
+		t.Errorf("Expected the findings to be suppressed, got %d: %s", code, out)
+	}
+
+	// The team sets the owner and the expiry date of an entry.
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := baseline.Parse(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range b.Entries {
+		if b.Entries[i].Code == "msg-value-loop" {
+			b.Entries[i].Owner, b.Entries[i].Expires = "@alice", "2020-01-01"
+		}
+	}
+	content, _ = b.Marshal()
+	write(file, string(content))
+	code, out = analyze()
+	for _, expected := range []string{"msg-value-loop]", "Expired baseline entries", "Vault.sol:10: msg-value-loop: `msg.value` used in a loop (owner @alice), expired after 2020-01-01"} {
+		if code != 1 || !strings.Contains(out, expected) {
+			t.Errorf("Expected exit code 1 and %q, got %d: %s", expected, code, out)
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"baseline", "stats", "--baseline", file}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	lines := []string{}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	expected := "2 entries||DETECTOR ENTRIES EXPIRED|always-true-condition 1 0|msg-value-loop 1 1||OWNER ENTRIES EXPIRED|(none) 1 0|@alice 1 1|"
+	if strings.Join(lines, "|") != expected {
+		t.Errorf("Expected %q, got %q", expected, strings.Join(lines, "|"))
+	}
+
+	// The condition was removed, so its entry is stale until pruned.
+	write(path, strings.Replace(src, "        require(FEE > 0);\n", "", 1))
+	code, out = analyze()
+	if code != 1 || !strings.Contains(out, "Stale baseline entries") || !strings.Contains(out, "Vault.sol:8: always-true-condition") {
+		t.Errorf("Expected the stale entry, got %d: %s", code, out)
+	}
+	stdout.Reset()
+	if code := run([]string{"baseline", "prune", "--baseline", file}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "Vault.sol:8: removed always-true-condition: The condition is always true") {
+		t.Errorf("Expected the removed entry, got %q", stdout.String())
+	}
+	content, _ = os.ReadFile(file)
+	if b, err := baseline.Parse(content); err != nil || len(b.Entries) != 1 || b.Entries[0].Owner != "@alice" {
+		t.Errorf("Expected the entry of msg-value-loop left, got %s %v", content, err)
+	}
+
+	write(file, `{"version": 2, "entries": []}`)
+	if code, out := analyze(); code != 1 || !strings.Contains(out, "unsupported baseline version 2") {
+		t.Errorf("Expected the version to be checked, got %d: %s", code, out)
+	}
+}
+
+// Test_CommandsDeterminism runs the commands over the fixtures of the
+// analysis with a single thread, then again in parallel tests with more
+// threads, and expects the same output every time.
+func Test_CommandsDeterminism(t *testing.T) {
+	root := filepath.Join("lsp", "analysis", "testdata", "determinism")
+	paths, _ := filepath.Glob(filepath.Join(root, "*.sol"))
+	commands := [][]string{
+		{"proxy-check", "--proxy", "VaultProxy", "--impl", "Vault", "--root", root, "--format", "plain"},
+		{"proxy-check", "--proxy", "VaultProxy", "--impl", "Vault", "--root", root, "--format", "pretty"},
+	}
+	for _, path := range paths {
+		commands = append(commands, []string{"parse", path, "--format", "plain"}, []string{"parse", path, "--format", "pretty"})
+	}
+	output := func() string {
+		var b strings.Builder
+		for _, args := range commands {
+			var stdout, stderr bytes.Buffer
+			code := run(args, nil, &stdout, &stderr)
+			fmt.Fprintf(&b, "%s: %d\n%s%s", strings.Join(args, " "), code, stdout.String(), stderr.String())
+		}
+		return b.String()
+	}
+
+	procs := runtime.GOMAXPROCS(1)
+	expected := output()
+	if !strings.Contains(expected, "\nVault.sol:27:13: error: Slot 0 of the proxy `VaultProxy`") {
+		t.Fatalf("Expected the storage collision relative to the root, got:\n%s", expected)
+	}
+	runtime.GOMAXPROCS(max(4, 2*procs))
+	defer runtime.GOMAXPROCS(procs)
+
+	t.Run("parallel", func(t *testing.T) {
+		for i := 0; i < 8; i++ {
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				if got := output(); got != expected {
+					t.Errorf("Expected the same output, got:\n%s\nthen:\n%s", expected, got)
+				}
+			})
+		}
+	})
+}
+
+// Test_Replay replays the checked-in recording of a session opening,
+// editing and hovering a document, and expects the same messages.
+func Test_Replay(t *testing.T) {
+	recording := filepath.Join("lsp", "replay", "testdata", "session.jsonl")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"replay", recording}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+	if expected := "Replayed 6 messages, 0 divergences\n"; stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+
+	// The response recorded before a regression.
+	src, err := os.ReadFile(recording)
+	if err != nil {
+		t.Fatalf("Cannot read the recording: %s", err)
+	}
+	changed := filepath.Join(t.TempDir(), "session.jsonl")
+	os.WriteFile(changed, bytes.Replace(src, []byte(`function deposit(uint256 amount) external\\n`), []byte(`function deposit(uint256) external\\n`), 1), 0644)
+	stdout.Reset()
+	if code := run([]string{"replay", changed}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+	expected := "response 3 (textDocument/hover): result.contents.value: expected \"```solidity\\nfunction deposit(uint256) external\\n```\", " +
+		"got \"```solidity\\nfunction deposit(uint256 amount) external\\n```\"\nReplayed 6 messages, 1 divergence\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+	stdout.Reset()
+	if code := run([]string{"replay", "--ignore", "textDocument/hover:result.contents.value", changed}, nil, &stdout, &stderr); code != 0 {
+		t.Errorf("Expected the ignored field not to diverge, got %d:\n%s", code, stdout.String())
+	}
+}
+
+func Test_NewDetector(t *testing.T) {
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"new-detector", "--dir", dir, "tx-origin"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	for _, name := range []string{"detector.go", "detector_test.go"} {
+		path := filepath.Join(dir, "txorigin", name)
+		if _, err := goparser.ParseFile(gotoken.NewFileSet(), path, nil, 0); err != nil {
+			t.Errorf("Expected %s to be valid Go, got %s", path, err)
+		}
+	}
+	fixture := filepath.Join(dir, "txorigin", "testdata", "txorigin.sol")
+	src, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("Expected the fixture, got %s", err)
+	}
+	p := parser.Parser{}
+	p.Init(token.NewFile(fixture, string(src)))
+	p.ParseFile()
+	if len(p.Errors()) != 0 {
+		t.Errorf("Expected the fixture to parse, got %v", p.Errors())
+	}
+
+	tests := []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{[]string{"--dir", dir, "tx-origin"}, 1, "detector.go already exists"},
+		{[]string{"--dir", dir, "msg-value-loop"}, 1, "taken by a built-in detector"},
+		{[]string{"--dir", dir, "TxOrigin"}, 2, "Invalid code `TxOrigin`"},
+		{[]string{}, 2, "Usage:"},
+	}
+	for _, tt := range tests {
+		stderr.Reset()
+		if code := run(append([]string{"new-detector"}, tt.args...), nil, &stdout, &stderr); code != tt.code {
+			t.Errorf("Expected exit code %d for %q, got %d", tt.code, tt.args, code)
+		}
+		if !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("Expected %q for %q, got %q", tt.stderr, tt.args, stderr.String())
+		}
+	}
+}
+
+func Test_Difftest(t *testing.T) {
+	fixtures := filepath.Join("difftest", "testdata")
+	var stdout, stderr bytes.Buffer
+	// The outputs of solc are generated by the real binary, see
+	// difftest.Test_CheckFixtures, and may be missing.
+	if _, err := os.Stat(filepath.Join(fixtures, "solc", "VERSION")); err == nil {
+		if code := run([]string{"difftest", fixtures, "--solc-json", filepath.Join(fixtures, "solc")}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("Expected exit code 0, got %d:\n%s%s", code, stdout.String(), stderr.String())
+		}
+		if !strings.HasPrefix(stdout.String(), "100.0% of nodes match across 3 files") {
+			t.Errorf("Expected all the nodes to match, got %q", stdout.String())
+		}
+		stdout.Reset()
+	}
+
+	counter := filepath.Join(fixtures, "Counter.sol")
+	if code := run([]string{"difftest", counter, "--solc-json", filepath.Join(fixtures, "mangled"), "--format", "json"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+	var report struct {
+		Summary struct {
+			Divergences int `json:"divergences"`
+		} `json:"summary"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("Expected the report in JSON, got %s", err)
+	}
+	if report.Summary.Divergences != 1 {
+		t.Errorf("Expected 1 divergence, got %d", report.Summary.Divergences)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"difftest", counter, "--emit"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), `{"license":"MIT","nodeType":"SourceUnit"`) {
+		t.Errorf("Expected the tree of the file, got %q", stdout.String())
+	}
+
+	if code := run([]string{"difftest", counter}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 without solc's trees, got %d", code)
+	}
+}
+
+func Test_DocsCheck(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"foundry.toml":  "[profile.default]\n",
+		"src/Vault.sol": "pragma solidity ^0.8.0;\n\ncontract Vault {}\n",
+		"docs/guide.md": "# Guide\n\n```solidity\nvault.deposit{value: 1 ether}();\n```\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"docs-check", filepath.Join(root, "docs")}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "No problems in the code blocks of 1 file\n" {
+		t.Errorf("Expected no problems, got %q", stdout.String())
+	}
+
+	broken := "# Guide\n\n```solidity\nvault.deposit{value: 1 ether}(;\n```\n"
+	if err := os.WriteFile(filepath.Join(root, "docs/guide.md"), []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := run([]string{"docs-check", root, "--format", "plain"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "docs/guide.md:4:") || !strings.Contains(stderr.String(), "[syntax-error]") {
+		t.Errorf("Expected the syntax error at line 4 of the guide, got %q", stderr.String())
+	}
+}
+
+func Test_ExtractRepro(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"foundry.toml":  "[profile.default]\n",
+		"src/Types.sol": "pragma solidity ^0.8.0;\n\nstruct Position {\n    uint256 amount;\n}\n\nstruct Reward {\n    uint256 rate;\n}\n",
+		"src/Staking.sol": `pragma solidity ^0.8.0;
+
+import {Position} from "./Types.sol";
+
+contract Staking {
+    Position[] positions;
+
+    function claim(uint256 i) external {
+        Position memory p = positions[i];
+        p.amount = 0;
+    }
+}
+`,
+	}
+	for name, src := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	output := filepath.Join(root, "repro")
+	args := []string{"extract-repro", "--diagnostic", filepath.Join(root, "src/Staking.sol") + ":9:lost-memory-write", "--output", output}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "Wrote 2 files to "+output+", lost-memory-write at Staking.sol:9:9\n") {
+		t.Errorf("Expected the summary of the files, got %q", stdout.String())
+	}
+	types, err := os.ReadFile(filepath.Join(output, "Types.sol"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(types) != "pragma solidity ^0.8.0;\n\nstruct Position {\n    uint256 amount;\n}\n" {
+		t.Errorf("Expected the struct only, got:\n%s", types)
+	}
+	manifest, err := os.ReadFile(filepath.Join(output, "MANIFEST.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"# Reproduction of lost-memory-write\n", "- Types.sol, from src/Types.sol\n", "- the struct `Reward` of src/Types.sol\n"} {
+		if !strings.Contains(string(manifest), s) {
+			t.Errorf("Expected the manifest to contain %q, got:\n%s", s, manifest)
+		}
+	}
+
+	if code := run([]string{"extract-repro", "--diagnostic", filepath.Join(root, "src/Staking.sol") + ":3:lost-memory-write", "--output", output}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1 without the diagnostic at the line, got %d", code)
+	}
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"solbot/token"
+	"strings"
+)
+
+// Lines counts the lines of a source file by what's on them.
+type Lines struct {
+	Total   int // all of the lines
+	Code    int // lines with a token other than a comment, the SLOC
+	Comment int // lines with only comments
+	Blank   int // lines with only whitespace
+}
+
+// CountLines counts the lines of the file from its tokens, the comments
+// included. A token spanning several lines, e.g. a block comment or a
+// string literal, counts on each of them.
+func CountLines(file *token.File, tokens []token.Token) Lines {
+	src := file.Src()
+	total := strings.Count(src, "\n") + 1
+	if strings.HasSuffix(src, "\n") || src == "" {
+		total--
+	}
+	code := make([]bool, total+1)
+	comment := make([]bool, total+1)
+	for _, tkn := range tokens {
+		first := file.Position(tkn.Pos).Line
+		last := file.Position(tkn.Pos + token.Pos(max(len(tkn.Literal), 1)) - 1).Line
+		for line := first; line <= last && line <= total; line++ {
+			if tkn.Type == token.COMMENT_LITERAL {
+				comment[line] = true
+			} else {
+				code[line] = true
+			}
+		}
+	}
+	res := Lines{Total: total}
+	for line := 1; line <= total; line++ {
+		switch {
+		case code[line]:
+			res.Code++
+		case comment[line]:
+			res.Comment++
+		default:
+			res.Blank++
+		}
+	}
+	return res
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"solbot/lexer"
+	"solbot/token"
+	"testing"
+)
+
+func Test_CountLines(t *testing.T) {
+	src := `// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+/**
+ * @title Vault
+ */
+contract Vault {
+    uint256 total; // the deposits
+
+    uint256 constant LIMIT =
+        1e18;
+
+    function deposit() external {
+        /* nothing */
+    }
+}
+`
+	handle := token.NewFile("Vault.sol", src)
+	l := lexer.Lex(handle)
+	tokens := []token.Token{}
+	for tkn := l.NextToken(); tkn.Type != token.EOF; tkn = l.NextToken() {
+		tokens = append(tokens, tkn)
+	}
+	// Code: 2, 7, 8, 10, 11, 13, 15, 16. Comments: 1, 4-6, 14. Blank: 3, 9, 12.
+	expected := Lines{Total: 16, Code: 8, Comment: 5, Blank: 3}
+	if got := CountLines(handle, tokens); got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
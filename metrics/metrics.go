@@ -0,0 +1,254 @@
+// metrics measures the size and the complexity of functions: the cyclomatic
+// complexity, the number of statements and parameters and the nesting depth.
+// The measurements are purely syntactic, the only thing that needs to be
+// resolved are the modifiers, whose decision points become a part of every
+// function that invokes them.
+package metrics
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/project"
+	"solbot/token"
+)
+
+type Function struct {
+	Decl       *ast.FunctionDeclaration
+	Contract   *ast.ContractDeclaration // enclosing contract; or nil for free functions
+	Complexity int                      // 1 + the decision points of the body and of the invoked modifiers
+	Statements int                      // statements in the body, not counting the blocks themselves
+	Parameters int                      // input parameters
+	Nesting    int                      // depth of the nested control flow statements
+}
+
+// Name returns the qualified name of the function e.g. "Vault.withdraw" or
+// "Vault.constructor".
+func (f Function) Name() string {
+	name := f.Decl.Kind.String()
+	if f.Decl.Name != nil {
+		name = f.Decl.Name.Name
+	}
+	if f.Contract != nil {
+		return f.Contract.Name.Name + "." + name
+	}
+	return name
+}
+
+// NameRange returns the range of the function name, or of the keyword e.g.
+// "constructor" if the function has no name.
+func (f Function) NameRange() token.Range {
+	if f.Decl.Name != nil {
+		return ast.NodeRange(f.Decl.Name)
+	}
+	start := f.Decl.Type.Func
+	return token.Range{Start: start, End: start + token.Pos(len(f.Decl.Kind.String()))}
+}
+
+// ModifierResolver returns the declaration of the modifier with the given
+// name, as seen from the contract; or nil if there is none e.g. the name is
+// a call of the base constructor.
+type ModifierResolver func(contract *ast.ContractDeclaration, name string) *ast.ModifierDeclaration
+
+// File measures all of the implemented functions of the file. If the
+// resolver is nil, only the modifiers declared in the same file are found.
+func File(file *ast.File, resolve ModifierResolver) []Function {
+	if resolve == nil {
+		resolve = LocalModifiers(file)
+	}
+	res := []Function{}
+	for _, decl := range file.Declarations {
+		switch decl := decl.(type) {
+		case *ast.FunctionDeclaration:
+			if decl.Body != nil {
+				res = append(res, Measure(decl, nil, resolve))
+			}
+		case *ast.ContractDeclaration:
+			for _, member := range decl.Body {
+				if fn, ok := member.(*ast.FunctionDeclaration); ok && fn.Body != nil {
+					res = append(res, Measure(fn, decl, resolve))
+				}
+			}
+		}
+	}
+	return res
+}
+
+// Measure returns the metrics of the function declared in the contract.
+func Measure(fn *ast.FunctionDeclaration, contract *ast.ContractDeclaration, resolve ModifierResolver) Function {
+	f := Function{Decl: fn, Contract: contract, Complexity: 1}
+	if fn.Type.Params != nil {
+		f.Parameters = len(fn.Type.Params.List)
+	}
+	if fn.Body == nil {
+		return f
+	}
+
+	f.Complexity += decisionPoints(fn.Body)
+	if contract != nil && resolve != nil {
+		for _, inv := range fn.Modifiers {
+			if mod := resolve(contract, ast.ModifierName(inv)); mod != nil && mod.Body != nil {
+				f.Complexity += decisionPoints(mod.Body)
+			}
+		}
+	}
+	f.Statements = countStatements(fn.Body)
+	f.Nesting = nesting(fn.Body, 0)
+	return f
+}
+
+// LocalModifiers finds the modifiers declared in the contract and in its
+// bases that are declared in the same file.
+func LocalModifiers(file *ast.File) ModifierResolver {
+	contracts := map[string]*ast.ContractDeclaration{}
+	for _, decl := range file.Declarations {
+		if c, ok := decl.(*ast.ContractDeclaration); ok {
+			contracts[c.Name.Name] = c
+		}
+	}
+
+	var find func(c *ast.ContractDeclaration, name string, visited map[*ast.ContractDeclaration]bool) *ast.ModifierDeclaration
+	find = func(c *ast.ContractDeclaration, name string, visited map[*ast.ContractDeclaration]bool) *ast.ModifierDeclaration {
+		if visited[c] {
+			return nil
+		}
+		visited[c] = true
+		for _, member := range c.Body {
+			if mod, ok := member.(*ast.ModifierDeclaration); ok && mod.Name.Name == name {
+				return mod
+			}
+		}
+		// The most derived base comes last in the inheritance list.
+		for i := len(c.Bases) - 1; i >= 0; i-- {
+			base, ok := c.Bases[i].Name.(*ast.Identifier)
+			if !ok || contracts[base.Name] == nil {
+				continue
+			}
+			if mod := find(contracts[base.Name], name, visited); mod != nil {
+				return mod
+			}
+		}
+		return nil
+	}
+
+	return func(c *ast.ContractDeclaration, name string) *ast.ModifierDeclaration {
+		return find(c, name, map[*ast.ContractDeclaration]bool{})
+	}
+}
+
+// decisionPoints counts the branches of the control flow: the conditions of
+// the if statements, loops and conditional expressions, the short-circuiting
+// operators and the catch clauses.
+func decisionPoints(body *ast.BlockStatement) int {
+	count := 0
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.IfStatement, *ast.ForStatement, *ast.WhileStatement,
+			*ast.DoWhileStatement, *ast.ConditionalExpression, *ast.CatchClause:
+			count++
+		case *ast.BinaryExpression:
+			if node.Operator == token.AND || node.Operator == token.OR {
+				count++
+			}
+		}
+		return true
+	})
+	return count
+}
+
+// countStatements counts the statements of the body. The blocks and the
+// initialization statements of the for loops are not counted, they are a
+// part of the enclosing statement.
+func countStatements(body *ast.BlockStatement) int {
+	count := 0
+	loopInit := map[ast.Node]bool{}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.BlockStatement:
+		case ast.Statement:
+			if loop, ok := node.(*ast.ForStatement); ok && loop.Init != nil {
+				loopInit[loop.Init] = true
+			}
+			if !loopInit[node] {
+				count++
+			}
+		}
+		return true
+	})
+	return count
+}
+
+// nesting returns the maximum depth of the control flow statements nested
+// in the statement. The `else if` chains don't nest, they are one level.
+func nesting(stmt ast.Statement, depth int) int {
+	deepest := depth
+	visit := func(stmt ast.Statement, depth int) {
+		if stmt == nil {
+			return
+		}
+		if d := nesting(stmt, depth); d > deepest {
+			deepest = d
+		}
+	}
+
+	switch stmt := stmt.(type) {
+	case *ast.BlockStatement:
+		for _, s := range stmt.Statements {
+			visit(s, depth)
+		}
+	case *ast.UncheckedBlockStatement:
+		visit(stmt.Body, depth)
+	case *ast.IfStatement:
+		visit(stmt.Consequence, depth+1)
+		if elseIf, ok := stmt.Alternative.(*ast.IfStatement); ok {
+			visit(elseIf, depth)
+		} else {
+			visit(stmt.Alternative, depth+1)
+		}
+	case *ast.ForStatement:
+		visit(stmt.Body, depth+1)
+	case *ast.WhileStatement:
+		visit(stmt.Body, depth+1)
+	case *ast.DoWhileStatement:
+		visit(stmt.Body, depth+1)
+	case *ast.TryStatement:
+		visit(stmt.Body, depth+1)
+		for _, c := range stmt.Catches {
+			visit(c.Body, depth+1)
+		}
+	}
+	return deepest
+}
+
+// Violation is a metric of a function above its threshold.
+type Violation struct {
+	Metric    string // e.g. "complexity"
+	Value     int    // measured value
+	Threshold int    // configured threshold
+}
+
+func (v Violation) Message(f Function) string {
+	descriptions := map[string]string{
+		"complexity": "Cyclomatic complexity",
+		"statements": "Number of statements",
+		"parameters": "Number of parameters",
+		"nesting":    "Nesting depth",
+	}
+	return fmt.Sprintf("%s of `%s` is %d, above the threshold of %d",
+		descriptions[v.Metric], f.Name(), v.Value, v.Threshold)
+}
+
+// Check returns the metrics of the function that exceed the enabled
+// thresholds.
+func (f Function) Check(thresholds project.Metrics) []Violation {
+	res := []Violation{}
+	check := func(metric string, value, threshold int) {
+		if threshold > 0 && value > threshold {
+			res = append(res, Violation{Metric: metric, Value: value, Threshold: threshold})
+		}
+	}
+	check("complexity", f.Complexity, thresholds.Complexity)
+	check("statements", f.Statements, thresholds.Statements)
+	check("parameters", f.Parameters, thresholds.Parameters)
+	check("nesting", f.Nesting, thresholds.Nesting)
+	return res
+}
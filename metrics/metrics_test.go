@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"solbot/ast"
+	"solbot/parser"
+	"solbot/project"
+	"solbot/token"
+	"testing"
+)
+
+func Test_MeasureNestedIfsAndLoop(t *testing.T) {
+	src := `contract Vault {
+        function distribute(address[] memory users, uint256 amount, bool force) public {
+            if (amount == 0) {
+                return;
+            }
+            for (uint256 i = 0; i < users.length; i++) {
+                if (users[i] != address(0) && !force) {
+                    if (balances[users[i]] > amount) {
+                        balances[users[i]] -= amount;
+                    } else if (force) {
+                        balances[users[i]] = 0;
+                    }
+                }
+            }
+        }
+
+        mapping(address => uint256) balances;
+    }
+    `
+
+	fns := File(parse(t, src), nil)
+	if len(fns) != 1 {
+		t.Fatalf("Expected 1 function, got %d", len(fns))
+	}
+	f := fns[0]
+
+	// 1 + if + for + if + && + if + else if
+	if f.Complexity != 7 {
+		t.Errorf("Expected complexity 7, got %d", f.Complexity)
+	}
+	// if, return, for, if, if, assignment, if, assignment; the loop header is not counted
+	if f.Statements != 8 {
+		t.Errorf("Expected 8 statements, got %d", f.Statements)
+	}
+	if f.Parameters != 3 {
+		t.Errorf("Expected 3 parameters, got %d", f.Parameters)
+	}
+	// for > if > if, the else if is on the same level
+	if f.Nesting != 3 {
+		t.Errorf("Expected nesting depth 3, got %d", f.Nesting)
+	}
+	if f.Name() != "Vault.distribute" {
+		t.Errorf("Expected name Vault.distribute, got %s", f.Name())
+	}
+}
+
+func Test_MeasureIncludesModifiers(t *testing.T) {
+	src := `contract Ownable {
+        address owner;
+
+        modifier onlyOwner() {
+            require(msg.sender == owner || msg.sender == address(this));
+            _;
+        }
+    }
+
+    contract Vault is Ownable {
+        bool paused;
+
+        modifier whenNotPaused() {
+            if (paused) {
+                revert();
+            }
+            _;
+        }
+
+        constructor() Ownable() {}
+
+        function withdraw(uint256 amount) public onlyOwner whenNotPaused {
+            payable(msg.sender).transfer(amount > 0 ? amount : 1);
+        }
+    }
+    `
+
+	expected := map[string]int{
+		"Vault.constructor": 1, // the base constructor is not a modifier
+		"Vault.withdraw":    4, // 1 + ?: + || in onlyOwner + if in whenNotPaused
+	}
+
+	fns := File(parse(t, src), nil)
+	if len(fns) != len(expected) {
+		t.Fatalf("Expected %d functions, got %d", len(expected), len(fns))
+	}
+	for _, f := range fns {
+		if f.Complexity != expected[f.Name()] {
+			t.Errorf("Expected complexity %d of %s, got %d", expected[f.Name()], f.Name(), f.Complexity)
+		}
+	}
+}
+
+func Test_Check(t *testing.T) {
+	f := Function{Complexity: 12, Statements: 10, Parameters: 2, Nesting: 4}
+
+	if violations := f.Check(project.Metrics{}); len(violations) != 0 {
+		t.Errorf("Expected no violations with the default thresholds, got %d", len(violations))
+	}
+
+	violations := f.Check(project.Metrics{Complexity: 10, Nesting: 4})
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %d", len(violations))
+	}
+	if v := violations[0]; v.Metric != "complexity" || v.Value != 12 || v.Threshold != 10 {
+		t.Errorf("Expected complexity 12 above 10, got %s %d above %d", v.Metric, v.Value, v.Threshold)
+	}
+}
+
+func parse(t *testing.T, src string) *ast.File {
+	t.Helper()
+	p := parser.Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	file := p.ParseFile()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Expected no parser errors, got %v", p.Errors())
+	}
+	return file
+}
@@ -0,0 +1,419 @@
+package metrics
+
+import (
+	"fmt"
+	"slices"
+	"solbot/ast"
+	"solbot/token"
+)
+
+// EIP170Limit is the maximum size in bytes of the runtime bytecode of a
+// deployed contract.
+const EIP170Limit = 24576
+
+// weights are the rough sizes in bytes of the code generated for the
+// features of a contract. They are meant to get the estimate into the right
+// order of magnitude, not to predict the compiler output.
+var weights = map[string]int{
+	"contract":          120, // dispatcher, free memory pointer and metadata
+	"function":          45,  // selector comparison and jump of an external function
+	"parameter":         20,  // ABI decoding or encoding of a static value
+	"dynamic parameter": 110, // ABI decoding or encoding of a string, bytes or array
+	"getter":            50,  // public state variable
+	"getter key":        25,  // mapping key or array index of a getter
+	"statement":         10,
+	"storage read":      10,
+	"storage write":     16,
+	"revert string":     45, // encoding of Error(string)
+	"string word":       35, // PUSH32 and MSTORE of every 32 bytes of a string
+	"custom error":      12,
+	"error argument":    6,
+	"string literal":    10,
+	"modifier use":      30, // on top of the inlined body
+}
+
+// Size is the estimated size of the runtime bytecode of a contract, with
+// the code inherited from its bases. The estimate is a heuristic over the
+// syntax tree, the contract isn't compiled.
+type Size struct {
+	Contract *ast.ContractDeclaration
+	Estimate int      // estimated size in bytes
+	Factors  []Factor // features contributing to the estimate, the largest first
+	Sources  []Source // contributions of the contract and of its bases, in the order of the linearization
+}
+
+// Factor is a feature of the contract contributing to its size e.g. the
+// revert strings.
+type Factor struct {
+	Name   string // e.g. "revert-strings"
+	Count  int    // occurrences of the feature e.g. the number of revert strings
+	Bytes  int    // estimated contribution in bytes
+	Detail string // e.g. "12 revert strings totaling 840 bytes; consider custom errors"
+}
+
+// Source is the code a contract in the linearization contributes.
+type Source struct {
+	Contract *ast.ContractDeclaration
+	Bytes    int // estimated contribution in bytes
+}
+
+// Name returns the name of the contract.
+func (s Size) Name() string {
+	return s.Contract.Name.Name
+}
+
+// Top returns at most n of the largest factors.
+func (s Size) Top(n int) []Factor {
+	return s.Factors[:min(n, len(s.Factors))]
+}
+
+// EstimateSize estimates the size of the contract from the C3 linearization
+// of the contract and its bases, the contract itself first. The functions
+// overridden in the linearization and the constructors are not counted,
+// they are not a part of the runtime code, while the modifiers are counted
+// once for every use, since the compiler inlines them.
+func EstimateSize(linearized []*ast.ContractDeclaration) Size {
+	e := &sizeEstimator{
+		linearized: linearized,
+		factors:    map[string]*tally{},
+		sources:    map[*ast.ContractDeclaration]int{},
+		storage:    map[string]bool{},
+	}
+	for _, c := range linearized {
+		for _, member := range c.Body {
+			if v, ok := member.(*ast.VariableDeclaration); ok && !v.Constant && !v.Immutable {
+				e.storage[v.Name.Name] = true
+			}
+		}
+	}
+
+	contract := linearized[0]
+	e.sources[contract] += weights["contract"]
+	seen := map[string]bool{}
+	for _, c := range linearized {
+		for _, member := range c.Body {
+			switch member := member.(type) {
+			case *ast.FunctionDeclaration:
+				if member.Kind == token.CONSTRUCTOR || member.Body == nil {
+					continue
+				}
+				key := functionKey(member)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				e.function(c, member)
+			case *ast.VariableDeclaration:
+				if member.Visibility == ast.Public {
+					e.getter(c, member)
+				}
+			}
+		}
+	}
+
+	size := Size{Contract: contract}
+	for _, c := range linearized {
+		size.Sources = append(size.Sources, Source{Contract: c, Bytes: e.sources[c]})
+		size.Estimate += e.sources[c]
+	}
+	for name, t := range e.factors {
+		size.Factors = append(size.Factors, Factor{Name: name, Count: t.count, Bytes: t.bytes, Detail: t.detail(name)})
+	}
+	slices.SortFunc(size.Factors, func(a, b Factor) int {
+		if a.Bytes != b.Bytes {
+			return b.Bytes - a.Bytes
+		}
+		if a.Name < b.Name {
+			return -1
+		}
+		return 1
+	})
+	return size
+}
+
+// tally is the occurrences and the size of a factor.
+type tally struct {
+	count   int
+	bytes   int
+	extra   int // second count in the detail e.g. the bytes of the strings or the parameters
+	dynamic int // dynamic parameters of the external functions
+}
+
+func (t *tally) detail(name string) string {
+	switch name {
+	case "revert-strings":
+		return fmt.Sprintf("%d revert strings totaling %d bytes; consider custom errors", t.count, t.extra)
+	case "custom-errors":
+		return fmt.Sprintf("%d custom error reverts", t.count)
+	case "external-functions":
+		return fmt.Sprintf("%d external and public functions with %d parameters and return values, %d of them dynamic; "+
+			"consider moving some of them to another contract", t.count, t.extra, t.dynamic)
+	case "getters":
+		return fmt.Sprintf("%d getters of public state variables; consider making the ones not read from outside internal", t.count)
+	case "modifiers":
+		return fmt.Sprintf("%d modifier uses inlining %d bytes; consider moving the modifier bodies to internal functions", t.count, t.bytes)
+	case "storage-reads":
+		return fmt.Sprintf("%d storage reads; consider caching the repeated ones in local variables", t.count)
+	case "storage-writes":
+		return fmt.Sprintf("%d storage writes", t.count)
+	case "string-literals":
+		return fmt.Sprintf("%d string literals totaling %d bytes", t.count, t.extra)
+	}
+	return fmt.Sprintf("%d %s", t.count, name)
+}
+
+type sizeEstimator struct {
+	linearized []*ast.ContractDeclaration
+	factors    map[string]*tally
+	sources    map[*ast.ContractDeclaration]int // contract -> bytes of the code it declares
+	storage    map[string]bool                  // names of the state variables in the storage
+}
+
+// add counts the occurrences of the factor in the code declared by the
+// contract.
+func (e *sizeEstimator) add(c *ast.ContractDeclaration, name string, count, bytes int) *tally {
+	t, ok := e.factors[name]
+	if !ok {
+		t = &tally{}
+		e.factors[name] = t
+	}
+	t.count += count
+	t.bytes += bytes
+	e.sources[c] += bytes
+	return t
+}
+
+func (e *sizeEstimator) function(c *ast.ContractDeclaration, fn *ast.FunctionDeclaration) {
+	visibility := fn.Type.Visibility
+	if visibility == ast.External || visibility == ast.Public || fn.Kind == token.FALLBACK || fn.Kind == token.RECEIVE {
+		bytes, params, dynamic := weights["function"], 0, 0
+		for _, list := range []*ast.ParamList{fn.Type.Params, fn.Type.Results} {
+			if list == nil {
+				continue
+			}
+			for _, p := range list.List {
+				params++
+				if isDynamic(p.Type) {
+					dynamic++
+					bytes += weights["dynamic parameter"]
+				} else {
+					bytes += weights["parameter"]
+				}
+			}
+		}
+		t := e.add(c, "external-functions", 1, bytes)
+		t.extra += params
+		t.dynamic += dynamic
+	}
+
+	for _, inv := range fn.Modifiers {
+		owner, mod := e.modifier(ast.ModifierName(inv))
+		if mod == nil {
+			continue
+		}
+		// The inlined body is a part of the modifier factor, not of the
+		// factors of its statements.
+		inlined := &sizeEstimator{factors: map[string]*tally{}, sources: map[*ast.ContractDeclaration]int{}, storage: e.storage}
+		inlined.body(owner, mod.Body, mod.Params, nil)
+		e.add(owner, "modifiers", 1, weights["modifier use"]+inlined.sources[owner])
+	}
+	e.body(c, fn.Body, fn.Type.Params, fn.Type.Results)
+}
+
+// modifier returns the first modifier with the name in the linearization,
+// and the contract declaring it.
+func (e *sizeEstimator) modifier(name string) (*ast.ContractDeclaration, *ast.ModifierDeclaration) {
+	for _, c := range e.linearized {
+		for _, member := range c.Body {
+			if mod, ok := member.(*ast.ModifierDeclaration); ok && mod.Name.Name == name && mod.Body != nil {
+				return c, mod
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (e *sizeEstimator) getter(c *ast.ContractDeclaration, v *ast.VariableDeclaration) {
+	bytes := weights["getter"]
+	for typ := v.Type; typ != nil; {
+		switch t := typ.(type) {
+		case *ast.MappingType:
+			bytes += weights["getter key"]
+			typ = t.Value
+		case *ast.ArrayType:
+			bytes += weights["getter key"]
+			typ = t.Elem
+		default:
+			typ = nil
+		}
+	}
+	e.add(c, "getters", 1, bytes)
+}
+
+// body counts the statements of the function or the modifier body, its
+// storage accesses, reverts and string literals.
+func (e *sizeEstimator) body(c *ast.ContractDeclaration, body *ast.BlockStatement, lists ...*ast.ParamList) {
+	// The locals shadowing the state variables are not storage accesses.
+	locals := map[string]bool{}
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for _, p := range list.List {
+			if p.Name != nil {
+				locals[p.Name.Name] = true
+			}
+		}
+	}
+	written, members, reverts := map[*ast.Identifier]bool{}, map[*ast.Identifier]bool{}, map[*ast.BasicLit]bool{}
+	customErrors := 0
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.RevertStatement:
+			if node.Call != nil {
+				customErrors++
+			}
+		case *ast.VariableDeclarationStatement:
+			for _, decl := range node.Declarations {
+				if decl != nil {
+					locals[decl.Name.Name] = true
+				}
+			}
+		case *ast.AssignmentExpression:
+			writtenRoots(node.Left, written)
+		case *ast.UnaryExpression:
+			if node.Operator == token.INC || node.Operator == token.DEC || node.Operator == token.DELETE {
+				writtenRoots(node.Operand, written)
+			}
+		case *ast.MemberAccessExpression:
+			members[node.Member] = true
+		}
+		return true
+	})
+
+	// The reverts with the custom errors are counted as the custom errors.
+	statements := countStatements(body) - customErrors
+	e.add(c, "statements", statements, weights["statement"]*statements)
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			if !e.storage[node.Name] || locals[node.Name] || members[node] {
+				break
+			}
+			if written[node] {
+				e.add(c, "storage-writes", 1, weights["storage write"])
+			} else {
+				e.add(c, "storage-reads", 1, weights["storage read"])
+			}
+		case *ast.RevertStatement:
+			if node.Call == nil {
+				break
+			}
+			e.add(c, "custom-errors", 1, weights["custom error"]+weights["error argument"]*len(node.Call.Args))
+		case *ast.CallExpression:
+			reason := revertReason(node)
+			switch reason := reason.(type) {
+			case *ast.BasicLit:
+				if reason.Kind == token.STRING_LITERAL {
+					n := stringLength(reason)
+					e.add(c, "revert-strings", 1, weights["revert string"]+weights["string word"]*words(n)).extra += n
+					reverts[reason] = true
+				}
+			case *ast.CallExpression:
+				// require(condition, CustomError(...))
+				e.add(c, "custom-errors", 1, weights["custom error"]+weights["error argument"]*len(reason.Args))
+			}
+		case *ast.BasicLit:
+			if node.Kind == token.STRING_LITERAL && !reverts[node] {
+				n := stringLength(node)
+				e.add(c, "string-literals", 1, weights["string literal"]+weights["string word"]*words(n)).extra += n
+			}
+		}
+		return true
+	})
+}
+
+// revertReason returns the reason of `require(condition, reason)` or of
+// `revert(reason)`; or nil if the call is neither.
+func revertReason(call *ast.CallExpression) ast.Expression {
+	name, ok := call.Function.(*ast.Identifier)
+	switch {
+	case !ok:
+	case name.Name == "require" && len(call.Args) == 2:
+		return call.Args[1]
+	case name.Name == "revert" && len(call.Args) == 1:
+		return call.Args[0]
+	}
+	return nil
+}
+
+// writtenRoots collects the variables written by an assignment to the
+// expression e.g. `balances` in `balances[owner].amount = 0`.
+func writtenRoots(expr ast.Expression, written map[*ast.Identifier]bool) {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		written[expr] = true
+	case *ast.IndexAccessExpression:
+		writtenRoots(expr.Expression, written)
+	case *ast.MemberAccessExpression:
+		writtenRoots(expr.Expression, written)
+	case *ast.TupleExpression:
+		for _, elem := range expr.Elements {
+			writtenRoots(elem, written)
+		}
+	}
+}
+
+// isDynamic reports whether the ABI encoding of the type has a dynamic
+// size: strings, bytes and dynamic arrays. The structs can't be told apart
+// from the other user-defined types without resolving them, they are
+// counted as static.
+func isDynamic(typ ast.Expression) bool {
+	switch typ := typ.(type) {
+	case *ast.ElementaryType:
+		return typ.Value == "string" || typ.Value == "bytes"
+	case *ast.ArrayType:
+		return typ.Len == nil || isDynamic(typ.Elem)
+	}
+	return false
+}
+
+// stringLength returns the length of the string literal without the
+// quotes, counting an escape sequence as a single byte.
+func stringLength(lit *ast.BasicLit) int {
+	n := 0
+	value := lit.Value[1 : len(lit.Value)-1]
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			switch value[i+1] {
+			case 'x':
+				i += 3
+			case 'u':
+				i += 5
+			default:
+				i++
+			}
+		}
+		n++
+	}
+	return n
+}
+
+// words returns the number of the 32-byte words the string occupies.
+func words(n int) int {
+	return (n + 31) / 32
+}
+
+// functionKey identifies the function among the ones it overrides e.g.
+// "transfer/2" or "fallback". Overloads with the same number of parameters
+// are rare enough to be counted once.
+func functionKey(fn *ast.FunctionDeclaration) string {
+	if fn.Name == nil {
+		return fn.Kind.String()
+	}
+	params := 0
+	if fn.Type.Params != nil {
+		params = len(fn.Type.Params.List)
+	}
+	return fmt.Sprintf("%s/%d", fn.Name.Name, params)
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"solbot/ast"
+	"testing"
+)
+
+func Test_EstimateSize(t *testing.T) {
+	src := `contract Base {
+        uint256 total;
+
+        modifier onlyPositive(uint256 amount) {
+            require(amount > 0);
+            _;
+        }
+
+        function deposit(uint256 amount) public virtual onlyPositive(amount) {
+            total += amount;
+        }
+
+        function name() external pure returns (string memory) {
+            return "Base";
+        }
+    }
+
+    contract Vault is Base {
+        mapping(address => uint256) public balances;
+
+        constructor() {
+            total = 1;
+            require(msg.sender != address(0), "Vault: the deployer is the zero address");
+        }
+
+        function deposit(uint256 amount) public override onlyPositive(amount) {
+            uint256 total = amount;
+            balances[msg.sender] += total;
+            revert("a\"b");
+        }
+    }
+    `
+	file := parse(t, src)
+	base := file.Declarations[0].(*ast.ContractDeclaration)
+	vault := file.Declarations[1].(*ast.ContractDeclaration)
+	size := EstimateSize([]*ast.ContractDeclaration{vault, base})
+
+	// The overridden Base.deposit, the constructor and the storage
+	// accesses of the shadowing local are not counted.
+	expected := map[string][2]int{ // factor -> count, bytes
+		"external-functions": {2, 45 + 20 + 45 + 110}, // deposit(uint256), name() returns (string)
+		"getters":            {1, 50 + 25},
+		"modifiers":          {1, 30 + 2*10}, // the require and the placeholder inlined once
+		"statements":         {4, 4 * 10},
+		"string-literals":    {1, 10 + 35},
+		"storage-writes":     {1, 16},
+		"revert-strings":     {1, 45 + 35},
+	}
+	if len(size.Factors) != len(expected) {
+		t.Errorf("Expected %d factors, got %v", len(expected), size.Factors)
+	}
+	for _, f := range size.Factors {
+		if e := expected[f.Name]; f.Count != e[0] || f.Bytes != e[1] {
+			t.Errorf("Expected %s to be %d times %d bytes, got %d times %d bytes", f.Name, e[0], e[1], f.Count, f.Bytes)
+		}
+	}
+	if top := size.Top(2); len(top) != 2 || top[0].Name != "external-functions" || top[1].Name != "revert-strings" {
+		t.Errorf("Expected the external functions and the revert strings first, got %v", top)
+	}
+	if detail := size.Factors[1].Detail; detail != "1 revert strings totaling 3 bytes; consider custom errors" {
+		t.Errorf("Expected the escaped quote counted as a byte, got %q", detail)
+	}
+
+	// The inherited code is attributed to the base declaring it.
+	if size.Estimate != 646 || len(size.Sources) != 2 || size.Sources[0].Bytes != 386 || size.Sources[1].Bytes != 260 {
+		t.Errorf("Expected 646 bytes, 386 of Vault and 260 of Base, got %d and %v", size.Estimate, size.Sources)
+	}
+}
+
+func Test_EstimateSizeConcatenatedString(t *testing.T) {
+	estimate := func(message string) Size {
+		src := `contract Vault {
+        function withdraw(bool ok) external {
+            require(ok, ` + message + `);
+        }
+    }`
+		file := parse(t, src)
+		return EstimateSize([]*ast.ContractDeclaration{file.Declarations[0].(*ast.ContractDeclaration)})
+	}
+
+	// The parts are counted once, by the length of the concatenated string.
+	single := estimate(`"Vault: the caller is not the owner of the funds"`)
+	parts := estimate(`"Vault: the caller is not " "the owner of the funds"`)
+	if parts.Estimate != single.Estimate {
+		t.Errorf("Expected %d bytes, got %d", single.Estimate, parts.Estimate)
+	}
+	for _, f := range parts.Factors {
+		if f.Name == "revert-strings" && f.Detail != "1 revert strings totaling 47 bytes; consider custom errors" {
+			t.Errorf("Expected a single revert string of 47 bytes, got %q", f.Detail)
+		}
+	}
+}
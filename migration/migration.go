@@ -0,0 +1,200 @@
+// migration finds the code that breaks when the version pragma of a file is
+// raised to a newer compiler e.g. from ^0.7.0 to ^0.8.0, together with the
+// edits fixing it where the fix is mechanical.
+//
+// The breaking changes are kept in a table of migrations keyed by the
+// version ranges they migrate between, so that new ones can be added
+// without touching the code that applies them.
+package migration
+
+import (
+	"slices"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/semver"
+	"solbot/token"
+	"strconv"
+	"strings"
+)
+
+// Migration is a set of the breaking changes between two version ranges.
+type Migration struct {
+	From   semver.Constraint // versions the code is migrated from
+	To     semver.Constraint // versions the code is migrated to
+	Target string            // suggested pragma value e.g. "^0.8.0"
+	Rules  []Rule
+}
+
+// Rule finds the code affected by one of the breaking changes.
+type Rule struct {
+	Name  string // e.g. "now", used as the diagnostic code
+	Check func(file *ast.File, src string) []Issue
+}
+
+// Issue is a piece of code that breaks after the migration.
+type Issue struct {
+	Rule    string
+	Range   token.Range
+	Message string
+	Fix     *Fix // nil if the fix is not mechanical
+}
+
+// Fix is a set of edits of the file that resolves the issue.
+type Fix struct {
+	Title string
+	Edits []Edit
+}
+
+// Edit replaces the text in the range, it's an insertion if the range is
+// empty.
+type Edit struct {
+	Range   token.Range
+	NewText string
+}
+
+// Migrations are all of the known migrations, from the oldest one.
+var Migrations = []Migration{
+	{
+		From:   semver.MustParseConstraint("<0.8.0"),
+		To:     semver.MustParseConstraint(">=0.8.0"),
+		Target: "^0.8.0",
+		Rules: []Rule{
+			{Name: "now", Check: checkNow},
+			{Name: "payable-sender", Check: checkPayableSender},
+			{Name: "abicoder-v2", Check: checkAbicoder},
+			{Name: "explicit-conversion", Check: checkConversions},
+			{Name: "byte", Check: checkByte},
+			{Name: "enum-conversion", Check: checkEnumConversions},
+			{Name: "safemath", Check: checkSafeMath},
+		},
+	},
+}
+
+// Applicable returns the migrations needed to compile the file with the
+// target version constraint. Files without a valid pragma are assumed to
+// target the latest compiler already, so nothing applies to them.
+func Applicable(file *ast.File, target semver.Constraint) []Migration {
+	current, ok := pragma.Solidity(file)
+	if !ok {
+		return nil
+	}
+	res := []Migration{}
+	for _, m := range Migrations {
+		if current.AllowsAny(m.From) && target.AllowsAny(m.To) {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// Check returns the issues of all of the migrations needed to compile the
+// file with the target version constraint, sorted by their position.
+func Check(file *ast.File, src string, target semver.Constraint) []Issue {
+	res := []Issue{}
+	for _, m := range Applicable(file, target) {
+		res = append(res, m.Check(file, src)...)
+	}
+	slices.SortStableFunc(res, func(a, b Issue) int { return int(a.Range.Start - b.Range.Start) })
+	return res
+}
+
+// Check returns the issues found by all of the rules of the migration,
+// regardless of the pragma of the file.
+func (m Migration) Check(file *ast.File, src string) []Issue {
+	res := []Issue{}
+	for _, rule := range m.Rules {
+		for _, issue := range rule.Check(file, src) {
+			issue.Rule = rule.Name
+			res = append(res, issue)
+		}
+	}
+	return res
+}
+
+// BumpPragma returns the edit replacing the value of the solidity pragma
+// with the target; or nil if the file doesn't have the pragma.
+func BumpPragma(file *ast.File, target string) *Edit {
+	p := file.Pragma("solidity")
+	if p == nil {
+		return nil
+	}
+	return &Edit{Range: token.Range{Start: p.Name.End(), End: p.Semicolon}, NewText: " " + target}
+}
+
+// inspect is like ast.Inspect, but it passes the parent of the node too.
+// The parent of the file is nil.
+func inspect(root ast.Node, f func(node, parent ast.Node) bool) {
+	stack := []ast.Node{nil}
+	ast.Inspect(root, func(node ast.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if !f(node, stack[len(stack)-1]) {
+			return false
+		}
+		stack = append(stack, node)
+		return true
+	})
+}
+
+// isMember reports whether the identifier is the member in its parent
+// member access e.g. `now` in `block.now`.
+func isMember(ident *ast.Identifier, parent ast.Node) bool {
+	access, ok := parent.(*ast.MemberAccessExpression)
+	return ok && access.Member == ident
+}
+
+// DeleteDirective returns the edit deleting the directive e.g. a pragma,
+// together with the rest of its lines if there is nothing else on them.
+func DeleteDirective(src string, decl ast.Node) Edit {
+	return deleteLines(src, directiveRange(decl))
+}
+
+// deleteLines returns the edit deleting the node together with the rest of
+// its lines if there is nothing else on them.
+func deleteLines(src string, r token.Range) Edit {
+	start, end := int(r.Start), int(r.End)
+	lineStart := start
+	for lineStart > 0 && (src[lineStart-1] == ' ' || src[lineStart-1] == '\t') {
+		lineStart--
+	}
+	lineEnd := end
+	for lineEnd < len(src) && (src[lineEnd] == ' ' || src[lineEnd] == '\t') {
+		lineEnd++
+	}
+	if (lineStart == 0 || src[lineStart-1] == '\n') && (lineEnd == len(src) || src[lineEnd] == '\n') {
+		start = lineStart
+		end = min(lineEnd+1, len(src))
+		// Don't leave a blank line after another one or after an
+		// opening brace e.g. when removing the first directive of a
+		// contract.
+		prev := strings.TrimSpace(src[strings.LastIndexByte(src[:max(start-1, 0)], '\n')+1 : max(start-1, 0)])
+		next := strings.IndexByte(src[end:], '\n')
+		if (prev == "" || strings.HasSuffix(prev, "{")) && next >= 0 && strings.TrimSpace(src[end:end+next]) == "" {
+			end += next + 1
+		}
+	}
+	return Edit{Range: token.Range{Start: token.Pos(start), End: token.Pos(end)}}
+}
+
+// directiveRange returns the range of the directive including the closing
+// semicolon, which is not a part of its End.
+func directiveRange(decl ast.Node) token.Range {
+	return token.Range{Start: decl.Start(), End: decl.End() + 1}
+}
+
+// integerType returns the signedness and the size in bits of the integer
+// type name e.g. "uint" or "int8".
+func integerType(name string) (signed bool, bits int, ok bool) {
+	signed = strings.HasPrefix(name, "int")
+	if !signed && !strings.HasPrefix(name, "uint") {
+		return false, 0, false
+	}
+	digits := strings.TrimPrefix(strings.TrimPrefix(name, "u"), "int")
+	if digits == "" {
+		return signed, 256, true
+	}
+	bits, err := strconv.Atoi(digits)
+	return signed, bits, err == nil
+}
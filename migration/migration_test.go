@@ -0,0 +1,132 @@
+package migration
+
+import (
+	"slices"
+	"solbot/ast"
+	"solbot/parser"
+	"solbot/semver"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, src string) *ast.File {
+	t.Helper()
+	p := parser.Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	file := p.ParseFile()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Expected no parser errors, got %v", p.Errors())
+	}
+	return file
+}
+
+// apply returns the source with the edits applied. The insertions at the
+// same position end up in the order of the edits.
+func apply(src string, edits []Edit) string {
+	order := make([]int, len(edits))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		a, b := edits[i].Range, edits[j].Range
+		if a.Start != b.Start {
+			return int(b.Start - a.Start)
+		}
+		if (a.Start == a.End) != (b.Start == b.End) {
+			if a.Start == a.End {
+				return 1
+			}
+			return -1
+		}
+		return j - i
+	})
+	for _, i := range order {
+		src = src[:edits[i].Range.Start] + edits[i].NewText + src[edits[i].Range.End:]
+	}
+	return src
+}
+
+func Test_SafeMathRewrite(t *testing.T) {
+	tests := []struct {
+		call     string
+		expected string
+	}{
+		{"a.add(b).mul(c)", "(a + b) * c"},
+		{"a.mul(b).add(c)", "a * b + c"},
+		{"a.sub(b).sub(c)", "a - b - c"},
+		{"a.sub(b.sub(c))", "a - (b - c)"},
+		{"a.div(b + c)", "a / (b + c)"},
+		{"(a + b).mod(c)", "(a + b) % c"},
+		{"-a.add(b)", "-(a + b)"},
+		{"SafeMath.add(a, b).mul(c)", "SafeMath.add(a, b) * c"},
+	}
+
+	for _, tt := range tests {
+		src := "pragma solidity ^0.7.0;\n\ncontract C {\n    using SafeMath for uint256;\n\n    function f(uint256 a, uint256 b, uint256 c) public pure returns (uint256) {\n        return " + tt.call + ";\n    }\n}\n"
+		issues := Check(parse(t, src), src, semver.MustParseConstraint("^0.8.0"))
+		if len(issues) != 2 || issues[1].Rule != "safemath" || issues[1].Fix == nil {
+			t.Fatalf("%s: expected the abicoder note and the safemath fix, got %v", tt.call, issues)
+		}
+		expected := "pragma solidity ^0.7.0;\n\ncontract C {\n    function f(uint256 a, uint256 b, uint256 c) public pure returns (uint256) {\n        return " + tt.expected + ";\n    }\n}\n"
+		if got := apply(src, issues[1].Fix.Edits); got != expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", tt.call, expected, got)
+		}
+	}
+
+	// Calls with a custom error message have no operator equivalent.
+	src := "pragma solidity ^0.7.0;\n\ncontract C {\n    using SafeMath for uint256;\n\n    function f(uint256 a) public pure returns (uint256) {\n        return a.sub(1, \"underflow\");\n    }\n}\n"
+	issues := Check(parse(t, src), src, semver.MustParseConstraint("^0.8.0"))
+	if len(issues) != 2 || issues[1].Fix != nil {
+		t.Errorf("Expected the safemath issue without a fix, got %v", issues)
+	}
+}
+
+func Test_Conversions(t *testing.T) {
+	src := `pragma solidity ^0.7.0;
+
+contract C {
+    function f(int256 x, uint256 y) public pure {
+        uint256 a = uint256(-1);
+        uint64 b = uint64(-2);
+        address c = address(uint256(y));
+        uint16 d = uint16(int8(x));
+        uint256 e = uint256(int256(x));
+    }
+}
+`
+	issues := Check(parse(t, src), src, semver.MustParseConstraint("^0.8.0"))
+	expected := []struct {
+		fixed string
+		text  string
+	}{
+		{"uint256 a = type(uint256).max;", "uint256(-1)"},
+		{"", "uint64(-2)"},
+		{"address c = address(uint160(uint256(y)));", "address(uint256(y))"},
+		{"uint16 d = uint16(int16(int8(x)));", "uint16(int8(x))"},
+	}
+
+	conversions := []Issue{}
+	for _, issue := range issues {
+		if issue.Rule == "explicit-conversion" {
+			conversions = append(conversions, issue)
+		}
+	}
+	if len(conversions) != len(expected) {
+		t.Fatalf("Expected %d conversions, got %d", len(expected), len(conversions))
+	}
+	for i, issue := range conversions {
+		if text := src[issue.Range.Start:issue.Range.End]; text != expected[i].text {
+			t.Errorf("Expected the issue at %s, got %s", expected[i].text, text)
+		}
+		if issue.Fix == nil {
+			if expected[i].fixed != "" {
+				t.Errorf("Expected a fix of %s, got none", expected[i].text)
+			}
+			continue
+		}
+		if got := apply(src, issue.Fix.Edits); !strings.Contains(got, expected[i].fixed) {
+			t.Errorf("Expected %q after the fix, got:\n%s", expected[i].fixed, got)
+		}
+	}
+}
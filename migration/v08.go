@@ -0,0 +1,489 @@
+package migration
+
+import (
+	"fmt"
+	"path"
+	"solbot/ast"
+	"solbot/token"
+	"strconv"
+	"strings"
+)
+
+// The breaking changes of Solidity 0.8.0, and of 0.7.0 where the legacy code
+// still compiles with 0.6.
+
+// checkNow reports the `now` alias of block.timestamp, removed in 0.7.0.
+func checkNow(file *ast.File, src string) []Issue {
+	if declares(file, "now") {
+		return nil
+	}
+	res := []Issue{}
+	inspect(file, func(node, parent ast.Node) bool {
+		ident, ok := node.(*ast.Identifier)
+		if !ok || ident.Name != "now" || isMember(ident, parent) {
+			return true
+		}
+		res = append(res, Issue{
+			Range:   ast.NodeRange(ident),
+			Message: "`now` was removed in Solidity 0.7.0, use `block.timestamp` instead",
+			Fix: &Fix{
+				Title: "Replace `now` with `block.timestamp`",
+				Edits: []Edit{{Range: ast.NodeRange(ident), NewText: "block.timestamp"}},
+			},
+		})
+		return true
+	})
+	return res
+}
+
+// checkPayableSender reports `msg.sender.transfer` and `msg.sender.send`,
+// since msg.sender is no longer `address payable`. The assignments of
+// msg.sender to payable variables are not reported.
+func checkPayableSender(file *ast.File, src string) []Issue {
+	res := []Issue{}
+	inspect(file, func(node, parent ast.Node) bool {
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+		fn, ok := call.Function.(*ast.MemberAccessExpression)
+		if !ok || (fn.Member.Name != "transfer" && fn.Member.Name != "send") || !isMsgSender(fn.Expression) {
+			return true
+		}
+		sender := fn.Expression
+		res = append(res, Issue{
+			Range:   ast.NodeRange(sender),
+			Message: fmt.Sprintf("`msg.sender` is not `address payable` since Solidity 0.8.0, it has to be converted to call `%s`", fn.Member.Name),
+			Fix: &Fix{
+				Title: "Convert `msg.sender` with `payable(msg.sender)`",
+				Edits: []Edit{
+					{Range: token.Range{Start: sender.Start(), End: sender.Start()}, NewText: "payable("},
+					{Range: token.Range{Start: sender.End(), End: sender.End()}, NewText: ")"},
+				},
+			},
+		})
+		return true
+	})
+	return res
+}
+
+func isMsgSender(x ast.Expression) bool {
+	access, ok := x.(*ast.MemberAccessExpression)
+	if !ok || access.Member.Name != "sender" {
+		return false
+	}
+	msg, ok := access.Expression.(*ast.Identifier)
+	return ok && msg.Name == "msg"
+}
+
+// checkAbicoder reports the pragmas enabling ABI coder v2, which is the
+// default since 0.8.0, and the files relying on the v1 default.
+func checkAbicoder(file *ast.File, src string) []Issue {
+	res := []Issue{}
+	explicit := false
+	for _, decl := range file.Declarations {
+		p, ok := decl.(*ast.PragmaDirective)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.Kind == ast.ExperimentalPragma && p.Argument == "ABIEncoderV2",
+			p.Kind == ast.AbicoderPragma && p.Argument == "v2":
+			explicit = true
+			res = append(res, Issue{
+				Range:   directiveRange(p),
+				Message: "ABI coder v2 is the default since Solidity 0.8.0, the pragma can be removed",
+				Fix: &Fix{
+					Title: "Remove the pragma",
+					Edits: []Edit{deleteLines(src, directiveRange(p))},
+				},
+			})
+		case p.Kind == ast.AbicoderPragma:
+			explicit = true
+		}
+	}
+
+	p := file.Pragma("solidity")
+	if explicit || p == nil || !declaresContract(file) {
+		return res
+	}
+	return append(res, Issue{
+		Range:   directiveRange(p),
+		Message: "ABI coder v2 is the default since Solidity 0.8.0, it validates the calldata and can change the gas costs; add `pragma abicoder v1;` to keep the old coder",
+	})
+}
+
+// checkConversions reports the explicit conversions disallowed since 0.8.0:
+// between integer types of a different sign and size, from integers other
+// than uint160 to address, and of negative literals to unsigned integers.
+func checkConversions(file *ast.File, src string) []Issue {
+	res := []Issue{}
+	inspect(file, func(node, parent ast.Node) bool {
+		outer, ok := conversion(node)
+		if !ok {
+			return true
+		}
+		arg := node.(*ast.CallExpression).Args[0]
+		outerSigned, outerBits, outerIsInt := integerType(outer.Value)
+
+		if inner, ok := conversion(arg); ok {
+			innerSigned, innerBits, innerIsInt := integerType(inner.Value)
+			if !innerIsInt {
+				return true
+			}
+			var via string
+			switch {
+			case outerIsInt && outerSigned != innerSigned && outerBits != innerBits:
+				via = intName(innerSigned, outerBits)
+			case outer.Value == "address" && (innerSigned || innerBits != 160):
+				via = "uint160"
+			default:
+				return true
+			}
+			res = append(res, Issue{
+				Range:   ast.NodeRange(node),
+				Message: fmt.Sprintf("Conversion from `%s` to `%s` is disallowed since Solidity 0.8.0, it has to go through `%s`", inner.Value, outer.Value, via),
+				Fix: &Fix{
+					Title: fmt.Sprintf("Convert through `%s`", via),
+					Edits: []Edit{
+						{Range: token.Range{Start: arg.Start(), End: arg.Start()}, NewText: via + "("},
+						{Range: token.Range{Start: arg.End(), End: arg.End()}, NewText: ")"},
+					},
+				},
+			})
+			return true
+		}
+
+		neg, ok := arg.(*ast.UnaryExpression)
+		if !ok || neg.Operator != token.SUB || !outerIsInt || outerSigned {
+			return true
+		}
+		lit, ok := neg.Operand.(*ast.BasicLit)
+		if !ok || lit.Kind != token.DECIMAL_NUMBER {
+			return true
+		}
+		issue := Issue{
+			Range:   ast.NodeRange(node),
+			Message: fmt.Sprintf("Conversion of a negative literal to `%s` is disallowed since Solidity 0.8.0", outer.Value),
+		}
+		if lit.Value == "1" {
+			issue.Fix = &Fix{
+				Title: fmt.Sprintf("Replace with `type(%s).max`", outer.Value),
+				Edits: []Edit{{Range: ast.NodeRange(node), NewText: fmt.Sprintf("type(%s).max", outer.Value)}},
+			}
+		}
+		res = append(res, issue)
+		return true
+	})
+	return res
+}
+
+// conversion returns the target type if the node is an explicit conversion
+// to an elementary type with a single argument e.g. `uint8(x)`.
+func conversion(node ast.Node) (*ast.ElementaryType, bool) {
+	call, ok := node.(*ast.CallExpression)
+	if !ok || len(call.Args) != 1 || call.Names != nil {
+		return nil, false
+	}
+	typ, ok := call.Function.(*ast.ElementaryType)
+	return typ, ok
+}
+
+func intName(signed bool, bits int) string {
+	if signed {
+		return "int" + strconv.Itoa(bits)
+	}
+	return "uint" + strconv.Itoa(bits)
+}
+
+// checkByte reports the `byte` type, an alias of bytes1 removed in 0.8.0.
+func checkByte(file *ast.File, src string) []Issue {
+	if declares(file, "byte") {
+		return nil
+	}
+	res := []Issue{}
+	inspect(file, func(node, parent ast.Node) bool {
+		ident, ok := node.(*ast.Identifier)
+		if !ok || ident.Name != "byte" || isMember(ident, parent) {
+			return true
+		}
+		res = append(res, Issue{
+			Range:   ast.NodeRange(ident),
+			Message: "`byte` was removed in Solidity 0.8.0, use `bytes1` instead",
+			Fix: &Fix{
+				Title: "Replace `byte` with `bytes1`",
+				Edits: []Edit{{Range: ast.NodeRange(ident), NewText: "bytes1"}},
+			},
+		})
+		return true
+	})
+	return res
+}
+
+// checkEnumConversions reports the conversions of integers to the enums
+// declared in the file. Since 0.8.0 the values out of the range of the enum
+// revert with a panic instead of consuming all of the gas, so the callers
+// may want to validate them first.
+func checkEnumConversions(file *ast.File, src string) []Issue {
+	enums := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if enum, ok := node.(*ast.EnumDeclaration); ok {
+			enums[enum.Name.Name] = true
+		}
+		return true
+	})
+
+	res := []Issue{}
+	inspect(file, func(node, parent ast.Node) bool {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || len(call.Args) != 1 {
+			return true
+		}
+		fn, ok := call.Function.(*ast.Identifier)
+		if !ok || !enums[fn.Name] {
+			return true
+		}
+		if _, ok := call.Args[0].(*ast.BasicLit); ok {
+			return true
+		}
+		res = append(res, Issue{
+			Range:   ast.NodeRange(call),
+			Message: fmt.Sprintf("Conversion to `%s` reverts with `Panic(0x21)` since Solidity 0.8.0 if the value is out of its range, consider checking the range explicitly", fn.Name),
+		})
+		return true
+	})
+	return res
+}
+
+// safeMathOperators are the SafeMath functions replaced by the checked
+// arithmetic.
+var safeMathOperators = map[string]string{
+	"add": "+",
+	"sub": "-",
+	"mul": "*",
+	"div": "/",
+	"mod": "%",
+}
+
+// checkSafeMath reports the `using SafeMath for ...` directives, which are
+// removable since the arithmetic is checked by default. The fix rewrites
+// the bound calls e.g. `a.add(b)` to the operators and removes the import of
+// the library if nothing else uses it. The calls can't be told apart from
+// the functions bound by other libraries without knowing the types, so the
+// fix is only offered if the directive is the only one in the contract.
+func checkSafeMath(file *ast.File, src string) []Issue {
+	res := []Issue{}
+	for _, decl := range file.Declarations {
+		c, ok := decl.(*ast.ContractDeclaration)
+		if !ok {
+			continue
+		}
+		directives := []*ast.UsingForDirective{}
+		for _, member := range c.Body {
+			if d, ok := member.(*ast.UsingForDirective); ok {
+				directives = append(directives, d)
+			}
+		}
+		for _, d := range directives {
+			lib, ok := d.Library.(*ast.Identifier)
+			if !ok || !strings.Contains(lib.Name, "SafeMath") {
+				continue
+			}
+			issue := Issue{
+				Range:   directiveRange(d),
+				Message: fmt.Sprintf("Arithmetic is checked by default since Solidity 0.8.0, `%s` is no longer needed", src[d.Start():d.End()+1]),
+			}
+			if len(directives) == 1 {
+				if edits, ok := rewriteSafeMath(file, src, c, d, lib.Name); ok {
+					issue.Fix = &Fix{Title: fmt.Sprintf("Remove `using %s` and use the arithmetic operators", lib.Name), Edits: edits}
+				}
+			}
+			res = append(res, issue)
+		}
+	}
+	return res
+}
+
+// rewriteSafeMath returns the edits removing the directive, replacing the
+// bound calls in the contract with the operators and removing the import of
+// the library. It fails if some of the calls can't be replaced e.g. the
+// ones with a custom error message.
+func rewriteSafeMath(file *ast.File, src string, c *ast.ContractDeclaration, d *ast.UsingForDirective, lib string) ([]Edit, bool) {
+	edits := []Edit{deleteLines(src, directiveRange(d))}
+	failed := false
+	inspect(c, func(node, parent ast.Node) bool {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || !isSafeMathCall(call, lib) {
+			return true
+		}
+		fn := call.Function.(*ast.MemberAccessExpression)
+		op := safeMathOperators[fn.Member.Name]
+		if len(call.Args) != 1 {
+			failed = true
+			return false
+		}
+
+		// The replaced calls used as the operands of the other ones are
+		// parenthesized by them, depending on the precedence. The
+		// insertions at the same position are applied in the order of
+		// the edits, so the outer parentheses come first.
+		left, right := fn.Expression, call.Args[0]
+		edits = append(edits, parenthesize(call, isOperand(parent) && !isSafeMathFunction(parent))...)
+		edits = append(edits, parenthesize(left, needsParens(left) ||
+			isSafeMathCall(left, lib) && precedence(operatorOf(left)) < precedence(op))...)
+		edits = append(edits,
+			Edit{Range: token.Range{Start: left.End(), End: right.Start()}, NewText: " " + op + " "},
+			Edit{Range: token.Range{Start: right.End(), End: call.End()}},
+		)
+		edits = append(edits, parenthesize(right, needsParens(right) ||
+			isSafeMathCall(right, lib) && precedence(operatorOf(right)) <= precedence(op))...)
+		return true
+	})
+	if failed {
+		return nil, false
+	}
+
+	if !referencedOutside(file, lib, d) {
+		for _, decl := range file.Declarations {
+			if imp, ok := decl.(*ast.ImportDirective); ok && importsOnly(imp, lib) {
+				edits = append(edits, deleteLines(src, directiveRange(imp)))
+			}
+		}
+	}
+	return edits, true
+}
+
+// isSafeMathCall reports whether the expression is a call of one of the
+// bound SafeMath functions e.g. `a.add(b)`. Direct calls e.g.
+// `SafeMath.add(a, b)` work without the directive, so they are not included.
+func isSafeMathCall(x ast.Expression, lib string) bool {
+	call, ok := x.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	fn, ok := call.Function.(*ast.MemberAccessExpression)
+	if !ok {
+		return false
+	}
+	if _, ok := safeMathOperators[fn.Member.Name]; !ok {
+		return false
+	}
+	ident, ok := fn.Expression.(*ast.Identifier)
+	return !ok || ident.Name != lib
+}
+
+// isSafeMathFunction reports whether the node is the bound function of a
+// SafeMath call e.g. `a.add` in `a.add(b)`.
+func isSafeMathFunction(node ast.Node) bool {
+	fn, ok := node.(*ast.MemberAccessExpression)
+	if !ok {
+		return false
+	}
+	_, ok = safeMathOperators[fn.Member.Name]
+	return ok
+}
+
+// operatorOf returns the operator replacing the SafeMath call.
+func operatorOf(x ast.Expression) string {
+	fn := x.(*ast.CallExpression).Function.(*ast.MemberAccessExpression)
+	return safeMathOperators[fn.Member.Name]
+}
+
+func precedence(op string) int {
+	if op == "+" || op == "-" {
+		return 1
+	}
+	return 2
+}
+
+// parenthesize returns the edits wrapping the expression in parentheses if
+// needed.
+func parenthesize(x ast.Expression, needed bool) []Edit {
+	if !needed {
+		return nil
+	}
+	return []Edit{
+		{Range: token.Range{Start: x.Start(), End: x.Start()}, NewText: "("},
+		{Range: token.Range{Start: x.End(), End: x.End()}, NewText: ")"},
+	}
+}
+
+// needsParens reports whether the operand of a binary operator has to be
+// parenthesized to keep the evaluation order.
+func needsParens(x ast.Expression) bool {
+	switch x.(type) {
+	case *ast.BinaryExpression, *ast.ConditionalExpression, *ast.AssignmentExpression:
+		return true
+	}
+	return false
+}
+
+// isOperand reports whether a replaced call is used by an expression that
+// binds tighter than the operators e.g. `-a.add(b)`.
+func isOperand(parent ast.Node) bool {
+	switch parent.(type) {
+	case *ast.BinaryExpression, *ast.UnaryExpression, *ast.MemberAccessExpression, *ast.IndexAccessExpression:
+		return true
+	}
+	return false
+}
+
+// referencedOutside reports whether the name is used in the file anywhere
+// else than in the directive and the imports.
+func referencedOutside(file *ast.File, name string, d *ast.UsingForDirective) bool {
+	found := false
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.ImportDirective:
+			return false
+		case *ast.UsingForDirective:
+			return n != d
+		case *ast.Identifier:
+			found = found || n.Name == name
+		}
+		return !found
+	})
+	return found
+}
+
+// importsOnly reports whether the import brings only the library into the
+// file e.g. `import {SafeMath} from "..."` or `import ".../SafeMath.sol"`.
+func importsOnly(imp *ast.ImportDirective, lib string) bool {
+	switch {
+	case imp.Symbols != nil:
+		return len(imp.Symbols) == 1 && imp.Symbols[0].Name.Name == lib && imp.Symbols[0].Alias == nil
+	case imp.Alias != nil:
+		return false
+	}
+	value := imp.Path.Value
+	return len(value) >= 2 && path.Base(value[1:len(value)-1]) == lib+".sol"
+}
+
+// declares reports whether the file declares something with the name, which
+// shadows the builtin.
+func declares(file *ast.File, name string) bool {
+	found := false
+	ast.Inspect(file, func(node ast.Node) bool {
+		var ident *ast.Identifier
+		switch n := node.(type) {
+		case *ast.VariableDeclaration:
+			ident = n.Name
+		case *ast.Param:
+			ident = n.Name
+		case *ast.FunctionDeclaration:
+			ident = n.Name
+		}
+		found = found || (ident != nil && ident.Name == name)
+		return !found
+	})
+	return found
+}
+
+func declaresContract(file *ast.File) bool {
+	for _, decl := range file.Declarations {
+		if c, ok := decl.(*ast.ContractDeclaration); ok && c.Kind == token.CONTRACT {
+			return true
+		}
+	}
+	return false
+}
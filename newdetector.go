@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"solbot/analyzer"
+	"strings"
+	"text/template"
+)
+
+// detectorCode is the code of a rule e.g. "tx-origin".
+var detectorCode = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
+// startNewDetector writes the skeleton of a detector: the package with the
+// detector, a fixture and a test running it with the detectortest harness
+// e.g.
+//
+//	solbot new-detector tx-origin
+//
+// creates analyzer/txorigin. The existing files are not overwritten.
+func startNewDetector(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("new-detector", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, "Usage: solbot new-detector [--dir dir] <code>\n\nThe code names the rule in kebab case e.g. tx-origin.\n\n")
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", "analyzer", "Directory of the detector packages")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	code := fs.Arg(0)
+	if !detectorCode.MatchString(code) {
+		fmt.Fprintf(stderr, "Invalid code `%s`: expected lowercase words separated by hyphens e.g. tx-origin\n", code)
+		return 2
+	}
+	if _, ok := analyzer.LookupRule(code); ok {
+		fmt.Fprintf(stderr, "Error: the code `%s` is taken by a built-in detector\n", code)
+		return 1
+	}
+
+	pkg := strings.ReplaceAll(code, "-", "")
+	data := struct{ Code, Package, Fixture string }{code, pkg, pkg + ".sol"}
+	files := []struct {
+		path   string
+		templ  *template.Template
+		source bool // is it Go code to format?
+	}{
+		{filepath.Join(*dir, pkg, "detector.go"), detectorTemplate, true},
+		{filepath.Join(*dir, pkg, "detector_test.go"), detectorTestTemplate, true},
+		{filepath.Join(*dir, pkg, "testdata", data.Fixture), fixtureTemplate, false},
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f.path); err == nil {
+			fmt.Fprintf(stderr, "Error: %s already exists\n", f.path)
+			return 1
+		}
+	}
+	for _, f := range files {
+		var b bytes.Buffer
+		if err := f.templ.Execute(&b, data); err != nil {
+			fmt.Fprintf(stderr, "Error generating %s: %s\n", f.path, err)
+			return 1
+		}
+		content := b.Bytes()
+		if f.source {
+			formatted, err := format.Source(content)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error formatting %s: %s\n", f.path, err)
+				return 1
+			}
+			content = formatted
+		}
+		if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+			fmt.Fprintf(stderr, "Error creating %s: %s\n", filepath.Dir(f.path), err)
+			return 1
+		}
+		if err := os.WriteFile(f.path, content, 0644); err != nil {
+			fmt.Fprintf(stderr, "Error writing %s: %s\n", f.path, err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Created %s\n", f.path)
+	}
+	fmt.Fprintf(stdout, "\nAdd &%s.Detector{} to GetAllDetectors in analyzer/analyzer.go, or register it with "+
+		"analyzer.RegisterDetector in an init function of the package.\n", pkg)
+	return 0
+}
+
+var detectorTemplate = template.Must(template.New("detector").Parse(`// {{ .Package }} detects @TODO: describe the problem.
+package {{ .Package }}
+
+import (
+	"solbot/ast"
+	"solbot/reporter"
+)
+
+const (
+	title          = "@TODO: one-line description"
+	severity       = "Warning"
+	descTempl      = "@TODO: what is wrong with the code: {{"{{"}} range .Locations {{"}}"}}\n- {{"{{"}} .Context {{"}}"}}{{"{{"}} end {{"}}"}}"
+	recommendation = "@TODO: how to fix it."
+)
+
+var rule = reporter.Rule{
+	Code:        "{{ .Code }}",
+	Title:       title,
+	Explanation: "@TODO: why the code is a problem.",
+	Scenario:    "@TODO: a concrete exploit or failure caused by the code.",
+	Remediation: recommendation,
+	References:  []string{"@TODO: e.g. SWC-101 or a link"},
+	Confidence:  reporter.Medium,
+}
+
+type Detector struct{}
+
+func (*Detector) Rule() reporter.Rule { return rule }
+
+func (*Detector) Detect(node ast.Node) *reporter.Finding {
+	file, ok := node.(*ast.File)
+	if !ok {
+		return nil
+	}
+
+	locations := []reporter.Location{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		// @TODO: Report the matching nodes, with solbot/token imported e.g.
+		//
+		//	locations = append(locations, reporter.Location{
+		//		Position: token.Position{Offset: node.Start()},
+		//		Context:  "the problem in the words of the user",
+		//	})
+		return true
+	})
+	if len(locations) == 0 {
+		return nil
+	}
+	return &reporter.Finding{
+		Title:          title,
+		Severity:       severity,
+		Description:    reporter.GenerateCustomDescription(descTempl, locations),
+		Recommendation: recommendation,
+		Locations:      locations,
+	}
+}
+`))
+
+var detectorTestTemplate = template.Must(template.New("test").Parse(`package {{ .Package }}
+
+import (
+	"solbot/analyzer/detectortest"
+	"testing"
+)
+
+func Test_Detect(t *testing.T) {
+	// @TODO: Add the expected findings, by the line of the fixture and a
+	// substring of the context e.g. {Line: 7, Contains: "` + "`withdraw`" + `"}.
+	detectortest.Run(t, &Detector{}, "testdata/{{ .Fixture }}", []detectortest.Finding{})
+}
+`))
+
+var fixtureTemplate = template.Must(template.New("fixture").Parse(`pragma solidity ^0.8.0;
+
+// The code {{ .Code }} finds, marked with "// match", and the code it
+// must not find.
+contract Fixture {
+    function f() external {}
+}
+`))
@@ -0,0 +1,8 @@
+//go:build solbot_assert
+
+package parser
+
+// assertions enables the checks of the invariants of the parsing loops,
+// see checkProgress. The tests of the parse functions run with
+// `go test -tags solbot_assert`.
+const assertions = true
@@ -0,0 +1,855 @@
+package parser
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/token"
+	"strings"
+)
+
+func (p *Parser) parseSourceUnitDeclaration() ast.Declaration {
+	if p.trace {
+		defer un(trace("parseSourceUnitDeclaration"))
+	}
+	p.unpoison()
+	switch tkType := p.currTkn.Type; {
+	case tkType == token.PRAGMA:
+		return toDeclaration(p.parsePragmaDirective())
+	case tkType == token.IMPORT:
+		return toDeclaration(p.parseImportDirective())
+	case tkType == token.ABSTRACT || tkType == token.CONTRACT ||
+		tkType == token.INTERFACE || tkType == token.LIBRARY:
+		return toDeclaration(p.parseContractDeclaration())
+	default:
+		return p.parseDeclaration()
+	}
+}
+
+// toDeclaration turns a nil pointer returned by one of the parse functions
+// into a nil interface, the same as toStatement.
+func toDeclaration[D any, PD interface {
+	*D
+	ast.Declaration
+}](decl PD) ast.Declaration {
+	if decl == nil {
+		return nil
+	}
+	return decl
+}
+
+// parseDeclaration parses declarations that can appear both at the file level
+// and inside of contracts. The file level allows only constant variables, but
+// we don't enforce it here.
+func (p *Parser) parseDeclaration() ast.Declaration {
+	if p.trace {
+		defer un(trace("parseDeclaration"))
+	}
+	p.unpoison()
+	switch tkType := p.currTkn.Type; {
+	case tkType == token.FUNCTION || tkType == token.CONSTRUCTOR ||
+		tkType == token.FALLBACK || tkType == token.RECEIVE:
+		return toDeclaration(p.parseFunctionDeclaration())
+	case tkType == token.MODIFIER:
+		return toDeclaration(p.parseModifierDeclaration())
+	case tkType == token.EVENT:
+		return toDeclaration(p.parseEventDeclaration())
+	case tkType == token.STRUCT:
+		return toDeclaration(p.parseStructDeclaration())
+	case tkType == token.ENUM:
+		return toDeclaration(p.parseEnumDeclaration())
+	case tkType == token.USING:
+		return toDeclaration(p.parseUsingForDirective())
+	case tkType == token.TYPE && p.peekTknIs(token.IDENTIFIER):
+		return toDeclaration(p.parseTypeDeclaration())
+	case p.currIdentIs("error") && p.peekTknIs(token.IDENTIFIER):
+		return toDeclaration(p.parseErrorDeclaration())
+	case token.IsElementaryType(tkType) || tkType == token.IDENTIFIER ||
+		tkType == token.MAPPING:
+		return toDeclaration(p.parseVariableDeclaration())
+	default:
+		msg := fmt.Sprintf("unexpected token: %s (at offset: %d)",
+			p.currTkn.Type.String(), p.currTkn.Pos)
+		p.error(p.currTkn.Pos, msg)
+		return nil
+	}
+}
+
+// pragma solidity ^0.8.0;
+// pragma experimental ABIEncoderV2;
+// pragma abicoder v2;
+// The value is kept as written, the lexer is not aware of version numbers.
+// The argument is the value without the quotes of the experimental features
+// e.g. `pragma experimental "SMTChecker";`.
+func (p *Parser) parsePragmaDirective() *ast.PragmaDirective {
+	if p.trace {
+		defer un(trace("parsePragmaDirective"))
+	}
+	decl := &ast.PragmaDirective{Pragma: p.currTkn.Pos}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	decl.Name = p.newIdentifier()
+	decl.Kind = ast.PragmaKindOf(decl.Name.Name)
+
+	for !p.peekTknIs(token.SEMICOLON) && !p.peekTknIs(token.EOF) {
+		p.nextToken()
+	}
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+
+	decl.Semicolon = p.currTkn.Pos
+	decl.Value = strings.TrimSpace(p.file.Src()[decl.Name.End():decl.Semicolon])
+	decl.Argument = decl.Value
+	if decl.Kind == ast.ExperimentalPragma && len(decl.Value) >= 2 && strings.ContainsRune(`"'`, rune(decl.Value[0])) {
+		decl.Argument = strings.Trim(decl.Value, `"'`)
+	}
+	return decl
+}
+
+func (p *Parser) parseImportDirective() *ast.ImportDirective {
+	if p.trace {
+		defer un(trace("parseImportDirective"))
+	}
+	decl := &ast.ImportDirective{Import: p.currTkn.Pos}
+
+	switch {
+	case p.peekTknIs(token.STRING_LITERAL):
+		// import "./Foo.sol" (as Foo)?;
+		p.nextToken()
+		decl.Path = p.parseStringLiteral()
+		if p.peekTknIs(token.AS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENTIFIER) {
+				return nil
+			}
+			decl.Alias = p.newIdentifier()
+		}
+	case p.peekTknIs(token.MUL):
+		// import * as Foo from "./Foo.sol";
+		p.nextToken()
+		if !p.expectPeek(token.AS) || !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		decl.Alias = p.newIdentifier()
+		if !p.parseImportFrom(decl) {
+			return nil
+		}
+	case p.peekTknIs(token.LBRACE):
+		// import {A, B as C} from "./Foo.sol";
+		p.nextToken()
+		decl.Symbols = []*ast.ImportSymbol{}
+		for {
+			if !p.expectPeek(token.IDENTIFIER) {
+				return nil
+			}
+			symbol := &ast.ImportSymbol{Name: p.newIdentifier()}
+			if p.peekTknIs(token.AS) {
+				p.nextToken()
+				if !p.expectPeek(token.IDENTIFIER) {
+					return nil
+				}
+				symbol.Alias = p.newIdentifier()
+			}
+			decl.Symbols = append(decl.Symbols, symbol)
+
+			if !p.peekTknIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.RBRACE) || !p.parseImportFrom(decl) {
+			return nil
+		}
+	default:
+		p.peekError(token.STRING_LITERAL)
+		return nil
+	}
+
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+	decl.Semicolon = p.currTkn.Pos
+	return decl
+}
+
+// parseImportFrom parses the `from "./Foo.sol"` part of the import directive.
+// The "from" word is not a keyword.
+func (p *Parser) parseImportFrom(decl *ast.ImportDirective) bool {
+	if !p.expectPeek(token.IDENTIFIER) {
+		return false
+	}
+	if !p.currIdentIs("from") {
+		msg := fmt.Sprintf("expected \"from\", got: %s instead (at offset: %d)",
+			p.currTkn.Literal, p.currTkn.Pos)
+		p.error(p.currTkn.Pos, msg)
+		return false
+	}
+	if !p.expectPeek(token.STRING_LITERAL) {
+		return false
+	}
+	decl.Path = p.parseStringLiteral()
+	return true
+}
+
+func (p *Parser) parseContractDeclaration() *ast.ContractDeclaration {
+	if p.trace {
+		defer un(trace("parseContractDeclaration"))
+	}
+	decl := &ast.ContractDeclaration{Contract: p.currTkn.Pos}
+
+	// 1. Optional abstract keyword and the contract kind
+	if p.currTknIs(token.ABSTRACT) {
+		decl.Abstract = true
+		if !p.expectPeek(token.CONTRACT) {
+			return nil
+		}
+	}
+	decl.Kind = p.currTkn.Type
+
+	// 2. Contract name
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	decl.Name = p.newIdentifier()
+
+	// 3. Inheritance specifiers
+	if p.peekTknIs(token.IS) {
+		p.nextToken()
+		for {
+			if !p.expectPeek(token.IDENTIFIER) {
+				return nil
+			}
+			base := &ast.InheritanceSpecifier{Name: p.parseIdentifierPath()}
+			if p.peekTknIs(token.LPAREN) {
+				p.nextToken()
+				base.Lparen = p.currTkn.Pos
+				base.Args = p.parseExpressionList(token.RPAREN)
+				if base.Args == nil {
+					return nil
+				}
+				base.Rparen = p.listEnd(base.Lparen, base.Args)
+			}
+			decl.Bases = append(decl.Bases, base)
+
+			if !p.peekTknIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
+	// 4. Contract body
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	decl.LeftBrace = p.currTkn.Pos
+	decl.Body = []ast.Declaration{}
+	p.contract = decl.Name
+	defer func() { p.contract = nil }()
+	p.nextToken()
+
+	var check loopCheck
+	for !p.currTknIs(token.RBRACE) && !p.currTknIs(token.EOF) {
+		if assertions {
+			p.checkProgress(&check, "parseContractDeclaration")
+		}
+		if !p.countDeclaration() {
+			break
+		}
+		member := p.parseDeclaration()
+		if member != nil {
+			decl.Body = append(decl.Body, member)
+		} else if p.synchronize() {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.currTknIs(token.RBRACE) && p.halted {
+		// The contract is closed where the parsing stopped.
+		decl.RightBrace = p.currTkn.Pos
+		return decl
+	}
+	if !p.currTknIs(token.RBRACE) {
+		p.error(p.currTkn.Pos, "expected } at the end of the contract body")
+		return nil
+	}
+	decl.RightBrace = p.currTkn.Pos
+	return decl
+}
+
+// parseFunctionDeclaration parses functions, constructors, fallback and
+// receive functions. All of them share the same structure.
+func (p *Parser) parseFunctionDeclaration() *ast.FunctionDeclaration {
+	if p.trace {
+		defer un(trace("parseFunctionDeclaration"))
+	}
+	decl := &ast.FunctionDeclaration{Kind: p.currTkn.Type}
+
+	// 1. Function keyword
+	fnType := &ast.FunctionType{}
+	fnType.Func = p.currTkn.Pos
+	decl.Type = fnType
+
+	// 2. Function identifier. The legacy fallback functions are unnamed and
+	// the legacy constructors are named after the contract.
+	switch {
+	case decl.Kind != token.FUNCTION:
+	case p.peekTknIs(token.LPAREN):
+		p.legacyConstruct(fnType.Func, "unnamed fallback function", "`fallback`")
+		decl.Kind = token.FALLBACK
+		decl.Legacy = true
+	default:
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		decl.Name = p.newIdentifier()
+		if p.contract != nil && decl.Name.Name == p.contract.Name {
+			p.legacyConstruct(decl.Name.Start(), "constructor named after the contract", "`constructor`")
+			decl.Kind = token.CONSTRUCTOR
+			decl.Legacy = true
+		}
+	}
+
+	// 3. ( Param List )
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	fnType.Params = p.parseParamList()
+	if fnType.Params == nil {
+		return nil
+	}
+
+	// 4. Visibility, State Mutability, Modifier Invocation, Override, Virtual
+	for {
+		switch tkType := p.peekTkn.Type; {
+		case isVisibility(tkType):
+			p.nextToken()
+			fnType.Visibility = toVisibility(tkType)
+			continue
+		case tkType == token.PURE || tkType == token.VIEW || tkType == token.PAYABLE:
+			p.nextToken()
+			fnType.Mutability = toMutability(tkType)
+			continue
+		case tkType == token.CONSTANT:
+			p.nextToken()
+			p.legacyConstruct(p.currTkn.Pos, "`constant` function", "`view`")
+			fnType.Mutability = ast.View
+			fnType.Constant = p.currTkn.Pos
+			continue
+		case tkType == token.VIRTUAL:
+			p.nextToken()
+			decl.Virtual = true
+			continue
+		case tkType == token.OVERRIDE:
+			p.nextToken()
+			decl.Override = p.parseOverrideSpecifier()
+			if decl.Override == nil {
+				return nil
+			}
+			continue
+		case tkType == token.IDENTIFIER:
+			p.nextToken()
+			mod := p.parseModifierInvocation()
+			if mod == nil {
+				return nil
+			}
+			decl.Modifiers = append(decl.Modifiers, mod)
+			continue
+		}
+		break
+	}
+
+	// The legacy functions are public by default.
+	if p.legacy && fnType.Visibility == 0 {
+		fnType.Visibility = ast.Public
+		fnType.Implicit = true
+	}
+
+	// 5. Returns ( Param List )
+	if p.peekTknIs(token.RETURNS) {
+		p.nextToken()
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		fnType.Results = p.parseParamList()
+		if fnType.Results == nil {
+			return nil
+		}
+	}
+
+	// 6. Semicolon or the body block
+	if p.peekTknIs(token.SEMICOLON) {
+		p.nextToken()
+		decl.Semicolon = p.currTkn.Pos
+		return decl
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	decl.Body = p.parseBlockStatement()
+	if decl.Body == nil {
+		return nil
+	}
+	return decl
+}
+
+// parseParamList parses parameters of functions, events, errors etc. We are
+// sitting on the opening parenthesis.
+func (p *Parser) parseParamList() *ast.ParamList {
+	if p.trace {
+		defer un(trace("parseParamList"))
+	}
+	params := &ast.ParamList{}
+	params.Opening = p.currTkn.Pos
+
+	if p.peekTknIs(token.RPAREN) {
+		p.nextToken()
+		params.Closing = p.currTkn.Pos
+		return params
+	}
+
+	for {
+		p.nextToken()
+		param := &ast.Param{}
+		param.Type = p.parseTypeName()
+		if param.Type == nil {
+			return nil
+		}
+		if p.peekTknIs(token.INDEXED) {
+			p.nextToken()
+			param.Indexed = true
+		}
+		if isDataLocation(p.peekTkn.Type) {
+			p.nextToken()
+			param.Location = toDataLocation(p.currTkn.Type)
+		}
+		if p.peekTknIs(token.IDENTIFIER) {
+			p.nextToken()
+			param.Name = p.newIdentifier()
+		}
+		params.List = append(params.List, param)
+
+		if !p.peekTknIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+		// The parameters being typed e.g. `(uint256 a,, uint256 b)` or
+		// `(uint256 a, )` are reported, and the rest is kept.
+		for p.peekTknIs(token.COMMA) {
+			msg := fmt.Sprintf("missing parameter before the comma (at offset: %d)", p.peekTkn.Pos)
+			p.error(p.peekTkn.Pos, msg)
+			p.nextToken()
+		}
+		if p.peekTknIs(token.RPAREN) {
+			msg := fmt.Sprintf("unexpected trailing comma (at offset: %d)", p.currTkn.Pos)
+			p.error(p.currTkn.Pos, msg)
+			break
+		}
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	params.Closing = p.currTkn.Pos
+	return params
+}
+
+// parseModifierInvocation parses e.g. `onlyOwner` or `onlyRole(ADMIN)`.
+// We are sitting on the first identifier of the modifier name.
+func (p *Parser) parseModifierInvocation() *ast.ModifierInvocation {
+	mod := &ast.ModifierInvocation{Name: p.parseIdentifierPath()}
+	if p.peekTknIs(token.LPAREN) {
+		p.nextToken()
+		mod.Lparen = p.currTkn.Pos
+		mod.Args = p.parseExpressionList(token.RPAREN)
+		if mod.Args == nil {
+			return nil
+		}
+		mod.Rparen = p.listEnd(mod.Lparen, mod.Args)
+	}
+	return mod
+}
+
+// parseOverrideSpecifier parses `override` or `override(A, B)`. We are
+// sitting on the override keyword.
+func (p *Parser) parseOverrideSpecifier() *ast.OverrideSpecifier {
+	spec := &ast.OverrideSpecifier{Override: p.currTkn.Pos}
+	if !p.peekTknIs(token.LPAREN) {
+		return spec
+	}
+	p.nextToken()
+	for {
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		spec.Bases = append(spec.Bases, p.parseIdentifierPath())
+		if !p.peekTknIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	spec.Rparen = p.currTkn.Pos
+	return spec
+}
+
+func (p *Parser) parseModifierDeclaration() *ast.ModifierDeclaration {
+	if p.trace {
+		defer un(trace("parseModifierDeclaration"))
+	}
+	decl := &ast.ModifierDeclaration{Modifier: p.currTkn.Pos}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	decl.Name = p.newIdentifier()
+
+	// The parameter list is optional for modifiers.
+	if p.peekTknIs(token.LPAREN) {
+		p.nextToken()
+		decl.Params = p.parseParamList()
+		if decl.Params == nil {
+			return nil
+		}
+	}
+
+	for {
+		if p.peekTknIs(token.VIRTUAL) {
+			p.nextToken()
+			decl.Virtual = true
+			continue
+		}
+		if p.peekTknIs(token.OVERRIDE) {
+			p.nextToken()
+			decl.Override = p.parseOverrideSpecifier()
+			if decl.Override == nil {
+				return nil
+			}
+			continue
+		}
+		break
+	}
+
+	if p.peekTknIs(token.SEMICOLON) {
+		p.nextToken()
+		decl.Semicolon = p.currTkn.Pos
+		return decl
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	decl.Body = p.parseBlockStatement()
+	if decl.Body == nil {
+		return nil
+	}
+	return decl
+}
+
+func (p *Parser) parseEventDeclaration() *ast.EventDeclaration {
+	if p.trace {
+		defer un(trace("parseEventDeclaration"))
+	}
+	decl := &ast.EventDeclaration{Event: p.currTkn.Pos}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	decl.Name = p.newIdentifier()
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	decl.Params = p.parseParamList()
+	if decl.Params == nil {
+		return nil
+	}
+
+	if p.peekTknIs(token.ANONYMOUS) {
+		p.nextToken()
+		decl.Anonymous = true
+	}
+
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+	decl.Semicolon = p.currTkn.Pos
+	return decl
+}
+
+// error InsufficientBalance(uint256 available, uint256 required);
+func (p *Parser) parseErrorDeclaration() *ast.ErrorDeclaration {
+	if p.trace {
+		defer un(trace("parseErrorDeclaration"))
+	}
+	decl := &ast.ErrorDeclaration{Error: p.currTkn.Pos}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	decl.Name = p.newIdentifier()
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	decl.Params = p.parseParamList()
+	if decl.Params == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+	decl.Semicolon = p.currTkn.Pos
+	return decl
+}
+
+func (p *Parser) parseStructDeclaration() *ast.StructDeclaration {
+	if p.trace {
+		defer un(trace("parseStructDeclaration"))
+	}
+	decl := &ast.StructDeclaration{Struct: p.currTkn.Pos}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	decl.Name = p.newIdentifier()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	decl.LeftBrace = p.currTkn.Pos
+	decl.Members = []*ast.VariableDeclaration{}
+
+	for !p.peekTknIs(token.RBRACE) && !p.peekTknIs(token.EOF) {
+		p.nextToken()
+		member := &ast.VariableDeclaration{}
+		member.Type = p.parseTypeName()
+		if member.Type == nil {
+			return nil
+		}
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		member.Name = p.newIdentifier()
+		if !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+		decl.Members = append(decl.Members, member)
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	decl.RightBrace = p.currTkn.Pos
+	return decl
+}
+
+func (p *Parser) parseEnumDeclaration() *ast.EnumDeclaration {
+	if p.trace {
+		defer un(trace("parseEnumDeclaration"))
+	}
+	decl := &ast.EnumDeclaration{Enum: p.currTkn.Pos}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	decl.Name = p.newIdentifier()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	decl.LeftBrace = p.currTkn.Pos
+	decl.Members = []*ast.Identifier{}
+
+	for !p.peekTknIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		decl.Members = append(decl.Members, p.newIdentifier())
+		if !p.peekTknIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	decl.RightBrace = p.currTkn.Pos
+	return decl
+}
+
+// type Price is uint128;
+func (p *Parser) parseTypeDeclaration() *ast.TypeDeclaration {
+	if p.trace {
+		defer un(trace("parseTypeDeclaration"))
+	}
+	decl := &ast.TypeDeclaration{Type: p.currTkn.Pos}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	decl.Name = p.newIdentifier()
+
+	if !p.expectPeek(token.IS) {
+		return nil
+	}
+	p.nextToken()
+	decl.Underlying = p.parseTypeName()
+	if decl.Underlying == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+	decl.Semicolon = p.currTkn.Pos
+	return decl
+}
+
+// using SafeMath for uint256;
+// using {add, sub as -} for Fixed global;
+func (p *Parser) parseUsingForDirective() *ast.UsingForDirective {
+	if p.trace {
+		defer un(trace("parseUsingForDirective"))
+	}
+	decl := &ast.UsingForDirective{Using: p.currTkn.Pos}
+
+	if p.peekTknIs(token.LBRACE) {
+		p.nextToken()
+		decl.Functions = []ast.Expression{}
+		for {
+			if !p.expectPeek(token.IDENTIFIER) {
+				return nil
+			}
+			decl.Functions = append(decl.Functions, p.parseIdentifierPath())
+			// User defined operators e.g. `add as +`. We don't keep
+			// track of the operators yet.
+			if p.peekTknIs(token.AS) {
+				p.nextToken()
+				p.nextToken()
+			}
+			if !p.peekTknIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		decl.Library = p.parseIdentifierPath()
+	}
+
+	if !p.expectPeek(token.FOR) {
+		return nil
+	}
+
+	p.nextToken()
+	if !p.currTknIs(token.MUL) {
+		decl.Type = p.parseTypeName()
+		if decl.Type == nil {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	if p.currIdentIs("global") {
+		decl.Global = true
+		p.nextToken()
+	}
+
+	if !p.currTknIs(token.SEMICOLON) {
+		msg := fmt.Sprintf("expected ;, got: %s instead (at offset: %d)",
+			p.currTkn.Type.String(), p.currTkn.Pos)
+		p.error(p.currTkn.Pos, msg)
+		return nil
+	}
+	decl.Semicolon = p.currTkn.Pos
+	return decl
+}
+
+// parseVariableDeclaration parses state variables and constants declared at
+// the file level. We are sitting on the first token of the variable type.
+func (p *Parser) parseVariableDeclaration() *ast.VariableDeclaration {
+	if p.trace {
+		defer un(trace("parseVariableDeclaration"))
+	}
+	decl := &ast.VariableDeclaration{}
+
+	// Set default values so that we don't have nil pointer dereferences
+	decl.Constant = false
+
+	// We are sitting on the variable type e.g. address or uint256
+	decl.Type = p.parseTypeName()
+	if decl.Type == nil {
+		return nil
+	}
+
+	for {
+		switch tkType := p.peekTkn.Type; {
+		case isVisibility(tkType):
+			p.nextToken()
+			decl.Visibility = toVisibility(tkType)
+			continue
+		case tkType == token.CONSTANT:
+			p.nextToken()
+			decl.Constant = true
+			continue
+		case tkType == token.IMMUTABLE:
+			p.nextToken()
+			decl.Immutable = true
+			continue
+		case tkType == token.OVERRIDE:
+			p.nextToken()
+			decl.Override = p.parseOverrideSpecifier()
+			if decl.Override == nil {
+				return nil
+			}
+			continue
+		case p.peekIdentIs(token.TRANSIENT.String()):
+			p.nextToken()
+			// It's the name of the variable e.g. `uint256 transient;`.
+			if p.peekTknIs(token.SEMICOLON) || p.peekTknIs(token.ASSIGN) {
+				decl.Name = p.newIdentifier()
+				break
+			}
+			decl.Transient = p.currTkn.Pos
+			continue
+		}
+		break
+	}
+
+	if decl.Name == nil {
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+		decl.Name = p.newIdentifier()
+	}
+
+	if p.peekTknIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		decl.Value = p.parseExpression(LOWEST)
+		if decl.Value == nil {
+			return nil
+		}
+	}
+
+	// The variable declaration ends with a semicolon.
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+
+	return decl
+}
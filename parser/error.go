@@ -1,14 +1,25 @@
 package parser
 
-import "solbot/token"
+import (
+	"solbot/token"
+	"strings"
+)
 
 type Error struct {
-	Pos token.Pos
-	Msg string
+	Pos   token.Pos
+	Msg   string
+	Limit bool // the parsing hit one of the limits, see Limits
 }
 
 type ErrorList []Error
 
 func (el *ErrorList) Add(pos token.Pos, msg string) {
-	*el = append(*el, Error{pos, msg})
+	*el = append(*el, Error{Pos: pos, Msg: msg})
+}
+
+// Message returns the message without the offset, for the output which
+// shows the position on its own e.g. "expected type name, got: ( instead".
+func (e Error) Message() string {
+	msg, _, _ := strings.Cut(e.Msg, " (at offset:")
+	return msg
 }
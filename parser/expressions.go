@@ -0,0 +1,962 @@
+package parser
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/token"
+	"strings"
+)
+
+// Operator precedence based on the [Solidity docs]. The higher the value, the
+// stronger the operator binds.
+// [Solidity docs]: https://docs.soliditylang.org/en/latest/cheatsheet.html#order-of-precedence-of-operators
+const (
+	_ int = iota
+	LOWEST
+	ASSIGNMENT  // =, +=, -= etc. and the ternary operator
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
+	EQUALITY    // ==, !=
+	RELATIONAL  // <, >, <=, >=
+	BITWISE_OR  // |
+	BITWISE_XOR // ^
+	BITWISE_AND // &
+	SHIFT       // <<, >>
+	ADDITIVE    // +, -
+	MULTIPLICATIVE
+	EXPONENT // **
+	PREFIX   // -x, !x, ++x, delete x
+	POSTFIX  // x++, x(), x[], x.y
+)
+
+var precedences = map[token.TokenType]int{
+	token.ASSIGN:                ASSIGNMENT,
+	token.ASSIGN_BIT_OR:         ASSIGNMENT,
+	token.ASSIGN_BIT_XOR:        ASSIGNMENT,
+	token.ASSIGN_BIT_AND:        ASSIGNMENT,
+	token.ASSIGN_SHL:            ASSIGNMENT,
+	token.ASSIGN_SAR:            ASSIGNMENT,
+	token.ASSIGN_SHR:            ASSIGNMENT,
+	token.ASSIGN_ADD:            ASSIGNMENT,
+	token.ASSIGN_SUB:            ASSIGNMENT,
+	token.ASSIGN_MUL:            ASSIGNMENT,
+	token.ASSIGN_DIV:            ASSIGNMENT,
+	token.ASSIGN_MOD:            ASSIGNMENT,
+	token.CONDITIONAL:           ASSIGNMENT,
+	token.OR:                    LOGICAL_OR,
+	token.AND:                   LOGICAL_AND,
+	token.EQUAL:                 EQUALITY,
+	token.NOT_EQUAL:             EQUALITY,
+	token.LESS_THAN:             RELATIONAL,
+	token.GREATER_THAN:          RELATIONAL,
+	token.LESS_THAN_OR_EQUAL:    RELATIONAL,
+	token.GREATER_THAN_OR_EQUAL: RELATIONAL,
+	token.BIT_OR:                BITWISE_OR,
+	token.BIT_XOR:               BITWISE_XOR,
+	token.BIT_AND:               BITWISE_AND,
+	token.SHL:                   SHIFT,
+	token.SAR:                   SHIFT,
+	token.SHR:                   SHIFT,
+	token.ADD:                   ADDITIVE,
+	token.SUB:                   ADDITIVE,
+	token.MUL:                   MULTIPLICATIVE,
+	token.DIV:                   MULTIPLICATIVE,
+	token.MOD:                   MULTIPLICATIVE,
+	token.EXP:                   EXPONENT,
+	token.INC:                   POSTFIX,
+	token.DEC:                   POSTFIX,
+	token.LPAREN:                POSTFIX,
+	token.LBRACKET:              POSTFIX,
+	token.PERIOD:                POSTFIX,
+}
+
+func (p *Parser) registerExpressionParseFns() {
+	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.registerPrefix(token.IDENTIFIER, p.parseIdentifier)
+	p.registerPrefix(token.DECIMAL_NUMBER, p.parseNumberLiteral)
+	p.registerPrefix(token.HEX_NUMBER, p.parseNumberLiteral)
+	p.registerPrefix(token.STRING_LITERAL, p.parseStringLiteralExpression)
+	p.registerPrefix(token.HEX, p.parsePrefixedStringLiteral)
+	p.registerPrefix(token.UNICODE, p.parsePrefixedStringLiteral)
+	p.registerPrefix(token.TRUE_LITERAL, p.parseBooleanLiteral)
+	p.registerPrefix(token.FALSE_LITERAL, p.parseBooleanLiteral)
+	p.registerPrefix(token.NOT, p.parsePrefixExpression)
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
+	p.registerPrefix(token.SUB, p.parsePrefixExpression)
+	p.registerPrefix(token.INC, p.parsePrefixExpression)
+	p.registerPrefix(token.DEC, p.parsePrefixExpression)
+	p.registerPrefix(token.DELETE, p.parsePrefixExpression)
+	p.registerPrefix(token.LPAREN, p.parseTupleExpression)
+	p.registerPrefix(token.LBRACKET, p.parseInlineArrayExpression)
+	p.registerPrefix(token.NEW, p.parseNewExpression)
+	p.registerPrefix(token.MAPPING, p.parseMappingTypeExpression)
+	p.registerPrefix(token.FUNCTION, p.parseFunctionTypeExpression)
+	// payable(x) and type(C) look like function calls.
+	p.registerPrefix(token.PAYABLE, p.parseKeywordIdentifier)
+	p.registerPrefix(token.TYPE, p.parseKeywordIdentifier)
+
+	p.infixParseFns = make(map[token.TokenType]infixParseFn)
+	for tkType, precedence := range precedences {
+		switch {
+		case precedence == ASSIGNMENT:
+			p.registerInfix(tkType, p.parseAssignmentExpression)
+		case precedence < POSTFIX:
+			p.registerInfix(tkType, p.parseBinaryExpression)
+		}
+	}
+	p.registerInfix(token.CONDITIONAL, p.parseConditionalExpression)
+	p.registerInfix(token.INC, p.parsePostfixExpression)
+	p.registerInfix(token.DEC, p.parsePostfixExpression)
+	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.PERIOD, p.parseMemberAccessExpression)
+	p.registerInfix(token.LBRACE, p.parseCallOptionsExpression)
+}
+
+func (p *Parser) registerPrefix(tkType token.TokenType, fn prefixParseFn) {
+	p.prefixParseFns[tkType] = fn
+}
+
+func (p *Parser) registerInfix(tkType token.TokenType, fn infixParseFn) {
+	p.infixParseFns[tkType] = fn
+}
+
+func (p *Parser) peekPrecedence() int {
+	// Curly braces after an expression are call options only if they look
+	// like `{value: ...}`. Otherwise, it's a block e.g. `try foo() {`.
+	if p.peekTknIs(token.LBRACE) {
+		if p.lookahead(1).Type == token.IDENTIFIER &&
+			p.lookahead(2).Type == token.COLON {
+			return POSTFIX
+		}
+		return LOWEST
+	}
+	if p, ok := precedences[p.peekTkn.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) currPrecedence() int {
+	if p, ok := precedences[p.currTkn.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	if p.trace {
+		defer un(trace("parseExpression"))
+	}
+	p.count()
+	prefix := p.prefixParseFns[p.currTkn.Type]
+	if prefix == nil && token.IsElementaryType(p.currTkn.Type) {
+		// Elementary types are used in conversions e.g. uint256(x).
+		prefix = p.parseElementaryType
+	}
+	if prefix == nil {
+		p.noPrefixParseFnError(p.currTkn)
+		return nil
+	}
+	if p.limits.MaxExpressionDepth > 0 {
+		if p.exprDepth >= p.limits.MaxExpressionDepth {
+			return p.truncateExpression(p.currTkn.Pos)
+		}
+		p.exprDepth++
+		x := p.parseInfixExpressions(prefix(), precedence)
+		p.exprDepth--
+		return x
+	}
+
+	return p.parseInfixExpressions(prefix(), precedence)
+}
+
+// parseInfixExpressions continues parsing an expression with the given left
+// side. It's useful when we had to parse the left side ourselves e.g. when we
+// didn't know if it's a tuple or a declaration.
+//
+// Every operator wraps the left side in one more level, which counts
+// towards the expression depth until the end of the expression, see Limits.
+func (p *Parser) parseInfixExpressions(left ast.Expression, precedence int) ast.Expression {
+	depth := p.exprDepth
+	for left != nil && !p.peekTknIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+		infix := p.infixParseFns[p.peekTkn.Type]
+		if infix == nil {
+			break
+		}
+		p.nextToken()
+		if p.limits.MaxExpressionDepth > 0 {
+			if p.exprDepth >= p.limits.MaxExpressionDepth {
+				left = p.truncateExpression(left.Start())
+				break
+			}
+			p.exprDepth++
+		}
+		left = infix(left)
+	}
+	p.exprDepth = depth
+	return left
+}
+
+func (p *Parser) noPrefixParseFnError(tkn token.Token) {
+	msg := fmt.Sprintf("no prefix parse function for %s found (at offset: %d)",
+		tkn.Type.String(), tkn.Pos)
+	p.error(tkn.Pos, msg)
+}
+
+func (p *Parser) parseIdentifier() ast.Expression {
+	return p.newIdentifier()
+}
+
+// parseKeywordIdentifier parses keywords that are used like function names
+// e.g. payable(x) or type(C).max
+func (p *Parser) parseKeywordIdentifier() ast.Expression {
+	return &ast.Identifier{
+		NamePos: p.currTkn.Pos,
+		Name:    p.currTkn.Type.String(),
+	}
+}
+
+// parseIdentifierPath parses a dot separated path of identifiers e.g.
+// `Ownable` or `IERC20.Transfer`. We are sitting on the first identifier.
+func (p *Parser) parseIdentifierPath() ast.Expression {
+	var path ast.Expression = p.newIdentifier()
+	for p.peekTknIs(token.PERIOD) && p.lookahead(1).Type == token.IDENTIFIER {
+		p.nextToken()
+		p.nextToken()
+		path = &ast.MemberAccessExpression{
+			Expression: path,
+			Member:     p.newIdentifier(),
+		}
+	}
+	return path
+}
+
+// 1_000, 0xff, 1 ether, 2 days
+func (p *Parser) parseNumberLiteral() ast.Expression {
+	lit := &ast.BasicLit{
+		ValuePos: p.currTkn.Pos,
+		Kind:     p.currTkn.Type,
+		Value:    p.currTkn.Literal,
+	}
+	if token.IsSubdenomination(p.peekTkn.Type) {
+		p.nextToken()
+		if p.currTknIs(token.SUB_YEAR) {
+			p.legacyConstruct(p.currTkn.Pos, "`years`", "`365 days`")
+		}
+		lit.Unit = &ast.Identifier{
+			NamePos: p.currTkn.Pos,
+			Name:    p.currTkn.Literal,
+		}
+	}
+	return lit
+}
+
+func (p *Parser) parseStringLiteral() *ast.BasicLit {
+	return &ast.BasicLit{
+		ValuePos: p.currTkn.Pos,
+		Kind:     token.STRING_LITERAL,
+		Value:    p.currTkn.Literal,
+	}
+}
+
+func (p *Parser) parseStringLiteralExpression() ast.Expression {
+	return p.concatStringLiterals(p.parseStringLiteral())
+}
+
+func (p *Parser) parsePrefixedStringLiteral() ast.Expression {
+	lit := p.parsePrefixedStringPart()
+	if lit == nil {
+		return nil
+	}
+	return p.concatStringLiterals(lit)
+}
+
+// hex"deadbeef" and unicode"Hello 😃" are lexed as a keyword followed by
+// a string literal.
+func (p *Parser) parsePrefixedStringPart() *ast.BasicLit {
+	lit := &ast.BasicLit{
+		ValuePos: p.currTkn.Pos,
+		Kind:     token.HEX_STRING_LITERAL,
+	}
+	if p.currTknIs(token.UNICODE) {
+		lit.Kind = token.UNICODE_STRING_LITERAL
+	}
+	if !p.expectPeek(token.STRING_LITERAL) {
+		return nil
+	}
+	lit.Value = p.file.Src()[lit.ValuePos : int(p.currTkn.Pos)+len(p.currTkn.Literal)]
+	return lit
+}
+
+// concatStringLiterals concatenates the string literals following the
+// first one e.g. "a long " "message". Only the literals of the same kind
+// can be concatenated: a hex string next to a regular one is an error.
+func (p *Parser) concatStringLiterals(first *ast.BasicLit) ast.Expression {
+	parts := []*ast.BasicLit{first}
+	for p.peekTknIs(token.STRING_LITERAL) || p.peekTknIs(token.HEX) || p.peekTknIs(token.UNICODE) {
+		p.nextToken()
+		var part *ast.BasicLit
+		if p.currTknIs(token.STRING_LITERAL) {
+			part = p.parseStringLiteral()
+		} else if part = p.parsePrefixedStringPart(); part == nil {
+			return nil
+		}
+		if part.Kind != first.Kind {
+			p.error(part.ValuePos, fmt.Sprintf("cannot concatenate %s with %s (at offset: %d)",
+				stringKind(part.Kind), stringKind(first.Kind), part.ValuePos))
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 1 {
+		return first
+	}
+	return &ast.BasicLit{
+		ValuePos: first.ValuePos,
+		Kind:     first.Kind,
+		Value:    joinStringParts(parts),
+		Parts:    parts,
+	}
+}
+
+func stringKind(kind token.TokenType) string {
+	switch kind {
+	case token.HEX_STRING_LITERAL:
+		return "a hex string literal"
+	case token.UNICODE_STRING_LITERAL:
+		return "a unicode string literal"
+	}
+	return "a string literal"
+}
+
+// joinStringParts returns the value of the concatenated literal: the
+// contents of the parts in the prefix and the quotes of the first one. The
+// quotes of the first part are escaped in the parts quoted with the other
+// ones.
+func joinStringParts(parts []*ast.BasicLit) string {
+	first := parts[0].Value
+	open := strings.IndexAny(first, `"'`)
+	quote := first[open]
+	var b strings.Builder
+	b.WriteString(first[:open+1])
+	for _, part := range parts {
+		i := strings.IndexAny(part.Value, `"'`)
+		content := part.Value[i+1 : len(part.Value)-1]
+		if part.Value[i] == quote {
+			b.WriteString(content)
+			continue
+		}
+		for j := 0; j < len(content); j++ {
+			switch {
+			case content[j] == '\\' && j+1 < len(content):
+				b.WriteString(content[j : j+2])
+				j++
+			case content[j] == quote:
+				b.WriteByte('\\')
+				b.WriteByte(quote)
+			default:
+				b.WriteByte(content[j])
+			}
+		}
+	}
+	b.WriteByte(quote)
+	return b.String()
+}
+
+func (p *Parser) parseBooleanLiteral() ast.Expression {
+	return &ast.BasicLit{
+		ValuePos: p.currTkn.Pos,
+		Kind:     p.currTkn.Type,
+		Value:    p.currTkn.Literal,
+	}
+}
+
+// parseElementaryType parses e.g. uint256, bool or address payable.
+func (p *Parser) parseElementaryType() ast.Expression {
+	typ := &ast.ElementaryType{
+		ValuePos: p.currTkn.Pos,
+		Kind:     p.currTkn,
+		Value:    p.currTkn.Literal,
+	}
+	if p.currTknIs(token.ADDRESS) && p.peekTknIs(token.PAYABLE) {
+		p.nextToken()
+		typ.Payable = p.currTkn.Pos
+	}
+	return typ
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	if p.trace {
+		defer un(trace("parsePrefixExpression"))
+	}
+	expr := &ast.UnaryExpression{
+		OpPos:    p.currTkn.Pos,
+		Operator: p.currTkn.Type,
+	}
+
+	p.nextToken()
+	expr.Operand = p.parseExpression(PREFIX)
+	if expr.Operand == nil {
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) parsePostfixExpression(operand ast.Expression) ast.Expression {
+	return &ast.UnaryExpression{
+		OpPos:    p.currTkn.Pos,
+		Operator: p.currTkn.Type,
+		Operand:  operand,
+		Postfix:  true,
+	}
+}
+
+func (p *Parser) parseBinaryExpression(left ast.Expression) ast.Expression {
+	if p.trace {
+		defer un(trace("parseBinaryExpression"))
+	}
+	expr := &ast.BinaryExpression{
+		Left:     left,
+		OpPos:    p.currTkn.Pos,
+		Operator: p.currTkn.Type,
+	}
+
+	precedence := p.currPrecedence()
+	if expr.Operator == token.EXP {
+		// Exponentiation is right associative e.g. 2**3**2 == 2**(3**2)
+		precedence--
+	}
+	p.nextToken()
+	expr.Right = p.parseExpression(precedence)
+	if expr.Right == nil {
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
+	if p.trace {
+		defer un(trace("parseAssignmentExpression"))
+	}
+	expr := &ast.AssignmentExpression{
+		Left:     left,
+		OpPos:    p.currTkn.Pos,
+		Operator: p.currTkn.Type,
+	}
+
+	// Assignments are right associative e.g. a = b = c is a = (b = c)
+	p.nextToken()
+	expr.Right = p.parseExpression(ASSIGNMENT - 1)
+	if expr.Right == nil {
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	if p.trace {
+		defer un(trace("parseConditionalExpression"))
+	}
+	expr := &ast.ConditionalExpression{
+		Condition: condition,
+		Question:  p.currTkn.Pos,
+	}
+
+	p.nextToken()
+	expr.True = p.parseExpression(LOWEST)
+	if expr.True == nil || !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	expr.False = p.parseExpression(ASSIGNMENT - 1)
+	if expr.False == nil {
+		return nil
+	}
+	return expr
+}
+
+// parseTupleExpression parses tuples and parenthesized expressions e.g.
+// (a, b), (a, , b) or (a + b).
+func (p *Parser) parseTupleExpression() ast.Expression {
+	if p.trace {
+		defer un(trace("parseTupleExpression"))
+	}
+	tuple := &ast.TupleExpression{Lparen: p.currTkn.Pos}
+
+	if p.peekTknIs(token.RPAREN) {
+		p.nextToken()
+		tuple.Rparen = p.currTkn.Pos
+		return tuple
+	}
+
+	for {
+		if p.peekTknIs(token.COMMA) || p.peekTknIs(token.RPAREN) {
+			// A missing tuple component.
+			tuple.Elements = append(tuple.Elements, nil)
+		} else {
+			p.nextToken()
+			elem := p.parseExpression(LOWEST)
+			if elem == nil {
+				return nil
+			}
+			tuple.Elements = append(tuple.Elements, elem)
+		}
+
+		if !p.peekTknIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	tuple.Rparen = p.currTkn.Pos
+	return tuple
+}
+
+func (p *Parser) parseInlineArrayExpression() ast.Expression {
+	if p.trace {
+		defer un(trace("parseInlineArrayExpression"))
+	}
+	array := &ast.InlineArrayExpression{Lbracket: p.currTkn.Pos}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+	array.Rbracket = p.listEnd(array.Lbracket, array.Elements)
+	return array
+}
+
+// parseExpressionList parses comma separated expressions until the closing
+// token e.g. function call arguments. We are sitting on the opening token.
+// It returns an empty, non-nil slice if there are no expressions and nil in
+// case of an error.
+//
+// The lists being typed are tolerated, each with a single error: a missing
+// expression between the commas is replaced with an ast.BadExpression, a
+// trailing comma is skipped, and a list not closed before the end of the
+// statement is closed there. We are sitting on the last token of the list
+// then, rather than on the closing token, and the unclosed flag is set; see
+// listEnd.
+func (p *Parser) parseExpressionList(closing token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	p.unclosed = false
+	if p.peekTknIs(closing) {
+		p.nextToken()
+		return list
+	}
+
+	var check loopCheck
+	for !p.atStatementEnd() {
+		if assertions {
+			p.checkProgress(&check, "parseExpressionList")
+		}
+		switch {
+		case p.peekTknIs(token.COMMA):
+			msg := fmt.Sprintf("missing expression before the comma (at offset: %d)", p.peekTkn.Pos)
+			p.error(p.peekTkn.Pos, msg)
+			list = append(list, p.badExpression())
+		case p.peekTknIs(closing):
+			msg := fmt.Sprintf("unexpected trailing comma (at offset: %d)", p.currTkn.Pos)
+			p.error(p.currTkn.Pos, msg)
+			p.nextToken()
+			return list
+		default:
+			p.nextToken()
+			expr := p.parseExpression(LOWEST)
+			if expr == nil {
+				return nil
+			}
+			list = append(list, expr)
+		}
+
+		if !p.peekTknIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+		if p.atStatementEnd() {
+			// The last expression is still being typed e.g. `f(a, `.
+			list = append(list, p.badExpression())
+		}
+	}
+
+	if p.atStatementEnd() {
+		// The list closed at the end of its last expression is reported
+		// already e.g. `f(g(a, `.
+		if !p.unclosed {
+			msg := fmt.Sprintf("expected next token to be: %s, got: %s instead (at offset: %d)",
+				closing.String(), p.peekTkn.Type.String(), p.peekTkn.Pos)
+			p.error(p.peekTkn.Pos, msg)
+		}
+		p.unclosed = true
+		return list
+	}
+	if !p.expectPeek(closing) {
+		return nil
+	}
+	return list
+}
+
+// listEnd returns the position of the token closing the list just parsed
+// with parseExpressionList; or the end of the list if it was closed at the
+// end of the statement, where the closing token is expected.
+func (p *Parser) listEnd(opening token.Pos, list []ast.Expression) token.Pos {
+	switch {
+	case !p.unclosed:
+		return p.currTkn.Pos
+	case len(list) == 0:
+		return opening + 1
+	}
+	return list[len(list)-1].End()
+}
+
+// badExpression returns the placeholder of the expression missing between
+// the current token and the next one.
+func (p *Parser) badExpression() *ast.BadExpression {
+	return &ast.BadExpression{From: p.currTkn.Pos + 1, To: p.peekTkn.Pos}
+}
+
+// atStatementEnd reports whether the next token ends the statement being
+// parsed, so that the unclosed lists are closed before it.
+func (p *Parser) atStatementEnd() bool {
+	return p.peekTknIs(token.SEMICOLON) || p.peekTknIs(token.RBRACE) || p.peekTknIs(token.EOF)
+}
+
+// new Vault(owner) or new uint256[](length)
+func (p *Parser) parseNewExpression() ast.Expression {
+	if p.trace {
+		defer un(trace("parseNewExpression"))
+	}
+	expr := &ast.NewExpression{New: p.currTkn.Pos}
+
+	p.nextToken()
+	expr.Type = p.parseTypeName()
+	if expr.Type == nil {
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) parseMappingTypeExpression() ast.Expression {
+	if typ := p.parseMappingType(); typ != nil {
+		return typ
+	}
+	return nil
+}
+
+func (p *Parser) parseFunctionTypeExpression() ast.Expression {
+	if typ := p.parseFunctionType(); typ != nil {
+		return typ
+	}
+	return nil
+}
+
+// parseCallExpression parses the arguments of a function call. We are
+// sitting on the opening parenthesis.
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	if p.trace {
+		defer un(trace("parseCallExpression"))
+	}
+	call := &ast.CallExpression{
+		Function: function,
+		Lparen:   p.currTkn.Pos,
+	}
+
+	// Named arguments e.g. f({to: a, amount: 1})
+	if p.peekTknIs(token.LBRACE) {
+		p.nextToken()
+		call.Names, call.Args = p.parseNamedArguments()
+		if call.Args == nil || !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		call.Rparen = p.currTkn.Pos
+		return call
+	}
+
+	call.Args = p.parseExpressionList(token.RPAREN)
+	if call.Args == nil {
+		return nil
+	}
+	call.Rparen = p.listEnd(call.Lparen, call.Args)
+	return call
+}
+
+// parseNamedArguments parses `{name: value, ...}` used both for named
+// arguments and call options. We are sitting on the opening brace and
+// finish on the closing brace. The values are nil in case of an error.
+func (p *Parser) parseNamedArguments() ([]*ast.Identifier, []ast.Expression) {
+	names := []*ast.Identifier{}
+	values := []ast.Expression{}
+
+	var check loopCheck
+	for !p.peekTknIs(token.RBRACE) {
+		if assertions {
+			p.checkProgress(&check, "parseNamedArguments")
+		}
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil, nil
+		}
+		names = append(names, p.newIdentifier())
+		if !p.expectPeek(token.COLON) {
+			return nil, nil
+		}
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil, nil
+		}
+		values = append(values, value)
+
+		if !p.peekTknIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil, nil
+	}
+	return names, values
+}
+
+// to.call{value: amount}("")
+func (p *Parser) parseCallOptionsExpression(expr ast.Expression) ast.Expression {
+	if p.trace {
+		defer un(trace("parseCallOptionsExpression"))
+	}
+	opts := &ast.CallOptionsExpression{
+		Expression: expr,
+		Lbrace:     p.currTkn.Pos,
+	}
+	opts.Names, opts.Values = p.parseNamedArguments()
+	if opts.Values == nil {
+		return nil
+	}
+	opts.Rbrace = p.currTkn.Pos
+	return opts
+}
+
+// parseIndexExpression parses index access e.g. balances[owner], array
+// types e.g. uint256[] and index range access e.g. data[4:].
+func (p *Parser) parseIndexExpression(expr ast.Expression) ast.Expression {
+	if p.trace {
+		defer un(trace("parseIndexExpression"))
+	}
+	lbracket := p.currTkn.Pos
+
+	var from ast.Expression
+	if !p.peekTknIs(token.RBRACKET) && !p.peekTknIs(token.COLON) {
+		p.nextToken()
+		from = p.parseExpression(LOWEST)
+		if from == nil {
+			return nil
+		}
+	}
+
+	if p.peekTknIs(token.COLON) {
+		p.nextToken()
+		rng := &ast.IndexRangeAccessExpression{
+			Expression: expr,
+			Lbracket:   lbracket,
+			From:       from,
+		}
+		if !p.peekTknIs(token.RBRACKET) {
+			p.nextToken()
+			rng.To = p.parseExpression(LOWEST)
+			if rng.To == nil {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		rng.Rbracket = p.currTkn.Pos
+		return rng
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return &ast.IndexAccessExpression{
+		Expression: expr,
+		Lbracket:   lbracket,
+		Index:      from,
+		Rbracket:   p.currTkn.Pos,
+	}
+}
+
+func (p *Parser) parseMemberAccessExpression(expr ast.Expression) ast.Expression {
+	// Members are identifiers, but function pointers also have the
+	// `address` member e.g. this.foo.address
+	if p.peekTknIs(token.ADDRESS) {
+		p.nextToken()
+	} else if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	return &ast.MemberAccessExpression{
+		Expression: expr,
+		Member:     p.newIdentifier(),
+	}
+}
+
+/*~*~*~*~*~*~*~*~*~*~*~*~*~*~ Types ~*~*~*~*~*~*~*~*~*~*~*~*~*~*~*/
+
+// parseTypeName parses the type of a variable or a parameter. We are sitting
+// on the first token of the type.
+func (p *Parser) parseTypeName() ast.Expression {
+	if p.trace {
+		defer un(trace("parseTypeName"))
+	}
+	var typ ast.Expression
+	switch {
+	case token.IsElementaryType(p.currTkn.Type):
+		typ = p.parseElementaryType()
+	case p.currTknIs(token.MAPPING):
+		if mapping := p.parseMappingType(); mapping != nil {
+			typ = mapping
+		}
+	case p.currTknIs(token.FUNCTION):
+		if fn := p.parseFunctionType(); fn != nil {
+			typ = fn
+		}
+	case p.currTknIs(token.IDENTIFIER):
+		typ = p.parseIdentifierPath()
+	default:
+		msg := fmt.Sprintf("expected type name, got: %s instead (at offset: %d)",
+			p.currTkn.Type.String(), p.currTkn.Pos)
+		p.error(p.currTkn.Pos, msg)
+		return nil
+	}
+
+	// Arrays e.g. uint256[], address[10][]
+	for typ != nil && p.peekTknIs(token.LBRACKET) {
+		p.nextToken()
+		array := &ast.ArrayType{Elem: typ, Lbracket: p.currTkn.Pos}
+		if !p.peekTknIs(token.RBRACKET) {
+			p.nextToken()
+			array.Len = p.parseExpression(LOWEST)
+			if array.Len == nil {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		array.Rbracket = p.currTkn.Pos
+		typ = array
+	}
+	return typ
+}
+
+// mapping(address owner => uint256 balance)
+func (p *Parser) parseMappingType() *ast.MappingType {
+	if p.trace {
+		defer un(trace("parseMappingType"))
+	}
+	mapping := &ast.MappingType{Mapping: p.currTkn.Pos}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	mapping.Key = p.parseTypeName()
+	if mapping.Key == nil {
+		return nil
+	}
+	if p.peekTknIs(token.IDENTIFIER) {
+		p.nextToken()
+		mapping.KeyName = p.newIdentifier()
+	}
+
+	if !p.expectPeek(token.DOUBLE_ARROW) {
+		return nil
+	}
+
+	p.nextToken()
+	mapping.Value = p.parseTypeName()
+	if mapping.Value == nil {
+		return nil
+	}
+	if p.peekTknIs(token.IDENTIFIER) {
+		p.nextToken()
+		mapping.ValueName = p.newIdentifier()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	mapping.Rparen = p.currTkn.Pos
+	return mapping
+}
+
+// function (uint256) external view returns (bool)
+func (p *Parser) parseFunctionType() *ast.FunctionType {
+	if p.trace {
+		defer un(trace("parseFunctionType"))
+	}
+	fnType := &ast.FunctionType{Func: p.currTkn.Pos}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	fnType.Params = p.parseParamList()
+	if fnType.Params == nil {
+		return nil
+	}
+
+	for {
+		switch tkType := p.peekTkn.Type; {
+		case isVisibility(tkType):
+			p.nextToken()
+			fnType.Visibility = toVisibility(tkType)
+			continue
+		case tkType == token.PURE || tkType == token.VIEW || tkType == token.PAYABLE:
+			p.nextToken()
+			fnType.Mutability = toMutability(tkType)
+			continue
+		}
+		break
+	}
+
+	if p.peekTknIs(token.RETURNS) {
+		p.nextToken()
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		fnType.Results = p.parseParamList()
+		if fnType.Results == nil {
+			return nil
+		}
+	}
+	return fnType
+}
+
+// typeFromExpression converts an expression parsed before we knew that it's a
+// type into a type name e.g. in the `uint256[] memory x` statement the
+// `uint256[]` part is first parsed as an index access expression.
+func (p *Parser) typeFromExpression(expr ast.Expression) ast.Expression {
+	switch x := expr.(type) {
+	case *ast.Identifier, *ast.ElementaryType, *ast.MappingType,
+		*ast.FunctionType, *ast.ArrayType, *ast.MemberAccessExpression:
+		return x
+	case *ast.IndexAccessExpression:
+		elem := p.typeFromExpression(x.Expression)
+		if elem == nil {
+			return nil
+		}
+		return &ast.ArrayType{
+			Elem:     elem,
+			Lbracket: x.Lbracket,
+			Len:      x.Index,
+			Rbracket: x.Rbracket,
+		}
+	}
+	msg := fmt.Sprintf("expected type name, got: %T instead (at offset: %d)",
+		expr, expr.Start())
+	p.error(expr.Start(), msg)
+	return nil
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/token"
+)
+
+// The fragments are pieces of code without the surrounding declarations
+// e.g. the snippets typed in a REPL, the examples in the documentation or
+// the expressions evaluated by the tools. The positions are the offsets in
+// the fragment source.
+
+// ParseStatements parses the statements as if they were in a function
+// body. The last statement may end at the end of the source instead of a
+// semicolon e.g. `uint256 x = 1; x + 1`.
+func ParseStatements(src string) ([]ast.Statement, ErrorList) {
+	p := newFragmentParser(src)
+	stmts := []ast.Statement{}
+	var check loopCheck
+	for !p.currTknIs(token.EOF) {
+		if assertions {
+			p.checkProgress(&check, "ParseStatements")
+		}
+		stmt := p.parseStatement()
+		if stmt != nil {
+			stmts = append(stmts, stmt)
+		} else if p.synchronize() {
+			p.error(p.currTkn.Pos, "unexpected } outside of a block")
+		}
+		p.nextToken()
+	}
+	return stmts, p.errors
+}
+
+// ParseExpression parses a single expression e.g. `x + 1 days`. The
+// expression may be followed by a semicolon.
+func ParseExpression(src string) (ast.Expression, ErrorList) {
+	p := newFragmentParser(src)
+	x := p.parseExpression(LOWEST)
+	if x == nil {
+		return nil, p.errors
+	}
+	if p.peekTknIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if !p.peekTknIs(token.EOF) {
+		msg := fmt.Sprintf("expected the end of the expression, got: %s instead (at offset: %d)",
+			p.peekTkn.Type.String(), p.peekTkn.Pos)
+		p.error(p.peekTkn.Pos, msg)
+		return nil, p.errors
+	}
+	return x, p.errors
+}
+
+func newFragmentParser(src string) *Parser {
+	p := &Parser{}
+	p.Init(token.NewFile("fragment", src))
+	p.fragment = true
+	return p
+}
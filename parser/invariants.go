@@ -0,0 +1,26 @@
+package parser
+
+import "fmt"
+
+// The invariants of the parsing loops, checked when the parser is built
+// with the assertions, see assertions. A loop going through an iteration
+// without consuming a token never ends: the next iteration starts on the
+// same token and does the same. A broken invariant panics with the token
+// the loop got stuck at.
+
+// loopCheck is the state of the progress check of a loop, see
+// checkProgress. The zero value is ready to use.
+type loopCheck struct {
+	started  bool
+	consumed int // the tokens consumed before the last iteration
+}
+
+// checkProgress panics if the loop didn't consume a token since its last
+// iteration. It's called at the start of every iteration.
+func (p *Parser) checkProgress(c *loopCheck, name string) {
+	if c.started && p.consumed == c.consumed {
+		panic(fmt.Sprintf("parser: %s went through an iteration without consuming a token, stuck at %s (offset %d)",
+			name, p.currTkn.Type, p.currTkn.Pos))
+	}
+	c.started, c.consumed = true, p.consumed
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"solbot/token"
+	"testing"
+)
+
+func Test_CheckProgressOfStuckLoop(t *testing.T) {
+	p := Parser{}
+	p.Init(token.NewFile("test.sol", "contract C { uint256 x; }"))
+
+	var check loopCheck
+	p.checkProgress(&check, "parseLoop")
+	p.consumed++ // a token consumed by the iteration
+	p.checkProgress(&check, "parseLoop")
+
+	defer func() {
+		expected := "parser: parseLoop went through an iteration without consuming a token, stuck at contract (offset 0)"
+		if r := recover(); r != expected {
+			t.Errorf("Expected %q, got %v", expected, r)
+		}
+	}()
+	p.checkProgress(&check, "parseLoop")
+}
+
+func Test_ConsumedTokens(t *testing.T) {
+	if !assertions {
+		t.Skip("The tokens are counted with the assertions only, run with `-tags solbot_assert`")
+	}
+	p := Parser{}
+	p.Init(token.NewFile("test.sol", "x;"))
+	p.nextToken()
+	p.nextToken()
+	p.nextToken()
+
+	// `x`, `;` and the EOF token, read by Init and the first nextToken.
+	if p.consumed != 3 {
+		t.Errorf("Expected 3 tokens consumed, got %d", p.consumed)
+	}
+}
+
+// BenchmarkParse parses a large contract. The checks of the invariants
+// are constants in the normal builds, so there should be no difference
+// from the run with them compiled out; compare with the run of
+// `go test -tags solbot_assert -bench Parse` to see what they cost.
+func BenchmarkParse(b *testing.B) {
+	src, err := os.ReadFile(filepath.Join("..", "lsp", "analysis", "testdata", "contractsize", "Registry.sol"))
+	if err != nil {
+		b.Fatalf("Cannot read the fixture: %s", err)
+	}
+	file := token.NewFile("Registry.sol", string(src))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := Parser{}
+		p.Init(file)
+		p.ParseFile()
+	}
+}
@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"solbot/ast"
+	"solbot/token"
+	"time"
+)
+
+// Limits bound the resources spent on parsing a file, so that the generated
+// or adversarial inputs e.g. 10,000 nested parentheses or a megabyte of
+// chained operators degrade predictably instead of exhausting the stack, the
+// memory or the time of an editor. The analysis walks the syntax trees
+// recursively, so the depth limits bound it too. A zero limit is no limit,
+// and the parser has none unless they're set, see SetLimits.
+type Limits struct {
+	// MaxExpressionDepth is the depth of the nested expressions, counting
+	// the operands chained by the operators, beyond which the expression is
+	// replaced with an ast.BadExpression.
+	MaxExpressionDepth int
+	// MaxStatementDepth is the depth of the nested statements beyond which
+	// the statement is replaced with an ast.BadStatement.
+	MaxStatementDepth int
+	// MaxDeclarations is the number of the declarations of the file, the
+	// members of the contracts included, after which the parsing stops.
+	MaxDeclarations int
+	// MaxNodes is the number of the declarations, the statements and the
+	// expressions after which the parsing stops.
+	MaxNodes int
+	// Timeout is how long the parsing of a file can take before it's
+	// aborted. The clock is read every clockInterval nodes.
+	Timeout time.Duration
+}
+
+// DefaultLimits returns the limits of the command line tools, generous
+// enough for any file written by hand. The language server has tighter
+// ones, see analysis.DefaultParserLimits.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxExpressionDepth: 1000,
+		MaxStatementDepth:  500,
+		MaxDeclarations:    100_000,
+		MaxNodes:           10_000_000,
+		Timeout:            time.Minute,
+	}
+}
+
+// clockInterval is the number of the nodes counted between the reads of
+// the clock, so that the time budget costs a counter check per node.
+const clockInterval = 1024
+
+// SetLimits bounds the parsing of the file, see Limits. It's called after
+// Init, and the clock starts with ParseFile.
+func (p *Parser) SetLimits(limits Limits) {
+	p.limits = limits
+}
+
+// startClock starts the time budget of the file and resets the counters.
+func (p *Parser) startClock() {
+	p.exprDepth, p.stmtDepth, p.declarations, p.nodes, p.nextCheck = 0, 0, 0, 0, 0
+	p.deadline = time.Time{}
+	if p.limits.Timeout > 0 {
+		p.deadline = time.Now().Add(p.limits.Timeout)
+	}
+}
+
+// count counts a node being parsed. The limits of the nodes and of the time
+// are checked when the count reaches the next check only.
+func (p *Parser) count() {
+	p.nodes++
+	if p.nodes >= p.nextCheck {
+		p.checkLimits()
+	}
+}
+
+// checkLimits halts the parsing if the file has more nodes than the limit
+// or if it took longer than the timeout, and sets the count of the next
+// check otherwise.
+func (p *Parser) checkLimits() {
+	if p.limits.MaxNodes > 0 && p.nodes > p.limits.MaxNodes {
+		p.halt(fmt.Sprintf("parsing stopped at the limit of %d nodes, %s", p.limits.MaxNodes, p.parsedSummary()))
+		return
+	}
+	if !p.deadline.IsZero() && time.Now().After(p.deadline) {
+		p.halt(fmt.Sprintf("internal error: parsing took longer than %s and was aborted, %s", p.limits.Timeout, p.parsedSummary()))
+		return
+	}
+	p.nextCheck = math.MaxInt
+	if !p.deadline.IsZero() {
+		p.nextCheck = p.nodes + clockInterval
+	}
+	if p.limits.MaxNodes > 0 {
+		p.nextCheck = min(p.nextCheck, p.limits.MaxNodes+1)
+	}
+}
+
+// countDeclaration counts a declaration about to be parsed. It halts the
+// parsing and returns false if the file has as many as the limit already.
+func (p *Parser) countDeclaration() bool {
+	if p.halted {
+		return false
+	}
+	if p.limits.MaxDeclarations > 0 && p.declarations >= p.limits.MaxDeclarations {
+		p.halt(fmt.Sprintf("parsing stopped at the limit of %d declarations, %s", p.limits.MaxDeclarations, p.parsedSummary()))
+		return false
+	}
+	p.declarations++
+	p.count()
+	return !p.halted
+}
+
+// parsedSummary tells how much of the file was parsed before the current
+// token e.g. "1024 of 4096 bytes were parsed (at offset: 1024)".
+func (p *Parser) parsedSummary() string {
+	return fmt.Sprintf("%d of %d bytes were parsed (at offset: %d)", p.currTkn.Pos, len(p.file.Src()), p.currTkn.Pos)
+}
+
+// halt stops the parsing at the current token with the error. The rest of
+// the file reads as its end, the blocks and the contracts being parsed are
+// closed there, and the errors of the other constructs it leaves unfinished
+// are dropped.
+func (p *Parser) halt(msg string) {
+	p.limitError(p.currTkn.Pos, msg)
+	p.halted = true
+	eof := token.Token{Type: token.EOF, Pos: p.currTkn.Pos}
+	p.currTkn, p.peekTkn, p.ahead = eof, eof, nil
+}
+
+// limitError records the error of a limit. It's recorded even if the
+// statement is poisoned, since it explains the missing part of the tree.
+func (p *Parser) limitError(pos token.Pos, msg string) {
+	p.errors = append(p.errors, Error{Pos: pos, Msg: msg, Limit: true})
+}
+
+// truncateExpression replaces the expression starting at the position,
+// which would be nested deeper than the limit, with an ast.BadExpression.
+// The rest of it is skipped, and the errors until the end of the statement
+// are dropped, so the limit is reported once.
+func (p *Parser) truncateExpression(from token.Pos) ast.Expression {
+	msg := fmt.Sprintf("expression nested deeper than %d levels, the rest of it is not parsed (at offset: %d)",
+		p.limits.MaxExpressionDepth, p.currTkn.Pos)
+	p.limitError(p.currTkn.Pos, msg)
+	p.skipExpression()
+	p.poisoned, p.poison = true, p.currTkn.Pos
+	return &ast.BadExpression{From: from, To: p.tokenEnd()}
+}
+
+// skipExpression skips the tokens up to the last one of the expression
+// starting at the current token, without recursion: the brackets opened in
+// it are matched, and it ends before the closing bracket, the comma or the
+// semicolon outside of them.
+func (p *Parser) skipExpression() {
+	depth := 0
+	for !p.currTknIs(token.EOF) {
+		switch p.currTkn.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			depth--
+		}
+		if depth <= 0 {
+			switch p.peekTkn.Type {
+			case token.RPAREN, token.RBRACKET, token.RBRACE, token.COMMA, token.SEMICOLON, token.EOF:
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
+// truncateStatement replaces the statement at the current token, which
+// would be nested deeper than the limit, with an ast.BadStatement. The rest
+// of it is skipped like after an error, see synchronize.
+func (p *Parser) truncateStatement() ast.Statement {
+	from := p.currTkn.Pos
+	msg := fmt.Sprintf("statement nested deeper than %d levels, the rest of it is not parsed (at offset: %d)",
+		p.limits.MaxStatementDepth, from)
+	p.limitError(from, msg)
+	p.synchronize()
+	p.poisoned, p.poison = true, p.currTkn.Pos
+	return &ast.BadStatement{From: from, To: p.tokenEnd()}
+}
+
+// tokenEnd returns the position right after the current token.
+func (p *Parser) tokenEnd() token.Pos {
+	return p.currTkn.Pos + token.Pos(len(p.currTkn.Literal))
+}
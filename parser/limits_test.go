@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"solbot/ast"
+	"solbot/token"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseWithLimits parses the source within the limits, and fails the test
+// unless it hits a single limit, with the message starting with the
+// prefix, in a bounded time.
+func parseWithLimits(t *testing.T, src string, limits Limits, prefix string) *ast.File {
+	t.Helper()
+	p := Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	p.SetLimits(limits)
+	start := time.Now()
+	file := p.ParseFile()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the parsing to end within 5s, took %s", elapsed)
+	}
+
+	limited := []Error{}
+	for _, e := range p.Errors() {
+		if e.Limit {
+			limited = append(limited, e)
+		}
+	}
+	if len(limited) != 1 || !strings.HasPrefix(limited[0].Msg, prefix) {
+		t.Fatalf("Expected a single error starting with %q, got %v", prefix, limited)
+	}
+	return file
+}
+
+func Test_LimitExpressionDepth(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"parentheses", strings.Repeat("(", 10_000) + "1" + strings.Repeat(")", 10_000)},
+		{"chained operators", "1" + strings.Repeat(" + 1", 200_000)},
+		{"prefix operators", strings.Repeat("-", 10_000) + "1"},
+		{"exponentiation", "2" + strings.Repeat(" ** 2", 10_000)},
+	}
+	for _, tt := range tests {
+		src := "contract C {\n    function f() public {\n        x = " + tt.expr + ";\n        y = 1;\n    }\n}\n"
+		file := parseWithLimits(t, src, Limits{MaxExpressionDepth: 100}, "expression nested deeper than 100 levels")
+
+		body := file.Declarations[0].(*ast.ContractDeclaration).Body[0].(*ast.FunctionDeclaration).Body
+		if len(body.Statements) != 2 {
+			t.Fatalf("%s: expected the statements after the truncated one to be parsed, got %d statements", tt.name, len(body.Statements))
+		}
+		bad := 0
+		ast.Inspect(body, func(node ast.Node) bool {
+			if _, ok := node.(*ast.BadExpression); ok {
+				bad++
+			}
+			return true
+		})
+		if bad != 1 {
+			t.Errorf("%s: expected a single placeholder, got %d", tt.name, bad)
+		}
+	}
+}
+
+func Test_LimitStatementDepth(t *testing.T) {
+	src := "contract C {\n    function f() public {\n        " + strings.Repeat("{ ", 10_000) + "x = 1;" + strings.Repeat(" }", 10_000) +
+		"\n        if (a) if (b) if (c) if (d) y = 1;\n        z = 1;\n    }\n}\n"
+	file := parseWithLimits(t, src, Limits{MaxStatementDepth: 50}, "statement nested deeper than 50 levels")
+
+	body := file.Declarations[0].(*ast.ContractDeclaration).Body[0].(*ast.FunctionDeclaration).Body
+	if len(body.Statements) != 3 {
+		t.Fatalf("Expected the statements after the truncated one to be parsed, got %d statements", len(body.Statements))
+	}
+	var bad *ast.BadStatement
+	ast.Inspect(body.Statements[0], func(node ast.Node) bool {
+		if b, ok := node.(*ast.BadStatement); ok {
+			bad = b
+		}
+		return true
+	})
+	if bad == nil || src[bad.From] != '{' || src[bad.To-1] != '}' {
+		t.Errorf("Expected the placeholder to span the truncated block, got %v", bad)
+	}
+}
+
+func Test_LimitDeclarations(t *testing.T) {
+	src := strings.Repeat("error E();\n", 50_000)
+	file := parseWithLimits(t, src, Limits{MaxDeclarations: 1000}, "parsing stopped at the limit of 1000 declarations, 11000 of 550000 bytes were parsed")
+	if len(file.Declarations) != 1000 {
+		t.Errorf("Expected 1000 declarations, got %d", len(file.Declarations))
+	}
+
+	// The contract being parsed is closed where the parsing stopped.
+	src = "contract C {\n" + strings.Repeat("    function f() public {}\n", 50_000) + "}\n"
+	file = parseWithLimits(t, src, Limits{MaxDeclarations: 1000}, "parsing stopped at the limit of 1000 declarations")
+	if len(file.Declarations) != 1 || len(file.Declarations[0].(*ast.ContractDeclaration).Body) != 999 {
+		t.Errorf("Expected the contract with 999 functions, got %d declarations", len(file.Declarations))
+	}
+}
+
+func Test_LimitNodes(t *testing.T) {
+	src := "contract C {\n    function f() public {\n" + strings.Repeat("        x = a + b;\n", 10_000) + "    }\n}\n"
+	file := parseWithLimits(t, src, Limits{MaxNodes: 5000}, "parsing stopped at the limit of 5000 nodes")
+
+	body := file.Declarations[0].(*ast.ContractDeclaration).Body[0].(*ast.FunctionDeclaration).Body
+	if n := len(body.Statements); n == 0 || n >= 10_000 {
+		t.Errorf("Expected the statements parsed before the limit, got %d", n)
+	}
+}
+
+func Test_LimitTimeout(t *testing.T) {
+	src := "contract C {\n    function f() public {\n" + strings.Repeat("        x = a + b;\n", 10_000) + "    }\n}\n"
+	parseWithLimits(t, src, Limits{Timeout: time.Nanosecond}, "internal error: parsing took longer than 1ns and was aborted")
+}
+
+// BenchmarkParseLimits parses a large contract without the limits and
+// within the default ones, which it doesn't hit; the two should take the
+// same time.
+func BenchmarkParseLimits(b *testing.B) {
+	src, err := os.ReadFile(filepath.Join("..", "lsp", "analysis", "testdata", "contractsize", "Registry.sol"))
+	if err != nil {
+		b.Fatalf("Cannot read the fixture: %s", err)
+	}
+	file := token.NewFile("Registry.sol", string(src))
+
+	for _, bm := range []struct {
+		name   string
+		limits Limits
+	}{
+		{"none", Limits{}},
+		{"default", DefaultLimits()},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p := Parser{}
+				p.Init(file)
+				p.SetLimits(bm.limits)
+				p.ParseFile()
+				if len(p.Errors()) > 0 {
+					b.Fatalf("Expected no errors, got %v", p.Errors())
+				}
+			}
+		})
+	}
+}
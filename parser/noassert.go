@@ -0,0 +1,7 @@
+//go:build !solbot_assert
+
+package parser
+
+// assertions is off in the normal builds, so the checks guarded by it are
+// compiled out.
+const assertions = false
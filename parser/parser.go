@@ -2,28 +2,103 @@ package parser
 
 import (
 	"fmt"
+	"solbot/analyzer/pragma"
 	"solbot/ast"
 	"solbot/lexer"
 	"solbot/token"
+	"time"
+)
+
+// Grammar is the range of the Solidity versions whose grammar the parser
+// supports, the 0.4 contracts in the legacy mode, see pragma.Legacy. The
+// 0.5 contracts are parsed as the modern ones, so their unnamed fallback
+// functions are errors, and the custom storage layouts of 0.8.29 are not
+// supported yet.
+const Grammar = ">=0.4.0 <0.5.0 || >=0.6.0 <0.8.29"
+
+type (
+	prefixParseFn func() ast.Expression
+	infixParseFn  func(ast.Expression) ast.Expression
 )
 
 type Parser struct {
 	file   *token.File
-	l      lexer.Lexer
+	l      *lexer.Lexer
 	errors ErrorList
 
 	// Tracing
 	trace bool
 
+	// In the fragment mode, the end of the input ends the last statement
+	// as well as a semicolon, see ParseStatements.
+	fragment bool
+
 	currTkn token.Token
 	peekTkn token.Token
+
+	// Tokens read from the lexer ahead of the peekTkn. Solidity needs more
+	// than one token of lookahead in a few places e.g. to tell apart call
+	// options from a block.
+	ahead []token.Token
+
+	comments []*ast.Comment
+
+	// The statement or the declaration with an illegal token e.g. an
+	// unterminated string is poisoned: its errors would only repeat the
+	// error of the lexer, so they are dropped until the next one starts.
+	poisoned bool
+	poison   token.Pos // position of the illegal token
+
+	// The last list of expressions was closed at the end of the statement,
+	// where the statement may miss its semicolon as well; the error about
+	// the list is enough then. See parseExpressionList.
+	unclosed bool
+
+	// The pragma of the file targets a compiler older than 0.5.0, so the
+	// legacy constructs e.g. `throw` and `var` are accepted, see
+	// legacyConstruct.
+	legacy bool
+
+	// Name of the contract being parsed; or nil. The legacy constructors
+	// are the functions named after it.
+	contract *ast.Identifier
+
+	// The tokens consumed so far, counted with the assertions only, see
+	// checkProgress. The EOF token repeated at the end doesn't count.
+	consumed int
+
+	// The limits of the resources and the counts they're checked against,
+	// see Limits. A halted parser reads the rest of the file as its end.
+	limits       Limits
+	exprDepth    int
+	stmtDepth    int
+	declarations int
+	nodes        int
+	nextCheck    int // the node count at which the limits are checked next, see count
+	deadline     time.Time
+	halted       bool
+
+	prefixParseFns map[token.TokenType]prefixParseFn
+	infixParseFns  map[token.TokenType]infixParseFn
 }
 
 func (p *Parser) Init(file *token.File) {
-	p.l = *lexer.Lex(file)
+	p.l = lexer.Lex(file)
 	p.errors = ErrorList{}
 	p.file = file
 	p.trace = false
+	p.fragment = false
+	p.ahead = nil
+	p.comments = nil
+	p.poisoned = false
+	p.unclosed = false
+	p.legacy = false
+	p.contract = nil
+	p.consumed = 0
+	p.halted = false
+	p.startClock()
+
+	p.registerExpressionParseFns()
 
 	// Read two tokens, so currTkn and peekTkn are both set
 	p.nextToken()
@@ -34,9 +109,72 @@ func (p *Parser) ToggleTracing() {
 	p.trace = !p.trace
 }
 
+// Errors returns the errors encountered while parsing.
+func (p *Parser) Errors() ErrorList {
+	return p.errors
+}
+
 func (p *Parser) nextToken() {
+	if assertions && p.currTkn != p.peekTkn {
+		p.consumed++
+	}
 	p.currTkn = p.peekTkn
-	p.peekTkn = p.l.NextToken()
+	if len(p.ahead) > 0 {
+		p.peekTkn = p.ahead[0]
+		p.ahead = p.ahead[1:]
+		return
+	}
+	p.peekTkn = p.readToken()
+}
+
+// lookahead returns the n-th token after the peekTkn without advancing
+// the parser. lookahead(1) is the token right after the peekTkn.
+func (p *Parser) lookahead(n int) token.Token {
+	for len(p.ahead) < n {
+		p.ahead = append(p.ahead, p.readToken())
+	}
+	return p.ahead[n-1]
+}
+
+// readToken reads the next meaningful token from the lexer. Comments are
+// collected on the side and errors reported by the lexer are recorded as
+// parser errors.
+func (p *Parser) readToken() token.Token {
+	if p.halted {
+		return p.currTkn
+	}
+	for {
+		tkn := p.l.NextToken()
+		switch tkn.Type {
+		case token.COMMENT_LITERAL:
+			p.comments = append(p.comments, &ast.Comment{
+				Slash: tkn.Pos,
+				Text:  tkn.Literal,
+			})
+		case token.ILLEGAL:
+			p.errors.Add(tkn.Pos, tkn.Literal)
+			p.poisoned, p.poison = true, tkn.Pos
+		default:
+			return tkn
+		}
+	}
+}
+
+// error records the error, unless the statement or the declaration being
+// parsed is poisoned, or the parsing was halted, see halt.
+func (p *Parser) error(pos token.Pos, msg string) {
+	if p.poisoned || p.halted {
+		return
+	}
+	p.errors.Add(pos, msg)
+}
+
+// unpoison reports the errors again once the parser starts a statement or
+// a declaration after the illegal token.
+func (p *Parser) unpoison() {
+	if p.poisoned && p.currTkn.Pos > p.poison {
+		p.poisoned = false
+	}
 }
 
 func (p *Parser) ParseFile() *ast.File {
@@ -45,166 +183,193 @@ func (p *Parser) ParseFile() *ast.File {
 	}
 
 	file := &ast.File{}
+	file.Name = p.file.Name()
 	file.Declarations = []ast.Declaration{}
 
+	p.startClock()
+	var check loopCheck
 	for p.currTkn.Type != token.EOF {
-		decl := p.parseDeclaration()
+		if assertions {
+			p.checkProgress(&check, "ParseFile")
+		}
+		if !p.countDeclaration() {
+			break
+		}
+		decl := p.parseSourceUnitDeclaration()
 		if decl != nil {
 			file.Declarations = append(file.Declarations, decl)
+			if _, ok := decl.(*ast.PragmaDirective); ok {
+				p.legacy = pragma.Legacy(file)
+			}
+		} else {
+			// If we end up on a stray closing brace, it's skipped below.
+			p.synchronize()
 		}
 		p.nextToken()
 	}
 
+	file.Comments = p.comments
 	return file
 }
 
-func (p *Parser) parseDeclaration() ast.Declaration {
-	if p.trace {
-		defer un(trace("parseDeclaration"))
-	}
-	switch tkType := p.currTkn.Type; {
-	case token.IsElementaryType(tkType):
-		return p.parseVariableDeclaration()
-	case tkType == token.FUNCTION:
-		return p.parseFunctionDeclaration()
-	default:
-		return nil
+// synchronize skips tokens after a parsing error until the end of the
+// broken statement or declaration. It stops on the semicolon ending it, on
+// the closing brace of a nested block that was part of it, or right before
+// the closing brace of the enclosing block. It reports whether the current
+// token is the closing brace of the enclosing block, which must not be
+// skipped by the caller.
+func (p *Parser) synchronize() bool {
+	depth := 0
+	for !p.currTknIs(token.EOF) {
+		switch p.currTkn.Type {
+		case token.SEMICOLON:
+			if depth == 0 {
+				return false
+			}
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+			if depth < 0 {
+				return true
+			}
+			if depth == 0 {
+				return false
+			}
+		}
+		if depth == 0 && p.peekTknIs(token.RBRACE) {
+			return false
+		}
+		p.nextToken()
 	}
+	return false
 }
 
-func (p *Parser) parseFunctionDeclaration() *ast.FunctionDeclaration {
-	if p.trace {
-		defer un(trace("parseFunctionDeclaration"))
-	}
-	decl := &ast.FunctionDeclaration{}
-
-	// 1. Function keyword
-	fnType := &ast.FunctionType{}
-	fnType.Func = p.currTkn.Pos
-
-	// 2. Function identifier
-	if !p.expectPeek(token.IDENTIFIER) {
-		return nil
-	}
-
-	decl.Name = &ast.Identifier{
-		NamePos: p.currTkn.Pos,
-		Name:    p.currTkn.Literal,
-	}
-
-	// 3. ( Param List )
-	if !p.expectPeek(token.LPAREN) {
-		return nil
-	}
-
-	params := &ast.ParamList{}
-	params.Opening = p.currTkn.Pos
-
-	for !p.currTknIs(token.RPAREN) {
+// expectPeek checks if the next token is of the expected type.
+// If it is it advances the tokens.
+func (p *Parser) expectPeek(t token.TokenType) bool {
+	if p.peekTkn.Type == t {
 		p.nextToken()
+		return true
+	} else {
+		p.peekError(t)
+		return false
 	}
+}
 
-	params.Closing = p.currTkn.Pos
-
-	// 4. Visibility, State Mutability, Modifier Invocation, Override, Virtual
-
-	// 5. Returns ( Param List )
-
-	// 6. Body block
-	for !p.currTknIs(token.LBRACE) {
-		p.nextToken()
+// expectSemicolon checks if the statement ends with a semicolon and
+// advances to it. In the fragment mode, the statement can end at the end
+// of the input instead.
+func (p *Parser) expectSemicolon() bool {
+	if p.fragment && p.peekTknIs(token.EOF) {
+		return true
 	}
+	if p.unclosed && (p.peekTknIs(token.RBRACE) || p.peekTknIs(token.EOF)) {
+		return true
+	}
+	return p.expectPeek(token.SEMICOLON)
+}
 
-	fnBody := p.parseBlockStatement()
-
-	// 7. Semicolon
-
-	fnType.Params = params
-	decl.Body = fnBody
-	decl.Type = fnType
-	return decl
+func (p *Parser) peekError(t token.TokenType) {
+	msg := fmt.Sprintf("expected next token to be: %s, got: %s instead (at offset: %d)",
+		t.String(), p.peekTkn.Type.String(), p.peekTkn.Pos)
+	p.error(p.peekTkn.Pos, msg)
 }
 
-func (p *Parser) parseVariableDeclaration() *ast.VariableDeclaration {
-	if p.trace {
-		defer un(trace("parseVariableDeclaration"))
-	}
-	decl := &ast.VariableDeclaration{}
+// currTknIs checks if the current token is of the expected type.
+func (p *Parser) currTknIs(t token.TokenType) bool {
+	return p.currTkn.Type == t
+}
 
-	// Set default values so that we don't have nil pointer dereferences
-	decl.Constant = false
+// peekTknIs checks if the next token is of the expected type.
+func (p *Parser) peekTknIs(t token.TokenType) bool {
+	return p.peekTkn.Type == t
+}
 
-	// We are sitting on the variable type e.g. address or uint256
-	decl.Type = &ast.ElementaryType{
-		ValuePos: p.currTkn.Pos,
-		Kind:     p.currTkn,
-		Value:    p.currTkn.Literal,
+// isVisibility checks if the token is one of the visibility specifiers.
+func isVisibility(t token.TokenType) bool {
+	switch t {
+	case token.PUBLIC, token.PRIVATE, token.INTERNAL, token.EXTERNAL:
+		return true
 	}
+	return false
+}
 
-	// @TODO: We need to handle visibility
-	if isVisibility(p.peekTkn.Type) {
-
+// isDataLocation checks if the token is one of the data location specifiers.
+func isDataLocation(t token.TokenType) bool {
+	switch t {
+	case token.STORAGE, token.MEMORY, token.CALLDATA:
+		return true
 	}
+	return false
+}
 
-	if p.peekTkn.Type == token.CONSTANT {
-		decl.Constant = true
-		p.nextToken()
-	}
+// currIdentIs checks if the current token is an identifier with the given
+// name. Solidity has a few words like "revert", "error" or "global" that
+// have a special meaning only in some places and are lexed as identifiers.
+func (p *Parser) currIdentIs(name string) bool {
+	return p.currTkn.Type == token.IDENTIFIER && p.currTkn.Literal == name
+}
 
-	if !p.expectPeek(token.IDENTIFIER) {
-		return nil
-	}
+// peekIdentIs checks if the next token is an identifier with the given
+// name, see currIdentIs.
+func (p *Parser) peekIdentIs(name string) bool {
+	return p.peekTkn.Type == token.IDENTIFIER && p.peekTkn.Literal == name
+}
 
-	decl.Name = &ast.Identifier{
+func (p *Parser) newIdentifier() *ast.Identifier {
+	return &ast.Identifier{
 		NamePos: p.currTkn.Pos,
 		Name:    p.currTkn.Literal,
 	}
-
-	// @TODO: We skip the Value for now since it is an expression.
-
-	// The variable declaration ends with a semicolon.
-	for !p.currTknIs(token.SEMICOLON) {
-		p.nextToken()
-	}
-
-	return decl
 }
 
-func (p *Parser) parseBlockStatement() *ast.BlockStatement {
-	if p.trace {
-		defer un(trace("parseBlockStatement"))
-	}
-	blockStmt := &ast.BlockStatement{}
-	blockStmt.LeftBrace = p.currTkn.Pos
-
-	for !p.currTknIs(token.RBRACE) {
-		p.nextToken()
+// legacyConstruct reports the construct dropped in Solidity 0.5.0 as an
+// error, unless the file targets an older compiler. The construct is
+// parsed either way.
+func (p *Parser) legacyConstruct(pos token.Pos, construct, replacement string) {
+	if p.legacy {
+		return
 	}
-
-	blockStmt.RightBrace = p.currTkn.Pos
-
-	return blockStmt
+	msg := fmt.Sprintf("%s was removed in Solidity 0.5.0, use %s instead (at offset: %d)",
+		construct, replacement, pos)
+	p.error(pos, msg)
 }
 
-// expectPeek checks if the next token is of the expected type.
-// If it is it advances the tokens.
-func (p *Parser) expectPeek(t token.TokenType) bool {
-	if p.peekTkn.Type == t {
-		p.nextToken()
-		return true
-	} else {
-		p.peekError(t)
-		return false
+func toVisibility(t token.TokenType) ast.Visibility {
+	switch t {
+	case token.PUBLIC:
+		return ast.Public
+	case token.PRIVATE:
+		return ast.Private
+	case token.INTERNAL:
+		return ast.Internal
+	case token.EXTERNAL:
+		return ast.External
 	}
+	return 0
 }
 
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be: %s, got: %s instead (at offset: %d)",
-		t.String(), p.peekTkn.Type.String(), p.peekTkn.Pos)
-	p.errors.Add(p.peekTkn.Pos, msg)
+func toMutability(t token.TokenType) ast.Mutability {
+	switch t {
+	case token.PURE:
+		return ast.Pure
+	case token.VIEW:
+		return ast.View
+	case token.PAYABLE:
+		return ast.Payable
+	}
+	return 0
 }
 
-// currTknIs checks if the current token is of the expected type.
-func (p *Parser) currTknIs(t token.TokenType) bool {
-	return p.currTkn.Type == t
+func toDataLocation(t token.TokenType) ast.DataLocation {
+	switch t {
+	case token.STORAGE:
+		return ast.Storage
+	case token.MEMORY:
+		return ast.Memory
+	case token.CALLDATA:
+		return ast.Calldata
+	}
+	return 0
 }
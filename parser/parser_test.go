@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"fmt"
 	"solbot/ast"
+	"solbot/semver"
 	"solbot/token"
+	"strings"
 	"testing"
 )
 
@@ -89,41 +92,170 @@ func Test_ParseFunctionDeclaration(t *testing.T) {
 		t.Fatalf("Expected ParamList, got nil")
 	}
 
-	// if len(fd.Type.Params.List) != 1 {
-	// 	t.Fatalf("Expected 1 parameter, got %d", len(fd.Type.Params.List))
-	// }
-	//
-	// param := fd.Type.Params.List[0]
-	// if param.Name.Name != "owner" {
-	// 	t.Errorf("Expected parameter name owner, got %s", param.Name.Name)
-	// }
-
-	// @TODO: We skip the type for now since it is an expression.
-	// if param.Type == nil {
-	// 	t.Fatalf("Expected ElementaryType, got nil")
-	// }
-
-	// et, ok := param.Type.(*ast.ElementaryType)
-	// if !ok {
-	// 	t.Fatalf("Expected ElementaryType, got %T", param.Type)
-	// }
-	//
-	// if et.Kind.Type != token.ADDRESS {
-	// 	t.Errorf("Expected token type ADDRESS, got %T", et.Kind.Type)
-	// }
+	if len(fd.Type.Params.List) != 1 {
+		t.Fatalf("Expected 1 parameter, got %d", len(fd.Type.Params.List))
+	}
+
+	param := fd.Type.Params.List[0]
+	if param.Name.Name != "owner" {
+		t.Errorf("Expected parameter name owner, got %s", param.Name.Name)
+	}
+
+	et, ok := param.Type.(*ast.ElementaryType)
+	if !ok {
+		t.Fatalf("Expected ElementaryType, got %T", param.Type)
+	}
+
+	if et.Kind.Type != token.ADDRESS {
+		t.Errorf("Expected token type ADDRESS, got %s", et.Kind.Type)
+	}
 
 	if fd.Body == nil {
 		t.Fatalf("Expected BlockStatement, got nil")
 	}
 
-	// fb, ok := fd.Body.(*ast.BlockStatement)
-	// if !ok {
-	// 	t.Fatalf("Expected BlockStatement, got %T", fd.Body)
-	// }
+	if len(fd.Body.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(fd.Body.Statements))
+	}
+}
+
+func Test_ParseOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a + b * c", "a + (b * c)"},
+		{"a * b + c", "(a * b) + c"},
+		{"a - b - c", "(a - b) - c"},
+		{"a ** b ** c", "a ** (b ** c)"},
+		{"-a * b", "(-a) * b"},
+		{"a = b += c", "a = (b += c)"},
+		{"a < b == c > d", "(a < b) == (c > d)"},
+		{"a && b || c", "(a && b) || c"},
+		{"a | b ^ c & d", "a | (b ^ (c & d))"},
+		{"a << b + c", "a << (b + c)"},
+		{"x.y[i]++ + 1", "(x.y[i]++) + 1"},
+		{"c ? a + b : d", "c ? (a + b) : d"},
+	}
+
+	for _, tt := range tests {
+		src := "function f() { " + tt.input + "; }"
+		p := Parser{}
+		p.Init(token.NewFile("test.sol", src))
+		file := p.ParseFile()
+		checkParserErrors(t, &p)
+
+		fd := file.Declarations[0].(*ast.FunctionDeclaration)
+		stmt, ok := fd.Body.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("Expected ExpressionStatement, got %T", fd.Body.Statements[0])
+		}
+
+		if got := parenthesize(stmt.Expression); got != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+}
+
+func Test_ParseContractDeclaration(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+    import {IERC20} from "./IERC20.sol";
+
+    abstract contract Vault is Ownable(msg.sender), IVault {
+        using SafeERC20 for IERC20;
+
+        uint256 public immutable fee;
+        mapping(address => uint256) balances;
+
+        event Deposit(address indexed user, uint256 amount);
+        error Unauthorized();
+
+        modifier onlyUser() {
+            if (balances[msg.sender] == 0) revert Unauthorized();
+            _;
+        }
+
+        function deposit(uint256 amount) external onlyUser {
+            balances[msg.sender] += amount;
+            emit Deposit(msg.sender, amount);
+        }
+    }
+    `
+
+	p := Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	file := p.ParseFile()
+	checkParserErrors(t, &p)
+
+	if len(file.Declarations) != 3 {
+		t.Fatalf("Expected 3 declarations, got %d", len(file.Declarations))
+	}
+
+	if pragma := file.Pragma("solidity"); pragma == nil || pragma.Value != "^0.8.0" {
+		t.Errorf("Expected solidity pragma ^0.8.0, got %v", pragma)
+	}
+
+	cd, ok := file.Declarations[2].(*ast.ContractDeclaration)
+	if !ok {
+		t.Fatalf("Expected ContractDeclaration, got %T", file.Declarations[2])
+	}
+
+	if cd.Name.Name != "Vault" || !cd.Abstract {
+		t.Errorf("Expected abstract contract Vault, got %s", cd.Name.Name)
+	}
+
+	if len(cd.Bases) != 2 {
+		t.Fatalf("Expected 2 base contracts, got %d", len(cd.Bases))
+	}
+
+	expectedMembers := []string{
+		"*ast.UsingForDirective",
+		"*ast.VariableDeclaration",
+		"*ast.VariableDeclaration",
+		"*ast.EventDeclaration",
+		"*ast.ErrorDeclaration",
+		"*ast.ModifierDeclaration",
+		"*ast.FunctionDeclaration",
+	}
+
+	if len(cd.Body) != len(expectedMembers) {
+		t.Fatalf("Expected %d members, got %d", len(expectedMembers), len(cd.Body))
+	}
+
+	for i, member := range cd.Body {
+		if got := fmt.Sprintf("%T", member); got != expectedMembers[i] {
+			t.Errorf("Expected member %d to be %s, got %s", i, expectedMembers[i], got)
+		}
+	}
+}
+
+// parenthesize returns the expression with each nested operation wrapped in
+// parentheses, which makes the precedence explicit.
+func parenthesize(x ast.Expression) string {
+	wrap := func(x ast.Expression) string {
+		switch x.(type) {
+		case *ast.BinaryExpression, *ast.AssignmentExpression,
+			*ast.UnaryExpression, *ast.ConditionalExpression:
+			return "(" + parenthesize(x) + ")"
+		}
+		return parenthesize(x)
+	}
 
-	// if len(fb.Statements) != 2 {
-	// 	t.Fatalf("Expected 2 statements, got %d", len(fb.Statements))
-	// }
+	switch x := x.(type) {
+	case *ast.BinaryExpression:
+		return wrap(x.Left) + " " + x.Operator.String() + " " + wrap(x.Right)
+	case *ast.AssignmentExpression:
+		return wrap(x.Left) + " " + x.Operator.String() + " " + wrap(x.Right)
+	case *ast.UnaryExpression:
+		if x.Postfix {
+			return wrap(x.Operand) + x.Operator.String()
+		}
+		return x.Operator.String() + wrap(x.Operand)
+	case *ast.ConditionalExpression:
+		return wrap(x.Condition) + " ? " + wrap(x.True) + " : " + wrap(x.False)
+	}
+	return ast.ExprString(x)
 }
 
 func testParseElementaryType(t *testing.T, decl ast.Declaration,
@@ -158,6 +290,60 @@ func testParseElementaryType(t *testing.T, decl ast.Declaration,
 	return true
 }
 
+func Test_ParseUnterminatedString(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    mapping(address => uint256) balances;
+
+    function withdraw(uint256 amount) external {
+        require(balances[msg.sender] >= amount, "insufficient balance);
+        balances[msg.sender] -= amount;
+    }
+
+    function deposit() external payable {
+        balances[msg.sender] += msg.value;
+    }
+
+    event Withdrawn(address user);
+}
+
+contract Fees {
+    uint256 fee;
+}
+`
+	p := Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	file := p.ParseFile()
+
+	errs := p.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v", errs)
+	}
+	if errs[0].Msg != "string literal not closed before end of line" || errs[0].Pos != token.Pos(strings.Index(src, `"insufficient`)) {
+		t.Errorf("Expected the unterminated string at its opening quote, got %q at %d", errs[0].Msg, errs[0].Pos)
+	}
+
+	names := []string{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.ContractDeclaration:
+			names = append(names, n.Name.Name)
+		case *ast.FunctionDeclaration:
+			names = append(names, n.Name.Name)
+		case *ast.EventDeclaration:
+			names = append(names, n.Name.Name)
+		case *ast.VariableDeclaration:
+			names = append(names, n.Name.Name)
+		}
+		return true
+	})
+	expected := "Vault balances withdraw deposit Withdrawn Fees fee"
+	if got := strings.Join(names, " "); got != expected {
+		t.Errorf("Expected the declarations %s, got %s", expected, got)
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.errors
 	if len(errors) == 0 {
@@ -170,3 +356,380 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func Test_ParseFragments(t *testing.T) {
+	stmts, errs := ParseStatements("uint256 x = 1 days;\nx += 2 hours")
+	if len(errs) > 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	if len(stmts) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(stmts))
+	}
+	if _, ok := stmts[1].(*ast.ExpressionStatement); !ok {
+		t.Errorf("Expected the last statement to end at EOF, got %T", stmts[1])
+	}
+
+	if _, errs := ParseStatements("x = 1\ny = 2"); len(errs) == 0 {
+		t.Errorf("Expected a missing semicolon before the last statement")
+	}
+
+	x, errs := ParseExpression("a + b * c;")
+	if len(errs) > 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	if s := ast.ExprString(x); s != "a + b * c" {
+		t.Errorf("Expected a + b * c, got %s", s)
+	}
+
+	if x, errs := ParseExpression("a + b c"); x != nil || len(errs) != 1 {
+		t.Errorf("Expected 1 error for the trailing identifier, got %v", errs)
+	}
+}
+
+func Test_CommentMap(t *testing.T) {
+	src := `// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+contract Vault {
+    // total of the balances
+    uint256 total;
+
+    function send(uint256 amount, /* the recipient */ address to) external {
+        total -= amount; // solbot-disable-line
+        if (amount > 0) {
+            payable(to).transfer(amount);
+        }
+        // nothing to send
+        else {
+            revert(
+                "empty" /* reason */
+            );
+        }
+    }
+
+    function noop() external {
+        // not implemented
+    }
+}
+`
+	p := Parser{}
+	handle := token.NewFile("test.sol", src)
+	p.Init(handle)
+	file := p.ParseFile()
+	checkParserErrors(t, &p)
+
+	cmap := ast.NewCommentMap(handle, file)
+	anchors := map[string]string{}
+	attached := 0
+	for node, a := range cmap {
+		for kind, comments := range map[string][]*ast.Comment{"leading": a.Leading, "trailing": a.Trailing, "inner": a.Inner} {
+			for _, c := range comments {
+				anchors[c.Text] = fmt.Sprintf("%s %T", kind, node)
+				attached++
+				// The printer emits the comment next to its anchor.
+				if d := handle.Position(c.Start()).Line - handle.Position(node.Start()).Line; kind == "leading" && (d < -1 || d > 0) {
+					t.Errorf("Expected %q within one line of its anchor, got %d lines", c.Text, d)
+				}
+				if d := handle.Position(c.Start()).Line - handle.Position(node.End()).Line; kind == "trailing" && (d < 0 || d > 1) {
+					t.Errorf("Expected %q within one line of its anchor, got %d lines", c.Text, d)
+				}
+			}
+		}
+	}
+	if attached != len(file.Comments) {
+		t.Errorf("Expected all of the %d comments attached, got %d", len(file.Comments), attached)
+	}
+
+	expected := map[string]string{
+		"// SPDX-License-Identifier: MIT": "leading *ast.PragmaDirective",
+		"// total of the balances":        "leading *ast.VariableDeclaration",
+		"/* the recipient */":             "leading *ast.Param",
+		"// solbot-disable-line":          "trailing *ast.ExpressionStatement",
+		"// nothing to send":              "leading *ast.BlockStatement",
+		"/* reason */":                    "trailing *ast.BasicLit",
+		"// not implemented":              "inner *ast.BlockStatement",
+	}
+	for text, anchor := range expected {
+		if anchors[text] != anchor {
+			t.Errorf("Expected %q attached as %s, got %s", text, anchor, anchors[text])
+		}
+	}
+
+	// The suppressions match the trailing comments by the line of the
+	// statement.
+	for node, a := range cmap {
+		for _, c := range a.Trailing {
+			if c.Text == "// solbot-disable-line" && handle.Position(node.Start()).Line != 9 {
+				t.Errorf("Expected the suppressed statement at line 9, got %d", handle.Position(node.Start()).Line)
+			}
+		}
+	}
+}
+
+func Test_ParseListsBeingTyped(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		args   string // arguments of the call in the first statement, `_` for a missing one
+		params int
+		err    string
+	}{
+		{"trailing comma", "transfer(recipient, );", "recipient", 0, "unexpected trailing comma"},
+		{"missing first argument", "f(, x);", "_ x", 0, "missing expression before the comma"},
+		{"missing middle argument", "f(a, , b);", "a _ b", 0, "missing expression before the comma"},
+		{"unclosed before semicolon", "f(a, b;", "a b", 0, "expected next token to be: ), got: ; instead"},
+		{"unclosed before brace", "token.transfer(recipient, \n", "recipient _", 0, "expected next token to be: ), got: } instead"},
+		{"unclosed nested", "f(g(a, ", "g(a, )", 0, "expected next token to be: ), got: } instead"},
+		{"missing parameter", "} function h(uint256 a,, uint256 b) external {", "", 2, "missing parameter before the comma"},
+		{"trailing parameter comma", "} function h(uint256 a, ) external {", "", 1, "unexpected trailing comma"},
+	}
+
+	for _, tt := range tests {
+		src := "contract C {\n    function g() external {\n        " + tt.body + "\n    }\n}\n"
+		p := Parser{}
+		p.Init(token.NewFile("test.sol", src))
+		file := p.ParseFile()
+
+		errs := p.Errors()
+		if len(errs) != 1 || !strings.HasPrefix(errs[0].Msg, tt.err) {
+			t.Errorf("%s: expected the error %q, got %v", tt.name, tt.err, errs)
+			continue
+		}
+		contract := file.Declarations[0].(*ast.ContractDeclaration)
+		fn := contract.Body[len(contract.Body)-1].(*ast.FunctionDeclaration)
+		if tt.params > 0 {
+			if len(fn.Type.Params.List) != tt.params {
+				t.Errorf("%s: expected %d parameters, got %d", tt.name, tt.params, len(fn.Type.Params.List))
+			}
+			continue
+		}
+		if len(fn.Body.Statements) != 1 {
+			t.Errorf("%s: expected the statement to be kept, got %d statements", tt.name, len(fn.Body.Statements))
+			continue
+		}
+		call := fn.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+		args := []string{}
+		for _, arg := range call.Args {
+			if _, ok := arg.(*ast.BadExpression); ok {
+				args = append(args, "_")
+				continue
+			}
+			args = append(args, ast.ExprString(arg))
+		}
+		if got := strings.Join(args, " "); got != tt.args {
+			t.Errorf("%s: expected the arguments %s, got %s", tt.name, tt.args, got)
+		}
+		if end := call.End(); end > token.Pos(strings.LastIndex(src, "}")) {
+			t.Errorf("%s: expected the call to end inside of the function, got %d", tt.name, end)
+		}
+	}
+}
+
+func Test_ParseLegacyConstructs(t *testing.T) {
+	body := `contract Token {
+    uint256 supply;
+
+    function Token() {
+        supply = 1 years;
+    }
+
+    function () payable {
+        throw;
+    }
+
+    function balance() constant returns (uint256) {
+        var total = supply;
+        var (a, , b) = (total, 1, 2);
+        return a + b;
+    }
+}
+`
+	p := Parser{}
+	p.Init(token.NewFile("test.sol", "pragma solidity ^0.4.24;\n"+body))
+	file := p.ParseFile()
+	checkParserErrors(t, &p)
+
+	contract := file.Declarations[1].(*ast.ContractDeclaration)
+	ctor := contract.Body[1].(*ast.FunctionDeclaration)
+	if ctor.Kind != token.CONSTRUCTOR || !ctor.Legacy || ctor.Name.Name != "Token" {
+		t.Errorf("Expected the legacy constructor Token, got %s %v %v", ctor.Kind, ctor.Legacy, ctor.Name)
+	}
+	fallback := contract.Body[2].(*ast.FunctionDeclaration)
+	if fallback.Kind != token.FALLBACK || !fallback.Legacy || fallback.Type.Visibility != ast.Public || !fallback.Type.Implicit {
+		t.Errorf("Expected the legacy fallback public by default, got %s %v %v %v", fallback.Kind, fallback.Legacy, fallback.Type.Visibility, fallback.Type.Implicit)
+	}
+	throw := fallback.Body.Statements[0].(*ast.RevertStatement)
+	if !throw.Legacy || throw.Call != nil || throw.End()-throw.Start() != 5 {
+		t.Errorf("Expected the throw statement, got %+v", throw)
+	}
+	balance := contract.Body[3].(*ast.FunctionDeclaration)
+	if balance.Type.Mutability != ast.View || balance.Type.Constant == 0 {
+		t.Errorf("Expected the constant function to be a view, got %v", balance.Type.Mutability)
+	}
+	total := balance.Body.Statements[0].(*ast.VariableDeclarationStatement)
+	if total.Var == 0 || total.Declarations[0].Type != nil || total.Start() != total.Var {
+		t.Errorf("Expected the var declaration without a type, got %+v", total)
+	}
+	tuple := balance.Body.Statements[1].(*ast.VariableDeclarationStatement)
+	if len(tuple.Declarations) != 3 || tuple.Declarations[1] != nil || tuple.Declarations[2].Name.Name != "b" {
+		t.Errorf("Expected the tuple var declaration of a, _ and b, got %+v", tuple.Declarations)
+	}
+
+	// The same constructs are errors under a modern pragma, but they are
+	// parsed anyway.
+	p.Init(token.NewFile("test.sol", "pragma solidity ^0.8.0;\n"+body))
+	file = p.ParseFile()
+	expected := []string{
+		"constructor named after the contract was removed in Solidity 0.5.0",
+		"`years` was removed in Solidity 0.5.0",
+		"unnamed fallback function was removed in Solidity 0.5.0",
+		"`throw` was removed in Solidity 0.5.0",
+		"`constant` function was removed in Solidity 0.5.0",
+		"`var` was removed in Solidity 0.5.0",
+		"`var` was removed in Solidity 0.5.0",
+	}
+	errs := p.Errors()
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if !strings.HasPrefix(err.Msg, expected[i]) {
+			t.Errorf("Expected the error %q, got %q", expected[i], err.Msg)
+		}
+	}
+	if len(file.Declarations[1].(*ast.ContractDeclaration).Body) != 4 {
+		t.Errorf("Expected the members to be parsed, got %v", file.Declarations[1])
+	}
+}
+
+func Test_ParseTransientStateVariables(t *testing.T) {
+	src := `pragma solidity ^0.8.28;
+
+contract Guard {
+    uint256 transient locked;
+    bool public transient entered;
+    uint256 transient;
+    address transient = address(0);
+}
+`
+	p := Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	file := p.ParseFile()
+	checkParserErrors(t, &p)
+
+	tests := []struct {
+		name      string
+		transient bool
+	}{
+		{"locked", true},
+		{"entered", true},
+		// `transient` is a valid name when it's not followed by one.
+		{"transient", false},
+		{"transient", false},
+	}
+	body := file.Declarations[1].(*ast.ContractDeclaration).Body
+	if len(body) != len(tests) {
+		t.Fatalf("Expected %d state variables, got %d", len(tests), len(body))
+	}
+	for i, tt := range tests {
+		v := body[i].(*ast.VariableDeclaration)
+		if v.Name.Name != tt.name || (v.Transient != 0) != tt.transient {
+			t.Errorf("Expected %s transient %v, got %s transient %v", tt.name, tt.transient, v.Name.Name, v.Transient != 0)
+		}
+		if tt.transient && src[v.Transient:v.Transient+9] != "transient" {
+			t.Errorf("Expected the position of the transient keyword of %s, got %d", tt.name, v.Transient)
+		}
+	}
+	if entered := body[1].(*ast.VariableDeclaration); entered.Visibility != ast.Public {
+		t.Errorf("Expected entered to be public, got %v", entered.Visibility)
+	}
+}
+
+func Test_ParseConcatenatedStrings(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    bytes constant MAGIC = hex"00ff" hex'11';
+
+    function withdraw(bool ok) external {
+        require(ok, "a very long message that "
+            'isn"t short');
+    }
+}
+`
+	p := Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	file := p.ParseFile()
+	checkParserErrors(t, &p)
+
+	lits := []*ast.BasicLit{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if lit, ok := node.(*ast.BasicLit); ok {
+			lits = append(lits, lit)
+		}
+		return true
+	})
+	if len(lits) != 2 {
+		t.Fatalf("Expected 2 literals, got %d", len(lits))
+	}
+
+	magic, message := lits[0], lits[1]
+	if magic.Kind != token.HEX_STRING_LITERAL || magic.Value != `hex"00ff11"` || len(magic.Parts) != 2 {
+		t.Errorf("Expected hex\"00ff11\" of 2 parts, got %s of %d", magic.Value, len(magic.Parts))
+	}
+	if message.Kind != token.STRING_LITERAL || message.Value != `"a very long message that isn\"t short"` {
+		t.Errorf("Expected the concatenated message, got %s", message.Value)
+	}
+	if len(message.Parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %d", len(message.Parts))
+	}
+	for i, expected := range []string{`"a very long message that "`, `'isn"t short'`} {
+		part := message.Parts[i]
+		if part.Value != expected || src[part.Start():part.End()] != expected {
+			t.Errorf("Expected the part %s at its position, got %s at %q", expected, part.Value, src[part.Start():part.End()])
+		}
+	}
+	if message.Start() != message.Parts[0].Start() || message.End() != message.Parts[1].End() {
+		t.Errorf("Expected the literal to span its parts, got %d-%d", message.Start(), message.End())
+	}
+}
+
+func Test_ParseMixedStringConcatenation(t *testing.T) {
+	src := `pragma solidity ^0.8.0;
+
+contract Vault {
+    bytes constant MAGIC = "00" hex"ff";
+}
+`
+	p := Parser{}
+	p.Init(token.NewFile("test.sol", src))
+	p.ParseFile()
+
+	errs := p.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v", errs)
+	}
+	at := strings.Index(src, `hex"ff"`)
+	expected := fmt.Sprintf("cannot concatenate a hex string literal with a string literal (at offset: %d)", at)
+	if errs[0].Msg != expected || errs[0].Pos != token.Pos(at) {
+		t.Errorf("Expected %q at %d, got %q at %d", expected, at, errs[0].Msg, errs[0].Pos)
+	}
+}
+
+func Test_Grammar(t *testing.T) {
+	c, err := semver.ParseConstraint(Grammar)
+	if err != nil {
+		t.Fatalf("Expected a valid constraint, got %s", err)
+	}
+	for version, expected := range map[string]bool{
+		"0.4.24": true, // the legacy mode
+		"0.5.17": false,
+		"0.6.0":  true,
+		"0.8.28": true, // the transient state variables
+		"0.8.29": false,
+	} {
+		if got := c.Allows(semver.MustParse(version)); got != expected {
+			t.Errorf("Expected %s to be supported: %t, got %t", version, expected, got)
+		}
+	}
+}
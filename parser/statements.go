@@ -0,0 +1,660 @@
+package parser
+
+import (
+	"fmt"
+	"solbot/ast"
+	"solbot/token"
+	"solbot/yul"
+)
+
+func (p *Parser) parseStatement() ast.Statement {
+	if p.trace {
+		defer un(trace("parseStatement"))
+	}
+	p.unpoison()
+	p.unclosed = false
+	p.count()
+	if p.limits.MaxStatementDepth > 0 {
+		if p.stmtDepth >= p.limits.MaxStatementDepth {
+			return p.truncateStatement()
+		}
+		p.stmtDepth++
+		defer func() { p.stmtDepth-- }()
+	}
+	switch p.currTkn.Type {
+	case token.LBRACE:
+		return toStatement(p.parseBlockStatement())
+	case token.UNCHECKED:
+		return toStatement(p.parseUncheckedBlockStatement())
+	case token.IF:
+		return toStatement(p.parseIfStatement())
+	case token.FOR:
+		return toStatement(p.parseForStatement())
+	case token.WHILE:
+		return toStatement(p.parseWhileStatement())
+	case token.DO:
+		return toStatement(p.parseDoWhileStatement())
+	case token.CONTINUE:
+		stmt := &ast.ContinueStatement{Continue: p.currTkn.Pos}
+		if !p.expectSemicolon() {
+			return nil
+		}
+		return stmt
+	case token.BREAK:
+		stmt := &ast.BreakStatement{Break: p.currTkn.Pos}
+		if !p.expectSemicolon() {
+			return nil
+		}
+		return stmt
+	case token.RETURN:
+		return toStatement(p.parseReturnStatement())
+	case token.EMIT:
+		return toStatement(p.parseEmitStatement())
+	case token.TRY:
+		return toStatement(p.parseTryStatement())
+	case token.ASSEMBLY:
+		return toStatement(p.parseAssemblyStatement())
+	case token.THROW:
+		return toStatement(p.parseThrowStatement())
+	case token.IDENTIFIER:
+		if p.currIdentIs("revert") && p.peekTknIs(token.IDENTIFIER) {
+			return toStatement(p.parseRevertStatement())
+		}
+		if p.currIdentIs("var") && (p.peekTknIs(token.IDENTIFIER) || p.peekTknIs(token.LPAREN)) {
+			return toStatement(p.parseVarStatement())
+		}
+		if p.currIdentIs("_") && p.peekTknIs(token.SEMICOLON) {
+			stmt := &ast.PlaceholderStatement{Underscore: p.currTkn.Pos}
+			p.nextToken()
+			return stmt
+		}
+	}
+	return p.parseSimpleStatement()
+}
+
+// toStatement makes sure that a nil pointer returned by one of the parse
+// functions is turned into a nil interface. Otherwise, the caller can't
+// check if the statement is nil.
+func toStatement[S any, PS interface {
+	*S
+	ast.Statement
+}](stmt PS) ast.Statement {
+	if stmt == nil {
+		return nil
+	}
+	return stmt
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	if p.trace {
+		defer un(trace("parseBlockStatement"))
+	}
+	blockStmt := &ast.BlockStatement{}
+	blockStmt.LeftBrace = p.currTkn.Pos
+	blockStmt.Statements = []ast.Statement{}
+	p.nextToken()
+
+	var check loopCheck
+	for !p.currTknIs(token.RBRACE) && !p.currTknIs(token.EOF) {
+		if assertions {
+			p.checkProgress(&check, "parseBlockStatement")
+		}
+		stmt := p.parseStatement()
+		if stmt != nil {
+			blockStmt.Statements = append(blockStmt.Statements, stmt)
+		} else if p.synchronize() {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.currTknIs(token.RBRACE) && p.halted {
+		// The block is closed where the parsing stopped.
+		blockStmt.RightBrace = p.currTkn.Pos
+		return blockStmt
+	}
+	if !p.currTknIs(token.RBRACE) {
+		p.error(p.currTkn.Pos, "expected } at the end of the block")
+		return nil
+	}
+	blockStmt.RightBrace = p.currTkn.Pos
+
+	return blockStmt
+}
+
+func (p *Parser) parseUncheckedBlockStatement() *ast.UncheckedBlockStatement {
+	if p.trace {
+		defer un(trace("parseUncheckedBlockStatement"))
+	}
+	stmt := &ast.UncheckedBlockStatement{Unchecked: p.currTkn.Pos}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	if stmt.Body == nil {
+		return nil
+	}
+	return stmt
+}
+
+func (p *Parser) parseIfStatement() *ast.IfStatement {
+	if p.trace {
+		defer un(trace("parseIfStatement"))
+	}
+	stmt := &ast.IfStatement{If: p.currTkn.Pos}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if stmt.Condition == nil || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Consequence = p.parseStatement()
+	if stmt.Consequence == nil {
+		return nil
+	}
+
+	if p.peekTknIs(token.ELSE) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Alternative = p.parseStatement()
+		if stmt.Alternative == nil {
+			return nil
+		}
+	}
+	return stmt
+}
+
+// for (<<init>>; <<condition>>; <<post>>) <<body>>
+// All three parts in the parentheses are optional.
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	if p.trace {
+		defer un(trace("parseForStatement"))
+	}
+	stmt := &ast.ForStatement{For: p.currTkn.Pos}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	if !p.currTknIs(token.SEMICOLON) {
+		// The simple statement consumes the semicolon.
+		stmt.Init = p.parseSimpleStatement()
+		if stmt.Init == nil {
+			return nil
+		}
+	}
+
+	if p.peekTknIs(token.SEMICOLON) {
+		p.nextToken()
+	} else {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(LOWEST)
+		if stmt.Condition == nil || !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	if !p.peekTknIs(token.RPAREN) {
+		p.nextToken()
+		stmt.Post = p.parseExpression(LOWEST)
+		if stmt.Post == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Body = p.parseStatement()
+	if stmt.Body == nil {
+		return nil
+	}
+	return stmt
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	if p.trace {
+		defer un(trace("parseWhileStatement"))
+	}
+	stmt := &ast.WhileStatement{While: p.currTkn.Pos}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if stmt.Condition == nil || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Body = p.parseStatement()
+	if stmt.Body == nil {
+		return nil
+	}
+	return stmt
+}
+
+func (p *Parser) parseDoWhileStatement() *ast.DoWhileStatement {
+	if p.trace {
+		defer un(trace("parseDoWhileStatement"))
+	}
+	stmt := &ast.DoWhileStatement{Do: p.currTkn.Pos}
+
+	p.nextToken()
+	stmt.Body = p.parseStatement()
+	if stmt.Body == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.WHILE) || !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if stmt.Condition == nil || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	stmt.Rparen = p.currTkn.Pos
+
+	if !p.expectSemicolon() {
+		return nil
+	}
+	return stmt
+}
+
+func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	if p.trace {
+		defer un(trace("parseReturnStatement"))
+	}
+	stmt := &ast.ReturnStatement{Return: p.currTkn.Pos}
+
+	if p.fragment && p.peekTknIs(token.EOF) {
+		return stmt
+	}
+	if p.peekTknIs(token.SEMICOLON) {
+		p.nextToken()
+		return stmt
+	}
+
+	p.nextToken()
+	stmt.Result = p.parseExpression(LOWEST)
+	if stmt.Result == nil || !p.expectSemicolon() {
+		return nil
+	}
+	return stmt
+}
+
+func (p *Parser) parseEmitStatement() *ast.EmitStatement {
+	if p.trace {
+		defer un(trace("parseEmitStatement"))
+	}
+	stmt := &ast.EmitStatement{Emit: p.currTkn.Pos}
+
+	p.nextToken()
+	stmt.Call = p.parseCallStatementExpression("emit")
+	if stmt.Call == nil || !p.expectSemicolon() {
+		return nil
+	}
+	return stmt
+}
+
+// revert InsufficientBalance(balance, amount);
+func (p *Parser) parseRevertStatement() *ast.RevertStatement {
+	if p.trace {
+		defer un(trace("parseRevertStatement"))
+	}
+	stmt := &ast.RevertStatement{Revert: p.currTkn.Pos}
+
+	p.nextToken()
+	stmt.Call = p.parseCallStatementExpression("revert")
+	if stmt.Call == nil || !p.expectSemicolon() {
+		return nil
+	}
+	return stmt
+}
+
+// throw; is the legacy revert without a reason.
+func (p *Parser) parseThrowStatement() *ast.RevertStatement {
+	if p.trace {
+		defer un(trace("parseThrowStatement"))
+	}
+	stmt := &ast.RevertStatement{Revert: p.currTkn.Pos, Legacy: true}
+	p.legacyConstruct(stmt.Revert, "`throw`", "`revert()`")
+	if !p.expectSemicolon() {
+		return nil
+	}
+	return stmt
+}
+
+// parseCallStatementExpression parses the event or error call of the emit and
+// revert statements.
+func (p *Parser) parseCallStatementExpression(keyword string) *ast.CallExpression {
+	expr := p.parseExpression(LOWEST)
+	if expr == nil {
+		return nil
+	}
+	call, ok := expr.(*ast.CallExpression)
+	if !ok {
+		msg := fmt.Sprintf("expected a call after %s, got: %T instead (at offset: %d)",
+			keyword, expr, expr.Start())
+		p.error(expr.Start(), msg)
+		return nil
+	}
+	return call
+}
+
+// try <<expression>> returns (<<params>>) { ... } catch ... { ... }
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	if p.trace {
+		defer un(trace("parseTryStatement"))
+	}
+	stmt := &ast.TryStatement{Try: p.currTkn.Pos}
+
+	p.nextToken()
+	stmt.Expression = p.parseExpression(LOWEST)
+	if stmt.Expression == nil {
+		return nil
+	}
+
+	if p.peekTknIs(token.RETURNS) {
+		p.nextToken()
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		stmt.Returns = p.parseParamList()
+		if stmt.Returns == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	if stmt.Body == nil {
+		return nil
+	}
+
+	for p.peekTknIs(token.CATCH) {
+		p.nextToken()
+		clause := &ast.CatchClause{Catch: p.currTkn.Pos}
+		if p.peekTknIs(token.IDENTIFIER) {
+			p.nextToken()
+			clause.Kind = p.newIdentifier()
+		}
+		if p.peekTknIs(token.LPAREN) {
+			p.nextToken()
+			clause.Params = p.parseParamList()
+			if clause.Params == nil {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		clause.Body = p.parseBlockStatement()
+		if clause.Body == nil {
+			return nil
+		}
+		stmt.Catches = append(stmt.Catches, clause)
+	}
+
+	if len(stmt.Catches) == 0 {
+		p.peekError(token.CATCH)
+		return nil
+	}
+	return stmt
+}
+
+// parseAssemblyStatement skips over the inline assembly block. The Yul code
+// is kept as a raw string, with the Solidity variables it refers to.
+func (p *Parser) parseAssemblyStatement() *ast.AssemblyStatement {
+	if p.trace {
+		defer un(trace("parseAssemblyStatement"))
+	}
+	stmt := &ast.AssemblyStatement{Assembly: p.currTkn.Pos}
+
+	// Optional dialect e.g. "evmasm" and flags e.g. ("memory-safe")
+	if p.peekTknIs(token.STRING_LITERAL) {
+		p.nextToken()
+	}
+	if p.peekTknIs(token.LPAREN) {
+		for !p.currTknIs(token.RPAREN) && !p.currTknIs(token.EOF) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.LeftBrace = p.currTkn.Pos
+
+	depth := 1
+	for depth > 0 {
+		p.nextToken()
+		switch p.currTkn.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+		case token.EOF:
+			p.error(p.currTkn.Pos, "expected } at the end of the assembly block")
+			return nil
+		}
+	}
+
+	stmt.RightBrace = p.currTkn.Pos
+	stmt.Body = p.file.Src()[stmt.LeftBrace+1 : stmt.RightBrace]
+	// The Yul code that doesn't parse is left to the compiler.
+	if refs, err := yul.References(stmt.Body, stmt.LeftBrace+1); err == nil {
+		for _, ref := range refs {
+			stmt.Identifiers = append(stmt.Identifiers, &ast.Identifier{NamePos: ref.Pos, Name: ref.Name})
+		}
+	}
+	return stmt
+}
+
+// parseSimpleStatement parses a variable declaration or an expression
+// statement. In Solidity we often can't tell them apart by looking at the
+// first token e.g. `Foo[] memory x` and `foo[1] = x` both start with an
+// identifier and a bracket. We parse an expression first and turn it into a
+// type if it's followed by a data location or an identifier.
+func (p *Parser) parseSimpleStatement() ast.Statement {
+	if p.trace {
+		defer un(trace("parseSimpleStatement"))
+	}
+	if p.currTknIs(token.LPAREN) {
+		return p.parseTupleStatement()
+	}
+
+	expr := p.parseExpression(LOWEST)
+	if expr == nil {
+		return nil
+	}
+
+	if p.peekTknIs(token.IDENTIFIER) || isDataLocation(p.peekTkn.Type) {
+		decl := p.parseLocalVariable(expr)
+		if decl == nil {
+			return nil
+		}
+		stmt := &ast.VariableDeclarationStatement{
+			Declarations: []*ast.VariableDeclaration{decl},
+		}
+		if p.peekTknIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			stmt.Value = p.parseExpression(LOWEST)
+			if stmt.Value == nil {
+				return nil
+			}
+		}
+		if !p.expectSemicolon() {
+			return nil
+		}
+		return stmt
+	}
+
+	if !p.expectSemicolon() {
+		return nil
+	}
+	return &ast.ExpressionStatement{Expression: expr}
+}
+
+// var x = 1; or var (a, b) = f(); declare the legacy local variables of
+// the inferred types, which are left nil.
+func (p *Parser) parseVarStatement() *ast.VariableDeclarationStatement {
+	if p.trace {
+		defer un(trace("parseVarStatement"))
+	}
+	stmt := &ast.VariableDeclarationStatement{Var: p.currTkn.Pos}
+	p.legacyConstruct(stmt.Var, "`var`", "an explicit type")
+
+	if p.peekTknIs(token.LPAREN) {
+		p.nextToken()
+		stmt.Lparen = p.currTkn.Pos
+		for {
+			var decl *ast.VariableDeclaration
+			if p.peekTknIs(token.IDENTIFIER) {
+				p.nextToken()
+				decl = &ast.VariableDeclaration{Name: p.newIdentifier()}
+			}
+			stmt.Declarations = append(stmt.Declarations, decl)
+
+			if !p.peekTknIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		stmt.Rparen = p.currTkn.Pos
+	} else {
+		p.nextToken()
+		stmt.Declarations = []*ast.VariableDeclaration{{Name: p.newIdentifier()}}
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value == nil || !p.expectSemicolon() {
+		return nil
+	}
+	return stmt
+}
+
+// parseLocalVariable parses the rest of a local variable declaration after
+// its type e.g. `memory data` in `bytes memory data`.
+func (p *Parser) parseLocalVariable(typ ast.Expression) *ast.VariableDeclaration {
+	decl := &ast.VariableDeclaration{}
+	decl.Type = p.typeFromExpression(typ)
+	if decl.Type == nil {
+		return nil
+	}
+	if isDataLocation(p.peekTkn.Type) {
+		p.nextToken()
+		decl.Location = toDataLocation(p.currTkn.Type)
+	}
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+	decl.Name = p.newIdentifier()
+	return decl
+}
+
+// parseTupleStatement parses statements starting with a parenthesis. It's
+// either a tuple declaration e.g. `(bool ok, ) = to.call("")` or an
+// expression e.g. `(a, b) = (b, a)`.
+func (p *Parser) parseTupleStatement() ast.Statement {
+	if p.trace {
+		defer un(trace("parseTupleStatement"))
+	}
+	lparen := p.currTkn.Pos
+	if p.peekTknIs(token.RPAREN) {
+		// An empty tuple can only be an expression.
+		expr := p.parseExpression(LOWEST)
+		if expr == nil || !p.expectSemicolon() {
+			return nil
+		}
+		return &ast.ExpressionStatement{Expression: expr}
+	}
+
+	elements := []ast.Expression{}
+	decls := []*ast.VariableDeclaration{}
+	isDecl := false
+	for {
+		var elem ast.Expression
+		var decl *ast.VariableDeclaration
+		if !p.peekTknIs(token.COMMA) && !p.peekTknIs(token.RPAREN) {
+			p.nextToken()
+			elem = p.parseExpression(LOWEST)
+			if elem == nil {
+				return nil
+			}
+			if p.peekTknIs(token.IDENTIFIER) || isDataLocation(p.peekTkn.Type) {
+				decl = p.parseLocalVariable(elem)
+				if decl == nil {
+					return nil
+				}
+				isDecl = true
+			}
+		}
+		elements = append(elements, elem)
+		decls = append(decls, decl)
+
+		if !p.peekTknIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	rparen := p.currTkn.Pos
+
+	if isDecl {
+		for i, elem := range elements {
+			if elem != nil && decls[i] == nil {
+				msg := fmt.Sprintf("expected a variable declaration (at offset: %d)", elem.Start())
+				p.error(elem.Start(), msg)
+				return nil
+			}
+		}
+		stmt := &ast.VariableDeclarationStatement{
+			Lparen:       lparen,
+			Declarations: decls,
+			Rparen:       rparen,
+		}
+		if !p.expectPeek(token.ASSIGN) {
+			return nil
+		}
+		p.nextToken()
+		stmt.Value = p.parseExpression(LOWEST)
+		if stmt.Value == nil || !p.expectSemicolon() {
+			return nil
+		}
+		return stmt
+	}
+
+	tuple := &ast.TupleExpression{
+		Lparen:   lparen,
+		Elements: elements,
+		Rparen:   rparen,
+	}
+	expr := p.parseInfixExpressions(tuple, LOWEST)
+	if expr == nil || !p.expectSemicolon() {
+		return nil
+	}
+	return &ast.ExpressionStatement{Expression: expr}
+}
@@ -0,0 +1,236 @@
+// project reads the configuration of a Solidity project: the default
+// Foundry profile from foundry.toml, the import remappings from
+// remappings.txt and solbot's own settings from solbot.toml. Only the
+// settings that influence the analysis and the compilation are read,
+// everything else is ignored.
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"solbot/clones"
+	"solbot/vfs"
+	"strconv"
+	"strings"
+)
+
+type Config struct {
+	Src             string      // directory with the contract sources e.g. "src"
+	Test            string      // directory with the Foundry tests e.g. "test"
+	Libs            []string    // directories with the dependencies e.g. ["lib"]
+	AllowPaths      []string    // directories outside of the root the imports may be read from e.g. ["../shared"]
+	Remappings      []Remapping // import remappings from foundry.toml and remappings.txt
+	Optimizer       bool        // is the optimizer enabled?
+	OptimizerRuns   int         // number of optimizer runs
+	EVMVersion      string      // target EVM version; or empty for the compiler default
+	SolcVersion     string      // pinned compiler version; or empty
+	Metrics         Metrics     // function metric thresholds from solbot.toml
+	ContractSize    int         // estimated bytecode size in bytes above which the contracts are reported; or 0
+	Migration       string      // pragma the files are checked against e.g. "^0.8.0"; or empty
+	InlayHints      InlayHints  // categories of the inlay hints shown in the editor
+	CodeLens        CodeLens    // kinds of the code lenses shown in the editor
+	ProxyBases      []string    // names of the base contracts that make a contract a proxy
+	Upgradeable     []string    // name patterns of the base contracts that make a contract upgradeable e.g. "*Upgradeable"
+	Imports         Imports     // how the imports are organized
+	TrustedSpenders []string    // constant names or addresses of the spenders approved without the reset to zero
+	Disabled        []string    // codes of the disabled detectors e.g. ["screaming-snake-const"]
+	Documentation   Documentation
+	Docs            Docs
+	Files           Files
+	Whitespace      Whitespace
+	Parser          Parser // limits of the parser overriding the defaults
+	Clones          Clones
+}
+
+// DefaultConfig returns the defaults used by Foundry.
+func DefaultConfig() Config {
+	detection := clones.DefaultOptions()
+	return Config{
+		Src:           "src",
+		Test:          "test",
+		Libs:          []string{"lib"},
+		OptimizerRuns: 200,
+		Metrics:       Metrics{Severity: "warning"},
+		ContractSize:  24576, // the EIP-170 limit
+		InlayHints:    InlayHints{Numbers: true},
+		CodeLens:      CodeLens{References: true, Selectors: true},
+		Imports:       Imports{Groups: true},
+		ProxyBases:    []string{"Proxy", "ERC1967Proxy", "TransparentUpgradeableProxy", "BeaconProxy", "UpgradeableProxy"},
+		Upgradeable:   []string{"*Upgradeable", "Initializable"},
+		Clones:        Clones{MinLines: detection.MinLines, MinSimilarity: detection.MinSimilarity},
+	}
+}
+
+// Load reads the configuration of the project in the root directory. The
+// missing files are not an error, the defaults are used instead.
+func Load(root string) (Config, error) {
+	return LoadFS(vfs.OS{}, root)
+}
+
+// LoadFS reads the configuration of the project in the root directory of
+// the file system, like Load.
+func LoadFS(fsys vfs.FS, root string) (Config, error) {
+	cfg := DefaultConfig()
+
+	src, err := vfs.ReadFile(fsys, filepath.Join(root, "foundry.toml"))
+	if err == nil {
+		if err := cfg.parseFoundryToml(string(src)); err != nil {
+			return cfg, fmt.Errorf("foundry.toml: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return cfg, err
+	}
+
+	src, err = vfs.ReadFile(fsys, filepath.Join(root, "remappings.txt"))
+	if err == nil {
+		remappings, err := ParseRemappings(string(src))
+		if err != nil {
+			return cfg, fmt.Errorf("remappings.txt: %w", err)
+		}
+		cfg.Remappings = append(cfg.Remappings, remappings...)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return cfg, err
+	}
+
+	src, err = vfs.ReadFile(fsys, filepath.Join(root, "solbot.toml"))
+	if err == nil {
+		if err := cfg.parseSolbotToml(string(src)); err != nil {
+			return cfg, fmt.Errorf("solbot.toml: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// parseFoundryToml reads the [profile.default] section.
+func (cfg *Config) parseFoundryToml(src string) error {
+	return parseToml(src, func(section, key, value string) error {
+		if section != "profile.default" {
+			return nil
+		}
+		return cfg.set(key, value)
+	})
+}
+
+// parseToml calls set for every key with its raw value. It's not a full
+// TOML parser, it understands the strings, booleans, integers and arrays of
+// strings that appear in the Foundry and solbot configs.
+func parseToml(src string, set func(section, key, value string) error) error {
+	section := ""
+	lines := strings.Split(src, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[] ")
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		// Arrays can span multiple lines.
+		if strings.HasPrefix(value, "[") {
+			for !strings.HasSuffix(value, "]") && i+1 < len(lines) {
+				i++
+				value += " " + strings.TrimSpace(stripComment(lines[i]))
+			}
+		}
+
+		if err := set(section, key, value); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) set(key, value string) error {
+	var err error
+	switch key {
+	case "src":
+		cfg.Src, err = parseString(value)
+	case "test":
+		cfg.Test, err = parseString(value)
+	case "libs":
+		cfg.Libs, err = parseStrings(value)
+	case "allow_paths":
+		cfg.AllowPaths, err = parseStrings(value)
+	case "remappings":
+		var list []string
+		list, err = parseStrings(value)
+		for _, s := range list {
+			r, rerr := ParseRemapping(s)
+			if rerr != nil {
+				return rerr
+			}
+			cfg.Remappings = append(cfg.Remappings, r)
+		}
+	case "optimizer":
+		cfg.Optimizer, err = strconv.ParseBool(value)
+	case "optimizer_runs":
+		cfg.OptimizerRuns, err = strconv.Atoi(strings.ReplaceAll(value, "_", ""))
+	case "evm_version":
+		cfg.EVMVersion, err = parseString(value)
+	case "solc", "solc_version":
+		cfg.SolcVersion, err = parseString(value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value of %s: %s", key, value)
+	}
+	return nil
+}
+
+func parseString(value string) (string, error) {
+	if len(value) < 2 || value[0] != value[len(value)-1] || (value[0] != '"' && value[0] != '\'') {
+		return "", fmt.Errorf("expected a string, got %s", value)
+	}
+	return value[1 : len(value)-1], nil
+}
+
+func parseStrings(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("expected an array, got %s", value)
+	}
+	res := []string{}
+	for _, elem := range strings.Split(value[1:len(value)-1], ",") {
+		elem = strings.TrimSpace(elem)
+		if elem == "" {
+			continue // trailing comma
+		}
+		s, err := parseString(elem)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// stripComment removes the `#` comment from the line, unless it's inside of
+// a string.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,267 @@
+package project
+
+import (
+	"slices"
+	"solbot/parser"
+	"testing"
+	"time"
+)
+
+func Test_ParseFoundryToml(t *testing.T) {
+	src := `
+[profile.default]
+src = "contracts"
+test = "tests"
+libs = ["lib", "node_modules"]
+allow_paths = ["../shared"]
+optimizer = true
+optimizer_runs = 10_000 # comment
+evm_version = "paris"
+remappings = [
+    "@openzeppelin/=lib/openzeppelin-contracts/",
+    "src/:forge-std/=lib/forge-std/src/",
+]
+
+[profile.ci]
+optimizer_runs = 1
+`
+
+	cfg := DefaultConfig()
+	if err := cfg.parseFoundryToml(src); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if cfg.Src != "contracts" {
+		t.Errorf("Expected src contracts, got %s", cfg.Src)
+	}
+	if cfg.Test != "tests" {
+		t.Errorf("Expected test tests, got %s", cfg.Test)
+	}
+	if len(cfg.Libs) != 2 || cfg.Libs[1] != "node_modules" {
+		t.Errorf("Expected libs [lib node_modules], got %v", cfg.Libs)
+	}
+	if len(cfg.AllowPaths) != 1 || cfg.AllowPaths[0] != "../shared" {
+		t.Errorf("Expected allow_paths [../shared], got %v", cfg.AllowPaths)
+	}
+	if !cfg.Optimizer || cfg.OptimizerRuns != 10000 {
+		t.Errorf("Expected optimizer with 10000 runs, got %t with %d runs", cfg.Optimizer, cfg.OptimizerRuns)
+	}
+	if cfg.EVMVersion != "paris" {
+		t.Errorf("Expected evm_version paris, got %s", cfg.EVMVersion)
+	}
+
+	expected := []string{
+		"@openzeppelin/=lib/openzeppelin-contracts/",
+		"src/:forge-std/=lib/forge-std/src/",
+	}
+	if len(cfg.Remappings) != len(expected) {
+		t.Fatalf("Expected %d remappings, got %d", len(expected), len(cfg.Remappings))
+	}
+	for i, r := range cfg.Remappings {
+		if r.String() != expected[i] {
+			t.Errorf("Expected remapping %s, got %s", expected[i], r)
+		}
+	}
+}
+
+func Test_Remap(t *testing.T) {
+	remappings, err := ParseRemappings(`
+@openzeppelin/=lib/openzeppelin-contracts/
+@openzeppelin/contracts/=lib/oz-v5/contracts/
+test/:@openzeppelin/=lib/oz-test/
+`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	tests := []struct {
+		importer   string
+		importPath string
+		expected   string
+	}{
+		{"src/Vault.sol", "@openzeppelin/utils/Address.sol", "lib/openzeppelin-contracts/utils/Address.sol"},
+		{"src/Vault.sol", "@openzeppelin/contracts/token/ERC20/IERC20.sol", "lib/oz-v5/contracts/token/ERC20/IERC20.sol"},
+		{"test/Vault.t.sol", "@openzeppelin/contracts/token/ERC20/IERC20.sol", "lib/oz-test/contracts/token/ERC20/IERC20.sol"},
+		{"src/Vault.sol", "src/IVault.sol", "src/IVault.sol"},
+	}
+
+	for _, tt := range tests {
+		if got, _ := Remap(remappings, tt.importer, tt.importPath); got != tt.expected {
+			t.Errorf("Expected %s in %s to be remapped to %s, got %s", tt.importPath, tt.importer, tt.expected, got)
+		}
+	}
+}
+
+func Test_Unmap(t *testing.T) {
+	remappings, err := ParseRemappings(`
+@openzeppelin/=lib/openzeppelin-contracts/
+@openzeppelin/contracts/=lib/oz-v5/contracts/
+test/:@openzeppelin/=lib/oz-test/
+`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	tests := []struct {
+		importer string
+		file     string
+		expected string // or empty if the file can't be imported through the remappings
+	}{
+		{"src/Vault.sol", "lib/openzeppelin-contracts/utils/Address.sol", "@openzeppelin/utils/Address.sol"},
+		{"src/Vault.sol", "lib/oz-v5/contracts/token/ERC20/IERC20.sol", "@openzeppelin/contracts/token/ERC20/IERC20.sol"},
+		{"test/Vault.t.sol", "lib/oz-test/utils/Address.sol", "@openzeppelin/utils/Address.sol"},
+		// The context doesn't apply to the importer.
+		{"src/Vault.sol", "lib/oz-test/utils/Address.sol", ""},
+		// The longer prefix takes the import path over.
+		{"src/Vault.sol", "lib/openzeppelin-contracts/contracts/Ownable.sol", ""},
+		{"src/Vault.sol", "src/IVault.sol", ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := Unmap(remappings, tt.importer, tt.file)
+		if !ok {
+			got = ""
+		}
+		if got != tt.expected {
+			t.Errorf("Expected %s in %s to be imported as %q, got %q", tt.file, tt.importer, tt.expected, got)
+		}
+	}
+}
+
+func Test_ParseSolbotToml(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Metrics.Enabled() {
+		t.Fatalf("Expected the metrics to be disabled by default")
+	}
+
+	err := cfg.parseSolbotToml(`
+[metrics]
+complexity = 10
+nesting = 4
+severity = "hint"
+`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	expected := Metrics{Complexity: 10, Nesting: 4, Severity: "hint"}
+	if cfg.Metrics != expected {
+		t.Errorf("Expected %+v, got %+v", expected, cfg.Metrics)
+	}
+
+	if err := cfg.parseSolbotToml("[metrics]\nseverity = \"error\""); err == nil {
+		t.Errorf("Expected an error for an unknown severity, got nil")
+	}
+
+	if cfg.ContractSize != 24576 {
+		t.Errorf("Expected the EIP-170 limit by default, got %d", cfg.ContractSize)
+	}
+	if err := cfg.parseSolbotToml("[contract_size]\nthreshold = 20_000"); err != nil || cfg.ContractSize != 20000 {
+		t.Errorf("Expected the contract size threshold 20000, got %d and error %v", cfg.ContractSize, err)
+	}
+	if err := cfg.parseSolbotToml("[contract_size]\nlimit = 1"); err == nil {
+		t.Errorf("Expected an error for an unknown contract_size setting, got nil")
+	}
+
+	if err := cfg.parseSolbotToml("[migration]\ntarget = \"^0.8.0\""); err != nil || cfg.Migration != "^0.8.0" {
+		t.Errorf("Expected the migration target ^0.8.0, got %q and error %v", cfg.Migration, err)
+	}
+	if err := cfg.parseSolbotToml("[migration]\ntarget = \"latest\""); err == nil {
+		t.Errorf("Expected an error for an invalid target, got nil")
+	}
+
+	if !cfg.InlayHints.Numbers {
+		t.Errorf("Expected the number hints to be enabled by default")
+	}
+	if err := cfg.parseSolbotToml("[inlay_hints]\nnumbers = false"); err != nil || cfg.InlayHints.Numbers {
+		t.Errorf("Expected the number hints to be disabled, got %v and error %v", cfg.InlayHints.Numbers, err)
+	}
+
+	if !cfg.CodeLens.References || !cfg.CodeLens.Selectors {
+		t.Errorf("Expected the reference and selector lenses to be enabled by default, got %+v", cfg.CodeLens)
+	}
+	if err := cfg.parseSolbotToml("[code_lens]\nselectors = false"); err != nil || !cfg.CodeLens.References || cfg.CodeLens.Selectors {
+		t.Errorf("Expected only the selector lenses to be disabled, got %+v and error %v", cfg.CodeLens, err)
+	}
+	if cfg.CodeLens.Tests {
+		t.Errorf("Expected the test lenses to be disabled by default")
+	}
+	if err := cfg.parseSolbotToml("[code_lens]\ntests = true"); err != nil || !cfg.CodeLens.Tests {
+		t.Errorf("Expected the test lenses to be enabled, got %+v and error %v", cfg.CodeLens, err)
+	}
+	if err := cfg.parseSolbotToml("[code_lens]\ngas = true"); err == nil {
+		t.Errorf("Expected an error for an unknown code lens kind, got nil")
+	}
+
+	if !slices.Contains(cfg.ProxyBases, "ERC1967Proxy") {
+		t.Errorf("Expected the OpenZeppelin proxies by default, got %v", cfg.ProxyBases)
+	}
+	if err := cfg.parseSolbotToml("[proxy]\nbases = [\"MyProxy\"]"); err != nil || !slices.Equal(cfg.ProxyBases, []string{"MyProxy"}) {
+		t.Errorf("Expected the proxy bases [MyProxy], got %v and error %v", cfg.ProxyBases, err)
+	}
+
+	if err := cfg.parseSolbotToml("[imports]\ngroups = false\nnamed = true"); err != nil || cfg.Imports != (Imports{Named: true}) {
+		t.Errorf("Expected the named imports without the groups, got %+v and error %v", cfg.Imports, err)
+	}
+
+	if !slices.Equal(cfg.Upgradeable, []string{"*Upgradeable", "Initializable"}) {
+		t.Errorf("Expected the OpenZeppelin upgradeable bases by default, got %v", cfg.Upgradeable)
+	}
+	if err := cfg.parseSolbotToml("[upgradeable]\nbases = [\"[\"]"); err == nil {
+		t.Errorf("Expected the invalid pattern to be an error")
+	}
+	if err := cfg.parseSolbotToml("[erc20]\ntrusted_spenders = [\"ROUTER\"]"); err != nil || !slices.Equal(cfg.TrustedSpenders, []string{"ROUTER"}) {
+		t.Errorf("Expected the trusted spenders [ROUTER], got %v and error %v", cfg.TrustedSpenders, err)
+	}
+	if cfg.Documentation.Coverage {
+		t.Errorf("Expected the documentation coverage to be off by default")
+	}
+	if err := cfg.parseSolbotToml("[documentation]\ncoverage = true"); err != nil || !cfg.Documentation.Coverage {
+		t.Errorf("Expected the documentation coverage to be on, got %+v and error %v", cfg.Documentation, err)
+	}
+	if cfg.Docs.Analysis {
+		t.Errorf("Expected the analysis of the docs to be off by default")
+	}
+	if err := cfg.parseSolbotToml("[docs]\nanalysis = true\ndiagnostics = [\"unused-variable\"]"); err != nil || !cfg.Docs.Analysis || !slices.Equal(cfg.Docs.Diagnostics, []string{"unused-variable"}) {
+		t.Errorf("Expected the analysis of the docs with unused-variable, got %+v and error %v", cfg.Docs, err)
+	}
+	if err := cfg.parseSolbotToml("[files]\nexclude = [\"script/\"]\ninclude = [\"out/Flat.sol\"]"); err != nil || !slices.Equal(cfg.Files.Exclude, []string{"script/"}) || !slices.Equal(cfg.Files.Include, []string{"out/Flat.sol"}) {
+		t.Errorf("Expected the excluded script/ and the included out/Flat.sol, got %+v and error %v", cfg.Files, err)
+	}
+	if err := cfg.parseSolbotToml("[files]\nignore = [\"out/\"]"); err == nil {
+		t.Errorf("Expected the unknown files setting to be an error")
+	}
+	if cfg.Whitespace != (Whitespace{}) {
+		t.Errorf("Expected the whitespace checks to be off by default, got %+v", cfg.Whitespace)
+	}
+	expectedWhitespace := Whitespace{Trailing: true, Indentation: 4, MaxLineLength: 120}
+	if err := cfg.parseSolbotToml("[whitespace]\ntrailing = true\nindentation = 4\nmax_line_length = 120"); err != nil || cfg.Whitespace != expectedWhitespace {
+		t.Errorf("Expected %+v, got %+v and error %v", expectedWhitespace, cfg.Whitespace, err)
+	}
+	if err := cfg.parseSolbotToml("[whitespace]\ntabs = true"); err == nil {
+		t.Errorf("Expected an error for an unknown whitespace setting, got nil")
+	}
+	// The limits of the parser which aren't set keep the defaults.
+	if err := cfg.parseSolbotToml("[parser]\nmax_expression_depth = 2_000\nmax_nodes = 0\ntimeout = \"2m\""); err != nil {
+		t.Errorf("Expected no error for the parser limits, got %s", err)
+	}
+	expectedLimits := parser.Limits{MaxExpressionDepth: 2000, MaxStatementDepth: 7, MaxDeclarations: 7, MaxNodes: 0, Timeout: 2 * time.Minute}
+	if limits := cfg.Parser.Apply(parser.Limits{MaxExpressionDepth: 7, MaxStatementDepth: 7, MaxDeclarations: 7, MaxNodes: 7, Timeout: 7}); limits != expectedLimits {
+		t.Errorf("Expected the parser limits %+v, got %+v", expectedLimits, limits)
+	}
+	if err := cfg.parseSolbotToml("[parser]\ntimeout = \"soon\""); err == nil {
+		t.Errorf("Expected an error for an invalid timeout, got nil")
+	}
+	if cfg.Clones.Diagnostics {
+		t.Errorf("Expected the clone diagnostics to be off by default")
+	}
+	expectedClones := Clones{Diagnostics: true, MinLines: 12, MinSimilarity: 0.85}
+	if err := cfg.parseSolbotToml("[clones]\ndiagnostics = true\nmin_lines = 12\nmin_similarity = 0.85"); err != nil || cfg.Clones != expectedClones {
+		t.Errorf("Expected %+v, got %+v and error %v", expectedClones, cfg.Clones, err)
+	}
+	if err := cfg.parseSolbotToml("[clones]\nmin_similarity = 1.5"); err == nil {
+		t.Errorf("Expected an error for a similarity above 1, got nil")
+	}
+	if err := cfg.parseSolbotToml("[detectors]\ndisabled = [\"msg-value-loop\"]"); err != nil || !slices.Equal(cfg.Disabled, []string{"msg-value-loop"}) {
+		t.Errorf("Expected the disabled detectors [msg-value-loop], got %v and error %v", cfg.Disabled, err)
+	}
+}
@@ -0,0 +1,109 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Remapping rewrites the import paths that start with the prefix e.g.
+// `@openzeppelin/=lib/openzeppelin-contracts/`. If the context is not
+// empty, the remapping applies only to the files whose path starts with it.
+// The format is the same as the one used by solc: `context:prefix=target`.
+type Remapping struct {
+	Context string
+	Prefix  string
+	Target  string
+}
+
+// ParseRemapping parses a single remapping e.g. "forge-std/=lib/forge-std/src/".
+func ParseRemapping(s string) (Remapping, error) {
+	s = strings.TrimSpace(s)
+	lhs, target, found := strings.Cut(s, "=")
+	if !found || lhs == "" {
+		return Remapping{}, fmt.Errorf("invalid remapping %q: expected prefix=target", s)
+	}
+	r := Remapping{Prefix: lhs, Target: target}
+	if context, prefix, found := strings.Cut(lhs, ":"); found {
+		r.Context, r.Prefix = context, prefix
+	}
+	if r.Prefix == "" {
+		return Remapping{}, fmt.Errorf("invalid remapping %q: the prefix is empty", s)
+	}
+	return r, nil
+}
+
+// ParseRemappings parses one remapping per line, the format of remappings.txt.
+func ParseRemappings(src string) ([]Remapping, error) {
+	res := []Remapping{}
+	for _, line := range strings.Split(src, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		r, err := ParseRemapping(line)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+func (r Remapping) String() string {
+	if r.Context != "" {
+		return r.Context + ":" + r.Prefix + "=" + r.Target
+	}
+	return r.Prefix + "=" + r.Target
+}
+
+// Remap applies the remappings to the import path found in the importer,
+// the same way solc does: the longest matching context wins, then the
+// longest matching prefix. The importer is the path relative to the project
+// root. The second result is false if no remapping applies.
+func Remap(remappings []Remapping, importer, importPath string) (string, bool) {
+	var best *Remapping
+	for i := range remappings {
+		r := &remappings[i]
+		if !strings.HasPrefix(importer, r.Context) || !strings.HasPrefix(importPath, r.Prefix) {
+			continue
+		}
+		if best == nil || len(r.Context) > len(best.Context) ||
+			(len(r.Context) == len(best.Context) && len(r.Prefix) > len(best.Prefix)) {
+			best = r
+		}
+	}
+	if best == nil {
+		return importPath, false
+	}
+	return best.Target + importPath[len(best.Prefix):], true
+}
+
+// Unmap is the reverse of Remap: it returns the import path the importer
+// imports the file with through the remappings e.g.
+// "@openzeppelin/utils/Address.sol" for
+// "lib/openzeppelin-contracts/utils/Address.sol". Both are paths relative to
+// the project root. The longest matching context wins, then the longest
+// matching target. The second result is false if the file is outside of the
+// targets of the remappings applying to the importer.
+func Unmap(remappings []Remapping, importer, file string) (string, bool) {
+	var best *Remapping
+	for i := range remappings {
+		r := &remappings[i]
+		if !strings.HasPrefix(importer, r.Context) || !strings.HasPrefix(file, r.Target) {
+			continue
+		}
+		if best == nil || len(r.Context) > len(best.Context) ||
+			(len(r.Context) == len(best.Context) && len(r.Target) > len(best.Target)) {
+			best = r
+		}
+	}
+	if best == nil {
+		return file, false
+	}
+	importPath := best.Prefix + file[len(best.Target):]
+	// The import path must lead back to the file, the remapping of a
+	// longer prefix may take over.
+	if remapped, _ := Remap(remappings, importer, importPath); remapped != file {
+		return file, false
+	}
+	return importPath, true
+}
@@ -0,0 +1,471 @@
+package project
+
+import (
+	"fmt"
+	"path"
+	"solbot/clones"
+	"solbot/parser"
+	"solbot/semver"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metrics are the thresholds above which the function metrics are reported
+// as diagnostics. A zero threshold disables the diagnostic, all of them are
+// disabled by default. They are set in the [metrics] section of solbot.toml:
+//
+//	[metrics]
+//	complexity = 10
+//	nesting = 4
+//	severity = "hint"
+type Metrics struct {
+	Complexity int    // cyclomatic complexity, including the invoked modifiers
+	Statements int    // number of statements in the body
+	Parameters int    // number of input parameters
+	Nesting    int    // depth of the nested control flow statements
+	Severity   string // "warning" or "hint"
+}
+
+// Enabled reports whether any of the thresholds is set.
+func (m Metrics) Enabled() bool {
+	return m.Complexity > 0 || m.Statements > 0 || m.Parameters > 0 || m.Nesting > 0
+}
+
+// InlayHints turn the categories of the inlay hints on and off in the
+// [inlay_hints] section of solbot.toml:
+//
+//	[inlay_hints]
+//	numbers = false
+type InlayHints struct {
+	Numbers bool // readable forms of the large numbers e.g. "= 1 ether"
+}
+
+// CodeLens turns the kinds of the code lenses on and off in the
+// [code_lens] section of solbot.toml:
+//
+//	[code_lens]
+//	references = true
+//	selectors = false
+//	tests = true
+type CodeLens struct {
+	References bool // "N references" above the declarations
+	Selectors  bool // selectors above the external and public functions
+	Tests      bool // "run | debug" above the Foundry tests; off by default
+}
+
+// Imports configure the organize imports action in the [imports] section of
+// solbot.toml:
+//
+//	[imports]
+//	groups = true
+//	named = true
+type Imports struct {
+	Groups bool // separate the remapped paths from the relative ones with a blank line
+	Named  bool // convert the plain imports to the named ones
+}
+
+// Documentation configures the NatSpec checks in the [documentation]
+// section of solbot.toml:
+//
+//	[documentation]
+//	coverage = true
+type Documentation struct {
+	Coverage bool // report the external and public functions without NatSpec
+}
+
+// Docs turns the analysis of the fenced Solidity code blocks of the
+// Markdown files on in the [docs] section of solbot.toml. The syntax
+// errors are reported in the blocks, and the diagnostics of the listed
+// codes too:
+//
+//	[docs]
+//	analysis = true
+//	diagnostics = ["undeclared-identifier", "unused-variable"]
+type Docs struct {
+	Analysis    bool     // analyze the ```solidity blocks of the .md files
+	Diagnostics []string // codes of the diagnostics reported in the blocks besides the syntax errors
+}
+
+// Files configure the files of the workspace that are analyzed in the
+// [files] section of solbot.toml, with the patterns of .gitignore. The
+// excluded files are never analyzed, and the included ones are analyzed
+// even if they're ignored by git:
+//
+//	[files]
+//	exclude = ["script/"]
+//	include = ["out/Flattened.sol"]
+type Files struct {
+	Exclude []string // patterns of the paths left out of the workspace
+	Include []string // patterns of the paths analyzed even if git ignores them
+}
+
+// Whitespace turns the whitespace checks on in the [whitespace] section of
+// solbot.toml, all of them are off by default. The indentation is checked
+// against the depth of the brackets, in the columns of an indentation
+// level, a tab counting as one level:
+//
+//	[whitespace]
+//	mixed_indentation = true
+//	trailing = true
+//	final_newline = true
+//	indentation = 4
+//	indentation_tolerance = 1
+//	max_line_length = 120
+type Whitespace struct {
+	MixedIndentation     bool // tabs and spaces in the indentation of a line
+	Trailing             bool // whitespace at the ends of the lines
+	FinalNewline         bool // no newline at the end of the file
+	Indentation          int  // columns of an indentation level; or 0 not to check the indentation
+	IndentationTolerance int  // columns the indentation may be off by
+	MaxLineLength        int  // characters of a line; or 0
+}
+
+// Clones configures the detection of the copied function bodies in the
+// [clones] section of solbot.toml. The diagnostics are off by default, and
+// the thresholds apply to `solbot clones` too, see clones.Options:
+//
+//	[clones]
+//	diagnostics = true
+//	min_lines = 8
+//	min_similarity = 0.9
+type Clones struct {
+	Diagnostics   bool    // report the clones as hints in the editor
+	MinLines      int     // lines of the bodies below which they're not compared
+	MinSimilarity float64 // similarity of the bodies, from 0 to 1, below which they're not clones
+}
+
+// Options returns the thresholds of the detection.
+func (c Clones) Options() clones.Options {
+	return clones.Options{MinLines: c.MinLines, MinSimilarity: c.MinSimilarity}
+}
+
+func (c *Clones) set(key, value string) error {
+	var err error
+	switch key {
+	case "diagnostics":
+		c.Diagnostics, err = strconv.ParseBool(value)
+	case "min_lines":
+		c.MinLines, err = parseThreshold(value)
+	case "min_similarity":
+		c.MinSimilarity, err = strconv.ParseFloat(value, 64)
+		if err == nil && (c.MinSimilarity < 0 || c.MinSimilarity > 1) {
+			return fmt.Errorf("invalid value of min_similarity: %s, expected a number from 0 to 1", value)
+		}
+	default:
+		return fmt.Errorf("unknown clones setting %s", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value of %s: %s", key, value)
+	}
+	return nil
+}
+
+// Parser overrides the limits of the parser in the [parser] section of
+// solbot.toml, see parser.Limits. The limits which aren't set keep the
+// defaults, which are tighter in the language server than in the command
+// line tools, and 0 is no limit:
+//
+//	[parser]
+//	max_expression_depth = 2000
+//	max_statement_depth = 1000
+//	max_declarations = 200_000
+//	max_nodes = 0
+//	timeout = "2m"
+type Parser struct {
+	MaxExpressionDepth *int
+	MaxStatementDepth  *int
+	MaxDeclarations    *int
+	MaxNodes           *int
+	Timeout            *time.Duration
+}
+
+// Apply returns the limits with the ones set in solbot.toml replaced.
+func (p Parser) Apply(limits parser.Limits) parser.Limits {
+	if p.MaxExpressionDepth != nil {
+		limits.MaxExpressionDepth = *p.MaxExpressionDepth
+	}
+	if p.MaxStatementDepth != nil {
+		limits.MaxStatementDepth = *p.MaxStatementDepth
+	}
+	if p.MaxDeclarations != nil {
+		limits.MaxDeclarations = *p.MaxDeclarations
+	}
+	if p.MaxNodes != nil {
+		limits.MaxNodes = *p.MaxNodes
+	}
+	if p.Timeout != nil {
+		limits.Timeout = *p.Timeout
+	}
+	return limits
+}
+
+func (p *Parser) set(key, value string) error {
+	if key == "timeout" {
+		s, err := parseString(value)
+		timeout, err2 := time.ParseDuration(s)
+		if err != nil || err2 != nil || timeout < 0 {
+			return fmt.Errorf("invalid value of timeout: %s", value)
+		}
+		p.Timeout = &timeout
+		return nil
+	}
+	var limit **int
+	switch key {
+	case "max_expression_depth":
+		limit = &p.MaxExpressionDepth
+	case "max_statement_depth":
+		limit = &p.MaxStatementDepth
+	case "max_declarations":
+		limit = &p.MaxDeclarations
+	case "max_nodes":
+		limit = &p.MaxNodes
+	default:
+		return fmt.Errorf("unknown parser setting %s", key)
+	}
+	n, err := parseThreshold(value)
+	if err != nil {
+		return fmt.Errorf("invalid value of %s: %s", key, value)
+	}
+	*limit = &n
+	return nil
+}
+
+func (w *Whitespace) set(key, value string) error {
+	var err error
+	switch key {
+	case "mixed_indentation":
+		w.MixedIndentation, err = strconv.ParseBool(value)
+	case "trailing":
+		w.Trailing, err = strconv.ParseBool(value)
+	case "final_newline":
+		w.FinalNewline, err = strconv.ParseBool(value)
+	case "indentation":
+		w.Indentation, err = parseThreshold(value)
+	case "indentation_tolerance":
+		w.IndentationTolerance, err = parseThreshold(value)
+	case "max_line_length":
+		w.MaxLineLength, err = parseThreshold(value)
+	default:
+		return fmt.Errorf("unknown whitespace setting %s", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value of %s: %s", key, value)
+	}
+	return nil
+}
+
+// parseSolbotToml reads the [metrics], [contract_size], [migration],
+// [inlay_hints], [code_lens], [proxy], [upgradeable], [erc20], [imports],
+// [documentation], [docs], [files], [whitespace], [parser], [clones] and
+// [detectors] sections. The threshold of the estimated contract size is
+// the EIP-170 limit by default, 0 disables it. The migration
+// mode reports the code that breaks when the pragmas are raised to the
+// target, while the proxy bases replace the well-known names of the
+// OpenZeppelin proxies, and the upgradeable bases, matched like the file
+// names, replace the OpenZeppelin upgradeable contracts. The trusted
+// spenders, the names of the constants or the addresses, can be approved
+// without resetting the allowance first:
+//
+//	[contract_size]
+//	threshold = 20_000
+//
+//	[migration]
+//	target = "^0.8.0"
+//
+//	[proxy]
+//	bases = ["Proxy", "MyProxy"]
+//
+//	[upgradeable]
+//	bases = ["*Upgradeable", "Initializable", "MyInitializable"]
+//
+//	[erc20]
+//	trusted_spenders = ["UNISWAP_ROUTER", "0x000000000022D473030F116dDEE9F6B43aC78BA3"]
+//
+//	[detectors]
+//	disabled = ["screaming-snake-const"]
+func (cfg *Config) parseSolbotToml(src string) error {
+	return parseToml(src, func(section, key, value string) error {
+		switch section {
+		case "metrics":
+			return cfg.Metrics.set(key, value)
+		case "contract_size":
+			if key != "threshold" {
+				return fmt.Errorf("unknown contract_size setting %s", key)
+			}
+			threshold, err := parseThreshold(value)
+			if err != nil {
+				return fmt.Errorf("invalid value of threshold: %s", value)
+			}
+			cfg.ContractSize = threshold
+		case "migration":
+			if key != "target" {
+				return fmt.Errorf("unknown migration setting %s", key)
+			}
+			target, err := parseString(value)
+			if err == nil {
+				_, err = semver.ParseConstraint(target)
+			}
+			if err != nil {
+				return fmt.Errorf("invalid value of target: %s", value)
+			}
+			cfg.Migration = target
+		case "inlay_hints":
+			if key != "numbers" {
+				return fmt.Errorf("unknown inlay hint category %s", key)
+			}
+			numbers, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value of numbers: %s", value)
+			}
+			cfg.InlayHints.Numbers = numbers
+		case "code_lens":
+			enabled, err := strconv.ParseBool(value)
+			switch {
+			case key != "references" && key != "selectors" && key != "tests":
+				return fmt.Errorf("unknown code lens kind %s", key)
+			case err != nil:
+				return fmt.Errorf("invalid value of %s: %s", key, value)
+			case key == "references":
+				cfg.CodeLens.References = enabled
+			case key == "selectors":
+				cfg.CodeLens.Selectors = enabled
+			default:
+				cfg.CodeLens.Tests = enabled
+			}
+		case "proxy":
+			if key != "bases" {
+				return fmt.Errorf("unknown proxy setting %s", key)
+			}
+			bases, err := parseStrings(value)
+			if err != nil {
+				return fmt.Errorf("invalid value of bases: %s", value)
+			}
+			cfg.ProxyBases = bases
+		case "upgradeable":
+			if key != "bases" {
+				return fmt.Errorf("unknown upgradeable setting %s", key)
+			}
+			bases, err := parseStrings(value)
+			if err == nil {
+				for _, base := range bases {
+					if _, err = path.Match(base, ""); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("invalid value of bases: %s", value)
+			}
+			cfg.Upgradeable = bases
+		case "erc20":
+			if key != "trusted_spenders" {
+				return fmt.Errorf("unknown erc20 setting %s", key)
+			}
+			spenders, err := parseStrings(value)
+			if err != nil {
+				return fmt.Errorf("invalid value of trusted_spenders: %s", value)
+			}
+			cfg.TrustedSpenders = spenders
+		case "documentation":
+			if key != "coverage" {
+				return fmt.Errorf("unknown documentation setting %s", key)
+			}
+			coverage, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value of coverage: %s", value)
+			}
+			cfg.Documentation.Coverage = coverage
+		case "docs":
+			switch key {
+			case "analysis":
+				analysis, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("invalid value of analysis: %s", value)
+				}
+				cfg.Docs.Analysis = analysis
+			case "diagnostics":
+				codes, err := parseStrings(value)
+				if err != nil {
+					return fmt.Errorf("invalid value of diagnostics: %s", value)
+				}
+				cfg.Docs.Diagnostics = codes
+			default:
+				return fmt.Errorf("unknown docs setting %s", key)
+			}
+		case "files":
+			patterns, err := parseStrings(value)
+			switch {
+			case key != "exclude" && key != "include":
+				return fmt.Errorf("unknown files setting %s", key)
+			case err != nil:
+				return fmt.Errorf("invalid value of %s: %s", key, value)
+			case key == "exclude":
+				cfg.Files.Exclude = patterns
+			default:
+				cfg.Files.Include = patterns
+			}
+		case "whitespace":
+			return cfg.Whitespace.set(key, value)
+		case "parser":
+			return cfg.Parser.set(key, value)
+		case "clones":
+			return cfg.Clones.set(key, value)
+		case "detectors":
+			if key != "disabled" {
+				return fmt.Errorf("unknown detectors setting %s", key)
+			}
+			disabled, err := parseStrings(value)
+			if err != nil {
+				return fmt.Errorf("invalid value of disabled: %s", value)
+			}
+			cfg.Disabled = disabled
+		case "imports":
+			enabled, err := strconv.ParseBool(value)
+			switch {
+			case key != "groups" && key != "named":
+				return fmt.Errorf("unknown imports setting %s", key)
+			case err != nil:
+				return fmt.Errorf("invalid value of %s: %s", key, value)
+			case key == "groups":
+				cfg.Imports.Groups = enabled
+			default:
+				cfg.Imports.Named = enabled
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Metrics) set(key, value string) error {
+	var err error
+	switch key {
+	case "complexity":
+		m.Complexity, err = parseThreshold(value)
+	case "statements":
+		m.Statements, err = parseThreshold(value)
+	case "parameters":
+		m.Parameters, err = parseThreshold(value)
+	case "nesting":
+		m.Nesting, err = parseThreshold(value)
+	case "severity":
+		m.Severity, err = parseString(value)
+		if err == nil && m.Severity != "warning" && m.Severity != "hint" {
+			return fmt.Errorf("invalid value of severity: %s, expected \"warning\" or \"hint\"", value)
+		}
+	default:
+		return fmt.Errorf("unknown metric %s", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value of %s: %s", key, value)
+	}
+	return nil
+}
+
+func parseThreshold(value string) (int, error) {
+	n, err := strconv.Atoi(strings.ReplaceAll(value, "_", ""))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a non-negative integer, got %s", value)
+	}
+	return n, nil
+}
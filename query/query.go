@@ -0,0 +1,281 @@
+// Package query parses the selectors of `solbot query`, a small language
+// matching the nodes of the syntax tree modeled after the CSS selectors e.g.
+//
+//	function[visibility=external][mutability!=view] > call[callee=*.delegatecall]
+//
+// The grammar is:
+//
+//	selector   = compound { combinator compound }
+//	combinator = " " | ">"                   // descendant or child
+//	compound   = ( kind | "*" ) { filter } | filter { filter }
+//	filter     = "[" attribute ( "=" | "!=" ) value "]"
+//	           | ":has(" [ ">" ] selector ")" | ":not(" selector ")"
+//
+// The values are glob patterns, the same as in path.Match, optionally
+// quoted. The nodes without a kind, like the blocks and the expression
+// statements, are transparent: the parent of a call in a function body is
+// the function.
+package query
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Kinds are the kinds of the nodes that can be selected.
+var Kinds = []string{
+	"contract", "function", "modifier", "event", "error", "struct", "enum", "variable", "param",
+	"call", "assignment", "binary", "unary", "conditional", "member", "index", "identifier", "literal", "new",
+	"if", "for", "while", "do", "return", "emit", "revert", "try", "catch", "unchecked", "assembly",
+	"import", "pragma", "using", "type",
+}
+
+// Attributes are the attributes of the nodes the filters can match.
+var Attributes = []string{"name", "kind", "visibility", "mutability", "location", "operator", "callee"}
+
+// Combinator is the relation of a step to the previous one.
+type Combinator int
+
+const (
+	Descendant Combinator = iota // any ancestor matches the previous step
+	Child                        // the parent matches the previous step
+)
+
+// Selector is a parsed selector. A node matches it if it matches the last
+// step and its ancestors match the other ones.
+type Selector struct {
+	Steps []Step
+}
+
+// Step is a compound selector e.g. `call[callee=*.delegatecall]`.
+type Step struct {
+	Combinator Combinator // relation to the previous step; or to the node of :has
+	Kind       string     // node kind e.g. "function"; or "*" for any kind
+	Filters    []Filter
+}
+
+// Filter is either an attribute filter e.g. `[visibility=external]` or a
+// pseudo-filter e.g. `:not(function[name=_*])`.
+type Filter struct {
+	Attribute string // e.g. "visibility"; or empty for the pseudo-filters
+	Negated   bool   // is the operator "!="?
+	Value     string // glob pattern
+
+	Pseudo   string    // "has" or "not"; or empty for the attribute filters
+	Selector *Selector // argument of the pseudo-filter
+}
+
+// Error is a syntax error in a selector.
+type Error struct {
+	Offset int // byte offset in the selector
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Offset+1, e.Msg)
+}
+
+// Parse parses the selector.
+func Parse(src string) (*Selector, error) {
+	p := parser{src: src}
+	sel, err := p.selector(false, false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %s, expected a combinator or the end of the selector", p.current())
+	}
+	return sel, nil
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &Error{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// current describes the character at the position for the errors.
+func (p *parser) current() string {
+	if p.pos >= len(p.src) {
+		return "end of the selector"
+	}
+	return fmt.Sprintf("`%c`", p.src[p.pos])
+}
+
+func (p *parser) skipSpaces() bool {
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *parser) peek(c byte) bool {
+	return p.pos < len(p.src) && p.src[p.pos] == c
+}
+
+// selector parses the steps up to the end or, if nested in a pseudo-filter,
+// up to the closing parenthesis. The relative selectors of :has can start
+// with the child combinator.
+func (p *parser) selector(nested, relative bool) (*Selector, error) {
+	sel := &Selector{}
+	p.skipSpaces()
+	combinator := Descendant
+	if relative && p.peek('>') {
+		p.pos++
+		p.skipSpaces()
+		combinator = Child
+	}
+	if p.pos == len(p.src) || nested && p.peek(')') {
+		return nil, p.errorf("empty selector")
+	}
+	for {
+		step, err := p.step()
+		if err != nil {
+			return nil, err
+		}
+		step.Combinator = combinator
+		sel.Steps = append(sel.Steps, step)
+
+		spaced := p.skipSpaces()
+		switch {
+		case p.pos == len(p.src) || nested && p.peek(')'):
+			return sel, nil
+		case p.peek('>'):
+			p.pos++
+			p.skipSpaces()
+			combinator = Child
+		case spaced:
+			combinator = Descendant
+		default:
+			return nil, p.errorf("unexpected %s, expected a combinator", p.current())
+		}
+	}
+}
+
+func (p *parser) step() (Step, error) {
+	step := Step{Kind: "*"}
+	switch {
+	case p.peek('*'):
+		p.pos++
+	case p.peek('[') || p.peek(':'):
+	default:
+		start := p.pos
+		kind := p.name()
+		if kind == "" {
+			return step, p.errorf("unexpected %s, expected a node kind, `*`, `[` or `:`", p.current())
+		}
+		if !slices.Contains(Kinds, kind) {
+			p.pos = start
+			return step, p.errorf("unknown node kind `%s`, expected one of %s", kind, strings.Join(Kinds, ", "))
+		}
+		step.Kind = kind
+	}
+
+	for p.peek('[') || p.peek(':') {
+		var filter Filter
+		var err error
+		if p.peek('[') {
+			filter, err = p.attributeFilter()
+		} else {
+			filter, err = p.pseudoFilter()
+		}
+		if err != nil {
+			return step, err
+		}
+		step.Filters = append(step.Filters, filter)
+	}
+	return step, nil
+}
+
+func (p *parser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) && ('a' <= p.src[p.pos] && p.src[p.pos] <= 'z' || p.src[p.pos] == '-') {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) attributeFilter() (Filter, error) {
+	p.pos++ // "["
+	p.skipSpaces()
+	start := p.pos
+	attr := p.name()
+	if attr == "" {
+		return Filter{}, p.errorf("unexpected %s, expected an attribute", p.current())
+	}
+	if !slices.Contains(Attributes, attr) {
+		p.pos = start
+		return Filter{}, p.errorf("unknown attribute `%s`, expected one of %s", attr, strings.Join(Attributes, ", "))
+	}
+	filter := Filter{Attribute: attr}
+	p.skipSpaces()
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "!="):
+		filter.Negated = true
+		p.pos += 2
+	case p.peek('='):
+		p.pos++
+	default:
+		return Filter{}, p.errorf("unexpected %s, expected `=` or `!=` after the attribute", p.current())
+	}
+
+	p.skipSpaces()
+	start = p.pos
+	if p.peek('"') || p.peek('\'') {
+		quote := p.src[p.pos]
+		end := strings.IndexByte(p.src[p.pos+1:], quote)
+		if end < 0 {
+			return Filter{}, p.errorf("quoted value not closed")
+		}
+		filter.Value = p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+	} else {
+		for p.pos < len(p.src) && p.src[p.pos] != ']' && p.src[p.pos] != ' ' {
+			p.pos++
+		}
+		filter.Value = p.src[start:p.pos]
+	}
+	if filter.Value == "" {
+		p.pos = start
+		return Filter{}, p.errorf("empty value of `%s`", attr)
+	}
+	if _, err := path.Match(filter.Value, ""); err != nil {
+		p.pos = start
+		return Filter{}, p.errorf("invalid pattern `%s`", filter.Value)
+	}
+	p.skipSpaces()
+	if !p.peek(']') {
+		return Filter{}, p.errorf("unexpected %s, expected `]` to close the filter", p.current())
+	}
+	p.pos++
+	return filter, nil
+}
+
+func (p *parser) pseudoFilter() (Filter, error) {
+	p.pos++ // ":"
+	start := p.pos
+	pseudo := p.name()
+	if pseudo != "has" && pseudo != "not" {
+		p.pos = start
+		return Filter{}, p.errorf("unknown pseudo-filter `:%s`, expected `:has` or `:not`", pseudo)
+	}
+	if !p.peek('(') {
+		return Filter{}, p.errorf("unexpected %s, expected `(` after `:%s`", p.current(), pseudo)
+	}
+	p.pos++
+	sel, err := p.selector(true, pseudo == "has")
+	if err != nil {
+		return Filter{}, err
+	}
+	if !p.peek(')') {
+		return Filter{}, p.errorf("unexpected %s, expected `)` to close `:%s`", p.current(), pseudo)
+	}
+	p.pos++
+	return Filter{Pseudo: pseudo, Selector: sel}, nil
+}
@@ -0,0 +1,67 @@
+package query
+
+import (
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	sel, err := Parse("function[visibility=external][mutability!=view] > call[callee='*.delegatecall']")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(sel.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %v", sel.Steps)
+	}
+	fn, call := sel.Steps[0], sel.Steps[1]
+	if fn.Kind != "function" || len(fn.Filters) != 2 || fn.Filters[1] != (Filter{Attribute: "mutability", Negated: true, Value: "view"}) {
+		t.Errorf("Expected the function with two filters, got %+v", fn)
+	}
+	if call.Combinator != Child || call.Kind != "call" || call.Filters[0].Value != "*.delegatecall" {
+		t.Errorf("Expected the child call of *.delegatecall, got %+v", call)
+	}
+
+	sel, err = Parse("contract :has(> function[name=_*]):not(contract[kind=library])")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	step := sel.Steps[1]
+	if step.Combinator != Descendant || step.Kind != "*" || len(step.Filters) != 2 {
+		t.Fatalf("Expected any descendant with two pseudo-filters, got %+v", step)
+	}
+	if has := step.Filters[0]; has.Pseudo != "has" || has.Selector.Steps[0].Combinator != Child {
+		t.Errorf("Expected :has with a child selector, got %+v", has)
+	}
+}
+
+func Test_ParseErrors(t *testing.T) {
+	tests := []struct {
+		selector string
+		expected string
+	}{
+		{"", "column 1: empty selector"},
+		{"fucntion", "column 1: unknown node kind `fucntion`, expected one of contract, function, modifier, event, error, struct, enum, " +
+			"variable, param, call, assignment, binary, unary, conditional, member, index, identifier, literal, new, if, for, while, do, " +
+			"return, emit, revert, try, catch, unchecked, assembly, import, pragma, using, type"},
+		{"function[visibility]", "column 20: unexpected `]`, expected `=` or `!=` after the attribute"},
+		{"function[colour=red]", "column 10: unknown attribute `colour`, expected one of name, kind, visibility, mutability, location, operator, callee"},
+		{"function[name=]", "column 15: empty value of `name`"},
+		{"function[name=x", "column 16: unexpected end of the selector, expected `]` to close the filter"},
+		{"call[callee=[a]", "column 13: invalid pattern `[a`"},
+		{"function > > call", "column 12: unexpected `>`, expected a node kind, `*`, `[` or `:`"},
+		{"function:is(call)", "column 10: unknown pseudo-filter `:is`, expected `:has` or `:not`"},
+		{"function:has(call", "column 18: unexpected end of the selector, expected `)` to close `:has`"},
+		{"function:not()", "column 14: empty selector"},
+		{"function)", "column 9: unexpected `)`, expected a combinator"},
+		{"call[name='x]", "column 11: quoted value not closed"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.selector)
+		if err == nil {
+			t.Errorf("Expected an error for %q", tt.selector)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.selector, err)
+		}
+	}
+}
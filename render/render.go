@@ -0,0 +1,376 @@
+// render prints the diagnostics in the terminal the way the compilers do:
+// the message, the location and the excerpt of the source with the range
+// underlined, followed by the related locations as notes e.g.
+//
+//	Error: Undeclared identifier `y`
+//	 --> src/Vault.sol:3:13
+//	  |
+//	3 |         x = y + 1;
+//	  |             ^
+//
+// The plain format prints a single line per diagnostic and per note
+// instead, which is easier to grep.
+package render
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"solbot/parser"
+	"solbot/reporter"
+	"solbot/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Diagnostic is a problem at a range of the source.
+type Diagnostic struct {
+	Severity string // e.g. "Error", "Warning" or the severity of a finding
+	Message  string
+	File     *token.File
+	Range    token.Range
+	Label    string // printed after the underline; or empty
+	Related  []Related
+}
+
+// Related is another location relevant to the diagnostic e.g. the
+// declaration of a shadowed variable.
+type Related struct {
+	Message string
+	File    *token.File
+	Range   token.Range
+}
+
+type Format int
+
+const (
+	Pretty Format = iota // source excerpts with underlines
+	Plain                // one line per diagnostic
+)
+
+type Options struct {
+	Format Format
+	Color  bool // use the ANSI colors
+}
+
+// tabWidth is the width of the tab stops the tabs are expanded to.
+const tabWidth = 4
+
+const (
+	reset  = "\x1b[0m"
+	bold   = "\x1b[1m"
+	red    = "\x1b[1;31m"
+	yellow = "\x1b[1;33m"
+	cyan   = "\x1b[1;36m"
+	blue   = "\x1b[1;34m"
+)
+
+// Render writes the diagnostics to the writer.
+func Render(w io.Writer, diagnostics []Diagnostic, opts Options) error {
+	var b strings.Builder
+	for i, d := range diagnostics {
+		if opts.Format == Plain {
+			renderPlain(&b, d)
+			continue
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		r := renderer{b: &b, color: opts.Color, gutter: gutterWidth(d)}
+		r.excerpt(d.Severity, severityColor(d.Severity), d.Message, d.File, d.Range, d.Label)
+		for _, rel := range d.Related {
+			r.excerpt("note", cyan, rel.Message, rel.File, rel.Range, "")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Sort orders the diagnostics by the file name and by the range, so that
+// the syntax errors and the findings of a file are printed in the source
+// order. The diagnostics at the same range keep their order.
+func Sort(diagnostics []Diagnostic) {
+	slices.SortStableFunc(diagnostics, func(a, b Diagnostic) int {
+		if c := cmp.Compare(fileName(a.File), fileName(b.File)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Range.Start, b.Range.Start); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Range.End, b.Range.End)
+	})
+}
+
+func fileName(file *token.File) string {
+	if file == nil {
+		return ""
+	}
+	return file.Name()
+}
+
+func renderPlain(b *strings.Builder, d Diagnostic) {
+	message := d.Message
+	if d.Label != "" {
+		message += ": " + d.Label
+	}
+	fmt.Fprintf(b, "%s: %s: %s\n", location(d.File, d.Range.Start), strings.ToLower(d.Severity), message)
+	for _, rel := range d.Related {
+		fmt.Fprintf(b, "%s: note: %s\n", location(rel.File, rel.Range.Start), rel.Message)
+	}
+}
+
+func location(file *token.File, pos token.Pos) string {
+	p := file.Position(pos)
+	return fmt.Sprintf("%s:%d:%d", file.Name(), p.Line, p.Column)
+}
+
+type renderer struct {
+	b      *strings.Builder
+	color  bool
+	gutter int // width of the line numbers
+}
+
+func (r *renderer) paint(color, s string) string {
+	if !r.color || s == "" {
+		return s
+	}
+	return color + s + reset
+}
+
+// excerpt writes the header, the location and the underlined source line.
+func (r *renderer) excerpt(severity, color, message string, file *token.File, rng token.Range, label string) {
+	fmt.Fprintf(r.b, "%s%s\n", r.paint(color, severity+":"), r.paint(bold, " "+message))
+
+	start := file.Position(rng.Start)
+	end := file.Position(max(rng.End, rng.Start))
+	pad := strings.Repeat(" ", r.gutter)
+	fmt.Fprintf(r.b, "%s%s %s\n", pad, r.paint(blue, "-->"), location(file, rng.Start))
+	fmt.Fprintf(r.b, "%s %s\n", pad, r.paint(blue, "|"))
+
+	line := sourceLine(file.Src(), start.Offset)
+	from := min(start.Column-1, len(line))
+	// A multi-line range is underlined up to the end of its first line.
+	to := len(line)
+	if end.Line == start.Line {
+		to = min(int(end.Offset)-int(start.Offset)+from, len(line))
+	}
+	line, from, to = truncate(line, from, to)
+	text, columns := expand(line)
+	col := columns[from]
+	width := columns[to] - col
+	underline := "^" + strings.Repeat("~", max(width-1, 0))
+	if end.Line > start.Line {
+		extent := fmt.Sprintf("(continues to line %d)", end.Line)
+		if label != "" {
+			label += " " + extent
+		} else {
+			label = extent
+		}
+	}
+	if label != "" {
+		underline += " " + label
+	}
+
+	number := fmt.Sprintf("%*d", r.gutter, start.Line)
+	fmt.Fprintf(r.b, "%s %s %s\n", r.paint(blue, number), r.paint(blue, "|"), text)
+	fmt.Fprintf(r.b, "%s %s %s%s\n", pad, r.paint(blue, "|"), strings.Repeat(" ", col), r.paint(color, underline))
+}
+
+// sourceLine returns the line containing the offset, without the line
+// break.
+func sourceLine(src string, offset token.Pos) string {
+	start := strings.LastIndexByte(src[:min(int(offset), len(src))], '\n') + 1
+	end := strings.IndexByte(src[start:], '\n')
+	if end < 0 {
+		return src[start:]
+	}
+	return strings.TrimSuffix(src[start:start+end], "\r")
+}
+
+// maxExcerptWidth is the length in bytes above which the excerpt of a line
+// is cut around the underlined range e.g. a line of a flattened file.
+const maxExcerptWidth = 120
+
+// ellipsis marks the parts of a truncated excerpt left out.
+const ellipsis = "..."
+
+// truncate cuts the line longer than maxExcerptWidth around the range from
+// the byte offset to the other one, which are moved with the cut. The range
+// keeps some context before it, and is cut itself if it's too long.
+func truncate(line string, from, to int) (string, int, int) {
+	if len(line) <= maxExcerptWidth {
+		return line, from, to
+	}
+	start := max(from-maxExcerptWidth/4, 0)
+	end := min(start+maxExcerptWidth, len(line))
+	start = max(end-maxExcerptWidth, 0)
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start++
+	}
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end--
+	}
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = ellipsis
+	}
+	if end < len(line) {
+		suffix = ellipsis
+	}
+	shift := len(prefix) - start
+	return prefix + line[start:end] + suffix, from + shift, min(to, end) + shift
+}
+
+// expand returns the line with the tabs expanded to the tab stops, and the
+// display column of every byte offset of the line, including the one right
+// after its end.
+func expand(line string) (string, []int) {
+	var b strings.Builder
+	columns := make([]int, len(line)+1)
+	col := 0
+	for i, r := range line {
+		w := 0
+		if r == '\t' {
+			w = tabWidth - col%tabWidth
+			b.WriteString(strings.Repeat(" ", w))
+		} else {
+			w = runeWidth(r)
+			b.WriteRune(r)
+		}
+		for j := i; j < i+utf8.RuneLen(r) && j < len(line); j++ {
+			columns[j] = col
+		}
+		col += w
+	}
+	columns[len(line)] = col
+	return b.String(), columns
+}
+
+// runeWidth returns the number of the terminal cells taken by the rune: 0
+// for the combining marks, 2 for the wide East Asian characters and the
+// emoji, and 1 for the rest.
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0x303E, // CJK radicals and punctuation
+		r >= 0x3041 && r <= 0x33FF, // Hiragana, Katakana, CJK symbols
+		r >= 0x3400 && r <= 0x4DBF, // CJK extension A
+		r >= 0x4E00 && r <= 0x9FFF, // CJK unified ideographs
+		r >= 0xA000 && r <= 0xA4CF, // Yi
+		r >= 0xAC00 && r <= 0xD7A3, // Hangul syllables
+		r >= 0xF900 && r <= 0xFAFF, // CJK compatibility ideographs
+		r >= 0xFE30 && r <= 0xFE4F, // CJK compatibility forms
+		r >= 0xFF00 && r <= 0xFF60, // fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F, // emoji
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD: // CJK extensions B and later
+		return 2
+	}
+	return 1
+}
+
+// gutterWidth returns the width of the largest line number printed for
+// the diagnostic, so that all of its excerpts are aligned.
+func gutterWidth(d Diagnostic) int {
+	width := len(fmt.Sprint(d.File.Position(d.Range.Start).Line))
+	for _, rel := range d.Related {
+		width = max(width, len(fmt.Sprint(rel.File.Position(rel.Range.Start).Line)))
+	}
+	return width
+}
+
+func severityColor(severity string) string {
+	switch strings.ToLower(severity) {
+	case "error", "high":
+		return red
+	case "warning", "medium":
+		return yellow
+	}
+	return cyan
+}
+
+// FromParserErrors returns the diagnostics of the syntax errors.
+func FromParserErrors(file *token.File, errs parser.ErrorList) []Diagnostic {
+	res := []Diagnostic{}
+	for _, err := range errs {
+		res = append(res, Diagnostic{
+			Severity: "Error",
+			Message:  err.Message(),
+			File:     file,
+			Range:    token.Range{Start: err.Pos, End: err.Pos},
+		})
+	}
+	return res
+}
+
+// FromFinding returns the diagnostic of the finding reported by the
+// analyzer. The first location is the primary one, while the others become
+// the notes. Every location underlines the word it starts at.
+func FromFinding(file *token.File, f reporter.Finding) Diagnostic {
+	d := Diagnostic{Severity: f.Severity, Message: f.Title, File: file}
+	if f.Code != "" {
+		// The code points to the writeup, see `solbot explain`.
+		d.Message += " [" + f.Code + "]"
+	}
+	for i, loc := range f.Locations {
+		r := WordRange(file.Src(), loc.Position.Offset)
+		if i == 0 {
+			d.Range, d.Label = r, loc.Context
+			continue
+		}
+		d.Related = append(d.Related, Related{Message: loc.Context, File: file, Range: r})
+	}
+	return d
+}
+
+// WordRange returns the range of the identifier or the number starting at
+// the offset; or of the single character if there is none.
+func WordRange(src string, offset token.Pos) token.Range {
+	end := int(offset)
+	for end < len(src) && (src[end] == '_' || src[end] == '$' ||
+		'a' <= src[end] && src[end] <= 'z' || 'A' <= src[end] && src[end] <= 'Z' || '0' <= src[end] && src[end] <= '9') {
+		end++
+	}
+	if end == int(offset) && end < len(src) {
+		_, w := utf8.DecodeRuneInString(src[end:])
+		end += w
+	}
+	return token.Range{Start: offset, End: token.Pos(end)}
+}
+
+// ParseFormat parses the value of the --format flag: "pretty" or "plain".
+func ParseFormat(value string) (Format, error) {
+	switch value {
+	case "pretty":
+		return Pretty, nil
+	case "plain":
+		return Plain, nil
+	}
+	return Pretty, fmt.Errorf("unknown format %q, expected pretty or plain", value)
+}
+
+// UseColor parses the value of the --color flag: "always", "never" or
+// "auto", which colors the output only if it goes to a terminal.
+func UseColor(value string, w io.Writer) (bool, error) {
+	switch value {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		f, ok := w.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("unknown color mode %q, expected auto, always or never", value)
+}
@@ -0,0 +1,167 @@
+package render
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"solbot/reporter"
+	"solbot/token"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// rangeOf returns the range of the n-th occurrence of the text in the
+// source, counting from 1.
+func rangeOf(t *testing.T, src, text string, n int) token.Range {
+	offset := -1
+	for i := 0; i < n; i++ {
+		next := strings.Index(src[offset+1:], text)
+		if next < 0 {
+			t.Fatalf("Expected %q in the source", text)
+		}
+		offset += next + 1
+	}
+	return token.Range{Start: token.Pos(offset), End: token.Pos(offset + len(text))}
+}
+
+func Test_RenderGolden(t *testing.T) {
+	vault := token.NewFile("src/Vault.sol", `pragma solidity ^0.8.0;
+
+contract Vault {
+    function deposit(uint256 amount) external {
+        total = amount + fee;
+    }
+}
+`)
+	tabs := token.NewFile("src/Label.sol", "contract Label {\n\tfunction f() external {\n\t\tstring memory s = \"日本\"; s = x;\n\t}\n}\n")
+
+	var shadowed strings.Builder
+	shadowed.WriteString("contract Shadow {\n    uint256 owner;\n")
+	for i := 0; i < 8; i++ {
+		shadowed.WriteString("\n")
+	}
+	shadowed.WriteString("    function f(address owner) external {\n        owner;\n    }\n}\n")
+	shadow := token.NewFile("src/Shadow.sol", shadowed.String())
+
+	var flattened strings.Builder
+	flattened.WriteString("contract Flattened { uint256 constant FEE = 1;")
+	for i := 0; i < 20; i++ {
+		flattened.WriteString(" function f() external pure returns (uint256) { return FEE; }")
+	}
+	flattened.WriteString(" function last() external { total = fee; } }\n")
+	minified := token.NewFile("src/Flattened.sol", flattened.String())
+
+	tests := []struct {
+		name        string
+		diagnostics []Diagnostic
+	}{
+		{"single_line", []Diagnostic{{
+			Severity: "Error",
+			Message:  "Undeclared identifier `fee`",
+			File:     vault,
+			Range:    rangeOf(t, vault.Src(), "fee", 1),
+		}}},
+		{"multi_line", []Diagnostic{{
+			Severity: "Warning",
+			Message:  "Function `deposit` never uses its result",
+			File:     vault,
+			Range:    token.Range{Start: rangeOf(t, vault.Src(), "function", 1).Start, End: rangeOf(t, vault.Src(), "    }", 1).End},
+			Label:    "declared here",
+		}}},
+		{"tab", []Diagnostic{{
+			Severity: "Error",
+			Message:  "Undeclared identifier `x`",
+			File:     tabs,
+			Range:    rangeOf(t, tabs.Src(), "x;", 1),
+		}}},
+		{"related", []Diagnostic{{
+			Severity: "Warning",
+			Message:  "`owner` shadows a state variable",
+			File:     shadow,
+			Range:    rangeOf(t, shadow.Src(), "owner", 2),
+			Label:    "the parameter",
+			Related: []Related{
+				{Message: "the state variable is declared here", File: shadow, Range: rangeOf(t, shadow.Src(), "owner", 1)},
+				{Message: "the parameter is read here", File: shadow, Range: rangeOf(t, shadow.Src(), "owner", 3)},
+			},
+		}}},
+		{"long_line", []Diagnostic{{
+			Severity: "Error",
+			Message:  "Undeclared identifier `fee`",
+			File:     minified,
+			Range:    rangeOf(t, minified.Src(), "fee", 1),
+		}}},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []Format{Pretty, Plain} {
+			name := tt.name
+			if format == Plain {
+				name += "_plain"
+			}
+			var b strings.Builder
+			if err := Render(&b, tt.diagnostics, Options{Format: format}); err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(b.String()), 0644); err != nil {
+					t.Fatalf("Cannot update %s: %s", golden, err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Cannot read %s: %s", golden, err)
+			}
+			if b.String() != string(expected) {
+				t.Errorf("Expected %s:\n%s\ngot:\n%s", golden, expected, b.String())
+			}
+		}
+	}
+}
+
+func Test_RenderColors(t *testing.T) {
+	file := token.NewFile("a.sol", "x;\n")
+	var b strings.Builder
+	d := Diagnostic{Severity: "Error", Message: "Undeclared identifier `x`", File: file, Range: token.Range{Start: 0, End: 1}}
+	if err := Render(&b, []Diagnostic{d}, Options{Color: true}); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !strings.HasPrefix(b.String(), red+"Error:"+reset) {
+		t.Errorf("Expected the severity in red, got %q", b.String())
+	}
+
+	if color, err := UseColor("auto", &b); err != nil || color {
+		t.Errorf("Expected no colors for a buffer, got %t, %v", color, err)
+	}
+	if _, err := UseColor("sometimes", &b); err == nil {
+		t.Errorf("Expected an error for an unknown mode")
+	}
+}
+
+func Test_FromFinding(t *testing.T) {
+	file := token.NewFile("a.sol", "uint256 constant maxSupply = 1;\nuint256 constant minSupply = 0;\n")
+	finding := reporter.Finding{
+		Code:     "screaming-snake-const",
+		Title:    "Constants should be in SCREAMING_SNAKE_CASE",
+		Severity: "Best Practices",
+		Locations: []reporter.Location{
+			{Position: token.Position{Offset: 17}, Context: "`maxSupply`"},
+			{Position: token.Position{Offset: 49}, Context: "`minSupply`"},
+		},
+	}
+
+	d := FromFinding(file, finding)
+	if d.Range != (token.Range{Start: 17, End: 26}) || d.Label != "`maxSupply`" {
+		t.Errorf("Expected `maxSupply` at 17:26, got %q at %v", d.Label, d.Range)
+	}
+	if len(d.Related) != 1 || d.Related[0].Range != (token.Range{Start: 49, End: 58}) {
+		t.Errorf("Expected `minSupply` as the note, got %v", d.Related)
+	}
+	if expected := "Constants should be in SCREAMING_SNAKE_CASE [screaming-snake-const]"; d.Message != expected {
+		t.Errorf("Expected %q, got %q", expected, d.Message)
+	}
+}
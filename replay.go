@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"solbot/lsp/replay"
+	"solbot/lsp/server"
+	"strings"
+)
+
+// startReplay replays the session recorded with `solbot lsp --record`
+// through the server and reports the messages the server sends differently
+// now e.g.
+//
+//	solbot replay session.jsonl
+//	solbot replay --paced --ignore 'textDocument/hover:result.contents' session.jsonl
+//
+// It exits with 1 if the messages diverge.
+func startReplay(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, "Usage: solbot replay [--paced] [--ignore rules] <recording>\n\n")
+		fs.PrintDefaults()
+	}
+	paced := fs.Bool("paced", false, "Wait between the messages as long as the client did")
+	ignore := fs.String("ignore", "", "Comma-separated rules of the parts of the messages to ignore, "+
+		"'method' or 'method:path' e.g. 'textDocument/hover:result.contents'; added to "+strings.Join(replay.DefaultIgnore, ", "))
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error opening the recording: %s\n", err)
+		return 1
+	}
+	defer f.Close()
+	recorded, err := replay.ReadRecording(f)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading the recording: %s\n", err)
+		return 1
+	}
+
+	rules := []replay.Rule{}
+	for _, rule := range append(replay.DefaultIgnore, strings.Split(*ignore, ",")...) {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, replay.ParseRule(rule))
+		}
+	}
+	replayed := replay.Replay(recorded, func(w io.Writer) replay.Handler {
+		srv := server.NewServer(w, slog.New(slog.NewTextHandler(io.Discard, nil)), false)
+		// The initialization options of the recorded session may ask to
+		// record it, which must not append to the recording being read.
+		srv.SetRecorder(replay.NewRecorder(io.Discard, false))
+		return srv
+	}, *paced)
+
+	divergences := replay.Compare(recorded, replayed, rules)
+	for _, d := range divergences {
+		fmt.Fprintln(stdout, d)
+	}
+	inbound := 0
+	for _, e := range recorded {
+		if e.Direction == replay.Inbound {
+			inbound++
+		}
+	}
+	noun := "divergences"
+	if len(divergences) == 1 {
+		noun = "divergence"
+	}
+	fmt.Fprintf(stdout, "Replayed %d messages, %d %s\n", inbound, len(divergences), noun)
+	if len(divergences) > 0 {
+		return 1
+	}
+	return 0
+}
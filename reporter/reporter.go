@@ -10,13 +10,95 @@ import (
 )
 
 type Finding struct {
+	Code           string // code of the rule of the detector e.g. "msg-value-loop"
 	Title          string
 	Severity       string
 	Description    string
 	Recommendation string
+	Confidence     Confidence // confidence of the detector in the finding
 	Locations      []Location
 }
 
+// Confidence is how likely the findings of a detector are true positives.
+type Confidence int
+
+const (
+	_ Confidence = iota
+	Low
+	Medium
+	High
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case Low:
+		return "low"
+	case Medium:
+		return "medium"
+	case High:
+		return "high"
+	}
+	return "unknown"
+}
+
+// ParseConfidence parses "low", "medium" or "high".
+func ParseConfidence(s string) (Confidence, error) {
+	for _, c := range []Confidence{Low, Medium, High} {
+		if s == c.String() {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid confidence %q, expected low, medium or high", s)
+}
+
+// Rule describes what a detector finds, so that the users understand its
+// findings before acting on them, see `solbot explain`.
+type Rule struct {
+	Code        string     // e.g. "msg-value-loop"
+	Title       string     // one-line description
+	Explanation string     // why the code is a problem
+	Scenario    string     // concrete exploit or failure caused by the code
+	Remediation string     // how to fix it
+	References  []string   // e.g. "SWC-101", "CWE-190" or links
+	Confidence  Confidence // default confidence in the findings
+}
+
+// Writeup returns the full description of the rule as plain text, the
+// way `solbot explain` prints it.
+func (r Rule) Writeup() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", r.Code, r.Title)
+	fmt.Fprintf(&b, "Confidence: %s\n\n", r.Confidence)
+	fmt.Fprintf(&b, "%s\n\n", r.Explanation)
+	fmt.Fprintf(&b, "Exploit scenario:\n%s\n\n", r.Scenario)
+	fmt.Fprintf(&b, "Remediation:\n%s\n", r.Remediation)
+	if len(r.References) > 0 {
+		b.WriteString("\nReferences:\n")
+		for _, ref := range r.References {
+			fmt.Fprintf(&b, "  - %s\n", ref)
+		}
+	}
+	return b.String()
+}
+
+// Markdown returns the full description of the rule as Markdown, for the
+// SARIF help and the editors.
+func (r Rule) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title)
+	fmt.Fprintf(&b, "`%s`, confidence: %s\n\n", r.Code, r.Confidence)
+	fmt.Fprintf(&b, "%s\n\n", r.Explanation)
+	fmt.Fprintf(&b, "## Exploit scenario\n\n%s\n\n", r.Scenario)
+	fmt.Fprintf(&b, "## Remediation\n\n%s\n", r.Remediation)
+	if len(r.References) > 0 {
+		b.WriteString("\n## References\n\n")
+		for _, ref := range r.References {
+			fmt.Fprintf(&b, "- %s\n", ref)
+		}
+	}
+	return b.String()
+}
+
 type Location struct {
 	Position token.Position // Position data of the finding e.g. file, line, column.
 	Context  string         // The line with the issue itself or with its surroundings.
@@ -24,11 +106,7 @@ type Location struct {
 
 func (f *Finding) CalculatePositions(file *token.File) {
 	for i := range f.Locations {
-		// @TODO: This resets reader's state, but it is inefficient.
-		reader := strings.NewReader(file.Src())
-		token.OffsetToPosition(reader, &f.Locations[i].Position)
-		// @TODO: Add file name to the position.
-		f.Locations[i].Position.Filename = file.Name()
+		f.Locations[i].Position = file.Position(f.Locations[i].Position.Offset)
 	}
 }
 
@@ -0,0 +1,157 @@
+// sarif writes the findings of the detectors in the SARIF 2.1.0 format, so
+// that the code scanning of GitHub shows them on the pull requests. Every
+// rule carries its writeup, see reporter.Rule, so the description, the
+// exploit scenario and the remediation are shown next to the findings
+// without leaving the review.
+package sarif
+
+import (
+	"path/filepath"
+	"solbot/reporter"
+	"strings"
+)
+
+// Version is the version of the SARIF format.
+const Version = "2.1.0"
+
+// Schema is the JSON schema of the SARIF format.
+const Schema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// Log is the content of a SARIF file.
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is a single run of solbot.
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Rule describes a detector, see reporter.Rule.
+type Rule struct {
+	ID               string         `json:"id"`
+	ShortDescription Message        `json:"shortDescription"`
+	FullDescription  Message        `json:"fullDescription"`
+	Help             Message        `json:"help"`
+	HelpURI          string         `json:"helpUri,omitempty"` // the first link of the references
+	Properties       RuleProperties `json:"properties"`
+}
+
+type RuleProperties struct {
+	Precision string   `json:"precision"` // the confidence of the rule e.g. "medium"
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// Message is a text with an optional Markdown rendering.
+type Message struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+// Result is a finding of a detector.
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	RuleIndex int        `json:"ruleIndex"`
+	Level     string     `json:"level"` // "error", "warning" or "note"
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations"`
+	// RelatedLocations are the other locations of the finding.
+	RelatedLocations []Location `json:"relatedLocations,omitempty"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+	Message          *Message         `json:"message,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           Region           `json:"region"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"` // relative to the project root, with the forward slashes
+}
+
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// New returns the log of a run of solbot with the rules, see
+// analyzer.Rules, and no results yet.
+func New(version string, rules []reporter.Rule) *Log {
+	driver := Driver{Name: "solbot", Version: version, Rules: []Rule{}}
+	for _, r := range rules {
+		rule := Rule{
+			ID:               r.Code,
+			ShortDescription: Message{Text: r.Title},
+			FullDescription:  Message{Text: r.Explanation},
+			Help:             Message{Text: r.Writeup(), Markdown: r.Markdown()},
+			Properties:       RuleProperties{Precision: r.Confidence.String()},
+		}
+		for _, ref := range r.References {
+			if strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://") {
+				if rule.HelpURI == "" {
+					rule.HelpURI = ref
+				}
+				continue
+			}
+			rule.Properties.Tags = append(rule.Properties.Tags, ref)
+		}
+		driver.Rules = append(driver.Rules, rule)
+	}
+	return &Log{Version: Version, Schema: Schema, Runs: []Run{{Tool: Tool{Driver: driver}, Results: []Result{}}}}
+}
+
+// Add adds the finding of the file at the path, relative to the project
+// root. Its positions must be calculated, see Finding.CalculatePositions.
+// The first location is the primary one, while the others are related to
+// it, with their context as the message.
+func (l *Log) Add(path string, f reporter.Finding) {
+	run := &l.Runs[0]
+	result := Result{RuleID: f.Code, RuleIndex: -1, Level: Level(f.Severity), Message: Message{Text: f.Title}, Locations: []Location{}}
+	for i, rule := range run.Tool.Driver.Rules {
+		if rule.ID == f.Code {
+			result.RuleIndex = i
+		}
+	}
+	for i, loc := range f.Locations {
+		location := Location{PhysicalLocation: PhysicalLocation{
+			ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(path)},
+			Region:           Region{StartLine: loc.Position.Line, StartColumn: loc.Position.Column},
+		}}
+		if i == 0 {
+			result.Locations = append(result.Locations, location)
+			continue
+		}
+		location.Message = &Message{Text: loc.Context}
+		result.RelatedLocations = append(result.RelatedLocations, location)
+	}
+	run.Results = append(run.Results, result)
+}
+
+// Level returns the SARIF level of the severity of a finding e.g. "error"
+// for "High".
+func Level(severity string) string {
+	switch strings.ToLower(severity) {
+	case "error", "high":
+		return "error"
+	case "warning", "medium":
+		return "warning"
+	}
+	return "note"
+}
@@ -0,0 +1,62 @@
+package sarif
+
+import (
+	"solbot/reporter"
+	"solbot/token"
+	"testing"
+)
+
+func Test_Log(t *testing.T) {
+	rule := reporter.Rule{
+		Code:        "msg-value-loop",
+		Title:       "`msg.value` used in a loop",
+		Explanation: "The value is counted once per iteration.",
+		Scenario:    "A batch pays every recipient the whole value.",
+		Remediation: "Track the total spent.",
+		References:  []string{"SWC-113", "https://swcregistry.io/docs/SWC-113"},
+		Confidence:  reporter.High,
+	}
+	log := New("v0.3.0", []reporter.Rule{rule})
+	driver := log.Runs[0].Tool.Driver
+	if driver.Name != "solbot" || driver.Version != "v0.3.0" || len(driver.Rules) != 1 {
+		t.Fatalf("Expected the driver with 1 rule, got %+v", driver)
+	}
+	r := driver.Rules[0]
+	if r.ID != rule.Code || r.ShortDescription.Text != rule.Title || r.FullDescription.Text != rule.Explanation {
+		t.Errorf("Expected the descriptions of the rule, got %+v", r)
+	}
+	if r.Help.Text != rule.Writeup() || r.Help.Markdown != rule.Markdown() {
+		t.Errorf("Expected the writeup as the help, got %+v", r.Help)
+	}
+	if r.HelpURI != "https://swcregistry.io/docs/SWC-113" || len(r.Properties.Tags) != 1 || r.Properties.Tags[0] != "SWC-113" || r.Properties.Precision != "high" {
+		t.Errorf("Expected the link, the tag and the precision, got %q %v %q", r.HelpURI, r.Properties.Tags, r.Properties.Precision)
+	}
+
+	log.Add("src/Vault.sol", reporter.Finding{
+		Code:     "msg-value-loop",
+		Title:    "`msg.value` used in a loop",
+		Severity: "High",
+		Locations: []reporter.Location{
+			{Position: token.Position{Line: 6, Column: 13}, Context: "payable(to[i]).transfer(msg.value);"},
+			{Position: token.Position{Line: 5, Column: 9}, Context: "the loop"},
+		},
+	})
+	log.Add("src/Vault.sol", reporter.Finding{Code: "unknown", Severity: "Info"})
+	results := log.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	res := results[0]
+	if res.RuleID != "msg-value-loop" || res.RuleIndex != 0 || res.Level != "error" {
+		t.Errorf("Expected the error of the rule 0, got %s %d %s", res.RuleID, res.RuleIndex, res.Level)
+	}
+	if len(res.Locations) != 1 || res.Locations[0].PhysicalLocation.Region != (Region{StartLine: 6, StartColumn: 13}) {
+		t.Errorf("Expected the primary location at 6:13, got %+v", res.Locations)
+	}
+	if len(res.RelatedLocations) != 1 || res.RelatedLocations[0].Message.Text != "the loop" {
+		t.Errorf("Expected the loop as the related location, got %+v", res.RelatedLocations)
+	}
+	if res := results[1]; res.RuleIndex != -1 || res.Level != "note" {
+		t.Errorf("Expected a note without a rule, got %d %s", res.RuleIndex, res.Level)
+	}
+}
@@ -0,0 +1,369 @@
+// semver evaluates version constraints used in the Solidity version pragma
+// e.g. `pragma solidity ^0.8.0;` or `pragma solidity >=0.6.0 <0.9.0;`.
+// The syntax is the same as the one used by solc, which follows npm's
+// [node-semver] ranges. Pre-release and build labels are not supported,
+// since solc doesn't allow them in pragmas either.
+//
+// [node-semver]: https://github.com/npm/node-semver#ranges
+package semver
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// infinity is greater than any version that can appear in a pragma.
+var infinity = Version{Major: math.MaxInt}
+
+// Parse parses a full version e.g. "0.8.20".
+func Parse(s string) (Version, error) {
+	v, parts, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if parts != 3 {
+		return Version{}, fmt.Errorf("incomplete version: %q", s)
+	}
+	return v, nil
+}
+
+// MustParse is like Parse, but panics if the version is invalid. It's meant
+// for versions hardcoded in the source e.g. semver.MustParse("0.8.0").
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is less than, equal to
+// or greater than other.
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Major != other.Major:
+		return compareInts(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return compareInts(v.Minor, other.Minor)
+	default:
+		return compareInts(v.Patch, other.Patch)
+	}
+}
+
+func (v Version) Less(other Version) bool {
+	return v.Compare(other) < 0
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// parsePartial parses a version with optional minor and patch parts e.g.
+// "0.8", "0.8.x" or "*". It returns the number of parts that were given. The
+// missing parts are set to 0.
+func parsePartial(s string) (Version, int, error) {
+	s = strings.TrimPrefix(s, "v")
+	if s == "*" || s == "x" || s == "X" {
+		return Version{}, 0, nil
+	}
+
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return Version{}, 0, fmt.Errorf("invalid version: %q", s)
+	}
+
+	nums := [3]int{}
+	parts := 0
+	for i, field := range fields {
+		if field == "*" || field == "x" || field == "X" {
+			break
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return Version{}, 0, fmt.Errorf("invalid version: %q", s)
+		}
+		nums[i] = n
+		parts++
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, parts, nil
+}
+
+// interval is a half-open range of versions [from, to).
+type interval struct {
+	from Version
+	to   Version
+}
+
+func (i interval) isEmpty() bool {
+	return !i.from.Less(i.to)
+}
+
+func (i interval) intersect(other interval) interval {
+	res := i
+	if res.from.Less(other.from) {
+		res.from = other.from
+	}
+	if other.to.Less(res.to) {
+		res.to = other.to
+	}
+	return res
+}
+
+// Constraint is a set of versions allowed by a pragma. It's a union of
+// version intervals, one for each `||` separated range.
+type Constraint struct {
+	intervals []interval
+	src       string
+}
+
+// ParseConstraint parses a version pragma value e.g. "^0.8.0",
+// ">=0.6.0 <0.9.0" or "0.7.6 || ^0.8.0".
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{src: strings.TrimSpace(s)}
+	for _, rng := range strings.Split(s, "||") {
+		i, err := parseRange(rng)
+		if err != nil {
+			return Constraint{}, err
+		}
+		if !i.isEmpty() {
+			c.intervals = append(c.intervals, i)
+		}
+	}
+	return c, nil
+}
+
+// MustParseConstraint is like ParseConstraint, but panics if the constraint
+// is invalid.
+func MustParseConstraint(s string) Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c Constraint) String() string {
+	return c.src
+}
+
+// parseRange parses space separated comparators that must all hold, or a
+// hyphen range e.g. "0.6.0 - 0.8.0".
+func parseRange(s string) (interval, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return interval{}, fmt.Errorf("empty version range")
+	}
+
+	if len(fields) == 3 && fields[1] == "-" {
+		from, _, err := parsePartial(fields[0])
+		if err != nil {
+			return interval{}, err
+		}
+		to, parts, err := parsePartial(fields[2])
+		if err != nil {
+			return interval{}, err
+		}
+		return interval{from: from, to: bump(to, parts)}, nil
+	}
+
+	// Solidity allows a space between the operator and the version
+	// e.g. ">= 0.8.0", so glue them back together.
+	comparators := []string{}
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if strings.Trim(field, "^~<>=") == "" && i+1 < len(fields) {
+			field += fields[i+1]
+			i++
+		}
+		comparators = append(comparators, field)
+	}
+
+	res := interval{from: Version{}, to: infinity}
+	for _, comp := range comparators {
+		i, err := parseComparator(comp)
+		if err != nil {
+			return interval{}, err
+		}
+		res = res.intersect(i)
+	}
+	return res, nil
+}
+
+func parseComparator(s string) (interval, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(s, prefix) {
+			op = prefix
+			break
+		}
+	}
+
+	v, parts, err := parsePartial(s[len(op):])
+	if err != nil {
+		return interval{}, err
+	}
+
+	switch op {
+	case ">=":
+		return interval{from: v, to: infinity}, nil
+	case ">":
+		return interval{from: bump(v, parts), to: infinity}, nil
+	case "<=":
+		return interval{from: Version{}, to: bump(v, parts)}, nil
+	case "<":
+		return interval{from: Version{}, to: v}, nil
+	case "^":
+		return interval{from: v, to: caretUpperBound(v, parts)}, nil
+	case "~":
+		if parts == 3 {
+			parts = 2
+		}
+		return interval{from: v, to: bump(v, parts)}, nil
+	default:
+		// An exact version or a partial one e.g. "0.8" or "0.8.x".
+		return interval{from: v, to: bump(v, parts)}, nil
+	}
+}
+
+// bump returns the first version that doesn't match the partial version
+// with the given number of parts e.g. 0.8 -> 0.9.0 and 0.8.1 -> 0.8.2.
+func bump(v Version, parts int) Version {
+	switch parts {
+	case 0:
+		return infinity
+	case 1:
+		return Version{Major: v.Major + 1}
+	case 2:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	default:
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+}
+
+// caretUpperBound allows changes that don't modify the left-most non-zero
+// part e.g. ^0.8.1 := >=0.8.1 <0.9.0 and ^1.2.3 := >=1.2.3 <2.0.0
+func caretUpperBound(v Version, parts int) Version {
+	switch {
+	case v.Major > 0 || parts == 1:
+		return bump(v, 1)
+	case v.Minor > 0 || parts == 2:
+		return bump(v, 2)
+	default:
+		return bump(v, parts)
+	}
+}
+
+// Allows reports whether the version satisfies the constraint.
+func (c Constraint) Allows(v Version) bool {
+	for _, i := range c.intervals {
+		if !v.Less(i.from) && v.Less(i.to) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAny reports whether there is at least one version satisfying both
+// constraints e.g. ^0.7.0 and <0.8.0.
+func (c Constraint) AllowsAny(other Constraint) bool {
+	for _, i := range c.intervals {
+		for _, j := range other.intervals {
+			if !i.intersect(j).isEmpty() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowsAll reports whether every version satisfying the other constraint
+// satisfies this one too e.g. >=0.8.0 and ^0.8.4.
+func (c Constraint) AllowsAll(other Constraint) bool {
+	for _, j := range other.intervals {
+		// The start of the interval moves past the intervals covering it.
+		from := j.from
+		for moved := true; moved && from.Less(j.to); {
+			moved = false
+			for _, i := range c.intervals {
+				if !from.Less(i.from) && from.Less(i.to) {
+					from, moved = i.to, true
+				}
+			}
+		}
+		if from.Less(j.to) {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersect returns the constraint allowing only the versions allowed by
+// both of the constraints e.g. the versions that can compile two files.
+func (c Constraint) Intersect(other Constraint) Constraint {
+	res := Constraint{src: c.src + " " + other.src}
+	for _, i := range c.intervals {
+		for _, j := range other.intervals {
+			if k := i.intersect(j); !k.isEmpty() {
+				res.intervals = append(res.intervals, k)
+			}
+		}
+	}
+	return res
+}
+
+// IsEmpty reports whether no version satisfies the constraint e.g.
+// ">0.8.0 <0.7.0".
+func (c Constraint) IsEmpty() bool {
+	return len(c.intervals) == 0
+}
+
+// Min returns the lowest version allowed by the constraint. The second
+// result is false if the constraint is empty.
+func (c Constraint) Min() (Version, bool) {
+	if c.IsEmpty() {
+		return Version{}, false
+	}
+	min := c.intervals[0].from
+	for _, i := range c.intervals[1:] {
+		if i.from.Less(min) {
+			min = i.from
+		}
+	}
+	return min, true
+}
+
+// Format returns a pragma value allowing exactly the versions of the
+// constraint e.g. ">=0.8.4 <0.9.0" for the intersection of ">=0.6.0
+// <0.9.0" and ">=0.8.4", unlike String which returns the source.
+func (c Constraint) Format() string {
+	ranges := []string{}
+	for _, i := range c.intervals {
+		switch {
+		case i.to == infinity:
+			ranges = append(ranges, ">="+i.from.String())
+		case i.to == bump(i.from, 3):
+			ranges = append(ranges, i.from.String())
+		default:
+			ranges = append(ranges, ">="+i.from.String()+" <"+i.to.String())
+		}
+	}
+	return strings.Join(ranges, " || ")
+}
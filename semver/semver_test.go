@@ -0,0 +1,158 @@
+package semver
+
+import "testing"
+
+func Test_ConstraintAllows(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"^0.8.0", "0.8.0", true},
+		{"^0.8.0", "0.8.26", true},
+		{"^0.8.0", "0.9.0", false},
+		{"^0.8.0", "0.7.6", false},
+		{"^0.0.3", "0.0.4", false},
+		{"^1.2.3", "1.9.0", true},
+		{"~0.8.1", "0.8.0", false},
+		{"~0.8.1", "0.8.9", true},
+		{"~0.8.1", "0.9.0", false},
+		{">=0.6.0 <0.9.0", "0.6.0", true},
+		{">=0.6.0 <0.9.0", "0.8.99", true},
+		{">=0.6.0 <0.9.0", "0.9.0", false},
+		{">= 0.6.0 < 0.8.0", "0.7.0", true},
+		{">0.7.0", "0.7.0", false},
+		{">0.7.0", "0.7.1", true},
+		{"<=0.7", "0.7.6", true},
+		{"<=0.7", "0.8.0", false},
+		{"0.8.19", "0.8.19", true},
+		{"0.8.19", "0.8.20", false},
+		{"=0.8.19", "0.8.19", true},
+		{"0.8", "0.8.5", true},
+		{"0.8.x", "0.9.0", false},
+		{"0.7.6 || ^0.8.0", "0.7.6", true},
+		{"0.7.6 || ^0.8.0", "0.8.1", true},
+		{"0.7.6 || ^0.8.0", "0.7.5", false},
+		{"0.6.0 - 0.8.0", "0.8.0", true},
+		{"0.6.0 - 0.8.0", "0.8.1", false},
+		{"*", "0.4.24", true},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, got %s", tt.constraint, err)
+		}
+		if got := c.Allows(MustParse(tt.version)); got != tt.expected {
+			t.Errorf("Expected %q allows %s to be %t, got %t",
+				tt.constraint, tt.version, tt.expected, got)
+		}
+	}
+}
+
+func Test_ConstraintAllowsAny(t *testing.T) {
+	pre08 := MustParseConstraint("<0.8.0")
+
+	tests := []struct {
+		constraint string
+		expected   bool
+	}{
+		{"^0.7.0", true},
+		{"^0.8.0", false},
+		{">=0.6.0 <0.9.0", true},
+		{">=0.8.0", false},
+		{"0.8.0 || 0.7.6", true},
+	}
+
+	for _, tt := range tests {
+		c := MustParseConstraint(tt.constraint)
+		if got := c.AllowsAny(pre08); got != tt.expected {
+			t.Errorf("Expected %q allows any <0.8.0 to be %t, got %t",
+				tt.constraint, tt.expected, got)
+		}
+	}
+}
+
+func Test_ConstraintAllowsAll(t *testing.T) {
+	tests := []struct {
+		constraint string
+		other      string
+		expected   bool
+	}{
+		{">=0.8.0", "^0.8.4", true},
+		{">=0.8.18", "^0.8.0", false},
+		{">=0.8.18", ">=0.8.18 <0.9.0", true},
+		{"<0.7.0", "^0.6.0", true},
+		{"<0.7.0", ">=0.6.0 <0.8.0", false},
+		{"^0.7.0 || ^0.8.0", ">=0.7.2 <0.8.5", true},
+		{"^0.7.0 || >=0.8.1", ">=0.7.2 <0.8.5", false},
+		{">=0.5.0", "0.4.26 || 0.5.1", false},
+		{">=0.4.0", "0.4.26 || 0.5.1", true},
+	}
+
+	for _, tt := range tests {
+		c := MustParseConstraint(tt.constraint)
+		if got := c.AllowsAll(MustParseConstraint(tt.other)); got != tt.expected {
+			t.Errorf("Expected %q allows all %q to be %t, got %t",
+				tt.constraint, tt.other, tt.expected, got)
+		}
+	}
+}
+
+func Test_ConstraintMinAndEmpty(t *testing.T) {
+	c := MustParseConstraint("^0.8.4 || >=0.7.2 <0.8.0")
+	min, ok := c.Min()
+	if !ok || min != MustParse("0.7.2") {
+		t.Errorf("Expected min version 0.7.2, got %s", min)
+	}
+
+	empty := MustParseConstraint(">0.8.0 <0.7.0")
+	if !empty.IsEmpty() {
+		t.Errorf("Expected %q to be empty", empty)
+	}
+	if _, ok := empty.Min(); ok {
+		t.Errorf("Expected no min version for an empty constraint")
+	}
+}
+
+func Test_ConstraintIntersect(t *testing.T) {
+	c := MustParseConstraint("0.7.6 || ^0.8.0").Intersect(MustParseConstraint(">=0.8.4 <0.9.0"))
+	if c.Allows(MustParse("0.7.6")) || c.Allows(MustParse("0.8.3")) {
+		t.Errorf("Expected %q to disallow 0.7.6 and 0.8.3", c)
+	}
+	if !c.Allows(MustParse("0.8.4")) || !c.Allows(MustParse("0.8.26")) {
+		t.Errorf("Expected %q to allow 0.8.4 and 0.8.26", c)
+	}
+
+	empty := MustParseConstraint("^0.7.0").Intersect(MustParseConstraint("^0.8.0"))
+	if !empty.IsEmpty() {
+		t.Errorf("Expected %q to be empty", empty)
+	}
+}
+
+func Test_ConstraintFormat(t *testing.T) {
+	tests := []struct {
+		c        Constraint
+		expected string
+	}{
+		{MustParseConstraint(">=0.6.0 <0.9.0").Intersect(MustParseConstraint(">=0.8.4")), ">=0.8.4 <0.9.0"},
+		{MustParseConstraint("0.7.6 || >=0.8.0"), "0.7.6 || >=0.8.0"},
+		{MustParseConstraint("^0.8.0"), ">=0.8.0 <0.9.0"},
+	}
+	for _, tt := range tests {
+		if res := tt.c.Format(); res != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, res)
+		}
+	}
+}
+
+func Test_ParseErrors(t *testing.T) {
+	for _, s := range []string{"", "abc", "0.8.0.1", "^0.a"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("Expected an error for %q, got nil", s)
+		}
+	}
+	if _, err := Parse("0.8"); err == nil {
+		t.Errorf("Expected an error for an incomplete version, got nil")
+	}
+}
@@ -0,0 +1,46 @@
+package standardjson
+
+import "solbot/semver"
+
+// releases are the solc versions that can be suggested, oldest first.
+var releases = func() []semver.Version {
+	// The last patch release of each minor version.
+	lastPatch := []struct{ minor, patch int }{
+		{4, 26}, {5, 17}, {6, 12}, {7, 6}, {8, 28},
+	}
+	res := []semver.Version{}
+	for _, v := range lastPatch {
+		for patch := 0; patch <= v.patch; patch++ {
+			res = append(res, semver.Version{Major: 0, Minor: v.minor, Patch: patch})
+		}
+	}
+	return res
+}()
+
+// evmVersions maps the first compiler release with a new default EVM
+// version to that version, newest first.
+var evmVersions = []struct {
+	since      semver.Version
+	evmVersion string
+}{
+	{semver.MustParse("0.8.25"), "cancun"},
+	{semver.MustParse("0.8.20"), "shanghai"},
+	{semver.MustParse("0.8.18"), "paris"},
+	{semver.MustParse("0.8.7"), "london"},
+	{semver.MustParse("0.8.5"), "berlin"},
+	{semver.MustParse("0.5.14"), "istanbul"},
+	{semver.MustParse("0.5.5"), "petersburg"},
+	{semver.MustParse("0.4.21"), "byzantium"},
+	{semver.MustParse("0.4.0"), "homestead"},
+}
+
+// defaultEVMVersion returns the EVM version targeted by the compiler
+// release, unless the settings say otherwise.
+func defaultEVMVersion(v semver.Version) string {
+	for _, evm := range evmVersions {
+		if !v.Less(evm.since) {
+			return evm.evmVersion
+		}
+	}
+	return ""
+}
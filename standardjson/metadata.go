@@ -0,0 +1,202 @@
+package standardjson
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"solbot/keccak"
+	"solbot/textedit"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metadata is the part of solc's contract metadata identifying the
+// compiled sources and the settings, e.g. the metadata.json published with
+// the verified code by the block explorers.
+type Metadata struct {
+	Compiler struct {
+		Version string `json:"version"`
+	} `json:"compiler"`
+	Settings MetadataSettings          `json:"settings"`
+	Sources  map[string]MetadataSource `json:"sources"`
+}
+
+type MetadataSettings struct {
+	CompilationTarget map[string]string `json:"compilationTarget"` // source name -> contract name
+	Remappings        []string          `json:"remappings"`
+	Optimizer         Optimizer         `json:"optimizer"`
+	EVMVersion        string            `json:"evmVersion"`
+}
+
+type MetadataSource struct {
+	Keccak256 string `json:"keccak256"`         // hash of the content e.g. "0x1f3c..."
+	Content   string `json:"content,omitempty"` // literal content; empty unless solc was asked to include it
+}
+
+// ParseMetadata parses the metadata and checks that it names a single
+// compilation target.
+func ParseMetadata(data []byte) (*Metadata, error) {
+	meta := &Metadata{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	if len(meta.Settings.CompilationTarget) != 1 {
+		return nil, fmt.Errorf("invalid metadata: expected one compilation target, got %d", len(meta.Settings.CompilationTarget))
+	}
+	return meta, nil
+}
+
+// Target returns the name of the source and of the contract compiled to
+// produce the metadata.
+func (meta *Metadata) Target() (source, contract string) {
+	for source, contract := range meta.Settings.CompilationTarget {
+		return source, contract
+	}
+	return "", ""
+}
+
+// SourceStatus is the result of comparing a local source with the hash of
+// the metadata.
+type SourceStatus string
+
+const (
+	SourceMatch    SourceStatus = "match"
+	SourceMismatch SourceStatus = "mismatch"
+	SourceMissing  SourceStatus = "missing" // in the metadata, but not imported by the local sources
+	SourceExtra    SourceStatus = "extra"   // imported by the local sources, but not in the metadata
+)
+
+type SourceCheck struct {
+	Name     string       `json:"name"`
+	Status   SourceStatus `json:"status"`
+	Expected string       `json:"expected,omitempty"` // hash in the metadata
+	Actual   string       `json:"actual,omitempty"`   // hash of the local content
+	Diff     string       `json:"diff,omitempty"`     // first hunk of the diff from the verified content to the local one
+}
+
+// SettingCheck is a compilation setting of the metadata different from the
+// one the project produces.
+type SettingCheck struct {
+	Name     string `json:"name"` // e.g. "remappings", "optimizer.runs" or "evmVersion"
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+type Verification struct {
+	Source   string         `json:"source"`   // compilation target
+	Contract string         `json:"contract"` // contract compiled from it
+	Sources  []SourceCheck  `json:"sources"`  // ordered by the name
+	Settings []SettingCheck `json:"settings"` // only the mismatches
+}
+
+// OK reports whether all of the sources and the settings match.
+func (v Verification) OK() bool {
+	for _, check := range v.Sources {
+		if check.Status != SourceMatch {
+			return false
+		}
+	}
+	return len(v.Settings) == 0
+}
+
+// Verify compares the local input, built with Build for the compilation
+// target of the metadata, with the metadata. The content of the local
+// sources is hashed with the line endings normalized to "\n", so that a
+// checkout with "\r\n" still matches. The diffs of the mismatches are
+// computed against the verified content, taken from the verified standard
+// JSON input or from the metadata itself, as long as it hashes to the
+// value in the metadata; verified may be nil.
+func Verify(local *Input, verified *Input, meta *Metadata) Verification {
+	v := Verification{Sources: []SourceCheck{}, Settings: []SettingCheck{}}
+	v.Source, v.Contract = meta.Target()
+
+	names := []string{}
+	for name := range meta.Sources {
+		names = append(names, name)
+	}
+	for name := range local.Sources {
+		if _, ok := meta.Sources[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		expected, inMetadata := meta.Sources[name]
+		src, inLocal := local.Sources[name]
+		content := normalizeLineEndings(src.Content)
+		check := SourceCheck{Name: name, Expected: expected.Keccak256}
+		if inLocal {
+			check.Actual = sourceHash(content)
+		}
+		switch {
+		case !inLocal:
+			check.Status = SourceMissing
+		case !inMetadata:
+			check.Status = SourceExtra
+		case strings.EqualFold(check.Actual, expected.Keccak256):
+			check.Status = SourceMatch
+		default:
+			check.Status = SourceMismatch
+			if original, ok := verifiedContent(verified, meta, name); ok {
+				check.Diff = firstHunk(textedit.Diff(name+" (verified)", name+" (local)", original, content))
+			}
+		}
+		v.Sources = append(v.Sources, check)
+	}
+
+	setting := func(name, expected, actual string) {
+		if expected != actual {
+			v.Settings = append(v.Settings, SettingCheck{Name: name, Expected: expected, Actual: actual})
+		}
+	}
+	expectedRemappings := slices.Clone(meta.Settings.Remappings)
+	actualRemappings := slices.Clone(local.Settings.Remappings)
+	sort.Strings(expectedRemappings)
+	sort.Strings(actualRemappings)
+	setting("remappings", strings.Join(expectedRemappings, ", "), strings.Join(actualRemappings, ", "))
+	setting("optimizer.enabled", strconv.FormatBool(meta.Settings.Optimizer.Enabled), strconv.FormatBool(local.Settings.Optimizer.Enabled))
+	setting("optimizer.runs", strconv.Itoa(meta.Settings.Optimizer.Runs), strconv.Itoa(local.Settings.Optimizer.Runs))
+	setting("evmVersion", meta.Settings.EVMVersion, local.Settings.EVMVersion)
+	return v
+}
+
+// verifiedContent returns the content of the source hashing to the value
+// of the metadata, from the verified input or from the metadata.
+func verifiedContent(verified *Input, meta *Metadata, name string) (string, bool) {
+	candidates := []string{meta.Sources[name].Content}
+	if verified != nil {
+		candidates = append([]string{verified.Sources[name].Content}, candidates...)
+	}
+	for _, content := range candidates {
+		if content != "" && strings.EqualFold(sourceHash(content), meta.Sources[name].Keccak256) {
+			return content, true
+		}
+	}
+	return "", false
+}
+
+// sourceHash returns the Keccak-256 hash of the content the way the
+// metadata lists it e.g. "0x1f3c...".
+func sourceHash(content string) string {
+	sum := keccak.Sum256([]byte(content))
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+func normalizeLineEndings(src string) string {
+	return strings.ReplaceAll(src, "\r\n", "\n")
+}
+
+// firstHunk returns the header and the first hunk of the unified diff.
+func firstHunk(diff string) string {
+	start := strings.Index(diff, "\n@@")
+	if start < 0 {
+		return diff
+	}
+	if next := strings.Index(diff[start+1:], "\n@@"); next >= 0 {
+		return diff[:start+1+next+1]
+	}
+	return diff
+}
@@ -0,0 +1,212 @@
+// standardjson builds the input of solc's --standard-json mode from the
+// workspace model, so that solbot can be used as the import resolution
+// front-end of any solc-based tool. The input contains the full import
+// closure of a file with the literal content of every source.
+//
+// The sources are keyed by the import paths as written in the import
+// statements, e.g. "@openzeppelin/contracts/token/ERC20/IERC20.sol", and
+// relative imports are joined with the key of the importing file. The entry
+// file is keyed by its path relative to the project root.
+//
+// The same unit is compared with the metadata of a deployed contract by
+// Verify, to check that the local sources are the verified ones.
+package standardjson
+
+import (
+	"fmt"
+	"path"
+	"solbot/analyzer/pragma"
+	"solbot/ast"
+	"solbot/lsp/analysis"
+	"solbot/semver"
+)
+
+type Input struct {
+	Language string            `json:"language"`
+	Sources  map[string]Source `json:"sources"`
+	Settings Settings          `json:"settings"`
+}
+
+type Source struct {
+	Content string `json:"content"`
+}
+
+type Settings struct {
+	Remappings      []string                       `json:"remappings,omitempty"`
+	Optimizer       Optimizer                      `json:"optimizer"`
+	EVMVersion      string                         `json:"evmVersion,omitempty"`
+	OutputSelection map[string]map[string][]string `json:"outputSelection"`
+}
+
+type Optimizer struct {
+	Enabled bool `json:"enabled"`
+	Runs    int  `json:"runs"`
+}
+
+// Options override the settings read from the project configuration.
+type Options struct {
+	Optimizer     *bool  // enable or disable the optimizer; or nil
+	OptimizerRuns *int   // number of optimizer runs; or nil
+	EVMVersion    string // target EVM version; or empty
+}
+
+// Hint is the compiler suggested for the compilation unit. It's not a part
+// of the standard JSON input, which doesn't select the compiler.
+type Hint struct {
+	Version    semver.Version    // the newest release allowed by all of the pragmas
+	EVMVersion string            // default EVM version of that release
+	Constraint semver.Constraint // intersection of all of the pragmas
+}
+
+// Build returns the standard JSON input compiling the file with the given
+// URI together with everything it imports.
+func Build(s *analysis.State, uri string, opts Options) (*Input, *Hint, error) {
+	entry, ok := s.Documents[uri]
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a part of the workspace", uri)
+	}
+
+	units, err := importClosure(s, entry)
+	if err != nil {
+		return nil, nil, err
+	}
+	hint, err := compilerHint(units)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The compiler pinned in foundry.toml wins, if it's allowed.
+	if v, err := semver.Parse(s.Config.SolcVersion); err == nil && hint != nil && hint.Constraint.Allows(v) {
+		hint.Version = v
+		hint.EVMVersion = defaultEVMVersion(v)
+	}
+
+	input := &Input{
+		Language: "Solidity",
+		Sources:  map[string]Source{},
+		Settings: Settings{
+			Optimizer: Optimizer{
+				Enabled: s.Config.Optimizer,
+				Runs:    s.Config.OptimizerRuns,
+			},
+			EVMVersion: s.Config.EVMVersion,
+			OutputSelection: map[string]map[string][]string{
+				"*": {
+					"":  {"ast"},
+					"*": {"abi", "evm.bytecode", "evm.deployedBytecode", "evm.methodIdentifiers", "metadata"},
+				},
+			},
+		},
+	}
+	for _, unit := range units {
+		input.Sources[unit.name] = Source{Content: unit.doc.Handle.Src()}
+	}
+	for _, r := range s.Config.Remappings {
+		input.Settings.Remappings = append(input.Settings.Remappings, r.String())
+	}
+
+	if opts.Optimizer != nil {
+		input.Settings.Optimizer.Enabled = *opts.Optimizer
+	}
+	if opts.OptimizerRuns != nil {
+		input.Settings.Optimizer.Runs = *opts.OptimizerRuns
+	}
+	if opts.EVMVersion != "" {
+		input.Settings.EVMVersion = opts.EVMVersion
+	}
+	if input.Settings.EVMVersion == "" && hint != nil {
+		input.Settings.EVMVersion = hint.EVMVersion
+	}
+
+	return input, hint, nil
+}
+
+// sourceUnit is a document with the name it has in the sources.
+type sourceUnit struct {
+	name string
+	doc  *analysis.Document
+}
+
+// importClosure returns the entry file and all of the files it imports,
+// directly or not, in the breadth-first order.
+func importClosure(s *analysis.State, entry *analysis.Document) ([]sourceUnit, error) {
+	name := s.RelativePath(entry.URI)
+	units := []sourceUnit{{name: name, doc: entry}}
+	seen := map[string]bool{name: true}
+	for i := 0; i < len(units); i++ {
+		unit := units[i]
+		for _, decl := range unit.doc.File.Declarations {
+			imp, ok := decl.(*ast.ImportDirective)
+			if !ok || imp.Path == nil {
+				continue
+			}
+			importPath := imp.Path.Value[1 : len(imp.Path.Value)-1]
+			target := s.ImportTarget(unit.doc, imp)
+			if target == nil {
+				pos := unit.doc.Handle.Position(imp.Path.Start())
+				return nil, fmt.Errorf("%s:%d:%d: cannot resolve the import %q", unit.name, pos.Line, pos.Column, importPath)
+			}
+
+			name := importPath
+			if analysis.IsRelativeImport(importPath) {
+				name = path.Join(path.Dir(unit.name), importPath)
+			}
+			if !seen[name] {
+				seen[name] = true
+				units = append(units, sourceUnit{name: name, doc: target})
+			}
+		}
+	}
+	return units, nil
+}
+
+// compilerHint intersects the version pragmas of all of the files. It
+// returns an error naming two conflicting pragmas if no compiler can
+// compile all of them. The hint is nil if none of the files has a pragma.
+func compilerHint(units []sourceUnit) (*Hint, error) {
+	var constraint *semver.Constraint
+	constrained := []sourceUnit{}
+	for _, unit := range units {
+		c, ok := pragma.Solidity(unit.doc.File)
+		if !ok {
+			continue
+		}
+		if constraint == nil {
+			constraint = &c
+			constrained = append(constrained, unit)
+			continue
+		}
+
+		res := constraint.Intersect(c)
+		if res.IsEmpty() {
+			return nil, conflictError(constrained, unit, c)
+		}
+		constraint = &res
+		constrained = append(constrained, unit)
+	}
+	if constraint == nil {
+		return nil, nil
+	}
+
+	hint := &Hint{Constraint: *constraint}
+	for i := len(releases) - 1; i >= 0; i-- {
+		if constraint.Allows(releases[i]) {
+			hint.Version = releases[i]
+			hint.EVMVersion = defaultEVMVersion(releases[i])
+			return hint, nil
+		}
+	}
+	return nil, fmt.Errorf("no released compiler satisfies the pragmas: %s", constraint)
+}
+
+func conflictError(earlier []sourceUnit, unit sourceUnit, c semver.Constraint) error {
+	for _, other := range earlier {
+		oc, _ := pragma.Solidity(other.doc.File)
+		if !oc.AllowsAny(c) {
+			return fmt.Errorf("the version pragmas are mutually unsatisfiable: `%s` in %s and `%s` in %s",
+				oc, other.name, c, unit.name)
+		}
+	}
+	// Every pair is compatible, but all of them together are not.
+	return fmt.Errorf("the version pragmas are mutually unsatisfiable: `%s` in %s conflicts with the other pragmas of the compilation unit",
+		c, unit.name)
+}
@@ -0,0 +1,268 @@
+package standardjson
+
+import (
+	"fmt"
+	"slices"
+	"solbot/lsp/analysis"
+	"solbot/project"
+	"strings"
+	"testing"
+)
+
+func newState(t *testing.T, files map[string]string, remappings ...string) *analysis.State {
+	s := analysis.NewState()
+	s.Root = "/ws"
+	for _, r := range remappings {
+		remapping, err := project.ParseRemapping(r)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		s.Config.Remappings = append(s.Config.Remappings, remapping)
+	}
+	for name, src := range files {
+		s.OpenDocument("file:///ws/"+name, 1, src)
+	}
+	return s
+}
+
+func Test_BuildWithRemappings(t *testing.T) {
+	s := newState(t, map[string]string{
+		"src/Token.sol": `pragma solidity ^0.8.20;
+import {ERC20} from "@openzeppelin/contracts/token/ERC20/ERC20.sol";
+import "./Base.sol";
+contract Token is ERC20, Base {}
+`,
+		"src/Base.sol": `pragma solidity >=0.8.0;
+contract Base {}
+`,
+		"lib/openzeppelin-contracts/contracts/token/ERC20/ERC20.sol": `pragma solidity ^0.8.20;
+import {IERC20} from "./IERC20.sol";
+abstract contract ERC20 is IERC20 {}
+`,
+		"lib/openzeppelin-contracts/contracts/token/ERC20/IERC20.sol": `pragma solidity >=0.4.16;
+interface IERC20 {}
+`,
+		"src/Unrelated.sol": `contract Unrelated {}`,
+	}, "@openzeppelin/=lib/openzeppelin-contracts/")
+
+	runs := 1000
+	input, hint, err := Build(s, "file:///ws/src/Token.sol", Options{OptimizerRuns: &runs})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	expectedSources := []string{
+		"src/Token.sol",
+		"src/Base.sol",
+		"@openzeppelin/contracts/token/ERC20/ERC20.sol",
+		"@openzeppelin/contracts/token/ERC20/IERC20.sol",
+	}
+	if len(input.Sources) != len(expectedSources) {
+		t.Errorf("Expected %d sources, got %d: %v", len(expectedSources), len(input.Sources), input.Sources)
+	}
+	for _, name := range expectedSources {
+		if _, ok := input.Sources[name]; !ok {
+			t.Errorf("Expected source %s, got none", name)
+		}
+	}
+	if !strings.Contains(input.Sources["@openzeppelin/contracts/token/ERC20/IERC20.sol"].Content, "interface IERC20") {
+		t.Errorf("Expected the literal content of IERC20.sol")
+	}
+
+	if len(input.Settings.Remappings) != 1 || input.Settings.Remappings[0] != "@openzeppelin/=lib/openzeppelin-contracts/" {
+		t.Errorf("Expected the remappings to be included, got %v", input.Settings.Remappings)
+	}
+	if input.Settings.Optimizer.Runs != 1000 {
+		t.Errorf("Expected 1000 optimizer runs, got %d", input.Settings.Optimizer.Runs)
+	}
+
+	if hint == nil {
+		t.Fatalf("Expected a compiler hint, got nil")
+	}
+	if hint.Version.String() != "0.8.28" || input.Settings.EVMVersion != "cancun" {
+		t.Errorf("Expected compiler 0.8.28 targeting cancun, got %s targeting %s", hint.Version, input.Settings.EVMVersion)
+	}
+}
+
+func Test_BuildPragmaConflict(t *testing.T) {
+	s := newState(t, map[string]string{
+		"src/Vault.sol": `pragma solidity ^0.8.0;
+import "./Legacy.sol";
+contract Vault {}
+`,
+		"src/Legacy.sol": `pragma solidity ^0.7.6;
+contract Legacy {}
+`,
+	})
+
+	_, _, err := Build(s, "file:///ws/src/Vault.sol", Options{})
+	if err == nil {
+		t.Fatalf("Expected an error, got nil")
+	}
+
+	expected := "`^0.8.0` in src/Vault.sol and `^0.7.6` in src/Legacy.sol"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error containing %q, got %q", expected, err)
+	}
+}
+
+var verifySources = map[string]string{
+	"src/Vault.sol": `pragma solidity ^0.8.20;
+import {IERC20} from "@openzeppelin/contracts/token/ERC20/IERC20.sol";
+import "./Fees.sol";
+
+contract Vault is Fees {
+    IERC20 public asset;
+
+    function deposit(uint256 amount) external {
+        asset.transferFrom(msg.sender, address(this), amount);
+    }
+}
+`,
+	"src/Fees.sol": `pragma solidity ^0.8.20;
+
+contract Fees {
+    uint256 public constant FEE_BPS = 30;
+}
+`,
+	"lib/openzeppelin-contracts/contracts/token/ERC20/IERC20.sol": `pragma solidity >=0.4.16;
+interface IERC20 {
+    function transferFrom(address from, address to, uint256 value) external returns (bool);
+}
+`,
+}
+
+// verifiedMetadata returns the metadata and the standard JSON input of the
+// sources as they were verified.
+func verifiedMetadata(t *testing.T, sources map[string]string, remappings ...string) (*Metadata, *Input) {
+	verified, _, err := Build(newState(t, sources, remappings...), "file:///ws/src/Vault.sol", Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	meta := &Metadata{Sources: map[string]MetadataSource{}}
+	meta.Settings.CompilationTarget = map[string]string{"src/Vault.sol": "Vault"}
+	meta.Settings.Remappings = verified.Settings.Remappings
+	meta.Settings.Optimizer = verified.Settings.Optimizer
+	meta.Settings.EVMVersion = verified.Settings.EVMVersion
+	for name, src := range verified.Sources {
+		meta.Sources[name] = MetadataSource{Keccak256: sourceHash(src.Content)}
+	}
+	return meta, verified
+}
+
+func Test_VerifyMatchingSources(t *testing.T) {
+	meta, verified := verifiedMetadata(t, verifySources, "@openzeppelin/=lib/openzeppelin-contracts/")
+
+	// The local checkout has the Windows line endings.
+	local := map[string]string{}
+	for name, src := range verifySources {
+		local[name] = strings.ReplaceAll(src, "\n", "\r\n")
+	}
+	input, _, err := Build(newState(t, local, "@openzeppelin/=lib/openzeppelin-contracts/"), "file:///ws/src/Vault.sol", Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	v := Verify(input, verified, meta)
+	if !v.OK() || len(v.Sources) != 3 {
+		t.Fatalf("Expected the 3 sources and the settings to match, got %+v", v)
+	}
+	if v.Source != "src/Vault.sol" || v.Contract != "Vault" || v.Sources[0].Name != "@openzeppelin/contracts/token/ERC20/IERC20.sol" {
+		t.Errorf("Expected the sources of Vault ordered by the name, got %+v", v)
+	}
+}
+
+func Test_VerifySourceDrift(t *testing.T) {
+	meta, verified := verifiedMetadata(t, verifySources, "@openzeppelin/=lib/openzeppelin-contracts/")
+
+	local := map[string]string{}
+	for name, src := range verifySources {
+		local[name] = src
+	}
+	local["src/Fees.sol"] = strings.Replace(local["src/Fees.sol"], "30", "31", 1)
+	input, _, err := Build(newState(t, local, "@openzeppelin/=lib/openzeppelin-contracts/"), "file:///ws/src/Vault.sol", Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	v := Verify(input, verified, meta)
+	if v.OK() || len(v.Settings) != 0 {
+		t.Fatalf("Expected only the sources to mismatch, got %+v", v)
+	}
+	statuses := []string{}
+	for _, check := range v.Sources {
+		statuses = append(statuses, fmt.Sprintf("%s %s", check.Status, check.Name))
+	}
+	expected := "match @openzeppelin/contracts/token/ERC20/IERC20.sol, mismatch src/Fees.sol, match src/Vault.sol"
+	if strings.Join(statuses, ", ") != expected {
+		t.Errorf("Expected %q, got %q", expected, strings.Join(statuses, ", "))
+	}
+
+	diff := v.Sources[1].Diff
+	expectedDiff := "--- src/Fees.sol (verified)\n+++ src/Fees.sol (local)\n@@ -1,5 +1,5 @@\n" +
+		" pragma solidity ^0.8.20;\n \n contract Fees {\n" +
+		"-    uint256 public constant FEE_BPS = 30;\n+    uint256 public constant FEE_BPS = 31;\n }\n"
+	if diff != expectedDiff {
+		t.Errorf("Expected the diff:\n%s\ngot:\n%s", expectedDiff, diff)
+	}
+
+	// Without the verified content there is nothing to diff against.
+	if v := Verify(input, nil, meta); v.Sources[1].Status != SourceMismatch || v.Sources[1].Diff != "" {
+		t.Errorf("Expected a mismatch without a diff, got %+v", v.Sources[1])
+	}
+}
+
+func Test_VerifySettingsMismatch(t *testing.T) {
+	meta, verified := verifiedMetadata(t, verifySources, "@openzeppelin/=lib/openzeppelin-contracts/")
+
+	// The same sources, reached through a different remapping.
+	local := map[string]string{}
+	for name, src := range verifySources {
+		local[strings.Replace(name, "openzeppelin-contracts/", "oz/", 1)] = src
+	}
+	input, _, err := Build(newState(t, local, "@openzeppelin/=lib/oz/"), "file:///ws/src/Vault.sol", Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	v := Verify(input, verified, meta)
+	for _, check := range v.Sources {
+		if check.Status != SourceMatch {
+			t.Errorf("Expected %s to match, got %s", check.Name, check.Status)
+		}
+	}
+	expected := []SettingCheck{{Name: "remappings", Expected: "@openzeppelin/=lib/openzeppelin-contracts/", Actual: "@openzeppelin/=lib/oz/"}}
+	if v.OK() || !slices.Equal(v.Settings, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, v.Settings)
+	}
+}
+
+func Test_ParseMetadata(t *testing.T) {
+	meta, err := ParseMetadata([]byte(`{
+  "compiler": {"version": "0.8.20+commit.a1b79de6"},
+  "language": "Solidity",
+  "settings": {
+    "compilationTarget": {"src/Vault.sol": "Vault"},
+    "evmVersion": "shanghai",
+    "optimizer": {"enabled": true, "runs": 200},
+    "remappings": [":@openzeppelin/=lib/openzeppelin-contracts/"]
+  },
+  "sources": {
+    "src/Vault.sol": {"keccak256": "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470", "urls": []}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if source, contract := meta.Target(); source != "src/Vault.sol" || contract != "Vault" {
+		t.Errorf("Expected the target Vault in src/Vault.sol, got %s in %s", contract, source)
+	}
+	// The hash of the empty content.
+	if meta.Sources["src/Vault.sol"].Keccak256 != sourceHash("") {
+		t.Errorf("Expected the hash of the empty source, got %s", sourceHash(""))
+	}
+
+	if _, err := ParseMetadata([]byte(`{"settings": {"compilationTarget": {}}}`)); err == nil {
+		t.Errorf("Expected an error without a compilation target")
+	}
+}
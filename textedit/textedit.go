@@ -0,0 +1,216 @@
+// textedit applies the text edits to a source and prints the difference
+// between two versions of it as a unified diff, e.g. for the quick fixes
+// applied by `solbot fix`.
+package textedit
+
+import (
+	"fmt"
+	"slices"
+	"solbot/token"
+	"strings"
+)
+
+// Edit replaces the text in the range, it's an insertion if the range is
+// empty.
+type Edit struct {
+	Range   token.Range
+	NewText string
+}
+
+// Conflict reports whether the result of applying both of the edits
+// depends on their order: the ranges overlap, one is inserted inside the
+// other, or both are inserted at the same position.
+func Conflict(a, b Edit) bool {
+	if a.Range == b.Range {
+		return true
+	}
+	return a.Range.Start < b.Range.End && b.Range.Start < a.Range.End
+}
+
+// Conflicts reports whether any of the edits of the first set conflicts
+// with any of the second one.
+func Conflicts(a, b []Edit) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if Conflict(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Apply returns the source with the edits applied. The edits are applied
+// from the end of the source, so that their ranges, which refer to the
+// original source, stay valid. The insertions at the same position end up
+// in the order of the edits, before the text replaced at that position.
+//
+// The edits must not overlap, see Conflict.
+func Apply(src string, edits []Edit) string {
+	sorted := slices.Clone(edits)
+	slices.SortStableFunc(sorted, func(a, b Edit) int {
+		if a.Range.Start != b.Range.Start {
+			return int(a.Range.Start - b.Range.Start)
+		}
+		// The insertions go first.
+		return int(a.Range.End - b.Range.End)
+	})
+	var b strings.Builder
+	last := 0
+	for _, e := range sorted {
+		b.WriteString(src[last:e.Range.Start])
+		b.WriteString(e.NewText)
+		last = int(e.Range.End)
+	}
+	b.WriteString(src[last:])
+	return b.String()
+}
+
+// context is the number of the unchanged lines around the changes.
+const context = 3
+
+// Diff returns the unified diff of the sources, with the given names in
+// the header; or an empty string if they are the same.
+func Diff(oldName, newName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	x, y := splitLines(a), splitLines(b)
+	ops := diffLines(x, y)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// The hunk starts before the first change and ends after the last
+		// change followed by less than twice the context.
+		start := max(0, i-context)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end > 2*context {
+				break
+			}
+		}
+		end = min(len(ops), end+context)
+		writeHunk(&out, ops[start:end])
+		i = end
+	}
+	return out.String()
+}
+
+// op is a line of the diff: kept (' '), deleted ('-') or inserted ('+'),
+// together with its line numbers in both of the sources.
+type op struct {
+	kind     byte
+	line     string
+	old, new int
+}
+
+func writeHunk(out *strings.Builder, ops []op) {
+	oldCount, newCount := 0, 0
+	for _, o := range ops {
+		if o.kind != '+' {
+			oldCount++
+		}
+		if o.kind != '-' {
+			newCount++
+		}
+	}
+	// The empty ranges point at the line before them.
+	oldStart, newStart := ops[0].old, ops[0].new
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, o := range ops {
+		out.WriteByte(o.kind)
+		out.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits the source after the new lines, the last line has none
+// if the source doesn't end with one.
+func splitLines(src string) []string {
+	lines := strings.SplitAfter(src, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the edit script turning the lines x into the lines y,
+// computed from their longest common subsequence. The common prefix and
+// suffix are skipped first, so that the quadratic part covers only the
+// changed lines in the usual case of the small edits.
+func diffLines(x, y []string) []op {
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	mx, my := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of mx[i:]
+	// and my[j:].
+	lcs := make([][]int, len(mx)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(my)+1)
+	}
+	for i := len(mx) - 1; i >= 0; i-- {
+		for j := len(my) - 1; j >= 0; j-- {
+			if mx[i] == my[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := []op{}
+	i, j := 0, 0
+	keep := func(line string) {
+		ops = append(ops, op{kind: ' ', line: line, old: i + 1, new: j + 1})
+		i, j = i+1, j+1
+	}
+	for i < prefix {
+		keep(x[i])
+	}
+	for a, b := 0, 0; a < len(mx) || b < len(my); {
+		switch {
+		case a < len(mx) && b < len(my) && mx[a] == my[b]:
+			keep(mx[a])
+			a, b = a+1, b+1
+		case b == len(my) || a < len(mx) && lcs[a+1][b] >= lcs[a][b+1]:
+			ops = append(ops, op{kind: '-', line: mx[a], old: i + 1, new: j + 1})
+			i, a = i+1, a+1
+		default:
+			ops = append(ops, op{kind: '+', line: my[b], old: i + 1, new: j + 1})
+			j, b = j+1, b+1
+		}
+	}
+	for i < len(x) {
+		keep(x[i])
+	}
+	return ops
+}
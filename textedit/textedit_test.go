@@ -0,0 +1,89 @@
+package textedit
+
+import (
+	"solbot/token"
+	"testing"
+)
+
+func edit(start, end int, text string) Edit {
+	return Edit{Range: token.Range{Start: token.Pos(start), End: token.Pos(end)}, NewText: text}
+}
+
+func Test_Apply(t *testing.T) {
+	src := "uint x = now;"
+	edits := []Edit{
+		edit(9, 12, "block.timestamp"),
+		edit(0, 0, "unchecked "),
+		edit(9, 9, "("),
+		edit(12, 12, ")"),
+		edit(9, 9, "uint256"),
+	}
+	expected := "unchecked uint x = (uint256block.timestamp);"
+	if got := Apply(src, edits); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_Conflict(t *testing.T) {
+	tests := []struct {
+		a, b     Edit
+		expected bool
+	}{
+		{edit(0, 4, "a"), edit(2, 6, "b"), true},
+		{edit(0, 4, "a"), edit(0, 4, "a"), true},
+		{edit(0, 4, "a"), edit(2, 2, "b"), true},
+		{edit(2, 2, "a"), edit(2, 2, "b"), true},
+		{edit(0, 4, "a"), edit(4, 6, "b"), false},
+		{edit(0, 4, "a"), edit(4, 4, "b"), false},
+		{edit(0, 4, "a"), edit(0, 0, "b"), false},
+	}
+	for _, tt := range tests {
+		if got := Conflict(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Expected %v for %v and %v, got %v", tt.expected, tt.a, tt.b, got)
+		}
+		if got := Conflict(tt.b, tt.a); got != tt.expected {
+			t.Errorf("Expected %v for %v and %v, got %v", tt.expected, tt.b, tt.a, got)
+		}
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20"
+	b := "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n"
+	expected := `--- a/x.sol
++++ b/x.sol
+@@ -1,7 +1,7 @@
+ 1
+ 2
+ 3
+-4
++four
+ 5
+ 6
+ 7
+@@ -17,4 +17,4 @@
+ 17
+ 18
+ 19
+-20
+\ No newline at end of file
++20
+`
+	if got := Diff("a/x.sol", "b/x.sol", a, b); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if got := Diff("a/x.sol", "b/x.sol", a, a); got != "" {
+		t.Errorf("Expected no diff, got %q", got)
+	}
+
+	// The changes closer than twice the context share the hunk.
+	b = "x\n2\n3\n4\n5\n6\n7\ny\n"
+	expected = "--- a\n+++ b\n@@ -1,8 +1,8 @@\n-1\n+x\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+y\n"
+	if got := Diff("a", "b", "1\n2\n3\n4\n5\n6\n7\n8\n", b); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	expected = "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if got := Diff("a", "b", "", "x\ny\n"); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
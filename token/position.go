@@ -3,6 +3,8 @@ package token
 import (
 	"bufio"
 	"io"
+	"sort"
+	"unicode/utf8"
 )
 
 // Pos is the offset to the beginning of a token, starting from 0
@@ -57,9 +59,10 @@ func OffsetToPosition(reader io.Reader, pos *Position) {
 }
 
 type File struct {
-	name  string // file name e.g. "foo.sol"
-	src   string // file content; source code passed to the parser
-	lines []int  // offsets of the first character of each line
+	name  string          // file name e.g. "foo.sol"
+	src   string          // file content; source code passed to the parser
+	lines []int           // offsets of the first character of each line
+	utf16 map[int][]int32 // UTF-16 columns of the long lines by their index, see utf16Columns
 }
 
 func (f *File) Name() string {
@@ -76,3 +79,187 @@ func NewFile(name, src string) *File {
 		src:  src,
 	}
 }
+
+// Position returns the line and column information for the given offset.
+// Lines and columns are 1-based, the same as in OffsetToPosition.
+func (f *File) Position(pos Pos) Position {
+	lines := f.lineStarts()
+	// Find the last line that starts at or before the offset.
+	line := sort.Search(len(lines), func(i int) bool { return lines[i] > int(pos) }) - 1
+	if line < 0 {
+		line = 0
+	}
+
+	return Position{
+		Filename: f.name,
+		Offset:   pos,
+		Line:     line + 1,
+		Column:   int(pos) - lines[line] + 1,
+	}
+}
+
+// Offset is the inverse of Position. It takes 1-based line and column
+// numbers and returns the offset in the source. Positions past the end of
+// the line or the file are clamped to the nearest valid offset.
+func (f *File) Offset(line, column int) Pos {
+	lines := f.lineStarts()
+	if line < 1 {
+		return 0
+	}
+	if line > len(lines) {
+		return Pos(len(f.src))
+	}
+
+	lineStart := lines[line-1]
+	lineEnd := len(f.src)
+	if line < len(lines) {
+		lineEnd = lines[line] - 1 // Don't go past the newline character.
+	}
+
+	offset := lineStart + column - 1
+	if offset < lineStart {
+		offset = lineStart
+	}
+	if offset > lineEnd {
+		offset = lineEnd
+	}
+	return Pos(offset)
+}
+
+// lineStarts lazily computes the offsets of the first character of each line.
+func (f *File) lineStarts() []int {
+	if f.lines != nil {
+		return f.lines
+	}
+
+	f.lines = []int{0}
+	for i := 0; i < len(f.src); i++ {
+		if f.src[i] == '\n' {
+			f.lines = append(f.lines, i+1)
+		}
+	}
+	return f.lines
+}
+
+// longLine is the length in bytes above which the UTF-16 columns of a line
+// are computed once and cached, so that the lookups on the long lines of
+// the generated or flattened files don't scan the whole line every time.
+const longLine = 256
+
+// UTF16Column returns the 1-based column of the offset counted in the
+// UTF-16 code units, the way the LSP counts the characters by default.
+func (f *File) UTF16Column(pos Pos) int {
+	pos = min(max(pos, 0), Pos(len(f.src)))
+	p := f.Position(pos)
+	if columns, ok := f.utf16Columns(p.Line - 1); ok {
+		if columns == nil {
+			return p.Column
+		}
+		return int(columns[p.Column-1]) + 1
+	}
+	return utf16Len(f.src[f.lines[p.Line-1]:pos]) + 1
+}
+
+// OffsetUTF16 is the inverse of UTF16Column, see Offset. A column inside of
+// a character encoded with two UTF-16 units is the offset of the next one.
+func (f *File) OffsetUTF16(line, column int) Pos {
+	lines := f.lineStarts()
+	if line < 1 || line > len(lines) {
+		return f.Offset(line, column)
+	}
+	columns, ok := f.utf16Columns(line - 1)
+	if ok && columns == nil {
+		return f.Offset(line, column)
+	}
+
+	lineStart, lineEnd := lines[line-1], f.lineEnd(line-1)
+	if ok {
+		i := sort.Search(len(columns), func(i int) bool { return int(columns[i]) >= column-1 })
+		return Pos(lineStart + min(i, lineEnd-lineStart))
+	}
+	units := 0
+	for i, r := range f.src[lineStart:lineEnd] {
+		if units >= column-1 {
+			return Pos(lineStart + i)
+		}
+		units += utf16Width(r)
+	}
+	return Pos(lineEnd)
+}
+
+// LineLength returns the length in bytes of the 1-based line, without the
+// line break; or 0 if there is no such line.
+func (f *File) LineLength(line int) int {
+	lines := f.lineStarts()
+	if line < 1 || line > len(lines) {
+		return 0
+	}
+	return f.lineEnd(line-1) - lines[line-1]
+}
+
+// lineEnd returns the offset of the line break ending the line with the
+// 0-based index, or the end of the file.
+func (f *File) lineEnd(i int) int {
+	if i+1 < len(f.lines) {
+		return f.lines[i+1] - 1
+	}
+	return len(f.src)
+}
+
+// utf16Columns returns the UTF-16 column of every byte offset of the long
+// line with the 0-based index, and of the offset right after it; or nil if
+// the line is ASCII, so that the columns are the offsets. It's false if
+// the line is not long, and its columns are not cached.
+func (f *File) utf16Columns(i int) ([]int32, bool) {
+	start := f.lineStarts()[i]
+	text := f.src[start:f.lineEnd(i)]
+	if len(text) <= longLine {
+		return nil, false
+	}
+	if columns, ok := f.utf16[i]; ok {
+		return columns, true
+	}
+	if f.utf16 == nil {
+		f.utf16 = map[int][]int32{}
+	}
+
+	ascii := true
+	for j := 0; j < len(text) && ascii; j++ {
+		ascii = text[j] < utf8.RuneSelf
+	}
+	if ascii {
+		f.utf16[i] = nil
+		return nil, true
+	}
+	columns := make([]int32, len(text)+1)
+	units := int32(0)
+	for j := 0; j < len(text); {
+		r, size := utf8.DecodeRuneInString(text[j:])
+		for k := j; k < j+size; k++ {
+			columns[k] = units
+		}
+		units += int32(utf16Width(r))
+		j += size
+	}
+	columns[len(text)] = units
+	f.utf16[i] = columns
+	return columns, true
+}
+
+// utf16Len returns the number of the UTF-16 code units of the text.
+func utf16Len(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16Width(r)
+	}
+	return n
+}
+
+// utf16Width returns the number of the UTF-16 code units of the rune: two
+// for the ones outside of the Basic Multilingual Plane e.g. the emoji.
+func utf16Width(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,81 @@
+package token
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_UTF16Column(t *testing.T) {
+	long := "string s = \"" + strings.Repeat("x", longLine) + "日本🚀\"; x = 1;"
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"short", "a\nstring s = \"日本🚀\"; x = 1;\n"},
+		{"long", "a\n" + long + "\n"},
+		{"long ASCII", "a\n" + strings.Repeat("y", 2*longLine) + " x = 1;\n"},
+		{"long without a line break", "a\n" + long},
+	}
+	for _, tt := range tests {
+		f := NewFile("a.sol", tt.src)
+		line := f.src[2:]
+		line = strings.TrimSuffix(line, "\n")
+		// The columns of every character, and of the end of the line.
+		offsets := []int{}
+		for i := range line {
+			offsets = append(offsets, i)
+		}
+		offsets = append(offsets, len(line))
+
+		expected := 1
+		for i, offset := range offsets {
+			pos := Pos(2 + offset)
+			if got := f.UTF16Column(pos); got != expected {
+				t.Fatalf("%s: expected the column %d of the offset %d, got %d", tt.name, expected, offset, got)
+			}
+			for _, repeat := range []int{1, 2} {
+				if got := f.OffsetUTF16(2, expected); got != pos {
+					t.Fatalf("%s: expected the offset %d of the column %d (lookup %d), got %d", tt.name, pos, expected, repeat, got)
+				}
+			}
+			if i+1 < len(offsets) {
+				expected += utf16Len(line[offset:offsets[i+1]])
+			}
+		}
+
+		// The columns past the end are clamped like in Offset.
+		if got := f.OffsetUTF16(2, expected+10); got != Pos(2+len(line)) {
+			t.Errorf("%s: expected the end of the line %d, got %d", tt.name, 2+len(line), got)
+		}
+		if got := f.LineLength(2); got != len(line) {
+			t.Errorf("%s: expected the line length %d, got %d", tt.name, len(line), got)
+		}
+	}
+
+	// The second unit of a surrogate pair is inside of the character.
+	f := NewFile("a.sol", "🚀x")
+	if got := f.OffsetUTF16(1, 2); got != 4 {
+		t.Errorf("Expected the offset after the emoji, got %d", got)
+	}
+}
+
+// BenchmarkUTF16Column looks up the columns near the end of a long line, the
+// way the hovers on a minified file do, with the columns of the line cached
+// and computed again every time.
+func BenchmarkUTF16Column(b *testing.B) {
+	line := strings.Repeat("uint256 constant FEE = 1; /* ü */ ", 50000/34)
+	f := NewFile("a.sol", "pragma solidity ^0.8.0;\n"+line+"\n")
+	pos := Pos(len(f.src) - 10)
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			f.OffsetUTF16(2, f.UTF16Column(pos))
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			f.utf16 = nil
+			f.OffsetUTF16(2, f.UTF16Column(pos))
+		}
+	})
+}
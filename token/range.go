@@ -0,0 +1,57 @@
+package token
+
+// Range is a half-open interval of offsets in a source file. It starts at the
+// first character of a node and ends at the first character immediately after
+// it, which matches the Start() and End() convention of the AST nodes.
+//
+// A zero-width range (Start == End) is a valid range. It is used for nodes
+// that have a position, but no text, e.g. a missing expression.
+type Range struct {
+	Start Pos // First character in the range
+	End   Pos // First character immediately after the range
+}
+
+// Contains reports whether pos falls inside the range. Since the range is
+// end-exclusive, the End position itself is not contained. The only exception
+// are zero-width ranges, which contain their Start position. Otherwise,
+// they could never be found by a position based search.
+func (r Range) Contains(pos Pos) bool {
+	if r.Start == r.End {
+		return pos == r.Start
+	}
+	return r.Start <= pos && pos < r.End
+}
+
+// ContainsRange reports whether the other range lies entirely inside r.
+func (r Range) ContainsRange(other Range) bool {
+	return r.Start <= other.Start && other.End <= r.End
+}
+
+// Overlaps reports whether the two ranges share at least one position.
+// Ranges that only touch e.g. [0, 5) and [5, 10) do not overlap.
+func (r Range) Overlaps(other Range) bool {
+	if r.Start == r.End {
+		return other.Contains(r.Start)
+	}
+	if other.Start == other.End {
+		return r.Contains(other.Start)
+	}
+	return r.Start < other.End && other.Start < r.End
+}
+
+// Len returns the number of bytes covered by the range.
+func (r Range) Len() int {
+	return int(r.End - r.Start)
+}
+
+// Union returns the smallest range that covers both ranges.
+func (r Range) Union(other Range) Range {
+	union := r
+	if other.Start < union.Start {
+		union.Start = other.Start
+	}
+	if other.End > union.End {
+		union.End = other.End
+	}
+	return union
+}
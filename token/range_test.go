@@ -0,0 +1,93 @@
+package token
+
+import "testing"
+
+func Test_RangeContains(t *testing.T) {
+	tests := []struct {
+		r        Range
+		pos      Pos
+		expected bool
+	}{
+		{Range{5, 10}, 4, false},
+		{Range{5, 10}, 5, true},   // start is inclusive
+		{Range{5, 10}, 9, true},   // last character
+		{Range{5, 10}, 10, false}, // end is exclusive
+		{Range{7, 7}, 7, true},    // zero-width range contains its start
+		{Range{7, 7}, 8, false},
+	}
+
+	for i, tt := range tests {
+		if got := tt.r.Contains(tt.pos); got != tt.expected {
+			t.Errorf("tests[%d] - %v.Contains(%d): expected %t, got %t",
+				i, tt.r, tt.pos, tt.expected, got)
+		}
+	}
+}
+
+func Test_RangeRelations(t *testing.T) {
+	outer := Range{0, 20}
+	inner := Range{5, 10}
+	touching := Range{20, 25}
+
+	if !outer.ContainsRange(inner) {
+		t.Errorf("Expected %v to contain %v", outer, inner)
+	}
+	if inner.ContainsRange(outer) {
+		t.Errorf("Expected %v not to contain %v", inner, outer)
+	}
+	if !outer.ContainsRange(outer) {
+		t.Errorf("Expected range to contain itself")
+	}
+	if !outer.Overlaps(inner) || !inner.Overlaps(outer) {
+		t.Errorf("Expected %v and %v to overlap", outer, inner)
+	}
+	if outer.Overlaps(touching) {
+		t.Errorf("Expected touching ranges %v and %v not to overlap", outer, touching)
+	}
+	if !inner.Overlaps(Range{7, 7}) {
+		t.Errorf("Expected zero-width range inside of %v to overlap it", inner)
+	}
+	if inner.Len() != 5 {
+		t.Errorf("Expected length 5, got %d", inner.Len())
+	}
+	if union := inner.Union(touching); union != (Range{5, 25}) {
+		t.Errorf("Expected union {5 25}, got %v", union)
+	}
+}
+
+func Test_FilePositionAndOffset(t *testing.T) {
+	src := "a\nbc\n\ndef"
+	file := NewFile("test.sol", src)
+
+	tests := []struct {
+		offset Pos
+		line   int
+		column int
+	}{
+		{0, 1, 1},
+		{1, 1, 2}, // the newline belongs to the first line
+		{2, 2, 1},
+		{5, 3, 1}, // empty line
+		{8, 4, 3},
+		{9, 4, 4}, // EOF
+	}
+
+	for i, tt := range tests {
+		position := file.Position(tt.offset)
+		if position.Line != tt.line || position.Column != tt.column {
+			t.Errorf("tests[%d] - expected %d:%d, got %d:%d",
+				i, tt.line, tt.column, position.Line, position.Column)
+		}
+		if position.Filename != "test.sol" {
+			t.Errorf("tests[%d] - expected filename test.sol, got %s", i, position.Filename)
+		}
+		if offset := file.Offset(tt.line, tt.column); offset != tt.offset {
+			t.Errorf("tests[%d] - expected offset %d, got %d", i, tt.offset, offset)
+		}
+	}
+
+	// Columns past the end of the line are clamped to the newline character.
+	if offset := file.Offset(2, 100); offset != 4 {
+		t.Errorf("Expected clamped offset 4, got %d", offset)
+	}
+}
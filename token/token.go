@@ -336,6 +336,9 @@ const (
 	// Yul-specific tokens, but not keywords
 	LEAVE // leave
 
+	// Words with a special meaning only in some places, lexed as identifiers
+	TRANSIENT // transient
+
 	// Experimental Solidity specific keywords
 	CLASS
 	INSTANTIATION
@@ -641,6 +644,9 @@ var Tokens = [...]string{
 	// Yul-specific tokens, but not keywords
 	LEAVE: "leave",
 
+	// Words with a special meaning only in some places, lexed as identifiers
+	TRANSIENT: "transient",
+
 	// Experimental Solidity specific keywords
 	CLASS:         "",
 	INSTANTIATION: "",
@@ -676,6 +682,14 @@ func init() {
 	// of the tokens.
 	keywords[Tokens[DELETE]] = DELETE
 
+	// The same goes for boolean literals and ether subdenominations. They
+	// can't be used as identifiers, but they are not keywords either.
+	keywords[Tokens[TRUE_LITERAL]] = TRUE_LITERAL
+	keywords[Tokens[FALSE_LITERAL]] = FALSE_LITERAL
+	for i := ether_subdenominations_beg + 1; i < ether_subdenominations_end; i++ {
+		keywords[Tokens[i]] = i
+	}
+
 	elementaryTypes = make(map[string]TokenType, elementary_type_end-(elementary_type_beg+1))
 	for i := elementary_type_beg + 1; i < elementary_type_end; i++ {
 		elementaryTypes[Tokens[i]] = i
@@ -695,6 +709,17 @@ func LookupIdent(ident string) TokenType {
 	return IDENTIFIER
 }
 
+// IsSubdenomination reports whether the token is an ether or time unit
+// e.g. ether, gwei, days.
+func IsSubdenomination(tt TokenType) bool {
+	return ether_subdenominations_beg < tt && tt < ether_subdenominations_end
+}
+
+// IsIntegerType reports whether the token is one of the int or uint types.
+func IsIntegerType(tt TokenType) bool {
+	return INT <= tt && tt <= UINT_256
+}
+
 func IsElementaryType(tt TokenType) bool {
 	return elementary_type_beg < tt && tt < elementary_type_end
 }
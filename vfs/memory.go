@@ -0,0 +1,165 @@
+package vfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is a file system held in memory e.g. the workspace of a test.
+// The directories are implied by the paths of the files, there are no
+// empty ones. It's safe for concurrent use.
+type Memory struct {
+	mu    sync.Mutex
+	files map[string][]byte // clean absolute path -> content
+}
+
+// NewMemory returns the file system with the files of the absolute paths.
+func NewMemory(files map[string]string) *Memory {
+	m := &Memory{files: map[string][]byte{}}
+	for name, src := range files {
+		m.files[filepath.Clean(name)] = []byte(src)
+	}
+	return m
+}
+
+func (m *Memory) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if data, ok := m.files[name]; ok {
+		return &memoryFile{info: memoryInfo{name: filepath.Base(name), size: int64(len(data))}, Reader: bytes.NewReader(data)}, nil
+	}
+	entries := m.entries(name)
+	if entries == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memoryDir{info: memoryInfo{name: filepath.Base(name), dir: true}, entries: entries}, nil
+}
+
+func (m *Memory) Stat(name string) (fs.FileInfo, error) {
+	f, err := m.Open(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: filepath.Clean(name), Err: fs.ErrNotExist}
+	}
+	return f.Stat()
+}
+
+func (m *Memory) Glob(pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := map[string]bool{}
+	for name := range m.files {
+		// The file and the directories it's in.
+		for p := name; !seen[p]; p = filepath.Dir(p) {
+			seen[p] = true
+		}
+	}
+	res := []string{}
+	for p := range seen {
+		if ok, _ := filepath.Match(pattern, p); ok {
+			res = append(res, p)
+		}
+	}
+	slices.Sort(res)
+	return res, nil
+}
+
+func (m *Memory) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if m.entries(name) != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	m.files[name] = slices.Clone(data)
+	return nil
+}
+
+// entries returns the entries of the directory; or nil if it's not one.
+func (m *Memory) entries(dir string) []fs.DirEntry {
+	prefix := strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+	seen := map[string]fs.DirEntry{}
+	for name, data := range m.files {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		if child, _, nested := strings.Cut(rest, string(filepath.Separator)); nested {
+			seen[child] = fs.FileInfoToDirEntry(memoryInfo{name: child, dir: true})
+		} else {
+			seen[rest] = fs.FileInfoToDirEntry(memoryInfo{name: rest, size: int64(len(data))})
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	res := []fs.DirEntry{}
+	for _, entry := range seen {
+		res = append(res, entry)
+	}
+	slices.SortFunc(res, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return res
+}
+
+type memoryInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i memoryInfo) Name() string       { return i.name }
+func (i memoryInfo) Size() int64        { return i.size }
+func (i memoryInfo) ModTime() time.Time { return time.Time{} }
+func (i memoryInfo) IsDir() bool        { return i.dir }
+func (i memoryInfo) Sys() any           { return nil }
+
+func (i memoryInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+type memoryFile struct {
+	info memoryInfo
+	*bytes.Reader
+}
+
+func (f *memoryFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memoryFile) Close() error               { return nil }
+
+type memoryDir struct {
+	info    memoryInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memoryDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memoryDir) Close() error               { return nil }
+
+func (d *memoryDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *memoryDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,130 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutside is the error of the accesses refused by Sandbox.
+var ErrOutside = errors.New("outside of the workspace")
+
+// Sandbox is the file system restricted to the directories of the
+// workspace: its roots and the directories allowed explicitly e.g. the
+// Foundry libraries installed elsewhere. The symbolic links are resolved
+// before the paths are checked, if the file system has them, so a link
+// can't lead outside either. The refused accesses are logged and fail with
+// ErrOutside.
+type Sandbox struct {
+	fsys     FS
+	logger   *slog.Logger
+	dirs     []string // clean absolute paths of the directories
+	resolved []string // dirs with the symbolic links resolved
+}
+
+// symlinkEvaluator is the file system with the symbolic links, see OS.
+type symlinkEvaluator interface {
+	EvalSymlinks(name string) (string, error)
+}
+
+// NewSandbox returns the file system allowing only the accesses within
+// the directories, absolute paths. The logger may be nil.
+func NewSandbox(fsys FS, logger *slog.Logger, dirs ...string) *Sandbox {
+	s := &Sandbox{fsys: fsys, logger: logger}
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		s.dirs = append(s.dirs, dir)
+		s.resolved = append(s.resolved, s.resolve(dir))
+	}
+	return s
+}
+
+// Contains reports whether the path is within one of the directories,
+// without resolving the symbolic links.
+func (s *Sandbox) Contains(name string) bool {
+	return within(s.dirs, filepath.Clean(name))
+}
+
+func within(dirs []string, name string) bool {
+	for _, dir := range dirs {
+		if name == dir || strings.HasPrefix(name, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the path with the symbolic links resolved. The missing
+// files are resolved up to their nearest existing directory, so that e.g.
+// a new file can't be written through a link either.
+func (s *Sandbox) resolve(name string) string {
+	evaluator, ok := s.fsys.(symlinkEvaluator)
+	if !ok {
+		return name
+	}
+	rest := ""
+	for p := name; ; p = filepath.Dir(p) {
+		if resolved, err := evaluator.EvalSymlinks(p); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		if filepath.Dir(p) == p {
+			return name
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+	}
+}
+
+// check returns the clean path if it's allowed, or the error refusing the
+// operation on it.
+func (s *Sandbox) check(op, name string) (string, error) {
+	name = filepath.Clean(name)
+	if within(s.resolved, s.resolve(name)) {
+		return name, nil
+	}
+	if s.logger != nil {
+		s.logger.Warn("refused the access outside of the workspace", "op", op, "path", name)
+	}
+	return "", &fs.PathError{Op: op, Path: name, Err: ErrOutside}
+}
+
+func (s *Sandbox) Open(name string) (fs.File, error) {
+	name, err := s.check("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return s.fsys.Open(name)
+}
+
+func (s *Sandbox) Stat(name string) (fs.FileInfo, error) {
+	name, err := s.check("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return s.fsys.Stat(name)
+}
+
+// Glob returns the matches of the pattern within the sandbox, the others
+// are left out.
+func (s *Sandbox) Glob(pattern string) ([]string, error) {
+	matches, err := s.fsys.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for _, match := range matches {
+		if _, err := s.check("glob", match); err == nil {
+			res = append(res, match)
+		}
+	}
+	return res, nil
+}
+
+func (s *Sandbox) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	name, err := s.check("write", name)
+	if err != nil {
+		return err
+	}
+	return s.fsys.WriteFile(name, data, perm)
+}
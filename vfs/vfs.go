@@ -0,0 +1,108 @@
+// vfs is the access to the files of the workspace. FS abstracts the disk,
+// so that the features reading the workspace can be tested in memory, see
+// Memory, and Sandbox keeps the accesses within the workspace and the
+// directories allowed explicitly, so that e.g. the remappings of a cloned
+// repository can't make the server read the files outside of it.
+package vfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FS is a file system addressed by the absolute paths of the host e.g.
+// /home/user/vault/src/Vault.sol. Opening a directory gives an
+// fs.ReadDirFile listing its entries.
+type FS interface {
+	Open(name string) (fs.File, error)
+	Stat(name string) (fs.FileInfo, error)
+	Glob(pattern string) ([]string, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// ReadFile returns the content of the file.
+func ReadFile(fsys FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// WalkDir walks the tree rooted at the directory like filepath.WalkDir: in
+// the lexical order, calling fn for every file and directory, without
+// following the symbolic links to the directories.
+func WalkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fsys FS, name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := ReadDir(fsys, name)
+	if err != nil {
+		// The directory is reported again with the error.
+		if err = fn(name, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := walkDir(fsys, filepath.Join(name, entry.Name()), entry, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadDir returns the entries of the directory sorted by name, like
+// os.ReadDir.
+func ReadDir(fsys FS, name string) ([]fs.DirEntry, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, err := dir.ReadDir(-1)
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, err
+}
+
+// OS is the file system of the host, without any restrictions.
+type OS struct{}
+
+func (OS) Open(name string) (fs.File, error)        { return os.Open(name) }
+func (OS) Stat(name string) (fs.FileInfo, error)    { return os.Stat(name) }
+func (OS) Glob(pattern string) ([]string, error)    { return filepath.Glob(pattern) }
+func (OS) EvalSymlinks(name string) (string, error) { return filepath.EvalSymlinks(name) }
+
+func (OS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
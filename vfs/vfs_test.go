@@ -0,0 +1,81 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_MemoryWalkDir(t *testing.T) {
+	m := NewMemory(map[string]string{
+		"/ws/src/Vault.sol":       "contract Vault {}",
+		"/ws/src/math/Math.sol":   "library Math {}",
+		"/ws/.git/HEAD":           "ref: refs/heads/main",
+		"/ws/foundry.toml":        "",
+		"/other/Outside.sol":      "",
+		"/ws/lib/token/Token.sol": "",
+	})
+	visited := []string{}
+	err := WalkDir(m, "/ws", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		visited = append(visited, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	expected := "/ws /ws/foundry.toml /ws/lib /ws/lib/token /ws/lib/token/Token.sol /ws/src /ws/src/Vault.sol /ws/src/math /ws/src/math/Math.sol"
+	if strings.Join(visited, " ") != expected {
+		t.Errorf("Expected %s, got %s", expected, strings.Join(visited, " "))
+	}
+
+	src, err := ReadFile(m, "/ws/src/../src/Vault.sol")
+	if err != nil || string(src) != "contract Vault {}" {
+		t.Errorf("Expected the content of Vault.sol, got %q %v", src, err)
+	}
+	if _, err := m.Stat("/ws/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func Test_Sandbox(t *testing.T) {
+	m := NewMemory(map[string]string{
+		"/ws/src/Vault.sol":     "",
+		"/shared/Math.sol":      "",
+		"/etc/passwd":           "",
+		"/ws-other/Context.sol": "",
+	})
+	s := NewSandbox(m, nil, "/ws", "/shared")
+
+	for _, name := range []string{"/ws/src/Vault.sol", "/ws/src/../../shared/Math.sol"} {
+		if _, err := ReadFile(s, name); err != nil {
+			t.Errorf("Expected %s to be read, got %s", name, err)
+		}
+	}
+	for _, name := range []string{"/etc/passwd", "/ws/../etc/passwd", "/ws-other/Context.sol"} {
+		if _, err := ReadFile(s, name); !errors.Is(err, ErrOutside) {
+			t.Errorf("Expected %s to be refused, got %v", name, err)
+		}
+	}
+	if err := s.WriteFile("/etc/hosts", nil, 0o644); !errors.Is(err, ErrOutside) {
+		t.Errorf("Expected the write outside to be refused, got %v", err)
+	}
+	if err := s.WriteFile("/ws/src/Fixed.sol", []byte("contract Fixed {}"), 0o644); err != nil {
+		t.Errorf("Expected the write within the workspace, got %s", err)
+	}
+
+	matches, err := s.Glob("/*/*.sol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(matches, " ") != "/shared/Math.sol" {
+		t.Errorf("Expected only the matches within the sandbox, got %v", matches)
+	}
+}
@@ -0,0 +1,547 @@
+// Package yul parses the Yul code of the inline assembly blocks, enough to
+// find the Solidity variables it refers to and the functions it calls,
+// with the effects of the builtins on the blockchain. The types and the
+// dialects are not checked, the builtins only against the EVM version
+// which added them; the code that doesn't parse is reported with an error
+// and no references.
+package yul
+
+import (
+	"fmt"
+	"slices"
+	"solbot/token"
+	"strings"
+)
+
+// Reference is an identifier of the Yul code not declared in it, so it
+// refers to a Solidity variable e.g. `amount` in `mstore(0, amount)` or
+// `slot` in `sload(slot.slot)`.
+type Reference struct {
+	Pos    token.Pos // position of the name in the file
+	Name   string    // name of the variable, without the suffix
+	Suffix string    // e.g. "slot", "offset" or "length" in `x.slot`; or empty
+}
+
+// suffixes are the members of the Solidity variables accessible in Yul.
+var suffixes = map[string]bool{
+	"slot":     true,
+	"offset":   true,
+	"length":   true,
+	"selector": true,
+	"address":  true,
+}
+
+// References returns the identifiers referring to the Solidity variables in
+// the body of an assembly block, which starts at the offset in the file. A
+// variable declared with `let` shadows the Solidity one from its
+// declaration to the end of the block, and the Yul functions don't see the
+// Solidity variables at all. The calls are never references, since Yul
+// can't call the Solidity functions.
+func References(body string, offset token.Pos) ([]Reference, error) {
+	_, root, err := parse(body, offset)
+	if err != nil {
+		return nil, err
+	}
+	return references(root, offset), nil
+}
+
+// parse returns the parser after the whole body, with the calls, and the
+// root block.
+func parse(body string, offset token.Pos) (*parser, *block, error) {
+	p := &parser{src: body, offset: offset}
+	p.next()
+	root := &block{}
+	for p.tok.kind != tokEOF {
+		p.statement(root)
+	}
+	if p.err != nil {
+		return nil, nil, p.err
+	}
+	return p, root, nil
+}
+
+func references(root *block, offset token.Pos) []Reference {
+	refs := []Reference{}
+	var visit func(b *block, scopes []map[string]bool, inFunction bool)
+	visit = func(b *block, scopes []map[string]bool, inFunction bool) {
+		scope := map[string]bool{}
+		for _, fn := range b.functions {
+			scope[fn] = true
+		}
+		scopes = append(scopes, scope)
+		declared := func(name string) bool {
+			for _, s := range scopes {
+				if s[name] {
+					return true
+				}
+			}
+			return false
+		}
+		for _, item := range b.items {
+			switch item := item.(type) {
+			case tok:
+				if inFunction || declared(item.text) {
+					continue
+				}
+				name, suffix, found := strings.Cut(item.text, ".")
+				if found && (!suffixes[suffix] || declared(name)) {
+					continue
+				}
+				refs = append(refs, Reference{Pos: offset + token.Pos(item.pos), Name: name, Suffix: suffix})
+			case declaration:
+				scope[item.name] = true
+			case *block:
+				visit(item, scopes, inFunction || item.function)
+			}
+		}
+	}
+	visit(root, nil, false)
+	return refs
+}
+
+// Call is a call in the Yul code, of a builtin or of a function declared
+// in it e.g. `tstore` in `tstore(0, 1)`.
+type Call struct {
+	Pos  token.Pos // position of the name in the file
+	Name string
+	Slot *Reference // the variable of the slot of a storage builtin e.g. `total` in `sstore(total.slot, 1)`; or nil
+}
+
+// Calls returns the calls of the Yul code of an assembly block, which
+// starts at the offset in the file, in the order of the code.
+func Calls(body string, offset token.Pos) ([]Call, error) {
+	p, root, err := parse(body, offset)
+	if err != nil {
+		return nil, err
+	}
+	// The slots of the variables declared in the Yul code, and the ones
+	// the Yul functions see, are not references.
+	refs := map[token.Pos]Reference{}
+	for _, ref := range references(root, offset) {
+		refs[ref.Pos] = ref
+	}
+	calls := []Call{}
+	for _, c := range p.calls {
+		call := Call{Pos: offset + token.Pos(c.name.pos), Name: c.name.text}
+		if ref, ok := refs[offset+token.Pos(c.slot.pos)]; ok && c.slot.text != "" && ref.Suffix == "slot" && slotted[c.name.text] {
+			call.Slot = &ref
+		}
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
+
+// Effect is the effect of a builtin on the blockchain, see BuiltinEffect.
+type Effect int
+
+const (
+	NoEffect        Effect = iota // only the memory, the stack, the calldata or the like
+	StorageRead                   // sload
+	StorageWrite                  // sstore
+	TransientRead                 // tload
+	TransientWrite                // tstore
+	AccountRead                   // the balance or the code of an account
+	EnvironmentRead               // the caller, the block, the transaction or the gas
+	StaticCall                    // staticcall
+	ExternalCall                  // call, callcode, delegatecall, create or create2
+	Emission                      // log0 to log4
+	SelfDestruct                  // selfdestruct
+)
+
+var builtinEffects = map[string]Effect{
+	"sload": StorageRead, "sstore": StorageWrite,
+	"tload": TransientRead, "tstore": TransientWrite,
+	"balance": AccountRead, "selfbalance": AccountRead, "extcodesize": AccountRead,
+	"extcodecopy": AccountRead, "extcodehash": AccountRead,
+	"address": EnvironmentRead, "origin": EnvironmentRead, "caller": EnvironmentRead,
+	"callvalue": EnvironmentRead, "gasprice": EnvironmentRead, "coinbase": EnvironmentRead,
+	"timestamp": EnvironmentRead, "number": EnvironmentRead, "difficulty": EnvironmentRead,
+	"prevrandao": EnvironmentRead, "gaslimit": EnvironmentRead, "chainid": EnvironmentRead,
+	"basefee": EnvironmentRead, "blobbasefee": EnvironmentRead, "blockhash": EnvironmentRead,
+	"blobhash": EnvironmentRead, "gas": EnvironmentRead,
+	"staticcall": StaticCall,
+	"call":       ExternalCall, "callcode": ExternalCall, "delegatecall": ExternalCall,
+	"create": ExternalCall, "create2": ExternalCall,
+	"log0": Emission, "log1": Emission, "log2": Emission, "log3": Emission, "log4": Emission,
+	"selfdestruct": SelfDestruct,
+}
+
+// slotted are the builtins taking a slot as the first argument.
+var slotted = map[string]bool{"sload": true, "sstore": true, "tload": true, "tstore": true}
+
+// BuiltinEffect returns the effect of calling the builtin e.g.
+// StorageWrite for `sstore`; or NoEffect if it has none, or it's not a
+// builtin.
+func BuiltinEffect(name string) Effect {
+	return builtinEffects[name]
+}
+
+// evmVersions are the EVM versions the compiler can target, oldest first.
+var evmVersions = []string{
+	"homestead", "tangerineWhistle", "spuriousDragon", "byzantium", "constantinople", "petersburg",
+	"istanbul", "berlin", "london", "paris", "shanghai", "cancun", "prague", "osaka",
+}
+
+// builtinSince maps the builtins added after homestead to the EVM version
+// which added them.
+var builtinSince = map[string]string{
+	"returndatasize": "byzantium", "returndatacopy": "byzantium", "staticcall": "byzantium",
+	"shl": "constantinople", "shr": "constantinople", "sar": "constantinople",
+	"create2": "constantinople", "extcodehash": "constantinople",
+	"chainid": "istanbul", "selfbalance": "istanbul",
+	"basefee": "london", "prevrandao": "paris",
+	"tload": "cancun", "tstore": "cancun", "mcopy": "cancun", "blobhash": "cancun", "blobbasefee": "cancun",
+}
+
+// BuiltinSince returns the EVM version which added the builtin e.g.
+// "cancun" for `tstore`; or an empty string if the builtin is older or
+// it's not one.
+func BuiltinSince(name string) string {
+	return builtinSince[name]
+}
+
+// BuiltinAvailable reports whether the builtin can be called when the code
+// is compiled for the EVM version. The versions it doesn't know, and the
+// names which are not builtins, are assumed to allow the call.
+func BuiltinAvailable(name, evmVersion string) bool {
+	since := slices.Index(evmVersions, builtinSince[name])
+	target := slices.Index(evmVersions, evmVersion)
+	return since < 0 || target < 0 || target >= since
+}
+
+// block is a Yul block, reduced to the order of the identifiers used, the
+// variables declared and the nested blocks.
+type block struct {
+	function  bool     // is it the body of a function? It doesn't see the Solidity variables.
+	functions []string // functions declared in the block, visible in all of it
+	items     []any    // tok for a use, declaration, or *block
+}
+
+type declaration struct {
+	name string
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokLiteral
+	tokPunct // one of { } ( ) , := -> :
+)
+
+type tok struct {
+	kind tokenKind
+	text string
+	pos  int // offset in the body
+}
+
+type parser struct {
+	src    string
+	offset token.Pos
+	pos    int
+	tok    tok
+	err    error
+	calls  []call // in the order of the code
+}
+
+// call is a call of a function, with the first argument if it's the slot
+// of a variable e.g. `x.slot`.
+type call struct {
+	name tok
+	slot tok
+}
+
+func (p *parser) errorf(format string, args ...any) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%d: %s", int(p.offset)+p.tok.pos, fmt.Sprintf(format, args...))
+	}
+	// Stop at the first error.
+	p.tok = tok{kind: tokEOF, pos: len(p.src)}
+	p.pos = len(p.src)
+}
+
+// next scans the next token, skipping the whitespace and the comments.
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	src := p.src
+	for p.pos < len(src) {
+		switch {
+		case strings.HasPrefix(src[p.pos:], "//"):
+			end := strings.IndexByte(src[p.pos:], '\n')
+			if end < 0 {
+				end = len(src) - p.pos
+			}
+			p.pos += end
+		case strings.HasPrefix(src[p.pos:], "/*"):
+			end := strings.Index(src[p.pos+2:], "*/")
+			if end < 0 {
+				p.tok.pos = p.pos
+				p.errorf("unterminated comment")
+				return
+			}
+			p.pos += end + 4
+		case strings.ContainsRune(" \t\r\n", rune(src[p.pos])):
+			p.pos++
+		default:
+			goto scan
+		}
+	}
+	p.tok = tok{kind: tokEOF, pos: len(src)}
+	return
+
+scan:
+	start := p.pos
+	c := src[p.pos]
+	switch {
+	case isLetter(c):
+		for p.pos < len(src) && (isLetter(src[p.pos]) || isDigit(src[p.pos]) || src[p.pos] == '.') {
+			p.pos++
+		}
+		text := src[start:p.pos]
+		kind := tokIdent
+		if text == "true" || text == "false" {
+			kind = tokLiteral
+		}
+		// hex"..." is a literal.
+		if text == "hex" && p.pos < len(src) && (src[p.pos] == '"' || src[p.pos] == '\'') {
+			p.scanString()
+			kind = tokLiteral
+		}
+		p.tok = tok{kind: kind, text: src[start:p.pos], pos: start}
+	case isDigit(c):
+		for p.pos < len(src) && (isLetter(src[p.pos]) || isDigit(src[p.pos])) {
+			p.pos++
+		}
+		p.tok = tok{kind: tokLiteral, text: src[start:p.pos], pos: start}
+	case c == '"' || c == '\'':
+		p.scanString()
+		p.tok = tok{kind: tokLiteral, text: src[start:p.pos], pos: start}
+	case strings.HasPrefix(src[p.pos:], ":=") || strings.HasPrefix(src[p.pos:], "->"):
+		p.pos += 2
+		p.tok = tok{kind: tokPunct, text: src[start:p.pos], pos: start}
+	case strings.ContainsRune("{}(),:", rune(c)):
+		p.pos++
+		p.tok = tok{kind: tokPunct, text: src[start:p.pos], pos: start}
+	default:
+		p.tok.pos = start
+		p.errorf("unexpected character %q", c)
+	}
+}
+
+func (p *parser) scanString() {
+	quote := p.src[p.pos]
+	p.pos++
+	for p.pos < len(p.src) && p.src[p.pos] != quote {
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		p.errorf("unterminated string")
+		return
+	}
+	p.pos++
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || c == '$' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func (p *parser) is(text string) bool {
+	return p.tok.kind != tokLiteral && p.tok.text == text
+}
+
+func (p *parser) expect(text string) {
+	if !p.is(text) {
+		p.errorf("expected %q, got %q", text, p.tok.text)
+		return
+	}
+	p.next()
+}
+
+// ident parses a name and returns its token; or the zero token after an
+// error.
+func (p *parser) ident() tok {
+	t := p.tok
+	if t.kind != tokIdent {
+		p.errorf("expected an identifier, got %q", t.text)
+		return tok{}
+	}
+	p.next()
+	return t
+}
+
+// typed skips the optional type of a name e.g. `:u256`.
+func (p *parser) typed() {
+	if p.is(":") {
+		p.next()
+		p.ident()
+	}
+}
+
+func (p *parser) block(b *block) {
+	p.expect("{")
+	for !p.is("}") && p.tok.kind != tokEOF {
+		p.statement(b)
+	}
+	p.expect("}")
+}
+
+func (p *parser) statement(b *block) {
+	switch {
+	case p.is("{"):
+		nested := &block{}
+		p.block(nested)
+		b.items = append(b.items, nested)
+	case p.is("function"):
+		p.next()
+		name := p.ident()
+		b.functions = append(b.functions, name.text)
+		body := &block{function: true}
+		p.expect("(")
+		for !p.is(")") && p.tok.kind != tokEOF {
+			body.items = append(body.items, declaration{p.ident().text})
+			p.typed()
+			if !p.is(")") {
+				p.expect(",")
+			}
+		}
+		p.expect(")")
+		if p.is("->") {
+			p.next()
+			body.items = append(body.items, declaration{p.ident().text})
+			p.typed()
+			for p.is(",") {
+				p.next()
+				body.items = append(body.items, declaration{p.ident().text})
+				p.typed()
+			}
+		}
+		p.block(body)
+		b.items = append(b.items, body)
+	case p.is("let"):
+		p.next()
+		names := []string{p.ident().text}
+		p.typed()
+		for p.is(",") {
+			p.next()
+			names = append(names, p.ident().text)
+			p.typed()
+		}
+		// The value is evaluated before the variables are declared.
+		if p.is(":=") {
+			p.next()
+			p.expression(b)
+		}
+		for _, name := range names {
+			b.items = append(b.items, declaration{name})
+		}
+	case p.is("if"):
+		p.next()
+		p.expression(b)
+		nested := &block{}
+		p.block(nested)
+		b.items = append(b.items, nested)
+	case p.is("switch"):
+		p.next()
+		p.expression(b)
+		if !p.is("case") && !p.is("default") {
+			p.errorf("expected case or default, got %q", p.tok.text)
+		}
+		for p.is("case") {
+			p.next()
+			if p.tok.kind != tokLiteral {
+				p.errorf("expected a literal, got %q", p.tok.text)
+			}
+			p.next()
+			nested := &block{}
+			p.block(nested)
+			b.items = append(b.items, nested)
+		}
+		if p.is("default") {
+			p.next()
+			nested := &block{}
+			p.block(nested)
+			b.items = append(b.items, nested)
+		}
+	case p.is("for"):
+		p.next()
+		// The variables of the init block are visible in the rest of the
+		// loop, so the rest is nested in it.
+		init := &block{}
+		p.expect("{")
+		for !p.is("}") && p.tok.kind != tokEOF {
+			p.statement(init)
+		}
+		p.expect("}")
+		p.expression(init)
+		post, body := &block{}, &block{}
+		p.block(post)
+		p.block(body)
+		init.items = append(init.items, post, body)
+		b.items = append(b.items, init)
+	case p.is("break"), p.is("continue"), p.is("leave"):
+		p.next()
+	case p.tok.kind == tokIdent:
+		first := p.tok
+		p.next()
+		if p.is(",") || p.is(":=") {
+			// Assignment e.g. `a, b := f()`.
+			b.items = append(b.items, first)
+			for p.is(",") {
+				p.next()
+				b.items = append(b.items, p.ident())
+			}
+			p.expect(":=")
+			p.expression(b)
+			return
+		}
+		p.call(b, first)
+	default:
+		p.errorf("unexpected %q", p.tok.text)
+	}
+}
+
+func (p *parser) expression(b *block) {
+	switch p.tok.kind {
+	case tokLiteral:
+		p.next()
+		p.typed()
+	case tokIdent:
+		first := p.tok
+		p.next()
+		p.call(b, first)
+	default:
+		p.errorf("expected an expression, got %q", p.tok.text)
+	}
+}
+
+// call parses the rest of the expression starting with the name: the
+// arguments of a call, or nothing if the name is a variable.
+func (p *parser) call(b *block, name tok) {
+	if !p.is("(") {
+		b.items = append(b.items, name)
+		return
+	}
+	i := len(p.calls)
+	p.calls = append(p.calls, call{name: name})
+	p.next()
+	for arg := 0; !p.is(")") && p.tok.kind != tokEOF; arg++ {
+		if arg == 0 && p.tok.kind == tokIdent && strings.HasSuffix(p.tok.text, ".slot") {
+			p.calls[i].slot = p.tok
+		}
+		p.expression(b)
+		if !p.is(")") {
+			p.expect(",")
+		}
+	}
+	p.expect(")")
+}
@@ -0,0 +1,186 @@
+package yul
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_References(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []string // name, suffix and offset of each reference
+	}{
+		{
+			"calls and literals",
+			`mstore(ptr, add(value, 0x20)) sstore(0, "abc")`,
+			[]string{"ptr@7", "value@16"},
+		},
+		{
+			"suffixes",
+			`let x := sload(slot.slot) calldatacopy(0, data.offset, data.length) y.z := 1`,
+			[]string{"slot.slot@15", "data.offset@42", "data.length@55"},
+		},
+		{
+			"let shadows to the end of the block",
+			`let a := a { a := 1 } b := a`,
+			[]string{"a@9", "b@22"},
+		},
+		{
+			"nested let ends with the block",
+			`{ let a := 1 } a := 2`,
+			[]string{"a@15"},
+		},
+		{
+			"functions don't see the Solidity variables",
+			`function f(x) -> r { r := add(x, y) } f(y)`,
+			[]string{"y@40"},
+		},
+		{
+			"functions are visible before their declaration",
+			`f() function f() {}`,
+			[]string{},
+		},
+		{
+			"for init scope",
+			`for { let i := 0 } lt(i, n) { i := add(i, 1) } { sum := add(sum, i) } i := 0`,
+			[]string{"n@25", "sum@49", "sum@60", "i@70"},
+		},
+		{
+			"if and switch",
+			"if iszero(ok) { revert(0, 0) }\nswitch mode case 0 { x := 1 } default { x := 2 }",
+			[]string{"ok@10", "mode@38", "x@52", "x@71"},
+		},
+		{
+			"typed literals and comments",
+			"// total\nlet a:u256 := 1:u256 /* total */ sstore(a, total)",
+			[]string{"total@52"},
+		},
+	}
+
+	for _, tt := range tests {
+		refs, err := References(tt.body, 100)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %s", tt.name, err)
+			continue
+		}
+		got := []string{}
+		for _, ref := range refs {
+			name := ref.Name
+			if ref.Suffix != "" {
+				name += "." + ref.Suffix
+			}
+			got = append(got, fmt.Sprintf("%s@%d", name, ref.Pos-100))
+		}
+		if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func Test_ReferencesErrors(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{`mstore(0 x)`, `109: expected ",", got "x"`},
+		{`let := 1`, `104: expected an identifier, got ":="`},
+		{`switch x { }`, `109: expected case or default, got "{"`},
+		{`x := 1 /* open`, `107: unterminated comment`},
+		{`a + b`, `102: unexpected character '+'`},
+	}
+
+	for _, tt := range tests {
+		refs, err := References(tt.body, 100)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Expected error %q for %q, got %v", tt.expected, tt.body, err)
+		}
+		if refs != nil {
+			t.Errorf("Expected no references for %q, got %v", tt.body, refs)
+		}
+	}
+}
+
+func Test_Calls(t *testing.T) {
+	calls, err := Calls("if iszero(tload(0)) { tstore(0, 1) } function f() {} f()", 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	got := []string{}
+	for _, call := range calls {
+		got = append(got, fmt.Sprintf("%s@%d", call.Name, call.Pos-100))
+	}
+	if expected := "iszero@3 tload@10 tstore@22 f@53"; strings.Join(got, " ") != expected {
+		t.Errorf("Expected %s, got %s", expected, strings.Join(got, " "))
+	}
+}
+
+func Test_BuiltinAvailable(t *testing.T) {
+	tests := []struct {
+		name       string
+		evmVersion string
+		expected   bool
+	}{
+		{"tstore", "cancun", true},
+		{"tstore", "prague", true},
+		{"tload", "shanghai", false},
+		{"mcopy", "paris", false},
+		{"push0", "london", true}, // not a builtin
+		{"sstore", "homestead", true},
+		{"tstore", "", true},
+		{"tstore", "unknown", true},
+	}
+	for _, tt := range tests {
+		if got := BuiltinAvailable(tt.name, tt.evmVersion); got != tt.expected {
+			t.Errorf("%s under %q: expected %v, got %v", tt.name, tt.evmVersion, tt.expected, got)
+		}
+	}
+}
+
+func Test_CallSlots(t *testing.T) {
+	body := `sstore(total.slot, add(sload(total.slot), 1))
+let p := sload(0x20)
+tstore(locked.slot, 1)
+mstore(total.slot, 1)
+function f() { sstore(hidden.slot, 1) }`
+	calls, err := Calls(body, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	got := []string{}
+	for _, call := range calls {
+		if BuiltinEffect(call.Name) == NoEffect {
+			continue
+		}
+		slot := "?"
+		if call.Slot != nil {
+			slot = call.Slot.Name
+		}
+		got = append(got, fmt.Sprintf("%s(%s)@%d", call.Name, slot, call.Pos-100))
+	}
+	expected := "sstore(total)@0 sload(total)@23 sload(?)@55 tstore(locked)@67 sstore(?)@127"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected %s, got %s", expected, strings.Join(got, " "))
+	}
+}
+
+func Test_BuiltinEffect(t *testing.T) {
+	tests := map[string]Effect{
+		"sstore":       StorageWrite,
+		"tload":        TransientRead,
+		"delegatecall": ExternalCall,
+		"create2":      ExternalCall,
+		"staticcall":   StaticCall,
+		"log2":         Emission,
+		"selfdestruct": SelfDestruct,
+		"balance":      AccountRead,
+		"mstore":       NoEffect,
+		"f":            NoEffect,
+	}
+	for name, expected := range tests {
+		if got := BuiltinEffect(name); got != expected {
+			t.Errorf("%s: expected %d, got %d", name, expected, got)
+		}
+	}
+}